@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/foxzi/sendry/internal/web/backup"
+	"github.com/foxzi/sendry/internal/web/config"
+	"github.com/foxzi/sendry/internal/web/db"
+	"github.com/spf13/cobra"
+)
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Create a database backup now",
+	Long:  `Creates a compressed backup of the database in the backup directory, uploads it to S3 if configured and prunes old backups.`,
+	RunE:  runBackup,
+}
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore <backup-file>",
+	Short: "Restore the database from a backup",
+	Long: `Replaces the database with a backup created by sendry-web. Stop sendry-web before restoring.
+The current database is kept next to it with a .before-restore suffix.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRestore,
+}
+
+var backupDir string
+
+func init() {
+	backupCmd.Flags().StringVarP(&configFile, "config", "c", "/etc/sendry/web.yaml", "Path to configuration file")
+	backupCmd.Flags().StringVar(&backupDir, "dir", "", "Backup directory (overrides config)")
+	restoreCmd.Flags().StringVarP(&configFile, "config", "c", "/etc/sendry/web.yaml", "Path to configuration file")
+}
+
+func runBackup(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(configFile)
+	if err != nil {
+		return err
+	}
+	if backupDir != "" {
+		cfg.Backup.Dir = backupDir
+	}
+
+	database, err := db.New(cfg.Database.Path)
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	result := backup.NewManager(cfg.Backup, database.DB, logger).Run(context.Background())
+	if result.Error != "" {
+		return fmt.Errorf("backup failed: %s", result.Error)
+	}
+
+	fmt.Printf("Backup created: %s (%d bytes) in %s\n", result.File, result.Size, result.Duration.Round(time.Millisecond))
+	if result.Uploaded != "" {
+		fmt.Printf("Uploaded: %s\n", result.Uploaded)
+	}
+	for _, name := range result.Pruned {
+		fmt.Printf("Pruned: %s\n", name)
+	}
+	return nil
+}
+
+func runRestore(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load(configFile)
+	if err != nil {
+		return err
+	}
+
+	previous, err := backup.Restore(args[0], cfg.Database.Path)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Database restored to %s\n", cfg.Database.Path)
+	if previous != "" {
+		fmt.Printf("Previous database saved as %s\n", previous)
+	}
+	return nil
+}
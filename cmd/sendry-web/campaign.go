@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/foxzi/sendry/internal/web/handlers"
+	"github.com/foxzi/sendry/internal/web/models"
+	"github.com/spf13/cobra"
+)
+
+var (
+	campaignList        string
+	campaignSegment     string
+	campaignServers     []string
+	campaignStrategy    string
+	campaignDryRun      bool
+	campaignDryRunLimit int
+	campaignRate        int
+	campaignRampUp      string
+	campaignSchedule    string
+	campaignWait        bool
+	campaignInterval    time.Duration
+)
+
+var campaignCmd = &cobra.Command{
+	Use:   "campaign",
+	Short: "Campaign commands",
+}
+
+var campaignRunCmd = &cobra.Command{
+	Use:   "run <campaign-id>",
+	Short: "Start sending a campaign to a recipient list",
+	Long: `Create the send job of a campaign like the Send button of the web interface.
+The job is processed by the worker of the running sendry-web server.
+
+With --wait the command follows the job until it ends and fails unless the
+job completed.
+
+Examples:
+  sendry-web campaign run 3f6d... --list 9a1c... --servers prod-1,prod-2
+  sendry-web campaign run 3f6d... --list 9a1c... --dry-run --dry-run-limit 5 --wait
+  sendry-web campaign run 3f6d... --list 9a1c... --rate 100 --ramp-up 30:20,60:50
+  sendry-web campaign run 3f6d... --list 9a1c... --schedule 2026-11-02T09:00:00Z`,
+	Args: cobra.ExactArgs(1),
+	RunE: runCampaignRun,
+}
+
+func init() {
+	campaignCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "/etc/sendry/web.yaml", "Path to configuration file")
+	campaignCmd.PersistentFlags().StringVar(&headlessActor, "actor", "cli", "Name recorded in the audit log")
+
+	campaignRunCmd.Flags().StringVar(&campaignList, "list", "", "Recipient list ID (required)")
+	campaignRunCmd.Flags().StringVar(&campaignSegment, "segment", "", "Segment of the list to send to")
+	campaignRunCmd.Flags().StringSliceVar(&campaignServers, "servers", nil, "Sendry servers to send through (default: all configured)")
+	campaignRunCmd.Flags().StringVar(&campaignStrategy, "strategy", models.StrategyRoundRobin, "Server distribution strategy (round-robin, random, weighted, failover, least-loaded)")
+	campaignRunCmd.Flags().BoolVar(&campaignDryRun, "dry-run", false, "Send to the first recipients only")
+	campaignRunCmd.Flags().IntVar(&campaignDryRunLimit, "dry-run-limit", 10, "Recipients of a dry run")
+	campaignRunCmd.Flags().IntVar(&campaignRate, "rate", 0, "Messages per minute, 0 for no limit")
+	campaignRunCmd.Flags().StringVar(&campaignRampUp, "ramp-up", "", "Lower rates at the start, as minutes:rate pairs (e.g. 30:20,60:50)")
+	campaignRunCmd.Flags().StringVar(&campaignSchedule, "schedule", "", "Start time of the job (RFC 3339)")
+	campaignRunCmd.Flags().BoolVar(&campaignWait, "wait", false, "Wait until the job ends")
+	campaignRunCmd.Flags().DurationVar(&campaignInterval, "interval", 5*time.Second, "Poll interval with --wait")
+	campaignRunCmd.MarkFlagRequired("list")
+
+	campaignCmd.AddCommand(campaignRunCmd)
+	rootCmd.AddCommand(campaignCmd)
+}
+
+func runCampaignRun(cmd *cobra.Command, args []string) error {
+	req := &handlers.APICampaignSendRequest{
+		RecipientListID: campaignList,
+		SegmentID:       campaignSegment,
+		Servers:         campaignServers,
+		Strategy:        campaignStrategy,
+		DryRun:          campaignDryRun,
+		DryRunLimit:     campaignDryRunLimit,
+	}
+
+	throttle := &models.JobThrottle{RatePerMinute: campaignRate}
+	steps, err := models.ParseRampUp(campaignRampUp)
+	if err != nil {
+		return fmt.Errorf("invalid --ramp-up: %w", err)
+	}
+	throttle.RampUp = steps
+	req.Throttle = throttle
+
+	if campaignSchedule != "" {
+		at, err := time.Parse(time.RFC3339, campaignSchedule)
+		if err != nil {
+			return fmt.Errorf("invalid --schedule: %w", err)
+		}
+		req.ScheduledAt = &at
+	}
+
+	h, cfg, closeDB, err := newHeadlessHandlers()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	if len(req.Servers) == 0 {
+		for _, s := range cfg.Sendry.Servers {
+			req.Servers = append(req.Servers, s.Name)
+		}
+	}
+
+	job, err := h.StartCampaign(context.Background(), headlessActor, args[0], req)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Job %s created (%s)\n", job.ID, job.Status)
+
+	if !campaignWait {
+		return nil
+	}
+	return waitForJob(h, job.ID)
+}
+
+// waitForJob prints the progress of a send job until it ends
+func waitForJob(h *handlers.Handlers, jobID string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ticker := time.NewTicker(campaignInterval)
+	defer ticker.Stop()
+
+	last := ""
+	for {
+		job, stats, err := h.JobProgress(jobID)
+		if err != nil {
+			return err
+		}
+
+		line := fmt.Sprintf("%s: %d/%d sent, %d queued, %d pending, %d failed, %d bounced",
+			job.Status, stats.Sent, stats.Total, stats.Queued, stats.Pending, stats.Failed, stats.Bounced)
+		if line != last {
+			fmt.Printf("[%s] %s\n", time.Now().Format("15:04:05"), line)
+			last = line
+		}
+
+		switch job.Status {
+		case "completed":
+			return nil
+		case "failed", "cancelled":
+			return fmt.Errorf("job %s %s", jobID, job.Status)
+		}
+
+		select {
+		case <-ctx.Done():
+			fmt.Printf("Stopped waiting, job %s keeps running on the server\n", jobID)
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+
+	"github.com/foxzi/sendry/internal/web/config"
+	"github.com/foxzi/sendry/internal/web/db"
+	"github.com/foxzi/sendry/internal/web/handlers"
+	"github.com/spf13/cobra"
+)
+
+var (
+	deployServers    []string
+	deployChangeNote string
+	deployJSON       bool
+
+	// Name the command line acts as in the audit log
+	headlessActor string
+)
+
+var deployCmd = &cobra.Command{
+	Use:   "deploy",
+	Short: "Deploy objects to Sendry servers from files",
+}
+
+var deployTemplateCmd = &cobra.Command{
+	Use:   "template <file>",
+	Short: "Save a template from an export file and deploy it",
+	Long: `Save a template from a JSON file in the format of the template export of the
+web interface, then deploy its current version to the given servers.
+
+The template is matched by name. It is created if it does not exist, and a
+new version is added only if the content of the file differs, so running the
+command again with the same file just redeploys. Templates breaking their
+content policy are rejected.
+
+Examples:
+  sendry-web deploy template templates/welcome.json --servers prod-1,prod-2
+  sendry-web deploy template welcome.json --servers prod-1 --change-note "$(git log -1 --format=%s)"`,
+	Args: cobra.ExactArgs(1),
+	RunE: runDeployTemplate,
+}
+
+func init() {
+	deployCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "/etc/sendry/web.yaml", "Path to configuration file")
+	deployCmd.PersistentFlags().StringVar(&headlessActor, "actor", "cli", "Name recorded in the audit log and deployment history")
+
+	deployTemplateCmd.Flags().StringSliceVar(&deployServers, "servers", nil, "Sendry servers to deploy to (comma-separated)")
+	deployTemplateCmd.Flags().StringVar(&deployChangeNote, "change-note", "", "Note of the new template version")
+	deployTemplateCmd.Flags().BoolVar(&deployJSON, "json", false, "Print the result as JSON")
+	deployTemplateCmd.MarkFlagRequired("servers")
+
+	deployCmd.AddCommand(deployTemplateCmd)
+	rootCmd.AddCommand(deployCmd)
+}
+
+// newHeadlessHandlers opens the database of the config and returns the
+// handlers whose services the command line runs
+func newHeadlessHandlers() (*handlers.Handlers, *config.Config, func(), error) {
+	cfg, err := config.Load(configFile)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	database, err := db.New(cfg.Database.Path)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	return handlers.New(cfg, database, logger, nil, nil), cfg, func() { database.Close() }, nil
+}
+
+func runDeployTemplate(cmd *cobra.Command, args []string) error {
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	var file handlers.TemplateExportData
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("invalid template file %s: %w", args[0], err)
+	}
+
+	h, _, closeDB, err := newHeadlessHandlers()
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	result, err := h.SyncTemplate(context.Background(), headlessActor, &file, deployChangeNote, deployServers)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, r := range result.Results {
+		if r.Status != "deployed" {
+			failed++
+		}
+	}
+
+	if deployJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(result); err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("Template %s (%s): version %d, %s\n\n", result.Name, result.TemplateID, result.Version, result.Change)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SERVER\tSTATUS\tREMOTE ID\tERROR")
+		for _, r := range result.Results {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.Server, r.Status, r.RemoteID, r.Error)
+		}
+		w.Flush()
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("deploy failed on %d of %d servers", failed, len(result.Results))
+	}
+	return nil
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/foxzi/sendry/internal/web/config"
 	"github.com/foxzi/sendry/internal/web/db"
 	"github.com/foxzi/sendry/internal/web/dnsprovider"
+	"github.com/foxzi/sendry/internal/web/dnspublish"
 	"github.com/foxzi/sendry/internal/web/dnssync"
 	"github.com/foxzi/sendry/internal/web/models"
 	"github.com/foxzi/sendry/internal/web/repository"
@@ -36,6 +37,10 @@ var dnsSyncCmd = &cobra.Command{
 Supported providers:
   - cloudflare
   - namedot (github.com/foxzi/namedot)
+  - route53, rfc2136 (credentials from the dns_publishing section of the config)
+
+Without --provider, the provider of the dns_publishing section is used when
+it is configured.
 
 Cloudflare authentication (one of):
   - API Token (recommended): --token or CLOUDFLARE_API_TOKEN.
@@ -55,7 +60,7 @@ func init() {
 	dnsSyncCmd.Flags().StringVarP(&dnsSyncDomain, "domain", "d", "", "Domain to sync (by domain name)")
 	dnsSyncCmd.Flags().BoolVar(&dnsSyncAll, "all", false, "Sync all domains")
 	dnsSyncCmd.Flags().BoolVar(&dnsSyncApply, "apply", false, "Apply changes (default is plan only)")
-	dnsSyncCmd.Flags().StringVar(&dnsSyncProvider, "provider", "cloudflare", "DNS provider (cloudflare, namedot)")
+	dnsSyncCmd.Flags().StringVar(&dnsSyncProvider, "provider", "cloudflare", "DNS provider (cloudflare, namedot, route53, rfc2136)")
 	dnsSyncCmd.Flags().StringVar(&dnsSyncToken, "token", "", "Provider API token or global key (overrides env)")
 	dnsSyncCmd.Flags().StringVar(&dnsSyncEmail, "email", "", "Account email for legacy Cloudflare Global API Key (overrides env)")
 	dnsSyncCmd.Flags().StringVar(&dnsSyncAuthMode, "auth", "auto", "Cloudflare auth mode: auto, token, global")
@@ -77,7 +82,7 @@ func runDNSSync(cmd *cobra.Command, args []string) error {
 	}
 	defer database.Close()
 
-	provider, err := buildProvider()
+	provider, err := buildProvider(cmd, cfg)
 	if err != nil {
 		return err
 	}
@@ -100,7 +105,7 @@ func runDNSSync(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("no domains found")
 	}
 
-	syncer := &dnssync.Syncer{Provider: provider}
+	syncer := &dnssync.Syncer{Provider: provider, TTL: cfg.DNSPublishing.TTL}
 	ctx := context.Background()
 
 	mode := "plan"
@@ -139,12 +144,20 @@ func runDNSSync(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func buildProvider() (dnsprovider.Provider, error) {
+func buildProvider(cmd *cobra.Command, cfg *config.Config) (dnsprovider.Provider, error) {
+	if !cmd.Flags().Changed("provider") && cfg.DNSPublishing.Provider != "" {
+		return dnspublish.NewProvider(cfg.DNSPublishing)
+	}
+
 	switch strings.ToLower(dnsSyncProvider) {
 	case "cloudflare", "cf":
 		return buildCloudflareProvider()
 	case "namedot":
 		return buildNamedotProvider()
+	case config.DNSProviderRoute53, config.DNSProviderRFC2136:
+		pc := cfg.DNSPublishing
+		pc.Provider = strings.ToLower(dnsSyncProvider)
+		return dnspublish.NewProvider(pc)
 	default:
 		return nil, fmt.Errorf("unsupported provider %q", dnsSyncProvider)
 	}
@@ -34,6 +34,8 @@ func init() {
 	rootCmd.AddCommand(configCmd)
 	rootCmd.AddCommand(cleanupCmd)
 	rootCmd.AddCommand(dnsSyncCmd)
+	rootCmd.AddCommand(backupCmd)
+	rootCmd.AddCommand(restoreCmd)
 }
 
 func main() {
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
@@ -18,10 +19,14 @@ var serveCmd = &cobra.Command{
 	RunE:  runServe,
 }
 
-var configFile string
+var (
+	configFile string
+	ssoOnly    bool
+)
 
 func init() {
 	serveCmd.Flags().StringVarP(&configFile, "config", "c", "/etc/sendry/web.yaml", "Path to configuration file")
+	serveCmd.Flags().BoolVar(&ssoOnly, "sso-only", false, "Disable password logins, overriding auth.local_enabled (requires OIDC)")
 }
 
 func runServe(cmd *cobra.Command, args []string) error {
@@ -30,6 +35,12 @@ func runServe(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	if ssoOnly {
+		if !cfg.Auth.OIDC.Enabled {
+			return fmt.Errorf("--sso-only requires auth.oidc.enabled")
+		}
+		cfg.Auth.LocalEnabled = false
+	}
 
 	// Setup logger
 	var handler slog.Handler
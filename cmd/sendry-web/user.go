@@ -9,6 +9,7 @@ import (
 
 	"github.com/foxzi/sendry/internal/web/config"
 	"github.com/foxzi/sendry/internal/web/db"
+	"github.com/foxzi/sendry/internal/web/repository"
 	"github.com/google/uuid"
 	"github.com/spf13/cobra"
 	"golang.org/x/crypto/bcrypt"
@@ -123,6 +124,9 @@ func runUserCreate(cmd *cobra.Command, args []string) error {
 		}
 		return fmt.Errorf("failed to create user: %w", err)
 	}
+	if err := repository.NewOrganizationRepository(database.DB).JoinSole(id); err != nil {
+		return fmt.Errorf("failed to add user to organization: %w", err)
+	}
 
 	fmt.Printf("User %s created successfully\n", userEmail)
 	return nil
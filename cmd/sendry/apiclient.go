@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/foxzi/sendry/internal/config"
+)
+
+// serverAPI holds the flags of commands that call the API of a running
+// server, by default the one of the config file
+type serverAPI struct {
+	url      string
+	apiKey   string
+	insecure bool
+}
+
+// register adds the flags to cmd and its subcommands
+func (a *serverAPI) register(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&a.url, "url", "", "API URL of the server (default: api.listen_addr of the config)")
+	cmd.PersistentFlags().StringVar(&a.apiKey, "api-key", "", "API key (default: api.api_key of the config)")
+	cmd.PersistentFlags().BoolVar(&a.insecure, "insecure", false, "Skip verification of the API certificate")
+}
+
+// do calls the API and returns the response of a successful request, the
+// caller closes its body. A non-nil body is sent as JSON. timeout 0 means
+// no timeout.
+func (a *serverAPI) do(method, path string, body any, timeout time.Duration) (*http.Response, error) {
+	baseURL, apiKey, tlsCfg, err := a.endpoint()
+	if err != nil {
+		return nil, err
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, baseURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	client := &http.Client{
+		Timeout:   timeout,
+		Transport: &http.Transport{TLSClientConfig: tlsCfg},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach the server API: %w", err)
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	var apiErr struct {
+		Error string `json:"error"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if json.Unmarshal(data, &apiErr) == nil && apiErr.Error != "" {
+		return nil, &apiError{status: resp.StatusCode, message: apiErr.Error}
+	}
+	return nil, &apiError{status: resp.StatusCode, message: resp.Status}
+}
+
+// requestJSON calls the API with an optional JSON body and decodes the JSON
+// response into out
+func (a *serverAPI) requestJSON(method, path string, body, out any) error {
+	resp, err := a.do(method, path, body, 30*time.Second)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// endpoint returns the API URL, API key and TLS settings from the flags and
+// the config file
+func (a *serverAPI) endpoint() (string, string, *tls.Config, error) {
+	tlsCfg := &tls.Config{InsecureSkipVerify: a.insecure}
+	if a.url != "" {
+		return strings.TrimSuffix(a.url, "/"), a.apiKey, tlsCfg, nil
+	}
+
+	if cfgFile == "" {
+		return "", "", nil, fmt.Errorf("config file or --url is required")
+	}
+	cfg, err := config.Load(cfgFile)
+	if err != nil {
+		return "", "", nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	apiKey := a.apiKey
+	if apiKey == "" {
+		apiKey = cfg.API.APIKey
+	}
+	addr := cfg.API.ListenAddr
+	if strings.HasPrefix(addr, ":") {
+		addr = "localhost" + addr
+	}
+	scheme := "http"
+	if cfg.HasTLS() {
+		// The API certificate is issued for the server hostname
+		scheme = "https"
+		tlsCfg.ServerName = cfg.Server.Hostname
+	}
+	return scheme + "://" + addr, apiKey, tlsCfg, nil
+}
+
+// apiError is an error response of the server API
+type apiError struct {
+	status  int
+	message string
+}
+
+func (e *apiError) Error() string {
+	return "server: " + e.message
+}
+
+// apiStatus returns the HTTP status of an API error response, 0 for other
+// errors
+func apiStatus(err error) int {
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		return apiErr.status
+	}
+	return 0
+}
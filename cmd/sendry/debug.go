@@ -236,7 +236,7 @@ func checkTLS(cfg *config.Config) {
 		if _, err := os.Stat(cfg.SMTP.TLS.ACME.CacheDir); os.IsNotExist(err) {
 			fmt.Printf("  [WARN] ACME cache directory does not exist\n")
 		}
-	} else {
+	} else if cfg.SMTP.TLS.CertFile != "" {
 		fmt.Println("  [OK] Manual certificates configured")
 		fmt.Printf("  Cert file: %s\n", cfg.SMTP.TLS.CertFile)
 		fmt.Printf("  Key file: %s\n", cfg.SMTP.TLS.KeyFile)
@@ -250,6 +250,18 @@ func checkTLS(cfg *config.Config) {
 		}
 	}
 
+	// Certificates served by SNI for their domains
+	for _, domain := range cfg.DomainCertificates() {
+		dc := cfg.Domains[domain]
+		fmt.Printf("  [OK] %s: domain certificate %s\n", domain, dc.TLS.CertFile)
+		if _, err := os.Stat(dc.TLS.CertFile); os.IsNotExist(err) {
+			fmt.Printf("  [ERR] %s: certificate file not found\n", domain)
+		}
+		if _, err := os.Stat(dc.TLS.KeyFile); os.IsNotExist(err) {
+			fmt.Printf("  [ERR] %s: key file not found\n", domain)
+		}
+	}
+
 	// Try to connect with TLS to submission port
 	submissionAddr := cfg.SMTP.SubmissionAddr
 	if strings.HasPrefix(submissionAddr, ":") {
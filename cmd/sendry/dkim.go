@@ -3,32 +3,76 @@ package main
 import (
 	"crypto/x509"
 	"encoding/base64"
+	"encoding/pem"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 
+	"github.com/foxzi/sendry/internal/config"
 	"github.com/foxzi/sendry/internal/dkim"
+	"github.com/foxzi/sendry/internal/dnscheck"
 )
 
+// dkimTXTChunk is the longest character-string allowed in a TXT record
+const dkimTXTChunk = 255
+
 var (
 	dkimDomain   string
 	dkimSelector string
 	dkimKeyFile  string
 	dkimOutDir   string
+	dkimKeysDir  string
+	dkimForce    bool
+	dkimBIND     bool
+	dkimOutFile  string
 )
 
 var dkimCmd = &cobra.Command{
 	Use:   "dkim",
 	Short: "DKIM key management commands",
+	Long: `Manage DKIM keys without the HTTP API.
+
+Keys are stored as <keys-dir>/<domain>/<selector>.key, the layout used by the
+/api/v1/dkim endpoints. The directory is dkim.keys_dir of the config given
+with -c, /var/lib/sendry/dkim without a config, or --keys-dir.`,
 }
 
 var dkimGenerateCmd = &cobra.Command{
 	Use:   "generate",
 	Short: "Generate a new DKIM key pair",
-	Long:  `Generate a new RSA 2048-bit DKIM key pair and output DNS record.`,
-	RunE:  runDKIMGenerate,
+	Long: `Generate a new RSA 2048-bit DKIM key pair in the keys directory and output the DNS record.
+
+With --out the key is written to <out>/<domain>.key instead.`,
+	RunE: runDKIMGenerate,
+}
+
+var dkimImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Import an existing DKIM private key",
+	Long:  `Copy a PEM encoded RSA private key into the keys directory and output the DNS record. Use --key - to read the key from stdin.`,
+	RunE:  runDKIMImport,
+}
+
+var dkimExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export a DKIM private key",
+	Long:  `Print the PEM encoded private key of a domain and selector, or write it to --out.`,
+	RunE:  runDKIMExport,
+}
+
+var dkimDNSRecordCmd = &cobra.Command{
+	Use:   "dns-record",
+	Short: "Print the DNS TXT record of a DKIM key",
+	Long: `Print the DNS TXT record of the key of a domain and selector. The value is
+printed on a single line, or with --bind as a zone file record split into
+255 character strings.`,
+	RunE: runDKIMDNSRecord,
 }
 
 var dkimShowCmd = &cobra.Command{
@@ -39,39 +83,139 @@ var dkimShowCmd = &cobra.Command{
 }
 
 func init() {
+	dkimCmd.PersistentFlags().StringVar(&dkimKeysDir, "keys-dir", "", "DKIM keys directory (default: dkim.keys_dir from config)")
+
 	dkimGenerateCmd.Flags().StringVar(&dkimDomain, "domain", "", "Domain name (required)")
 	dkimGenerateCmd.Flags().StringVar(&dkimSelector, "selector", "sendry", "DKIM selector")
-	dkimGenerateCmd.Flags().StringVar(&dkimOutDir, "out", ".", "Output directory for key file")
+	dkimGenerateCmd.Flags().StringVar(&dkimOutDir, "out", "", "Output directory for <domain>.key instead of the keys directory")
+	dkimGenerateCmd.Flags().BoolVar(&dkimForce, "force", false, "Overwrite an existing key")
+	dkimGenerateCmd.Flags().BoolVar(&dkimBIND, "bind", false, "Print the DNS record in BIND zone file format")
 	dkimGenerateCmd.MarkFlagRequired("domain")
 
+	dkimImportCmd.Flags().StringVar(&dkimDomain, "domain", "", "Domain name (required)")
+	dkimImportCmd.Flags().StringVar(&dkimSelector, "selector", "sendry", "DKIM selector")
+	dkimImportCmd.Flags().StringVar(&dkimKeyFile, "key", "", "Path to private key file, - for stdin (required)")
+	dkimImportCmd.Flags().BoolVar(&dkimForce, "force", false, "Overwrite an existing key")
+	dkimImportCmd.Flags().BoolVar(&dkimBIND, "bind", false, "Print the DNS record in BIND zone file format")
+	dkimImportCmd.MarkFlagRequired("domain")
+	dkimImportCmd.MarkFlagRequired("key")
+
+	dkimExportCmd.Flags().StringVar(&dkimDomain, "domain", "", "Domain name (required)")
+	dkimExportCmd.Flags().StringVar(&dkimSelector, "selector", "sendry", "DKIM selector")
+	dkimExportCmd.Flags().StringVar(&dkimOutFile, "out", "", "Write the key to this file instead of stdout")
+	dkimExportCmd.MarkFlagRequired("domain")
+
+	dkimDNSRecordCmd.Flags().StringVar(&dkimDomain, "domain", "", "Domain name (required)")
+	dkimDNSRecordCmd.Flags().StringVar(&dkimSelector, "selector", "sendry", "DKIM selector")
+	dkimDNSRecordCmd.Flags().BoolVar(&dkimBIND, "bind", false, "Print the record in BIND zone file format")
+	dkimDNSRecordCmd.MarkFlagRequired("domain")
+
 	dkimShowCmd.Flags().StringVar(&dkimKeyFile, "key", "", "Path to private key file (required)")
 	dkimShowCmd.Flags().StringVar(&dkimDomain, "domain", "", "Domain name (required)")
 	dkimShowCmd.Flags().StringVar(&dkimSelector, "selector", "sendry", "DKIM selector")
 	dkimShowCmd.MarkFlagRequired("key")
 	dkimShowCmd.MarkFlagRequired("domain")
 
-	dkimCmd.AddCommand(dkimGenerateCmd, dkimShowCmd)
+	dkimCmd.AddCommand(dkimGenerateCmd, dkimImportCmd, dkimExportCmd, dkimDNSRecordCmd, dkimShowCmd)
 	rootCmd.AddCommand(dkimCmd)
 }
 
 func runDKIMGenerate(cmd *cobra.Command, args []string) error {
+	keyPath := filepath.Join(dkimOutDir, fmt.Sprintf("%s.key", dkimDomain))
+	if dkimOutDir == "" {
+		var err error
+		if keyPath, err = dkimKeyPath(dkimDomain, dkimSelector); err != nil {
+			return err
+		}
+	}
+	if err := checkKeyOverwrite(keyPath); err != nil {
+		return err
+	}
+
 	kp, err := dkim.GenerateKey(dkimDomain, dkimSelector)
 	if err != nil {
 		return fmt.Errorf("failed to generate key: %w", err)
 	}
 
-	keyPath := filepath.Join(dkimOutDir, fmt.Sprintf("%s.key", dkimDomain))
 	if err := kp.SavePrivateKey(keyPath); err != nil {
 		return fmt.Errorf("failed to save private key: %w", err)
 	}
 
 	fmt.Printf("DKIM key generated successfully\n\n")
 	fmt.Printf("Private key saved to: %s\n\n", keyPath)
-	fmt.Printf("DNS Record:\n")
-	fmt.Printf("  Name: %s\n", kp.DNSName())
-	fmt.Printf("  Type: TXT\n")
-	fmt.Printf("  Value: %s\n", kp.DNSRecord())
+	printDKIMRecord(kp)
+
+	return nil
+}
+
+func runDKIMImport(cmd *cobra.Command, args []string) error {
+	keyPath, err := dkimKeyPath(dkimDomain, dkimSelector)
+	if err != nil {
+		return err
+	}
+	if err := checkKeyOverwrite(keyPath); err != nil {
+		return err
+	}
+
+	var data []byte
+	if dkimKeyFile == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(dkimKeyFile)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read key file: %w", err)
+	}
+
+	privateKey, err := dkim.ParsePrivateKey(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	kp := &dkim.KeyPair{PrivateKey: privateKey, Domain: dkimDomain, Selector: dkimSelector}
+	if err := kp.SavePrivateKey(keyPath); err != nil {
+		return fmt.Errorf("failed to save private key: %w", err)
+	}
+
+	fmt.Printf("DKIM key imported successfully\n\n")
+	fmt.Printf("Private key saved to: %s\n\n", keyPath)
+	printDKIMRecord(kp)
+
+	return nil
+}
+
+func runDKIMExport(cmd *cobra.Command, args []string) error {
+	kp, err := loadDKIMKey(dkimDomain, dkimSelector)
+	if err != nil {
+		return err
+	}
+
+	data := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(kp.PrivateKey),
+	})
+	if dkimOutFile == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(dkimOutFile, data, 0600); err != nil {
+		return fmt.Errorf("failed to write key file: %w", err)
+	}
+	fmt.Printf("Private key written to: %s\n", dkimOutFile)
+	return nil
+}
+
+func runDKIMDNSRecord(cmd *cobra.Command, args []string) error {
+	kp, err := loadDKIMKey(dkimDomain, dkimSelector)
+	if err != nil {
+		return err
+	}
 
+	if dkimBIND {
+		fmt.Println(bindTXTRecord(kp.DNSName(), kp.DNSRecord()))
+	} else {
+		fmt.Println(kp.DNSRecord())
+	}
 	return nil
 }
 
@@ -101,3 +245,95 @@ func runDKIMShow(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// resolveDKIMKeysDir returns --keys-dir, dkim.keys_dir of the config or
+// the default keys directory
+func resolveDKIMKeysDir() (string, error) {
+	if dkimKeysDir != "" {
+		return dkimKeysDir, nil
+	}
+	if cfgFile == "" {
+		return "/var/lib/sendry/dkim", nil
+	}
+	cfg, err := config.Load(cfgFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to load config: %w", err)
+	}
+	return cfg.DKIM.KeysDir, nil
+}
+
+// dkimKeyPath returns the path of the key of a domain and selector in the
+// keys directory
+func dkimKeyPath(domain, selector string) (string, error) {
+	// Domain and selector become path elements, so reject anything else
+	if err := dnscheck.ValidateDomain(domain); err != nil {
+		return "", fmt.Errorf("%w: %s", err, domain)
+	}
+	if selector == "" {
+		return "", fmt.Errorf("selector is required")
+	}
+	if err := dnscheck.ValidateSelector(selector); err != nil {
+		return "", fmt.Errorf("%w: %s", err, selector)
+	}
+
+	dir, err := resolveDKIMKeysDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, domain, selector+".key"), nil
+}
+
+func loadDKIMKey(domain, selector string) (*dkim.KeyPair, error) {
+	keyPath, err := dkimKeyPath(domain, selector)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, err := dkim.LoadPrivateKey(keyPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("no DKIM key for %s with selector %s in %s", domain, selector, filepath.Dir(keyPath))
+		}
+		return nil, fmt.Errorf("failed to load private key: %w", err)
+	}
+	return &dkim.KeyPair{PrivateKey: privateKey, Domain: domain, Selector: selector}, nil
+}
+
+func checkKeyOverwrite(keyPath string) error {
+	if _, err := os.Stat(keyPath); err == nil && !dkimForce {
+		return fmt.Errorf("key file %s already exists (use --force to overwrite)", keyPath)
+	}
+	return nil
+}
+
+func printDKIMRecord(kp *dkim.KeyPair) {
+	if dkimBIND {
+		fmt.Printf("DNS Record:\n%s\n", bindTXTRecord(kp.DNSName(), kp.DNSRecord()))
+		return
+	}
+	fmt.Printf("DNS Record:\n")
+	fmt.Printf("  Name: %s\n", kp.DNSName())
+	fmt.Printf("  Type: TXT\n")
+	fmt.Printf("  Value: %s\n", kp.DNSRecord())
+}
+
+// bindTXTRecord formats a TXT record for a zone file, splitting the value
+// into quoted strings of at most 255 characters that resolvers join again
+func bindTXTRecord(name, value string) string {
+	var chunks []string
+	for len(value) > dkimTXTChunk {
+		chunks = append(chunks, value[:dkimTXTChunk])
+		value = value[dkimTXTChunk:]
+	}
+	chunks = append(chunks, value)
+
+	if len(chunks) == 1 {
+		return fmt.Sprintf("%s. IN TXT \"%s\"", name, chunks[0])
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s. IN TXT (", name)
+	for _, chunk := range chunks {
+		fmt.Fprintf(&b, "\n\t\"%s\"", chunk)
+	}
+	b.WriteString(" )")
+	return b.String()
+}
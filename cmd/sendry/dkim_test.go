@@ -0,0 +1,78 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/foxzi/sendry/internal/dkim"
+)
+
+func TestBindTXTRecord(t *testing.T) {
+	if got := bindTXTRecord("s._domainkey.example.com", "v=DKIM1; p=abc"); got != `s._domainkey.example.com. IN TXT "v=DKIM1; p=abc"` {
+		t.Errorf("short record = %q", got)
+	}
+
+	value := strings.Repeat("a", 600)
+	got := bindTXTRecord("s._domainkey.example.com", value)
+	lines := strings.Split(got, "\n")
+	if len(lines) != 4 || lines[0] != "s._domainkey.example.com. IN TXT (" || !strings.HasSuffix(lines[3], `" )`) {
+		t.Fatalf("long record = %q", got)
+	}
+	var joined string
+	for i, line := range lines[1:] {
+		chunk := strings.TrimSuffix(strings.TrimSpace(line), " )")
+		chunk = strings.Trim(chunk, `"`)
+		if len(chunk) > dkimTXTChunk {
+			t.Errorf("chunk %d has %d characters", i, len(chunk))
+		}
+		joined += chunk
+	}
+	if joined != value {
+		t.Error("chunks do not join to the value")
+	}
+}
+
+func TestDKIMImportExport(t *testing.T) {
+	dir := t.TempDir()
+	dkimKeysDir, dkimDomain, dkimSelector = filepath.Join(dir, "keys"), "example.com", "mail"
+	defer func() { dkimKeysDir, dkimDomain, dkimSelector, dkimKeyFile, dkimForce = "", "", "", "", false }()
+
+	kp, err := dkim.GenerateKey("example.com", "mail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dkimKeyFile = filepath.Join(dir, "import.key")
+	if err := kp.SavePrivateKey(dkimKeyFile); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runDKIMImport(nil, nil); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "keys", "example.com", "mail.key")); err != nil {
+		t.Fatalf("imported key: %v", err)
+	}
+	if err := runDKIMImport(nil, nil); err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Errorf("second import without --force: %v", err)
+	}
+
+	loaded, err := loadDKIMKey("example.com", "mail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.PrivateKey.Equal(kp.PrivateKey) || loaded.DNSRecord() != kp.DNSRecord() {
+		t.Error("loaded key differs from the imported one")
+	}
+
+	if _, err := loadDKIMKey("example.com", "other"); err == nil || !strings.Contains(err.Error(), "no DKIM key") {
+		t.Errorf("missing selector: %v", err)
+	}
+	if _, err := dkimKeyPath("../example.com", "mail"); err == nil {
+		t.Error("path traversal in domain accepted")
+	}
+	if _, err := dkimKeyPath("example.com", "../mail"); err == nil {
+		t.Error("path traversal in selector accepted")
+	}
+}
@@ -0,0 +1,478 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/foxzi/sendry/internal/config"
+	"github.com/foxzi/sendry/internal/dkim"
+	"github.com/foxzi/sendry/internal/dnscheck"
+)
+
+// Exit codes of the doctor command. 1 is left to cobra for usage and
+// configuration errors, so CI can tell a broken setup from a broken run.
+const (
+	doctorExitErrors   = 2
+	doctorExitWarnings = 3
+)
+
+var (
+	doctorSelector string
+	doctorHostname string
+	doctorIPs      []string
+	doctorProbes   []string
+	doctorSkipPort bool
+	doctorStrict   bool
+	doctorFormat   string
+	doctorTimeout  time.Duration
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor <domain>",
+	Short: "Diagnose DNS and deliverability problems for a sending domain",
+	Long: `Run the DNS record checks for a domain, verify that the DKIM key in the
+config matches the record published in DNS, check that the reverse DNS of
+the sending IPs points back to the HELO hostname, and test whether outbound
+connections to port 25 are possible. Every problem comes with a suggested fix.
+
+Exit status is 0 when no problems were found, 2 when a check failed and 3
+when only warnings were found and --strict is set.
+
+Examples:
+  sendry doctor example.com -c /etc/sendry/config.yaml
+  sendry doctor example.com --ip 203.0.113.10 --strict
+  sendry doctor example.com --skip-port25 --format json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runDoctor,
+}
+
+func init() {
+	doctorCmd.Flags().StringVar(&doctorSelector, "selector", "sendry", "DKIM selector to check when the domain has no DKIM config")
+	doctorCmd.Flags().StringVar(&doctorHostname, "hostname", "", "HELO hostname (default: server.hostname from config)")
+	doctorCmd.Flags().StringSliceVar(&doctorIPs, "ip", nil, "Sending IP to check reverse DNS for (default: addresses of the HELO hostname)")
+	doctorCmd.Flags().StringSliceVar(&doctorProbes, "probe", []string{"gmail-smtp-in.l.google.com:25", "mx.yandex.ru:25"}, "Mail servers used to test outbound port 25")
+	doctorCmd.Flags().BoolVar(&doctorSkipPort, "skip-port25", false, "Skip the outbound port 25 test")
+	doctorCmd.Flags().BoolVar(&doctorStrict, "strict", false, "Exit with a nonzero status on warnings too")
+	doctorCmd.Flags().StringVar(&doctorFormat, "format", "table", "Output format (table, json)")
+	doctorCmd.Flags().DurationVar(&doctorTimeout, "timeout", 30*time.Second, "Timeout for all checks")
+
+	rootCmd.AddCommand(doctorCmd)
+}
+
+// doctorCheck is a single finding of the doctor command
+type doctorCheck struct {
+	Section string `json:"section"`
+	dnscheck.CheckResult
+	Fix string `json:"fix,omitempty"`
+}
+
+// doctorReport is the full output of the doctor command
+type doctorReport struct {
+	Domain   string           `json:"domain"`
+	Hostname string           `json:"hostname"`
+	Checks   []doctorCheck    `json:"checks"`
+	Summary  dnscheck.Summary `json:"summary"`
+}
+
+// doctorResolver is the subset of net.Resolver used by the reverse DNS checks
+type doctorResolver interface {
+	LookupAddr(ctx context.Context, addr string) ([]string, error)
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	domain := strings.ToLower(strings.TrimSuffix(args[0], "."))
+	if err := dnscheck.ValidateDomain(domain); err != nil {
+		return fmt.Errorf("%w: %s", err, domain)
+	}
+	if doctorFormat != "table" && doctorFormat != "json" {
+		return fmt.Errorf("unknown format %q (use table or json)", doctorFormat)
+	}
+
+	var cfg *config.Config
+	if cfgFile != "" {
+		var err error
+		if cfg, err = config.Load(cfgFile); err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+	}
+
+	hostname := doctorHostname
+	if hostname == "" && cfg != nil {
+		hostname = cfg.Server.Hostname
+	}
+	if hostname == "" {
+		hostname, _ = os.Hostname()
+	}
+	hostname = strings.ToLower(strings.TrimSuffix(hostname, "."))
+
+	ctx, cancel := context.WithTimeout(context.Background(), doctorTimeout)
+	defer cancel()
+
+	report := &doctorReport{Domain: domain, Hostname: hostname}
+	report.add("DNS", doctorDNSChecks(ctx, domain)...)
+	report.add("DKIM", doctorDKIMCheck(ctx, cfg, domain))
+	report.add("Reverse DNS", doctorPTRChecks(ctx, net.DefaultResolver, hostname, doctorIPs)...)
+	if !doctorSkipPort {
+		report.add("Outbound SMTP", doctorPortCheck(ctx, doctorProbes))
+	}
+
+	if doctorFormat == "json" {
+		if err := outputJSON(report); err != nil {
+			return err
+		}
+	} else {
+		printDoctorReport(report)
+	}
+
+	if code := doctorExitCode(report.Summary, doctorStrict); code != 0 {
+		os.Exit(code)
+	}
+	return nil
+}
+
+func (r *doctorReport) add(section string, checks ...doctorCheck) {
+	for _, c := range checks {
+		c.Section = section
+		r.Checks = append(r.Checks, c)
+		switch c.Status {
+		case "ok":
+			r.Summary.OK++
+		case "warning":
+			r.Summary.Warnings++
+		case "error":
+			r.Summary.Errors++
+		case "not_found":
+			r.Summary.NotFound++
+		}
+	}
+}
+
+// doctorExitCode maps a report summary to the process exit status
+func doctorExitCode(s dnscheck.Summary, strict bool) int {
+	if s.Errors > 0 {
+		return doctorExitErrors
+	}
+	if strict && s.Warnings > 0 {
+		return doctorExitWarnings
+	}
+	return 0
+}
+
+// doctorDNSChecks runs the dnscheck suite. Missing SPF is an error and a
+// missing MX or DMARC record a warning, since large mailbox providers reject
+// or junk mail without them; MTA-STS stays optional.
+func doctorDNSChecks(ctx context.Context, domain string) []doctorCheck {
+	mx := doctorCheck{CheckResult: dnscheck.CheckMX(ctx, domain)}
+	if mx.Status == "not_found" {
+		mx.Status = "warning"
+		mx.Fix = fmt.Sprintf("Publish an MX record for %s so bounces and replies can be delivered", domain)
+	}
+
+	spf := doctorCheck{CheckResult: dnscheck.CheckSPF(ctx, domain)}
+	switch spf.Status {
+	case "not_found":
+		spf.Status = "error"
+		spf.Fix = fmt.Sprintf(`Add a TXT record to %s: "v=spf1 a mx ~all" and list every sending IP with ip4:/ip6:`, domain)
+	case "warning":
+		spf.Fix = "Replace +all with ~all or -all so only listed hosts may send for the domain"
+	}
+
+	dmarc := doctorCheck{CheckResult: dnscheck.CheckDMARC(ctx, domain)}
+	switch dmarc.Status {
+	case "not_found":
+		dmarc.Status = "warning"
+		dmarc.Fix = fmt.Sprintf(`Add a TXT record to _dmarc.%s: "v=DMARC1; p=none; rua=mailto:dmarc@%s"`, domain, domain)
+	case "warning":
+		dmarc.Fix = "Move to p=quarantine or p=reject once the aggregate reports show only your own sources"
+	}
+
+	mtasts := doctorCheck{CheckResult: dnscheck.CheckMTASTS(ctx, domain)}
+
+	checks := []doctorCheck{mx, spf, dmarc, mtasts}
+	for i := range checks {
+		if checks[i].Status == "error" && checks[i].Fix == "" {
+			checks[i].Fix = "Check that the domain exists and its name servers answer"
+		}
+	}
+	return checks
+}
+
+// doctorDKIMCheck verifies that the public key published for the configured
+// selector belongs to the configured private key
+func doctorDKIMCheck(ctx context.Context, cfg *config.Config, domain string) doctorCheck {
+	if cfg == nil {
+		check := doctorCheck{CheckResult: dnscheck.CheckDKIM(ctx, domain, doctorSelector)}
+		check.Message = strings.TrimSpace(check.Message + " (no config given, key not compared)")
+		if check.Status == "not_found" {
+			check.Status = "error"
+			check.Fix = fmt.Sprintf("Generate a key with 'sendry dkim generate --domain %s' and publish the printed TXT record", domain)
+		}
+		return check
+	}
+
+	enabled, selector, keyFile := cfg.GetDKIMConfig(domain)
+	check := doctorCheck{CheckResult: dnscheck.CheckResult{Type: fmt.Sprintf("DKIM Key (%s._domainkey)", selector)}}
+	if !enabled {
+		check.Type = "DKIM Key"
+		check.Status = "error"
+		check.Message = "DKIM signing is not enabled for this domain"
+		check.Fix = fmt.Sprintf("Generate a key with 'sendry dkim generate --domain %s' and set domains.%s.dkim in the config", domain, domain)
+		return check
+	}
+
+	privateKey, err := dkim.LoadPrivateKey(keyFile)
+	if err != nil {
+		check.Status = "error"
+		check.Message = fmt.Sprintf("Cannot load key file %s: %v", keyFile, err)
+		check.Fix = "Check dkim.key_file in the config and the file permissions"
+		return check
+	}
+	expected := (&dkim.KeyPair{PrivateKey: privateKey, Domain: domain, Selector: selector}).DNSRecord()
+
+	name := fmt.Sprintf("%s._domainkey.%s", selector, domain)
+	txtRecords, err := net.DefaultResolver.LookupTXT(ctx, name)
+	if err != nil {
+		check.Status = "error"
+		if dnsErr, ok := err.(*net.DNSError); ok && dnsErr.IsNotFound {
+			check.Message = fmt.Sprintf("No DKIM record published for selector '%s'", selector)
+		} else {
+			check.Message = fmt.Sprintf("Lookup failed: %v", err)
+		}
+		check.Fix = fmt.Sprintf(`Add a TXT record to %s: "%s"`, name, expected)
+		return check
+	}
+
+	record := strings.Join(txtRecords, "")
+	check.Value = truncateString(record, 100)
+	check.Status, check.Message = matchDKIMKey(record, &privateKey.PublicKey)
+	if check.Status != "ok" {
+		check.Fix = fmt.Sprintf(`Replace the TXT record at %s with: "%s"`, name, expected)
+	}
+	return check
+}
+
+// matchDKIMKey compares the public key of a DKIM TXT record with the key
+// used for signing
+func matchDKIMKey(record string, key *rsa.PublicKey) (status, message string) {
+	tags := make(map[string]string)
+	for _, tag := range strings.Split(record, ";") {
+		name, value, ok := strings.Cut(tag, "=")
+		if !ok {
+			continue
+		}
+		tags[strings.TrimSpace(name)] = strings.Join(strings.Fields(value), "")
+	}
+
+	if v, ok := tags["v"]; ok && v != "DKIM1" {
+		return "error", "TXT record is not a DKIM1 record"
+	}
+	if k, ok := tags["k"]; ok && k != "rsa" {
+		return "error", fmt.Sprintf("DNS publishes a %s key but the configured key is RSA", k)
+	}
+	p, ok := tags["p"]
+	if !ok {
+		return "error", "DKIM record has no public key (p=)"
+	}
+	if p == "" {
+		return "error", "DKIM key is revoked in DNS (empty p=)"
+	}
+
+	der, err := base64.StdEncoding.DecodeString(p)
+	if err != nil {
+		return "error", fmt.Sprintf("Public key in DNS is not valid base64: %v", err)
+	}
+	var published *rsa.PublicKey
+	if pub, err := x509.ParsePKIXPublicKey(der); err == nil {
+		published, _ = pub.(*rsa.PublicKey)
+	} else if pub, err := x509.ParsePKCS1PublicKey(der); err == nil {
+		published = pub
+	}
+	if published == nil {
+		return "error", "Public key in DNS cannot be parsed as an RSA key"
+	}
+	if !published.Equal(key) {
+		return "error", "Public key in DNS does not match the configured private key"
+	}
+	return "ok", fmt.Sprintf("DNS key matches the configured private key (RSA %d bits)", key.N.BitLen())
+}
+
+// doctorPTRChecks verifies that the HELO hostname is a resolvable FQDN and
+// that every sending IP has a PTR record which names the HELO hostname and
+// resolves back to the same IP (forward-confirmed reverse DNS)
+func doctorPTRChecks(ctx context.Context, resolver doctorResolver, hostname string, ips []string) []doctorCheck {
+	helo := doctorCheck{CheckResult: dnscheck.CheckResult{Type: "HELO Hostname", Value: hostname}}
+	if !strings.Contains(hostname, ".") {
+		helo.Status = "error"
+		helo.Message = "HELO hostname is not a fully qualified domain name"
+		helo.Fix = "Set server.hostname to the FQDN the sending IP's PTR record points to"
+		return []doctorCheck{helo}
+	}
+
+	if len(ips) == 0 {
+		addrs, err := resolver.LookupHost(ctx, hostname)
+		if err != nil || len(addrs) == 0 {
+			helo.Status = "error"
+			helo.Message = "HELO hostname does not resolve"
+			helo.Fix = fmt.Sprintf("Add an A record for %s pointing to the server's public IP, or pass --ip", hostname)
+			return []doctorCheck{helo}
+		}
+		ips = addrs
+	}
+	helo.Status = "ok"
+	helo.Message = "Sending IPs: " + strings.Join(ips, ", ")
+	checks := []doctorCheck{helo}
+
+	for _, ip := range ips {
+		check := doctorCheck{CheckResult: dnscheck.CheckResult{Type: "PTR " + ip}}
+		names, err := resolver.LookupAddr(ctx, ip)
+		if err != nil || len(names) == 0 {
+			check.Status = "error"
+			check.Message = "No PTR record"
+			check.Fix = fmt.Sprintf("Ask the owner of %s (usually the hosting provider) to set its PTR record to %s", ip, hostname)
+			checks = append(checks, check)
+			continue
+		}
+		for i := range names {
+			names[i] = strings.ToLower(strings.TrimSuffix(names[i], "."))
+		}
+		check.Value = strings.Join(names, ", ")
+
+		matched := ""
+		for _, name := range names {
+			if name == hostname {
+				matched = name
+				break
+			}
+		}
+		if matched == "" {
+			check.Status = "warning"
+			check.Message = fmt.Sprintf("PTR does not match HELO hostname %s", hostname)
+			check.Fix = fmt.Sprintf("Set server.hostname to %s or change the PTR record of %s to %s", names[0], ip, hostname)
+			checks = append(checks, check)
+			continue
+		}
+
+		forward, err := resolver.LookupHost(ctx, matched)
+		confirmed := false
+		for _, addr := range forward {
+			if net.ParseIP(addr).Equal(net.ParseIP(ip)) {
+				confirmed = true
+				break
+			}
+		}
+		if err != nil || !confirmed {
+			check.Status = "error"
+			check.Message = fmt.Sprintf("%s does not resolve back to %s", matched, ip)
+			check.Fix = fmt.Sprintf("Add %s to the A/AAAA records of %s", ip, matched)
+		} else {
+			check.Status = "ok"
+			check.Message = "PTR matches HELO hostname and resolves back"
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// doctorPortCheck tries to open an SMTP session with well-known mail servers
+// to detect providers that block outbound port 25
+func doctorPortCheck(ctx context.Context, probes []string) doctorCheck {
+	check := doctorCheck{CheckResult: dnscheck.CheckResult{Type: "Outbound Port 25"}}
+
+	var failures []string
+	for _, addr := range probes {
+		banner, err := smtpBanner(ctx, addr)
+		if err == nil {
+			check.Status = "ok"
+			check.Value = addr
+			check.Message = "Connected: " + truncateString(banner, 80)
+			return check
+		}
+		failures = append(failures, fmt.Sprintf("%s: %v", addr, err))
+	}
+
+	check.Status = "error"
+	check.Message = "Outbound port 25 appears blocked (" + strings.Join(failures, "; ") + ")"
+	check.Fix = "Many cloud providers block port 25 by default; ask them to lift the block or relay through a smarthost"
+	return check
+}
+
+// smtpBanner connects to addr and returns the server greeting
+func smtpBanner(ctx context.Context, addr string) (string, error) {
+	dialCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	conn, err := (&net.Dialer{}).DialContext(dialCtx, "tcp", addr)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	deadline, _ := dialCtx.Deadline()
+	conn.SetDeadline(deadline)
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("no greeting: %w", err)
+	}
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "220") {
+		return "", fmt.Errorf("unexpected greeting %q", line)
+	}
+	return line, nil
+}
+
+func printDoctorReport(r *doctorReport) {
+	fmt.Printf("Deliverability report for %s (HELO %s)\n", r.Domain, r.Hostname)
+
+	section := ""
+	for _, c := range r.Checks {
+		if c.Section != section {
+			section = c.Section
+			fmt.Printf("\n%s:\n", section)
+		}
+		statusIcon := "?"
+		switch c.Status {
+		case "ok":
+			statusIcon = "[OK]"
+		case "warning":
+			statusIcon = "[WARN]"
+		case "error":
+			statusIcon = "[ERR]"
+		case "not_found":
+			statusIcon = "[N/A]"
+		}
+		fmt.Printf("  %s %s\n", statusIcon, c.Type)
+		if c.Value != "" {
+			fmt.Printf("      Value: %s\n", c.Value)
+		}
+		if c.Message != "" {
+			fmt.Printf("      %s\n", c.Message)
+		}
+		if c.Fix != "" {
+			fmt.Printf("      Fix: %s\n", c.Fix)
+		}
+	}
+
+	fmt.Println()
+	summary := fmt.Sprintf("Summary: %d OK", r.Summary.OK)
+	if r.Summary.Warnings > 0 {
+		summary += fmt.Sprintf(", %d warnings", r.Summary.Warnings)
+	}
+	if r.Summary.Errors > 0 {
+		summary += fmt.Sprintf(", %d errors", r.Summary.Errors)
+	}
+	if r.Summary.NotFound > 0 {
+		summary += fmt.Sprintf(", %d not configured", r.Summary.NotFound)
+	}
+	fmt.Println(summary)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/foxzi/sendry/internal/dnscheck"
+)
+
+func TestMatchDKIMKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkix, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	p := base64.StdEncoding.EncodeToString(pkix)
+	pkcs1 := base64.StdEncoding.EncodeToString(x509.MarshalPKCS1PublicKey(&key.PublicKey))
+	otherPKIX, _ := x509.MarshalPKIXPublicKey(&other.PublicKey)
+	otherP := base64.StdEncoding.EncodeToString(otherPKIX)
+
+	tests := map[string]string{
+		"v=DKIM1; k=rsa; p=" + p:              "ok",
+		"v=DKIM1; p=" + p[:40] + " " + p[40:]: "ok",
+		"v=DKIM1; k=rsa; p=" + pkcs1:          "ok",
+		"v=DKIM1; k=rsa; p=" + otherP:         "error",
+		"v=DKIM1; k=rsa; p=":                  "error",
+		"v=DKIM1; k=ed25519; p=" + p:          "error",
+		"v=DKIM1; k=rsa":                      "error",
+	}
+	for record, want := range tests {
+		if got, msg := matchDKIMKey(record, &key.PublicKey); got != want {
+			t.Errorf("matchDKIMKey(%.40q) = %s (%s), want %s", record, got, msg, want)
+		}
+	}
+}
+
+type fakeResolver struct {
+	ptr  map[string][]string
+	host map[string][]string
+}
+
+func (f fakeResolver) LookupAddr(_ context.Context, addr string) ([]string, error) {
+	if names, ok := f.ptr[addr]; ok {
+		return names, nil
+	}
+	return nil, errors.New("not found")
+}
+
+func (f fakeResolver) LookupHost(_ context.Context, host string) ([]string, error) {
+	if addrs, ok := f.host[host]; ok {
+		return addrs, nil
+	}
+	return nil, errors.New("not found")
+}
+
+func TestDoctorPTRChecks(t *testing.T) {
+	resolver := fakeResolver{
+		ptr: map[string][]string{
+			"192.0.2.1": {"mail.example.com."},
+			"192.0.2.2": {"host-2.provider.net."},
+			"192.0.2.3": {"Mail.Example.com."},
+		},
+		host: map[string][]string{
+			"mail.example.com": {"192.0.2.1"},
+		},
+	}
+
+	checks := doctorPTRChecks(context.Background(), resolver, "mail.example.com", nil)
+	if len(checks) != 2 || checks[0].Status != "ok" || checks[1].Status != "ok" {
+		t.Fatalf("resolved HELO: %+v", checks)
+	}
+
+	checks = doctorPTRChecks(context.Background(), resolver, "mail.example.com", []string{"192.0.2.2", "192.0.2.3", "192.0.2.4"})
+	var got []string
+	for _, c := range checks {
+		got = append(got, c.Status)
+	}
+	// mismatched PTR, PTR without forward confirmation, missing PTR
+	want := []string{"ok", "warning", "error", "error"}
+	if len(got) != len(want) {
+		t.Fatalf("statuses = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("statuses = %v, want %v", got, want)
+			break
+		}
+	}
+
+	checks = doctorPTRChecks(context.Background(), resolver, "localhost", nil)
+	if len(checks) != 1 || checks[0].Status != "error" {
+		t.Errorf("short HELO: %+v", checks)
+	}
+}
+
+func TestDoctorExitCode(t *testing.T) {
+	if code := doctorExitCode(dnscheck.Summary{OK: 3, Warnings: 1, NotFound: 1}, false); code != 0 {
+		t.Errorf("warnings without --strict: %d", code)
+	}
+	if code := doctorExitCode(dnscheck.Summary{OK: 3, Warnings: 1}, true); code != doctorExitWarnings {
+		t.Errorf("warnings with --strict: %d", code)
+	}
+	if code := doctorExitCode(dnscheck.Summary{Warnings: 1, Errors: 1}, true); code != doctorExitErrors {
+		t.Errorf("errors: %d", code)
+	}
+}
@@ -77,7 +77,7 @@ func runDomainList(cmd *cobra.Command, args []string) error {
 				dkimStatus = fmt.Sprintf("enabled (%s)", dc.DKIM.Selector)
 			}
 			if dc.RateLimit != nil {
-				rateLimit = fmt.Sprintf("%d/h, %d/d", dc.RateLimit.MessagesPerHour, dc.RateLimit.MessagesPerDay)
+				rateLimit = fmt.Sprintf("%d/m, %d/h, %d/d", dc.RateLimit.MessagesPerMinute, dc.RateLimit.MessagesPerHour, dc.RateLimit.MessagesPerDay)
 			}
 		}
 
@@ -143,6 +143,7 @@ func runDomainShow(cmd *cobra.Command, args []string) error {
 	// Rate Limits
 	fmt.Println("Rate Limits:")
 	if dc != nil && dc.RateLimit != nil {
+		fmt.Printf("  Messages per minute: %d\n", dc.RateLimit.MessagesPerMinute)
 		fmt.Printf("  Messages per hour: %d\n", dc.RateLimit.MessagesPerHour)
 		fmt.Printf("  Messages per day: %d\n", dc.RateLimit.MessagesPerDay)
 		fmt.Printf("  Recipients per message: %d\n", dc.RateLimit.RecipientsPerMessage)
@@ -199,7 +200,8 @@ func runDomainStats(cmd *cobra.Command, args []string) error {
 	fmt.Printf("  DKIM Enabled: %v\n", dkimEnabled)
 
 	if dc != nil && dc.RateLimit != nil {
-		fmt.Printf("  Rate Limit: %d/hour, %d/day\n",
+		fmt.Printf("  Rate Limit: %d/minute, %d/hour, %d/day\n",
+			dc.RateLimit.MessagesPerMinute,
 			dc.RateLimit.MessagesPerHour,
 			dc.RateLimit.MessagesPerDay)
 	}
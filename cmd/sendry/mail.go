@@ -0,0 +1,352 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-sasl"
+	"github.com/emersion/go-smtp"
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+
+	"github.com/foxzi/sendry/internal/api"
+	"github.com/foxzi/sendry/internal/config"
+	"github.com/foxzi/sendry/internal/email"
+)
+
+var (
+	mailAPI serverAPI
+
+	mailFrom         string
+	mailTo           []string
+	mailSubject      string
+	mailBody         string
+	mailHTML         string
+	mailTemplate     string
+	mailDataFile     string
+	mailVia          string
+	mailSMTPAddr     string
+	mailSMTPUser     string
+	mailSMTPPassword string
+	mailNoTLS        bool
+	mailWait         bool
+	mailWaitTimeout  time.Duration
+)
+
+// mailPollInterval is how often --wait looks up the message status
+const mailPollInterval = 2 * time.Second
+
+var mailCmd = &cobra.Command{
+	Use:   "mail",
+	Short: "Mail submission commands",
+}
+
+var mailSendCmd = &cobra.Command{
+	Use:   "send",
+	Short: "Send an email through the server",
+	Long: `Send an email through the server, for quick testing.
+
+The message is submitted with the API of the server named by the config file,
+or of --url. With --via smtp it is submitted to the SMTP submission port
+instead. The queue ID of the message is printed; with --wait the command
+follows its status until it is delivered or failed.
+
+Examples:
+  sendry mail send -c config.yaml --to user@example.net --subject Hi --body Hello --wait
+  sendry mail send -c config.yaml --to user@example.net --template welcome --data-file data.json
+  sendry mail send -c config.yaml --via smtp --smtp-user app --smtp-password secret --to user@example.net`,
+	RunE: runMailSend,
+}
+
+func init() {
+	mailAPI.register(mailCmd)
+
+	mailSendCmd.Flags().StringVar(&mailFrom, "from", "", "Sender address (default: test@ the smtp.domain of the config)")
+	mailSendCmd.Flags().StringSliceVar(&mailTo, "to", nil, "Recipient address, repeat or separate with commas (required)")
+	mailSendCmd.Flags().StringVar(&mailSubject, "subject", "Test message from Sendry", "Subject")
+	mailSendCmd.Flags().StringVar(&mailBody, "body", "This is a test message sent from Sendry MTA.", "Plain text body")
+	mailSendCmd.Flags().StringVar(&mailHTML, "html", "", "HTML body")
+	mailSendCmd.Flags().StringVar(&mailTemplate, "template", "", "Template ID or name to render instead of subject and body (API only)")
+	mailSendCmd.Flags().StringVar(&mailDataFile, "data-file", "", "JSON file with the template data, - for standard input")
+	mailSendCmd.Flags().StringVar(&mailVia, "via", "api", "Submission method (api, smtp)")
+	mailSendCmd.Flags().StringVar(&mailSMTPAddr, "smtp-addr", "", "SMTP submission address (default: smtp.submission_addr of the config on localhost)")
+	mailSendCmd.Flags().StringVar(&mailSMTPUser, "smtp-user", "", "SMTP username")
+	mailSendCmd.Flags().StringVar(&mailSMTPPassword, "smtp-password", "", "SMTP password")
+	mailSendCmd.Flags().BoolVar(&mailNoTLS, "no-tls", false, "Skip STARTTLS (for local testing without certificates)")
+	mailSendCmd.Flags().BoolVar(&mailWait, "wait", false, "Follow the message status until it is delivered or failed")
+	mailSendCmd.Flags().DurationVar(&mailWaitTimeout, "timeout", 5*time.Minute, "Maximum time to wait with --wait")
+	mailSendCmd.MarkFlagRequired("to")
+
+	mailCmd.AddCommand(mailSendCmd)
+	rootCmd.AddCommand(mailCmd)
+}
+
+func runMailSend(cmd *cobra.Command, args []string) error {
+	if mailVia != "api" && mailVia != "smtp" {
+		return fmt.Errorf("invalid --via %q, use api or smtp", mailVia)
+	}
+	if mailTemplate != "" && mailVia != "api" {
+		return fmt.Errorf("--template requires --via api")
+	}
+	if mailDataFile != "" && mailTemplate == "" {
+		return fmt.Errorf("--data-file requires --template")
+	}
+
+	var cfg *config.Config
+	if cfgFile != "" {
+		var err error
+		if cfg, err = config.Load(cfgFile); err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+	}
+
+	from := mailFrom
+	if from == "" {
+		if cfg == nil {
+			return fmt.Errorf("--from is required without a config file")
+		}
+		from = "test@" + cfg.SMTP.Domain
+	}
+
+	var id string
+	var err error
+	switch {
+	case mailVia == "smtp":
+		id, err = submitSMTP(cfg, from)
+	case mailTemplate != "":
+		id, err = submitTemplate(from)
+	default:
+		var resp api.SendResponse
+		err = mailAPI.requestJSON(http.MethodPost, "/api/v1/send", &api.SendRequest{
+			From:    from,
+			To:      mailTo,
+			Subject: mailSubject,
+			Body:    mailBody,
+			HTML:    mailHTML,
+		}, &resp)
+		id = resp.ID
+	}
+	if err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+
+	if id == "" {
+		fmt.Println("Message accepted, the server did not report its queue ID")
+		return nil
+	}
+	fmt.Printf("Message queued: %s\n", id)
+
+	if !mailWait {
+		return nil
+	}
+	return waitForDelivery(id)
+}
+
+// submitTemplate queues a message rendered from --template through the API
+func submitTemplate(from string) (string, error) {
+	req := &api.SendTemplateRequest{From: from, To: mailTo}
+	if _, err := uuid.Parse(mailTemplate); err == nil {
+		req.TemplateID = mailTemplate
+	} else {
+		req.TemplateName = mailTemplate
+	}
+
+	if mailDataFile != "" {
+		var data []byte
+		var err error
+		if mailDataFile == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(mailDataFile)
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read data file: %w", err)
+		}
+		if err := json.Unmarshal(data, &req.Data); err != nil {
+			return "", fmt.Errorf("invalid data file: %w", err)
+		}
+	}
+
+	var resp api.SendResponse
+	if err := mailAPI.requestJSON(http.MethodPost, "/api/v1/send/template", req, &resp); err != nil {
+		return "", err
+	}
+	return resp.ID, nil
+}
+
+// submitSMTP submits the message to the SMTP submission port and returns
+// the queue ID of the reply
+func submitSMTP(cfg *config.Config, from string) (string, error) {
+	addr := mailSMTPAddr
+	serverName := ""
+	if addr == "" {
+		if cfg == nil {
+			return "", fmt.Errorf("--smtp-addr is required without a config file")
+		}
+		_, port, err := net.SplitHostPort(cfg.SMTP.SubmissionAddr)
+		if err != nil {
+			return "", fmt.Errorf("invalid smtp.submission_addr: %w", err)
+		}
+		// The certificate is issued for the server hostname
+		addr, serverName = net.JoinHostPort("localhost", port), cfg.Server.Hostname
+	}
+	if serverName == "" {
+		serverName, _, _ = net.SplitHostPort(addr)
+	}
+
+	var client *smtp.Client
+	var err error
+	if mailNoTLS {
+		client, err = smtp.Dial(addr)
+	} else {
+		client, err = smtp.DialStartTLS(addr, &tls.Config{ServerName: serverName, InsecureSkipVerify: mailAPI.insecure})
+		if err != nil {
+			err = fmt.Errorf("%w (use --no-tls without certificates)", err)
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+
+	if mailSMTPUser != "" {
+		if err := client.Auth(sasl.NewPlainClient("", mailSMTPUser, mailSMTPPassword)); err != nil {
+			return "", fmt.Errorf("authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(from, nil); err != nil {
+		return "", err
+	}
+	for _, to := range mailTo {
+		if err := client.Rcpt(to, nil); err != nil {
+			return "", err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return "", err
+	}
+	if _, err := w.Write(buildMailMessage(from, mailTo, mailSubject, mailBody, mailHTML)); err != nil {
+		return "", err
+	}
+	resp, err := w.CloseWithResponse()
+	if err != nil {
+		return "", err
+	}
+	client.Quit()
+
+	return queuedID(resp.StatusText), nil
+}
+
+// queuedID returns the queue ID of a "queued as" DATA reply, empty if the
+// reply has none
+func queuedID(reply string) string {
+	_, id, ok := strings.Cut(reply, "queued as ")
+	if !ok {
+		return ""
+	}
+	return strings.TrimSpace(id)
+}
+
+// buildMailMessage returns an RFC 5322 message with a plain text body and,
+// if html is set, an HTML alternative
+func buildMailMessage(from string, to []string, subject, body, html string) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: <%s@%s>\r\n", uuid.New().String(), email.ExtractDomainOrDefault(from, "localhost"))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("X-Mailer: Sendry CLI\r\n")
+
+	if html == "" {
+		buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+		buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+		writeQuotedPrintable(&buf, body)
+		return buf.Bytes()
+	}
+
+	mw := multipart.NewWriter(&buf)
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", mw.Boundary())
+	for _, part := range []struct{ contentType, text string }{{"text/plain", body}, {"text/html", html}} {
+		w, _ := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType + "; charset=utf-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		writeQuotedPrintable(w, part.text)
+	}
+	mw.Close()
+	return buf.Bytes()
+}
+
+// writeQuotedPrintable writes text quoted-printable encoded, with CRLF line
+// breaks
+func writeQuotedPrintable(w io.Writer, text string) {
+	qp := quotedprintable.NewWriter(w)
+	qp.Write([]byte(text + "\n"))
+	qp.Close()
+}
+
+// waitForDelivery prints the status of a message as it changes, until it is
+// delivered or failed or --timeout passes
+func waitForDelivery(id string) error {
+	deadline := time.Now().Add(mailWaitTimeout)
+	last := ""
+	for {
+		st, err := messageStatus(id)
+		if err != nil {
+			return err
+		}
+		if st.Status != last {
+			line := fmt.Sprintf("%s  %s", time.Now().Format("15:04:05"), st.Status)
+			if st.LastError != "" && st.Status != "delivered" {
+				line += ": " + st.LastError
+			}
+			fmt.Println(line)
+			last = st.Status
+		}
+
+		switch st.Status {
+		case "delivered":
+			return nil
+		case "failed":
+			return fmt.Errorf("message %s failed", id)
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("message %s is still %s after %s", id, st.Status, mailWaitTimeout)
+		}
+		time.Sleep(mailPollInterval)
+	}
+}
+
+// messageStatus returns the status of a message of the queue or, once it was
+// moved there, of the DLQ
+func messageStatus(id string) (*api.StatusResponse, error) {
+	var st api.StatusResponse
+	err := mailAPI.requestJSON(http.MethodGet, "/api/v1/status/"+url.PathEscape(id), nil, &st)
+	if apiStatus(err) == http.StatusNotFound {
+		err = mailAPI.requestJSON(http.MethodGet, "/api/v1/dlq/"+url.PathEscape(id), nil, &st)
+	}
+	if status := apiStatus(err); status == http.StatusNotFound || status == http.StatusNotImplemented {
+		return nil, fmt.Errorf("message not found: %s", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message status: %w", err)
+	}
+	return &st, nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"testing"
+)
+
+func TestQueuedID(t *testing.T) {
+	tests := map[string]string{
+		"OK: queued as 4348cab8-3899-4395-b72e-63a68fef2e1b": "4348cab8-3899-4395-b72e-63a68fef2e1b",
+		"2.0.0 Ok: queued as 3F2A1C0B4D":                     "3F2A1C0B4D",
+		"OK: queued":                                         "",
+	}
+	for reply, want := range tests {
+		if got := queuedID(reply); got != want {
+			t.Errorf("queuedID(%q) = %q, want %q", reply, got, want)
+		}
+	}
+}
+
+func TestBuildMailMessage(t *testing.T) {
+	data := buildMailMessage("a@example.com", []string{"b@example.net", "c@example.net"}, "Привет", "line 1\nline 2", "<p>Hi</p>")
+
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject")); subject != "Привет" {
+		t.Errorf("Subject = %q", subject)
+	}
+	if msg.Header.Get("To") != "b@example.net, c@example.net" {
+		t.Errorf("To = %q", msg.Header.Get("To"))
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q, %v", msg.Header.Get("Content-Type"), err)
+	}
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	var bodies []string
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(part)
+		bodies = append(bodies, string(body))
+	}
+	if len(bodies) != 2 || bodies[0] != "line 1\r\nline 2\r\n" || bodies[1] != "<p>Hi</p>\r\n" {
+		t.Errorf("parts = %q", bodies)
+	}
+}
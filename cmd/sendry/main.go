@@ -1,15 +1,19 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 
 	"github.com/foxzi/sendry/internal/app"
 	"github.com/foxzi/sendry/internal/config"
+	"github.com/foxzi/sendry/internal/smtpauth"
 )
 
 var (
@@ -49,6 +53,14 @@ var configValidateCmd = &cobra.Command{
 	RunE:  runConfigValidate,
 }
 
+var configHashPasswordCmd = &cobra.Command{
+	Use:   "hash-password",
+	Short: "Print the bcrypt hash of a password for smtp.auth.users",
+	Long:  `Read a password from the terminal, or from stdin when it is not a terminal, and print its bcrypt hash.`,
+	Args:  cobra.NoArgs,
+	RunE:  runConfigHashPassword,
+}
+
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Print version information",
@@ -64,9 +76,9 @@ var versionCmd = &cobra.Command{
 }
 
 func init() {
-	rootCmd.PersistentFlags().StringVarP(&cfgFile, "config", "c", "", "config file path")
+	rootCmd.PersistentFlags().StringVarP(&cfgFile, "config", "c", os.Getenv("SENDRY_CONFIG"), "config file path (env: SENDRY_CONFIG)")
 
-	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configValidateCmd, configHashPasswordCmd)
 	rootCmd.AddCommand(serveCmd, configCmd, versionCmd)
 }
 
@@ -117,3 +129,32 @@ func runConfigValidate(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+func runConfigHashPassword(cmd *cobra.Command, args []string) error {
+	var password string
+	if fd := int(os.Stdin.Fd()); term.IsTerminal(fd) {
+		fmt.Fprint(os.Stderr, "Password: ")
+		pw, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return fmt.Errorf("failed to read password: %w", err)
+		}
+		password = string(pw)
+	} else {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return fmt.Errorf("failed to read password: %w", err)
+		}
+		password = strings.TrimRight(line, "\r\n")
+	}
+	if password == "" {
+		return fmt.Errorf("password is empty")
+	}
+
+	hash, err := smtpauth.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	fmt.Println(hash)
+	return nil
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/foxzi/sendry/internal/api"
+)
+
+var openapiCmd = &cobra.Command{
+	Use:   "openapi",
+	Short: "Print the OpenAPI document of the HTTP API",
+	Long: `Print the OpenAPI 3 document of the HTTP API to stdout.
+
+The document is generated from the server's route table and request and
+response types, the same one served at GET /api/v1/openapi.json.`,
+	RunE: runOpenAPI,
+}
+
+func init() {
+	rootCmd.AddCommand(openapiCmd)
+}
+
+func runOpenAPI(cmd *cobra.Command, args []string) error {
+	doc, err := api.OpenAPIDocument()
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(doc)
+	return err
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/spf13/cobra"
+
+	"github.com/foxzi/sendry/internal/api"
+	"github.com/foxzi/sendry/internal/privacy"
+)
+
+var (
+	privacyAPI          serverAPI
+	privacyPurgeEmail   string
+	privacyPurgePending bool
+)
+
+var privacyCmd = &cobra.Command{
+	Use:   "privacy",
+	Short: "Personal data commands",
+	Long: `Handle personal data stored by a running server, e.g. for GDPR requests.
+
+The commands call the API of the server named by the config file, or of --url.`,
+}
+
+var privacyPurgeCmd = &cobra.Command{
+	Use:   "purge",
+	Short: "Delete the stored messages of an email address",
+	Long: `Delete the messages sent from or to an email address from the queue, the
+dead letter queue, the sandbox and the message archive.
+
+Messages waiting for delivery are kept unless --pending is given, as deleting
+them also cancels their mail to other recipients. Delivery log files and the
+suppression list are not changed.
+
+Examples:
+  sendry privacy purge -c config.yaml --email=user@example.com
+  sendry privacy purge -c config.yaml --email=user@example.com --pending`,
+	RunE: runPrivacyPurge,
+}
+
+func init() {
+	privacyAPI.register(privacyCmd)
+
+	privacyPurgeCmd.Flags().StringVar(&privacyPurgeEmail, "email", "", "Email address whose messages are deleted")
+	privacyPurgeCmd.Flags().BoolVar(&privacyPurgePending, "pending", false, "Also delete messages waiting for delivery")
+	privacyPurgeCmd.MarkFlagRequired("email")
+
+	privacyCmd.AddCommand(privacyPurgeCmd)
+	rootCmd.AddCommand(privacyCmd)
+}
+
+func runPrivacyPurge(cmd *cobra.Command, args []string) error {
+	var result privacy.PurgeResult
+	req := api.PurgeRequest{Email: privacyPurgeEmail, Pending: privacyPurgePending}
+	err := privacyAPI.requestJSON(http.MethodPost, "/api/v1/privacy/purge", req, &result)
+	var apiErr *apiError
+	if errors.As(err, &apiErr) && apiErr.status == http.StatusNotFound {
+		return fmt.Errorf("the server does not support privacy requests, upgrade it")
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Queue:    %d\n", result.Queue)
+	fmt.Printf("DLQ:      %d\n", result.DLQ)
+	fmt.Printf("Sandbox:  %d\n", result.Sandbox)
+	fmt.Printf("Archive:  %d\n", result.Archive)
+	fmt.Printf("Deleted %d messages of %s\n", result.Total, privacyPurgeEmail)
+	if result.Skipped > 0 {
+		fmt.Printf("Kept %d messages waiting for or in delivery, run again later or use --pending\n", result.Skipped)
+	}
+	return nil
+}
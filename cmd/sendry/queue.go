@@ -1,28 +1,56 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"fmt"
+	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"syscall"
 	"text/tabwriter"
 	"time"
 
 	"github.com/spf13/cobra"
 
+	"github.com/foxzi/sendry/internal/api"
 	"github.com/foxzi/sendry/internal/config"
 	"github.com/foxzi/sendry/internal/queue"
 )
 
 var (
+	queueAPI    serverAPI
+	queueFormat string
+
 	queueListStatus string
 	queueListLimit  int
 	queueListDomain string
+
+	queueStatsWatch    bool
+	queueStatsInterval time.Duration
+
+	queueExportStatus string
+	queueExportDomain string
+	queueExportLimit  int
+	queueExportOutput string
 )
 
 var queueCmd = &cobra.Command{
 	Use:   "queue",
 	Short: "Queue management commands",
+	Long: `Queue management commands.
+
+list, show, stats, retry and delete open the storage of the config file,
+which the bolt driver locks while the server runs. With --api-url they call
+the API of the running server instead.
+
+Examples:
+  sendry queue list -c config.yaml --status deferred
+  sendry queue list --api-url http://localhost:8080 --api-key KEY --domain example.com
+  sendry queue stats --api-url http://localhost:8080 --api-key KEY --watch`,
 }
 
 var queueListCmd = &cobra.Command{
@@ -58,16 +86,60 @@ var queueDeleteCmd = &cobra.Command{
 	RunE:  runQueueDelete,
 }
 
+var queueExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export queue messages to a JSON lines file",
+	Long: `Export queue messages, including their data and DLQ membership, as one
+JSON record per line. The export can be imported on another server with
+"sendry queue import". With the bolt driver stop the server first, or take
+an online copy with "sendry backup".
+
+Examples:
+  sendry queue export -c config.yaml -o queue.jsonl
+  sendry queue export -c config.yaml --status deferred -o deferred.jsonl`,
+	RunE: runQueueExport,
+}
+
+var queueImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import queue messages from an export file",
+	Long: `Import queue messages written by "sendry queue export", keeping their
+status and DLQ membership. Messages that are already queued are skipped.
+Use - to read from standard input.
+
+Examples:
+  sendry queue import -c config.yaml queue.jsonl`,
+	Args: cobra.ExactArgs(1),
+	RunE: runQueueImport,
+}
+
 func init() {
+	for _, cmd := range []*cobra.Command{queueListCmd, queueShowCmd, queueStatsCmd, queueRetryCmd, queueDeleteCmd} {
+		cmd.Flags().StringVar(&queueAPI.url, "api-url", "", "API URL of a running server to use instead of the storage")
+		cmd.Flags().StringVar(&queueAPI.apiKey, "api-key", "", "API key of the server")
+		cmd.Flags().BoolVar(&queueAPI.insecure, "insecure", false, "Skip verification of the API certificate")
+	}
+	for _, cmd := range []*cobra.Command{queueListCmd, queueShowCmd, queueStatsCmd} {
+		cmd.Flags().StringVar(&queueFormat, "format", "table", "Output format (table, json)")
+	}
+
 	queueListCmd.Flags().StringVar(&queueListStatus, "status", "", "Filter by status (pending, sending, delivered, failed, deferred)")
 	queueListCmd.Flags().IntVar(&queueListLimit, "limit", 50, "Maximum number of messages to show")
-	queueListCmd.Flags().StringVar(&queueListDomain, "domain", "", "Filter by domain")
+	queueListCmd.Flags().StringVar(&queueListDomain, "domain", "", "Filter by sender domain")
+
+	queueStatsCmd.Flags().BoolVarP(&queueStatsWatch, "watch", "w", false, "Refresh the statistics until interrupted")
+	queueStatsCmd.Flags().DurationVar(&queueStatsInterval, "interval", 2*time.Second, "Refresh interval of --watch")
 
-	queueCmd.AddCommand(queueListCmd, queueShowCmd, queueStatsCmd, queueRetryCmd, queueDeleteCmd)
+	queueExportCmd.Flags().StringVar(&queueExportStatus, "status", "", "Only messages with this status (pending, sending, delivered, failed, deferred)")
+	queueExportCmd.Flags().StringVar(&queueExportDomain, "domain", "", "Only messages of this sender domain")
+	queueExportCmd.Flags().IntVar(&queueExportLimit, "limit", 0, "Maximum number of messages (0 = all)")
+	queueExportCmd.Flags().StringVarP(&queueExportOutput, "output", "o", "-", "Output file, - for standard output")
+
+	queueCmd.AddCommand(queueListCmd, queueShowCmd, queueStatsCmd, queueRetryCmd, queueDeleteCmd, queueExportCmd, queueImportCmd)
 	rootCmd.AddCommand(queueCmd)
 }
 
-func openQueueStorage() (*queue.BoltStorage, error) {
+func openQueueStorage() (queue.Storage, error) {
 	if cfgFile == "" {
 		return nil, fmt.Errorf("config file is required (use -c flag)")
 	}
@@ -77,34 +149,67 @@ func openQueueStorage() (*queue.BoltStorage, error) {
 		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
 
-	storage, err := queue.NewBoltStorage(cfg.Storage.Path)
+	storage, err := openStorageDriver(cfg, cfg.Storage.Driver)
+	if err != nil || !cfg.Storage.Encryption.Enabled {
+		return storage, err
+	}
+	enc, err := queue.LoadEncryptor(cfg.Storage.Encryption.Key, cfg.Storage.Encryption.KeyFile, cfg.Storage.Encryption.PreviousKeys)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open queue storage: %w", err)
+		storage.Close()
+		return nil, fmt.Errorf("storage.encryption: %w", err)
 	}
-
-	return storage, nil
+	return queue.NewEncryptedStorage(storage, enc), nil
 }
 
 func runQueueList(cmd *cobra.Command, args []string) error {
-	storage, err := openQueueStorage()
-	if err != nil {
+	if err := checkQueueFormat(); err != nil {
 		return err
 	}
-	defer storage.Close()
 
-	ctx := context.Background()
+	var messages []*api.MessageSummary
+	if queueAPI.url != "" {
+		params := url.Values{"limit": {strconv.Itoa(queueListLimit)}}
+		if queueListStatus != "" {
+			params.Set("status", queueListStatus)
+		}
+		if queueListDomain != "" {
+			params.Set("sender_domain", queueListDomain)
+		}
+		var resp api.QueueResponse
+		if err := queueAPI.requestJSON(http.MethodGet, "/api/v1/queue?"+params.Encode(), nil, &resp); err != nil {
+			return fmt.Errorf("failed to list messages: %w", err)
+		}
+		messages = resp.Messages
+	} else {
+		storage, err := openQueueStorage()
+		if err != nil {
+			return err
+		}
+		defer storage.Close()
 
-	filter := queue.ListFilter{
-		Limit: queueListLimit,
-	}
+		filter := queue.ListFilter{
+			SenderDomain: queueListDomain,
+			Limit:        queueListLimit,
+		}
+
+		if queueListStatus != "" {
+			filter.Status = queue.MessageStatus(queueListStatus)
+		}
 
-	if queueListStatus != "" {
-		filter.Status = queue.MessageStatus(queueListStatus)
+		list, err := storage.List(context.Background(), filter)
+		if err != nil {
+			return fmt.Errorf("failed to list messages: %w", err)
+		}
+		for _, msg := range list {
+			messages = append(messages, api.NewMessageSummary(msg))
+		}
 	}
 
-	messages, err := storage.List(ctx, filter)
-	if err != nil {
-		return fmt.Errorf("failed to list messages: %w", err)
+	if queueFormat == "json" {
+		if messages == nil {
+			messages = []*api.MessageSummary{}
+		}
+		return outputJSON(messages)
 	}
 
 	if len(messages) == 0 {
@@ -112,20 +217,9 @@ func runQueueList(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
-	// Filter by domain if specified
-	if queueListDomain != "" {
-		filtered := make([]*queue.Message, 0)
-		for _, msg := range messages {
-			if strings.Contains(msg.From, "@"+queueListDomain) {
-				filtered = append(filtered, msg)
-			}
-		}
-		messages = filtered
-	}
-
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "ID\tSTATUS\tFROM\tTO\tCREATED\tRETRIES")
-	fmt.Fprintln(w, "--\t------\t----\t--\t-------\t-------")
+	fmt.Fprintln(w, "ID\tSTATUS\tPRIORITY\tFROM\tTO\tCREATED\tRETRIES")
+	fmt.Fprintln(w, "--\t------\t--------\t----\t--\t-------\t-------")
 
 	for _, msg := range messages {
 		to := strings.Join(msg.To, ", ")
@@ -135,9 +229,10 @@ func runQueueList(cmd *cobra.Command, args []string) error {
 
 		created := msg.CreatedAt.Format("2006-01-02 15:04")
 
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\n",
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%d\n",
 			truncateID(msg.ID),
 			msg.Status,
+			msg.Priority,
 			msg.From,
 			to,
 			created,
@@ -152,6 +247,14 @@ func runQueueList(cmd *cobra.Command, args []string) error {
 }
 
 func runQueueShow(cmd *cobra.Command, args []string) error {
+	if err := checkQueueFormat(); err != nil {
+		return err
+	}
+	id := args[0]
+	if queueAPI.url != "" {
+		return showRemoteMessage(id)
+	}
+
 	storage, err := openQueueStorage()
 	if err != nil {
 		return err
@@ -159,7 +262,6 @@ func runQueueShow(cmd *cobra.Command, args []string) error {
 	defer storage.Close()
 
 	ctx := context.Background()
-	id := args[0]
 
 	msg, err := storage.Get(ctx, id)
 	if err != nil {
@@ -167,11 +269,75 @@ func runQueueShow(cmd *cobra.Command, args []string) error {
 	}
 
 	if msg == nil {
+		// Try DLQ
+		msg, err = storage.GetFromDLQ(ctx, id)
+		if err != nil {
+			return fmt.Errorf("failed to get message from DLQ: %w", err)
+		}
+	}
+
+	if msg == nil {
+		return fmt.Errorf("message not found: %s", id)
+	}
+
+	if queueFormat == "json" {
+		return outputJSON(api.NewStatusResponse(msg))
+	}
+	printQueueMessage(msg)
+	return nil
+}
+
+// showRemoteMessage shows a message of the queue or the DLQ of the server
+func showRemoteMessage(id string) error {
+	var resp api.MessageResponse
+	err := queueAPI.requestJSON(http.MethodGet, "/api/v1/queue/"+url.PathEscape(id), nil, &resp)
+	var out any = &resp
+	if apiStatus(err) == http.StatusNotFound {
+		// Messages moved to the DLQ are not in the queue
+		err = queueAPI.requestJSON(http.MethodGet, "/api/v1/dlq/"+url.PathEscape(id), nil, &resp.StatusResponse)
+		out = &resp.StatusResponse
+	}
+	if status := apiStatus(err); status == http.StatusNotFound || status == http.StatusNotImplemented {
 		return fmt.Errorf("message not found: %s", id)
 	}
+	if err != nil {
+		return fmt.Errorf("failed to get message: %w", err)
+	}
+
+	if queueFormat == "json" {
+		return outputJSON(out)
+	}
+	printQueueMessage(statusMessage(resp.StatusResponse))
+	if resp.DKIM != nil {
+		fmt.Printf("\nSize: %d bytes\n", resp.Size)
+		fmt.Printf("DKIM: %s\n", resp.DKIM.Status)
+	}
+	return nil
+}
+
+// statusMessage returns the message of an API status response, with the
+// fields printed by printQueueMessage
+func statusMessage(st api.StatusResponse) *queue.Message {
+	return &queue.Message{
+		ID:         st.ID,
+		From:       st.From,
+		To:         st.To,
+		Status:     queue.MessageStatus(st.Status),
+		CreatedAt:  st.CreatedAt,
+		UpdatedAt:  st.UpdatedAt,
+		Priority:   queue.Priority(st.Priority),
+		RetryCount: st.RetryCount,
+		LastError:  st.LastError,
+		Results:    st.Recipients,
+		Attempts:   st.Attempts,
+	}
+}
 
+// printQueueMessage prints the details of a message
+func printQueueMessage(msg *queue.Message) {
 	fmt.Printf("Message: %s\n\n", msg.ID)
 	fmt.Printf("Status:      %s\n", msg.Status)
+	fmt.Printf("Priority:    %s\n", msg.EffectivePriority())
 	fmt.Printf("From:        %s\n", msg.From)
 	fmt.Printf("To:          %s\n", strings.Join(msg.To, ", "))
 	fmt.Printf("Created:     %s\n", msg.CreatedAt.Format(time.RFC3339))
@@ -190,6 +356,40 @@ func runQueueShow(cmd *cobra.Command, args []string) error {
 		fmt.Printf("\nClient IP: %s\n", msg.ClientIP)
 	}
 
+	if len(msg.Results) > 0 {
+		fmt.Println("\nRecipients:")
+		for _, rcpt := range msg.To {
+			r := msg.Results[rcpt]
+			if r == nil {
+				fmt.Printf("  %s: pending\n", rcpt)
+				continue
+			}
+			status := string(r.Status)
+			if r.Expired {
+				status += " (expired)"
+			}
+			fmt.Printf("  %s: %s", rcpt, status)
+			if r.MXHost != "" {
+				fmt.Printf(" via %s", r.MXHost)
+			}
+			if r.Error != "" {
+				fmt.Printf(": %s", r.Error)
+			}
+			fmt.Println()
+		}
+	}
+
+	if len(msg.Attempts) > 0 {
+		fmt.Printf("\nDelivery Attempts (%d):\n", len(msg.Attempts))
+		for _, a := range msg.Attempts {
+			result := "ok"
+			if !a.Success {
+				result = a.Error
+			}
+			fmt.Printf("  %s  %s  %s  %s\n", a.Timestamp.Format(time.RFC3339), a.MXHost, a.Recipient, result)
+		}
+	}
+
 	// Show message data preview
 	if len(msg.Data) > 0 {
 		fmt.Println("\nMessage Preview (first 500 bytes):")
@@ -201,24 +401,115 @@ func runQueueShow(cmd *cobra.Command, args []string) error {
 		fmt.Println(preview)
 		fmt.Println("---")
 	}
+}
 
-	return nil
+// queueStatsView is the output of queue stats
+type queueStatsView struct {
+	Queue *queue.QueueStats `json:"queue"`
+	DLQ   *queue.DLQStats   `json:"dlq,omitempty"`
 }
 
 func runQueueStats(cmd *cobra.Command, args []string) error {
-	storage, err := openQueueStorage()
-	if err != nil {
+	if err := checkQueueFormat(); err != nil {
 		return err
 	}
-	defer storage.Close()
 
-	ctx := context.Background()
+	fetch := remoteQueueStats
+	if queueAPI.url == "" {
+		storage, err := openQueueStorage()
+		if err != nil {
+			return err
+		}
+		defer storage.Close()
+
+		fetch = func(ctx context.Context) (*queueStatsView, error) {
+			return storageQueueStats(ctx, storage)
+		}
+	}
+
+	if !queueStatsWatch {
+		stats, err := fetch(context.Background())
+		if err != nil {
+			return err
+		}
+		return printQueueStats(stats)
+	}
 
+	if queueStatsInterval <= 0 {
+		return fmt.Errorf("--interval must be positive")
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ticker := time.NewTicker(queueStatsInterval)
+	defer ticker.Stop()
+
+	for {
+		stats, err := fetch(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if queueFormat == "table" {
+			// Clear the screen and redraw
+			fmt.Print("\033[H\033[2J")
+			fmt.Printf("Every %s, Ctrl+C to stop: %s\n\n", queueStatsInterval, time.Now().Format("2006-01-02 15:04:05"))
+		}
+		if err != nil {
+			// Keep watching while the server restarts
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		} else if err := printQueueStats(stats); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// storageQueueStats returns the queue and DLQ statistics of the storage
+func storageQueueStats(ctx context.Context, storage queue.Storage) (*queueStatsView, error) {
 	stats, err := storage.Stats(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get queue stats: %w", err)
+		return nil, fmt.Errorf("failed to get queue stats: %w", err)
+	}
+	view := &queueStatsView{Queue: stats}
+
+	// DLQ stats
+	if dlqStats, err := storage.DLQStats(ctx); err == nil {
+		view.DLQ = dlqStats
+	}
+	return view, nil
+}
+
+// remoteQueueStats returns the queue and DLQ statistics of the server
+func remoteQueueStats(ctx context.Context) (*queueStatsView, error) {
+	var resp api.QueueResponse
+	if err := queueAPI.requestJSON(http.MethodGet, "/api/v1/queue?limit=1", nil, &resp); err != nil {
+		return nil, fmt.Errorf("failed to get queue stats: %w", err)
+	}
+	view := &queueStatsView{Queue: resp.Stats}
+
+	var dlq api.DLQResponse
+	err := queueAPI.requestJSON(http.MethodGet, "/api/v1/dlq", nil, &dlq)
+	if apiStatus(err) == http.StatusNotImplemented {
+		return view, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get DLQ stats: %w", err)
+	}
+	view.DLQ = dlq.Stats
+	return view, nil
+}
+
+// printQueueStats prints queue statistics in the --format
+func printQueueStats(view *queueStatsView) error {
+	if queueFormat == "json" {
+		return outputJSON(view)
 	}
 
+	stats := view.Queue
 	fmt.Println("Queue Statistics")
 	fmt.Println("================")
 	fmt.Printf("Total:     %d\n", stats.Total)
@@ -228,9 +519,7 @@ func runQueueStats(cmd *cobra.Command, args []string) error {
 	fmt.Printf("Delivered: %d\n", stats.Delivered)
 	fmt.Printf("Failed:    %d\n", stats.Failed)
 
-	// DLQ stats
-	dlqStats, err := storage.DLQStats(ctx)
-	if err == nil && dlqStats.Total > 0 {
+	if dlqStats := view.DLQ; dlqStats != nil && dlqStats.Total > 0 {
 		fmt.Println("\nDead Letter Queue")
 		fmt.Println("-----------------")
 		fmt.Printf("Total:     %d\n", dlqStats.Total)
@@ -244,6 +533,10 @@ func runQueueStats(cmd *cobra.Command, args []string) error {
 }
 
 func runQueueRetry(cmd *cobra.Command, args []string) error {
+	if queueAPI.url != "" {
+		return retryRemoteMessage(args[0])
+	}
+
 	storage, err := openQueueStorage()
 	if err != nil {
 		return err
@@ -293,6 +586,10 @@ func runQueueRetry(cmd *cobra.Command, args []string) error {
 }
 
 func runQueueDelete(cmd *cobra.Command, args []string) error {
+	if queueAPI.url != "" {
+		return deleteRemoteMessage(args[0])
+	}
+
 	storage, err := openQueueStorage()
 	if err != nil {
 		return err
@@ -325,6 +622,145 @@ func runQueueDelete(cmd *cobra.Command, args []string) error {
 	return fmt.Errorf("message not found: %s", id)
 }
 
+// retryRemoteMessage retries a message of the queue or the DLQ of the server
+func retryRemoteMessage(id string) error {
+	var result api.ActionResponse
+	err := queueAPI.requestJSON(http.MethodPost, "/api/v1/queue/"+url.PathEscape(id)+"/retry", nil, &result)
+	if err == nil {
+		fmt.Printf("Message %s queued for retry\n", id)
+		return nil
+	}
+	if apiStatus(err) != http.StatusNotFound {
+		return fmt.Errorf("failed to retry message: %w", err)
+	}
+
+	// Messages moved to the DLQ are not in the queue
+	found, err := inRemoteDLQ(id)
+	if err != nil {
+		return fmt.Errorf("failed to get message from DLQ: %w", err)
+	}
+	if !found {
+		return fmt.Errorf("message not found: %s", id)
+	}
+	if err := queueAPI.requestJSON(http.MethodPost, "/api/v1/dlq/"+url.PathEscape(id)+"/retry", nil, &result); err != nil {
+		return fmt.Errorf("failed to retry message from DLQ: %w", err)
+	}
+	fmt.Printf("Message %s moved from DLQ to pending queue\n", id)
+	return nil
+}
+
+// deleteRemoteMessage deletes a message of the queue or the DLQ of the server
+func deleteRemoteMessage(id string) error {
+	path, from := "/api/v1/queue/"+url.PathEscape(id), "queue"
+	var msg api.MessageResponse
+	err := queueAPI.requestJSON(http.MethodGet, path, nil, &msg)
+	if apiStatus(err) == http.StatusNotFound {
+		found, err := inRemoteDLQ(id)
+		if err != nil {
+			return fmt.Errorf("failed to get message from DLQ: %w", err)
+		}
+		if !found {
+			return fmt.Errorf("message not found: %s", id)
+		}
+		path, from = "/api/v1/dlq/"+url.PathEscape(id), "DLQ"
+	} else if err != nil {
+		return fmt.Errorf("failed to get message: %w", err)
+	}
+
+	resp, err := queueAPI.do(http.MethodDelete, path, nil, 30*time.Second)
+	if err != nil {
+		return fmt.Errorf("failed to delete message: %w", err)
+	}
+	resp.Body.Close()
+	fmt.Printf("Message %s deleted from %s\n", id, from)
+	return nil
+}
+
+// inRemoteDLQ reports whether the DLQ of the server holds a message
+func inRemoteDLQ(id string) (bool, error) {
+	var st api.StatusResponse
+	err := queueAPI.requestJSON(http.MethodGet, "/api/v1/dlq/"+url.PathEscape(id), nil, &st)
+	switch apiStatus(err) {
+	case http.StatusNotFound, http.StatusNotImplemented:
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// checkQueueFormat validates the --format flag
+func checkQueueFormat() error {
+	if queueFormat != "table" && queueFormat != "json" {
+		return fmt.Errorf("invalid format %q, use table or json", queueFormat)
+	}
+	return nil
+}
+
+func runQueueExport(cmd *cobra.Command, args []string) error {
+	storage, err := openQueueStorage()
+	if err != nil {
+		return err
+	}
+	defer storage.Close()
+
+	out := os.Stdout
+	if queueExportOutput != "-" {
+		f, err := os.OpenFile(queueExportOutput, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to create export file: %w", err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	w := bufio.NewWriter(out)
+	result, err := queue.Export(context.Background(), storage, w, queue.ListFilter{
+		Status:       queue.MessageStatus(queueExportStatus),
+		SenderDomain: queueExportDomain,
+		Limit:        queueExportLimit,
+	})
+	if err != nil {
+		return fmt.Errorf("export failed: %w", err)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	if out != os.Stdout {
+		if err := out.Close(); err != nil {
+			return fmt.Errorf("failed to write export: %w", err)
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Exported %d messages (%d in DLQ)\n", result.Messages, result.DLQ)
+	return nil
+}
+
+func runQueueImport(cmd *cobra.Command, args []string) error {
+	in := os.Stdin
+	if args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to open export file: %w", err)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	storage, err := openQueueStorage()
+	if err != nil {
+		return err
+	}
+	defer storage.Close()
+
+	result, err := queue.ImportExport(context.Background(), storage, in)
+	if err != nil {
+		return fmt.Errorf("import failed after %d messages: %w", result.Messages, err)
+	}
+
+	fmt.Printf("Imported %d messages (%d in DLQ), skipped %d already queued\n",
+		result.Messages, result.DLQ, result.Skipped)
+	return nil
+}
+
 func truncateID(id string) string {
 	if len(id) <= 12 {
 		return id
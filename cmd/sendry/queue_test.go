@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestRemoteQueueDLQFallback(t *testing.T) {
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		if r.Header.Get("Authorization") != "Bearer key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.Method + " " + r.URL.Path {
+		case "GET /api/v1/dlq/dead", "POST /api/v1/dlq/dead/retry":
+			w.Write([]byte(`{"id":"dead","status":"failed"}`))
+		case "DELETE /api/v1/dlq/dead":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"Message not found"}`))
+		}
+	}))
+	defer srv.Close()
+
+	queueAPI = serverAPI{url: srv.URL, apiKey: "key"}
+	defer func() { queueAPI = serverAPI{} }()
+
+	if err := retryRemoteMessage("dead"); err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	if err := deleteRemoteMessage("dead"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	want := []string{
+		"POST /api/v1/queue/dead/retry", "GET /api/v1/dlq/dead", "POST /api/v1/dlq/dead/retry",
+		"GET /api/v1/queue/dead", "GET /api/v1/dlq/dead", "DELETE /api/v1/dlq/dead",
+	}
+	if !reflect.DeepEqual(requests, want) {
+		t.Errorf("requests = %q, want %q", requests, want)
+	}
+
+	if err := retryRemoteMessage("missing"); err == nil || err.Error() != "message not found: missing" {
+		t.Errorf("retry of missing message: %v", err)
+	}
+}
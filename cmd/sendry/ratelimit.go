@@ -40,7 +40,12 @@ func runRatelimitShow(cmd *cobra.Command, args []string) error {
 
 	fmt.Println("Rate Limiting Configuration")
 	fmt.Println("===========================")
-	fmt.Printf("Enabled: %v\n\n", rl.Enabled)
+	fmt.Printf("Enabled: %v\n", rl.Enabled)
+	algorithm := rl.Algorithm
+	if algorithm == "" {
+		algorithm = "fixed"
+	}
+	fmt.Printf("Algorithm: %s\n\n", algorithm)
 
 	if !rl.Enabled {
 		fmt.Println("Rate limiting is disabled")
@@ -50,8 +55,9 @@ func runRatelimitShow(cmd *cobra.Command, args []string) error {
 	// Global limits
 	fmt.Println("Global Limits:")
 	if rl.Global != nil {
-		fmt.Printf("  Messages per hour: %d\n", rl.Global.MessagesPerHour)
-		fmt.Printf("  Messages per day:  %d\n", rl.Global.MessagesPerDay)
+		fmt.Printf("  Messages per minute: %d\n", rl.Global.MessagesPerMinute)
+		fmt.Printf("  Messages per hour:   %d\n", rl.Global.MessagesPerHour)
+		fmt.Printf("  Messages per day:    %d\n", rl.Global.MessagesPerDay)
 	} else {
 		fmt.Println("  Not configured")
 	}
@@ -59,31 +65,31 @@ func runRatelimitShow(cmd *cobra.Command, args []string) error {
 
 	// Default limits table
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "LEVEL\tMESSAGES/HOUR\tMESSAGES/DAY")
-	fmt.Fprintln(w, "-----\t-------------\t------------")
+	fmt.Fprintln(w, "LEVEL\tMESSAGES/MIN\tMESSAGES/HOUR\tMESSAGES/DAY\tALGORITHM")
+	fmt.Fprintln(w, "-----\t------------\t-------------\t------------\t---------")
 
 	if rl.DefaultDomain != nil {
-		fmt.Fprintf(w, "Per Domain\t%d\t%d\n", rl.DefaultDomain.MessagesPerHour, rl.DefaultDomain.MessagesPerDay)
+		fmt.Fprintf(w, "Per Domain\t%d\t%d\t%d\t%s\n", rl.DefaultDomain.MessagesPerMinute, rl.DefaultDomain.MessagesPerHour, rl.DefaultDomain.MessagesPerDay, limitAlgorithm(rl.DefaultDomain, algorithm))
 	} else {
-		fmt.Fprintln(w, "Per Domain\t-\t-")
+		fmt.Fprintln(w, "Per Domain\t-\t-\t-\t-")
 	}
 
 	if rl.DefaultSender != nil {
-		fmt.Fprintf(w, "Per Sender\t%d\t%d\n", rl.DefaultSender.MessagesPerHour, rl.DefaultSender.MessagesPerDay)
+		fmt.Fprintf(w, "Per Sender\t%d\t%d\t%d\t%s\n", rl.DefaultSender.MessagesPerMinute, rl.DefaultSender.MessagesPerHour, rl.DefaultSender.MessagesPerDay, limitAlgorithm(rl.DefaultSender, algorithm))
 	} else {
-		fmt.Fprintln(w, "Per Sender\t-\t-")
+		fmt.Fprintln(w, "Per Sender\t-\t-\t-\t-")
 	}
 
 	if rl.DefaultIP != nil {
-		fmt.Fprintf(w, "Per IP\t%d\t%d\n", rl.DefaultIP.MessagesPerHour, rl.DefaultIP.MessagesPerDay)
+		fmt.Fprintf(w, "Per IP\t%d\t%d\t%d\t%s\n", rl.DefaultIP.MessagesPerMinute, rl.DefaultIP.MessagesPerHour, rl.DefaultIP.MessagesPerDay, limitAlgorithm(rl.DefaultIP, algorithm))
 	} else {
-		fmt.Fprintln(w, "Per IP\t-\t-")
+		fmt.Fprintln(w, "Per IP\t-\t-\t-\t-")
 	}
 
 	if rl.DefaultAPIKey != nil {
-		fmt.Fprintf(w, "Per API Key\t%d\t%d\n", rl.DefaultAPIKey.MessagesPerHour, rl.DefaultAPIKey.MessagesPerDay)
+		fmt.Fprintf(w, "Per API Key\t%d\t%d\t%d\t%s\n", rl.DefaultAPIKey.MessagesPerMinute, rl.DefaultAPIKey.MessagesPerHour, rl.DefaultAPIKey.MessagesPerDay, limitAlgorithm(rl.DefaultAPIKey, algorithm))
 	} else {
-		fmt.Fprintln(w, "Per API Key\t-\t-")
+		fmt.Fprintln(w, "Per API Key\t-\t-\t-\t-")
 	}
 
 	w.Flush()
@@ -97,12 +103,13 @@ func runRatelimitShow(cmd *cobra.Command, args []string) error {
 			if !hasOverrides {
 				fmt.Println()
 				w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-				fmt.Fprintln(w, "DOMAIN\tMESSAGES/HOUR\tMESSAGES/DAY\tRECIPIENTS/MSG")
-				fmt.Fprintln(w, "------\t-------------\t------------\t--------------")
+				fmt.Fprintln(w, "DOMAIN\tMESSAGES/MIN\tMESSAGES/HOUR\tMESSAGES/DAY\tRECIPIENTS/MSG")
+				fmt.Fprintln(w, "------\t------------\t-------------\t------------\t--------------")
 				hasOverrides = true
 			}
-			fmt.Fprintf(w, "%s\t%d\t%d\t%d\n",
+			fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\n",
 				domain,
+				dc.RateLimit.MessagesPerMinute,
 				dc.RateLimit.MessagesPerHour,
 				dc.RateLimit.MessagesPerDay,
 				dc.RateLimit.RecipientsPerMessage,
@@ -121,3 +128,11 @@ func runRatelimitShow(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// limitAlgorithm returns the algorithm of a level, falling back to the default
+func limitAlgorithm(lv *config.LimitValues, def string) string {
+	if lv.Algorithm != "" {
+		return lv.Algorithm
+	}
+	return def
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/foxzi/sendry/internal/replication"
+)
+
+var replicateAPI serverAPI
+
+var replicateCmd = &cobra.Command{
+	Use:   "replicate",
+	Short: "Queue replication commands",
+	Long: `Inspect and control primary/standby queue replication of a running server.
+
+The commands call the API of the server named by the config file, or of --url.`,
+}
+
+var replicateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the replication state of the server",
+	RunE:  runReplicateStatus,
+}
+
+var replicatePromoteCmd = &cobra.Command{
+	Use:   "promote",
+	Short: "Promote a standby to deliver the replicated queue",
+	Long: `Promote a standby to deliver the replicated queue.
+
+Use it after the primary failed. The promoted standby accepts and delivers
+mail and no longer accepts changes from the old primary, which must not be
+started again with the same queue.`,
+	RunE: runReplicatePromote,
+}
+
+func init() {
+	replicateAPI.register(replicateCmd)
+
+	replicateCmd.AddCommand(replicateStatusCmd, replicatePromoteCmd)
+	rootCmd.AddCommand(replicateCmd)
+}
+
+func runReplicateStatus(cmd *cobra.Command, args []string) error {
+	var st replication.Status
+	if err := replicationRequest(http.MethodGet, "/api/v1/replication/status", &st); err != nil {
+		return err
+	}
+
+	fmt.Printf("Role:          %s\n", st.Role)
+	if st.Peer != "" {
+		fmt.Printf("Peer:          %s\n", st.Peer)
+	}
+	if st.Role != replication.RolePromoted {
+		fmt.Printf("Connected:     %t\n", st.Connected)
+	}
+	fmt.Printf("Last sync:     %s\n", formatReplicationTime(st.LastSync))
+	fmt.Printf("Last snapshot: %s\n", formatReplicationTime(st.LastSnapshot))
+	if st.Role == replication.RolePrimary {
+		fmt.Printf("Pending:       %d\n", st.Pending)
+	}
+	fmt.Printf("Replicated:    %d\n", st.Replicated)
+	if st.PromotedAt != nil {
+		fmt.Printf("Promoted at:   %s\n", formatReplicationTime(st.PromotedAt))
+	}
+	if st.LastError != "" {
+		fmt.Printf("Last error:    %s\n", st.LastError)
+	}
+	return nil
+}
+
+func runReplicatePromote(cmd *cobra.Command, args []string) error {
+	var st replication.Status
+	if err := replicationRequest(http.MethodPost, "/api/v1/replication/promote", &st); err != nil {
+		return err
+	}
+	fmt.Printf("Standby promoted at %s, it now delivers the queue\n", formatReplicationTime(st.PromotedAt))
+	return nil
+}
+
+// replicationRequest calls a replication endpoint of the server
+func replicationRequest(method, path string, out any) error {
+	err := replicateAPI.requestJSON(method, path, nil, out)
+	var apiErr *apiError
+	if errors.As(err, &apiErr) && apiErr.status == http.StatusNotFound {
+		return fmt.Errorf("replication is not enabled on the server")
+	}
+	return err
+}
+
+func formatReplicationTime(t *time.Time) string {
+	if t == nil {
+		return "never"
+	}
+	return t.Local().Format("2006-01-02 15:04:05")
+}
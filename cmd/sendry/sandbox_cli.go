@@ -1,13 +1,8 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"fmt"
-	"io"
-	"mime"
-	"mime/multipart"
-	"net/mail"
 	"os"
 	"strings"
 	"text/tabwriter"
@@ -17,6 +12,7 @@ import (
 	bolt "go.etcd.io/bbolt"
 
 	"github.com/foxzi/sendry/internal/config"
+	"github.com/foxzi/sendry/internal/message"
 	"github.com/foxzi/sendry/internal/sandbox"
 )
 
@@ -408,59 +404,9 @@ func runSandboxStats(cmd *cobra.Command, args []string) error {
 
 // extractHTMLPart extracts the HTML part from a MIME email message
 func extractHTMLPart(data []byte) (string, error) {
-	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	msg, err := message.Parse(data)
 	if err != nil {
 		return "", fmt.Errorf("failed to parse email: %w", err)
 	}
-
-	contentType := msg.Header.Get("Content-Type")
-	if contentType == "" {
-		return "", nil // No Content-Type, plain text only
-	}
-
-	mediaType, params, err := mime.ParseMediaType(contentType)
-	if err != nil {
-		return "", nil // Invalid Content-Type
-	}
-
-	// Check if it's directly HTML
-	if strings.HasPrefix(mediaType, "text/html") {
-		body, err := io.ReadAll(msg.Body)
-		if err != nil {
-			return "", err
-		}
-		return string(body), nil
-	}
-
-	// Check if it's multipart
-	if !strings.HasPrefix(mediaType, "multipart/") {
-		return "", nil // Not multipart, no HTML
-	}
-
-	boundary := params["boundary"]
-	if boundary == "" {
-		return "", nil
-	}
-
-	mr := multipart.NewReader(msg.Body, boundary)
-	for {
-		part, err := mr.NextPart()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return "", err
-		}
-
-		partType := part.Header.Get("Content-Type")
-		if strings.HasPrefix(partType, "text/html") {
-			body, err := io.ReadAll(part)
-			if err != nil {
-				return "", err
-			}
-			return string(body), nil
-		}
-	}
-
-	return "", nil // No HTML part found
+	return msg.HTMLBody(), nil
 }
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/foxzi/sendry/internal/config"
+	"github.com/foxzi/sendry/internal/queue"
+)
+
+var (
+	storageMigrateFrom string
+	storageMigrateTo   string
+)
+
+var storageCmd = &cobra.Command{
+	Use:   "storage",
+	Short: "Storage management commands",
+}
+
+var storageMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Copy queue messages between storage backends",
+	Long: `Copy all queue messages, including the dead letter queue, from one
+storage backend to another. Stop the server before migrating, then set
+storage.driver to the target backend.
+
+Examples:
+  sendry storage migrate -c config.yaml --from bolt --to sqlite`,
+	RunE: runStorageMigrate,
+}
+
+func init() {
+	storageMigrateCmd.Flags().StringVar(&storageMigrateFrom, "from", config.StorageDriverBolt, "Source backend (bolt, sqlite)")
+	storageMigrateCmd.Flags().StringVar(&storageMigrateTo, "to", "", "Target backend (bolt, sqlite)")
+	storageMigrateCmd.MarkFlagRequired("to")
+
+	storageCmd.AddCommand(storageMigrateCmd)
+	rootCmd.AddCommand(storageCmd)
+}
+
+// openStorageDriver opens the queue storage for the given backend
+func openStorageDriver(cfg *config.Config, driver string) (queue.Storage, error) {
+	switch driver {
+	case config.StorageDriverBolt:
+		if cfg.Storage.Shards > 1 {
+			storage, err := queue.NewShardedStorage(cfg.Storage.ShardDir, cfg.Storage.Shards)
+			if err != nil {
+				return nil, fmt.Errorf("failed to open queue storage: %w", err)
+			}
+			return storage, nil
+		}
+		storage, err := queue.NewBoltStorage(cfg.Storage.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open queue storage: %w", err)
+		}
+		return storage, nil
+	case config.StorageDriverSQLite:
+		storage, err := queue.NewSQLiteStorage(cfg.Storage.DSN)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open queue storage: %w", err)
+		}
+		return storage, nil
+	default:
+		return nil, fmt.Errorf("unsupported storage driver: %s (must be bolt or sqlite)", driver)
+	}
+}
+
+func runStorageMigrate(cmd *cobra.Command, args []string) error {
+	if cfgFile == "" {
+		return fmt.Errorf("config file is required (use -c flag)")
+	}
+
+	if storageMigrateFrom == storageMigrateTo {
+		return fmt.Errorf("source and target backends must differ")
+	}
+
+	cfg, err := config.Load(cfgFile)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	src, err := openStorageDriver(cfg, storageMigrateFrom)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := openStorageDriver(cfg, storageMigrateTo)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	result, err := queue.Migrate(context.Background(), src, dst)
+	if err != nil {
+		return fmt.Errorf("migration failed: %w", err)
+	}
+
+	fmt.Printf("Migrated %d messages (%d in DLQ) from %s to %s\n",
+		result.Messages, result.DLQ, storageMigrateFrom, storageMigrateTo)
+	return nil
+}
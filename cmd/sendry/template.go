@@ -328,6 +328,7 @@ func runTemplatePreview(cmd *cobra.Command, args []string) error {
 	}
 
 	engine := template.NewEngine()
+	engine.SetPartials(storage)
 	result, err := engine.Render(tmpl, data)
 	if err != nil {
 		return fmt.Errorf("failed to render template: %w", err)
@@ -0,0 +1,195 @@
+// Package addrpolicy enforces allow and deny lists of sender addresses,
+// recipient addresses and recipient domains before messages are queued.
+// Lists of the config and lists managed through the API apply together.
+package addrpolicy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/foxzi/sendry/internal/config"
+	"github.com/foxzi/sendry/internal/email"
+)
+
+// Message sources
+const (
+	SourceSMTP = "smtp"
+	SourceAPI  = "api"
+)
+
+// Violation is the error of an address refused by a list
+type Violation struct {
+	Address   string
+	Recipient bool // The address is a recipient, otherwise the sender
+}
+
+func (v *Violation) Error() string {
+	if v.Recipient {
+		return fmt.Sprintf("recipient %s is not allowed by address policy", v.Address)
+	}
+	return fmt.Sprintf("sender %s is not allowed by address policy", v.Address)
+}
+
+// Match reports whether an address matches one of the entries: an address,
+// with * and ? wildcards in the local part, a domain or *.domain for its
+// subdomains
+func Match(entries []string, addr string) bool {
+	for _, entry := range entries {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if at := strings.LastIndex(entry, "@"); at > 0 && strings.ContainsAny(entry[:at], "*?") {
+			a := strings.ToLower(email.ASCIIAddress(addr))
+			i := strings.LastIndex(a, "@")
+			if i < 0 || a[i+1:] != email.ASCIIDomain(entry[at+1:]) {
+				continue
+			}
+			if matched, _ := path.Match(entry[:at], a[:i]); matched {
+				return true
+			}
+			continue
+		}
+		if email.SenderAllowed([]string{entry}, addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// DomainLookup returns the configuration of a sender domain, nil if the
+// domain is not configured
+type DomainLookup func(domain string) *config.DomainConfig
+
+// Enforcer checks envelope addresses against the lists of their sender
+// domain or the global lists
+type Enforcer struct {
+	mu      sync.RWMutex
+	global  config.AddressPolicyConfig
+	domains DomainLookup
+	storage *Storage
+	logger  *slog.Logger
+}
+
+// New creates an enforcer of the global lists. domains may be nil.
+func New(global config.AddressPolicyConfig, domains DomainLookup, logger *slog.Logger) *Enforcer {
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	return &Enforcer{global: global, domains: domains, logger: logger}
+}
+
+// SetPolicy replaces the global lists of the config, e.g. on a config
+// reload
+func (e *Enforcer) SetPolicy(global config.AddressPolicyConfig) {
+	e.mu.Lock()
+	e.global = global
+	e.mu.Unlock()
+}
+
+// SetStorage sets the lists managed through the API
+func (e *Enforcer) SetStorage(s *Storage) {
+	e.mu.Lock()
+	e.storage = s
+	e.mu.Unlock()
+}
+
+// PolicyFor returns the lists that apply to messages from a sender. The
+// lists of the sender domain, from the config or the API, replace the
+// global ones.
+func (e *Enforcer) PolicyFor(from string) config.AddressPolicyConfig {
+	e.mu.RLock()
+	global, storage := e.global, e.storage
+	e.mu.RUnlock()
+
+	domain := NormalizeScope(email.ExtractDomain(from))
+	var stored *Policy
+	if storage != nil && domain != "" {
+		// A storage error leaves the config lists
+		stored, _ = storage.Get(context.Background(), domain)
+	}
+	var dc *config.DomainConfig
+	if e.domains != nil && domain != "" {
+		dc = e.domains(domain)
+	}
+
+	if stored != nil || (dc != nil && dc.AddressPolicy != nil) {
+		var p config.AddressPolicyConfig
+		if dc != nil && dc.AddressPolicy != nil {
+			p = *dc.AddressPolicy
+		}
+		if stored != nil {
+			p = merge(p, stored.AddressPolicyConfig)
+		}
+		return p
+	}
+
+	if storage != nil {
+		if stored, _ := storage.Get(context.Background(), GlobalScope); stored != nil {
+			return merge(global, stored.AddressPolicyConfig)
+		}
+	}
+	return global
+}
+
+// merge returns the entries of both lists
+func merge(a, b config.AddressPolicyConfig) config.AddressPolicyConfig {
+	return config.AddressPolicyConfig{
+		AllowSenders:    append(append([]string(nil), a.AllowSenders...), b.AllowSenders...),
+		DenySenders:     append(append([]string(nil), a.DenySenders...), b.DenySenders...),
+		AllowRecipients: append(append([]string(nil), a.AllowRecipients...), b.AllowRecipients...),
+		DenyRecipients:  append(append([]string(nil), a.DenyRecipients...), b.DenyRecipients...),
+	}
+}
+
+// allowed reports whether an address passes a deny and an allow list. An
+// empty allow list allows all addresses.
+func allowed(allow, deny []string, addr string) bool {
+	if Match(deny, addr) {
+		return false
+	}
+	return len(allow) == 0 || Match(allow, addr)
+}
+
+// CheckSender checks the envelope sender. A null sender and a nil enforcer
+// are accepted.
+func (e *Enforcer) CheckSender(source, from string) error {
+	if e == nil || from == "" {
+		return nil
+	}
+	p := e.PolicyFor(from)
+	if !allowed(p.AllowSenders, p.DenySenders, from) {
+		e.logger.Info("sender refused by address policy", "source", source, "from", from)
+		return &Violation{Address: from}
+	}
+	return nil
+}
+
+// CheckRecipient checks a recipient of a message from a sender. A nil
+// enforcer accepts all recipients.
+func (e *Enforcer) CheckRecipient(source, from, to string) error {
+	if e == nil {
+		return nil
+	}
+	p := e.PolicyFor(from)
+	if !allowed(p.AllowRecipients, p.DenyRecipients, to) {
+		e.logger.Info("recipient refused by address policy", "source", source, "from", from, "to", to)
+		return &Violation{Address: to, Recipient: true}
+	}
+	return nil
+}
+
+// Check checks the sender and all recipients of a message
+func (e *Enforcer) Check(source, from string, to []string) error {
+	if err := e.CheckSender(source, from); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := e.CheckRecipient(source, from, rcpt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
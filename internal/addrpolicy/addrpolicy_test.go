@@ -0,0 +1,156 @@
+package addrpolicy
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/foxzi/sendry/internal/config"
+)
+
+func newTestStorage(t *testing.T) *Storage {
+	t.Helper()
+
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	storage, err := NewStorage(db)
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+	return storage
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		entry string
+		addr  string
+		want  bool
+	}{
+		{"user@example.com", "User@Example.com", true},
+		{"user@example.com", "other@example.com", false},
+		{"example.com", "user@example.com", true},
+		{"example.com", "user@mail.example.com", false},
+		{"*.example.com", "user@mail.example.com", true},
+		{"*.example.com", "user@example.com", false},
+		{"*@example.com", "anyone@example.com", true},
+		{"noreply-*@example.com", "noreply-billing@example.com", true},
+		{"noreply-*@example.com", "billing@example.com", false},
+		{"noreply-*@example.com", "noreply-billing@example.org", false},
+		{"user?@example.com", "user1@example.com", true},
+		{"пример.рф", "user@xn--e1afmkfd.xn--p1ai", true},
+	}
+	for _, tt := range tests {
+		if got := Match([]string{tt.entry}, tt.addr); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.entry, tt.addr, got, tt.want)
+		}
+	}
+	if Match(nil, "user@example.com") {
+		t.Error("Match() with no entries = true, want false")
+	}
+}
+
+func TestEnforcer(t *testing.T) {
+	domains := map[string]*config.DomainConfig{
+		"partner.example": {AddressPolicy: &config.AddressPolicyConfig{AllowRecipients: []string{"partner.example"}}},
+	}
+	e := New(config.AddressPolicyConfig{
+		DenySenders:    []string{"spammer@example.com"},
+		DenyRecipients: []string{"blocked.example", "ceo@example.org"},
+	}, func(domain string) *config.DomainConfig { return domains[domain] }, nil)
+
+	var v *Violation
+	if err := e.CheckSender(SourceSMTP, "spammer@example.com"); !errors.As(err, &v) || v.Recipient {
+		t.Errorf("CheckSender() denied sender error = %v, want sender violation", err)
+	}
+	if err := e.CheckSender(SourceSMTP, ""); err != nil {
+		t.Errorf("CheckSender() null sender error = %v", err)
+	}
+	if err := e.Check(SourceAPI, "news@example.com", []string{"a@example.net", "b@blocked.example"}); !errors.As(err, &v) || !v.Recipient || v.Address != "b@blocked.example" {
+		t.Errorf("Check() denied recipient error = %v, want recipient violation", err)
+	}
+	if err := e.Check(SourceAPI, "news@example.com", []string{"a@example.net"}); err != nil {
+		t.Errorf("Check() error = %v", err)
+	}
+
+	// The lists of the sender domain replace the global lists
+	if err := e.CheckRecipient(SourceSMTP, "ops@partner.example", "ceo@example.org"); err == nil {
+		t.Error("CheckRecipient() outside the domain allow list accepted")
+	}
+	if err := e.CheckRecipient(SourceSMTP, "ops@partner.example", "team@partner.example"); err != nil {
+		t.Errorf("CheckRecipient() domain allow list error = %v", err)
+	}
+
+	// Stored lists are added to the lists of their scope
+	storage := newTestStorage(t)
+	e.SetStorage(storage)
+	ctx := context.Background()
+	if err := storage.Put(ctx, &Policy{Scope: GlobalScope, AddressPolicyConfig: config.AddressPolicyConfig{DenySenders: []string{"*.spam.example"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.Put(ctx, &Policy{Scope: "Acme.Example", AddressPolicyConfig: config.AddressPolicyConfig{AllowSenders: []string{"billing@acme.example"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.CheckSender(SourceAPI, "bulk@mx.spam.example"); err == nil {
+		t.Error("CheckSender() sender denied by stored global list accepted")
+	}
+	if err := e.CheckSender(SourceAPI, "spammer@example.com"); err == nil {
+		t.Error("CheckSender() sender denied by config accepted with stored global list")
+	}
+	if err := e.CheckSender(SourceAPI, "news@acme.example"); err == nil {
+		t.Error("CheckSender() sender outside stored domain allow list accepted")
+	}
+	if err := e.CheckRecipient(SourceAPI, "billing@acme.example", "ceo@example.org"); err != nil {
+		t.Errorf("CheckRecipient() error = %v, want global lists replaced by domain lists", err)
+	}
+
+	// The config is replaced on reload
+	e.SetPolicy(config.AddressPolicyConfig{})
+	if err := e.CheckSender(SourceAPI, "spammer@example.com"); err != nil {
+		t.Errorf("CheckSender() after reload error = %v", err)
+	}
+
+	var nilEnforcer *Enforcer
+	if err := nilEnforcer.Check(SourceAPI, "spammer@example.com", []string{"a@blocked.example"}); err != nil {
+		t.Errorf("nil enforcer Check() error = %v", err)
+	}
+}
+
+func TestStorage(t *testing.T) {
+	storage := newTestStorage(t)
+	ctx := context.Background()
+
+	for _, scope := range []string{"zeta.example", GlobalScope, "alpha.example"} {
+		if err := storage.Put(ctx, &Policy{Scope: scope, AddressPolicyConfig: config.AddressPolicyConfig{DenySenders: []string{"x@example.com"}}}); err != nil {
+			t.Fatalf("Put(%s) error = %v", scope, err)
+		}
+	}
+	if err := storage.Put(ctx, &Policy{Scope: "bad.example", AddressPolicyConfig: config.AddressPolicyConfig{DenySenders: []string{"not valid"}}}); err == nil {
+		t.Error("Put() with invalid entry succeeded")
+	}
+
+	list, err := storage.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var scopes []string
+	for _, p := range list {
+		scopes = append(scopes, p.Scope)
+	}
+	if len(scopes) != 3 || scopes[0] != GlobalScope || scopes[1] != "alpha.example" || scopes[2] != "zeta.example" {
+		t.Errorf("List() scopes = %v, want global first then by domain", scopes)
+	}
+
+	if err := storage.Delete(ctx, "ALPHA.example"); err != nil {
+		t.Fatal(err)
+	}
+	if p, err := storage.Get(ctx, "alpha.example"); err != nil || p != nil {
+		t.Errorf("Get() after Delete() = %v, %v, want nil", p, err)
+	}
+}
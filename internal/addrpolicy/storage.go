@@ -0,0 +1,125 @@
+package addrpolicy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/foxzi/sendry/internal/config"
+)
+
+var bucketPolicies = []byte("address_policies")
+
+// GlobalScope is the scope of the lists that apply to senders of domains
+// without lists of their own
+const GlobalScope = "global"
+
+// Policy is the allow and deny lists of a scope managed through the API
+type Policy struct {
+	Scope string `json:"scope"` // global or a sender domain
+	config.AddressPolicyConfig
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Storage provides policy storage
+type Storage struct {
+	db *bolt.DB
+}
+
+// NewStorage creates a new policy storage using the provided BoltDB instance
+func NewStorage(db *bolt.DB) (*Storage, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucketPolicies)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create address policies bucket: %w", err)
+	}
+	return &Storage{db: db}, nil
+}
+
+// NormalizeScope returns the scope in the form policies are stored under
+func NormalizeScope(scope string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(scope), "."))
+}
+
+// Get retrieves the policy of a scope, nil if it has none
+func (s *Storage) Get(ctx context.Context, scope string) (*Policy, error) {
+	var p *Policy
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bucketPolicies).Get([]byte(NormalizeScope(scope)))
+		if data == nil {
+			return nil
+		}
+		p = &Policy{}
+		return json.Unmarshal(data, p)
+	})
+
+	return p, err
+}
+
+// List returns all policies, the global one first, then ordered by domain
+func (s *Storage) List(ctx context.Context) ([]*Policy, error) {
+	var result []*Policy
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketPolicies).ForEach(func(k, v []byte) error {
+			var p Policy
+			if err := json.Unmarshal(v, &p); err != nil {
+				return nil // Skip invalid entries
+			}
+			result = append(result, &p)
+			return nil
+		})
+	})
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Scope == GlobalScope && result[j].Scope != GlobalScope
+	})
+	return result, err
+}
+
+// Put creates or replaces the policy of a scope
+func (s *Storage) Put(ctx context.Context, p *Policy) error {
+	p.Scope = NormalizeScope(p.Scope)
+	if p.Scope == "" {
+		return fmt.Errorf("scope is required")
+	}
+	if err := config.ValidateAddressPolicy("policy", &p.AddressPolicyConfig); err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketPolicies)
+
+		now := time.Now()
+		p.CreatedAt = now
+		if existing := b.Get([]byte(p.Scope)); existing != nil {
+			var old Policy
+			if err := json.Unmarshal(existing, &old); err == nil {
+				p.CreatedAt = old.CreatedAt
+			}
+		}
+		p.UpdatedAt = now
+
+		data, err := json.Marshal(p)
+		if err != nil {
+			return fmt.Errorf("failed to marshal policy: %w", err)
+		}
+		return b.Put([]byte(p.Scope), data)
+	})
+}
+
+// Delete removes the policy of a scope
+func (s *Storage) Delete(ctx context.Context, scope string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketPolicies).Delete([]byte(NormalizeScope(scope)))
+	})
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"net/mail"
+	"strings"
+
+	"github.com/foxzi/sendry/internal/email"
+	"github.com/foxzi/sendry/internal/queue"
+)
+
+// formatAddressList formats addresses for a From, To or Cc header. Display
+// names with non-ASCII characters are RFC 2047 encoded, the addresses are
+// kept as they are, internationalized ones as UTF-8 (RFC 6532).
+func formatAddressList(addrs []string) string {
+	formatted := make([]string, len(addrs))
+	for i, addr := range addrs {
+		formatted[i] = formatAddress(addr)
+	}
+	return strings.Join(formatted, ", ")
+}
+
+// formatAddress formats one address of a header
+func formatAddress(addr string) string {
+	parsed, err := mail.ParseAddress(addr)
+	if err != nil {
+		return sanitizeHeaderValue(addr)
+	}
+	if parsed.Name == "" {
+		return parsed.Address
+	}
+	return parsed.String()
+}
+
+// encodeHeaderValue RFC 2047 encodes a header value with non-ASCII
+// characters, folding the line between encoded words. ASCII values are
+// returned as they are.
+func encodeHeaderValue(v string) string {
+	return strings.ReplaceAll(mime.QEncoding.Encode("utf-8", v), "?= =?", "?=\r\n =?")
+}
+
+// checkEAI refuses a message with internationalized addresses when
+// smtp.reject_eai is set
+func checkEAI(reject bool, msg *queue.Message) (int, string) {
+	if !reject {
+		return 0, ""
+	}
+	for _, addr := range append([]string{msg.From}, msg.To...) {
+		if parsed, err := mail.ParseAddress(addr); err == nil {
+			addr = parsed.Address
+		}
+		if email.IsInternational(addr) {
+			return http.StatusBadRequest, fmt.Sprintf("internationalized address not accepted: %s", addr)
+		}
+	}
+	return 0, ""
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/foxzi/sendry/internal/config"
+)
+
+func TestFormatAddressList(t *testing.T) {
+	got := formatAddressList([]string{"user@example.com", "Иван <ivan@пример.рф>", "пользователь@пример.рф"})
+	want := "user@example.com, =?utf-8?q?=D0=98=D0=B2=D0=B0=D0=BD?= <ivan@пример.рф>, пользователь@пример.рф"
+	if got != want {
+		t.Errorf("formatAddressList() = %q, want %q", got, want)
+	}
+}
+
+func TestEncodeHeaderValue(t *testing.T) {
+	if got := encodeHeaderValue("Hello"); got != "Hello" {
+		t.Errorf("encodeHeaderValue(ASCII) = %q", got)
+	}
+
+	got := encodeHeaderValue(strings.Repeat("Привет ", 20))
+	for _, line := range strings.Split(got, "\r\n") {
+		if len(line) > 78 {
+			t.Errorf("line of %d characters: %q", len(line), line)
+		}
+		if !strings.HasPrefix(strings.TrimSpace(line), "=?utf-8?q?") {
+			t.Errorf("line is not an encoded word: %q", line)
+		}
+	}
+}
+
+func TestSendEAI(t *testing.T) {
+	body := `{
+		"from": "отправитель@пример.рф",
+		"to": ["Получатель <получатель@example.com>"],
+		"subject": "Тема",
+		"body": "Привет"
+	}`
+
+	t.Run("accepted", func(t *testing.T) {
+		server, q := setupTestServer("")
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, httptest.NewRequest("POST", "/api/v1/send", bytes.NewBufferString(body)))
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("Status = %d: %s", w.Code, w.Body.String())
+		}
+
+		var resp SendResponse
+		json.NewDecoder(w.Body).Decode(&resp)
+		data := string(q.messages[resp.ID].Data)
+		for _, want := range []string{
+			"From: отправитель@пример.рф\r\n",
+			"To: =?utf-8?q?",
+			"<получатель@example.com>\r\n",
+			"Subject: =?utf-8?q?=D0=A2=D0=B5=D0=BC=D0=B0?=\r\n",
+		} {
+			if !strings.Contains(data, want) {
+				t.Errorf("message lacks %q:\n%s", want, data)
+			}
+		}
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		q := newMockQueue()
+		server := NewServerWithOptions(ServerOptions{
+			Queue:      q,
+			Config:     &config.APIConfig{ListenAddr: ":8080"},
+			FullConfig: &config.Config{SMTP: config.SMTPConfig{RejectEAI: true}},
+			Logger:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+		})
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, httptest.NewRequest("POST", "/api/v1/send", bytes.NewBufferString(body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Status = %d, want 400", w.Code)
+		}
+
+		// IDN domains alone are not internationalized addresses
+		idn := `{"from": "shop@пример.рф", "to": ["user@example.com"], "subject": "s", "body": "b"}`
+		w = httptest.NewRecorder()
+		server.router.ServeHTTP(w, httptest.NewRequest("POST", "/api/v1/send", bytes.NewBufferString(idn)))
+		if w.Code != http.StatusAccepted {
+			t.Errorf("IDN sender Status = %d: %s", w.Code, w.Body.String())
+		}
+	})
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/foxzi/sendry/internal/apikey"
+	"github.com/foxzi/sendry/internal/email"
+	"github.com/foxzi/sendry/internal/queue"
+	"github.com/foxzi/sendry/internal/ratelimit"
+)
+
+const ctxKeyAPIKey ctxKey = "api_key"
+
+// APIKeyServer handles API key management requests
+type APIKeyServer struct {
+	storage *apikey.Storage
+}
+
+// NewAPIKeyServer creates a new API key server
+func NewAPIKeyServer(storage *apikey.Storage) *APIKeyServer {
+	return &APIKeyServer{storage: storage}
+}
+
+// RegisterRoutes registers API key routes
+func (s *APIKeyServer) RegisterRoutes(r chi.Router) {
+	r.Get("/apikeys", s.handleList)
+	r.Post("/apikeys", s.handleCreate)
+	r.Get("/apikeys/{id}", s.handleGet)
+	r.Delete("/apikeys/{id}", s.handleRevoke)
+}
+
+// APIKeyCreateRequest is the request body for POST /apikeys
+type APIKeyCreateRequest struct {
+	Name      string                 `json:"name"`
+	Scopes    []apikey.Scope         `json:"scopes"`
+	RateLimit *ratelimit.LimitConfig `json:"rate_limit,omitempty"`
+	Senders   []string               `json:"senders,omitempty"`
+}
+
+// APIKeyCreateResponse is a new key with its token, which is shown only once
+type APIKeyCreateResponse struct {
+	*apikey.Key
+	Token string `json:"token"`
+}
+
+// APIKeyListResponse represents API key list response
+type APIKeyListResponse struct {
+	Keys  []*apikey.Key `json:"keys"`
+	Total int           `json:"total"`
+}
+
+func (s *APIKeyServer) handleList(w http.ResponseWriter, r *http.Request) {
+	keys, err := s.storage.List(r.Context())
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if keys == nil {
+		keys = []*apikey.Key{}
+	}
+	sendJSON(w, http.StatusOK, APIKeyListResponse{Keys: keys, Total: len(keys)})
+}
+
+func (s *APIKeyServer) handleCreate(w http.ResponseWriter, r *http.Request) {
+	var req APIKeyCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	key, token, err := s.storage.Create(r.Context(), req.Name, req.Scopes, req.RateLimit, req.Senders)
+	if err != nil {
+		sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	sendJSON(w, http.StatusCreated, APIKeyCreateResponse{Key: key, Token: token})
+}
+
+func (s *APIKeyServer) handleGet(w http.ResponseWriter, r *http.Request) {
+	key, err := s.storage.Get(r.Context(), chi.URLParam(r, "id"))
+	if errors.Is(err, apikey.ErrNotFound) {
+		sendError(w, http.StatusNotFound, "API key not found")
+		return
+	}
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	sendJSON(w, http.StatusOK, key)
+}
+
+func (s *APIKeyServer) handleRevoke(w http.ResponseWriter, r *http.Request) {
+	key, err := s.storage.Revoke(r.Context(), chi.URLParam(r, "id"))
+	if errors.Is(err, apikey.ErrNotFound) {
+		sendError(w, http.StatusNotFound, "API key not found")
+		return
+	}
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	sendJSON(w, http.StatusOK, key)
+}
+
+// requiredScope returns the scope a request needs. Message submission and
+// status lookups need send, other reads need read and everything else,
+// including key management, needs admin.
+func requiredScope(r *http.Request) apikey.Scope {
+	path := r.URL.Path
+	// A backup contains API keys and all stored data
+	if strings.HasPrefix(path, "/api/v1/apikeys") || strings.HasPrefix(path, "/api/v1/smtp-users") || path == "/api/v1/backup" {
+		return apikey.ScopeAdmin
+	}
+	if isSubmission(r) || (r.Method == http.MethodGet && strings.HasPrefix(path, "/api/v1/status/")) {
+		return apikey.ScopeSend
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		return apikey.ScopeRead
+	}
+	if r.Method == http.MethodPost && strings.HasSuffix(path, "/preview") {
+		return apikey.ScopeRead
+	}
+	return apikey.ScopeAdmin
+}
+
+// isSubmission reports whether a request submits messages
+func isSubmission(r *http.Request) bool {
+	if r.Method != http.MethodPost {
+		return false
+	}
+	switch r.URL.Path {
+	case "/api/v1/send", "/api/v1/send/batch", "/api/v1/send/raw", "/api/v1/send/template":
+		return true
+	}
+	return false
+}
+
+// apiKeyFromContext returns the API key that authenticated the request, nil
+// for the api.api_key of the config or when auth is off
+func apiKeyFromContext(ctx context.Context) *apikey.Key {
+	key, _ := ctx.Value(ctxKeyAPIKey).(*apikey.Key)
+	return key
+}
+
+// setMessageAPIKey records on a new message the API key that submitted it,
+// sandbox overrides of the key match on it
+func setMessageAPIKey(ctx context.Context, msg *queue.Message) {
+	if key := apiKeyFromContext(ctx); key != nil {
+		msg.APIKeyID = key.ID
+		msg.APIKeyName = key.Name
+	}
+}
+
+// checkSenders refuses a message whose envelope sender or From header the
+// API key of the request is not bound to
+func checkSenders(ctx context.Context, msg *queue.Message) (int, string) {
+	key := apiKeyFromContext(ctx)
+	if key == nil {
+		return 0, ""
+	}
+	if addr := email.UnauthorizedSender(key.Senders, msg.From, msg.Data); addr != "" {
+		return http.StatusForbidden, fmt.Sprintf("sender %s is not authorized for this API key", addr)
+	}
+	return 0, ""
+}
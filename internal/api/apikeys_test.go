@@ -0,0 +1,249 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/foxzi/sendry/internal/apikey"
+	"github.com/foxzi/sendry/internal/config"
+	"github.com/foxzi/sendry/internal/ratelimit"
+)
+
+const testSendBody = `{"from": "sender@example.com", "to": ["a@example.com"], "subject": "Hi", "body": "Hello"}`
+
+func setupAPIKeyServer(t *testing.T, adminKey string) (*Server, *apikey.Storage) {
+	t.Helper()
+
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	storage, err := apikey.NewStorage(db)
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+
+	limiter, err := ratelimit.NewLimiter(db, &ratelimit.Config{FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("NewLimiter() error = %v", err)
+	}
+	t.Cleanup(func() { limiter.Stop() })
+
+	return NewServerWithOptions(ServerOptions{
+		Queue:         newMockQueue(),
+		Config:        &config.APIConfig{ListenAddr: ":8080", APIKey: adminKey},
+		Logger:        slog.New(slog.NewTextHandler(io.Discard, nil)),
+		RateLimiter:   limiter,
+		APIKeyStorage: storage,
+	}), storage
+}
+
+func doWithToken(server *Server, method, path, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAPIKeyManagement(t *testing.T) {
+	server, _ := setupAPIKeyServer(t, "admin-secret")
+
+	w := doWithToken(server, "POST", "/api/v1/apikeys", "admin-secret",
+		`{"name": "billing", "scopes": ["send"]}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create status = %d, body: %s", w.Code, w.Body.String())
+	}
+	var created APIKeyCreateResponse
+	if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+	if created.Token == "" || created.ID == "" {
+		t.Fatalf("created = %+v", created)
+	}
+
+	w = doWithToken(server, "GET", "/api/v1/apikeys", "admin-secret", "")
+	var list APIKeyListResponse
+	if err := json.NewDecoder(w.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	if list.Total != 1 || list.Keys[0].Name != "billing" {
+		t.Errorf("list = %+v", list)
+	}
+
+	// Invalid scopes are rejected
+	w = doWithToken(server, "POST", "/api/v1/apikeys", "admin-secret", `{"name": "x", "scopes": ["root"]}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("invalid scope status = %d", w.Code)
+	}
+
+	w = doWithToken(server, "DELETE", "/api/v1/apikeys/"+created.ID, "admin-secret", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("revoke status = %d, body: %s", w.Code, w.Body.String())
+	}
+	if w := doWithToken(server, "POST", "/api/v1/send", created.Token, testSendBody); w.Code != http.StatusUnauthorized {
+		t.Errorf("revoked key status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+	if w := doWithToken(server, "DELETE", "/api/v1/apikeys/unknown", "admin-secret", ""); w.Code != http.StatusNotFound {
+		t.Errorf("revoke unknown status = %d", w.Code)
+	}
+}
+
+func TestAPIKeyScopes(t *testing.T) {
+	server, storage := setupAPIKeyServer(t, "")
+
+	_, sendToken, err := storage.Create(t.Context(), "sender", []apikey.Scope{apikey.ScopeSend}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, readToken, err := storage.Create(t.Context(), "viewer", []apikey.Scope{apikey.ScopeRead}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		token  string
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{"send key sends", sendToken, "POST", "/api/v1/send", testSendBody, http.StatusAccepted},
+		{"send key reads queue", sendToken, "GET", "/api/v1/queue", "", http.StatusForbidden},
+		{"send key manages keys", sendToken, "GET", "/api/v1/apikeys", "", http.StatusForbidden},
+		{"read key reads queue", readToken, "GET", "/api/v1/queue", "", http.StatusOK},
+		{"read key sends", readToken, "POST", "/api/v1/send", testSendBody, http.StatusForbidden},
+		{"read key deletes", readToken, "DELETE", "/api/v1/queue/abc", "", http.StatusForbidden},
+		{"unknown token", "sndr_unknown", "GET", "/api/v1/queue", "", http.StatusUnauthorized},
+		// Stored keys turn authentication on without api.api_key
+		{"no token", "", "GET", "/api/v1/queue", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doWithToken(server, tt.method, tt.path, tt.token, tt.body)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d. Body: %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+
+	keys, err := storage.List(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range keys {
+		if key.LastUsedAt == nil {
+			t.Errorf("key %s has no last use", key.Name)
+		}
+	}
+}
+
+func TestAPIKeyRecordedOnMessage(t *testing.T) {
+	server, storage := setupAPIKeyServer(t, "admin-key")
+
+	key, token, err := storage.Create(t.Context(), "staging", []apikey.Scope{apikey.ScopeSend}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Sandbox overrides of API keys match on the recorded key
+	for _, tt := range []struct {
+		token            string
+		wantID, wantName string
+	}{
+		{token, key.ID, "staging"},
+		{"admin-key", "", ""},
+	} {
+		w := doWithToken(server, "POST", "/api/v1/send", tt.token, testSendBody)
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("send status = %d. Body: %s", w.Code, w.Body.String())
+		}
+		var resp SendResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+
+		msg := server.queue.(*mockQueue).messages[resp.ID]
+		if msg == nil {
+			t.Fatalf("message %s not queued", resp.ID)
+		}
+		if msg.APIKeyID != tt.wantID || msg.APIKeyName != tt.wantName {
+			t.Errorf("message records key %q/%q, want %q/%q", msg.APIKeyID, msg.APIKeyName, tt.wantID, tt.wantName)
+		}
+	}
+}
+
+func TestAPIKeyRateLimit(t *testing.T) {
+	server, storage := setupAPIKeyServer(t, "")
+
+	limit := &ratelimit.LimitConfig{MessagesPerMinute: 2}
+	_, token, err := storage.Create(t.Context(), "limited", []apikey.Scope{apikey.ScopeSend}, limit, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range 2 {
+		if w := doWithToken(server, "POST", "/api/v1/send", token, testSendBody); w.Code != http.StatusAccepted {
+			t.Fatalf("send %d status = %d", i, w.Code)
+		}
+	}
+	w := doWithToken(server, "POST", "/api/v1/send", token, testSendBody)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusTooManyRequests)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("Retry-After header missing")
+	}
+
+	// Status lookups do not count against the limit
+	if w := doWithToken(server, "GET", "/api/v1/status/unknown", token, ""); w.Code != http.StatusNotFound {
+		t.Errorf("status lookup = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestAPIKeySenders(t *testing.T) {
+	server, storage := setupAPIKeyServer(t, "admin-key")
+
+	_, token, err := storage.Create(t.Context(), "acme", []apikey.Scope{apikey.ScopeSend}, nil, []string{"example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if w := doWithToken(server, "POST", "/api/v1/send", token, testSendBody); w.Code != http.StatusAccepted {
+		t.Errorf("authorized sender status = %d. Body: %s", w.Code, w.Body.String())
+	}
+	foreign := strings.Replace(testSendBody, "sender@example.com", "ceo@globex.example", 1)
+	w := doWithToken(server, "POST", "/api/v1/send", token, foreign)
+	if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), "ceo@globex.example") {
+		t.Errorf("foreign sender status = %d, want 403 naming the sender. Body: %s", w.Code, w.Body.String())
+	}
+
+	// The From header of raw messages is checked besides the envelope
+	raw := "From: CEO <ceo@globex.example>\r\nTo: a@example.com\r\nSubject: Hi\r\n\r\nHello\r\n"
+	req := httptest.NewRequest("POST", "/api/v1/send/raw?from=bounce@example.com", strings.NewReader(raw))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "message/rfc822")
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("raw foreign From header status = %d, want 403", w.Code)
+	}
+
+	// Keys without senders and the config key may use any sender
+	if w := doWithToken(server, "POST", "/api/v1/send", "admin-key", foreign); w.Code != http.StatusAccepted {
+		t.Errorf("config key status = %d, want 202", w.Code)
+	}
+}
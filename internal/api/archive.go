@@ -0,0 +1,150 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/foxzi/sendry/internal/archive"
+)
+
+// ArchiveServer handles message archive API requests
+type ArchiveServer struct {
+	storage *archive.Storage
+}
+
+// NewArchiveServer creates a new archive server
+func NewArchiveServer(storage *archive.Storage) *ArchiveServer {
+	return &ArchiveServer{storage: storage}
+}
+
+// RegisterRoutes registers archive routes
+func (s *ArchiveServer) RegisterRoutes(r chi.Router) {
+	r.Get("/archive", s.handleSearch)
+	r.Get("/archive/{id}", s.handleGet)
+	r.Get("/archive/{id}/raw", s.handleRaw)
+}
+
+// ArchiveSearchResponse represents archive search response
+type ArchiveSearchResponse struct {
+	Messages []*archive.Message `json:"messages"`
+	Total    int                `json:"total"`
+}
+
+func (s *ArchiveServer) handleSearch(w http.ResponseWriter, r *http.Request) {
+	q, err := parseArchiveQuery(r)
+	if err != nil {
+		sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	messages, total, err := s.storage.Search(r.Context(), q)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	sendJSON(w, http.StatusOK, ArchiveSearchResponse{
+		Messages: messages,
+		Total:    total,
+	})
+}
+
+func (s *ArchiveServer) handleGet(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "invalid message id")
+		return
+	}
+
+	msg, err := s.storage.Get(r.Context(), id)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if msg == nil {
+		sendError(w, http.StatusNotFound, "message not found")
+		return
+	}
+
+	sendJSON(w, http.StatusOK, msg)
+}
+
+func (s *ArchiveServer) handleRaw(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "invalid message id")
+		return
+	}
+
+	raw, err := s.storage.Raw(r.Context(), id)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if raw == nil {
+		sendError(w, http.StatusNotFound, "message not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "message/rfc822")
+	w.WriteHeader(http.StatusOK)
+	w.Write(raw)
+}
+
+// parseArchiveQuery parses the search parameters of an archive request
+func parseArchiveQuery(r *http.Request) (archive.Query, error) {
+	params := r.URL.Query()
+	q := archive.Query{
+		Text:      params.Get("query"),
+		Sender:    params.Get("sender"),
+		Recipient: params.Get("recipient"),
+		Subject:   params.Get("subject"),
+	}
+
+	var err error
+	if q.Since, err = parseArchiveTime(params.Get("since"), false); err != nil {
+		return q, fmt.Errorf("invalid since: %w", err)
+	}
+	if q.Until, err = parseArchiveTime(params.Get("until"), true); err != nil {
+		return q, fmt.Errorf("invalid until: %w", err)
+	}
+
+	if l := params.Get("limit"); l != "" {
+		limit, err := strconv.Atoi(l)
+		if err != nil || limit < 1 || limit > 1000 {
+			return q, fmt.Errorf("limit must be between 1 and 1000")
+		}
+		q.Limit = limit
+	}
+	if o := params.Get("offset"); o != "" {
+		offset, err := strconv.Atoi(o)
+		if err != nil || offset < 0 {
+			return q, fmt.Errorf("offset must not be negative")
+		}
+		q.Offset = offset
+	}
+	return q, nil
+}
+
+// parseArchiveTime parses an RFC 3339 time or a YYYY-MM-DD date. A date used
+// as an upper bound includes the whole day.
+func parseArchiveTime(value string, end bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither RFC 3339 nor YYYY-MM-DD", value)
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
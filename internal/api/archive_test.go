@@ -0,0 +1,89 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/foxzi/sendry/internal/archive"
+	"github.com/foxzi/sendry/internal/config"
+	"github.com/foxzi/sendry/internal/queue"
+)
+
+func setupArchiveServer(t *testing.T) (*Server, *archive.Storage) {
+	t.Helper()
+
+	storage, err := archive.NewStorage(filepath.Join(t.TempDir(), "archive.db"))
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+	t.Cleanup(func() { storage.Close() })
+
+	server := NewServerWithOptions(ServerOptions{
+		Queue:          newMockQueue(),
+		Config:         &config.APIConfig{ListenAddr: ":8080"},
+		Logger:         slog.New(slog.NewTextHandler(io.Discard, nil)),
+		ArchiveStorage: storage,
+	})
+	return server, storage
+}
+
+func TestArchiveAPI(t *testing.T) {
+	server, storage := setupArchiveServer(t)
+
+	data := "Subject: Order shipped\r\n\r\nYour parcel is on its way"
+	err := storage.Archive(context.Background(), &queue.Message{
+		ID:        "q1",
+		From:      "shop@example.com",
+		To:        []string{"user@example.org"},
+		Data:      []byte(data),
+		UpdatedAt: time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("Archive() error = %v", err)
+	}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w
+	}
+
+	w := get("/api/v1/archive?query=parcel&since=2026-03-10&until=2026-03-10")
+	if w.Code != http.StatusOK {
+		t.Fatalf("search status = %d: %s", w.Code, w.Body.String())
+	}
+	var resp ArchiveSearchResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Total != 1 || len(resp.Messages) != 1 || resp.Messages[0].Subject != "Order shipped" {
+		t.Fatalf("search response = %+v", resp)
+	}
+
+	id := resp.Messages[0].ID
+	w = get("/api/v1/archive/" + strconv.FormatInt(id, 10) + "/raw")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "message/rfc822" || w.Body.String() != data {
+		t.Errorf("raw = %d %q %q", w.Code, w.Header().Get("Content-Type"), w.Body.String())
+	}
+
+	for path, want := range map[string]int{
+		"/api/v1/archive/" + strconv.FormatInt(id, 10): http.StatusOK,
+		"/api/v1/archive/999":                          http.StatusNotFound,
+		"/api/v1/archive/abc":                          http.StatusBadRequest,
+		"/api/v1/archive?since=yesterday":              http.StatusBadRequest,
+		"/api/v1/archive?limit=0":                      http.StatusBadRequest,
+		"/api/v1/archive?offset=-1":                    http.StatusBadRequest,
+	} {
+		if w := get(path); w.Code != want {
+			t.Errorf("GET %s status = %d, want %d", path, w.Code, want)
+		}
+	}
+}
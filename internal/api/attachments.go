@@ -0,0 +1,152 @@
+package api
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"path/filepath"
+
+	"github.com/google/uuid"
+)
+
+// defaultMaxMessageBytes is used when no SMTP size limit is configured
+const defaultMaxMessageBytes = 10 * 1024 * 1024
+
+// Attachment is a file attached to a message sent via the API
+type Attachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type,omitempty"` // Detected from filename if empty
+	Content     string `json:"content"`                // Base64 encoded
+}
+
+// mailAttachment is a validated and decoded attachment
+type mailAttachment struct {
+	filename    string
+	contentType string
+	data        []byte
+}
+
+// decodeAttachments validates attachments and decodes their content
+func decodeAttachments(attachments []Attachment) ([]*mailAttachment, error) {
+	result := make([]*mailAttachment, 0, len(attachments))
+
+	for i, a := range attachments {
+		filename := sanitizeFilename(a.Filename)
+		if filename == "" {
+			return nil, fmt.Errorf("attachments[%d]: filename is required", i)
+		}
+
+		data, err := base64.StdEncoding.DecodeString(a.Content)
+		if err != nil {
+			return nil, fmt.Errorf("attachments[%d]: content must be base64 encoded", i)
+		}
+
+		contentType := sanitizeHeaderValue(a.ContentType)
+		if contentType == "" {
+			contentType = mime.TypeByExtension(filepath.Ext(filename))
+		}
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		mediaType, params, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			return nil, fmt.Errorf("attachments[%d]: invalid content_type: %s", i, a.ContentType)
+		}
+		params["name"] = filename
+
+		result = append(result, &mailAttachment{
+			filename:    filename,
+			contentType: mime.FormatMediaType(mediaType, params),
+			data:        data,
+		})
+	}
+
+	return result, nil
+}
+
+// attachmentsSize returns the decoded size of all attachments
+func attachmentsSize(attachments []*mailAttachment) int {
+	size := 0
+	for _, a := range attachments {
+		size += len(a.data)
+	}
+	return size
+}
+
+// writeMIMEBody writes the MIME headers and body of a message.
+// Text and HTML parts are wrapped in multipart/mixed when attachments are present.
+func writeMIMEBody(buf *bytes.Buffer, text, html string, attachments []*mailAttachment) {
+	if len(attachments) == 0 {
+		writeContent(buf, text, html, true)
+		return
+	}
+
+	boundary := uuid.New().String()
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString(fmt.Sprintf("Content-Type: multipart/mixed; boundary=\"%s\"\r\n", boundary))
+	buf.WriteString("\r\n")
+
+	buf.WriteString(fmt.Sprintf("--%s\r\n", boundary))
+	writeContent(buf, text, html, false)
+	buf.WriteString("\r\n")
+
+	for _, a := range attachments {
+		buf.WriteString(fmt.Sprintf("--%s\r\n", boundary))
+		buf.WriteString(fmt.Sprintf("Content-Type: %s\r\n", a.contentType))
+		buf.WriteString("Content-Transfer-Encoding: base64\r\n")
+		buf.WriteString(fmt.Sprintf("Content-Disposition: %s\r\n",
+			mime.FormatMediaType("attachment", map[string]string{"filename": a.filename})))
+		buf.WriteString("\r\n")
+		writeBase64Lines(buf, a.data)
+	}
+
+	buf.WriteString(fmt.Sprintf("--%s--\r\n", boundary))
+}
+
+// writeContent writes the text/html content, as multipart/alternative if both are set
+func writeContent(buf *bytes.Buffer, text, html string, mimeVersion bool) {
+	if html == "" {
+		buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+		buf.WriteString("\r\n")
+		buf.WriteString(text)
+		return
+	}
+
+	boundary := uuid.New().String()
+	if mimeVersion {
+		buf.WriteString("MIME-Version: 1.0\r\n")
+	}
+	buf.WriteString(fmt.Sprintf("Content-Type: multipart/alternative; boundary=\"%s\"\r\n", boundary))
+	buf.WriteString("\r\n")
+
+	// Plain text part
+	if text != "" {
+		buf.WriteString(fmt.Sprintf("--%s\r\n", boundary))
+		buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+		buf.WriteString("\r\n")
+		buf.WriteString(text)
+		buf.WriteString("\r\n")
+	}
+
+	// HTML part
+	buf.WriteString(fmt.Sprintf("--%s\r\n", boundary))
+	buf.WriteString("Content-Type: text/html; charset=utf-8\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(html)
+	buf.WriteString("\r\n")
+
+	buf.WriteString(fmt.Sprintf("--%s--\r\n", boundary))
+}
+
+// writeBase64Lines writes base64 encoded data wrapped at 76 characters (RFC 2045)
+func writeBase64Lines(buf *bytes.Buffer, data []byte) {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		buf.WriteString(encoded[:76])
+		buf.WriteString("\r\n")
+		encoded = encoded[76:]
+	}
+	buf.WriteString(encoded)
+	buf.WriteString("\r\n")
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"strings"
+	"testing"
+
+	"github.com/foxzi/sendry/internal/config"
+)
+
+func postSend(t *testing.T, server *Server, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest("POST", "/api/v1/send", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer test-api-key")
+	w := httptest.NewRecorder()
+
+	server.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestSendWithAttachments(t *testing.T) {
+	server, q := setupTestServer("test-api-key")
+
+	content := []byte("%PDF-1.4 test document")
+	body := fmt.Sprintf(`{
+		"from": "sender@example.com",
+		"to": ["recipient@example.com"],
+		"subject": "Invoice",
+		"body": "See attached",
+		"html": "<p>See attached</p>",
+		"attachments": [
+			{"filename": "invoice.pdf", "content": %q},
+			{"filename": "notes.txt", "content_type": "text/plain", "content": %q}
+		]
+	}`, base64.StdEncoding.EncodeToString(content), base64.StdEncoding.EncodeToString([]byte("notes")))
+
+	w := postSend(t, server, body)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("Status = %d, want %d. Body: %s", w.Code, http.StatusAccepted, w.Body.String())
+	}
+
+	if len(q.messages) != 1 {
+		t.Fatalf("Queue has %d messages, want 1", len(q.messages))
+	}
+
+	var data []byte
+	for _, msg := range q.messages {
+		data = msg.Data
+	}
+
+	parsed, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ReadMessage() error = %v", err)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatalf("ParseMediaType() error = %v", err)
+	}
+	if mediaType != "multipart/mixed" {
+		t.Fatalf("Content-Type = %q, want multipart/mixed", mediaType)
+	}
+
+	mr := multipart.NewReader(parsed.Body, params["boundary"])
+
+	part, err := mr.NextPart()
+	if err != nil {
+		t.Fatalf("NextPart() error = %v", err)
+	}
+	if !strings.HasPrefix(part.Header.Get("Content-Type"), "multipart/alternative") {
+		t.Errorf("first part Content-Type = %q, want multipart/alternative", part.Header.Get("Content-Type"))
+	}
+
+	part, err = mr.NextPart()
+	if err != nil {
+		t.Fatalf("NextPart() error = %v", err)
+	}
+	if part.FileName() != "invoice.pdf" {
+		t.Errorf("FileName() = %q, want invoice.pdf", part.FileName())
+	}
+	if !strings.HasPrefix(part.Header.Get("Content-Type"), "application/pdf") {
+		t.Errorf("Content-Type = %q, want application/pdf", part.Header.Get("Content-Type"))
+	}
+	encoded, _ := io.ReadAll(part)
+	decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(encoded), "\r\n", ""))
+	if err != nil {
+		t.Fatalf("attachment is not base64: %v", err)
+	}
+	if !bytes.Equal(decoded, content) {
+		t.Errorf("attachment content = %q, want %q", decoded, content)
+	}
+
+	part, err = mr.NextPart()
+	if err != nil {
+		t.Fatalf("NextPart() error = %v", err)
+	}
+	if part.FileName() != "notes.txt" {
+		t.Errorf("FileName() = %q, want notes.txt", part.FileName())
+	}
+
+	if _, err := mr.NextPart(); err != io.EOF {
+		t.Errorf("expected 3 parts, got more (err = %v)", err)
+	}
+}
+
+func TestSendWithInvalidAttachment(t *testing.T) {
+	server, q := setupTestServer("test-api-key")
+
+	tests := []struct {
+		name       string
+		attachment string
+	}{
+		{"missing filename", `{"content": "aGVsbG8="}`},
+		{"invalid base64", `{"filename": "a.txt", "content": "not base64!"}`},
+		{"invalid content type", `{"filename": "a.txt", "content_type": "text/", "content": "aGVsbG8="}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `{
+				"from": "sender@example.com",
+				"to": ["recipient@example.com"],
+				"subject": "Test",
+				"attachments": [` + tt.attachment + `]
+			}`
+
+			w := postSend(t, server, body)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Status = %d, want %d. Body: %s", w.Code, http.StatusBadRequest, w.Body.String())
+			}
+		})
+	}
+
+	if len(q.messages) != 0 {
+		t.Errorf("Queue has %d messages, want 0", len(q.messages))
+	}
+}
+
+func TestSendAttachmentTooLarge(t *testing.T) {
+	server, q := setupTestServer("test-api-key")
+	server.fullConfig = &config.Config{SMTP: config.SMTPConfig{MaxMessageBytes: 1024}}
+
+	// Fits before encoding, exceeds the limit once base64 encoded
+	content := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte("x"), 900))
+	body := fmt.Sprintf(`{
+		"from": "sender@example.com",
+		"to": ["recipient@example.com"],
+		"subject": "Test",
+		"attachments": [{"filename": "big.bin", "content": %q}]
+	}`, content)
+
+	w := postSend(t, server, body)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Status = %d, want %d. Body: %s", w.Code, http.StatusRequestEntityTooLarge, w.Body.String())
+	}
+	if len(q.messages) != 0 {
+		t.Errorf("Queue has %d messages, want 0", len(q.messages))
+	}
+}
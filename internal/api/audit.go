@@ -0,0 +1,227 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+
+	"github.com/foxzi/sendry/internal/audit"
+	"github.com/foxzi/sendry/internal/replication"
+)
+
+type ctxKey string
+
+const (
+	ctxKeyCorrelationID ctxKey = "correlation_id"
+	ctxKeyAuditChange   ctxKey = "audit_change"
+)
+
+// maxAuditErrorBytes limits how much of an error response is kept for the audit log
+const maxAuditErrorBytes = 4096
+
+// AuditServer handles audit log API requests
+type AuditServer struct {
+	storage *audit.Storage
+}
+
+// NewAuditServer creates a new audit server
+func NewAuditServer(storage *audit.Storage) *AuditServer {
+	return &AuditServer{storage: storage}
+}
+
+// RegisterRoutes registers audit routes
+func (s *AuditServer) RegisterRoutes(r chi.Router) {
+	r.Get("/audit", s.handleList)
+}
+
+// AuditListResponse represents audit log list response
+type AuditListResponse struct {
+	Entries []*audit.Entry `json:"entries"`
+	Total   int            `json:"total"`
+}
+
+func (s *AuditServer) handleList(w http.ResponseWriter, r *http.Request) {
+	filter := audit.Filter{
+		CorrelationID: r.URL.Query().Get("correlation_id"),
+		Actor:         r.URL.Query().Get("actor"),
+		PathPrefix:    r.URL.Query().Get("path"),
+	}
+	if l := r.URL.Query().Get("limit"); l != "" {
+		limit, err := strconv.Atoi(l)
+		if err != nil || limit < 1 || limit > 1000 {
+			sendError(w, http.StatusBadRequest, "limit must be between 1 and 1000")
+			return
+		}
+		filter.Limit = limit
+	}
+
+	entries, err := s.storage.List(r.Context(), filter)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	sendJSON(w, http.StatusOK, AuditListResponse{
+		Entries: entries,
+		Total:   len(entries),
+	})
+}
+
+// correlationMiddleware assigns a correlation ID to every request. A valid
+// X-Correlation-ID header from the client is kept, otherwise the request ID is used.
+func (s *Server) correlationMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := audit.SanitizeCorrelationID(r.Header.Get(audit.CorrelationHeader))
+		if id == "" {
+			id = middleware.GetReqID(r.Context())
+		}
+
+		w.Header().Set(audit.CorrelationHeader, id)
+		ctx := context.WithValue(r.Context(), ctxKeyCorrelationID, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// correlationID returns the correlation ID of the request context
+func correlationID(ctx context.Context) string {
+	id, _ := ctx.Value(ctxKeyCorrelationID).(string)
+	return id
+}
+
+// auditMiddleware records management API changes in the audit log
+func (s *Server) auditMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isAuditable(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		body := &cappedBuffer{max: maxAuditErrorBytes}
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		ww.Tee(body)
+
+		change := &auditChange{}
+		next.ServeHTTP(ww, r.WithContext(context.WithValue(r.Context(), ctxKeyAuditChange, change)))
+
+		entry := &audit.Entry{
+			Time:           start,
+			CorrelationID:  correlationID(r.Context()),
+			Method:         r.Method,
+			Path:           r.URL.Path,
+			Status:         ww.Status(),
+			RemoteAddr:     r.RemoteAddr,
+			ClientIdentity: s.clientIdentity(r),
+			Actor:          audit.SanitizeActor(r.Header.Get(audit.ActorHeader)),
+			Old:            change.before,
+			New:            change.after,
+			Duration:       time.Since(start),
+		}
+		if key := apiKeyFromContext(r.Context()); key != nil {
+			entry.APIKeyID = key.ID
+		}
+		if entry.Status == 0 {
+			entry.Status = http.StatusOK
+		}
+		if entry.Failed() {
+			entry.Error = responseError(body.Bytes())
+			s.logger.Warn("management request failed",
+				"correlation_id", entry.CorrelationID,
+				"method", entry.Method,
+				"path", entry.Path,
+				"status", entry.Status,
+				"error", entry.Error,
+			)
+		}
+
+		if err := s.auditStorage.Add(context.Background(), entry); err != nil {
+			s.logger.Error("failed to write audit entry",
+				"correlation_id", entry.CorrelationID,
+				"error", err,
+			)
+		}
+	})
+}
+
+// auditChange holds the values a handler changed, for the audit entry
+type auditChange struct {
+	before json.RawMessage
+	after  json.RawMessage
+}
+
+// recordChange attaches the value before and after a change to the audit
+// entry of the request. A nil value is left out, e.g. before for a create.
+func recordChange(r *http.Request, before, after any) {
+	recordContextChange(r.Context(), before, after)
+}
+
+// recordContextChange attaches a change to the audit entry of the request
+// of ctx, for requests that are not HTTP
+func recordContextChange(ctx context.Context, before, after any) {
+	change, ok := ctx.Value(ctxKeyAuditChange).(*auditChange)
+	if !ok {
+		return
+	}
+	change.before = marshalChange(before)
+	change.after = marshalChange(after)
+}
+
+func marshalChange(v any) json.RawMessage {
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil || string(data) == "null" {
+		return nil
+	}
+	return data
+}
+
+// isAuditable reports whether a request changes server state. Message
+// submission and template previews are not recorded.
+func isAuditable(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	path := r.URL.Path
+	if strings.HasPrefix(path, "/api/v1/send") || strings.HasSuffix(path, "/preview") || path == replication.StreamPath {
+		return false
+	}
+	return true
+}
+
+// responseError extracts the error message from an error response body
+func responseError(body []byte) string {
+	var resp struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &resp); err == nil && resp.Error != "" {
+		return resp.Error
+	}
+	return strings.TrimSpace(string(body))
+}
+
+// cappedBuffer keeps at most max bytes and silently drops the rest
+type cappedBuffer struct {
+	bytes.Buffer
+	max int
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.Len(); room > 0 {
+		if len(p) > room {
+			b.Buffer.Write(p[:room])
+		} else {
+			b.Buffer.Write(p)
+		}
+	}
+	return len(p), nil
+}
@@ -0,0 +1,200 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/foxzi/sendry/internal/audit"
+	"github.com/foxzi/sendry/internal/config"
+)
+
+func setupAuditServer(t *testing.T) *Server {
+	t.Helper()
+
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	storage, err := audit.NewStorage(db)
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+
+	tmpDir := t.TempDir()
+	return NewServerWithOptions(ServerOptions{
+		Queue:        newMockQueue(),
+		Config:       &config.APIConfig{ListenAddr: ":8080"},
+		FullConfig:   &config.Config{SMTP: config.SMTPConfig{Domain: "example.com"}},
+		Logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+		AuditStorage: storage,
+		DKIMKeysDir:  tmpDir,
+		TLSCertsDir:  tmpDir,
+	})
+}
+
+func listAudit(t *testing.T, server *Server, query string) AuditListResponse {
+	t.Helper()
+
+	req := httptest.NewRequest("GET", "/api/v1/audit"+query, nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("audit list status = %d: %s", w.Code, w.Body.String())
+	}
+	var resp AuditListResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return resp
+}
+
+func TestCorrelationIDPropagation(t *testing.T) {
+	server := setupAuditServer(t)
+
+	req := httptest.NewRequest("POST", "/api/v1/domains/", bytes.NewBufferString(`{"domain": "new.com"}`))
+	req.Header.Set(audit.CorrelationHeader, "deploy-42")
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	if got := w.Header().Get(audit.CorrelationHeader); got != "deploy-42" {
+		t.Errorf("response correlation ID = %q, want deploy-42", got)
+	}
+
+	// A failing change is linked to the same correlation ID
+	req = httptest.NewRequest("POST", "/api/v1/domains/", bytes.NewBufferString(`{"domain": "new.com"}`))
+	req.Header.Set(audit.CorrelationHeader, "deploy-42")
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusConflict, w.Code, w.Body.String())
+	}
+
+	resp := listAudit(t, server, "?correlation_id=deploy-42")
+	if resp.Total != 2 {
+		t.Fatalf("audit entries = %d, want 2", resp.Total)
+	}
+	failed := resp.Entries[0]
+	if failed.Status != http.StatusConflict || failed.Error == "" {
+		t.Errorf("failed entry = %+v, want conflict with error message", failed)
+	}
+	ok := resp.Entries[1]
+	if ok.Method != "POST" || ok.Path != "/api/v1/domains/" || ok.Status != http.StatusCreated {
+		t.Errorf("created entry = %+v", ok)
+	}
+}
+
+func TestCorrelationIDGenerated(t *testing.T) {
+	server := setupAuditServer(t)
+
+	req := httptest.NewRequest("PUT", "/api/v1/domains/gen.com", bytes.NewBufferString(`{"mode": "production"}`))
+	req.Header.Set(audit.CorrelationHeader, "not valid\n")
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	id := w.Header().Get(audit.CorrelationHeader)
+	if id == "" || id == "not valid\n" {
+		t.Fatalf("expected generated correlation ID, got %q", id)
+	}
+
+	resp := listAudit(t, server, "?correlation_id="+id)
+	if resp.Total != 1 {
+		t.Errorf("audit entries for generated ID = %d, want 1", resp.Total)
+	}
+}
+
+func TestAuditSkipsReadsAndSends(t *testing.T) {
+	server := setupAuditServer(t)
+
+	for _, tc := range []struct{ method, path, body string }{
+		{"GET", "/api/v1/domains", ""},
+		{"POST", "/api/v1/send", `{"from": "a@example.com", "to": ["b@example.com"], "subject": "hi", "body": "x"}`},
+	} {
+		req := httptest.NewRequest(tc.method, tc.path, bytes.NewBufferString(tc.body))
+		server.router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	if resp := listAudit(t, server, ""); resp.Total != 0 {
+		t.Errorf("audit entries = %d, want 0: %+v", resp.Total, resp.Entries)
+	}
+}
+
+func TestAuditListInvalidLimit(t *testing.T) {
+	server := setupAuditServer(t)
+
+	req := httptest.NewRequest("GET", "/api/v1/audit?limit=0", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestAuditRecordsChanges(t *testing.T) {
+	server := setupAuditServer(t)
+
+	do := func(method, path, body string) {
+		t.Helper()
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set(audit.ActorHeader, "admin@example.com")
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		if w.Code >= 400 {
+			t.Fatalf("%s %s status = %d: %s", method, path, w.Code, w.Body.String())
+		}
+	}
+
+	do("POST", "/api/v1/domains/", `{"domain": "change.com", "mode": "sandbox"}`)
+	do("PUT", "/api/v1/domains/change.com", `{"mode": "production"}`)
+	do("PUT", "/api/v1/ratelimits/change.com", `{"messages_per_hour": 100}`)
+	do("DELETE", "/api/v1/domains/change.com", "")
+
+	resp := listAudit(t, server, "?actor=admin@example.com&path=/api/v1/domains/")
+	if resp.Total != 3 {
+		t.Fatalf("audit entries = %d, want 3: %+v", resp.Total, resp.Entries)
+	}
+	deleted, updated, created := resp.Entries[0], resp.Entries[1], resp.Entries[2]
+
+	mode := func(raw json.RawMessage) string {
+		var d DomainResponse
+		if len(raw) == 0 {
+			return ""
+		}
+		if err := json.Unmarshal(raw, &d); err != nil {
+			t.Fatalf("failed to decode change %s: %v", raw, err)
+		}
+		return d.Mode
+	}
+	if created.Old != nil || mode(created.New) != "sandbox" {
+		t.Errorf("create change = %s -> %s", created.Old, created.New)
+	}
+	if mode(updated.Old) != "sandbox" || mode(updated.New) != "production" {
+		t.Errorf("update change = %s -> %s", updated.Old, updated.New)
+	}
+	if mode(deleted.Old) != "production" || deleted.New != nil {
+		t.Errorf("delete change = %s -> %s", deleted.Old, deleted.New)
+	}
+	if created.Actor != "admin@example.com" {
+		t.Errorf("actor = %q", created.Actor)
+	}
+
+	limits := listAudit(t, server, "?path=/api/v1/ratelimits/")
+	if limits.Total != 1 || limits.Entries[0].Old != nil || !strings.Contains(string(limits.Entries[0].New), `"messages_per_hour":100`) {
+		t.Errorf("rate limit entries = %+v", limits.Entries)
+	}
+}
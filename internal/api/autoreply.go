@@ -0,0 +1,268 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/foxzi/sendry/internal/autoreply"
+	"github.com/foxzi/sendry/internal/dnscheck"
+)
+
+// AutoReplyServer handles auto-reply (vacation responder) API endpoints
+type AutoReplyServer struct {
+	storage *autoreply.Storage
+}
+
+// NewAutoReplyServer creates a new auto-reply server
+func NewAutoReplyServer(storage *autoreply.Storage) *AutoReplyServer {
+	return &AutoReplyServer{storage: storage}
+}
+
+// RegisterRoutes registers auto-reply API routes
+func (s *AutoReplyServer) RegisterRoutes(r chi.Router) {
+	r.Route("/autoreplies", func(r chi.Router) {
+		r.Get("/", s.handleList)
+		r.Post("/", s.handleCreate)
+		r.Get("/{id}", s.handleGet)
+		r.Put("/{id}", s.handleUpdate)
+		r.Delete("/{id}", s.handleDelete)
+	})
+}
+
+// AutoReplyRequest is the request for creating or updating an auto-reply responder
+type AutoReplyRequest struct {
+	Address      string     `json:"address,omitempty"`
+	From         string     `json:"from,omitempty"`
+	Subject      string     `json:"subject,omitempty"`
+	Text         string     `json:"text,omitempty"`
+	HTML         string     `json:"html,omitempty"`
+	Enabled      *bool      `json:"enabled,omitempty"`
+	StartAt      *time.Time `json:"start_at,omitempty"`
+	EndAt        *time.Time `json:"end_at,omitempty"`
+	IntervalDays *int       `json:"interval_days,omitempty"`
+}
+
+// AutoReplyListResponse is the response for listing auto-reply responders
+type AutoReplyListResponse struct {
+	AutoReplies []*autoreply.Responder `json:"autoreplies"`
+	Total       int                    `json:"total"`
+}
+
+// handleList handles GET /api/v1/autoreplies
+func (s *AutoReplyServer) handleList(w http.ResponseWriter, r *http.Request) {
+	filter := autoreply.ListFilter{
+		Domain: r.URL.Query().Get("domain"),
+	}
+
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if limit, err := strconv.Atoi(limitStr); err == nil && limit > 0 {
+			filter.Limit = limit
+			if filter.Limit > 1000 {
+				filter.Limit = 1000 // Prevent DoS via excessive limit
+			}
+		}
+	}
+
+	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
+		if offset, err := strconv.Atoi(offsetStr); err == nil && offset > 0 {
+			filter.Offset = offset
+		}
+	}
+
+	responders, err := s.storage.List(r.Context(), filter)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to list auto-replies")
+		return
+	}
+
+	if responders == nil {
+		responders = []*autoreply.Responder{}
+	}
+
+	sendJSON(w, http.StatusOK, AutoReplyListResponse{
+		AutoReplies: responders,
+		Total:       len(responders),
+	})
+}
+
+// handleCreate handles POST /api/v1/autoreplies
+func (s *AutoReplyServer) handleCreate(w http.ResponseWriter, r *http.Request) {
+	var req AutoReplyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Address == "" {
+		sendError(w, http.StatusBadRequest, "address is required")
+		return
+	}
+
+	responder := &autoreply.Responder{Enabled: true}
+	applyAutoReplyRequest(responder, &req)
+
+	if msg := validateAutoReply(responder); msg != "" {
+		sendError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	if err := s.storage.Create(r.Context(), responder); err != nil {
+		if strings.Contains(err.Error(), "already exists") {
+			sendError(w, http.StatusConflict, err.Error())
+			return
+		}
+		sendError(w, http.StatusInternalServerError, "Failed to create auto-reply")
+		return
+	}
+
+	sendJSON(w, http.StatusCreated, responder)
+}
+
+// handleGet handles GET /api/v1/autoreplies/{id}
+func (s *AutoReplyServer) handleGet(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	responder, err := s.storage.Get(r.Context(), id)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to get auto-reply")
+		return
+	}
+
+	if responder == nil {
+		// Try by address
+		responder, err = s.storage.GetByAddress(r.Context(), id)
+		if err != nil {
+			sendError(w, http.StatusInternalServerError, "Failed to get auto-reply")
+			return
+		}
+	}
+
+	if responder == nil {
+		sendError(w, http.StatusNotFound, "Auto-reply not found")
+		return
+	}
+
+	sendJSON(w, http.StatusOK, responder)
+}
+
+// handleUpdate handles PUT /api/v1/autoreplies/{id}
+func (s *AutoReplyServer) handleUpdate(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req AutoReplyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	responder, err := s.storage.Get(r.Context(), id)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to get auto-reply")
+		return
+	}
+
+	if responder == nil {
+		sendError(w, http.StatusNotFound, "Auto-reply not found")
+		return
+	}
+
+	applyAutoReplyRequest(responder, &req)
+
+	if msg := validateAutoReply(responder); msg != "" {
+		sendError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	if err := s.storage.Update(r.Context(), responder); err != nil {
+		if strings.Contains(err.Error(), "already exists") {
+			sendError(w, http.StatusConflict, err.Error())
+			return
+		}
+		sendError(w, http.StatusInternalServerError, "Failed to update auto-reply")
+		return
+	}
+
+	sendJSON(w, http.StatusOK, responder)
+}
+
+// handleDelete handles DELETE /api/v1/autoreplies/{id}
+func (s *AutoReplyServer) handleDelete(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	if err := s.storage.Delete(r.Context(), id); err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to delete auto-reply")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// applyAutoReplyRequest copies the fields set in the request to the responder
+func applyAutoReplyRequest(responder *autoreply.Responder, req *AutoReplyRequest) {
+	if req.Address != "" {
+		responder.Address = req.Address
+	}
+	if req.From != "" {
+		responder.From = sanitizeHeaderValue(req.From)
+	}
+	if req.Subject != "" {
+		responder.Subject = sanitizeHeaderValue(req.Subject)
+	}
+	if req.Text != "" {
+		responder.Text = req.Text
+	}
+	if req.HTML != "" {
+		responder.HTML = req.HTML
+	}
+	if req.Enabled != nil {
+		responder.Enabled = *req.Enabled
+	}
+	if req.StartAt != nil {
+		responder.StartAt = req.StartAt
+	}
+	if req.EndAt != nil {
+		responder.EndAt = req.EndAt
+	}
+	if req.IntervalDays != nil {
+		responder.IntervalDays = *req.IntervalDays
+	}
+}
+
+// validateAutoReply returns an error message if the responder is invalid
+func validateAutoReply(responder *autoreply.Responder) string {
+	address := autoreply.NormalizeAddress(responder.Address)
+	if strings.Contains(address, "@") {
+		if _, err := mail.ParseAddress(address); err != nil {
+			return fmt.Sprintf("invalid address: %s", responder.Address)
+		}
+	} else if dnscheck.ValidateDomain(address) != nil {
+		return fmt.Sprintf("invalid domain: %s", responder.Address)
+	}
+
+	if responder.From != "" {
+		if _, err := mail.ParseAddress(responder.From); err != nil {
+			return fmt.Sprintf("invalid from address: %s", responder.From)
+		}
+	}
+
+	if responder.Text == "" && responder.HTML == "" {
+		return "text or html is required"
+	}
+
+	if responder.IntervalDays < 0 {
+		return "interval_days must not be negative"
+	}
+
+	if responder.StartAt != nil && responder.EndAt != nil && !responder.EndAt.After(*responder.StartAt) {
+		return "end_at must be after start_at"
+	}
+
+	return ""
+}
@@ -0,0 +1,31 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// handleBackup handles GET /api/v1/backup, a consistent copy of the BoltDB
+// database taken while the server keeps running
+func (s *Server) handleBackup(w http.ResponseWriter, r *http.Request) {
+	if s.backupStorage == nil {
+		s.sendError(w, http.StatusNotFound, "Backup is not available")
+		return
+	}
+
+	// A large database takes longer than the API write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	name := fmt.Sprintf("sendry-%s.db", time.Now().UTC().Format("20060102-150405"))
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+
+	n, err := s.backupStorage.Backup(w)
+	if err != nil {
+		// The status is sent already, the client sees a truncated body
+		s.logger.Error("backup failed", "written", n, "error", err)
+		return
+	}
+	s.logger.Info("backup downloaded", "bytes", n, "remote_addr", r.RemoteAddr)
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/foxzi/sendry/internal/config"
+	"github.com/foxzi/sendry/internal/queue"
+)
+
+func TestBackup(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	storage, err := queue.NewBoltStorage(filepath.Join(dir, "queue.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storage.Close()
+	msg := &queue.Message{ID: "m1", Status: queue.StatusPending, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	if err := storage.Enqueue(ctx, msg); err != nil {
+		t.Fatal(err)
+	}
+
+	server := NewServerWithOptions(ServerOptions{
+		Queue:         storage,
+		Config:        &config.APIConfig{ListenAddr: ":8080"},
+		Logger:        slog.New(slog.NewTextHandler(io.Discard, nil)),
+		BackupStorage: storage,
+	})
+
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/backup", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("backup status = %d: %s", w.Code, w.Body.String())
+	}
+
+	// The copy is a database with the queued message, opened while the
+	// original is still open
+	copyPath := filepath.Join(dir, "copy.db")
+	if err := os.WriteFile(copyPath, w.Body.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := queue.NewBoltStorage(copyPath)
+	if err != nil {
+		t.Fatalf("failed to open backup: %v", err)
+	}
+	defer restored.Close()
+	if got, _ := restored.Get(ctx, "m1"); got == nil {
+		t.Error("backup does not contain the queued message")
+	}
+}
+
+func TestBackup_Unavailable(t *testing.T) {
+	server := NewServerWithOptions(ServerOptions{
+		Queue:  newMockQueue(),
+		Config: &config.APIConfig{ListenAddr: ":8080"},
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/backup", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("backup without storage status = %d, want 404", w.Code)
+	}
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/foxzi/sendry/internal/ipguard"
+)
+
+// BanServer handles requests to the SMTP client ban list
+type BanServer struct {
+	guard *ipguard.Guard
+}
+
+// NewBanServer creates a new ban server
+func NewBanServer(guard *ipguard.Guard) *BanServer {
+	return &BanServer{guard: guard}
+}
+
+// RegisterRoutes registers ban routes
+func (s *BanServer) RegisterRoutes(r chi.Router) {
+	r.Get("/bans", s.handleList)
+	r.Delete("/bans/{ip}", s.handleDelete)
+}
+
+// BanListResponse represents the list of banned SMTP clients
+type BanListResponse struct {
+	Bans  []ipguard.Ban `json:"bans"`
+	Total int           `json:"total"`
+}
+
+func (s *BanServer) handleList(w http.ResponseWriter, r *http.Request) {
+	bans := s.guard.Bans()
+	if bans == nil {
+		bans = []ipguard.Ban{}
+	}
+	sendJSON(w, http.StatusOK, BanListResponse{Bans: bans, Total: len(bans)})
+}
+
+func (s *BanServer) handleDelete(w http.ResponseWriter, r *http.Request) {
+	ip := net.ParseIP(chi.URLParam(r, "ip"))
+	if ip == nil {
+		sendError(w, http.StatusBadRequest, "Invalid IP address")
+		return
+	}
+	ban := s.guard.Unban(ip.String())
+	if ban == nil {
+		sendError(w, http.StatusNotFound, "IP address is not banned")
+		return
+	}
+	recordChange(r, ban, nil)
+	sendJSON(w, http.StatusOK, ActionResponse{Status: "unbanned", Message: "Ban lifted"})
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/foxzi/sendry/internal/config"
+	"github.com/foxzi/sendry/internal/ipguard"
+)
+
+func TestBansAPI(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	guard := ipguard.New(config.IPGuardConfig{BanAfter: 1}, logger)
+	guard.RelayAttempt("192.0.2.1")
+
+	server := NewServerWithOptions(ServerOptions{
+		Queue:   newMockQueue(),
+		Config:  &config.APIConfig{ListenAddr: ":8080"},
+		Logger:  logger,
+		IPGuard: guard,
+	})
+	do := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	w := do("GET", "/api/v1/bans")
+	if w.Code != http.StatusOK {
+		t.Fatalf("list status = %d", w.Code)
+	}
+	var list BanListResponse
+	if err := json.NewDecoder(w.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	if list.Total != 1 || list.Bans[0].IP != "192.0.2.1" || list.Bans[0].Reason != ipguard.ReasonRelay {
+		t.Errorf("list = %+v, want the ban of 192.0.2.1", list)
+	}
+
+	if w := do("DELETE", "/api/v1/bans/not-an-ip"); w.Code != http.StatusBadRequest {
+		t.Errorf("delete invalid IP status = %d, want 400", w.Code)
+	}
+	if w := do("DELETE", "/api/v1/bans/192.0.2.2"); w.Code != http.StatusNotFound {
+		t.Errorf("delete unbanned IP status = %d, want 404", w.Code)
+	}
+	if w := do("DELETE", "/api/v1/bans/192.0.2.1"); w.Code != http.StatusOK {
+		t.Errorf("delete status = %d, want 200", w.Code)
+	}
+	if guard.Banned("192.0.2.1") {
+		t.Error("client still banned after delete")
+	}
+}
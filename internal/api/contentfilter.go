@@ -0,0 +1,45 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/foxzi/sendry/internal/config"
+	"github.com/foxzi/sendry/internal/contentfilter"
+	"github.com/foxzi/sendry/internal/queue"
+)
+
+// filterMessage passes a message built from an API request to the content
+// filter. A modified message gets the new data. A refused or discarded
+// message returns the HTTP status and error of the response, 0 otherwise.
+func filterMessage(ctx context.Context, filter *contentfilter.Checker, msg *queue.Message) (int, string) {
+	if !filter.Applies(config.ContentFilterSourceAPI) {
+		return 0, ""
+	}
+
+	v := filter.Check(ctx, &contentfilter.Envelope{
+		ID:       msg.ID,
+		Source:   config.ContentFilterSourceAPI,
+		From:     msg.From,
+		To:       msg.To,
+		ClientIP: msg.ClientIP,
+	}, msg.Data)
+
+	switch v.Action {
+	case contentfilter.ActionModify:
+		msg.Data = v.Data
+	case contentfilter.ActionReject, contentfilter.ActionDiscard:
+		return http.StatusUnprocessableEntity, filterReason("message rejected by content filter", v)
+	case contentfilter.ActionTempfail:
+		return http.StatusServiceUnavailable, filterReason("message deferred by content filter", v)
+	}
+	return 0, ""
+}
+
+// filterReason appends the reply text of the filter to an error message
+func filterReason(text string, v *contentfilter.Verdict) string {
+	if v.Message == "" {
+		return text
+	}
+	return text + ": " + v.Message
+}
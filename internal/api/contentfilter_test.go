@@ -0,0 +1,93 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/foxzi/sendry/internal/config"
+	"github.com/foxzi/sendry/internal/contentfilter"
+)
+
+// verdictFilter returns the same verdict for all messages
+type verdictFilter struct {
+	verdict contentfilter.Verdict
+	checked []*contentfilter.Envelope
+}
+
+func (f *verdictFilter) Check(ctx context.Context, env *contentfilter.Envelope, data []byte) (*contentfilter.Verdict, error) {
+	f.checked = append(f.checked, env)
+	v := f.verdict
+	return &v, nil
+}
+
+func TestSendContentFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		verdict contentfilter.Verdict
+		status  int
+		errText string
+	}{
+		{"accept", contentfilter.Verdict{Action: contentfilter.ActionAccept}, http.StatusAccepted, ""},
+		{"modify", contentfilter.Verdict{Action: contentfilter.ActionModify, Data: []byte("X-Spam: Yes\r\n\r\nHello")}, http.StatusAccepted, ""},
+		{"reject", contentfilter.Verdict{Action: contentfilter.ActionReject, Message: "Spam"}, http.StatusUnprocessableEntity, "message rejected by content filter: Spam"},
+		{"tempfail", contentfilter.Verdict{Action: contentfilter.ActionTempfail}, http.StatusServiceUnavailable, "message deferred by content filter"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, q := setupTestServer("test-api-key")
+			filter := &verdictFilter{verdict: tt.verdict}
+			server.filter = contentfilter.NewChecker(filter, time.Second, false,
+				map[string]bool{config.ContentFilterSourceAPI: true}, nil)
+
+			req := httptest.NewRequest("POST", "/api/v1/send", bytes.NewBufferString(dkimSendBody))
+			req.Header.Set("Authorization", "Bearer test-api-key")
+			w := httptest.NewRecorder()
+			server.router.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body: %s", w.Code, tt.status, w.Body.String())
+			}
+			if len(filter.checked) != 1 || filter.checked[0].Source != config.ContentFilterSourceAPI {
+				t.Fatalf("checked = %+v, want one API message", filter.checked)
+			}
+			if tt.errText != "" {
+				if !strings.Contains(w.Body.String(), tt.errText) {
+					t.Errorf("body = %s, want %q", w.Body.String(), tt.errText)
+				}
+				if len(q.messages) != 0 {
+					t.Errorf("refused message was queued")
+				}
+				return
+			}
+			msg := q.messages[filter.checked[0].ID]
+			if msg == nil {
+				t.Fatal("message not queued under the checked ID")
+			}
+			if tt.verdict.Data != nil && string(msg.Data) != string(tt.verdict.Data) {
+				t.Errorf("queued data = %q, want the modified message", msg.Data)
+			}
+		})
+	}
+}
+
+func TestSendContentFilterSources(t *testing.T) {
+	server, q := setupTestServer("test-api-key")
+	filter := &verdictFilter{verdict: contentfilter.Verdict{Action: contentfilter.ActionReject}}
+	server.filter = contentfilter.NewChecker(filter, time.Second, false,
+		map[string]bool{config.ContentFilterSourceSMTP: true}, nil)
+
+	req := httptest.NewRequest("POST", "/api/v1/send", bytes.NewBufferString(dkimSendBody))
+	req.Header.Set("Authorization", "Bearer test-api-key")
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusAccepted || len(q.messages) != 1 || len(filter.checked) != 0 {
+		t.Errorf("status = %d, checked %d, want API messages not filtered", w.Code, len(filter.checked))
+	}
+}
@@ -0,0 +1,21 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/foxzi/sendry/internal/contentpolicy"
+	"github.com/foxzi/sendry/internal/queue"
+)
+
+// checkPolicy checks a message built from an API request against the
+// content policy. Stripped parts are removed from the message data. A
+// refused message returns the HTTP status and error of the response, 0
+// otherwise.
+func checkPolicy(policy *contentpolicy.Enforcer, msg *queue.Message) (int, string) {
+	data, err := policy.Check(contentpolicy.SourceAPI, msg.From, msg.To, msg.Data)
+	if err != nil {
+		return http.StatusBadRequest, "message refused by content policy: " + err.Error()
+	}
+	msg.Data = data
+	return 0, ""
+}
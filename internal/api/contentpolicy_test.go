@@ -0,0 +1,74 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/foxzi/sendry/internal/config"
+	"github.com/foxzi/sendry/internal/contentpolicy"
+)
+
+func TestSendContentPolicy(t *testing.T) {
+	const withAttachment = `{"from": "sender@example.com", "to": ["rcpt@example.org"], "subject": "Test", "body": "Hello",
+		"attachments": [{"filename": "setup.exe", "content": "TVqQAAMAAAAEAAAA"}]}`
+	tests := []struct {
+		name    string
+		policy  config.ContentPolicyConfig
+		body    string
+		status  int
+		errText string
+	}{
+		{"no limits", config.ContentPolicyConfig{}, withAttachment, http.StatusAccepted, ""},
+		{"banned extension", config.ContentPolicyConfig{BannedExtensions: []string{".exe"}}, withAttachment, http.StatusBadRequest, "extension .exe is not allowed"},
+		{"attachment size", config.ContentPolicyConfig{MaxAttachmentBytes: 10}, withAttachment, http.StatusBadRequest, "too large"},
+		{"recipients", config.ContentPolicyConfig{MaxRecipients: 1}, `{"from": "sender@example.com", "to": ["a@example.org"], "cc": ["b@example.org"], "subject": "Test", "body": "Hello"}`, http.StatusBadRequest, "too many recipients (max 1)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, q := setupTestServer("test-api-key")
+			server.policy = contentpolicy.New(tt.policy, nil, nil)
+
+			req := httptest.NewRequest("POST", "/api/v1/send", bytes.NewBufferString(tt.body))
+			req.Header.Set("Authorization", "Bearer test-api-key")
+			w := httptest.NewRecorder()
+			server.router.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body: %s", w.Code, tt.status, w.Body.String())
+			}
+			if tt.errText != "" {
+				if !strings.Contains(w.Body.String(), tt.errText) {
+					t.Errorf("body = %s, want %q", w.Body.String(), tt.errText)
+				}
+				if len(q.messages) != 0 {
+					t.Errorf("refused message was queued")
+				}
+			}
+		})
+	}
+}
+
+func TestSendContentPolicyStrip(t *testing.T) {
+	server, q := setupTestServer("test-api-key")
+	server.policy = contentpolicy.New(config.ContentPolicyConfig{BannedExtensions: []string{".exe"}, StripBanned: true}, nil, nil)
+
+	body := `{"from": "sender@example.com", "to": ["rcpt@example.org"], "subject": "Test", "body": "Hello",
+		"attachments": [{"filename": "setup.exe", "content": "TVqQAAMAAAAEAAAA"}]}`
+	req := httptest.NewRequest("POST", "/api/v1/send", bytes.NewBufferString(body))
+	req.Header.Set("Authorization", "Bearer test-api-key")
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusAccepted || len(q.messages) != 1 {
+		t.Fatalf("status = %d, want the message queued, body: %s", w.Code, w.Body.String())
+	}
+	for _, msg := range q.messages {
+		if strings.Contains(string(msg.Data), "TVqQAAMAAAAEAAAA") || !strings.Contains(string(msg.Data), `The attachment "setup.exe" was removed`) {
+			t.Errorf("queued data still has the banned attachment:\n%s", msg.Data)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"strings"
+
+	"github.com/foxzi/sendry/internal/dkim"
+	"github.com/foxzi/sendry/internal/queue"
+)
+
+// DKIMProvider provides DKIM signers for sender addresses
+type DKIMProvider interface {
+	GetSignerForEmail(email string) *dkim.Signer
+}
+
+// DKIM statuses of a queued message
+const (
+	DKIMStatusSigned  = "signed"  // Signed at enqueue time
+	DKIMStatusPending = "pending" // Signed when delivered
+	DKIMStatusSkipped = "skipped" // Delivered unsigned on request
+	DKIMStatusNone    = "none"    // No DKIM key for the sender domain
+)
+
+// MessageDKIM describes the DKIM signing of a queued message
+type MessageDKIM struct {
+	Status    string `json:"status"`
+	Domain    string `json:"domain,omitempty"`
+	Selector  string `json:"selector,omitempty"`
+	Signature string `json:"signature,omitempty"` // DKIM-Signature header value
+	Preview   bool   `json:"preview,omitempty"`   // Signature computed now, delivery signs again with a new timestamp
+}
+
+// signAtEnqueue signs the message data with the DKIM key of the sender
+// domain and marks the message so delivery does not sign it again. Messages
+// flagged to skip signing or without a key are left unsigned.
+func signAtEnqueue(provider DKIMProvider, msg *queue.Message) error {
+	if provider == nil || msg.SkipDKIM {
+		return nil
+	}
+	signer := provider.GetSignerForEmail(msg.From)
+	if signer == nil {
+		return nil
+	}
+	signed, err := signer.Sign(msg.Data)
+	if err != nil {
+		return err
+	}
+	msg.Data = signed
+	msg.DKIMSigned = true
+	return nil
+}
+
+// messageDKIM describes how a queued message is or will be signed. For a
+// message signed at delivery the signature is a preview computed now.
+func messageDKIM(provider DKIMProvider, msg *queue.Message) *MessageDKIM {
+	switch {
+	case msg.SkipDKIM:
+		return &MessageDKIM{Status: DKIMStatusSkipped}
+	case msg.DKIMSigned:
+		sig := dkim.SignatureHeader(msg.Data)
+		return &MessageDKIM{
+			Status:    DKIMStatusSigned,
+			Domain:    signatureTag(sig, "d"),
+			Selector:  signatureTag(sig, "s"),
+			Signature: sig,
+		}
+	}
+
+	var signer *dkim.Signer
+	if provider != nil {
+		signer = provider.GetSignerForEmail(msg.From)
+	}
+	if signer == nil {
+		return &MessageDKIM{Status: DKIMStatusNone}
+	}
+
+	info := &MessageDKIM{
+		Status:   DKIMStatusPending,
+		Domain:   signer.Domain(),
+		Selector: signer.Selector(),
+	}
+	if signed, err := signer.Sign(msg.Data); err == nil {
+		info.Signature = dkim.SignatureHeader(signed)
+		info.Preview = true
+	}
+	return info
+}
+
+// signatureTag returns the value of a tag of a DKIM-Signature header
+func signatureTag(sig, name string) string {
+	for _, tag := range strings.Split(sig, ";") {
+		key, value, ok := strings.Cut(tag, "=")
+		if ok && strings.TrimSpace(key) == name {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/foxzi/sendry/internal/dkim"
+	"github.com/foxzi/sendry/internal/queue"
+)
+
+// staticDKIM returns one signer for all senders
+type staticDKIM struct {
+	signer *dkim.Signer
+}
+
+func (p staticDKIM) GetSignerForEmail(string) *dkim.Signer {
+	return p.signer
+}
+
+func setupDKIMServer(t *testing.T, signOnEnqueue bool) (*Server, *mockQueue) {
+	t.Helper()
+	kp, err := dkim.GenerateKey("example.com", "sendry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, q := setupTestServer("test-api-key")
+	server.config.DKIMSignOnEnqueue = signOnEnqueue
+	server.dkim = staticDKIM{signer: dkim.NewSigner(kp.PrivateKey, "example.com", "sendry")}
+	return server, q
+}
+
+func sendAndGet(t *testing.T, server *Server, q *mockQueue, body string) (*queue.Message, MessageResponse) {
+	t.Helper()
+	req := httptest.NewRequest("POST", "/api/v1/send", bytes.NewBufferString(body))
+	req.Header.Set("Authorization", "Bearer test-api-key")
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("send status = %d, body: %s", w.Code, w.Body.String())
+	}
+	var sent SendResponse
+	if err := json.NewDecoder(w.Body).Decode(&sent); err != nil {
+		t.Fatal(err)
+	}
+
+	req = httptest.NewRequest("GET", "/api/v1/queue/"+sent.ID, nil)
+	req.Header.Set("Authorization", "Bearer test-api-key")
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("get status = %d, body: %s", w.Code, w.Body.String())
+	}
+	var resp MessageResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	return q.messages[sent.ID], resp
+}
+
+const dkimSendBody = `{"from": "sender@example.com", "to": ["rcpt@example.org"], "subject": "Test", "body": "Hello"}`
+
+func TestSendSignsAtEnqueue(t *testing.T) {
+	server, q := setupDKIMServer(t, true)
+
+	msg, resp := sendAndGet(t, server, q, dkimSendBody)
+	if !msg.DKIMSigned || !bytes.HasPrefix(msg.Data, []byte("DKIM-Signature:")) {
+		t.Fatalf("queued message not signed: signed = %v", msg.DKIMSigned)
+	}
+	if resp.DKIM == nil || resp.DKIM.Status != DKIMStatusSigned || resp.DKIM.Preview {
+		t.Fatalf("dkim = %+v, want signed", resp.DKIM)
+	}
+	if resp.DKIM.Domain != "example.com" || resp.DKIM.Selector != "sendry" {
+		t.Errorf("dkim domain and selector = %q, %q", resp.DKIM.Domain, resp.DKIM.Selector)
+	}
+	if resp.DKIM.Signature != dkim.SignatureHeader(msg.Data) {
+		t.Errorf("signature = %q, want the queued one", resp.DKIM.Signature)
+	}
+	if resp.Size != len(msg.Data) {
+		t.Errorf("size = %d, want %d", resp.Size, len(msg.Data))
+	}
+}
+
+func TestSendSignsAtDelivery(t *testing.T) {
+	server, q := setupDKIMServer(t, false)
+
+	msg, resp := sendAndGet(t, server, q, dkimSendBody)
+	if msg.DKIMSigned || bytes.Contains(msg.Data, []byte("DKIM-Signature:")) {
+		t.Fatal("message signed at enqueue time")
+	}
+	if resp.DKIM == nil || resp.DKIM.Status != DKIMStatusPending || !resp.DKIM.Preview {
+		t.Fatalf("dkim = %+v, want pending with a preview", resp.DKIM)
+	}
+	if !strings.Contains(resp.DKIM.Signature, "d=example.com") {
+		t.Errorf("preview signature = %q", resp.DKIM.Signature)
+	}
+}
+
+func TestSendSkipDKIM(t *testing.T) {
+	server, q := setupDKIMServer(t, true)
+
+	body := `{"from": "sender@example.com", "to": ["rcpt@example.org"], "subject": "Test", "body": "Hello", "skip_dkim": true}`
+	msg, resp := sendAndGet(t, server, q, body)
+	if !msg.SkipDKIM || msg.DKIMSigned {
+		t.Fatalf("message flags: skip = %v, signed = %v", msg.SkipDKIM, msg.DKIMSigned)
+	}
+	if resp.DKIM == nil || resp.DKIM.Status != DKIMStatusSkipped || resp.DKIM.Signature != "" {
+		t.Errorf("dkim = %+v, want skipped", resp.DKIM)
+	}
+}
+
+func TestGetMessageNoDKIMKey(t *testing.T) {
+	server, q := setupTestServer("test-api-key")
+
+	_, resp := sendAndGet(t, server, q, dkimSendBody)
+	if resp.DKIM == nil || resp.DKIM.Status != DKIMStatusNone {
+		t.Errorf("dkim = %+v, want none", resp.DKIM)
+	}
+
+	req := httptest.NewRequest("GET", "/api/v1/queue/missing", nil)
+	req.Header.Set("Authorization", "Bearer test-api-key")
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("missing message status = %d, want 404", w.Code)
+	}
+}
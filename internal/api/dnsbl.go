@@ -0,0 +1,197 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/foxzi/sendry/internal/dnsbl"
+	"github.com/foxzi/sendry/internal/dnscheck"
+)
+
+// MonitoredIP is a monitored outbound IP with its latest DNSBL check
+type MonitoredIP struct {
+	*dnsbl.MonitoredIP
+	Latest *dnsbl.Result `json:"latest,omitempty"`
+}
+
+// MonitoredIPsResponse is the response for GET /api/v1/ip/monitored
+type MonitoredIPsResponse struct {
+	IPs   []MonitoredIP `json:"ips"`
+	Total int           `json:"total"`
+}
+
+// MonitoredIPRequest is the request for POST /api/v1/ip/monitored
+type MonitoredIPRequest struct {
+	IP   string `json:"ip"`
+	Note string `json:"note,omitempty"`
+}
+
+// DNSBLHistoryResponse is the response for GET /api/v1/ip/monitored/{ip}/history
+type DNSBLHistoryResponse struct {
+	IP      string          `json:"ip"`
+	Latest  *dnsbl.Result   `json:"latest"`
+	History []*dnsbl.Result `json:"history"`
+	Total   int             `json:"total"`
+}
+
+// handleMonitoredIPList handles GET /api/v1/ip/monitored
+func (m *ManagementServer) handleMonitoredIPList(w http.ResponseWriter, r *http.Request) {
+	if m.dnsbl == nil {
+		sendError(w, http.StatusNotFound, "DNSBL monitoring is not enabled")
+		return
+	}
+
+	ips, err := m.dnsbl.IPs(r.Context())
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to list monitored IPs")
+		return
+	}
+
+	resp := MonitoredIPsResponse{IPs: make([]MonitoredIP, 0, len(ips))}
+	for _, ip := range ips {
+		latest, err := m.dnsbl.Storage().Latest(r.Context(), ip.IP)
+		if err != nil {
+			sendError(w, http.StatusInternalServerError, "Failed to get DNSBL check results")
+			return
+		}
+		resp.IPs = append(resp.IPs, MonitoredIP{MonitoredIP: ip, Latest: latest})
+	}
+	resp.Total = len(resp.IPs)
+
+	sendJSON(w, http.StatusOK, resp)
+}
+
+// handleMonitoredIPAdd handles POST /api/v1/ip/monitored
+func (m *ManagementServer) handleMonitoredIPAdd(w http.ResponseWriter, r *http.Request) {
+	if m.dnsbl == nil {
+		sendError(w, http.StatusNotFound, "DNSBL monitoring is not enabled")
+		return
+	}
+
+	var req MonitoredIPRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	ip, err := m.dnsbl.AddIP(r.Context(), req.IP, req.Note)
+	if err != nil {
+		switch {
+		case errors.Is(err, dnscheck.ErrInvalidIP):
+			sendError(w, http.StatusBadRequest, "invalid IPv4 address format")
+		case errors.Is(err, dnscheck.ErrIPv6NotSupported):
+			sendError(w, http.StatusBadRequest, "only IPv4 addresses are supported")
+		default:
+			sendError(w, http.StatusInternalServerError, "Failed to add monitored IP")
+		}
+		return
+	}
+
+	sendJSON(w, http.StatusCreated, MonitoredIP{MonitoredIP: ip})
+}
+
+// handleMonitoredIPDelete handles DELETE /api/v1/ip/monitored/{ip}
+func (m *ManagementServer) handleMonitoredIPDelete(w http.ResponseWriter, r *http.Request) {
+	ip, ok := m.monitoredIP(w, r)
+	if !ok {
+		return
+	}
+
+	if err := m.dnsbl.RemoveIP(r.Context(), ip); err != nil {
+		if errors.Is(err, dnsbl.ErrConfigIP) {
+			sendError(w, http.StatusConflict, "IP is configured in dnsbl_monitor.ips and cannot be removed via the API")
+			return
+		}
+		sendError(w, http.StatusInternalServerError, "Failed to remove monitored IP")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleMonitoredIPHistory handles GET /api/v1/ip/monitored/{ip}/history
+func (m *ManagementServer) handleMonitoredIPHistory(w http.ResponseWriter, r *http.Request) {
+	ip, ok := m.monitoredIP(w, r)
+	if !ok {
+		return
+	}
+
+	hours := 7 * 24
+	if v := r.URL.Query().Get("hours"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxHistoryHours {
+			sendError(w, http.StatusBadRequest, fmt.Sprintf("hours must be between 1 and %d", maxHistoryHours))
+			return
+		}
+		hours = n
+	}
+
+	latest, err := m.dnsbl.Storage().Latest(r.Context(), ip)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to get DNSBL check results")
+		return
+	}
+	history, err := m.dnsbl.Storage().History(r.Context(), ip, time.Now().Add(-time.Duration(hours)*time.Hour))
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to get DNSBL check results")
+		return
+	}
+	if history == nil {
+		history = []*dnsbl.Result{}
+	}
+
+	sendJSON(w, http.StatusOK, DNSBLHistoryResponse{
+		IP:      ip,
+		Latest:  latest,
+		History: history,
+		Total:   len(history),
+	})
+}
+
+// handleMonitoredIPCheck handles POST /api/v1/ip/monitored/{ip}/check
+func (m *ManagementServer) handleMonitoredIPCheck(w http.ResponseWriter, r *http.Request) {
+	ip, ok := m.monitoredIP(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := m.dnsbl.Check(r.Context(), ip)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to check IP")
+		return
+	}
+
+	sendJSON(w, http.StatusOK, result)
+}
+
+// monitoredIP returns the monitored IP of the request, writing an error
+// response if monitoring is disabled or the IP is not monitored
+func (m *ManagementServer) monitoredIP(w http.ResponseWriter, r *http.Request) (string, bool) {
+	if m.dnsbl == nil {
+		sendError(w, http.StatusNotFound, "DNSBL monitoring is not enabled")
+		return "", false
+	}
+
+	ip, err := dnsbl.ParseIP(chi.URLParam(r, "ip"))
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "invalid IPv4 address")
+		return "", false
+	}
+
+	monitored, err := m.dnsbl.Monitored(r.Context(), ip)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to get monitored IP")
+		return "", false
+	}
+	if !monitored {
+		sendError(w, http.StatusNotFound, "IP is not monitored")
+		return "", false
+	}
+	return ip, true
+}
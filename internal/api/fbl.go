@@ -0,0 +1,87 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/foxzi/sendry/internal/fbl"
+)
+
+// maxReportSize limits uploaded complaint reports
+const maxReportSize = 10 << 20
+
+// FBLServer handles complaint feedback loop API endpoints
+type FBLServer struct {
+	processor *fbl.Processor
+	storage   *fbl.Storage
+}
+
+// NewFBLServer creates a new feedback loop server
+func NewFBLServer(processor *fbl.Processor, storage *fbl.Storage) *FBLServer {
+	return &FBLServer{processor: processor, storage: storage}
+}
+
+// RegisterRoutes registers feedback loop API routes
+func (s *FBLServer) RegisterRoutes(r chi.Router) {
+	r.Route("/fbl", func(r chi.Router) {
+		r.Post("/reports", s.handleUpload)
+		r.Get("/complaints", s.handleList)
+	})
+}
+
+// ComplaintListResponse is the response for listing complaints
+type ComplaintListResponse struct {
+	Complaints []*fbl.Complaint `json:"complaints"`
+	Total      int              `json:"total"`
+}
+
+// handleUpload handles POST /api/v1/fbl/reports with a raw ARF message body
+func (s *FBLServer) handleUpload(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxReportSize))
+	if err != nil {
+		sendError(w, http.StatusRequestEntityTooLarge, "Report is too large")
+		return
+	}
+
+	complaint, err := s.processor.Process(r.Context(), data, fbl.SourceAPI)
+	if err != nil {
+		if errors.Is(err, fbl.ErrInvalidReport) {
+			sendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		sendError(w, http.StatusInternalServerError, "Failed to process report")
+		return
+	}
+
+	sendJSON(w, http.StatusCreated, complaint)
+}
+
+// handleList handles GET /api/v1/fbl/complaints
+func (s *FBLServer) handleList(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := fbl.Filter{
+		Domain:     q.Get("domain"),
+		CampaignID: q.Get("campaign_id"),
+		Recipient:  q.Get("recipient"),
+	}
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > 1000 {
+			sendError(w, http.StatusBadRequest, "limit must be between 1 and 1000")
+			return
+		}
+		filter.Limit = limit
+	}
+
+	complaints, err := s.storage.List(r.Context(), filter)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to list complaints")
+		return
+	}
+
+	sendJSON(w, http.StatusOK, ComplaintListResponse{Complaints: complaints, Total: len(complaints)})
+}
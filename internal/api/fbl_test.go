@@ -0,0 +1,163 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/foxzi/sendry/internal/config"
+	"github.com/foxzi/sendry/internal/fbl"
+	"github.com/foxzi/sendry/internal/reputation"
+	"github.com/foxzi/sendry/internal/suppression"
+)
+
+const testARFReport = "From: abuse@mail.example.net\r\n" +
+	"Content-Type: multipart/report; report-type=feedback-report; boundary=b\r\n" +
+	"\r\n" +
+	"--b\r\n" +
+	"Content-Type: text/plain\r\n" +
+	"\r\n" +
+	"This is an abuse report\r\n" +
+	"--b\r\n" +
+	"Content-Type: message/feedback-report\r\n" +
+	"\r\n" +
+	"Feedback-Type: abuse\r\n" +
+	"Original-Rcpt-To: user@example.net\r\n" +
+	"Reporting-MTA: dns; mail.example.net\r\n" +
+	"--b\r\n" +
+	"Content-Type: text/rfc822-headers\r\n" +
+	"\r\n" +
+	"From: news@example.com\r\n" +
+	"Message-ID: <1@example.com>\r\n" +
+	"X-Sendry-Campaign: spring\r\n" +
+	"--b--\r\n"
+
+func setupFBLServer(t *testing.T) (*Server, *suppression.Storage, *reputation.Storage) {
+	t.Helper()
+
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	suppressions, err := suppression.NewStorage(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	complaints, err := fbl.NewStorage(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rep, err := reputation.NewStorage(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	processor := fbl.NewProcessor(complaints, suppressions, nil)
+	processor.SetComplaintRecorder(rep)
+
+	server := NewServerWithOptions(ServerOptions{
+		Queue:              newMockQueue(),
+		Config:             &config.APIConfig{ListenAddr: ":8080"},
+		Logger:             slog.New(slog.NewTextHandler(io.Discard, nil)),
+		SuppressionStorage: suppressions,
+		FBLProcessor:       processor,
+		FBLStorage:         complaints,
+	})
+	return server, suppressions, rep
+}
+
+func TestFBLAPI(t *testing.T) {
+	server, suppressions, rep := setupFBLServer(t)
+	ctx := context.Background()
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+
+	w := do("POST", "/api/v1/fbl/reports", testARFReport)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("upload status = %d: %s", w.Code, w.Body.String())
+	}
+	var complaint fbl.Complaint
+	if err := json.NewDecoder(w.Body).Decode(&complaint); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if complaint.Recipient != "user@example.net" || complaint.CampaignID != "spring" || !complaint.Suppressed {
+		t.Errorf("complaint = %+v", complaint)
+	}
+
+	if ok, _ := suppressions.Suppressed(ctx, "user@example.net"); !ok {
+		t.Error("complaining recipient is not suppressed")
+	}
+	counts, err := rep.Sum(ctx, "example.com", time.Now().Add(-time.Hour))
+	if err != nil || counts.Complaints != 1 {
+		t.Errorf("reputation counts = %+v, %v", counts, err)
+	}
+
+	if w := do("POST", "/api/v1/fbl/reports", "Subject: hi\r\n\r\nnot a report"); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid report status = %d, want 400", w.Code)
+	}
+
+	w = do("GET", "/api/v1/fbl/complaints?campaign_id=spring", "")
+	var list ComplaintListResponse
+	if err := json.NewDecoder(w.Body).Decode(&list); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if w.Code != http.StatusOK || list.Total != 1 {
+		t.Errorf("list status = %d, total = %d", w.Code, list.Total)
+	}
+	if w := do("GET", "/api/v1/fbl/complaints?limit=0", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid limit status = %d, want 400", w.Code)
+	}
+}
+
+func TestSuppressionAPI(t *testing.T) {
+	server, _, _ := setupFBLServer(t)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+
+	if w := do("POST", "/api/v1/suppressions", `{"email": "Blocked@Example.com", "detail": "asked by phone"}`); w.Code != http.StatusCreated {
+		t.Fatalf("add status = %d: %s", w.Code, w.Body.String())
+	}
+	if w := do("POST", "/api/v1/suppressions", `{"email": "blocked@example.com"}`); w.Code != http.StatusConflict {
+		t.Errorf("duplicate add status = %d, want 409", w.Code)
+	}
+	if w := do("POST", "/api/v1/suppressions", `{"email": "invalid"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid add status = %d, want 400", w.Code)
+	}
+
+	w := do("GET", "/api/v1/suppressions?reason=manual", "")
+	var list SuppressionListResponse
+	if err := json.NewDecoder(w.Body).Decode(&list); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if list.Total != 1 || list.Suppressions[0].Email != "blocked@example.com" || list.Suppressions[0].Detail != "asked by phone" {
+		t.Errorf("list = %+v", list)
+	}
+
+	if w := do("GET", "/api/v1/suppressions/blocked@example.com", ""); w.Code != http.StatusOK {
+		t.Errorf("get status = %d", w.Code)
+	}
+	if w := do("DELETE", "/api/v1/suppressions/blocked@example.com", ""); w.Code != http.StatusOK {
+		t.Errorf("delete status = %d", w.Code)
+	}
+	if w := do("DELETE", "/api/v1/suppressions/blocked@example.com", ""); w.Code != http.StatusNotFound {
+		t.Errorf("second delete status = %d, want 404", w.Code)
+	}
+}
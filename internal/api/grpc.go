@@ -0,0 +1,411 @@
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/foxzi/sendry/internal/apikey"
+	"github.com/foxzi/sendry/internal/audit"
+	"github.com/foxzi/sendry/internal/eventlog"
+	"github.com/foxzi/sendry/internal/metrics"
+	"github.com/foxzi/sendry/internal/tracing"
+	"github.com/foxzi/sendry/pkg/sendrypb"
+)
+
+// grpcMessageOverhead is the room above the message size limit for the
+// other fields of a gRPC request
+const grpcMessageOverhead = 64 << 10
+
+// grpcScopes are the API key scopes of the gRPC methods, as requiredScope
+// of the HTTP routes. Methods not listed need admin.
+var grpcScopes = map[string]apikey.Scope{
+	sendrypb.Sendry_Send_FullMethodName:           apikey.ScopeSend,
+	sendrypb.Sendry_SendRaw_FullMethodName:        apikey.ScopeSend,
+	sendrypb.Sendry_SendStream_FullMethodName:     apikey.ScopeSend,
+	sendrypb.Sendry_GetStatus_FullMethodName:      apikey.ScopeSend,
+	sendrypb.Sendry_ListQueue_FullMethodName:      apikey.ScopeRead,
+	sendrypb.Sendry_GetMessage_FullMethodName:     apikey.ScopeRead,
+	sendrypb.Sendry_ListDLQ_FullMethodName:        apikey.ScopeRead,
+	sendrypb.Sendry_GetPauseStatus_FullMethodName: apikey.ScopeRead,
+	sendrypb.Sendry_ListDomains_FullMethodName:    apikey.ScopeRead,
+	sendrypb.Sendry_GetDomain_FullMethodName:      apikey.ScopeRead,
+	sendrypb.Sendry_WatchEvents_FullMethodName:    apikey.ScopeRead,
+}
+
+// grpcAudited are the gRPC methods that change server state, recorded in
+// the audit log
+var grpcAudited = map[string]bool{
+	sendrypb.Sendry_DeleteMessage_FullMethodName: true,
+	sendrypb.Sendry_RetryDLQ_FullMethodName:      true,
+	sendrypb.Sendry_DeleteDLQ_FullMethodName:     true,
+	sendrypb.Sendry_Pause_FullMethodName:         true,
+	sendrypb.Sendry_Resume_FullMethodName:        true,
+}
+
+// GRPCServer serves the gRPC API. It shares message submission, API keys,
+// rate limits, allowed IPs, the audit log and TLS with the HTTP API.
+type GRPCServer struct {
+	sendrypb.UnimplementedSendryServer
+
+	api      *Server
+	events   *eventlog.Broker
+	server   *grpc.Server
+	addr     string
+	logger   *slog.Logger
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+// NewGRPCServer creates the gRPC API of s listening on addr. events feeds
+// WatchEvents, which is unavailable without it.
+func NewGRPCServer(s *Server, addr string, events *eventlog.Broker) *GRPCServer {
+	g := &GRPCServer{
+		api:    s,
+		events: events,
+		addr:   addr,
+		logger: s.logger.With("protocol", "grpc"),
+		stopCh: make(chan struct{}),
+	}
+
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(g.unaryInterceptor),
+		grpc.ChainStreamInterceptor(g.streamInterceptor),
+		grpc.MaxRecvMsgSize(s.maxMessageBytes() + grpcMessageOverhead),
+	}
+	if s.tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(s.tlsConfig)))
+	}
+	g.server = grpc.NewServer(opts...)
+	sendrypb.RegisterSendryServer(g.server, g)
+	return g
+}
+
+// ListenAndServe starts the gRPC server
+func (g *GRPCServer) ListenAndServe() error {
+	lis, err := net.Listen("tcp", g.addr)
+	if err != nil {
+		return err
+	}
+	g.logger.Info("starting gRPC API server", "addr", g.addr, "tls", g.api.tlsConfig != nil)
+	return g.Serve(lis)
+}
+
+// Serve serves the gRPC API on lis
+func (g *GRPCServer) Serve(lis net.Listener) error {
+	return g.server.Serve(lis)
+}
+
+// Shutdown ends the event streams and waits for running calls, until ctx
+// is done
+func (g *GRPCServer) Shutdown(ctx context.Context) error {
+	g.logger.Info("shutting down gRPC API server")
+	g.stopOnce.Do(func() { close(g.stopCh) })
+
+	done := make(chan struct{})
+	go func() {
+		g.server.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		g.server.Stop()
+		return ctx.Err()
+	}
+}
+
+// unaryInterceptor authenticates, traces, audits and logs unary calls
+func (g *GRPCServer) unaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	ctx, span := g.startCall(ctx, info.FullMethod)
+	defer span.End()
+
+	ctx, err := g.authorize(ctx, info.FullMethod)
+	var resp any
+	if err == nil {
+		if grpcAudited[info.FullMethod] && g.api.auditStorage != nil {
+			change := &auditChange{}
+			resp, err = handler(context.WithValue(ctx, ctxKeyAuditChange, change), req)
+			g.recordAudit(ctx, info.FullMethod, start, change, err)
+		} else {
+			resp, err = handler(ctx, req)
+		}
+	}
+	g.endCall(ctx, span, info.FullMethod, start, err)
+	return resp, err
+}
+
+// streamInterceptor authenticates, traces and logs streaming calls
+func (g *GRPCServer) streamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	ctx, span := g.startCall(ss.Context(), info.FullMethod)
+	defer span.End()
+
+	ctx, err := g.authorize(ctx, info.FullMethod)
+	if err == nil {
+		err = handler(srv, &grpcStream{ServerStream: ss, ctx: ctx})
+	}
+	g.endCall(ctx, span, info.FullMethod, start, err)
+	return err
+}
+
+// grpcStream is a server stream with the context of the interceptor
+type grpcStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the context of the stream
+func (s *grpcStream) Context() context.Context {
+	return s.ctx
+}
+
+// startCall starts the span of a call, joining a traceparent of the client,
+// and assigns its correlation ID, returned in the x-correlation-id header
+func (g *GRPCServer) startCall(ctx context.Context, method string) (context.Context, trace.Span) {
+	ctx = tracing.Extract(ctx, metadataValue(ctx, "traceparent"))
+	ctx, span := tracing.Start(ctx, method,
+		attribute.String("rpc.system", "grpc"),
+		attribute.String("rpc.method", method),
+		attribute.String("client.address", peerAddr(ctx)),
+	)
+
+	id := audit.SanitizeCorrelationID(metadataValue(ctx, strings.ToLower(audit.CorrelationHeader)))
+	if id == "" {
+		id = uuid.New().String()
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs(strings.ToLower(audit.CorrelationHeader), id))
+	return context.WithValue(ctx, ctxKeyCorrelationID, id), span
+}
+
+// endCall records the outcome of a call on its span and in the log
+func (g *GRPCServer) endCall(ctx context.Context, span trace.Span, method string, start time.Time, err error) {
+	code := status.Code(err)
+	span.SetAttributes(attribute.Int("rpc.grpc.status_code", int(code)))
+	if grpcServerError(code) {
+		tracing.Fail(span, err)
+	}
+
+	g.logger.Info("grpc request",
+		"method", method,
+		"code", code.String(),
+		"duration", time.Since(start),
+		"remote_addr", peerAddr(ctx),
+		"correlation_id", correlationID(ctx),
+	)
+}
+
+// authorize checks the allowed IPs and the API key of a call, as the HTTP
+// API does. The api.api_key of the config grants full access, keys from
+// the key store grant their scopes.
+func (g *GRPCServer) authorize(ctx context.Context, method string) (context.Context, error) {
+	s := g.api
+	addr := peerAddr(ctx)
+	if s.ipFilter != nil && !s.ipFilter.IsAllowedAddr(addr) {
+		g.logger.Warn("gRPC request from disallowed IP", "remote_addr", addr, "method", method)
+		return ctx, status.Error(codes.PermissionDenied, "IP address not allowed")
+	}
+
+	token := metadataValue(ctx, "authorization")
+	if token == "" {
+		token = metadataValue(ctx, "x-api-key")
+	}
+	token = strings.TrimPrefix(token, "Bearer ")
+
+	if key := s.staticAPIKey(); key != "" && token == key {
+		return ctx, nil
+	}
+
+	if s.apiKeyStorage != nil && token != "" {
+		key, err := s.apiKeyStorage.Authenticate(ctx, token)
+		if err != nil {
+			g.logger.Error("failed to check API key", "error", err)
+			return ctx, status.Error(codes.Internal, "failed to check API key")
+		}
+		if key != nil {
+			scope := grpcScope(method)
+			if !key.Allows(scope) {
+				g.logger.Warn("API key lacks scope", "api_key_id", key.ID, "scope", scope, "method", method)
+				return ctx, status.Error(codes.PermissionDenied, "API key lacks the "+string(scope)+" scope")
+			}
+			if err := s.apiKeyStorage.Touch(ctx, key.ID); err != nil {
+				g.logger.Error("failed to record API key use", "api_key_id", key.ID, "error", err)
+			}
+			return context.WithValue(ctx, ctxKeyAPIKey, key), nil
+		}
+	}
+
+	if s.staticAPIKey() == "" && !s.hasAPIKeys(ctx) {
+		// No API key configured, allow all
+		return ctx, nil
+	}
+
+	g.logger.Warn("unauthorized gRPC request", "remote_addr", addr, "method", method)
+	return ctx, status.Error(codes.Unauthenticated, "invalid or missing API key")
+}
+
+// grpcScope returns the scope a gRPC method needs
+func grpcScope(method string) apikey.Scope {
+	if scope, ok := grpcScopes[method]; ok {
+		return scope
+	}
+	return apikey.ScopeAdmin
+}
+
+// allowSubmission applies the rate limit of the API key of a call to a
+// submitted message
+func (g *GRPCServer) allowSubmission(ctx context.Context) error {
+	key := apiKeyFromContext(ctx)
+	if key == nil || g.api.rateLimiter == nil {
+		return nil
+	}
+	result, err := g.api.rateLimiter.AllowAPIKey(ctx, key.ID, key.RateLimit)
+	if err != nil {
+		g.logger.Error("rate limit check error", "error", err)
+		return nil
+	}
+	if !result.Allowed {
+		metrics.IncRateLimitExceeded(string(result.DeniedBy))
+		return status.Errorf(codes.ResourceExhausted, "rate limit exceeded, try again in %s", (result.RetryAfter + time.Second).Truncate(time.Second))
+	}
+	return nil
+}
+
+// recordAudit writes the audit entry of a call that changes server state
+func (g *GRPCServer) recordAudit(ctx context.Context, method string, start time.Time, change *auditChange, err error) {
+	entry := &audit.Entry{
+		Time:           start,
+		CorrelationID:  correlationID(ctx),
+		Method:         "GRPC",
+		Path:           method,
+		Status:         grpcHTTPStatus(status.Code(err)),
+		RemoteAddr:     peerAddr(ctx),
+		ClientIdentity: g.api.certificateIdentity(peerTLS(ctx)),
+		Actor:          audit.SanitizeActor(metadataValue(ctx, strings.ToLower(audit.ActorHeader))),
+		Old:            change.before,
+		New:            change.after,
+		Duration:       time.Since(start),
+	}
+	if key := apiKeyFromContext(ctx); key != nil {
+		entry.APIKeyID = key.ID
+	}
+	if err != nil {
+		entry.Error = status.Convert(err).Message()
+	}
+
+	if err := g.api.auditStorage.Add(context.Background(), entry); err != nil {
+		g.logger.Error("failed to write audit entry",
+			"correlation_id", entry.CorrelationID,
+			"error", err,
+		)
+	}
+}
+
+// metadataValue returns the first value of an incoming metadata key
+func metadataValue(ctx context.Context, key string) string {
+	if values := metadata.ValueFromIncomingContext(ctx, key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// peerAddr returns the address of the client of a call
+func peerAddr(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return p.Addr.String()
+	}
+	return ""
+}
+
+// peerTLS returns the TLS state of the connection of a call, nil without TLS
+func peerTLS(ctx context.Context) *tls.ConnectionState {
+	if p, ok := peer.FromContext(ctx); ok {
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			return &info.State
+		}
+	}
+	return nil
+}
+
+// grpcError converts the HTTP status and message of a refused request to a
+// gRPC status error
+func grpcError(httpStatus int, message string) error {
+	code := codes.Internal
+	switch httpStatus {
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity:
+		code = codes.InvalidArgument
+	case http.StatusUnauthorized:
+		code = codes.Unauthenticated
+	case http.StatusForbidden:
+		code = codes.PermissionDenied
+	case http.StatusNotFound:
+		code = codes.NotFound
+	case http.StatusConflict:
+		code = codes.AlreadyExists
+	case http.StatusTooManyRequests:
+		code = codes.ResourceExhausted
+	case http.StatusNotImplemented:
+		code = codes.Unimplemented
+	case http.StatusServiceUnavailable:
+		code = codes.Unavailable
+	}
+	return status.Error(code, message)
+}
+
+// grpcHTTPStatus returns the HTTP status of a gRPC code, for the audit log
+func grpcHTTPStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.InvalidArgument, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.FailedPrecondition:
+		return http.StatusPreconditionFailed
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Canceled:
+		return 499 // Client closed request
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusInternalServerError
+}
+
+// grpcServerError reports whether a code is a server failure rather than a
+// refused request
+func grpcServerError(code codes.Code) bool {
+	switch code {
+	case codes.Unknown, codes.Internal, codes.DataLoss, codes.Unavailable:
+		return true
+	}
+	return false
+}
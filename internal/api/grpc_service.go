@@ -0,0 +1,660 @@
+package api
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/foxzi/sendry/internal/domain"
+	"github.com/foxzi/sendry/internal/eventlog"
+	"github.com/foxzi/sendry/internal/queue"
+	"github.com/foxzi/sendry/internal/replication"
+	"github.com/foxzi/sendry/internal/tracing"
+	"github.com/foxzi/sendry/pkg/sendrypb"
+)
+
+// grpcPriorities are the queue priorities of the gRPC priorities
+var grpcPriorities = map[sendrypb.Priority]string{
+	sendrypb.Priority_PRIORITY_UNSPECIFIED: "",
+	sendrypb.Priority_PRIORITY_HIGH:        string(queue.PriorityHigh),
+	sendrypb.Priority_PRIORITY_NORMAL:      string(queue.PriorityNormal),
+	sendrypb.Priority_PRIORITY_LOW:         string(queue.PriorityLow),
+}
+
+// Send queues a message built from its parts
+func (g *GRPCServer) Send(ctx context.Context, req *sendrypb.SendRequest) (*sendrypb.SendResponse, error) {
+	return g.submit(ctx, req)
+}
+
+// SendStream queues the messages of a stream and answers each in order
+func (g *GRPCServer) SendStream(stream sendrypb.Sendry_SendStreamServer) error {
+	ctx := stream.Context()
+	for index := int64(0); ; index++ {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		resp := &sendrypb.SendStreamResponse{Index: index}
+		if resp.Result, err = g.submit(ctx, req); err != nil {
+			st := status.Convert(err)
+			resp.Code = int32(st.Code())
+			resp.Error = st.Message()
+		}
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+}
+
+// submit validates and queues a message of Send or SendStream
+func (g *GRPCServer) submit(ctx context.Context, req *sendrypb.SendRequest) (*sendrypb.SendResponse, error) {
+	if err := g.allowSubmission(ctx); err != nil {
+		return nil, err
+	}
+
+	priority, ok := grpcPriorities[req.GetPriority()]
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid priority %d", req.GetPriority())
+	}
+	sendReq := &SendRequest{
+		From:     req.GetFrom(),
+		To:       req.GetTo(),
+		CC:       req.GetCc(),
+		BCC:      req.GetBcc(),
+		Subject:  req.GetSubject(),
+		Body:     req.GetBody(),
+		HTML:     req.GetHtml(),
+		Headers:  req.GetHeaders(),
+		SendAt:   protoTime(req.GetSendAt()),
+		Priority: priority,
+		SkipDKIM: req.GetSkipDkim(),
+	}
+	for _, a := range req.GetAttachments() {
+		sendReq.Attachments = append(sendReq.Attachments, Attachment{
+			Filename:    a.GetFilename(),
+			ContentType: a.GetContentType(),
+			Content:     base64.StdEncoding.EncodeToString(a.GetContent()),
+		})
+	}
+
+	msg, httpStatus, errMsg := g.api.buildMessageFromRequest(ctx, sendReq, peerAddr(ctx))
+	if msg == nil {
+		return nil, grpcError(httpStatus, errMsg)
+	}
+	if err := g.enqueue(ctx, msg); err != nil {
+		return nil, err
+	}
+
+	g.logger.Info("message queued via API",
+		"id", msg.ID,
+		"from", msg.From,
+		"to", msg.To,
+	)
+	return newSendResponseProto(msg), nil
+}
+
+// SendRaw queues a complete RFC 5322 message
+func (g *GRPCServer) SendRaw(ctx context.Context, req *sendrypb.SendRawRequest) (*sendrypb.SendResponse, error) {
+	if err := g.allowSubmission(ctx); err != nil {
+		return nil, err
+	}
+
+	name, ok := grpcPriorities[req.GetPriority()]
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid priority %d", req.GetPriority())
+	}
+	priority, err := queue.ParsePriority(name)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	env := &rawEnvelope{
+		from:     req.GetFrom(),
+		to:       req.GetTo(),
+		sendAt:   protoTime(req.GetSendAt()),
+		priority: priority,
+		skipDKIM: req.GetSkipDkim(),
+	}
+	if err := checkRawEnvelope(env); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if maxSize := g.api.maxMessageBytes(); len(req.GetData()) > maxSize {
+		return nil, status.Errorf(codes.InvalidArgument, "email content too large (max %d bytes)", maxSize)
+	}
+
+	msg, httpStatus, errMsg := g.api.buildRawMessage(ctx, env, req.GetData(), peerAddr(ctx))
+	if msg == nil {
+		return nil, grpcError(httpStatus, errMsg)
+	}
+	if err := g.enqueue(ctx, msg); err != nil {
+		return nil, err
+	}
+
+	g.logger.Info("raw message queued via API",
+		"id", msg.ID,
+		"from", msg.From,
+		"to", msg.To,
+		"size", len(msg.Data),
+	)
+	return newSendResponseProto(msg), nil
+}
+
+// enqueue adds a submitted message to the queue
+func (g *GRPCServer) enqueue(ctx context.Context, msg *queue.Message) error {
+	if err := g.api.queue.Enqueue(ctx, msg); err != nil {
+		if errors.Is(err, replication.ErrStandby) {
+			return status.Error(codes.Unavailable, err.Error())
+		}
+		g.logger.Error("failed to enqueue message", "error", err)
+		return status.Error(codes.Internal, "failed to queue message")
+	}
+	return nil
+}
+
+// GetStatus returns the delivery status of a message
+func (g *GRPCServer) GetStatus(ctx context.Context, req *sendrypb.GetStatusRequest) (*sendrypb.MessageStatus, error) {
+	msg, err := g.getMessage(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	return newMessageStatusProto(msg), nil
+}
+
+// ListQueue returns the queue counters and a page of messages
+func (g *GRPCServer) ListQueue(ctx context.Context, req *sendrypb.ListQueueRequest) (*sendrypb.ListQueueResponse, error) {
+	filter := queue.ListFilter{
+		Status:          queue.MessageStatus(req.GetStatus()),
+		Sender:          req.GetSender(),
+		Recipient:       req.GetRecipient(),
+		SenderDomain:    req.GetSenderDomain(),
+		RecipientDomain: req.GetRecipientDomain(),
+		Domain:          req.GetDomain(),
+		IDPrefix:        req.GetIdPrefix(),
+		Cursor:          req.GetCursor(),
+		Limit:           int(req.GetLimit()),
+	}
+	if !validListStatus(filter.Status) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid status %q", filter.Status)
+	}
+	if since := protoTime(req.GetSince()); since != nil {
+		filter.Since = *since
+	}
+	if until := protoTime(req.GetUntil()); until != nil {
+		filter.Until = *until
+	}
+	if filter.Limit == 0 {
+		filter.Limit = 100
+	}
+	if filter.Limit < 1 || filter.Limit > 1000 {
+		return nil, status.Error(codes.InvalidArgument, "limit must be between 1 and 1000")
+	}
+
+	stats, err := g.api.queue.Stats(ctx)
+	if err != nil {
+		g.logger.Error("failed to get queue stats", "error", err)
+		return nil, status.Error(codes.Internal, "failed to get queue stats")
+	}
+	messages, err := g.api.queue.List(ctx, filter)
+	if err != nil {
+		g.logger.Error("failed to list messages", "error", err)
+		return nil, status.Error(codes.Internal, "failed to list messages")
+	}
+
+	resp := &sendrypb.ListQueueResponse{
+		Stats: &sendrypb.QueueStats{
+			Pending:   stats.Pending,
+			Sending:   stats.Sending,
+			Delivered: stats.Delivered,
+			Failed:    stats.Failed,
+			Deferred:  stats.Deferred,
+			Total:     stats.Total,
+		},
+		Messages: newMessageSummariesProto(messages),
+	}
+	if len(messages) == filter.Limit {
+		resp.NextCursor = messages[len(messages)-1].ID
+	}
+	return resp, nil
+}
+
+// GetMessage returns a queued message
+func (g *GRPCServer) GetMessage(ctx context.Context, req *sendrypb.GetMessageRequest) (*sendrypb.GetMessageResponse, error) {
+	msg, err := g.getMessage(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	return &sendrypb.GetMessageResponse{
+		Status: newMessageStatusProto(msg),
+		Size:   int64(len(msg.Data)),
+	}, nil
+}
+
+// getMessage returns a queued message by ID
+func (g *GRPCServer) getMessage(ctx context.Context, id string) (*queue.Message, error) {
+	if id == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+	msg, err := g.api.queue.Get(ctx, id)
+	if err != nil {
+		g.logger.Error("failed to get message", "id", id, "error", err)
+		return nil, status.Error(codes.Internal, "failed to get message")
+	}
+	if msg == nil {
+		return nil, status.Error(codes.NotFound, "message not found")
+	}
+	return msg, nil
+}
+
+// DeleteMessage removes a message from the queue
+func (g *GRPCServer) DeleteMessage(ctx context.Context, req *sendrypb.DeleteMessageRequest) (*sendrypb.DeleteMessageResponse, error) {
+	id := req.GetId()
+	if id == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	msg, _ := g.api.queue.Get(ctx, id)
+	if err := g.api.queue.Delete(ctx, id); err != nil {
+		g.logger.Error("failed to delete message", "id", id, "error", err)
+		return nil, status.Error(codes.Internal, "failed to delete message")
+	}
+
+	if msg != nil {
+		recordContextChange(ctx, NewMessageSummary(msg), nil)
+	}
+	return &sendrypb.DeleteMessageResponse{}, nil
+}
+
+// ListDLQ returns the dead letter queue counters and messages
+func (g *GRPCServer) ListDLQ(ctx context.Context, _ *sendrypb.ListDLQRequest) (*sendrypb.ListDLQResponse, error) {
+	storage, err := g.dlq()
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := storage.DLQStats(ctx)
+	if err != nil {
+		g.logger.Error("failed to get DLQ stats", "error", err)
+		return nil, status.Error(codes.Internal, "failed to get DLQ stats")
+	}
+	messages, err := storage.ListDLQ(ctx, 100, 0)
+	if err != nil {
+		g.logger.Error("failed to list DLQ messages", "error", err)
+		return nil, status.Error(codes.Internal, "failed to list DLQ messages")
+	}
+
+	return &sendrypb.ListDLQResponse{
+		Stats: &sendrypb.DLQStats{
+			Total:     stats.Total,
+			TotalSize: stats.TotalSize,
+			OldestAt:  timeProto(stats.OldestAt),
+		},
+		Messages: newMessageSummariesProto(messages),
+	}, nil
+}
+
+// RetryDLQ moves a message of the dead letter queue back to the queue
+func (g *GRPCServer) RetryDLQ(ctx context.Context, req *sendrypb.RetryDLQRequest) (*sendrypb.RetryDLQResponse, error) {
+	id := req.GetId()
+	if id == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+	storage, err := g.dlq()
+	if err != nil {
+		return nil, err
+	}
+
+	msg, _ := storage.GetFromDLQ(ctx, id)
+	if err := storage.RetryFromDLQ(ctx, id); err != nil {
+		g.logger.Error("failed to retry DLQ message", "id", id, "error", err)
+		return nil, status.Error(codes.Internal, "failed to retry message")
+	}
+
+	if msg != nil {
+		retried := NewMessageSummary(msg)
+		retried.Status = string(queue.StatusPending)
+		recordContextChange(ctx, NewMessageSummary(msg), retried)
+	}
+	g.logger.Info("message retried from DLQ", "id", id)
+	return &sendrypb.RetryDLQResponse{}, nil
+}
+
+// DeleteDLQ removes a message from the dead letter queue
+func (g *GRPCServer) DeleteDLQ(ctx context.Context, req *sendrypb.DeleteDLQRequest) (*sendrypb.DeleteDLQResponse, error) {
+	id := req.GetId()
+	if id == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+	storage, err := g.dlq()
+	if err != nil {
+		return nil, err
+	}
+
+	msg, _ := storage.GetFromDLQ(ctx, id)
+	if err := storage.DeleteFromDLQ(ctx, id); err != nil {
+		g.logger.Error("failed to delete DLQ message", "id", id, "error", err)
+		return nil, status.Error(codes.Internal, "failed to delete message")
+	}
+
+	if msg != nil {
+		recordContextChange(ctx, NewMessageSummary(msg), nil)
+	}
+	g.logger.Info("message deleted from DLQ", "id", id)
+	return &sendrypb.DeleteDLQResponse{}, nil
+}
+
+// dlq returns the dead letter queue of the storage
+func (g *GRPCServer) dlq() (queue.DLQManager, error) {
+	if g.api.dlqStorage == nil {
+		return nil, status.Error(codes.Unimplemented, "DLQ not supported with this storage backend")
+	}
+	return g.api.dlqStorage, nil
+}
+
+// GetPauseStatus returns the paused deliveries
+func (g *GRPCServer) GetPauseStatus(context.Context, *sendrypb.GetPauseStatusRequest) (*sendrypb.PauseStatus, error) {
+	if g.api.pauses == nil {
+		return nil, status.Error(codes.Unimplemented, "delivery pause is not available")
+	}
+	return newPauseStatusProto(g.api.pauses.Status()), nil
+}
+
+// Pause pauses delivery of a domain, or all delivery
+func (g *GRPCServer) Pause(ctx context.Context, req *sendrypb.PauseRequest) (*sendrypb.PauseStatus, error) {
+	return g.changePause(ctx, req.GetDomain(), true)
+}
+
+// Resume resumes delivery of a domain, or all delivery
+func (g *GRPCServer) Resume(ctx context.Context, req *sendrypb.ResumeRequest) (*sendrypb.PauseStatus, error) {
+	return g.changePause(ctx, req.GetDomain(), false)
+}
+
+// changePause pauses or resumes delivery of a domain
+func (g *GRPCServer) changePause(ctx context.Context, domain string, pause bool) (*sendrypb.PauseStatus, error) {
+	pauses := g.api.pauses
+	if pauses == nil {
+		return nil, status.Error(codes.Unimplemented, "delivery pause is not available")
+	}
+	domain, err := pauseDomain(domain)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	before := pauses.Status()
+	if pause {
+		pauses.Pause(domain)
+		g.logger.Info("delivery paused", "domain", domain)
+	} else {
+		pauses.Resume(domain)
+		g.logger.Info("delivery resumed", "domain", domain)
+	}
+
+	after := pauses.Status()
+	recordContextChange(ctx, before, after)
+	return newPauseStatusProto(after), nil
+}
+
+// ListDomains returns the sending domains
+func (g *GRPCServer) ListDomains(context.Context, *sendrypb.ListDomainsRequest) (*sendrypb.ListDomainsResponse, error) {
+	domains := g.domains()
+	if domains == nil {
+		return &sendrypb.ListDomainsResponse{Domains: []*sendrypb.Domain{}}, nil
+	}
+
+	names := domains.ListDomains()
+	resp := &sendrypb.ListDomainsResponse{Domains: make([]*sendrypb.Domain, 0, len(names))}
+	for _, name := range names {
+		d := &sendrypb.Domain{Domain: name}
+		if dc := domains.GetDomainConfig(name); dc != nil {
+			d = newDomainProto(name, newDomainResponse(name, *dc))
+		}
+		resp.Domains = append(resp.Domains, d)
+	}
+	return resp, nil
+}
+
+// GetDomain returns a sending domain
+func (g *GRPCServer) GetDomain(_ context.Context, req *sendrypb.GetDomainRequest) (*sendrypb.Domain, error) {
+	name := req.GetDomain()
+	if name == "" {
+		return nil, status.Error(codes.InvalidArgument, "domain is required")
+	}
+	domains := g.domains()
+	if domains == nil {
+		return nil, status.Error(codes.NotFound, "domain not found")
+	}
+	dc := domains.GetDomainConfig(name)
+	if dc == nil {
+		return nil, status.Error(codes.NotFound, "domain not found")
+	}
+	return newDomainProto(name, newDomainResponse(name, *dc)), nil
+}
+
+// domains returns the domain manager of the management API, nil without
+// a full config
+func (g *GRPCServer) domains() *domain.Manager {
+	if g.api.managementServer == nil {
+		return nil
+	}
+	return g.api.managementServer.domainManager
+}
+
+// WatchEvents streams the delivery events of messages until the client
+// cancels, the server shuts down or the client falls behind
+func (g *GRPCServer) WatchEvents(req *sendrypb.WatchEventsRequest, stream sendrypb.Sendry_WatchEventsServer) error {
+	if g.events == nil {
+		return status.Error(codes.Unimplemented, "event stream is not available")
+	}
+	for _, event := range req.GetEvents() {
+		switch event {
+		case eventlog.EventAccepted, eventlog.EventDelivered, eventlog.EventDeferred, eventlog.EventBounced:
+		default:
+			return status.Errorf(codes.InvalidArgument, "invalid event %q", event)
+		}
+	}
+
+	events, cancel := g.events.Subscribe()
+	defer cancel()
+
+	ctx := stream.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-g.stopCh:
+			return status.Error(codes.Unavailable, "server is shutting down")
+		case event, ok := <-events:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "event stream fell behind, events were dropped")
+			}
+			if !matchEvent(req, event) {
+				continue
+			}
+			if err := stream.Send(newEventProto(event)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// matchEvent reports whether an event passes the filter of a WatchEvents call
+func matchEvent(req *sendrypb.WatchEventsRequest, event eventlog.Event) bool {
+	if len(req.GetEvents()) > 0 {
+		found := false
+		for _, e := range req.GetEvents() {
+			if e == event.Event {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if req.GetMessageId() != "" && req.GetMessageId() != event.MessageID {
+		return false
+	}
+	if req.GetSenderDomain() != "" && !strings.EqualFold(req.GetSenderDomain(), event.SenderDomain) {
+		return false
+	}
+	return true
+}
+
+// protoTime returns the time of a timestamp, nil if unset
+func protoTime(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	t := ts.AsTime()
+	return &t
+}
+
+// timeProto returns the timestamp of a time, nil for the zero time
+func timeProto(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+// newSendResponseProto returns the gRPC response of a queued message
+func newSendResponseProto(msg *queue.Message) *sendrypb.SendResponse {
+	return &sendrypb.SendResponse{
+		Id:     msg.ID,
+		Status: string(msg.Status),
+		SendAt: timeProto(msg.SendAt),
+	}
+}
+
+// newMessageStatusProto returns the gRPC status of a message
+func newMessageStatusProto(msg *queue.Message) *sendrypb.MessageStatus {
+	s := &sendrypb.MessageStatus{
+		Id:         msg.ID,
+		Status:     string(msg.Status),
+		From:       msg.From,
+		To:         msg.To,
+		CreatedAt:  timeProto(msg.CreatedAt),
+		UpdatedAt:  timeProto(msg.UpdatedAt),
+		RetryCount: int32(msg.RetryCount),
+		LastError:  msg.LastError,
+		SendAt:     timeProto(msg.SendAt),
+		Priority:   string(msg.EffectivePriority()),
+		TraceId:    tracing.TraceID(msg.TraceParent),
+	}
+	if len(msg.Results) > 0 {
+		s.Recipients = make(map[string]*sendrypb.RecipientResult, len(msg.Results))
+		for rcpt, r := range msg.Results {
+			s.Recipients[rcpt] = &sendrypb.RecipientResult{
+				Status:     string(r.Status),
+				MxHost:     r.MXHost,
+				Error:      r.Error,
+				Expired:    r.Expired,
+				Bounced:    r.Bounced,
+				Greylisted: r.Greylisted,
+				UpdatedAt:  timeProto(r.UpdatedAt),
+			}
+		}
+	}
+	for _, a := range msg.Attempts {
+		s.Attempts = append(s.Attempts, &sendrypb.DeliveryAttempt{
+			Timestamp: timeProto(a.Timestamp),
+			Recipient: a.Recipient,
+			MxHost:    a.MXHost,
+			Success:   a.Success,
+			Permanent: a.Permanent,
+			Error:     a.Error,
+			Response:  a.Response,
+		})
+	}
+	return s
+}
+
+// newMessageSummariesProto returns the gRPC list representation of messages
+func newMessageSummariesProto(messages []*queue.Message) []*sendrypb.MessageSummary {
+	summaries := make([]*sendrypb.MessageSummary, len(messages))
+	for i, msg := range messages {
+		summaries[i] = &sendrypb.MessageSummary{
+			Id:        msg.ID,
+			From:      msg.From,
+			To:        msg.To,
+			Status:    string(msg.Status),
+			CreatedAt: timeProto(msg.CreatedAt),
+			SendAt:    timeProto(msg.SendAt),
+			Priority:  string(msg.EffectivePriority()),
+		}
+	}
+	return summaries
+}
+
+// newPauseStatusProto returns the gRPC representation of paused deliveries
+func newPauseStatusProto(s queue.PauseStatus) *sendrypb.PauseStatus {
+	return &sendrypb.PauseStatus{
+		Global:        s.Global,
+		Domains:       s.Domains,
+		ConfigDomains: s.ConfigDomains,
+	}
+}
+
+// newDomainProto returns the gRPC representation of a domain
+func newDomainProto(name string, dr DomainResponse) *sendrypb.Domain {
+	d := &sendrypb.Domain{
+		Domain:      name,
+		Mode:        dr.Mode,
+		DefaultFrom: dr.DefaultFrom,
+		RedirectTo:  dr.RedirectTo,
+		BccTo:       dr.BCCTo,
+		Paused:      dr.Paused,
+	}
+	if dr.DKIM != nil {
+		d.Dkim = &sendrypb.DomainDKIM{Enabled: dr.DKIM.Enabled, Selector: dr.DKIM.Selector}
+	}
+	if rl := dr.RateLimit; rl != nil {
+		d.RateLimit = &sendrypb.DomainRateLimit{
+			MessagesPerMinute:    int32(rl.MessagesPerMinute),
+			MessagesPerHour:      int32(rl.MessagesPerHour),
+			MessagesPerDay:       int32(rl.MessagesPerDay),
+			RecipientsPerMessage: int32(rl.RecipientsPerMessage),
+		}
+	}
+	return d
+}
+
+// newEventProto returns the gRPC representation of a delivery event
+func newEventProto(e eventlog.Event) *sendrypb.Event {
+	event := &sendrypb.Event{
+		Timestamp:    timeProto(e.Timestamp),
+		Event:        e.Event,
+		Host:         e.Host,
+		MessageId:    e.MessageID,
+		From:         e.From,
+		SenderDomain: e.SenderDomain,
+		To:           e.To,
+		Size:         int64(e.Size),
+		RetryCount:   int32(e.RetryCount),
+		ClientIp:     e.ClientIP,
+		AuthUser:     e.AuthUser,
+		ApiKeyId:     e.APIKeyID,
+		Error:        e.Error,
+	}
+	if e.NextRetryAt != nil {
+		event.NextRetryAt = timestamppb.New(*e.NextRetryAt)
+	}
+	for _, r := range e.Recipients {
+		event.Recipients = append(event.Recipients, &sendrypb.EventRecipient{
+			Address: r.Address,
+			Status:  r.Status,
+			MxHost:  r.MXHost,
+			Error:   r.Error,
+		})
+	}
+	return event
+}
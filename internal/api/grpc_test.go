@@ -0,0 +1,377 @@
+package api
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/foxzi/sendry/internal/apikey"
+	"github.com/foxzi/sendry/internal/audit"
+	"github.com/foxzi/sendry/internal/config"
+	"github.com/foxzi/sendry/internal/eventlog"
+	"github.com/foxzi/sendry/internal/queue"
+	"github.com/foxzi/sendry/pkg/sendrypb"
+)
+
+// grpcTest is a gRPC API served over an in-memory connection
+type grpcTest struct {
+	client  sendrypb.SendryClient
+	queue   *mockQueue
+	broker  *eventlog.Broker
+	keys    *apikey.Storage
+	audit   *audit.Storage
+	pauses  *queue.Pauses
+	adminMD metadata.MD
+}
+
+func setupGRPC(t *testing.T) *grpcTest {
+	t.Helper()
+
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	keys, err := apikey.NewStorage(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auditStorage, err := audit.NewStorage(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gt := &grpcTest{
+		queue:   newMockQueue(),
+		broker:  eventlog.NewBroker("mail.example.com", 10),
+		keys:    keys,
+		audit:   auditStorage,
+		pauses:  queue.NewPauses(),
+		adminMD: metadata.Pairs("authorization", "Bearer admin-key"),
+	}
+	server := NewServerWithOptions(ServerOptions{
+		Queue:         &acceptingQueue{mockQueue: gt.queue, events: gt.broker},
+		Config:        &config.APIConfig{APIKey: "admin-key"},
+		Logger:        slog.New(slog.NewTextHandler(io.Discard, nil)),
+		APIKeyStorage: keys,
+		AuditStorage:  auditStorage,
+		Pauses:        gt.pauses,
+	})
+	g := NewGRPCServer(server, "", gt.broker)
+
+	lis := bufconn.Listen(1 << 20)
+	go g.Serve(lis)
+	t.Cleanup(func() { g.Shutdown(context.Background()) })
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	gt.client = sendrypb.NewSendryClient(conn)
+	return gt
+}
+
+// acceptingQueue publishes the messages added to the queue
+type acceptingQueue struct {
+	*mockQueue
+	events *eventlog.Broker
+}
+
+func (q *acceptingQueue) Enqueue(ctx context.Context, msg *queue.Message) error {
+	if err := q.mockQueue.Enqueue(ctx, msg); err != nil {
+		return err
+	}
+	q.events.Accepted(msg)
+	return nil
+}
+
+func (gt *grpcTest) admin(ctx context.Context) context.Context {
+	return metadata.NewOutgoingContext(ctx, gt.adminMD)
+}
+
+func TestGRPCSendAndStatus(t *testing.T) {
+	gt := setupGRPC(t)
+	ctx := gt.admin(t.Context())
+
+	resp, err := gt.client.Send(ctx, &sendrypb.SendRequest{
+		From:     "sender@example.com",
+		To:       []string{"a@example.net"},
+		Subject:  "Hi",
+		Body:     "Hello",
+		Priority: sendrypb.Priority_PRIORITY_HIGH,
+		Attachments: []*sendrypb.Attachment{
+			{Filename: "a.txt", Content: []byte("attached")},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if resp.GetId() == "" || resp.GetStatus() != string(queue.StatusPending) {
+		t.Errorf("Send() = %v", resp)
+	}
+
+	msg := gt.queue.messages[resp.GetId()]
+	if msg == nil {
+		t.Fatal("message not queued")
+	}
+	if msg.Priority != queue.PriorityHigh {
+		t.Errorf("priority = %q, want high", msg.Priority)
+	}
+
+	st, err := gt.client.GetStatus(ctx, &sendrypb.GetStatusRequest{Id: resp.GetId()})
+	if err != nil {
+		t.Fatalf("GetStatus() error = %v", err)
+	}
+	if st.GetFrom() != "sender@example.com" || st.GetPriority() != "high" {
+		t.Errorf("GetStatus() = %v", st)
+	}
+
+	_, err = gt.client.GetStatus(ctx, &sendrypb.GetStatusRequest{Id: "missing"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("GetStatus(missing) code = %v, want NotFound", status.Code(err))
+	}
+
+	_, err = gt.client.Send(ctx, &sendrypb.SendRequest{From: "sender@example.com", Subject: "Hi"})
+	if status.Code(err) != codes.InvalidArgument || status.Convert(err).Message() != "to is required" {
+		t.Errorf("Send(no to) error = %v, want InvalidArgument", err)
+	}
+}
+
+func TestGRPCSendRaw(t *testing.T) {
+	gt := setupGRPC(t)
+	ctx := gt.admin(t.Context())
+
+	data := []byte("From: sender@example.com\r\nTo: a@example.net\r\nBcc: b@example.net\r\nSubject: Hi\r\n\r\nHello\r\n")
+	resp, err := gt.client.SendRaw(ctx, &sendrypb.SendRawRequest{Data: data})
+	if err != nil {
+		t.Fatalf("SendRaw() error = %v", err)
+	}
+	msg := gt.queue.messages[resp.GetId()]
+	if msg == nil || msg.From != "sender@example.com" || len(msg.To) != 2 {
+		t.Fatalf("queued message = %+v", msg)
+	}
+
+	_, err = gt.client.SendRaw(ctx, &sendrypb.SendRawRequest{Data: []byte("Subject: Hi\r\n\r\nHello\r\n")})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("SendRaw(no from) code = %v, want InvalidArgument", status.Code(err))
+	}
+}
+
+func TestGRPCSendStream(t *testing.T) {
+	gt := setupGRPC(t)
+
+	stream, err := gt.client.SendStream(gt.admin(t.Context()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests := []*sendrypb.SendRequest{
+		{From: "sender@example.com", To: []string{"a@example.net"}, Subject: "One"},
+		{From: "not an address", To: []string{"b@example.net"}, Subject: "Two"},
+		{From: "sender@example.com", To: []string{"c@example.net"}, Subject: "Three"},
+	}
+	for _, req := range requests {
+		if err := stream.Send(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := stream.CloseSend(); err != nil {
+		t.Fatal(err)
+	}
+
+	var responses []*sendrypb.SendStreamResponse
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv() error = %v", err)
+		}
+		responses = append(responses, resp)
+	}
+
+	if len(responses) != 3 {
+		t.Fatalf("got %d responses, want 3", len(responses))
+	}
+	for i, resp := range responses {
+		if resp.GetIndex() != int64(i) {
+			t.Errorf("response %d has index %d", i, resp.GetIndex())
+		}
+	}
+	if responses[0].GetResult() == nil || responses[2].GetResult() == nil {
+		t.Error("valid messages not queued")
+	}
+	if responses[1].GetResult() != nil || codes.Code(responses[1].GetCode()) != codes.InvalidArgument {
+		t.Errorf("invalid message response = %v", responses[1])
+	}
+	if len(gt.queue.messages) != 2 {
+		t.Errorf("queued %d messages, want 2", len(gt.queue.messages))
+	}
+}
+
+func TestGRPCAuth(t *testing.T) {
+	gt := setupGRPC(t)
+	_, sendToken, err := gt.keys.Create(t.Context(), "sender", []apikey.Scope{apikey.ScopeSend}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, readToken, err := gt.keys.Create(t.Context(), "viewer", []apikey.Scope{apikey.ScopeRead}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		md   metadata.MD
+		call func(ctx context.Context) error
+		want codes.Code
+	}{
+		{"no key", nil, func(ctx context.Context) error {
+			_, err := gt.client.ListQueue(ctx, &sendrypb.ListQueueRequest{})
+			return err
+		}, codes.Unauthenticated},
+		{"wrong key", metadata.Pairs("x-api-key", "wrong"), func(ctx context.Context) error {
+			_, err := gt.client.ListQueue(ctx, &sendrypb.ListQueueRequest{})
+			return err
+		}, codes.Unauthenticated},
+		{"read key lists", metadata.Pairs("x-api-key", readToken), func(ctx context.Context) error {
+			_, err := gt.client.ListQueue(ctx, &sendrypb.ListQueueRequest{})
+			return err
+		}, codes.OK},
+		{"read key cannot send", metadata.Pairs("authorization", "Bearer "+readToken), func(ctx context.Context) error {
+			_, err := gt.client.Send(ctx, &sendrypb.SendRequest{From: "a@example.com", To: []string{"b@example.net"}, Subject: "Hi"})
+			return err
+		}, codes.PermissionDenied},
+		{"send key sends", metadata.Pairs("authorization", "Bearer "+sendToken), func(ctx context.Context) error {
+			_, err := gt.client.Send(ctx, &sendrypb.SendRequest{From: "a@example.com", To: []string{"b@example.net"}, Subject: "Hi"})
+			return err
+		}, codes.OK},
+		{"send key cannot pause", metadata.Pairs("authorization", "Bearer "+sendToken), func(ctx context.Context) error {
+			_, err := gt.client.Pause(ctx, &sendrypb.PauseRequest{})
+			return err
+		}, codes.PermissionDenied},
+		{"send key cannot watch", metadata.Pairs("authorization", "Bearer "+sendToken), func(ctx context.Context) error {
+			stream, err := gt.client.WatchEvents(ctx, &sendrypb.WatchEventsRequest{})
+			if err != nil {
+				return err
+			}
+			_, err = stream.Recv()
+			return err
+		}, codes.PermissionDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := t.Context()
+			if tt.md != nil {
+				ctx = metadata.NewOutgoingContext(ctx, tt.md)
+			}
+			if code := status.Code(tt.call(ctx)); code != tt.want {
+				t.Errorf("code = %v, want %v", code, tt.want)
+			}
+		})
+	}
+}
+
+func TestGRPCPauseAudited(t *testing.T) {
+	gt := setupGRPC(t)
+	ctx := metadata.AppendToOutgoingContext(gt.admin(t.Context()),
+		"x-correlation-id", "deploy-42",
+		"x-audit-actor", "alice",
+	)
+
+	st, err := gt.client.Pause(ctx, &sendrypb.PauseRequest{Domain: "Example.NET"})
+	if err != nil {
+		t.Fatalf("Pause() error = %v", err)
+	}
+	if len(st.GetDomains()) != 1 || st.GetDomains()[0] != "example.net" {
+		t.Errorf("Pause() = %v", st)
+	}
+	if !gt.pauses.Paused("example.net") {
+		t.Error("domain not paused")
+	}
+
+	if _, err := gt.client.Pause(ctx, &sendrypb.PauseRequest{Domain: "user@example.net"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Pause(address) error = %v, want InvalidArgument", err)
+	}
+
+	entries, err := gt.audit.List(t.Context(), audit.Filter{CorrelationID: "deploy-42"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d audit entries, want 2", len(entries))
+	}
+	var ok, failed *audit.Entry
+	for _, e := range entries {
+		if e.Failed() {
+			failed = e
+		} else {
+			ok = e
+		}
+	}
+	if ok == nil || failed == nil {
+		t.Fatalf("audit entries = %+v", entries)
+	}
+	if ok.Method != "GRPC" || ok.Path != sendrypb.Sendry_Pause_FullMethodName || ok.Actor != "alice" || len(ok.New) == 0 {
+		t.Errorf("audit entry = %+v", ok)
+	}
+	if failed.Status != 400 || failed.Error != "domain must be a domain name" {
+		t.Errorf("failed audit entry = %+v", failed)
+	}
+}
+
+func TestGRPCWatchEvents(t *testing.T) {
+	gt := setupGRPC(t)
+	ctx, cancel := context.WithTimeout(gt.admin(t.Context()), 5*time.Second)
+	defer cancel()
+
+	stream, err := gt.client.WatchEvents(ctx, &sendrypb.WatchEventsRequest{SenderDomain: "example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The subscription starts with the call on the server
+	for gt.broker.Subscribers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	for _, from := range []string{"other@example.org", "sender@example.com"} {
+		if _, err := gt.client.Send(ctx, &sendrypb.SendRequest{From: from, To: []string{"a@example.net"}, Subject: "Hi"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	event, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv() error = %v", err)
+	}
+	if event.GetEvent() != eventlog.EventAccepted || event.GetFrom() != "sender@example.com" || event.GetHost() != "mail.example.com" {
+		t.Errorf("event = %v", event)
+	}
+
+	bad, err := gt.client.WatchEvents(ctx, &sendrypb.WatchEventsRequest{Events: []string{"opened"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bad.Recv(); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("WatchEvents(opened) error = %v, want InvalidArgument", err)
+	}
+}
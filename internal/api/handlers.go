@@ -2,10 +2,14 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"mime"
 	"net/http"
 	"net/mail"
+	"strconv"
 	"strings"
 	"time"
 
@@ -14,51 +18,104 @@ import (
 
 	"github.com/foxzi/sendry/internal/email"
 	"github.com/foxzi/sendry/internal/queue"
+	"github.com/foxzi/sendry/internal/replication"
+	"github.com/foxzi/sendry/internal/tracing"
 )
 
 // SendRequest is the request body for POST /send
 type SendRequest struct {
-	From    string            `json:"from"`
-	To      []string          `json:"to"`
-	CC      []string          `json:"cc,omitempty"`
-	BCC     []string          `json:"bcc,omitempty"`
-	Subject string            `json:"subject"`
-	Body    string            `json:"body"`
-	HTML    string            `json:"html,omitempty"`
-	Headers map[string]string `json:"headers,omitempty"`
+	From        string            `json:"from"`
+	To          []string          `json:"to"`
+	CC          []string          `json:"cc,omitempty"`
+	BCC         []string          `json:"bcc,omitempty"`
+	Subject     string            `json:"subject"`
+	Body        string            `json:"body"`
+	HTML        string            `json:"html,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Attachments []Attachment      `json:"attachments,omitempty"`
+	SendAt      *time.Time        `json:"send_at,omitempty"`   // Hold until this time (RFC 3339)
+	Priority    string            `json:"priority,omitempty"`  // high, normal or low
+	SkipDKIM    bool              `json:"skip_dkim,omitempty"` // Deliver without a DKIM signature, for debugging
 }
 
 // SendResponse is the response for POST /send
 type SendResponse struct {
-	ID     string `json:"id"`
-	Status string `json:"status"`
+	ID     string     `json:"id"`
+	Status string     `json:"status"`
+	SendAt *time.Time `json:"send_at,omitempty"`
 }
 
 // StatusResponse is the response for GET /status/{id}
 type StatusResponse struct {
-	ID         string    `json:"id"`
-	Status     string    `json:"status"`
-	From       string    `json:"from"`
-	To         []string  `json:"to"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
-	RetryCount int       `json:"retry_count"`
-	LastError  string    `json:"last_error,omitempty"`
+	ID         string     `json:"id"`
+	Status     string     `json:"status"`
+	From       string     `json:"from"`
+	To         []string   `json:"to"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	RetryCount int        `json:"retry_count"`
+	LastError  string     `json:"last_error,omitempty"`
+	SendAt     *time.Time `json:"send_at,omitempty"`
+	Priority   string     `json:"priority"`
+	TraceID    string     `json:"trace_id,omitempty"` // OpenTelemetry trace of the message
+
+	Recipients map[string]*queue.RecipientResult `json:"recipients,omitempty"` // Per-recipient delivery outcome
+	Attempts   []queue.DeliveryAttempt           `json:"attempts,omitempty"`   // Delivery attempt log
+}
+
+// MessageResponse is the response for GET /queue/{id}
+type MessageResponse struct {
+	StatusResponse
+	Size int          `json:"size"` // Message size in bytes
+	DKIM *MessageDKIM `json:"dkim"`
 }
 
 // QueueResponse is the response for GET /queue
 type QueueResponse struct {
-	Stats    *queue.QueueStats `json:"stats"`
-	Messages []*MessageSummary `json:"messages,omitempty"`
+	Stats      *queue.QueueStats `json:"stats"`
+	Messages   []*MessageSummary `json:"messages,omitempty"`
+	NextCursor string            `json:"next_cursor,omitempty"` // Set when more messages may follow
 }
 
 // MessageSummary is a summary of a message
 type MessageSummary struct {
-	ID        string    `json:"id"`
-	From      string    `json:"from"`
-	To        []string  `json:"to"`
-	Status    string    `json:"status"`
-	CreatedAt time.Time `json:"created_at"`
+	ID         string     `json:"id"`
+	From       string     `json:"from"`
+	To         []string   `json:"to"`
+	Subject    string     `json:"subject,omitempty"`
+	Status     string     `json:"status"`
+	CreatedAt  time.Time  `json:"created_at"`
+	SendAt     *time.Time `json:"send_at,omitempty"`
+	Priority   string     `json:"priority"`
+	RetryCount int        `json:"retry_count"`
+}
+
+// NewMessageSummary returns the list representation of a message
+func NewMessageSummary(msg *queue.Message) *MessageSummary {
+	return &MessageSummary{
+		ID:         msg.ID,
+		From:       msg.From,
+		To:         msg.To,
+		Subject:    messageSubject(msg.Data),
+		Status:     string(msg.Status),
+		CreatedAt:  msg.CreatedAt,
+		SendAt:     scheduledAt(msg),
+		Priority:   string(msg.EffectivePriority()),
+		RetryCount: msg.RetryCount,
+	}
+}
+
+// messageSubject returns the decoded Subject header of raw message data
+func messageSubject(data []byte) string {
+	parsed, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return ""
+	}
+	subject := parsed.Header.Get("Subject")
+	if decoded, err := new(mime.WordDecoder).DecodeHeader(subject); err == nil {
+		return decoded
+	}
+	return subject
 }
 
 // HealthResponse is the response for GET /health
@@ -69,6 +126,12 @@ type HealthResponse struct {
 	Queue   *queue.QueueStats `json:"queue"`
 }
 
+// LivenessResponse is the response for GET /healthz
+type LivenessResponse struct {
+	Status string `json:"status"`
+	Uptime string `json:"uptime"`
+}
+
 // ErrorResponse is the error response
 type ErrorResponse struct {
 	Error string `json:"error"`
@@ -82,7 +145,7 @@ func (s *Server) handleSend(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	msg, status, errMsg := s.buildMessageFromRequest(&req, r.RemoteAddr)
+	msg, status, errMsg := s.buildMessageFromRequest(r.Context(), &req, r.RemoteAddr)
 	if msg == nil {
 		s.sendError(w, status, errMsg)
 		return
@@ -90,6 +153,10 @@ func (s *Server) handleSend(w http.ResponseWriter, r *http.Request) {
 
 	// Enqueue
 	if err := s.queue.Enqueue(r.Context(), msg); err != nil {
+		if errors.Is(err, replication.ErrStandby) {
+			s.sendError(w, http.StatusServiceUnavailable, err.Error())
+			return
+		}
 		s.logger.Error("failed to enqueue message", "error", err)
 		s.sendError(w, http.StatusInternalServerError, "Failed to queue message")
 		return
@@ -104,6 +171,7 @@ func (s *Server) handleSend(w http.ResponseWriter, r *http.Request) {
 	s.sendJSON(w, http.StatusAccepted, SendResponse{
 		ID:     msg.ID,
 		Status: string(msg.Status),
+		SendAt: scheduledAt(msg),
 	})
 }
 
@@ -157,7 +225,7 @@ func (s *Server) handleSendBatch(w http.ResponseWriter, r *http.Request) {
 	rejected := 0
 
 	for i := range req.Messages {
-		msg, status, errMsg := s.buildMessageFromRequest(&req.Messages[i], r.RemoteAddr)
+		msg, status, errMsg := s.buildMessageFromRequest(r.Context(), &req.Messages[i], r.RemoteAddr)
 		if msg == nil {
 			results[i] = BatchSendResultItem{Index: i, Error: errMsg}
 			rejected++
@@ -174,8 +242,12 @@ func (s *Server) handleSendBatch(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if len(toEnqueue) > 0 {
-		if bs, ok := s.queue.(*queue.BoltStorage); ok {
+		if bs, ok := s.queue.(queue.BatchEnqueuer); ok {
 			if err := bs.EnqueueBatch(r.Context(), toEnqueue); err != nil {
+				if errors.Is(err, replication.ErrStandby) {
+					s.sendError(w, http.StatusServiceUnavailable, err.Error())
+					return
+				}
 				s.logger.Error("failed to enqueue batch", "error", err, "size", len(toEnqueue))
 				s.sendError(w, http.StatusInternalServerError, "Failed to queue batch")
 				return
@@ -211,7 +283,7 @@ func (s *Server) handleSendBatch(w http.ResponseWriter, r *http.Request) {
 
 // buildMessageFromRequest validates a SendRequest and builds a queue.Message.
 // On validation failure returns (nil, httpStatus, errorMessage).
-func (s *Server) buildMessageFromRequest(req *SendRequest, remoteAddr string) (*queue.Message, int, string) {
+func (s *Server) buildMessageFromRequest(ctx context.Context, req *SendRequest, remoteAddr string) (*queue.Message, int, string) {
 	if req.From == "" {
 		return nil, http.StatusBadRequest, "from is required"
 	}
@@ -240,17 +312,30 @@ func (s *Server) buildMessageFromRequest(req *SendRequest, remoteAddr string) (*
 		return nil, http.StatusBadRequest, "subject, body or html is required"
 	}
 
-	maxEmailSize := 10 * 1024 * 1024
-	if s.fullConfig != nil && s.fullConfig.SMTP.MaxMessageBytes > 0 {
-		maxEmailSize = s.fullConfig.SMTP.MaxMessageBytes
+	priority, err := queue.ParsePriority(req.Priority)
+	if err != nil {
+		return nil, http.StatusBadRequest, err.Error()
 	}
-	totalSize := len(req.Body) + len(req.HTML) + len(req.Subject)
+
+	attachments, err := decodeAttachments(req.Attachments)
+	if err != nil {
+		return nil, http.StatusBadRequest, err.Error()
+	}
+
+	maxEmailSize := s.maxMessageBytes()
+	totalSize := len(req.Body) + len(req.HTML) + len(req.Subject) + attachmentsSize(attachments)
 	if totalSize > maxEmailSize {
 		return nil, http.StatusRequestEntityTooLarge,
 			fmt.Sprintf("email content too large (max %d bytes)", maxEmailSize)
 	}
 
-	data := s.buildEmailData(req)
+	data := s.buildEmailData(req, attachments)
+
+	// Base64 encoding grows attachments by a third, check the final size too
+	if len(data) > maxEmailSize {
+		return nil, http.StatusRequestEntityTooLarge,
+			fmt.Sprintf("email content too large (max %d bytes)", maxEmailSize)
+	}
 
 	envelopeTo := make([]string, 0, len(req.To)+len(req.CC)+len(req.BCC))
 	envelopeTo = append(envelopeTo, req.To...)
@@ -267,10 +352,76 @@ func (s *Server) buildMessageFromRequest(req *SendRequest, remoteAddr string) (*
 		CreatedAt: now,
 		UpdatedAt: now,
 		ClientIP:  remoteAddr,
+		Priority:  priority,
+	}
+	if status, errMsg := s.prepareMessage(ctx, msg, req.SendAt, req.SkipDKIM); status != 0 {
+		return nil, status, errMsg
 	}
 	return msg, http.StatusAccepted, ""
 }
 
+// prepareMessage checks the senders and addresses of a new message, schedules it and
+// runs the content policy, the content filter and DKIM signing at enqueue. On refusal it returns the HTTP
+// status and error message.
+func (s *Server) prepareMessage(ctx context.Context, msg *queue.Message, sendAt *time.Time, skipDKIM bool) (int, string) {
+	setMessageAPIKey(ctx, msg)
+	if status, errMsg := checkSenders(ctx, msg); status != 0 {
+		return status, errMsg
+	}
+	if status, errMsg := checkEAI(s.fullConfig != nil && s.fullConfig.SMTP.RejectEAI, msg); status != 0 {
+		return status, errMsg
+	}
+	if status, errMsg := checkAddressPolicy(s.addrPolicy, msg); status != 0 {
+		return status, errMsg
+	}
+	if sendAt != nil {
+		msg.Schedule(*sendAt)
+	}
+	if status, errMsg := checkPolicy(s.policy, msg); status != 0 {
+		return status, errMsg
+	}
+	if status, errMsg := filterMessage(ctx, s.filter, msg); status != 0 {
+		return status, errMsg
+	}
+	if skipDKIM {
+		msg.SkipDKIM = true
+	} else if s.config != nil && s.config.DKIMSignOnEnqueue {
+		// Delivery signs the message if signing fails here
+		if err := signAtEnqueue(s.dkim, msg); err != nil {
+			s.logger.Warn("DKIM signing at enqueue failed", "from", msg.From, "error", err)
+		}
+	}
+	return 0, ""
+}
+
+// maxMessageBytes returns the size limit of submitted messages
+func (s *Server) maxMessageBytes() int {
+	if s.fullConfig != nil && s.fullConfig.SMTP.MaxMessageBytes > 0 {
+		return s.fullConfig.SMTP.MaxMessageBytes
+	}
+	return defaultMaxMessageBytes
+}
+
+// BuildMessage validates a send request that did not come over HTTP, e.g.
+// from a broker consumer, and builds its queue message. source is recorded
+// as the client address.
+func (s *Server) BuildMessage(req *SendRequest, source string) (*queue.Message, error) {
+	msg, _, errMsg := s.buildMessageFromRequest(context.Background(), req, source)
+	if msg == nil {
+		return nil, errors.New(errMsg)
+	}
+	return msg, nil
+}
+
+// scheduledAt returns the send time of a scheduled message, or nil
+func scheduledAt(msg *queue.Message) *time.Time {
+	if msg.SendAt.IsZero() {
+		return nil
+	}
+	sendAt := msg.SendAt
+	return &sendAt
+}
+
 // handleStatus handles GET /api/v1/status/{id}
 func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
@@ -291,16 +442,7 @@ func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.sendJSON(w, http.StatusOK, StatusResponse{
-		ID:         msg.ID,
-		Status:     string(msg.Status),
-		From:       msg.From,
-		To:         msg.To,
-		CreatedAt:  msg.CreatedAt,
-		UpdatedAt:  msg.UpdatedAt,
-		RetryCount: msg.RetryCount,
-		LastError:  msg.LastError,
-	})
+	s.sendJSON(w, http.StatusOK, NewStatusResponse(msg))
 }
 
 // handleQueue handles GET /api/v1/queue
@@ -312,8 +454,13 @@ func (s *Server) handleQueue(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get recent messages
-	messages, err := s.queue.List(r.Context(), queue.ListFilter{Limit: 100})
+	filter, err := parseQueueFilter(r)
+	if err != nil {
+		s.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	messages, err := s.queue.List(r.Context(), filter)
 	if err != nil {
 		s.logger.Error("failed to list messages", "error", err)
 		s.sendError(w, http.StatusInternalServerError, "Failed to list messages")
@@ -322,18 +469,89 @@ func (s *Server) handleQueue(w http.ResponseWriter, r *http.Request) {
 
 	summaries := make([]*MessageSummary, len(messages))
 	for i, msg := range messages {
-		summaries[i] = &MessageSummary{
-			ID:        msg.ID,
-			From:      msg.From,
-			To:        msg.To,
-			Status:    string(msg.Status),
-			CreatedAt: msg.CreatedAt,
-		}
+		summaries[i] = NewMessageSummary(msg)
 	}
 
-	s.sendJSON(w, http.StatusOK, QueueResponse{
+	resp := QueueResponse{
 		Stats:    stats,
 		Messages: summaries,
+	}
+	if len(messages) == filter.Limit {
+		resp.NextCursor = messages[len(messages)-1].ID
+	}
+	s.sendJSON(w, http.StatusOK, resp)
+}
+
+// parseQueueFilter parses the filter parameters of a queue listing request
+func parseQueueFilter(r *http.Request) (queue.ListFilter, error) {
+	params := r.URL.Query()
+	filter := queue.ListFilter{
+		Status:          queue.MessageStatus(params.Get("status")),
+		Sender:          params.Get("sender"),
+		Recipient:       params.Get("recipient"),
+		SenderDomain:    params.Get("sender_domain"),
+		RecipientDomain: params.Get("recipient_domain"),
+		Domain:          params.Get("domain"),
+		IDPrefix:        params.Get("id_prefix"),
+		Cursor:          params.Get("cursor"),
+		Limit:           100,
+	}
+
+	if !validListStatus(filter.Status) {
+		return filter, fmt.Errorf("invalid status %q", filter.Status)
+	}
+
+	var err error
+	if filter.Since, err = parseArchiveTime(params.Get("since"), false); err != nil {
+		return filter, fmt.Errorf("invalid since: %w", err)
+	}
+	if filter.Until, err = parseArchiveTime(params.Get("until"), true); err != nil {
+		return filter, fmt.Errorf("invalid until: %w", err)
+	}
+
+	if l := params.Get("limit"); l != "" {
+		limit, err := strconv.Atoi(l)
+		if err != nil || limit < 1 || limit > 1000 {
+			return filter, fmt.Errorf("limit must be between 1 and 1000")
+		}
+		filter.Limit = limit
+	}
+	return filter, nil
+}
+
+// validListStatus reports whether status can filter a queue listing, empty
+// for any status
+func validListStatus(status queue.MessageStatus) bool {
+	switch status {
+	case "", queue.StatusPending, queue.StatusSending, queue.StatusDelivered, queue.StatusFailed, queue.StatusDeferred:
+		return true
+	}
+	return false
+}
+
+// handleGetMessage handles GET /api/v1/queue/{id}
+func (s *Server) handleGetMessage(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		s.sendError(w, http.StatusBadRequest, "id is required")
+		return
+	}
+
+	msg, err := s.queue.Get(r.Context(), id)
+	if err != nil {
+		s.logger.Error("failed to get message", "id", id, "error", err)
+		s.sendError(w, http.StatusInternalServerError, "Failed to get message")
+		return
+	}
+	if msg == nil {
+		s.sendError(w, http.StatusNotFound, "Message not found")
+		return
+	}
+
+	s.sendJSON(w, http.StatusOK, MessageResponse{
+		StatusResponse: NewStatusResponse(msg),
+		Size:           len(msg.Data),
+		DKIM:           messageDKIM(s.dkim, msg),
 	})
 }
 
@@ -345,15 +563,64 @@ func (s *Server) handleDeleteMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	msg, _ := s.queue.Get(r.Context(), id)
+
 	if err := s.queue.Delete(r.Context(), id); err != nil {
 		s.logger.Error("failed to delete message", "id", id, "error", err)
 		s.sendError(w, http.StatusInternalServerError, "Failed to delete message")
 		return
 	}
 
+	if msg != nil {
+		recordChange(r, NewMessageSummary(msg), nil)
+	}
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// handleRetryMessage handles POST /api/v1/queue/{id}/retry: a deferred or
+// failed message is sent again now, with its retry count reset
+func (s *Server) handleRetryMessage(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		s.sendError(w, http.StatusBadRequest, "id is required")
+		return
+	}
+
+	msg, err := s.queue.Get(r.Context(), id)
+	if err != nil {
+		s.logger.Error("failed to get message", "id", id, "error", err)
+		s.sendError(w, http.StatusInternalServerError, "Failed to get message")
+		return
+	}
+	if msg == nil {
+		s.sendError(w, http.StatusNotFound, "Message not found")
+		return
+	}
+	if msg.Status != queue.StatusDeferred && msg.Status != queue.StatusFailed {
+		s.sendError(w, http.StatusConflict, fmt.Sprintf("Message is %s, only deferred and failed messages can be retried", msg.Status))
+		return
+	}
+
+	before := NewMessageSummary(msg)
+	msg.Status = queue.StatusPending
+	msg.RetryCount = 0
+	msg.LastError = ""
+	msg.NextRetryAt = time.Time{}
+	msg.UpdatedAt = time.Now()
+	if err := s.queue.Update(r.Context(), msg); err != nil {
+		s.logger.Error("failed to retry message", "id", id, "error", err)
+		s.sendError(w, http.StatusInternalServerError, "Failed to retry message")
+		return
+	}
+
+	recordChange(r, before, NewMessageSummary(msg))
+	s.logger.Info("message queued for retry", "id", id)
+	s.sendJSON(w, http.StatusOK, map[string]string{
+		"status":  "ok",
+		"message": "Message queued for retry",
+	})
+}
+
 // handleHealth handles GET /health
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	stats, err := s.queue.Stats(r.Context())
@@ -369,17 +636,36 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// handleLiveness handles GET /healthz: the process is up and serving
+func (s *Server) handleLiveness(w http.ResponseWriter, r *http.Request) {
+	s.sendJSON(w, http.StatusOK, LivenessResponse{
+		Status: "ok",
+		Uptime: time.Since(s.startTime).String(),
+	})
+}
+
+// handleReadiness handles GET /readyz: 200 while the node should take
+// traffic, 503 when a check fails or it is draining
+func (s *Server) handleReadiness(w http.ResponseWriter, r *http.Request) {
+	report := s.health.Check(r.Context())
+	status := http.StatusOK
+	if !report.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+	s.sendJSON(w, status, report)
+}
+
 // buildEmailData constructs RFC 5322 email data
-func (s *Server) buildEmailData(req *SendRequest) []byte {
+func (s *Server) buildEmailData(req *SendRequest, attachments []*mailAttachment) []byte {
 	var buf bytes.Buffer
 
 	// Headers
-	buf.WriteString(fmt.Sprintf("From: %s\r\n", req.From))
-	buf.WriteString(fmt.Sprintf("To: %s\r\n", strings.Join(req.To, ", ")))
+	buf.WriteString(fmt.Sprintf("From: %s\r\n", formatAddress(req.From)))
+	buf.WriteString(fmt.Sprintf("To: %s\r\n", formatAddressList(req.To)))
 	if len(req.CC) > 0 {
-		buf.WriteString(fmt.Sprintf("Cc: %s\r\n", strings.Join(req.CC, ", ")))
+		buf.WriteString(fmt.Sprintf("Cc: %s\r\n", formatAddressList(req.CC)))
 	}
-	buf.WriteString(fmt.Sprintf("Subject: %s\r\n", req.Subject))
+	buf.WriteString(fmt.Sprintf("Subject: %s\r\n", encodeHeaderValue(sanitizeHeaderValue(req.Subject))))
 	buf.WriteString(fmt.Sprintf("Date: %s\r\n", time.Now().Format(time.RFC1123Z)))
 	buf.WriteString(fmt.Sprintf("Message-ID: <%s@%s>\r\n", uuid.New().String(), email.ExtractDomainOrDefault(req.From, "localhost")))
 
@@ -389,39 +675,11 @@ func (s *Server) buildEmailData(req *SendRequest) []byte {
 		k = sanitizeHeaderValue(k)
 		v = sanitizeHeaderValue(v)
 		if k != "" {
-			buf.WriteString(fmt.Sprintf("%s: %s\r\n", k, v))
+			buf.WriteString(fmt.Sprintf("%s: %s\r\n", k, encodeHeaderValue(v)))
 		}
 	}
 
-	// MIME headers
-	if req.HTML != "" {
-		boundary := uuid.New().String()
-		buf.WriteString("MIME-Version: 1.0\r\n")
-		buf.WriteString(fmt.Sprintf("Content-Type: multipart/alternative; boundary=\"%s\"\r\n", boundary))
-		buf.WriteString("\r\n")
-
-		// Plain text part
-		if req.Body != "" {
-			buf.WriteString(fmt.Sprintf("--%s\r\n", boundary))
-			buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
-			buf.WriteString("\r\n")
-			buf.WriteString(req.Body)
-			buf.WriteString("\r\n")
-		}
-
-		// HTML part
-		buf.WriteString(fmt.Sprintf("--%s\r\n", boundary))
-		buf.WriteString("Content-Type: text/html; charset=utf-8\r\n")
-		buf.WriteString("\r\n")
-		buf.WriteString(req.HTML)
-		buf.WriteString("\r\n")
-
-		buf.WriteString(fmt.Sprintf("--%s--\r\n", boundary))
-	} else {
-		buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
-		buf.WriteString("\r\n")
-		buf.WriteString(req.Body)
-	}
+	writeMIMEBody(&buf, req.Body, req.HTML, attachments)
 
 	return buf.Bytes()
 }
@@ -462,17 +720,17 @@ func (s *Server) sendError(w http.ResponseWriter, status int, message string) {
 
 // DLQResponse is the response for GET /api/v1/dlq
 type DLQResponse struct {
-	Stats    *queue.DLQStats  `json:"stats"`
+	Stats    *queue.DLQStats   `json:"stats"`
 	Messages []*MessageSummary `json:"messages,omitempty"`
 }
 
 // handleDLQ handles GET /api/v1/dlq
 func (s *Server) handleDLQ(w http.ResponseWriter, r *http.Request) {
-	if s.boltStorage == nil {
+	if s.dlqStorage == nil {
 		s.sendError(w, http.StatusNotImplemented, "DLQ not supported with this storage backend")
 		return
 	}
-	storage := s.boltStorage
+	storage := s.dlqStorage
 
 	stats, err := storage.DLQStats(r.Context())
 	if err != nil {
@@ -490,13 +748,7 @@ func (s *Server) handleDLQ(w http.ResponseWriter, r *http.Request) {
 
 	summaries := make([]*MessageSummary, len(messages))
 	for i, msg := range messages {
-		summaries[i] = &MessageSummary{
-			ID:        msg.ID,
-			From:      msg.From,
-			To:        msg.To,
-			Status:    string(msg.Status),
-			CreatedAt: msg.CreatedAt,
-		}
+		summaries[i] = NewMessageSummary(msg)
 	}
 
 	s.sendJSON(w, http.StatusOK, DLQResponse{
@@ -513,11 +765,11 @@ func (s *Server) handleDLQGet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if s.boltStorage == nil {
+	if s.dlqStorage == nil {
 		s.sendError(w, http.StatusNotImplemented, "DLQ not supported with this storage backend")
 		return
 	}
-	storage := s.boltStorage
+	storage := s.dlqStorage
 
 	msg, err := storage.GetFromDLQ(r.Context(), id)
 	if err != nil {
@@ -531,7 +783,12 @@ func (s *Server) handleDLQGet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.sendJSON(w, http.StatusOK, StatusResponse{
+	s.sendJSON(w, http.StatusOK, NewStatusResponse(msg))
+}
+
+// NewStatusResponse returns the status representation of a message
+func NewStatusResponse(msg *queue.Message) StatusResponse {
+	return StatusResponse{
 		ID:         msg.ID,
 		Status:     string(msg.Status),
 		From:       msg.From,
@@ -540,7 +797,12 @@ func (s *Server) handleDLQGet(w http.ResponseWriter, r *http.Request) {
 		UpdatedAt:  msg.UpdatedAt,
 		RetryCount: msg.RetryCount,
 		LastError:  msg.LastError,
-	})
+		SendAt:     scheduledAt(msg),
+		Priority:   string(msg.EffectivePriority()),
+		TraceID:    tracing.TraceID(msg.TraceParent),
+		Recipients: msg.Results,
+		Attempts:   msg.Attempts,
+	}
 }
 
 // handleDLQRetry handles POST /api/v1/dlq/{id}/retry
@@ -551,11 +813,13 @@ func (s *Server) handleDLQRetry(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if s.boltStorage == nil {
+	if s.dlqStorage == nil {
 		s.sendError(w, http.StatusNotImplemented, "DLQ not supported with this storage backend")
 		return
 	}
-	storage := s.boltStorage
+	storage := s.dlqStorage
+
+	msg, _ := storage.GetFromDLQ(r.Context(), id)
 
 	if err := storage.RetryFromDLQ(r.Context(), id); err != nil {
 		s.logger.Error("failed to retry DLQ message", "id", id, "error", err)
@@ -563,6 +827,11 @@ func (s *Server) handleDLQRetry(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if msg != nil {
+		retried := NewMessageSummary(msg)
+		retried.Status = string(queue.StatusPending)
+		recordChange(r, NewMessageSummary(msg), retried)
+	}
 	s.logger.Info("message retried from DLQ", "id", id)
 	s.sendJSON(w, http.StatusOK, map[string]string{
 		"status":  "ok",
@@ -578,11 +847,13 @@ func (s *Server) handleDLQDelete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if s.boltStorage == nil {
+	if s.dlqStorage == nil {
 		s.sendError(w, http.StatusNotImplemented, "DLQ not supported with this storage backend")
 		return
 	}
-	storage := s.boltStorage
+	storage := s.dlqStorage
+
+	msg, _ := storage.GetFromDLQ(r.Context(), id)
 
 	if err := storage.DeleteFromDLQ(r.Context(), id); err != nil {
 		s.logger.Error("failed to delete DLQ message", "id", id, "error", err)
@@ -590,6 +861,9 @@ func (s *Server) handleDLQDelete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if msg != nil {
+		recordChange(r, NewMessageSummary(msg), nil)
+	}
 	s.logger.Info("message deleted from DLQ", "id", id)
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -4,14 +4,17 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/foxzi/sendry/internal/config"
+	"github.com/foxzi/sendry/internal/health"
 	"github.com/foxzi/sendry/internal/queue"
 )
 
@@ -45,7 +48,7 @@ func (m *mockQueue) Get(ctx context.Context, id string) (*queue.Message, error)
 func (m *mockQueue) List(ctx context.Context, filter queue.ListFilter) ([]*queue.Message, error) {
 	var result []*queue.Message
 	for _, msg := range m.messages {
-		if filter.Status != "" && msg.Status != filter.Status {
+		if !filter.Match(msg) {
 			continue
 		}
 		result = append(result, msg)
@@ -117,6 +120,71 @@ func TestHealthEndpoint(t *testing.T) {
 	}
 }
 
+func TestReadinessEndpoints(t *testing.T) {
+	server, _ := setupTestServer("secret")
+	checker := health.New(time.Second)
+	var storageErr error
+	checker.Add("storage", func(ctx context.Context) error { return storageErr })
+	server.health = checker
+
+	get := func(path string) (int, health.Report) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		var report health.Report
+		json.NewDecoder(w.Body).Decode(&report)
+		return w.Code, report
+	}
+
+	if code, _ := get("/healthz"); code != http.StatusOK {
+		t.Errorf("/healthz status = %d, want %d", code, http.StatusOK)
+	}
+	if code, report := get("/readyz"); code != http.StatusOK || report.Status != health.StatusReady {
+		t.Errorf("/readyz = %d %q, want 200 ready", code, report.Status)
+	}
+
+	storageErr = errors.New("disk failed")
+	code, report := get("/readyz")
+	if code != http.StatusServiceUnavailable || report.Status != health.StatusNotReady {
+		t.Errorf("/readyz with failed check = %d %q, want 503 not_ready", code, report.Status)
+	}
+	if len(report.Checks) != 1 || report.Checks[0].Error != "disk failed" {
+		t.Errorf("checks = %+v", report.Checks)
+	}
+
+	storageErr = nil
+	checker.SetDraining(true)
+	if code, report := get("/readyz"); code != http.StatusServiceUnavailable || report.Status != health.StatusDraining {
+		t.Errorf("/readyz while draining = %d %q, want 503 draining", code, report.Status)
+	}
+	if code, _ := get("/healthz"); code != http.StatusOK {
+		t.Errorf("/healthz while draining = %d, want %d", code, http.StatusOK)
+	}
+}
+
+func TestSetAPIKey(t *testing.T) {
+	server, _ := setupTestServer("old-key")
+
+	status := func(key string) int {
+		req := httptest.NewRequest("GET", "/api/v1/queue", nil)
+		req.Header.Set("Authorization", "Bearer "+key)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := status("old-key"); code != http.StatusOK {
+		t.Fatalf("old key status = %d, want %d", code, http.StatusOK)
+	}
+	server.SetAPIKey("new-key")
+	if code := status("old-key"); code != http.StatusUnauthorized {
+		t.Errorf("old key after rotation status = %d, want %d", code, http.StatusUnauthorized)
+	}
+	if code := status("new-key"); code != http.StatusOK {
+		t.Errorf("new key status = %d, want %d", code, http.StatusOK)
+	}
+}
+
 func TestSendEndpoint(t *testing.T) {
 	server, q := setupTestServer("test-api-key")
 
@@ -156,6 +224,124 @@ func TestSendEndpoint(t *testing.T) {
 	}
 }
 
+func TestSendScheduled(t *testing.T) {
+	server, q := setupTestServer("test-api-key")
+
+	sendAt := time.Now().Add(2 * time.Hour).UTC().Truncate(time.Second)
+	body := `{
+		"from": "sender@example.com",
+		"to": ["recipient@example.com"],
+		"subject": "Later",
+		"body": "Hello",
+		"send_at": "` + sendAt.Format(time.RFC3339) + `"
+	}`
+
+	req := httptest.NewRequest("POST", "/api/v1/send", bytes.NewBufferString(body))
+	req.Header.Set("Authorization", "Bearer test-api-key")
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("Status = %d, want %d. Body: %s", w.Code, http.StatusAccepted, w.Body.String())
+	}
+
+	var resp SendResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Status != "deferred" {
+		t.Errorf("Status = %q, want deferred", resp.Status)
+	}
+	if resp.SendAt == nil || !resp.SendAt.Equal(sendAt) {
+		t.Errorf("SendAt = %v, want %v", resp.SendAt, sendAt)
+	}
+
+	msg := q.messages[resp.ID]
+	if msg == nil || !msg.NextRetryAt.Equal(sendAt) {
+		t.Fatalf("queued message = %+v, want next retry at %v", msg, sendAt)
+	}
+
+	// Status exposes the scheduled time
+	req = httptest.NewRequest("GET", "/api/v1/status/"+resp.ID, nil)
+	req.Header.Set("Authorization", "Bearer test-api-key")
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	var status StatusResponse
+	json.NewDecoder(w.Body).Decode(&status)
+	if status.SendAt == nil || !status.SendAt.Equal(sendAt) {
+		t.Errorf("status SendAt = %v, want %v", status.SendAt, sendAt)
+	}
+}
+
+func TestSendScheduledInPast(t *testing.T) {
+	server, _ := setupTestServer("")
+
+	body := `{
+		"from": "sender@example.com",
+		"to": ["recipient@example.com"],
+		"subject": "Now",
+		"body": "Hello",
+		"send_at": "2020-01-01T00:00:00Z"
+	}`
+
+	req := httptest.NewRequest("POST", "/api/v1/send", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	var resp SendResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.Status != "pending" {
+		t.Errorf("Status = %q, want pending for a send time in the past", resp.Status)
+	}
+}
+
+func TestSendPriority(t *testing.T) {
+	server, q := setupTestServer("")
+
+	body := `{
+		"from": "sender@example.com",
+		"to": ["recipient@example.com"],
+		"subject": "Password reset",
+		"body": "Hello",
+		"priority": "high"
+	}`
+
+	req := httptest.NewRequest("POST", "/api/v1/send", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("Status = %d, want %d. Body: %s", w.Code, http.StatusAccepted, w.Body.String())
+	}
+
+	var resp SendResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if msg := q.messages[resp.ID]; msg == nil || msg.Priority != queue.PriorityHigh {
+		t.Fatalf("queued message = %+v, want high priority", msg)
+	}
+
+	req = httptest.NewRequest("GET", "/api/v1/status/"+resp.ID, nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	var status StatusResponse
+	json.NewDecoder(w.Body).Decode(&status)
+	if status.Priority != "high" {
+		t.Errorf("status Priority = %q, want high", status.Priority)
+	}
+
+	// Invalid priority is rejected
+	body = `{"from": "sender@example.com", "to": ["recipient@example.com"], "subject": "Hi", "priority": "urgent"}`
+	req = httptest.NewRequest("POST", "/api/v1/send", bytes.NewBufferString(body))
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Status = %d, want %d for invalid priority", w.Code, http.StatusBadRequest)
+	}
+}
+
 func TestSendEndpointValidation(t *testing.T) {
 	server, _ := setupTestServer("test-api-key")
 
@@ -252,6 +438,8 @@ func TestStatusEndpoint(t *testing.T) {
 		To:     []string{"c@d.com"},
 		Status: queue.StatusDelivered,
 	}
+	q.messages["test-id"].SetResult("c@d.com", queue.RecipientResult{Status: queue.StatusDelivered, MXHost: "mx.d.com"})
+	q.messages["test-id"].RecordAttempt(queue.DeliveryAttempt{Recipient: "c@d.com", MXHost: "mx.d.com", Success: true})
 
 	req := httptest.NewRequest("GET", "/api/v1/status/test-id", nil)
 	req.Header.Set("Authorization", "Bearer test-key")
@@ -272,6 +460,12 @@ func TestStatusEndpoint(t *testing.T) {
 	if resp.Status != "delivered" {
 		t.Errorf("Status = %q, want %q", resp.Status, "delivered")
 	}
+	if r := resp.Recipients["c@d.com"]; r == nil || r.MXHost != "mx.d.com" {
+		t.Errorf("Recipients = %+v, want c@d.com delivered via mx.d.com", resp.Recipients)
+	}
+	if len(resp.Attempts) != 1 {
+		t.Errorf("Attempts = %d, want 1", len(resp.Attempts))
+	}
 }
 
 func TestStatusEndpointNotFound(t *testing.T) {
@@ -292,7 +486,8 @@ func TestQueueEndpoint(t *testing.T) {
 	server, q := setupTestServer("test-key")
 
 	// Add some messages
-	q.messages["1"] = &queue.Message{ID: "1", Status: queue.StatusPending}
+	q.messages["1"] = &queue.Message{ID: "1", Status: queue.StatusPending,
+		Data: []byte("Subject: =?UTF-8?B?0J/RgNC40LLQtdGC?=\r\n\r\nBody")}
 	q.messages["2"] = &queue.Message{ID: "2", Status: queue.StatusDelivered}
 
 	req := httptest.NewRequest("GET", "/api/v1/queue", nil)
@@ -311,6 +506,50 @@ func TestQueueEndpoint(t *testing.T) {
 	if resp.Stats.Total != 2 {
 		t.Errorf("Stats.Total = %d, want 2", resp.Stats.Total)
 	}
+	for _, m := range resp.Messages {
+		if m.ID == "1" && m.Subject != "Привет" {
+			t.Errorf("Subject = %q, want decoded subject", m.Subject)
+		}
+	}
+}
+
+func TestQueueEndpointFilter(t *testing.T) {
+	server, q := setupTestServer("test-key")
+
+	q.messages["1"] = &queue.Message{ID: "1", From: "a@shop.example", Status: queue.StatusPending}
+	q.messages["2"] = &queue.Message{ID: "2", From: "b@other.example", Status: queue.StatusPending}
+
+	tests := []struct {
+		query    string
+		wantCode int
+		wantIDs  int
+		cursor   bool
+	}{
+		{"?sender_domain=shop.example", http.StatusOK, 1, false},
+		{"?domain=shop.example&limit=1", http.StatusOK, 1, true},
+		{"?status=bogus", http.StatusBadRequest, 0, false},
+		{"?since=yesterday", http.StatusBadRequest, 0, false},
+		{"?limit=0", http.StatusBadRequest, 0, false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/api/v1/queue"+tt.query, nil)
+		req.Header.Set("Authorization", "Bearer test-key")
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+
+		if w.Code != tt.wantCode {
+			t.Errorf("%s: Status = %d, want %d", tt.query, w.Code, tt.wantCode)
+			continue
+		}
+		if w.Code != http.StatusOK {
+			continue
+		}
+		var resp QueueResponse
+		json.NewDecoder(w.Body).Decode(&resp)
+		if len(resp.Messages) != tt.wantIDs || (resp.NextCursor != "") != tt.cursor {
+			t.Errorf("%s: %d messages, next_cursor %q", tt.query, len(resp.Messages), resp.NextCursor)
+		}
+	}
 }
 
 func TestDeleteEndpoint(t *testing.T) {
@@ -333,6 +572,38 @@ func TestDeleteEndpoint(t *testing.T) {
 	}
 }
 
+func TestRetryEndpoint(t *testing.T) {
+	server, q := setupTestServer("test-key")
+
+	q.messages["deferred"] = &queue.Message{ID: "deferred", Status: queue.StatusDeferred, RetryCount: 3,
+		LastError: "451 try later", NextRetryAt: time.Now().Add(time.Hour)}
+	q.messages["delivered"] = &queue.Message{ID: "delivered", Status: queue.StatusDelivered}
+
+	tests := []struct {
+		id       string
+		wantCode int
+	}{
+		{"deferred", http.StatusOK},
+		{"delivered", http.StatusConflict},
+		{"missing", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "/api/v1/queue/"+tt.id+"/retry", nil)
+		req.Header.Set("Authorization", "Bearer test-key")
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+
+		if w.Code != tt.wantCode {
+			t.Errorf("%s: Status = %d, want %d", tt.id, w.Code, tt.wantCode)
+		}
+	}
+
+	msg := q.messages["deferred"]
+	if msg.Status != queue.StatusPending || msg.RetryCount != 0 || msg.LastError != "" || !msg.NextRetryAt.IsZero() {
+		t.Errorf("retried message = %+v", msg)
+	}
+}
+
 func TestSendWithCCAndBCC(t *testing.T) {
 	server, q := setupTestServer("test-api-key")
 
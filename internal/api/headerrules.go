@@ -0,0 +1,190 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/foxzi/sendry/internal/dnscheck"
+	"github.com/foxzi/sendry/internal/headers"
+)
+
+// HeaderRulesServer handles header rule set API endpoints
+type HeaderRulesServer struct {
+	storage   *headers.Storage
+	processor *headers.Processor
+}
+
+// NewHeaderRulesServer creates a new header rules server. The processor
+// previews the rules delivery applies, it may be nil.
+func NewHeaderRulesServer(storage *headers.Storage, processor *headers.Processor) *HeaderRulesServer {
+	return &HeaderRulesServer{
+		storage:   storage,
+		processor: processor,
+	}
+}
+
+// RegisterRoutes registers header rules API routes
+func (s *HeaderRulesServer) RegisterRoutes(r chi.Router) {
+	r.Route("/headerrules", func(r chi.Router) {
+		r.Get("/", s.handleList)
+		r.Post("/preview", s.handlePreview)
+		r.Get("/{domain}", s.handleGet)
+		r.Put("/{domain}", s.handlePut)
+		r.Delete("/{domain}", s.handleDelete)
+	})
+}
+
+// HeaderRulesRequest is the request for creating or replacing the rule set
+// of a domain
+type HeaderRulesRequest struct {
+	Rules []headers.Rule `json:"rules"`
+}
+
+// HeaderRulesListResponse is the response for listing rule sets
+type HeaderRulesListResponse struct {
+	RuleSets []*headers.RuleSet `json:"rule_sets"`
+	Total    int                `json:"total"`
+}
+
+// HeaderRulesPreviewRequest is a message to apply header rules to
+type HeaderRulesPreviewRequest struct {
+	From string   `json:"from"`
+	To   []string `json:"to,omitempty"`
+	Data string   `json:"data"` // Raw message, only its header is changed
+
+	// Rules to try, the rules delivery applies to the sender if missing
+	Rules []headers.Rule `json:"rules,omitempty"`
+}
+
+// HeaderRulesPreviewResponse is the message with the rules applied
+type HeaderRulesPreviewResponse struct {
+	Data string `json:"data"`
+}
+
+// handleList handles GET /api/v1/headerrules
+func (s *HeaderRulesServer) handleList(w http.ResponseWriter, r *http.Request) {
+	sets, err := s.storage.List(r.Context())
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to list header rules")
+		return
+	}
+
+	if sets == nil {
+		sets = []*headers.RuleSet{}
+	}
+
+	sendJSON(w, http.StatusOK, HeaderRulesListResponse{
+		RuleSets: sets,
+		Total:    len(sets),
+	})
+}
+
+// handleGet handles GET /api/v1/headerrules/{domain}
+func (s *HeaderRulesServer) handleGet(w http.ResponseWriter, r *http.Request) {
+	domain := chi.URLParam(r, "domain")
+
+	set, err := s.storage.Get(r.Context(), domain)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to get header rules")
+		return
+	}
+
+	if set == nil {
+		sendError(w, http.StatusNotFound, "Header rules not found")
+		return
+	}
+
+	sendJSON(w, http.StatusOK, set)
+}
+
+// handlePut handles PUT /api/v1/headerrules/{domain}
+func (s *HeaderRulesServer) handlePut(w http.ResponseWriter, r *http.Request) {
+	domain := headers.NormalizeDomain(chi.URLParam(r, "domain"))
+	if err := dnscheck.ValidateDomain(domain); err != nil {
+		sendError(w, http.StatusBadRequest, fmt.Sprintf("invalid domain: %s", domain))
+		return
+	}
+
+	var req HeaderRulesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if len(req.Rules) == 0 {
+		sendError(w, http.StatusBadRequest, "rules are required, delete the rule set to remove all rules")
+		return
+	}
+	if err := headers.ValidateRules(req.Rules); err != nil {
+		sendError(w, http.StatusBadRequest, "rules"+err.Error())
+		return
+	}
+
+	before, err := s.storage.Get(r.Context(), domain)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to get header rules")
+		return
+	}
+
+	set := &headers.RuleSet{Domain: domain, Rules: req.Rules}
+	if err := s.storage.Put(r.Context(), set); err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to save header rules")
+		return
+	}
+
+	recordChange(r, before, set)
+	sendJSON(w, http.StatusOK, set)
+}
+
+// handleDelete handles DELETE /api/v1/headerrules/{domain}
+func (s *HeaderRulesServer) handleDelete(w http.ResponseWriter, r *http.Request) {
+	domain := chi.URLParam(r, "domain")
+
+	before, err := s.storage.Get(r.Context(), domain)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to get header rules")
+		return
+	}
+
+	if err := s.storage.Delete(r.Context(), domain); err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to delete header rules")
+		return
+	}
+
+	recordChange(r, before, nil)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlePreview handles POST /api/v1/headerrules/preview
+func (s *HeaderRulesServer) handlePreview(w http.ResponseWriter, r *http.Request) {
+	var req HeaderRulesPreviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Data == "" {
+		sendError(w, http.StatusBadRequest, "data is required")
+		return
+	}
+	if err := headers.ValidateRules(req.Rules); err != nil {
+		sendError(w, http.StatusBadRequest, "rules"+err.Error())
+		return
+	}
+
+	env := headers.Envelope{From: req.From, To: req.To}
+	var data []byte
+	switch {
+	case len(req.Rules) > 0:
+		data = headers.Preview([]byte(req.Data), req.Rules, env)
+	case s.processor != nil:
+		data = s.processor.ProcessMessage([]byte(req.Data), env)
+	default:
+		data = []byte(req.Data)
+	}
+
+	sendJSON(w, http.StatusOK, HeaderRulesPreviewResponse{Data: string(data)})
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/foxzi/sendry/internal/config"
+	"github.com/foxzi/sendry/internal/headers"
+)
+
+func TestHeaderRulesAPI(t *testing.T) {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	storage, err := headers.NewStorage(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	processor := headers.NewProcessor(nil)
+	processor.SetStorage(storage)
+
+	server := NewServerWithOptions(ServerOptions{
+		Queue:             newMockQueue(),
+		Config:            &config.APIConfig{ListenAddr: ":8080"},
+		Logger:            slog.New(slog.NewTextHandler(io.Discard, nil)),
+		HeaderRuleStorage: storage,
+		HeaderProcessor:   processor,
+	})
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+
+	rules := `{"rules": [{"action": "add", "header": "X-Team", "value": "billing", "when": {"recipient": "@example\\.org$"}}]}`
+	if w := do("PUT", "/api/v1/headerrules/Example.com", rules); w.Code != http.StatusOK {
+		t.Fatalf("put status = %d: %s", w.Code, w.Body.String())
+	}
+
+	w := do("GET", "/api/v1/headerrules/example.com", "")
+	var set headers.RuleSet
+	if err := json.NewDecoder(w.Body).Decode(&set); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if w.Code != http.StatusOK || set.Domain != "example.com" || len(set.Rules) != 1 {
+		t.Errorf("get status = %d, rule set = %+v", w.Code, set)
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		body   string
+		status int
+	}{
+		{"invalid regex", "/api/v1/headerrules/example.com", `{"rules": [{"action": "remove", "headers": ["X-A"], "pattern": "("}]}`, http.StatusBadRequest},
+		{"unknown action", "/api/v1/headerrules/example.com", `{"rules": [{"action": "rename", "header": "X-A"}]}`, http.StatusBadRequest},
+		{"no rules", "/api/v1/headerrules/example.com", `{"rules": []}`, http.StatusBadRequest},
+		{"invalid domain", "/api/v1/headerrules/not_a_domain", rules, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := do("PUT", tt.path, tt.body); w.Code != tt.status {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+		})
+	}
+
+	const msg = "From: a@example.com\r\nSubject: Hi\r\n\r\nBody\r\n"
+	preview := func(to string) string {
+		body, _ := json.Marshal(HeaderRulesPreviewRequest{From: "a@example.com", To: []string{to}, Data: msg})
+		w := do("POST", "/api/v1/headerrules/preview", string(body))
+		var resp HeaderRulesPreviewResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("preview status = %d, err = %v", w.Code, err)
+		}
+		return resp.Data
+	}
+	if data := preview("user@example.org"); !strings.Contains(data, "X-Team: billing") {
+		t.Errorf("preview for matching recipient lacks the header:\n%s", data)
+	}
+	if data := preview("user@example.net"); strings.Contains(data, "X-Team") {
+		t.Errorf("preview for other recipient has the header:\n%s", data)
+	}
+
+	if w := do("DELETE", "/api/v1/headerrules/example.com", ""); w.Code != http.StatusNoContent {
+		t.Errorf("delete status = %d", w.Code)
+	}
+	if w := do("GET", "/api/v1/headerrules/example.com", ""); w.Code != http.StatusNotFound {
+		t.Errorf("get after delete status = %d, want 404", w.Code)
+	}
+}
@@ -0,0 +1,154 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+
+	"github.com/foxzi/sendry/internal/idempotency"
+)
+
+// ReplayedHeader marks a response replayed for a repeated idempotency key
+const ReplayedHeader = "Idempotent-Replayed"
+
+// ctxKeyIdempotency holds the *idempotentRequest of a raw message, which the
+// handler claims once it has read and hashed the body
+const ctxKeyIdempotency ctxKey = "idempotency"
+
+// idempotentRequest is a submission sent with an Idempotency-Key
+type idempotentRequest struct {
+	scope   string
+	claimed bool
+}
+
+// idempotentRequestFromContext returns the Idempotency-Key of a raw message
+// that the handler has to claim, nil without a key
+func idempotentRequestFromContext(ctx context.Context) *idempotentRequest {
+	req, _ := ctx.Value(ctxKeyIdempotency).(*idempotentRequest)
+	return req
+}
+
+// idempotencyMiddleware returns the stored response when a message
+// submission is retried with the same Idempotency-Key header
+func (s *Server) idempotencyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotency.Header)
+		if key == "" || !isIdempotent(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !idempotency.ValidKey(key) {
+			s.sendError(w, http.StatusBadRequest, "Idempotency-Key must be 1 to 255 printable characters")
+			return
+		}
+
+		idem := &idempotentRequest{scope: idempotencyScope(r, key)}
+		var resp bytes.Buffer
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		ww.Tee(&resp)
+
+		if r.URL.Path == "/api/v1/send/raw" {
+			// Raw messages are hashed by the handler while it reads them,
+			// so the body is not read into memory twice
+			next.ServeHTTP(ww, r.WithContext(context.WithValue(r.Context(), ctxKeyIdempotency, idem)))
+		} else {
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				s.sendError(w, http.StatusBadRequest, "Invalid request body")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			sum := sha256.Sum256(body)
+			if !s.claimIdempotency(r.Context(), w, idem, sum[:]) {
+				return
+			}
+			next.ServeHTTP(ww, r)
+		}
+		if !idem.claimed {
+			return
+		}
+
+		var err error
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		// Only accepted submissions are remembered, failed ones may be retried
+		if status >= 200 && status < 300 {
+			err = s.idempotencyStorage.Complete(context.Background(), idem.scope, &idempotency.Record{
+				Status:      status,
+				ContentType: ww.Header().Get("Content-Type"),
+				Body:        resp.Bytes(),
+			})
+		} else {
+			err = s.idempotencyStorage.Release(context.Background(), idem.scope)
+		}
+		if err != nil {
+			s.logger.Error("failed to store idempotency key", "error", err)
+		}
+	})
+}
+
+// claimIdempotency claims the Idempotency-Key of a request with the hash of
+// its body. It returns false when the request must not be processed, after
+// replaying the stored response or sending an error.
+func (s *Server) claimIdempotency(ctx context.Context, w http.ResponseWriter, idem *idempotentRequest, sum []byte) bool {
+	state, rec, err := s.idempotencyStorage.Claim(ctx, idem.scope, hex.EncodeToString(sum))
+	if err != nil {
+		s.logger.Error("failed to check idempotency key", "error", err)
+		s.sendError(w, http.StatusInternalServerError, "Failed to check idempotency key")
+		return false
+	}
+
+	switch state {
+	case idempotency.Completed:
+		if rec.ContentType != "" {
+			w.Header().Set("Content-Type", rec.ContentType)
+		}
+		w.Header().Set(ReplayedHeader, "true")
+		w.WriteHeader(rec.Status)
+		w.Write(rec.Body)
+		return false
+	case idempotency.InProgress:
+		s.sendError(w, http.StatusConflict, "A request with this Idempotency-Key is in progress")
+		return false
+	case idempotency.Mismatch:
+		s.sendError(w, http.StatusUnprocessableEntity, "Idempotency-Key was used for a different request")
+		return false
+	}
+	idem.claimed = true
+	return true
+}
+
+// idempotencyScope returns the storage key of an Idempotency-Key. Keys are
+// scoped to the API key that sent them and to the endpoint, so clients
+// choosing the same key never see each other's responses. Requests
+// authenticated by api.api_key or sent without authentication share one
+// scope.
+func idempotencyScope(r *http.Request, key string) string {
+	var owner string
+	if k := apiKeyFromContext(r.Context()); k != nil {
+		owner = k.ID
+	}
+	return owner + "\x00" + r.URL.Path + "\x00" + key
+}
+
+// isIdempotent reports whether a request is a message submission that
+// supports idempotency keys
+func isIdempotent(r *http.Request) bool {
+	if r.Method != http.MethodPost {
+		return false
+	}
+	switch r.URL.Path {
+	case "/api/v1/send", "/api/v1/send/batch", "/api/v1/send/raw", "/api/v1/send/template":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,244 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/foxzi/sendry/internal/apikey"
+	"github.com/foxzi/sendry/internal/config"
+	"github.com/foxzi/sendry/internal/idempotency"
+)
+
+func setupIdempotencyServer(t *testing.T) (*Server, *mockQueue) {
+	t.Helper()
+
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	storage, err := idempotency.NewStorage(db)
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+
+	q := newMockQueue()
+	return NewServerWithOptions(ServerOptions{
+		Queue:              q,
+		Config:             &config.APIConfig{ListenAddr: ":8080"},
+		Logger:             slog.New(slog.NewTextHandler(io.Discard, nil)),
+		IdempotencyStorage: storage,
+	}), q
+}
+
+func sendWithKey(server *Server, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/api/v1/send", bytes.NewBufferString(body))
+	if key != "" {
+		req.Header.Set(idempotency.Header, key)
+	}
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestSendIdempotencyKey(t *testing.T) {
+	server, q := setupIdempotencyServer(t)
+	body := `{"from": "sender@example.com", "to": ["a@example.com"], "subject": "Hi", "body": "Hello"}`
+
+	first := sendWithKey(server, "order-42", body)
+	if first.Code != http.StatusAccepted {
+		t.Fatalf("Status = %d, want %d. Body: %s", first.Code, http.StatusAccepted, first.Body.String())
+	}
+
+	retry := sendWithKey(server, "order-42", body)
+	if retry.Code != http.StatusAccepted {
+		t.Fatalf("retry status = %d, want %d. Body: %s", retry.Code, http.StatusAccepted, retry.Body.String())
+	}
+	if retry.Header().Get(ReplayedHeader) != "true" {
+		t.Errorf("retry lacks %s header", ReplayedHeader)
+	}
+
+	var a, b SendResponse
+	json.Unmarshal(first.Body.Bytes(), &a)
+	json.Unmarshal(retry.Body.Bytes(), &b)
+	if a.ID == "" || a.ID != b.ID {
+		t.Errorf("retry ID = %q, want %q", b.ID, a.ID)
+	}
+	if len(q.messages) != 1 {
+		t.Errorf("enqueued %d messages, want 1", len(q.messages))
+	}
+
+	// A different request with the same key is refused
+	w := sendWithKey(server, "order-42", `{"from": "sender@example.com", "to": ["b@example.com"], "subject": "Hi", "body": "Hello"}`)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("reused key status = %d, want %d", w.Code, http.StatusUnprocessableEntity)
+	}
+
+	// Requests without a key are not deduplicated
+	sendWithKey(server, "", body)
+	if len(q.messages) != 2 {
+		t.Errorf("enqueued %d messages, want 2", len(q.messages))
+	}
+}
+
+func TestSendIdempotencyKeyFailedRequest(t *testing.T) {
+	server, q := setupIdempotencyServer(t)
+
+	// Rejected requests are not remembered and can be retried
+	w := sendWithKey(server, "order-43", `{"from": "sender@example.com", "subject": "Hi"}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	w = sendWithKey(server, "order-43", `{"from": "sender@example.com", "to": ["a@example.com"], "subject": "Hi", "body": "Hello"}`)
+	if w.Code != http.StatusAccepted || len(q.messages) != 1 {
+		t.Errorf("retry status = %d, enqueued %d. Body: %s", w.Code, len(q.messages), w.Body.String())
+	}
+
+	if w := sendWithKey(server, "bad\x01key", `{}`); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid key status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestIdempotencyKeyBatchAndRaw(t *testing.T) {
+	server, q := setupIdempotencyServer(t)
+
+	post := func(path, contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set(idempotency.Header, "import-7")
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	batch := `{"messages": [{"from": "a@example.com", "to": ["x@example.com"], "subject": "s", "body": "b"}]}`
+	for i := 0; i < 2; i++ {
+		if w := post("/api/v1/send/batch", "application/json", batch); w.Code != http.StatusAccepted {
+			t.Fatalf("batch %d status = %d. Body: %s", i, w.Code, w.Body.String())
+		}
+	}
+	if len(q.messages) != 1 {
+		t.Errorf("batch retried: enqueued %d messages, want 1", len(q.messages))
+	}
+
+	// The same key on another endpoint is a different request
+	for i := 0; i < 2; i++ {
+		if w := post("/api/v1/send/raw", rawMediaType, rawTestMessage); w.Code != http.StatusAccepted {
+			t.Fatalf("raw %d status = %d. Body: %s", i, w.Code, w.Body.String())
+		}
+	}
+	if len(q.messages) != 2 {
+		t.Errorf("raw retried: enqueued %d messages, want 2", len(q.messages))
+	}
+}
+
+func TestIdempotencyKeyRaw(t *testing.T) {
+	server, q := setupIdempotencyServer(t)
+	server.fullConfig = &config.Config{SMTP: config.SMTPConfig{MaxMessageBytes: 256}}
+
+	post := func(body io.Reader) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/v1/send/raw", body)
+		req.Header.Set("Content-Type", rawMediaType)
+		req.Header.Set(idempotency.Header, "raw-1")
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	// A body over the limit, chunked so it is only refused while read,
+	// does not claim the key
+	large := io.MultiReader(strings.NewReader(rawTestMessage), strings.NewReader(strings.Repeat("x", 512)))
+	if w := post(large); w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("large status = %d, want %d. Body: %s", w.Code, http.StatusRequestEntityTooLarge, w.Body.String())
+	}
+
+	w := post(strings.NewReader(rawTestMessage))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("first status = %d. Body: %s", w.Code, w.Body.String())
+	}
+	w = post(strings.NewReader(rawTestMessage))
+	if w.Code != http.StatusAccepted || w.Header().Get(ReplayedHeader) != "true" {
+		t.Errorf("retry status = %d, replayed = %q", w.Code, w.Header().Get(ReplayedHeader))
+	}
+	if len(q.messages) != 1 {
+		t.Errorf("enqueued %d messages, want 1", len(q.messages))
+	}
+
+	if w := post(strings.NewReader(rawTestMessage + "changed\r\n")); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("other body status = %d, want %d", w.Code, http.StatusUnprocessableEntity)
+	}
+}
+
+func TestIdempotencyKeyScopedToAPIKey(t *testing.T) {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	storage, err := idempotency.NewStorage(db)
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+	keys, err := apikey.NewStorage(db)
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+	q := newMockQueue()
+	server := NewServerWithOptions(ServerOptions{
+		Queue:              q,
+		Config:             &config.APIConfig{ListenAddr: ":8080"},
+		Logger:             slog.New(slog.NewTextHandler(io.Discard, nil)),
+		IdempotencyStorage: storage,
+		APIKeyStorage:      keys,
+	})
+
+	_, tokenA, err := keys.Create(t.Context(), "tenant-a", []apikey.Scope{apikey.ScopeSend}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, tokenB, err := keys.Create(t.Context(), "tenant-b", []apikey.Scope{apikey.ScopeSend}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	send := func(token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/v1/send", bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set(idempotency.Header, "order-1")
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	a := send(tokenA, testSendBody)
+	b := send(tokenB, testSendBody)
+	if a.Code != http.StatusAccepted || b.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, %d. Bodies: %s %s", a.Code, b.Code, a.Body.String(), b.Body.String())
+	}
+	if b.Header().Get(ReplayedHeader) != "" {
+		t.Error("second API key got the response of the first one")
+	}
+	var ra, rb SendResponse
+	json.Unmarshal(a.Body.Bytes(), &ra)
+	json.Unmarshal(b.Body.Bytes(), &rb)
+	if ra.ID == rb.ID || len(q.messages) != 2 {
+		t.Errorf("IDs %q and %q, enqueued %d messages, want 2 distinct", ra.ID, rb.ID, len(q.messages))
+	}
+
+	// Within one API key a reused key still needs the same body
+	other := `{"from": "sender@example.com", "to": ["c@example.com"], "subject": "Hi", "body": "Hello"}`
+	if w := send(tokenB, other); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("reuse within one API key status = %d, want %d", w.Code, http.StatusUnprocessableEntity)
+	}
+}
@@ -0,0 +1,387 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/foxzi/sendry/internal/maillist"
+	"github.com/foxzi/sendry/internal/queue"
+)
+
+// ListServer handles mailing list API endpoints
+type ListServer struct {
+	storage *maillist.Storage
+	queue   queue.Queue
+}
+
+// NewListServer creates a new mailing list server
+func NewListServer(storage *maillist.Storage, q queue.Queue) *ListServer {
+	return &ListServer{
+		storage: storage,
+		queue:   q,
+	}
+}
+
+// RegisterRoutes registers mailing list API routes
+func (s *ListServer) RegisterRoutes(r chi.Router) {
+	r.Route("/lists", func(r chi.Router) {
+		r.Get("/", s.handleList)
+		r.Post("/", s.handleCreate)
+		r.Get("/{id}", s.handleGet)
+		r.Put("/{id}", s.handleUpdate)
+		r.Delete("/{id}", s.handleDelete)
+		r.Post("/{id}/members", s.handleAddMembers)
+		r.Delete("/{id}/members/{member}", s.handleRemoveMember)
+		r.Get("/{id}/messages/{message_id}", s.handleDeliveries)
+	})
+}
+
+// ListRequest is the request for creating or updating a mailing list
+type ListRequest struct {
+	Address        string   `json:"address,omitempty"`
+	Name           string   `json:"name,omitempty"`
+	Members        []string `json:"members,omitempty"`
+	SubjectPrefix  string   `json:"subject_prefix,omitempty"`
+	SenderPolicy   string   `json:"sender_policy,omitempty"`
+	AllowedSenders []string `json:"allowed_senders,omitempty"`
+	Enabled        *bool    `json:"enabled,omitempty"`
+}
+
+// ListMembersRequest is the request for adding list members
+type ListMembersRequest struct {
+	Members []string `json:"members"`
+}
+
+// ListListResponse is the response for listing mailing lists
+type ListListResponse struct {
+	Lists []*maillist.List `json:"lists"`
+	Total int              `json:"total"`
+}
+
+// ListMemberDelivery is the delivery status of a list message for one member
+type ListMemberDelivery struct {
+	Member     string `json:"member"`
+	MessageID  string `json:"message_id"`
+	Status     string `json:"status"`
+	RetryCount int    `json:"retry_count,omitempty"`
+	LastError  string `json:"last_error,omitempty"`
+}
+
+// ListDeliveriesResponse is the response for per-member delivery tracking
+type ListDeliveriesResponse struct {
+	MessageID  string                `json:"message_id"`
+	ListID     string                `json:"list_id"`
+	ExpandedAt time.Time             `json:"expanded_at"`
+	Deliveries []*ListMemberDelivery `json:"deliveries"`
+}
+
+// handleList handles GET /api/v1/lists
+func (s *ListServer) handleList(w http.ResponseWriter, r *http.Request) {
+	filter := maillist.ListFilter{
+		Domain: r.URL.Query().Get("domain"),
+	}
+
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if limit, err := strconv.Atoi(limitStr); err == nil && limit > 0 {
+			filter.Limit = limit
+			if filter.Limit > 1000 {
+				filter.Limit = 1000 // Prevent DoS via excessive limit
+			}
+		}
+	}
+
+	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
+		if offset, err := strconv.Atoi(offsetStr); err == nil && offset > 0 {
+			filter.Offset = offset
+		}
+	}
+
+	lists, err := s.storage.List(r.Context(), filter)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to list mailing lists")
+		return
+	}
+
+	if lists == nil {
+		lists = []*maillist.List{}
+	}
+
+	sendJSON(w, http.StatusOK, ListListResponse{
+		Lists: lists,
+		Total: len(lists),
+	})
+}
+
+// handleCreate handles POST /api/v1/lists
+func (s *ListServer) handleCreate(w http.ResponseWriter, r *http.Request) {
+	var req ListRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Address == "" {
+		sendError(w, http.StatusBadRequest, "address is required")
+		return
+	}
+
+	list := &maillist.List{Enabled: true}
+	applyListRequest(list, &req)
+
+	if msg := validateList(list); msg != "" {
+		sendError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	if err := s.storage.Create(r.Context(), list); err != nil {
+		if strings.Contains(err.Error(), "already exists") {
+			sendError(w, http.StatusConflict, err.Error())
+			return
+		}
+		sendError(w, http.StatusInternalServerError, "Failed to create mailing list")
+		return
+	}
+
+	sendJSON(w, http.StatusCreated, list)
+}
+
+// handleGet handles GET /api/v1/lists/{id}
+func (s *ListServer) handleGet(w http.ResponseWriter, r *http.Request) {
+	list, ok := s.getList(w, r)
+	if !ok {
+		return
+	}
+
+	sendJSON(w, http.StatusOK, list)
+}
+
+// handleUpdate handles PUT /api/v1/lists/{id}
+func (s *ListServer) handleUpdate(w http.ResponseWriter, r *http.Request) {
+	var req ListRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	list, ok := s.getList(w, r)
+	if !ok {
+		return
+	}
+
+	applyListRequest(list, &req)
+	s.save(w, r, list)
+}
+
+// handleDelete handles DELETE /api/v1/lists/{id}
+func (s *ListServer) handleDelete(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	if err := s.storage.Delete(r.Context(), id); err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to delete mailing list")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleAddMembers handles POST /api/v1/lists/{id}/members
+func (s *ListServer) handleAddMembers(w http.ResponseWriter, r *http.Request) {
+	var req ListMembersRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if len(req.Members) == 0 {
+		sendError(w, http.StatusBadRequest, "members is required")
+		return
+	}
+
+	list, ok := s.getList(w, r)
+	if !ok {
+		return
+	}
+
+	list.Members = append(list.Members, req.Members...)
+	s.save(w, r, list)
+}
+
+// handleRemoveMember handles DELETE /api/v1/lists/{id}/members/{member}
+func (s *ListServer) handleRemoveMember(w http.ResponseWriter, r *http.Request) {
+	member := maillist.NormalizeAddress(chi.URLParam(r, "member"))
+
+	list, ok := s.getList(w, r)
+	if !ok {
+		return
+	}
+
+	if !list.IsMember(member) {
+		sendError(w, http.StatusNotFound, "Member not found")
+		return
+	}
+
+	members := make([]string, 0, len(list.Members))
+	for _, m := range list.Members {
+		if maillist.NormalizeAddress(m) != member {
+			members = append(members, m)
+		}
+	}
+	list.Members = members
+	s.save(w, r, list)
+}
+
+// handleDeliveries handles GET /api/v1/lists/{id}/messages/{message_id}
+func (s *ListServer) handleDeliveries(w http.ResponseWriter, r *http.Request) {
+	list, ok := s.getList(w, r)
+	if !ok {
+		return
+	}
+
+	messageID := chi.URLParam(r, "message_id")
+	exp, err := s.storage.GetExpansion(r.Context(), list.ID, messageID)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to get list deliveries")
+		return
+	}
+	if exp == nil {
+		sendError(w, http.StatusNotFound, "Message was not expanded by this list")
+		return
+	}
+
+	members := make([]string, 0, len(exp.Members))
+	for member := range exp.Members {
+		members = append(members, member)
+	}
+	sort.Strings(members)
+
+	resp := ListDeliveriesResponse{
+		MessageID:  exp.MessageID,
+		ListID:     exp.ListID,
+		ExpandedAt: exp.CreatedAt,
+		Deliveries: make([]*ListMemberDelivery, 0, len(members)),
+	}
+
+	for _, member := range members {
+		delivery := &ListMemberDelivery{
+			Member:    member,
+			MessageID: exp.Members[member],
+			Status:    "unknown", // Removed by retention cleanup
+		}
+
+		msg, err := s.queue.Get(r.Context(), delivery.MessageID)
+		if err != nil {
+			sendError(w, http.StatusInternalServerError, "Failed to get message")
+			return
+		}
+		if msg != nil {
+			delivery.Status = string(msg.Status)
+			delivery.RetryCount = msg.RetryCount
+			delivery.LastError = msg.LastError
+		}
+
+		resp.Deliveries = append(resp.Deliveries, delivery)
+	}
+
+	sendJSON(w, http.StatusOK, resp)
+}
+
+// getList loads the list from the {id} URL parameter (ID or address)
+func (s *ListServer) getList(w http.ResponseWriter, r *http.Request) (*maillist.List, bool) {
+	id := chi.URLParam(r, "id")
+
+	list, err := s.storage.Get(r.Context(), id)
+	if err == nil && list == nil {
+		list, err = s.storage.GetByAddress(r.Context(), id)
+	}
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to get mailing list")
+		return nil, false
+	}
+
+	if list == nil {
+		sendError(w, http.StatusNotFound, "Mailing list not found")
+		return nil, false
+	}
+
+	return list, true
+}
+
+// save validates and stores an updated list, then writes the response
+func (s *ListServer) save(w http.ResponseWriter, r *http.Request, list *maillist.List) {
+	if msg := validateList(list); msg != "" {
+		sendError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	if err := s.storage.Update(r.Context(), list); err != nil {
+		if strings.Contains(err.Error(), "already exists") {
+			sendError(w, http.StatusConflict, err.Error())
+			return
+		}
+		sendError(w, http.StatusInternalServerError, "Failed to update mailing list")
+		return
+	}
+
+	sendJSON(w, http.StatusOK, list)
+}
+
+// applyListRequest copies the fields set in the request to the list
+func applyListRequest(list *maillist.List, req *ListRequest) {
+	if req.Address != "" {
+		list.Address = req.Address
+	}
+	if req.Name != "" {
+		list.Name = sanitizeHeaderValue(req.Name)
+	}
+	if req.Members != nil {
+		list.Members = req.Members
+	}
+	if req.SubjectPrefix != "" {
+		list.SubjectPrefix = sanitizeHeaderValue(req.SubjectPrefix)
+	}
+	if req.SenderPolicy != "" {
+		list.SenderPolicy = req.SenderPolicy
+	}
+	if req.AllowedSenders != nil {
+		list.AllowedSenders = req.AllowedSenders
+	}
+	if req.Enabled != nil {
+		list.Enabled = *req.Enabled
+	}
+}
+
+// validateList returns an error message if the list is invalid
+func validateList(list *maillist.List) string {
+	if _, err := mail.ParseAddress(list.Address); err != nil {
+		return fmt.Sprintf("invalid address: %s", list.Address)
+	}
+
+	for _, m := range list.Members {
+		if _, err := mail.ParseAddress(m); err != nil {
+			return fmt.Sprintf("invalid member address: %s", m)
+		}
+	}
+
+	if !maillist.ValidSenderPolicy(list.SenderPolicy) {
+		return fmt.Sprintf("invalid sender_policy: %s (must be anyone, members or allowed)", list.SenderPolicy)
+	}
+
+	for _, a := range list.AllowedSenders {
+		if strings.HasPrefix(a, "@") {
+			continue
+		}
+		if _, err := mail.ParseAddress(a); err != nil {
+			return fmt.Sprintf("invalid allowed sender: %s", a)
+		}
+	}
+
+	return ""
+}
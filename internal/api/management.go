@@ -3,19 +3,30 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 
 	"github.com/foxzi/sendry/internal/config"
 	"github.com/foxzi/sendry/internal/dkim"
+	"github.com/foxzi/sendry/internal/dnsbl"
 	"github.com/foxzi/sendry/internal/dnscheck"
+	"github.com/foxzi/sendry/internal/dnsmonitor"
 	"github.com/foxzi/sendry/internal/domain"
+	"github.com/foxzi/sendry/internal/queue"
 	"github.com/foxzi/sendry/internal/ratelimit"
+	"github.com/foxzi/sendry/internal/retry"
+	sendryTLS "github.com/foxzi/sendry/internal/tls"
 )
 
 // ManagementServer handles domain, DKIM, TLS, and rate limit management APIs
@@ -25,6 +36,16 @@ type ManagementServer struct {
 	config        *config.Config
 	dkimKeysDir   string
 	tlsCertsDir   string
+	reloader      ConfigReloader
+	pauses        *queue.Pauses
+	dnsChecks     *dnsmonitor.Storage
+	dnsbl         *dnsbl.Monitor
+	acme          *sendryTLS.ACMEManager
+	retries       *retry.Scheduler
+
+	// Rate limits of the last applied config, replaced on a reload while
+	// handlers read them
+	rateLimits atomic.Pointer[config.RateLimitConfig]
 }
 
 // NewManagementServer creates a new management server
@@ -35,13 +56,25 @@ func NewManagementServer(
 	dkimKeysDir string,
 	tlsCertsDir string,
 ) *ManagementServer {
-	return &ManagementServer{
+	if domainMgr == nil {
+		// Without a domain manager nothing is signed, the domains of cfg
+		// are still managed
+		domainMgr = domain.NewUnsignedManager(cfg, slog.Default())
+	}
+	m := &ManagementServer{
 		domainManager: domainMgr,
 		rateLimiter:   rateLimiter,
 		config:        cfg,
 		dkimKeysDir:   dkimKeysDir,
 		tlsCertsDir:   tlsCertsDir,
 	}
+	m.setRateLimits(cfg.RateLimit)
+	return m
+}
+
+// setRateLimits replaces the rate limits shown by the rate limit endpoints
+func (m *ManagementServer) setRateLimits(rl config.RateLimitConfig) {
+	m.rateLimits.Store(&rl)
 }
 
 // RegisterRoutes registers management API routes
@@ -59,6 +92,7 @@ func (m *ManagementServer) RegisterRoutes(r chi.Router) {
 	r.Route("/tls", func(r chi.Router) {
 		r.Get("/certificates", m.handleTLSList)
 		r.Post("/certificates", m.handleTLSUpload)
+		r.Post("/certificates/{domain}/renew", m.handleTLSRenew)
 		r.Post("/letsencrypt/{domain}", m.handleTLSLetsEncrypt)
 	})
 
@@ -69,6 +103,7 @@ func (m *ManagementServer) RegisterRoutes(r chi.Router) {
 		r.Get("/{domain}", m.handleDomainsGet)
 		r.Put("/{domain}", m.handleDomainsUpdate)
 		r.Delete("/{domain}", m.handleDomainsDelete)
+		r.Get("/{domain}/dns/history", m.handleDNSHistory)
 	})
 
 	// Rate limits management
@@ -87,7 +122,18 @@ func (m *ManagementServer) RegisterRoutes(r chi.Router) {
 	r.Route("/ip", func(r chi.Router) {
 		r.Get("/check/{ip}", m.handleIPCheck)
 		r.Get("/dnsbls", m.handleDNSBLList)
+		r.Get("/monitored", m.handleMonitoredIPList)
+		r.Post("/monitored", m.handleMonitoredIPAdd)
+		r.Delete("/monitored/{ip}", m.handleMonitoredIPDelete)
+		r.Get("/monitored/{ip}/history", m.handleMonitoredIPHistory)
+		r.Post("/monitored/{ip}/check", m.handleMonitoredIPCheck)
 	})
+
+	// Effective retry policies of a recipient domain
+	r.Get("/retry/{domain}", m.handleRetryPolicy)
+
+	// Config reload
+	r.Post("/config/reload", m.handleConfigReload)
 }
 
 // DKIM Handlers
@@ -148,13 +194,15 @@ func (m *ManagementServer) handleDKIMGenerate(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	sendJSON(w, http.StatusCreated, DKIMGenerateResponse{
+	resp := DKIMGenerateResponse{
 		Domain:    req.Domain,
 		Selector:  req.Selector,
 		DNSName:   keyPair.DNSName(),
 		DNSRecord: keyPair.DNSRecord(),
 		KeyFile:   keyFile,
-	})
+	}
+	recordChange(r, nil, resp)
+	sendJSON(w, http.StatusCreated, resp)
 }
 
 // DKIMUploadRequest is the request for POST /api/v1/dkim/upload
@@ -223,13 +271,16 @@ func (m *ManagementServer) handleDKIMUpload(w http.ResponseWriter, r *http.Reque
 		Selector:   req.Selector,
 	}
 
-	sendJSON(w, http.StatusCreated, DKIMGenerateResponse{
+	// The audit entry records where the key went, never the key itself
+	resp := DKIMGenerateResponse{
 		Domain:    req.Domain,
 		Selector:  req.Selector,
 		DNSName:   keyPair.DNSName(),
 		DNSRecord: keyPair.DNSRecord(),
 		KeyFile:   keyFile,
-	})
+	}
+	recordChange(r, nil, resp)
+	sendJSON(w, http.StatusCreated, resp)
 }
 
 // DKIMInfoResponse is the response for GET /api/v1/dkim/{domain}
@@ -264,7 +315,7 @@ func (m *ManagementServer) handleDKIMGet(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Check config for DKIM settings
-	enabled, selector, keyFile := m.config.GetDKIMConfig(domainName)
+	enabled, selector, keyFile := m.domainManager.GetDKIMConfig(domainName)
 	if enabled {
 		response.Enabled = true
 		response.Selector = selector
@@ -323,7 +374,7 @@ func (m *ManagementServer) handleDKIMVerify(w http.ResponseWriter, r *http.Reque
 	selector := r.URL.Query().Get("selector")
 	if selector == "" {
 		// Try to get from config
-		enabled, configSelector, _ := m.config.GetDKIMConfig(domainName)
+		enabled, configSelector, _ := m.domainManager.GetDKIMConfig(domainName)
 		if enabled {
 			selector = configSelector
 		} else {
@@ -347,7 +398,7 @@ func (m *ManagementServer) handleDKIMVerify(w http.ResponseWriter, r *http.Reque
 	}
 
 	// Check if key file exists (use sanitized values for path)
-	enabled, _, keyFile := m.config.GetDKIMConfig(domainName)
+	enabled, _, keyFile := m.domainManager.GetDKIMConfig(domainName)
 	if !enabled {
 		keyFile = filepath.Join(m.dkimKeysDir, safeDomain, safeSelector+".key")
 	}
@@ -402,17 +453,29 @@ func (m *ManagementServer) handleDKIMDelete(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	recordChange(r, map[string]string{"domain": domainName, "selector": safeSelector, "key_file": keyFile}, nil)
 	w.WriteHeader(http.StatusNoContent)
 }
 
 // TLS Handlers
 
+// acmeRenewTimeout bounds a forced ACME renewal
+const acmeRenewTimeout = 2 * time.Minute
+
 // TLSCertificateInfo represents TLS certificate information
 type TLSCertificateInfo struct {
 	Domain   string `json:"domain"`
-	CertFile string `json:"cert_file"`
-	KeyFile  string `json:"key_file"`
+	CertFile string `json:"cert_file,omitempty"` // Empty for ACME certificates
+	KeyFile  string `json:"key_file,omitempty"`
 	ACME     bool   `json:"acme"`
+
+	// Unset if the certificate cannot be read, Error says why
+	Issuer    string     `json:"issuer,omitempty"`
+	DNSNames  []string   `json:"dns_names,omitempty"`
+	NotBefore *time.Time `json:"not_before,omitempty"`
+	NotAfter  *time.Time `json:"not_after,omitempty"`
+	DaysLeft  *int       `json:"days_left,omitempty"`
+	Error     string     `json:"error,omitempty"`
 }
 
 // TLSListResponse is the response for GET /api/v1/tls/certificates
@@ -422,6 +485,19 @@ type TLSListResponse struct {
 	ACMEDomains  []string             `json:"acme_domains,omitempty"`
 }
 
+// newACMECertificateInfo returns the API representation of an ACME certificate
+func newACMECertificateInfo(c sendryTLS.CertificateInfo) TLSCertificateInfo {
+	return TLSCertificateInfo{
+		Domain:    c.Domain,
+		ACME:      true,
+		Issuer:    c.Issuer,
+		DNSNames:  c.DNSNames,
+		NotBefore: &c.NotBefore,
+		NotAfter:  &c.NotAfter,
+		DaysLeft:  &c.DaysLeft,
+	}
+}
+
 // handleTLSList handles GET /api/v1/tls/certificates
 func (m *ManagementServer) handleTLSList(w http.ResponseWriter, r *http.Request) {
 	response := TLSListResponse{
@@ -441,7 +517,7 @@ func (m *ManagementServer) handleTLSList(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Add domain-specific certificates
-	for domain, dc := range m.config.Domains {
+	for domain, dc := range m.domainManager.Domains() {
 		if dc.TLS != nil && dc.TLS.CertFile != "" {
 			response.Certificates = append(response.Certificates, TLSCertificateInfo{
 				Domain:   domain,
@@ -471,6 +547,43 @@ func (m *ManagementServer) handleTLSList(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
+	for i := range response.Certificates {
+		c := &response.Certificates[i]
+		info, err := sendryTLS.GetCertificateInfo(c.CertFile)
+		if err != nil {
+			c.Error = err.Error()
+			continue
+		}
+		c.Issuer = info.Issuer
+		c.DNSNames = info.DNSNames
+		c.NotBefore = &info.NotBefore
+		c.NotAfter = &info.NotAfter
+		c.DaysLeft = &info.DaysLeft
+	}
+
+	// Add ACME certificates from the cache
+	if m.acme != nil {
+		cached, err := m.acme.GetCachedCertificates()
+		if err != nil {
+			sendError(w, http.StatusInternalServerError, "Failed to read ACME certificates")
+			return
+		}
+		found := make(map[string]bool, len(cached))
+		for _, c := range cached {
+			found[c.Domain] = true
+			response.Certificates = append(response.Certificates, newACMECertificateInfo(c))
+		}
+		for _, domain := range m.acme.Domains() {
+			if !found[domain] {
+				response.Certificates = append(response.Certificates, TLSCertificateInfo{
+					Domain: domain,
+					ACME:   true,
+					Error:  "certificate not obtained yet",
+				})
+			}
+		}
+	}
+
 	sendJSON(w, http.StatusOK, response)
 }
 
@@ -522,12 +635,14 @@ func (m *ManagementServer) handleTLSUpload(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	sendJSON(w, http.StatusCreated, TLSCertificateInfo{
+	resp := TLSCertificateInfo{
 		Domain:   req.Domain,
 		CertFile: certFile,
 		KeyFile:  keyFile,
 		ACME:     false,
-	})
+	}
+	recordChange(r, nil, resp)
+	sendJSON(w, http.StatusCreated, resp)
 }
 
 // handleTLSLetsEncrypt handles POST /api/v1/tls/letsencrypt/{domain}
@@ -564,6 +679,45 @@ func (m *ManagementServer) handleTLSLetsEncrypt(w http.ResponseWriter, r *http.R
 	})
 }
 
+// handleTLSRenew handles POST /api/v1/tls/certificates/{domain}/renew
+func (m *ManagementServer) handleTLSRenew(w http.ResponseWriter, r *http.Request) {
+	domainName := chi.URLParam(r, "domain")
+
+	if m.acme == nil {
+		sendError(w, http.StatusBadRequest, "ACME (Let's Encrypt) is not enabled in configuration")
+		return
+	}
+	if !slices.Contains(m.acme.Domains(), domainName) {
+		sendError(w, http.StatusNotFound, "Domain not in ACME allowed domains list")
+		return
+	}
+
+	// Without the always-on challenge server, serve HTTP-01 during renewal
+	if m.config.SMTP.TLS.ACME.OnDemand {
+		stop, err := m.acme.StartChallengeServer(":80")
+		if err != nil {
+			sendError(w, http.StatusServiceUnavailable, err.Error())
+			return
+		}
+		defer stop()
+	}
+
+	// Renewal can take longer than the API write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Now().Add(acmeRenewTimeout + 10*time.Second))
+	ctx, cancel := context.WithTimeout(r.Context(), acmeRenewTimeout)
+	defer cancel()
+
+	cert, err := m.acme.Renew(ctx, domainName)
+	if err != nil {
+		sendError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	resp := newACMECertificateInfo(*cert)
+	recordChange(r, nil, resp)
+	sendJSON(w, http.StatusOK, resp)
+}
+
 // Domains Handlers
 
 // DomainResponse represents a domain configuration
@@ -576,8 +730,42 @@ type DomainResponse struct {
 	DefaultFrom string                        `json:"default_from,omitempty"`
 	RedirectTo  []string                      `json:"redirect_to,omitempty"`
 	BCCTo       []string                      `json:"bcc_to,omitempty"`
+	Paused      bool                          `json:"paused,omitempty"`
+	Inbound     []config.InboundRule          `json:"inbound,omitempty"`
+
+	ContentPolicy *config.ContentPolicyConfig    `json:"content_policy,omitempty"`
+	Delivery      *config.DomainDeliveryConfig   `json:"delivery,omitempty"`
+	ReturnPath    *config.DomainReturnPathConfig `json:"return_path,omitempty"`
+	Retry         *retry.Config                  `json:"retry,omitempty"`
 }
 
+// newDomainResponse returns the API representation of a domain config
+func newDomainResponse(name string, dc config.DomainConfig) DomainResponse {
+	return DomainResponse{
+		Domain:      name,
+		DKIM:        dc.DKIM,
+		TLS:         dc.TLS,
+		RateLimit:   dc.RateLimit,
+		Mode:        dc.Mode,
+		DefaultFrom: dc.DefaultFrom,
+		RedirectTo:  dc.RedirectTo,
+		BCCTo:       dc.BCCTo,
+		Paused:      dc.Paused,
+		Inbound:     dc.Inbound,
+
+		ContentPolicy: dc.ContentPolicy,
+		Delivery:      dc.Delivery,
+		ReturnPath:    dc.ReturnPath,
+		Retry:         dc.Retry,
+	}
+}
+
+// Errors of the domain updates, mapped to HTTP statuses by the handlers
+var (
+	errDomainExists   = errors.New("domain already exists")
+	errDomainNotFound = errors.New("domain not found")
+)
+
 // DomainsListResponse is the response for GET /api/v1/domains
 type DomainsListResponse struct {
 	Domains []DomainResponse `json:"domains"`
@@ -585,13 +773,7 @@ type DomainsListResponse struct {
 
 // handleDomainsList handles GET /api/v1/domains
 func (m *ManagementServer) handleDomainsList(w http.ResponseWriter, r *http.Request) {
-	var domains []string
-	if m.domainManager != nil {
-		domains = m.domainManager.ListDomains()
-	} else {
-		// Fallback to config if domain manager is not available
-		domains = m.config.GetAllDomains()
-	}
+	domains := m.domainManager.ListDomains()
 
 	response := DomainsListResponse{
 		Domains: make([]DomainResponse, 0, len(domains)),
@@ -599,7 +781,7 @@ func (m *ManagementServer) handleDomainsList(w http.ResponseWriter, r *http.Requ
 
 	for _, d := range domains {
 		dr := DomainResponse{Domain: d}
-		if dc := m.config.GetDomainConfig(d); dc != nil {
+		if dc := m.domainManager.GetDomainConfig(d); dc != nil {
 			dr.DKIM = dc.DKIM
 			dr.TLS = dc.TLS
 			dr.RateLimit = dc.RateLimit
@@ -607,6 +789,12 @@ func (m *ManagementServer) handleDomainsList(w http.ResponseWriter, r *http.Requ
 			dr.DefaultFrom = dc.DefaultFrom
 			dr.RedirectTo = dc.RedirectTo
 			dr.BCCTo = dc.BCCTo
+			dr.Paused = dc.Paused
+			dr.Inbound = dc.Inbound
+			dr.ContentPolicy = dc.ContentPolicy
+			dr.Delivery = dc.Delivery
+			dr.ReturnPath = dc.ReturnPath
+			dr.Retry = dc.Retry
 		}
 		response.Domains = append(response.Domains, dr)
 	}
@@ -624,6 +812,13 @@ type DomainCreateRequest struct {
 	DefaultFrom string                        `json:"default_from,omitempty"`
 	RedirectTo  []string                      `json:"redirect_to,omitempty"`
 	BCCTo       []string                      `json:"bcc_to,omitempty"`
+	Paused      bool                          `json:"paused,omitempty"`
+	Inbound     []config.InboundRule          `json:"inbound,omitempty"`
+
+	ContentPolicy *config.ContentPolicyConfig    `json:"content_policy,omitempty"`
+	Delivery      *config.DomainDeliveryConfig   `json:"delivery,omitempty"`
+	ReturnPath    *config.DomainReturnPathConfig `json:"return_path,omitempty"`
+	Retry         *retry.Config                  `json:"retry,omitempty"`
 }
 
 // handleDomainsCreate handles POST /api/v1/domains
@@ -645,18 +840,28 @@ func (m *ManagementServer) handleDomainsCreate(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	// Check if domain already exists
-	if m.config.GetDomainConfig(req.Domain) != nil {
-		sendError(w, http.StatusConflict, "Domain already exists")
+	if err := config.ValidateInboundRules("inbound", req.Inbound); err != nil {
+		sendError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-
-	// Add domain to config
-	if m.config.Domains == nil {
-		m.config.Domains = make(map[string]config.DomainConfig)
+	if err := config.ValidateContentPolicy("content_policy", req.ContentPolicy); err != nil {
+		sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := config.ValidateDelivery("delivery", req.Delivery, req.Inbound); err != nil {
+		sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := config.ValidateReturnPath("return_path", req.ReturnPath); err != nil {
+		sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := req.Retry.Validate(); err != nil {
+		sendError(w, http.StatusBadRequest, "retry."+err.Error())
+		return
 	}
 
-	m.config.Domains[req.Domain] = config.DomainConfig{
+	dc := config.DomainConfig{
 		DKIM:        req.DKIM,
 		TLS:         req.TLS,
 		RateLimit:   req.RateLimit,
@@ -664,29 +869,41 @@ func (m *ManagementServer) handleDomainsCreate(w http.ResponseWriter, r *http.Re
 		DefaultFrom: req.DefaultFrom,
 		RedirectTo:  req.RedirectTo,
 		BCCTo:       req.BCCTo,
+		Paused:      req.Paused,
+		Inbound:     req.Inbound,
+
+		ContentPolicy: req.ContentPolicy,
+		Delivery:      req.Delivery,
+		ReturnPath:    req.ReturnPath,
+		Retry:         req.Retry,
 	}
 
-	// Persist domain config to file
-	if err := m.config.SaveDomains(); err != nil {
+	// Add the domain and persist the domain configs to file
+	err := m.domainManager.UpdateDomains(func(domains map[string]config.DomainConfig) error {
+		if _, ok := domains[req.Domain]; ok {
+			return errDomainExists
+		}
+		domains[req.Domain] = dc
+		return nil
+	})
+	if errors.Is(err, errDomainExists) {
+		sendError(w, http.StatusConflict, "Domain already exists")
+		return
+	}
+	if err != nil {
 		sendError(w, http.StatusInternalServerError, "Failed to save domain config")
 		return
 	}
+	m.syncPauses()
 
 	// Load DKIM signer if DKIM config provided
-	if req.DKIM != nil && req.DKIM.Enabled && m.domainManager != nil {
+	if req.DKIM != nil && req.DKIM.Enabled {
 		_ = m.domainManager.ReloadSigner(req.Domain)
 	}
 
-	sendJSON(w, http.StatusCreated, DomainResponse{
-		Domain:      req.Domain,
-		DKIM:        req.DKIM,
-		TLS:         req.TLS,
-		RateLimit:   req.RateLimit,
-		Mode:        req.Mode,
-		DefaultFrom: req.DefaultFrom,
-		RedirectTo:  req.RedirectTo,
-		BCCTo:       req.BCCTo,
-	})
+	resp := newDomainResponse(req.Domain, dc)
+	recordChange(r, nil, resp)
+	sendJSON(w, http.StatusCreated, resp)
 }
 
 // handleDomainsGet handles GET /api/v1/domains/{domain}
@@ -697,22 +914,13 @@ func (m *ManagementServer) handleDomainsGet(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	dc := m.config.GetDomainConfig(domainName)
+	dc := m.domainManager.GetDomainConfig(domainName)
 	if dc == nil {
 		sendError(w, http.StatusNotFound, "Domain not found")
 		return
 	}
 
-	sendJSON(w, http.StatusOK, DomainResponse{
-		Domain:      domainName,
-		DKIM:        dc.DKIM,
-		TLS:         dc.TLS,
-		RateLimit:   dc.RateLimit,
-		Mode:        dc.Mode,
-		DefaultFrom: dc.DefaultFrom,
-		RedirectTo:  dc.RedirectTo,
-		BCCTo:       dc.BCCTo,
-	})
+	sendJSON(w, http.StatusOK, newDomainResponse(domainName, *dc))
 }
 
 // handleDomainsUpdate handles PUT /api/v1/domains/{domain}
@@ -735,13 +943,28 @@ func (m *ManagementServer) handleDomainsUpdate(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	// Check if domain exists in explicit config
-	if m.config.Domains == nil {
-		m.config.Domains = make(map[string]config.DomainConfig)
+	if err := config.ValidateInboundRules("inbound", req.Inbound); err != nil {
+		sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := config.ValidateContentPolicy("content_policy", req.ContentPolicy); err != nil {
+		sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := config.ValidateDelivery("delivery", req.Delivery, req.Inbound); err != nil {
+		sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := config.ValidateReturnPath("return_path", req.ReturnPath); err != nil {
+		sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := req.Retry.Validate(); err != nil {
+		sendError(w, http.StatusBadRequest, "retry."+err.Error())
+		return
 	}
 
-	// Update or create domain config
-	m.config.Domains[domainName] = config.DomainConfig{
+	dc := config.DomainConfig{
 		DKIM:        req.DKIM,
 		TLS:         req.TLS,
 		RateLimit:   req.RateLimit,
@@ -749,32 +972,48 @@ func (m *ManagementServer) handleDomainsUpdate(w http.ResponseWriter, r *http.Re
 		DefaultFrom: req.DefaultFrom,
 		RedirectTo:  req.RedirectTo,
 		BCCTo:       req.BCCTo,
+		Paused:      req.Paused,
+		Inbound:     req.Inbound,
+
+		ContentPolicy: req.ContentPolicy,
+		Delivery:      req.Delivery,
+		ReturnPath:    req.ReturnPath,
+		Retry:         req.Retry,
 	}
 
-	// Persist domain config to file
-	if err := m.config.SaveDomains(); err != nil {
+	// Update or create the domain and persist the domain configs to file
+	var before any
+	err := m.domainManager.UpdateDomains(func(domains map[string]config.DomainConfig) error {
+		if old, ok := domains[domainName]; ok {
+			before = newDomainResponse(domainName, old)
+		}
+		domains[domainName] = dc
+		return nil
+	})
+	if err != nil {
 		sendError(w, http.StatusInternalServerError, "Failed to save domain config")
 		return
 	}
+	m.syncPauses()
 
 	// Reload DKIM signer if DKIM config changed
-	if req.DKIM != nil && m.domainManager != nil {
+	if req.DKIM != nil {
 		if err := m.domainManager.ReloadSigner(domainName); err != nil {
 			// Log error but don't fail the request - config is saved
 			// Signer will be loaded on next restart
 		}
 	}
 
-	sendJSON(w, http.StatusOK, DomainResponse{
-		Domain:      domainName,
-		DKIM:        req.DKIM,
-		TLS:         req.TLS,
-		RateLimit:   req.RateLimit,
-		Mode:        req.Mode,
-		DefaultFrom: req.DefaultFrom,
-		RedirectTo:  req.RedirectTo,
-		BCCTo:       req.BCCTo,
-	})
+	resp := newDomainResponse(domainName, dc)
+	recordChange(r, before, resp)
+	sendJSON(w, http.StatusOK, resp)
+}
+
+// syncPauses applies the paused flags of the domain configs
+func (m *ManagementServer) syncPauses() {
+	if m.pauses != nil {
+		m.pauses.SetConfigured(m.domainManager.PausedDomains())
+	}
 }
 
 // handleDomainsDelete handles DELETE /api/v1/domains/{domain}
@@ -791,29 +1030,30 @@ func (m *ManagementServer) handleDomainsDelete(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	if m.config.Domains == nil {
-		sendError(w, http.StatusNotFound, "Domain not found")
-		return
-	}
-
-	if _, exists := m.config.Domains[domainName]; !exists {
+	// Remove the domain and persist the domain configs to file
+	var dc config.DomainConfig
+	err := m.domainManager.UpdateDomains(func(domains map[string]config.DomainConfig) error {
+		var ok bool
+		if dc, ok = domains[domainName]; !ok {
+			return errDomainNotFound
+		}
+		delete(domains, domainName)
+		return nil
+	})
+	if errors.Is(err, errDomainNotFound) {
 		sendError(w, http.StatusNotFound, "Domain not found")
 		return
 	}
-
-	delete(m.config.Domains, domainName)
-
-	// Persist domain config to file
-	if err := m.config.SaveDomains(); err != nil {
+	if err != nil {
 		sendError(w, http.StatusInternalServerError, "Failed to save domain config")
 		return
 	}
+	m.syncPauses()
 
 	// Remove DKIM signer
-	if m.domainManager != nil {
-		_ = m.domainManager.ReloadSigner(domainName)
-	}
+	_ = m.domainManager.ReloadSigner(domainName)
 
+	recordChange(r, newDomainResponse(domainName, dc), nil)
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -822,37 +1062,60 @@ func (m *ManagementServer) handleDomainsDelete(w http.ResponseWriter, r *http.Re
 // RateLimitsResponse is the response for GET /api/v1/ratelimits
 type RateLimitsResponse struct {
 	Enabled       bool                 `json:"enabled"`
+	Algorithm     string               `json:"algorithm"`
 	Global        *config.LimitValues  `json:"global,omitempty"`
 	DefaultDomain *config.LimitValues  `json:"default_domain,omitempty"`
 	DefaultSender *config.LimitValues  `json:"default_sender,omitempty"`
 	DefaultIP     *config.LimitValues  `json:"default_ip,omitempty"`
 	DefaultAPIKey *config.LimitValues  `json:"default_api_key,omitempty"`
 	Domains       map[string]*DomainRL `json:"domains,omitempty"`
+
+	// Recipient domains slowed down after throttling responses
+	Adaptive []ratelimit.AdaptiveRate `json:"adaptive,omitempty"`
 }
 
 // DomainRL represents rate limits for a domain
 type DomainRL struct {
+	MessagesPerMinute    int `json:"messages_per_minute"`
 	MessagesPerHour      int `json:"messages_per_hour"`
 	MessagesPerDay       int `json:"messages_per_day"`
 	RecipientsPerMessage int `json:"recipients_per_message"`
 }
 
+// newDomainRL returns the API representation of domain rate limits
+func newDomainRL(rl *config.DomainRateLimitConfig) *DomainRL {
+	return &DomainRL{
+		MessagesPerMinute:    rl.MessagesPerMinute,
+		MessagesPerHour:      rl.MessagesPerHour,
+		MessagesPerDay:       rl.MessagesPerDay,
+		RecipientsPerMessage: rl.RecipientsPerMessage,
+	}
+}
+
 // handleRateLimitsGet handles GET /api/v1/ratelimits
 func (m *ManagementServer) handleRateLimitsGet(w http.ResponseWriter, r *http.Request) {
+	rl := m.rateLimits.Load()
+	algorithm := rl.Algorithm
+	if algorithm == "" {
+		algorithm = string(ratelimit.AlgorithmFixed)
+	}
+
 	response := RateLimitsResponse{
-		Enabled:       m.config.RateLimit.Enabled,
-		Global:        m.config.RateLimit.Global,
-		DefaultDomain: m.config.RateLimit.DefaultDomain,
-		DefaultSender: m.config.RateLimit.DefaultSender,
-		DefaultIP:     m.config.RateLimit.DefaultIP,
-		DefaultAPIKey: m.config.RateLimit.DefaultAPIKey,
+		Enabled:       rl.Enabled,
+		Algorithm:     algorithm,
+		Global:        rl.Global,
+		DefaultDomain: rl.DefaultDomain,
+		DefaultSender: rl.DefaultSender,
+		DefaultIP:     rl.DefaultIP,
+		DefaultAPIKey: rl.DefaultAPIKey,
 		Domains:       make(map[string]*DomainRL),
 	}
 
 	// Add domain-specific rate limits
-	for domain, dc := range m.config.Domains {
+	for domain, dc := range m.domainManager.Domains() {
 		if dc.RateLimit != nil {
 			response.Domains[domain] = &DomainRL{
+				MessagesPerMinute:    dc.RateLimit.MessagesPerMinute,
 				MessagesPerHour:      dc.RateLimit.MessagesPerHour,
 				MessagesPerDay:       dc.RateLimit.MessagesPerDay,
 				RecipientsPerMessage: dc.RateLimit.RecipientsPerMessage,
@@ -860,6 +1123,10 @@ func (m *ManagementServer) handleRateLimitsGet(w http.ResponseWriter, r *http.Re
 		}
 	}
 
+	if m.rateLimiter != nil {
+		response.Adaptive = m.rateLimiter.AdaptiveRates()
+	}
+
 	sendJSON(w, http.StatusOK, response)
 }
 
@@ -867,8 +1134,11 @@ func (m *ManagementServer) handleRateLimitsGet(w http.ResponseWriter, r *http.Re
 type RateLimitStatsResponse struct {
 	Level       string `json:"level"`
 	Key         string `json:"key"`
+	Algorithm   string `json:"algorithm"`
+	MinuteCount int    `json:"minute_count"`
 	HourlyCount int    `json:"hourly_count"`
 	DailyCount  int    `json:"daily_count"`
+	MinuteLimit int    `json:"minute_limit"`
 	HourlyLimit int    `json:"hourly_limit"`
 	DailyLimit  int    `json:"daily_limit"`
 }
@@ -897,47 +1167,50 @@ func (m *ManagementServer) handleRateLimitStats(w http.ResponseWriter, r *http.R
 	response := RateLimitStatsResponse{
 		Level:       level,
 		Key:         key,
+		Algorithm:   string(stats.Algorithm),
+		MinuteCount: stats.MinuteCount,
 		HourlyCount: stats.HourlyCount,
 		DailyCount:  stats.DailyCount,
 	}
 
 	// Get configured limits
+	rl := m.rateLimits.Load()
+	var limits *config.LimitValues
 	switch ratelimit.Level(level) {
 	case ratelimit.LevelGlobal:
-		if m.config.RateLimit.Global != nil {
-			response.HourlyLimit = m.config.RateLimit.Global.MessagesPerHour
-			response.DailyLimit = m.config.RateLimit.Global.MessagesPerDay
-		}
+		limits = rl.Global
 	case ratelimit.LevelDomain:
-		if dc := m.config.GetDomainConfig(key); dc != nil && dc.RateLimit != nil {
+		if dc := m.domainManager.GetDomainConfig(key); dc != nil && dc.RateLimit != nil {
+			response.MinuteLimit = dc.RateLimit.MessagesPerMinute
 			response.HourlyLimit = dc.RateLimit.MessagesPerHour
 			response.DailyLimit = dc.RateLimit.MessagesPerDay
-		} else if m.config.RateLimit.DefaultDomain != nil {
-			response.HourlyLimit = m.config.RateLimit.DefaultDomain.MessagesPerHour
-			response.DailyLimit = m.config.RateLimit.DefaultDomain.MessagesPerDay
+		} else {
+			limits = rl.DefaultDomain
 		}
 	case ratelimit.LevelSender:
-		if m.config.RateLimit.DefaultSender != nil {
-			response.HourlyLimit = m.config.RateLimit.DefaultSender.MessagesPerHour
-			response.DailyLimit = m.config.RateLimit.DefaultSender.MessagesPerDay
-		}
+		limits = rl.DefaultSender
 	case ratelimit.LevelIP:
-		if m.config.RateLimit.DefaultIP != nil {
-			response.HourlyLimit = m.config.RateLimit.DefaultIP.MessagesPerHour
-			response.DailyLimit = m.config.RateLimit.DefaultIP.MessagesPerDay
-		}
+		limits = rl.DefaultIP
 	case ratelimit.LevelAPIKey:
-		if m.config.RateLimit.DefaultAPIKey != nil {
-			response.HourlyLimit = m.config.RateLimit.DefaultAPIKey.MessagesPerHour
-			response.DailyLimit = m.config.RateLimit.DefaultAPIKey.MessagesPerDay
+		limits = rl.DefaultAPIKey
+	case ratelimit.LevelRecipient:
+		limits = rl.RecipientDomains[key]
+		if limits == nil {
+			limits = rl.DefaultRecipientDomain
 		}
 	}
+	if limits != nil {
+		response.MinuteLimit = limits.MessagesPerMinute
+		response.HourlyLimit = limits.MessagesPerHour
+		response.DailyLimit = limits.MessagesPerDay
+	}
 
 	sendJSON(w, http.StatusOK, response)
 }
 
 // RateLimitUpdateRequest is the request for PUT /api/v1/ratelimits/{domain}
 type RateLimitUpdateRequest struct {
+	MessagesPerMinute    int `json:"messages_per_minute"`
 	MessagesPerHour      int `json:"messages_per_hour"`
 	MessagesPerDay       int `json:"messages_per_day"`
 	RecipientsPerMessage int `json:"recipients_per_message"`
@@ -957,24 +1230,37 @@ func (m *ManagementServer) handleRateLimitsUpdate(w http.ResponseWriter, r *http
 		return
 	}
 
-	// Update config
-	if m.config.Domains == nil {
-		m.config.Domains = make(map[string]config.DomainConfig)
+	if err := config.ValidateLimitWindows("rate_limit", req.MessagesPerMinute, req.MessagesPerHour, req.MessagesPerDay); err != nil {
+		sendError(w, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	dc := m.config.Domains[domainName]
-	dc.RateLimit = &config.DomainRateLimitConfig{
+	limits := &config.DomainRateLimitConfig{
+		MessagesPerMinute:    req.MessagesPerMinute,
 		MessagesPerHour:      req.MessagesPerHour,
 		MessagesPerDay:       req.MessagesPerDay,
 		RecipientsPerMessage: req.RecipientsPerMessage,
 	}
-	m.config.Domains[domainName] = dc
 
-	sendJSON(w, http.StatusOK, DomainRL{
-		MessagesPerHour:      req.MessagesPerHour,
-		MessagesPerDay:       req.MessagesPerDay,
-		RecipientsPerMessage: req.RecipientsPerMessage,
+	// Update config
+	var before any
+	err := m.domainManager.UpdateDomains(func(domains map[string]config.DomainConfig) error {
+		dc := domains[domainName]
+		if dc.RateLimit != nil {
+			before = newDomainRL(dc.RateLimit)
+		}
+		dc.RateLimit = limits
+		domains[domainName] = dc
+		return nil
 	})
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to save domain config")
+		return
+	}
+
+	resp := newDomainRL(limits)
+	recordChange(r, before, resp)
+	sendJSON(w, http.StatusOK, resp)
 }
 
 // Helper functions
@@ -1068,6 +1354,64 @@ func (m *ManagementServer) handleDNSCheck(w http.ResponseWriter, r *http.Request
 	sendJSON(w, http.StatusOK, result)
 }
 
+// DNSHistoryResponse is the response for GET /api/v1/domains/{domain}/dns/history
+type DNSHistoryResponse struct {
+	Domain  string               `json:"domain"`
+	Latest  *dnsmonitor.Result   `json:"latest"`
+	History []*dnsmonitor.Result `json:"history"`
+	Total   int                  `json:"total"`
+}
+
+// handleDNSHistory handles GET /api/v1/domains/{domain}/dns/history
+func (m *ManagementServer) handleDNSHistory(w http.ResponseWriter, r *http.Request) {
+	if m.dnsChecks == nil {
+		sendError(w, http.StatusNotFound, "DNS monitoring is not enabled")
+		return
+	}
+
+	domainName := strings.ToLower(chi.URLParam(r, "domain"))
+	if err := dnscheck.ValidateDomain(domainName); err != nil {
+		sendError(w, http.StatusBadRequest, "invalid domain")
+		return
+	}
+
+	hours := 7 * 24
+	if v := r.URL.Query().Get("hours"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxHistoryHours {
+			sendError(w, http.StatusBadRequest, fmt.Sprintf("hours must be between 1 and %d", maxHistoryHours))
+			return
+		}
+		hours = n
+	}
+
+	latest, err := m.dnsChecks.Latest(r.Context(), domainName)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to get DNS check results")
+		return
+	}
+	if latest == nil {
+		sendError(w, http.StatusNotFound, "Domain has not been checked")
+		return
+	}
+
+	history, err := m.dnsChecks.History(r.Context(), domainName, time.Now().Add(-time.Duration(hours)*time.Hour))
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to get DNS check results")
+		return
+	}
+	if history == nil {
+		history = []*dnsmonitor.Result{}
+	}
+
+	sendJSON(w, http.StatusOK, DNSHistoryResponse{
+		Domain:  domainName,
+		Latest:  latest,
+		History: history,
+		Total:   len(history),
+	})
+}
+
 // handleIPCheck handles GET /api/v1/ip/check/{ip}
 func (m *ManagementServer) handleIPCheck(w http.ResponseWriter, r *http.Request) {
 	ipAddr := chi.URLParam(r, "ip")
@@ -2,16 +2,36 @@ package api
 
 import (
 	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"log/slog"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	bolt "go.etcd.io/bbolt"
 
 	"github.com/foxzi/sendry/internal/config"
+	"github.com/foxzi/sendry/internal/dnsbl"
+	"github.com/foxzi/sendry/internal/dnscheck"
+	"github.com/foxzi/sendry/internal/dnsmonitor"
+	"github.com/foxzi/sendry/internal/domain"
+	"github.com/foxzi/sendry/internal/ratelimit"
+	"github.com/foxzi/sendry/internal/retry"
+	sendryTLS "github.com/foxzi/sendry/internal/tls"
 )
 
 func TestDKIMGenerate(t *testing.T) {
@@ -190,11 +210,67 @@ func TestDomainsCreate(t *testing.T) {
 	}
 
 	// Check domain was added to config
-	if _, exists := cfg.Domains["new.com"]; !exists {
+	if mgmt.domainManager.GetDomainConfig("new.com") == nil {
 		t.Error("domain was not added to config")
 	}
 }
 
+func TestDomainsRetryPolicy(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	cfg := &config.Config{
+		SMTP: config.SMTPConfig{
+			Domain: "example.com",
+		},
+	}
+
+	mgmt := NewManagementServer(nil, nil, cfg, tmpDir, tmpDir)
+	mgmt.retries = retry.NewScheduler(retry.Policy{Interval: 5 * time.Minute, MaxRetries: 5}, nil, func(d string) *retry.Config {
+		if dc := mgmt.domainManager.GetDomainConfig(d); dc != nil {
+			return dc.Retry
+		}
+		return nil
+	})
+
+	router := chi.NewRouter()
+	mgmt.RegisterRoutes(router)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do("POST", "/domains/", `{"domain": "bad.com", "retry": {"policy": {"strategy": "linear"}}}`); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid policy: expected status %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+
+	body := `{"domain": "gmail.com", "retry": {"classes": {"greylisting": {"strategy": "steps", "steps": ["5m", "15m"], "max_retries": 3}}}}`
+	if w := do("POST", "/domains/", body); w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	if rc := mgmt.domainManager.GetDomainConfig("gmail.com").Retry; rc == nil || len(rc.Classes["greylisting"].Steps) != 2 {
+		t.Fatalf("retry config = %+v", rc)
+	}
+
+	w := do("GET", "/retry/gmail.com", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var resp RetryPolicyResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if got := resp.Classes["greylisting"].Schedule; len(got) != 2 || got[0] != "5m0s" || got[1] != "15m0s" {
+		t.Errorf("greylisting schedule = %v, want [5m0s 15m0s]", got)
+	}
+	if got := resp.Classes["temporary"].Schedule; len(got) != 4 || got[0] != "5m0s" {
+		t.Errorf("temporary schedule = %v, want the default exponential backoff", got)
+	}
+}
+
 func TestDomainsUpdate(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -226,8 +302,8 @@ func TestDomainsUpdate(t *testing.T) {
 	}
 
 	// Check domain was updated
-	if cfg.Domains["test.com"].Mode != "production" {
-		t.Errorf("expected mode production, got %s", cfg.Domains["test.com"].Mode)
+	if mode := mgmt.domainManager.GetDomainConfig("test.com").Mode; mode != "production" {
+		t.Errorf("expected mode production, got %s", mode)
 	}
 }
 
@@ -260,7 +336,7 @@ func TestDomainsDelete(t *testing.T) {
 	}
 
 	// Check domain was deleted
-	if _, exists := cfg.Domains["test.com"]; exists {
+	if mgmt.domainManager.GetDomainConfig("test.com") != nil {
 		t.Error("domain was not deleted from config")
 	}
 }
@@ -273,10 +349,12 @@ func TestRateLimitsGet(t *testing.T) {
 			Domain: "example.com",
 		},
 		RateLimit: config.RateLimitConfig{
-			Enabled: true,
+			Enabled:   true,
+			Algorithm: "sliding",
 			Global: &config.LimitValues{
-				MessagesPerHour: 1000,
-				MessagesPerDay:  10000,
+				MessagesPerMinute: 50,
+				MessagesPerHour:   1000,
+				MessagesPerDay:    10000,
 			},
 		},
 	}
@@ -309,6 +387,213 @@ func TestRateLimitsGet(t *testing.T) {
 	if resp.Global.MessagesPerHour != 1000 {
 		t.Errorf("expected messages per hour 1000, got %d", resp.Global.MessagesPerHour)
 	}
+	if resp.Global.MessagesPerMinute != 50 {
+		t.Errorf("expected messages per minute 50, got %d", resp.Global.MessagesPerMinute)
+	}
+	if resp.Algorithm != "sliding" {
+		t.Errorf("expected algorithm sliding, got %s", resp.Algorithm)
+	}
+
+	// A reload replaces the limits without touching the shared config
+	mgmt.setRateLimits(config.RateLimitConfig{Enabled: true, Global: &config.LimitValues{MessagesPerHour: 2000}})
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/ratelimits/", nil))
+	resp = RateLimitsResponse{}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Global == nil || resp.Global.MessagesPerHour != 2000 || resp.Algorithm != "fixed" {
+		t.Errorf("after reload: global %+v, algorithm %s", resp.Global, resp.Algorithm)
+	}
+	if cfg.RateLimit.Global.MessagesPerHour != 1000 {
+		t.Errorf("shared config changed to %d messages per hour", cfg.RateLimit.Global.MessagesPerHour)
+	}
+}
+
+func TestRateLimitStats(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	db, err := bolt.Open(filepath.Join(tmpDir, "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	defer db.Close()
+
+	limiter, err := ratelimit.NewLimiter(db, &ratelimit.Config{
+		DefaultSender: &ratelimit.LimitConfig{MessagesPerMinute: 5, MessagesPerHour: 100, MessagesPerDay: 1000},
+	})
+	if err != nil {
+		t.Fatalf("failed to create limiter: %v", err)
+	}
+	defer limiter.Stop()
+
+	for i := 0; i < 3; i++ {
+		limiter.Allow(context.Background(), &ratelimit.Request{Sender: "user@example.com"})
+	}
+
+	cfg := &config.Config{
+		SMTP: config.SMTPConfig{Domain: "example.com"},
+		RateLimit: config.RateLimitConfig{
+			Enabled:       true,
+			DefaultSender: &config.LimitValues{MessagesPerMinute: 5, MessagesPerHour: 100, MessagesPerDay: 1000},
+		},
+	}
+
+	mgmt := NewManagementServer(nil, limiter, cfg, tmpDir, tmpDir)
+
+	router := chi.NewRouter()
+	mgmt.RegisterRoutes(router)
+
+	req := httptest.NewRequest("GET", "/ratelimits/sender/user@example.com", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var resp RateLimitStatsResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if resp.Algorithm != "fixed" {
+		t.Errorf("expected algorithm fixed, got %s", resp.Algorithm)
+	}
+	if resp.MinuteCount != 3 || resp.HourlyCount != 3 || resp.DailyCount != 3 {
+		t.Errorf("expected counts 3/3/3, got %d/%d/%d", resp.MinuteCount, resp.HourlyCount, resp.DailyCount)
+	}
+	if resp.MinuteLimit != 5 || resp.HourlyLimit != 100 || resp.DailyLimit != 1000 {
+		t.Errorf("expected limits 5/100/1000, got %d/%d/%d", resp.MinuteLimit, resp.HourlyLimit, resp.DailyLimit)
+	}
+}
+
+func TestRateLimitsAdaptive(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	db, err := bolt.Open(filepath.Join(tmpDir, "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	defer db.Close()
+
+	limiter, err := ratelimit.NewLimiter(db, &ratelimit.Config{
+		RecipientDomains: map[string]*ratelimit.LimitConfig{"gmail.com": {MessagesPerMinute: 10}},
+		Adaptive:         &ratelimit.AdaptiveConfig{Enabled: true, Threshold: 1},
+	})
+	if err != nil {
+		t.Fatalf("failed to create limiter: %v", err)
+	}
+	defer limiter.Stop()
+	limiter.RecordThrottle("gmail.com")
+
+	cfg := &config.Config{SMTP: config.SMTPConfig{Domain: "example.com"}}
+	mgmt := NewManagementServer(nil, limiter, cfg, tmpDir, tmpDir)
+
+	router := chi.NewRouter()
+	mgmt.RegisterRoutes(router)
+
+	req := httptest.NewRequest("GET", "/ratelimits/", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var resp RateLimitsResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Adaptive) != 1 || resp.Adaptive[0].Domain != "gmail.com" || resp.Adaptive[0].MessagesPerMinute != 5 {
+		t.Errorf("adaptive = %+v, want gmail.com at 5 messages per minute", resp.Adaptive)
+	}
+}
+
+func TestRateLimitsUpdateInvalidWindows(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	cfg := &config.Config{
+		SMTP: config.SMTPConfig{Domain: "example.com"},
+	}
+
+	mgmt := NewManagementServer(nil, nil, cfg, tmpDir, tmpDir)
+
+	router := chi.NewRouter()
+	mgmt.RegisterRoutes(router)
+
+	body := `{"messages_per_minute": 500, "messages_per_hour": 100}`
+	req := httptest.NewRequest("PUT", "/ratelimits/example.com", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+	if mgmt.domainManager.GetDomainConfig("example.com") != nil {
+		t.Error("invalid rate limits were stored")
+	}
+}
+
+type mockReloader struct {
+	domains *domain.Manager
+	err     error
+}
+
+func (m *mockReloader) Reload(ctx context.Context) error {
+	if m.err != nil {
+		return m.err
+	}
+	return m.domains.Reload(&config.Config{
+		SMTP:    config.SMTPConfig{Domain: "example.com"},
+		Domains: map[string]config.DomainConfig{"new.com": {Mode: "production"}},
+	})
+}
+
+func TestConfigReload(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	cfg := &config.Config{
+		SMTP:    config.SMTPConfig{Domain: "example.com"},
+		Domains: map[string]config.DomainConfig{},
+	}
+
+	mgmt := NewManagementServer(nil, nil, cfg, tmpDir, tmpDir)
+
+	router := chi.NewRouter()
+	mgmt.RegisterRoutes(router)
+
+	reload := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("POST", "/config/reload", nil))
+		return w
+	}
+
+	if w := reload(); w.Code != http.StatusNotImplemented {
+		t.Errorf("without reloader: expected status %d, got %d", http.StatusNotImplemented, w.Code)
+	}
+
+	reloader := &mockReloader{domains: mgmt.domainManager}
+	mgmt.reloader = reloader
+
+	w := reload()
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var resp ConfigReloadResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Status != "reloaded" || len(resp.Domains) != 2 || resp.Domains[0] != "example.com" || resp.Domains[1] != "new.com" {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+
+	reloader.err = errors.New("invalid configuration")
+	if w := reload(); w.Code != http.StatusInternalServerError {
+		t.Errorf("failed reload: expected status %d, got %d", http.StatusInternalServerError, w.Code)
+	}
 }
 
 func TestTLSList(t *testing.T) {
@@ -351,6 +636,98 @@ func TestTLSList(t *testing.T) {
 	}
 }
 
+func TestTLSListCertificateDetails(t *testing.T) {
+	tmpDir := t.TempDir()
+	tlsDir := filepath.Join(tmpDir, "tls")
+	if err := os.MkdirAll(filepath.Join(tlsDir, "mail.example.com"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "mail.example.com"},
+		DNSNames:     []string{"mail.example.com", "smtp.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(10*24*time.Hour + time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	if err := os.WriteFile(filepath.Join(tlsDir, "mail.example.com", "cert.pem"), certPEM, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		SMTP: config.SMTPConfig{
+			Domain: "example.com",
+			TLS: config.TLSConfig{
+				CertFile: "/path/to/cert.pem",
+				KeyFile:  "/path/to/key.pem",
+				ACME:     config.ACMEConfig{Enabled: true, Domains: []string{"mx.example.com"}},
+			},
+		},
+	}
+	mgmt := NewManagementServer(nil, nil, cfg, tmpDir, tlsDir)
+	mgmt.acme = sendryTLS.NewACMEManager("", cfg.SMTP.TLS.ACME.Domains, filepath.Join(tmpDir, "acme"))
+
+	router := chi.NewRouter()
+	mgmt.RegisterRoutes(router)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/tls/certificates", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var resp TLSListResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	certs := make(map[string]TLSCertificateInfo)
+	for _, c := range resp.Certificates {
+		certs[c.Domain] = c
+	}
+
+	if c := certs["example.com"]; c.Error == "" || c.NotAfter != nil {
+		t.Errorf("unreadable certificate = %+v, want error", c)
+	}
+	c := certs["mail.example.com"]
+	if c.Issuer != "mail.example.com" || len(c.DNSNames) != 2 || c.NotAfter == nil || c.DaysLeft == nil || *c.DaysLeft != 10 {
+		t.Errorf("uploaded certificate = %+v", c)
+	}
+	if c := certs["mx.example.com"]; !c.ACME || c.Error == "" {
+		t.Errorf("ACME certificate = %+v, want not obtained", c)
+	}
+}
+
+func TestTLSRenew(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := &config.Config{}
+	mgmt := NewManagementServer(nil, nil, cfg, tmpDir, tmpDir)
+
+	router := chi.NewRouter()
+	mgmt.RegisterRoutes(router)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/tls/certificates/mx.example.com/renew", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("without ACME: expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	mgmt.acme = sendryTLS.NewACMEManager("", []string{"mx.example.com"}, tmpDir)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/tls/certificates/other.example.com/renew", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("unknown domain: expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
 func TestTLSUpload(t *testing.T) {
 	tmpDir := t.TempDir()
 	tlsDir := filepath.Join(tmpDir, "tls")
@@ -595,3 +972,148 @@ func TestDNSBLList(t *testing.T) {
 		t.Errorf("expected at least 10 DNSBLs, got %d", len(dnsbls))
 	}
 }
+
+func TestDNSHistory(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	mgmt := NewManagementServer(nil, nil, &config.Config{}, tmpDir, tmpDir)
+	router := chi.NewRouter()
+	mgmt.RegisterRoutes(router)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w
+	}
+
+	if w := get("/domains/example.com/dns/history"); w.Code != http.StatusNotFound {
+		t.Errorf("without dns monitor: expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+
+	db, err := bolt.Open(filepath.Join(tmpDir, "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	defer db.Close()
+	storage, err := dnsmonitor.NewStorage(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgmt.dnsChecks = storage
+
+	now := time.Now()
+	for i, status := range []string{"ok", "not_found"} {
+		r := &dnsmonitor.Result{
+			Domain:    "example.com",
+			CheckedAt: now.Add(time.Duration(i-1) * time.Hour),
+			Results:   []dnscheck.CheckResult{{Type: "DKIM Record (mail._domainkey)", Status: status}},
+		}
+		if err := storage.Save(context.Background(), r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := get("/domains/Example.com/dns/history?hours=24")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var resp DNSHistoryResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Total != 2 || resp.Latest == nil || resp.Latest.Results[0].Status != "not_found" {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+
+	for path, code := range map[string]int{
+		"/domains/other.com/dns/history":           http.StatusNotFound,
+		"/domains/example.com/dns/history?hours=0": http.StatusBadRequest,
+		"/domains/bad_domain!/dns/history":         http.StatusBadRequest,
+	} {
+		if w := get(path); w.Code != code {
+			t.Errorf("GET %s: expected status %d, got %d", path, code, w.Code)
+		}
+	}
+}
+
+func TestMonitoredIPs(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	mgmt := NewManagementServer(nil, nil, &config.Config{}, tmpDir, tmpDir)
+	router := chi.NewRouter()
+	mgmt.RegisterRoutes(router)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, bytes.NewBufferString(body)))
+		return w
+	}
+
+	if w := do("GET", "/ip/monitored", ""); w.Code != http.StatusNotFound {
+		t.Errorf("without dnsbl monitor: expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+
+	db, err := bolt.Open(filepath.Join(tmpDir, "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	defer db.Close()
+	storage, err := dnsbl.NewStorage(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgmt.dnsbl = dnsbl.NewMonitor(storage, dnsbl.Config{IPs: []string{"192.0.2.10"}},
+		slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	if w := do("POST", "/ip/monitored", `{"ip":"198.51.100.7","note":"relay"}`); w.Code != http.StatusCreated {
+		t.Fatalf("add: expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	listing := dnscheck.DNSBLResult{
+		DNSBL:  dnscheck.DNSBLInfo{Name: "SpamCop", Zone: "bl.spamcop.net", DelistURL: "https://www.spamcop.net/bl.shtml"},
+		Listed: true,
+	}
+	err = storage.Save(context.Background(), &dnsbl.Result{
+		IP:          "192.0.2.10",
+		CheckedAt:   time.Now(),
+		Listings:    []dnscheck.DNSBLResult{listing},
+		NewlyListed: []string{"bl.spamcop.net"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := do("GET", "/ip/monitored", "")
+	var list MonitoredIPsResponse
+	if err := json.NewDecoder(w.Body).Decode(&list); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if list.Total != 2 || list.IPs[0].Source != dnsbl.SourceConfig || list.IPs[0].Latest == nil || list.IPs[1].Latest != nil {
+		t.Errorf("unexpected list response: %+v", list)
+	}
+
+	w = do("GET", "/ip/monitored/192.0.2.10/history", "")
+	var history DNSBLHistoryResponse
+	if err := json.NewDecoder(w.Body).Decode(&history); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if history.Total != 1 || history.Latest.Listings[0].DNSBL.DelistURL == "" {
+		t.Errorf("unexpected history response: %+v", history)
+	}
+
+	for _, tc := range []struct {
+		method, path, body string
+		code               int
+	}{
+		{"POST", "/ip/monitored", `{"ip":"2001:db8::1"}`, http.StatusBadRequest},
+		{"GET", "/ip/monitored/203.0.113.1/history", "", http.StatusNotFound},
+		{"GET", "/ip/monitored/192.0.2.10/history?hours=0", "", http.StatusBadRequest},
+		{"DELETE", "/ip/monitored/192.0.2.10", "", http.StatusConflict},
+		{"DELETE", "/ip/monitored/198.51.100.7", "", http.StatusNoContent},
+		{"DELETE", "/ip/monitored/198.51.100.7", "", http.StatusNotFound},
+	} {
+		if w := do(tc.method, tc.path, tc.body); w.Code != tc.code {
+			t.Errorf("%s %s: expected status %d, got %d", tc.method, tc.path, tc.code, w.Code)
+		}
+	}
+}
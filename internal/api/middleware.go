@@ -1,11 +1,16 @@
 package api
 
 import (
+	"context"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5/middleware"
+
+	"github.com/foxzi/sendry/internal/apikey"
+	"github.com/foxzi/sendry/internal/metrics"
 )
 
 // loggingMiddleware logs HTTP requests
@@ -23,19 +28,15 @@ func (s *Server) loggingMiddleware(next http.Handler) http.Handler {
 			"duration", time.Since(start),
 			"bytes", ww.BytesWritten(),
 			"remote_addr", r.RemoteAddr,
+			"correlation_id", correlationID(r.Context()),
 		)
 	})
 }
 
-// authMiddleware checks API key authentication
+// authMiddleware checks API key authentication. The api.api_key of the
+// config grants full access, keys from the key store grant their scopes.
 func (s *Server) authMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if s.config.APIKey == "" {
-			// No API key configured, allow all
-			next.ServeHTTP(w, r)
-			return
-		}
-
 		// Check Authorization header
 		auth := r.Header.Get("Authorization")
 		if auth == "" {
@@ -48,15 +49,80 @@ func (s *Server) authMiddleware(next http.Handler) http.Handler {
 			auth = strings.TrimPrefix(auth, "Bearer ")
 		}
 
-		if auth != s.config.APIKey {
-			s.logger.Warn("unauthorized API request",
-				"remote_addr", r.RemoteAddr,
-				"path", r.URL.Path,
-			)
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		if key := s.staticAPIKey(); key != "" && auth == key {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if s.apiKeyStorage != nil && auth != "" {
+			key, err := s.apiKeyStorage.Authenticate(r.Context(), auth)
+			if err != nil {
+				s.logger.Error("failed to check API key", "error", err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			if key != nil {
+				s.serveWithAPIKey(w, r, key, next)
+				return
+			}
+		}
+
+		if s.staticAPIKey() == "" && !s.hasAPIKeys(r.Context()) {
+			// No API key configured, allow all
+			next.ServeHTTP(w, r)
 			return
 		}
 
-		next.ServeHTTP(w, r)
+		s.logger.Warn("unauthorized API request",
+			"remote_addr", r.RemoteAddr,
+			"path", r.URL.Path,
+		)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 	})
 }
+
+// serveWithAPIKey checks the scope and rate limit of a stored API key
+func (s *Server) serveWithAPIKey(w http.ResponseWriter, r *http.Request, key *apikey.Key, next http.Handler) {
+	if scope := requiredScope(r); !key.Allows(scope) {
+		s.logger.Warn("API key lacks scope",
+			"api_key_id", key.ID,
+			"scope", scope,
+			"path", r.URL.Path,
+		)
+		s.sendError(w, http.StatusForbidden, "API key lacks the "+string(scope)+" scope")
+		return
+	}
+
+	if err := s.apiKeyStorage.Touch(r.Context(), key.ID); err != nil {
+		s.logger.Error("failed to record API key use", "api_key_id", key.ID, "error", err)
+	}
+
+	if s.rateLimiter != nil && isSubmission(r) {
+		result, err := s.rateLimiter.AllowAPIKey(r.Context(), key.ID, key.RateLimit)
+		if err != nil {
+			s.logger.Error("rate limit check error", "error", err)
+		} else if !result.Allowed {
+			metrics.IncRateLimitExceeded(string(result.DeniedBy))
+			w.Header().Set("Retry-After", strconv.Itoa(int(result.RetryAfter.Seconds())+1))
+			s.sendError(w, http.StatusTooManyRequests, "Rate limit exceeded, try again later")
+			return
+		}
+	}
+
+	ctx := context.WithValue(r.Context(), ctxKeyAPIKey, key)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// hasAPIKeys reports whether the key store has usable keys, which turns
+// authentication on even without api.api_key
+func (s *Server) hasAPIKeys(ctx context.Context) bool {
+	if s.apiKeyStorage == nil {
+		return false
+	}
+	ok, err := s.apiKeyStorage.HasActive(ctx)
+	if err != nil {
+		s.logger.Error("failed to check API keys", "error", err)
+		return true
+	}
+	return ok
+}
@@ -0,0 +1,27 @@
+package api
+
+import (
+	"crypto/tls"
+	"net/http"
+)
+
+// clientIdentity returns the identity of the client certificate of an mTLS
+// request. The common name is mapped through api.tls.identities and used
+// as is when it has no mapping. Requests without a client certificate
+// return an empty string.
+func (s *Server) clientIdentity(r *http.Request) string {
+	return s.certificateIdentity(r.TLS)
+}
+
+// certificateIdentity returns the identity of the client certificate of a
+// TLS connection, empty without a client certificate
+func (s *Server) certificateIdentity(state *tls.ConnectionState) string {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return ""
+	}
+	cn := state.PeerCertificates[0].Subject.CommonName
+	if identity, ok := s.config.TLS.Identities[cn]; ok {
+		return identity
+	}
+	return cn
+}
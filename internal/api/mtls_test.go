@@ -0,0 +1,49 @@
+package api
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAuditClientIdentity(t *testing.T) {
+	server := setupAuditServer(t)
+	server.config.TLS.Identities = map[string]string{"sendry-web": "web-prod"}
+
+	for _, tc := range []struct{ domain, cn string }{
+		{"mapped.com", "sendry-web"},
+		{"unmapped.com", "deploy-bot"},
+		{"plain.com", ""},
+	} {
+		req := httptest.NewRequest("POST", "/api/v1/domains/", bytes.NewBufferString(`{"domain": "`+tc.domain+`"}`))
+		req.Header.Set("X-Correlation-ID", tc.domain)
+		if tc.cn != "" {
+			req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{
+				{Subject: pkix.Name{CommonName: tc.cn}},
+			}}
+		}
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("create %s status = %d: %s", tc.domain, w.Code, w.Body.String())
+		}
+	}
+
+	for id, want := range map[string]string{
+		"mapped.com":   "web-prod",
+		"unmapped.com": "deploy-bot",
+		"plain.com":    "",
+	} {
+		resp := listAudit(t, server, "?correlation_id="+id)
+		if resp.Total != 1 {
+			t.Fatalf("audit entries for %s = %d, want 1", id, resp.Total)
+		}
+		if got := resp.Entries[0].ClientIdentity; got != want {
+			t.Errorf("client identity for %s = %q, want %q", id, got, want)
+		}
+	}
+}
@@ -0,0 +1,600 @@
+package api
+
+import (
+	"encoding"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/foxzi/sendry/internal/addrpolicy"
+	"github.com/foxzi/sendry/internal/apikey"
+	"github.com/foxzi/sendry/internal/archive"
+	"github.com/foxzi/sendry/internal/autoreply"
+	"github.com/foxzi/sendry/internal/config"
+	"github.com/foxzi/sendry/internal/dnsbl"
+	"github.com/foxzi/sendry/internal/dnscheck"
+	"github.com/foxzi/sendry/internal/fbl"
+	"github.com/foxzi/sendry/internal/headers"
+	"github.com/foxzi/sendry/internal/health"
+	"github.com/foxzi/sendry/internal/idempotency"
+	"github.com/foxzi/sendry/internal/maillist"
+	"github.com/foxzi/sendry/internal/privacy"
+	"github.com/foxzi/sendry/internal/queue"
+	"github.com/foxzi/sendry/internal/replication"
+	"github.com/foxzi/sendry/internal/smtpauth"
+	"github.com/foxzi/sendry/internal/suppression"
+)
+
+// apiOperation documents a route of the HTTP API. The OpenAPI document is
+// built from these and the request and response types of the handlers.
+type apiOperation struct {
+	Method       string
+	Path         string // Route pattern, e.g. /api/v1/queue/{id}
+	ID           string // operationId, also the method name of pkg/client where there is one
+	Tag          string
+	Summary      string
+	Query        []apiParam
+	Request      any    // JSON request body, nil for none
+	RequestType  string // Media type of a non-JSON request body
+	Status       int    // Status of a successful response
+	Response     any    // JSON response body, nil for none
+	ResponseType string // Media type of a non-JSON response body
+}
+
+// apiParam is a query parameter of an operation
+type apiParam struct {
+	Name        string
+	Type        string // string, integer or boolean
+	Description string
+}
+
+// oneOf is a response that has one of several types
+type oneOf []any
+
+func query(name, typ, description string) apiParam {
+	return apiParam{Name: name, Type: typ, Description: description}
+}
+
+var (
+	limitParam  = query("limit", "integer", "Maximum number of entries")
+	offsetParam = query("offset", "integer", "Number of entries to skip")
+	domainParam = query("domain", "string", "Only entries of this domain")
+	hoursParam  = query("hours", "integer", "Hours of history, default 24")
+
+	reportParams = []apiParam{
+		query("range", "string", "Period up to now or until, e.g. 6h or 30d, default 24h"),
+		query("since", "string", "Start of the period, RFC 3339 or YYYY-MM-DD"),
+		query("until", "string", "End of the period, RFC 3339 or YYYY-MM-DD, default now"),
+		query("top", "integer", "Recipient domains per row, default 5, at most 50"),
+		query("format", "string", "json or csv, csv returns the rows as text/csv"),
+	}
+)
+
+// ActionResponse is the response of actions that report a status text
+type ActionResponse struct {
+	Status    string `json:"status"`
+	Message   string `json:"message,omitempty"`
+	MessageID string `json:"message_id,omitempty"`
+	Domain    string `json:"domain,omitempty"`
+}
+
+// apiOperations lists the routes of the HTTP API, TestOpenAPIRoutes checks
+// that it matches the router
+var apiOperations = []apiOperation{
+	{Method: "GET", Path: "/health", ID: "Health", Tag: "system", Summary: "Server health and queue statistics", Status: 200, Response: HealthResponse{}},
+	{Method: "GET", Path: "/healthz", ID: "Liveness", Tag: "system", Summary: "Liveness of the process", Status: 200, Response: LivenessResponse{}},
+	{Method: "GET", Path: "/readyz", ID: "Readiness", Tag: "system", Summary: "Readiness checks, 503 when not ready or draining", Status: 200, Response: health.Report{}},
+	{Method: "GET", Path: "/api/v1/openapi.json", ID: "OpenAPI", Tag: "system", Summary: "This OpenAPI document", Status: 200, Response: map[string]any{}},
+
+	{Method: "POST", Path: "/api/v1/send", ID: "Send", Tag: "send", Summary: "Queue a message", Request: SendRequest{}, Status: 202, Response: SendResponse{}},
+	{Method: "POST", Path: "/api/v1/send/batch", ID: "SendBatch", Tag: "send", Summary: "Queue up to 1000 messages", Request: BatchSendRequest{}, Status: 202, Response: BatchSendResponse{}},
+	{Method: "POST", Path: "/api/v1/send/raw", ID: "SendRaw", Tag: "send", Summary: "Queue a complete RFC 5322 message", RequestType: rawMediaType, Query: []apiParam{
+		query("from", "string", "Envelope sender, default the From header"),
+		query("to", "string", "Envelope recipients, repeated or comma separated, default the To, Cc and Bcc headers"),
+		query("send_at", "string", "Hold until this time (RFC 3339)"),
+		query("priority", "string", "high, normal or low"),
+		query("skip_dkim", "boolean", "Deliver without a DKIM signature"),
+	}, Status: 202, Response: SendResponse{}},
+	{Method: "POST", Path: "/api/v1/send/template", ID: "SendWithTemplate", Tag: "send", Summary: "Queue a message rendered from a template, one per recipient with recipients", Request: SendTemplateRequest{}, Status: 202, Response: oneOf{SendResponse{}, BatchSendResponse{}}},
+	{Method: "GET", Path: "/api/v1/status/{id}", ID: "GetStatus", Tag: "send", Summary: "Delivery status of a message", Status: 200, Response: StatusResponse{}},
+
+	{Method: "GET", Path: "/api/v1/queue", ID: "GetQueue", Tag: "queue", Summary: "Queue statistics and messages", Query: []apiParam{
+		query("status", "string", "Only messages with this status"),
+		query("sender", "string", "Only messages from this address"),
+		query("recipient", "string", "Only messages to this address"),
+		query("sender_domain", "string", "Only messages from this domain"),
+		query("recipient_domain", "string", "Only messages to this domain"),
+		query("domain", "string", "Only messages from or to this domain"),
+		query("id_prefix", "string", "Only messages whose ID starts with this"),
+		query("since", "string", "Only messages created at or after this time (RFC 3339 or date)"),
+		query("until", "string", "Only messages created before this time (RFC 3339 or date)"),
+		query("cursor", "string", "next_cursor of the previous page"),
+		limitParam,
+	}, Status: 200, Response: QueueResponse{}},
+	{Method: "GET", Path: "/api/v1/queue/pause", ID: "GetPauseStatus", Tag: "queue", Summary: "Paused delivery", Status: 200, Response: queue.PauseStatus{}},
+	{Method: "POST", Path: "/api/v1/queue/pause", ID: "Pause", Tag: "queue", Summary: "Pause delivery of a domain or of all mail", Request: PauseRequest{}, Status: 200, Response: queue.PauseStatus{}},
+	{Method: "POST", Path: "/api/v1/queue/resume", ID: "Resume", Tag: "queue", Summary: "Resume delivery of a domain or of all mail", Request: PauseRequest{}, Status: 200, Response: queue.PauseStatus{}},
+	{Method: "GET", Path: "/api/v1/queue/{id}", ID: "GetMessage", Tag: "queue", Summary: "Queued message with its DKIM signature", Status: 200, Response: MessageResponse{}},
+	{Method: "DELETE", Path: "/api/v1/queue/{id}", ID: "DeleteFromQueue", Tag: "queue", Summary: "Delete a queued message", Status: 204},
+	{Method: "POST", Path: "/api/v1/queue/{id}/retry", ID: "RetryMessage", Tag: "queue", Summary: "Send a deferred or failed message again now", Status: 200, Response: ActionResponse{}},
+
+	{Method: "GET", Path: "/api/v1/dlq", ID: "GetDLQ", Tag: "dlq", Summary: "Dead letter queue statistics and messages", Status: 200, Response: DLQResponse{}},
+	{Method: "GET", Path: "/api/v1/dlq/{id}", ID: "GetDLQMessage", Tag: "dlq", Summary: "Message in the dead letter queue", Status: 200, Response: StatusResponse{}},
+	{Method: "POST", Path: "/api/v1/dlq/{id}/retry", ID: "RetryDLQ", Tag: "dlq", Summary: "Move a message back to the queue", Status: 200, Response: ActionResponse{}},
+	{Method: "DELETE", Path: "/api/v1/dlq/{id}", ID: "DeleteFromDLQ", Tag: "dlq", Summary: "Delete a message from the dead letter queue", Status: 204},
+
+	{Method: "GET", Path: "/api/v1/backup", ID: "Backup", Tag: "system", Summary: "Consistent copy of the database", Status: 200, ResponseType: "application/octet-stream"},
+	{Method: "POST", Path: "/api/v1/config/reload", ID: "ReloadConfig", Tag: "system", Summary: "Reload the configuration file", Status: 200, Response: ConfigReloadResponse{}},
+
+	{Method: "POST", Path: "/api/v1/dkim/generate", ID: "GenerateDKIM", Tag: "dkim", Summary: "Generate a DKIM key", Request: DKIMGenerateRequest{}, Status: 201, Response: DKIMGenerateResponse{}},
+	{Method: "POST", Path: "/api/v1/dkim/upload", ID: "UploadDKIM", Tag: "dkim", Summary: "Upload a DKIM private key", Request: DKIMUploadRequest{}, Status: 201, Response: DKIMGenerateResponse{}},
+	{Method: "GET", Path: "/api/v1/dkim/{domain}", ID: "GetDKIM", Tag: "dkim", Summary: "DKIM keys of a domain", Status: 200, Response: DKIMInfoResponse{}},
+	{Method: "GET", Path: "/api/v1/dkim/{domain}/verify", ID: "VerifyDKIM", Tag: "dkim", Summary: "Compare the DKIM key with its DNS record", Query: []apiParam{
+		query("selector", "string", "Selector to verify, default the one of the domain"),
+	}, Status: 200, Response: DKIMVerifyResponse{}},
+	{Method: "DELETE", Path: "/api/v1/dkim/{domain}/{selector}", ID: "DeleteDKIM", Tag: "dkim", Summary: "Delete a DKIM key", Status: 204},
+
+	{Method: "GET", Path: "/api/v1/tls/certificates", ID: "ListTLSCertificates", Tag: "tls", Summary: "TLS certificates", Status: 200, Response: TLSListResponse{}},
+	{Method: "POST", Path: "/api/v1/tls/certificates", ID: "UploadTLSCertificate", Tag: "tls", Summary: "Upload a TLS certificate", Request: TLSUploadRequest{}, Status: 201, Response: TLSCertificateInfo{}},
+	{Method: "POST", Path: "/api/v1/tls/certificates/{domain}/renew", ID: "RenewTLSCertificate", Tag: "tls", Summary: "Renew an ACME certificate now", Status: 200, Response: TLSCertificateInfo{}},
+	{Method: "POST", Path: "/api/v1/tls/letsencrypt/{domain}", ID: "RequestLetsEncrypt", Tag: "tls", Summary: "Obtain a Let's Encrypt certificate", Status: 202, Response: ActionResponse{}},
+
+	{Method: "GET", Path: "/api/v1/domains", ID: "ListDomains", Tag: "domains", Summary: "Configured domains", Status: 200, Response: DomainsListResponse{}},
+	{Method: "POST", Path: "/api/v1/domains", ID: "CreateDomain", Tag: "domains", Summary: "Add a domain", Request: DomainCreateRequest{}, Status: 201, Response: DomainResponse{}},
+	{Method: "GET", Path: "/api/v1/domains/{domain}", ID: "GetDomain", Tag: "domains", Summary: "Domain settings", Status: 200, Response: DomainResponse{}},
+	{Method: "PUT", Path: "/api/v1/domains/{domain}", ID: "UpdateDomain", Tag: "domains", Summary: "Change domain settings", Request: DomainCreateRequest{}, Status: 200, Response: DomainResponse{}},
+	{Method: "DELETE", Path: "/api/v1/domains/{domain}", ID: "DeleteDomain", Tag: "domains", Summary: "Remove a domain", Status: 204},
+	{Method: "GET", Path: "/api/v1/domains/{domain}/dns/history", ID: "GetDNSHistory", Tag: "domains", Summary: "DNS monitoring history of a domain", Query: []apiParam{hoursParam}, Status: 200, Response: DNSHistoryResponse{}},
+	{Method: "GET", Path: "/api/v1/retry/{domain}", ID: "GetRetryPolicy", Tag: "domains", Summary: "Retry policy of a recipient domain", Status: 200, Response: RetryPolicyResponse{}},
+
+	{Method: "GET", Path: "/api/v1/ratelimits", ID: "GetRateLimits", Tag: "ratelimits", Summary: "Rate limit configuration", Status: 200, Response: RateLimitsResponse{}},
+	{Method: "GET", Path: "/api/v1/ratelimits/{level}/{key}", ID: "GetRateLimitStats", Tag: "ratelimits", Summary: "Current usage of a rate limit", Status: 200, Response: RateLimitStatsResponse{}},
+	{Method: "PUT", Path: "/api/v1/ratelimits/{domain}", ID: "UpdateRateLimit", Tag: "ratelimits", Summary: "Change the rate limit of a domain", Request: RateLimitUpdateRequest{}, Status: 200, Response: DomainRL{}},
+
+	{Method: "GET", Path: "/api/v1/dns/check/{domain}", ID: "CheckDNS", Tag: "dns", Summary: "Check the DNS records of a domain", Query: []apiParam{
+		query("mx", "boolean", "Check MX records"),
+		query("spf", "boolean", "Check the SPF record"),
+		query("dkim", "boolean", "Check the DKIM record"),
+		query("dmarc", "boolean", "Check the DMARC record"),
+		query("mta_sts", "boolean", "Check MTA-STS"),
+		query("selector", "string", "DKIM selector, default sendry"),
+	}, Status: 200, Response: dnscheck.DomainCheckResult{}},
+	{Method: "GET", Path: "/api/v1/ip/check/{ip}", ID: "CheckIP", Tag: "dns", Summary: "Check an IP against DNS blocklists", Status: 200, Response: dnscheck.IPCheckResult{}},
+	{Method: "GET", Path: "/api/v1/ip/dnsbls", ID: "ListDNSBLs", Tag: "dns", Summary: "DNS blocklists that are checked", Status: 200, Response: struct {
+		DNSBLs []dnscheck.DNSBLInfo `json:"dnsbls"`
+		Count  int                  `json:"count"`
+	}{}},
+	{Method: "GET", Path: "/api/v1/ip/monitored", ID: "ListMonitoredIPs", Tag: "dns", Summary: "Monitored outbound IPs", Status: 200, Response: MonitoredIPsResponse{}},
+	{Method: "POST", Path: "/api/v1/ip/monitored", ID: "AddMonitoredIP", Tag: "dns", Summary: "Monitor an outbound IP", Request: MonitoredIPRequest{}, Status: 201, Response: MonitoredIP{}},
+	{Method: "DELETE", Path: "/api/v1/ip/monitored/{ip}", ID: "RemoveMonitoredIP", Tag: "dns", Summary: "Stop monitoring an IP", Status: 204},
+	{Method: "GET", Path: "/api/v1/ip/monitored/{ip}/history", ID: "GetMonitoredIPHistory", Tag: "dns", Summary: "DNSBL check history of a monitored IP", Query: []apiParam{hoursParam}, Status: 200, Response: DNSBLHistoryResponse{}},
+	{Method: "POST", Path: "/api/v1/ip/monitored/{ip}/check", ID: "CheckMonitoredIP", Tag: "dns", Summary: "Check a monitored IP now", Status: 200, Response: dnsbl.Result{}},
+
+	{Method: "GET", Path: "/api/v1/sandbox/messages", ID: "ListSandboxMessages", Tag: "sandbox", Summary: "Captured messages", Query: []apiParam{
+		domainParam,
+		query("mode", "string", "Only messages captured in this mode"),
+		query("from", "string", "Only messages from this address"),
+		query("q", "string", "Search in subject and recipients"),
+		limitParam,
+		offsetParam,
+	}, Status: 200, Response: SandboxListResponse{}},
+	{Method: "DELETE", Path: "/api/v1/sandbox/messages", ID: "ClearSandbox", Tag: "sandbox", Summary: "Delete captured messages", Query: []apiParam{
+		domainParam,
+		query("older_than", "string", "Only messages older than this duration, e.g. 24h"),
+	}, Status: 200, Response: struct {
+		Cleared int `json:"cleared"`
+	}{}},
+	{Method: "GET", Path: "/api/v1/sandbox/messages/{id}", ID: "GetSandboxMessage", Tag: "sandbox", Summary: "Captured message", Status: 200, Response: SandboxMessageDetailResponse{}},
+	{Method: "GET", Path: "/api/v1/sandbox/messages/{id}/raw", ID: "GetSandboxRaw", Tag: "sandbox", Summary: "Source of a captured message", Status: 200, ResponseType: rawMediaType},
+	{Method: "DELETE", Path: "/api/v1/sandbox/messages/{id}", ID: "DeleteSandboxMessage", Tag: "sandbox", Summary: "Delete a captured message", Status: 204},
+	{Method: "POST", Path: "/api/v1/sandbox/messages/{id}/resend", ID: "ResendSandboxMessage", Tag: "sandbox", Summary: "Capture a message again", Status: 200, Response: ActionResponse{}},
+	{Method: "POST", Path: "/api/v1/sandbox/messages/{id}/release", ID: "ReleaseSandboxMessage", Tag: "sandbox", Summary: "Deliver a captured message", Status: 200, Response: ActionResponse{}},
+	{Method: "POST", Path: "/api/v1/sandbox/release", ID: "ReleaseSandbox", Tag: "sandbox", Summary: "Deliver captured messages", Request: SandboxReleaseRequest{}, Status: 200, Response: SandboxReleaseResponse{}},
+	{Method: "GET", Path: "/api/v1/sandbox/stats", ID: "GetSandboxStats", Tag: "sandbox", Summary: "Sandbox statistics", Status: 200, Response: SandboxStatsResponse{}},
+
+	{Method: "GET", Path: "/api/v1/templates", ID: "ListTemplates", Tag: "templates", Summary: "Templates", Query: []apiParam{
+		query("search", "string", "Search in name and description"),
+		limitParam,
+		offsetParam,
+	}, Status: 200, Response: TemplateListResponse{}},
+	{Method: "POST", Path: "/api/v1/templates", ID: "CreateTemplate", Tag: "templates", Summary: "Create a template", Request: TemplateCreateRequest{}, Status: 201, Response: TemplateResponse{}},
+	{Method: "GET", Path: "/api/v1/templates/{id}", ID: "GetTemplate", Tag: "templates", Summary: "Template by ID or name", Status: 200, Response: TemplateResponse{}},
+	{Method: "PUT", Path: "/api/v1/templates/{id}", ID: "UpdateTemplate", Tag: "templates", Summary: "Change a template", Request: TemplateUpdateRequest{}, Status: 200, Response: TemplateResponse{}},
+	{Method: "DELETE", Path: "/api/v1/templates/{id}", ID: "DeleteTemplate", Tag: "templates", Summary: "Delete a template", Status: 204},
+	{Method: "POST", Path: "/api/v1/templates/{id}/preview", ID: "PreviewTemplate", Tag: "templates", Summary: "Render a template with data", Request: TemplatePreviewRequest{}, Status: 200, Response: TemplatePreviewResponse{}},
+	{Method: "GET", Path: "/api/v1/partials", ID: "ListPartials", Tag: "templates", Summary: "Template partials", Status: 200, Response: PartialListResponse{}},
+	{Method: "POST", Path: "/api/v1/partials", ID: "CreatePartial", Tag: "templates", Summary: "Create a partial", Request: PartialRequest{}, Status: 201, Response: PartialResponse{}},
+	{Method: "GET", Path: "/api/v1/partials/{id}", ID: "GetPartial", Tag: "templates", Summary: "Partial by ID or name", Status: 200, Response: PartialResponse{}},
+	{Method: "PUT", Path: "/api/v1/partials/{id}", ID: "UpdatePartial", Tag: "templates", Summary: "Change a partial", Request: PartialRequest{}, Status: 200, Response: PartialResponse{}},
+	{Method: "DELETE", Path: "/api/v1/partials/{id}", ID: "DeletePartial", Tag: "templates", Summary: "Delete a partial", Query: []apiParam{
+		query("force", "boolean", "Delete even if templates use it"),
+	}, Status: 204},
+
+	{Method: "GET", Path: "/api/v1/lists", ID: "ListMailingLists", Tag: "lists", Summary: "Mailing lists", Query: []apiParam{domainParam, limitParam, offsetParam}, Status: 200, Response: ListListResponse{}},
+	{Method: "POST", Path: "/api/v1/lists", ID: "CreateMailingList", Tag: "lists", Summary: "Create a mailing list", Request: ListRequest{}, Status: 201, Response: maillist.List{}},
+	{Method: "GET", Path: "/api/v1/lists/{id}", ID: "GetMailingList", Tag: "lists", Summary: "Mailing list by ID or address", Status: 200, Response: maillist.List{}},
+	{Method: "PUT", Path: "/api/v1/lists/{id}", ID: "UpdateMailingList", Tag: "lists", Summary: "Change a mailing list", Request: ListRequest{}, Status: 200, Response: maillist.List{}},
+	{Method: "DELETE", Path: "/api/v1/lists/{id}", ID: "DeleteMailingList", Tag: "lists", Summary: "Delete a mailing list", Status: 204},
+	{Method: "POST", Path: "/api/v1/lists/{id}/members", ID: "AddMailingListMembers", Tag: "lists", Summary: "Add members", Request: ListMembersRequest{}, Status: 200, Response: maillist.List{}},
+	{Method: "DELETE", Path: "/api/v1/lists/{id}/members/{member}", ID: "RemoveMailingListMember", Tag: "lists", Summary: "Remove a member", Status: 200, Response: maillist.List{}},
+	{Method: "GET", Path: "/api/v1/lists/{id}/messages/{message_id}", ID: "GetMailingListDeliveries", Tag: "lists", Summary: "Delivery of a list message to the members", Status: 200, Response: ListDeliveriesResponse{}},
+
+	{Method: "GET", Path: "/api/v1/autoreplies", ID: "ListAutoReplies", Tag: "autoreplies", Summary: "Auto-responders", Query: []apiParam{domainParam, limitParam, offsetParam}, Status: 200, Response: AutoReplyListResponse{}},
+	{Method: "POST", Path: "/api/v1/autoreplies", ID: "CreateAutoReply", Tag: "autoreplies", Summary: "Create an auto-responder", Request: AutoReplyRequest{}, Status: 201, Response: autoreply.Responder{}},
+	{Method: "GET", Path: "/api/v1/autoreplies/{id}", ID: "GetAutoReply", Tag: "autoreplies", Summary: "Auto-responder", Status: 200, Response: autoreply.Responder{}},
+	{Method: "PUT", Path: "/api/v1/autoreplies/{id}", ID: "UpdateAutoReply", Tag: "autoreplies", Summary: "Change an auto-responder", Request: AutoReplyRequest{}, Status: 200, Response: autoreply.Responder{}},
+	{Method: "DELETE", Path: "/api/v1/autoreplies/{id}", ID: "DeleteAutoReply", Tag: "autoreplies", Summary: "Delete an auto-responder", Status: 204},
+
+	{Method: "GET", Path: "/api/v1/shaping", ID: "ListShapingSchedules", Tag: "shaping", Summary: "Send schedules", Status: 200, Response: ScheduleListResponse{}},
+	{Method: "GET", Path: "/api/v1/shaping/{domain}", ID: "GetShapingSchedule", Tag: "shaping", Summary: "Send schedule of a domain", Status: 200, Response: ScheduleResponse{}},
+	{Method: "PUT", Path: "/api/v1/shaping/{domain}", ID: "PutShapingSchedule", Tag: "shaping", Summary: "Set the send schedule of a domain", Request: ScheduleRequest{}, Status: 200, Response: ScheduleResponse{}},
+	{Method: "DELETE", Path: "/api/v1/shaping/{domain}", ID: "DeleteShapingSchedule", Tag: "shaping", Summary: "Remove the send schedule of a domain", Status: 204},
+
+	{Method: "GET", Path: "/api/v1/headerrules", ID: "ListHeaderRules", Tag: "headerrules", Summary: "Header rule sets", Status: 200, Response: HeaderRulesListResponse{}},
+	{Method: "POST", Path: "/api/v1/headerrules/preview", ID: "PreviewHeaderRules", Tag: "headerrules", Summary: "Apply rules to a message without storing them", Request: HeaderRulesPreviewRequest{}, Status: 200, Response: HeaderRulesPreviewResponse{}},
+	{Method: "GET", Path: "/api/v1/headerrules/{domain}", ID: "GetHeaderRules", Tag: "headerrules", Summary: "Header rule set of a domain", Status: 200, Response: headers.RuleSet{}},
+	{Method: "PUT", Path: "/api/v1/headerrules/{domain}", ID: "PutHeaderRules", Tag: "headerrules", Summary: "Set the header rules of a domain", Request: HeaderRulesRequest{}, Status: 200, Response: headers.RuleSet{}},
+	{Method: "DELETE", Path: "/api/v1/headerrules/{domain}", ID: "DeleteHeaderRules", Tag: "headerrules", Summary: "Remove the header rules of a domain", Status: 204},
+
+	{Method: "GET", Path: "/api/v1/policies", ID: "ListPolicies", Tag: "policies", Summary: "Address policies", Status: 200, Response: PolicyListResponse{}},
+	{Method: "GET", Path: "/api/v1/policies/{scope}", ID: "GetPolicy", Tag: "policies", Summary: "Address policy of global or a domain", Status: 200, Response: addrpolicy.Policy{}},
+	{Method: "PUT", Path: "/api/v1/policies/{scope}", ID: "PutPolicy", Tag: "policies", Summary: "Set the allow and deny lists of global or a domain", Request: config.AddressPolicyConfig{}, Status: 200, Response: addrpolicy.Policy{}},
+	{Method: "DELETE", Path: "/api/v1/policies/{scope}", ID: "DeletePolicy", Tag: "policies", Summary: "Remove the address policy of global or a domain", Status: 204},
+
+	{Method: "GET", Path: "/api/v1/bans", ID: "ListBans", Tag: "bans", Summary: "SMTP clients banned by the IP guard", Status: 200, Response: BanListResponse{}},
+	{Method: "DELETE", Path: "/api/v1/bans/{ip}", ID: "DeleteBan", Tag: "bans", Summary: "Lift the ban of an SMTP client", Status: 200, Response: ActionResponse{}},
+
+	{Method: "POST", Path: "/api/v1/privacy/purge", ID: "PurgeAddress", Tag: "privacy", Summary: "Delete the stored messages of an email address", Request: PurgeRequest{}, Status: 200, Response: privacy.PurgeResult{}},
+
+	{Method: "GET", Path: "/api/v1/audit", ID: "ListAuditLog", Tag: "audit", Summary: "Audit log of management changes", Query: []apiParam{
+		query("correlation_id", "string", "Only entries with this correlation ID"),
+		query("actor", "string", "Only entries of this actor"),
+		query("path", "string", "Only entries whose path starts with this"),
+		limitParam,
+	}, Status: 200, Response: AuditListResponse{}},
+
+	{Method: "GET", Path: "/api/v1/archive", ID: "SearchArchive", Tag: "archive", Summary: "Search archived messages", Query: []apiParam{
+		query("query", "string", "Full-text search"),
+		query("sender", "string", "Only messages from this address"),
+		query("recipient", "string", "Only messages to this address"),
+		query("subject", "string", "Only messages with this subject"),
+		query("since", "string", "Only messages archived at or after this time (RFC 3339 or date)"),
+		query("until", "string", "Only messages archived before this time (RFC 3339 or date)"),
+		limitParam,
+		offsetParam,
+	}, Status: 200, Response: ArchiveSearchResponse{}},
+	{Method: "GET", Path: "/api/v1/archive/{id}", ID: "GetArchivedMessage", Tag: "archive", Summary: "Archived message", Status: 200, Response: archive.Message{}},
+	{Method: "GET", Path: "/api/v1/archive/{id}/raw", ID: "GetArchivedRaw", Tag: "archive", Summary: "Source of an archived message", Status: 200, ResponseType: rawMediaType},
+
+	{Method: "GET", Path: "/api/v1/apikeys", ID: "ListAPIKeys", Tag: "apikeys", Summary: "API keys", Status: 200, Response: APIKeyListResponse{}},
+	{Method: "POST", Path: "/api/v1/apikeys", ID: "CreateAPIKey", Tag: "apikeys", Summary: "Create an API key, the token is only returned here", Request: APIKeyCreateRequest{}, Status: 201, Response: APIKeyCreateResponse{}},
+	{Method: "GET", Path: "/api/v1/apikeys/{id}", ID: "GetAPIKey", Tag: "apikeys", Summary: "API key", Status: 200, Response: apikey.Key{}},
+	{Method: "DELETE", Path: "/api/v1/apikeys/{id}", ID: "RevokeAPIKey", Tag: "apikeys", Summary: "Revoke an API key", Status: 200, Response: apikey.Key{}},
+
+	{Method: "GET", Path: "/api/v1/smtp-users", ID: "ListSMTPUsers", Tag: "smtp-users", Summary: "Users of the SMTP credential store", Status: 200, Response: SMTPUserListResponse{}},
+	{Method: "POST", Path: "/api/v1/smtp-users", ID: "CreateSMTPUser", Tag: "smtp-users", Summary: "Create an SMTP user", Request: SMTPUserCreateRequest{}, Status: 201, Response: smtpauth.User{}},
+	{Method: "GET", Path: "/api/v1/smtp-users/{username}", ID: "GetSMTPUser", Tag: "smtp-users", Summary: "SMTP user", Status: 200, Response: smtpauth.User{}},
+	{Method: "PUT", Path: "/api/v1/smtp-users/{username}", ID: "UpdateSMTPUser", Tag: "smtp-users", Summary: "Rotate the password, disable or enable an SMTP user or change its senders", Request: SMTPUserUpdateRequest{}, Status: 200, Response: smtpauth.User{}},
+	{Method: "DELETE", Path: "/api/v1/smtp-users/{username}", ID: "DeleteSMTPUser", Tag: "smtp-users", Summary: "Delete an SMTP user", Status: 200, Response: ActionResponse{}},
+
+	{Method: "GET", Path: "/api/v1/reputation", ID: "ListReputation", Tag: "reputation", Summary: "Sending reputation of all domains", Status: 200, Response: ReputationListResponse{}},
+	{Method: "GET", Path: "/api/v1/reputation/{domain}", ID: "GetReputation", Tag: "reputation", Summary: "Sending reputation of a domain", Query: []apiParam{hoursParam}, Status: 200, Response: ReputationResponse{}},
+	{Method: "POST", Path: "/api/v1/reputation/{domain}/feedback", ID: "RecordFeedback", Tag: "reputation", Summary: "Record complaints or blocklistings", Request: FeedbackRequest{}, Status: 202, Response: ActionResponse{}},
+
+	{Method: "GET", Path: "/api/v1/stats/history", ID: "GetStatsHistory", Tag: "stats", Summary: "History of queue sizes and delivery counts", Query: []apiParam{
+		query("range", "string", "Period up to now, e.g. 6h or 7d, default 24h"),
+		query("step", "string", "Length of a point, e.g. 5m or 1h, default picked from the range"),
+		query("domain", "string", "Counts of this sender domain instead of all messages"),
+	}, Status: 200, Response: StatsHistoryResponse{}},
+	{Method: "GET", Path: "/api/v1/reports/domains", ID: "GetDomainReport", Tag: "stats", Summary: "Sending report by sender domain", Query: reportParams, Status: 200, Response: ReportResponse{}},
+	{Method: "GET", Path: "/api/v1/reports/keys", ID: "GetKeyReport", Tag: "stats", Summary: "Sending report by API key", Query: reportParams, Status: 200, Response: ReportResponse{}},
+
+	{Method: "GET", Path: "/api/v1/suppressions", ID: "ListSuppressions", Tag: "suppressions", Summary: "Suppressed recipients", Query: []apiParam{
+		query("reason", "string", "Only entries with this reason"),
+		limitParam,
+		offsetParam,
+	}, Status: 200, Response: SuppressionListResponse{}},
+	{Method: "POST", Path: "/api/v1/suppressions", ID: "AddSuppression", Tag: "suppressions", Summary: "Suppress a recipient", Request: SuppressionRequest{}, Status: 201, Response: suppression.Entry{}},
+	{Method: "GET", Path: "/api/v1/suppressions/{email}", ID: "GetSuppression", Tag: "suppressions", Summary: "Suppression of a recipient", Status: 200, Response: suppression.Entry{}},
+	{Method: "DELETE", Path: "/api/v1/suppressions/{email}", ID: "RemoveSuppression", Tag: "suppressions", Summary: "Remove a suppression", Status: 200, Response: ActionResponse{}},
+
+	{Method: "POST", Path: "/api/v1/fbl/reports", ID: "UploadFeedbackReport", Tag: "fbl", Summary: "Process an ARF complaint report", RequestType: rawMediaType, Status: 201, Response: fbl.Complaint{}},
+	{Method: "GET", Path: "/api/v1/fbl/complaints", ID: "ListComplaints", Tag: "fbl", Summary: "Spam complaints", Query: []apiParam{
+		domainParam,
+		query("campaign_id", "string", "Only complaints about this campaign"),
+		query("recipient", "string", "Only complaints of this recipient"),
+		limitParam,
+	}, Status: 200, Response: ComplaintListResponse{}},
+
+	{Method: "GET", Path: "/api/v1/replication/status", ID: "GetReplicationStatus", Tag: "replication", Summary: "Replication state of this node", Status: 200, Response: replication.Status{}},
+	{Method: "POST", Path: "/api/v1/replication/stream", ID: "ReplicationStream", Tag: "replication", Summary: "Queue changes of the primary, sent to a standby", RequestType: "application/x-ndjson", Status: 200, Response: struct {
+		Applied int `json:"applied"`
+	}{}},
+	{Method: "POST", Path: "/api/v1/replication/promote", ID: "PromoteStandby", Tag: "replication", Summary: "Promote this standby to primary", Status: 200, Response: replication.Status{}},
+}
+
+var (
+	openAPIOnce sync.Once
+	openAPIDoc  []byte
+	openAPIErr  error
+)
+
+// OpenAPIDocument returns the OpenAPI 3 document of the HTTP API as JSON
+func OpenAPIDocument() ([]byte, error) {
+	openAPIOnce.Do(func() {
+		doc := newOpenAPIBuilder().document(apiOperations)
+		openAPIDoc, openAPIErr = json.MarshalIndent(doc, "", "  ")
+		openAPIDoc = append(openAPIDoc, '\n')
+	})
+	return openAPIDoc, openAPIErr
+}
+
+// handleOpenAPI handles GET /api/v1/openapi.json
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	doc, err := OpenAPIDocument()
+	if err != nil {
+		s.sendError(w, http.StatusInternalServerError, "Failed to build OpenAPI document")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(doc)
+}
+
+// openAPIBuilder collects the schemas of the types used by the operations
+type openAPIBuilder struct {
+	schemas map[string]any
+	names   map[reflect.Type]string
+}
+
+func newOpenAPIBuilder() *openAPIBuilder {
+	return &openAPIBuilder{
+		schemas: make(map[string]any),
+		names:   make(map[reflect.Type]string),
+	}
+}
+
+var pathParamRe = regexp.MustCompile(`\{([^}]+)\}`)
+
+// document returns the OpenAPI document of ops
+func (b *openAPIBuilder) document(ops []apiOperation) map[string]any {
+	paths := make(map[string]map[string]any)
+	for _, op := range ops {
+		if paths[op.Path] == nil {
+			paths[op.Path] = make(map[string]any)
+		}
+		paths[op.Path][strings.ToLower(op.Method)] = b.operation(op)
+	}
+
+	b.schema(reflect.TypeOf(ErrorResponse{}))
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "Sendry API",
+			"version":     "v1",
+			"description": "HTTP API of the Sendry MTA. Requests authenticate with an API key; stored API keys need the scope given as x-sendry-scope of each operation.",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": b.schemas,
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{"type": "http", "scheme": "bearer"},
+				"apiKeyAuth": map[string]any{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+			},
+		},
+		"security": []any{
+			map[string]any{"bearerAuth": []string{}},
+			map[string]any{"apiKeyAuth": []string{}},
+		},
+	}
+}
+
+// operation returns the OpenAPI operation object of op
+func (b *openAPIBuilder) operation(op apiOperation) map[string]any {
+	out := map[string]any{
+		"operationId": op.ID,
+		"summary":     op.Summary,
+		"tags":        []string{op.Tag},
+	}
+
+	var params []any
+	for _, m := range pathParamRe.FindAllStringSubmatch(op.Path, -1) {
+		params = append(params, map[string]any{
+			"name":     m[1],
+			"in":       "path",
+			"required": true,
+			"schema":   map[string]any{"type": "string"},
+		})
+	}
+	for _, p := range op.Query {
+		params = append(params, map[string]any{
+			"name":        p.Name,
+			"in":          "query",
+			"description": p.Description,
+			"schema":      map[string]any{"type": p.Type},
+		})
+	}
+
+	req, _ := http.NewRequest(op.Method, op.Path, nil)
+	if strings.HasPrefix(op.Path, "/api/") {
+		out["x-sendry-scope"] = string(requiredScope(req))
+		params = append(params, map[string]any{
+			"name":        "X-Correlation-ID",
+			"in":          "header",
+			"description": "Correlation ID recorded in the audit log",
+			"schema":      map[string]any{"type": "string"},
+		})
+	} else {
+		out["security"] = []any{}
+	}
+	if isIdempotent(req) {
+		params = append(params, map[string]any{
+			"name":        idempotency.Header,
+			"in":          "header",
+			"description": "Replay the stored response when the request is retried with the same key",
+			"schema":      map[string]any{"type": "string"},
+		})
+	}
+	if len(params) > 0 {
+		out["parameters"] = params
+	}
+
+	switch {
+	case op.Request != nil:
+		out["requestBody"] = map[string]any{
+			"required": true,
+			"content":  map[string]any{"application/json": map[string]any{"schema": b.schema(reflect.TypeOf(op.Request))}},
+		}
+	case op.RequestType != "":
+		out["requestBody"] = map[string]any{
+			"required": true,
+			"content":  map[string]any{op.RequestType: map[string]any{"schema": map[string]any{"type": "string", "format": "binary"}}},
+		}
+	}
+
+	success := map[string]any{"description": http.StatusText(op.Status)}
+	switch {
+	case op.Response != nil:
+		var schema any
+		if alternatives, ok := op.Response.(oneOf); ok {
+			var list []any
+			for _, a := range alternatives {
+				list = append(list, b.schema(reflect.TypeOf(a)))
+			}
+			schema = map[string]any{"oneOf": list}
+		} else {
+			schema = b.schema(reflect.TypeOf(op.Response))
+		}
+		success["content"] = map[string]any{"application/json": map[string]any{"schema": schema}}
+	case op.ResponseType != "":
+		success["content"] = map[string]any{op.ResponseType: map[string]any{"schema": map[string]any{"type": "string", "format": "binary"}}}
+	}
+	out["responses"] = map[string]any{
+		strconv.Itoa(op.Status): success,
+		"default": map[string]any{
+			"description": "Error",
+			"content":     map[string]any{"application/json": map[string]any{"schema": map[string]any{"$ref": "#/components/schemas/ErrorResponse"}}},
+		},
+	}
+	return out
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	durationType      = reflect.TypeOf(time.Duration(0))
+	rawMessageType    = reflect.TypeOf(json.RawMessage{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// schema returns the schema of t as encoding/json writes it, named structs
+// as references to components
+func (b *openAPIBuilder) schema(t reflect.Type) any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t == durationType:
+		return map[string]any{"type": "integer", "format": "int64", "description": "Nanoseconds"}
+	case t == rawMessageType:
+		return map[string]any{}
+	case t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType):
+		return map[string]any{}
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		return map[string]any{"type": "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.structSchema(t)
+		}
+		name, ok := b.names[t]
+		if !ok {
+			name = b.componentName(t)
+			b.names[t] = name
+			b.schemas[name] = nil // Reserved for recursive types
+			b.schemas[name] = b.structSchema(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + name}
+	}
+	return map[string]any{}
+}
+
+// componentName returns a unique component name of a named type, with its
+// package name when another package has a type of the same name
+func (b *openAPIBuilder) componentName(t reflect.Type) string {
+	name := t.Name()
+	if _, taken := b.schemas[name]; !taken {
+		return name
+	}
+	pkg := t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]
+	return strings.ToUpper(pkg[:1]) + pkg[1:] + name
+}
+
+// structSchema returns the object schema of the JSON fields of a struct
+func (b *openAPIBuilder) structSchema(t reflect.Type) map[string]any {
+	props := make(map[string]any)
+	b.addFields(t, props)
+	out := map[string]any{"type": "object", "properties": props}
+	if len(props) == 0 {
+		delete(out, "properties")
+	}
+	return out
+}
+
+// addFields adds the JSON fields of t to props, flattening embedded structs
+func (b *openAPIBuilder) addFields(t reflect.Type, props map[string]any) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				b.addFields(ft, props)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if _, ok := props[name]; !ok {
+			props[name] = b.schema(f.Type)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/foxzi/sendry/internal/queue"
+	"github.com/foxzi/sendry/internal/smtpauth"
+	"github.com/foxzi/sendry/pkg/client"
+)
+
+// openAPIDocPath is the committed OpenAPI document, regenerated with
+// make openapi
+const openAPIDocPath = "../../pkg/client/openapi.json"
+
+func TestOpenAPIRoutes(t *testing.T) {
+	server, _ := setupTestServer("")
+	server.router = chi.NewRouter()
+	server.managementServer = &ManagementServer{}
+	server.sandboxServer = &SandboxServer{}
+	server.templateServer = &TemplateServer{}
+	server.autoReplyServer = &AutoReplyServer{}
+	server.listServer = &ListServer{}
+	server.shapingServer = &ShapingServer{}
+	server.headerRulesServer = &HeaderRulesServer{}
+	server.policyServer = &PolicyServer{}
+	server.banServer = &BanServer{}
+	server.privacyServer = &PrivacyServer{}
+	server.auditServer = &AuditServer{}
+	server.archiveServer = &ArchiveServer{}
+	server.apiKeyServer = &APIKeyServer{}
+	server.smtpUserServer = &SMTPUserServer{}
+	server.reputationServer = &ReputationServer{}
+	server.statsServer = &StatsServer{}
+	server.suppressionServer = &SuppressionServer{}
+	server.fblServer = &FBLServer{}
+	server.replicationServer = &ReplicationServer{}
+	server.setupRoutes()
+
+	var routes []string
+	err := chi.Walk(server.router, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if len(route) > 1 {
+			route = strings.TrimSuffix(route, "/")
+		}
+		routes = append(routes, method+" "+route)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(routes)
+
+	var documented []string
+	for _, op := range apiOperations {
+		documented = append(documented, op.Method+" "+op.Path)
+	}
+	sort.Strings(documented)
+
+	if strings.Join(routes, "\n") != strings.Join(documented, "\n") {
+		t.Errorf("documented operations differ from routes\nroutes:\n%s\n\ndocumented:\n%s",
+			strings.Join(routes, "\n"), strings.Join(documented, "\n"))
+	}
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	doc, err := OpenAPIDocument()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var parsed struct {
+		Paths      map[string]map[string]map[string]any `json:"paths"`
+		Components struct {
+			Schemas map[string]any `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(doc, &parsed); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+
+	ids := make(map[string]bool)
+	for path, ops := range parsed.Paths {
+		for method, op := range ops {
+			id, _ := op["operationId"].(string)
+			if id == "" || ids[id] {
+				t.Errorf("%s %s: missing or duplicate operationId %q", method, path, id)
+			}
+			ids[id] = true
+		}
+	}
+
+	if scope := parsed.Paths["/api/v1/send"]["post"]["x-sendry-scope"]; scope != "send" {
+		t.Errorf("send scope = %v, want send", scope)
+	}
+	if scope := parsed.Paths["/api/v1/apikeys"]["get"]["x-sendry-scope"]; scope != "admin" {
+		t.Errorf("apikeys scope = %v, want admin", scope)
+	}
+	if _, ok := parsed.Paths["/health"]["get"]["x-sendry-scope"]; ok {
+		t.Error("health has a scope, want none")
+	}
+
+	// Every reference resolves to a component
+	for _, ref := range strings.Split(string(doc), `"$ref": "#/components/schemas/`)[1:] {
+		name := ref[:strings.Index(ref, `"`)]
+		if _, ok := parsed.Components.Schemas[name]; !ok {
+			t.Errorf("unresolved schema reference %q", name)
+		}
+	}
+
+	// Embedded structs are flattened
+	var message struct {
+		Properties map[string]any `json:"properties"`
+	}
+	data, _ := json.Marshal(parsed.Components.Schemas["MessageResponse"])
+	json.Unmarshal(data, &message)
+	for _, field := range []string{"id", "status", "recipients", "size", "dkim"} {
+		if _, ok := message.Properties[field]; !ok {
+			t.Errorf("MessageResponse has no %q property", field)
+		}
+	}
+
+	committed, err := os.ReadFile(openAPIDocPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(committed, doc) {
+		t.Errorf("%s is out of date, run make openapi", openAPIDocPath)
+	}
+}
+
+func TestOpenAPIEndpoint(t *testing.T) {
+	server, _ := setupTestServer("test-key")
+
+	req := httptest.NewRequest("GET", "/api/v1/openapi.json", nil)
+	req.Header.Set("Authorization", "Bearer test-key")
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Status = %d, want %d", w.Code, http.StatusOK)
+	}
+	doc, _ := OpenAPIDocument()
+	if !bytes.Equal(w.Body.Bytes(), doc) {
+		t.Error("endpoint does not return the OpenAPI document")
+	}
+}
+
+// jsonFields returns the JSON field names of a struct type, with the fields
+// of embedded structs
+func jsonFields(t reflect.Type) map[string]bool {
+	fields := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if f.Anonymous && name == "" {
+			for field := range jsonFields(f.Type) {
+				fields[field] = true
+			}
+			continue
+		}
+		if name != "-" && f.IsExported() {
+			fields[name] = true
+		}
+	}
+	return fields
+}
+
+func TestOpenAPIClientTypes(t *testing.T) {
+	tests := []struct {
+		api    any
+		client any
+	}{
+		{SendRequest{}, client.SendRequest{}},
+		{SendResponse{}, client.SendResponse{}},
+		{Attachment{}, client.Attachment{}},
+		{BatchSendRequest{}, client.BatchSendRequest{}},
+		{BatchSendResponse{}, client.BatchSendResponse{}},
+		{BatchSendResultItem{}, client.BatchSendResult{}},
+		{SendTemplateRequest{}, client.SendTemplateRequest{}},
+		{StatusResponse{}, client.StatusResponse{}},
+		{MessageResponse{}, client.MessageResponse{}},
+		{MessageDKIM{}, client.MessageDKIM{}},
+		{QueueResponse{}, client.QueueResponse{}},
+		{MessageSummary{}, client.MessageSummary{}},
+		{queue.QueueStats{}, client.QueueStats{}},
+		{queue.PauseStatus{}, client.PauseStatus{}},
+		{HealthResponse{}, client.HealthResponse{}},
+		{SuppressionRequest{}, client.SuppressionRequest{}},
+		{smtpauth.User{}, client.SMTPUser{}},
+		{SMTPUserCreateRequest{}, client.SMTPUserCreateRequest{}},
+		{SMTPUserUpdateRequest{}, client.SMTPUserUpdateRequest{}},
+	}
+
+	for _, tt := range tests {
+		apiType, clientType := reflect.TypeOf(tt.api), reflect.TypeOf(tt.client)
+		apiFields, clientFields := jsonFields(apiType), jsonFields(clientType)
+		for field := range apiFields {
+			if !clientFields[field] {
+				t.Errorf("client.%s has no field %q of %s", clientType.Name(), field, apiType)
+			}
+		}
+		for field := range clientFields {
+			if !apiFields[field] {
+				t.Errorf("client.%s has field %q unknown to %s", clientType.Name(), field, apiType)
+			}
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/foxzi/sendry/internal/template"
+)
+
+// PartialRequest is the request for creating or updating a partial
+type PartialRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Content     string `json:"content"`
+}
+
+// PartialResponse is the response for a partial
+type PartialResponse struct {
+	ID          string               `json:"id"`
+	Name        string               `json:"name"`
+	Description string               `json:"description,omitempty"`
+	Content     string               `json:"content"`
+	Version     int                  `json:"version"`
+	CreatedAt   time.Time            `json:"created_at"`
+	UpdatedAt   time.Time            `json:"updated_at"`
+	UsedBy      []template.Dependent `json:"used_by,omitempty"` // Templates and partials that include it
+}
+
+// PartialListResponse is the response for listing partials
+type PartialListResponse struct {
+	Partials []*PartialResponse `json:"partials"`
+	Total    int                `json:"total"`
+}
+
+// PartialInUseResponse is the response for deleting or renaming a partial
+// that is in use
+type PartialInUseResponse struct {
+	Error  string               `json:"error"`
+	UsedBy []template.Dependent `json:"used_by"`
+}
+
+// handleListPartials handles GET /api/v1/partials
+func (s *TemplateServer) handleListPartials(w http.ResponseWriter, r *http.Request) {
+	partials, err := s.storage.ListPartials(r.Context())
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to list partials")
+		return
+	}
+
+	response := PartialListResponse{
+		Partials: make([]*PartialResponse, len(partials)),
+		Total:    len(partials),
+	}
+	for i, p := range partials {
+		response.Partials[i] = partialToResponse(p)
+	}
+
+	sendJSON(w, http.StatusOK, response)
+}
+
+// handleCreatePartial handles POST /api/v1/partials
+func (s *TemplateServer) handleCreatePartial(w http.ResponseWriter, r *http.Request) {
+	var req PartialRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := template.ValidatePartialName(req.Name); err != nil {
+		sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Content == "" {
+		sendError(w, http.StatusBadRequest, "content is required")
+		return
+	}
+
+	p := &template.Partial{
+		Name:        req.Name,
+		Description: req.Description,
+		Content:     req.Content,
+	}
+	if err := s.engine.ValidatePartial(p); err != nil {
+		sendError(w, http.StatusBadRequest, fmt.Sprintf("Invalid template syntax: %v", err))
+		return
+	}
+
+	if err := s.storage.CreatePartial(r.Context(), p); err != nil {
+		if strings.Contains(err.Error(), "already exists") {
+			sendError(w, http.StatusConflict, err.Error())
+			return
+		}
+		sendError(w, http.StatusInternalServerError, "Failed to create partial")
+		return
+	}
+
+	sendJSON(w, http.StatusCreated, partialToResponse(p))
+}
+
+// handleGetPartial handles GET /api/v1/partials/{id}
+func (s *TemplateServer) handleGetPartial(w http.ResponseWriter, r *http.Request) {
+	p, ok := s.findPartial(w, r)
+	if !ok {
+		return
+	}
+
+	usedBy, err := s.storage.PartialDependents(r.Context(), p.Name)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to get partial dependents")
+		return
+	}
+
+	resp := partialToResponse(p)
+	resp.UsedBy = usedBy
+	sendJSON(w, http.StatusOK, resp)
+}
+
+// handleUpdatePartial handles PUT /api/v1/partials/{id}
+func (s *TemplateServer) handleUpdatePartial(w http.ResponseWriter, r *http.Request) {
+	p, ok := s.findPartial(w, r)
+	if !ok {
+		return
+	}
+
+	var req PartialRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Name != "" {
+		if err := template.ValidatePartialName(req.Name); err != nil {
+			sendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		p.Name = req.Name
+	}
+	if req.Description != "" {
+		p.Description = req.Description
+	}
+	if req.Content != "" {
+		p.Content = req.Content
+	}
+
+	if err := s.engine.ValidatePartial(p); err != nil {
+		sendError(w, http.StatusBadRequest, fmt.Sprintf("Invalid template syntax: %v", err))
+		return
+	}
+
+	if err := s.storage.UpdatePartial(r.Context(), p); err != nil {
+		var inUse *template.PartialInUseError
+		switch {
+		case errors.As(err, &inUse):
+			sendJSON(w, http.StatusConflict, PartialInUseResponse{Error: inUse.Error(), UsedBy: inUse.UsedBy})
+		case strings.Contains(err.Error(), "already exists"):
+			sendError(w, http.StatusConflict, err.Error())
+		default:
+			sendError(w, http.StatusInternalServerError, "Failed to update partial")
+		}
+		return
+	}
+	s.engine.Cache().Clear()
+
+	sendJSON(w, http.StatusOK, partialToResponse(p))
+}
+
+// handleDeletePartial handles DELETE /api/v1/partials/{id}. A partial that
+// is in use is only deleted with ?force=true.
+func (s *TemplateServer) handleDeletePartial(w http.ResponseWriter, r *http.Request) {
+	p, ok := s.findPartial(w, r)
+	if !ok {
+		return
+	}
+
+	force := r.URL.Query().Get("force") == "true"
+	if err := s.storage.DeletePartial(r.Context(), p.ID, force); err != nil {
+		var inUse *template.PartialInUseError
+		if errors.As(err, &inUse) {
+			sendJSON(w, http.StatusConflict, PartialInUseResponse{Error: inUse.Error(), UsedBy: inUse.UsedBy})
+			return
+		}
+		sendError(w, http.StatusInternalServerError, "Failed to delete partial")
+		return
+	}
+	s.engine.Cache().Clear()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// findPartial looks up the partial of the request by ID or name. It
+// writes the error response and returns false if there is none.
+func (s *TemplateServer) findPartial(w http.ResponseWriter, r *http.Request) (*template.Partial, bool) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		sendError(w, http.StatusBadRequest, "id is required")
+		return nil, false
+	}
+
+	p, err := s.storage.GetPartial(r.Context(), id)
+	if err == nil && p == nil {
+		p, err = s.storage.GetPartialByName(r.Context(), id)
+	}
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to get partial")
+		return nil, false
+	}
+	if p == nil {
+		sendError(w, http.StatusNotFound, "Partial not found")
+		return nil, false
+	}
+	return p, true
+}
+
+func partialToResponse(p *template.Partial) *PartialResponse {
+	return &PartialResponse{
+		ID:          p.ID,
+		Name:        p.Name,
+		Description: p.Description,
+		Content:     p.Content,
+		Version:     p.Version,
+		CreatedAt:   p.CreatedAt,
+		UpdatedAt:   p.UpdatedAt,
+	}
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestPartials(t *testing.T) {
+	_, _, r := setupTemplateServer(t)
+
+	if w := doTemplateRequest(t, r, "POST", "/partials", `{"name": "text", "content": "x"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("reserved name status = %d, want 400", w.Code)
+	}
+	if w := doTemplateRequest(t, r, "POST", "/partials", `{"name": "broken", "content": "{{if .A}}"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid syntax status = %d, want 400", w.Code)
+	}
+
+	w := doTemplateRequest(t, r, "POST", "/partials", `{"name": "footer", "content": "<footer>{{.Company}}</footer>"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create partial status = %d: %s", w.Code, w.Body.String())
+	}
+	var footer PartialResponse
+	json.NewDecoder(w.Body).Decode(&footer)
+
+	w = doTemplateRequest(t, r, "POST", "/templates",
+		`{"name": "welcome", "subject": "Hi", "html": "<p>Hi</p>{{template \"footer\" .}}{{template \"legal\"}}"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create template status = %d: %s", w.Code, w.Body.String())
+	}
+	var tmpl TemplateResponse
+	json.NewDecoder(w.Body).Decode(&tmpl)
+	if len(tmpl.Warnings) != 1 || tmpl.Warnings[0].Code != "undefined_partial" || tmpl.Warnings[0].Value != "legal" {
+		t.Errorf("warnings = %+v, want undefined partial legal", tmpl.Warnings)
+	}
+
+	if w := doTemplateRequest(t, r, "POST", "/partials", `{"name": "legal", "content": "<small>Legal</small>"}`); w.Code != http.StatusCreated {
+		t.Fatalf("create partial status = %d: %s", w.Code, w.Body.String())
+	}
+
+	preview := func() string {
+		t.Helper()
+		w := doTemplateRequest(t, r, "POST", "/templates/welcome/preview", `{"data": {"Company": "Acme"}}`)
+		if w.Code != http.StatusOK {
+			t.Fatalf("preview status = %d: %s", w.Code, w.Body.String())
+		}
+		var resp TemplatePreviewResponse
+		json.NewDecoder(w.Body).Decode(&resp)
+		return resp.HTML
+	}
+	if got, want := preview(), "<p>Hi</p><footer>Acme</footer><small>Legal</small>"; got != want {
+		t.Errorf("preview = %q, want %q", got, want)
+	}
+
+	if w := doTemplateRequest(t, r, "PUT", "/partials/footer", `{"content": "<footer>{{.Company}} Inc</footer>"}`); w.Code != http.StatusOK {
+		t.Fatalf("update partial status = %d: %s", w.Code, w.Body.String())
+	}
+	if got := preview(); !strings.Contains(got, "<footer>Acme Inc</footer>") {
+		t.Errorf("preview after partial update = %q", got)
+	}
+
+	w = doTemplateRequest(t, r, "GET", "/partials/"+footer.ID, "")
+	var got PartialResponse
+	json.NewDecoder(w.Body).Decode(&got)
+	if len(got.UsedBy) != 1 || got.UsedBy[0].Name != "welcome" || got.Version != 2 {
+		t.Errorf("get partial = %+v, want version 2 used by welcome", got)
+	}
+
+	for _, req := range []struct{ method, body string }{
+		{"DELETE", ""},
+		{"PUT", `{"name": "site-footer"}`},
+	} {
+		w = doTemplateRequest(t, r, req.method, "/partials/footer", req.body)
+		if w.Code != http.StatusConflict {
+			t.Fatalf("%s partial in use status = %d, want 409", req.method, w.Code)
+		}
+		var inUse PartialInUseResponse
+		json.NewDecoder(w.Body).Decode(&inUse)
+		if len(inUse.UsedBy) != 1 || inUse.UsedBy[0].Type != "template" || inUse.UsedBy[0].Name != "welcome" {
+			t.Errorf("used_by = %+v, want template welcome", inUse.UsedBy)
+		}
+	}
+
+	if w := doTemplateRequest(t, r, "DELETE", "/partials/footer?force=true", ""); w.Code != http.StatusNoContent {
+		t.Fatalf("force delete status = %d: %s", w.Code, w.Body.String())
+	}
+	w = doTemplateRequest(t, r, "POST", "/templates/welcome/preview", `{"data": {}}`)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `partial \"footer\" not found`) {
+		t.Errorf("preview without partial = %d: %s", w.Code, w.Body.String())
+	}
+
+	w = doTemplateRequest(t, r, "GET", "/partials", "")
+	var list PartialListResponse
+	json.NewDecoder(w.Body).Decode(&list)
+	if list.Total != 1 || list.Partials[0].Name != "legal" {
+		t.Errorf("list = %+v, want only legal", list)
+	}
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// PauseRequest is the request for POST /api/v1/queue/pause and
+// POST /api/v1/queue/resume. An empty domain pauses or resumes all delivery.
+type PauseRequest struct {
+	Domain string `json:"domain,omitempty"`
+}
+
+// handlePauseStatus handles GET /api/v1/queue/pause
+func (s *Server) handlePauseStatus(w http.ResponseWriter, r *http.Request) {
+	if s.pauses == nil {
+		s.sendError(w, http.StatusNotImplemented, "Delivery pause is not available")
+		return
+	}
+	s.sendJSON(w, http.StatusOK, s.pauses.Status())
+}
+
+// handlePause handles POST /api/v1/queue/pause
+func (s *Server) handlePause(w http.ResponseWriter, r *http.Request) {
+	s.changePause(w, r, true)
+}
+
+// handleResume handles POST /api/v1/queue/resume
+func (s *Server) handleResume(w http.ResponseWriter, r *http.Request) {
+	s.changePause(w, r, false)
+}
+
+// changePause pauses or resumes delivery of the requested domain
+func (s *Server) changePause(w http.ResponseWriter, r *http.Request, pause bool) {
+	if s.pauses == nil {
+		s.sendError(w, http.StatusNotImplemented, "Delivery pause is not available")
+		return
+	}
+
+	var req PauseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		s.sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	domain, err := pauseDomain(req.Domain)
+	if err != nil {
+		s.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	before := s.pauses.Status()
+	if pause {
+		s.pauses.Pause(domain)
+		s.logger.Info("delivery paused", "domain", domain)
+	} else {
+		s.pauses.Resume(domain)
+		s.logger.Info("delivery resumed", "domain", domain)
+	}
+
+	after := s.pauses.Status()
+	recordChange(r, before, after)
+	s.sendJSON(w, http.StatusOK, after)
+}
+
+// pauseDomain normalizes the domain of a pause request, empty for all
+// delivery
+func pauseDomain(domain string) (string, error) {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	if strings.ContainsAny(domain, "@ ") {
+		return "", errors.New("domain must be a domain name")
+	}
+	return domain, nil
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/foxzi/sendry/internal/config"
+	"github.com/foxzi/sendry/internal/queue"
+)
+
+func TestPauseEndpoints(t *testing.T) {
+	pauses := queue.NewPauses()
+	pauses.SetConfigured([]string{"held.example"})
+	server := NewServerWithOptions(ServerOptions{
+		Queue:  newMockQueue(),
+		Config: &config.APIConfig{APIKey: "test-key"},
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Pauses: pauses,
+	})
+
+	do := func(method, path, body string) (int, queue.PauseStatus) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer test-key")
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+
+		var status queue.PauseStatus
+		json.NewDecoder(w.Body).Decode(&status)
+		return w.Code, status
+	}
+
+	if code, status := do("POST", "/api/v1/queue/pause", ""); code != http.StatusOK || !status.Global {
+		t.Errorf("global pause: %d %+v", code, status)
+	}
+	if code, status := do("POST", "/api/v1/queue/pause", `{"domain":"Gmail.com"}`); code != http.StatusOK || len(status.Domains) != 1 || status.Domains[0] != "gmail.com" {
+		t.Errorf("domain pause: %d %+v", code, status)
+	}
+	if code, _ := do("POST", "/api/v1/queue/pause", `{"domain":"user@gmail.com"}`); code != http.StatusBadRequest {
+		t.Errorf("address as domain: status %d, want 400", code)
+	}
+	if code, status := do("POST", "/api/v1/queue/resume", `{}`); code != http.StatusOK || status.Global || len(status.Domains) != 1 {
+		t.Errorf("global resume: %d %+v", code, status)
+	}
+
+	// Domains paused in the config stay paused
+	do("POST", "/api/v1/queue/resume", `{"domain":"held.example"}`)
+	if code, status := do("GET", "/api/v1/queue/pause", ""); code != http.StatusOK || len(status.ConfigDomains) != 1 || !pauses.Paused("held.example") {
+		t.Errorf("status: %d %+v", code, status)
+	}
+}
+
+func TestPauseUnavailable(t *testing.T) {
+	server, _ := setupTestServer("test-key")
+
+	req := httptest.NewRequest("POST", "/api/v1/queue/pause", nil)
+	req.Header.Set("Authorization", "Bearer test-key")
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("Status = %d, want %d", w.Code, http.StatusNotImplemented)
+	}
+}
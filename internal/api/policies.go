@@ -0,0 +1,139 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/foxzi/sendry/internal/addrpolicy"
+	"github.com/foxzi/sendry/internal/config"
+	"github.com/foxzi/sendry/internal/dnscheck"
+	"github.com/foxzi/sendry/internal/queue"
+)
+
+// PolicyServer handles address policy API endpoints
+type PolicyServer struct {
+	storage *addrpolicy.Storage
+}
+
+// NewPolicyServer creates a new address policy server
+func NewPolicyServer(storage *addrpolicy.Storage) *PolicyServer {
+	return &PolicyServer{storage: storage}
+}
+
+// RegisterRoutes registers address policy API routes
+func (s *PolicyServer) RegisterRoutes(r chi.Router) {
+	r.Route("/policies", func(r chi.Router) {
+		r.Get("/", s.handleList)
+		r.Get("/{scope}", s.handleGet)
+		r.Put("/{scope}", s.handlePut)
+		r.Delete("/{scope}", s.handleDelete)
+	})
+}
+
+// PolicyListResponse is the response for listing address policies
+type PolicyListResponse struct {
+	Policies []*addrpolicy.Policy `json:"policies"`
+	Total    int                  `json:"total"`
+}
+
+// handleList handles GET /api/v1/policies
+func (s *PolicyServer) handleList(w http.ResponseWriter, r *http.Request) {
+	policies, err := s.storage.List(r.Context())
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to list policies")
+		return
+	}
+
+	if policies == nil {
+		policies = []*addrpolicy.Policy{}
+	}
+
+	sendJSON(w, http.StatusOK, PolicyListResponse{
+		Policies: policies,
+		Total:    len(policies),
+	})
+}
+
+// handleGet handles GET /api/v1/policies/{scope}
+func (s *PolicyServer) handleGet(w http.ResponseWriter, r *http.Request) {
+	p, err := s.storage.Get(r.Context(), chi.URLParam(r, "scope"))
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to get policy")
+		return
+	}
+
+	if p == nil {
+		sendError(w, http.StatusNotFound, "Policy not found")
+		return
+	}
+
+	sendJSON(w, http.StatusOK, p)
+}
+
+// handlePut handles PUT /api/v1/policies/{scope}
+func (s *PolicyServer) handlePut(w http.ResponseWriter, r *http.Request) {
+	scope := addrpolicy.NormalizeScope(chi.URLParam(r, "scope"))
+	if scope != addrpolicy.GlobalScope {
+		if err := dnscheck.ValidateDomain(scope); err != nil {
+			sendError(w, http.StatusBadRequest, fmt.Sprintf("invalid scope: %s (must be global or a domain)", scope))
+			return
+		}
+	}
+
+	var req config.AddressPolicyConfig
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if err := config.ValidateAddressPolicy("policy", &req); err != nil {
+		sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	before, err := s.storage.Get(r.Context(), scope)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to get policy")
+		return
+	}
+
+	p := &addrpolicy.Policy{Scope: scope, AddressPolicyConfig: req}
+	if err := s.storage.Put(r.Context(), p); err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to save policy")
+		return
+	}
+
+	recordChange(r, before, p)
+	sendJSON(w, http.StatusOK, p)
+}
+
+// handleDelete handles DELETE /api/v1/policies/{scope}
+func (s *PolicyServer) handleDelete(w http.ResponseWriter, r *http.Request) {
+	scope := chi.URLParam(r, "scope")
+
+	before, err := s.storage.Get(r.Context(), scope)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to get policy")
+		return
+	}
+
+	if err := s.storage.Delete(r.Context(), scope); err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to delete policy")
+		return
+	}
+
+	recordChange(r, before, nil)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// checkAddressPolicy checks the envelope of a message built from an API
+// request against the allow and deny lists. A refused message returns the
+// HTTP status and error of the response, 0 otherwise.
+func checkAddressPolicy(policy *addrpolicy.Enforcer, msg *queue.Message) (int, string) {
+	if err := policy.Check(addrpolicy.SourceAPI, msg.From, msg.To); err != nil {
+		return http.StatusForbidden, err.Error()
+	}
+	return 0, ""
+}
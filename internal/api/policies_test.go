@@ -0,0 +1,92 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/foxzi/sendry/internal/addrpolicy"
+	"github.com/foxzi/sendry/internal/config"
+)
+
+func TestPoliciesAPI(t *testing.T) {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	storage, err := addrpolicy.NewStorage(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enforcer := addrpolicy.New(config.AddressPolicyConfig{}, nil, nil)
+	enforcer.SetStorage(storage)
+
+	q := newMockQueue()
+	server := NewServerWithOptions(ServerOptions{
+		Queue:         q,
+		Config:        &config.APIConfig{ListenAddr: ":8080"},
+		Logger:        slog.New(slog.NewTextHandler(io.Discard, nil)),
+		AddressPolicy: enforcer,
+		PolicyStorage: storage,
+	})
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+
+	if w := do("PUT", "/api/v1/policies/global", `{"deny_recipients": ["blocked.example"]}`); w.Code != http.StatusOK {
+		t.Fatalf("put global status = %d: %s", w.Code, w.Body.String())
+	}
+	if w := do("PUT", "/api/v1/policies/Example.com", `{"allow_senders": ["noreply-*@example.com"]}`); w.Code != http.StatusOK {
+		t.Fatalf("put domain status = %d: %s", w.Code, w.Body.String())
+	}
+	if w := do("PUT", "/api/v1/policies/not_a_domain", `{}`); w.Code != http.StatusBadRequest {
+		t.Errorf("put invalid scope status = %d, want 400", w.Code)
+	}
+	if w := do("PUT", "/api/v1/policies/global", `{"deny_senders": ["not an address"]}`); w.Code != http.StatusBadRequest {
+		t.Errorf("put invalid entry status = %d, want 400", w.Code)
+	}
+
+	w := do("GET", "/api/v1/policies", "")
+	var list PolicyListResponse
+	if err := json.NewDecoder(w.Body).Decode(&list); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if w.Code != http.StatusOK || list.Total != 2 || list.Policies[0].Scope != addrpolicy.GlobalScope || list.Policies[1].Scope != "example.com" {
+		t.Errorf("list status = %d, policies = %+v", w.Code, list.Policies)
+	}
+
+	send := func(from, to string) *httptest.ResponseRecorder {
+		return do("POST", "/api/v1/send", `{"from": "`+from+`", "to": ["`+to+`"], "subject": "Hi", "body": "Hi"}`)
+	}
+	if w := send("news@example.com", "user@example.org"); w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), "sender news@example.com") {
+		t.Errorf("send from sender outside allow list status = %d: %s", w.Code, w.Body.String())
+	}
+	if w := send("noreply-billing@example.com", "user@example.org"); w.Code != http.StatusAccepted {
+		t.Errorf("send from allowed sender status = %d: %s", w.Code, w.Body.String())
+	}
+	if w := send("ops@example.net", "user@blocked.example"); w.Code != http.StatusForbidden {
+		t.Errorf("send to denied recipient status = %d, want 403", w.Code)
+	}
+
+	if w := do("DELETE", "/api/v1/policies/example.com", ""); w.Code != http.StatusNoContent {
+		t.Errorf("delete status = %d", w.Code)
+	}
+	if w := do("GET", "/api/v1/policies/example.com", ""); w.Code != http.StatusNotFound {
+		t.Errorf("get deleted status = %d, want 404", w.Code)
+	}
+	if w := send("news@example.com", "user@example.org"); w.Code != http.StatusAccepted {
+		t.Errorf("send after delete status = %d: %s", w.Code, w.Body.String())
+	}
+}
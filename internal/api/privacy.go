@@ -0,0 +1,62 @@
+package api
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/foxzi/sendry/internal/privacy"
+)
+
+// PrivacyServer handles erasure requests for the stored data of an address
+type PrivacyServer struct {
+	purger *privacy.Purger
+	logger *slog.Logger
+}
+
+// NewPrivacyServer creates a new privacy server
+func NewPrivacyServer(purger *privacy.Purger, logger *slog.Logger) *PrivacyServer {
+	return &PrivacyServer{purger: purger, logger: logger}
+}
+
+// RegisterRoutes registers privacy routes
+func (s *PrivacyServer) RegisterRoutes(r chi.Router) {
+	r.Post("/privacy/purge", s.handlePurge)
+}
+
+// PurgeRequest is the request body for POST /privacy/purge. The address is
+// sent in the body, so it does not end up in access logs.
+type PurgeRequest struct {
+	Email   string `json:"email"`
+	Pending bool   `json:"pending,omitempty"` // Also delete messages waiting for delivery
+}
+
+func (s *PrivacyServer) handlePurge(w http.ResponseWriter, r *http.Request) {
+	var req PurgeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if !strings.Contains(req.Email, "@") {
+		sendError(w, http.StatusBadRequest, "email must be an email address")
+		return
+	}
+
+	result, err := s.purger.Purge(r.Context(), req.Email, req.Pending)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	// The address is left out, the log must not keep what was erased
+	s.logger.Info("purged messages of an address",
+		"queue", result.Queue,
+		"dlq", result.DLQ,
+		"sandbox", result.Sandbox,
+		"archive", result.Archive,
+		"skipped", result.Skipped,
+	)
+	sendJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/foxzi/sendry/internal/config"
+	"github.com/foxzi/sendry/internal/privacy"
+	"github.com/foxzi/sendry/internal/queue"
+)
+
+func TestPrivacyPurgeAPI(t *testing.T) {
+	storage, err := queue.NewBoltStorage(filepath.Join(t.TempDir(), "queue.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storage.Close()
+	ctx := context.Background()
+	for id, status := range map[string]queue.MessageStatus{"delivered": queue.StatusDelivered, "pending": queue.StatusPending} {
+		msg := &queue.Message{ID: id, From: "shop@example.org", To: []string{"user@example.com"}, Status: status, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+		if err := storage.Enqueue(ctx, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	server := NewServerWithOptions(ServerOptions{
+		Queue:  newMockQueue(),
+		Config: &config.APIConfig{ListenAddr: ":8080"},
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Purger: privacy.NewPurger(storage, nil, nil),
+	})
+	post := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, httptest.NewRequest("POST", "/api/v1/privacy/purge", strings.NewReader(body)))
+		return w
+	}
+
+	if w := post(`{"email":"user"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid address status = %d, want 400", w.Code)
+	}
+
+	w := post(`{"email":"user@example.com"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("purge status = %d: %s", w.Code, w.Body)
+	}
+	var result privacy.PurgeResult
+	if err := json.NewDecoder(w.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Queue != 1 || result.Skipped != 1 || result.Total != 1 {
+		t.Errorf("result = %+v, want 1 deleted and 1 skipped", result)
+	}
+	if msg, _ := storage.Get(ctx, "pending"); msg == nil {
+		t.Error("pending message deleted")
+	}
+}
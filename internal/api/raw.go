@@ -0,0 +1,241 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"mime"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/foxzi/sendry/internal/headers"
+	"github.com/foxzi/sendry/internal/queue"
+	"github.com/foxzi/sendry/internal/replication"
+)
+
+// rawMediaType is the content type of POST /api/v1/send/raw
+const rawMediaType = "message/rfc822"
+
+// rawEnvelope is the envelope of a raw message, from the query string or
+// the message headers
+type rawEnvelope struct {
+	from     string
+	to       []string
+	sendAt   *time.Time
+	priority queue.Priority
+	skipDKIM bool
+}
+
+// handleSendRaw handles POST /api/v1/send/raw. The body is a complete MIME
+// message. It is not streamed to storage: queue messages carry their data,
+// which DKIM signing and header rules need whole, so the message is read
+// into memory once and refused as soon as it exceeds the size limit. The
+// hash of an Idempotency-Key is computed while reading.
+func (s *Server) handleSendRaw(w http.ResponseWriter, r *http.Request) {
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != rawMediaType {
+		s.sendError(w, http.StatusUnsupportedMediaType, "Content-Type must be "+rawMediaType)
+		return
+	}
+
+	env, err := parseRawEnvelope(r)
+	if err != nil {
+		s.sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	maxSize := s.maxMessageBytes()
+	if r.ContentLength > int64(maxSize) {
+		s.sendError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("email content too large (max %d bytes)", maxSize))
+		return
+	}
+	idem := idempotentRequestFromContext(r.Context())
+	var sum hash.Hash
+	if idem != nil {
+		sum = sha256.New()
+	}
+	data, err := readRawMessage(w, r, maxSize, sum)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			s.sendError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("email content too large (max %d bytes)", maxSize))
+			return
+		}
+		s.sendError(w, http.StatusBadRequest, "Failed to read message")
+		return
+	}
+	if idem != nil && !s.claimIdempotency(r.Context(), w, idem, sum.Sum(nil)) {
+		return
+	}
+
+	msg, status, errMsg := s.buildRawMessage(r.Context(), env, data, r.RemoteAddr)
+	if msg == nil {
+		s.sendError(w, status, errMsg)
+		return
+	}
+
+	if err := s.queue.Enqueue(r.Context(), msg); err != nil {
+		if errors.Is(err, replication.ErrStandby) {
+			s.sendError(w, http.StatusServiceUnavailable, err.Error())
+			return
+		}
+		s.logger.Error("failed to enqueue message", "error", err)
+		s.sendError(w, http.StatusInternalServerError, "Failed to queue message")
+		return
+	}
+
+	s.logger.Info("raw message queued via API",
+		"id", msg.ID,
+		"from", msg.From,
+		"to", msg.To,
+		"size", len(msg.Data),
+	)
+
+	s.sendJSON(w, http.StatusAccepted, SendResponse{
+		ID:     msg.ID,
+		Status: string(msg.Status),
+		SendAt: scheduledAt(msg),
+	})
+}
+
+// buildRawMessage completes the envelope of a raw message from its headers
+// and builds its queue message. On refusal it returns the HTTP status and
+// error message.
+func (s *Server) buildRawMessage(ctx context.Context, env *rawEnvelope, data []byte, remoteAddr string) (*queue.Message, int, string) {
+	data, status, errMsg := completeRawEnvelope(env, data)
+	if status != 0 {
+		return nil, status, errMsg
+	}
+
+	now := time.Now()
+	msg := &queue.Message{
+		ID:        uuid.New().String(),
+		From:      env.from,
+		To:        env.to,
+		Data:      data,
+		Status:    queue.StatusPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+		ClientIP:  remoteAddr,
+		Priority:  env.priority,
+	}
+	if status, errMsg := s.prepareMessage(ctx, msg, env.sendAt, env.skipDKIM); status != 0 {
+		return nil, status, errMsg
+	}
+	return msg, http.StatusAccepted, ""
+}
+
+// readRawMessage reads the request body, chunked or not, into memory and
+// fails with *http.MaxBytesError once it exceeds maxSize without reading the
+// rest. The body is written to sum as it is read unless sum is nil.
+func readRawMessage(w http.ResponseWriter, r *http.Request, maxSize int, sum hash.Hash) ([]byte, error) {
+	var buf bytes.Buffer
+	if r.ContentLength > 0 {
+		buf.Grow(int(r.ContentLength))
+	}
+	var body io.Reader = http.MaxBytesReader(w, r.Body, int64(maxSize))
+	if sum != nil {
+		body = io.TeeReader(body, sum)
+	}
+	if _, err := buf.ReadFrom(body); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// parseRawEnvelope parses the envelope parameters of the query string
+func parseRawEnvelope(r *http.Request) (*rawEnvelope, error) {
+	params := r.URL.Query()
+	env := &rawEnvelope{from: params.Get("from")}
+	for _, value := range params["to"] {
+		for _, to := range strings.Split(value, ",") {
+			if to = strings.TrimSpace(to); to != "" {
+				env.to = append(env.to, to)
+			}
+		}
+	}
+	if err := checkRawEnvelope(env); err != nil {
+		return nil, err
+	}
+
+	if value := params.Get("send_at"); value != "" {
+		sendAt, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, errors.New("invalid send_at (must be RFC 3339)")
+		}
+		env.sendAt = &sendAt
+	}
+
+	var err error
+	if env.priority, err = queue.ParsePriority(params.Get("priority")); err != nil {
+		return nil, err
+	}
+	if value := params.Get("skip_dkim"); value != "" {
+		if env.skipDKIM, err = strconv.ParseBool(value); err != nil {
+			return nil, errors.New("invalid skip_dkim")
+		}
+	}
+	return env, nil
+}
+
+// checkRawEnvelope checks the addresses given with a raw message
+func checkRawEnvelope(env *rawEnvelope) error {
+	if env.from != "" {
+		if _, err := mail.ParseAddress(env.from); err != nil {
+			return errors.New("invalid from address")
+		}
+	}
+	for _, to := range env.to {
+		if _, err := mail.ParseAddress(to); err != nil {
+			return fmt.Errorf("invalid to address: %s", to)
+		}
+	}
+	return nil
+}
+
+// completeRawEnvelope checks the message headers and takes the sender and
+// recipients missing from the query string from the From, To, Cc and Bcc
+// headers. The Bcc header is removed so recipients do not see it.
+func completeRawEnvelope(env *rawEnvelope, data []byte) ([]byte, int, string) {
+	parsed, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return nil, http.StatusBadRequest, "invalid message: " + err.Error()
+	}
+
+	if env.from == "" {
+		from, err := parsed.Header.AddressList("From")
+		if err != nil || len(from) == 0 {
+			return nil, http.StatusBadRequest, "from is required (query parameter or From header)"
+		}
+		env.from = from[0].Address
+	}
+	if len(env.to) == 0 {
+		for _, name := range []string{"To", "Cc", "Bcc"} {
+			list, err := parsed.Header.AddressList(name)
+			if err != nil && !errors.Is(err, mail.ErrHeaderNotPresent) {
+				return nil, http.StatusBadRequest, fmt.Sprintf("invalid %s header: %v", name, err)
+			}
+			for _, addr := range list {
+				env.to = append(env.to, addr.Address)
+			}
+		}
+		if len(env.to) == 0 {
+			return nil, http.StatusBadRequest, "to is required (query parameter or To, Cc, Bcc headers)"
+		}
+	}
+
+	if parsed.Header.Get("Bcc") != "" {
+		data = headers.Apply(data, []headers.Rule{
+			{Action: headers.ActionRemove, Headers: []string{"Bcc"}},
+		})
+	}
+	return data, 0, ""
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/foxzi/sendry/internal/config"
+	"github.com/foxzi/sendry/internal/queue"
+)
+
+const rawTestMessage = "From: Sender <sender@example.com>\r\n" +
+	"To: a@example.com, B <b@example.com>\r\n" +
+	"Bcc: hidden@example.com\r\n" +
+	"Subject: Raw\r\n" +
+	"\r\n" +
+	"Hello\r\n"
+
+func sendRaw(server *Server, query string, body io.Reader) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/api/v1/send/raw"+query, body)
+	req.Header.Set("Authorization", "Bearer test-api-key")
+	req.Header.Set("Content-Type", "message/rfc822")
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestSendRaw(t *testing.T) {
+	server, q := setupTestServer("test-api-key")
+
+	w := sendRaw(server, "", strings.NewReader(rawTestMessage))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("Status = %d, want %d: %s", w.Code, http.StatusAccepted, w.Body.String())
+	}
+	var resp SendResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := q.messages[resp.ID]
+	if msg == nil {
+		t.Fatal("message was not queued")
+	}
+	if msg.From != "sender@example.com" {
+		t.Errorf("From = %q, want sender@example.com", msg.From)
+	}
+	want := []string{"a@example.com", "b@example.com", "hidden@example.com"}
+	if strings.Join(msg.To, ",") != strings.Join(want, ",") {
+		t.Errorf("To = %v, want %v", msg.To, want)
+	}
+	if bytes.Contains(msg.Data, []byte("Bcc:")) {
+		t.Error("Bcc header was not removed")
+	}
+	if !bytes.Contains(msg.Data, []byte("Subject: Raw\r\n")) {
+		t.Errorf("message data changed: %q", msg.Data)
+	}
+}
+
+func TestSendRaw_QueryEnvelope(t *testing.T) {
+	server, q := setupTestServer("test-api-key")
+
+	w := sendRaw(server, "?from=bounce@example.com&to=x@example.net,y@example.net&priority=high&send_at=2099-01-01T00:00:00Z",
+		strings.NewReader(rawTestMessage))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("Status = %d: %s", w.Code, w.Body.String())
+	}
+	var resp SendResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+
+	msg := q.messages[resp.ID]
+	if msg.From != "bounce@example.com" || strings.Join(msg.To, ",") != "x@example.net,y@example.net" {
+		t.Errorf("envelope = %s %v, want the query parameters", msg.From, msg.To)
+	}
+	if msg.Priority != queue.PriorityHigh {
+		t.Errorf("Priority = %q, want high", msg.Priority)
+	}
+	if msg.Status != queue.StatusDeferred || resp.SendAt == nil {
+		t.Errorf("Status = %q, want a scheduled message", msg.Status)
+	}
+}
+
+func TestSendRaw_TooLarge(t *testing.T) {
+	server, q := setupTestServer("test-api-key")
+	server.fullConfig = &config.Config{SMTP: config.SMTPConfig{MaxMessageBytes: 1024}}
+
+	large := rawTestMessage + strings.Repeat("x", 2048)
+
+	// With Content-Length the request is refused before reading the body
+	if w := sendRaw(server, "", strings.NewReader(large)); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Status = %d, want 413", w.Code)
+	}
+
+	// A chunked body is refused once the limit is reached
+	if w := sendRaw(server, "", io.MultiReader(strings.NewReader(large))); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("chunked Status = %d, want 413", w.Code)
+	}
+
+	if len(q.messages) != 0 {
+		t.Errorf("%d messages queued, want 0", len(q.messages))
+	}
+}
+
+func TestSendRaw_Invalid(t *testing.T) {
+	server, _ := setupTestServer("test-api-key")
+
+	tests := []struct {
+		name   string
+		query  string
+		body   string
+		status int
+	}{
+		{"no recipients", "", "From: a@example.com\r\nSubject: x\r\n\r\nbody", http.StatusBadRequest},
+		{"no sender", "", "To: a@example.com\r\n\r\nbody", http.StatusBadRequest},
+		{"invalid to", "?to=not-an-address", rawTestMessage, http.StatusBadRequest},
+		{"invalid priority", "?priority=urgent", rawTestMessage, http.StatusBadRequest},
+		{"invalid send_at", "?send_at=tomorrow", rawTestMessage, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := sendRaw(server, tt.query, strings.NewReader(tt.body)); w.Code != tt.status {
+				t.Errorf("Status = %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+		})
+	}
+
+	req := httptest.NewRequest("POST", "/api/v1/send/raw", strings.NewReader(rawTestMessage))
+	req.Header.Set("Authorization", "Bearer test-api-key")
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("wrong content type Status = %d, want 415", w.Code)
+	}
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"sort"
+)
+
+// ConfigReloader re-reads the config and applies the settings that can
+// change at runtime
+type ConfigReloader interface {
+	Reload(ctx context.Context) error
+}
+
+// ConfigReloadResponse is the response for POST /api/v1/config/reload
+type ConfigReloadResponse struct {
+	Status  string   `json:"status"`
+	Domains []string `json:"domains"`
+}
+
+// handleConfigReload handles POST /api/v1/config/reload
+func (m *ManagementServer) handleConfigReload(w http.ResponseWriter, r *http.Request) {
+	if m.reloader == nil {
+		sendError(w, http.StatusNotImplemented, "Config reload is not available")
+		return
+	}
+
+	if err := m.reloader.Reload(r.Context()); err != nil {
+		sendError(w, http.StatusInternalServerError, "Config reload failed: "+err.Error())
+		return
+	}
+
+	domains := m.domainManager.ListDomains()
+	sort.Strings(domains)
+	sendJSON(w, http.StatusOK, ConfigReloadResponse{
+		Status:  "reloaded",
+		Domains: domains,
+	})
+}
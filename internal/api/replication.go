@@ -0,0 +1,81 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/foxzi/sendry/internal/replication"
+)
+
+// ReplicationServer handles queue replication API endpoints
+type ReplicationServer struct {
+	primary *replication.Primary
+	standby *replication.Standby
+	timeout time.Duration // Read timeout of one stream
+}
+
+// NewReplicationServer creates a new replication server for the node's role,
+// one of primary and standby is nil
+func NewReplicationServer(primary *replication.Primary, standby *replication.Standby, timeout time.Duration) *ReplicationServer {
+	return &ReplicationServer{primary: primary, standby: standby, timeout: timeout}
+}
+
+// RegisterRoutes registers replication API routes
+func (s *ReplicationServer) RegisterRoutes(r chi.Router) {
+	r.Route("/replication", func(r chi.Router) {
+		r.Get("/status", s.handleStatus)
+		r.Post("/stream", s.handleStream)
+		r.Post("/promote", s.handlePromote)
+	})
+}
+
+// handleStatus handles GET /api/v1/replication/status
+func (s *ReplicationServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if s.primary != nil {
+		sendJSON(w, http.StatusOK, s.primary.Status())
+		return
+	}
+	sendJSON(w, http.StatusOK, s.standby.Status())
+}
+
+// handleStream handles POST /api/v1/replication/stream, the changes of the
+// primary's queue as JSON lines
+func (s *ReplicationServer) handleStream(w http.ResponseWriter, r *http.Request) {
+	if s.standby == nil {
+		sendError(w, http.StatusServiceUnavailable, replication.ErrNotStandby.Error())
+		return
+	}
+
+	// A snapshot of a large queue takes longer than the API read timeout
+	if s.timeout > 0 {
+		http.NewResponseController(w).SetReadDeadline(time.Now().Add(s.timeout))
+	}
+
+	applied, err := s.standby.Receive(r.Context(), r.Body, r.RemoteAddr)
+	switch {
+	case errors.Is(err, replication.ErrSnapshotRequired):
+		sendError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, replication.ErrNotStandby):
+		sendError(w, http.StatusServiceUnavailable, err.Error())
+	case err != nil:
+		sendError(w, http.StatusBadRequest, err.Error())
+	default:
+		sendJSON(w, http.StatusOK, map[string]int{"applied": applied})
+	}
+}
+
+// handlePromote handles POST /api/v1/replication/promote
+func (s *ReplicationServer) handlePromote(w http.ResponseWriter, r *http.Request) {
+	if s.standby == nil {
+		sendError(w, http.StatusConflict, replication.ErrNotStandby.Error())
+		return
+	}
+	if err := s.standby.Promote(); err != nil {
+		sendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	sendJSON(w, http.StatusOK, s.standby.Status())
+}
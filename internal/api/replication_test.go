@@ -0,0 +1,114 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/foxzi/sendry/internal/config"
+	"github.com/foxzi/sendry/internal/queue"
+	"github.com/foxzi/sendry/internal/replication"
+)
+
+func TestReplicationAPI(t *testing.T) {
+	dir := t.TempDir()
+	storage, err := queue.NewBoltStorage(filepath.Join(dir, "queue.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storage.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	standby, err := replication.NewStandby(storage, replication.StandbyConfig{StatePath: filepath.Join(dir, "queue.db.promoted")}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServerWithOptions(ServerOptions{
+		Queue:              standby,
+		Config:             &config.APIConfig{ListenAddr: ":8080"},
+		Logger:             logger,
+		ReplicationStandby: standby,
+	})
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+	status := func() replication.Status {
+		t.Helper()
+		w := do("GET", "/api/v1/replication/status", "")
+		var st replication.Status
+		if err := json.NewDecoder(w.Body).Decode(&st); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("status = %d, %v", w.Code, err)
+		}
+		return st
+	}
+
+	if st := status(); st.Role != replication.RoleStandby || st.Connected {
+		t.Errorf("initial status = %+v", st)
+	}
+
+	// Changes before a full copy are refused
+	if w := do("POST", replication.StreamPath, `{"op":"heartbeat"}`); w.Code != http.StatusConflict {
+		t.Errorf("heartbeat before snapshot status = %d, want 409", w.Code)
+	}
+
+	msg, _ := json.Marshal(replication.Record{Op: replication.OpPut, Message: &queue.Message{
+		ID:        "m1",
+		From:      "sender@example.com",
+		To:        []string{"rcpt@example.com"},
+		Status:    queue.StatusPending,
+		CreatedAt: time.Now(),
+	}})
+	stream := `{"op":"snapshot"}` + "\n" + string(msg) + "\n" + `{"op":"snapshot_end"}` + "\n"
+	w := do("POST", replication.StreamPath, stream)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"applied":1`) {
+		t.Fatalf("snapshot status = %d: %s", w.Code, w.Body.String())
+	}
+	if m, _ := storage.Get(t.Context(), "m1"); m == nil {
+		t.Error("replicated message is not stored")
+	}
+	if w := do("POST", replication.StreamPath, "not json"); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid stream status = %d, want 400", w.Code)
+	}
+
+	// A standby does not accept mail
+	send := `{"from":"sender@example.com","to":["rcpt@example.com"],"subject":"Hi","body":"Hello"}`
+	if w := do("POST", "/api/v1/send", send); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("send to standby status = %d, want 503", w.Code)
+	}
+
+	if w := do("POST", "/api/v1/replication/promote", ""); w.Code != http.StatusOK {
+		t.Fatalf("promote status = %d: %s", w.Code, w.Body.String())
+	}
+	if st := status(); st.Role != replication.RolePromoted || st.PromotedAt == nil {
+		t.Errorf("promoted status = %+v", st)
+	}
+	if w := do("POST", replication.StreamPath, `{"op":"heartbeat"}`); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("stream to promoted standby status = %d, want 503", w.Code)
+	}
+	if w := do("POST", "/api/v1/send", send); w.Code != http.StatusAccepted && w.Code != http.StatusOK {
+		t.Errorf("send to promoted standby status = %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestReplicationAPI_Disabled(t *testing.T) {
+	server := NewServerWithOptions(ServerOptions{
+		Queue:  newMockQueue(),
+		Config: &config.APIConfig{ListenAddr: ":8080"},
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/replication/status", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status without replication = %d, want 404", w.Code)
+	}
+}
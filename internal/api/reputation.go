@@ -0,0 +1,141 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/foxzi/sendry/internal/dnscheck"
+	"github.com/foxzi/sendry/internal/reputation"
+)
+
+// maxHistoryHours limits the score history returned for a domain
+const maxHistoryHours = 24 * 90
+
+// ReputationServer handles sending reputation API endpoints
+type ReputationServer struct {
+	storage *reputation.Storage
+}
+
+// NewReputationServer creates a new reputation server
+func NewReputationServer(storage *reputation.Storage) *ReputationServer {
+	return &ReputationServer{storage: storage}
+}
+
+// RegisterRoutes registers reputation API routes
+func (s *ReputationServer) RegisterRoutes(r chi.Router) {
+	r.Route("/reputation", func(r chi.Router) {
+		r.Get("/", s.handleList)
+		r.Get("/{domain}", s.handleGet)
+		r.Post("/{domain}/feedback", s.handleFeedback)
+	})
+}
+
+// ReputationListResponse is the response for listing domain scores
+type ReputationListResponse struct {
+	Domains []*reputation.Score `json:"domains"`
+	Total   int                 `json:"total"`
+}
+
+// ReputationResponse is the score of a domain with its history
+type ReputationResponse struct {
+	*reputation.Score
+	History []reputation.Point `json:"history"`
+}
+
+// FeedbackRequest reports complaints and unsubscribes of a sender domain
+type FeedbackRequest struct {
+	Complaints   int `json:"complaints"`
+	Unsubscribes int `json:"unsubscribes"`
+}
+
+// handleList handles GET /api/v1/reputation
+func (s *ReputationServer) handleList(w http.ResponseWriter, r *http.Request) {
+	scores, err := s.storage.List(r.Context())
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to list reputation scores")
+		return
+	}
+
+	if scores == nil {
+		scores = []*reputation.Score{}
+	}
+
+	sendJSON(w, http.StatusOK, ReputationListResponse{
+		Domains: scores,
+		Total:   len(scores),
+	})
+}
+
+// handleGet handles GET /api/v1/reputation/{domain}
+func (s *ReputationServer) handleGet(w http.ResponseWriter, r *http.Request) {
+	domain := chi.URLParam(r, "domain")
+
+	hours := 7 * 24
+	if v := r.URL.Query().Get("hours"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxHistoryHours {
+			sendError(w, http.StatusBadRequest, fmt.Sprintf("hours must be between 1 and %d", maxHistoryHours))
+			return
+		}
+		hours = n
+	}
+
+	score, err := s.storage.Get(r.Context(), domain)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to get reputation score")
+		return
+	}
+	if score == nil {
+		sendError(w, http.StatusNotFound, "Domain has not been scored")
+		return
+	}
+
+	history, err := s.storage.History(r.Context(), domain, time.Now().Add(-time.Duration(hours)*time.Hour))
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to get score history")
+		return
+	}
+	if history == nil {
+		history = []reputation.Point{}
+	}
+
+	sendJSON(w, http.StatusOK, ReputationResponse{Score: score, History: history})
+}
+
+// handleFeedback handles POST /api/v1/reputation/{domain}/feedback
+func (s *ReputationServer) handleFeedback(w http.ResponseWriter, r *http.Request) {
+	domain := strings.ToLower(chi.URLParam(r, "domain"))
+	if err := dnscheck.ValidateDomain(domain); err != nil {
+		sendError(w, http.StatusBadRequest, fmt.Sprintf("invalid domain: %s", domain))
+		return
+	}
+
+	var req FeedbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Complaints < 0 || req.Unsubscribes < 0 {
+		sendError(w, http.StatusBadRequest, "complaints and unsubscribes must not be negative")
+		return
+	}
+	if req.Complaints == 0 && req.Unsubscribes == 0 {
+		sendError(w, http.StatusBadRequest, "complaints or unsubscribes is required")
+		return
+	}
+
+	counts := &reputation.Counts{Complaints: req.Complaints, Unsubscribes: req.Unsubscribes}
+	if err := s.storage.Add(r.Context(), domain, time.Now(), counts); err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to record feedback")
+		return
+	}
+
+	sendJSON(w, http.StatusAccepted, map[string]string{"status": "recorded"})
+}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/foxzi/sendry/internal/config"
+	"github.com/foxzi/sendry/internal/reputation"
+)
+
+func setupReputationServer(t *testing.T) (*Server, *reputation.Storage) {
+	t.Helper()
+
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	storage, err := reputation.NewStorage(db)
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+
+	server := NewServerWithOptions(ServerOptions{
+		Queue:             newMockQueue(),
+		Config:            &config.APIConfig{ListenAddr: ":8080"},
+		Logger:            slog.New(slog.NewTextHandler(io.Discard, nil)),
+		ReputationStorage: storage,
+	})
+	return server, storage
+}
+
+func TestReputationAPI(t *testing.T) {
+	server, storage := setupReputationServer(t)
+	ctx := context.Background()
+
+	now := time.Now()
+	for i, score := range []int{95, 70} {
+		s := &reputation.Score{Domain: "example.com", Score: score, UpdatedAt: now.Add(time.Duration(i-1) * time.Hour)}
+		if err := storage.Save(ctx, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+
+	w := do("GET", "/api/v1/reputation", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("list status = %d: %s", w.Code, w.Body.String())
+	}
+	var list ReputationListResponse
+	if err := json.NewDecoder(w.Body).Decode(&list); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if list.Total != 1 || list.Domains[0].Score != 70 {
+		t.Errorf("list = %+v", list)
+	}
+
+	w = do("GET", "/api/v1/reputation/example.com?hours=24", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("get status = %d: %s", w.Code, w.Body.String())
+	}
+	var resp ReputationResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Score.Score != 70 || len(resp.History) != 2 || resp.History[0].Score != 95 {
+		t.Errorf("get = %+v, history %+v", resp.Score, resp.History)
+	}
+
+	if w := do("GET", "/api/v1/reputation/unknown.com", ""); w.Code != http.StatusNotFound {
+		t.Errorf("unknown domain status = %d, want 404", w.Code)
+	}
+	if w := do("GET", "/api/v1/reputation/example.com?hours=0", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid hours status = %d, want 400", w.Code)
+	}
+}
+
+func TestReputationFeedback(t *testing.T) {
+	server, storage := setupReputationServer(t)
+
+	do := func(path, body string) int {
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, httptest.NewRequest("POST", path, strings.NewReader(body)))
+		return w.Code
+	}
+
+	if code := do("/api/v1/reputation/example.com/feedback", `{"complaints": 2, "unsubscribes": 5}`); code != http.StatusAccepted {
+		t.Fatalf("feedback status = %d", code)
+	}
+	if code := do("/api/v1/reputation/example.com/feedback", `{}`); code != http.StatusBadRequest {
+		t.Errorf("empty feedback status = %d, want 400", code)
+	}
+	if code := do("/api/v1/reputation/example.com/feedback", `{"complaints": -1}`); code != http.StatusBadRequest {
+		t.Errorf("negative feedback status = %d, want 400", code)
+	}
+	if code := do("/api/v1/reputation/not_a_domain/feedback", `{"complaints": 1}`); code != http.StatusBadRequest {
+		t.Errorf("invalid domain status = %d, want 400", code)
+	}
+
+	counts, err := storage.Sum(context.Background(), "example.com", time.Now().Add(-time.Hour))
+	if err != nil || counts.Complaints != 2 || counts.Unsubscribes != 5 {
+		t.Errorf("Sum() = %+v, %v", counts, err)
+	}
+}
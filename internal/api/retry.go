@@ -0,0 +1,47 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/foxzi/sendry/internal/retry"
+)
+
+// RetryClassPolicy is the effective retry policy of an error class
+type RetryClassPolicy struct {
+	Policy   retry.Policy `json:"policy"`
+	Schedule []string     `json:"schedule"` // Delays of all retries, without jitter
+}
+
+// RetryPolicyResponse is the response for GET /api/v1/retry/{domain}
+type RetryPolicyResponse struct {
+	Domain  string                      `json:"domain"`
+	Classes map[string]RetryClassPolicy `json:"classes"`
+}
+
+// handleRetryPolicy handles GET /api/v1/retry/{domain}: the retry policies
+// deliveries to a recipient domain follow, per error class
+func (m *ManagementServer) handleRetryPolicy(w http.ResponseWriter, r *http.Request) {
+	if m.retries == nil {
+		sendError(w, http.StatusNotFound, "Retry policies are not available")
+		return
+	}
+
+	domainName := strings.ToLower(chi.URLParam(r, "domain"))
+	response := RetryPolicyResponse{
+		Domain:  domainName,
+		Classes: make(map[string]RetryClassPolicy, len(retry.Classes)),
+	}
+	for _, class := range retry.Classes {
+		p := m.retries.Policy(domainName, class)
+		schedule := make([]string, 0, p.MaxRetries)
+		for _, d := range p.Schedule() {
+			schedule = append(schedule, d.String())
+		}
+		response.Classes[class] = RetryClassPolicy{Policy: p, Schedule: schedule}
+	}
+
+	sendJSON(w, http.StatusOK, response)
+}
@@ -1,12 +1,17 @@
 package api
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 
+	"github.com/foxzi/sendry/internal/mailauth"
 	"github.com/foxzi/sendry/internal/queue"
 	"github.com/foxzi/sendry/internal/sandbox"
 )
@@ -15,6 +20,10 @@ import (
 type SandboxServer struct {
 	storage *sandbox.Storage
 	queue   queue.Queue
+	dkim    *mailauth.Verifier
+
+	maxAge   time.Duration
+	maxCount int
 }
 
 // NewSandboxServer creates a new sandbox server
@@ -25,6 +34,18 @@ func NewSandboxServer(storage *sandbox.Storage, q queue.Queue) *SandboxServer {
 	}
 }
 
+// SetDKIMVerifier sets the verifier of the DKIM signatures of captured
+// messages
+func (s *SandboxServer) SetDKIMVerifier(v *mailauth.Verifier) {
+	s.dkim = v
+}
+
+// SetRetention sets the retention limits reported by the stats endpoint
+func (s *SandboxServer) SetRetention(maxAge time.Duration, maxCount int) {
+	s.maxAge = maxAge
+	s.maxCount = maxCount
+}
+
 // RegisterRoutes registers sandbox API routes
 func (s *SandboxServer) RegisterRoutes(r chi.Router) {
 	r.Route("/sandbox", func(r chi.Router) {
@@ -34,6 +55,8 @@ func (s *SandboxServer) RegisterRoutes(r chi.Router) {
 		r.Delete("/messages", s.handleClear)
 		r.Delete("/messages/{id}", s.handleDelete)
 		r.Post("/messages/{id}/resend", s.handleResend)
+		r.Post("/messages/{id}/release", s.handleRelease)
+		r.Post("/release", s.handleReleaseBulk)
 		r.Get("/stats", s.handleStats)
 	})
 }
@@ -52,6 +75,22 @@ type SandboxMessageResponse struct {
 	SimulatedErr string    `json:"simulated_error,omitempty"`
 }
 
+// newSandboxMessageResponse returns the API representation of a captured message
+func newSandboxMessageResponse(msg *sandbox.Message) *SandboxMessageResponse {
+	return &SandboxMessageResponse{
+		ID:           msg.ID,
+		From:         msg.From,
+		To:           msg.To,
+		OriginalTo:   msg.OriginalTo,
+		Subject:      msg.Subject,
+		Domain:       msg.Domain,
+		Mode:         msg.Mode,
+		CapturedAt:   msg.CapturedAt,
+		ClientIP:     msg.ClientIP,
+		SimulatedErr: msg.SimulatedErr,
+	}
+}
+
 // SandboxListResponse is the response for GET /api/v1/sandbox/messages
 type SandboxListResponse struct {
 	Messages []*SandboxMessageResponse `json:"messages"`
@@ -69,6 +108,7 @@ func (s *SandboxServer) handleList(w http.ResponseWriter, r *http.Request) {
 		Domain: r.URL.Query().Get("domain"),
 		Mode:   r.URL.Query().Get("mode"),
 		From:   r.URL.Query().Get("from"),
+		Search: r.URL.Query().Get("q"),
 		Limit:  100, // Default limit
 	}
 
@@ -102,18 +142,7 @@ func (s *SandboxServer) handleList(w http.ResponseWriter, r *http.Request) {
 	}
 
 	for i, msg := range messages {
-		response.Messages[i] = &SandboxMessageResponse{
-			ID:           msg.ID,
-			From:         msg.From,
-			To:           msg.To,
-			OriginalTo:   msg.OriginalTo,
-			Subject:      msg.Subject,
-			Domain:       msg.Domain,
-			Mode:         msg.Mode,
-			CapturedAt:   msg.CapturedAt,
-			ClientIP:     msg.ClientIP,
-			SimulatedErr: msg.SimulatedErr,
-		}
+		response.Messages[i] = newSandboxMessageResponse(msg)
 	}
 
 	sendJSON(w, http.StatusOK, response)
@@ -122,10 +151,27 @@ func (s *SandboxServer) handleList(w http.ResponseWriter, r *http.Request) {
 // SandboxMessageDetailResponse is the response for GET /api/v1/sandbox/messages/{id}
 type SandboxMessageDetailResponse struct {
 	SandboxMessageResponse
-	Headers map[string]string `json:"headers,omitempty"`
-	Body    string            `json:"body,omitempty"`
-	HTML    string            `json:"html,omitempty"`
-	Size    int               `json:"size"`
+	Headers     map[string]string    `json:"headers,omitempty"`
+	HeaderList  []SandboxHeader      `json:"header_list,omitempty"`
+	Body        string               `json:"body,omitempty"`
+	HTML        string               `json:"html,omitempty"` // Sanitized for display
+	Attachments []sandbox.Attachment `json:"attachments,omitempty"`
+	DKIMSigned  bool                 `json:"dkim_signed"`
+	DKIM        []SandboxDKIMResult  `json:"dkim"` // null when DKIM is not verified
+	Size        int                  `json:"size"`
+}
+
+// SandboxHeader is a header field of a captured message
+type SandboxHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// SandboxDKIMResult is the verification of one DKIM signature
+type SandboxDKIMResult struct {
+	Domain string `json:"domain,omitempty"`
+	Result string `json:"result"` // pass, fail, temperror, permerror
+	Reason string `json:"reason,omitempty"`
 }
 
 // handleGet handles GET /api/v1/sandbox/messages/{id}
@@ -152,26 +198,34 @@ func (s *SandboxServer) handleGet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse headers and body
-	headers, body, html := parseEmailData(msg.Data)
+	rendered := sandbox.Render(msg.Data)
 
 	response := SandboxMessageDetailResponse{
-		SandboxMessageResponse: SandboxMessageResponse{
-			ID:           msg.ID,
-			From:         msg.From,
-			To:           msg.To,
-			OriginalTo:   msg.OriginalTo,
-			Subject:      msg.Subject,
-			Domain:       msg.Domain,
-			Mode:         msg.Mode,
-			CapturedAt:   msg.CapturedAt,
-			ClientIP:     msg.ClientIP,
-			SimulatedErr: msg.SimulatedErr,
-		},
-		Headers: headers,
-		Body:    body,
-		HTML:    html,
-		Size:    len(msg.Data),
+		SandboxMessageResponse: *newSandboxMessageResponse(msg),
+		Headers:     make(map[string]string),
+		Body:        rendered.Text,
+		HTML:        rendered.HTML,
+		Attachments: rendered.Attachments,
+		DKIMSigned:  msg.DKIMSigned,
+		Size:        len(msg.Data),
+	}
+	for _, f := range rendered.Headers {
+		// The map keeps the first field of each name, the list keeps all
+		if _, ok := response.Headers[f.Name]; !ok {
+			response.Headers[f.Name] = f.Value
+		}
+		response.HeaderList = append(response.HeaderList, SandboxHeader{Name: f.Name, Value: f.Value})
+	}
+
+	if s.dkim != nil {
+		response.DKIM = []SandboxDKIMResult{}
+		for _, res := range s.dkim.VerifyDKIM(r.Context(), msg.Data) {
+			response.DKIM = append(response.DKIM, SandboxDKIMResult{
+				Domain: res.Domain,
+				Result: string(res.Value),
+				Reason: res.Reason,
+			})
+		}
 	}
 
 	sendJSON(w, http.StatusOK, response)
@@ -311,6 +365,11 @@ func (s *SandboxServer) handleResend(w http.ResponseWriter, r *http.Request) {
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 		ClientIP:  msg.ClientIP,
+		// A signed capture is not signed again on delivery
+		DKIMSigned: msg.DKIMSigned,
+		// The resent message gets the override of its API key again
+		APIKeyID:   msg.APIKeyID,
+		APIKeyName: msg.APIKeyName,
 	}
 
 	if err := s.queue.Enqueue(r.Context(), queueMsg); err != nil {
@@ -324,159 +383,206 @@ func (s *SandboxServer) handleResend(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// SandboxStatsResponse is the response for GET /api/v1/sandbox/stats
-type SandboxStatsResponse struct {
-	Total     int64            `json:"total"`
-	ByDomain  map[string]int64 `json:"by_domain"`
-	ByMode    map[string]int64 `json:"by_mode"`
-	OldestAt  *time.Time       `json:"oldest_at,omitempty"`
-	NewestAt  *time.Time       `json:"newest_at,omitempty"`
-	TotalSize int64            `json:"total_size"`
+// maxReleaseBatch bounds the messages released by one bulk request
+const maxReleaseBatch = 1000
+
+// release enqueues a captured message for delivery and removes it from the
+// sandbox
+func (s *SandboxServer) release(ctx context.Context, msg *sandbox.Message) (*queue.Message, error) {
+	queueMsg, err := msg.Release()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.queue.Enqueue(ctx, queueMsg); err != nil {
+		return nil, fmt.Errorf("failed to enqueue message: %w", err)
+	}
+	// The message is queued, a copy left behind is only listed again
+	s.storage.Delete(ctx, msg.ID)
+	return queueMsg, nil
 }
 
-// handleStats handles GET /api/v1/sandbox/stats
-func (s *SandboxServer) handleStats(w http.ResponseWriter, r *http.Request) {
+// handleRelease handles POST /api/v1/sandbox/messages/{id}/release
+func (s *SandboxServer) handleRelease(w http.ResponseWriter, r *http.Request) {
 	if s.storage == nil {
 		sendError(w, http.StatusServiceUnavailable, "Sandbox storage not available")
 		return
 	}
 
-	stats, err := s.storage.Stats(r.Context())
+	if s.queue == nil {
+		sendError(w, http.StatusServiceUnavailable, "Queue not available")
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	msg, err := s.storage.Get(r.Context(), id)
 	if err != nil {
-		sendError(w, http.StatusInternalServerError, "Failed to get stats")
+		sendError(w, http.StatusInternalServerError, "Failed to get message")
 		return
 	}
 
-	response := SandboxStatsResponse{
-		Total:     stats.Total,
-		ByDomain:  stats.ByDomain,
-		ByMode:    stats.ByMode,
-		TotalSize: stats.TotalSize,
+	if msg == nil {
+		sendError(w, http.StatusNotFound, "Message not found")
+		return
 	}
 
-	if !stats.OldestAt.IsZero() {
-		response.OldestAt = &stats.OldestAt
+	queueMsg, err := s.release(r.Context(), msg)
+	if errors.Is(err, sandbox.ErrDelivered) {
+		sendError(w, http.StatusConflict, "A bcc copy was delivered when it was captured")
+		return
 	}
-	if !stats.NewestAt.IsZero() {
-		response.NewestAt = &stats.NewestAt
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to enqueue message")
+		return
 	}
 
-	sendJSON(w, http.StatusOK, response)
+	recordChange(r, newSandboxMessageResponse(msg), NewMessageSummary(queueMsg))
+	sendJSON(w, http.StatusOK, map[string]string{
+		"status":     "queued",
+		"message_id": queueMsg.ID,
+	})
 }
 
-// parseEmailData extracts headers and body from raw email data
-func parseEmailData(data []byte) (headers map[string]string, body string, html string) {
-	headers = make(map[string]string)
-	content := string(data)
-
-	// Split headers and body
-	parts := splitHeadersBody(content)
-	headerSection := parts[0]
-	bodySection := ""
-	if len(parts) > 1 {
-		bodySection = parts[1]
-	}
-
-	// Parse headers
-	var currentHeader string
-	var currentValue string
-	for _, line := range splitLines(headerSection) {
-		if len(line) > 0 && (line[0] == ' ' || line[0] == '\t') {
-			// Continuation of previous header
-			currentValue += " " + trimSpace(line)
-		} else if idx := indexByte(line, ':'); idx > 0 {
-			// Save previous header
-			if currentHeader != "" {
-				headers[currentHeader] = currentValue
-			}
-			currentHeader = line[:idx]
-			currentValue = trimSpace(line[idx+1:])
-		}
+// SandboxReleaseRequest is the request for POST /api/v1/sandbox/release
+type SandboxReleaseRequest struct {
+	IDs     []string `json:"ids,omitempty"`    // Messages to release
+	Domain  string   `json:"domain,omitempty"` // Release the messages of a domain instead
+	Confirm bool     `json:"confirm"`          // Must be true, the messages reach their real recipients
+}
+
+// SandboxReleased is a message released for delivery
+type SandboxReleased struct {
+	ID        string `json:"id"`         // Sandbox message
+	MessageID string `json:"message_id"` // Queued message
+}
+
+// SandboxReleaseSkipped is a message that was not released
+type SandboxReleaseSkipped struct {
+	ID    string `json:"id"`
+	Error string `json:"error"`
+}
+
+// SandboxReleaseResponse is the response for POST /api/v1/sandbox/release
+type SandboxReleaseResponse struct {
+	Released []SandboxReleased       `json:"released"`
+	Skipped  []SandboxReleaseSkipped `json:"skipped,omitempty"`
+}
+
+// handleReleaseBulk handles POST /api/v1/sandbox/release
+func (s *SandboxServer) handleReleaseBulk(w http.ResponseWriter, r *http.Request) {
+	if s.storage == nil {
+		sendError(w, http.StatusServiceUnavailable, "Sandbox storage not available")
+		return
 	}
-	// Save last header
-	if currentHeader != "" {
-		headers[currentHeader] = currentValue
+
+	if s.queue == nil {
+		sendError(w, http.StatusServiceUnavailable, "Queue not available")
+		return
 	}
 
-	// Simple body extraction (not handling MIME properly for simplicity)
-	body = bodySection
-	if contentType, ok := headers["Content-Type"]; ok {
-		if contains(contentType, "text/html") {
-			html = bodySection
-			body = ""
-		}
+	var req SandboxReleaseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
 	}
 
-	return headers, body, html
-}
+	switch {
+	case len(req.IDs) == 0 && req.Domain == "":
+		sendError(w, http.StatusBadRequest, "ids or domain is required")
+		return
+	case len(req.IDs) > 0 && req.Domain != "":
+		sendError(w, http.StatusBadRequest, "ids and domain are mutually exclusive")
+		return
+	case len(req.IDs) > maxReleaseBatch:
+		sendError(w, http.StatusBadRequest, fmt.Sprintf("At most %d messages can be released at once", maxReleaseBatch))
+		return
+	case !req.Confirm:
+		sendError(w, http.StatusBadRequest, "confirm must be true, released messages are delivered to their real recipients")
+		return
+	}
 
-func splitHeadersBody(s string) []string {
-	// Find empty line that separates headers from body
-	for i := 0; i < len(s)-1; i++ {
-		if s[i] == '\n' && (s[i+1] == '\n' || (i+2 < len(s) && s[i+1] == '\r' && s[i+2] == '\n')) {
-			if s[i+1] == '\n' {
-				return []string{s[:i], s[i+2:]}
-			}
-			return []string{s[:i], s[i+3:]}
+	response := SandboxReleaseResponse{Released: []SandboxReleased{}}
+	var messages []*sandbox.Message
+	if req.Domain != "" {
+		found, err := s.storage.List(r.Context(), sandbox.ListFilter{
+			Domain:     req.Domain,
+			Releasable: true,
+			WithData:   true,
+			Limit:      maxReleaseBatch,
+		})
+		if err != nil {
+			sendError(w, http.StatusInternalServerError, "Failed to list messages")
+			return
+		}
+		// Oldest first
+		for i := len(found) - 1; i >= 0; i-- {
+			messages = append(messages, found[i])
 		}
-		if s[i] == '\r' && i+1 < len(s) && s[i+1] == '\n' {
-			if i+2 < len(s) && s[i+2] == '\r' && i+3 < len(s) && s[i+3] == '\n' {
-				return []string{s[:i], s[i+4:]}
+	} else {
+		for _, id := range req.IDs {
+			msg, err := s.storage.Get(r.Context(), id)
+			if err != nil || msg == nil {
+				response.Skipped = append(response.Skipped, SandboxReleaseSkipped{ID: id, Error: "message not found"})
+				continue
 			}
+			messages = append(messages, msg)
 		}
 	}
-	return []string{s}
-}
 
-func splitLines(s string) []string {
-	var lines []string
-	start := 0
-	for i := 0; i < len(s); i++ {
-		if s[i] == '\n' {
-			end := i
-			if end > start && s[end-1] == '\r' {
-				end--
-			}
-			lines = append(lines, s[start:end])
-			start = i + 1
+	for _, msg := range messages {
+		queueMsg, err := s.release(r.Context(), msg)
+		if err != nil {
+			response.Skipped = append(response.Skipped, SandboxReleaseSkipped{ID: msg.ID, Error: err.Error()})
+			continue
 		}
+		response.Released = append(response.Released, SandboxReleased{ID: msg.ID, MessageID: queueMsg.ID})
 	}
-	if start < len(s) {
-		lines = append(lines, s[start:])
-	}
-	return lines
+
+	recordChange(r, nil, response)
+	sendJSON(w, http.StatusOK, response)
 }
 
-func indexByte(s string, c byte) int {
-	for i := 0; i < len(s); i++ {
-		if s[i] == c {
-			return i
-		}
-	}
-	return -1
+// SandboxStatsResponse is the response for GET /api/v1/sandbox/stats
+type SandboxStatsResponse struct {
+	Total     int64            `json:"total"`
+	ByDomain  map[string]int64 `json:"by_domain"`
+	ByMode    map[string]int64 `json:"by_mode"`
+	OldestAt  *time.Time       `json:"oldest_at,omitempty"`
+	NewestAt  *time.Time       `json:"newest_at,omitempty"`
+	TotalSize int64            `json:"total_size"`
+	MaxAge    string           `json:"max_age,omitempty"`   // Retention of sandbox.max_age, empty to keep forever
+	MaxCount  int              `json:"max_count,omitempty"` // Retention of sandbox.max_count, 0 for unlimited
 }
 
-func trimSpace(s string) string {
-	start := 0
-	end := len(s)
-	for start < end && (s[start] == ' ' || s[start] == '\t' || s[start] == '\r' || s[start] == '\n') {
-		start++
+// handleStats handles GET /api/v1/sandbox/stats
+func (s *SandboxServer) handleStats(w http.ResponseWriter, r *http.Request) {
+	if s.storage == nil {
+		sendError(w, http.StatusServiceUnavailable, "Sandbox storage not available")
+		return
 	}
-	for end > start && (s[end-1] == ' ' || s[end-1] == '\t' || s[end-1] == '\r' || s[end-1] == '\n') {
-		end--
+
+	stats, err := s.storage.Stats(r.Context())
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to get stats")
+		return
 	}
-	return s[start:end]
-}
 
-func contains(s, substr string) bool {
-	return len(s) >= len(substr) && (s == substr || len(substr) == 0 || findSubstring(s, substr) >= 0)
-}
+	response := SandboxStatsResponse{
+		Total:     stats.Total,
+		ByDomain:  stats.ByDomain,
+		ByMode:    stats.ByMode,
+		TotalSize: stats.TotalSize,
+		MaxCount:  s.maxCount,
+	}
+	if s.maxAge > 0 {
+		response.MaxAge = s.maxAge.String()
+	}
 
-func findSubstring(s, substr string) int {
-	for i := 0; i <= len(s)-len(substr); i++ {
-		if s[i:i+len(substr)] == substr {
-			return i
-		}
+	if !stats.OldestAt.IsZero() {
+		response.OldestAt = &stats.OldestAt
+	}
+	if !stats.NewestAt.IsZero() {
+		response.NewestAt = &stats.NewestAt
 	}
-	return -1
+
+	sendJSON(w, http.StatusOK, response)
 }
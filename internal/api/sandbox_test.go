@@ -0,0 +1,219 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/foxzi/sendry/internal/dkim"
+	"github.com/foxzi/sendry/internal/mailauth"
+	"github.com/foxzi/sendry/internal/sandbox"
+)
+
+// dkimKeyResolver publishes one DKIM public key
+type dkimKeyResolver struct {
+	name   string
+	record string
+}
+
+func (r dkimKeyResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	if name == r.name {
+		return []string{r.record}, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func (r dkimKeyResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+func (r dkimKeyResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func TestSandboxAPI(t *testing.T) {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "sandbox.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	defer db.Close()
+	storage, err := sandbox.NewStorage(db)
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	pub, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	signed, err := dkim.NewSigner(key, "example.com", "s1").Sign([]byte("From: App <app@example.com>\r\n" +
+		"To: user@example.org\r\n" +
+		"Subject: Reset your password\r\n" +
+		"Content-Type: text/html; charset=utf-8\r\n" +
+		"\r\n" +
+		"<p>Click <a href=\"https://example.com/reset\">here</a></p><script>track()</script>\r\n"))
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	ctx := context.Background()
+	for i, msg := range []*sandbox.Message{
+		{ID: "reset", From: "app@example.com", To: []string{"user@example.org"}, Subject: "Reset your password", Data: signed, DKIMSigned: true},
+		{ID: "welcome", From: "app@example.com", To: []string{"other@example.org"}, Subject: "Welcome", Data: []byte("Subject: Welcome\r\n\r\nHi\r\n")},
+	} {
+		msg.Domain = "example.com"
+		msg.Mode = "sandbox"
+		msg.CapturedAt = time.Now().Add(time.Duration(i) * time.Second)
+		if err := storage.Save(ctx, msg); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+
+	s := NewSandboxServer(storage, nil)
+	s.SetDKIMVerifier(mailauth.NewVerifier(dkimKeyResolver{
+		name:   "s1._domainkey.example.com",
+		record: "v=DKIM1; k=rsa; p=" + base64.StdEncoding.EncodeToString(pub),
+	}, "mx.example.com"))
+	s.SetRetention(7*24*time.Hour, 500)
+	r := chi.NewRouter()
+	s.RegisterRoutes(r)
+
+	get := func(path string, v any) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s status = %d, body: %s", path, w.Code, w.Body.String())
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+	}
+
+	var list SandboxListResponse
+	get("/sandbox/messages?domain=example.com&q=PASSWORD", &list)
+	if list.Total != 1 || list.Messages[0].ID != "reset" {
+		t.Fatalf("search returned %+v, want the reset message", list.Messages)
+	}
+
+	var detail SandboxMessageDetailResponse
+	get("/sandbox/messages/reset", &detail)
+	if detail.Headers["Subject"] != "Reset your password" || len(detail.HeaderList) != 5 || detail.HeaderList[0].Name != "DKIM-Signature" {
+		t.Errorf("headers = %v, list = %+v", detail.Headers, detail.HeaderList)
+	}
+	want := `<p>Click <a href="https://example.com/reset" target="_blank" rel="noopener noreferrer">here</a></p>`
+	if strings.TrimSpace(detail.HTML) != want {
+		t.Errorf("html = %q, want %q", detail.HTML, want)
+	}
+	if !detail.DKIMSigned || len(detail.DKIM) != 1 || detail.DKIM[0].Domain != "example.com" || detail.DKIM[0].Result != "pass" {
+		t.Errorf("dkim = %+v, want pass for example.com", detail.DKIM)
+	}
+
+	get("/sandbox/messages/welcome", &detail)
+	if detail.DKIM == nil || len(detail.DKIM) != 0 {
+		t.Errorf("dkim of unsigned message = %#v, want empty", detail.DKIM)
+	}
+
+	var stats SandboxStatsResponse
+	get("/sandbox/stats", &stats)
+	if stats.Total != 2 || stats.MaxAge != "168h0m0s" || stats.MaxCount != 500 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestSandboxRelease(t *testing.T) {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "sandbox.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	defer db.Close()
+	storage, err := sandbox.NewStorage(db)
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+
+	ctx := context.Background()
+	for i, msg := range []*sandbox.Message{
+		{ID: "captured", Domain: "example.com", Mode: "sandbox", To: []string{"user@example.org"}},
+		{ID: "redirected", Domain: "example.com", Mode: "redirect", To: []string{"qa@example.com"}, OriginalTo: []string{"real@example.org"}},
+		{ID: "copied", Domain: "example.com", Mode: "bcc", To: []string{"user@example.org", "archive@example.com"}},
+		{ID: "other", Domain: "other.com", Mode: "sandbox", To: []string{"user@other.com"}},
+	} {
+		msg.From = "app@" + msg.Domain
+		msg.Data = []byte("Subject: Hi\r\n\r\nHi\r\n")
+		msg.CapturedAt = time.Now().Add(time.Duration(i) * time.Second)
+		if err := storage.Save(ctx, msg); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+
+	q := newMockQueue()
+	r := chi.NewRouter()
+	NewSandboxServer(storage, q).RegisterRoutes(r)
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("POST", path, strings.NewReader(body)))
+		return w
+	}
+
+	// A bcc copy was delivered already
+	if w := post("/sandbox/messages/copied/release", ""); w.Code != http.StatusConflict {
+		t.Errorf("release of bcc copy status = %d, want 409", w.Code)
+	}
+
+	w := post("/sandbox/messages/other/release", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("release status = %d, body: %s", w.Code, w.Body.String())
+	}
+	var single map[string]string
+	json.Unmarshal(w.Body.Bytes(), &single)
+	released := q.messages[single["message_id"]]
+	if released == nil || !released.Released || released.To[0] != "user@other.com" {
+		t.Errorf("queued message = %+v, want released to the recipient", released)
+	}
+	if msg, _ := storage.Get(ctx, "other"); msg != nil {
+		t.Error("released message is still in the sandbox")
+	}
+
+	// Bulk release needs a confirmation
+	if w := post("/sandbox/release", `{"domain":"example.com"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("unconfirmed release status = %d, want 400", w.Code)
+	}
+
+	w = post("/sandbox/release", `{"domain":"example.com","confirm":true}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("bulk release status = %d, body: %s", w.Code, w.Body.String())
+	}
+	var bulk SandboxReleaseResponse
+	json.Unmarshal(w.Body.Bytes(), &bulk)
+	if len(bulk.Released) != 2 || bulk.Released[0].ID != "captured" || bulk.Released[1].ID != "redirected" {
+		t.Fatalf("released = %+v, want captured and redirected, oldest first", bulk.Released)
+	}
+	if to := q.messages[bulk.Released[1].MessageID].To; len(to) != 1 || to[0] != "real@example.org" {
+		t.Errorf("redirected message released to %v, want the original recipient", to)
+	}
+	if msg, _ := storage.Get(ctx, "copied"); msg == nil {
+		t.Error("bcc copy was released")
+	}
+
+	w = post("/sandbox/release", `{"ids":["copied","missing"],"confirm":true}`)
+	json.Unmarshal(w.Body.Bytes(), &bulk)
+	if len(bulk.Released) != 0 || len(bulk.Skipped) != 2 {
+		t.Errorf("release by ids = %+v, want both skipped", bulk)
+	}
+}
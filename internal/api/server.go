@@ -5,54 +5,140 @@ import (
 	"crypto/tls"
 	"log/slog"
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 
+	"github.com/foxzi/sendry/internal/addrpolicy"
+	"github.com/foxzi/sendry/internal/apikey"
+	"github.com/foxzi/sendry/internal/archive"
+	"github.com/foxzi/sendry/internal/audit"
+	"github.com/foxzi/sendry/internal/autoreply"
 	"github.com/foxzi/sendry/internal/config"
+	"github.com/foxzi/sendry/internal/contentfilter"
+	"github.com/foxzi/sendry/internal/contentpolicy"
+	"github.com/foxzi/sendry/internal/dnsbl"
+	"github.com/foxzi/sendry/internal/dnsmonitor"
 	"github.com/foxzi/sendry/internal/domain"
+	"github.com/foxzi/sendry/internal/fbl"
+	"github.com/foxzi/sendry/internal/headers"
+	"github.com/foxzi/sendry/internal/health"
+	"github.com/foxzi/sendry/internal/idempotency"
 	"github.com/foxzi/sendry/internal/ipfilter"
+	"github.com/foxzi/sendry/internal/ipguard"
+	"github.com/foxzi/sendry/internal/mailauth"
+	"github.com/foxzi/sendry/internal/maillist"
 	"github.com/foxzi/sendry/internal/metrics"
+	"github.com/foxzi/sendry/internal/privacy"
 	"github.com/foxzi/sendry/internal/queue"
 	"github.com/foxzi/sendry/internal/ratelimit"
+	"github.com/foxzi/sendry/internal/replication"
+	"github.com/foxzi/sendry/internal/reputation"
+	"github.com/foxzi/sendry/internal/retry"
 	"github.com/foxzi/sendry/internal/sandbox"
+	"github.com/foxzi/sendry/internal/shaping"
+	"github.com/foxzi/sendry/internal/smtpauth"
+	"github.com/foxzi/sendry/internal/stats"
+	"github.com/foxzi/sendry/internal/suppression"
 	"github.com/foxzi/sendry/internal/template"
+	sendryTLS "github.com/foxzi/sendry/internal/tls"
+	"github.com/foxzi/sendry/internal/tracing"
 )
 
 // Server is the HTTP API server
 type Server struct {
-	router           *chi.Mux
-	httpServer       *http.Server
-	queue            queue.Queue
-	boltStorage      *queue.BoltStorage // typed reference for DLQ operations
-	config           *config.APIConfig
-	fullConfig       *config.Config
-	logger           *slog.Logger
-	startTime        time.Time
-	domainManager    *domain.Manager
-	rateLimiter      *ratelimit.Limiter
-	managementServer *ManagementServer
-	sandboxServer    *SandboxServer
-	sandboxStorage   *sandbox.Storage
-	tlsConfig        *tls.Config
-	templateServer   *TemplateServer
-	ipFilter         *ipfilter.Filter
+	router             *chi.Mux
+	httpServer         *http.Server
+	queue              queue.Queue
+	dlqStorage         queue.DLQManager // typed reference for DLQ operations
+	config             *config.APIConfig
+	fullConfig         *config.Config
+	logger             *slog.Logger
+	startTime          time.Time
+	domainManager      *domain.Manager
+	rateLimiter        *ratelimit.Limiter
+	managementServer   *ManagementServer
+	sandboxServer      *SandboxServer
+	sandboxStorage     *sandbox.Storage
+	tlsConfig          *tls.Config
+	templateServer     *TemplateServer
+	ipFilter           *ipfilter.Filter
+	autoReplyServer    *AutoReplyServer
+	listServer         *ListServer
+	shapingServer      *ShapingServer
+	headerRulesServer  *HeaderRulesServer
+	policyServer       *PolicyServer
+	banServer          *BanServer
+	privacyServer      *PrivacyServer
+	auditStorage       *audit.Storage
+	idempotencyStorage *idempotency.Storage
+	apiKeyStorage      *apikey.Storage
+	apiKeyServer       *APIKeyServer
+	smtpUserServer     *SMTPUserServer
+	auditServer        *AuditServer
+	archiveServer      *ArchiveServer
+	reputationServer   *ReputationServer
+	statsServer        *StatsServer
+	suppressionServer  *SuppressionServer
+	fblServer          *FBLServer
+	replicationServer  *ReplicationServer
+	backupStorage      *queue.BoltStorage
+	pauses             *queue.Pauses
+	dkim               DKIMProvider
+	filter             *contentfilter.Checker
+	policy             *contentpolicy.Enforcer
+	addrPolicy         *addrpolicy.Enforcer
+	health             *health.Checker
+
+	// The static API key of the config, replaced on reload
+	apiKey atomic.Pointer[string]
 }
 
 // ServerOptions contains options for creating an API server
 type ServerOptions struct {
-	Queue           queue.Queue
-	Config          *config.APIConfig
-	FullConfig      *config.Config
-	Logger          *slog.Logger
-	DomainManager   *domain.Manager
-	RateLimiter     *ratelimit.Limiter
-	SandboxStorage  *sandbox.Storage
-	TemplateStorage *template.Storage
-	DKIMKeysDir     string
-	TLSCertsDir     string
-	TLSConfig       *tls.Config
+	Queue              queue.Queue
+	Config             *config.APIConfig
+	FullConfig         *config.Config
+	Logger             *slog.Logger
+	DomainManager      *domain.Manager
+	RateLimiter        *ratelimit.Limiter
+	SandboxStorage     *sandbox.Storage
+	TemplateStorage    *template.Storage
+	AutoReplyStorage   *autoreply.Storage
+	ListStorage        *maillist.Storage
+	ShapingStorage     *shaping.Storage
+	HeaderRuleStorage  *headers.Storage   // Header rule sets managed through the API
+	HeaderProcessor    *headers.Processor // Applies the header rules of delivery, for previews
+	AuditStorage       *audit.Storage
+	ArchiveStorage     *archive.Storage
+	IdempotencyStorage *idempotency.Storage
+	APIKeyStorage      *apikey.Storage
+	SMTPUserStorage    *smtpauth.Storage // SMTP credential store, set with smtp.auth.store
+	ReputationStorage  *reputation.Storage
+	StatsStorage       *stats.Storage      // History of queue sizes and delivery counts
+	DNSCheckStorage    *dnsmonitor.Storage // Results of scheduled DNS checks
+	DNSBLMonitor       *dnsbl.Monitor      // Scheduled DNSBL checks of outbound IPs
+	ACMEManager        *sendryTLS.ACMEManager
+	SuppressionStorage *suppression.Storage
+	FBLProcessor       *fbl.Processor
+	FBLStorage         *fbl.Storage
+	Pauses             *queue.Pauses
+	RetryScheduler     *retry.Scheduler        // Retry policies of recipient domains
+	ReplicationPrimary *replication.Primary    // Queue replicated to the standby
+	ReplicationStandby *replication.Standby    // Queue replicated from the primary
+	BackupStorage      *queue.BoltStorage      // Database served by GET /api/v1/backup
+	ContentFilter      *contentfilter.Checker  // Checks messages before they are queued
+	ContentPolicy      *contentpolicy.Enforcer // Recipient and attachment limits of messages
+	AddressPolicy      *addrpolicy.Enforcer    // Allow and deny lists of senders and recipients
+	PolicyStorage      *addrpolicy.Storage     // Address lists managed through the API
+	IPGuard            *ipguard.Guard          // SMTP client throttling, serves the ban list
+	Health             *health.Checker         // Readiness checks of /readyz
+	Purger             *privacy.Purger         // Deletes the messages of an address
+	DKIMKeysDir        string
+	TLSCertsDir        string
+	TLSConfig          *tls.Config
 }
 
 // NewServer creates a new API server
@@ -67,16 +153,26 @@ func NewServer(q queue.Queue, cfg *config.APIConfig, logger *slog.Logger) *Serve
 // NewServerWithOptions creates a new API server with full options
 func NewServerWithOptions(opts ServerOptions) *Server {
 	s := &Server{
-		router:         chi.NewRouter(),
-		queue:          opts.Queue,
-		config:         opts.Config,
-		fullConfig:     opts.FullConfig,
-		logger:         opts.Logger,
-		startTime:      time.Now(),
-		domainManager:  opts.DomainManager,
-		rateLimiter:    opts.RateLimiter,
-		sandboxStorage: opts.SandboxStorage,
-		tlsConfig:      opts.TLSConfig,
+		router:             chi.NewRouter(),
+		queue:              opts.Queue,
+		config:             opts.Config,
+		fullConfig:         opts.FullConfig,
+		logger:             opts.Logger,
+		startTime:          time.Now(),
+		domainManager:      opts.DomainManager,
+		rateLimiter:        opts.RateLimiter,
+		sandboxStorage:     opts.SandboxStorage,
+		tlsConfig:          opts.TLSConfig,
+		auditStorage:       opts.AuditStorage,
+		idempotencyStorage: opts.IdempotencyStorage,
+		apiKeyStorage:      opts.APIKeyStorage,
+		pauses:             opts.Pauses,
+		backupStorage:      opts.BackupStorage,
+	}
+	if opts.Config != nil {
+		s.SetAPIKey(opts.Config.APIKey)
+	} else {
+		s.SetAPIKey("")
 	}
 
 	// Create IP filter if allowed_ips is configured
@@ -85,9 +181,18 @@ func NewServerWithOptions(opts ServerOptions) *Server {
 		opts.Logger.Info("API IP filtering enabled", "allowed_networks", s.ipFilter.Count())
 	}
 
+	// Sign API messages with the domain DKIM keys
+	if opts.DomainManager != nil {
+		s.dkim = opts.DomainManager
+	}
+	s.filter = opts.ContentFilter
+	s.policy = opts.ContentPolicy
+	s.addrPolicy = opts.AddressPolicy
+	s.health = opts.Health
+
 	// Store typed reference for DLQ operations
-	if bs, ok := opts.Queue.(*queue.BoltStorage); ok {
-		s.boltStorage = bs
+	if dm, ok := opts.Queue.(queue.DLQManager); ok {
+		s.dlqStorage = dm
 	}
 
 	// Create management server if we have full config
@@ -107,26 +212,157 @@ func NewServerWithOptions(opts ServerOptions) *Server {
 			dkimDir,
 			tlsDir,
 		)
+		s.managementServer.pauses = opts.Pauses
+		s.managementServer.dnsChecks = opts.DNSCheckStorage
+		s.managementServer.dnsbl = opts.DNSBLMonitor
+		s.managementServer.acme = opts.ACMEManager
+		s.managementServer.retries = opts.RetryScheduler
 	}
 
 	// Create sandbox server if storage is available
 	if opts.SandboxStorage != nil {
 		s.sandboxServer = NewSandboxServer(opts.SandboxStorage, opts.Queue)
+		if opts.FullConfig != nil {
+			s.sandboxServer.SetDKIMVerifier(mailauth.NewVerifier(nil, opts.FullConfig.SMTP.Domain))
+			s.sandboxServer.SetRetention(opts.FullConfig.Sandbox.MaxAge, opts.FullConfig.Sandbox.MaxCount)
+		}
 	}
 
 	// Create template server if storage is available
 	if opts.TemplateStorage != nil {
 		s.templateServer = NewTemplateServer(opts.TemplateStorage, opts.Queue)
+		if opts.FullConfig != nil {
+			s.templateServer.SetMaxMessageBytes(opts.FullConfig.SMTP.MaxMessageBytes)
+			s.templateServer.SetRejectEAI(opts.FullConfig.SMTP.RejectEAI)
+		}
+		if opts.Config != nil && opts.Config.DKIMSignOnEnqueue {
+			s.templateServer.SetDKIMProvider(s.dkim)
+		}
+		s.templateServer.SetContentFilter(opts.ContentFilter)
+		s.templateServer.SetContentPolicy(opts.ContentPolicy)
+		s.templateServer.SetAddressPolicy(opts.AddressPolicy)
+	}
+
+	// Create auto-reply server if storage is available
+	if opts.AutoReplyStorage != nil {
+		s.autoReplyServer = NewAutoReplyServer(opts.AutoReplyStorage)
+	}
+
+	// Create mailing list server if storage is available
+	if opts.ListStorage != nil {
+		s.listServer = NewListServer(opts.ListStorage, opts.Queue)
+	}
+
+	// Create send schedule server if storage is available
+	if opts.ShapingStorage != nil {
+		s.shapingServer = NewShapingServer(opts.ShapingStorage)
+	}
+
+	// Create header rules server if storage is available
+	if opts.HeaderRuleStorage != nil {
+		s.headerRulesServer = NewHeaderRulesServer(opts.HeaderRuleStorage, opts.HeaderProcessor)
+	}
+
+	// Create address policy server if storage is available
+	if opts.PolicyStorage != nil {
+		s.policyServer = NewPolicyServer(opts.PolicyStorage)
+	}
+
+	// Create ban list server if the SMTP IP guard is enabled
+	if opts.IPGuard != nil {
+		s.banServer = NewBanServer(opts.IPGuard)
+	}
+
+	// Create privacy server for erasure requests
+	if opts.Purger != nil {
+		s.privacyServer = NewPrivacyServer(opts.Purger, opts.Logger)
+	}
+
+	// Create audit server if storage is available
+	if opts.AuditStorage != nil {
+		s.auditServer = NewAuditServer(opts.AuditStorage)
+	}
+
+	// Create API key server if storage is available
+	if opts.APIKeyStorage != nil {
+		s.apiKeyServer = NewAPIKeyServer(opts.APIKeyStorage)
+	}
+
+	// Create SMTP user server if the credential store is enabled
+	if opts.SMTPUserStorage != nil {
+		s.smtpUserServer = NewSMTPUserServer(opts.SMTPUserStorage)
+	}
+
+	// Create archive server if storage is available
+	if opts.ArchiveStorage != nil {
+		s.archiveServer = NewArchiveServer(opts.ArchiveStorage)
+	}
+
+	// Create reputation server if scoring is enabled
+	if opts.ReputationStorage != nil {
+		s.reputationServer = NewReputationServer(opts.ReputationStorage)
+	}
+
+	// Create stats history server if the history is recorded
+	if opts.StatsStorage != nil {
+		s.statsServer = NewStatsServer(opts.StatsStorage)
+	}
+
+	// Create suppression list server if storage is available
+	if opts.SuppressionStorage != nil {
+		s.suppressionServer = NewSuppressionServer(opts.SuppressionStorage)
+	}
+
+	// Create feedback loop server if report processing is enabled
+	if opts.FBLProcessor != nil && opts.FBLStorage != nil {
+		s.fblServer = NewFBLServer(opts.FBLProcessor, opts.FBLStorage)
+	}
+
+	// Create replication server if the node is a primary or standby
+	if opts.ReplicationPrimary != nil || opts.ReplicationStandby != nil {
+		var timeout time.Duration
+		if opts.FullConfig != nil {
+			timeout = opts.FullConfig.Replication.Timeout
+		}
+		s.replicationServer = NewReplicationServer(opts.ReplicationPrimary, opts.ReplicationStandby, timeout)
 	}
 
 	s.setupRoutes()
 	return s
 }
 
+// SetAPIKey replaces the static API key, e.g. after a rotated secret was
+// reloaded
+func (s *Server) SetAPIKey(key string) {
+	s.apiKey.Store(&key)
+}
+
+// staticAPIKey returns the static API key, empty when there is none
+func (s *Server) staticAPIKey() string {
+	return *s.apiKey.Load()
+}
+
+// SetRateLimits replaces the rate limits reported by the management API,
+// e.g. after a config reload
+func (s *Server) SetRateLimits(rl config.RateLimitConfig) {
+	if s.managementServer != nil {
+		s.managementServer.setRateLimits(rl)
+	}
+}
+
+// SetConfigReloader sets the reloader behind POST /api/v1/config/reload
+func (s *Server) SetConfigReloader(r ConfigReloader) {
+	if s.managementServer != nil {
+		s.managementServer.reloader = r
+	}
+}
+
 // setupRoutes configures the HTTP routes
 func (s *Server) setupRoutes() {
 	// Middleware
 	s.router.Use(middleware.RequestID)
+	s.router.Use(tracing.HTTPMiddleware)
+	s.router.Use(s.correlationMiddleware)
 	s.router.Use(middleware.RealIP)
 	s.router.Use(metrics.HTTPMiddleware)
 	s.router.Use(s.loggingMiddleware)
@@ -134,6 +370,8 @@ func (s *Server) setupRoutes() {
 
 	// Health check (no auth or IP filter required - for load balancers)
 	s.router.Get("/health", s.handleHealth)
+	s.router.Get("/healthz", s.handleLiveness)
+	s.router.Get("/readyz", s.handleReadiness)
 
 	// API v1 routes (auth and IP filter required)
 	s.router.Route("/api/v1", func(r chi.Router) {
@@ -143,11 +381,27 @@ func (s *Server) setupRoutes() {
 		}
 		r.Use(s.authMiddleware)
 
+		// Record management changes once the request is authenticated
+		if s.auditStorage != nil {
+			r.Use(s.auditMiddleware)
+		}
+
+		// Replay message submissions retried with the same Idempotency-Key
+		if s.idempotencyStorage != nil {
+			r.Use(s.idempotencyMiddleware)
+		}
+
 		r.Post("/send", s.handleSend)
 		r.Post("/send/batch", s.handleSendBatch)
+		r.Post("/send/raw", s.handleSendRaw)
 		r.Get("/status/{id}", s.handleStatus)
 		r.Get("/queue", s.handleQueue)
+		r.Get("/queue/pause", s.handlePauseStatus)
+		r.Post("/queue/pause", s.handlePause)
+		r.Post("/queue/resume", s.handleResume)
+		r.Get("/queue/{id}", s.handleGetMessage)
 		r.Delete("/queue/{id}", s.handleDeleteMessage)
+		r.Post("/queue/{id}/retry", s.handleRetryMessage)
 
 		// Dead Letter Queue routes
 		r.Get("/dlq", s.handleDLQ)
@@ -155,6 +409,12 @@ func (s *Server) setupRoutes() {
 		r.Post("/dlq/{id}/retry", s.handleDLQRetry)
 		r.Delete("/dlq/{id}", s.handleDLQDelete)
 
+		// Online copy of the database
+		r.Get("/backup", s.handleBackup)
+
+		// OpenAPI document of this API
+		r.Get("/openapi.json", s.handleOpenAPI)
+
 		// Management routes (DKIM, TLS, domains, rate limits)
 		if s.managementServer != nil {
 			s.managementServer.RegisterRoutes(r)
@@ -169,6 +429,86 @@ func (s *Server) setupRoutes() {
 		if s.templateServer != nil {
 			s.templateServer.RegisterRoutes(r)
 		}
+
+		// Auto-reply routes
+		if s.autoReplyServer != nil {
+			s.autoReplyServer.RegisterRoutes(r)
+		}
+
+		// Mailing list routes
+		if s.listServer != nil {
+			s.listServer.RegisterRoutes(r)
+		}
+
+		// Send schedule routes
+		if s.shapingServer != nil {
+			s.shapingServer.RegisterRoutes(r)
+		}
+
+		// Header rule set routes
+		if s.headerRulesServer != nil {
+			s.headerRulesServer.RegisterRoutes(r)
+		}
+
+		// Address policy routes
+		if s.policyServer != nil {
+			s.policyServer.RegisterRoutes(r)
+		}
+
+		// SMTP client ban routes
+		if s.banServer != nil {
+			s.banServer.RegisterRoutes(r)
+		}
+
+		// Privacy routes
+		if s.privacyServer != nil {
+			s.privacyServer.RegisterRoutes(r)
+		}
+
+		// Audit log routes
+		if s.auditServer != nil {
+			s.auditServer.RegisterRoutes(r)
+		}
+
+		// Message archive routes
+		if s.archiveServer != nil {
+			s.archiveServer.RegisterRoutes(r)
+		}
+
+		// API key management
+		if s.apiKeyServer != nil {
+			s.apiKeyServer.RegisterRoutes(r)
+		}
+
+		// SMTP credential store
+		if s.smtpUserServer != nil {
+			s.smtpUserServer.RegisterRoutes(r)
+		}
+
+		// Sending reputation routes
+		if s.reputationServer != nil {
+			s.reputationServer.RegisterRoutes(r)
+		}
+
+		// Stats history routes
+		if s.statsServer != nil {
+			s.statsServer.RegisterRoutes(r)
+		}
+
+		// Suppression list routes
+		if s.suppressionServer != nil {
+			s.suppressionServer.RegisterRoutes(r)
+		}
+
+		// Complaint feedback loop routes
+		if s.fblServer != nil {
+			s.fblServer.RegisterRoutes(r)
+		}
+
+		// Queue replication routes
+		if s.replicationServer != nil {
+			s.replicationServer.RegisterRoutes(r)
+		}
 	})
 }
 
@@ -0,0 +1,165 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/foxzi/sendry/internal/dnscheck"
+	"github.com/foxzi/sendry/internal/shaping"
+)
+
+// ShapingServer handles send schedule API endpoints
+type ShapingServer struct {
+	storage *shaping.Storage
+	shaper  *shaping.Shaper
+}
+
+// NewShapingServer creates a new send schedule server
+func NewShapingServer(storage *shaping.Storage) *ShapingServer {
+	return &ShapingServer{
+		storage: storage,
+		shaper:  shaping.NewShaper(storage),
+	}
+}
+
+// RegisterRoutes registers send schedule API routes
+func (s *ShapingServer) RegisterRoutes(r chi.Router) {
+	r.Route("/shaping", func(r chi.Router) {
+		r.Get("/", s.handleList)
+		r.Get("/{domain}", s.handleGet)
+		r.Put("/{domain}", s.handlePut)
+		r.Delete("/{domain}", s.handleDelete)
+	})
+}
+
+// ScheduleRequest is the request for creating or replacing a send schedule
+type ScheduleRequest struct {
+	Timezone string  `json:"timezone,omitempty"`
+	Hours    [][]int `json:"hours"` // 7 rows (Sunday first) of 24 hourly limits
+	Enabled  *bool   `json:"enabled,omitempty"`
+}
+
+// ScheduleResponse is a send schedule with its current hour usage
+type ScheduleResponse struct {
+	*shaping.Schedule
+	CurrentLimit int `json:"current_limit"`
+	SentThisHour int `json:"sent_this_hour"`
+}
+
+// ScheduleListResponse is the response for listing send schedules
+type ScheduleListResponse struct {
+	Schedules []*shaping.Schedule `json:"schedules"`
+	Total     int                 `json:"total"`
+}
+
+// handleList handles GET /api/v1/shaping
+func (s *ShapingServer) handleList(w http.ResponseWriter, r *http.Request) {
+	schedules, err := s.storage.List(r.Context())
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to list schedules")
+		return
+	}
+
+	if schedules == nil {
+		schedules = []*shaping.Schedule{}
+	}
+
+	sendJSON(w, http.StatusOK, ScheduleListResponse{
+		Schedules: schedules,
+		Total:     len(schedules),
+	})
+}
+
+// handleGet handles GET /api/v1/shaping/{domain}
+func (s *ShapingServer) handleGet(w http.ResponseWriter, r *http.Request) {
+	domain := chi.URLParam(r, "domain")
+
+	sched, err := s.storage.Get(r.Context(), domain)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to get schedule")
+		return
+	}
+
+	if sched == nil {
+		sendError(w, http.StatusNotFound, "Schedule not found")
+		return
+	}
+
+	s.sendSchedule(w, r, http.StatusOK, sched)
+}
+
+// handlePut handles PUT /api/v1/shaping/{domain}
+func (s *ShapingServer) handlePut(w http.ResponseWriter, r *http.Request) {
+	domain := shaping.NormalizeDomain(chi.URLParam(r, "domain"))
+	if err := dnscheck.ValidateDomain(domain); err != nil {
+		sendError(w, http.StatusBadRequest, fmt.Sprintf("invalid domain: %s", domain))
+		return
+	}
+
+	var req ScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if len(req.Hours) != 7 {
+		sendError(w, http.StatusBadRequest, "hours must have 7 rows (Sunday first)")
+		return
+	}
+
+	sched := &shaping.Schedule{
+		Domain:   domain,
+		Timezone: req.Timezone,
+		Enabled:  true,
+	}
+	if req.Enabled != nil {
+		sched.Enabled = *req.Enabled
+	}
+
+	for day, row := range req.Hours {
+		if len(row) != 24 {
+			sendError(w, http.StatusBadRequest, fmt.Sprintf("hours[%d] must have 24 values", day))
+			return
+		}
+		copy(sched.Hours[day][:], row)
+	}
+
+	if err := sched.Validate(); err != nil {
+		sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := s.storage.Put(r.Context(), sched); err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to save schedule")
+		return
+	}
+
+	s.sendSchedule(w, r, http.StatusOK, sched)
+}
+
+// handleDelete handles DELETE /api/v1/shaping/{domain}
+func (s *ShapingServer) handleDelete(w http.ResponseWriter, r *http.Request) {
+	domain := chi.URLParam(r, "domain")
+
+	if err := s.storage.Delete(r.Context(), domain); err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to delete schedule")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// sendSchedule writes the schedule together with its current hour usage
+func (s *ShapingServer) sendSchedule(w http.ResponseWriter, r *http.Request, status int, sched *shaping.Schedule) {
+	resp := ScheduleResponse{Schedule: sched, CurrentLimit: shaping.Unlimited}
+
+	if st, err := s.shaper.Status(r.Context(), sched.Domain); err == nil {
+		resp.CurrentLimit = st.Limit
+		resp.SentThisHour = st.Sent
+	}
+
+	sendJSON(w, status, resp)
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/foxzi/sendry/internal/smtpauth"
+)
+
+// SMTPUserServer handles requests to the SMTP credential store
+type SMTPUserServer struct {
+	storage *smtpauth.Storage
+}
+
+// NewSMTPUserServer creates a new SMTP user server
+func NewSMTPUserServer(storage *smtpauth.Storage) *SMTPUserServer {
+	return &SMTPUserServer{storage: storage}
+}
+
+// RegisterRoutes registers SMTP user routes
+func (s *SMTPUserServer) RegisterRoutes(r chi.Router) {
+	r.Get("/smtp-users", s.handleList)
+	r.Post("/smtp-users", s.handleCreate)
+	r.Get("/smtp-users/{username}", s.handleGet)
+	r.Put("/smtp-users/{username}", s.handleUpdate)
+	r.Delete("/smtp-users/{username}", s.handleDelete)
+}
+
+// SMTPUserCreateRequest is the request body for POST /smtp-users
+type SMTPUserCreateRequest struct {
+	Username string   `json:"username"`
+	Password string   `json:"password"`
+	Senders  []string `json:"senders,omitempty"`
+}
+
+// SMTPUserUpdateRequest is the request body for PUT /smtp-users/{username},
+// omitted fields are kept
+type SMTPUserUpdateRequest struct {
+	Password *string   `json:"password,omitempty"` // Rotates the password
+	Disabled *bool     `json:"disabled,omitempty"`
+	Senders  *[]string `json:"senders,omitempty"`
+}
+
+// SMTPUserListResponse represents SMTP user list response
+type SMTPUserListResponse struct {
+	Users []*smtpauth.User `json:"users"`
+	Total int              `json:"total"`
+}
+
+func (s *SMTPUserServer) handleList(w http.ResponseWriter, r *http.Request) {
+	users, err := s.storage.List(r.Context())
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if users == nil {
+		users = []*smtpauth.User{}
+	}
+	sendJSON(w, http.StatusOK, SMTPUserListResponse{Users: users, Total: len(users)})
+}
+
+func (s *SMTPUserServer) handleCreate(w http.ResponseWriter, r *http.Request) {
+	var req SMTPUserCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	user, err := s.storage.Create(r.Context(), req.Username, req.Password, req.Senders)
+	if errors.Is(err, smtpauth.ErrExists) {
+		sendError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	recordChange(r, nil, user)
+	sendJSON(w, http.StatusCreated, user)
+}
+
+func (s *SMTPUserServer) handleGet(w http.ResponseWriter, r *http.Request) {
+	user, err := s.storage.Get(r.Context(), chi.URLParam(r, "username"))
+	if errors.Is(err, smtpauth.ErrNotFound) {
+		sendError(w, http.StatusNotFound, "SMTP user not found")
+		return
+	}
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	sendJSON(w, http.StatusOK, user)
+}
+
+func (s *SMTPUserServer) handleUpdate(w http.ResponseWriter, r *http.Request) {
+	var req SMTPUserUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	username := chi.URLParam(r, "username")
+	before, err := s.storage.Get(r.Context(), username)
+	if errors.Is(err, smtpauth.ErrNotFound) {
+		sendError(w, http.StatusNotFound, "SMTP user not found")
+		return
+	}
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	user, err := s.storage.Update(r.Context(), username, smtpauth.Update{
+		Password: req.Password,
+		Disabled: req.Disabled,
+		Senders:  req.Senders,
+	})
+	if errors.Is(err, smtpauth.ErrNotFound) {
+		sendError(w, http.StatusNotFound, "SMTP user not found")
+		return
+	}
+	if err != nil {
+		sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	recordChange(r, before, user)
+	sendJSON(w, http.StatusOK, user)
+}
+
+func (s *SMTPUserServer) handleDelete(w http.ResponseWriter, r *http.Request) {
+	username := chi.URLParam(r, "username")
+	before, err := s.storage.Get(r.Context(), username)
+	if err == nil {
+		err = s.storage.Delete(r.Context(), username)
+	}
+	if errors.Is(err, smtpauth.ErrNotFound) {
+		sendError(w, http.StatusNotFound, "SMTP user not found")
+		return
+	}
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	recordChange(r, before, nil)
+	sendJSON(w, http.StatusOK, ActionResponse{Status: "deleted", Message: "SMTP user deleted"})
+}
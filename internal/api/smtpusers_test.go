@@ -0,0 +1,84 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/foxzi/sendry/internal/config"
+	"github.com/foxzi/sendry/internal/smtpauth"
+)
+
+func TestSMTPUserManagement(t *testing.T) {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	storage, err := smtpauth.NewStorage(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServerWithOptions(ServerOptions{
+		Queue:           newMockQueue(),
+		Config:          &config.APIConfig{ListenAddr: ":8080", APIKey: "admin-key"},
+		Logger:          slog.New(slog.NewTextHandler(io.Discard, nil)),
+		SMTPUserStorage: storage,
+	})
+
+	w := doWithToken(server, "POST", "/api/v1/smtp-users", "admin-key",
+		`{"username": "billing", "password": "first-secret", "senders": ["billing.example"]}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create status = %d. Body: %s", w.Code, w.Body.String())
+	}
+	var user smtpauth.User
+	json.Unmarshal(w.Body.Bytes(), &user)
+	if user.Username != "billing" || len(user.Senders) != 1 {
+		t.Errorf("created user = %+v", user)
+	}
+	if w := doWithToken(server, "POST", "/api/v1/smtp-users", "admin-key", `{"username": "billing", "password": "other-secret"}`); w.Code != http.StatusConflict {
+		t.Errorf("duplicate status = %d, want 409", w.Code)
+	}
+	if w := doWithToken(server, "POST", "/api/v1/smtp-users", "admin-key", `{"username": "short", "password": "abc"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("short password status = %d, want 400", w.Code)
+	}
+
+	// Rotate and disable
+	w = doWithToken(server, "PUT", "/api/v1/smtp-users/billing", "admin-key", `{"password": "second-secret", "disabled": true}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("update status = %d. Body: %s", w.Code, w.Body.String())
+	}
+	if _, err := storage.Verify(t.Context(), "billing", "second-secret"); err != smtpauth.ErrInvalidCredentials {
+		t.Errorf("Verify(disabled) error = %v, want ErrInvalidCredentials", err)
+	}
+	w = doWithToken(server, "PUT", "/api/v1/smtp-users/billing", "admin-key", `{"disabled": false}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("enable status = %d", w.Code)
+	}
+	if _, err := storage.Verify(t.Context(), "billing", "second-secret"); err != nil {
+		t.Errorf("Verify(rotated) error = %v", err)
+	}
+
+	w = doWithToken(server, "GET", "/api/v1/smtp-users", "admin-key", "")
+	var list SMTPUserListResponse
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if w.Code != http.StatusOK || list.Total != 1 {
+		t.Errorf("list = %d, %+v", w.Code, list)
+	}
+	if strings.Contains(w.Body.String(), "$2a$") {
+		t.Error("list exposes a password hash")
+	}
+
+	if w := doWithToken(server, "DELETE", "/api/v1/smtp-users/billing", "admin-key", ""); w.Code != http.StatusOK {
+		t.Errorf("delete status = %d", w.Code)
+	}
+	if w := doWithToken(server, "GET", "/api/v1/smtp-users/billing", "admin-key", ""); w.Code != http.StatusNotFound {
+		t.Errorf("get deleted status = %d, want 404", w.Code)
+	}
+}
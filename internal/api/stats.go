@@ -0,0 +1,223 @@
+package api
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/foxzi/sendry/internal/stats"
+)
+
+// maxStatsRange limits the range of a stats history or report
+const maxStatsRange = 366 * 24 * time.Hour
+
+// Report recipient domain limits
+const (
+	defaultReportTop = 5
+	maxReportTop     = 50
+)
+
+// StatsServer handles statistics history API endpoints
+type StatsServer struct {
+	storage *stats.Storage
+	now     func() time.Time
+}
+
+// NewStatsServer creates a new stats history server
+func NewStatsServer(storage *stats.Storage) *StatsServer {
+	return &StatsServer{storage: storage, now: time.Now}
+}
+
+// RegisterRoutes registers stats history routes
+func (s *StatsServer) RegisterRoutes(r chi.Router) {
+	r.Get("/stats/history", s.handleHistory)
+	r.Get("/reports/domains", s.handleReport(stats.ByDomain))
+	r.Get("/reports/keys", s.handleReport(stats.ByKey))
+}
+
+// StatsHistoryResponse is a time series of queue sizes and delivery counts
+type StatsHistoryResponse struct {
+	From   time.Time     `json:"from"`
+	To     time.Time     `json:"to"`
+	Step   string        `json:"step"`
+	Domain string        `json:"domain,omitempty"`
+	Points []stats.Point `json:"points"`
+}
+
+// handleHistory handles GET /api/v1/stats/history
+func (s *StatsServer) handleHistory(w http.ResponseWriter, r *http.Request) {
+	rng := 24 * time.Hour
+	if v := r.URL.Query().Get("range"); v != "" {
+		d, err := parseDays(v)
+		if err != nil || d <= 0 || d > maxStatsRange {
+			sendError(w, http.StatusBadRequest, "range must be a duration up to 366d, e.g. 6h or 7d")
+			return
+		}
+		rng = d
+	}
+
+	step := stats.Step(rng)
+	if v := r.URL.Query().Get("step"); v != "" {
+		d, err := parseDays(v)
+		if err != nil || d < time.Minute || rng/d > stats.MaxPoints {
+			sendError(w, http.StatusBadRequest, fmt.Sprintf("step must be at least 1m and split the range into at most %d points", stats.MaxPoints))
+			return
+		}
+		step = d
+	}
+
+	to := s.now()
+	from := to.Add(-rng)
+	points, step, err := s.storage.History(r.Context(), from, to, step)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to get stats history")
+		return
+	}
+
+	domain := strings.ToLower(r.URL.Query().Get("domain"))
+	if domain != "" {
+		points = stats.ForDomain(points, domain)
+	}
+	if points == nil {
+		points = []stats.Point{}
+	}
+
+	sendJSON(w, http.StatusOK, StatsHistoryResponse{
+		From:   from,
+		To:     to,
+		Step:   step.String(),
+		Domain: domain,
+		Points: points,
+	})
+}
+
+// ReportResponse is the usage of sending domains or API keys over a period
+type ReportResponse struct {
+	By   string            `json:"by"`
+	From time.Time         `json:"from"`
+	To   time.Time         `json:"to"`
+	Rows []stats.ReportRow `json:"rows"`
+}
+
+// handleReport handles GET /api/v1/reports/domains and /api/v1/reports/keys
+func (s *StatsServer) handleReport(by string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+
+		to := s.now()
+		rng := 24 * time.Hour
+		if v := params.Get("range"); v != "" {
+			d, err := parseDays(v)
+			if err != nil || d <= 0 || d > maxStatsRange {
+				sendError(w, http.StatusBadRequest, "range must be a duration up to 366d, e.g. 6h or 7d")
+				return
+			}
+			rng = d
+		}
+		from := to.Add(-rng)
+
+		// since and until override the range
+		since, err := parseArchiveTime(params.Get("since"), false)
+		if err != nil {
+			sendError(w, http.StatusBadRequest, fmt.Sprintf("invalid since: %v", err))
+			return
+		}
+		until, err := parseArchiveTime(params.Get("until"), true)
+		if err != nil {
+			sendError(w, http.StatusBadRequest, fmt.Sprintf("invalid until: %v", err))
+			return
+		}
+		if !until.IsZero() {
+			to = until
+			if since.IsZero() {
+				from = to.Add(-rng)
+			}
+		}
+		if !since.IsZero() {
+			from = since
+		}
+		if !from.Before(to) || to.Sub(from) > maxStatsRange {
+			sendError(w, http.StatusBadRequest, "since must be before until and at most 366d earlier")
+			return
+		}
+
+		top := defaultReportTop
+		if v := params.Get("top"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > maxReportTop {
+				sendError(w, http.StatusBadRequest, fmt.Sprintf("top must be between 1 and %d", maxReportTop))
+				return
+			}
+			top = n
+		}
+
+		format := params.Get("format")
+		if format != "" && format != "json" && format != "csv" {
+			sendError(w, http.StatusBadRequest, "format must be json or csv")
+			return
+		}
+
+		rows, err := s.storage.Report(r.Context(), by, from, to, top)
+		if err != nil {
+			sendError(w, http.StatusInternalServerError, "Failed to get report")
+			return
+		}
+
+		if format == "csv" {
+			writeReportCSV(w, by, from, to, rows)
+			return
+		}
+		sendJSON(w, http.StatusOK, ReportResponse{
+			By:   by,
+			From: from,
+			To:   to,
+			Rows: rows,
+		})
+	}
+}
+
+// writeReportCSV writes report rows as a CSV download, recipient domains as
+// domain=recipients pairs separated by semicolons
+func writeReportCSV(w http.ResponseWriter, by string, from, to time.Time, rows []stats.ReportRow) {
+	filename := fmt.Sprintf("sendry-%s-report-%s-%s.csv", by, from.UTC().Format("20060102T1504"), to.UTC().Format("20060102T1504"))
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+
+	writer := csv.NewWriter(w)
+	defer writer.Flush()
+
+	writer.Write([]string{"name", "key_name", "sent", "delivered", "deferred", "bounced", "avg_delivery_seconds", "top_recipient_domains"})
+	for _, row := range rows {
+		domains := make([]string, 0, len(row.TopRecipientDomains))
+		for _, d := range row.TopRecipientDomains {
+			domains = append(domains, fmt.Sprintf("%s=%d", d.Domain, d.Delivered+d.Deferred+d.Bounced))
+		}
+		writer.Write([]string{
+			row.Name,
+			row.KeyName,
+			strconv.Itoa(row.Sent),
+			strconv.Itoa(row.Delivered),
+			strconv.Itoa(row.Deferred),
+			strconv.Itoa(row.Bounced),
+			strconv.FormatFloat(row.AvgDeliverySeconds, 'f', 2, 64),
+			strings.Join(domains, ";"),
+		})
+	}
+}
+
+// parseDays parses a duration that may also be given in days, e.g. 7d
+func parseDays(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration: %s", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
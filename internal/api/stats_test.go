@@ -0,0 +1,156 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/foxzi/sendry/internal/stats"
+)
+
+func TestStatsHistoryAPI(t *testing.T) {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	storage, err := stats.NewStorage(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2026, 5, 3, 12, 0, 0, 0, time.UTC)
+	for h := 72; h > 0; h-- {
+		p := &stats.Point{
+			Time:    now.Add(-time.Duration(h) * time.Hour),
+			Totals:  stats.Counts{Delivered: 3},
+			Domains: map[string]*stats.Counts{"example.com": {Delivered: 1}},
+		}
+		if err := storage.Add(context.Background(), p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Keep the hours only
+	if _, err := storage.Prune(context.Background(), now, now.Add(-30*24*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	server := NewStatsServer(storage)
+	server.now = func() time.Time { return now }
+	r := chi.NewRouter()
+	server.RegisterRoutes(r)
+	get := func(query string) (*httptest.ResponseRecorder, StatsHistoryResponse) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/stats/history"+query, nil))
+		var resp StatsHistoryResponse
+		if w.Code == http.StatusOK {
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return w, resp
+	}
+
+	w, resp := get("?range=2d")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	// No minute points cover the range, so hours are returned
+	if resp.Step != "1h0m0s" || len(resp.Points) != 48 || resp.Points[0].Totals.Delivered != 3 {
+		t.Errorf("range=2d: step %s, %d points", resp.Step, len(resp.Points))
+	}
+
+	_, resp = get("?range=7d&step=24h&domain=Example.com")
+	if resp.Domain != "example.com" || len(resp.Points) != 4 || resp.Points[1].Totals.Delivered != 24 || resp.Points[1].Domains != nil {
+		t.Errorf("domain history = %+v", resp)
+	}
+
+	for _, query := range []string{"?range=abc", "?range=400d", "?range=-1h", "?step=10s", "?range=30d&step=1m"} {
+		if w, _ := get(query); w.Code != http.StatusBadRequest {
+			t.Errorf("%s status = %d, want 400", query, w.Code)
+		}
+	}
+}
+
+func TestReportAPI(t *testing.T) {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	storage, err := stats.NewStorage(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2026, 5, 3, 12, 30, 0, 0, time.UTC)
+	ctx := context.Background()
+	usage := map[string]*stats.Usage{
+		"k1": {Label: "billing", Accepted: 2, Delivered: 2, LatencyMillis: 3000, Recipients: map[string]*stats.RecipientCounts{
+			"gmail.com": {Delivered: 1},
+			"yahoo.com": {Delivered: 1},
+		}},
+		stats.NoKey: {Accepted: 1, Bounced: 1},
+	}
+	for _, at := range []time.Time{now, now.Add(-3 * 24 * time.Hour)} {
+		if err := storage.AddUsage(ctx, stats.ByKey, at, usage); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	server := NewStatsServer(storage)
+	server.now = func() time.Time { return now }
+	r := chi.NewRouter()
+	server.RegisterRoutes(r)
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w
+	}
+
+	w := get("/reports/keys?top=1")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	var resp ReportResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.By != stats.ByKey || len(resp.Rows) != 2 {
+		t.Fatalf("keys report = %+v", resp)
+	}
+	row := resp.Rows[0]
+	if row.Name != "k1" || row.KeyName != "billing" || row.Sent != 2 || row.AvgDeliverySeconds != 1.5 || len(row.TopRecipientDomains) != 1 || row.TopRecipientDomains[0].Domain != "gmail.com" {
+		t.Errorf("first row = %+v", row)
+	}
+
+	w = get("/reports/keys?since=2026-04-30&until=2026-05-03&format=csv")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/csv; charset=utf-8" {
+		t.Fatalf("csv = %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	want := "name,key_name,sent,delivered,deferred,bounced,avg_delivery_seconds,top_recipient_domains\n" +
+		"k1,billing,4,4,0,0,1.50,gmail.com=2;yahoo.com=2\n" +
+		"-,,2,0,0,2,0.00,\n"
+	if w.Body.String() != want {
+		t.Errorf("csv body = %q, want %q", w.Body.String(), want)
+	}
+
+	if w := get("/reports/domains"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"rows":[]`) {
+		t.Errorf("empty domain report = %d %s", w.Code, w.Body)
+	}
+
+	for _, query := range []string{"?range=400d", "?top=0", "?top=51", "?format=xml", "?since=yesterday", "?since=2026-05-04&until=2026-05-02"} {
+		if w := get("/reports/keys" + query); w.Code != http.StatusBadRequest {
+			t.Errorf("%s status = %d, want 400", query, w.Code)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/foxzi/sendry/internal/suppression"
+)
+
+// SuppressionServer handles suppression list API endpoints
+type SuppressionServer struct {
+	storage *suppression.Storage
+}
+
+// NewSuppressionServer creates a new suppression list server
+func NewSuppressionServer(storage *suppression.Storage) *SuppressionServer {
+	return &SuppressionServer{storage: storage}
+}
+
+// RegisterRoutes registers suppression list API routes
+func (s *SuppressionServer) RegisterRoutes(r chi.Router) {
+	r.Route("/suppressions", func(r chi.Router) {
+		r.Get("/", s.handleList)
+		r.Post("/", s.handleAdd)
+		r.Get("/{email}", s.handleGet)
+		r.Delete("/{email}", s.handleDelete)
+	})
+}
+
+// SuppressionListResponse is the response for listing suppressed addresses
+type SuppressionListResponse struct {
+	Suppressions []*suppression.Entry `json:"suppressions"`
+	Total        int                  `json:"total"`
+}
+
+// SuppressionRequest adds an address to the suppression list
+type SuppressionRequest struct {
+	Email  string `json:"email"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// handleList handles GET /api/v1/suppressions
+func (s *SuppressionServer) handleList(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := suppression.Filter{Reason: q.Get("reason")}
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > 1000 {
+			sendError(w, http.StatusBadRequest, "limit must be between 1 and 1000")
+			return
+		}
+		filter.Limit = limit
+	}
+	if v := q.Get("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			sendError(w, http.StatusBadRequest, "offset must not be negative")
+			return
+		}
+		filter.Offset = offset
+	}
+
+	entries, total, err := s.storage.List(r.Context(), filter)
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to list suppressions")
+		return
+	}
+
+	sendJSON(w, http.StatusOK, SuppressionListResponse{Suppressions: entries, Total: total})
+}
+
+// handleAdd handles POST /api/v1/suppressions
+func (s *SuppressionServer) handleAdd(w http.ResponseWriter, r *http.Request) {
+	var req SuppressionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	entry := &suppression.Entry{Email: req.Email, Reason: suppression.ReasonManual, Detail: req.Detail}
+	added, err := s.storage.Add(r.Context(), entry)
+	if err != nil {
+		sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !added {
+		sendError(w, http.StatusConflict, "Address is already suppressed")
+		return
+	}
+
+	recordChange(r, nil, entry)
+	sendJSON(w, http.StatusCreated, entry)
+}
+
+// handleGet handles GET /api/v1/suppressions/{email}
+func (s *SuppressionServer) handleGet(w http.ResponseWriter, r *http.Request) {
+	entry, err := s.storage.Get(r.Context(), chi.URLParam(r, "email"))
+	if errors.Is(err, suppression.ErrNotFound) {
+		sendError(w, http.StatusNotFound, "Address is not suppressed")
+		return
+	}
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to get suppression")
+		return
+	}
+
+	sendJSON(w, http.StatusOK, entry)
+}
+
+// handleDelete handles DELETE /api/v1/suppressions/{email}
+func (s *SuppressionServer) handleDelete(w http.ResponseWriter, r *http.Request) {
+	email := chi.URLParam(r, "email")
+	entry, err := s.storage.Get(r.Context(), email)
+	if errors.Is(err, suppression.ErrNotFound) {
+		sendError(w, http.StatusNotFound, "Address is not suppressed")
+		return
+	}
+	if err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to get suppression")
+		return
+	}
+
+	if err := s.storage.Remove(r.Context(), email); err != nil && !errors.Is(err, suppression.ErrNotFound) {
+		sendError(w, http.StatusInternalServerError, "Failed to remove suppression")
+		return
+	}
+
+	recordChange(r, entry, nil)
+	sendJSON(w, http.StatusOK, map[string]string{"status": "removed"})
+}
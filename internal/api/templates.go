@@ -13,6 +13,9 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 
+	"github.com/foxzi/sendry/internal/addrpolicy"
+	"github.com/foxzi/sendry/internal/contentfilter"
+	"github.com/foxzi/sendry/internal/contentpolicy"
 	"github.com/foxzi/sendry/internal/email"
 	"github.com/foxzi/sendry/internal/queue"
 	"github.com/foxzi/sendry/internal/template"
@@ -20,20 +23,64 @@ import (
 
 // TemplateServer handles template API endpoints
 type TemplateServer struct {
-	storage *template.Storage
-	engine  *template.Engine
-	queue   queue.Queue
+	storage         *template.Storage
+	engine          *template.Engine
+	queue           queue.Queue
+	maxMessageBytes int
+	rejectEAI       bool                    // Refuse internationalized addresses
+	dkim            DKIMProvider            // Signs messages at enqueue time if set
+	filter          *contentfilter.Checker  // Checks messages before they are queued if set
+	policy          *contentpolicy.Enforcer // Recipient and attachment limits if set
+	addrPolicy      *addrpolicy.Enforcer    // Allow and deny lists of addresses if set
 }
 
 // NewTemplateServer creates a new template server
 func NewTemplateServer(storage *template.Storage, q queue.Queue) *TemplateServer {
+	engine := template.NewCachedEngine(template.NewCache(template.DefaultCacheSize))
+	engine.SetPartials(storage)
 	return &TemplateServer{
-		storage: storage,
-		engine:  template.NewEngine(),
-		queue:   q,
+		storage:         storage,
+		engine:          engine,
+		queue:           q,
+		maxMessageBytes: defaultMaxMessageBytes,
 	}
 }
 
+// SetMaxMessageBytes sets the maximum size of messages sent via templates
+func (s *TemplateServer) SetMaxMessageBytes(n int) {
+	if n > 0 {
+		s.maxMessageBytes = n
+	}
+}
+
+// SetRejectEAI makes template sends refuse internationalized addresses
+func (s *TemplateServer) SetRejectEAI(reject bool) {
+	s.rejectEAI = reject
+}
+
+// SetDKIMProvider makes messages sent via templates DKIM-signed when they
+// are queued rather than when they are delivered
+func (s *TemplateServer) SetDKIMProvider(provider DKIMProvider) {
+	s.dkim = provider
+}
+
+// SetContentFilter passes messages sent via templates to the content filter
+func (s *TemplateServer) SetContentFilter(c *contentfilter.Checker) {
+	s.filter = c
+}
+
+// SetContentPolicy enforces recipient and attachment limits on messages
+// sent via templates
+func (s *TemplateServer) SetContentPolicy(e *contentpolicy.Enforcer) {
+	s.policy = e
+}
+
+// SetAddressPolicy enforces allow and deny lists of senders and recipients
+// on messages sent via templates
+func (s *TemplateServer) SetAddressPolicy(e *addrpolicy.Enforcer) {
+	s.addrPolicy = e
+}
+
 // RegisterRoutes registers template API routes
 func (s *TemplateServer) RegisterRoutes(r chi.Router) {
 	r.Route("/templates", func(r chi.Router) {
@@ -45,6 +92,14 @@ func (s *TemplateServer) RegisterRoutes(r chi.Router) {
 		r.Post("/{id}/preview", s.handlePreview)
 	})
 
+	r.Route("/partials", func(r chi.Router) {
+		r.Get("/", s.handleListPartials)
+		r.Post("/", s.handleCreatePartial)
+		r.Get("/{id}", s.handleGetPartial)
+		r.Put("/{id}", s.handleUpdatePartial)
+		r.Delete("/{id}", s.handleDeletePartial)
+	})
+
 	r.Post("/send/template", s.handleSendTemplate)
 }
 
@@ -82,6 +137,13 @@ type TemplateResponse struct {
 	Version     int                     `json:"version"`
 	CreatedAt   time.Time               `json:"created_at"`
 	UpdatedAt   time.Time               `json:"updated_at"`
+	Warnings    []template.Warning      `json:"warnings,omitempty"` // Lint warnings, on create and update
+}
+
+// TemplateErrorResponse is the response for a template that does not parse
+type TemplateErrorResponse struct {
+	Error    string             `json:"error"`
+	Warnings []template.Warning `json:"warnings,omitempty"`
 }
 
 // TemplateListResponse is the response for listing templates
@@ -112,6 +174,20 @@ type SendTemplateRequest struct {
 	BCC          []string               `json:"bcc,omitempty"`
 	Data         map[string]interface{} `json:"data"`
 	Headers      map[string]string      `json:"headers,omitempty"`
+	Attachments  []Attachment           `json:"attachments,omitempty"`
+	SendAt       *time.Time             `json:"send_at,omitempty"`   // Hold until this time (RFC 3339)
+	Priority     string                 `json:"priority,omitempty"`  // high, normal or low
+	SkipDKIM     bool                   `json:"skip_dkim,omitempty"` // Deliver without a DKIM signature, for debugging
+
+	// Recipients replaces to/cc/bcc: one message is rendered and queued
+	// per recipient, with its data merged over the shared data
+	Recipients []TemplateRecipient `json:"recipients,omitempty"`
+}
+
+// TemplateRecipient is a recipient of a personalized template send
+type TemplateRecipient struct {
+	To   string                 `json:"to"`
+	Data map[string]interface{} `json:"data,omitempty"` // Overrides of the shared data
 }
 
 // handleList handles GET /api/v1/templates
@@ -188,9 +264,13 @@ func (s *TemplateServer) handleCreate(w http.ResponseWriter, r *http.Request) {
 		Variables:   req.Variables,
 	}
 
-	// Validate template syntax
+	// Validate template syntax, other problems are returned as warnings
+	warnings := s.lint(r, tmpl)
 	if err := s.engine.Validate(tmpl); err != nil {
-		sendError(w, http.StatusBadRequest, fmt.Sprintf("Invalid template syntax: %v", err))
+		sendJSON(w, http.StatusBadRequest, TemplateErrorResponse{
+			Error:    fmt.Sprintf("Invalid template syntax: %v", err),
+			Warnings: warnings,
+		})
 		return
 	}
 
@@ -203,7 +283,20 @@ func (s *TemplateServer) handleCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sendJSON(w, http.StatusCreated, templateToResponse(tmpl))
+	resp := templateToResponse(tmpl)
+	resp.Warnings = warnings
+	sendJSON(w, http.StatusCreated, resp)
+}
+
+// lint returns the lint warnings of a template, including partials it
+// includes that do not exist
+func (s *TemplateServer) lint(r *http.Request, tmpl *template.Template) []template.Warning {
+	warnings := template.Lint(tmpl)
+	missing, err := template.LintPartials(r.Context(), tmpl, s.storage)
+	if err != nil {
+		return warnings
+	}
+	return append(warnings, missing...)
 }
 
 // handleGet handles GET /api/v1/templates/{id}
@@ -282,9 +375,13 @@ func (s *TemplateServer) handleUpdate(w http.ResponseWriter, r *http.Request) {
 		tmpl.Variables = req.Variables
 	}
 
-	// Validate template syntax
+	// Validate template syntax, other problems are returned as warnings
+	warnings := s.lint(r, tmpl)
 	if err := s.engine.Validate(tmpl); err != nil {
-		sendError(w, http.StatusBadRequest, fmt.Sprintf("Invalid template syntax: %v", err))
+		sendJSON(w, http.StatusBadRequest, TemplateErrorResponse{
+			Error:    fmt.Sprintf("Invalid template syntax: %v", err),
+			Warnings: warnings,
+		})
 		return
 	}
 
@@ -296,8 +393,11 @@ func (s *TemplateServer) handleUpdate(w http.ResponseWriter, r *http.Request) {
 		sendError(w, http.StatusInternalServerError, "Failed to update template")
 		return
 	}
+	s.engine.Cache().Invalidate(tmpl.ID)
 
-	sendJSON(w, http.StatusOK, templateToResponse(tmpl))
+	resp := templateToResponse(tmpl)
+	resp.Warnings = warnings
+	sendJSON(w, http.StatusOK, resp)
 }
 
 // handleDelete handles DELETE /api/v1/templates/{id}
@@ -312,6 +412,7 @@ func (s *TemplateServer) handleDelete(w http.ResponseWriter, r *http.Request) {
 		sendError(w, http.StatusInternalServerError, "Failed to delete template")
 		return
 	}
+	s.engine.Cache().Invalidate(id)
 
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -381,8 +482,24 @@ func (s *TemplateServer) handleSendTemplate(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	if len(req.To) == 0 {
-		sendError(w, http.StatusBadRequest, "to is required")
+	if len(req.Recipients) > 0 {
+		if len(req.To) > 0 || len(req.CC) > 0 || len(req.BCC) > 0 {
+			sendError(w, http.StatusBadRequest, "to, cc and bcc cannot be combined with recipients")
+			return
+		}
+		if len(req.Recipients) > maxBatchSize {
+			sendError(w, http.StatusRequestEntityTooLarge,
+				fmt.Sprintf("too many recipients: %d (max %d)", len(req.Recipients), maxBatchSize))
+			return
+		}
+		for _, rcpt := range req.Recipients {
+			if _, err := mail.ParseAddress(rcpt.To); err != nil {
+				sendError(w, http.StatusBadRequest, fmt.Sprintf("invalid recipient address: %s", rcpt.To))
+				return
+			}
+		}
+	} else if len(req.To) == 0 {
+		sendError(w, http.StatusBadRequest, "to or recipients is required")
 		return
 	}
 	for _, to := range req.To {
@@ -404,9 +521,20 @@ func (s *TemplateServer) handleSendTemplate(w http.ResponseWriter, r *http.Reque
 		}
 	}
 
+	priority, err := queue.ParsePriority(req.Priority)
+	if err != nil {
+		sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	attachments, err := decodeAttachments(req.Attachments)
+	if err != nil {
+		sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	// Get template
 	var tmpl *template.Template
-	var err error
 
 	if req.TemplateID != "" {
 		tmpl, err = s.storage.Get(r.Context(), req.TemplateID)
@@ -424,19 +552,91 @@ func (s *TemplateServer) handleSendTemplate(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	// Render template
-	result, err := s.engine.Render(tmpl, req.Data)
-	if err != nil {
-		sendError(w, http.StatusBadRequest, fmt.Sprintf("Failed to render template: %v", err))
+	if len(req.Recipients) > 0 {
+		s.sendTemplateRecipients(w, r, &req, tmpl, priority, attachments)
+		return
+	}
+
+	msg, status, errMsg := s.buildTemplateMessage(r, &req, tmpl, req.To, req.Data, priority, attachments)
+	if msg == nil {
+		sendError(w, status, errMsg)
+		return
+	}
+
+	// Enqueue
+	if err := s.queue.Enqueue(r.Context(), msg); err != nil {
+		sendError(w, http.StatusInternalServerError, "Failed to queue message")
 		return
 	}
 
+	sendJSON(w, http.StatusAccepted, SendResponse{
+		ID:     msg.ID,
+		Status: string(msg.Status),
+		SendAt: scheduledAt(msg),
+	})
+}
+
+// sendTemplateRecipients renders a copy of the template for each recipient
+// with the shared data and the data of the recipient, and queues one
+// message per recipient. Recipients whose copy cannot be rendered or is
+// refused are reported without blocking the others.
+func (s *TemplateServer) sendTemplateRecipients(w http.ResponseWriter, r *http.Request, req *SendTemplateRequest, tmpl *template.Template, priority queue.Priority, attachments []*mailAttachment) {
+	results := make([]BatchSendResultItem, len(req.Recipients))
+	toEnqueue := make([]*queue.Message, 0, len(req.Recipients))
+	indexes := make([]int, 0, len(req.Recipients)) // Result index of each message
+	for i, rcpt := range req.Recipients {
+		msg, _, errMsg := s.buildTemplateMessage(r, req, tmpl, []string{rcpt.To}, mergeTemplateData(req.Data, rcpt.Data), priority, attachments)
+		if msg == nil {
+			results[i] = BatchSendResultItem{Index: i, Error: errMsg}
+			continue
+		}
+		results[i] = BatchSendResultItem{Index: i, ID: msg.ID, Status: string(msg.Status)}
+		toEnqueue = append(toEnqueue, msg)
+		indexes = append(indexes, i)
+	}
+
+	if bs, ok := s.queue.(queue.BatchEnqueuer); ok && len(toEnqueue) > 0 {
+		if err := bs.EnqueueBatch(r.Context(), toEnqueue); err != nil {
+			sendError(w, http.StatusInternalServerError, "Failed to queue messages")
+			return
+		}
+	} else {
+		for j, msg := range toEnqueue {
+			if err := s.queue.Enqueue(r.Context(), msg); err != nil {
+				results[indexes[j]] = BatchSendResultItem{Index: indexes[j], Error: "Failed to queue message"}
+			}
+		}
+	}
+
+	resp := BatchSendResponse{Results: results}
+	for _, res := range results {
+		if res.Error != "" {
+			resp.Rejected++
+		} else {
+			resp.Accepted++
+		}
+	}
+	sendJSON(w, http.StatusAccepted, resp)
+}
+
+// buildTemplateMessage renders the template for the recipients and builds
+// the message to queue. On failure it returns nil, the HTTP status and the
+// error of the response.
+func (s *TemplateServer) buildTemplateMessage(r *http.Request, req *SendTemplateRequest, tmpl *template.Template, to []string, data map[string]interface{}, priority queue.Priority, attachments []*mailAttachment) (*queue.Message, int, string) {
+	result, err := s.engine.Render(tmpl, data)
+	if err != nil {
+		return nil, http.StatusBadRequest, fmt.Sprintf("Failed to render template: %v", err)
+	}
+
 	// Build email data
-	data := s.buildEmailData(req.From, req.To, req.CC, result.Subject, result.Text, result.HTML, req.Headers)
+	raw := s.buildEmailData(req.From, to, req.CC, result.Subject, result.Text, result.HTML, req.Headers, attachments)
+	if len(raw) > s.maxMessageBytes {
+		return nil, http.StatusRequestEntityTooLarge, fmt.Sprintf("email content too large (max %d bytes)", s.maxMessageBytes)
+	}
 
 	// Envelope recipients = To + CC + BCC
-	envelopeTo := make([]string, 0, len(req.To)+len(req.CC)+len(req.BCC))
-	envelopeTo = append(envelopeTo, req.To...)
+	envelopeTo := make([]string, 0, len(to)+len(req.CC)+len(req.BCC))
+	envelopeTo = append(envelopeTo, to...)
 	envelopeTo = append(envelopeTo, req.CC...)
 	envelopeTo = append(envelopeTo, req.BCC...)
 
@@ -445,36 +645,65 @@ func (s *TemplateServer) handleSendTemplate(w http.ResponseWriter, r *http.Reque
 		ID:        uuid.New().String(),
 		From:      req.From,
 		To:        envelopeTo,
-		Data:      data,
+		Data:      raw,
 		Status:    queue.StatusPending,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 		ClientIP:  r.RemoteAddr,
+		Priority:  priority,
 	}
-
-	// Enqueue
-	if err := s.queue.Enqueue(r.Context(), msg); err != nil {
-		sendError(w, http.StatusInternalServerError, "Failed to queue message")
-		return
+	setMessageAPIKey(r.Context(), msg)
+	if status, errMsg := checkSenders(r.Context(), msg); status != 0 {
+		return nil, status, errMsg
+	}
+	if status, errMsg := checkEAI(s.rejectEAI, msg); status != 0 {
+		return nil, status, errMsg
+	}
+	if status, errMsg := checkAddressPolicy(s.addrPolicy, msg); status != 0 {
+		return nil, status, errMsg
+	}
+	if req.SendAt != nil {
+		msg.Schedule(*req.SendAt)
+	}
+	if status, errMsg := checkPolicy(s.policy, msg); status != 0 {
+		return nil, status, errMsg
+	}
+	if status, errMsg := filterMessage(r.Context(), s.filter, msg); status != 0 {
+		return nil, status, errMsg
+	}
+	if req.SkipDKIM {
+		msg.SkipDKIM = true
+	} else {
+		// Delivery signs the message if signing fails here
+		_ = signAtEnqueue(s.dkim, msg)
 	}
+	return msg, 0, ""
+}
 
-	sendJSON(w, http.StatusAccepted, SendResponse{
-		ID:     msg.ID,
-		Status: string(msg.Status),
-	})
+// mergeTemplateData returns the shared data with the top-level keys of the
+// recipient data replacing those of the shared data
+func mergeTemplateData(shared, recipient map[string]interface{}) map[string]interface{} {
+	data := make(map[string]interface{}, len(shared)+len(recipient))
+	for k, v := range shared {
+		data[k] = v
+	}
+	for k, v := range recipient {
+		data[k] = v
+	}
+	return data
 }
 
 // buildEmailData constructs RFC 5322 email data
-func (s *TemplateServer) buildEmailData(from string, to []string, cc []string, subject, text, html string, headers map[string]string) []byte {
+func (s *TemplateServer) buildEmailData(from string, to []string, cc []string, subject, text, html string, headers map[string]string, attachments []*mailAttachment) []byte {
 	var buf bytes.Buffer
 
 	// Headers
-	buf.WriteString(fmt.Sprintf("From: %s\r\n", from))
-	buf.WriteString(fmt.Sprintf("To: %s\r\n", strings.Join(to, ", ")))
+	buf.WriteString(fmt.Sprintf("From: %s\r\n", formatAddress(from)))
+	buf.WriteString(fmt.Sprintf("To: %s\r\n", formatAddressList(to)))
 	if len(cc) > 0 {
-		buf.WriteString(fmt.Sprintf("Cc: %s\r\n", strings.Join(cc, ", ")))
+		buf.WriteString(fmt.Sprintf("Cc: %s\r\n", formatAddressList(cc)))
 	}
-	buf.WriteString(fmt.Sprintf("Subject: %s\r\n", subject))
+	buf.WriteString(fmt.Sprintf("Subject: %s\r\n", encodeHeaderValue(sanitizeHeaderValue(subject))))
 	buf.WriteString(fmt.Sprintf("Date: %s\r\n", time.Now().Format(time.RFC1123Z)))
 	buf.WriteString(fmt.Sprintf("Message-ID: <%s@%s>\r\n", uuid.New().String(), email.ExtractDomainOrDefault(from, "localhost")))
 
@@ -483,39 +712,11 @@ func (s *TemplateServer) buildEmailData(from string, to []string, cc []string, s
 		k = sanitizeHeaderValue(k)
 		v = sanitizeHeaderValue(v)
 		if k != "" {
-			buf.WriteString(fmt.Sprintf("%s: %s\r\n", k, v))
+			buf.WriteString(fmt.Sprintf("%s: %s\r\n", k, encodeHeaderValue(v)))
 		}
 	}
 
-	// MIME headers
-	if html != "" {
-		boundary := uuid.New().String()
-		buf.WriteString("MIME-Version: 1.0\r\n")
-		buf.WriteString(fmt.Sprintf("Content-Type: multipart/alternative; boundary=\"%s\"\r\n", boundary))
-		buf.WriteString("\r\n")
-
-		// Plain text part
-		if text != "" {
-			buf.WriteString(fmt.Sprintf("--%s\r\n", boundary))
-			buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
-			buf.WriteString("\r\n")
-			buf.WriteString(text)
-			buf.WriteString("\r\n")
-		}
-
-		// HTML part
-		buf.WriteString(fmt.Sprintf("--%s\r\n", boundary))
-		buf.WriteString("Content-Type: text/html; charset=utf-8\r\n")
-		buf.WriteString("\r\n")
-		buf.WriteString(html)
-		buf.WriteString("\r\n")
-
-		buf.WriteString(fmt.Sprintf("--%s--\r\n", boundary))
-	} else {
-		buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
-		buf.WriteString("\r\n")
-		buf.WriteString(text)
-	}
+	writeMIMEBody(&buf, text, html, attachments)
 
 	return buf.Bytes()
 }
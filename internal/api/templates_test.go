@@ -0,0 +1,237 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/foxzi/sendry/internal/dkim"
+	"github.com/foxzi/sendry/internal/queue"
+	"github.com/foxzi/sendry/internal/template"
+)
+
+func setupTemplateServer(t *testing.T) (*TemplateServer, *mockQueue, chi.Router) {
+	t.Helper()
+
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	storage, err := template.NewStorage(db)
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+
+	q := newMockQueue()
+	server := NewTemplateServer(storage, q)
+	r := chi.NewRouter()
+	server.RegisterRoutes(r)
+	return server, q, r
+}
+
+func doTemplateRequest(t *testing.T, r chi.Router, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestSendTemplateUsesUpdatedVersion(t *testing.T) {
+	server, q, r := setupTemplateServer(t)
+
+	w := doTemplateRequest(t, r, "POST", "/templates", `{"name": "welcome", "subject": "Hello {{.Name}}", "text": "v1"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create status = %d: %s", w.Code, w.Body.String())
+	}
+	var tmpl TemplateResponse
+	json.NewDecoder(w.Body).Decode(&tmpl)
+
+	send := func() string {
+		w := doTemplateRequest(t, r, "POST", "/send/template",
+			`{"template_name": "welcome", "from": "a@example.com", "to": ["b@example.com"], "data": {"Name": "John"}}`)
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("send status = %d: %s", w.Code, w.Body.String())
+		}
+		var resp SendResponse
+		json.NewDecoder(w.Body).Decode(&resp)
+		return string(q.messages[resp.ID].Data)
+	}
+
+	send()
+	if data := send(); !strings.Contains(data, "Subject: Hello John") {
+		t.Errorf("message = %q, want rendered subject", data)
+	}
+	if stats := server.engine.Cache().Stats(); stats.Hits != 1 || stats.Misses != 1 {
+		t.Errorf("cache stats = %+v, want 1 hit and 1 miss", stats)
+	}
+
+	w = doTemplateRequest(t, r, "PUT", "/templates/"+tmpl.ID, `{"subject": "Hi {{.Name}}"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("update status = %d: %s", w.Code, w.Body.String())
+	}
+
+	if data := send(); !strings.Contains(data, "Subject: Hi John") {
+		t.Errorf("message after update = %q, want new subject", data)
+	}
+
+	w = doTemplateRequest(t, r, "DELETE", "/templates/"+tmpl.ID, "")
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("delete status = %d", w.Code)
+	}
+	if stats := server.engine.Cache().Stats(); stats.Entries != 0 {
+		t.Errorf("cache entries after delete = %d, want 0", stats.Entries)
+	}
+}
+
+func TestSendTemplateSignsAtEnqueue(t *testing.T) {
+	server, q, r := setupTemplateServer(t)
+	kp, err := dkim.GenerateKey("example.com", "sendry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.SetDKIMProvider(staticDKIM{signer: dkim.NewSigner(kp.PrivateKey, "example.com", "sendry")})
+
+	w := doTemplateRequest(t, r, "POST", "/templates", `{"name": "welcome", "subject": "Hello", "text": "v1"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create status = %d: %s", w.Code, w.Body.String())
+	}
+
+	send := func(skip bool) *queue.Message {
+		body := fmt.Sprintf(`{"template_name": "welcome", "from": "a@example.com", "to": ["b@example.org"], "skip_dkim": %v}`, skip)
+		w := doTemplateRequest(t, r, "POST", "/send/template", body)
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("send status = %d: %s", w.Code, w.Body.String())
+		}
+		var resp SendResponse
+		json.NewDecoder(w.Body).Decode(&resp)
+		return q.messages[resp.ID]
+	}
+
+	if msg := send(false); !msg.DKIMSigned || dkim.SignatureHeader(msg.Data) == "" {
+		t.Error("template message not signed at enqueue")
+	}
+	if msg := send(true); msg.DKIMSigned || !msg.SkipDKIM || dkim.SignatureHeader(msg.Data) != "" {
+		t.Error("template message signed despite skip_dkim")
+	}
+}
+
+func TestSendTemplateRecipients(t *testing.T) {
+	_, q, r := setupTemplateServer(t)
+
+	w := doTemplateRequest(t, r, "POST", "/templates",
+		`{"name": "promo", "subject": "{{.Name}}, {{.Offer}}", "html": "<p>Hi {{.Name}}, your code is {{.Code}}</p>"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create status = %d: %s", w.Code, w.Body.String())
+	}
+
+	body := `{
+		"template_name": "promo",
+		"from": "shop@example.com",
+		"data": {"Name": "customer", "Offer": "20% off", "Code": "SALE"},
+		"recipients": [
+			{"to": "alice@example.org", "data": {"Name": "Alice", "Code": "A1"}},
+			{"to": "bob@example.org"},
+			{"to": "carol@example.org", "data": {"Offer": "30% off"}}
+		]
+	}`
+	w = doTemplateRequest(t, r, "POST", "/send/template", body)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("send status = %d: %s", w.Code, w.Body.String())
+	}
+	var resp BatchSendResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.Accepted != 3 || resp.Rejected != 0 || len(resp.Results) != 3 {
+		t.Fatalf("response = %+v, want 3 accepted", resp)
+	}
+
+	want := []struct {
+		to, subject, html string
+	}{
+		{"alice@example.org", "Subject: Alice, 20% off", "Hi Alice, your code is A1"},
+		{"bob@example.org", "Subject: customer, 20% off", "Hi customer, your code is SALE"},
+		{"carol@example.org", "Subject: customer, 30% off", "Hi customer, your code is SALE"},
+	}
+	for i, tt := range want {
+		msg := q.messages[resp.Results[i].ID]
+		if msg == nil {
+			t.Fatalf("result %d not queued", i)
+		}
+		data := string(msg.Data)
+		if len(msg.To) != 1 || msg.To[0] != tt.to || !strings.Contains(data, "To: "+tt.to+"\r\n") {
+			t.Errorf("message %d recipients = %v, want only %s", i, msg.To, tt.to)
+		}
+		if !strings.Contains(data, tt.subject+"\r\n") || !strings.Contains(data, tt.html) {
+			t.Errorf("message %d = %q, want %q and %q", i, data, tt.subject, tt.html)
+		}
+	}
+
+	// A copy that fails to render is reported, the others are queued
+	q.messages = map[string]*queue.Message{}
+	w = doTemplateRequest(t, r, "POST", "/templates", `{"name": "strict", "subject": "{{.Name.First}}", "text": "x"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create status = %d: %s", w.Code, w.Body.String())
+	}
+	w = doTemplateRequest(t, r, "POST", "/send/template", `{"template_name": "strict", "from": "shop@example.com",
+		"data": {"Name": {"First": "Ann"}},
+		"recipients": [{"to": "a@example.org"}, {"to": "b@example.org", "data": {"Name": "plain"}}]}`)
+	json.NewDecoder(w.Body).Decode(&resp)
+	if w.Code != http.StatusAccepted || resp.Accepted != 1 || resp.Rejected != 1 || resp.Results[1].Error == "" || len(q.messages) != 1 {
+		t.Errorf("partial send = %d %+v, want one accepted and one rejected", w.Code, resp)
+	}
+
+	for _, body := range []string{
+		`{"template_name": "promo", "from": "shop@example.com", "to": ["x@example.org"], "recipients": [{"to": "a@example.org"}]}`,
+		`{"template_name": "promo", "from": "shop@example.com", "recipients": [{"to": "not an address"}]}`,
+		`{"template_name": "promo", "from": "shop@example.com"}`,
+	} {
+		if w := doTemplateRequest(t, r, "POST", "/send/template", body); w.Code != http.StatusBadRequest {
+			t.Errorf("send %s status = %d, want 400", body, w.Code)
+		}
+	}
+}
+
+func TestTemplateLintWarnings(t *testing.T) {
+	_, _, r := setupTemplateServer(t)
+
+	w := doTemplateRequest(t, r, "POST", "/templates", `{"name": "lint", "subject": "Hi {{.Name}}",
+		"html": "<a href=\"\">x</a> {{.Promo}}", "variables": [{"name": "Name"}]}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create status = %d: %s", w.Code, w.Body.String())
+	}
+	var tmpl TemplateResponse
+	json.NewDecoder(w.Body).Decode(&tmpl)
+	codes := map[string]bool{}
+	for _, warning := range tmpl.Warnings {
+		codes[warning.Code+":"+warning.Value] = true
+	}
+	if len(tmpl.Warnings) != 2 || !codes["empty_url:"] || !codes["undefined_variable:Promo"] {
+		t.Errorf("create warnings = %+v", tmpl.Warnings)
+	}
+
+	w = doTemplateRequest(t, r, "PUT", "/templates/"+tmpl.ID, `{"html": "<p>{{.Name</p>"}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("update status = %d, want 400", w.Code)
+	}
+	var errResp TemplateErrorResponse
+	json.NewDecoder(w.Body).Decode(&errResp)
+	if !strings.Contains(errResp.Error, "Invalid template syntax") || len(errResp.Warnings) != 1 || errResp.Warnings[0].Code != "syntax" {
+		t.Errorf("update error = %+v", errResp)
+	}
+
+	w = doTemplateRequest(t, r, "PUT", "/templates/"+tmpl.ID, `{"html": "<a href=\"https://example.com\">{{.Name}}</a>"}`)
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), "warnings") {
+		t.Errorf("clean update = %d: %s", w.Code, w.Body.String())
+	}
+}
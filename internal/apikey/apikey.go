@@ -0,0 +1,101 @@
+// Package apikey stores the HTTP API keys with their scopes, rate limits
+// and last use.
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/foxzi/sendry/internal/email"
+	"github.com/foxzi/sendry/internal/ratelimit"
+)
+
+// TokenPrefix starts every generated token
+const TokenPrefix = "sndr_"
+
+// Scope grants access to a group of API endpoints
+type Scope string
+
+const (
+	// ScopeSend allows message submission and status lookups
+	ScopeSend Scope = "send"
+	// ScopeRead allows all read-only requests
+	ScopeRead Scope = "read"
+	// ScopeAdmin allows everything, including key management
+	ScopeAdmin Scope = "admin"
+)
+
+// ValidScope reports whether s is a known scope
+func ValidScope(s Scope) bool {
+	switch s {
+	case ScopeSend, ScopeRead, ScopeAdmin:
+		return true
+	}
+	return false
+}
+
+// Key is an API key. The token itself is only returned on creation.
+type Key struct {
+	ID         string                 `json:"id"`
+	Name       string                 `json:"name"`
+	Prefix     string                 `json:"prefix"` // Start of the token, to recognize it
+	Scopes     []Scope                `json:"scopes"`
+	RateLimit  *ratelimit.LimitConfig `json:"rate_limit,omitempty"` // Overrides rate_limit.default_api_key
+	Senders    []string               `json:"senders,omitempty"`    // Domains, *.domains and addresses the key may send as, empty for any
+	CreatedAt  time.Time              `json:"created_at"`
+	LastUsedAt *time.Time             `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time             `json:"revoked_at,omitempty"`
+}
+
+// Active reports whether the key can be used
+func (k *Key) Active() bool {
+	return k.RevokedAt == nil
+}
+
+// Allows reports whether the key grants scope. Admin keys grant every scope.
+func (k *Key) Allows(scope Scope) bool {
+	return slices.Contains(k.Scopes, ScopeAdmin) || slices.Contains(k.Scopes, scope)
+}
+
+// AllowsSender reports whether the key may submit messages from addr
+func (k *Key) AllowsSender(addr string) bool {
+	return email.SenderAllowed(k.Senders, addr)
+}
+
+// newToken returns a random token
+func newToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return TokenPrefix + hex.EncodeToString(b), nil
+}
+
+// hashToken returns the stored form of a token
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeScopes removes duplicates and checks the scopes
+func normalizeScopes(scopes []Scope) ([]Scope, error) {
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("at least one scope is required")
+	}
+	var out []Scope
+	for _, s := range scopes {
+		s = Scope(strings.ToLower(strings.TrimSpace(string(s))))
+		if !ValidScope(s) {
+			return nil, fmt.Errorf("invalid scope %q (must be send, read or admin)", s)
+		}
+		if !slices.Contains(out, s) {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
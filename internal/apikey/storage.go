@@ -0,0 +1,243 @@
+package apikey
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/foxzi/sendry/internal/email"
+	"github.com/foxzi/sendry/internal/ratelimit"
+)
+
+var (
+	bucketKeys   = []byte("api_keys")
+	bucketHashes = []byte("api_key_hashes")
+)
+
+// touchInterval limits how often the last use of a key is written
+const touchInterval = time.Minute
+
+// ErrNotFound is returned when a key does not exist
+var ErrNotFound = errors.New("api key not found")
+
+// record is the stored form of a key
+type record struct {
+	Key
+	Hash string `json:"hash"`
+}
+
+// Storage stores API keys in BoltDB
+type Storage struct {
+	db  *bolt.DB
+	now func() time.Time
+
+	mu      sync.Mutex
+	touched map[string]time.Time // Last use written per key
+}
+
+// NewStorage creates a new API key storage using the provided BoltDB instance
+func NewStorage(db *bolt.DB) (*Storage, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(bucketKeys); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(bucketHashes)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create api key buckets: %w", err)
+	}
+	return &Storage{db: db, now: time.Now, touched: make(map[string]time.Time)}, nil
+}
+
+// Create stores a new key and returns it with its token. The token cannot
+// be recovered later.
+func (s *Storage) Create(ctx context.Context, name string, scopes []Scope, limit *ratelimit.LimitConfig, senders []string) (*Key, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", fmt.Errorf("name is required")
+	}
+	scopes, err := normalizeScopes(scopes)
+	if err != nil {
+		return nil, "", err
+	}
+	if limit != nil && (limit.MessagesPerMinute < 0 || limit.MessagesPerHour < 0 || limit.MessagesPerDay < 0) {
+		return nil, "", fmt.Errorf("rate limits must not be negative")
+	}
+	senders, err = email.NormalizeSenders(senders)
+	if err != nil {
+		return nil, "", err
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return nil, "", err
+	}
+	rec := &record{
+		Key: Key{
+			ID:        uuid.New().String(),
+			Name:      name,
+			Prefix:    token[:len(TokenPrefix)+8],
+			Scopes:    scopes,
+			RateLimit: limit,
+			Senders:   senders,
+			CreatedAt: s.now(),
+		},
+		Hash: hashToken(token),
+	}
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(bucketHashes).Put([]byte(rec.Hash), []byte(rec.ID)); err != nil {
+			return err
+		}
+		return putRecord(tx, rec)
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to store api key: %w", err)
+	}
+	return &rec.Key, token, nil
+}
+
+// List returns all keys, revoked ones included, oldest first
+func (s *Storage) List(ctx context.Context) ([]*Key, error) {
+	var keys []*Key
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketKeys).ForEach(func(k, v []byte) error {
+			var rec record
+			if err := json.Unmarshal(v, &rec); err != nil {
+				return nil // Skip corrupted entries
+			}
+			keys = append(keys, &rec.Key)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(keys, func(a, b *Key) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return keys, nil
+}
+
+// Get returns a key by ID
+func (s *Storage) Get(ctx context.Context, id string) (*Key, error) {
+	var rec *record
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		rec, err = getRecord(tx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &rec.Key, nil
+}
+
+// Revoke disables a key. Revoked keys stay listed.
+func (s *Storage) Revoke(ctx context.Context, id string) (*Key, error) {
+	var rec *record
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		var err error
+		if rec, err = getRecord(tx, id); err != nil {
+			return err
+		}
+		if rec.RevokedAt != nil {
+			return nil
+		}
+		now := s.now()
+		rec.RevokedAt = &now
+		if err := tx.Bucket(bucketHashes).Delete([]byte(rec.Hash)); err != nil {
+			return err
+		}
+		return putRecord(tx, rec)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &rec.Key, nil
+}
+
+// Authenticate returns the active key of a token, or nil if there is none
+func (s *Storage) Authenticate(ctx context.Context, token string) (*Key, error) {
+	if !strings.HasPrefix(token, TokenPrefix) {
+		return nil, nil
+	}
+
+	var rec *record
+	err := s.db.View(func(tx *bolt.Tx) error {
+		id := tx.Bucket(bucketHashes).Get([]byte(hashToken(token)))
+		if id == nil {
+			return nil
+		}
+		var err error
+		rec, err = getRecord(tx, string(id))
+		return err
+	})
+	if err != nil || rec == nil || !rec.Active() {
+		return nil, err
+	}
+	return &rec.Key, nil
+}
+
+// HasActive reports whether any key can be used
+func (s *Storage) HasActive(ctx context.Context) (bool, error) {
+	var found bool
+	err := s.db.View(func(tx *bolt.Tx) error {
+		k, _ := tx.Bucket(bucketHashes).Cursor().First()
+		found = k != nil
+		return nil
+	})
+	return found, err
+}
+
+// Touch records the use of a key. Writes are limited to one per key and
+// touchInterval, so last use is accurate to that interval.
+func (s *Storage) Touch(ctx context.Context, id string) error {
+	now := s.now()
+
+	s.mu.Lock()
+	if last, ok := s.touched[id]; ok && now.Sub(last) < touchInterval {
+		s.mu.Unlock()
+		return nil
+	}
+	s.touched[id] = now
+	s.mu.Unlock()
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		rec, err := getRecord(tx, id)
+		if err != nil {
+			return err
+		}
+		rec.LastUsedAt = &now
+		return putRecord(tx, rec)
+	})
+}
+
+func getRecord(tx *bolt.Tx, id string) (*record, error) {
+	data := tx.Bucket(bucketKeys).Get([]byte(id))
+	if data == nil {
+		return nil, ErrNotFound
+	}
+	var rec record
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal api key: %w", err)
+	}
+	return &rec, nil
+}
+
+func putRecord(tx *bolt.Tx, rec *record) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to marshal api key: %w", err)
+	}
+	return tx.Bucket(bucketKeys).Put([]byte(rec.ID), data)
+}
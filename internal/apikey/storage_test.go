@@ -0,0 +1,170 @@
+package apikey
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/foxzi/sendry/internal/ratelimit"
+)
+
+func newTestStorage(t *testing.T) *Storage {
+	t.Helper()
+
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	storage, err := NewStorage(db)
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+	return storage
+}
+
+func TestStorageCreateAuthenticate(t *testing.T) {
+	storage := newTestStorage(t)
+	ctx := context.Background()
+
+	limit := &ratelimit.LimitConfig{MessagesPerMinute: 10}
+	key, token, err := storage.Create(ctx, "billing", []Scope{"send", "SEND", "read"}, limit, nil)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if !strings.HasPrefix(token, TokenPrefix) || !strings.HasPrefix(token, key.Prefix) {
+		t.Errorf("token = %q, prefix = %q", token, key.Prefix)
+	}
+	if len(key.Scopes) != 2 {
+		t.Errorf("Scopes = %v, want send and read", key.Scopes)
+	}
+
+	got, err := storage.Authenticate(ctx, token)
+	if err != nil || got == nil || got.ID != key.ID {
+		t.Fatalf("Authenticate() = %+v, %v", got, err)
+	}
+	if got.RateLimit == nil || got.RateLimit.MessagesPerMinute != 10 {
+		t.Errorf("RateLimit = %+v", got.RateLimit)
+	}
+	if !got.Allows(ScopeSend) || got.Allows(ScopeAdmin) {
+		t.Errorf("Allows() of %v is wrong", got.Scopes)
+	}
+
+	for _, bad := range []string{"", "other", TokenPrefix + "0000"} {
+		if got, _ := storage.Authenticate(ctx, bad); got != nil {
+			t.Errorf("Authenticate(%q) = %+v, want nil", bad, got)
+		}
+	}
+}
+
+func TestStorageCreateValidation(t *testing.T) {
+	storage := newTestStorage(t)
+	ctx := context.Background()
+
+	if _, _, err := storage.Create(ctx, "", []Scope{ScopeSend}, nil, nil); err == nil {
+		t.Error("Create() without name succeeded")
+	}
+	if _, _, err := storage.Create(ctx, "a", nil, nil, nil); err == nil {
+		t.Error("Create() without scopes succeeded")
+	}
+	if _, _, err := storage.Create(ctx, "a", []Scope{"write"}, nil, nil); err == nil {
+		t.Error("Create() with unknown scope succeeded")
+	}
+	for _, sender := range []string{"localhost", "@example.com", "mail.*.example.com"} {
+		if _, _, err := storage.Create(ctx, "a", []Scope{ScopeSend}, nil, []string{sender}); err == nil {
+			t.Errorf("Create() with sender %q succeeded", sender)
+		}
+	}
+}
+
+func TestStorageCreateSenders(t *testing.T) {
+	storage := newTestStorage(t)
+	ctx := context.Background()
+
+	senders := []string{" Acme.example ", "*.acme.example", "Billing@Globex.example", "acme.example"}
+	_, token, err := storage.Create(ctx, "acme", []Scope{ScopeSend}, nil, senders)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	key, _ := storage.Authenticate(ctx, token)
+	if key == nil || len(key.Senders) != 3 || key.Senders[0] != "acme.example" {
+		t.Fatalf("Senders = %+v, want three normalized entries", key)
+	}
+	for addr, want := range map[string]bool{
+		"news@acme.example":      true,
+		"news@mail.acme.example": true,
+		"billing@globex.example": true,
+		"ceo@globex.example":     false,
+	} {
+		if got := key.AllowsSender(addr); got != want {
+			t.Errorf("AllowsSender(%q) = %t, want %t", addr, got, want)
+		}
+	}
+}
+
+func TestStorageRevoke(t *testing.T) {
+	storage := newTestStorage(t)
+	ctx := context.Background()
+
+	if ok, _ := storage.HasActive(ctx); ok {
+		t.Error("HasActive() of empty storage = true")
+	}
+
+	key, token, _ := storage.Create(ctx, "ci", []Scope{ScopeAdmin}, nil, nil)
+	if ok, _ := storage.HasActive(ctx); !ok {
+		t.Error("HasActive() = false, want true")
+	}
+
+	revoked, err := storage.Revoke(ctx, key.ID)
+	if err != nil || revoked.Active() {
+		t.Fatalf("Revoke() = %+v, %v", revoked, err)
+	}
+	if got, _ := storage.Authenticate(ctx, token); got != nil {
+		t.Error("revoked key still authenticates")
+	}
+	if ok, _ := storage.HasActive(ctx); ok {
+		t.Error("HasActive() after revoke = true")
+	}
+
+	keys, _ := storage.List(ctx)
+	if len(keys) != 1 || keys[0].RevokedAt == nil {
+		t.Errorf("List() = %+v", keys)
+	}
+	if _, err := storage.Revoke(ctx, "missing"); err != ErrNotFound {
+		t.Errorf("Revoke() of missing key error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestStorageTouch(t *testing.T) {
+	storage := newTestStorage(t)
+	ctx := context.Background()
+
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	storage.now = func() time.Time { return now }
+
+	key, _, _ := storage.Create(ctx, "app", []Scope{ScopeSend}, nil, nil)
+	if err := storage.Touch(ctx, key.ID); err != nil {
+		t.Fatalf("Touch() error = %v", err)
+	}
+
+	// Uses within the touch interval are not written
+	first := now
+	now = now.Add(10 * time.Second)
+	storage.Touch(ctx, key.ID)
+	got, _ := storage.Get(ctx, key.ID)
+	if got.LastUsedAt == nil || !got.LastUsedAt.Equal(first) {
+		t.Errorf("LastUsedAt = %v, want %v", got.LastUsedAt, first)
+	}
+
+	now = now.Add(touchInterval)
+	storage.Touch(ctx, key.ID)
+	got, _ = storage.Get(ctx, key.ID)
+	if !got.LastUsedAt.Equal(now) {
+		t.Errorf("LastUsedAt = %v, want %v", got.LastUsedAt, now)
+	}
+}
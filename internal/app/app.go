@@ -3,39 +3,71 @@ package app
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
+	"github.com/foxzi/sendry/internal/addrpolicy"
 	"github.com/foxzi/sendry/internal/api"
+	"github.com/foxzi/sendry/internal/apikey"
+	"github.com/foxzi/sendry/internal/archive"
+	"github.com/foxzi/sendry/internal/audit"
+	"github.com/foxzi/sendry/internal/autoreply"
 	"github.com/foxzi/sendry/internal/bounce"
 	"github.com/foxzi/sendry/internal/config"
+	"github.com/foxzi/sendry/internal/consumer"
+	"github.com/foxzi/sendry/internal/contentfilter"
+	"github.com/foxzi/sendry/internal/contentpolicy"
 	"github.com/foxzi/sendry/internal/dns"
+	"github.com/foxzi/sendry/internal/dnsbl"
+	"github.com/foxzi/sendry/internal/dnsmonitor"
 	"github.com/foxzi/sendry/internal/domain"
+	"github.com/foxzi/sendry/internal/eventlog"
+	"github.com/foxzi/sendry/internal/fbl"
 	"github.com/foxzi/sendry/internal/headers"
+	"github.com/foxzi/sendry/internal/health"
+	"github.com/foxzi/sendry/internal/idempotency"
+	"github.com/foxzi/sendry/internal/inbound"
+	"github.com/foxzi/sendry/internal/ipguard"
+	"github.com/foxzi/sendry/internal/maillist"
 	"github.com/foxzi/sendry/internal/metrics"
+	"github.com/foxzi/sendry/internal/privacy"
 	"github.com/foxzi/sendry/internal/queue"
 	"github.com/foxzi/sendry/internal/ratelimit"
+	"github.com/foxzi/sendry/internal/replication"
+	"github.com/foxzi/sendry/internal/reputation"
+	"github.com/foxzi/sendry/internal/retry"
 	"github.com/foxzi/sendry/internal/sandbox"
+	"github.com/foxzi/sendry/internal/shaping"
 	"github.com/foxzi/sendry/internal/smtp"
+	"github.com/foxzi/sendry/internal/smtpauth"
+	"github.com/foxzi/sendry/internal/stats"
+	"github.com/foxzi/sendry/internal/suppression"
 	"github.com/foxzi/sendry/internal/template"
 	sendryTLS "github.com/foxzi/sendry/internal/tls"
+	"github.com/foxzi/sendry/internal/tracing"
 )
 
 // App is the main application
 type App struct {
 	config           *config.Config
 	queue            queue.Queue
+	boltStorage      *queue.BoltStorage
 	smtpServer       *smtp.Server
 	smtpSubmission   *smtp.Server
 	smtpsServer      *smtp.Server
 	apiServer        *api.Server
+	grpcServer       *api.GRPCServer
 	processor        *queue.Processor
 	cleaner          *queue.Cleaner
+	archiveStorage   *archive.Storage
+	archiveCleaner   *archive.Cleaner
 	logger           *slog.Logger
 	tlsConfig        *tls.Config
 	acmeManager      *sendryTLS.ACMEManager
@@ -44,8 +76,32 @@ type App struct {
 	rateLimiter      *ratelimit.Limiter
 	sandboxStorage   *sandbox.Storage
 	sandboxSender    *sandbox.Sender
+	sandboxCleaner   *sandbox.Cleaner
 	metricsServer    *metrics.Server
 	metricsCollector *metrics.Collector
+	consumer         *consumer.Consumer
+	reputation       *reputation.Monitor
+	statsRecorder    *stats.Recorder
+	dnsMonitor       *dnsmonitor.Monitor
+	dnsblMonitor     *dnsbl.Monitor
+	replPrimary      *replication.Primary
+	replStandby      *replication.Standby
+	health           *health.Checker
+	healthResponder  *health.Responder
+	smtpAuth         *smtpauth.Authenticator
+	secretsDigest    string // Digest of the secrets of the last applied config
+	headerProcessor  *headers.Processor
+	contentPolicy    *contentpolicy.Enforcer
+	addrPolicy       *addrpolicy.Enforcer
+	pauses           *queue.Pauses
+	connPool         *smtp.ConnPool
+	concurrency      *queue.Concurrency
+	retries          *retry.Scheduler
+	eventLog         *eventlog.Logger
+	stopTracing      func(context.Context) error
+
+	// Serializes config reloads from SIGHUP and the API
+	reloadMu sync.Mutex
 }
 
 // New creates a new application
@@ -59,57 +115,131 @@ func New(cfg *config.Config) (*App, error) {
 		return nil, fmt.Errorf("failed to create storage: %w", err)
 	}
 
-	// Create rate limiter if enabled
-	var rateLimiter *ratelimit.Limiter
-	if cfg.RateLimit.Enabled {
-		rlConfig := &ratelimit.Config{}
-		if cfg.RateLimit.Global != nil {
-			rlConfig.Global = &ratelimit.LimitConfig{
-				MessagesPerHour: cfg.RateLimit.Global.MessagesPerHour,
-				MessagesPerDay:  cfg.RateLimit.Global.MessagesPerDay,
-			}
+	// Select queue backend, BoltDB stays open for the auxiliary stores
+	var messageQueue queue.Storage = storage
+	if cfg.Storage.Driver == config.StorageDriverSQLite {
+		sqliteStorage, err := queue.NewSQLiteStorage(cfg.Storage.DSN)
+		if err != nil {
+			storage.Close()
+			return nil, fmt.Errorf("failed to create sqlite queue storage: %w", err)
 		}
-		if cfg.RateLimit.DefaultDomain != nil {
-			rlConfig.DefaultDomain = &ratelimit.LimitConfig{
-				MessagesPerHour: cfg.RateLimit.DefaultDomain.MessagesPerHour,
-				MessagesPerDay:  cfg.RateLimit.DefaultDomain.MessagesPerDay,
-			}
+		messageQueue = sqliteStorage
+		logger.Info("using sqlite queue storage", "dsn", cfg.Storage.DSN)
+	} else {
+		sharded, err := openShardedQueue(cfg.Storage, storage, logger)
+		if err != nil {
+			storage.Close()
+			return nil, err
 		}
-		if cfg.RateLimit.DefaultSender != nil {
-			rlConfig.DefaultSender = &ratelimit.LimitConfig{
-				MessagesPerHour: cfg.RateLimit.DefaultSender.MessagesPerHour,
-				MessagesPerDay:  cfg.RateLimit.DefaultSender.MessagesPerDay,
-			}
+		if sharded != nil {
+			messageQueue = sharded
 		}
-		if cfg.RateLimit.DefaultIP != nil {
-			rlConfig.DefaultIP = &ratelimit.LimitConfig{
-				MessagesPerHour: cfg.RateLimit.DefaultIP.MessagesPerHour,
-				MessagesPerDay:  cfg.RateLimit.DefaultIP.MessagesPerDay,
-			}
+	}
+
+	// Encrypt message data at rest, below replication so a standby stores
+	// what it receives encrypted with its own copy of the key
+	if cfg.Storage.Encryption.Enabled {
+		enc, err := queue.LoadEncryptor(cfg.Storage.Encryption.Key, cfg.Storage.Encryption.KeyFile, cfg.Storage.Encryption.PreviousKeys)
+		if err != nil {
+			storage.Close()
+			return nil, fmt.Errorf("storage.encryption: %w", err)
 		}
-		if cfg.RateLimit.DefaultAPIKey != nil {
-			rlConfig.DefaultAPIKey = &ratelimit.LimitConfig{
-				MessagesPerHour: cfg.RateLimit.DefaultAPIKey.MessagesPerHour,
-				MessagesPerDay:  cfg.RateLimit.DefaultAPIKey.MessagesPerDay,
-			}
+		messageQueue = queue.NewEncryptedStorage(messageQueue, enc)
+		logger.Info("queue message encryption enabled")
+	}
+
+	// Replicate the queue before anything writes to it
+	var replPrimary *replication.Primary
+	var replStandby *replication.Standby
+	if cfg.Replication.Enabled {
+		replPrimary, replStandby, err = openReplication(cfg, messageQueue, logger)
+		if err != nil {
+			storage.Close()
+			return nil, err
 		}
-		if cfg.RateLimit.DefaultRecipientDomain != nil {
-			rlConfig.DefaultRecipientDomain = &ratelimit.LimitConfig{
-				MessagesPerHour: cfg.RateLimit.DefaultRecipientDomain.MessagesPerHour,
-				MessagesPerDay:  cfg.RateLimit.DefaultRecipientDomain.MessagesPerDay,
-			}
+		if replPrimary != nil {
+			messageQueue = replPrimary
+		} else {
+			messageQueue = replStandby
 		}
-		if cfg.RateLimit.RecipientDomains != nil {
-			rlConfig.RecipientDomains = make(map[string]*ratelimit.LimitConfig)
-			for domain, limit := range cfg.RateLimit.RecipientDomains {
-				rlConfig.RecipientDomains[domain] = &ratelimit.LimitConfig{
-					MessagesPerHour: limit.MessagesPerHour,
-					MessagesPerDay:  limit.MessagesPerDay,
-				}
-			}
+	}
+
+	// Log delivery events, accepted messages as they are queued
+	var eventLog *eventlog.Logger
+	if cfg.Logging.DeliveryLog.Enabled {
+		eventLog, err = eventlog.New(deliveryLogConfig(cfg), logger.With("component", "delivery_log"))
+		if err != nil {
+			storage.Close()
+			return nil, fmt.Errorf("failed to open delivery log: %w", err)
+		}
+		logger.Info("delivery log enabled",
+			"file", cfg.Logging.DeliveryLog.File,
+			"syslog", cfg.Logging.DeliveryLog.Syslog.Enabled,
+		)
+	}
+
+	// Hand delivery events to the event streams of the gRPC API
+	var eventBroker *eventlog.Broker
+	if cfg.API.GRPC.Enabled {
+		eventBroker = eventlog.NewBroker(cfg.Server.Hostname, cfg.API.GRPC.EventBuffer)
+	}
+
+	// Record the history of queue sizes and delivery counts
+	var statsStorage *stats.Storage
+	var statsRecorder *stats.Recorder
+	if cfg.Stats.Enabled {
+		statsStorage, err = stats.NewStorage(storage.DB())
+		if err != nil {
+			storage.Close()
+			return nil, fmt.Errorf("failed to create stats storage: %w", err)
+		}
+		statsRecorder = stats.NewRecorder(
+			statsStorage,
+			messageQueue,
+			stats.Config{
+				MinuteRetention: cfg.Stats.MinuteRetention,
+				Retention:       cfg.Stats.Retention,
+			},
+			logger.With("component", "stats"),
+		)
+	}
+
+	var events eventlog.Tee
+	if eventLog != nil {
+		events = append(events, eventLog)
+	}
+	if eventBroker != nil {
+		events = append(events, eventBroker)
+	}
+	if statsRecorder != nil {
+		events = append(events, statsRecorder)
+	}
+	if len(events) > 0 {
+		messageQueue = eventlog.NewQueue(messageQueue, events)
+	}
+
+	// Export traces of API requests, queue processing and deliveries; the
+	// trace context is stored on queued messages
+	var stopTracing func(context.Context) error
+	if cfg.Tracing.Enabled {
+		stopTracing, err = tracing.Setup(context.Background(), tracing.Config{
+			Endpoint:    cfg.Tracing.Endpoint,
+			Headers:     cfg.Tracing.Headers,
+			ServiceName: cfg.Tracing.ServiceName,
+			SampleRatio: cfg.Tracing.SampleRatio,
+		})
+		if err != nil {
+			storage.Close()
+			return nil, err
 		}
+		messageQueue = queue.NewTracedStorage(messageQueue)
+		logger.Info("tracing enabled", "endpoint", cfg.Tracing.Endpoint, "sample_ratio", cfg.Tracing.SampleRatio)
+	}
 
-		rateLimiter, err = ratelimit.NewLimiter(storage.DB(), rlConfig)
+	// Create rate limiter if enabled
+	var rateLimiter *ratelimit.Limiter
+	if cfg.RateLimit.Enabled {
+		rateLimiter, err = ratelimit.NewLimiter(storage.DB(), rateLimitConfig(&cfg.RateLimit))
 		if err != nil {
 			return nil, fmt.Errorf("failed to create rate limiter: %w", err)
 		}
@@ -126,7 +256,7 @@ func New(cfg *config.Config) (*App, error) {
 		metrics.SetGlobal(metricsInstance)
 
 		// Create queue stats adapter for metrics
-		queueStatsAdapter := &queueStatsAdapter{queue: storage}
+		queueStatsAdapter := &queueStatsAdapter{queue: messageQueue}
 
 		metricsCollector, err = metrics.NewCollector(
 			storage.DB(),
@@ -161,10 +291,19 @@ func New(cfg *config.Config) (*App, error) {
 
 	// Create SMTP client
 	smtpClient := smtp.NewClient(resolver, cfg.Server.Hostname, 30*time.Second, logger.With("component", "smtp_client"))
+	smtpClient.SetMXHealth(smtp.NewMXHealth(cfg.Delivery.MXDeadTTL))
+	smtpClient.SetMXFallbackDelay(cfg.Delivery.MXFallbackDelay)
+	smtpClient.SetMaxAttemptsPerMX(cfg.Delivery.MaxAttemptsPerMX)
+	var connPool *smtp.ConnPool
+	if pool := cfg.Delivery.Pool; pool.Enabled {
+		connPool = smtp.NewConnPool(pool.MaxConnectionsPerMX, pool.IdleTimeout, pool.MaxMessagesPerConnection)
+		smtpClient.SetConnPool(connPool)
+	}
 
 	// Setup DKIM provider for multi-domain signing (always set, even if no keys yet)
 	// This allows keys added via API to be used without restart
 	smtpClient.SetDKIMProvider(domainMgr)
+	smtpClient.SetDomainConfigs(domainMgr)
 	if domainMgr.HasDKIM() {
 		logger.Info("DKIM signing enabled", "domains", domainMgr.ListDomains())
 	}
@@ -175,6 +314,16 @@ func New(cfg *config.Config) (*App, error) {
 		return nil, fmt.Errorf("failed to create sandbox storage: %w", err)
 	}
 	logger.Info("sandbox storage enabled")
+	sandboxCleaner := sandbox.NewCleaner(
+		sandboxStorage,
+		sandbox.CleanerConfig{
+			MaxAge:       cfg.Sandbox.MaxAge,
+			MaxCount:     cfg.Sandbox.MaxCount,
+			Interval:     cfg.Sandbox.CleanupInterval,
+			DomainMaxAge: cfg.SandboxRetention(),
+		},
+		logger.With("component", "sandbox_cleaner"),
+	)
 
 	// Create template storage
 	templateStorage, err := template.NewStorage(storage.DB())
@@ -183,6 +332,71 @@ func New(cfg *config.Config) (*App, error) {
 	}
 	logger.Info("template storage enabled")
 
+	// Create auto-reply storage
+	autoReplyStorage, err := autoreply.NewStorage(storage.DB())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create autoreply storage: %w", err)
+	}
+
+	// Create mailing list storage
+	listStorage, err := maillist.NewStorage(storage.DB())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create maillist storage: %w", err)
+	}
+
+	// Create send schedule storage
+	shapingStorage, err := shaping.NewStorage(storage.DB())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create shaping storage: %w", err)
+	}
+
+	// Create management API audit log storage
+	auditStorage, err := audit.NewStorage(storage.DB())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create audit storage: %w", err)
+	}
+
+	// Create send API idempotency key storage
+	idempotencyStorage, err := idempotency.NewStorage(storage.DB())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create idempotency storage: %w", err)
+	}
+	idempotencyStorage.SetTTL(cfg.API.IdempotencyTTL)
+
+	// Create API key storage
+	apiKeyStorage, err := apikey.NewStorage(storage.DB())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create API key storage: %w", err)
+	}
+
+	// Verify SMTP AUTH against the config users, the credential store and
+	// the hook
+	var smtpUserStorage *smtpauth.Storage
+	if cfg.SMTP.Auth.Store {
+		smtpUserStorage, err = smtpauth.NewStorage(storage.DB())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create SMTP user storage: %w", err)
+		}
+		logger.Info("SMTP credential store enabled")
+	}
+	var authHook *smtpauth.Hook
+	if cfg.SMTP.Auth.Hook.URL != "" {
+		authHook = smtpauth.NewHook(cfg.SMTP.Auth.Hook)
+		logger.Info("SMTP auth hook enabled", "url", cfg.SMTP.Auth.Hook.URL)
+	}
+	for user, password := range cfg.SMTP.Auth.Users {
+		if !smtpauth.IsHash(password) {
+			logger.Warn("SMTP user has a plaintext password, use a bcrypt or argon2id hash", "username", user)
+		}
+	}
+	smtpAuth := smtpauth.New(&cfg.SMTP.Auth, smtpUserStorage, authHook)
+
+	// Create recipient suppression list storage
+	suppressionStorage, err := suppression.NewStorage(storage.DB())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create suppression storage: %w", err)
+	}
+
 	// Create sandbox sender that wraps the real sender
 	sandboxSender := sandbox.NewSender(
 		smtpClient,
@@ -190,38 +404,82 @@ func New(cfg *config.Config) (*App, error) {
 		sandboxStorage,
 		logger.With("component", "sandbox_sender"),
 	)
+	sandboxSender.SetDKIMProvider(domainMgr)
+	sandboxSender.SetOverrides(cfg.Sandbox.Overrides)
 
-	// Setup header rules processor if configured
-	if cfg.HeaderRules != nil && cfg.HeaderRules.HasRules() {
-		headerProcessor := headers.NewProcessor(cfg.HeaderRules)
-		sandboxSender.SetHeaderProcessor(headerProcessor)
+	// Setup header rules processor, always set so rules added by a config
+	// reload apply without restart
+	headerProcessor := headers.NewProcessor(cfg.HeaderRules)
+	headerRuleStorage, err := headers.NewStorage(storage.DB())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create header rules storage: %w", err)
+	}
+	headerProcessor.SetStorage(headerRuleStorage)
+	sandboxSender.SetHeaderProcessor(headerProcessor)
+	if cfg.HeaderRules.HasRules() {
 		logger.Info("header rules enabled")
 	}
 
-	// Create queue processor with sandbox sender
-	processor := queue.NewProcessor(
-		storage,
+	// Deliver mail for domains with inbound rules locally, relay the rest
+	inboundRouter := inbound.NewRouter(domainMgr)
+	inboundSender := inbound.NewSender(
+		inboundRouter,
 		sandboxSender,
+		messageQueue,
+		smtp.IsTemporaryError,
+		logger.With("component", "inbound"),
+	)
+
+	// Attribute bounces received by inbound bounce rules, always set so
+	// return paths added through the API work without restart
+	inboundSender.SetBounceProcessor(bounce.NewProcessor(messageQueue, suppressionStorage, logger.With("component", "bounce")))
+
+	// Create queue processor with inbound and sandbox senders
+	processor := queue.NewProcessor(
+		messageQueue,
+		inboundSender,
 		queue.ProcessorConfig{
 			Workers:         cfg.Queue.Workers,
 			RetryInterval:   cfg.Queue.RetryInterval,
 			MaxRetries:      cfg.Queue.MaxRetries,
 			ProcessInterval: cfg.Queue.ProcessInterval,
 			DLQEnabled:      cfg.DLQ.Enabled,
+			MaxDeliveryTime: cfg.Delivery.MaxDeliveryTime,
 		},
 		smtp.IsTemporaryError,
 		logger.With("component", "processor"),
 	)
 
+	// Limit parallel deliveries per recipient domain, always set so limits
+	// added by a config reload apply without restart
+	concurrency := queue.NewConcurrency(cfg.Queue.MaxConcurrentConnections, cfg.Queue.DomainConcurrency)
+	processor.SetConcurrency(concurrency)
+
+	// Schedule retries by the policies of recipient domains and error
+	// classes, always set so policies added later apply without restart
+	retries := retry.NewScheduler(defaultRetryPolicy(&cfg.Queue), cfg.Queue.Retry, func(d string) *retry.Config {
+		if dc := domainMgr.GetDomainConfig(d); dc != nil {
+			return dc.Retry
+		}
+		return nil
+	})
+	retries.SetGreylistRetry(cfg.Queue.GreylistRetry)
+	processor.SetRetryScheduler(retries)
+
+	if len(events) > 0 {
+		processor.SetEventLogger(events)
+	}
+
 	// Create cleaner for automatic cleanup
 	cleaner := queue.NewCleaner(
-		storage,
+		messageQueue,
 		queue.CleanerConfig{
-			DeliveredMaxAge:   cfg.Storage.Retention.DeliveredMaxAge,
-			DeliveredInterval: cfg.Storage.Retention.CleanupInterval,
-			DLQMaxAge:         cfg.DLQ.MaxAge,
-			DLQMaxCount:       cfg.DLQ.MaxCount,
-			DLQInterval:       cfg.DLQ.CleanupInterval,
+			DeliveredMaxAge:       cfg.Storage.Retention.DeliveredMaxAge,
+			DeliveredInterval:     cfg.Storage.Retention.CleanupInterval,
+			DeliveredDomainMaxAge: cfg.DeliveredRetention(),
+			DLQMaxAge:             cfg.DLQ.MaxAge,
+			DLQMaxCount:           cfg.DLQ.MaxCount,
+			DLQInterval:           cfg.DLQ.CleanupInterval,
 		},
 		logger.With("component", "cleaner"),
 	)
@@ -236,56 +494,257 @@ func New(cfg *config.Config) (*App, error) {
 		processor.SetRateLimiter(rateLimiter)
 	}
 
-	// Setup TLS configuration
+	// Skip recipients on the suppression list
+	processor.SetSuppressor(suppressionStorage)
+
+	// Setup auto-responder for vacation replies
+	processor.SetAutoResponder(autoreply.NewEngine(autoReplyStorage))
+
+	// Setup mailing list expansion
+	processor.SetListExpander(maillist.NewExpander(listStorage))
+
+	// Setup per-domain send schedules
+	processor.SetTrafficShaper(shaping.NewShaper(shapingStorage))
+
+	// Setup delivery pauses, domains can be paused in the config
+	pauses := queue.NewPauses()
+	pauses.SetConfigured(cfg.PausedDomains())
+	processor.SetPauses(pauses)
+
+	// Setup message archive of delivered mail
+	var archiveStorage *archive.Storage
+	var archiveCleaner *archive.Cleaner
+	if cfg.Archive.Enabled {
+		archiveStorage, err = archive.NewStorage(cfg.Archive.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create archive storage: %w", err)
+		}
+		processor.SetArchiver(archiveStorage)
+		archiveCleaner = archive.NewCleaner(
+			archiveStorage,
+			archive.CleanerConfig{
+				MaxAge:       cfg.Archive.MaxAge,
+				MaxCount:     cfg.Archive.MaxCount,
+				Interval:     cfg.Archive.CleanupInterval,
+				DomainMaxAge: cfg.DeliveredRetention(),
+			},
+			logger.With("component", "archive_cleaner"),
+		)
+		logger.Info("message archive enabled", "path", cfg.Archive.Path, "fts", archiveStorage.FTS())
+		if archiveStorage.FTS() != "fts5" {
+			logger.Warn("archive search uses FTS4, build with -tags sqlite_fts5 for FTS5")
+		}
+	}
+
+	// Setup sending reputation scores of sender domains
+	var reputationStorage *reputation.Storage
+	var reputationMonitor *reputation.Monitor
+	if cfg.Reputation.Enabled {
+		reputationStorage, err = reputation.NewStorage(storage.DB())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create reputation storage: %w", err)
+		}
+		reputationMonitor = reputation.NewMonitor(
+			reputationStorage,
+			domainMgr,
+			reputation.Config{
+				Interval:  cfg.Reputation.Interval,
+				Window:    cfg.Reputation.Window,
+				Retention: cfg.Reputation.Retention,
+				IPs:       cfg.Reputation.IPs,
+				Hostname:  cfg.Server.Hostname,
+			},
+			logger.With("component", "reputation"),
+		)
+		processor.SetDeliveryRecorder(reputationMonitor)
+		logger.Info("reputation scoring enabled", "interval", cfg.Reputation.Interval)
+	}
+
+	// Setup scheduled DNS checks of configured domains
+	var dnsStorage *dnsmonitor.Storage
+	var dnsMonitor *dnsmonitor.Monitor
+	if cfg.DNSMonitor.Enabled {
+		dnsStorage, err = dnsmonitor.NewStorage(storage.DB())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create dns check storage: %w", err)
+		}
+		dnsMonitor = dnsmonitor.NewMonitor(
+			dnsStorage,
+			domainMgr,
+			dnsmonitor.Config{
+				Interval:  cfg.DNSMonitor.Interval,
+				Retention: cfg.DNSMonitor.Retention,
+			},
+			logger.With("component", "dns_monitor"),
+		)
+		logger.Info("dns monitoring enabled", "interval", cfg.DNSMonitor.Interval)
+	}
+
+	// Setup scheduled DNSBL checks of outbound IPs
+	var dnsblMonitor *dnsbl.Monitor
+	if cfg.DNSBLMonitor.Enabled {
+		dnsblStorage, err := dnsbl.NewStorage(storage.DB())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create dnsbl storage: %w", err)
+		}
+		dnsblMonitor = dnsbl.NewMonitor(
+			dnsblStorage,
+			dnsbl.Config{
+				Interval:  cfg.DNSBLMonitor.Interval,
+				Retention: cfg.DNSBLMonitor.Retention,
+				IPs:       cfg.DNSBLMonitor.IPs,
+			},
+			logger.With("component", "dnsbl_monitor"),
+		)
+		logger.Info("dnsbl monitoring enabled", "interval", cfg.DNSBLMonitor.Interval, "ips", len(cfg.DNSBLMonitor.IPs))
+	}
+
+	// Setup complaint feedback loop report processing
+	var fblStorage *fbl.Storage
+	var fblProcessor *fbl.Processor
+	if cfg.FBL.Enabled {
+		fblStorage, err = fbl.NewStorage(storage.DB())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create complaint storage: %w", err)
+		}
+		fblProcessor = fbl.NewProcessor(fblStorage, suppressionStorage, logger.With("component", "fbl"))
+		if reputationStorage != nil {
+			fblProcessor.SetComplaintRecorder(reputationStorage)
+		}
+		inboundSender.SetReportProcessor(fblProcessor)
+		logger.Info("complaint feedback loop enabled")
+	}
+
+	// Setup TLS configuration. Certificates are selected by SNI: domains with
+	// a tls section get their own certificate, other names the ACME or
+	// manual one.
 	var tlsConfig *tls.Config
 	var acmeManager *sendryTLS.ACMEManager
 
+	certStore := sendryTLS.NewSNIStore(func(domain string) (string, string) {
+		if dc := domainMgr.GetDomainConfig(domain); dc != nil && dc.TLS != nil {
+			return dc.TLS.CertFile, dc.TLS.KeyFile
+		}
+		return "", ""
+	}, logger.With("component", "tls"))
+	domainCerts := cfg.DomainCertificates()
+
 	if cfg.SMTP.TLS.ACME.Enabled {
 		acmeManager = sendryTLS.NewACMEManager(
 			cfg.SMTP.TLS.ACME.Email,
 			cfg.SMTP.TLS.ACME.Domains,
 			cfg.SMTP.TLS.ACME.CacheDir,
 		)
-		tlsConfig = acmeManager.TLSConfig()
+		certStore.SetFallback(acmeManager.TLSConfig())
+		tlsConfig = certStore.TLSConfig()
 		logger.Info("ACME (Let's Encrypt) enabled", "domains", cfg.SMTP.TLS.ACME.Domains)
 	} else if cfg.SMTP.TLS.CertFile != "" && cfg.SMTP.TLS.KeyFile != "" {
-		tlsConfig, err = sendryTLS.LoadCertificate(cfg.SMTP.TLS.CertFile, cfg.SMTP.TLS.KeyFile)
-		if err != nil {
+		if err := certStore.SetDefault(cfg.SMTP.TLS.CertFile, cfg.SMTP.TLS.KeyFile); err != nil {
 			return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
 		}
+		tlsConfig = certStore.TLSConfig()
 		logger.Info("TLS enabled with manual certificates")
+	} else if len(domainCerts) > 0 {
+		tlsConfig = certStore.TLSConfig()
+		logger.Warn("TLS enabled with domain certificates only, clients that send no server name cannot connect")
+	}
+	if tlsConfig != nil && len(domainCerts) > 0 {
+		logger.Info("per-domain TLS certificates enabled", "domains", domainCerts)
 	}
 
 	// Get allowed domains for anti-relay protection
 	allowedDomains := cfg.GetAllDomains()
 
+	// External content filter of received and API messages
+	var contentFilter *contentfilter.Checker
+	if cfg.ContentFilter.Enabled {
+		contentFilter, err = contentfilter.New(cfg.ContentFilter, cfg.Server.Hostname, logger.With("component", "content_filter"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create content filter: %w", err)
+		}
+		logger.Info("content filter enabled", "type", cfg.ContentFilter.Type, "sources", cfg.ContentFilter.Sources)
+	}
+
+	// Recipient and attachment limits of received and API messages
+	contentPolicy := contentpolicy.New(cfg.ContentPolicy, domainMgr.GetDomainConfig, logger.With("component", "content_policy"))
+
+	// Allow and deny lists of senders and recipients, from the config and
+	// managed through the API
+	addrPolicy := addrpolicy.New(cfg.AddressPolicy, domainMgr.GetDomainConfig, logger.With("component", "address_policy"))
+	policyStorage, err := addrpolicy.NewStorage(storage.DB())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create address policy storage: %w", err)
+	}
+	addrPolicy.SetStorage(policyStorage)
+
+	// Throttling and temporary bans of client IPs, shared by the listeners
+	var ipGuard *ipguard.Guard
+	if cfg.SMTP.IPGuard.Enabled {
+		ipGuard = ipguard.New(cfg.SMTP.IPGuard, logger.With("component", "ip_guard"))
+		logger.Info("SMTP IP guard enabled", "exempt", len(cfg.SMTP.IPGuard.Exempt))
+	}
+
+	// Ask for client certificates on the listeners with certificate auth
+	listenerTLS := map[string]*tls.Config{"smtp": tlsConfig, "submission": tlsConfig, "smtps": tlsConfig}
+	listenerCerts := map[string]*smtpauth.Certificates{}
+	if cc := cfg.SMTP.ClientCerts; cc.CAFile != "" {
+		if tlsConfig == nil {
+			return nil, fmt.Errorf("smtp.client_certs requires TLS to be configured")
+		}
+		certTLS, err := sendryTLS.RequestClientCertificate(tlsConfig, cc.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure SMTP client certificates: %w", err)
+		}
+		certs := smtpauth.NewCertificates(cc.Clients)
+		for listener := range listenerTLS {
+			if cc.Enabled(listener) {
+				listenerTLS[listener] = certTLS
+				listenerCerts[listener] = certs
+			}
+		}
+		logger.Info("SMTP client certificate authentication enabled", "clients", len(cc.Clients))
+	}
+
 	// Create SMTP server (port 25) with STARTTLS
 	smtpServer := smtp.NewServerWithOptions(smtp.ServerOptions{
 		Config:         &cfg.SMTP,
-		Queue:          storage,
+		Queue:          messageQueue,
 		Logger:         logger.With("component", "smtp_server"),
-		TLSConfig:      tlsConfig,
+		TLSConfig:      listenerTLS["smtp"],
 		Implicit:       false,
 		Addr:           cfg.SMTP.ListenAddr,
 		RateLimiter:    rateLimiter,
 		ServerType:     "smtp",
 		AllowedDomains: allowedDomains,
 		AllowedIPs:     cfg.SMTP.AllowedIPs,
+		ContentFilter:  contentFilter,
+		ContentPolicy:  contentPolicy,
+		AddressPolicy:  addrPolicy,
+		InboundRouter:  inboundRouter,
+		Authenticator:  smtpAuth,
+		Certificates:   listenerCerts["smtp"],
+		IPGuard:        ipGuard,
 	})
 
 	// Create SMTP submission server (port 587) with STARTTLS
 	submissionCfg := cfg.SMTP
 	smtpSubmission := smtp.NewServerWithOptions(smtp.ServerOptions{
 		Config:         &submissionCfg,
-		Queue:          storage,
+		Queue:          messageQueue,
 		Logger:         logger.With("component", "smtp_submission"),
-		TLSConfig:      tlsConfig,
+		TLSConfig:      listenerTLS["submission"],
 		Implicit:       false,
 		Addr:           cfg.SMTP.SubmissionAddr,
 		RateLimiter:    rateLimiter,
 		ServerType:     "submission",
 		AllowedDomains: allowedDomains,
 		AllowedIPs:     cfg.SMTP.AllowedIPs,
+		ContentFilter:  contentFilter,
+		ContentPolicy:  contentPolicy,
+		AddressPolicy:  addrPolicy,
+		Authenticator:  smtpAuth,
+		Certificates:   listenerCerts["submission"],
+		IPGuard:        ipGuard,
 	})
 
 	// Create SMTPS server (port 465) with implicit TLS
@@ -293,50 +752,169 @@ func New(cfg *config.Config) (*App, error) {
 	if tlsConfig != nil {
 		smtpsServer = smtp.NewServerWithOptions(smtp.ServerOptions{
 			Config:         &cfg.SMTP,
-			Queue:          storage,
+			Queue:          messageQueue,
 			Logger:         logger.With("component", "smtps_server"),
-			TLSConfig:      tlsConfig,
+			TLSConfig:      listenerTLS["smtps"],
 			Implicit:       true,
 			Addr:           cfg.SMTP.SMTPSAddr,
 			RateLimiter:    rateLimiter,
 			ServerType:     "smtps",
 			AllowedDomains: allowedDomains,
 			AllowedIPs:     cfg.SMTP.AllowedIPs,
+			ContentFilter:  contentFilter,
+			ContentPolicy:  contentPolicy,
+			AddressPolicy:  addrPolicy,
+			Authenticator:  smtpAuth,
+			Certificates:   listenerCerts["smtps"],
+			IPGuard:        ipGuard,
 		})
 	}
 
+	// Require client certificates on the API when a client CA is configured
+	apiTLSConfig := tlsConfig
+	if cfg.API.TLS.ClientCAFile != "" {
+		apiTLSConfig, err = sendryTLS.RequireClientCertificate(tlsConfig, cfg.API.TLS.ClientCAFile, cfg.API.TLS.AllowedFingerprints)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure API client certificates: %w", err)
+		}
+		logger.Info("API client certificate authentication enabled",
+			"allowed_fingerprints", len(cfg.API.TLS.AllowedFingerprints),
+		)
+	}
+
+	// Readiness checks of /readyz and the TCP health responder
+	checker := health.New(cfg.Health.CheckTimeout)
+	checker.Add("storage", health.StorageCheck(messageQueue))
+	if replStandby == nil {
+		checker.Add("processor", health.RunningCheck("queue processor", processor.Running))
+	}
+	if cfg.Health.MaxQueueBacklog > 0 {
+		checker.Add("queue_backlog", health.BacklogCheck(messageQueue, cfg.Health.MaxQueueBacklog))
+	}
+	if certs := servedCertificates(cfg, domainMgr, acmeManager); certs != nil {
+		checker.Add("certificate", health.CertificateCheck(certs, cfg.Health.CertMinValidity))
+	}
+	var healthResponder *health.Responder
+	if cfg.Health.ListenAddr != "" {
+		healthResponder = health.NewResponder(cfg.Health.ListenAddr, checker, logger.With("component", "health"))
+	}
+
 	// Create API server with full options
 	apiServer := api.NewServerWithOptions(api.ServerOptions{
-		Queue:           storage,
-		Config:          &cfg.API,
-		FullConfig:      cfg,
-		Logger:          logger.With("component", "api"),
-		DomainManager:   domainMgr,
-		RateLimiter:     rateLimiter,
-		SandboxStorage:  sandboxStorage,
-		TemplateStorage: templateStorage,
-		TLSConfig:       tlsConfig,
+		Queue:              messageQueue,
+		Config:             &cfg.API,
+		FullConfig:         cfg,
+		Logger:             logger.With("component", "api"),
+		DomainManager:      domainMgr,
+		DKIMKeysDir:        cfg.DKIM.KeysDir,
+		RateLimiter:        rateLimiter,
+		SandboxStorage:     sandboxStorage,
+		TemplateStorage:    templateStorage,
+		AutoReplyStorage:   autoReplyStorage,
+		ListStorage:        listStorage,
+		ShapingStorage:     shapingStorage,
+		HeaderRuleStorage:  headerRuleStorage,
+		HeaderProcessor:    headerProcessor,
+		AuditStorage:       auditStorage,
+		ArchiveStorage:     archiveStorage,
+		Purger:             privacy.NewPurger(messageQueue, sandboxStorage, archiveStorage),
+		IdempotencyStorage: idempotencyStorage,
+		APIKeyStorage:      apiKeyStorage,
+		SMTPUserStorage:    smtpUserStorage,
+		ReputationStorage:  reputationStorage,
+		StatsStorage:       statsStorage,
+		DNSCheckStorage:    dnsStorage,
+		DNSBLMonitor:       dnsblMonitor,
+		ACMEManager:        acmeManager,
+		SuppressionStorage: suppressionStorage,
+		FBLProcessor:       fblProcessor,
+		FBLStorage:         fblStorage,
+		Pauses:             pauses,
+		RetryScheduler:     retries,
+		ReplicationPrimary: replPrimary,
+		ReplicationStandby: replStandby,
+		BackupStorage:      storage,
+		ContentFilter:      contentFilter,
+		ContentPolicy:      contentPolicy,
+		AddressPolicy:      addrPolicy,
+		PolicyStorage:      policyStorage,
+		IPGuard:            ipGuard,
+		Health:             checker,
+		TLSConfig:          apiTLSConfig,
 	})
 
-	return &App{
+	// Serve the gRPC API next to the HTTP API
+	var grpcServer *api.GRPCServer
+	if cfg.API.GRPC.Enabled {
+		grpcServer = api.NewGRPCServer(apiServer, cfg.API.GRPC.ListenAddr, eventBroker)
+	}
+
+	// Create broker consumer if enabled
+	var brokerConsumer *consumer.Consumer
+	if cfg.Consumer.Enabled {
+		var source consumer.Source
+		switch cfg.Consumer.Type {
+		case config.ConsumerTypeNATS:
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			source, err = consumer.NewNATSSource(ctx, cfg.Consumer.NATS)
+			cancel()
+			if err != nil {
+				return nil, err
+			}
+		case config.ConsumerTypeKafka:
+			source = consumer.NewKafkaSource(cfg.Consumer.Kafka)
+		}
+		brokerConsumer = consumer.New(source, apiServer, messageQueue, logger)
+		brokerConsumer.SetIdempotencyStorage(idempotencyStorage)
+	}
+
+	a := &App{
 		config:           cfg,
-		queue:            storage,
+		queue:            messageQueue,
+		boltStorage:      storage,
 		smtpServer:       smtpServer,
 		smtpSubmission:   smtpSubmission,
 		smtpsServer:      smtpsServer,
 		apiServer:        apiServer,
+		grpcServer:       grpcServer,
 		processor:        processor,
 		cleaner:          cleaner,
+		archiveStorage:   archiveStorage,
+		archiveCleaner:   archiveCleaner,
 		logger:           logger,
 		tlsConfig:        tlsConfig,
 		sandboxStorage:   sandboxStorage,
 		sandboxSender:    sandboxSender,
+		sandboxCleaner:   sandboxCleaner,
 		acmeManager:      acmeManager,
 		domainManager:    domainMgr,
 		rateLimiter:      rateLimiter,
 		metricsServer:    metricsServer,
 		metricsCollector: metricsCollector,
-	}, nil
+		consumer:         brokerConsumer,
+		reputation:       reputationMonitor,
+		statsRecorder:    statsRecorder,
+		dnsMonitor:       dnsMonitor,
+		dnsblMonitor:     dnsblMonitor,
+		replPrimary:      replPrimary,
+		replStandby:      replStandby,
+		health:           checker,
+		healthResponder:  healthResponder,
+		smtpAuth:         smtpAuth,
+		secretsDigest:    cfg.SecretsDigest(),
+		headerProcessor:  headerProcessor,
+		contentPolicy:    contentPolicy,
+		addrPolicy:       addrPolicy,
+		pauses:           pauses,
+		connPool:         connPool,
+		concurrency:      concurrency,
+		retries:          retries,
+		eventLog:         eventLog,
+		stopTracing:      stopTracing,
+	}
+	apiServer.SetConfigReloader(a)
+
+	return a, nil
 }
 
 // Run starts all components and waits for shutdown
@@ -350,17 +928,69 @@ func (a *App) Run(ctx context.Context) error {
 	if a.smtpsServer != nil {
 		logAttrs = append(logAttrs, "smtps_addr", a.config.SMTP.SMTPSAddr)
 	}
+	if a.grpcServer != nil {
+		logAttrs = append(logAttrs, "grpc_addr", a.config.API.GRPC.ListenAddr)
+	}
 	a.logger.Info("starting sendry", logAttrs...)
 
 	// Create context that listens for signals
 	ctx, cancel := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 
-	// Start queue processor
-	a.processor.Start(ctx)
+	// Reload the config on SIGHUP
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	go a.reloadOnSignal(ctx, hup)
+
+	// Reload the config when a referenced secret is rotated
+	if interval := a.config.Secrets.RefreshInterval; interval > 0 && a.config.SecretsDigest() != "" {
+		go a.refreshSecrets(ctx, interval)
+	}
+
+	// Start queue processor, a standby delivers once it is promoted
+	if a.replStandby != nil {
+		a.replStandby.OnPromote(func() { a.processor.Start(ctx) })
+	} else {
+		a.processor.Start(ctx)
+	}
+
+	// Start queue replication to the standby
+	if a.replPrimary != nil {
+		a.replPrimary.Start(ctx)
+	}
 
 	// Start cleaner for automatic cleanup
 	a.cleaner.Start(ctx)
+	if a.archiveCleaner != nil {
+		a.archiveCleaner.Start(ctx)
+	}
+	a.sandboxCleaner.Start(ctx)
+
+	// Start broker consumer if enabled
+	if a.consumer != nil {
+		a.consumer.Start(ctx)
+	}
+
+	// Start reputation scoring if enabled
+	if a.reputation != nil {
+		a.reputation.Start(ctx)
+	}
+
+	// Start the stats history if enabled
+	if a.statsRecorder != nil {
+		a.statsRecorder.Start(ctx)
+	}
+
+	// Start DNS monitoring if enabled
+	if a.dnsMonitor != nil {
+		a.dnsMonitor.Start(ctx)
+	}
+
+	// Start DNSBL monitoring if enabled
+	if a.dnsblMonitor != nil {
+		a.dnsblMonitor.Start(ctx)
+	}
 
 	// Start metrics collector and server if enabled
 	if a.metricsCollector != nil {
@@ -375,7 +1005,16 @@ func (a *App) Run(ctx context.Context) error {
 	}
 
 	// Channel to collect errors
-	errCh := make(chan error, 4)
+	errCh := make(chan error, 6)
+
+	// Start the TCP health responder for load balancers
+	if a.healthResponder != nil {
+		go func() {
+			if err := a.healthResponder.ListenAndServe(); err != nil {
+				errCh <- fmt.Errorf("health responder: %w", err)
+			}
+		}()
+	}
 
 	// Start SMTP server
 	go func() {
@@ -407,6 +1046,15 @@ func (a *App) Run(ctx context.Context) error {
 		}
 	}()
 
+	// Start gRPC API server if enabled
+	if a.grpcServer != nil {
+		go func() {
+			if err := a.grpcServer.ListenAndServe(); err != nil {
+				errCh <- fmt.Errorf("grpc server: %w", err)
+			}
+		}()
+	}
+
 	// Handle ACME certificates if enabled
 	if a.acmeManager != nil {
 		if a.config.SMTP.TLS.ACME.OnDemand {
@@ -503,6 +1151,13 @@ func (a *App) Run(ctx context.Context) error {
 func (a *App) Shutdown(ctx context.Context) error {
 	a.logger.Info("shutting down")
 
+	// Report draining so load balancers stop sending new connections
+	a.health.SetDraining(true)
+	if d := a.config.Health.DrainDelay; d > 0 {
+		a.logger.Info("draining before shutdown", "delay", d)
+		time.Sleep(d)
+	}
+
 	// Create timeout context
 	shutdownCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
@@ -510,8 +1165,49 @@ func (a *App) Shutdown(ctx context.Context) error {
 	// Stop processor first (stop accepting new work)
 	a.processor.Stop()
 
+	// Close idle MX connections
+	if a.connPool != nil {
+		a.connPool.Close()
+	}
+
+	// Stop broker consumer, unacknowledged requests are delivered again
+	if a.consumer != nil {
+		if err := a.consumer.Stop(); err != nil {
+			a.logger.Error("consumer stop error", "error", err)
+		}
+	}
+
+	// Stop reputation scoring (persists recorded outcomes)
+	if a.reputation != nil {
+		a.reputation.Stop()
+	}
+
+	// Stop the stats history (persists recorded counts)
+	if a.statsRecorder != nil {
+		a.statsRecorder.Stop()
+	}
+
+	// Stop DNS monitoring
+	if a.dnsMonitor != nil {
+		a.dnsMonitor.Stop()
+	}
+
+	// Stop DNSBL monitoring
+	if a.dnsblMonitor != nil {
+		a.dnsblMonitor.Stop()
+	}
+
 	// Stop cleaner
 	a.cleaner.Stop()
+	if a.archiveCleaner != nil {
+		a.archiveCleaner.Stop()
+	}
+	a.sandboxCleaner.Stop()
+
+	// Stop queue replication after the last queue changes
+	if a.replPrimary != nil {
+		a.replPrimary.Stop()
+	}
 
 	// Shutdown servers
 	if err := a.smtpServer.Shutdown(shutdownCtx); err != nil {
@@ -532,6 +1228,12 @@ func (a *App) Shutdown(ctx context.Context) error {
 		a.logger.Error("api server shutdown error", "error", err)
 	}
 
+	if a.grpcServer != nil {
+		if err := a.grpcServer.Shutdown(shutdownCtx); err != nil {
+			a.logger.Error("grpc server shutdown error", "error", err)
+		}
+	}
+
 	// Shutdown ACME server if running
 	if a.acmeServer != nil {
 		if err := a.acmeServer.Shutdown(shutdownCtx); err != nil {
@@ -560,10 +1262,38 @@ func (a *App) Shutdown(ctx context.Context) error {
 		}
 	}
 
+	// Stop the health responder last, it reports draining until here
+	if a.healthResponder != nil {
+		a.healthResponder.Close()
+	}
+
 	// Close storage
 	if err := a.queue.Close(); err != nil {
 		a.logger.Error("storage close error", "error", err)
 	}
+	if a.boltStorage != nil && queue.Queue(a.boltStorage) != a.queue {
+		if err := a.boltStorage.Close(); err != nil {
+			a.logger.Error("storage close error", "error", err)
+		}
+	}
+
+	if a.archiveStorage != nil {
+		if err := a.archiveStorage.Close(); err != nil {
+			a.logger.Error("archive close error", "error", err)
+		}
+	}
+
+	if a.eventLog != nil {
+		if err := a.eventLog.Close(); err != nil {
+			a.logger.Error("delivery log close error", "error", err)
+		}
+	}
+
+	if a.stopTracing != nil {
+		if err := a.stopTracing(shutdownCtx); err != nil {
+			a.logger.Error("tracing shutdown error", "error", err)
+		}
+	}
 
 	a.logger.Info("shutdown complete")
 	return nil
@@ -586,6 +1316,9 @@ func setupLogger(cfg config.LoggingConfig) *slog.Logger {
 	opts := &slog.HandlerOptions{
 		Level: level,
 	}
+	if redactor := logRedactor(cfg); redactor != nil {
+		opts.ReplaceAttr = redactor.ReplaceAttr
+	}
 
 	if cfg.Format == "json" {
 		handler = slog.NewJSONHandler(os.Stdout, opts)
@@ -606,12 +1339,230 @@ func (a *queueStatsAdapter) Stats(ctx context.Context) (*metrics.QueueStats, err
 	if err != nil {
 		return nil, err
 	}
-	return &metrics.QueueStats{
+	result := &metrics.QueueStats{
 		Pending:   stats.Pending,
 		Sending:   stats.Sending,
 		Deferred:  stats.Deferred,
 		Delivered: stats.Delivered,
 		Failed:    stats.Failed,
 		Total:     stats.Total,
-	}, nil
+	}
+	if dlq, ok := a.queue.(queue.DLQManager); ok {
+		if dlqStats, err := dlq.DLQStats(ctx); err == nil {
+			result.DLQ = dlqStats.Total
+		}
+	}
+	return result, nil
+}
+
+// limitConfig converts configured limit values to rate limiter limits
+// rateLimitConfig converts the rate limit section of the config
+func rateLimitConfig(cfg *config.RateLimitConfig) *ratelimit.Config {
+	rlConfig := &ratelimit.Config{
+		Algorithm:              ratelimit.Algorithm(cfg.Algorithm),
+		Global:                 limitConfig(cfg.Global),
+		DefaultDomain:          limitConfig(cfg.DefaultDomain),
+		DefaultSender:          limitConfig(cfg.DefaultSender),
+		DefaultIP:              limitConfig(cfg.DefaultIP),
+		DefaultAPIKey:          limitConfig(cfg.DefaultAPIKey),
+		DefaultRecipientDomain: limitConfig(cfg.DefaultRecipientDomain),
+	}
+	if cfg.RecipientDomains != nil {
+		rlConfig.RecipientDomains = make(map[string]*ratelimit.LimitConfig)
+		for domain, limit := range cfg.RecipientDomains {
+			rlConfig.RecipientDomains[domain] = limitConfig(limit)
+		}
+	}
+	if a := cfg.Adaptive; a != nil {
+		rlConfig.Adaptive = &ratelimit.AdaptiveConfig{
+			Enabled:   a.Enabled,
+			Threshold: a.Threshold,
+			Decrease:  a.Decrease,
+			MinFactor: a.MinFactor,
+			Recovery:  a.Recovery,
+			BaseRate:  a.BaseRate,
+		}
+	}
+	return rlConfig
+}
+
+// deliveryLogConfig converts the delivery log section of the config
+func deliveryLogConfig(cfg *config.Config) eventlog.Config {
+	dl := cfg.Logging.DeliveryLog
+	elCfg := eventlog.Config{
+		File:       dl.File,
+		MaxSize:    int64(dl.MaxSize) << 20,
+		MaxBackups: dl.MaxBackups,
+		Events:     dl.Events,
+		Hostname:   cfg.Server.Hostname,
+		Redactor:   logRedactor(cfg.Logging),
+	}
+	if dl.Syslog.Enabled {
+		elCfg.Syslog = &eventlog.SyslogConfig{
+			Network:  dl.Syslog.Network,
+			Address:  dl.Syslog.Address,
+			Tag:      dl.Syslog.Tag,
+			Facility: dl.Syslog.Facility,
+		}
+	}
+	return elCfg
+}
+
+// logRedactor returns the redactor of personal data in logs, nil when
+// nothing is redacted
+func logRedactor(cfg config.LoggingConfig) *privacy.Redactor {
+	return privacy.NewRedactor(privacy.RedactConfig{
+		Addresses: cfg.Redact.Addresses,
+		Subjects:  cfg.Redact.Subjects,
+		Mode:      cfg.Redact.Mode,
+		Salt:      cfg.Redact.Salt,
+	})
+}
+
+// servedCertificates returns the certificates of the health check: the
+// ACME or manual default and those of domains. ACME certificates are read
+// from the cache, so a check never requests one. The domains are those of
+// domainMgr at the time of the check. It returns nil without TLS at startup.
+func servedCertificates(cfg *config.Config, domainMgr *domain.Manager, acmeManager *sendryTLS.ACMEManager) func() ([]health.Certificate, error) {
+	manual := cfg.SMTP.TLS.CertFile != "" && cfg.SMTP.TLS.KeyFile != ""
+	if acmeManager == nil && !manual && len(domainMgr.DomainCertificates()) == 0 {
+		return nil
+	}
+
+	return func() ([]health.Certificate, error) {
+		var certs []health.Certificate
+		if acmeManager != nil {
+			cached, err := acmeManager.GetCachedCertificates()
+			if err != nil {
+				return nil, err
+			}
+			for _, c := range cached {
+				certs = append(certs, health.Certificate{Name: c.Domain, NotAfter: c.NotAfter})
+			}
+		} else if manual {
+			info, err := sendryTLS.GetCertificateInfo(cfg.SMTP.TLS.CertFile)
+			if err != nil {
+				return nil, err
+			}
+			certs = append(certs, health.Certificate{Name: info.Subject, NotAfter: info.NotAfter})
+		}
+		for _, name := range domainMgr.DomainCertificates() {
+			dc := domainMgr.GetDomainConfig(name)
+			if dc == nil || dc.TLS == nil {
+				continue // removed since the list was taken
+			}
+			info, err := sendryTLS.GetCertificateInfo(dc.TLS.CertFile)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			certs = append(certs, health.Certificate{Name: name, NotAfter: info.NotAfter})
+		}
+		return certs, nil
+	}
+}
+
+// defaultRetryPolicy returns the retry policy of deliveries without a
+// configured one: exponential backoff from queue.retry_interval
+func defaultRetryPolicy(cfg *config.QueueConfig) retry.Policy {
+	return retry.Policy{Interval: cfg.RetryInterval, MaxRetries: cfg.MaxRetries}
+}
+
+func limitConfig(v *config.LimitValues) *ratelimit.LimitConfig {
+	if v == nil {
+		return nil
+	}
+	return &ratelimit.LimitConfig{
+		MessagesPerMinute: v.MessagesPerMinute,
+		MessagesPerHour:   v.MessagesPerHour,
+		MessagesPerDay:    v.MessagesPerDay,
+		Algorithm:         ratelimit.Algorithm(v.Algorithm),
+	}
+}
+
+// openShardedQueue opens the sharded queue if storage.shards is above one and
+// moves messages left in the single-file queue into it. With sharding turned
+// off, messages in leftover shard files are moved back and nil is returned.
+func openShardedQueue(cfg config.StorageConfig, single *queue.BoltStorage, logger *slog.Logger) (*queue.ShardedStorage, error) {
+	if cfg.Shards <= 1 {
+		files, err := queue.ShardFiles(cfg.ShardDir)
+		if err != nil || len(files) == 0 {
+			return nil, err
+		}
+
+		sharded, err := queue.NewShardedStorage(cfg.ShardDir, 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open queue shards: %w", err)
+		}
+		result, err := queue.Move(context.Background(), sharded, single)
+		sharded.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to move messages out of queue shards: %w", err)
+		}
+		for _, f := range files {
+			if err := os.Remove(f); err != nil {
+				return nil, fmt.Errorf("failed to remove queue shard: %w", err)
+			}
+		}
+		logger.Info("queue sharding disabled, messages moved back", "messages", result.Messages, "dlq", result.DLQ)
+		return nil, nil
+	}
+
+	sharded, err := queue.NewShardedStorage(cfg.ShardDir, cfg.Shards)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sharded queue storage: %w", err)
+	}
+	result, err := queue.Move(context.Background(), single, sharded)
+	if err != nil {
+		sharded.Close()
+		return nil, fmt.Errorf("failed to move messages into queue shards: %w", err)
+	}
+	if result.Messages > 0 {
+		logger.Info("moved messages into queue shards", "messages", result.Messages, "dlq", result.DLQ)
+	}
+	logger.Info("using sharded queue storage", "shards", sharded.Shards(), "dir", cfg.ShardDir)
+	return sharded, nil
+}
+
+// openReplication wraps the queue as the primary or standby of a replicated
+// pair
+func openReplication(cfg *config.Config, q queue.Storage, logger *slog.Logger) (*replication.Primary, *replication.Standby, error) {
+	r := cfg.Replication
+	logger = logger.With("component", "replication")
+
+	if r.Role == config.ReplicationStandby {
+		standby, err := replication.NewStandby(q, replication.StandbyConfig{
+			StatePath: cfg.Storage.Path + ".promoted",
+			Interval:  r.Interval,
+		}, logger)
+		if err != nil {
+			return nil, nil, err
+		}
+		if standby.Promoted() {
+			logger.Warn("standby was promoted, delivering the queue and ignoring the primary")
+		} else {
+			logger.Info("queue replication standby, waiting for the primary")
+		}
+		return nil, standby, nil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if r.CAFile != "" {
+		data, err := os.ReadFile(r.CAFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read replication CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, nil, fmt.Errorf("no certificates found in replication CA file %s", r.CAFile)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+
+	primary := replication.NewPrimary(q, replication.PrimaryConfig{
+		PeerURL:  r.PeerURL,
+		APIKey:   r.APIKey,
+		Client:   &http.Client{Transport: transport, Timeout: r.Timeout},
+		Interval: r.Interval,
+	}, logger)
+	return primary, nil, nil
 }
@@ -0,0 +1,132 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/foxzi/sendry/internal/config"
+)
+
+// Reload re-reads the config file and the dynamic domains file and applies
+// the settings that can change at runtime: rate limits, recipient domain
+// concurrency limits, retry policies, domains (with their DKIM keys, inbound rules,
+// content policies and pauses), header rules, the content policy, the
+// sandbox overrides of API keys and senders, the SMTP users and the static
+// API key, so rotated secrets take effect. The new config is validated
+// and its DKIM keys are loaded before anything is replaced, so an invalid
+// config leaves the running one in place. Other settings take effect after
+// a restart.
+func (a *App) Reload(ctx context.Context) error {
+	a.reloadMu.Lock()
+	defer a.reloadMu.Unlock()
+
+	path := a.config.Path()
+	if path == "" {
+		return fmt.Errorf("config was not loaded from a file")
+	}
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	cfg.SetDomainsFile(a.config.DomainsFile())
+	if err := cfg.LoadDynamicDomains(); err != nil {
+		return fmt.Errorf("failed to load dynamic domains: %w", err)
+	}
+
+	// Domains go first, loading their DKIM keys is the only step that can fail
+	if err := a.domainManager.Reload(cfg); err != nil {
+		return fmt.Errorf("failed to reload domains: %w", err)
+	}
+
+	allowedDomains := a.domainManager.ListDomains()
+	a.pauses.SetConfigured(a.domainManager.PausedDomains())
+	a.smtpServer.SetAllowedDomains(allowedDomains)
+	a.smtpSubmission.SetAllowedDomains(allowedDomains)
+	if a.smtpsServer != nil {
+		a.smtpsServer.SetAllowedDomains(allowedDomains)
+	}
+
+	// The limiter is wired into the SMTP servers and the API at startup,
+	// so only its limits can change. Each component below keeps its own copy
+	// of its settings behind its own lock: the shared config is read
+	// concurrently, so its reloadable sections keep their startup values.
+	rateLimit := cfg.RateLimit
+	if rateLimit.Enabled != a.config.RateLimit.Enabled {
+		a.logger.Warn("enabling or disabling rate limiting takes effect after a restart")
+		rateLimit.Enabled = a.config.RateLimit.Enabled
+	}
+	if a.rateLimiter != nil {
+		a.rateLimiter.SetConfig(rateLimitConfig(&rateLimit))
+	}
+	a.apiServer.SetRateLimits(rateLimit)
+
+	a.concurrency.SetLimits(cfg.Queue.MaxConcurrentConnections, cfg.Queue.DomainConcurrency)
+	a.retries.SetConfig(defaultRetryPolicy(&cfg.Queue), cfg.Queue.Retry)
+	a.retries.SetGreylistRetry(cfg.Queue.GreylistRetry)
+	a.headerProcessor.SetConfig(cfg.HeaderRules)
+	a.contentPolicy.SetPolicy(cfg.ContentPolicy)
+	a.addrPolicy.SetPolicy(cfg.AddressPolicy)
+	a.sandboxSender.SetOverrides(cfg.Sandbox.Overrides)
+
+	// SMTP users and the static API key, e.g. after a password was rotated
+	a.smtpAuth.SetConfig(&cfg.SMTP.Auth)
+	a.apiServer.SetAPIKey(cfg.API.APIKey)
+	a.secretsDigest = cfg.SecretsDigest()
+
+	a.logger.Info("config reloaded",
+		"path", path,
+		"domains", len(allowedDomains),
+		"rate_limit", rateLimit.Enabled,
+		"header_rules", cfg.HeaderRules.HasRules(),
+	)
+	return nil
+}
+
+// reloadOnSignal reloads the config on every signal received on hup until
+// ctx is done
+func (a *App) reloadOnSignal(ctx context.Context, hup <-chan os.Signal) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			a.logger.Info("reload signal received")
+			if err := a.Reload(ctx); err != nil {
+				a.logger.Error("config reload failed", "error", err)
+			}
+		}
+	}
+}
+
+// refreshSecrets loads the config every interval and reloads it when one of
+// its referenced secrets changed, until ctx is done
+func (a *App) refreshSecrets(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			cfg, err := config.Load(a.config.Path())
+			if err != nil {
+				a.logger.Error("failed to refresh secrets", "error", err)
+				continue
+			}
+			a.reloadMu.Lock()
+			changed := cfg.SecretsDigest() != a.secretsDigest
+			a.reloadMu.Unlock()
+			if !changed {
+				continue
+			}
+			a.logger.Info("secrets changed, reloading config")
+			if err := a.Reload(ctx); err != nil {
+				a.logger.Error("config reload failed", "error", err)
+			}
+		}
+	}
+}
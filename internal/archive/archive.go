@@ -0,0 +1,78 @@
+// Package archive keeps a searchable copy of delivered messages, separate
+// from the queue and its retention.
+package archive
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/foxzi/sendry/internal/message"
+)
+
+// maxBodyText limits how much message text is indexed for search
+const maxBodyText = 256 * 1024
+
+// Message is an archived message
+type Message struct {
+	ID          int64     `json:"id"`
+	QueueID     string    `json:"queue_id"`
+	MessageID   string    `json:"message_id,omitempty"`
+	From        string    `json:"from"`
+	To          []string  `json:"to"`
+	Subject     string    `json:"subject"`
+	Size        int       `json:"size"`
+	DeliveredAt time.Time `json:"delivered_at"`
+	Body        string    `json:"body,omitempty"` // Message text, only set by Get
+}
+
+// Query selects archived messages. All set fields must match.
+type Query struct {
+	Text      string    // Full-text search over sender, recipients, subject and body
+	Sender    string    // Substring of the sender address
+	Recipient string    // Substring of a recipient address
+	Subject   string    // Substring of the subject
+	Since     time.Time // Delivered at or after
+	Until     time.Time // Delivered before
+	Limit     int
+	Offset    int
+}
+
+// parseMessage returns the subject, Message-ID and searchable text of a
+// raw message. Unparsable messages are indexed as they are.
+func parseMessage(data []byte) (subject, messageID, text string) {
+	m, err := message.Parse(data)
+	if err != nil {
+		return "", "", truncate(string(data))
+	}
+
+	subject = m.Header.Decoded("Subject")
+	messageID = strings.TrimSpace(m.Header.Get("Message-Id"))
+
+	text = m.TextBody()
+	if strings.TrimSpace(text) == "" {
+		text = htmlText(m.HTMLBody())
+	}
+	return subject, messageID, truncate(text)
+}
+
+var (
+	htmlSkipRE = regexp.MustCompile(`(?is)<(script|style|head)\b.*?</(script|style|head)>`)
+	htmlTagRE  = regexp.MustCompile(`(?s)<[^>]*>`)
+	spaceRE    = regexp.MustCompile(`[ \t\r\n]+`)
+)
+
+// htmlText returns the text of an HTML body
+func htmlText(s string) string {
+	s = htmlSkipRE.ReplaceAllString(s, " ")
+	s = htmlTagRE.ReplaceAllString(s, " ")
+	return strings.TrimSpace(spaceRE.ReplaceAllString(html.UnescapeString(s), " "))
+}
+
+func truncate(s string) string {
+	if len(s) > maxBodyText {
+		return s[:maxBodyText]
+	}
+	return s
+}
@@ -0,0 +1,102 @@
+package archive
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// CleanerConfig contains archive retention settings
+type CleanerConfig struct {
+	MaxAge   time.Duration
+	MaxCount int
+	Interval time.Duration
+
+	// Shorter max ages of the messages of sender domains
+	DomainMaxAge map[string]time.Duration
+}
+
+// Cleaner removes archived messages beyond the retention limits
+type Cleaner struct {
+	storage *Storage
+	cfg     CleanerConfig
+	logger  *slog.Logger
+	wg      sync.WaitGroup
+	done    chan struct{}
+}
+
+// NewCleaner creates a new archive cleaner
+func NewCleaner(storage *Storage, cfg CleanerConfig, logger *slog.Logger) *Cleaner {
+	return &Cleaner{
+		storage: storage,
+		cfg:     cfg,
+		logger:  logger,
+		done:    make(chan struct{}),
+	}
+}
+
+// Start starts the cleanup goroutine
+func (c *Cleaner) Start(ctx context.Context) {
+	if (c.cfg.MaxAge <= 0 && c.cfg.MaxCount <= 0 && len(c.cfg.DomainMaxAge) == 0) || c.cfg.Interval <= 0 {
+		return
+	}
+
+	c.wg.Add(1)
+	go c.loop(ctx)
+
+	c.logger.Info("archive cleaner started",
+		"max_age", c.cfg.MaxAge,
+		"max_count", c.cfg.MaxCount,
+		"domains", len(c.cfg.DomainMaxAge),
+		"interval", c.cfg.Interval,
+	)
+}
+
+// Stop stops the cleaner and waits for the goroutine to finish
+func (c *Cleaner) Stop() {
+	close(c.done)
+	c.wg.Wait()
+}
+
+func (c *Cleaner) loop(ctx context.Context) {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(c.cfg.Interval)
+	defer ticker.Stop()
+
+	// Run cleanup immediately on start
+	c.run(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.done:
+			return
+		case <-ticker.C:
+			c.run(ctx)
+		}
+	}
+}
+
+func (c *Cleaner) run(ctx context.Context) {
+	deleted, err := c.storage.Cleanup(ctx, c.cfg.MaxAge, c.cfg.MaxCount)
+	if err != nil {
+		c.logger.Error("failed to cleanup archive", "error", err)
+		return
+	}
+
+	for domain, maxAge := range c.cfg.DomainMaxAge {
+		n, err := c.storage.CleanupDomain(ctx, domain, maxAge)
+		if err != nil {
+			c.logger.Error("failed to cleanup archive", "domain", domain, "error", err)
+			return
+		}
+		deleted += n
+	}
+
+	if deleted > 0 {
+		c.logger.Info("cleaned up archived messages", "deleted", deleted)
+	}
+}
@@ -0,0 +1,21 @@
+//go:build sqlite_fts5
+
+package archive
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// Release builds pass the sqlite_fts5 tag, the archive must use FTS5 there
+func TestStorageUsesFTS5(t *testing.T) {
+	s, err := NewStorage(filepath.Join(t.TempDir(), "archive.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if s.FTS() != "fts5" {
+		t.Errorf("FTS() = %s, want fts5 with the sqlite_fts5 build tag", s.FTS())
+	}
+}
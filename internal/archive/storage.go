@@ -0,0 +1,387 @@
+package archive
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/foxzi/sendry/internal/queue"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS messages (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	queue_id TEXT NOT NULL,
+	message_id TEXT NOT NULL DEFAULT '',
+	sender TEXT NOT NULL,
+	recipients TEXT NOT NULL,
+	subject TEXT NOT NULL DEFAULT '',
+	size INTEGER NOT NULL,
+	delivered_at INTEGER NOT NULL,
+	raw BLOB NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_messages_delivered ON messages(delivered_at);
+CREATE INDEX IF NOT EXISTS idx_messages_queue_id ON messages(queue_id);
+`
+
+// ftsSchema is the full-text index of archived messages. FTS5 needs
+// go-sqlite3 built with the sqlite_fts5 tag, FTS4 is always available.
+const ftsSchema = `CREATE VIRTUAL TABLE IF NOT EXISTS messages_fts USING %s(sender, recipients, subject, body)`
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 1000
+)
+
+// Storage is the SQLite message archive
+type Storage struct {
+	db  *sql.DB
+	fts string
+}
+
+// NewStorage opens or creates the archive database at path
+func NewStorage(path string) (*Storage, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	db, err := sql.Open("sqlite3", path+"?_journal_mode=WAL&_busy_timeout=5000")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive database: %w", err)
+	}
+
+	s := &Storage{db: db}
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Storage) migrate() error {
+	if _, err := s.db.Exec(schema); err != nil {
+		return fmt.Errorf("failed to create archive schema: %w", err)
+	}
+
+	var existing string
+	err := s.db.QueryRow(`SELECT sql FROM sqlite_master WHERE name = 'messages_fts'`).Scan(&existing)
+	switch {
+	case err == sql.ErrNoRows:
+		s.fts = "fts5"
+		if _, err := s.db.Exec(fmt.Sprintf(ftsSchema, s.fts)); err != nil {
+			if !strings.Contains(err.Error(), "no such module") {
+				return fmt.Errorf("failed to create archive index: %w", err)
+			}
+			s.fts = "fts4"
+			if _, err := s.db.Exec(fmt.Sprintf(ftsSchema, s.fts)); err != nil {
+				return fmt.Errorf("failed to create archive index: %w", err)
+			}
+		}
+	case err != nil:
+		return fmt.Errorf("failed to read archive schema: %w", err)
+	default:
+		s.fts = "fts4"
+		if strings.Contains(strings.ToLower(existing), "fts5") {
+			s.fts = "fts5"
+		}
+		if _, err := s.db.Exec(`SELECT rowid FROM messages_fts LIMIT 1`); err != nil {
+			return fmt.Errorf("archive index %s is not supported by this build: %w", s.fts, err)
+		}
+	}
+	return nil
+}
+
+// FTS returns the full-text search module of the index (fts5 or fts4)
+func (s *Storage) FTS() string {
+	return s.fts
+}
+
+// Close closes the database
+func (s *Storage) Close() error {
+	return s.db.Close()
+}
+
+// Archive stores a copy of a delivered queue message for its delivered
+// recipients. It implements queue.Archiver.
+func (s *Storage) Archive(ctx context.Context, msg *queue.Message) error {
+	to := msg.DeliveredRecipients()
+	if len(to) == 0 {
+		to = msg.To
+	}
+	subject, messageID, text := parseMessage(msg.Data)
+
+	deliveredAt := msg.UpdatedAt
+	if deliveredAt.IsZero() {
+		deliveredAt = time.Now()
+	}
+
+	return s.Add(ctx, &Message{
+		QueueID:     msg.ID,
+		MessageID:   messageID,
+		From:        msg.From,
+		To:          to,
+		Subject:     subject,
+		Size:        len(msg.Data),
+		DeliveredAt: deliveredAt,
+	}, msg.Data, text)
+}
+
+// Add stores a message with its raw data and searchable text
+func (s *Storage) Add(ctx context.Context, m *Message, raw []byte, text string) error {
+	recipients := strings.ToLower(strings.Join(m.To, " "))
+	sender := strings.ToLower(m.From)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO messages (queue_id, message_id, sender, recipients, subject, size, delivered_at, raw)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		m.QueueID, m.MessageID, sender, recipients, m.Subject, m.Size, m.DeliveredAt.UnixNano(), raw,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to archive message: %w", err)
+	}
+	if m.ID, err = res.LastInsertId(); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO messages_fts (rowid, sender, recipients, subject, body) VALUES (?, ?, ?, ?, ?)`,
+		m.ID, sender, recipients, m.Subject, text,
+	); err != nil {
+		return fmt.Errorf("failed to index message: %w", err)
+	}
+	return tx.Commit()
+}
+
+// Search returns the archived messages matching q, newest first, and the
+// total number of matches
+func (s *Storage) Search(ctx context.Context, q Query) ([]*Message, int, error) {
+	where, args := q.where()
+
+	var total int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM messages"+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count archived messages: %w", err)
+	}
+
+	limit := q.Limit
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+	if limit > maxListLimit {
+		limit = maxListLimit
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, queue_id, message_id, sender, recipients, subject, size, delivered_at
+		FROM messages`+where+`
+		ORDER BY delivered_at DESC, id DESC LIMIT ? OFFSET ?`,
+		append(args, limit, max(q.Offset, 0))...,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search archive: %w", err)
+	}
+	defer rows.Close()
+
+	messages := []*Message{}
+	for rows.Next() {
+		m, err := scanMessage(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		messages = append(messages, m)
+	}
+	return messages, total, rows.Err()
+}
+
+// Get returns an archived message with its text, or nil if not found
+func (s *Storage) Get(ctx context.Context, id int64) (*Message, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT id, queue_id, message_id, sender, recipients, subject, size, delivered_at
+		FROM messages WHERE id = ?`, id)
+	m, err := scanMessage(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.QueryRowContext(ctx, `SELECT body FROM messages_fts WHERE rowid = ?`, id).Scan(&m.Body)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Raw returns the raw data of an archived message, or nil if not found
+func (s *Storage) Raw(ctx context.Context, id int64) ([]byte, error) {
+	var raw []byte
+	err := s.db.QueryRowContext(ctx, `SELECT raw FROM messages WHERE id = ?`, id).Scan(&raw)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return raw, err
+}
+
+// Cleanup deletes messages delivered more than maxAge ago and the oldest
+// messages above maxCount. Zero values disable the limit.
+func (s *Storage) Cleanup(ctx context.Context, maxAge time.Duration, maxCount int) (int, error) {
+	var conds []string
+	var args []any
+	if maxAge > 0 {
+		conds = append(conds, "delivered_at < ?")
+		args = append(args, time.Now().Add(-maxAge).UnixNano())
+	}
+	if maxCount > 0 {
+		conds = append(conds, "id NOT IN (SELECT id FROM messages ORDER BY delivered_at DESC, id DESC LIMIT ?)")
+		args = append(args, maxCount)
+	}
+	if len(conds) == 0 {
+		return 0, nil
+	}
+	return s.delete(ctx, " WHERE "+strings.Join(conds, " OR "), args...)
+}
+
+// CleanupDomain deletes the messages of a sender domain delivered more
+// than maxAge ago
+func (s *Storage) CleanupDomain(ctx context.Context, domain string, maxAge time.Duration) (int, error) {
+	if maxAge <= 0 {
+		return 0, nil
+	}
+	where := ` WHERE sender LIKE ? ESCAPE '\' AND delivered_at < ?`
+	return s.delete(ctx, where, "%@"+likeEscape(strings.ToLower(domain)), time.Now().Add(-maxAge).UnixNano())
+}
+
+// DeleteAddress deletes the messages sent from or to an address
+func (s *Storage) DeleteAddress(ctx context.Context, address string) (int, error) {
+	address = strings.ToLower(address)
+	rows, err := s.db.QueryContext(ctx, `SELECT id, sender, recipients FROM messages
+		WHERE sender = ? OR recipients LIKE ? ESCAPE '\'`, address, likePattern(address))
+	if err != nil {
+		return 0, fmt.Errorf("failed to search archive: %w", err)
+	}
+	var ids []any
+	for rows.Next() {
+		var id int64
+		var sender, recipients string
+		if err := rows.Scan(&id, &sender, &recipients); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		// LIKE matches substrings, keep whole addresses only
+		if sender == address || slices.Contains(strings.Fields(recipients), address) {
+			ids = append(ids, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	return s.delete(ctx, " WHERE id IN (?"+strings.Repeat(", ?", len(ids)-1)+")", ids...)
+}
+
+// delete deletes the messages matching where and their index entries
+func (s *Storage) delete(ctx context.Context, where string, args ...any) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM messages_fts WHERE rowid IN (SELECT id FROM messages"+where+")", args...); err != nil {
+		return 0, fmt.Errorf("failed to delete from archive index: %w", err)
+	}
+	res, err := tx.ExecContext(ctx, "DELETE FROM messages"+where, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete from archive: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return int(n), tx.Commit()
+}
+
+// where builds the WHERE clause of a query
+func (q Query) where() (string, []any) {
+	var conds []string
+	var args []any
+
+	if match := matchExpr(q.Text); match != "" {
+		conds = append(conds, "id IN (SELECT rowid FROM messages_fts WHERE messages_fts MATCH ?)")
+		args = append(args, match)
+	}
+	if q.Sender != "" {
+		conds = append(conds, `sender LIKE ? ESCAPE '\'`)
+		args = append(args, likePattern(q.Sender))
+	}
+	if q.Recipient != "" {
+		conds = append(conds, `recipients LIKE ? ESCAPE '\'`)
+		args = append(args, likePattern(q.Recipient))
+	}
+	if q.Subject != "" {
+		conds = append(conds, `subject LIKE ? ESCAPE '\'`)
+		args = append(args, likePattern(q.Subject))
+	}
+	if !q.Since.IsZero() {
+		conds = append(conds, "delivered_at >= ?")
+		args = append(args, q.Since.UnixNano())
+	}
+	if !q.Until.IsZero() {
+		conds = append(conds, "delivered_at < ?")
+		args = append(args, q.Until.UnixNano())
+	}
+
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// matchExpr turns free text into a full-text query matching all words.
+// Words are quoted, so query operators in user input are taken literally.
+func matchExpr(text string) string {
+	var terms []string
+	for _, word := range strings.Fields(text) {
+		terms = append(terms, `"`+strings.ReplaceAll(word, `"`, `""`)+`"`)
+	}
+	return strings.Join(terms, " ")
+}
+
+// likePattern returns a LIKE pattern matching s as a substring
+func likePattern(s string) string {
+	return "%" + likeEscape(s) + "%"
+}
+
+// likeEscape escapes the LIKE wildcards of s
+func likeEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanMessage(row scanner) (*Message, error) {
+	var m Message
+	var recipients string
+	var deliveredAt int64
+	if err := row.Scan(&m.ID, &m.QueueID, &m.MessageID, &m.From, &recipients, &m.Subject, &m.Size, &deliveredAt); err != nil {
+		return nil, err
+	}
+	m.To = strings.Fields(recipients)
+	m.DeliveredAt = time.Unix(0, deliveredAt)
+	return &m, nil
+}
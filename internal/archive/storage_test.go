@@ -0,0 +1,285 @@
+package archive
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/foxzi/sendry/internal/queue"
+)
+
+func newTestStorage(t *testing.T) *Storage {
+	t.Helper()
+	s, err := NewStorage(filepath.Join(t.TempDir(), "archive.db"))
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func archiveMessage(t *testing.T, s *Storage, id, from string, to []string, data string, deliveredAt time.Time) {
+	t.Helper()
+	msg := &queue.Message{
+		ID:        id,
+		From:      from,
+		To:        to,
+		Data:      []byte(data),
+		Status:    queue.StatusDelivered,
+		UpdatedAt: deliveredAt,
+	}
+	if err := s.Archive(context.Background(), msg); err != nil {
+		t.Fatalf("Archive() error = %v", err)
+	}
+}
+
+const multipartData = "From: billing@example.com\r\n" +
+	"Subject: =?UTF-8?Q?Invoice_f=C3=BCr_March?=\r\n" +
+	"Message-ID: <inv-1@example.com>\r\n" +
+	"Content-Type: multipart/mixed; boundary=b1\r\n\r\n" +
+	"--b1\r\n" +
+	"Content-Type: multipart/alternative; boundary=b2\r\n\r\n" +
+	"--b2\r\n" +
+	"Content-Type: text/plain; charset=utf-8\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n\r\n" +
+	"Your invoice total is 42 EUR, due =\r\nsoon.\r\n" +
+	"--b2\r\n" +
+	"Content-Type: text/html\r\n\r\n" +
+	"<p>Your invoice</p>\r\n" +
+	"--b2--\r\n" +
+	"--b1\r\n" +
+	"Content-Type: text/plain\r\n" +
+	"Content-Disposition: attachment; filename=secret.txt\r\n\r\n" +
+	"attachmentword\r\n" +
+	"--b1--\r\n"
+
+func TestParseMessage(t *testing.T) {
+	t.Run("multipart", func(t *testing.T) {
+		subject, messageID, text := parseMessage([]byte(multipartData))
+		if subject != "Invoice für March" {
+			t.Errorf("subject = %q", subject)
+		}
+		if messageID != "<inv-1@example.com>" {
+			t.Errorf("message id = %q", messageID)
+		}
+		if !strings.Contains(text, "due soon") {
+			t.Errorf("text = %q, want decoded plain part", text)
+		}
+		if strings.Contains(text, "attachmentword") || strings.Contains(text, "<p>") {
+			t.Errorf("text = %q, want no attachment or HTML", text)
+		}
+	})
+
+	t.Run("html only", func(t *testing.T) {
+		data := "Subject: Hi\r\nContent-Type: text/html\r\nContent-Transfer-Encoding: base64\r\n\r\n" +
+			"PGh0bWw+PHN0eWxlPnB7fTwvc3R5bGU+PGJvZHk+SGVsbG8gJmFtcDsg\r\nd2VsY29tZTwvYm9keT48L2h0bWw+\r\n"
+		_, _, text := parseMessage([]byte(data))
+		if text != "Hello & welcome" {
+			t.Errorf("text = %q, want %q", text, "Hello & welcome")
+		}
+	})
+}
+
+func TestStorageSearch(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	day := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	archiveMessage(t, s, "q1", "billing@example.com", []string{"alice@example.org"}, multipartData, day)
+	archiveMessage(t, s, "q2", "news@example.com", []string{"Bob@Example.net", "carol@example.org"},
+		"Subject: Weekly news\r\n\r\nRelease notes and the invoice reminder", day.Add(24*time.Hour))
+	archiveMessage(t, s, "q3", "support@example.com", []string{"alice@example.org"},
+		"Subject: Ticket 100% resolved\r\n\r\nClosed", day.Add(48*time.Hour))
+
+	tests := []struct {
+		name string
+		q    Query
+		want []string
+	}{
+		{"all newest first", Query{}, []string{"q3", "q2", "q1"}},
+		{"body text", Query{Text: "invoice"}, []string{"q2", "q1"}},
+		{"all terms", Query{Text: "invoice reminder"}, []string{"q2"}},
+		{"decoded text", Query{Text: "due soon"}, []string{"q1"}},
+		{"attachment not indexed", Query{Text: "attachmentword"}, nil},
+		{"query syntax is literal", Query{Text: `"invoice OR" NEAR(`}, nil},
+		{"sender", Query{Sender: "news@"}, []string{"q2"}},
+		{"recipient case insensitive", Query{Recipient: "bob@example"}, []string{"q2"}},
+		{"subject like escaped", Query{Subject: "100%"}, []string{"q3"}},
+		{"since", Query{Since: day.Add(time.Hour)}, []string{"q3", "q2"}},
+		{"until", Query{Until: day.Add(25 * time.Hour)}, []string{"q2", "q1"}},
+		{"combined", Query{Text: "invoice", Recipient: "alice"}, []string{"q1"}},
+		{"offset", Query{Limit: 1, Offset: 1}, []string{"q2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages, total, err := s.Search(ctx, tt.q)
+			if err != nil {
+				t.Fatalf("Search() error = %v", err)
+			}
+			var got []string
+			for _, m := range messages {
+				got = append(got, m.QueueID)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Search() = %v, want %v", got, tt.want)
+			}
+			if tt.q.Limit == 0 && total != len(tt.want) {
+				t.Errorf("total = %d, want %d", total, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestStorageGet(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	archiveMessage(t, s, "q1", "Billing@Example.com", []string{"alice@example.org"}, multipartData, time.Now())
+
+	messages, _, err := s.Search(ctx, Query{})
+	if err != nil || len(messages) != 1 {
+		t.Fatalf("Search() = %v, %v", messages, err)
+	}
+
+	m, err := s.Get(ctx, messages[0].ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if m.From != "billing@example.com" || m.Subject != "Invoice für March" || m.Size != len(multipartData) {
+		t.Errorf("Get() = %+v", m)
+	}
+	if !strings.Contains(m.Body, "invoice total") {
+		t.Errorf("Body = %q", m.Body)
+	}
+
+	raw, err := s.Raw(ctx, m.ID)
+	if err != nil || string(raw) != multipartData {
+		t.Errorf("Raw() = %q, %v", raw, err)
+	}
+
+	if m, err := s.Get(ctx, 999); m != nil || err != nil {
+		t.Errorf("Get(missing) = %v, %v", m, err)
+	}
+	if raw, err := s.Raw(ctx, 999); raw != nil || err != nil {
+		t.Errorf("Raw(missing) = %v, %v", raw, err)
+	}
+}
+
+func TestStorageArchiveDeliveredRecipients(t *testing.T) {
+	s := newTestStorage(t)
+	msg := &queue.Message{
+		ID:   "partial",
+		From: "sender@example.com",
+		To:   []string{"ok@example.org", "bounced@example.org"},
+		Data: []byte("Subject: Hi\r\n\r\nHello"),
+	}
+	msg.SetResult("ok@example.org", queue.RecipientResult{Status: queue.StatusDelivered})
+	msg.SetResult("bounced@example.org", queue.RecipientResult{Status: queue.StatusFailed})
+
+	if err := s.Archive(context.Background(), msg); err != nil {
+		t.Fatalf("Archive() error = %v", err)
+	}
+	messages, _, err := s.Search(context.Background(), Query{Recipient: "bounced"})
+	if err != nil || len(messages) != 0 {
+		t.Errorf("failed recipient archived: %v, %v", messages, err)
+	}
+}
+
+func TestStorageCleanup(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	archiveMessage(t, s, "old", "a@example.com", []string{"x@example.org"}, "Subject: old\r\n\r\nold", now.Add(-48*time.Hour))
+	for _, id := range []string{"m1", "m2", "m3"} {
+		archiveMessage(t, s, id, "a@example.com", []string{"x@example.org"}, "Subject: "+id+"\r\n\r\nrecent", now)
+	}
+
+	deleted, err := s.Cleanup(ctx, 24*time.Hour, 2)
+	if err != nil {
+		t.Fatalf("Cleanup() error = %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("Cleanup() deleted %d, want 2", deleted)
+	}
+
+	messages, total, err := s.Search(ctx, Query{})
+	if err != nil || total != 2 || messages[0].QueueID != "m3" || messages[1].QueueID != "m2" {
+		t.Errorf("remaining = %v (total %d), %v", messages, total, err)
+	}
+	if messages, _, _ := s.Search(ctx, Query{Text: "old"}); len(messages) != 0 {
+		t.Errorf("index still finds deleted messages: %v", messages)
+	}
+
+	if deleted, err := s.Cleanup(ctx, 0, 0); deleted != 0 || err != nil {
+		t.Errorf("Cleanup() without limits = %d, %v", deleted, err)
+	}
+}
+
+func TestStorageCleanupDomain(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	old := time.Now().Add(-48 * time.Hour)
+
+	archiveMessage(t, s, "old", "a@example.com", []string{"x@example.org"}, "Subject: old\r\n\r\nold", old)
+	archiveMessage(t, s, "sub", "a@mail.example.com", []string{"x@example.org"}, "Subject: sub\r\n\r\nsub", old)
+	archiveMessage(t, s, "other", "a@example.org", []string{"x@example.com"}, "Subject: other\r\n\r\nother", old)
+	archiveMessage(t, s, "new", "a@example.com", []string{"x@example.org"}, "Subject: new\r\n\r\nnew", time.Now())
+
+	deleted, err := s.CleanupDomain(ctx, "Example.com", 24*time.Hour)
+	if err != nil {
+		t.Fatalf("CleanupDomain() error = %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("CleanupDomain() deleted %d, want 1", deleted)
+	}
+	if _, total, _ := s.Search(ctx, Query{}); total != 3 {
+		t.Errorf("remaining = %d, want 3", total)
+	}
+}
+
+func TestStorageDeleteAddress(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	archiveMessage(t, s, "from", "User@example.com", []string{"x@example.org"}, "Subject: a\r\n\r\nfrom", now)
+	archiveMessage(t, s, "to", "shop@example.org", []string{"a@example.org", "user@example.com"}, "Subject: b\r\n\r\nto", now)
+	archiveMessage(t, s, "similar", "shop@example.org", []string{"superuser@example.com"}, "Subject: c\r\n\r\nsimilar", now)
+
+	deleted, err := s.DeleteAddress(ctx, "user@example.com")
+	if err != nil {
+		t.Fatalf("DeleteAddress() error = %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("DeleteAddress() deleted %d, want 2", deleted)
+	}
+	messages, total, err := s.Search(ctx, Query{})
+	if err != nil || total != 1 || messages[0].QueueID != "similar" {
+		t.Errorf("remaining = %v (total %d), %v", messages, total, err)
+	}
+	if messages, _, _ := s.Search(ctx, Query{Text: "from"}); len(messages) != 0 {
+		t.Errorf("index still finds deleted messages: %v", messages)
+	}
+}
+
+func TestStorageReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive.db")
+	s, err := NewStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fts := s.FTS()
+	s.Close()
+
+	s, err = NewStorage(path)
+	if err != nil {
+		t.Fatalf("reopen error = %v", err)
+	}
+	defer s.Close()
+	if s.FTS() != fts {
+		t.Errorf("FTS() = %s after reopen, want %s", s.FTS(), fts)
+	}
+}
@@ -0,0 +1,104 @@
+// Package audit records management API changes so they can be traced
+// back to the client action (e.g. a sendry-web deploy) that caused them.
+package audit
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// CorrelationHeader carries the correlation ID between systems
+const CorrelationHeader = "X-Correlation-ID"
+
+// ActorHeader carries the user a client (e.g. sendry-web) acts on behalf of
+const ActorHeader = "X-Audit-Actor"
+
+// maxCorrelationIDLength limits client supplied correlation IDs
+const maxCorrelationIDLength = 128
+
+// maxActorLength limits client supplied actors
+const maxActorLength = 254
+
+// Entry is a single audit record of a management API request
+type Entry struct {
+	ID             uint64          `json:"id"`
+	Time           time.Time       `json:"time"`
+	CorrelationID  string          `json:"correlation_id,omitempty"`
+	Method         string          `json:"method"`
+	Path           string          `json:"path"`
+	Status         int             `json:"status"`
+	Error          string          `json:"error,omitempty"`
+	RemoteAddr     string          `json:"remote_addr,omitempty"`
+	APIKeyID       string          `json:"api_key_id,omitempty"`      // Stored API key used, empty for api.api_key
+	ClientIdentity string          `json:"client_identity,omitempty"` // Identity of the mTLS client certificate
+	Actor          string          `json:"actor,omitempty"`           // User the client acted for, from the X-Audit-Actor header
+	Old            json.RawMessage `json:"old,omitempty"`             // Value before the change
+	New            json.RawMessage `json:"new,omitempty"`             // Value after the change
+	Duration       time.Duration   `json:"duration"`
+}
+
+// Failed returns true if the request was rejected or failed
+func (e *Entry) Failed() bool {
+	return e.Status >= 400
+}
+
+// Filter selects audit entries
+type Filter struct {
+	CorrelationID string
+	Actor         string
+	PathPrefix    string
+	Limit         int
+}
+
+// Match reports whether an entry passes the filter
+func (f Filter) Match(e *Entry) bool {
+	if f.CorrelationID != "" && e.CorrelationID != f.CorrelationID {
+		return false
+	}
+	if f.Actor != "" && e.Actor != f.Actor {
+		return false
+	}
+	if f.PathPrefix != "" && !strings.HasPrefix(e.Path, f.PathPrefix) {
+		return false
+	}
+	return true
+}
+
+// SanitizeCorrelationID returns id if it is safe to log and store,
+// or an empty string otherwise. Allowed characters are letters, digits,
+// '-', '_', '.' and ':'.
+func SanitizeCorrelationID(id string) string {
+	id = strings.TrimSpace(id)
+	if id == "" || len(id) > maxCorrelationIDLength {
+		return ""
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return ""
+		}
+	}
+	return id
+}
+
+// SanitizeActor returns actor if it is safe to log and store, or an empty
+// string otherwise. Allowed characters are letters, digits and the ones of
+// e-mail addresses: '-', '_', '.', '@', '+' and ':'.
+func SanitizeActor(actor string) string {
+	actor = strings.TrimSpace(actor)
+	if actor == "" || len(actor) > maxActorLength {
+		return ""
+	}
+	for _, c := range actor {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == '@', c == '+', c == ':':
+		default:
+			return ""
+		}
+	}
+	return actor
+}
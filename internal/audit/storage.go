@@ -0,0 +1,119 @@
+package audit
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var bucketAudit = []byte("audit_log")
+
+const (
+	// DefaultMaxEntries is the number of entries kept before the oldest are pruned
+	DefaultMaxEntries = 10000
+
+	// defaultListLimit is used when a filter has no limit
+	defaultListLimit = 100
+)
+
+// Storage provides audit log storage
+type Storage struct {
+	db         *bolt.DB
+	maxEntries int
+}
+
+// NewStorage creates a new audit storage using the provided BoltDB instance
+func NewStorage(db *bolt.DB) (*Storage, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucketAudit)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create audit bucket: %w", err)
+	}
+	return &Storage{db: db, maxEntries: DefaultMaxEntries}, nil
+}
+
+// SetMaxEntries sets how many entries are retained
+func (s *Storage) SetMaxEntries(n int) {
+	if n > 0 {
+		s.maxEntries = n
+	}
+}
+
+// Add appends an entry, pruning the oldest ones above the retention limit
+func (s *Storage) Add(ctx context.Context, e *Entry) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketAudit)
+
+		id, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		e.ID = id
+
+		data, err := json.Marshal(e)
+		if err != nil {
+			return fmt.Errorf("failed to marshal audit entry: %w", err)
+		}
+		if err := b.Put(itob(id), data); err != nil {
+			return err
+		}
+
+		// Entries are keyed by sequence, so everything below the
+		// retention window sits at the start of the bucket
+		if id <= uint64(s.maxEntries) {
+			return nil
+		}
+		oldest := id - uint64(s.maxEntries)
+		c := b.Cursor()
+		for k, _ := c.First(); k != nil && binary.BigEndian.Uint64(k) <= oldest; k, _ = c.First() {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// List returns entries matching the filter, newest first
+func (s *Storage) List(ctx context.Context, filter Filter) ([]*Entry, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+
+	entries := make([]*Entry, 0)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucketAudit).Cursor()
+		for k, v := c.Last(); k != nil && len(entries) < limit; k, v = c.Prev() {
+			var e Entry
+			if err := json.Unmarshal(v, &e); err != nil {
+				continue
+			}
+			if !filter.Match(&e) {
+				continue
+			}
+			entries = append(entries, &e)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func itob(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return b
+}
@@ -0,0 +1,151 @@
+package audit
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func newTestStorage(t *testing.T) *Storage {
+	t.Helper()
+
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	storage, err := NewStorage(db)
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+	return storage
+}
+
+func TestStorageAddList(t *testing.T) {
+	storage := newTestStorage(t)
+	ctx := context.Background()
+
+	entries := []*Entry{
+		{CorrelationID: "deploy-1", Method: "PUT", Path: "/api/v1/domains/a.com", Status: 200, Actor: "admin@example.com"},
+		{CorrelationID: "deploy-2", Method: "POST", Path: "/api/v1/dkim/upload", Status: 400, Error: "invalid key"},
+		{CorrelationID: "deploy-1", Method: "POST", Path: "/api/v1/dkim/upload", Status: 201},
+	}
+	for _, e := range entries {
+		if err := storage.Add(ctx, e); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+		if e.ID == 0 || e.Time.IsZero() {
+			t.Errorf("Add() did not set ID/Time: %+v", e)
+		}
+	}
+
+	all, err := storage.List(ctx, Filter{})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(all) != 3 {
+		t.Fatalf("List() returned %d entries, want 3", len(all))
+	}
+	if all[0].ID != entries[2].ID {
+		t.Errorf("List() first ID = %d, want newest %d", all[0].ID, entries[2].ID)
+	}
+
+	linked, err := storage.List(ctx, Filter{CorrelationID: "deploy-1"})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(linked) != 2 {
+		t.Fatalf("List(deploy-1) returned %d entries, want 2", len(linked))
+	}
+	for _, e := range linked {
+		if e.CorrelationID != "deploy-1" {
+			t.Errorf("List(deploy-1) returned %q", e.CorrelationID)
+		}
+	}
+
+	failed, _ := storage.List(ctx, Filter{CorrelationID: "deploy-2"})
+	if len(failed) != 1 || !failed[0].Failed() || failed[0].Error != "invalid key" {
+		t.Errorf("List(deploy-2) = %+v", failed)
+	}
+
+	byActor, _ := storage.List(ctx, Filter{Actor: "admin@example.com"})
+	if len(byActor) != 1 || byActor[0].ID != entries[0].ID {
+		t.Errorf("List(actor) = %+v", byActor)
+	}
+
+	dkim, _ := storage.List(ctx, Filter{PathPrefix: "/api/v1/dkim/"})
+	if len(dkim) != 2 {
+		t.Errorf("List(path prefix) returned %d entries, want 2", len(dkim))
+	}
+
+	limited, _ := storage.List(ctx, Filter{Limit: 1})
+	if len(limited) != 1 {
+		t.Errorf("List(limit 1) returned %d entries", len(limited))
+	}
+}
+
+func TestStorageRetention(t *testing.T) {
+	storage := newTestStorage(t)
+	storage.SetMaxEntries(3)
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		if err := storage.Add(ctx, &Entry{Method: "PUT", Path: "/api/v1/domains/a.com", Status: 200}); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+
+	all, err := storage.List(ctx, Filter{})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(all) != 3 {
+		t.Fatalf("List() returned %d entries, want 3", len(all))
+	}
+	if all[2].ID != 3 {
+		t.Errorf("oldest kept ID = %d, want 3", all[2].ID)
+	}
+}
+
+func TestSanitizeCorrelationID(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", ""},
+		{"6f1c2a9e-0d4b-4d8e-9a57-3c1f0b6e2d11", "6f1c2a9e-0d4b-4d8e-9a57-3c1f0b6e2d11"},
+		{" web:deploy_1.2 ", "web:deploy_1.2"},
+		{"bad id", ""},
+		{"line\nbreak", ""},
+		{string(make([]byte, 200)), ""},
+	}
+
+	for _, tt := range tests {
+		if got := SanitizeCorrelationID(tt.in); got != tt.want {
+			t.Errorf("SanitizeCorrelationID(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSanitizeActor(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", ""},
+		{" admin+ops@example.com ", "admin+ops@example.com"},
+		{"web:deploy_bot", "web:deploy_bot"},
+		{"Admin <admin@example.com>", ""},
+		{"line\nbreak", ""},
+		{string(make([]byte, 300)), ""},
+	}
+
+	for _, tt := range tests {
+		if got := SanitizeActor(tt.in); got != tt.want {
+			t.Errorf("SanitizeActor(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
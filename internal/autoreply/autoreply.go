@@ -0,0 +1,68 @@
+package autoreply
+
+import (
+	"strings"
+	"time"
+)
+
+// DefaultIntervalDays is the default number of days between replies to the same sender
+const DefaultIntervalDays = 7
+
+// Responder represents an auto-reply (vacation) rule for a mailbox or a whole domain
+type Responder struct {
+	ID string `json:"id"`
+	// Address is a mailbox (user@example.com) or a domain (example.com)
+	Address      string     `json:"address"`
+	From         string     `json:"from,omitempty"` // Reply sender, defaults to the recipient address
+	Subject      string     `json:"subject,omitempty"`
+	Text         string     `json:"text,omitempty"`
+	HTML         string     `json:"html,omitempty"`
+	Enabled      bool       `json:"enabled"`
+	StartAt      *time.Time `json:"start_at,omitempty"`
+	EndAt        *time.Time `json:"end_at,omitempty"`
+	IntervalDays int        `json:"interval_days"` // One reply per sender per N days
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// IsDomain reports whether the responder covers a whole domain
+func (r *Responder) IsDomain() bool {
+	return !strings.Contains(r.Address, "@")
+}
+
+// Active reports whether the responder should reply at the given time
+func (r *Responder) Active(now time.Time) bool {
+	if !r.Enabled {
+		return false
+	}
+	if r.StartAt != nil && now.Before(*r.StartAt) {
+		return false
+	}
+	if r.EndAt != nil && !now.Before(*r.EndAt) {
+		return false
+	}
+	return true
+}
+
+// Interval returns the minimum time between replies to the same sender
+func (r *Responder) Interval() time.Duration {
+	days := r.IntervalDays
+	if days <= 0 {
+		days = DefaultIntervalDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// ListFilter contains filters for listing responders
+type ListFilter struct {
+	Limit  int
+	Offset int
+	Domain string
+}
+
+// NormalizeAddress lowercases and trims a mailbox or domain address
+func NormalizeAddress(addr string) string {
+	addr = strings.TrimSpace(addr)
+	addr = strings.Trim(addr, "<>")
+	return strings.ToLower(addr)
+}
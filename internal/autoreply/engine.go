@@ -0,0 +1,207 @@
+package autoreply
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/foxzi/sendry/internal/email"
+	"github.com/foxzi/sendry/internal/queue"
+)
+
+// Engine generates auto-replies for delivered messages
+type Engine struct {
+	storage *Storage
+	now     func() time.Time
+}
+
+// NewEngine creates a new auto-reply engine
+func NewEngine(storage *Storage) *Engine {
+	return &Engine{
+		storage: storage,
+		now:     time.Now,
+	}
+}
+
+// Respond returns auto-reply messages for the recipients of msg that have an active responder.
+// Each responder replies at most once per sender within its interval.
+func (e *Engine) Respond(ctx context.Context, msg *queue.Message) ([]*queue.Message, error) {
+	sender := NormalizeAddress(msg.From)
+	if isSystemSender(sender) {
+		return nil, nil
+	}
+
+	parsed, err := mail.ReadMessage(bytes.NewReader(msg.Data))
+	if err != nil {
+		return nil, nil // Not a parseable message, nothing to reply to
+	}
+	if shouldSuppress(parsed.Header) {
+		return nil, nil
+	}
+
+	now := e.now()
+	replied := make(map[string]bool)
+	var replies []*queue.Message
+
+	for _, rcpt := range msg.To {
+		rcpt = NormalizeAddress(rcpt)
+		if rcpt == sender {
+			continue
+		}
+
+		r, err := e.storage.Match(ctx, rcpt)
+		if err != nil {
+			return replies, fmt.Errorf("failed to match responder: %w", err)
+		}
+		if r == nil || replied[r.ID] || !r.Active(now) {
+			continue
+		}
+
+		last, err := e.storage.LastReply(ctx, r.ID, sender)
+		if err != nil {
+			return replies, fmt.Errorf("failed to get reply history: %w", err)
+		}
+		if !last.IsZero() && now.Sub(last) < r.Interval() {
+			continue
+		}
+
+		reply := buildReply(r, rcpt, sender, parsed.Header, now)
+		if err := e.storage.RecordReply(ctx, r.ID, sender, now); err != nil {
+			return replies, fmt.Errorf("failed to record reply: %w", err)
+		}
+
+		replied[r.ID] = true
+		replies = append(replies, reply)
+	}
+
+	return replies, nil
+}
+
+// buildReply builds the auto-reply message per RFC 3834
+func buildReply(r *Responder, rcpt, sender string, original mail.Header, now time.Time) *queue.Message {
+	from := sanitizeHeaderValue(r.From)
+	if from == "" {
+		from = rcpt
+	}
+
+	subject := r.Subject
+	if subject == "" {
+		subject = "Auto: " + original.Get("Subject")
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(fmt.Sprintf("From: %s\r\n", from))
+	buf.WriteString(fmt.Sprintf("To: %s\r\n", sender))
+	buf.WriteString(fmt.Sprintf("Subject: %s\r\n", sanitizeHeaderValue(subject)))
+	buf.WriteString(fmt.Sprintf("Date: %s\r\n", now.Format(time.RFC1123Z)))
+	buf.WriteString(fmt.Sprintf("Message-ID: <%s@%s>\r\n", uuid.New().String(), email.ExtractDomainOrDefault(from, "localhost")))
+	if msgID := sanitizeHeaderValue(original.Get("Message-ID")); msgID != "" {
+		buf.WriteString(fmt.Sprintf("In-Reply-To: %s\r\n", msgID))
+		references := sanitizeHeaderValue(original.Get("References"))
+		if references != "" {
+			references += " "
+		}
+		buf.WriteString(fmt.Sprintf("References: %s%s\r\n", references, msgID))
+	}
+	buf.WriteString("Auto-Submitted: auto-replied\r\n")
+	buf.WriteString("X-Auto-Response-Suppress: All\r\n")
+
+	if r.HTML != "" {
+		boundary := uuid.New().String()
+		buf.WriteString("MIME-Version: 1.0\r\n")
+		buf.WriteString(fmt.Sprintf("Content-Type: multipart/alternative; boundary=\"%s\"\r\n", boundary))
+		buf.WriteString("\r\n")
+
+		if r.Text != "" {
+			buf.WriteString(fmt.Sprintf("--%s\r\n", boundary))
+			buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+			buf.WriteString("\r\n")
+			buf.WriteString(r.Text)
+			buf.WriteString("\r\n")
+		}
+
+		buf.WriteString(fmt.Sprintf("--%s\r\n", boundary))
+		buf.WriteString("Content-Type: text/html; charset=utf-8\r\n")
+		buf.WriteString("\r\n")
+		buf.WriteString(r.HTML)
+		buf.WriteString("\r\n")
+
+		buf.WriteString(fmt.Sprintf("--%s--\r\n", boundary))
+	} else {
+		buf.WriteString("MIME-Version: 1.0\r\n")
+		buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+		buf.WriteString("\r\n")
+		buf.WriteString(r.Text)
+	}
+
+	envelopeFrom := from
+	if addr, err := mail.ParseAddress(from); err == nil {
+		envelopeFrom = addr.Address
+	}
+
+	return &queue.Message{
+		ID:        uuid.New().String(),
+		From:      envelopeFrom,
+		To:        []string{sender},
+		Data:      buf.Bytes(),
+		Status:    queue.StatusPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// shouldSuppress reports whether the message must not be auto-replied to (RFC 3834 loop protection)
+func shouldSuppress(h mail.Header) bool {
+	if v := strings.ToLower(strings.TrimSpace(h.Get("Auto-Submitted"))); v != "" && v != "no" {
+		return true
+	}
+
+	switch strings.ToLower(strings.TrimSpace(h.Get("Precedence"))) {
+	case "bulk", "junk", "list":
+		return true
+	}
+
+	for _, name := range []string{"List-Id", "List-Unsubscribe", "X-Autoreply", "X-Autorespond"} {
+		if h.Get(name) != "" {
+			return true
+		}
+	}
+
+	suppress := strings.ToLower(h.Get("X-Auto-Response-Suppress"))
+	return strings.Contains(suppress, "all") || strings.Contains(suppress, "oof")
+}
+
+// isSystemSender reports whether the envelope sender is a null or system address
+func isSystemSender(sender string) bool {
+	if sender == "" {
+		return true
+	}
+
+	at := strings.LastIndex(sender, "@")
+	if at <= 0 {
+		return true
+	}
+
+	local := sender[:at]
+	switch {
+	case local == "mailer-daemon", local == "postmaster", local == "listserv", local == "majordomo":
+		return true
+	case strings.HasPrefix(local, "owner-"), strings.HasSuffix(local, "-request"):
+		return true
+	case strings.HasPrefix(local, "noreply"), strings.HasPrefix(local, "no-reply"), strings.HasPrefix(local, "do-not-reply"):
+		return true
+	}
+	return false
+}
+
+// sanitizeHeaderValue removes CR/LF characters to prevent header injection
+func sanitizeHeaderValue(s string) string {
+	s = strings.ReplaceAll(s, "\r", "")
+	s = strings.ReplaceAll(s, "\n", "")
+	return strings.TrimSpace(s)
+}
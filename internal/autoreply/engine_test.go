@@ -0,0 +1,144 @@
+package autoreply
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/foxzi/sendry/internal/queue"
+)
+
+func incoming(from string, to []string, extraHeaders string) *queue.Message {
+	data := "From: " + from + "\r\n" +
+		"To: " + strings.Join(to, ", ") + "\r\n" +
+		"Subject: Hello\r\n" +
+		"Message-ID: <orig-1@remote.example>\r\n" +
+		extraHeaders +
+		"\r\n" +
+		"Body"
+	return &queue.Message{
+		ID:   "msg-1",
+		From: from,
+		To:   to,
+		Data: []byte(data),
+	}
+}
+
+func TestEngine_Respond(t *testing.T) {
+	storage := setupTestStorage(t)
+	ctx := context.Background()
+
+	r := &Responder{Address: "alice@example.com", Text: "I'm on vacation", Enabled: true, IntervalDays: 1}
+	if err := storage.Create(ctx, r); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	engine := NewEngine(storage)
+	now := time.Now()
+	engine.now = func() time.Time { return now }
+
+	msg := incoming("sender@remote.example", []string{"alice@example.com"}, "")
+	replies, err := engine.Respond(ctx, msg)
+	if err != nil {
+		t.Fatalf("Respond() error = %v", err)
+	}
+	if len(replies) != 1 {
+		t.Fatalf("expected 1 reply, got %d", len(replies))
+	}
+
+	reply := replies[0]
+	if reply.From != "alice@example.com" {
+		t.Errorf("expected reply from alice@example.com, got %s", reply.From)
+	}
+	if len(reply.To) != 1 || reply.To[0] != "sender@remote.example" {
+		t.Errorf("unexpected reply recipients: %v", reply.To)
+	}
+	data := string(reply.Data)
+	for _, want := range []string{
+		"Auto-Submitted: auto-replied",
+		"In-Reply-To: <orig-1@remote.example>",
+		"Subject: Auto: Hello",
+		"I'm on vacation",
+	} {
+		if !strings.Contains(data, want) {
+			t.Errorf("reply should contain %q", want)
+		}
+	}
+
+	// Second message within the interval gets no reply
+	replies, _ = engine.Respond(ctx, msg)
+	if len(replies) != 0 {
+		t.Errorf("expected no reply within interval, got %d", len(replies))
+	}
+
+	// After the interval the sender gets a reply again
+	engine.now = func() time.Time { return now.Add(25 * time.Hour) }
+	replies, _ = engine.Respond(ctx, msg)
+	if len(replies) != 1 {
+		t.Errorf("expected reply after interval, got %d", len(replies))
+	}
+}
+
+func TestEngine_Schedule(t *testing.T) {
+	storage := setupTestStorage(t)
+	ctx := context.Background()
+
+	start := time.Now().Add(time.Hour)
+	r := &Responder{Address: "example.com", Text: "Closed", Enabled: true, StartAt: &start}
+	if err := storage.Create(ctx, r); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	engine := NewEngine(storage)
+	msg := incoming("sender@remote.example", []string{"info@example.com"}, "")
+
+	replies, _ := engine.Respond(ctx, msg)
+	if len(replies) != 0 {
+		t.Errorf("expected no reply before start, got %d", len(replies))
+	}
+
+	engine.now = func() time.Time { return start.Add(time.Minute) }
+	replies, _ = engine.Respond(ctx, msg)
+	if len(replies) != 1 {
+		t.Errorf("expected reply inside window, got %d", len(replies))
+	}
+}
+
+func TestEngine_LoopProtection(t *testing.T) {
+	storage := setupTestStorage(t)
+	ctx := context.Background()
+
+	if err := storage.Create(ctx, &Responder{Address: "example.com", Text: "Away", Enabled: true}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	engine := NewEngine(storage)
+
+	tests := []struct {
+		name    string
+		from    string
+		headers string
+	}{
+		{"auto-submitted", "sender@remote.example", "Auto-Submitted: auto-replied\r\n"},
+		{"precedence bulk", "sender@remote.example", "Precedence: bulk\r\n"},
+		{"mailing list", "sender@remote.example", "List-Id: <news.remote.example>\r\n"},
+		{"suppress header", "sender@remote.example", "X-Auto-Response-Suppress: OOF\r\n"},
+		{"null sender", "", ""},
+		{"mailer-daemon", "MAILER-DAEMON@remote.example", ""},
+		{"noreply", "noreply@remote.example", ""},
+		{"list owner", "owner-news@remote.example", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := incoming(tt.from, []string{"info@example.com"}, tt.headers)
+			replies, err := engine.Respond(ctx, msg)
+			if err != nil {
+				t.Fatalf("Respond() error = %v", err)
+			}
+			if len(replies) != 0 {
+				t.Errorf("expected no reply, got %d", len(replies))
+			}
+		})
+	}
+}
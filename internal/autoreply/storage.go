@@ -0,0 +1,282 @@
+package autoreply
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/foxzi/sendry/internal/email"
+)
+
+var (
+	bucketResponders   = []byte("autoreplies")
+	bucketAddresses    = []byte("autoreply_addresses")
+	bucketReplyHistory = []byte("autoreply_history")
+)
+
+// Storage provides auto-reply responder storage
+type Storage struct {
+	db *bolt.DB
+}
+
+// NewStorage creates a new auto-reply storage using the provided BoltDB instance
+func NewStorage(db *bolt.DB) (*Storage, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{bucketResponders, bucketAddresses, bucketReplyHistory} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create autoreply buckets: %w", err)
+	}
+	return &Storage{db: db}, nil
+}
+
+// Create creates a new responder
+func (s *Storage) Create(ctx context.Context, r *Responder) error {
+	r.Address = NormalizeAddress(r.Address)
+	if r.Address == "" {
+		return fmt.Errorf("address is required")
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		responders := tx.Bucket(bucketResponders)
+		addresses := tx.Bucket(bucketAddresses)
+
+		if existing := addresses.Get([]byte(r.Address)); existing != nil {
+			return fmt.Errorf("responder for %q already exists", r.Address)
+		}
+
+		r.ID = uuid.New().String()
+		r.CreatedAt = time.Now()
+		r.UpdatedAt = r.CreatedAt
+
+		data, err := json.Marshal(r)
+		if err != nil {
+			return fmt.Errorf("failed to marshal responder: %w", err)
+		}
+
+		if err := responders.Put([]byte(r.ID), data); err != nil {
+			return err
+		}
+		return addresses.Put([]byte(r.Address), []byte(r.ID))
+	})
+}
+
+// Get retrieves a responder by ID
+func (s *Storage) Get(ctx context.Context, id string) (*Responder, error) {
+	var r *Responder
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bucketResponders).Get([]byte(id))
+		if data == nil {
+			return nil
+		}
+		r = &Responder{}
+		return json.Unmarshal(data, r)
+	})
+
+	return r, err
+}
+
+// GetByAddress retrieves a responder by mailbox or domain address
+func (s *Storage) GetByAddress(ctx context.Context, address string) (*Responder, error) {
+	var r *Responder
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		id := tx.Bucket(bucketAddresses).Get([]byte(NormalizeAddress(address)))
+		if id == nil {
+			return nil
+		}
+		data := tx.Bucket(bucketResponders).Get(id)
+		if data == nil {
+			return nil
+		}
+		r = &Responder{}
+		return json.Unmarshal(data, r)
+	})
+
+	return r, err
+}
+
+// Match returns the responder for a recipient, preferring a mailbox rule over a domain rule
+func (s *Storage) Match(ctx context.Context, recipient string) (*Responder, error) {
+	r, err := s.GetByAddress(ctx, recipient)
+	if err != nil || r != nil {
+		return r, err
+	}
+
+	domain := email.ExtractDomain(NormalizeAddress(recipient))
+	if domain == "" {
+		return nil, nil
+	}
+	return s.GetByAddress(ctx, domain)
+}
+
+// List returns responders with optional filtering
+func (s *Storage) List(ctx context.Context, filter ListFilter) ([]*Responder, error) {
+	var responders []*Responder
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucketResponders).Cursor()
+
+		skipped := 0
+		count := 0
+
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			var r Responder
+			if err := json.Unmarshal(v, &r); err != nil {
+				continue
+			}
+
+			if filter.Domain != "" {
+				domain := r.Address
+				if !r.IsDomain() {
+					domain = email.ExtractDomain(r.Address)
+				}
+				if domain != NormalizeAddress(filter.Domain) {
+					continue
+				}
+			}
+
+			// Apply offset
+			if skipped < filter.Offset {
+				skipped++
+				continue
+			}
+
+			responders = append(responders, &r)
+			count++
+
+			// Apply limit
+			if filter.Limit > 0 && count >= filter.Limit {
+				break
+			}
+		}
+
+		return nil
+	})
+
+	return responders, err
+}
+
+// Update updates an existing responder
+func (s *Storage) Update(ctx context.Context, r *Responder) error {
+	r.Address = NormalizeAddress(r.Address)
+	if r.Address == "" {
+		return fmt.Errorf("address is required")
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		responders := tx.Bucket(bucketResponders)
+		addresses := tx.Bucket(bucketAddresses)
+
+		existingData := responders.Get([]byte(r.ID))
+		if existingData == nil {
+			return fmt.Errorf("responder not found")
+		}
+
+		var existing Responder
+		if err := json.Unmarshal(existingData, &existing); err != nil {
+			return err
+		}
+
+		// If address changed, update index
+		if existing.Address != r.Address {
+			if existingID := addresses.Get([]byte(r.Address)); existingID != nil {
+				return fmt.Errorf("responder for %q already exists", r.Address)
+			}
+			if err := addresses.Delete([]byte(existing.Address)); err != nil {
+				return err
+			}
+			if err := addresses.Put([]byte(r.Address), []byte(r.ID)); err != nil {
+				return err
+			}
+		}
+
+		r.CreatedAt = existing.CreatedAt
+		r.UpdatedAt = time.Now()
+
+		data, err := json.Marshal(r)
+		if err != nil {
+			return fmt.Errorf("failed to marshal responder: %w", err)
+		}
+
+		return responders.Put([]byte(r.ID), data)
+	})
+}
+
+// Delete removes a responder and its reply history
+func (s *Storage) Delete(ctx context.Context, id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		responders := tx.Bucket(bucketResponders)
+
+		data := responders.Get([]byte(id))
+		if data == nil {
+			return nil // Already deleted
+		}
+
+		var r Responder
+		if err := json.Unmarshal(data, &r); err != nil {
+			return err
+		}
+
+		if err := tx.Bucket(bucketAddresses).Delete([]byte(r.Address)); err != nil {
+			return err
+		}
+
+		// Remove reply history for this responder
+		history := tx.Bucket(bucketReplyHistory)
+		prefix := []byte(id + ":")
+		c := history.Cursor()
+		var keysToDelete [][]byte
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			keysToDelete = append(keysToDelete, k)
+		}
+		for _, k := range keysToDelete {
+			if err := history.Delete(k); err != nil {
+				return err
+			}
+		}
+
+		return responders.Delete([]byte(id))
+	})
+}
+
+// LastReply returns when the responder last replied to the sender (zero if never)
+func (s *Storage) LastReply(ctx context.Context, responderID, sender string) (time.Time, error) {
+	var last time.Time
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bucketReplyHistory).Get(historyKey(responderID, sender))
+		if data == nil {
+			return nil
+		}
+		return last.UnmarshalText(data)
+	})
+
+	return last, err
+}
+
+// RecordReply stores the time a reply was sent to the sender
+func (s *Storage) RecordReply(ctx context.Context, responderID, sender string, at time.Time) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		data, err := at.MarshalText()
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(bucketReplyHistory).Put(historyKey(responderID, sender), data)
+	})
+}
+
+func historyKey(responderID, sender string) []byte {
+	return []byte(responderID + ":" + NormalizeAddress(sender))
+}
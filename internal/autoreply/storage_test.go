@@ -0,0 +1,97 @@
+package autoreply
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func setupTestStorage(t *testing.T) *Storage {
+	t.Helper()
+
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	storage, err := NewStorage(db)
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+	return storage
+}
+
+func TestStorage_CRUD(t *testing.T) {
+	storage := setupTestStorage(t)
+	ctx := context.Background()
+
+	r := &Responder{Address: " Alice@Example.com ", Text: "Away", Enabled: true}
+	if err := storage.Create(ctx, r); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if r.Address != "alice@example.com" {
+		t.Errorf("expected normalized address, got %q", r.Address)
+	}
+
+	if err := storage.Create(ctx, &Responder{Address: "alice@example.com"}); err == nil {
+		t.Error("expected error for duplicate address")
+	}
+
+	got, err := storage.GetByAddress(ctx, "ALICE@example.com")
+	if err != nil || got == nil || got.ID != r.ID {
+		t.Fatalf("GetByAddress() = %v, %v", got, err)
+	}
+
+	r.Address = "bob@example.com"
+	if err := storage.Update(ctx, r); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if got, _ := storage.GetByAddress(ctx, "alice@example.com"); got != nil {
+		t.Error("old address index should be removed")
+	}
+
+	list, err := storage.List(ctx, ListFilter{Domain: "example.com"})
+	if err != nil || len(list) != 1 {
+		t.Fatalf("List() = %d items, %v", len(list), err)
+	}
+
+	if err := storage.RecordReply(ctx, r.ID, "x@remote.example", time.Now()); err != nil {
+		t.Fatalf("RecordReply() error = %v", err)
+	}
+	if err := storage.Delete(ctx, r.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if got, _ := storage.Get(ctx, r.ID); got != nil {
+		t.Error("responder should be deleted")
+	}
+	if last, _ := storage.LastReply(ctx, r.ID, "x@remote.example"); !last.IsZero() {
+		t.Error("reply history should be deleted")
+	}
+}
+
+func TestStorage_MatchPrefersMailbox(t *testing.T) {
+	storage := setupTestStorage(t)
+	ctx := context.Background()
+
+	domain := &Responder{Address: "example.com", Enabled: true}
+	mailbox := &Responder{Address: "alice@example.com", Enabled: true}
+	for _, r := range []*Responder{domain, mailbox} {
+		if err := storage.Create(ctx, r); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	if got, _ := storage.Match(ctx, "alice@example.com"); got == nil || got.ID != mailbox.ID {
+		t.Errorf("expected mailbox responder, got %v", got)
+	}
+	if got, _ := storage.Match(ctx, "bob@example.com"); got == nil || got.ID != domain.ID {
+		t.Errorf("expected domain responder, got %v", got)
+	}
+	if got, _ := storage.Match(ctx, "bob@other.com"); got != nil {
+		t.Errorf("expected no responder, got %v", got)
+	}
+}
@@ -0,0 +1,187 @@
+package bounce
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/textproto"
+	"strings"
+)
+
+// maxPartSize bounds the parts of a DSN that are read
+const maxPartSize = 10 << 20
+
+// ErrInvalidDSN is returned for messages that are not delivery status
+// notifications
+var ErrInvalidDSN = errors.New("not a delivery status notification")
+
+// DSN is a parsed delivery status notification (RFC 3464)
+type DSN struct {
+	ReportingMTA string
+	Recipients   []RecipientStatus
+}
+
+// RecipientStatus is the per-recipient part of a DSN
+type RecipientStatus struct {
+	FinalRecipient    string
+	OriginalRecipient string
+	Action            string // failed, delayed, delivered, relayed or expanded
+	Status            string // Enhanced status code, e.g. 5.1.1
+	DiagnosticCode    string // Reply of the remote MTA, e.g. "550 5.1.1 User unknown"
+	RemoteMTA         string
+}
+
+// Permanent reports whether the recipient failed permanently
+func (r *RecipientStatus) Permanent() bool {
+	return r.Action == "failed" || strings.HasPrefix(r.Status, "5.")
+}
+
+// ParseDSN parses a delivery status notification: a multipart/report
+// message with report-type delivery-status, whose machine-readable part
+// describes the outcome per recipient. Internationalized notifications
+// (message/global-delivery-status, RFC 6533) are accepted too.
+func ParseDSN(data []byte) (*DSN, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDSN, err)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/report" {
+		return nil, fmt.Errorf("%w: content type is not multipart/report", ErrInvalidDSN)
+	}
+	if rt := strings.ToLower(params["report-type"]); rt != "delivery-status" && rt != "global-delivery-status" {
+		return nil, fmt.Errorf("%w: report type is not delivery-status", ErrInvalidDSN)
+	}
+	if params["boundary"] == "" {
+		return nil, fmt.Errorf("%w: missing multipart boundary", ErrInvalidDSN)
+	}
+
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidDSN, err)
+		}
+
+		partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		if partType != "message/delivery-status" && partType != "message/global-delivery-status" {
+			continue
+		}
+		body, err := readPart(part)
+		if err != nil {
+			return nil, err
+		}
+		return parseDeliveryStatus(body)
+	}
+
+	return nil, fmt.Errorf("%w: missing message/delivery-status part", ErrInvalidDSN)
+}
+
+// readPart reads a report part, decoding base64 content. Quoted-printable
+// parts are decoded by the multipart reader.
+func readPart(part *multipart.Part) ([]byte, error) {
+	var r io.Reader = io.LimitReader(part, maxPartSize)
+	if strings.EqualFold(strings.TrimSpace(part.Header.Get("Content-Transfer-Encoding")), "base64") {
+		r = base64.NewDecoder(base64.StdEncoding, r)
+	}
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDSN, err)
+	}
+	return body, nil
+}
+
+// parseDeliveryStatus parses the per-message fields and the per-recipient
+// field groups of a delivery-status part, separated by empty lines
+func parseDeliveryStatus(data []byte) (*DSN, error) {
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+
+	var groups []textproto.MIMEHeader
+	for _, block := range bytes.Split(data, []byte("\n\n")) {
+		if len(bytes.TrimSpace(block)) == 0 {
+			continue
+		}
+		if h := parseFields(block); h != nil {
+			groups = append(groups, h)
+		}
+	}
+	if len(groups) < 2 {
+		return nil, fmt.Errorf("%w: no per-recipient fields", ErrInvalidDSN)
+	}
+
+	dsn := &DSN{ReportingMTA: stripType(groups[0].Get("Reporting-MTA"))}
+	for _, h := range groups[1:] {
+		rs := RecipientStatus{
+			FinalRecipient:    stripType(h.Get("Final-Recipient")),
+			OriginalRecipient: stripType(h.Get("Original-Recipient")),
+			Action:            strings.ToLower(strings.TrimSpace(h.Get("Action"))),
+			Status:            statusCode(h.Get("Status")),
+			DiagnosticCode:    stripType(h.Get("Diagnostic-Code")),
+			RemoteMTA:         stripType(h.Get("Remote-MTA")),
+		}
+		if rs.FinalRecipient == "" || rs.Action == "" {
+			continue
+		}
+		dsn.Recipients = append(dsn.Recipients, rs)
+	}
+	if len(dsn.Recipients) == 0 {
+		return nil, fmt.Errorf("%w: no recipient with Final-Recipient and Action", ErrInvalidDSN)
+	}
+	return dsn, nil
+}
+
+// parseFields parses a block of fields. Blocks without a terminating empty
+// line are accepted.
+func parseFields(data []byte) textproto.MIMEHeader {
+	data = append(bytes.Clone(data), "\r\n\r\n"...)
+	h, err := textproto.NewReader(bufio.NewReader(bytes.NewReader(data))).ReadMIMEHeader()
+	if err != nil && len(h) == 0 {
+		return nil
+	}
+	return h
+}
+
+// Recipient returns the status of a recipient, nil if the DSN does not
+// name it
+func (d *DSN) Recipient(rcpt string) *RecipientStatus {
+	for i := range d.Recipients {
+		r := &d.Recipients[i]
+		if strings.EqualFold(r.FinalRecipient, rcpt) || strings.EqualFold(r.OriginalRecipient, rcpt) {
+			return r
+		}
+	}
+	return nil
+}
+
+// statusCode returns the enhanced status code of a Status field without
+// trailing comments
+func statusCode(s string) string {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// stripType removes the type prefix of a field, e.g. "rfc822;" of an
+// address or "dns;" of an MTA name, and surrounding angle brackets
+func stripType(s string) string {
+	if _, value, ok := strings.Cut(s, ";"); ok {
+		s = value
+	}
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "<") && strings.HasSuffix(s, ">") {
+		s = s[1 : len(s)-1]
+	}
+	return s
+}
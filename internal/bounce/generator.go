@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"net/mail"
+	"regexp"
 	"strings"
 	"text/template"
 	"time"
@@ -45,22 +46,34 @@ func (g *Generator) GenerateDSN(msg *queue.Message, errorMsg string, permanent b
 		Date:            time.Now().Format(time.RFC1123Z),
 		MessageID:       fmt.Sprintf("<%s.dsn@%s>", msg.ID, g.hostname),
 		OriginalFrom:    msg.From,
-		Recipients:      msg.To,
 		OriginalSubject: originalSubject,
 		ErrorMessage:    errorMsg,
 		OriginalID:      msg.ID,
-		Action:          "failed",
-		Status:          "5.0.0",
-		DiagnosticCode:  errorMsg,
 		Boundary:        fmt.Sprintf("==Boundary_%s==", msg.ID),
 	}
 
-	if permanent {
-		data.Action = "failed"
-		data.Status = "5.0.0" // Permanent failure
-	} else {
-		data.Action = "delayed"
-		data.Status = "4.0.0" // Temporary failure
+	action, status := "failed", "5.0.0" // Permanent failure
+	if !permanent {
+		action, status = "delayed", "4.0.0" // Temporary failure
+	}
+
+	for _, rcpt := range msg.To {
+		r := dsnRecipient{
+			Address:        rcpt,
+			Action:         action,
+			Status:         status,
+			DiagnosticCode: errorMsg,
+		}
+		// Report what happened to this recipient when the result is known
+		if result := msg.Results[rcpt]; result != nil && result.Status == queue.StatusFailed {
+			r.Action = "failed"
+			r.Status = recipientStatus(result)
+			r.RemoteMTA = result.MXHost
+			if result.Error != "" {
+				r.DiagnosticCode = result.Error
+			}
+		}
+		data.Recipients = append(data.Recipients, r)
 	}
 
 	var buf bytes.Buffer
@@ -94,6 +107,20 @@ func (g *Generator) GenerateSimpleBounce(msg *queue.Message, errorMsg string) ([
 	return buf.Bytes(), nil
 }
 
+// enhancedCodePattern matches RFC 3463 enhanced status codes
+var enhancedCodePattern = regexp.MustCompile(`\b([245])\.(\d{1,3})\.(\d{1,3})\b`)
+
+// recipientStatus returns the DSN status code of a failed recipient
+func recipientStatus(result *queue.RecipientResult) string {
+	if result.Expired {
+		return "4.4.7" // Delivery time expired
+	}
+	if code := enhancedCodePattern.FindString(result.Error); strings.HasPrefix(code, "5") {
+		return code
+	}
+	return "5.0.0"
+}
+
 type dsnData struct {
 	Hostname        string
 	Postmaster      string
@@ -101,16 +128,22 @@ type dsnData struct {
 	Date            string
 	MessageID       string
 	OriginalFrom    string
-	Recipients      []string
+	Recipients      []dsnRecipient
 	OriginalSubject string
 	ErrorMessage    string
 	OriginalID      string
-	Action          string
-	Status          string
-	DiagnosticCode  string
 	Boundary        string
 }
 
+// dsnRecipient is the per-recipient part of a DSN
+type dsnRecipient struct {
+	Address        string
+	Action         string
+	Status         string
+	RemoteMTA      string
+	DiagnosticCode string
+}
+
 type simpleBounceData struct {
 	Hostname        string
 	Postmaster      string
@@ -146,7 +179,7 @@ For further assistance, please contact <{{.Postmaster}}>.
 
 Subject: {{.OriginalSubject}}
 Recipients:{{range .Recipients}}
-  - {{.}}{{end}}
+  - {{.Address}}: {{.DiagnosticCode}}{{end}}
 
 --- Error details ---
 
@@ -158,10 +191,11 @@ Content-Type: message/delivery-status
 Reporting-MTA: dns; {{.ReportingMTA}}
 Arrival-Date: {{.Date}}
 {{range .Recipients}}
-Final-Recipient: rfc822; {{.}}
-Action: {{$.Action}}
-Status: {{$.Status}}
-Diagnostic-Code: smtp; {{$.DiagnosticCode}}
+Final-Recipient: rfc822; {{.Address}}
+Action: {{.Action}}
+Status: {{.Status}}{{if .RemoteMTA}}
+Remote-MTA: dns; {{.RemoteMTA}}{{end}}
+Diagnostic-Code: smtp; {{.DiagnosticCode}}
 {{end}}
 --{{.Boundary}}--
 `))
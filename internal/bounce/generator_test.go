@@ -236,3 +236,42 @@ func TestGenerateDSNWithCustomPostmaster(t *testing.T) {
 		t.Error("custom postmaster not used in body")
 	}
 }
+
+func TestGenerateDSNPerRecipientResults(t *testing.T) {
+	g := NewGenerator("mail.example.com")
+
+	msg := &queue.Message{
+		ID:   "test-456",
+		From: "sender@example.com",
+		To:   []string{"unknown@test.com", "slow@other.com"},
+		Data: []byte("Subject: Test\r\n\r\nBody"),
+	}
+	msg.SetResult("unknown@test.com", queue.RecipientResult{
+		Status: queue.StatusFailed,
+		MXHost: "mx1.test.com",
+		Error:  "550 5.1.1 User unknown",
+	})
+	msg.SetResult("slow@other.com", queue.RecipientResult{
+		Status:  queue.StatusFailed,
+		MXHost:  "mx.other.com",
+		Error:   "max retries exceeded: 451 4.3.0 Try again later",
+		Expired: true,
+	})
+
+	dsn, err := g.GenerateDSN(msg, "delivery failed", true)
+	if err != nil {
+		t.Fatalf("GenerateDSN failed: %v", err)
+	}
+	dsnStr := string(dsn)
+
+	for _, want := range []string{
+		"Final-Recipient: rfc822; unknown@test.com\nAction: failed\nStatus: 5.1.1\nRemote-MTA: dns; mx1.test.com\nDiagnostic-Code: smtp; 550 5.1.1 User unknown",
+		"Final-Recipient: rfc822; slow@other.com\nAction: failed\nStatus: 4.4.7\nRemote-MTA: dns; mx.other.com",
+		"  - unknown@test.com: 550 5.1.1 User unknown",
+		"  - slow@other.com: max retries exceeded: 451 4.3.0 Try again later",
+	} {
+		if !strings.Contains(dsnStr, want) {
+			t.Errorf("DSN missing %q", want)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package bounce
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/foxzi/sendry/internal/metrics"
+	"github.com/foxzi/sendry/internal/queue"
+	"github.com/foxzi/sendry/internal/suppression"
+)
+
+// Attributions name how a bounce was matched to the recipient it is about
+const (
+	AttributionVERP = "verp" // Envelope recipient of the bounce is a VERP address
+	AttributionDSN  = "dsn"  // Final-Recipient of the DSN, the message is unknown
+)
+
+// Bounce is the outcome of one recipient reported by a received DSN
+type Bounce struct {
+	MessageID    string // Queue message ID, from VERP
+	Recipient    string
+	Attribution  string // verp or dsn
+	Action       string // failed or delayed
+	Status       string
+	Diagnostic   string
+	ReportingMTA string
+	Permanent    bool
+	Suppressed   bool
+	Updated      bool // The recipient result of the queued message was updated
+}
+
+// Processor attributes received bounces to the messages and recipients
+// they are about: it fails hard-bounced recipients of their queued message
+// and puts addresses that do not exist on the suppression list
+type Processor struct {
+	queue        queue.Queue
+	suppressions *suppression.Storage
+	logger       *slog.Logger
+}
+
+// NewProcessor creates a new bounce processor
+func NewProcessor(q queue.Queue, suppressions *suppression.Storage, logger *slog.Logger) *Processor {
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	return &Processor{queue: q, suppressions: suppressions, logger: logger}
+}
+
+// ProcessBounce processes a bounce received by an inbound bounce rule.
+// rcpt is the envelope recipient of the bounce, the return path of the
+// bounced message.
+func (p *Processor) ProcessBounce(ctx context.Context, rcpt string, data []byte) error {
+	_, err := p.Process(ctx, rcpt, data)
+	return err
+}
+
+// Process parses a DSN sent to the return path rcpt and records the
+// outcome of the recipients it reports. A VERP return path names the
+// message and recipient; otherwise the failed Final-Recipients of the DSN
+// are only suppressed. It returns an error wrapping ErrInvalidDSN if data
+// is not a DSN, e.g. an auto-reply sent to the return path.
+func (p *Processor) Process(ctx context.Context, rcpt string, data []byte) ([]*Bounce, error) {
+	dsn, err := ParseDSN(data)
+	if err != nil {
+		return nil, err
+	}
+
+	bounces := attribute(dsn, rcpt)
+	for _, b := range bounces {
+		if b.Permanent && suppressible(b.Status) {
+			added, err := p.suppressions.Add(ctx, &suppression.Entry{
+				Email:  b.Recipient,
+				Reason: suppression.ReasonBounce,
+				Detail: bounceDetail(b),
+			})
+			if err != nil {
+				p.logger.Warn("failed to suppress bounced recipient", "recipient", b.Recipient, "error", err)
+			} else {
+				b.Suppressed = true
+				if added {
+					p.logger.Info("recipient suppressed after bounce", "recipient", b.Recipient, "status", b.Status)
+				}
+			}
+		}
+
+		if b.MessageID != "" {
+			if err := p.updateMessage(ctx, b); err != nil {
+				return nil, fmt.Errorf("failed to update bounced message: %w", err)
+			}
+		}
+
+		kind := "soft"
+		if b.Permanent {
+			kind = "hard"
+		}
+		metrics.IncBounces(kind, b.Attribution)
+
+		p.logger.Warn("bounce received",
+			"message_id", b.MessageID,
+			"recipient", b.Recipient,
+			"attribution", b.Attribution,
+			"action", b.Action,
+			"status", b.Status,
+			"diagnostic", b.Diagnostic,
+			"reporting_mta", b.ReportingMTA,
+			"suppressed", b.Suppressed,
+			"updated", b.Updated,
+		)
+	}
+	return bounces, nil
+}
+
+// attribute returns the bounces a DSN reports. With a VERP return path the
+// recipient comes from the address, and its status from the DSN entry of
+// the recipient or, when the recipient was forwarded, the first failed or
+// delayed entry. Entries for delivered, relayed or expanded recipients are
+// ignored.
+func attribute(dsn *DSN, rcpt string) []*Bounce {
+	newBounce := func(rs *RecipientStatus, recipient string) *Bounce {
+		return &Bounce{
+			Recipient:    suppression.Normalize(recipient),
+			Action:       rs.Action,
+			Status:       rs.Status,
+			Diagnostic:   rs.DiagnosticCode,
+			ReportingMTA: dsn.ReportingMTA,
+			Permanent:    rs.Permanent(),
+		}
+	}
+
+	var bounces []*Bounce
+	if msgID, recipient, ok := DecodeVERP(rcpt); ok {
+		rs := dsn.Recipient(recipient)
+		if rs == nil || !isBounce(rs) {
+			rs = nil
+			for i := range dsn.Recipients {
+				if isBounce(&dsn.Recipients[i]) {
+					rs = &dsn.Recipients[i]
+					break
+				}
+			}
+		}
+		if rs != nil {
+			b := newBounce(rs, recipient)
+			b.MessageID = msgID
+			b.Attribution = AttributionVERP
+			bounces = append(bounces, b)
+		}
+		return bounces
+	}
+
+	for i := range dsn.Recipients {
+		rs := &dsn.Recipients[i]
+		if !isBounce(rs) {
+			continue
+		}
+		b := newBounce(rs, rs.FinalRecipient)
+		b.Attribution = AttributionDSN
+		bounces = append(bounces, b)
+	}
+	return bounces
+}
+
+// isBounce reports whether a DSN entry reports a failed or delayed recipient
+func isBounce(rs *RecipientStatus) bool {
+	return rs.Action == "failed" || rs.Action == "delayed"
+}
+
+// suppressible reports whether a permanent bounce means the address does
+// not accept mail: an addressing error (5.1.x) or a disabled mailbox
+// (5.2.1). Policy rejections and full mailboxes are not suppressed.
+func suppressible(status string) bool {
+	return strings.HasPrefix(status, "5.1.") || status == "5.2.1"
+}
+
+// updateMessage records a bounce on the queued message it is about. A hard
+// bounce fails the recipient, and the message if no recipient is left
+// delivered; a delayed DSN is only added to the attempt log. Messages that
+// were cleaned up or do not have the recipient are left alone.
+func (p *Processor) updateMessage(ctx context.Context, b *Bounce) error {
+	msg, err := p.queue.Get(ctx, b.MessageID)
+	if err != nil {
+		return err
+	}
+	if msg == nil {
+		return nil
+	}
+	to := ""
+	for _, r := range msg.To {
+		if strings.EqualFold(r, b.Recipient) {
+			to = r
+			break
+		}
+	}
+	if to == "" {
+		return nil
+	}
+
+	host := "bounce"
+	if b.ReportingMTA != "" {
+		host += ":" + b.ReportingMTA
+	}
+	msg.RecordAttempt(queue.DeliveryAttempt{
+		Recipient: to,
+		MXHost:    host,
+		Permanent: b.Permanent,
+		Error:     bounceDetail(b),
+	})
+
+	if b.Permanent {
+		result := queue.RecipientResult{Status: queue.StatusFailed, Bounced: true, Error: "bounced: " + bounceDetail(b)}
+		if prev := msg.Results[to]; prev != nil {
+			result.MXHost = prev.MXHost
+		}
+		msg.SetResult(to, result)
+		if msg.Status == queue.StatusDelivered && len(msg.DeliveredRecipients()) == 0 {
+			msg.Status = queue.StatusFailed
+			msg.LastError = result.Error
+		}
+	}
+
+	if err := p.queue.Update(ctx, msg); err != nil {
+		return err
+	}
+	b.Updated = true
+	return nil
+}
+
+// bounceDetail describes a bounce, e.g. "5.1.1 from mx.example.net: 550
+// user unknown"
+func bounceDetail(b *Bounce) string {
+	detail := b.Status
+	if detail == "" {
+		detail = b.Action
+	}
+	if b.ReportingMTA != "" {
+		detail += " from " + b.ReportingMTA
+	}
+	if b.Diagnostic != "" {
+		detail += ": " + b.Diagnostic
+	}
+	return detail
+}
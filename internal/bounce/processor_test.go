@@ -0,0 +1,196 @@
+package bounce
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/foxzi/sendry/internal/queue"
+	"github.com/foxzi/sendry/internal/suppression"
+)
+
+// testDSN returns a DSN with one per-recipient field group
+func testDSN(rcpt, action, status, diagnostic string) []byte {
+	return []byte("From: MAILER-DAEMON@mx.example.net\r\n" +
+		"To: bounce+msg-1=user=example.net@bounces.example.com\r\n" +
+		"Subject: Undelivered Mail Returned to Sender\r\n" +
+		"Content-Type: multipart/report; report-type=delivery-status; boundary=b\r\n" +
+		"\r\n" +
+		"--b\r\n" +
+		"Content-Type: text/plain\r\n" +
+		"\r\n" +
+		"The mail could not be delivered.\r\n" +
+		"--b\r\n" +
+		"Content-Type: message/delivery-status\r\n" +
+		"\r\n" +
+		"Reporting-MTA: dns; mx.example.net\r\n" +
+		"Arrival-Date: Mon, 12 Oct 2026 10:00:00 +0000\r\n" +
+		"\r\n" +
+		"Final-Recipient: rfc822; " + rcpt + "\r\n" +
+		"Action: " + action + "\r\n" +
+		"Status: " + status + "\r\n" +
+		"Diagnostic-Code: smtp; " + diagnostic + "\r\n" +
+		"\r\n" +
+		"--b\r\n" +
+		"Content-Type: text/rfc822-headers\r\n" +
+		"\r\n" +
+		"From: news@example.com\r\n" +
+		"--b--\r\n")
+}
+
+func TestParseDSN(t *testing.T) {
+	dsn, err := ParseDSN(testDSN("user@example.net", "failed", "5.1.1 (bad mailbox)", "550 5.1.1 User unknown"))
+	if err != nil {
+		t.Fatalf("ParseDSN() error = %v", err)
+	}
+	if dsn.ReportingMTA != "mx.example.net" || len(dsn.Recipients) != 1 {
+		t.Fatalf("dsn = %+v", dsn)
+	}
+	rs := dsn.Recipients[0]
+	if rs.FinalRecipient != "user@example.net" || rs.Status != "5.1.1" || rs.DiagnosticCode != "550 5.1.1 User unknown" || !rs.Permanent() {
+		t.Errorf("recipient status = %+v", rs)
+	}
+	if dsn.Recipient("USER@example.net") == nil {
+		t.Error("Recipient() did not find the recipient case-insensitively")
+	}
+
+	for _, data := range []string{
+		"Subject: Out of office\r\n\r\nI am away.\r\n",
+		"Content-Type: multipart/report; report-type=feedback-report; boundary=b\r\n\r\n--b--\r\n",
+	} {
+		if _, err := ParseDSN([]byte(data)); !errors.Is(err, ErrInvalidDSN) {
+			t.Errorf("ParseDSN(%q) error = %v, want ErrInvalidDSN", data, err)
+		}
+	}
+}
+
+func newTestProcessor(t *testing.T) (*Processor, queue.Queue, *suppression.Storage) {
+	t.Helper()
+
+	dir := t.TempDir()
+	q, err := queue.NewBoltStorage(filepath.Join(dir, "queue.db"))
+	if err != nil {
+		t.Fatalf("NewBoltStorage() error = %v", err)
+	}
+	t.Cleanup(func() { q.Close() })
+
+	db, err := bolt.Open(filepath.Join(dir, "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	suppressions, err := suppression.NewStorage(db)
+	if err != nil {
+		t.Fatalf("suppression.NewStorage() error = %v", err)
+	}
+
+	return NewProcessor(q, suppressions, nil), q, suppressions
+}
+
+func enqueueDelivered(t *testing.T, q queue.Queue, to ...string) {
+	t.Helper()
+	msg := &queue.Message{ID: "msg-1", From: "news@example.com", To: to, Data: []byte("Subject: Hi\r\n\r\nHi\r\n")}
+	if err := q.Enqueue(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	msg.Status = queue.StatusDelivered
+	for _, rcpt := range to {
+		msg.SetResult(rcpt, queue.RecipientResult{Status: queue.StatusDelivered, MXHost: "mx.example.net"})
+	}
+	if err := q.Update(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProcessorVERP(t *testing.T) {
+	p, q, suppressions := newTestProcessor(t)
+	ctx := context.Background()
+	enqueueDelivered(t, q, "user@example.net", "other@example.net")
+
+	// The DSN names the address the message was forwarded to, VERP the
+	// recipient it was sent to
+	const verp = "bounce+msg-1=user=example.net@bounces.example.com"
+	bounces, err := p.Process(ctx, verp, testDSN("forwarded@example.org", "failed", "5.1.1", "550 5.1.1 User unknown"))
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if len(bounces) != 1 {
+		t.Fatalf("got %d bounces, want 1", len(bounces))
+	}
+	b := bounces[0]
+	if b.MessageID != "msg-1" || b.Recipient != "user@example.net" || b.Attribution != AttributionVERP || !b.Suppressed || !b.Updated {
+		t.Errorf("bounce = %+v", b)
+	}
+	if ok, _ := suppressions.Suppressed(ctx, "user@example.net"); !ok {
+		t.Error("hard-bounced recipient is not suppressed")
+	}
+
+	msg, _ := q.Get(ctx, "msg-1")
+	r := msg.Results["user@example.net"]
+	if r == nil || r.Status != queue.StatusFailed || !r.Bounced || r.MXHost != "mx.example.net" || !strings.Contains(r.Error, "User unknown") {
+		t.Errorf("result = %+v, want failed by bounce", r)
+	}
+	if msg.Status != queue.StatusDelivered {
+		t.Errorf("status = %s, want delivered while another recipient is delivered", msg.Status)
+	}
+
+	// The last delivered recipient bouncing fails the message
+	if _, err := p.Process(ctx, "bounce+msg-1=other=example.net@bounces.example.com", testDSN("other@example.net", "failed", "5.2.1", "550 Mailbox disabled")); err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if msg, _ = q.Get(ctx, "msg-1"); msg.Status != queue.StatusFailed {
+		t.Errorf("status = %s, want failed", msg.Status)
+	}
+}
+
+func TestProcessorNotSuppressed(t *testing.T) {
+	p, q, suppressions := newTestProcessor(t)
+	ctx := context.Background()
+	enqueueDelivered(t, q, "user@example.net")
+
+	tests := []struct {
+		name, action, status string
+		wantFailed           bool
+	}{
+		{"delayed", "delayed", "4.2.2", false},
+		{"policy rejection", "failed", "5.7.1", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bounces, err := p.Process(ctx, "bounce+msg-1=user=example.net@bounces.example.com", testDSN("user@example.net", tt.action, tt.status, "rejected"))
+			if err != nil || len(bounces) != 1 {
+				t.Fatalf("Process() = %v, %v", bounces, err)
+			}
+			if bounces[0].Suppressed {
+				t.Errorf("bounce %s suppressed the recipient", tt.status)
+			}
+			msg, _ := q.Get(ctx, "msg-1")
+			if failed := msg.Results["user@example.net"].Status == queue.StatusFailed; failed != tt.wantFailed {
+				t.Errorf("recipient failed = %v, want %v", failed, tt.wantFailed)
+			}
+		})
+	}
+	if ok, _ := suppressions.Suppressed(ctx, "user@example.net"); ok {
+		t.Error("recipient suppressed after a delay and a policy rejection")
+	}
+}
+
+func TestProcessorWithoutVERP(t *testing.T) {
+	p, _, suppressions := newTestProcessor(t)
+	ctx := context.Background()
+
+	bounces, err := p.Process(ctx, "bounce@bounces.example.com", testDSN("User@Example.net", "failed", "5.1.1", "550 User unknown"))
+	if err != nil || len(bounces) != 1 {
+		t.Fatalf("Process() = %v, %v", bounces, err)
+	}
+	if b := bounces[0]; b.Attribution != AttributionDSN || b.MessageID != "" || b.Updated {
+		t.Errorf("bounce = %+v", b)
+	}
+	if ok, _ := suppressions.Suppressed(ctx, "user@example.net"); !ok {
+		t.Error("Final-Recipient of the DSN is not suppressed")
+	}
+}
@@ -0,0 +1,48 @@
+package bounce
+
+import (
+	"strings"
+
+	"github.com/foxzi/sendry/internal/email"
+)
+
+// EncodeVERP returns the VERP envelope sender of a message to one
+// recipient: the base address local@host becomes
+// local+msgid=user=domain@host, so a bounce sent to it names the message
+// and the recipient. The recipient domain is encoded in A-label form.
+func EncodeVERP(base, msgID, rcpt string) string {
+	at := strings.LastIndex(base, "@")
+	rat := strings.LastIndex(rcpt, "@")
+	if at <= 0 || rat <= 0 || msgID == "" {
+		return base
+	}
+	user, domain := rcpt[:rat], email.ASCIIDomain(rcpt[rat+1:])
+	return base[:at] + "+" + msgID + "=" + user + "=" + domain + base[at:]
+}
+
+// DecodeVERP decodes a VERP envelope sender of the form
+// local+msgid=user=domain@host into the queue message ID and the recipient
+// user@domain
+func DecodeVERP(addr string) (msgID, rcpt string, ok bool) {
+	at := strings.LastIndex(addr, "@")
+	if at <= 0 {
+		return "", "", false
+	}
+	_, tag, found := strings.Cut(addr[:at], "+")
+	if !found {
+		return "", "", false
+	}
+	msgID, rest, found := strings.Cut(tag, "=")
+	if !found || msgID == "" {
+		return "", "", false
+	}
+	i := strings.LastIndex(rest, "=")
+	if i <= 0 || i == len(rest)-1 {
+		return "", "", false
+	}
+	user, domain := rest[:i], rest[i+1:]
+	if !strings.Contains(domain, ".") {
+		return "", "", false
+	}
+	return msgID, strings.ToLower(user + "@" + domain), true
+}
@@ -0,0 +1,47 @@
+package bounce
+
+import "testing"
+
+func TestEncodeVERP(t *testing.T) {
+	tests := []struct {
+		base, msgID, rcpt string
+		want              string
+	}{
+		{"bounce@bounces.example.com", "42ab", "user@example.net", "bounce+42ab=user=example.net@bounces.example.com"},
+		{"bounce@bounces.example.com", "42ab", "user@пример.рф", "bounce+42ab=user=xn--e1afmkfd.xn--p1ai@bounces.example.com"},
+		{"bounce@bounces.example.com", "", "user@example.net", "bounce@bounces.example.com"},
+		{"bounce@bounces.example.com", "42ab", "invalid", "bounce@bounces.example.com"},
+	}
+	for _, tt := range tests {
+		if got := EncodeVERP(tt.base, tt.msgID, tt.rcpt); got != tt.want {
+			t.Errorf("EncodeVERP(%q, %q, %q) = %q, want %q", tt.base, tt.msgID, tt.rcpt, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeVERP(t *testing.T) {
+	tests := []struct {
+		addr   string
+		msgID  string
+		rcpt   string
+		wantOK bool
+	}{
+		{"bounce+42ab=user=example.net@bounces.example.com", "42ab", "user@example.net", true},
+		{"bounce+42ab=first=last=example.net@bounces.example.com", "42ab", "first=last@example.net", true},
+		{"news@example.com", "", "", false},
+		{"news+tag@example.com", "", "", false},
+		{"bounce+42ab=user=localhost@example.com", "", "", false},
+	}
+	for _, tt := range tests {
+		msgID, rcpt, ok := DecodeVERP(tt.addr)
+		if ok != tt.wantOK || msgID != tt.msgID || rcpt != tt.rcpt {
+			t.Errorf("DecodeVERP(%q) = %q, %q, %v", tt.addr, msgID, rcpt, ok)
+		}
+	}
+
+	// Encoded addresses decode to the message and recipient
+	addr := EncodeVERP("bounce@bounces.example.com", "3f2c-11ef", "John.Doe+tag@Example.net")
+	if msgID, rcpt, ok := DecodeVERP(addr); !ok || msgID != "3f2c-11ef" || rcpt != "john.doe+tag@example.net" {
+		t.Errorf("DecodeVERP(%q) = %q, %q, %v", addr, msgID, rcpt, ok)
+	}
+}
@@ -1,31 +1,81 @@
 package config
 
 import (
+	"context"
+	"encoding/hex"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/foxzi/sendry/internal/email"
 	"github.com/foxzi/sendry/internal/headers"
+	"github.com/foxzi/sendry/internal/retry"
+	"github.com/foxzi/sendry/internal/secrets"
 	"gopkg.in/yaml.v3"
 )
 
 // Config is the main configuration structure
 type Config struct {
-	Server      ServerConfig            `yaml:"server"`
-	SMTP        SMTPConfig              `yaml:"smtp"`
-	API         APIConfig               `yaml:"api"`
-	Queue       QueueConfig             `yaml:"queue"`
-	Storage     StorageConfig           `yaml:"storage"`
-	Logging     LoggingConfig           `yaml:"logging"`
-	DKIM        DKIMConfig              `yaml:"dkim"`         // Legacy single-domain DKIM config
-	Domains     map[string]DomainConfig `yaml:"domains"`      // Multi-domain configuration
-	RateLimit   RateLimitConfig         `yaml:"rate_limit"`   // Rate limiting configuration
-	HeaderRules *headers.Config         `yaml:"header_rules"` // Header manipulation rules
-	Metrics     MetricsConfig           `yaml:"metrics"`      // Prometheus metrics configuration
-	DLQ         DLQConfig               `yaml:"dlq"`          // Dead Letter Queue configuration
+	Server        ServerConfig            `yaml:"server"`
+	SMTP          SMTPConfig              `yaml:"smtp"`
+	API           APIConfig               `yaml:"api"`
+	Queue         QueueConfig             `yaml:"queue"`
+	Storage       StorageConfig           `yaml:"storage"`
+	Logging       LoggingConfig           `yaml:"logging"`
+	DKIM          DKIMConfig              `yaml:"dkim"`           // Legacy single-domain DKIM config
+	Domains       map[string]DomainConfig `yaml:"domains"`        // Multi-domain configuration
+	RateLimit     RateLimitConfig         `yaml:"rate_limit"`     // Rate limiting configuration
+	HeaderRules   *headers.Config         `yaml:"header_rules"`   // Header manipulation rules
+	Metrics       MetricsConfig           `yaml:"metrics"`        // Prometheus metrics configuration
+	DLQ           DLQConfig               `yaml:"dlq"`            // Dead Letter Queue configuration
+	Delivery      DeliveryConfig          `yaml:"delivery"`       // Outbound delivery settings
+	Archive       ArchiveConfig           `yaml:"archive"`        // Searchable archive of delivered messages
+	Sandbox       SandboxConfig           `yaml:"sandbox"`        // Retention of messages captured in sandbox mode
+	Consumer      ConsumerConfig          `yaml:"consumer"`       // Send requests pulled from NATS or Kafka
+	Reputation    ReputationConfig        `yaml:"reputation"`     // Per-domain sending reputation scores
+	Stats         StatsConfig             `yaml:"stats"`          // History of queue sizes and delivery counts
+	DNSMonitor    DNSMonitorConfig        `yaml:"dns_monitor"`    // Scheduled DNS checks of configured domains
+	DNSBLMonitor  DNSBLMonitorConfig      `yaml:"dnsbl_monitor"`  // Scheduled DNSBL checks of outbound IPs
+	FBL           FBLConfig               `yaml:"fbl"`            // Complaint feedback loop reports
+	ContentFilter ContentFilterConfig     `yaml:"content_filter"` // External content filter (HTTP or milter)
+	ContentPolicy ContentPolicyConfig     `yaml:"content_policy"` // Attachment and recipient limits of messages
+	AddressPolicy AddressPolicyConfig     `yaml:"address_policy"` // Allow and deny lists of senders and recipients
+	Replication   ReplicationConfig       `yaml:"replication"`    // Queue replication to a standby node
+	Tracing       TracingConfig           `yaml:"tracing"`        // OpenTelemetry tracing of message delivery
+	Health        HealthConfig            `yaml:"health"`         // Readiness checks for load balancers and orchestrators
+	Secrets       secrets.Config          `yaml:"secrets"`        // Vault and SOPS backends of secret references
 
 	// Internal: path to dynamic domains config file (not in YAML)
 	domainsFile string `yaml:"-"`
+
+	// Internal: path of the file the config was loaded from (not in YAML)
+	path string `yaml:"-"`
+
+	// Internal: digest of the secrets resolved by Load (not in YAML)
+	secretsDigest string `yaml:"-"`
+}
+
+// DeliveryConfig contains outbound delivery settings
+type DeliveryConfig struct {
+	MXDeadTTL        time.Duration `yaml:"mx_dead_ttl"`         // Skip an unreachable MX host for this long (default: 5m)
+	MXFallbackDelay  time.Duration `yaml:"mx_fallback_delay"`   // Head start of an MX before the next one is dialed (default: 3s)
+	MaxAttemptsPerMX int           `yaml:"max_attempts_per_mx"` // Attempts per recipient at one MX host (0 = unlimited)
+	MaxDeliveryTime  time.Duration `yaml:"max_delivery_time"`   // Wall-clock delivery budget of a message (0 = unlimited)
+	Pool             PoolConfig    `yaml:"pool"`                // Reuse of MX connections across messages
+}
+
+// PoolConfig contains outbound connection pool settings
+type PoolConfig struct {
+	Enabled                  bool          `yaml:"enabled"`
+	MaxConnectionsPerMX      int           `yaml:"max_connections_per_mx"`      // Open connections per MX host (0 = unlimited)
+	IdleTimeout              time.Duration `yaml:"idle_timeout"`                // Close idle connections after (default: 30s)
+	MaxMessagesPerConnection int           `yaml:"max_messages_per_connection"` // Messages before a connection is closed (default: 100)
 }
 
 // MetricsConfig contains Prometheus metrics settings
@@ -45,10 +95,199 @@ type DLQConfig struct {
 	CleanupInterval time.Duration `yaml:"cleanup_interval"` // How often to run DLQ cleanup
 }
 
+// ArchiveConfig contains message archive settings
+type ArchiveConfig struct {
+	Enabled         bool          `yaml:"enabled"`          // Archive delivered messages
+	Path            string        `yaml:"path"`             // SQLite database (default: archive.db next to storage.path)
+	MaxAge          time.Duration `yaml:"max_age"`          // Delete archived messages older than this (0 = keep forever)
+	MaxCount        int           `yaml:"max_count"`        // Max archived messages (0 = unlimited)
+	CleanupInterval time.Duration `yaml:"cleanup_interval"` // How often to run archive cleanup (default: 1h)
+}
+
+// SandboxConfig contains retention settings of messages captured in sandbox mode
+type SandboxConfig struct {
+	MaxAge          time.Duration `yaml:"max_age"`          // Delete captured messages older than this (0 = keep forever)
+	MaxCount        int           `yaml:"max_count"`        // Max captured messages (0 = unlimited)
+	CleanupInterval time.Duration `yaml:"cleanup_interval"` // How often to run sandbox cleanup (default: 1h)
+
+	// Modes of API keys and senders, in place of the mode of the sender domain
+	Overrides []SandboxOverride `yaml:"overrides,omitempty"`
+}
+
+// SandboxOverride sets the mode of the messages of an API key or a sender,
+// whatever the mode of their sender domain. An API key override wins over a
+// sender address override, which wins over an @domain override.
+type SandboxOverride struct {
+	APIKey     string   `yaml:"api_key,omitempty"`     // Name or ID of the API key that submitted the message
+	Sender     string   `yaml:"sender,omitempty"`      // Envelope sender address, or @domain for all its senders
+	Mode       string   `yaml:"mode"`                  // production, sandbox, redirect or bcc
+	RedirectTo []string `yaml:"redirect_to,omitempty"` // Required in redirect mode
+	BCCTo      []string `yaml:"bcc_to,omitempty"`      // Required in bcc mode
+}
+
+// Consumer types
+const (
+	ConsumerTypeNATS  = "nats"
+	ConsumerTypeKafka = "kafka"
+)
+
+// ConsumerConfig contains settings for pulling send requests from a broker
+type ConsumerConfig struct {
+	Enabled bool                `yaml:"enabled"`
+	Type    string              `yaml:"type"` // nats or kafka
+	NATS    NATSConsumerConfig  `yaml:"nats"`
+	Kafka   KafkaConsumerConfig `yaml:"kafka"`
+}
+
+// NATSConsumerConfig contains NATS JetStream consumer settings
+type NATSConsumerConfig struct {
+	URL               string        `yaml:"url"`                 // Default: nats://127.0.0.1:4222
+	CredentialsFile   string        `yaml:"credentials_file"`    // Optional .creds file
+	Stream            string        `yaml:"stream"`              // Stream holding the subject
+	Subject           string        `yaml:"subject"`             // Subject of send requests
+	Durable           string        `yaml:"durable"`             // Durable consumer name (default: sendry)
+	DeadLetterSubject string        `yaml:"dead_letter_subject"` // Subject for malformed payloads
+	AckWait           time.Duration `yaml:"ack_wait"`            // Redelivery delay of unacked messages (default: 30s)
+}
+
+// KafkaConsumerConfig contains Kafka consumer group settings
+type KafkaConsumerConfig struct {
+	Brokers         []string `yaml:"brokers"`
+	Topic           string   `yaml:"topic"`             // Topic of send requests
+	GroupID         string   `yaml:"group_id"`          // Consumer group (default: sendry)
+	DeadLetterTopic string   `yaml:"dead_letter_topic"` // Topic for malformed payloads
+}
+
+// ReputationConfig contains sending reputation scoring settings
+type ReputationConfig struct {
+	Enabled   bool          `yaml:"enabled"`   // Track delivery signals and score sender domains
+	Interval  time.Duration `yaml:"interval"`  // How often scores are updated (default: 1h)
+	Window    time.Duration `yaml:"window"`    // Delivery signals that count toward a score (default: 24h)
+	Retention time.Duration `yaml:"retention"` // How long hourly signals and score history are kept (default: 720h)
+	IPs       []string      `yaml:"ips"`       // Sending IPs checked against DNSBLs (default: addresses of server.hostname)
+}
+
+// StatsConfig contains queue statistics history settings
+type StatsConfig struct {
+	Enabled         bool          `yaml:"enabled"`          // Record queue sizes and delivery counts every minute
+	MinuteRetention time.Duration `yaml:"minute_retention"` // How long per-minute points are kept (default: 48h)
+	Retention       time.Duration `yaml:"retention"`        // How long hourly points are kept (default: 2160h)
+}
+
+// DNSMonitorConfig contains scheduled DNS check settings
+type DNSMonitorConfig struct {
+	Enabled   bool          `yaml:"enabled"`   // Check MX, SPF, DKIM and DMARC records of configured domains
+	Interval  time.Duration `yaml:"interval"`  // How often the domains are checked (default: 6h)
+	Retention time.Duration `yaml:"retention"` // How long check results are kept (default: 720h)
+}
+
+// DNSBLMonitorConfig contains scheduled DNSBL check settings
+type DNSBLMonitorConfig struct {
+	Enabled   bool          `yaml:"enabled"`   // Check outbound IPs against DNSBLs
+	Interval  time.Duration `yaml:"interval"`  // How often the IPs are checked (default: 1h)
+	Retention time.Duration `yaml:"retention"` // How long check results are kept (default: 720h)
+	IPs       []string      `yaml:"ips"`       // Outbound IPs, more can be registered via the API (default: reputation.ips)
+}
+
+// TracingConfig contains OpenTelemetry tracing settings
+type TracingConfig struct {
+	Enabled     bool              `yaml:"enabled"`
+	Endpoint    string            `yaml:"endpoint"`     // OTLP/HTTP collector URL, e.g. http://localhost:4318
+	Headers     map[string]string `yaml:"headers"`      // Headers of export requests, e.g. for authentication
+	ServiceName string            `yaml:"service_name"` // service.name of the spans (default: sendry)
+	SampleRatio float64           `yaml:"sample_ratio"` // Share of new traces recorded, 0-1 (default: 1)
+}
+
+// HealthConfig sets the readiness checks of /readyz and the TCP health
+// responder for load balancers
+type HealthConfig struct {
+	ListenAddr      string        `yaml:"listen_addr"`       // TCP responder in HAProxy agent-check format, empty to disable
+	MaxQueueBacklog int64         `yaml:"max_queue_backlog"` // Pending and deferred messages before not ready (0 = no limit)
+	CertMinValidity time.Duration `yaml:"cert_min_validity"` // Not ready when the TLS certificate expires within this (0 = only once expired)
+	CheckTimeout    time.Duration `yaml:"check_timeout"`     // Time limit of each check (default: 2s)
+	DrainDelay      time.Duration `yaml:"drain_delay"`       // Time between reporting draining and stopping the servers on shutdown (default: 0)
+}
+
+// ReplicationConfig contains primary/standby queue replication settings
+type ReplicationConfig struct {
+	Enabled  bool          `yaml:"enabled"`
+	Role     string        `yaml:"role"`     // primary or standby
+	PeerURL  string        `yaml:"peer_url"` // API URL of the standby (primary only)
+	APIKey   string        `yaml:"api_key"`  // API key of the standby (primary only)
+	CAFile   string        `yaml:"ca_file"`  // CA of the standby's API certificate (default: system roots)
+	Interval time.Duration `yaml:"interval"` // Heartbeat and retry interval (default: 10s)
+	Timeout  time.Duration `yaml:"timeout"`  // Timeout of one exchange with the standby (default: 5m)
+}
+
+// Replication roles
+const (
+	ReplicationPrimary = "primary"
+	ReplicationStandby = "standby"
+)
+
+// FBLConfig contains complaint feedback loop settings
+type FBLConfig struct {
+	Enabled bool `yaml:"enabled"` // Accept ARF complaint reports by inbound fbl rules and the API
+}
+
+// Content filter types
+const (
+	ContentFilterHTTP   = "http"
+	ContentFilterMilter = "milter"
+)
+
+// Content filter error policies
+const (
+	ContentFilterOnErrorAccept   = "accept"
+	ContentFilterOnErrorTempfail = "tempfail"
+)
+
+// Content filter message sources
+const (
+	ContentFilterSourceSMTP = "smtp"
+	ContentFilterSourceAPI  = "api"
+)
+
+// ContentFilterConfig contains settings of the external content filter
+// that checks messages before they are queued
+type ContentFilterConfig struct {
+	Enabled bool          `yaml:"enabled"`
+	Type    string        `yaml:"type"`     // http or milter
+	URL     string        `yaml:"url"`      // Endpoint of the http filter
+	Address string        `yaml:"address"`  // Milter socket: unix:/path or inet:host:port
+	Timeout time.Duration `yaml:"timeout"`  // Time limit of a check (default: 30s)
+	OnError string        `yaml:"on_error"` // accept or tempfail (default) when the filter fails
+	Sources []string      `yaml:"sources"`  // smtp, api (default: both)
+}
+
+// ContentPolicyConfig contains limits on messages checked before they are
+// queued. Zero values disable a check.
+type ContentPolicyConfig struct {
+	MaxAttachmentBytes int64    `yaml:"max_attachment_bytes" json:"max_attachment_bytes,omitempty"` // Decoded size of an attachment
+	BannedExtensions   []string `yaml:"banned_extensions" json:"banned_extensions,omitempty"`       // File name extensions, e.g. .exe
+	BannedTypes        []string `yaml:"banned_types" json:"banned_types,omitempty"`                 // MIME types, type/* matches all subtypes
+	MaxRecipients      int      `yaml:"max_recipients" json:"max_recipients,omitempty"`             // Envelope recipients of a message
+	StripBanned        bool     `yaml:"strip_banned" json:"strip_banned,omitempty"`                 // Replace banned parts by a note instead of refusing the message
+}
+
+// AddressPolicyConfig contains allow and deny lists of envelope addresses
+// checked before messages are queued. Entries are addresses, with * and ?
+// wildcards in the local part, domains and *.domains for subdomains. A deny
+// list entry wins over an allow list entry, an empty allow list allows all.
+type AddressPolicyConfig struct {
+	AllowSenders    []string `yaml:"allow_senders" json:"allow_senders,omitempty"`
+	DenySenders     []string `yaml:"deny_senders" json:"deny_senders,omitempty"`
+	AllowRecipients []string `yaml:"allow_recipients" json:"allow_recipients,omitempty"` // Recipient addresses and domains
+	DenyRecipients  []string `yaml:"deny_recipients" json:"deny_recipients,omitempty"`
+}
+
 // RateLimitConfig contains global rate limiting settings
 type RateLimitConfig struct {
 	Enabled bool `yaml:"enabled"`
 
+	// Counting algorithm for all levels: fixed (default) or sliding
+	Algorithm string `yaml:"algorithm,omitempty"`
+
 	// Global limits (for entire server)
 	Global *LimitValues `yaml:"global,omitempty"`
 
@@ -69,12 +308,28 @@ type RateLimitConfig struct {
 
 	// Per-recipient-domain limits (overrides DefaultRecipientDomain)
 	RecipientDomains map[string]*LimitValues `yaml:"recipient_domains,omitempty"`
+
+	// Slow down recipient domains that answer with throttling responses
+	Adaptive *AdaptiveThrottleConfig `yaml:"adaptive,omitempty"`
+}
+
+// AdaptiveThrottleConfig contains adaptive recipient domain throttling
+// settings. Zero values use the defaults of the rate limiter.
+type AdaptiveThrottleConfig struct {
+	Enabled   bool          `yaml:"enabled"`
+	Threshold int           `yaml:"threshold,omitempty"`  // Throttling responses that reduce the rate (default 3)
+	Decrease  float64       `yaml:"decrease,omitempty"`   // Rate multiplier per reduction (default 0.5)
+	MinFactor float64       `yaml:"min_factor,omitempty"` // Lowest share of the rate (default 0.05)
+	Recovery  time.Duration `yaml:"recovery,omitempty"`   // Quiet time after which the rate doubles (default 10m)
+	BaseRate  int           `yaml:"base_rate,omitempty"`  // Messages per minute of domains without limits (default 60)
 }
 
 // LimitValues contains rate limit values
 type LimitValues struct {
-	MessagesPerHour int `yaml:"messages_per_hour"`
-	MessagesPerDay  int `yaml:"messages_per_day"`
+	MessagesPerMinute int    `yaml:"messages_per_minute"`
+	MessagesPerHour   int    `yaml:"messages_per_hour"`
+	MessagesPerDay    int    `yaml:"messages_per_day"`
+	Algorithm         string `yaml:"algorithm,omitempty"` // Overrides rate_limit.algorithm
 }
 
 // DomainConfig contains per-domain settings
@@ -99,8 +354,105 @@ type DomainConfig struct {
 
 	// BCC settings (when mode=bcc)
 	BCCTo []string `yaml:"bcc_to,omitempty"`
+
+	// Hold delivery of messages from or to this domain until unpaused
+	Paused bool `yaml:"paused,omitempty"`
+
+	// Inbound routing rules for mail received for this domain, first match wins
+	Inbound []InboundRule `yaml:"inbound,omitempty"`
+
+	// Content policy of messages from this domain, replaces content_policy
+	ContentPolicy *ContentPolicyConfig `yaml:"content_policy,omitempty"`
+
+	// Address lists of messages from this domain, replaces address_policy
+	AddressPolicy *AddressPolicyConfig `yaml:"address_policy,omitempty"`
+
+	// Delivery of mail to recipients of this domain, MX lookup if not set
+	Delivery *DomainDeliveryConfig `yaml:"delivery,omitempty"`
+
+	// Envelope sender of mail from this domain, the sender address if not set
+	ReturnPath *DomainReturnPathConfig `yaml:"return_path,omitempty"`
+
+	// Retry schedule of deferred deliveries to recipients of this domain,
+	// replaces queue.retry
+	Retry *retry.Config `yaml:"retry,omitempty"`
+
+	// Shorter retention of the messages of this domain
+	Retention *DomainRetentionConfig `yaml:"retention,omitempty"`
+}
+
+// DomainRetentionConfig limits how long messages from a domain are kept.
+// The global limits still apply, so a domain limit can only shorten them.
+type DomainRetentionConfig struct {
+	DeliveredMaxAge time.Duration `yaml:"delivered_max_age,omitempty" json:"delivered_max_age,omitempty"` // Delivered messages in the queue and the archive
+	SandboxMaxAge   time.Duration `yaml:"sandbox_max_age,omitempty" json:"sandbox_max_age,omitempty"`     // Messages captured in sandbox mode
+}
+
+// DomainReturnPathConfig sets the envelope sender (Return-Path) of mail from
+// a domain, usually an address of a bounce domain with an inbound bounce rule
+type DomainReturnPathConfig struct {
+	Address string `yaml:"address" json:"address"`               // e.g. bounce@bounces.example.com
+	VERP    bool   `yaml:"verp,omitempty" json:"verp,omitempty"` // Encode message and recipient: bounce+<id>=<user>=<domain>@bounces.example.com
+}
+
+// DomainDeliveryConfig selects how mail to recipients of a domain is delivered
+type DomainDeliveryConfig struct {
+	Protocol string             `yaml:"protocol" json:"protocol"`                   // smtp (MX lookup, default) or lmtp
+	Address  string             `yaml:"address,omitempty" json:"address,omitempty"` // LMTP server: host:port or unix socket path
+	TLS      *DeliveryTLSConfig `yaml:"tls,omitempty" json:"tls,omitempty"`         // STARTTLS to the LMTP server
+}
+
+// DeliveryTLSConfig contains the STARTTLS settings of an LMTP delivery
+type DeliveryTLSConfig struct {
+	Enabled            bool   `yaml:"enabled" json:"enabled"`                                               // Require STARTTLS
+	ServerName         string `yaml:"server_name,omitempty" json:"server_name,omitempty"`                   // Certificate name, the address host if empty
+	CAFile             string `yaml:"ca_file,omitempty" json:"ca_file,omitempty"`                           // CA certificates, the system pool if empty
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify,omitempty" json:"insecure_skip_verify,omitempty"` // Do not verify the certificate
+}
+
+// Delivery protocols
+const (
+	DeliveryProtocolSMTP = "smtp"
+	DeliveryProtocolLMTP = "lmtp"
+)
+
+// IsLMTP reports whether mail is delivered over LMTP
+func (d *DomainDeliveryConfig) IsLMTP() bool {
+	return d != nil && d.Protocol == DeliveryProtocolLMTP
+}
+
+// Network returns the network and address to dial the LMTP server, a unix
+// socket if the address is a path
+func (d *DomainDeliveryConfig) Network() (network, address string) {
+	if strings.HasPrefix(d.Address, "unix:") {
+		return "unix", strings.TrimPrefix(d.Address, "unix:")
+	}
+	if strings.HasPrefix(d.Address, "/") {
+		return "unix", d.Address
+	}
+	return "tcp", d.Address
+}
+
+// InboundRule routes mail received for matching recipients of a domain
+type InboundRule struct {
+	Recipient string   `yaml:"recipient,omitempty" json:"recipient,omitempty"` // Local part pattern (glob), empty matches all
+	Action    string   `yaml:"action" json:"action"`                           // webhook, maildir, forward, fbl, bounce or lmtp
+	URL       string   `yaml:"url,omitempty" json:"url,omitempty"`             // Webhook endpoint
+	Secret    string   `yaml:"secret,omitempty" json:"secret,omitempty"`       // Webhook HMAC-SHA256 signing secret
+	Path      string   `yaml:"path,omitempty" json:"path,omitempty"`           // Maildir path, may contain {domain} and {user}
+	To        []string `yaml:"to,omitempty" json:"to,omitempty"`               // Forward addresses
 }
 
+// Inbound rule actions
+const (
+	InboundActionWebhook = "webhook"
+	InboundActionMaildir = "maildir"
+	InboundActionForward = "forward"
+	InboundActionFBL     = "fbl"    // Process as an ARF complaint report
+	InboundActionLMTP    = "lmtp"   // Deliver by the LMTP delivery of the domain
+	InboundActionBounce  = "bounce" // Process as a delivery status notification
+)
+
 // DomainDKIMConfig contains DKIM settings for a domain
 type DomainDKIMConfig struct {
 	Enabled  bool   `yaml:"enabled"`
@@ -116,6 +468,7 @@ type DomainTLSConfig struct {
 
 // DomainRateLimitConfig contains rate limit settings for a domain
 type DomainRateLimitConfig struct {
+	MessagesPerMinute    int `yaml:"messages_per_minute"`
 	MessagesPerHour      int `yaml:"messages_per_hour"`
 	MessagesPerDay       int `yaml:"messages_per_day"`
 	RecipientsPerMessage int `yaml:"recipients_per_message"`
@@ -139,6 +492,114 @@ type SMTPConfig struct {
 	Auth            AuthConfig    `yaml:"auth"`
 	TLS             TLSConfig     `yaml:"tls"`
 	AllowedIPs      []string      `yaml:"allowed_ips"` // IP addresses/CIDRs allowed to connect (empty = allow all)
+
+	// Authentication of machine senders by TLS client certificate
+	ClientCerts SMTPClientCertConfig `yaml:"client_certs"`
+
+	// Per client IP throttling, tarpitting and temporary bans
+	IPGuard IPGuardConfig `yaml:"ip_guard"`
+
+	// Client addresses from load balancers in front of the listeners
+	ProxyProtocol SMTPProxyProtocolConfig `yaml:"proxy_protocol"`
+
+	Banner        string               `yaml:"banner"`          // Greeting text after the 220 code (default: "<domain> ESMTP Service Ready")
+	Extensions    SMTPExtensionsConfig `yaml:"extensions"`      // EHLO extensions to advertise
+	MaxLineLength SMTPLineLengthConfig `yaml:"max_line_length"` // Max line length per listener
+
+	// SPF/DKIM/DMARC verification of mail for inbound routes received on
+	// the SMTP port: off (default), tag or reject
+	InboundAuth string `yaml:"inbound_auth"`
+
+	// Refuse internationalized addresses (UTF-8 local parts) on SMTP and API
+	// submission, for setups whose downstream hosts lack SMTPUTF8
+	RejectEAI bool `yaml:"reject_eai"`
+}
+
+// SMTPProxyProtocolConfig accepts the PROXY protocol (v1 and v2) from load
+// balancers such as HAProxy, so that rate limits, allowed_ips and logs use
+// the real client address. Trusted proxies must send the header, other
+// peers connect directly.
+type SMTPProxyProtocolConfig struct {
+	Listeners      []string      `yaml:"listeners"`       // smtp, submission, smtps
+	TrustedProxies []string      `yaml:"trusted_proxies"` // IPs/CIDRs of the proxies
+	HeaderTimeout  time.Duration `yaml:"header_timeout"`  // Time limit for reading the header (default: 5s)
+}
+
+// Enabled reports whether a listener accepts the PROXY protocol
+func (c *SMTPProxyProtocolConfig) Enabled(listener string) bool {
+	return slices.Contains(c.Listeners, listener)
+}
+
+// IPGuardConfig throttles SMTP clients by IP address on all listeners.
+// Clients that keep getting rejected are slowed down, clients that keep
+// trying to relay are banned for a while.
+type IPGuardConfig struct {
+	Enabled        bool          `yaml:"enabled"`
+	MaxConnections int           `yaml:"max_connections"` // Concurrent connections per IP (default: 20)
+	ConnectionRate int           `yaml:"connection_rate"` // New connections per IP per minute (default: 60)
+	CommandRate    int           `yaml:"command_rate"`    // EHLO, AUTH, MAIL, RCPT and DATA per IP per minute (default: 600)
+	TarpitAfter    int           `yaml:"tarpit_after"`    // Rejected commands before replies are delayed (default: 5)
+	TarpitDelay    time.Duration `yaml:"tarpit_delay"`    // Delay of each reply to a tarpitted client (default: 5s)
+	BanAfter       int           `yaml:"ban_after"`       // Relay attempts before the IP is banned (default: 10)
+	Window         time.Duration `yaml:"window"`          // Window for counting rejected commands and relay attempts (default: 10m)
+	BanDuration    time.Duration `yaml:"ban_duration"`    // How long a ban lasts (default: 1h)
+	Exempt         []string      `yaml:"exempt"`          // IPs/CIDRs never throttled, e.g. application servers
+}
+
+// SMTPClientCertConfig maps TLS client certificates to SMTP users. Clients
+// presenting a listed certificate signed by the CA are authenticated without
+// AUTH, other clients may still use AUTH.
+type SMTPClientCertConfig struct {
+	CAFile    string           `yaml:"ca_file"`   // CA bundle that signs client certificates, enables certificate auth
+	Listeners []string         `yaml:"listeners"` // smtp, submission, smtps (default: submission and smtps)
+	Clients   []SMTPCertClient `yaml:"clients"`
+}
+
+// SMTPCertClient is a client certificate, matched by fingerprint or common
+// name, and the user it authenticates as
+type SMTPCertClient struct {
+	CommonName  string   `yaml:"common_name"`
+	Fingerprint string   `yaml:"fingerprint"` // SHA-256 fingerprint, takes precedence over common_name
+	User        string   `yaml:"user"`        // Username recorded in logs and headers (default: common name)
+	Senders     []string `yaml:"senders"`     // Domains, *.domains and addresses the client may send as, empty for any
+}
+
+// Enabled reports whether certificate auth is enabled on a listener
+func (c *SMTPClientCertConfig) Enabled(listener string) bool {
+	if c.CAFile == "" {
+		return false
+	}
+	if len(c.Listeners) == 0 {
+		return listener == "submission" || listener == "smtps"
+	}
+	return slices.Contains(c.Listeners, listener)
+}
+
+// SMTPExtensionsConfig selects the EHLO extensions the listeners advertise
+type SMTPExtensionsConfig struct {
+	Disable8BitMIME bool `yaml:"disable_8bitmime"` // Do not advertise 8BITMIME, reject BODY=8BITMIME
+	DisableChunking bool `yaml:"disable_chunking"` // Do not advertise CHUNKING (BDAT)
+	SMTPUTF8        bool `yaml:"smtputf8"`         // Advertise and accept SMTPUTF8
+	Size            int  `yaml:"size"`             // SIZE value to advertise (0 = max_message_bytes)
+}
+
+// SMTPLineLengthConfig contains the max line length of each listener,
+// commands and message lines included (0 = 2000)
+type SMTPLineLengthConfig struct {
+	SMTP       int `yaml:"smtp"`
+	Submission int `yaml:"submission"`
+	SMTPS      int `yaml:"smtps"`
+}
+
+// For returns the max line length of a listener (smtp, submission or smtps)
+func (c SMTPLineLengthConfig) For(listener string) int {
+	switch listener {
+	case "submission":
+		return c.Submission
+	case "smtps":
+		return c.SMTPS
+	}
+	return c.SMTP
 }
 
 // TLSConfig contains TLS certificate settings
@@ -163,12 +624,26 @@ type DKIMConfig struct {
 	Selector string `yaml:"selector"`
 	KeyFile  string `yaml:"key_file"`
 	Domain   string `yaml:"domain"`
+
+	// Directory of the keys managed by the API and 'sendry dkim', stored as
+	// <keys_dir>/<domain>/<selector>.key (default: /var/lib/sendry/dkim)
+	KeysDir string `yaml:"keys_dir"`
 }
 
 // AuthConfig contains SMTP authentication settings
 type AuthConfig struct {
 	Required bool              `yaml:"required"`
-	Users    map[string]string `yaml:"users"` // username -> password
+	Users    map[string]string `yaml:"users"` // username -> bcrypt or argon2id hash, or plaintext password
+
+	// Domains, *.domains and addresses each user may send as, in MAIL FROM
+	// and the From header. Users without an entry may use any sender.
+	Senders map[string][]string `yaml:"senders"`
+
+	// Check users of the credential store managed with /api/v1/smtp-users
+	Store bool `yaml:"store"`
+
+	// External verification of users unknown to users and the store
+	Hook AuthHookConfig `yaml:"hook"`
 
 	// Brute force protection settings
 	MaxFailures   int           `yaml:"max_failures"`   // Max auth failures before blocking (default: 5)
@@ -176,6 +651,15 @@ type AuthConfig struct {
 	FailureWindow time.Duration `yaml:"failure_window"` // Window for counting failures (default: 5m)
 }
 
+// AuthHookConfig is an HTTP endpoint that verifies SMTP credentials. It gets
+// a JSON body with username, password and client_ip and answers 2xx to
+// accept, 401 or 403 to refuse.
+type AuthHookConfig struct {
+	URL     string            `yaml:"url"`
+	Timeout time.Duration     `yaml:"timeout"` // Time limit of a call (default: 5s)
+	Headers map[string]string `yaml:"headers"` // Sent with every call, e.g. Authorization
+}
+
 // APIConfig contains HTTP API settings
 type APIConfig struct {
 	ListenAddr     string        `yaml:"listen_addr"`
@@ -185,6 +669,29 @@ type APIConfig struct {
 	WriteTimeout   time.Duration `yaml:"write_timeout"`    // HTTP write timeout (default: 30s)
 	IdleTimeout    time.Duration `yaml:"idle_timeout"`     // HTTP idle timeout (default: 60s)
 	AllowedIPs     []string      `yaml:"allowed_ips"`      // IP addresses/CIDRs allowed to access API (empty = allow all)
+	IdempotencyTTL time.Duration `yaml:"idempotency_ttl"`  // How long Idempotency-Key responses are kept (default: 24h)
+	TLS            APITLSConfig  `yaml:"tls"`              // Client certificate (mTLS) settings
+	GRPC           APIGRPCConfig `yaml:"grpc"`             // gRPC API
+
+	// Sign API messages with DKIM when they are queued instead of when
+	// they are delivered, so GET /api/v1/queue/{id} shows the signature
+	DKIMSignOnEnqueue bool `yaml:"dkim_sign_on_enqueue"`
+}
+
+// APITLSConfig contains mutual TLS settings of the HTTP API. The server
+// certificate is the one of smtp.tls.
+type APITLSConfig struct {
+	ClientCAFile        string            `yaml:"client_ca_file"`       // CA bundle that signs client certificates, enables mTLS
+	AllowedFingerprints []string          `yaml:"allowed_fingerprints"` // SHA-256 fingerprints of allowed client certificates (empty = any signed by the CA)
+	Identities          map[string]string `yaml:"identities"`           // Client certificate CN -> identity recorded in audit logs
+}
+
+// APIGRPCConfig contains settings of the gRPC API. It shares the API keys,
+// allowed IPs and TLS settings of the HTTP API.
+type APIGRPCConfig struct {
+	Enabled     bool   `yaml:"enabled"`
+	ListenAddr  string `yaml:"listen_addr"`  // Default: :9091
+	EventBuffer int    `yaml:"event_buffer"` // Events buffered for each WatchEvents stream (default: 1000)
 }
 
 // QueueConfig contains queue processor settings
@@ -193,14 +700,53 @@ type QueueConfig struct {
 	RetryInterval   time.Duration `yaml:"retry_interval"`
 	MaxRetries      int           `yaml:"max_retries"`
 	ProcessInterval time.Duration `yaml:"process_interval"`
+
+	// Deliveries in flight per recipient domain (0 = unlimited)
+	MaxConcurrentConnections int `yaml:"max_concurrent_connections"`
+
+	// max_concurrent_connections overrides by recipient domain
+	DomainConcurrency map[string]int `yaml:"domain_concurrency,omitempty"`
+
+	// Retry schedule of deferred deliveries with overrides per error class,
+	// exponential backoff from retry_interval if not set
+	Retry *retry.Config `yaml:"retry,omitempty"`
+
+	// Delay of the first retry of greylisted deliveries when it is sooner
+	// than the regular one (default 5m)
+	GreylistRetry time.Duration `yaml:"greylist_retry"`
 }
 
 // StorageConfig contains storage settings
 type StorageConfig struct {
 	Path      string           `yaml:"path"`
+	Driver    string           `yaml:"driver"`    // Queue backend: bolt (default) or sqlite
+	DSN       string           `yaml:"dsn"`       // Queue database path for sqlite driver
+	Shards    int              `yaml:"shards"`    // Number of BoltDB queue shards, 0 or 1 disables sharding
+	ShardDir  string           `yaml:"shard_dir"` // Directory of the shard files
 	Retention *RetentionConfig `yaml:"retention"` // Message retention settings
+
+	Encryption StorageEncryptionConfig `yaml:"encryption"` // Encryption of queued message data
+}
+
+// StorageEncryptionConfig contains the encryption of queued message data
+// with AES-256-GCM. Keys are 32 bytes as base64 or hex, e.g. from
+// openssl rand -base64 32.
+type StorageEncryptionConfig struct {
+	Enabled      bool     `yaml:"enabled"`
+	Key          string   `yaml:"key"`           // Current key, new data is encrypted with it
+	KeyFile      string   `yaml:"key_file"`      // File with the current key, instead of key
+	PreviousKeys []string `yaml:"previous_keys"` // Older keys, data encrypted with them is still read
 }
 
+// Storage drivers
+const (
+	StorageDriverBolt   = "bolt"
+	StorageDriverSQLite = "sqlite"
+)
+
+// MaxStorageShards is the largest supported storage.shards value
+const MaxStorageShards = 64
+
 // RetentionConfig contains message retention settings
 type RetentionConfig struct {
 	DeliveredMaxAge time.Duration `yaml:"delivered_max_age"` // Delete delivered messages older than this (0 = keep forever)
@@ -209,11 +755,60 @@ type RetentionConfig struct {
 
 // LoggingConfig contains logging settings
 type LoggingConfig struct {
-	Level  string `yaml:"level"`  // debug, info, warn, error
-	Format string `yaml:"format"` // json, text
+	Level       string            `yaml:"level"`        // debug, info, warn, error
+	Format      string            `yaml:"format"`       // json, text
+	DeliveryLog DeliveryLogConfig `yaml:"delivery_log"` // Structured log of delivery events
+	Redact      LogRedactConfig   `yaml:"redact"`       // Personal data left out of logs
+}
+
+// LogRedactConfig contains the redaction of personal data in the server
+// log and the delivery log
+type LogRedactConfig struct {
+	Addresses bool   `yaml:"addresses"` // Redact email addresses
+	Subjects  bool   `yaml:"subjects"`  // Redact subjects
+	Mode      string `yaml:"mode"`      // Addresses: mask (j***@example.com, default) or hash
+	Salt      string `yaml:"salt"`      // Key of hashed addresses
+}
+
+// Address redaction modes
+const (
+	RedactModeMask = "mask"
+	RedactModeHash = "hash"
+)
+
+// Delivery log events
+const (
+	DeliveryEventAccepted  = "accepted"
+	DeliveryEventDelivered = "delivered"
+	DeliveryEventDeferred  = "deferred"
+	DeliveryEventBounced   = "bounced"
+)
+
+// DeliveryLogConfig contains settings of the delivery event log, one JSON
+// record per message event written to a file and/or syslog
+type DeliveryLogConfig struct {
+	Enabled    bool                    `yaml:"enabled"`
+	File       string                  `yaml:"file"`        // JSONL file, rotated by size
+	MaxSize    int                     `yaml:"max_size"`    // Rotate the file at this size in MB (default: 100)
+	MaxBackups int                     `yaml:"max_backups"` // Rotated files kept (default: 5)
+	Events     []string                `yaml:"events"`      // Logged events (default: all)
+	Syslog     DeliveryLogSyslogConfig `yaml:"syslog"`
+}
+
+// DeliveryLogSyslogConfig contains the syslog output of the delivery log
+type DeliveryLogSyslogConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	Network  string `yaml:"network"`  // udp, tcp or unix, empty for the local syslog
+	Address  string `yaml:"address"`  // host:port or socket path of a remote syslog
+	Tag      string `yaml:"tag"`      // Program name of the records (default: sendry)
+	Facility string `yaml:"facility"` // mail (default), daemon, user or local0-local7
 }
 
-// Load loads configuration from a YAML file
+// SyslogFacilities lists the facilities the delivery log can use
+var SyslogFacilities = []string{"mail", "daemon", "user", "local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7"}
+
+// Load loads configuration from a YAML file, overridden by SENDRY_*
+// environment variables, and resolves its vault: and sops: references
 func Load(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -225,12 +820,21 @@ func Load(path string) (*Config, error) {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
 
+	if err := cfg.applyEnv(); err != nil {
+		return nil, fmt.Errorf("failed to apply environment variables: %w", err)
+	}
+
+	if err := cfg.resolveSecrets(); err != nil {
+		return nil, fmt.Errorf("failed to resolve secrets: %w", err)
+	}
+
 	cfg.setDefaults()
 
 	if err := cfg.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid configuration: %w", err)
 	}
 
+	cfg.path = path
 	return cfg, nil
 }
 
@@ -253,6 +857,9 @@ func (c *Config) setDefaults() {
 	if c.SMTP.TLS.ACME.CacheDir == "" {
 		c.SMTP.TLS.ACME.CacheDir = "/var/lib/sendry/certs"
 	}
+	if c.DKIM.KeysDir == "" {
+		c.DKIM.KeysDir = "/var/lib/sendry/dkim"
+	}
 	if c.SMTP.MaxMessageBytes == 0 {
 		c.SMTP.MaxMessageBytes = 10 * 1024 * 1024 // 10MB
 	}
@@ -292,6 +899,15 @@ func (c *Config) setDefaults() {
 	if c.API.IdleTimeout == 0 {
 		c.API.IdleTimeout = 60 * time.Second
 	}
+	if c.API.IdempotencyTTL == 0 {
+		c.API.IdempotencyTTL = 24 * time.Hour
+	}
+	if c.API.GRPC.ListenAddr == "" {
+		c.API.GRPC.ListenAddr = ":9091"
+	}
+	if c.API.GRPC.EventBuffer == 0 {
+		c.API.GRPC.EventBuffer = 1000
+	}
 
 	if c.Queue.Workers == 0 {
 		c.Queue.Workers = 4
@@ -309,6 +925,15 @@ func (c *Config) setDefaults() {
 	if c.Storage.Path == "" {
 		c.Storage.Path = "/var/lib/sendry/queue.db"
 	}
+	if c.Storage.Driver == "" {
+		c.Storage.Driver = StorageDriverBolt
+	}
+	if c.Storage.DSN == "" {
+		c.Storage.DSN = filepath.Join(filepath.Dir(c.Storage.Path), "queue.sqlite")
+	}
+	if c.Storage.ShardDir == "" {
+		c.Storage.ShardDir = filepath.Join(filepath.Dir(c.Storage.Path), "queue-shards")
+	}
 
 	if c.Logging.Level == "" {
 		c.Logging.Level = "info"
@@ -316,6 +941,18 @@ func (c *Config) setDefaults() {
 	if c.Logging.Format == "" {
 		c.Logging.Format = "json"
 	}
+	if c.Logging.DeliveryLog.MaxSize == 0 {
+		c.Logging.DeliveryLog.MaxSize = 100
+	}
+	if c.Logging.DeliveryLog.MaxBackups == 0 {
+		c.Logging.DeliveryLog.MaxBackups = 5
+	}
+	if c.Logging.DeliveryLog.Syslog.Tag == "" {
+		c.Logging.DeliveryLog.Syslog.Tag = "sendry"
+	}
+	if c.Logging.DeliveryLog.Syslog.Facility == "" {
+		c.Logging.DeliveryLog.Syslog.Facility = "mail"
+	}
 
 	// Metrics defaults
 	if c.Metrics.ListenAddr == "" {
@@ -337,6 +974,112 @@ func (c *Config) setDefaults() {
 		c.DLQ.CleanupInterval = time.Hour
 	}
 
+	// Archive defaults
+	if c.Archive.Path == "" {
+		c.Archive.Path = filepath.Join(filepath.Dir(c.Storage.Path), "archive.db")
+	}
+	if c.Archive.CleanupInterval == 0 {
+		c.Archive.CleanupInterval = time.Hour
+	}
+
+	// Sandbox defaults
+	if c.Sandbox.CleanupInterval == 0 {
+		c.Sandbox.CleanupInterval = time.Hour
+	}
+
+	// Consumer defaults
+	if c.Consumer.NATS.URL == "" {
+		c.Consumer.NATS.URL = "nats://127.0.0.1:4222"
+	}
+	if c.Consumer.NATS.Durable == "" {
+		c.Consumer.NATS.Durable = "sendry"
+	}
+	if c.Consumer.NATS.AckWait == 0 {
+		c.Consumer.NATS.AckWait = 30 * time.Second
+	}
+	if c.Consumer.Kafka.GroupID == "" {
+		c.Consumer.Kafka.GroupID = "sendry"
+	}
+
+	// Reputation defaults
+	if c.Reputation.Interval == 0 {
+		c.Reputation.Interval = time.Hour
+	}
+	if c.Reputation.Window == 0 {
+		c.Reputation.Window = 24 * time.Hour
+	}
+	if c.Reputation.Retention == 0 {
+		c.Reputation.Retention = 30 * 24 * time.Hour
+	}
+
+	// Stats history defaults
+	if c.Stats.MinuteRetention == 0 {
+		c.Stats.MinuteRetention = 48 * time.Hour
+	}
+	if c.Stats.Retention == 0 {
+		c.Stats.Retention = 90 * 24 * time.Hour
+	}
+
+	// DNS monitor defaults
+	if c.DNSMonitor.Interval == 0 {
+		c.DNSMonitor.Interval = 6 * time.Hour
+	}
+	if c.DNSMonitor.Retention == 0 {
+		c.DNSMonitor.Retention = 30 * 24 * time.Hour
+	}
+
+	// DNSBL monitor defaults
+	if c.DNSBLMonitor.Interval == 0 {
+		c.DNSBLMonitor.Interval = time.Hour
+	}
+	if c.DNSBLMonitor.Retention == 0 {
+		c.DNSBLMonitor.Retention = 30 * 24 * time.Hour
+	}
+	if len(c.DNSBLMonitor.IPs) == 0 {
+		c.DNSBLMonitor.IPs = c.Reputation.IPs
+	}
+
+	// Replication defaults
+	if c.Replication.Interval == 0 {
+		c.Replication.Interval = 10 * time.Second
+	}
+	if c.Replication.Timeout == 0 {
+		c.Replication.Timeout = 5 * time.Minute
+	}
+
+	// Tracing defaults
+	if c.Tracing.ServiceName == "" {
+		c.Tracing.ServiceName = "sendry"
+	}
+	if c.Tracing.SampleRatio == 0 {
+		c.Tracing.SampleRatio = 1
+	}
+
+	// Content filter defaults
+	if c.ContentFilter.Timeout == 0 {
+		c.ContentFilter.Timeout = 30 * time.Second
+	}
+	if c.ContentFilter.OnError == "" {
+		c.ContentFilter.OnError = ContentFilterOnErrorTempfail
+	}
+	if len(c.ContentFilter.Sources) == 0 {
+		c.ContentFilter.Sources = []string{ContentFilterSourceSMTP, ContentFilterSourceAPI}
+	}
+
+	// Delivery defaults
+	if c.Delivery.MXDeadTTL == 0 {
+		c.Delivery.MXDeadTTL = 5 * time.Minute
+	}
+	if c.Delivery.MXFallbackDelay == 0 {
+		c.Delivery.MXFallbackDelay = 3 * time.Second
+	}
+	if c.Delivery.Pool.IdleTimeout == 0 {
+		c.Delivery.Pool.IdleTimeout = 30 * time.Second
+	}
+	if c.Delivery.Pool.MaxMessagesPerConnection == 0 {
+		c.Delivery.Pool.MaxMessagesPerConnection = 100
+	}
+
 	// Retention defaults
 	if c.Storage.Retention == nil {
 		c.Storage.Retention = &RetentionConfig{}
@@ -352,8 +1095,26 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("smtp.domain is required")
 	}
 
-	if c.SMTP.Auth.Required && len(c.SMTP.Auth.Users) == 0 {
-		return fmt.Errorf("smtp.auth.users must not be empty when auth is required")
+	if c.SMTP.Auth.Required && len(c.SMTP.Auth.Users) == 0 && !c.SMTP.Auth.Store && c.SMTP.Auth.Hook.URL == "" && c.SMTP.ClientCerts.CAFile == "" {
+		return fmt.Errorf("smtp.auth.users must not be empty when auth is required without store, hook or client certificates")
+	}
+	for user, senders := range c.SMTP.Auth.Senders {
+		if _, ok := c.SMTP.Auth.Users[user]; !ok {
+			return fmt.Errorf("smtp.auth.senders: unknown user %q", user)
+		}
+		if _, err := email.NormalizeSenders(senders); err != nil {
+			return fmt.Errorf("smtp.auth.senders.%s: %w", user, err)
+		}
+	}
+	if hook := c.SMTP.Auth.Hook.URL; hook != "" {
+		u, err := url.Parse(hook)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("smtp.auth.hook.url must be an http or https URL")
+		}
+	}
+
+	if err := c.validateSMTPCapabilities(); err != nil {
+		return err
 	}
 
 	validLogLevels := map[string]bool{"debug": true, "info": true, "warn": true, "error": true}
@@ -365,40 +1126,568 @@ func (c *Config) Validate() error {
 	if !validLogFormats[c.Logging.Format] {
 		return fmt.Errorf("invalid logging.format: %s (must be json or text)", c.Logging.Format)
 	}
-
-	// Validate TLS configuration
-	if err := c.validateTLS(); err != nil {
+	if err := c.validateDeliveryLog(); err != nil {
 		return err
 	}
+	switch c.Logging.Redact.Mode {
+	case "", RedactModeMask, RedactModeHash:
+	default:
+		return fmt.Errorf("invalid logging.redact.mode: %s (must be mask or hash)", c.Logging.Redact.Mode)
+	}
 
-	// Validate DKIM configuration
-	if err := c.validateDKIM(); err != nil {
-		return err
+	switch c.Storage.Driver {
+	case "", StorageDriverBolt, StorageDriverSQLite:
+	case "postgres":
+		return fmt.Errorf("storage.driver postgres is not supported in this build (use bolt or sqlite)")
+	default:
+		return fmt.Errorf("invalid storage.driver: %s (must be bolt or sqlite)", c.Storage.Driver)
+	}
+	if c.Storage.Shards < 0 || c.Storage.Shards > MaxStorageShards {
+		return fmt.Errorf("invalid storage.shards: %d (must be 0-%d)", c.Storage.Shards, MaxStorageShards)
+	}
+	if c.Storage.Shards > 1 && c.Storage.Driver == StorageDriverSQLite {
+		return fmt.Errorf("storage.shards requires the bolt driver")
+	}
+	if enc := c.Storage.Encryption; enc.Enabled && enc.Key == "" && enc.KeyFile == "" {
+		return fmt.Errorf("storage.encryption requires key or key_file")
+	}
+	if enc := c.Storage.Encryption; enc.Key != "" && enc.KeyFile != "" {
+		return fmt.Errorf("storage.encryption.key and key_file are mutually exclusive")
 	}
 
-	// Validate multi-domain configuration
-	if err := c.validateDomains(); err != nil {
+	if c.API.IdempotencyTTL < 0 {
+		return fmt.Errorf("api.idempotency_ttl must not be negative")
+	}
+
+	if err := c.validateAPITLS(); err != nil {
+		return err
+	}
+	if err := c.validateSMTPClientCerts(); err != nil {
+		return err
+	}
+	if err := c.validateIPGuard(); err != nil {
 		return err
 	}
+	if err := c.validateProxyProtocol(); err != nil {
+		return err
+	}
+	if c.Health.MaxQueueBacklog < 0 || c.Health.CertMinValidity < 0 || c.Health.CheckTimeout < 0 || c.Health.DrainDelay < 0 {
+		return fmt.Errorf("health settings must not be negative")
+	}
+	if c.Secrets.RefreshInterval < 0 {
+		return fmt.Errorf("secrets.refresh_interval must not be negative")
+	}
+	if c.API.GRPC.EventBuffer < 0 {
+		return fmt.Errorf("api.grpc.event_buffer must not be negative")
+	}
+	if c.API.GRPC.Enabled && c.API.GRPC.ListenAddr == c.API.ListenAddr {
+		return fmt.Errorf("api.grpc.listen_addr must differ from api.listen_addr")
+	}
 
-	return nil
-}
+	if c.Queue.MaxConcurrentConnections < 0 {
+		return fmt.Errorf("queue.max_concurrent_connections must not be negative")
+	}
+	for domain, limit := range c.Queue.DomainConcurrency {
+		if limit < 0 {
+			return fmt.Errorf("queue.domain_concurrency.%s must not be negative", domain)
+		}
+	}
+	if err := c.Queue.Retry.Validate(); err != nil {
+		return fmt.Errorf("queue.retry.%w", err)
+	}
+	if c.Queue.GreylistRetry < 0 {
+		return fmt.Errorf("queue.greylist_retry must not be negative")
+	}
 
-// validateTLS validates TLS configuration
-func (c *Config) validateTLS() error {
-	tls := c.SMTP.TLS
-	hasCerts := tls.CertFile != "" && tls.KeyFile != ""
-	hasACME := tls.ACME.Enabled
+	if c.Delivery.MaxAttemptsPerMX < 0 {
+		return fmt.Errorf("delivery.max_attempts_per_mx must not be negative")
+	}
+	if c.Delivery.MaxDeliveryTime < 0 {
+		return fmt.Errorf("delivery.max_delivery_time must not be negative")
+	}
+	if c.Delivery.Pool.MaxConnectionsPerMX < 0 {
+		return fmt.Errorf("delivery.pool.max_connections_per_mx must not be negative")
+	}
+	if c.Delivery.Pool.IdleTimeout < 0 {
+		return fmt.Errorf("delivery.pool.idle_timeout must not be negative")
+	}
+	if c.Delivery.Pool.MaxMessagesPerConnection < 0 {
+		return fmt.Errorf("delivery.pool.max_messages_per_connection must not be negative")
+	}
 
-	if hasCerts && hasACME {
-		return fmt.Errorf("cannot use both manual certificates and ACME")
+	if c.Archive.MaxAge < 0 {
+		return fmt.Errorf("archive.max_age must not be negative")
+	}
+	if c.Archive.MaxCount < 0 {
+		return fmt.Errorf("archive.max_count must not be negative")
 	}
 
-	if hasCerts {
-		if tls.CertFile == "" {
-			return fmt.Errorf("smtp.tls.cert_file is required when using manual certificates")
+	if c.Sandbox.MaxAge < 0 {
+		return fmt.Errorf("sandbox.max_age must not be negative")
+	}
+	if c.Sandbox.MaxCount < 0 {
+		return fmt.Errorf("sandbox.max_count must not be negative")
+	}
+	for i, o := range c.Sandbox.Overrides {
+		if (o.APIKey == "") == (o.Sender == "") {
+			return fmt.Errorf("sandbox.overrides[%d] requires either api_key or sender", i)
 		}
-		if tls.KeyFile == "" {
+		if o.Sender != "" && !strings.Contains(o.Sender, "@") {
+			return fmt.Errorf("sandbox.overrides[%d].sender must be an address or @domain", i)
+		}
+		switch o.Mode {
+		case "production", "sandbox":
+		case "redirect":
+			if len(o.RedirectTo) == 0 {
+				return fmt.Errorf("sandbox.overrides[%d].redirect_to is required when mode is redirect", i)
+			}
+		case "bcc":
+			if len(o.BCCTo) == 0 {
+				return fmt.Errorf("sandbox.overrides[%d].bcc_to is required when mode is bcc", i)
+			}
+		default:
+			return fmt.Errorf("sandbox.overrides[%d].mode must be one of: production, sandbox, redirect, bcc", i)
+		}
+	}
+
+	if err := c.HeaderRules.Validate(); err != nil {
+		return fmt.Errorf("header_rules.%w", err)
+	}
+
+	if err := c.validateConsumer(); err != nil {
+		return err
+	}
+
+	if err := c.validateReputation(); err != nil {
+		return err
+	}
+
+	if err := c.validateStats(); err != nil {
+		return err
+	}
+
+	if c.DNSMonitor.Interval < 0 || c.DNSMonitor.Retention < 0 {
+		return fmt.Errorf("dns_monitor.interval and retention must not be negative")
+	}
+
+	if c.DNSBLMonitor.Interval < 0 || c.DNSBLMonitor.Retention < 0 {
+		return fmt.Errorf("dnsbl_monitor.interval and retention must not be negative")
+	}
+	for _, ip := range c.DNSBLMonitor.IPs {
+		if parsed := net.ParseIP(ip); parsed == nil || parsed.To4() == nil {
+			return fmt.Errorf("dnsbl_monitor.ips: %q is not an IPv4 address", ip)
+		}
+	}
+
+	if err := c.validateReplication(); err != nil {
+		return err
+	}
+
+	if err := c.validateTracing(); err != nil {
+		return err
+	}
+
+	if err := c.validateContentFilter(); err != nil {
+		return err
+	}
+
+	if err := ValidateContentPolicy("content_policy", &c.ContentPolicy); err != nil {
+		return err
+	}
+	if err := ValidateAddressPolicy("address_policy", &c.AddressPolicy); err != nil {
+		return err
+	}
+
+	// Validate TLS configuration
+	if err := c.validateTLS(); err != nil {
+		return err
+	}
+
+	// Validate DKIM configuration
+	if err := c.validateDKIM(); err != nil {
+		return err
+	}
+
+	// Validate multi-domain configuration
+	if err := c.validateDomains(); err != nil {
+		return err
+	}
+
+	// Validate rate limit configuration
+	if err := c.validateRateLimits(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// validateReputation validates the sending reputation settings
+func (c *Config) validateReputation() error {
+	if c.Reputation.Interval < 0 || c.Reputation.Window < 0 || c.Reputation.Retention < 0 {
+		return fmt.Errorf("reputation.interval, window and retention must not be negative")
+	}
+	if c.Reputation.Retention > 0 && c.Reputation.Retention < c.Reputation.Window {
+		return fmt.Errorf("reputation.retention must not be shorter than reputation.window")
+	}
+	for _, ip := range c.Reputation.IPs {
+		if parsed := net.ParseIP(ip); parsed == nil || parsed.To4() == nil {
+			return fmt.Errorf("reputation.ips: %q is not an IPv4 address", ip)
+		}
+	}
+	return nil
+}
+
+// validateStats validates the statistics history settings
+func (c *Config) validateStats() error {
+	if c.Stats.MinuteRetention < 0 || c.Stats.Retention < 0 {
+		return fmt.Errorf("stats.minute_retention and retention must not be negative")
+	}
+	if c.Stats.Retention > 0 && c.Stats.Retention < c.Stats.MinuteRetention {
+		return fmt.Errorf("stats.retention must not be shorter than stats.minute_retention")
+	}
+	return nil
+}
+
+// validateReplication validates the queue replication settings
+func (c *Config) validateReplication() error {
+	r := c.Replication
+	if !r.Enabled {
+		return nil
+	}
+
+	switch r.Role {
+	case ReplicationPrimary:
+		if !strings.HasPrefix(r.PeerURL, "http://") && !strings.HasPrefix(r.PeerURL, "https://") {
+			return fmt.Errorf("replication.peer_url must be an http or https URL")
+		}
+	case ReplicationStandby:
+	default:
+		return fmt.Errorf("invalid replication.role: %s (must be primary or standby)", r.Role)
+	}
+	if r.Interval < 0 || r.Timeout < 0 {
+		return fmt.Errorf("replication.interval and timeout must not be negative")
+	}
+	return nil
+}
+
+// validateTracing validates the OpenTelemetry tracing settings
+func (c *Config) validateTracing() error {
+	t := c.Tracing
+	if !t.Enabled {
+		return nil
+	}
+	u, err := url.Parse(t.Endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("tracing.endpoint must be an http or https URL")
+	}
+	if t.SampleRatio < 0 || t.SampleRatio > 1 {
+		return fmt.Errorf("tracing.sample_ratio must be between 0 and 1")
+	}
+	return nil
+}
+
+// validateContentFilter validates the external content filter settings
+func (c *Config) validateContentFilter() error {
+	f := c.ContentFilter
+	if !f.Enabled {
+		return nil
+	}
+
+	switch f.Type {
+	case ContentFilterHTTP:
+		if !strings.HasPrefix(f.URL, "http://") && !strings.HasPrefix(f.URL, "https://") {
+			return fmt.Errorf("content_filter.url must be an http or https URL")
+		}
+	case ContentFilterMilter:
+		if f.Address == "" {
+			return fmt.Errorf("content_filter.address is required for the milter filter")
+		}
+	default:
+		return fmt.Errorf("invalid content_filter.type: %q (must be http or milter)", f.Type)
+	}
+
+	if f.Timeout < 0 {
+		return fmt.Errorf("content_filter.timeout must not be negative")
+	}
+	switch f.OnError {
+	case "", ContentFilterOnErrorAccept, ContentFilterOnErrorTempfail:
+	default:
+		return fmt.Errorf("invalid content_filter.on_error: %q (must be accept or tempfail)", f.OnError)
+	}
+	for _, source := range f.Sources {
+		if source != ContentFilterSourceSMTP && source != ContentFilterSourceAPI {
+			return fmt.Errorf("invalid content_filter.sources: %q (must be smtp or api)", source)
+		}
+	}
+	return nil
+}
+
+// ValidateContentPolicy validates a content policy. prefix is the config
+// path of the policy used in error messages.
+func ValidateContentPolicy(prefix string, p *ContentPolicyConfig) error {
+	if p == nil {
+		return nil
+	}
+	if p.MaxAttachmentBytes < 0 || p.MaxRecipients < 0 {
+		return fmt.Errorf("%s.max_attachment_bytes and max_recipients must not be negative", prefix)
+	}
+	for _, ext := range p.BannedExtensions {
+		if strings.Trim(ext, ". ") == "" {
+			return fmt.Errorf("%s.banned_extensions: %q is not a file name extension", prefix, ext)
+		}
+	}
+	for _, t := range p.BannedTypes {
+		if major, minor, ok := strings.Cut(t, "/"); !ok || major == "" || minor == "" {
+			return fmt.Errorf("%s.banned_types: %q is not a MIME type", prefix, t)
+		}
+	}
+	return nil
+}
+
+// ValidateAddressPolicy validates address lists. prefix is the config path
+// of the lists used in error messages.
+func ValidateAddressPolicy(prefix string, p *AddressPolicyConfig) error {
+	if p == nil {
+		return nil
+	}
+	lists := []struct {
+		name    string
+		entries []string
+	}{
+		{"allow_senders", p.AllowSenders},
+		{"deny_senders", p.DenySenders},
+		{"allow_recipients", p.AllowRecipients},
+		{"deny_recipients", p.DenyRecipients},
+	}
+	for _, l := range lists {
+		for _, entry := range l.entries {
+			if strings.TrimSpace(entry) == "" {
+				return fmt.Errorf("%s.%s: entries must not be empty", prefix, l.name)
+			}
+			if _, err := email.NormalizeSenders([]string{entry}); err != nil {
+				return fmt.Errorf("%s.%s: %q is not an address, a domain or *.domain", prefix, l.name, entry)
+			}
+			if at := strings.LastIndex(entry, "@"); at > 0 {
+				if _, err := path.Match(entry[:at], ""); err != nil {
+					return fmt.Errorf("%s.%s: %q has an invalid wildcard", prefix, l.name, entry)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// validateAPITLS validates the mutual TLS settings of the API
+func (c *Config) validateAPITLS() error {
+	t := c.API.TLS
+	if t.ClientCAFile == "" {
+		if len(t.AllowedFingerprints) > 0 || len(t.Identities) > 0 {
+			return fmt.Errorf("api.tls.client_ca_file is required for allowed_fingerprints and identities")
+		}
+		return nil
+	}
+	hasCerts := c.SMTP.TLS.CertFile != "" && c.SMTP.TLS.KeyFile != ""
+	if !hasCerts && !c.SMTP.TLS.ACME.Enabled {
+		return fmt.Errorf("api.tls.client_ca_file requires a server certificate in smtp.tls")
+	}
+	for _, fp := range t.AllowedFingerprints {
+		if !validFingerprint(fp) {
+			return fmt.Errorf("api.tls.allowed_fingerprints: %q is not a SHA-256 fingerprint", fp)
+		}
+	}
+	for cn, identity := range t.Identities {
+		if cn == "" || strings.TrimSpace(identity) == "" {
+			return fmt.Errorf("api.tls.identities: common name and identity must not be empty")
+		}
+	}
+	return nil
+}
+
+// validateIPGuard validates the SMTP client throttling settings
+func (c *Config) validateIPGuard() error {
+	g := c.SMTP.IPGuard
+	if g.MaxConnections < 0 || g.ConnectionRate < 0 || g.CommandRate < 0 || g.TarpitAfter < 0 || g.BanAfter < 0 {
+		return fmt.Errorf("smtp.ip_guard limits must not be negative")
+	}
+	if g.TarpitDelay < 0 || g.Window < 0 || g.BanDuration < 0 {
+		return fmt.Errorf("smtp.ip_guard durations must not be negative")
+	}
+	if g.TarpitDelay > time.Minute {
+		return fmt.Errorf("smtp.ip_guard.tarpit_delay must not exceed 1m")
+	}
+	for _, entry := range g.Exempt {
+		if !validIPOrCIDR(entry) {
+			return fmt.Errorf("smtp.ip_guard.exempt: invalid IP or CIDR %q", entry)
+		}
+	}
+	return nil
+}
+
+// validateProxyProtocol validates the PROXY protocol settings
+func (c *Config) validateProxyProtocol() error {
+	pp := c.SMTP.ProxyProtocol
+	for _, l := range pp.Listeners {
+		if l != "smtp" && l != "submission" && l != "smtps" {
+			return fmt.Errorf("smtp.proxy_protocol.listeners: unknown listener %q", l)
+		}
+	}
+	if len(pp.Listeners) > 0 && len(pp.TrustedProxies) == 0 {
+		return fmt.Errorf("smtp.proxy_protocol.trusted_proxies is required with listeners")
+	}
+	for _, entry := range pp.TrustedProxies {
+		if !validIPOrCIDR(entry) {
+			return fmt.Errorf("smtp.proxy_protocol.trusted_proxies: invalid IP or CIDR %q", entry)
+		}
+	}
+	if pp.HeaderTimeout < 0 {
+		return fmt.Errorf("smtp.proxy_protocol.header_timeout must not be negative")
+	}
+	return nil
+}
+
+// validateSMTPClientCerts validates the client certificate auth settings
+func (c *Config) validateSMTPClientCerts() error {
+	cc := c.SMTP.ClientCerts
+	if cc.CAFile == "" {
+		if len(cc.Clients) > 0 || len(cc.Listeners) > 0 {
+			return fmt.Errorf("smtp.client_certs.ca_file is required for listeners and clients")
+		}
+		return nil
+	}
+	hasCerts := c.SMTP.TLS.CertFile != "" && c.SMTP.TLS.KeyFile != ""
+	if !hasCerts && !c.SMTP.TLS.ACME.Enabled {
+		return fmt.Errorf("smtp.client_certs.ca_file requires a server certificate in smtp.tls")
+	}
+	for _, l := range cc.Listeners {
+		if l != "smtp" && l != "submission" && l != "smtps" {
+			return fmt.Errorf("smtp.client_certs.listeners: unknown listener %q", l)
+		}
+	}
+	if len(cc.Clients) == 0 {
+		return fmt.Errorf("smtp.client_certs.clients must not be empty")
+	}
+	for i, client := range cc.Clients {
+		if client.CommonName == "" && client.Fingerprint == "" {
+			return fmt.Errorf("smtp.client_certs.clients[%d]: common_name or fingerprint is required", i)
+		}
+		if client.Fingerprint != "" && !validFingerprint(client.Fingerprint) {
+			return fmt.Errorf("smtp.client_certs.clients[%d]: %q is not a SHA-256 fingerprint", i, client.Fingerprint)
+		}
+		if client.CommonName == "" && client.User == "" {
+			return fmt.Errorf("smtp.client_certs.clients[%d]: user is required with a fingerprint only", i)
+		}
+		if _, err := email.NormalizeSenders(client.Senders); err != nil {
+			return fmt.Errorf("smtp.client_certs.clients[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// validFingerprint reports whether fp is a hex SHA-256 digest, optionally
+// with colon separators
+func validFingerprint(fp string) bool {
+	fp = strings.ReplaceAll(fp, ":", "")
+	if len(fp) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(fp)
+	return err == nil
+}
+
+// validIPOrCIDR reports whether s is an IP address or a CIDR network
+func validIPOrCIDR(s string) bool {
+	if strings.Contains(s, "/") {
+		_, _, err := net.ParseCIDR(s)
+		return err == nil
+	}
+	return net.ParseIP(s) != nil
+}
+
+// validateConsumer validates the broker consumer settings
+func (c *Config) validateConsumer() error {
+	if !c.Consumer.Enabled {
+		return nil
+	}
+
+	switch c.Consumer.Type {
+	case ConsumerTypeNATS:
+		n := c.Consumer.NATS
+		if n.Stream == "" || n.Subject == "" {
+			return fmt.Errorf("consumer.nats.stream and consumer.nats.subject are required")
+		}
+		if n.DeadLetterSubject == "" {
+			return fmt.Errorf("consumer.nats.dead_letter_subject is required")
+		}
+		if n.DeadLetterSubject == n.Subject {
+			return fmt.Errorf("consumer.nats.dead_letter_subject must differ from consumer.nats.subject")
+		}
+		if n.AckWait < 0 {
+			return fmt.Errorf("consumer.nats.ack_wait must not be negative")
+		}
+	case ConsumerTypeKafka:
+		k := c.Consumer.Kafka
+		if len(k.Brokers) == 0 || k.Topic == "" {
+			return fmt.Errorf("consumer.kafka.brokers and consumer.kafka.topic are required")
+		}
+		if k.DeadLetterTopic == "" {
+			return fmt.Errorf("consumer.kafka.dead_letter_topic is required")
+		}
+		if k.DeadLetterTopic == k.Topic {
+			return fmt.Errorf("consumer.kafka.dead_letter_topic must differ from consumer.kafka.topic")
+		}
+	default:
+		return fmt.Errorf("invalid consumer.type: %q (must be nats or kafka)", c.Consumer.Type)
+	}
+	return nil
+}
+
+// validateSMTPCapabilities validates the banner, extensions and line lengths
+func (c *Config) validateSMTPCapabilities() error {
+	if strings.ContainsAny(c.SMTP.Banner, "\r\n") {
+		return fmt.Errorf("smtp.banner must be a single line")
+	}
+
+	ext := c.SMTP.Extensions
+	if ext.SMTPUTF8 && c.SMTP.RejectEAI {
+		return fmt.Errorf("smtp.extensions.smtputf8 cannot be combined with smtp.reject_eai")
+	}
+	if ext.Size < 0 {
+		return fmt.Errorf("smtp.extensions.size must not be negative")
+	}
+	if c.SMTP.MaxMessageBytes > 0 && ext.Size > c.SMTP.MaxMessageBytes {
+		return fmt.Errorf("smtp.extensions.size must not exceed smtp.max_message_bytes (%d)", c.SMTP.MaxMessageBytes)
+	}
+
+	for _, listener := range []string{"smtp", "submission", "smtps"} {
+		n := c.SMTP.MaxLineLength.For(listener)
+		if n != 0 && (n < 1000 || n > 1024*1024) {
+			return fmt.Errorf("smtp.max_line_length.%s must be between 1000 and 1048576", listener)
+		}
+	}
+
+	switch c.SMTP.InboundAuth {
+	case "", "off", "tag", "reject":
+	default:
+		return fmt.Errorf("invalid smtp.inbound_auth: %s (must be off, tag or reject)", c.SMTP.InboundAuth)
+	}
+	return nil
+}
+
+// validateTLS validates TLS configuration
+func (c *Config) validateTLS() error {
+	tls := c.SMTP.TLS
+	hasCerts := tls.CertFile != "" && tls.KeyFile != ""
+	hasACME := tls.ACME.Enabled
+
+	if hasCerts && hasACME {
+		return fmt.Errorf("cannot use both manual certificates and ACME")
+	}
+
+	if hasCerts {
+		if tls.CertFile == "" {
+			return fmt.Errorf("smtp.tls.cert_file is required when using manual certificates")
+		}
+		if tls.KeyFile == "" {
 			return fmt.Errorf("smtp.tls.key_file is required when using manual certificates")
 		}
 	}
@@ -434,9 +1723,11 @@ func (c *Config) validateDKIM() error {
 	return nil
 }
 
-// HasTLS returns true if TLS is configured
+// HasTLS returns true if TLS is configured, also when only domains have
+// certificates
 func (c *Config) HasTLS() bool {
-	return (c.SMTP.TLS.CertFile != "" && c.SMTP.TLS.KeyFile != "") || c.SMTP.TLS.ACME.Enabled
+	return (c.SMTP.TLS.CertFile != "" && c.SMTP.TLS.KeyFile != "") || c.SMTP.TLS.ACME.Enabled ||
+		len(c.DomainCertificates()) > 0
 }
 
 // GetDomainConfig returns the configuration for a specific domain
@@ -450,6 +1741,19 @@ func (c *Config) GetDomainConfig(domain string) *DomainConfig {
 	return nil
 }
 
+// DomainCertificates returns the sorted domains that have their own TLS
+// certificate
+func (c *Config) DomainCertificates() []string {
+	var domains []string
+	for domain, dc := range c.Domains {
+		if dc.TLS != nil && dc.TLS.CertFile != "" && dc.TLS.KeyFile != "" {
+			domains = append(domains, domain)
+		}
+	}
+	slices.Sort(domains)
+	return domains
+}
+
 // GetDKIMConfig returns DKIM config for a domain
 // First checks multi-domain config, then falls back to legacy config
 func (c *Config) GetDKIMConfig(domain string) (enabled bool, selector, keyFile string) {
@@ -492,6 +1796,127 @@ func (c *Config) GetAllDomains() []string {
 	return result
 }
 
+// PausedDomains returns the domains whose delivery is paused
+func (c *Config) PausedDomains() []string {
+	var domains []string
+	for domain, dc := range c.Domains {
+		if dc.Paused {
+			domains = append(domains, domain)
+		}
+	}
+	return domains
+}
+
+// DeliveredRetention returns the max ages of delivered messages of domains
+// with a retention limit
+func (c *Config) DeliveredRetention() map[string]time.Duration {
+	ages := make(map[string]time.Duration)
+	for domain, dc := range c.Domains {
+		if dc.Retention != nil && dc.Retention.DeliveredMaxAge > 0 {
+			ages[domain] = dc.Retention.DeliveredMaxAge
+		}
+	}
+	return ages
+}
+
+// SandboxRetention returns the max ages of sandbox messages of domains with
+// a retention limit
+func (c *Config) SandboxRetention() map[string]time.Duration {
+	ages := make(map[string]time.Duration)
+	for domain, dc := range c.Domains {
+		if dc.Retention != nil && dc.Retention.SandboxMaxAge > 0 {
+			ages[domain] = dc.Retention.SandboxMaxAge
+		}
+	}
+	return ages
+}
+
+// ValidateInboundRules validates inbound routing rules, prefix names the
+// rules in error messages
+func ValidateInboundRules(prefix string, rules []InboundRule) error {
+	for i, rule := range rules {
+		name := fmt.Sprintf("%s[%d]", prefix, i)
+		if _, err := path.Match(rule.Recipient, ""); err != nil {
+			return fmt.Errorf("%s.recipient is not a valid pattern: %s", name, rule.Recipient)
+		}
+
+		switch rule.Action {
+		case InboundActionWebhook:
+			u, err := url.Parse(rule.URL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("%s.url must be an http or https URL", name)
+			}
+		case InboundActionMaildir:
+			if rule.Path == "" {
+				return fmt.Errorf("%s.path is required for maildir", name)
+			}
+		case InboundActionForward:
+			if len(rule.To) == 0 {
+				return fmt.Errorf("%s.to is required for forward", name)
+			}
+		case InboundActionFBL, InboundActionBounce, InboundActionLMTP:
+		default:
+			return fmt.Errorf("%s.action must be one of: webhook, maildir, forward, fbl, bounce, lmtp", name)
+		}
+	}
+	return nil
+}
+
+// ValidateReturnPath validates the return path of a domain, prefix names
+// the settings in error messages
+func ValidateReturnPath(prefix string, rp *DomainReturnPathConfig) error {
+	if rp == nil {
+		return nil
+	}
+	at := strings.LastIndex(rp.Address, "@")
+	if at <= 0 || at == len(rp.Address)-1 || strings.ContainsAny(rp.Address, " <>,\t\r\n") {
+		return fmt.Errorf("%s.address must be an email address: %q", prefix, rp.Address)
+	}
+	if rp.VERP && strings.ContainsAny(rp.Address[:at], "+=") {
+		return fmt.Errorf("%s.address must not contain + or = in the local part with verp", prefix)
+	}
+	return nil
+}
+
+// ValidateDelivery validates the delivery settings of a domain and that its
+// inbound lmtp rules have an LMTP delivery, prefix names the settings in
+// error messages
+func ValidateDelivery(prefix string, d *DomainDeliveryConfig, inbound []InboundRule) error {
+	if !d.IsLMTP() {
+		for _, rule := range inbound {
+			if rule.Action == InboundActionLMTP {
+				return fmt.Errorf("%s.protocol must be lmtp for inbound lmtp rules", prefix)
+			}
+		}
+	}
+	if d == nil {
+		return nil
+	}
+
+	switch d.Protocol {
+	case "", DeliveryProtocolSMTP:
+		if d.Address != "" || d.TLS != nil {
+			return fmt.Errorf("%s.address and tls apply to lmtp only", prefix)
+		}
+	case DeliveryProtocolLMTP:
+		network, address := d.Network()
+		if address == "" {
+			return fmt.Errorf("%s.address is required for lmtp", prefix)
+		}
+		if network == "tcp" {
+			if _, _, err := net.SplitHostPort(address); err != nil {
+				return fmt.Errorf("%s.address must be host:port or a unix socket path: %s", prefix, d.Address)
+			}
+		}
+		if d.TLS != nil && !d.TLS.Enabled && (d.TLS.CAFile != "" || d.TLS.ServerName != "" || d.TLS.InsecureSkipVerify) {
+			return fmt.Errorf("%s.tls settings require tls.enabled", prefix)
+		}
+	default:
+		return fmt.Errorf("%s.protocol must be smtp or lmtp", prefix)
+	}
+	return nil
+}
+
 // validateDomains validates multi-domain configuration
 func (c *Config) validateDomains() error {
 	for domain, dc := range c.Domains {
@@ -530,11 +1955,118 @@ func (c *Config) validateDomains() error {
 				return fmt.Errorf("domains.%s.bcc_to is required when mode is bcc", domain)
 			}
 		}
+
+		if err := ValidateInboundRules("domains."+domain+".inbound", dc.Inbound); err != nil {
+			return err
+		}
+		if err := ValidateContentPolicy("domains."+domain+".content_policy", dc.ContentPolicy); err != nil {
+			return err
+		}
+		if err := ValidateAddressPolicy("domains."+domain+".address_policy", dc.AddressPolicy); err != nil {
+			return err
+		}
+		if err := ValidateDelivery("domains."+domain+".delivery", dc.Delivery, dc.Inbound); err != nil {
+			return err
+		}
+		if err := ValidateReturnPath("domains."+domain+".return_path", dc.ReturnPath); err != nil {
+			return err
+		}
+		if err := dc.Retry.Validate(); err != nil {
+			return fmt.Errorf("domains.%s.retry.%w", domain, err)
+		}
+		if r := dc.Retention; r != nil && (r.DeliveredMaxAge < 0 || r.SandboxMaxAge < 0) {
+			return fmt.Errorf("domains.%s.retention max ages cannot be negative", domain)
+		}
+
+		// Validate rate limits
+		if dc.RateLimit != nil {
+			rl := dc.RateLimit
+			if err := ValidateLimitWindows("domains."+domain+".rate_limit", rl.MessagesPerMinute, rl.MessagesPerHour, rl.MessagesPerDay); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateRateLimits validates rate limit algorithms and window consistency
+func (c *Config) validateRateLimits() error {
+	rl := c.RateLimit
+	if err := validateRateLimitAlgorithm("rate_limit.algorithm", rl.Algorithm); err != nil {
+		return err
+	}
+
+	levels := map[string]*LimitValues{
+		"global":                   rl.Global,
+		"default_domain":           rl.DefaultDomain,
+		"default_sender":           rl.DefaultSender,
+		"default_ip":               rl.DefaultIP,
+		"default_api_key":          rl.DefaultAPIKey,
+		"default_recipient_domain": rl.DefaultRecipientDomain,
+	}
+	for domain, lv := range rl.RecipientDomains {
+		levels["recipient_domains."+domain] = lv
+	}
+
+	for name, lv := range levels {
+		if lv == nil {
+			continue
+		}
+		path := "rate_limit." + name
+		if err := validateRateLimitAlgorithm(path+".algorithm", lv.Algorithm); err != nil {
+			return err
+		}
+		if err := ValidateLimitWindows(path, lv.MessagesPerMinute, lv.MessagesPerHour, lv.MessagesPerDay); err != nil {
+			return err
+		}
+	}
+
+	if a := rl.Adaptive; a != nil {
+		switch {
+		case a.Threshold < 0:
+			return fmt.Errorf("rate_limit.adaptive.threshold must not be negative")
+		case a.Decrease < 0 || a.Decrease >= 1:
+			return fmt.Errorf("rate_limit.adaptive.decrease must be between 0 and 1")
+		case a.MinFactor < 0 || a.MinFactor > 1:
+			return fmt.Errorf("rate_limit.adaptive.min_factor must be between 0 and 1")
+		case a.Recovery < 0:
+			return fmt.Errorf("rate_limit.adaptive.recovery must not be negative")
+		case a.BaseRate < 0:
+			return fmt.Errorf("rate_limit.adaptive.base_rate must not be negative")
+		}
 	}
 
 	return nil
 }
 
+// validateRateLimitAlgorithm checks a rate limit algorithm name
+func validateRateLimitAlgorithm(path, algorithm string) error {
+	switch algorithm {
+	case "", "fixed", "sliding":
+		return nil
+	}
+	return fmt.Errorf("invalid %s: %s (must be fixed or sliding)", path, algorithm)
+}
+
+// ValidateLimitWindows checks that limits are not negative and that a
+// smaller window never allows more than a larger one. Zero means unlimited.
+func ValidateLimitWindows(path string, perMinute, perHour, perDay int) error {
+	if perMinute < 0 || perHour < 0 || perDay < 0 {
+		return fmt.Errorf("%s: limits must not be negative", path)
+	}
+	if perMinute > 0 && perHour > 0 && perMinute > perHour {
+		return fmt.Errorf("%s: messages_per_minute (%d) must not exceed messages_per_hour (%d)", path, perMinute, perHour)
+	}
+	if perHour > 0 && perDay > 0 && perHour > perDay {
+		return fmt.Errorf("%s: messages_per_hour (%d) must not exceed messages_per_day (%d)", path, perHour, perDay)
+	}
+	if perMinute > 0 && perDay > 0 && perMinute > perDay {
+		return fmt.Errorf("%s: messages_per_minute (%d) must not exceed messages_per_day (%d)", path, perMinute, perDay)
+	}
+	return nil
+}
+
 // LoadDynamicDomains loads domain configs from the dynamic domains file
 // This merges API-created domains with static config file domains
 func (c *Config) LoadDynamicDomains() error {
@@ -588,3 +2120,69 @@ func (c *Config) SaveDomains() error {
 func (c *Config) SetDomainsFile(path string) {
 	c.domainsFile = path
 }
+
+// DomainsFile returns the path for dynamic domains persistence
+func (c *Config) DomainsFile() string {
+	return c.domainsFile
+}
+
+// Path returns the path of the file the config was loaded from, empty if
+// it was not loaded from a file
+func (c *Config) Path() string {
+	return c.path
+}
+
+// resolveSecrets replaces the vault: and sops: references of the config
+// with their secrets
+func (c *Config) resolveSecrets() error {
+	resolver := secrets.NewResolver(c.Secrets)
+	if err := resolver.Resolve(context.Background(), c); err != nil {
+		return err
+	}
+	c.secretsDigest = resolver.Digest()
+	return nil
+}
+
+// SecretsDigest identifies the secrets resolved by Load, it changes when a
+// referenced secret does. It is empty without references.
+func (c *Config) SecretsDigest() string {
+	return c.secretsDigest
+}
+
+// validateDeliveryLog checks the delivery event log settings
+func (c *Config) validateDeliveryLog() error {
+	dl := &c.Logging.DeliveryLog
+	if !dl.Enabled {
+		return nil
+	}
+	if dl.File == "" && !dl.Syslog.Enabled {
+		return fmt.Errorf("logging.delivery_log requires file or syslog.enabled")
+	}
+	if dl.MaxSize < 0 || dl.MaxBackups < 0 {
+		return fmt.Errorf("logging.delivery_log.max_size and max_backups must not be negative")
+	}
+	for _, event := range dl.Events {
+		switch event {
+		case DeliveryEventAccepted, DeliveryEventDelivered, DeliveryEventDeferred, DeliveryEventBounced:
+		default:
+			return fmt.Errorf("logging.delivery_log.events: unknown event %q (must be accepted, delivered, deferred or bounced)", event)
+		}
+	}
+
+	if !dl.Syslog.Enabled {
+		return nil
+	}
+	switch dl.Syslog.Network {
+	case "":
+	case "udp", "tcp", "unix":
+		if dl.Syslog.Address == "" {
+			return fmt.Errorf("logging.delivery_log.syslog.address is required for network %s", dl.Syslog.Network)
+		}
+	default:
+		return fmt.Errorf("logging.delivery_log.syslog.network: unknown network %q (must be udp, tcp or unix)", dl.Syslog.Network)
+	}
+	if !slices.Contains(SyslogFacilities, dl.Syslog.Facility) {
+		return fmt.Errorf("logging.delivery_log.syslog.facility: unknown facility %q", dl.Syslog.Facility)
+	}
+	return nil
+}
@@ -1,10 +1,16 @@
 package config
 
 import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/foxzi/sendry/internal/retry"
 )
 
 func TestLoad(t *testing.T) {
@@ -82,6 +88,9 @@ logging:
 	if cfg.Logging.Level != "debug" {
 		t.Errorf("Logging.Level = %v, want debug", cfg.Logging.Level)
 	}
+	if cfg.Path() != cfgPath {
+		t.Errorf("Path() = %v, want %v", cfg.Path(), cfgPath)
+	}
 }
 
 func TestLoadDefaults(t *testing.T) {
@@ -113,15 +122,36 @@ smtp:
 	if cfg.API.ListenAddr != ":8080" {
 		t.Errorf("API.ListenAddr = %v, want :8080", cfg.API.ListenAddr)
 	}
+	if cfg.API.IdempotencyTTL != 24*time.Hour {
+		t.Errorf("API.IdempotencyTTL = %v, want 24h", cfg.API.IdempotencyTTL)
+	}
 	if cfg.Queue.Workers != 4 {
 		t.Errorf("Queue.Workers = %v, want 4", cfg.Queue.Workers)
 	}
+	if cfg.DKIM.KeysDir != "/var/lib/sendry/dkim" {
+		t.Errorf("DKIM.KeysDir = %v, want /var/lib/sendry/dkim", cfg.DKIM.KeysDir)
+	}
 	if cfg.Logging.Level != "info" {
 		t.Errorf("Logging.Level = %v, want info", cfg.Logging.Level)
 	}
 	if cfg.Logging.Format != "json" {
 		t.Errorf("Logging.Format = %v, want json", cfg.Logging.Format)
 	}
+	if cfg.Delivery.MXDeadTTL != 5*time.Minute {
+		t.Errorf("Delivery.MXDeadTTL = %v, want 5m", cfg.Delivery.MXDeadTTL)
+	}
+	if cfg.Delivery.MXFallbackDelay != 3*time.Second {
+		t.Errorf("Delivery.MXFallbackDelay = %v, want 3s", cfg.Delivery.MXFallbackDelay)
+	}
+	if cfg.Delivery.Pool.Enabled || cfg.Delivery.Pool.IdleTimeout != 30*time.Second || cfg.Delivery.Pool.MaxMessagesPerConnection != 100 {
+		t.Errorf("Delivery.Pool = %+v, want disabled with 30s idle timeout and 100 messages", cfg.Delivery.Pool)
+	}
+	if cfg.Archive.Enabled {
+		t.Error("Archive.Enabled = true, want false")
+	}
+	if cfg.Archive.Path != "/var/lib/sendry/archive.db" {
+		t.Errorf("Archive.Path = %v, want /var/lib/sendry/archive.db", cfg.Archive.Path)
+	}
 }
 
 func TestValidate(t *testing.T) {
@@ -157,6 +187,20 @@ func TestValidate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "senders of unknown user",
+			cfg: Config{
+				SMTP: SMTPConfig{
+					Domain: "test.com",
+					Auth: AuthConfig{
+						Users:   map[string]string{"alice": "secret"},
+						Senders: map[string][]string{"bob": {"example.com"}},
+					},
+				},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+			},
+			wantErr: true,
+		},
 		{
 			name: "invalid log level",
 			cfg: Config{
@@ -173,270 +217,1298 @@ func TestValidate(t *testing.T) {
 			},
 			wantErr: true,
 		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			err := tt.cfg.Validate()
-			if (err != nil) != tt.wantErr {
-				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
-			}
-		})
-	}
-}
-
-func TestValidateTLS(t *testing.T) {
-	tests := []struct {
-		name    string
-		cfg     Config
-		wantErr bool
-	}{
 		{
-			name: "no TLS configured",
+			name: "sqlite storage driver",
 			cfg: Config{
 				SMTP:    SMTPConfig{Domain: "test.com"},
 				Logging: LoggingConfig{Level: "info", Format: "json"},
+				Storage: StorageConfig{Driver: "sqlite"},
 			},
 			wantErr: false,
 		},
 		{
-			name: "manual certs configured",
+			name: "unsupported storage driver",
 			cfg: Config{
-				SMTP: SMTPConfig{
-					Domain: "test.com",
-					TLS: TLSConfig{
-						CertFile: "/path/to/cert.pem",
-						KeyFile:  "/path/to/key.pem",
-					},
-				},
+				SMTP:    SMTPConfig{Domain: "test.com"},
 				Logging: LoggingConfig{Level: "info", Format: "json"},
+				Storage: StorageConfig{Driver: "postgres"},
 			},
-			wantErr: false,
+			wantErr: true,
 		},
 		{
-			name: "ACME configured",
+			name: "sharded bolt storage",
 			cfg: Config{
-				SMTP: SMTPConfig{
-					Domain: "test.com",
-					TLS: TLSConfig{
-						ACME: ACMEConfig{
-							Enabled: true,
-							Email:   "admin@test.com",
-							Domains: []string{"mail.test.com"},
-						},
-					},
-				},
+				SMTP:    SMTPConfig{Domain: "test.com"},
 				Logging: LoggingConfig{Level: "info", Format: "json"},
+				Storage: StorageConfig{Driver: "bolt", Shards: 8},
 			},
 			wantErr: false,
 		},
 		{
-			name: "both manual and ACME configured",
+			name: "too many storage shards",
 			cfg: Config{
-				SMTP: SMTPConfig{
-					Domain: "test.com",
-					TLS: TLSConfig{
-						CertFile: "/path/to/cert.pem",
-						KeyFile:  "/path/to/key.pem",
-						ACME: ACMEConfig{
-							Enabled: true,
-							Email:   "admin@test.com",
-							Domains: []string{"mail.test.com"},
-						},
-					},
-				},
+				SMTP:    SMTPConfig{Domain: "test.com"},
 				Logging: LoggingConfig{Level: "info", Format: "json"},
+				Storage: StorageConfig{Shards: 65},
 			},
 			wantErr: true,
 		},
 		{
-			name: "ACME without email",
+			name: "sharded sqlite storage",
 			cfg: Config{
-				SMTP: SMTPConfig{
-					Domain: "test.com",
-					TLS: TLSConfig{
-						ACME: ACMEConfig{
-							Enabled: true,
-							Domains: []string{"mail.test.com"},
-						},
-					},
-				},
+				SMTP:    SMTPConfig{Domain: "test.com"},
 				Logging: LoggingConfig{Level: "info", Format: "json"},
+				Storage: StorageConfig{Driver: "sqlite", Shards: 4},
 			},
 			wantErr: true,
 		},
 		{
-			name: "ACME without domains",
+			name: "storage encryption with key file",
 			cfg: Config{
-				SMTP: SMTPConfig{
-					Domain: "test.com",
-					TLS: TLSConfig{
-						ACME: ACMEConfig{
-							Enabled: true,
-							Email:   "admin@test.com",
-						},
-					},
-				},
+				SMTP:    SMTPConfig{Domain: "test.com"},
 				Logging: LoggingConfig{Level: "info", Format: "json"},
+				Storage: StorageConfig{Encryption: StorageEncryptionConfig{Enabled: true, KeyFile: "/etc/sendry/queue.key"}},
 			},
-			wantErr: true,
+			wantErr: false,
 		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			err := tt.cfg.Validate()
-			if (err != nil) != tt.wantErr {
-				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
-			}
-		})
-	}
-}
-
-func TestValidateDKIM(t *testing.T) {
-	tests := []struct {
-		name    string
-		cfg     Config
-		wantErr bool
-	}{
 		{
-			name: "DKIM disabled",
+			name: "storage encryption without key",
 			cfg: Config{
 				SMTP:    SMTPConfig{Domain: "test.com"},
 				Logging: LoggingConfig{Level: "info", Format: "json"},
-				DKIM:    DKIMConfig{Enabled: false},
+				Storage: StorageConfig{Encryption: StorageEncryptionConfig{Enabled: true}},
 			},
-			wantErr: false,
+			wantErr: true,
 		},
 		{
-			name: "DKIM enabled with all fields",
+			name: "storage encryption with key and key file",
 			cfg: Config{
 				SMTP:    SMTPConfig{Domain: "test.com"},
 				Logging: LoggingConfig{Level: "info", Format: "json"},
-				DKIM: DKIMConfig{
-					Enabled:  true,
-					Selector: "sendry",
-					KeyFile:  "/path/to/key.pem",
-					Domain:   "test.com",
-				},
+				Storage: StorageConfig{Encryption: StorageEncryptionConfig{Enabled: true, Key: "a2V5", KeyFile: "/etc/sendry/queue.key"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "log redaction",
+			cfg: Config{
+				SMTP:    SMTPConfig{Domain: "test.com"},
+				Logging: LoggingConfig{Level: "info", Format: "json", Redact: LogRedactConfig{Addresses: true, Mode: RedactModeHash, Salt: "s"}},
 			},
 			wantErr: false,
 		},
 		{
-			name: "DKIM enabled without selector",
+			name: "invalid log redaction mode",
 			cfg: Config{
 				SMTP:    SMTPConfig{Domain: "test.com"},
-				Logging: LoggingConfig{Level: "info", Format: "json"},
-				DKIM: DKIMConfig{
-					Enabled: true,
-					KeyFile: "/path/to/key.pem",
-					Domain:  "test.com",
-				},
+				Logging: LoggingConfig{Level: "info", Format: "json", Redact: LogRedactConfig{Addresses: true, Mode: "drop"}},
 			},
 			wantErr: true,
 		},
 		{
-			name: "DKIM enabled without key file",
+			name: "negative domain retention",
 			cfg: Config{
 				SMTP:    SMTPConfig{Domain: "test.com"},
 				Logging: LoggingConfig{Level: "info", Format: "json"},
-				DKIM: DKIMConfig{
-					Enabled:  true,
-					Selector: "sendry",
-					Domain:   "test.com",
+				Domains: map[string]DomainConfig{
+					"test.com": {Retention: &DomainRetentionConfig{SandboxMaxAge: -time.Hour}},
 				},
 			},
 			wantErr: true,
 		},
 		{
-			name: "DKIM enabled without domain",
+			name: "stats retention shorter than minute retention",
 			cfg: Config{
 				SMTP:    SMTPConfig{Domain: "test.com"},
 				Logging: LoggingConfig{Level: "info", Format: "json"},
-				DKIM: DKIMConfig{
-					Enabled:  true,
-					Selector: "sendry",
-					KeyFile:  "/path/to/key.pem",
-				},
+				Stats:   StatsConfig{Enabled: true, MinuteRetention: 48 * time.Hour, Retention: time.Hour},
 			},
 			wantErr: true,
 		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			err := tt.cfg.Validate()
-			if (err != nil) != tt.wantErr {
-				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
-			}
-		})
-	}
-}
-
-func TestHasTLS(t *testing.T) {
-	tests := []struct {
-		name string
-		cfg  Config
-		want bool
-	}{
 		{
-			name: "no TLS",
-			cfg:  Config{},
-			want: false,
+			name: "custom banner and extensions",
+			cfg: Config{
+				SMTP: SMTPConfig{
+					Domain:          "test.com",
+					MaxMessageBytes: 10485760,
+					Banner:          "mx.test.com ESMTP",
+					Extensions:      SMTPExtensionsConfig{DisableChunking: true, Size: 5242880},
+					MaxLineLength:   SMTPLineLengthConfig{SMTP: 1000, Submission: 4096},
+				},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+			},
+			wantErr: false,
 		},
 		{
-			name: "manual certs",
+			name: "multiline banner",
+			cfg: Config{
+				SMTP:    SMTPConfig{Domain: "test.com", Banner: "mx.test.com\r\n250 injected"},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "advertised size above max message size",
 			cfg: Config{
 				SMTP: SMTPConfig{
-					TLS: TLSConfig{
-						CertFile: "/path/cert.pem",
-						KeyFile:  "/path/key.pem",
-					},
+					Domain:          "test.com",
+					MaxMessageBytes: 1024 * 1024,
+					Extensions:      SMTPExtensionsConfig{Size: 2 * 1024 * 1024},
 				},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
 			},
-			want: true,
+			wantErr: true,
 		},
 		{
-			name: "ACME enabled",
+			name: "SMTPUTF8 with rejected EAI",
 			cfg: Config{
 				SMTP: SMTPConfig{
-					TLS: TLSConfig{
-						ACME: ACMEConfig{Enabled: true},
-					},
+					Domain:     "test.com",
+					Extensions: SMTPExtensionsConfig{SMTPUTF8: true},
+					RejectEAI:  true,
 				},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
 			},
-			want: true,
+			wantErr: true,
 		},
 		{
-			name: "only cert file",
+			name: "too short line length",
 			cfg: Config{
-				SMTP: SMTPConfig{
-					TLS: TLSConfig{
-						CertFile: "/path/cert.pem",
-					},
+				SMTP:    SMTPConfig{Domain: "test.com", MaxLineLength: SMTPLineLengthConfig{SMTPS: 100}},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "inbound auth reject",
+			cfg: Config{
+				SMTP:    SMTPConfig{Domain: "test.com", InboundAuth: "reject"},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid inbound auth",
+			cfg: Config{
+				SMTP:    SMTPConfig{Domain: "test.com", InboundAuth: "quarantine"},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "milter content filter",
+			cfg: Config{
+				SMTP:          SMTPConfig{Domain: "test.com"},
+				Logging:       LoggingConfig{Level: "info", Format: "json"},
+				ContentFilter: ContentFilterConfig{Enabled: true, Type: ContentFilterMilter, Address: "inet:localhost:11332"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "http content filter without url",
+			cfg: Config{
+				SMTP:          SMTPConfig{Domain: "test.com"},
+				Logging:       LoggingConfig{Level: "info", Format: "json"},
+				ContentFilter: ContentFilterConfig{Enabled: true, Type: ContentFilterHTTP},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid content filter source",
+			cfg: Config{
+				SMTP:    SMTPConfig{Domain: "test.com"},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+				ContentFilter: ContentFilterConfig{
+					Enabled: true, Type: ContentFilterHTTP, URL: "http://127.0.0.1:8000/filter", Sources: []string{"consumer"},
 				},
 			},
-			want: false,
+			wantErr: true,
 		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			if got := tt.cfg.HasTLS(); got != tt.want {
-				t.Errorf("HasTLS() = %v, want %v", got, tt.want)
-			}
-		})
-	}
-}
-
-func TestLoadFileNotFound(t *testing.T) {
-	_, err := Load("/nonexistent/config.yaml")
-	if err == nil {
-		t.Error("Load() expected error for nonexistent file")
-	}
-}
-
-func TestLoadInvalidYAML(t *testing.T) {
+		{
+			name: "content policy",
+			cfg: Config{
+				SMTP:    SMTPConfig{Domain: "test.com"},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+				ContentPolicy: ContentPolicyConfig{
+					MaxAttachmentBytes: 10 << 20, BannedExtensions: []string{".exe", "js"}, BannedTypes: []string{"application/x-msdownload", "video/*"},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid content policy type",
+			cfg: Config{
+				SMTP:          SMTPConfig{Domain: "test.com"},
+				Logging:       LoggingConfig{Level: "info", Format: "json"},
+				ContentPolicy: ContentPolicyConfig{BannedTypes: []string{"exe"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative domain content policy recipients",
+			cfg: Config{
+				SMTP:    SMTPConfig{Domain: "test.com"},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+				Domains: map[string]DomainConfig{
+					"example.com": {ContentPolicy: &ContentPolicyConfig{MaxRecipients: -1}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "archive retention",
+			cfg: Config{
+				SMTP:    SMTPConfig{Domain: "test.com"},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+				Archive: ArchiveConfig{Enabled: true, MaxAge: 90 * 24 * time.Hour, MaxCount: 100000},
+			},
+			wantErr: false,
+		},
+		{
+			name: "negative archive max count",
+			cfg: Config{
+				SMTP:    SMTPConfig{Domain: "test.com"},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+				Archive: ArchiveConfig{Enabled: true, MaxCount: -1},
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative sandbox max age",
+			cfg: Config{
+				SMTP:    SMTPConfig{Domain: "test.com"},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+				Sandbox: SandboxConfig{MaxAge: -time.Hour},
+			},
+			wantErr: true,
+		},
+		{
+			name: "sandbox overrides",
+			cfg: Config{
+				SMTP:    SMTPConfig{Domain: "test.com"},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+				Sandbox: SandboxConfig{Overrides: []SandboxOverride{
+					{APIKey: "staging", Mode: "sandbox"},
+					{Sender: "qa@test.com", Mode: "redirect", RedirectTo: []string{"qa@example.com"}},
+					{Sender: "@test.com", Mode: "production"},
+				}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "sandbox override with api key and sender",
+			cfg: Config{
+				SMTP:    SMTPConfig{Domain: "test.com"},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+				Sandbox: SandboxConfig{Overrides: []SandboxOverride{
+					{APIKey: "staging", Sender: "qa@test.com", Mode: "sandbox"},
+				}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "sandbox override bcc without addresses",
+			cfg: Config{
+				SMTP:    SMTPConfig{Domain: "test.com"},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+				Sandbox: SandboxConfig{Overrides: []SandboxOverride{{Sender: "@test.com", Mode: "bcc"}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "sandbox override without mode",
+			cfg: Config{
+				SMTP:    SMTPConfig{Domain: "test.com"},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+				Sandbox: SandboxConfig{Overrides: []SandboxOverride{{APIKey: "staging"}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "domain concurrency",
+			cfg: Config{
+				SMTP:    SMTPConfig{Domain: "test.com"},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+				Queue:   QueueConfig{MaxConcurrentConnections: 10, DomainConcurrency: map[string]int{"gmail.com": 5}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "negative domain concurrency",
+			cfg: Config{
+				SMTP:    SMTPConfig{Domain: "test.com"},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+				Queue:   QueueConfig{DomainConcurrency: map[string]int{"gmail.com": -1}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "kafka consumer",
+			cfg: Config{
+				SMTP:    SMTPConfig{Domain: "test.com"},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+				Consumer: ConsumerConfig{Enabled: true, Type: ConsumerTypeKafka, Kafka: KafkaConsumerConfig{
+					Brokers: []string{"localhost:9092"}, Topic: "sendry-send", DeadLetterTopic: "sendry-send-dead",
+				}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "nats consumer without dead letter subject",
+			cfg: Config{
+				SMTP:    SMTPConfig{Domain: "test.com"},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+				Consumer: ConsumerConfig{Enabled: true, Type: ConsumerTypeNATS, NATS: NATSConsumerConfig{
+					Stream: "SENDRY", Subject: "sendry.send",
+				}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown consumer type",
+			cfg: Config{
+				SMTP:     SMTPConfig{Domain: "test.com"},
+				Logging:  LoggingConfig{Level: "info", Format: "json"},
+				Consumer: ConsumerConfig{Enabled: true, Type: "rabbitmq"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "reputation IPv6 address",
+			cfg: Config{
+				SMTP:       SMTPConfig{Domain: "test.com"},
+				Logging:    LoggingConfig{Level: "info", Format: "json"},
+				Reputation: ReputationConfig{Enabled: true, IPs: []string{"2001:db8::1"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "replication primary",
+			cfg: Config{
+				SMTP:        SMTPConfig{Domain: "test.com"},
+				Logging:     LoggingConfig{Level: "info", Format: "json"},
+				Replication: ReplicationConfig{Enabled: true, Role: ReplicationPrimary, PeerURL: "https://standby.example.com:8080"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "replication primary without peer",
+			cfg: Config{
+				SMTP:        SMTPConfig{Domain: "test.com"},
+				Logging:     LoggingConfig{Level: "info", Format: "json"},
+				Replication: ReplicationConfig{Enabled: true, Role: ReplicationPrimary},
+			},
+			wantErr: true,
+		},
+		{
+			name: "replication unknown role",
+			cfg: Config{
+				SMTP:        SMTPConfig{Domain: "test.com"},
+				Logging:     LoggingConfig{Level: "info", Format: "json"},
+				Replication: ReplicationConfig{Enabled: true, Role: "replica"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "api client CA without server certificate",
+			cfg: Config{
+				SMTP:    SMTPConfig{Domain: "test.com"},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+				API:     APIConfig{TLS: APITLSConfig{ClientCAFile: "/etc/sendry/clients.pem"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "api client certificate fingerprint",
+			cfg: Config{
+				SMTP: SMTPConfig{
+					Domain: "test.com",
+					TLS:    TLSConfig{CertFile: "/etc/sendry/cert.pem", KeyFile: "/etc/sendry/key.pem"},
+				},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+				API: APIConfig{TLS: APITLSConfig{
+					ClientCAFile:        "/etc/sendry/clients.pem",
+					AllowedFingerprints: []string{"AB:" + strings.Repeat("0", 62)},
+				}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "api client certificate invalid fingerprint",
+			cfg: Config{
+				SMTP: SMTPConfig{
+					Domain: "test.com",
+					TLS:    TLSConfig{CertFile: "/etc/sendry/cert.pem", KeyFile: "/etc/sendry/key.pem"},
+				},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+				API: APIConfig{TLS: APITLSConfig{
+					ClientCAFile:        "/etc/sendry/clients.pem",
+					AllowedFingerprints: []string{"abcd"},
+				}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "smtp client certificates",
+			cfg: Config{
+				SMTP: SMTPConfig{
+					Domain: "test.com",
+					Auth:   AuthConfig{Required: true},
+					TLS:    TLSConfig{CertFile: "/etc/sendry/cert.pem", KeyFile: "/etc/sendry/key.pem"},
+					ClientCerts: SMTPClientCertConfig{
+						CAFile:    "/etc/sendry/clients.pem",
+						Listeners: []string{"submission"},
+						Clients: []SMTPCertClient{
+							{CommonName: "billing", Senders: []string{"billing.example"}},
+							{Fingerprint: strings.Repeat("ab", 32), User: "reports"},
+						},
+					},
+				},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "address policy",
+			cfg: Config{
+				SMTP:    SMTPConfig{Domain: "test.com"},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+				AddressPolicy: AddressPolicyConfig{
+					AllowSenders:   []string{"noreply-*@test.com", "*.test.com"},
+					DenyRecipients: []string{"blocked.example", "ceo@example.org"},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "address policy invalid entry",
+			cfg: Config{
+				SMTP:          SMTPConfig{Domain: "test.com"},
+				Logging:       LoggingConfig{Level: "info", Format: "json"},
+				AddressPolicy: AddressPolicyConfig{DenySenders: []string{"[a-@test.com"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "smtp client certificates without clients",
+			cfg: Config{
+				SMTP: SMTPConfig{
+					Domain:      "test.com",
+					TLS:         TLSConfig{CertFile: "/etc/sendry/cert.pem", KeyFile: "/etc/sendry/key.pem"},
+					ClientCerts: SMTPClientCertConfig{CAFile: "/etc/sendry/clients.pem"},
+				},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "smtp client certificates unknown listener",
+			cfg: Config{
+				SMTP: SMTPConfig{
+					Domain: "test.com",
+					TLS:    TLSConfig{CertFile: "/etc/sendry/cert.pem", KeyFile: "/etc/sendry/key.pem"},
+					ClientCerts: SMTPClientCertConfig{
+						CAFile:    "/etc/sendry/clients.pem",
+						Listeners: []string{"lmtp"},
+						Clients:   []SMTPCertClient{{CommonName: "billing"}},
+					},
+				},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "smtp ip guard",
+			cfg: Config{
+				SMTP: SMTPConfig{
+					Domain: "test.com",
+					IPGuard: IPGuardConfig{
+						Enabled:     true,
+						BanAfter:    3,
+						BanDuration: 30 * time.Minute,
+						Exempt:      []string{"10.0.0.0/8", "192.0.2.10"},
+					},
+				},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "smtp ip guard invalid exempt",
+			cfg: Config{
+				SMTP:    SMTPConfig{Domain: "test.com", IPGuard: IPGuardConfig{Enabled: true, Exempt: []string{"10.0.0.0/33"}}},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "smtp ip guard negative limit",
+			cfg: Config{
+				SMTP:    SMTPConfig{Domain: "test.com", IPGuard: IPGuardConfig{CommandRate: -1}},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "smtp proxy protocol",
+			cfg: Config{
+				SMTP: SMTPConfig{
+					Domain: "test.com",
+					ProxyProtocol: SMTPProxyProtocolConfig{
+						Listeners:      []string{"smtp", "submission"},
+						TrustedProxies: []string{"10.0.0.5", "10.1.0.0/16"},
+					},
+				},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "smtp proxy protocol without trusted proxies",
+			cfg: Config{
+				SMTP:    SMTPConfig{Domain: "test.com", ProxyProtocol: SMTPProxyProtocolConfig{Listeners: []string{"smtp"}}},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "smtp proxy protocol unknown listener",
+			cfg: Config{
+				SMTP: SMTPConfig{Domain: "test.com", ProxyProtocol: SMTPProxyProtocolConfig{
+					Listeners:      []string{"lmtp"},
+					TrustedProxies: []string{"10.0.0.5"},
+				}},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "health checks",
+			cfg: Config{
+				SMTP:    SMTPConfig{Domain: "test.com"},
+				Health:  HealthConfig{ListenAddr: ":8081", MaxQueueBacklog: 10000, CertMinValidity: 72 * time.Hour, DrainDelay: 10 * time.Second},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "health negative backlog",
+			cfg: Config{
+				SMTP:    SMTPConfig{Domain: "test.com"},
+				Health:  HealthConfig{MaxQueueBacklog: -1},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "smtp client certificate fingerprint without user",
+			cfg: Config{
+				SMTP: SMTPConfig{
+					Domain: "test.com",
+					TLS:    TLSConfig{CertFile: "/etc/sendry/cert.pem", KeyFile: "/etc/sendry/key.pem"},
+					ClientCerts: SMTPClientCertConfig{
+						CAFile:  "/etc/sendry/clients.pem",
+						Clients: []SMTPCertClient{{Fingerprint: strings.Repeat("ab", 32)}},
+					},
+				},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateTLS(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{
+			name: "no TLS configured",
+			cfg: Config{
+				SMTP:    SMTPConfig{Domain: "test.com"},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "manual certs configured",
+			cfg: Config{
+				SMTP: SMTPConfig{
+					Domain: "test.com",
+					TLS: TLSConfig{
+						CertFile: "/path/to/cert.pem",
+						KeyFile:  "/path/to/key.pem",
+					},
+				},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "ACME configured",
+			cfg: Config{
+				SMTP: SMTPConfig{
+					Domain: "test.com",
+					TLS: TLSConfig{
+						ACME: ACMEConfig{
+							Enabled: true,
+							Email:   "admin@test.com",
+							Domains: []string{"mail.test.com"},
+						},
+					},
+				},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "both manual and ACME configured",
+			cfg: Config{
+				SMTP: SMTPConfig{
+					Domain: "test.com",
+					TLS: TLSConfig{
+						CertFile: "/path/to/cert.pem",
+						KeyFile:  "/path/to/key.pem",
+						ACME: ACMEConfig{
+							Enabled: true,
+							Email:   "admin@test.com",
+							Domains: []string{"mail.test.com"},
+						},
+					},
+				},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "ACME without email",
+			cfg: Config{
+				SMTP: SMTPConfig{
+					Domain: "test.com",
+					TLS: TLSConfig{
+						ACME: ACMEConfig{
+							Enabled: true,
+							Domains: []string{"mail.test.com"},
+						},
+					},
+				},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "ACME without domains",
+			cfg: Config{
+				SMTP: SMTPConfig{
+					Domain: "test.com",
+					TLS: TLSConfig{
+						ACME: ACMEConfig{
+							Enabled: true,
+							Email:   "admin@test.com",
+						},
+					},
+				},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateDKIM(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{
+			name: "DKIM disabled",
+			cfg: Config{
+				SMTP:    SMTPConfig{Domain: "test.com"},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+				DKIM:    DKIMConfig{Enabled: false},
+			},
+			wantErr: false,
+		},
+		{
+			name: "DKIM enabled with all fields",
+			cfg: Config{
+				SMTP:    SMTPConfig{Domain: "test.com"},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+				DKIM: DKIMConfig{
+					Enabled:  true,
+					Selector: "sendry",
+					KeyFile:  "/path/to/key.pem",
+					Domain:   "test.com",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "DKIM enabled without selector",
+			cfg: Config{
+				SMTP:    SMTPConfig{Domain: "test.com"},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+				DKIM: DKIMConfig{
+					Enabled: true,
+					KeyFile: "/path/to/key.pem",
+					Domain:  "test.com",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "DKIM enabled without key file",
+			cfg: Config{
+				SMTP:    SMTPConfig{Domain: "test.com"},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+				DKIM: DKIMConfig{
+					Enabled:  true,
+					Selector: "sendry",
+					Domain:   "test.com",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "DKIM enabled without domain",
+			cfg: Config{
+				SMTP:    SMTPConfig{Domain: "test.com"},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+				DKIM: DKIMConfig{
+					Enabled:  true,
+					Selector: "sendry",
+					KeyFile:  "/path/to/key.pem",
+				},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateRateLimits(t *testing.T) {
+	tests := []struct {
+		name      string
+		rateLimit RateLimitConfig
+		domains   map[string]DomainConfig
+		wantErr   bool
+	}{
+		{
+			name: "consistent windows",
+			rateLimit: RateLimitConfig{
+				Algorithm: "sliding",
+				Global:    &LimitValues{MessagesPerMinute: 100, MessagesPerHour: 1000, MessagesPerDay: 10000},
+			},
+			wantErr: false,
+		},
+		{
+			name: "only minute limit",
+			rateLimit: RateLimitConfig{
+				DefaultSender: &LimitValues{MessagesPerMinute: 10},
+			},
+			wantErr: false,
+		},
+		{
+			name: "minute exceeds hour",
+			rateLimit: RateLimitConfig{
+				DefaultSender: &LimitValues{MessagesPerMinute: 200, MessagesPerHour: 100},
+			},
+			wantErr: true,
+		},
+		{
+			name: "hour exceeds day",
+			rateLimit: RateLimitConfig{
+				RecipientDomains: map[string]*LimitValues{
+					"gmail.com": {MessagesPerHour: 500, MessagesPerDay: 100},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "minute exceeds day without hour",
+			rateLimit: RateLimitConfig{
+				DefaultIP: &LimitValues{MessagesPerMinute: 50, MessagesPerDay: 10},
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative limit",
+			rateLimit: RateLimitConfig{
+				Global: &LimitValues{MessagesPerMinute: -1},
+			},
+			wantErr: true,
+		},
+		{
+			name:      "invalid algorithm",
+			rateLimit: RateLimitConfig{Algorithm: "leaky"},
+			wantErr:   true,
+		},
+		{
+			name: "invalid level algorithm",
+			rateLimit: RateLimitConfig{
+				DefaultAPIKey: &LimitValues{MessagesPerHour: 10, Algorithm: "token"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "adaptive throttling",
+			rateLimit: RateLimitConfig{
+				Adaptive: &AdaptiveThrottleConfig{Enabled: true, Threshold: 5, Decrease: 0.7, Recovery: 5 * time.Minute},
+			},
+			wantErr: false,
+		},
+		{
+			name: "adaptive decrease not below one",
+			rateLimit: RateLimitConfig{
+				Adaptive: &AdaptiveThrottleConfig{Enabled: true, Decrease: 1.5},
+			},
+			wantErr: true,
+		},
+		{
+			name: "domain minute exceeds hour",
+			domains: map[string]DomainConfig{
+				"example.com": {RateLimit: &DomainRateLimitConfig{MessagesPerMinute: 100, MessagesPerHour: 10}},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				SMTP:      SMTPConfig{Domain: "test.com"},
+				Logging:   LoggingConfig{Level: "info", Format: "json"},
+				RateLimit: tt.rateLimit,
+				Domains:   tt.domains,
+			}
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateInboundRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   []InboundRule
+		wantErr bool
+	}{
+		{
+			name: "valid rules",
+			rules: []InboundRule{
+				{Recipient: "reply+*", Action: InboundActionWebhook, URL: "https://app.example.com/hook"},
+				{Recipient: "support", Action: InboundActionForward, To: []string{"helpdesk@example.org"}},
+				{Recipient: "fbl", Action: InboundActionFBL},
+				{Recipient: "bounce*", Action: InboundActionBounce},
+				{Action: InboundActionMaildir, Path: "/var/mail/{domain}/{user}"},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "invalid pattern",
+			rules:   []InboundRule{{Recipient: "reply[", Action: InboundActionMaildir, Path: "/var/mail"}},
+			wantErr: true,
+		},
+		{
+			name:    "webhook without url",
+			rules:   []InboundRule{{Action: InboundActionWebhook}},
+			wantErr: true,
+		},
+		{
+			name:    "webhook with non-http url",
+			rules:   []InboundRule{{Action: InboundActionWebhook, URL: "ftp://app.example.com/hook"}},
+			wantErr: true,
+		},
+		{
+			name:    "maildir without path",
+			rules:   []InboundRule{{Action: InboundActionMaildir}},
+			wantErr: true,
+		},
+		{
+			name:    "forward without addresses",
+			rules:   []InboundRule{{Action: InboundActionForward}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown action",
+			rules:   []InboundRule{{Action: "reject"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				SMTP:    SMTPConfig{Domain: "test.com"},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+				Domains: map[string]DomainConfig{"reply.example.com": {Inbound: tt.rules}},
+			}
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateReturnPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		rp      *DomainReturnPathConfig
+		wantErr bool
+	}{
+		{name: "not set"},
+		{name: "fixed address", rp: &DomainReturnPathConfig{Address: "bounce@bounces.example.com"}},
+		{name: "verp", rp: &DomainReturnPathConfig{Address: "bounce@bounces.example.com", VERP: true}},
+		{name: "plus without verp", rp: &DomainReturnPathConfig{Address: "bounce+list@bounces.example.com"}},
+		{name: "missing address", rp: &DomainReturnPathConfig{VERP: true}, wantErr: true},
+		{name: "missing domain", rp: &DomainReturnPathConfig{Address: "bounce@"}, wantErr: true},
+		{name: "display name", rp: &DomainReturnPathConfig{Address: "Bounces <bounce@bounces.example.com>"}, wantErr: true},
+		{name: "plus with verp", rp: &DomainReturnPathConfig{Address: "bounce+list@bounces.example.com", VERP: true}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				SMTP:    SMTPConfig{Domain: "test.com"},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+				Domains: map[string]DomainConfig{"example.com": {ReturnPath: tt.rp}},
+			}
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateRetry(t *testing.T) {
+	steps := &retry.Config{Classes: map[string]retry.Policy{
+		retry.ClassGreylisting: {Strategy: retry.StrategySteps, Steps: []time.Duration{5 * time.Minute}},
+	}}
+	invalid := &retry.Config{Policy: &retry.Policy{Jitter: 2}}
+
+	tests := []struct {
+		name    string
+		queue   *retry.Config
+		domain  *retry.Config
+		wantErr bool
+	}{
+		{name: "not set"},
+		{name: "queue and domain", queue: steps, domain: steps},
+		{name: "invalid queue policy", queue: invalid, wantErr: true},
+		{name: "invalid domain policy", domain: invalid, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				SMTP:    SMTPConfig{Domain: "test.com"},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+				Queue:   QueueConfig{Retry: tt.queue},
+				Domains: map[string]DomainConfig{"gmail.com": {Retry: tt.domain}},
+			}
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateDeliveryLog(t *testing.T) {
+	tests := []struct {
+		name    string
+		log     DeliveryLogConfig
+		wantErr bool
+	}{
+		{name: "disabled", log: DeliveryLogConfig{Events: []string{"unknown"}}},
+		{name: "file", log: DeliveryLogConfig{Enabled: true, File: "/var/log/sendry/delivery.jsonl", Events: []string{"delivered", "bounced"}}},
+		{name: "local syslog", log: DeliveryLogConfig{Enabled: true, Syslog: DeliveryLogSyslogConfig{Enabled: true, Facility: "local3"}}},
+		{name: "remote syslog", log: DeliveryLogConfig{Enabled: true, Syslog: DeliveryLogSyslogConfig{Enabled: true, Network: "udp", Address: "logs:514", Facility: "mail"}}},
+		{name: "no output", log: DeliveryLogConfig{Enabled: true}, wantErr: true},
+		{name: "unknown event", log: DeliveryLogConfig{Enabled: true, File: "d.jsonl", Events: []string{"opened"}}, wantErr: true},
+		{name: "negative max_size", log: DeliveryLogConfig{Enabled: true, File: "d.jsonl", MaxSize: -1}, wantErr: true},
+		{name: "remote syslog without address", log: DeliveryLogConfig{Enabled: true, Syslog: DeliveryLogSyslogConfig{Enabled: true, Network: "tcp", Facility: "mail"}}, wantErr: true},
+		{name: "unknown network", log: DeliveryLogConfig{Enabled: true, Syslog: DeliveryLogSyslogConfig{Enabled: true, Network: "http", Address: "logs:514", Facility: "mail"}}, wantErr: true},
+		{name: "unknown facility", log: DeliveryLogConfig{Enabled: true, Syslog: DeliveryLogSyslogConfig{Enabled: true, Facility: "kern"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				SMTP:    SMTPConfig{Domain: "test.com"},
+				Logging: LoggingConfig{Level: "info", Format: "json", DeliveryLog: tt.log},
+			}
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateTracing(t *testing.T) {
+	tests := []struct {
+		name    string
+		tracing TracingConfig
+		wantErr bool
+	}{
+		{name: "disabled", tracing: TracingConfig{Endpoint: "invalid"}},
+		{name: "collector", tracing: TracingConfig{Enabled: true, Endpoint: "http://localhost:4318", SampleRatio: 0.5}},
+		{name: "no endpoint", tracing: TracingConfig{Enabled: true, SampleRatio: 1}, wantErr: true},
+		{name: "endpoint without scheme", tracing: TracingConfig{Enabled: true, Endpoint: "localhost:4318", SampleRatio: 1}, wantErr: true},
+		{name: "sample ratio above 1", tracing: TracingConfig{Enabled: true, Endpoint: "https://otlp.example.com", SampleRatio: 2}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				SMTP:    SMTPConfig{Domain: "test.com"},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+				Tracing: tt.tracing,
+			}
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateAPIGRPC(t *testing.T) {
+	tests := []struct {
+		name    string
+		api     APIConfig
+		wantErr bool
+	}{
+		{name: "disabled", api: APIConfig{ListenAddr: ":8080"}},
+		{name: "enabled", api: APIConfig{ListenAddr: ":8080", GRPC: APIGRPCConfig{Enabled: true, ListenAddr: ":9091"}}},
+		{name: "same address as HTTP", api: APIConfig{ListenAddr: ":8080", GRPC: APIGRPCConfig{Enabled: true, ListenAddr: ":8080"}}, wantErr: true},
+		{name: "negative event buffer", api: APIConfig{GRPC: APIGRPCConfig{EventBuffer: -1}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				SMTP:    SMTPConfig{Domain: "test.com"},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+				API:     tt.api,
+			}
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateDelivery(t *testing.T) {
+	lmtpRules := []InboundRule{{Action: InboundActionLMTP}}
+	tests := []struct {
+		name     string
+		delivery *DomainDeliveryConfig
+		inbound  []InboundRule
+		wantErr  bool
+	}{
+		{name: "not set"},
+		{name: "smtp", delivery: &DomainDeliveryConfig{Protocol: DeliveryProtocolSMTP}},
+		{
+			name:     "lmtp over tcp with tls",
+			delivery: &DomainDeliveryConfig{Protocol: DeliveryProtocolLMTP, Address: "127.0.0.1:24", TLS: &DeliveryTLSConfig{Enabled: true, CAFile: "/etc/ssl/ca.pem"}},
+			inbound:  lmtpRules,
+		},
+		{
+			name:     "lmtp over unix socket",
+			delivery: &DomainDeliveryConfig{Protocol: DeliveryProtocolLMTP, Address: "/run/dovecot/lmtp"},
+		},
+		{
+			name:     "lmtp with unix prefix",
+			delivery: &DomainDeliveryConfig{Protocol: DeliveryProtocolLMTP, Address: "unix:/run/dovecot/lmtp"},
+		},
+		{
+			name:     "lmtp without address",
+			delivery: &DomainDeliveryConfig{Protocol: DeliveryProtocolLMTP},
+			wantErr:  true,
+		},
+		{
+			name:     "lmtp without port",
+			delivery: &DomainDeliveryConfig{Protocol: DeliveryProtocolLMTP, Address: "127.0.0.1"},
+			wantErr:  true,
+		},
+		{
+			name:     "tls settings without enabled",
+			delivery: &DomainDeliveryConfig{Protocol: DeliveryProtocolLMTP, Address: "127.0.0.1:24", TLS: &DeliveryTLSConfig{InsecureSkipVerify: true}},
+			wantErr:  true,
+		},
+		{
+			name:     "smtp with address",
+			delivery: &DomainDeliveryConfig{Address: "127.0.0.1:24"},
+			wantErr:  true,
+		},
+		{
+			name:     "unknown protocol",
+			delivery: &DomainDeliveryConfig{Protocol: "uucp"},
+			wantErr:  true,
+		},
+		{
+			name:    "lmtp rule without lmtp delivery",
+			inbound: lmtpRules,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				SMTP:    SMTPConfig{Domain: "test.com"},
+				Logging: LoggingConfig{Level: "info", Format: "json"},
+				Domains: map[string]DomainConfig{"example.com": {Delivery: tt.delivery, Inbound: tt.inbound}},
+			}
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHasTLS(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		want bool
+	}{
+		{
+			name: "no TLS",
+			cfg:  Config{},
+			want: false,
+		},
+		{
+			name: "manual certs",
+			cfg: Config{
+				SMTP: SMTPConfig{
+					TLS: TLSConfig{
+						CertFile: "/path/cert.pem",
+						KeyFile:  "/path/key.pem",
+					},
+				},
+			},
+			want: true,
+		},
+		{
+			name: "ACME enabled",
+			cfg: Config{
+				SMTP: SMTPConfig{
+					TLS: TLSConfig{
+						ACME: ACMEConfig{Enabled: true},
+					},
+				},
+			},
+			want: true,
+		},
+		{
+			name: "only cert file",
+			cfg: Config{
+				SMTP: SMTPConfig{
+					TLS: TLSConfig{
+						CertFile: "/path/cert.pem",
+					},
+				},
+			},
+			want: false,
+		},
+		{
+			name: "domain certs",
+			cfg: Config{
+				Domains: map[string]DomainConfig{
+					"example.com": {TLS: &DomainTLSConfig{CertFile: "/path/cert.pem", KeyFile: "/path/key.pem"}},
+				},
+			},
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cfg.HasTLS(); got != tt.want {
+				t.Errorf("HasTLS() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDomainCertificates(t *testing.T) {
+	cfg := Config{
+		Domains: map[string]DomainConfig{
+			"example.org": {TLS: &DomainTLSConfig{CertFile: "/org/cert.pem", KeyFile: "/org/key.pem"}},
+			"example.com": {TLS: &DomainTLSConfig{CertFile: "/com/cert.pem", KeyFile: "/com/key.pem"}},
+			"example.net": {TLS: &DomainTLSConfig{}},
+			"example.io":  {},
+		},
+	}
+	got := cfg.DomainCertificates()
+	if len(got) != 2 || got[0] != "example.com" || got[1] != "example.org" {
+		t.Errorf("DomainCertificates() = %v, want [example.com example.org]", got)
+	}
+}
+
+func TestDomainRetention(t *testing.T) {
+	cfg := Config{
+		Domains: map[string]DomainConfig{
+			"example.org": {Retention: &DomainRetentionConfig{DeliveredMaxAge: 24 * time.Hour}},
+			"example.com": {Retention: &DomainRetentionConfig{SandboxMaxAge: time.Hour}},
+			"example.io":  {},
+		},
+	}
+	delivered, sandbox := cfg.DeliveredRetention(), cfg.SandboxRetention()
+	if len(delivered) != 1 || delivered["example.org"] != 24*time.Hour {
+		t.Errorf("DeliveredRetention() = %v", delivered)
+	}
+	if len(sandbox) != 1 || sandbox["example.com"] != time.Hour {
+		t.Errorf("SandboxRetention() = %v", sandbox)
+	}
+}
+
+func TestLoadFileNotFound(t *testing.T) {
+	_, err := Load("/nonexistent/config.yaml")
+	if err == nil {
+		t.Error("Load() expected error for nonexistent file")
+	}
+}
+
+func TestLoadInvalidYAML(t *testing.T) {
 	content := `invalid: yaml: content: [`
 	tmpDir := t.TempDir()
 	cfgPath := filepath.Join(tmpDir, "config.yaml")
@@ -449,3 +1521,50 @@ func TestLoadInvalidYAML(t *testing.T) {
 		t.Error("Load() expected error for invalid YAML")
 	}
 }
+
+func TestLoadSecretReferences(t *testing.T) {
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root-token" || r.URL.Path != "/v1/secret/data/sendry" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		fmt.Fprint(w, `{"data": {"data": {"api_key": "key-1", "password": "smtp-secret"}, "metadata": {}}}`)
+	}))
+	defer vault.Close()
+
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	content := `
+smtp:
+  domain: example.com
+  auth:
+    users:
+      app: "vault:secret/data/sendry#password"
+api:
+  api_key: "vault:secret/data/sendry#api_key"
+secrets:
+  vault:
+    address: ` + vault.URL + `
+    token: root-token
+`
+	if err := os.WriteFile(cfgPath, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(cfgPath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.API.APIKey != "key-1" || cfg.SMTP.Auth.Users["app"] != "smtp-secret" {
+		t.Errorf("api_key = %q, users = %v, want the Vault secrets", cfg.API.APIKey, cfg.SMTP.Auth.Users)
+	}
+	if cfg.SecretsDigest() == "" {
+		t.Error("SecretsDigest() is empty with references")
+	}
+
+	// A reference that cannot be resolved fails the load
+	t.Setenv("SENDRY_API_API_KEY", "vault:secret/data/other#api_key")
+	if _, err := Load(cfgPath); err == nil {
+		t.Error("Load() with an unknown secret succeeded")
+	}
+}
@@ -0,0 +1,182 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix is the prefix of the environment variables that override config
+// keys: smtp.listen_addr is SENDRY_SMTP_LISTEN_ADDR
+const EnvPrefix = "SENDRY"
+
+// envFileSuffix marks a variable holding the path of a file with the value,
+// e.g. SENDRY_API_API_KEY_FILE for a mounted secret
+const envFileSuffix = "_FILE"
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+)
+
+// applyEnv overrides config keys with environment variables. The name of a
+// key is its YAML path in upper case joined with underscores. Scalars take
+// their plain value, lists of scalars a comma separated list, maps, lists of
+// objects and whole sections a YAML or JSON document. A variable with the
+// _FILE suffix names a file to read the value from.
+func (c *Config) applyEnv() error {
+	return c.applyEnvFrom(os.Environ())
+}
+
+// applyEnvFrom applies the variables of environ, in the "KEY=value" form of
+// os.Environ
+func (c *Config) applyEnvFrom(environ []string) error {
+	return applyEnvStruct(reflect.ValueOf(c).Elem(), EnvPrefix, environ)
+}
+
+// applyEnvStruct applies the variables of the fields of a struct
+func applyEnvStruct(v reflect.Value, prefix string, environ []string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, inline := yamlKey(field)
+		if name == "-" {
+			continue
+		}
+		key := prefix
+		if !inline {
+			key = prefix + "_" + strings.ToUpper(name)
+		}
+		if err := applyEnvField(v.Field(i), key, environ); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyEnvField applies the variable of a key, or those of its fields
+func applyEnvField(v reflect.Value, key string, environ []string) error {
+	raw, ok, err := lookupEnv(environ, key)
+	if err != nil {
+		return err
+	}
+	if ok {
+		if err := setEnvValue(v, raw); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		return nil
+	}
+
+	switch {
+	case v.Kind() == reflect.Struct && v.Type() != timeType:
+		return applyEnvStruct(v, key, environ)
+	case v.Kind() == reflect.Pointer && v.Type().Elem().Kind() == reflect.Struct:
+		if v.IsNil() {
+			if !hasEnvPrefix(environ, key+"_") {
+				return nil
+			}
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return applyEnvStruct(v.Elem(), key, environ)
+	}
+	return nil
+}
+
+// lookupEnv returns the value of a variable of environ or the content of the
+// file named by its _FILE variable
+func lookupEnv(environ []string, key string) (string, bool, error) {
+	if value, ok := envValue(environ, key); ok {
+		return value, true, nil
+	}
+	path, ok := envValue(environ, key+envFileSuffix)
+	if !ok {
+		return "", false, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, fmt.Errorf("%s%s: %w", key, envFileSuffix, err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), true, nil
+}
+
+// setEnvValue sets a field from the value of its variable
+func setEnvValue(v reflect.Value, raw string) error {
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	case v.Kind() == reflect.String:
+		v.SetString(raw)
+		return nil
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+		return nil
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String && !strings.HasPrefix(strings.TrimSpace(raw), "["):
+		list := reflect.MakeSlice(v.Type(), 0, 0)
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = reflect.Append(list, reflect.ValueOf(item).Convert(v.Type().Elem()))
+			}
+		}
+		v.Set(list)
+		return nil
+	}
+
+	// Numbers, other lists, maps and sections are YAML, which also reads JSON
+	parsed := reflect.New(v.Type())
+	if err := yaml.Unmarshal([]byte(raw), parsed.Interface()); err != nil {
+		return err
+	}
+	v.Set(parsed.Elem())
+	return nil
+}
+
+// yamlKey returns the YAML key of a field and whether it is inlined
+func yamlKey(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("yaml")
+	name, opts, _ := strings.Cut(tag, ",")
+	if strings.Contains(opts, "inline") {
+		return "", true
+	}
+	if name == "" {
+		name = strings.ToLower(field.Name)
+	}
+	return name, false
+}
+
+// envValue returns the value of a variable of environ. The first one wins
+// when a variable is set twice, as with os.LookupEnv.
+func envValue(environ []string, key string) (string, bool) {
+	for _, kv := range environ {
+		if name, value, ok := strings.Cut(kv, "="); ok && name == key {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// hasEnvPrefix reports whether a variable starts with prefix
+func hasEnvPrefix(environ []string, prefix string) bool {
+	for _, kv := range environ {
+		if strings.HasPrefix(kv, prefix) {
+			return true
+		}
+	}
+	return false
+}
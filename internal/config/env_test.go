@@ -0,0 +1,133 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestLoadEnvOverrides(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	yamlData := `
+server:
+  hostname: yaml.example.com
+smtp:
+  domain: example.com
+  listen_addr: ":2525"
+  auth:
+    users:
+      alice: from-yaml
+api:
+  api_key: from-yaml
+`
+	if err := os.WriteFile(cfgPath, []byte(yamlData), 0600); err != nil {
+		t.Fatal(err)
+	}
+	secretPath := filepath.Join(dir, "api_key")
+	if err := os.WriteFile(secretPath, []byte("from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("SENDRY_SMTP_LISTEN_ADDR", ":25")
+	t.Setenv("SENDRY_SMTP_MAX_RECIPIENTS", "50")
+	t.Setenv("SENDRY_SMTP_READ_TIMEOUT", "2m")
+	t.Setenv("SENDRY_SMTP_AUTH_REQUIRED", "true")
+	t.Setenv("SENDRY_SMTP_AUTH_USERS", `{"bob": "secret"}`)
+	t.Setenv("SENDRY_SMTP_ALLOWED_IPS", "10.0.0.0/8, 192.168.1.10")
+	t.Setenv("SENDRY_API_API_KEY_FILE", secretPath)
+	t.Setenv("SENDRY_HEALTH_DRAIN_DELAY", "15s")
+	t.Setenv("SENDRY_RATE_LIMIT_DEFAULT_API_KEY_MESSAGES_PER_HOUR", "100")
+
+	cfg, err := Load(cfgPath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if cfg.Server.Hostname != "yaml.example.com" {
+		t.Errorf("Server.Hostname = %q, want the YAML value", cfg.Server.Hostname)
+	}
+	if cfg.SMTP.ListenAddr != ":25" {
+		t.Errorf("SMTP.ListenAddr = %q, want %q", cfg.SMTP.ListenAddr, ":25")
+	}
+	if cfg.SMTP.MaxRecipients != 50 {
+		t.Errorf("SMTP.MaxRecipients = %d, want 50", cfg.SMTP.MaxRecipients)
+	}
+	if cfg.SMTP.ReadTimeout != 2*time.Minute {
+		t.Errorf("SMTP.ReadTimeout = %v, want 2m", cfg.SMTP.ReadTimeout)
+	}
+	if !cfg.SMTP.Auth.Required {
+		t.Error("SMTP.Auth.Required = false, want true")
+	}
+	if len(cfg.SMTP.Auth.Users) != 1 || cfg.SMTP.Auth.Users["bob"] != "secret" {
+		t.Errorf("SMTP.Auth.Users = %v, want the variable to replace the YAML map", cfg.SMTP.Auth.Users)
+	}
+	if !slices.Equal(cfg.SMTP.AllowedIPs, []string{"10.0.0.0/8", "192.168.1.10"}) {
+		t.Errorf("SMTP.AllowedIPs = %v", cfg.SMTP.AllowedIPs)
+	}
+	if cfg.API.APIKey != "from-file" {
+		t.Errorf("API.APIKey = %q, want the file content", cfg.API.APIKey)
+	}
+	if cfg.Health.DrainDelay != 15*time.Second {
+		t.Errorf("Health.DrainDelay = %v, want 15s", cfg.Health.DrainDelay)
+	}
+	if cfg.RateLimit.DefaultAPIKey == nil || cfg.RateLimit.DefaultAPIKey.MessagesPerHour != 100 {
+		t.Errorf("RateLimit.DefaultAPIKey = %+v, want the section created from the variable", cfg.RateLimit.DefaultAPIKey)
+	}
+	if cfg.RateLimit.DefaultSender != nil {
+		t.Errorf("RateLimit.DefaultSender = %+v, want nil without variables", cfg.RateLimit.DefaultSender)
+	}
+}
+
+func TestLoadEnvErrors(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(cfgPath, []byte("smtp:\n  domain: example.com\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		key   string
+		value string
+	}{
+		{name: "bad number", key: "SENDRY_SMTP_MAX_RECIPIENTS", value: "many"},
+		{name: "bad duration", key: "SENDRY_SMTP_READ_TIMEOUT", value: "soon"},
+		{name: "bad bool", key: "SENDRY_SMTP_AUTH_REQUIRED", value: "maybe"},
+		{name: "missing file", key: "SENDRY_API_API_KEY_FILE", value: "/nonexistent/api_key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(tt.key, tt.value)
+			if _, err := Load(cfgPath); err == nil {
+				t.Errorf("Load() with %s=%q succeeded, want error", tt.key, tt.value)
+			}
+		})
+	}
+}
+
+func TestApplyEnvFrom(t *testing.T) {
+	t.Setenv("SENDRY_SMTP_MAX_RECIPIENTS", "999")
+
+	var cfg Config
+	environ := []string{
+		"SENDRY_SMTP_MAX_RECIPIENTS=20",
+		"SENDRY_SMTP_MAX_RECIPIENTS=30",
+		"SENDRY_API_LISTEN_ADDR=:9090",
+		"SENDRY_RATE_LIMIT_GLOBAL_MESSAGES_PER_HOUR=5",
+	}
+	if err := cfg.applyEnvFrom(environ); err != nil {
+		t.Fatalf("applyEnvFrom() error = %v", err)
+	}
+	if cfg.SMTP.MaxRecipients != 20 {
+		t.Errorf("MaxRecipients = %d, want 20 from the given environment", cfg.SMTP.MaxRecipients)
+	}
+	if cfg.API.ListenAddr != ":9090" {
+		t.Errorf("ListenAddr = %q", cfg.API.ListenAddr)
+	}
+	if cfg.RateLimit.Global == nil || cfg.RateLimit.Global.MessagesPerHour != 5 {
+		t.Errorf("Global = %+v", cfg.RateLimit.Global)
+	}
+}
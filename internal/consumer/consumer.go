@@ -0,0 +1,277 @@
+// Package consumer pulls send requests from a message broker (NATS JetStream
+// or Kafka) and queues them like the send API does. Deliveries are
+// acknowledged only after the message is queued, so a request is delivered at
+// least once. Payloads that can never be accepted are dead-lettered.
+package consumer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/foxzi/sendry/internal/api"
+	"github.com/foxzi/sendry/internal/idempotency"
+	"github.com/foxzi/sendry/internal/metrics"
+	"github.com/foxzi/sendry/internal/queue"
+)
+
+// Results of a delivery, used as metric label
+const (
+	ResultQueued     = "queued"
+	ResultDuplicate  = "duplicate"
+	ResultDeadLetter = "dead_letter"
+)
+
+const (
+	// Headers set on dead-lettered payloads
+	HeaderError  = "Sendry-Error"
+	HeaderSource = "Sendry-Source"
+
+	// idempotencyScope separates consumer keys from send API keys
+	idempotencyScope = "consumer\x00"
+
+	defaultRetryDelay = time.Second
+	maxRetryDelay     = 30 * time.Second
+)
+
+// Delivery is a send request received from a broker
+type Delivery struct {
+	Data    []byte
+	Headers map[string]string
+	ID      string // Broker message ID used for deduplication, may be empty
+
+	raw     any  // Broker message, used by the source to acknowledge it
+	handled bool // Queued or dead-lettered, only the ack is missing
+}
+
+// header returns a header of the delivery, the name is case-insensitive
+func (d *Delivery) header(name string) string {
+	if v, ok := d.Headers[name]; ok {
+		return v
+	}
+	for k, v := range d.Headers {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return ""
+}
+
+// Source receives send requests from a broker
+type Source interface {
+	// Name identifies the source in logs, metrics and dead letters
+	Name() string
+	// Receive waits for the next delivery
+	Receive(ctx context.Context) (*Delivery, error)
+	// Ack confirms a delivery, so it is not delivered again
+	Ack(ctx context.Context, d *Delivery) error
+	// DeadLetter publishes a delivery that can never be accepted
+	DeadLetter(ctx context.Context, d *Delivery, reason string) error
+	// Close disconnects from the broker
+	Close() error
+}
+
+// Builder validates a send request and builds its queue message
+type Builder interface {
+	BuildMessage(req *api.SendRequest, source string) (*queue.Message, error)
+}
+
+// Consumer moves send requests from a source to the queue
+type Consumer struct {
+	source      Source
+	builder     Builder
+	queue       queue.Queue
+	idempotency *idempotency.Storage
+	logger      *slog.Logger
+	retryDelay  time.Duration
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New creates a new consumer
+func New(source Source, builder Builder, q queue.Queue, logger *slog.Logger) *Consumer {
+	return &Consumer{
+		source:     source,
+		builder:    builder,
+		queue:      q,
+		logger:     logger.With("component", "consumer", "source", source.Name()),
+		retryDelay: defaultRetryDelay,
+	}
+}
+
+// SetIdempotencyStorage enables deduplication of redelivered requests by
+// their Idempotency-Key header or broker message ID
+func (c *Consumer) SetIdempotencyStorage(s *idempotency.Storage) {
+	c.idempotency = s
+}
+
+// Start starts the consumer goroutine
+func (c *Consumer) Start(ctx context.Context) {
+	ctx, c.cancel = context.WithCancel(ctx)
+	c.wg.Add(1)
+	go c.loop(ctx)
+
+	c.logger.Info("consumer started")
+}
+
+// Stop stops the consumer, waits for the delivery in progress and
+// disconnects from the broker. An unacknowledged delivery is redelivered.
+func (c *Consumer) Stop() error {
+	if c.cancel != nil {
+		c.cancel()
+	}
+	c.wg.Wait()
+	return c.source.Close()
+}
+
+func (c *Consumer) loop(ctx context.Context) {
+	defer c.wg.Done()
+
+	// A failed delivery is retried before the next one is received: Kafka
+	// commits offsets in order, so skipping it would commit it too
+	var d *Delivery
+	delay := c.retryDelay
+	for ctx.Err() == nil {
+		var err error
+		if d == nil {
+			d, err = c.source.Receive(ctx)
+		}
+		if err == nil && !d.handled {
+			if err = c.handle(ctx, d); err == nil {
+				d.handled = true
+			}
+		}
+		if err == nil {
+			if err = c.source.Ack(ctx, d); err == nil {
+				d = nil
+				delay = c.retryDelay
+				continue
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		c.logger.Error("consumer error, retrying", "error", err, "delay", delay)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxRetryDelay)
+	}
+}
+
+// handle queues or dead-letters a delivery. On error it is retried, and
+// delivered again by the source if sendry stops before that.
+func (c *Consumer) handle(ctx context.Context, d *Delivery) error {
+	var req api.SendRequest
+	if err := json.Unmarshal(d.Data, &req); err != nil {
+		return c.deadLetter(ctx, d, "invalid JSON: "+err.Error())
+	}
+
+	key := d.header(idempotency.Header)
+	if key == "" {
+		key = d.ID
+	}
+	if key != "" && c.idempotency != nil {
+		if !idempotency.ValidKey(key) {
+			return c.deadLetter(ctx, d, "invalid "+idempotency.Header+" header")
+		}
+		return c.handleOnce(ctx, d, &req, idempotencyScope+key)
+	}
+
+	msg, err := c.builder.BuildMessage(&req, c.source.Name())
+	if err != nil {
+		return c.deadLetter(ctx, d, err.Error())
+	}
+	if err := c.queue.Enqueue(ctx, msg); err != nil {
+		return err
+	}
+	c.queued(msg)
+	return nil
+}
+
+// handleOnce queues a delivery unless a delivery with the same key was
+// queued before
+func (c *Consumer) handleOnce(ctx context.Context, d *Delivery, req *api.SendRequest, scope string) error {
+	sum := sha256.Sum256(d.Data)
+	state, _, err := c.idempotency.Claim(ctx, scope, hex.EncodeToString(sum[:]))
+	if err != nil {
+		return err
+	}
+
+	switch state {
+	case idempotency.Completed:
+		c.logger.Info("duplicate send request acknowledged", "key", scope[len(idempotencyScope):])
+		metrics.IncConsumerMessages(c.source.Name(), ResultDuplicate)
+		return nil
+	case idempotency.Mismatch:
+		return c.deadLetter(ctx, d, "idempotency key was used for a different payload")
+	case idempotency.InProgress:
+		return errors.New("send request with the same idempotency key is in progress")
+	}
+
+	msg, err := c.builder.BuildMessage(req, c.source.Name())
+	if err != nil {
+		if rerr := c.idempotency.Release(context.Background(), scope); rerr != nil {
+			c.logger.Error("failed to release idempotency key", "error", rerr)
+		}
+		return c.deadLetter(ctx, d, err.Error())
+	}
+	if err := c.queue.Enqueue(ctx, msg); err != nil {
+		if rerr := c.idempotency.Release(context.Background(), scope); rerr != nil {
+			c.logger.Error("failed to release idempotency key", "error", rerr)
+		}
+		return err
+	}
+	c.queued(msg)
+
+	body, _ := json.Marshal(api.SendResponse{ID: msg.ID, Status: string(msg.Status)})
+	if err := c.idempotency.Complete(context.Background(), scope, &idempotency.Record{
+		Status:      http.StatusAccepted,
+		ContentType: "application/json",
+		Body:        body,
+	}); err != nil {
+		c.logger.Error("failed to store idempotency key", "error", err)
+	}
+	return nil
+}
+
+// deadLetter publishes a payload that can never be queued
+func (c *Consumer) deadLetter(ctx context.Context, d *Delivery, reason string) error {
+	if err := c.source.DeadLetter(ctx, d, reason); err != nil {
+		return err
+	}
+	c.logger.Warn("send request dead-lettered", "reason", reason)
+	metrics.IncConsumerMessages(c.source.Name(), ResultDeadLetter)
+	return nil
+}
+
+func (c *Consumer) queued(msg *queue.Message) {
+	c.logger.Info("message queued via consumer",
+		"id", msg.ID,
+		"from", msg.From,
+		"to", msg.To,
+	)
+	metrics.IncConsumerMessages(c.source.Name(), ResultQueued)
+}
+
+// deadLetterHeaders returns the headers of a dead-lettered payload
+func deadLetterHeaders(d *Delivery, source, reason string) map[string]string {
+	headers := make(map[string]string, len(d.Headers)+2)
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+	headers[HeaderError] = reason
+	headers[HeaderSource] = source
+	return headers
+}
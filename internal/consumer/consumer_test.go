@@ -0,0 +1,190 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/foxzi/sendry/internal/api"
+	"github.com/foxzi/sendry/internal/config"
+	"github.com/foxzi/sendry/internal/idempotency"
+	"github.com/foxzi/sendry/internal/queue"
+)
+
+const validRequest = `{"from": "sender@example.com", "to": ["a@example.com"], "subject": "Hi", "body": "Hello"}`
+
+// memorySource delivers payloads from a channel and records the outcome
+type memorySource struct {
+	deliveries chan *Delivery
+
+	mu          sync.Mutex
+	acked       []*Delivery
+	deadLetters []string
+	ackCh       chan struct{}
+}
+
+func newMemorySource(payloads ...*Delivery) *memorySource {
+	s := &memorySource{
+		deliveries: make(chan *Delivery, len(payloads)),
+		ackCh:      make(chan struct{}, len(payloads)),
+	}
+	for _, d := range payloads {
+		s.deliveries <- d
+	}
+	return s
+}
+
+func (s *memorySource) Name() string { return "memory" }
+
+func (s *memorySource) Receive(ctx context.Context) (*Delivery, error) {
+	select {
+	case d := <-s.deliveries:
+		return d, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (s *memorySource) Ack(ctx context.Context, d *Delivery) error {
+	s.mu.Lock()
+	s.acked = append(s.acked, d)
+	s.mu.Unlock()
+	s.ackCh <- struct{}{}
+	return nil
+}
+
+func (s *memorySource) DeadLetter(ctx context.Context, d *Delivery, reason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deadLetters = append(s.deadLetters, reason)
+	return nil
+}
+
+func (s *memorySource) Close() error { return nil }
+
+// waitAcks waits until n deliveries are acknowledged
+func (s *memorySource) waitAcks(t *testing.T, n int) {
+	t.Helper()
+	for range n {
+		select {
+		case <-s.ackCh:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for ack")
+		}
+	}
+}
+
+// recordQueue records enqueued messages and fails the first failures calls
+type recordQueue struct {
+	queue.Queue
+	mu       sync.Mutex
+	failures int
+	enqueued []*queue.Message
+}
+
+func (q *recordQueue) Enqueue(ctx context.Context, msg *queue.Message) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.failures > 0 {
+		q.failures--
+		return errors.New("storage unavailable")
+	}
+	q.enqueued = append(q.enqueued, msg)
+	return nil
+}
+
+func startConsumer(t *testing.T, source Source, q *recordQueue, store *idempotency.Storage) *Consumer {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	builder := api.NewServer(q, &config.APIConfig{}, logger)
+
+	c := New(source, builder, q, logger)
+	c.retryDelay = time.Millisecond
+	if store != nil {
+		c.SetIdempotencyStorage(store)
+	}
+	c.Start(context.Background())
+	t.Cleanup(func() { c.Stop() })
+	return c
+}
+
+func TestConsumerQueuesAndDeadLetters(t *testing.T) {
+	source := newMemorySource(
+		&Delivery{Data: []byte(validRequest)},
+		&Delivery{Data: []byte(`{"from": `)},
+		&Delivery{Data: []byte(`{"from": "not an address", "to": ["a@example.com"], "subject": "Hi"}`)},
+	)
+	q := &recordQueue{}
+	startConsumer(t, source, q, nil)
+	source.waitAcks(t, 3)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.enqueued) != 1 || q.enqueued[0].From != "sender@example.com" || q.enqueued[0].ClientIP != "memory" {
+		t.Errorf("enqueued = %+v", q.enqueued)
+	}
+
+	source.mu.Lock()
+	defer source.mu.Unlock()
+	if len(source.deadLetters) != 2 ||
+		!strings.HasPrefix(source.deadLetters[0], "invalid JSON") ||
+		source.deadLetters[1] != "invalid from address" {
+		t.Errorf("dead letters = %q", source.deadLetters)
+	}
+}
+
+func TestConsumerRetriesUntilQueued(t *testing.T) {
+	source := newMemorySource(&Delivery{Data: []byte(validRequest)})
+	q := &recordQueue{failures: 2}
+	startConsumer(t, source, q, nil)
+	source.waitAcks(t, 1)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.enqueued) != 1 || q.failures != 0 {
+		t.Errorf("enqueued = %d, failures left = %d", len(q.enqueued), q.failures)
+	}
+}
+
+func TestConsumerDeduplicates(t *testing.T) {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	store, err := idempotency.NewStorage(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	headers := map[string]string{"idempotency-key": "order-42"}
+	source := newMemorySource(
+		&Delivery{Data: []byte(validRequest), Headers: headers},
+		&Delivery{Data: []byte(validRequest), Headers: headers},
+		&Delivery{Data: []byte(validRequest), ID: "msg-1"},
+		&Delivery{Data: []byte(`{"from": "sender@example.com", "to": ["b@example.com"], "subject": "Other"}`), Headers: headers},
+	)
+	q := &recordQueue{}
+	startConsumer(t, source, q, store)
+	source.waitAcks(t, 4)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.enqueued) != 2 {
+		t.Errorf("enqueued = %d, want 2", len(q.enqueued))
+	}
+
+	source.mu.Lock()
+	defer source.mu.Unlock()
+	if len(source.deadLetters) != 1 || !strings.Contains(source.deadLetters[0], "different payload") {
+		t.Errorf("dead letters = %q", source.deadLetters)
+	}
+}
@@ -0,0 +1,84 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/segmentio/kafka-go"
+
+	"github.com/foxzi/sendry/internal/config"
+)
+
+// KafkaSource receives send requests as a member of a Kafka consumer group.
+// Offsets are committed as messages are acknowledged.
+type KafkaSource struct {
+	cfg    config.KafkaConsumerConfig
+	reader *kafka.Reader
+	writer *kafka.Writer
+}
+
+// NewKafkaSource creates a consumer group reader and a dead letter writer
+func NewKafkaSource(cfg config.KafkaConsumerConfig) *KafkaSource {
+	return &KafkaSource{
+		cfg: cfg,
+		reader: kafka.NewReader(kafka.ReaderConfig{
+			Brokers: cfg.Brokers,
+			Topic:   cfg.Topic,
+			GroupID: cfg.GroupID,
+		}),
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(cfg.Brokers...),
+			Topic:        cfg.DeadLetterTopic,
+			RequiredAcks: kafka.RequireAll,
+		},
+	}
+}
+
+// Name returns the source name
+func (s *KafkaSource) Name() string {
+	return "kafka:" + s.cfg.Topic
+}
+
+// Receive fetches the next message of the group without committing it
+func (s *KafkaSource) Receive(ctx context.Context) (*Delivery, error) {
+	msg, err := s.reader.FetchMessage(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Delivery{
+		Data:    msg.Value,
+		Headers: make(map[string]string, len(msg.Headers)),
+		raw:     msg,
+	}
+	for _, h := range msg.Headers {
+		d.Headers[h.Key] = string(h.Value)
+	}
+	return d, nil
+}
+
+// Ack commits the offset of a message
+func (s *KafkaSource) Ack(ctx context.Context, d *Delivery) error {
+	return s.reader.CommitMessages(ctx, d.raw.(kafka.Message))
+}
+
+// DeadLetter writes a message to the dead letter topic, keeping its key
+func (s *KafkaSource) DeadLetter(ctx context.Context, d *Delivery, reason string) error {
+	msg := kafka.Message{
+		Key:   d.raw.(kafka.Message).Key,
+		Value: d.Data,
+	}
+	for k, v := range deadLetterHeaders(d, s.Name(), reason) {
+		msg.Headers = append(msg.Headers, kafka.Header{Key: k, Value: []byte(v)})
+	}
+	if err := s.writer.WriteMessages(ctx, msg); err != nil {
+		return fmt.Errorf("failed to write dead letter: %w", err)
+	}
+	return nil
+}
+
+// Close leaves the consumer group and closes the dead letter writer
+func (s *KafkaSource) Close() error {
+	return errors.Join(s.reader.Close(), s.writer.Close())
+}
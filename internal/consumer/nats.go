@@ -0,0 +1,115 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/foxzi/sendry/internal/config"
+)
+
+// natsFetchWait limits how long one fetch waits, so Receive notices a
+// cancelled context
+const natsFetchWait = 5 * time.Second
+
+// NATSSource receives send requests from a JetStream durable pull consumer
+type NATSSource struct {
+	cfg      config.NATSConsumerConfig
+	conn     *nats.Conn
+	js       jetstream.JetStream
+	consumer jetstream.Consumer
+}
+
+// NewNATSSource connects to NATS and creates or updates the durable consumer.
+// The stream must exist, and a stream must capture the dead letter subject
+// for dead letters to be acknowledged.
+func NewNATSSource(ctx context.Context, cfg config.NATSConsumerConfig) (*NATSSource, error) {
+	opts := []nats.Option{nats.Name("sendry"), nats.MaxReconnects(-1)}
+	if cfg.CredentialsFile != "" {
+		opts = append(opts, nats.UserCredentials(cfg.CredentialsFile))
+	}
+
+	conn, err := nats.Connect(cfg.URL, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to open JetStream: %w", err)
+	}
+
+	consumer, err := js.CreateOrUpdateConsumer(ctx, cfg.Stream, jetstream.ConsumerConfig{
+		Durable:       cfg.Durable,
+		FilterSubject: cfg.Subject,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		AckWait:       cfg.AckWait,
+	})
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to create JetStream consumer %s on stream %s: %w", cfg.Durable, cfg.Stream, err)
+	}
+
+	return &NATSSource{cfg: cfg, conn: conn, js: js, consumer: consumer}, nil
+}
+
+// Name returns the source name
+func (s *NATSSource) Name() string {
+	return "nats:" + s.cfg.Subject
+}
+
+// Receive waits for the next message of the consumer
+func (s *NATSSource) Receive(ctx context.Context) (*Delivery, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		msg, err := s.consumer.Next(jetstream.FetchMaxWait(natsFetchWait))
+		if errors.Is(err, nats.ErrTimeout) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		d := &Delivery{
+			Data:    msg.Data(),
+			Headers: make(map[string]string, len(msg.Headers())),
+			ID:      msg.Headers().Get(jetstream.MsgIDHeader),
+			raw:     msg,
+		}
+		for k := range msg.Headers() {
+			d.Headers[k] = msg.Headers().Get(k)
+		}
+		return d, nil
+	}
+}
+
+// Ack acknowledges a message and waits for the server to confirm it
+func (s *NATSSource) Ack(ctx context.Context, d *Delivery) error {
+	return d.raw.(jetstream.Msg).DoubleAck(ctx)
+}
+
+// DeadLetter publishes a message to the dead letter subject
+func (s *NATSSource) DeadLetter(ctx context.Context, d *Delivery, reason string) error {
+	msg := nats.NewMsg(s.cfg.DeadLetterSubject)
+	msg.Data = d.Data
+	for k, v := range deadLetterHeaders(d, s.Name(), reason) {
+		msg.Header.Set(k, v)
+	}
+	if _, err := s.js.PublishMsg(ctx, msg); err != nil {
+		return fmt.Errorf("failed to publish dead letter: %w", err)
+	}
+	return nil
+}
+
+// Close drains the connection
+func (s *NATSSource) Close() error {
+	return s.conn.Drain()
+}
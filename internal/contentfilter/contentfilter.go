@@ -0,0 +1,170 @@
+// Package contentfilter passes messages to an external filter before they
+// are queued: an HTTP endpoint or a milter such as rspamd or
+// SpamAssassin's spamass-milter. The filter accepts, rejects, defers or
+// modifies each message.
+package contentfilter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/foxzi/sendry/internal/config"
+	"github.com/foxzi/sendry/internal/metrics"
+)
+
+// Action is the verdict of a filter
+type Action string
+
+const (
+	ActionAccept   Action = "accept"   // Queue the message unchanged
+	ActionModify   Action = "modify"   // Queue the modified message
+	ActionReject   Action = "reject"   // Refuse the message permanently
+	ActionTempfail Action = "tempfail" // Refuse the message, the client may retry
+	ActionDiscard  Action = "discard"  // Accept the message and drop it
+)
+
+// Envelope describes a message to filter
+type Envelope struct {
+	ID       string   `json:"id"`
+	Source   string   `json:"source"` // smtp or api
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+	ClientIP string   `json:"client_ip,omitempty"`
+	Helo     string   `json:"helo,omitempty"`
+	AuthUser string   `json:"auth_user,omitempty"`
+}
+
+// Verdict is the outcome of a check
+type Verdict struct {
+	Action       Action
+	Code         int    // SMTP reply code of a refusal, 0 = default
+	EnhancedCode [3]int // Enhanced status code of a refusal, zero = default
+	Message      string // Reply text of a refusal
+	Data         []byte // Message data after ActionModify
+}
+
+// Filter checks a message
+type Filter interface {
+	Check(ctx context.Context, env *Envelope, data []byte) (*Verdict, error)
+}
+
+// Checker runs the configured filter for the enabled message sources,
+// bounded by the timeout. A failing filter accepts or defers messages
+// depending on the error policy.
+type Checker struct {
+	filter   Filter
+	timeout  time.Duration
+	failOpen bool
+	sources  map[string]bool
+	logger   *slog.Logger
+}
+
+// New creates a checker for the content filter configuration. hostname is
+// passed to milters as the name of this server.
+func New(cfg config.ContentFilterConfig, hostname string, logger *slog.Logger) (*Checker, error) {
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+
+	var filter Filter
+	switch cfg.Type {
+	case config.ContentFilterHTTP:
+		filter = NewHTTPFilter(cfg.URL)
+	case config.ContentFilterMilter:
+		m, err := NewMilter(cfg.Address, hostname)
+		if err != nil {
+			return nil, err
+		}
+		filter = m
+	default:
+		return nil, fmt.Errorf("unknown content filter type: %q", cfg.Type)
+	}
+
+	sources := make(map[string]bool, len(cfg.Sources))
+	for _, source := range cfg.Sources {
+		sources[source] = true
+	}
+	return NewChecker(filter, cfg.Timeout, cfg.OnError == config.ContentFilterOnErrorAccept, sources, logger), nil
+}
+
+// NewChecker creates a checker for a filter. Messages of sources not in
+// sources are not checked.
+func NewChecker(filter Filter, timeout time.Duration, failOpen bool, sources map[string]bool, logger *slog.Logger) *Checker {
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	return &Checker{
+		filter:   filter,
+		timeout:  timeout,
+		failOpen: failOpen,
+		sources:  sources,
+		logger:   logger,
+	}
+}
+
+// Applies reports whether messages of a source are checked
+func (c *Checker) Applies(source string) bool {
+	return c != nil && c.sources[source]
+}
+
+// Check passes a message to the filter. It always returns a verdict: a
+// failed check accepts the message with the accept error policy and
+// defers it otherwise.
+func (c *Checker) Check(ctx context.Context, env *Envelope, data []byte) *Verdict {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	v, err := c.filter.Check(ctx, env, data)
+	if err != nil {
+		c.logger.Error("content filter failed", "id", env.ID, "source", env.Source, "error", err)
+		metrics.IncContentFilter(env.Source, "error")
+		if c.failOpen {
+			return &Verdict{Action: ActionAccept}
+		}
+		return &Verdict{Action: ActionTempfail, Message: "Content filter unavailable, try again later"}
+	}
+
+	if v.Action == ActionModify && v.Data == nil {
+		v.Action = ActionAccept
+	}
+	metrics.IncContentFilter(env.Source, string(v.Action))
+	if v.Action != ActionAccept {
+		c.logger.Info("content filter verdict",
+			"id", env.ID,
+			"source", env.Source,
+			"from", env.From,
+			"action", v.Action,
+			"code", v.Code,
+			"message", v.Message,
+		)
+	}
+	return v
+}
+
+// parseEnhancedCode parses an enhanced status code such as 5.7.1
+func parseEnhancedCode(s string) ([3]int, bool) {
+	var code [3]int
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return code, false
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 || n > 999 {
+			return code, false
+		}
+		code[i] = n
+	}
+	if code[0] != 2 && code[0] != 4 && code[0] != 5 {
+		return code, false
+	}
+	return code, true
+}
@@ -0,0 +1,113 @@
+package contentfilter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/foxzi/sendry/internal/config"
+)
+
+const testMessage = "From: alice@example.com\r\nTo: bob@example.net\r\nSubject: Hello\r\n\r\nHi Bob\r\n"
+
+func testEnvelope() *Envelope {
+	return &Envelope{
+		ID:       "msg-1",
+		Source:   config.ContentFilterSourceSMTP,
+		From:     "alice@example.com",
+		To:       []string{"bob@example.net"},
+		ClientIP: "192.0.2.1:41234",
+		Helo:     "mail.example.com",
+	}
+}
+
+func TestHTTPFilter(t *testing.T) {
+	var got HTTPRequest
+	response := `{"action": "accept"}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		w.Write([]byte(response))
+	}))
+	defer srv.Close()
+	f := NewHTTPFilter(srv.URL)
+
+	v, err := f.Check(context.Background(), testEnvelope(), []byte(testMessage))
+	if err != nil || v.Action != ActionAccept {
+		t.Fatalf("Check() = %+v, %v, want accept", v, err)
+	}
+	if got.ID != "msg-1" || got.From != "alice@example.com" || string(got.Raw) != testMessage {
+		t.Errorf("request = %+v", got)
+	}
+
+	response = `{"action": "reject", "code": 554, "enhanced_code": "5.7.1", "message": "Spam"}`
+	v, err = f.Check(context.Background(), testEnvelope(), []byte(testMessage))
+	if err != nil || v.Action != ActionReject || v.Code != 554 || v.EnhancedCode != [3]int{5, 7, 1} || v.Message != "Spam" {
+		t.Errorf("Check() = %+v, %v, want reject", v, err)
+	}
+
+	response = `{"action": "modify", "headers": [{"action": "add", "header": "X-Spam-Score", "value": "3.2"}]}`
+	v, err = f.Check(context.Background(), testEnvelope(), []byte(testMessage))
+	if err != nil || v.Action != ActionModify || !strings.Contains(string(v.Data), "X-Spam-Score: 3.2\r\n") {
+		t.Errorf("Check() = %+v, %v, want modified headers", v, err)
+	}
+
+	response = `{"action": "quarantine"}`
+	if _, err := f.Check(context.Background(), testEnvelope(), []byte(testMessage)); err == nil {
+		t.Error("Check() with an unknown action succeeded")
+	}
+}
+
+func TestHTTPFilterError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "overloaded", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	if _, err := NewHTTPFilter(srv.URL).Check(context.Background(), testEnvelope(), []byte(testMessage)); err == nil {
+		t.Error("Check() with a 503 response succeeded")
+	}
+}
+
+// failingFilter fails every check
+type failingFilter struct{}
+
+func (failingFilter) Check(ctx context.Context, env *Envelope, data []byte) (*Verdict, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestCheckerErrorPolicy(t *testing.T) {
+	sources := map[string]bool{config.ContentFilterSourceSMTP: true}
+
+	c := NewChecker(failingFilter{}, time.Second, false, sources, nil)
+	if v := c.Check(context.Background(), testEnvelope(), []byte(testMessage)); v.Action != ActionTempfail {
+		t.Errorf("Check() = %+v, want tempfail", v)
+	}
+	c = NewChecker(failingFilter{}, time.Second, true, sources, nil)
+	if v := c.Check(context.Background(), testEnvelope(), []byte(testMessage)); v.Action != ActionAccept {
+		t.Errorf("Check() with the accept policy = %+v, want accept", v)
+	}
+
+	if !c.Applies(config.ContentFilterSourceSMTP) || c.Applies(config.ContentFilterSourceAPI) {
+		t.Error("Applies() does not follow the configured sources")
+	}
+	var none *Checker
+	if none.Applies(config.ContentFilterSourceSMTP) {
+		t.Error("nil checker applies")
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(config.ContentFilterConfig{Type: config.ContentFilterMilter, Address: "unix:/run/rspamd/milter.sock"}, "mx.example.com", nil); err != nil {
+		t.Errorf("New(milter) error = %v", err)
+	}
+	if _, err := New(config.ContentFilterConfig{Type: config.ContentFilterMilter, Address: "rspamd"}, "mx.example.com", nil); err == nil {
+		t.Error("New() with an invalid milter address succeeded")
+	}
+}
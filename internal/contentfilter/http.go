@@ -0,0 +1,104 @@
+package contentfilter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/foxzi/sendry/internal/headers"
+)
+
+// maxHTTPResponseBytes bounds the response of an HTTP filter, which may
+// carry a replacement message
+const maxHTTPResponseBytes = 64 << 20
+
+// HTTPRequest is the JSON body posted to an HTTP filter
+type HTTPRequest struct {
+	Envelope
+	Raw []byte `json:"raw"` // Message data, base64 encoded
+}
+
+// HTTPResponse is the JSON verdict of an HTTP filter
+type HTTPResponse struct {
+	Action       Action         `json:"action"`
+	Code         int            `json:"code,omitempty"`          // SMTP reply code of a refusal
+	EnhancedCode string         `json:"enhanced_code,omitempty"` // e.g. 5.7.1
+	Message      string         `json:"message,omitempty"`
+	Headers      []headers.Rule `json:"headers,omitempty"` // Header changes of a modify verdict
+	Raw          []byte         `json:"raw,omitempty"`     // Replacement message of a modify verdict, base64 encoded
+}
+
+// HTTPFilter posts messages to an HTTP endpoint and reads the verdict
+// from its JSON response
+type HTTPFilter struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTPFilter creates a filter for an HTTP endpoint
+func NewHTTPFilter(url string) *HTTPFilter {
+	return &HTTPFilter{url: url, client: &http.Client{}}
+}
+
+// Check posts the message and returns the verdict of the endpoint. Non-2xx
+// responses are errors.
+func (f *HTTPFilter) Check(ctx context.Context, env *Envelope, data []byte) (*Verdict, error) {
+	body, err := json.Marshal(&HTTPRequest{Envelope: *env, Raw: data})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, f.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "sendry-content-filter")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		return nil, fmt.Errorf("content filter returned %s", resp.Status)
+	}
+
+	var r HTTPResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxHTTPResponseBytes)).Decode(&r); err != nil {
+		return nil, fmt.Errorf("invalid content filter response: %w", err)
+	}
+	return r.verdict(data)
+}
+
+// verdict converts the response to a verdict for the message data
+func (r *HTTPResponse) verdict(data []byte) (*Verdict, error) {
+	v := &Verdict{Action: r.Action, Code: r.Code, Message: r.Message}
+	if r.EnhancedCode != "" {
+		code, ok := parseEnhancedCode(r.EnhancedCode)
+		if !ok {
+			return nil, fmt.Errorf("invalid enhanced code in content filter response: %q", r.EnhancedCode)
+		}
+		v.EnhancedCode = code
+	}
+
+	switch r.Action {
+	case ActionAccept, ActionReject, ActionTempfail, ActionDiscard:
+	case ActionModify:
+		if len(r.Raw) > 0 {
+			data = r.Raw
+		}
+		if len(r.Headers) > 0 {
+			data = headers.Apply(data, r.Headers)
+		}
+		v.Data = data
+	default:
+		return nil, fmt.Errorf("unknown content filter action: %q", r.Action)
+	}
+	return v, nil
+}
@@ -0,0 +1,581 @@
+package contentfilter
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Milter protocol version spoken to filters
+const milterVersion = 6
+
+// Commands sent to a milter
+const (
+	cmdBody    = 'B'
+	cmdConnect = 'C'
+	cmdMacro   = 'D'
+	cmdEOB     = 'E'
+	cmdHelo    = 'H'
+	cmdHeader  = 'L'
+	cmdMail    = 'M'
+	cmdEOH     = 'N'
+	cmdOptNeg  = 'O'
+	cmdQuit    = 'Q'
+	cmdRcpt    = 'R'
+	cmdData    = 'T'
+)
+
+// Responses of a milter
+const (
+	respAccept     = 'a'
+	respContinue   = 'c'
+	respDiscard    = 'd'
+	respReject     = 'r'
+	respTempfail   = 't'
+	respReplyCode  = 'y'
+	respSkip       = 's'
+	respProgress   = 'p'
+	respAddHeader  = 'h'
+	respInsHeader  = 'i'
+	respChgHeader  = 'm'
+	respReplBody   = 'b'
+	respQuarantine = 'q'
+	respOptNeg     = 'O'
+)
+
+// Message modifications offered to a milter: headers and body. Recipient
+// and sender changes are not supported.
+const (
+	actAddHeaders = 0x01
+	actChgBody    = 0x02
+	actChgHeaders = 0x10
+
+	actOffered = actAddHeaders | actChgBody | actChgHeaders
+)
+
+// Protocol steps a milter may skip (no*) or not reply to (nr*)
+const (
+	protoNoConnect = 0x01
+	protoNoHelo    = 0x02
+	protoNoMail    = 0x04
+	protoNoRcpt    = 0x08
+	protoNoBody    = 0x10
+	protoNoHeaders = 0x20
+	protoNoEOH     = 0x40
+	protoNRHeader  = 0x80
+	protoNoData    = 0x200
+	protoSkip      = 0x400
+	protoNRConnect = 0x1000
+	protoNRHelo    = 0x2000
+	protoNRMail    = 0x4000
+	protoNRRcpt    = 0x8000
+	protoNRData    = 0x10000
+	protoNREOH     = 0x40000
+	protoNRBody    = 0x80000
+
+	protoOffered = protoNoConnect | protoNoHelo | protoNoMail | protoNoRcpt |
+		protoNoBody | protoNoHeaders | protoNoEOH | protoNRHeader | protoNoData |
+		protoSkip | protoNRConnect | protoNRHelo | protoNRMail | protoNRRcpt |
+		protoNRData | protoNREOH | protoNRBody
+)
+
+const (
+	// maxBodyChunk is the largest body chunk sent in one packet
+	maxBodyChunk = 65535
+	// maxMilterPacket bounds packets read from a milter
+	maxMilterPacket = 1 << 20
+)
+
+// Milter passes messages to a filter speaking the Sendmail milter
+// protocol, e.g. rspamd or spamass-milter. A connection is opened per
+// message.
+type Milter struct {
+	network  string
+	address  string
+	hostname string
+	dialer   net.Dialer
+}
+
+// NewMilter creates a filter for a milter socket: unix:/path,
+// inet:host:port, inet:port@host or host:port. hostname is passed as the
+// name of this server (macro j).
+func NewMilter(address, hostname string) (*Milter, error) {
+	network, addr, err := parseMilterAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	return &Milter{network: network, address: addr, hostname: hostname}, nil
+}
+
+// parseMilterAddress returns the network and address of a milter socket
+func parseMilterAddress(address string) (string, string, error) {
+	kind, addr, ok := strings.Cut(address, ":")
+	switch {
+	case ok && (kind == "unix" || kind == "local"):
+		if addr == "" {
+			return "", "", fmt.Errorf("invalid milter address: %q", address)
+		}
+		return "unix", addr, nil
+	case ok && (kind == "inet" || kind == "inet6"):
+		// Sendmail writes port@host
+		if port, host, ok := strings.Cut(addr, "@"); ok {
+			addr = net.JoinHostPort(host, port)
+		}
+	default:
+		addr = address
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return "", "", fmt.Errorf("invalid milter address: %q", address)
+	}
+	return "tcp", addr, nil
+}
+
+// Check runs a milter session for the message
+func (m *Milter) Check(ctx context.Context, env *Envelope, data []byte) (*Verdict, error) {
+	conn, err := m.dialer.DialContext(ctx, m.network, m.address)
+	if err != nil {
+		return nil, fmt.Errorf("milter connect: %w", err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	s := &milterSession{conn: conn, r: bufio.NewReader(conn)}
+	v, err := s.check(m.hostname, env, data)
+	if err != nil {
+		return nil, fmt.Errorf("milter: %w", err)
+	}
+	s.write(cmdQuit, nil)
+	return v, nil
+}
+
+// milterSession is the connection to a milter for one message
+type milterSession struct {
+	conn     net.Conn
+	r        *bufio.Reader
+	actions  uint32 // Modifications the milter may make
+	protocol uint32 // Steps the milter skips or does not reply to
+}
+
+// check sends the envelope and message to the milter and returns its
+// verdict
+func (s *milterSession) check(hostname string, env *Envelope, data []byte) (*Verdict, error) {
+	if err := s.negotiate(); err != nil {
+		return nil, err
+	}
+	msg := parseMilterMessage(data)
+
+	s.macros(cmdConnect, "j", hostname, "{daemon_name}", "sendry")
+	if v, _, err := s.step(cmdConnect, connectData(env.ClientIP), protoNoConnect, protoNRConnect); v != nil || err != nil {
+		return v, err
+	}
+	helo := env.Helo
+	if helo == "" {
+		helo = hostname
+	}
+	if v, _, err := s.step(cmdHelo, cstrings(helo), protoNoHelo, protoNRHelo); v != nil || err != nil {
+		return v, err
+	}
+	s.macros(cmdMail, "i", env.ID, "{auth_authen}", env.AuthUser)
+	if v, _, err := s.step(cmdMail, cstrings("<"+env.From+">"), protoNoMail, protoNRMail); v != nil || err != nil {
+		return v, err
+	}
+	for _, rcpt := range env.To {
+		if v, _, err := s.step(cmdRcpt, cstrings("<"+rcpt+">"), protoNoRcpt, protoNRRcpt); v != nil || err != nil {
+			return v, err
+		}
+	}
+	if v, _, err := s.step(cmdData, nil, protoNoData, protoNRData); v != nil || err != nil {
+		return v, err
+	}
+	for _, f := range msg.fields {
+		if v, _, err := s.step(cmdHeader, cstrings(f.name, milterValue(f.value)), protoNoHeaders, protoNRHeader); v != nil || err != nil {
+			return v, err
+		}
+	}
+	if v, _, err := s.step(cmdEOH, nil, protoNoEOH, protoNREOH); v != nil || err != nil {
+		return v, err
+	}
+	for body := msg.body; len(body) > 0; {
+		n := min(len(body), maxBodyChunk)
+		v, resp, err := s.step(cmdBody, body[:n], protoNoBody, protoNRBody)
+		if v != nil || err != nil {
+			return v, err
+		}
+		if resp == respSkip {
+			break
+		}
+		body = body[n:]
+	}
+
+	if err := s.write(cmdEOB, nil); err != nil {
+		return nil, err
+	}
+	return s.endOfMessage(msg)
+}
+
+// negotiate agrees on the protocol version, modifications and steps
+func (s *milterSession) negotiate() error {
+	data := make([]byte, 12)
+	binary.BigEndian.PutUint32(data[0:], milterVersion)
+	binary.BigEndian.PutUint32(data[4:], actOffered)
+	binary.BigEndian.PutUint32(data[8:], protoOffered)
+	if err := s.write(cmdOptNeg, data); err != nil {
+		return err
+	}
+
+	cmd, resp, err := s.read()
+	if err != nil {
+		return err
+	}
+	if cmd != respOptNeg || len(resp) < 12 {
+		return fmt.Errorf("unexpected negotiation response %q", cmd)
+	}
+	if version := binary.BigEndian.Uint32(resp[0:]); version < 2 {
+		return fmt.Errorf("unsupported protocol version %d", version)
+	}
+	s.actions = binary.BigEndian.Uint32(resp[4:]) & actOffered
+	s.protocol = binary.BigEndian.Uint32(resp[8:]) & protoOffered
+	return nil
+}
+
+// macros defines macros for the next command. Empty values are left out.
+func (s *milterSession) macros(cmd byte, pairs ...string) {
+	data := []byte{cmd}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if pairs[i+1] != "" {
+			data = append(data, cstrings(pairs[i], pairs[i+1])...)
+		}
+	}
+	if len(data) > 1 {
+		s.write(cmdMacro, data)
+	}
+}
+
+// step sends a command unless the milter skips it and reads the reply
+// unless the milter does not send one. It returns the verdict of a reply
+// that ends the session and the reply code.
+func (s *milterSession) step(cmd byte, data []byte, skip, noReply uint32) (*Verdict, byte, error) {
+	if s.protocol&skip != 0 {
+		return nil, 0, nil
+	}
+	if err := s.write(cmd, data); err != nil {
+		return nil, 0, err
+	}
+	if s.protocol&noReply != 0 {
+		return nil, 0, nil
+	}
+
+	for {
+		resp, data, err := s.read()
+		if err != nil {
+			return nil, 0, err
+		}
+		switch resp {
+		case respProgress:
+			continue
+		case respContinue, respSkip:
+			return nil, resp, nil
+		}
+		v, err := finalVerdict(resp, data)
+		return v, resp, err
+	}
+}
+
+// endOfMessage applies the modifications sent after the end of the
+// message until the final verdict
+func (s *milterSession) endOfMessage(msg *milterMessage) (*Verdict, error) {
+	var body *bytes.Buffer
+	for {
+		resp, data, err := s.read()
+		if err != nil {
+			return nil, err
+		}
+
+		switch resp {
+		case respProgress, respQuarantine:
+			continue
+		case respAddHeader, respInsHeader, respChgHeader:
+			if s.actions&(actAddHeaders|actChgHeaders) == 0 {
+				return nil, fmt.Errorf("header modification %q not negotiated", resp)
+			}
+			if err := msg.modifyHeader(resp, data); err != nil {
+				return nil, err
+			}
+			continue
+		case respReplBody:
+			if s.actions&actChgBody == 0 {
+				return nil, errors.New("body replacement not negotiated")
+			}
+			if body == nil {
+				body = new(bytes.Buffer)
+			}
+			body.Write(data)
+			continue
+		case respContinue:
+			resp = respAccept
+		}
+
+		v, err := finalVerdict(resp, data)
+		if err != nil || v.Action != ActionAccept {
+			return v, err
+		}
+		if body != nil {
+			msg.body = body.Bytes()
+			msg.modified = true
+		}
+		if msg.modified {
+			v.Action = ActionModify
+			v.Data = msg.bytes()
+		}
+		return v, nil
+	}
+}
+
+// finalVerdict converts a response that ends the session
+func finalVerdict(resp byte, data []byte) (*Verdict, error) {
+	switch resp {
+	case respAccept:
+		return &Verdict{Action: ActionAccept}, nil
+	case respDiscard:
+		return &Verdict{Action: ActionDiscard}, nil
+	case respReject:
+		return &Verdict{Action: ActionReject}, nil
+	case respTempfail:
+		return &Verdict{Action: ActionTempfail}, nil
+	case respReplyCode:
+		return parseReply(string(bytes.TrimRight(data, "\x00")))
+	}
+	return nil, fmt.Errorf("unexpected response %q", resp)
+}
+
+// parseReply converts a reply set by the milter, e.g. "550 5.7.1 Spam"
+func parseReply(text string) (*Verdict, error) {
+	line, _, _ := strings.Cut(text, "\r\n")
+	if len(line) < 3 {
+		return nil, fmt.Errorf("invalid reply %q", text)
+	}
+	code, err := strconv.Atoi(line[:3])
+	if err != nil || code < 400 || code > 599 {
+		return nil, fmt.Errorf("invalid reply %q", text)
+	}
+
+	v := &Verdict{Action: ActionReject, Code: code}
+	if code < 500 {
+		v.Action = ActionTempfail
+	}
+	rest := strings.TrimLeft(line[3:], " -")
+	if status, message, _ := strings.Cut(rest, " "); status != "" {
+		if ec, ok := parseEnhancedCode(status); ok {
+			v.EnhancedCode = ec
+			rest = message
+		}
+	}
+	v.Message = strings.TrimSpace(rest)
+	return v, nil
+}
+
+// write sends a packet
+func (s *milterSession) write(cmd byte, data []byte) error {
+	packet := make([]byte, 5, 5+len(data))
+	binary.BigEndian.PutUint32(packet, uint32(len(data)+1))
+	packet[4] = cmd
+	_, err := s.conn.Write(append(packet, data...))
+	return err
+}
+
+// read receives a packet
+func (s *milterSession) read() (byte, []byte, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(s.r, size[:]); err != nil {
+		return 0, nil, err
+	}
+	n := binary.BigEndian.Uint32(size[:])
+	if n == 0 || n > maxMilterPacket {
+		return 0, nil, fmt.Errorf("invalid packet length %d", n)
+	}
+	packet := make([]byte, n)
+	if _, err := io.ReadFull(s.r, packet); err != nil {
+		return 0, nil, err
+	}
+	return packet[0], packet[1:], nil
+}
+
+// connectData describes the client of the message
+func connectData(clientIP string) []byte {
+	host, port, err := net.SplitHostPort(clientIP)
+	if err != nil {
+		host, port = clientIP, "0"
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return append(cstrings("localhost"), 'U')
+	}
+
+	data := cstrings("[" + ip.String() + "]")
+	if ip.To4() != nil {
+		data = append(data, '4')
+	} else {
+		data = append(data, '6')
+	}
+	p, _ := strconv.ParseUint(port, 10, 16)
+	data = binary.BigEndian.AppendUint16(data, uint16(p))
+	return append(data, cstrings(ip.String())...)
+}
+
+// cstrings encodes NUL terminated strings
+func cstrings(s ...string) []byte {
+	var data []byte
+	for _, v := range s {
+		data = append(data, v...)
+		data = append(data, 0)
+	}
+	return data
+}
+
+// splitCStrings decodes NUL terminated strings
+func splitCStrings(data []byte) []string {
+	return strings.Split(strings.TrimSuffix(string(data), "\x00"), "\x00")
+}
+
+// milterValue returns a header value the way milters expect it: folded
+// lines separated by LF
+func milterValue(value string) string {
+	return strings.ReplaceAll(value, "\r\n", "\n")
+}
+
+// milterMessage is a message split into header fields and body
+type milterMessage struct {
+	fields   []milterField
+	body     []byte
+	modified bool
+}
+
+// milterField is a header field
+type milterField struct {
+	name  string
+	value string // Without leading whitespace, folded lines joined by CRLF
+	raw   string // Original field with line endings, empty when set by the milter
+}
+
+// parseMilterMessage splits message data into header fields and body
+func parseMilterMessage(data []byte) *milterMessage {
+	msg := &milterMessage{}
+	header := data
+	if idx := bytes.Index(data, []byte("\r\n\r\n")); idx != -1 {
+		header, msg.body = data[:idx+2], data[idx+4:]
+	} else if idx := bytes.Index(data, []byte("\n\n")); idx != -1 {
+		header, msg.body = data[:idx+1], data[idx+2:]
+	}
+
+	for len(header) > 0 {
+		end := bytes.IndexByte(header, '\n') + 1
+		if end == 0 {
+			end = len(header)
+		}
+		line := string(header[:end])
+		header = header[end:]
+		text := strings.TrimRight(line, "\r\n")
+		if !strings.HasSuffix(line, "\n") {
+			line += "\r\n"
+		}
+
+		if (line[0] == ' ' || line[0] == '\t') && len(msg.fields) > 0 {
+			f := &msg.fields[len(msg.fields)-1]
+			f.value += "\r\n" + text
+			f.raw += line
+			continue
+		}
+		name, value, ok := strings.Cut(text, ":")
+		if !ok {
+			continue
+		}
+		msg.fields = append(msg.fields, milterField{
+			name:  strings.TrimSpace(name),
+			value: strings.TrimLeft(value, " \t"),
+			raw:   line,
+		})
+	}
+	return msg
+}
+
+// modifyHeader applies an add, insert or change header response
+func (m *milterMessage) modifyHeader(resp byte, data []byte) error {
+	var index uint32
+	if resp != respAddHeader {
+		if len(data) < 4 {
+			return fmt.Errorf("short header modification %q", resp)
+		}
+		index = binary.BigEndian.Uint32(data)
+		data = data[4:]
+	}
+	parts := splitCStrings(data)
+	if len(parts) < 2 || parts[0] == "" {
+		return fmt.Errorf("invalid header modification %q", resp)
+	}
+	f := milterField{name: parts[0], value: parts[1]}
+	m.modified = true
+
+	switch resp {
+	case respAddHeader:
+		m.fields = append(m.fields, f)
+	case respInsHeader:
+		i := min(int(index), len(m.fields))
+		m.fields = append(m.fields[:i], append([]milterField{f}, m.fields[i:]...)...)
+	case respChgHeader:
+		// index counts the fields of that name from 1, an empty value
+		// deletes the field
+		n := uint32(0)
+		for i := range m.fields {
+			if !strings.EqualFold(m.fields[i].name, f.name) {
+				continue
+			}
+			if n++; n < index {
+				continue
+			}
+			if f.value == "" {
+				m.fields = append(m.fields[:i], m.fields[i+1:]...)
+			} else {
+				m.fields[i].value, m.fields[i].raw = f.value, ""
+			}
+			return nil
+		}
+		if f.value != "" {
+			m.fields = append(m.fields, f)
+		}
+	}
+	return nil
+}
+
+// bytes rebuilds the message. Fields the milter did not touch keep their
+// original form.
+func (m *milterMessage) bytes() []byte {
+	var buf bytes.Buffer
+	for _, f := range m.fields {
+		if f.raw != "" {
+			buf.WriteString(f.raw)
+			continue
+		}
+		buf.WriteString(f.name)
+		buf.WriteString(": ")
+		buf.WriteString(strings.ReplaceAll(milterValue(f.value), "\n", "\r\n"))
+		buf.WriteString("\r\n")
+	}
+	buf.WriteString("\r\n")
+	buf.Write(m.body)
+	return buf.Bytes()
+}
@@ -0,0 +1,201 @@
+package contentfilter
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeMilter serves one milter session per connection. reply returns the
+// response packets to a command, nil for none.
+type fakeMilter struct {
+	protocol uint32
+	reply    func(cmd byte, data []byte) [][]byte
+
+	mu       sync.Mutex
+	commands []string // Command and first argument of each packet
+}
+
+func packet(cmd byte, data ...byte) []byte {
+	p := binary.BigEndian.AppendUint32(nil, uint32(len(data)+1))
+	return append(append(p, cmd), data...)
+}
+
+func (m *fakeMilter) listen(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go m.serve(conn)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func (m *fakeMilter) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		var size [4]byte
+		if _, err := io.ReadFull(r, size[:]); err != nil {
+			return
+		}
+		p := make([]byte, binary.BigEndian.Uint32(size[:]))
+		if _, err := io.ReadFull(r, p); err != nil {
+			return
+		}
+		cmd, data := p[0], p[1:]
+
+		m.mu.Lock()
+		m.commands = append(m.commands, string(cmd)+" "+strings.Split(string(data), "\x00")[0])
+		m.mu.Unlock()
+
+		var replies [][]byte
+		switch cmd {
+		case cmdOptNeg:
+			neg := binary.BigEndian.AppendUint32(nil, 6)
+			neg = binary.BigEndian.AppendUint32(neg, actOffered)
+			neg = binary.BigEndian.AppendUint32(neg, m.protocol)
+			replies = [][]byte{packet(respOptNeg, neg...)}
+		case cmdMacro:
+		case cmdQuit:
+			return
+		default:
+			replies = m.reply(cmd, data)
+		}
+		for _, reply := range replies {
+			conn.Write(reply)
+		}
+	}
+}
+
+func (m *fakeMilter) received() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.commands...)
+}
+
+func TestMilterModify(t *testing.T) {
+	m := &fakeMilter{reply: func(cmd byte, data []byte) [][]byte {
+		if cmd != cmdEOB {
+			return [][]byte{packet(respContinue)}
+		}
+		return [][]byte{
+			packet(respAddHeader, cstrings("X-Spam", "Yes")...),
+			packet(respChgHeader, append([]byte{0, 0, 0, 1}, cstrings("Subject", "*** SPAM *** Hello")...)...),
+			packet(respInsHeader, append([]byte{0, 0, 0, 0}, cstrings("X-Spam-Score", "7.5")...)...),
+			packet(respAccept),
+		}
+	}}
+	f, err := NewMilter("inet:"+m.listen(t), "mx.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := f.Check(context.Background(), testEnvelope(), []byte(testMessage))
+	if err != nil || v.Action != ActionModify {
+		t.Fatalf("Check() = %+v, %v, want modify", v, err)
+	}
+	want := "X-Spam-Score: 7.5\r\nFrom: alice@example.com\r\nTo: bob@example.net\r\nSubject: *** SPAM *** Hello\r\nX-Spam: Yes\r\n\r\nHi Bob\r\n"
+	if string(v.Data) != want {
+		t.Errorf("Data = %q, want %q", v.Data, want)
+	}
+
+	got := strings.Join(m.received(), "|")
+	for _, cmd := range []string{"C [192.0.2.1]", "H mail.example.com", "M <alice@example.com>", "R <bob@example.net>", "L Subject", "B Hi Bob\r\n", "E "} {
+		if !strings.Contains(got, cmd) {
+			t.Errorf("milter did not receive %q: %s", cmd, got)
+		}
+	}
+}
+
+func TestMilterReject(t *testing.T) {
+	m := &fakeMilter{reply: func(cmd byte, data []byte) [][]byte {
+		if cmd == cmdRcpt {
+			return [][]byte{packet(respReplyCode, cstrings("550 5.7.1 Spam not welcome")...)}
+		}
+		return [][]byte{packet(respContinue)}
+	}}
+	f, _ := NewMilter(m.listen(t), "mx.example.com")
+
+	v, err := f.Check(context.Background(), testEnvelope(), []byte(testMessage))
+	if err != nil || v.Action != ActionReject || v.Code != 550 || v.EnhancedCode != [3]int{5, 7, 1} || v.Message != "Spam not welcome" {
+		t.Fatalf("Check() = %+v, %v, want reject", v, err)
+	}
+	if got := m.received(); strings.Contains(strings.Join(got, "|"), "E ") {
+		t.Errorf("message sent after the rejection: %v", got)
+	}
+}
+
+func TestMilterSkippedSteps(t *testing.T) {
+	// The milter only wants the body and does not reply to it
+	m := &fakeMilter{
+		protocol: protoNoConnect | protoNoHelo | protoNoMail | protoNoRcpt | protoNoData | protoNoHeaders | protoNoEOH | protoNRBody,
+		reply: func(cmd byte, data []byte) [][]byte {
+			if cmd == cmdEOB {
+				return [][]byte{packet(respTempfail)}
+			}
+			return nil
+		},
+	}
+	f, _ := NewMilter(m.listen(t), "mx.example.com")
+
+	v, err := f.Check(context.Background(), testEnvelope(), []byte(testMessage))
+	if err != nil || v.Action != ActionTempfail {
+		t.Fatalf("Check() = %+v, %v, want tempfail", v, err)
+	}
+	var got []string
+	for _, cmd := range m.received() {
+		if cmd[0] != cmdMacro && cmd[0] != cmdQuit {
+			got = append(got, cmd)
+		}
+	}
+	if len(got) != 3 || got[1] != "B Hi Bob\r\n" {
+		t.Errorf("received %q, want negotiation, body and end of body", got)
+	}
+}
+
+func TestMilterTimeout(t *testing.T) {
+	m := &fakeMilter{reply: func(cmd byte, data []byte) [][]byte { return nil }}
+	f, _ := NewMilter(m.listen(t), "mx.example.com")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := f.Check(ctx, testEnvelope(), []byte(testMessage)); err == nil {
+		t.Error("Check() without milter replies succeeded")
+	}
+}
+
+func TestParseMilterAddress(t *testing.T) {
+	tests := []struct {
+		address, network, addr string
+	}{
+		{"unix:/run/rspamd/milter.sock", "unix", "/run/rspamd/milter.sock"},
+		{"inet:localhost:11332", "tcp", "localhost:11332"},
+		{"inet:11332@127.0.0.1", "tcp", "127.0.0.1:11332"},
+		{"127.0.0.1:11332", "tcp", "127.0.0.1:11332"},
+	}
+	for _, tt := range tests {
+		network, addr, err := parseMilterAddress(tt.address)
+		if err != nil || network != tt.network || addr != tt.addr {
+			t.Errorf("parseMilterAddress(%q) = %q, %q, %v", tt.address, network, addr, err)
+		}
+	}
+	if _, _, err := parseMilterAddress("unix:"); err == nil {
+		t.Error("parseMilterAddress(unix:) succeeded")
+	}
+}
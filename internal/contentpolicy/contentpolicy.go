@@ -0,0 +1,185 @@
+// Package contentpolicy enforces limits on messages before they are
+// queued: the number of recipients and the size and type of attachments.
+// Banned parts refuse the message or are replaced by a note.
+package contentpolicy
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/foxzi/sendry/internal/config"
+	"github.com/foxzi/sendry/internal/email"
+	"github.com/foxzi/sendry/internal/metrics"
+)
+
+// Message sources
+const (
+	SourceSMTP = "smtp"
+	SourceAPI  = "api"
+)
+
+// Reason is the kind of a policy violation
+type Reason string
+
+const (
+	ReasonRecipients     Reason = "recipients"      // Too many recipients
+	ReasonAttachmentSize Reason = "attachment_size" // Attachment larger than allowed
+	ReasonBanned         Reason = "banned"          // Banned file extension or MIME type
+)
+
+// Violation is the error of a message refused by the policy
+type Violation struct {
+	Reason  Reason
+	Message string
+}
+
+func (v *Violation) Error() string {
+	return v.Message
+}
+
+// DomainLookup returns the configuration of a sender domain, nil if the
+// domain is not configured
+type DomainLookup func(domain string) *config.DomainConfig
+
+// Enforcer checks messages against the global policy or the policy of
+// their sender domain
+type Enforcer struct {
+	mu      sync.RWMutex
+	global  config.ContentPolicyConfig
+	domains DomainLookup
+	logger  *slog.Logger
+}
+
+// New creates an enforcer of the global policy. domains may be nil.
+func New(global config.ContentPolicyConfig, domains DomainLookup, logger *slog.Logger) *Enforcer {
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	return &Enforcer{global: global, domains: domains, logger: logger}
+}
+
+// SetPolicy replaces the global policy, e.g. on a config reload
+func (e *Enforcer) SetPolicy(global config.ContentPolicyConfig) {
+	e.mu.Lock()
+	e.global = global
+	e.mu.Unlock()
+}
+
+// PolicyFor returns the policy of messages from a sender. The policy of
+// the sender domain replaces the global one. Without a recipient limit
+// the recipients_per_message rate limit of the domain applies.
+func (e *Enforcer) PolicyFor(from string) config.ContentPolicyConfig {
+	e.mu.RLock()
+	p := e.global
+	e.mu.RUnlock()
+
+	if e.domains == nil {
+		return p
+	}
+	dc := e.domains(email.ExtractDomain(from))
+	if dc == nil {
+		return p
+	}
+	if dc.ContentPolicy != nil {
+		p = *dc.ContentPolicy
+	}
+	if p.MaxRecipients == 0 && dc.RateLimit != nil {
+		p.MaxRecipients = dc.RateLimit.RecipientsPerMessage
+	}
+	return p
+}
+
+// CheckRecipients checks the number of recipients of a message. A nil
+// enforcer accepts all messages.
+func (e *Enforcer) CheckRecipients(source, from string, n int) error {
+	if e == nil {
+		return nil
+	}
+	p := e.PolicyFor(from)
+	if p.MaxRecipients > 0 && n > p.MaxRecipients {
+		metrics.IncContentPolicy(source, string(ReasonRecipients), "reject")
+		e.logger.Info("content policy violation", "source", source, "from", from, "reason", ReasonRecipients, "recipients", n)
+		return &Violation{
+			Reason:  ReasonRecipients,
+			Message: fmt.Sprintf("too many recipients (max %d)", p.MaxRecipients),
+		}
+	}
+	return nil
+}
+
+// Check checks the recipients and parts of a message. It returns the data
+// to queue, with banned parts replaced when the policy strips them, or a
+// *Violation. A nil enforcer accepts all messages.
+func (e *Enforcer) Check(source, from string, to []string, data []byte) ([]byte, error) {
+	if e == nil {
+		return data, nil
+	}
+	if err := e.CheckRecipients(source, from, len(to)); err != nil {
+		return nil, err
+	}
+	return e.CheckContent(source, from, data)
+}
+
+// CheckContent checks the parts of a message, see Check
+func (e *Enforcer) CheckContent(source, from string, data []byte) ([]byte, error) {
+	if e == nil {
+		return data, nil
+	}
+	p := e.PolicyFor(from)
+	if p.MaxAttachmentBytes == 0 && len(p.BannedExtensions) == 0 && len(p.BannedTypes) == 0 {
+		return data, nil
+	}
+
+	w := &walker{policy: newRules(p)}
+	out, err := w.entity(data, true)
+	if err != nil {
+		v := err.(*Violation)
+		metrics.IncContentPolicy(source, string(v.Reason), "reject")
+		e.logger.Info("content policy violation", "source", source, "from", from, "reason", v.Reason, "error", v.Message)
+		return nil, err
+	}
+	for _, name := range w.stripped {
+		metrics.IncContentPolicy(source, string(ReasonBanned), "strip")
+		e.logger.Info("banned part stripped", "source", source, "from", from, "part", name)
+	}
+	return out, nil
+}
+
+// rules is a policy prepared for matching
+type rules struct {
+	maxAttachment int64
+	extensions    []string // Lower case with a leading dot
+	types         []string // Lower case, type/ for type/*
+	strip         bool
+}
+
+func newRules(p config.ContentPolicyConfig) *rules {
+	r := &rules{maxAttachment: p.MaxAttachmentBytes, strip: p.StripBanned}
+	for _, ext := range p.BannedExtensions {
+		r.extensions = append(r.extensions, "."+strings.ToLower(strings.Trim(ext, ". ")))
+	}
+	for _, t := range p.BannedTypes {
+		r.types = append(r.types, strings.TrimSuffix(strings.ToLower(t), "*"))
+	}
+	return r
+}
+
+// banned returns why a part is banned, empty if it is not
+func (r *rules) banned(mediaType, filename string) string {
+	for _, t := range r.types {
+		if mediaType == t || (strings.HasSuffix(t, "/") && strings.HasPrefix(mediaType, t)) {
+			return "type " + mediaType + " is not allowed"
+		}
+	}
+	// Trailing dots and spaces are dropped by Windows
+	name := strings.ToLower(strings.TrimRight(filename, ". "))
+	for _, ext := range r.extensions {
+		if name != "" && strings.HasSuffix(name, ext) {
+			return "extension " + ext + " is not allowed"
+		}
+	}
+	return ""
+}
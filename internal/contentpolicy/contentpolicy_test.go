@@ -0,0 +1,156 @@
+package contentpolicy
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/foxzi/sendry/internal/config"
+)
+
+const testMessage = "From: alice@example.com\r\n" +
+	"To: bob@example.net\r\n" +
+	"Subject: Invoice\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=\"outer\"\r\n" +
+	"\r\n" +
+	"This is a multi-part message in MIME format.\r\n" +
+	"--outer\r\n" +
+	"Content-Type: multipart/alternative; boundary=inner\r\n" +
+	"\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/plain\r\n" +
+	"\r\n" +
+	"Hi Bob\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/html\r\n" +
+	"\r\n" +
+	"<p>Hi Bob</p>\r\n" +
+	"--inner--\r\n" +
+	"\r\n" +
+	"--outer\r\n" +
+	"Content-Type: application/pdf; name=\"invoice.pdf\"\r\n" +
+	"Content-Disposition: attachment; filename=\"invoice.pdf\"\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"JVBERi0xLjQKJcfs\r\n" +
+	"j6IKNSAwIG9iago=\r\n" +
+	"--outer\r\n" +
+	"Content-Type: application/octet-stream\r\n" +
+	"Content-Disposition: attachment; filename=\"setup.EXE\"\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"TVqQAAMAAAAEAAAA\r\n" +
+	"--outer--\r\n"
+
+func violation(t *testing.T, err error, reason Reason) {
+	t.Helper()
+	var v *Violation
+	if !errors.As(err, &v) || v.Reason != reason {
+		t.Fatalf("error = %v, want %s violation", err, reason)
+	}
+}
+
+func TestCheckBanned(t *testing.T) {
+	e := New(config.ContentPolicyConfig{BannedExtensions: []string{"exe"}}, nil, nil)
+	_, err := e.CheckContent(SourceSMTP, "alice@example.com", []byte(testMessage))
+	violation(t, err, ReasonBanned)
+	if !strings.Contains(err.Error(), `"setup.EXE"`) {
+		t.Errorf("error = %v, want the file name", err)
+	}
+
+	e = New(config.ContentPolicyConfig{BannedTypes: []string{"application/*"}}, nil, nil)
+	_, err = e.CheckContent(SourceSMTP, "alice@example.com", []byte(testMessage))
+	violation(t, err, ReasonBanned)
+
+	e = New(config.ContentPolicyConfig{BannedTypes: []string{"application/zip"}, BannedExtensions: []string{".js"}}, nil, nil)
+	if data, err := e.CheckContent(SourceSMTP, "alice@example.com", []byte(testMessage)); err != nil || string(data) != testMessage {
+		t.Errorf("CheckContent() = %v, want the message unchanged", err)
+	}
+}
+
+func TestCheckStripBanned(t *testing.T) {
+	e := New(config.ContentPolicyConfig{BannedExtensions: []string{".exe"}, StripBanned: true}, nil, nil)
+	data, err := e.CheckContent(SourceSMTP, "alice@example.com", []byte(testMessage))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(data)
+	if strings.Contains(got, "TVqQAAMAAAAEAAAA") || !strings.Contains(got, `The attachment "setup.EXE" was removed: extension .exe is not allowed.`) {
+		t.Errorf("banned part not replaced:\n%s", got)
+	}
+	// The other parts and the delimiters are kept as they were
+	want := strings.Replace(testMessage, testMessage[strings.LastIndex(testMessage, "--outer\r\n")+9:strings.LastIndex(testMessage, "\r\n--outer--")], string(removedPart("setup.EXE", "extension .exe is not allowed")), 1)
+	if got != want {
+		t.Errorf("message = %q, want %q", got, want)
+	}
+}
+
+func TestCheckStripTopLevel(t *testing.T) {
+	msg := "From: alice@example.com\r\nContent-Type: application/x-msdownload\r\nContent-Disposition: attachment; filename=a.exe\r\n\r\nMZ\r\n"
+	e := New(config.ContentPolicyConfig{BannedTypes: []string{"application/x-msdownload"}, StripBanned: true}, nil, nil)
+	_, err := e.CheckContent(SourceAPI, "alice@example.com", []byte(msg))
+	violation(t, err, ReasonBanned)
+}
+
+func TestCheckAttachmentSize(t *testing.T) {
+	// invoice.pdf decodes to 23 bytes
+	e := New(config.ContentPolicyConfig{MaxAttachmentBytes: 23}, nil, nil)
+	if _, err := e.CheckContent(SourceSMTP, "alice@example.com", []byte(testMessage)); err != nil {
+		t.Errorf("CheckContent() error = %v", err)
+	}
+	e.SetPolicy(config.ContentPolicyConfig{MaxAttachmentBytes: 22})
+	_, err := e.CheckContent(SourceSMTP, "alice@example.com", []byte(testMessage))
+	violation(t, err, ReasonAttachmentSize)
+}
+
+func TestCheckUnterminatedMultipart(t *testing.T) {
+	msg := strings.TrimSuffix(testMessage, "--outer--\r\n")
+	e := New(config.ContentPolicyConfig{BannedExtensions: []string{".exe"}}, nil, nil)
+	_, err := e.CheckContent(SourceSMTP, "alice@example.com", []byte(msg))
+	violation(t, err, ReasonBanned)
+}
+
+func TestPolicyFor(t *testing.T) {
+	domains := map[string]*config.DomainConfig{
+		"strict.example.com": {ContentPolicy: &config.ContentPolicyConfig{MaxRecipients: 2}},
+		"limited.example.com": {
+			RateLimit: &config.DomainRateLimitConfig{RecipientsPerMessage: 3},
+		},
+	}
+	e := New(config.ContentPolicyConfig{MaxRecipients: 10, BannedExtensions: []string{".exe"}}, func(domain string) *config.DomainConfig {
+		return domains[domain]
+	}, nil)
+
+	if p := e.PolicyFor("alice@strict.example.com"); p.MaxRecipients != 2 || len(p.BannedExtensions) != 0 {
+		t.Errorf("PolicyFor(strict) = %+v, want the domain policy", p)
+	}
+	if p := e.PolicyFor("alice@limited.example.com"); p.MaxRecipients != 10 {
+		t.Errorf("PolicyFor(limited) = %+v, want the global recipient limit", p)
+	}
+	e.SetPolicy(config.ContentPolicyConfig{})
+	if p := e.PolicyFor("alice@limited.example.com"); p.MaxRecipients != 3 {
+		t.Errorf("PolicyFor(limited) = %+v, want the rate limit recipients", p)
+	}
+
+	violation(t, e.CheckRecipients(SourceSMTP, "alice@strict.example.com", 3), ReasonRecipients)
+	if err := e.CheckRecipients(SourceSMTP, "alice@other.example.com", 100); err != nil {
+		t.Errorf("CheckRecipients() without limit error = %v", err)
+	}
+	_, err := e.Check(SourceAPI, "alice@limited.example.com", []string{"a@x", "b@x", "c@x", "d@x"}, []byte(testMessage))
+	violation(t, err, ReasonRecipients)
+
+	var none *Enforcer
+	if data, err := none.Check(SourceAPI, "alice@example.com", nil, []byte(testMessage)); err != nil || string(data) != testMessage {
+		t.Errorf("nil enforcer Check() = %v", err)
+	}
+}
+
+func TestDecodedSize(t *testing.T) {
+	if n := decodedSize([]byte("SGVsbG8g\r\nV29ybGQ=\r\n"), "base64"); n != 11 {
+		t.Errorf("base64 size = %d, want 11", n)
+	}
+	if n := decodedSize([]byte("caf=C3=A9 =\r\nbar"), "quoted-printable"); n != 9 {
+		t.Errorf("quoted-printable size = %d, want 9", n)
+	}
+}
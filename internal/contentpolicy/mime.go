@@ -0,0 +1,207 @@
+package contentpolicy
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+// maxDepth bounds the nesting of multipart entities that are inspected
+const maxDepth = 20
+
+// walker checks the MIME entities of a message
+type walker struct {
+	policy   *rules
+	stripped []string // Names of the parts replaced by a note
+	depth    int
+}
+
+// entity checks a MIME entity and returns it, rebuilt if parts were
+// stripped. The message itself (top) is refused rather than stripped.
+func (w *walker) entity(data []byte, top bool) ([]byte, error) {
+	header, body := splitEntity(data)
+	h := parseHeader(header)
+
+	mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", nil
+	}
+	if strings.HasPrefix(mediaType, "multipart/") && params["boundary"] != "" && w.depth < maxDepth {
+		return w.multipart(data, body, params["boundary"])
+	}
+
+	disposition, dispParams, _ := mime.ParseMediaType(h.Get("Content-Disposition"))
+	filename := dispParams["filename"]
+	if filename == "" {
+		filename = params["name"]
+	}
+	if decoded, err := new(mime.WordDecoder).DecodeHeader(filename); err == nil {
+		filename = decoded
+	}
+	name := filename
+	if name == "" {
+		name = mediaType
+	}
+
+	if reason := w.policy.banned(mediaType, filename); reason != "" {
+		if !w.policy.strip || top {
+			return nil, &Violation{
+				Reason:  ReasonBanned,
+				Message: fmt.Sprintf("attachment %s is not allowed: %s", strconv.QuoteToASCII(name), reason),
+			}
+		}
+		w.stripped = append(w.stripped, name)
+		return removedPart(name, reason), nil
+	}
+
+	if w.policy.maxAttachment > 0 && (filename != "" || disposition == "attachment") {
+		size := decodedSize(body, h.Get("Content-Transfer-Encoding"))
+		if size > w.policy.maxAttachment {
+			return nil, &Violation{
+				Reason:  ReasonAttachmentSize,
+				Message: fmt.Sprintf("attachment %s is too large (%d bytes, max %d)", strconv.QuoteToASCII(name), size, w.policy.maxAttachment),
+			}
+		}
+	}
+	return data, nil
+}
+
+// multipart checks the parts of a multipart entity
+func (w *walker) multipart(data, body []byte, boundary string) ([]byte, error) {
+	w.depth++
+	defer func() { w.depth-- }()
+
+	stripped := len(w.stripped)
+	pieces := splitMultipart(body, boundary)
+	for i := range pieces {
+		if !pieces[i].part {
+			continue
+		}
+		part, err := w.entity(pieces[i].data, false)
+		if err != nil {
+			return nil, err
+		}
+		pieces[i].data = part
+	}
+	if len(w.stripped) == stripped {
+		return data, nil
+	}
+
+	out := append([]byte(nil), data[:len(data)-len(body)]...)
+	for _, p := range pieces {
+		out = append(out, p.data...)
+	}
+	return out, nil
+}
+
+// splitEntity splits an entity at the first empty line into the header
+// and the body
+func splitEntity(data []byte) (header, body []byte) {
+	for pos := 0; pos < len(data); {
+		end := bytes.IndexByte(data[pos:], '\n')
+		if end == -1 {
+			break
+		}
+		end += pos + 1
+		if line := data[pos:end]; len(bytes.TrimRight(line, "\r\n")) == 0 {
+			return data[:pos], data[end:]
+		}
+		pos = end
+	}
+	return data, nil
+}
+
+// parseHeader parses the header fields of an entity. Malformed fields
+// end the header.
+func parseHeader(header []byte) textproto.MIMEHeader {
+	r := textproto.NewReader(bufio.NewReader(io.MultiReader(bytes.NewReader(header), strings.NewReader("\r\n"))))
+	h, _ := r.ReadMIMEHeader()
+	return h
+}
+
+// piece is a part of a multipart body or the text around parts
+type piece struct {
+	data []byte
+	part bool
+}
+
+// splitMultipart splits a multipart body into its parts and the text
+// around them: preamble, delimiter lines and epilogue. Joining the pieces
+// gives the body again. A body without a close delimiter ends with its
+// last part.
+func splitMultipart(body []byte, boundary string) []piece {
+	delim := []byte("--" + boundary)
+	var pieces []piece
+	last := 0   // Start of the text not in a piece yet
+	start := -1 // Start of the current part
+	for pos := 0; pos < len(body); {
+		end := bytes.IndexByte(body[pos:], '\n')
+		if end == -1 {
+			end = len(body)
+		} else {
+			end += pos + 1
+		}
+
+		line := bytes.TrimRight(body[pos:end], " \t\r\n")
+		rest, ok := bytes.CutPrefix(line, delim)
+		closing := ok && string(rest) == "--"
+		if ok && (len(rest) == 0 || closing) {
+			if start >= 0 {
+				// The line break before a delimiter belongs to it
+				partEnd := pos
+				if partEnd > start && body[partEnd-1] == '\n' {
+					partEnd--
+				}
+				if partEnd > start && body[partEnd-1] == '\r' {
+					partEnd--
+				}
+				pieces = append(pieces, piece{data: body[last:start]}, piece{data: body[start:partEnd], part: true})
+				last = partEnd
+			}
+			start = end
+			if closing {
+				start = -1
+				break
+			}
+		}
+		pos = end
+	}
+	if start >= 0 && start < len(body) {
+		pieces = append(pieces, piece{data: body[last:start]}, piece{data: body[start:], part: true})
+		last = len(body)
+	}
+	return append(pieces, piece{data: body[last:]})
+}
+
+// decodedSize returns the size of a body after the transfer decoding
+func decodedSize(body []byte, encoding string) int64 {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		n := 0
+		for _, c := range body {
+			if c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '+' || c == '/' {
+				n++
+			}
+		}
+		return int64(n) * 3 / 4
+	case "quoted-printable":
+		if n, err := io.Copy(io.Discard, quotedprintable.NewReader(bytes.NewReader(body))); err == nil {
+			return n
+		}
+	}
+	return int64(len(body))
+}
+
+// removedPart returns the note that replaces a stripped part
+func removedPart(name, reason string) []byte {
+	return []byte("Content-Type: text/plain; charset=us-ascii\r\n" +
+		"Content-Disposition: inline\r\n" +
+		"\r\n" +
+		"The attachment " + strconv.QuoteToASCII(name) + " was removed: " + reason + ".")
+}
@@ -1,12 +1,16 @@
 package dkim
 
 import (
+	"bufio"
 	"bytes"
 	"crypto"
 	"crypto/rsa"
 	"fmt"
+	"net/textproto"
 
 	"github.com/emersion/go-msgauth/dkim"
+
+	"github.com/foxzi/sendry/internal/metrics"
 )
 
 // Signer signs email messages with DKIM
@@ -48,6 +52,7 @@ func (s *Signer) Sign(message []byte) ([]byte, error) {
 
 	var signedMsg bytes.Buffer
 	if err := dkim.Sign(&signedMsg, bytes.NewReader(message), options); err != nil {
+		metrics.IncDKIMSignFailures(s.domain)
 		return nil, fmt.Errorf("failed to sign message: %w", err)
 	}
 
@@ -63,3 +68,14 @@ func (s *Signer) Domain() string {
 func (s *Signer) Selector() string {
 	return s.selector
 }
+
+// SignatureHeader returns the unfolded value of the first DKIM-Signature
+// header of a message, or an empty string if it is not signed
+func SignatureHeader(message []byte) string {
+	r := textproto.NewReader(bufio.NewReader(bytes.NewReader(message)))
+	header, err := r.ReadMIMEHeader()
+	if err != nil && len(header) == 0 {
+		return ""
+	}
+	return header.Get("DKIM-Signature")
+}
@@ -201,3 +201,28 @@ func createTempKeyFile(t *testing.T) (string, func()) {
 	kp.SavePrivateKey(keyPath)
 	return keyPath, func() { os.RemoveAll(tmpDir) }
 }
+
+func TestSignatureHeader(t *testing.T) {
+	kp, err := GenerateKey("example.com", "sendry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := NewSigner(kp.PrivateKey, "example.com", "sendry")
+
+	message := []byte("From: sender@example.com\r\nTo: rcpt@example.org\r\nSubject: Test\r\n\r\nBody\r\n")
+	if got := SignatureHeader(message); got != "" {
+		t.Errorf("SignatureHeader() of unsigned message = %q", got)
+	}
+
+	signed, err := signer.Sign(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := SignatureHeader(signed)
+	if !strings.Contains(got, "d=example.com") || !strings.Contains(got, "s=sendry") {
+		t.Errorf("SignatureHeader() = %q, want domain and selector", got)
+	}
+	if strings.ContainsAny(got, "\r\n") {
+		t.Errorf("SignatureHeader() = %q, want unfolded value", got)
+	}
+}
@@ -7,6 +7,11 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/foxzi/sendry/internal/email"
+	"github.com/foxzi/sendry/internal/tracing"
 )
 
 // MXRecord represents an MX record
@@ -38,9 +43,13 @@ func NewResolver(cacheTTL time.Duration) *Resolver {
 	}
 }
 
-// LookupMX returns MX records sorted by priority
+// LookupMX returns MX records sorted by priority. Internationalized domain
+// names are looked up in their A-label form.
 func (r *Resolver) LookupMX(ctx context.Context, domain string) ([]MXRecord, error) {
-	domain = strings.ToLower(domain)
+	domain = email.ASCIIDomain(strings.ToLower(domain))
+
+	ctx, span := tracing.Start(ctx, "dns.lookup_mx", attribute.String("dns.domain", domain))
+	defer span.End()
 
 	// Check cache
 	r.mu.RLock()
@@ -48,6 +57,7 @@ func (r *Resolver) LookupMX(ctx context.Context, domain string) ([]MXRecord, err
 	r.mu.RUnlock()
 
 	if ok && time.Now().Before(entry.expiresAt) {
+		span.SetAttributes(attribute.Bool("dns.cached", true), attribute.Int("dns.mx_count", len(entry.records)))
 		return entry.records, nil
 	}
 
@@ -56,10 +66,13 @@ func (r *Resolver) LookupMX(ctx context.Context, domain string) ([]MXRecord, err
 	if err != nil {
 		// If no MX records, fall back to A record (domain itself)
 		if dnsErr, ok := err.(*net.DNSError); ok && dnsErr.IsNotFound {
+			span.SetAttributes(attribute.Bool("dns.implicit_mx", true))
 			return []MXRecord{{Host: domain, Priority: 0}}, nil
 		}
+		tracing.Fail(span, err)
 		return nil, err
 	}
+	span.SetAttributes(attribute.Int("dns.mx_count", len(mxRecords)))
 
 	// Convert to our format and sort by priority
 	records := make([]MXRecord, len(mxRecords))
@@ -0,0 +1,91 @@
+// Package dnsbl periodically checks the registered outbound IPs against
+// DNS blocklists, keeps the results history and flags new listings.
+package dnsbl
+
+import (
+	"time"
+
+	"github.com/foxzi/sendry/internal/dnscheck"
+)
+
+// IP sources
+const (
+	SourceConfig = "config"
+	SourceAPI    = "api"
+)
+
+// MonitoredIP is an outbound IP checked against DNSBLs
+type MonitoredIP struct {
+	IP        string    `json:"ip"`
+	Note      string    `json:"note,omitempty"`
+	Source    string    `json:"source"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Result is one DNSBL check run of an IP
+type Result struct {
+	IP        string                 `json:"ip"`
+	CheckedAt time.Time              `json:"checked_at"`
+	Listings  []dnscheck.DNSBLResult `json:"listings"`
+	Checked   int                    `json:"checked"`
+	Errors    int                    `json:"errors"`
+	// Zones that list the IP since this run and zones that stopped listing it
+	NewlyListed []string `json:"newly_listed,omitempty"`
+	Delisted    []string `json:"delisted,omitempty"`
+}
+
+// Listed returns true if at least one DNSBL lists the IP
+func (r *Result) Listed() bool {
+	return len(r.Listings) > 0
+}
+
+// NewResult builds a result from a DNSBL check, keeping only the listings
+func NewResult(check *dnscheck.IPCheckResult, checkedAt time.Time) *Result {
+	r := &Result{
+		IP:        check.IP,
+		CheckedAt: checkedAt,
+		Listings:  []dnscheck.DNSBLResult{},
+		Checked:   len(check.Results),
+		Errors:    check.Summary.Errors,
+	}
+	for _, res := range check.Results {
+		if res.Listed {
+			r.Listings = append(r.Listings, res)
+		}
+	}
+	return r
+}
+
+// Compare sets the zones of cur newly listed or delisted since prev. A
+// listing of prev whose lookup failed in cur is carried over, so a flaky
+// DNSBL does not flap between delisted and newly listed. Without a
+// previous run every listing is new.
+func Compare(prev, cur *Result, failed map[string]bool) {
+	before := make(map[string]bool)
+	if prev != nil {
+		for _, l := range prev.Listings {
+			before[l.DNSBL.Zone] = true
+		}
+	}
+
+	now := make(map[string]bool, len(cur.Listings))
+	for _, l := range cur.Listings {
+		now[l.DNSBL.Zone] = true
+		if !before[l.DNSBL.Zone] {
+			cur.NewlyListed = append(cur.NewlyListed, l.DNSBL.Zone)
+		}
+	}
+
+	if prev == nil {
+		return
+	}
+	for _, l := range prev.Listings {
+		switch {
+		case now[l.DNSBL.Zone]:
+		case failed[l.DNSBL.Zone]:
+			cur.Listings = append(cur.Listings, l)
+		default:
+			cur.Delisted = append(cur.Delisted, l.DNSBL.Zone)
+		}
+	}
+}
@@ -0,0 +1,275 @@
+package dnsbl
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/foxzi/sendry/internal/dnscheck"
+	"github.com/foxzi/sendry/internal/metrics"
+)
+
+// checkTimeout bounds the DNSBL lookups of one IP
+const checkTimeout = 30 * time.Second
+
+// ErrConfigIP is returned when removing an IP listed in the configuration
+var ErrConfigIP = errors.New("ip is configured in the configuration file")
+
+// CheckFunc runs the DNSBL checks of an IP
+type CheckFunc func(ctx context.Context, ip string) (*dnscheck.IPCheckResult, error)
+
+// Config contains DNSBL monitor settings
+type Config struct {
+	Interval  time.Duration // How often the IPs are checked
+	Retention time.Duration // How long check results are kept
+	IPs       []string      // IPs from the configuration file
+}
+
+// Monitor periodically checks the monitored IPs against DNSBLs
+type Monitor struct {
+	storage *Storage
+	check   CheckFunc
+	cfg     Config
+	logger  *slog.Logger
+
+	wg   sync.WaitGroup
+	done chan struct{}
+}
+
+// NewMonitor creates a new DNSBL monitor
+func NewMonitor(storage *Storage, cfg Config, logger *slog.Logger) *Monitor {
+	return &Monitor{
+		storage: storage,
+		check:   dnscheck.CheckIP,
+		cfg:     cfg,
+		logger:  logger,
+		done:    make(chan struct{}),
+	}
+}
+
+// Storage returns the monitor storage
+func (m *Monitor) Storage() *Storage {
+	return m.storage
+}
+
+// Start starts the check goroutine
+func (m *Monitor) Start(ctx context.Context) {
+	m.wg.Add(1)
+	go m.loop(ctx)
+
+	m.logger.Info("dnsbl monitor started", "interval", m.cfg.Interval)
+}
+
+// Stop stops the monitor and waits for a running check
+func (m *Monitor) Stop() {
+	close(m.done)
+	m.wg.Wait()
+}
+
+func (m *Monitor) loop(ctx context.Context) {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+
+	// Check immediately on start
+	m.run(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-m.done:
+			return
+		case <-ticker.C:
+			m.run(ctx)
+		}
+	}
+}
+
+func (m *Monitor) run(ctx context.Context) {
+	if _, err := m.CheckAll(ctx); err != nil {
+		m.logger.Error("failed to check dnsbl listings", "error", err)
+	}
+}
+
+// ParseIP validates and normalizes a monitored IP. Only IPv4 addresses can
+// be checked against DNSBLs.
+func ParseIP(s string) (string, error) {
+	ip := net.ParseIP(strings.TrimSpace(s))
+	if ip == nil {
+		return "", dnscheck.ErrInvalidIP
+	}
+	ip4 := ip.To4()
+	if ip4 == nil {
+		return "", dnscheck.ErrIPv6NotSupported
+	}
+	return ip4.String(), nil
+}
+
+// IPs returns the IPs from the configuration file and the registered IPs,
+// sorted by address
+func (m *Monitor) IPs(ctx context.Context) ([]*MonitoredIP, error) {
+	registered, err := m.storage.ListIPs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var ips []*MonitoredIP
+	for _, s := range m.cfg.IPs {
+		ip, err := ParseIP(s)
+		if err != nil || seen[ip] {
+			continue
+		}
+		seen[ip] = true
+		ips = append(ips, &MonitoredIP{IP: ip, Source: SourceConfig})
+	}
+	for _, ip := range registered {
+		if seen[ip.IP] {
+			continue
+		}
+		seen[ip.IP] = true
+		ips = append(ips, ip)
+	}
+
+	sort.Slice(ips, func(i, j int) bool {
+		return bytes.Compare(net.ParseIP(ips[i].IP).To4(), net.ParseIP(ips[j].IP).To4()) < 0
+	})
+	return ips, nil
+}
+
+// Monitored returns true if the IP is monitored
+func (m *Monitor) Monitored(ctx context.Context, ip string) (bool, error) {
+	if m.configured(ip) {
+		return true, nil
+	}
+	registered, err := m.storage.GetIP(ctx, ip)
+	return registered != nil, err
+}
+
+// AddIP registers an IP for monitoring
+func (m *Monitor) AddIP(ctx context.Context, ip, note string) (*MonitoredIP, error) {
+	ip, err := ParseIP(ip)
+	if err != nil {
+		return nil, err
+	}
+	if m.configured(ip) {
+		return &MonitoredIP{IP: ip, Source: SourceConfig}, nil
+	}
+
+	monitored := &MonitoredIP{
+		IP:        ip,
+		Note:      strings.TrimSpace(note),
+		Source:    SourceAPI,
+		CreatedAt: time.Now(),
+	}
+	if err := m.storage.AddIP(ctx, monitored); err != nil {
+		return nil, fmt.Errorf("failed to save monitored ip: %w", err)
+	}
+	return monitored, nil
+}
+
+// RemoveIP stops monitoring a registered IP and deletes its history
+func (m *Monitor) RemoveIP(ctx context.Context, ip string) error {
+	if m.configured(ip) {
+		return ErrConfigIP
+	}
+	if err := m.storage.DeleteIP(ctx, ip); err != nil {
+		return err
+	}
+	metrics.DeleteDNSBLListings(ip)
+	return nil
+}
+
+// configured returns true if the IP is listed in the configuration file
+func (m *Monitor) configured(ip string) bool {
+	for _, s := range m.cfg.IPs {
+		if c, err := ParseIP(s); err == nil && c == ip {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckAll checks all monitored IPs, stores the results and prunes
+// expired results
+func (m *Monitor) CheckAll(ctx context.Context) ([]*Result, error) {
+	ips, err := m.IPs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list monitored ips: %w", err)
+	}
+
+	var results []*Result
+	for _, ip := range ips {
+		select {
+		case <-ctx.Done():
+			return results, ctx.Err()
+		case <-m.done:
+			return results, nil
+		default:
+		}
+
+		r, err := m.Check(ctx, ip.IP)
+		if err != nil {
+			m.logger.Warn("failed to check dnsbl listings", "ip", ip.IP, "error", err)
+			continue
+		}
+		results = append(results, r)
+	}
+
+	if m.cfg.Retention > 0 {
+		if _, err := m.storage.Prune(ctx, time.Now().Add(-m.cfg.Retention)); err != nil {
+			return results, fmt.Errorf("failed to prune dnsbl check results: %w", err)
+		}
+	}
+
+	return results, nil
+}
+
+// Check checks an IP against the DNSBLs, compares the listings with the
+// previous result and stores the result
+func (m *Monitor) Check(ctx context.Context, ip string) (*Result, error) {
+	checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	check, err := m.check(checkCtx, ip)
+	if err != nil {
+		return nil, err
+	}
+
+	prev, err := m.storage.Latest(ctx, ip)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get previous result: %w", err)
+	}
+
+	failed := make(map[string]bool)
+	for _, res := range check.Results {
+		if res.Error != "" {
+			failed[res.DNSBL.Zone] = true
+		}
+	}
+
+	r := NewResult(check, time.Now())
+	Compare(prev, r, failed)
+
+	if err := m.storage.Save(ctx, r); err != nil {
+		return nil, fmt.Errorf("failed to save result: %w", err)
+	}
+
+	metrics.SetDNSBLListings(ip, len(r.Listings))
+	for _, zone := range r.NewlyListed {
+		m.logger.Warn("ip listed on dnsbl", "ip", ip, "dnsbl", zone)
+	}
+	for _, zone := range r.Delisted {
+		m.logger.Info("ip delisted from dnsbl", "ip", ip, "dnsbl", zone)
+	}
+	return r, nil
+}
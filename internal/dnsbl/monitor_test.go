@@ -0,0 +1,162 @@
+package dnsbl
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/foxzi/sendry/internal/dnscheck"
+)
+
+func newTestStorage(t *testing.T) *Storage {
+	t.Helper()
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	storage, err := NewStorage(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return storage
+}
+
+// fakeCheck returns a check func listing an IP on the given zones
+func fakeCheck(listed map[string][]string, failed map[string]bool) CheckFunc {
+	return func(ctx context.Context, ip string) (*dnscheck.IPCheckResult, error) {
+		r := &dnscheck.IPCheckResult{IP: ip}
+		for _, zone := range []string{"zen.spamhaus.org", "bl.spamcop.net", "psbl.surriel.com"} {
+			res := dnscheck.DNSBLResult{DNSBL: dnscheck.DNSBLInfo{Zone: zone}}
+			switch {
+			case failed[zone]:
+				res.Error = "timeout"
+				r.Summary.Errors++
+			case contains(listed[ip], zone):
+				res.Listed = true
+				r.Summary.Listed++
+			default:
+				r.Summary.Clean++
+			}
+			r.Results = append(r.Results, res)
+		}
+		return r, nil
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func TestMonitorCheck(t *testing.T) {
+	ctx := context.Background()
+	storage := newTestStorage(t)
+	m := NewMonitor(storage, Config{Interval: time.Hour, Retention: 24 * time.Hour, IPs: []string{"192.0.2.10"}},
+		slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	if _, err := m.AddIP(ctx, "198.51.100.7", "backup relay"); err != nil {
+		t.Fatalf("AddIP() error = %v", err)
+	}
+	if _, err := m.AddIP(ctx, "2001:db8::1", ""); !errors.Is(err, dnscheck.ErrIPv6NotSupported) {
+		t.Errorf("AddIP(ipv6) error = %v, want ErrIPv6NotSupported", err)
+	}
+
+	ips, err := m.IPs(ctx)
+	if err != nil || len(ips) != 2 || ips[0].Source != SourceConfig || ips[1].Note != "backup relay" {
+		t.Fatalf("IPs() = %+v, %v", ips, err)
+	}
+
+	listed := map[string][]string{"192.0.2.10": {"bl.spamcop.net"}}
+	failed := map[string]bool{}
+	m.check = fakeCheck(listed, failed)
+
+	results, err := m.CheckAll(ctx)
+	if err != nil || len(results) != 2 {
+		t.Fatalf("CheckAll() = %d results, %v, want 2", len(results), err)
+	}
+	if !reflect.DeepEqual(results[0].NewlyListed, []string{"bl.spamcop.net"}) || results[0].Checked != 3 {
+		t.Errorf("first run = %+v", results[0])
+	}
+	if results[1].Listed() {
+		t.Errorf("clean ip listed = %+v", results[1])
+	}
+
+	// A failing lookup keeps the listing
+	failed["bl.spamcop.net"] = true
+	r, err := m.Check(ctx, "192.0.2.10")
+	if err != nil || len(r.Listings) != 1 || len(r.NewlyListed) != 0 || len(r.Delisted) != 0 {
+		t.Fatalf("Check() with failed lookup = %+v, %v", r, err)
+	}
+
+	delete(failed, "bl.spamcop.net")
+	listed["192.0.2.10"] = []string{"zen.spamhaus.org"}
+	r, err = m.Check(ctx, "192.0.2.10")
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if !reflect.DeepEqual(r.NewlyListed, []string{"zen.spamhaus.org"}) || !reflect.DeepEqual(r.Delisted, []string{"bl.spamcop.net"}) {
+		t.Errorf("Check() after relisting = %+v", r)
+	}
+
+	history, err := storage.History(ctx, "192.0.2.10", time.Now().Add(-time.Hour))
+	if err != nil || len(history) != 3 {
+		t.Errorf("History() = %d results, %v, want 3", len(history), err)
+	}
+
+	if err := m.RemoveIP(ctx, "192.0.2.10"); !errors.Is(err, ErrConfigIP) {
+		t.Errorf("RemoveIP(config ip) error = %v, want ErrConfigIP", err)
+	}
+	if err := m.RemoveIP(ctx, "198.51.100.7"); err != nil {
+		t.Fatalf("RemoveIP() error = %v", err)
+	}
+	if latest, _ := storage.Latest(ctx, "198.51.100.7"); latest != nil {
+		t.Errorf("Latest() after remove = %+v, want nil", latest)
+	}
+	if ok, _ := m.Monitored(ctx, "198.51.100.7"); ok {
+		t.Error("Monitored() after remove = true")
+	}
+}
+
+func TestStoragePrune(t *testing.T) {
+	ctx := context.Background()
+	storage := newTestStorage(t)
+
+	now := time.Now()
+	for _, r := range []*Result{
+		{IP: "192.0.2.1", CheckedAt: now.Add(-72 * time.Hour)},
+		{IP: "192.0.2.1", CheckedAt: now},
+		{IP: "192.0.2.10", CheckedAt: now.Add(-72 * time.Hour)},
+		{IP: "192.0.2.10", CheckedAt: now.Add(-48 * time.Hour)},
+	} {
+		if err := storage.Save(ctx, r); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+
+	n, err := storage.Prune(ctx, now.Add(-24*time.Hour))
+	if err != nil || n != 2 {
+		t.Fatalf("Prune() = %d, %v, want 2", n, err)
+	}
+
+	latest, _ := storage.Latest(ctx, "192.0.2.1")
+	if latest == nil || !latest.CheckedAt.Equal(now) {
+		t.Errorf("Latest(192.0.2.1) = %+v", latest)
+	}
+	latest, _ = storage.Latest(ctx, "192.0.2.10")
+	if latest == nil || !latest.CheckedAt.Equal(now.Add(-48*time.Hour)) {
+		t.Errorf("Latest(192.0.2.10) after prune = %+v", latest)
+	}
+}
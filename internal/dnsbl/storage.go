@@ -0,0 +1,218 @@
+package dnsbl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	bucketIPs    = []byte("dnsbl_ips")
+	bucketChecks = []byte("dnsbl_checks")
+)
+
+// timeLayout is the fixed-width, sortable time of result keys
+const timeLayout = "2006-01-02T15:04:05.000000000Z"
+
+// Storage provides monitored IPs and DNSBL check results history storage
+type Storage struct {
+	db *bolt.DB
+}
+
+// NewStorage creates a new DNSBL storage using the provided BoltDB instance
+func NewStorage(db *bolt.DB) (*Storage, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(bucketIPs); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(bucketChecks)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dnsbl buckets: %w", err)
+	}
+	return &Storage{db: db}, nil
+}
+
+// resultKey returns the key of an IP result at a time
+func resultKey(ip string, t time.Time) []byte {
+	return []byte(ip + "\x00" + t.UTC().Format(timeLayout))
+}
+
+// ipPrefix returns the key prefix of an IP
+func ipPrefix(ip string) []byte {
+	return []byte(ip + "\x00")
+}
+
+// AddIP stores a monitored IP, replacing an existing one
+func (s *Storage) AddIP(ctx context.Context, ip *MonitoredIP) error {
+	data, err := json.Marshal(ip)
+	if err != nil {
+		return fmt.Errorf("failed to marshal monitored ip: %w", err)
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketIPs).Put([]byte(ip.IP), data)
+	})
+}
+
+// GetIP returns a monitored IP, nil if it is not registered
+func (s *Storage) GetIP(ctx context.Context, ip string) (*MonitoredIP, error) {
+	var result *MonitoredIP
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(bucketIPs).Get([]byte(ip))
+		if v == nil {
+			return nil
+		}
+		result = &MonitoredIP{}
+		return json.Unmarshal(v, result)
+	})
+
+	return result, err
+}
+
+// ListIPs returns the monitored IPs
+func (s *Storage) ListIPs(ctx context.Context) ([]*MonitoredIP, error) {
+	var ips []*MonitoredIP
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketIPs).ForEach(func(k, v []byte) error {
+			var ip MonitoredIP
+			if err := json.Unmarshal(v, &ip); err != nil {
+				return nil // Skip invalid entries
+			}
+			ips = append(ips, &ip)
+			return nil
+		})
+	})
+
+	return ips, err
+}
+
+// DeleteIP removes a monitored IP and its check results
+func (s *Storage) DeleteIP(ctx context.Context, ip string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(bucketIPs).Delete([]byte(ip)); err != nil {
+			return err
+		}
+
+		b := tx.Bucket(bucketChecks)
+		prefix := ipPrefix(ip)
+		var keys [][]byte
+		c := b.Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			keys = append(keys, append([]byte(nil), k...))
+		}
+		for _, k := range keys {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Save stores a check result
+func (s *Storage) Save(ctx context.Context, r *Result) error {
+	if r.IP == "" {
+		return fmt.Errorf("ip is required")
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dnsbl check result: %w", err)
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketChecks).Put(resultKey(r.IP, r.CheckedAt), data)
+	})
+}
+
+// Latest returns the latest check result of an IP, nil if it was never checked
+func (s *Storage) Latest(ctx context.Context, ip string) (*Result, error) {
+	var result *Result
+	prefix := ipPrefix(ip)
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucketChecks).Cursor()
+		// Seek past the last key of the IP, then step back
+		k, v := c.Seek([]byte(ip + "\x01"))
+		if k == nil {
+			k, v = c.Last()
+		} else {
+			k, v = c.Prev()
+		}
+		if k == nil || !bytes.HasPrefix(k, prefix) {
+			return nil
+		}
+		result = &Result{}
+		return json.Unmarshal(v, result)
+	})
+
+	return result, err
+}
+
+// History returns the check results of an IP since the given time, oldest first
+func (s *Storage) History(ctx context.Context, ip string, since time.Time) ([]*Result, error) {
+	var results []*Result
+	prefix := ipPrefix(ip)
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucketChecks).Cursor()
+		for k, v := c.Seek(resultKey(ip, since)); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var r Result
+			if err := json.Unmarshal(v, &r); err != nil {
+				continue // Skip invalid entries
+			}
+			results = append(results, &r)
+		}
+		return nil
+	})
+
+	return results, err
+}
+
+// Prune deletes check results older than the given time. The latest result
+// of each IP is kept so new listings are still detected after a long pause.
+func (s *Storage) Prune(ctx context.Context, before time.Time) (int, error) {
+	cutoff := []byte(before.UTC().Format(timeLayout))
+	deleted := 0
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketChecks)
+
+		var stale [][]byte
+		var prevKey []byte
+		var prevIP []byte
+		err := b.ForEach(func(k, v []byte) error {
+			ip, at, ok := bytes.Cut(k, []byte{0})
+			// The previous key is stale if it is old and not the last of its IP
+			if prevKey != nil && bytes.Equal(ip, prevIP) {
+				stale = append(stale, prevKey)
+			}
+			prevKey, prevIP = nil, nil
+			if !ok || bytes.Compare(at, cutoff) < 0 {
+				prevKey = append([]byte(nil), k...)
+				prevIP = append([]byte(nil), ip...)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, k := range stale {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		deleted = len(stale)
+		return nil
+	})
+
+	return deleted, err
+}
@@ -358,6 +358,7 @@ type DNSBLInfo struct {
 	Name        string `json:"name"`
 	Zone        string `json:"zone"`
 	Description string `json:"description"`
+	DelistURL   string `json:"delist_url,omitempty"` // Lookup and removal request page
 }
 
 // DNSBLResult represents a single DNSBL check result
@@ -384,21 +385,21 @@ type IPSummary struct {
 
 // DefaultDNSBLs is the list of popular DNSBL services
 var DefaultDNSBLs = []DNSBLInfo{
-	{Name: "Spamhaus ZEN", Zone: "zen.spamhaus.org", Description: "Combined Spamhaus blocklist (SBL, XBL, PBL)"},
-	{Name: "Spamhaus SBL", Zone: "sbl.spamhaus.org", Description: "Spamhaus Block List"},
-	{Name: "Spamhaus XBL", Zone: "xbl.spamhaus.org", Description: "Exploits Block List"},
-	{Name: "Spamhaus PBL", Zone: "pbl.spamhaus.org", Description: "Policy Block List"},
-	{Name: "Barracuda", Zone: "b.barracudacentral.org", Description: "Barracuda Reputation Block List"},
-	{Name: "SpamCop", Zone: "bl.spamcop.net", Description: "SpamCop Blocking List"},
-	{Name: "SORBS DNSBL", Zone: "dnsbl.sorbs.net", Description: "SORBS aggregate zone"},
-	{Name: "SORBS Spam", Zone: "spam.dnsbl.sorbs.net", Description: "SORBS spam sources"},
-	{Name: "UCEPROTECT L1", Zone: "dnsbl-1.uceprotect.net", Description: "UCEPROTECT Level 1"},
-	{Name: "UCEPROTECT L2", Zone: "dnsbl-2.uceprotect.net", Description: "UCEPROTECT Level 2"},
-	{Name: "UCEPROTECT L3", Zone: "dnsbl-3.uceprotect.net", Description: "UCEPROTECT Level 3"},
-	{Name: "PSBL", Zone: "psbl.surriel.com", Description: "Passive Spam Block List"},
-	{Name: "Mailspike BL", Zone: "bl.mailspike.net", Description: "Mailspike Blocklist"},
-	{Name: "JustSpam", Zone: "dnsbl.justspam.org", Description: "JustSpam.org DNSBL"},
-	{Name: "0Spam", Zone: "bl.0spam.org", Description: "0spam Project Blocklist"},
+	{Name: "Spamhaus ZEN", Zone: "zen.spamhaus.org", Description: "Combined Spamhaus blocklist (SBL, XBL, PBL)", DelistURL: "https://check.spamhaus.org/"},
+	{Name: "Spamhaus SBL", Zone: "sbl.spamhaus.org", Description: "Spamhaus Block List", DelistURL: "https://check.spamhaus.org/"},
+	{Name: "Spamhaus XBL", Zone: "xbl.spamhaus.org", Description: "Exploits Block List", DelistURL: "https://check.spamhaus.org/"},
+	{Name: "Spamhaus PBL", Zone: "pbl.spamhaus.org", Description: "Policy Block List", DelistURL: "https://check.spamhaus.org/"},
+	{Name: "Barracuda", Zone: "b.barracudacentral.org", Description: "Barracuda Reputation Block List", DelistURL: "https://www.barracudacentral.org/rbl/removal-request"},
+	{Name: "SpamCop", Zone: "bl.spamcop.net", Description: "SpamCop Blocking List", DelistURL: "https://www.spamcop.net/bl.shtml"},
+	{Name: "SORBS DNSBL", Zone: "dnsbl.sorbs.net", Description: "SORBS aggregate zone", DelistURL: "http://www.sorbs.net/delisting/"},
+	{Name: "SORBS Spam", Zone: "spam.dnsbl.sorbs.net", Description: "SORBS spam sources", DelistURL: "http://www.sorbs.net/delisting/"},
+	{Name: "UCEPROTECT L1", Zone: "dnsbl-1.uceprotect.net", Description: "UCEPROTECT Level 1", DelistURL: "https://www.uceprotect.net/en/rblcheck.php"},
+	{Name: "UCEPROTECT L2", Zone: "dnsbl-2.uceprotect.net", Description: "UCEPROTECT Level 2", DelistURL: "https://www.uceprotect.net/en/rblcheck.php"},
+	{Name: "UCEPROTECT L3", Zone: "dnsbl-3.uceprotect.net", Description: "UCEPROTECT Level 3", DelistURL: "https://www.uceprotect.net/en/rblcheck.php"},
+	{Name: "PSBL", Zone: "psbl.surriel.com", Description: "Passive Spam Block List", DelistURL: "https://psbl.org/remove"},
+	{Name: "Mailspike BL", Zone: "bl.mailspike.net", Description: "Mailspike Blocklist", DelistURL: "https://mailspike.org/iplookup.html"},
+	{Name: "JustSpam", Zone: "dnsbl.justspam.org", Description: "JustSpam.org DNSBL", DelistURL: "http://www.justspam.org/check-an-ip"},
+	{Name: "0Spam", Zone: "bl.0spam.org", Description: "0spam Project Blocklist", DelistURL: "https://0spam.org/"},
 }
 
 // CheckIP checks an IP address against DNSBL services
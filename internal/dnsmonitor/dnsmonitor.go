@@ -0,0 +1,75 @@
+// Package dnsmonitor periodically checks the MX, SPF, DKIM and DMARC records
+// of the configured domains, keeps the results history and flags regressions.
+package dnsmonitor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/foxzi/sendry/internal/dnscheck"
+)
+
+// Result is one DNS check run of a domain
+type Result struct {
+	Domain      string                 `json:"domain"`
+	CheckedAt   time.Time              `json:"checked_at"`
+	Results     []dnscheck.CheckResult `json:"results"`
+	Summary     dnscheck.Summary       `json:"summary"`
+	Regressions []Regression           `json:"regressions,omitempty"`
+}
+
+// Regression is a record that got worse since the previous run
+type Regression struct {
+	Type    string `json:"type"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Message string `json:"message"`
+}
+
+// Healthy returns true if no record is missing or invalid
+func (r *Result) Healthy() bool {
+	return r.Summary.Errors == 0 && r.Summary.NotFound == 0
+}
+
+// statusRank orders check statuses from good to bad. Lookup errors are
+// not ranked: a failing resolver says nothing about the record.
+var statusRank = map[string]int{
+	"ok":        0,
+	"warning":   1,
+	"not_found": 2,
+}
+
+// Compare returns the checks of cur that got worse than in prev. Checks
+// missing from prev, e.g. after a DKIM selector change, are not compared.
+func Compare(prev, cur *Result) []Regression {
+	if prev == nil {
+		return nil
+	}
+
+	before := make(map[string]dnscheck.CheckResult, len(prev.Results))
+	for _, r := range prev.Results {
+		before[r.Type] = r
+	}
+
+	var regressions []Regression
+	for _, r := range cur.Results {
+		p, ok := before[r.Type]
+		if !ok {
+			continue
+		}
+		from, okFrom := statusRank[p.Status]
+		to, okTo := statusRank[r.Status]
+		if !okFrom || !okTo || to <= from {
+			continue
+		}
+		msg := fmt.Sprintf("%s changed from %s to %s", r.Type, p.Status, r.Status)
+		if r.Status == "not_found" {
+			msg = fmt.Sprintf("%s was removed", r.Type)
+		}
+		if r.Message != "" {
+			msg += ": " + r.Message
+		}
+		regressions = append(regressions, Regression{Type: r.Type, From: p.Status, To: r.Status, Message: msg})
+	}
+	return regressions
+}
@@ -0,0 +1,71 @@
+package dnsmonitor
+
+import (
+	"testing"
+
+	"github.com/foxzi/sendry/internal/dnscheck"
+)
+
+func result(statuses map[string]string) *Result {
+	r := &Result{Domain: "example.com"}
+	for _, typ := range []string{"MX Records", "SPF Record", "DKIM Record (mail._domainkey)", "DMARC Record"} {
+		if status, ok := statuses[typ]; ok {
+			r.Results = append(r.Results, dnscheck.CheckResult{Type: typ, Status: status})
+		}
+	}
+	return r
+}
+
+func TestCompare(t *testing.T) {
+	prev := result(map[string]string{
+		"MX Records":                    "ok",
+		"SPF Record":                    "ok",
+		"DKIM Record (mail._domainkey)": "ok",
+		"DMARC Record":                  "warning",
+	})
+
+	tests := []struct {
+		name string
+		cur  map[string]string
+		want []string
+	}{
+		{
+			name: "unchanged",
+			cur:  map[string]string{"MX Records": "ok", "SPF Record": "ok", "DKIM Record (mail._domainkey)": "ok", "DMARC Record": "warning"},
+		},
+		{
+			name: "dkim removed and spf weakened",
+			cur:  map[string]string{"MX Records": "ok", "SPF Record": "warning", "DKIM Record (mail._domainkey)": "not_found", "DMARC Record": "warning"},
+			want: []string{"SPF Record", "DKIM Record (mail._domainkey)"},
+		},
+		{
+			name: "improvement",
+			cur:  map[string]string{"MX Records": "ok", "SPF Record": "ok", "DKIM Record (mail._domainkey)": "ok", "DMARC Record": "ok"},
+		},
+		{
+			name: "lookup error is not a regression",
+			cur:  map[string]string{"MX Records": "error", "SPF Record": "ok", "DKIM Record (mail._domainkey)": "ok", "DMARC Record": "warning"},
+		},
+		{
+			name: "new check is not compared",
+			cur:  map[string]string{"MX Records": "ok", "SPF Record": "ok", "DMARC Record": "warning"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Compare(prev, result(tt.cur))
+			if len(got) != len(tt.want) {
+				t.Fatalf("Compare() = %+v, want %v", got, tt.want)
+			}
+			for i, typ := range tt.want {
+				if got[i].Type != typ {
+					t.Errorf("Compare()[%d].Type = %s, want %s", i, got[i].Type, typ)
+				}
+			}
+		})
+	}
+
+	if got := Compare(nil, prev); got != nil {
+		t.Errorf("Compare(nil) = %+v, want none", got)
+	}
+}
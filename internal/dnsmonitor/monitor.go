@@ -0,0 +1,179 @@
+package dnsmonitor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/foxzi/sendry/internal/dkim"
+	"github.com/foxzi/sendry/internal/dnscheck"
+)
+
+// checkTimeout bounds the DNS lookups of one domain
+const checkTimeout = 30 * time.Second
+
+// Domains provides the configured domains and their DKIM signers
+type Domains interface {
+	ListDomains() []string
+	GetSigner(domain string) *dkim.Signer
+}
+
+// CheckFunc runs the DNS checks of a domain
+type CheckFunc func(ctx context.Context, domain string, opts dnscheck.CheckOptions) (*dnscheck.DomainCheckResult, error)
+
+// Config contains DNS monitor settings
+type Config struct {
+	Interval  time.Duration // How often the domains are checked
+	Retention time.Duration // How long check results are kept
+}
+
+// Monitor periodically checks the DNS records of the configured domains
+type Monitor struct {
+	storage *Storage
+	domains Domains
+	check   CheckFunc
+	cfg     Config
+	logger  *slog.Logger
+
+	wg   sync.WaitGroup
+	done chan struct{}
+}
+
+// NewMonitor creates a new DNS monitor
+func NewMonitor(storage *Storage, domains Domains, cfg Config, logger *slog.Logger) *Monitor {
+	return &Monitor{
+		storage: storage,
+		domains: domains,
+		check:   dnscheck.CheckDomain,
+		cfg:     cfg,
+		logger:  logger,
+		done:    make(chan struct{}),
+	}
+}
+
+// Start starts the check goroutine
+func (m *Monitor) Start(ctx context.Context) {
+	m.wg.Add(1)
+	go m.loop(ctx)
+
+	m.logger.Info("dns monitor started", "interval", m.cfg.Interval)
+}
+
+// Stop stops the monitor and waits for a running check
+func (m *Monitor) Stop() {
+	close(m.done)
+	m.wg.Wait()
+}
+
+func (m *Monitor) loop(ctx context.Context) {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+
+	// Check immediately on start
+	m.run(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-m.done:
+			return
+		case <-ticker.C:
+			m.run(ctx)
+		}
+	}
+}
+
+func (m *Monitor) run(ctx context.Context) {
+	results, err := m.CheckAll(ctx)
+	if err != nil {
+		m.logger.Error("failed to check domain dns records", "error", err)
+	}
+
+	for _, r := range results {
+		for _, reg := range r.Regressions {
+			m.logger.Warn("domain dns record regressed",
+				"domain", r.Domain,
+				"record", reg.Type,
+				"from", reg.From,
+				"to", reg.To,
+				"message", reg.Message,
+			)
+		}
+	}
+}
+
+// CheckAll checks all configured domains, stores the results and prunes
+// expired results
+func (m *Monitor) CheckAll(ctx context.Context) ([]*Result, error) {
+	domains := m.domains.ListDomains()
+	sort.Strings(domains)
+
+	var results []*Result
+	for _, domain := range domains {
+		select {
+		case <-ctx.Done():
+			return results, ctx.Err()
+		case <-m.done:
+			return results, nil
+		default:
+		}
+
+		r, err := m.Check(ctx, domain)
+		if err != nil {
+			m.logger.Warn("failed to check domain dns records", "domain", domain, "error", err)
+			continue
+		}
+		results = append(results, r)
+	}
+
+	if m.cfg.Retention > 0 {
+		if _, err := m.storage.Prune(ctx, time.Now().Add(-m.cfg.Retention)); err != nil {
+			return results, fmt.Errorf("failed to prune dns check results: %w", err)
+		}
+	}
+
+	return results, nil
+}
+
+// Check checks the records of a domain, compares them with the previous
+// result and stores the result
+func (m *Monitor) Check(ctx context.Context, domain string) (*Result, error) {
+	opts := dnscheck.CheckOptions{MX: true, SPF: true, DMARC: true}
+	// DKIM is only checked for domains that sign with their own key
+	if signer := m.domains.GetSigner(domain); signer != nil && signer.Domain() == domain {
+		opts.DKIM = true
+		opts.Selector = signer.Selector()
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	check, err := m.check(checkCtx, domain, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	prev, err := m.storage.Latest(ctx, domain)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get previous result: %w", err)
+	}
+
+	r := &Result{
+		Domain:    check.Domain,
+		CheckedAt: time.Now(),
+		Results:   check.Results,
+		Summary:   check.Summary,
+	}
+	r.Regressions = Compare(prev, r)
+
+	if err := m.storage.Save(ctx, r); err != nil {
+		return nil, fmt.Errorf("failed to save result: %w", err)
+	}
+	return r, nil
+}
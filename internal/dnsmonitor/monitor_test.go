@@ -0,0 +1,139 @@
+package dnsmonitor
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/foxzi/sendry/internal/dkim"
+	"github.com/foxzi/sendry/internal/dnscheck"
+)
+
+// staticDomains returns fixed domains and signers
+type staticDomains map[string]*dkim.Signer
+
+func (d staticDomains) ListDomains() []string {
+	domains := make([]string, 0, len(d))
+	for domain := range d {
+		domains = append(domains, domain)
+	}
+	return domains
+}
+
+func (d staticDomains) GetSigner(domain string) *dkim.Signer { return d[domain] }
+
+func newTestStorage(t *testing.T) *Storage {
+	t.Helper()
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	storage, err := NewStorage(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return storage
+}
+
+func TestMonitorCheckAll(t *testing.T) {
+	ctx := context.Background()
+	storage := newTestStorage(t)
+	domains := staticDomains{
+		"example.com": dkim.NewSigner(nil, "example.com", "mail"),
+		"other.org":   nil,
+	}
+	m := NewMonitor(storage, domains, Config{Interval: time.Hour, Retention: 24 * time.Hour},
+		slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	dkimStatus := "ok"
+	var selectors []string
+	m.check = func(ctx context.Context, domain string, opts dnscheck.CheckOptions) (*dnscheck.DomainCheckResult, error) {
+		r := &dnscheck.DomainCheckResult{Domain: domain}
+		r.Results = append(r.Results, dnscheck.CheckResult{Type: "MX Records", Status: "ok"})
+		r.Summary.OK++
+		if opts.DKIM {
+			selectors = append(selectors, opts.Selector)
+			r.Results = append(r.Results, dnscheck.CheckResult{Type: "DKIM Record (" + opts.Selector + "._domainkey)", Status: dkimStatus})
+			if dkimStatus == "not_found" {
+				r.Summary.NotFound++
+			}
+		}
+		return r, nil
+	}
+
+	results, err := m.CheckAll(ctx)
+	if err != nil || len(results) != 2 {
+		t.Fatalf("CheckAll() = %d results, %v, want 2", len(results), err)
+	}
+	if len(selectors) != 1 || selectors[0] != "mail" {
+		t.Errorf("DKIM selectors checked = %v, want [mail]", selectors)
+	}
+	if len(results[0].Regressions) != 0 {
+		t.Errorf("first run regressions = %+v", results[0].Regressions)
+	}
+
+	dkimStatus = "not_found"
+	r, err := m.Check(ctx, "example.com")
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if len(r.Regressions) != 1 || r.Regressions[0].To != "not_found" || r.Healthy() {
+		t.Errorf("Check() after DKIM removal = %+v", r)
+	}
+
+	latest, err := storage.Latest(ctx, "EXAMPLE.com")
+	if err != nil || latest == nil || len(latest.Regressions) != 1 {
+		t.Fatalf("Latest() = %+v, %v", latest, err)
+	}
+	if other, _ := storage.Latest(ctx, "other.org"); other == nil || other.Domain != "other.org" {
+		t.Errorf("Latest(other.org) = %+v", other)
+	}
+	if none, _ := storage.Latest(ctx, "example.net"); none != nil {
+		t.Errorf("Latest(unchecked) = %+v, want nil", none)
+	}
+
+	history, err := storage.History(ctx, "example.com", time.Now().Add(-time.Hour))
+	if err != nil || len(history) != 2 || history[1].CheckedAt.Before(history[0].CheckedAt) {
+		t.Errorf("History() = %d results, %v, want 2 oldest first", len(history), err)
+	}
+}
+
+func TestStoragePrune(t *testing.T) {
+	ctx := context.Background()
+	storage := newTestStorage(t)
+
+	now := time.Now()
+	for _, r := range []*Result{
+		{Domain: "a.com", CheckedAt: now.Add(-72 * time.Hour)},
+		{Domain: "a.com", CheckedAt: now.Add(-48 * time.Hour)},
+		{Domain: "a.com", CheckedAt: now},
+		{Domain: "b.com", CheckedAt: now.Add(-72 * time.Hour)},
+		{Domain: "b.com", CheckedAt: now.Add(-48 * time.Hour)},
+	} {
+		if err := storage.Save(ctx, r); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+
+	n, err := storage.Prune(ctx, now.Add(-24*time.Hour))
+	if err != nil || n != 3 {
+		t.Fatalf("Prune() = %d, %v, want 3", n, err)
+	}
+
+	// The latest result of a domain is kept even if it is old
+	latest, _ := storage.Latest(ctx, "b.com")
+	if latest == nil || !latest.CheckedAt.Equal(now.Add(-48*time.Hour)) {
+		t.Errorf("Latest(b.com) after prune = %+v", latest)
+	}
+	history, _ := storage.History(ctx, "a.com", now.Add(-96*time.Hour))
+	if len(history) != 1 {
+		t.Errorf("History(a.com) after prune = %d results, want 1", len(history))
+	}
+}
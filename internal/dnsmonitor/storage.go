@@ -0,0 +1,152 @@
+package dnsmonitor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var bucketChecks = []byte("dns_checks")
+
+// timeLayout is the fixed-width, sortable time of result keys
+const timeLayout = "2006-01-02T15:04:05.000000000Z"
+
+// Storage provides DNS check results history storage
+type Storage struct {
+	db *bolt.DB
+}
+
+// NewStorage creates a new DNS check storage using the provided BoltDB instance
+func NewStorage(db *bolt.DB) (*Storage, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucketChecks)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dns check bucket: %w", err)
+	}
+	return &Storage{db: db}, nil
+}
+
+// resultKey returns the key of a domain result at a time
+func resultKey(domain string, t time.Time) []byte {
+	return []byte(domain + "\x00" + t.UTC().Format(timeLayout))
+}
+
+// domainPrefix returns the key prefix of a domain
+func domainPrefix(domain string) []byte {
+	return []byte(domain + "\x00")
+}
+
+// normalizeDomain lowercases a domain name
+func normalizeDomain(domain string) string {
+	return strings.ToLower(strings.TrimSpace(domain))
+}
+
+// Save stores a check result
+func (s *Storage) Save(ctx context.Context, r *Result) error {
+	r.Domain = normalizeDomain(r.Domain)
+	if r.Domain == "" {
+		return fmt.Errorf("domain is required")
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dns check result: %w", err)
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketChecks).Put(resultKey(r.Domain, r.CheckedAt), data)
+	})
+}
+
+// Latest returns the latest check result of a domain, nil if it was never checked
+func (s *Storage) Latest(ctx context.Context, domain string) (*Result, error) {
+	var result *Result
+	domain = normalizeDomain(domain)
+	prefix := domainPrefix(domain)
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucketChecks).Cursor()
+		// Seek past the last key of the domain, then step back
+		k, v := c.Seek([]byte(domain + "\x01"))
+		if k == nil {
+			k, v = c.Last()
+		} else {
+			k, v = c.Prev()
+		}
+		if k == nil || !bytes.HasPrefix(k, prefix) {
+			return nil
+		}
+		result = &Result{}
+		return json.Unmarshal(v, result)
+	})
+
+	return result, err
+}
+
+// History returns the check results of a domain since the given time, oldest first
+func (s *Storage) History(ctx context.Context, domain string, since time.Time) ([]*Result, error) {
+	var results []*Result
+	domain = normalizeDomain(domain)
+	prefix := domainPrefix(domain)
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucketChecks).Cursor()
+		for k, v := c.Seek(resultKey(domain, since)); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var r Result
+			if err := json.Unmarshal(v, &r); err != nil {
+				continue // Skip invalid entries
+			}
+			results = append(results, &r)
+		}
+		return nil
+	})
+
+	return results, err
+}
+
+// Prune deletes check results older than the given time. The latest result
+// of each domain is kept so regressions are still detected after a long pause.
+func (s *Storage) Prune(ctx context.Context, before time.Time) (int, error) {
+	cutoff := []byte(before.UTC().Format(timeLayout))
+	deleted := 0
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketChecks)
+
+		var stale [][]byte
+		var prevKey []byte
+		var prevDomain []byte
+		err := b.ForEach(func(k, v []byte) error {
+			domain, at, ok := bytes.Cut(k, []byte{0})
+			// The previous key is stale if it is old and not the last of its domain
+			if prevKey != nil && bytes.Equal(domain, prevDomain) {
+				stale = append(stale, prevKey)
+			}
+			prevKey, prevDomain = nil, nil
+			if !ok || bytes.Compare(at, cutoff) < 0 {
+				prevKey = append([]byte(nil), k...)
+				prevDomain = append([]byte(nil), domain...)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, k := range stale {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		deleted = len(stale)
+		return nil
+	})
+
+	return deleted, err
+}
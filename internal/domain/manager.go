@@ -3,17 +3,23 @@ package domain
 import (
 	"fmt"
 	"log/slog"
+	"maps"
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/foxzi/sendry/internal/config"
 	"github.com/foxzi/sendry/internal/dkim"
 	"github.com/foxzi/sendry/internal/email"
 )
 
-// Manager manages domain-specific configurations including DKIM signers
+// Manager manages domain-specific configurations including DKIM signers.
+// It keeps its own copy of the domain sections of the config: the shared
+// config is read concurrently, so its domains keep their startup values.
 type Manager struct {
-	config  *config.Config
+	// Domain sections of the config, replaced as a whole and never changed
+	// once stored
+	config  atomic.Pointer[config.Config]
 	signers map[string]*dkim.Signer // domain -> signer
 	mu      sync.RWMutex
 	logger  *slog.Logger
@@ -21,43 +27,84 @@ type Manager struct {
 
 // NewManager creates a new domain manager
 func NewManager(cfg *config.Config, logger *slog.Logger) (*Manager, error) {
+	m := NewUnsignedManager(cfg, logger)
+
+	signers, err := m.loadSigners(cfg)
+	if err != nil {
+		return nil, err
+	}
+	m.signers = signers
+
+	return m, nil
+}
+
+// NewUnsignedManager creates a domain manager for the domains of cfg
+// without loading their DKIM signers
+func NewUnsignedManager(cfg *config.Config, logger *slog.Logger) *Manager {
 	m := &Manager{
-		config:  cfg,
 		signers: make(map[string]*dkim.Signer),
 		logger:  logger,
 	}
+	m.config.Store(domainConfig(cfg, maps.Clone(cfg.Domains)))
+	return m
+}
 
-	if err := m.loadSigners(); err != nil {
-		return nil, err
+// domainConfig returns a config holding only the domain sections of cfg,
+// with domains as its domain configurations
+func domainConfig(cfg *config.Config, domains map[string]config.DomainConfig) *config.Config {
+	dc := &config.Config{
+		SMTP:    config.SMTPConfig{Domain: cfg.SMTP.Domain},
+		DKIM:    cfg.DKIM,
+		Domains: domains,
 	}
+	dc.SetDomainsFile(cfg.DomainsFile())
+	return dc
+}
 
-	return m, nil
+// Reload replaces the domain configurations and DKIM signers with those of
+// cfg, e.g. on a config reload. All signers are loaded before anything is
+// replaced, so a missing key leaves the current configuration in place.
+func (m *Manager) Reload(cfg *config.Config) error {
+	signers, err := m.loadSigners(cfg)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.config.Store(domainConfig(cfg, maps.Clone(cfg.Domains)))
+	m.signers = signers
+	m.mu.Unlock()
+
+	m.logger.Info("domains reloaded", "domains", len(cfg.Domains), "dkim_signers", len(signers))
+	return nil
 }
 
-// loadSigners loads DKIM signers for all configured domains
-func (m *Manager) loadSigners() error {
+// loadSigners loads DKIM signers for all domains configured in cfg
+func (m *Manager) loadSigners(cfg *config.Config) (map[string]*dkim.Signer, error) {
+	signers := make(map[string]*dkim.Signer)
+
 	// Load legacy DKIM config
-	if m.config.DKIM.Enabled {
+	if cfg.DKIM.Enabled {
 		signer, err := dkim.NewSignerFromFile(
-			m.config.DKIM.KeyFile,
-			m.config.DKIM.Domain,
-			m.config.DKIM.Selector,
+			cfg.DKIM.KeyFile,
+			cfg.DKIM.Domain,
+			cfg.DKIM.Selector,
 		)
 		if err != nil {
-			return fmt.Errorf("failed to load legacy DKIM signer for %s: %w", m.config.DKIM.Domain, err)
+			return nil, fmt.Errorf("failed to load legacy DKIM signer for %s: %w", cfg.DKIM.Domain, err)
 		}
-		m.signers[m.config.DKIM.Domain] = signer
+		signers[cfg.DKIM.Domain] = signer
 		m.logger.Info("loaded DKIM signer",
-			"domain", m.config.DKIM.Domain,
-			"selector", m.config.DKIM.Selector,
+			"domain", cfg.DKIM.Domain,
+			"selector", cfg.DKIM.Selector,
 		)
 	}
 
 	// Load multi-domain DKIM configs
-	for domain, dc := range m.config.Domains {
+	for domain, dc := range cfg.Domains {
 		if dc.DKIM != nil && dc.DKIM.Enabled {
 			// Skip if already loaded from legacy config
-			if _, exists := m.signers[domain]; exists {
+			if _, exists := signers[domain]; exists {
 				continue
 			}
 
@@ -67,9 +114,9 @@ func (m *Manager) loadSigners() error {
 				dc.DKIM.Selector,
 			)
 			if err != nil {
-				return fmt.Errorf("failed to load DKIM signer for %s: %w", domain, err)
+				return nil, fmt.Errorf("failed to load DKIM signer for %s: %w", domain, err)
 			}
-			m.signers[domain] = signer
+			signers[domain] = signer
 			m.logger.Info("loaded DKIM signer",
 				"domain", domain,
 				"selector", dc.DKIM.Selector,
@@ -77,7 +124,7 @@ func (m *Manager) loadSigners() error {
 		}
 	}
 
-	return nil
+	return signers, nil
 }
 
 // GetSigner returns the DKIM signer for a domain
@@ -112,15 +159,60 @@ func (m *Manager) GetSignerForEmail(addr string) *dkim.Signer {
 	return m.GetSigner(domain)
 }
 
+// UpdateDomains applies update to a copy of the domain configurations,
+// saves them to the dynamic domains file and replaces the current ones.
+// Nothing is replaced if update or saving fails.
+func (m *Manager) UpdateDomains(update func(domains map[string]config.DomainConfig) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current := m.config.Load()
+	domains := maps.Clone(current.Domains)
+	if domains == nil {
+		domains = make(map[string]config.DomainConfig)
+	}
+	if err := update(domains); err != nil {
+		return err
+	}
+
+	next := domainConfig(current, domains)
+	if err := next.SaveDomains(); err != nil {
+		return err
+	}
+	m.config.Store(next)
+	return nil
+}
+
+// Domains returns a copy of the configurations of the domains
+func (m *Manager) Domains() map[string]config.DomainConfig {
+	return maps.Clone(m.config.Load().Domains)
+}
+
 // GetDomainConfig returns the configuration for a specific domain
 func (m *Manager) GetDomainConfig(domain string) *config.DomainConfig {
-	return m.config.GetDomainConfig(domain)
+	return m.config.Load().GetDomainConfig(domain)
+}
+
+// GetDKIMConfig returns the DKIM settings of a domain
+func (m *Manager) GetDKIMConfig(domain string) (enabled bool, selector, keyFile string) {
+	return m.config.Load().GetDKIMConfig(domain)
+}
+
+// PausedDomains returns the domains whose delivery is paused
+func (m *Manager) PausedDomains() []string {
+	return m.config.Load().PausedDomains()
+}
+
+// DomainCertificates returns the sorted domains that have their own TLS
+// certificate
+func (m *Manager) DomainCertificates() []string {
+	return m.config.Load().DomainCertificates()
 }
 
 // GetDomainMode returns the mode for a domain (production, sandbox, redirect, bcc)
 // Defaults to "production" if not specified
 func (m *Manager) GetDomainMode(domain string) string {
-	dc := m.config.GetDomainConfig(domain)
+	dc := m.GetDomainConfig(domain)
 	if dc != nil && dc.Mode != "" {
 		return dc.Mode
 	}
@@ -129,7 +221,7 @@ func (m *Manager) GetDomainMode(domain string) string {
 
 // GetRedirectAddresses returns redirect addresses for a domain in redirect mode
 func (m *Manager) GetRedirectAddresses(domain string) []string {
-	dc := m.config.GetDomainConfig(domain)
+	dc := m.GetDomainConfig(domain)
 	if dc != nil {
 		return dc.RedirectTo
 	}
@@ -138,7 +230,7 @@ func (m *Manager) GetRedirectAddresses(domain string) []string {
 
 // GetBCCAddresses returns BCC addresses for a domain in bcc mode
 func (m *Manager) GetBCCAddresses(domain string) []string {
-	dc := m.config.GetDomainConfig(domain)
+	dc := m.GetDomainConfig(domain)
 	if dc != nil {
 		return dc.BCCTo
 	}
@@ -147,7 +239,7 @@ func (m *Manager) GetBCCAddresses(domain string) []string {
 
 // ListDomains returns all configured domains
 func (m *Manager) ListDomains() []string {
-	return m.config.GetAllDomains()
+	return m.config.Load().GetAllDomains()
 }
 
 // HasDKIM returns true if DKIM is configured for any domain
@@ -160,7 +252,7 @@ func (m *Manager) HasDKIM() bool {
 // ReloadSigner reloads or creates a DKIM signer for a domain based on current config
 // This should be called after domain DKIM config is updated via API
 func (m *Manager) ReloadSigner(domain string) error {
-	dc := m.config.GetDomainConfig(domain)
+	dc := m.GetDomainConfig(domain)
 	if dc == nil || dc.DKIM == nil || !dc.DKIM.Enabled || dc.DKIM.KeyFile == "" {
 		// Remove signer if DKIM is disabled or not configured
 		m.mu.Lock()
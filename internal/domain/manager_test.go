@@ -1,8 +1,11 @@
 package domain
 
 import (
+	"fmt"
 	"log/slog"
 	"os"
+	"path/filepath"
+	"sync"
 	"testing"
 
 	"github.com/foxzi/sendry/internal/config"
@@ -254,3 +257,121 @@ func TestGetSignerWithoutDKIM(t *testing.T) {
 		t.Error("expected nil signer for email when DKIM not configured")
 	}
 }
+
+func TestReload(t *testing.T) {
+	cfg := &config.Config{
+		Domains: map[string]config.DomainConfig{
+			"example.com": {Mode: "sandbox"},
+		},
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	m, err := NewManager(cfg, logger)
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+
+	err = m.Reload(&config.Config{
+		Domains: map[string]config.DomainConfig{
+			"example.com": {Mode: "production"},
+			"other.com":   {Mode: "redirect", RedirectTo: []string{"test@other.com"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if mode := m.GetDomainMode("example.com"); mode != "production" {
+		t.Errorf("GetDomainMode(example.com) = %q, want production", mode)
+	}
+	if addrs := m.GetRedirectAddresses("other.com"); len(addrs) != 1 {
+		t.Errorf("GetRedirectAddresses(other.com) = %v", addrs)
+	}
+
+	// A missing DKIM key leaves the current configuration in place
+	err = m.Reload(&config.Config{
+		Domains: map[string]config.DomainConfig{
+			"broken.com": {DKIM: &config.DomainDKIMConfig{Enabled: true, Selector: "mail", KeyFile: "/nonexistent/key.pem"}},
+		},
+	})
+	if err == nil {
+		t.Fatal("Reload with missing DKIM key succeeded")
+	}
+	if m.GetDomainConfig("broken.com") != nil || m.GetDomainConfig("other.com") == nil {
+		t.Errorf("failed reload changed the domains: %v", m.ListDomains())
+	}
+}
+
+func TestReloadWhileReading(t *testing.T) {
+	cfg := &config.Config{
+		SMTP: config.SMTPConfig{Domain: "example.com"},
+		Domains: map[string]config.DomainConfig{
+			"example.com": {Mode: "production"},
+		},
+	}
+	cfg.SetDomainsFile(filepath.Join(t.TempDir(), "domains.yaml"))
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+
+	m, err := NewManager(cfg, logger)
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				for _, d := range m.ListDomains() {
+					m.GetDomainMode(d)
+				}
+				m.PausedDomains()
+				m.DomainCertificates()
+				m.GetSigner("example.com")
+				for range m.Domains() {
+				}
+			}
+		}()
+	}
+
+	for i := range 100 {
+		name := fmt.Sprintf("d%d.com", i)
+		err := m.Reload(&config.Config{
+			SMTP: config.SMTPConfig{Domain: "example.com"},
+			Domains: map[string]config.DomainConfig{
+				"example.com": {Mode: "sandbox"},
+				name:          {Paused: true},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Reload failed: %v", err)
+		}
+		err = m.UpdateDomains(func(domains map[string]config.DomainConfig) error {
+			domains[name+".api"] = config.DomainConfig{Mode: "bcc"}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("UpdateDomains failed: %v", err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+
+	if mode := m.GetDomainMode("example.com"); mode != "sandbox" {
+		t.Errorf("GetDomainMode(example.com) = %q, want sandbox", mode)
+	}
+	if m.GetDomainConfig("d99.com.api") == nil {
+		t.Errorf("updated domain missing: %v", m.ListDomains())
+	}
+	if cfg.Domains["d99.com"].Paused || len(cfg.Domains) != 1 {
+		t.Errorf("manager changed the config it was created from: %v", cfg.Domains)
+	}
+}
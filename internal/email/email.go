@@ -2,8 +2,14 @@
 package email
 
 import (
+	"bytes"
+	"fmt"
 	"net/mail"
+	"slices"
 	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
 )
 
 // ExtractDomain extracts the domain part from an email address.
@@ -34,3 +40,127 @@ func ExtractDomainOrDefault(email, defaultDomain string) string {
 	}
 	return domain
 }
+
+// ASCIIDomain returns the A-label (punycode) form of an internationalized
+// domain name, as used in DNS. ASCII and invalid names are returned as they
+// are.
+func ASCIIDomain(domain string) string {
+	if IsASCII(domain) {
+		return domain
+	}
+	ascii, err := idna.Lookup.ToASCII(domain)
+	if err != nil {
+		return domain
+	}
+	return ascii
+}
+
+// ASCIIAddress returns the address with its domain in A-label form. The
+// local part is not changed.
+func ASCIIAddress(addr string) string {
+	at := strings.LastIndex(addr, "@")
+	if at < 0 {
+		return addr
+	}
+	return addr[:at+1] + ASCIIDomain(addr[at+1:])
+}
+
+// IsInternational reports whether an address needs SMTPUTF8 (RFC 6531):
+// its local part, or a domain that has no A-label form, is not ASCII
+func IsInternational(addr string) bool {
+	return !IsASCII(ASCIIAddress(addr))
+}
+
+// IsASCII reports whether s has only ASCII characters
+func IsASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// SenderAllowed reports whether addr may be used as a sender by a client
+// bound to senders. An entry is an address, a domain or *.domain for its
+// subdomains. An empty list allows every sender.
+func SenderAllowed(senders []string, addr string) bool {
+	if len(senders) == 0 {
+		return true
+	}
+	if parsed, err := mail.ParseAddress(addr); err == nil {
+		addr = parsed.Address
+	}
+	domain := ASCIIDomain(ExtractDomain(addr))
+	for _, s := range senders {
+		s = strings.ToLower(strings.TrimSpace(s))
+		switch {
+		case strings.Contains(s, "@"):
+			if strings.EqualFold(ASCIIAddress(s), ASCIIAddress(addr)) {
+				return true
+			}
+		case strings.HasPrefix(s, "*."):
+			if domain != "" && strings.HasSuffix(domain, "."+ASCIIDomain(s[2:])) {
+				return true
+			}
+		case domain != "" && domain == ASCIIDomain(s):
+			return true
+		}
+	}
+	return false
+}
+
+// UnauthorizedSender returns the envelope sender or the first From header
+// address of a message that senders does not allow, "" when all are allowed.
+// A null envelope sender is allowed.
+func UnauthorizedSender(senders []string, from string, data []byte) string {
+	if len(senders) == 0 {
+		return ""
+	}
+	if from != "" && !SenderAllowed(senders, from) {
+		return from
+	}
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return ""
+	}
+	list, err := msg.Header.AddressList("From")
+	if err != nil {
+		if raw := msg.Header.Get("From"); raw != "" {
+			return raw
+		}
+		return ""
+	}
+	for _, a := range list {
+		if !SenderAllowed(senders, a.Address) {
+			return a.Address
+		}
+	}
+	return ""
+}
+
+// NormalizeSenders lowercases authorized senders, removes duplicates and checks
+// that each is an address, a domain or *.domain
+func NormalizeSenders(senders []string) ([]string, error) {
+	var out []string
+	for _, s := range senders {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if s == "" {
+			continue
+		}
+		name := strings.TrimPrefix(s, "*.")
+		if at := strings.LastIndex(s, "@"); at >= 0 {
+			if at == 0 {
+				return nil, fmt.Errorf("invalid sender %q", s)
+			}
+			name = s[at+1:]
+		}
+		if name == "" || strings.ContainsAny(name, "@* ") || !strings.Contains(name, ".") {
+			return nil, fmt.Errorf("invalid sender %q (must be an address, a domain or *.domain)", s)
+		}
+		if !slices.Contains(out, s) {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
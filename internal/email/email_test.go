@@ -52,3 +52,72 @@ func TestExtractDomainOrDefault(t *testing.T) {
 		})
 	}
 }
+
+func TestInternationalAddresses(t *testing.T) {
+	tests := []struct {
+		addr          string
+		ascii         string
+		international bool
+	}{
+		{"user@example.com", "user@example.com", false},
+		{"user@пример.рф", "user@xn--e1afmkfd.xn--p1ai", false},
+		{"user@Bücher.example", "user@xn--bcher-kva.example", false},
+		{"пользователь@пример.рф", "пользователь@xn--e1afmkfd.xn--p1ai", true},
+		{"josé@example.com", "josé@example.com", true},
+	}
+
+	for _, tc := range tests {
+		if got := ASCIIAddress(tc.addr); got != tc.ascii {
+			t.Errorf("ASCIIAddress(%q) = %q, want %q", tc.addr, got, tc.ascii)
+		}
+		if got := IsInternational(tc.addr); got != tc.international {
+			t.Errorf("IsInternational(%q) = %t, want %t", tc.addr, got, tc.international)
+		}
+	}
+}
+
+func TestSenderAllowed(t *testing.T) {
+	senders := []string{"example.com", "*.example.org", "Billing@Example.net"}
+	tests := []struct {
+		addr    string
+		allowed bool
+	}{
+		{"user@example.com", true},
+		{"User <user@EXAMPLE.com>", true},
+		{"user@mail.example.com", false},
+		{"user@mail.example.org", true},
+		{"user@example.org", false},
+		{"billing@example.net", true},
+		{"sales@example.net", false},
+		{"user@other.com", false},
+		{"invalid", false},
+	}
+
+	for _, tc := range tests {
+		if got := SenderAllowed(senders, tc.addr); got != tc.allowed {
+			t.Errorf("SenderAllowed(%q) = %t, want %t", tc.addr, got, tc.allowed)
+		}
+	}
+	if !SenderAllowed(nil, "user@other.com") {
+		t.Error("SenderAllowed(nil) = false, want every sender allowed")
+	}
+}
+
+func TestUnauthorizedSender(t *testing.T) {
+	senders := []string{"example.com"}
+	data := []byte("From: Other <user@other.com>\r\nSubject: Hi\r\n\r\nBody\r\n")
+
+	if got := UnauthorizedSender(senders, "bounce@other.com", data); got != "bounce@other.com" {
+		t.Errorf("envelope sender: got %q, want bounce@other.com", got)
+	}
+	if got := UnauthorizedSender(senders, "bounce@example.com", data); got != "user@other.com" {
+		t.Errorf("header From: got %q, want user@other.com", got)
+	}
+	ok := []byte("From: user@example.com\r\n\r\nBody\r\n")
+	if got := UnauthorizedSender(senders, "", ok); got != "" {
+		t.Errorf("allowed message: got %q, want none", got)
+	}
+	if got := UnauthorizedSender(nil, "bounce@other.com", data); got != "" {
+		t.Errorf("unrestricted: got %q, want none", got)
+	}
+}
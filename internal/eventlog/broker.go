@@ -0,0 +1,142 @@
+package eventlog
+
+import (
+	"sync"
+	"time"
+
+	"github.com/foxzi/sendry/internal/queue"
+)
+
+// Recorder records delivery events. Logger and Broker implement it.
+type Recorder interface {
+	queue.EventLogger
+	Accepted(msg *queue.Message)
+}
+
+// Tee records delivery events to several recorders
+type Tee []Recorder
+
+// Accepted records a message added to the queue
+func (t Tee) Accepted(msg *queue.Message) {
+	for _, r := range t {
+		r.Accepted(msg)
+	}
+}
+
+// Delivered records the recipients of a message delivered by an attempt
+func (t Tee) Delivered(msg *queue.Message, recipients []string) {
+	for _, r := range t {
+		r.Delivered(msg, recipients)
+	}
+}
+
+// Deferred records the recipients of a message deferred by an attempt
+func (t Tee) Deferred(msg *queue.Message, recipients []string) {
+	for _, r := range t {
+		r.Deferred(msg, recipients)
+	}
+}
+
+// Bounced records the recipients of a message that failed for good
+func (t Tee) Bounced(msg *queue.Message, recipients []string) {
+	for _, r := range t {
+		r.Bounced(msg, recipients)
+	}
+}
+
+// Broker hands delivery events to subscribers as they happen, e.g. to gRPC
+// event streams. A subscriber that falls behind by more than its buffer is
+// dropped: its channel is closed.
+type Broker struct {
+	mu     sync.Mutex
+	subs   map[chan Event]struct{}
+	host   string
+	buffer int
+	now    func() time.Time
+}
+
+// NewBroker creates a broker whose subscribers buffer up to buffer events.
+// hostname is recorded in the events.
+func NewBroker(hostname string, buffer int) *Broker {
+	if buffer < 1 {
+		buffer = 1
+	}
+	return &Broker{
+		subs:   make(map[chan Event]struct{}),
+		host:   hostname,
+		buffer: buffer,
+		now:    time.Now,
+	}
+}
+
+// Subscribe returns a channel of the events from now on and a function that
+// ends the subscription. The channel is closed when the subscription ends,
+// including when the subscriber fell behind.
+func (b *Broker) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, b.buffer)
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subs[ch]; ok {
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// Subscribers returns the number of subscriptions
+func (b *Broker) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
+
+// Accepted publishes a message added to the queue
+func (b *Broker) Accepted(msg *queue.Message) {
+	b.publish(EventAccepted, msg, msg.To)
+}
+
+// Delivered publishes the recipients of a message delivered by an attempt
+func (b *Broker) Delivered(msg *queue.Message, recipients []string) {
+	b.publish(EventDelivered, msg, recipients)
+}
+
+// Deferred publishes the recipients of a message deferred by an attempt
+func (b *Broker) Deferred(msg *queue.Message, recipients []string) {
+	b.publish(EventDeferred, msg, recipients)
+}
+
+// Bounced publishes the recipients of a message that failed for good
+func (b *Broker) Bounced(msg *queue.Message, recipients []string) {
+	b.publish(EventBounced, msg, recipients)
+}
+
+func (b *Broker) publish(event string, msg *queue.Message, recipients []string) {
+	if len(recipients) == 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.subs) == 0 {
+		return
+	}
+
+	// Subscribers read the event after the delivery moved on
+	record := newEvent(event, msg, append([]string(nil), recipients...))
+	record.Timestamp = b.now().UTC()
+	record.Host = b.host
+	for ch := range b.subs {
+		select {
+		case ch <- record:
+		default:
+			// Never block delivery on a slow subscriber
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package eventlog
+
+import (
+	"testing"
+
+	"github.com/foxzi/sendry/internal/queue"
+)
+
+func TestBroker(t *testing.T) {
+	b := NewBroker("mail.example.com", 10)
+	events, cancel := b.Subscribe()
+
+	msg := &queue.Message{
+		ID:   "msg-1",
+		From: "news@example.com",
+		To:   []string{"a@example.net", "b@example.net"},
+		Results: map[string]*queue.RecipientResult{
+			"a@example.net": {Status: queue.StatusDelivered, MXHost: "mx.example.net"},
+		},
+	}
+	b.Accepted(msg)
+	b.Delivered(msg, []string{"a@example.net"})
+	b.Deferred(msg, nil) // No recipients, no event
+
+	accepted := <-events
+	if accepted.Event != EventAccepted || accepted.Host != "mail.example.com" || len(accepted.To) != 2 {
+		t.Errorf("accepted event = %+v", accepted)
+	}
+	delivered := <-events
+	if delivered.Event != EventDelivered || delivered.SenderDomain != "example.com" ||
+		len(delivered.Recipients) != 1 || delivered.Recipients[0].MXHost != "mx.example.net" {
+		t.Errorf("delivered event = %+v", delivered)
+	}
+	select {
+	case e := <-events:
+		t.Errorf("unexpected event %+v", e)
+	default:
+	}
+
+	cancel()
+	if _, ok := <-events; ok {
+		t.Error("channel open after cancel")
+	}
+	if b.Subscribers() != 0 {
+		t.Errorf("Subscribers() = %d, want 0", b.Subscribers())
+	}
+	cancel() // Ending a subscription twice is harmless
+}
+
+func TestBrokerDropsSlowSubscriber(t *testing.T) {
+	b := NewBroker("", 2)
+	slow, _ := b.Subscribe()
+	fast, cancel := b.Subscribe()
+	defer cancel()
+
+	msg := &queue.Message{ID: "msg-1", From: "a@example.com", To: []string{"b@example.net"}}
+	for i := 0; i < 3; i++ {
+		b.Accepted(msg)
+		<-fast
+	}
+
+	received := 0
+	for range slow {
+		received++
+	}
+	if received != 2 {
+		t.Errorf("slow subscriber received %d events before it was dropped, want 2", received)
+	}
+	if b.Subscribers() != 1 {
+		t.Errorf("Subscribers() = %d, want 1", b.Subscribers())
+	}
+}
+
+func TestTee(t *testing.T) {
+	a := NewBroker("", 10)
+	b := NewBroker("", 10)
+	aEvents, cancelA := a.Subscribe()
+	defer cancelA()
+	bEvents, cancelB := b.Subscribe()
+	defer cancelB()
+
+	msg := &queue.Message{ID: "msg-1", From: "a@example.com", To: []string{"b@example.net"}}
+	tee := Tee{a, b}
+	tee.Accepted(msg)
+	tee.Bounced(msg, msg.To)
+
+	for _, events := range []<-chan Event{aEvents, bEvents} {
+		if e := <-events; e.Event != EventAccepted {
+			t.Errorf("first event = %q, want accepted", e.Event)
+		}
+		if e := <-events; e.Event != EventBounced {
+			t.Errorf("second event = %q, want bounced", e.Event)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+// Package eventlog writes a structured record of every delivery event of a
+// message (accepted, delivered, deferred, bounced) to a rotating JSONL file
+// and/or syslog, for export to log pipelines.
+package eventlog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/foxzi/sendry/internal/email"
+	"github.com/foxzi/sendry/internal/privacy"
+	"github.com/foxzi/sendry/internal/queue"
+)
+
+// Delivery events
+const (
+	EventAccepted  = "accepted"
+	EventDelivered = "delivered"
+	EventDeferred  = "deferred"
+	EventBounced   = "bounced"
+)
+
+// Config configures the outputs of a logger. At least one of File and
+// Syslog must be set.
+type Config struct {
+	File       string            // JSONL file
+	MaxSize    int64             // Rotate the file at this size in bytes (0 = never)
+	MaxBackups int               // Rotated files kept
+	Events     []string          // Logged events, all if empty
+	Syslog     *SyslogConfig     // Syslog output
+	Hostname   string            // Host name of the records
+	Redactor   *privacy.Redactor // Redacts addresses in the records
+}
+
+// Event is a record of the delivery log
+type Event struct {
+	Timestamp    time.Time   `json:"timestamp"`
+	Event        string      `json:"event"`
+	Host         string      `json:"host,omitempty"`
+	MessageID    string      `json:"message_id"`
+	From         string      `json:"from"`
+	SenderDomain string      `json:"sender_domain,omitempty"`
+	To           []string    `json:"to"`
+	Size         int         `json:"size"`
+	RetryCount   int         `json:"retry_count,omitempty"`
+	ClientIP     string      `json:"client_ip,omitempty"`
+	AuthUser     string      `json:"auth_user,omitempty"`
+	APIKeyID     string      `json:"api_key_id,omitempty"`
+	NextRetryAt  *time.Time  `json:"next_retry_at,omitempty"`
+	Error        string      `json:"error,omitempty"`
+	Recipients   []Recipient `json:"recipients,omitempty"`
+}
+
+// Recipient is the outcome of a recipient in a delivery event
+type Recipient struct {
+	Address string `json:"address"`
+	Status  string `json:"status"`
+	MXHost  string `json:"mx_host,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Logger writes delivery events. It implements queue.EventLogger.
+type Logger struct {
+	mu     sync.Mutex
+	file   *RotatingFile
+	syslog *syslogWriter
+	events map[string]bool
+	host   string
+	redact *privacy.Redactor
+	logger *slog.Logger
+	now    func() time.Time
+}
+
+// New opens the outputs of a delivery log
+func New(cfg Config, logger *slog.Logger) (*Logger, error) {
+	l := &Logger{host: cfg.Hostname, redact: cfg.Redactor, logger: logger, now: time.Now}
+	if len(cfg.Events) > 0 {
+		l.events = make(map[string]bool, len(cfg.Events))
+		for _, event := range cfg.Events {
+			l.events[event] = true
+		}
+	}
+
+	if cfg.File != "" {
+		file, err := OpenFile(cfg.File, cfg.MaxSize, cfg.MaxBackups)
+		if err != nil {
+			return nil, err
+		}
+		l.file = file
+	}
+	if cfg.Syslog != nil {
+		w, err := dialSyslog(*cfg.Syslog)
+		if err != nil {
+			l.Close()
+			return nil, err
+		}
+		l.syslog = w
+	}
+	if l.file == nil && l.syslog == nil {
+		return nil, fmt.Errorf("delivery log has no output")
+	}
+	return l, nil
+}
+
+// Close closes the outputs
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var firstErr error
+	if l.file != nil {
+		firstErr = l.file.Close()
+	}
+	if l.syslog != nil {
+		if err := l.syslog.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Accepted logs a message added to the queue
+func (l *Logger) Accepted(msg *queue.Message) {
+	l.log(EventAccepted, msg, msg.To)
+}
+
+// Delivered logs the recipients of a message delivered by an attempt
+func (l *Logger) Delivered(msg *queue.Message, recipients []string) {
+	l.log(EventDelivered, msg, recipients)
+}
+
+// Deferred logs the recipients of a message deferred by an attempt
+func (l *Logger) Deferred(msg *queue.Message, recipients []string) {
+	l.log(EventDeferred, msg, recipients)
+}
+
+// Bounced logs the recipients of a message that failed for good
+func (l *Logger) Bounced(msg *queue.Message, recipients []string) {
+	l.log(EventBounced, msg, recipients)
+}
+
+func (l *Logger) log(event string, msg *queue.Message, recipients []string) {
+	if len(recipients) == 0 || (l.events != nil && !l.events[event]) {
+		return
+	}
+
+	record := newEvent(event, msg, recipients)
+	record.Timestamp = l.now().UTC()
+	record.Host = l.host
+	if l.redact != nil {
+		record.From = l.redact.Address(record.From)
+		record.To = l.redact.Addresses(record.To)
+		record.Error = l.redact.Text(record.Error)
+		for i := range record.Recipients {
+			record.Recipients[i].Address = l.redact.Address(record.Recipients[i].Address)
+			record.Recipients[i].Error = l.redact.Text(record.Recipients[i].Error)
+		}
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		l.logger.Error("failed to encode delivery event", "error", err, "id", msg.ID)
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file != nil {
+		if _, err := l.file.Write(append(line, '\n')); err != nil {
+			l.logger.Error("failed to write delivery log", "error", err, "id", msg.ID)
+		}
+	}
+	if l.syslog != nil {
+		if err := l.syslog.write(event, string(line)); err != nil {
+			l.logger.Error("failed to send delivery event to syslog", "error", err, "id", msg.ID)
+		}
+	}
+}
+
+// newEvent builds the record of an event of a message for recipients
+func newEvent(event string, msg *queue.Message, recipients []string) Event {
+	e := Event{
+		Event:        event,
+		MessageID:    msg.ID,
+		From:         msg.From,
+		SenderDomain: email.ExtractDomain(msg.From),
+		To:           recipients,
+		Size:         len(msg.Data),
+		RetryCount:   msg.RetryCount,
+		ClientIP:     msg.ClientIP,
+		AuthUser:     msg.AuthUser,
+		APIKeyID:     msg.APIKeyID,
+	}
+	if event == EventAccepted {
+		return e
+	}
+
+	if event != EventDelivered {
+		e.Error = msg.LastError
+	}
+	if event == EventDeferred && !msg.NextRetryAt.IsZero() {
+		next := msg.NextRetryAt.UTC()
+		e.NextRetryAt = &next
+	}
+	for _, rcpt := range recipients {
+		r := msg.Results[rcpt]
+		if r == nil {
+			continue
+		}
+		e.Recipients = append(e.Recipients, Recipient{
+			Address: rcpt,
+			Status:  string(r.Status),
+			MXHost:  r.MXHost,
+			Error:   r.Error,
+		})
+	}
+	return e
+}
+
+// Queue wraps a queue storage to record the messages added to it
+type Queue struct {
+	queue.Storage
+	log Recorder
+}
+
+// NewQueue wraps storage to record accepted messages to log
+func NewQueue(storage queue.Storage, log Recorder) *Queue {
+	return &Queue{Storage: storage, log: log}
+}
+
+// Enqueue adds a message to the queue
+func (q *Queue) Enqueue(ctx context.Context, msg *queue.Message) error {
+	if err := q.Storage.Enqueue(ctx, msg); err != nil {
+		return err
+	}
+	q.log.Accepted(msg)
+	return nil
+}
+
+// EnqueueBatch adds messages to the queue in a single transaction
+func (q *Queue) EnqueueBatch(ctx context.Context, msgs []*queue.Message) error {
+	if err := q.Storage.EnqueueBatch(ctx, msgs); err != nil {
+		return err
+	}
+	for _, msg := range msgs {
+		q.log.Accepted(msg)
+	}
+	return nil
+}
@@ -0,0 +1,252 @@
+package eventlog
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/foxzi/sendry/internal/privacy"
+	"github.com/foxzi/sendry/internal/queue"
+)
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+}
+
+func readEvents(t *testing.T, path string) []Event {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("invalid record %q: %v", scanner.Text(), err)
+		}
+		events = append(events, e)
+	}
+	return events
+}
+
+func TestLoggerFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log", "delivery.jsonl")
+	l, err := New(Config{File: path, Hostname: "mail.example.com"}, testLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+
+	msg := &queue.Message{
+		ID:          "msg-1",
+		From:        "news@example.com",
+		To:          []string{"a@gmail.com", "b@outlook.com"},
+		Data:        []byte("Subject: hi\r\n\r\nhello"),
+		RetryCount:  1,
+		LastError:   "451 4.7.1 Greylisted",
+		NextRetryAt: now.Add(5 * time.Minute),
+		Results: map[string]*queue.RecipientResult{
+			"a@gmail.com":   {Status: queue.StatusDelivered, MXHost: "mx.gmail.com"},
+			"b@outlook.com": {Status: queue.StatusDeferred, MXHost: "mx.outlook.com", Error: "451 4.7.1 Greylisted"},
+		},
+	}
+	l.Accepted(msg)
+	l.Delivered(msg, []string{"a@gmail.com"})
+	l.Deferred(msg, []string{"b@outlook.com"})
+	l.Bounced(msg, nil) // Nothing to log
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	events := readEvents(t, path)
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want 3", len(events))
+	}
+
+	accepted := events[0]
+	if accepted.Event != EventAccepted || len(accepted.To) != 2 || accepted.Recipients != nil || accepted.Error != "" {
+		t.Errorf("accepted = %+v", accepted)
+	}
+	if accepted.Host != "mail.example.com" || accepted.SenderDomain != "example.com" || accepted.Size != len(msg.Data) || !accepted.Timestamp.Equal(now) {
+		t.Errorf("accepted = %+v", accepted)
+	}
+
+	delivered := events[1]
+	if delivered.Event != EventDelivered || delivered.Error != "" || len(delivered.Recipients) != 1 || delivered.Recipients[0].MXHost != "mx.gmail.com" {
+		t.Errorf("delivered = %+v", delivered)
+	}
+
+	deferred := events[2]
+	if deferred.Event != EventDeferred || deferred.Error != msg.LastError || deferred.NextRetryAt == nil || !deferred.NextRetryAt.Equal(msg.NextRetryAt) {
+		t.Errorf("deferred = %+v", deferred)
+	}
+	if len(deferred.Recipients) != 1 || deferred.Recipients[0].Status != "deferred" || deferred.Recipients[0].Address != "b@outlook.com" {
+		t.Errorf("deferred recipients = %+v", deferred.Recipients)
+	}
+}
+
+func TestLoggerEvents(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "delivery.jsonl")
+	l, err := New(Config{File: path, Events: []string{EventBounced}}, testLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := &queue.Message{ID: "msg-1", From: "a@example.com", To: []string{"b@example.net"}}
+	l.Accepted(msg)
+	l.Delivered(msg, msg.To)
+	l.Bounced(msg, msg.To)
+	l.Close()
+
+	events := readEvents(t, path)
+	if len(events) != 1 || events[0].Event != EventBounced {
+		t.Errorf("events = %+v, want only bounced", events)
+	}
+}
+
+func TestLoggerRedact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "delivery.jsonl")
+	redactor := privacy.NewRedactor(privacy.RedactConfig{Addresses: true})
+	l, err := New(Config{File: path, Redactor: redactor}, testLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := &queue.Message{
+		ID:        "msg-1",
+		From:      "alice@example.com",
+		To:        []string{"bob@example.net"},
+		LastError: "550 5.1.1 <bob@example.net>: user unknown",
+		Results: map[string]*queue.RecipientResult{
+			"bob@example.net": {Status: queue.StatusFailed, Error: "550 5.1.1 <bob@example.net>: user unknown"},
+		},
+	}
+	l.Bounced(msg, msg.To)
+	l.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "alice@") || strings.Contains(string(data), "bob@") {
+		t.Errorf("delivery log has addresses: %s", data)
+	}
+	events := readEvents(t, path)
+	if len(events) != 1 || events[0].From != "a***@example.com" || events[0].SenderDomain != "example.com" {
+		t.Errorf("events = %+v", events)
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "delivery.jsonl")
+	f, err := OpenFile(path, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f.Close()
+
+	want := map[string]string{
+		path:        "fourth\n",
+		path + ".1": "third\n",
+		path + ".2": "second\n",
+	}
+	for name, content := range want {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != content {
+			t.Errorf("%s = %q, want %q", filepath.Base(name), data, content)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("backup beyond max_backups kept: %v", err)
+	}
+}
+
+func TestLoggerSyslog(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("udp not available: %v", err)
+	}
+	defer conn.Close()
+
+	l, err := New(Config{Syslog: &SyslogConfig{Network: "udp", Address: conn.LocalAddr().String(), Tag: "sendry", Facility: "mail"}}, testLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	msg := &queue.Message{ID: "msg-1", From: "a@example.com", To: []string{"b@example.net"}, LastError: "550 5.1.1 User unknown"}
+	l.Bounced(msg, msg.To)
+
+	buf := make([]byte, 4096)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := string(buf[:n])
+	// mail facility (2) * 8 + err severity (3)
+	if !strings.HasPrefix(record, "<19>") || !strings.Contains(record, "sendry") || !strings.Contains(record, `"event":"bounced"`) {
+		t.Errorf("syslog record = %q", record)
+	}
+}
+
+func TestQueueAccepted(t *testing.T) {
+	dir := t.TempDir()
+	storage, err := queue.NewBoltStorage(filepath.Join(dir, "queue.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storage.Close()
+
+	path := filepath.Join(dir, "delivery.jsonl")
+	l, err := New(Config{File: path}, testLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := NewQueue(storage, l)
+
+	ctx := context.Background()
+	msg := &queue.Message{ID: "one", From: "a@example.com", To: []string{"b@example.net"}, Status: queue.StatusPending, CreatedAt: time.Now()}
+	if err := q.Enqueue(ctx, msg); err != nil {
+		t.Fatal(err)
+	}
+	batch := []*queue.Message{
+		{ID: "two", From: "a@example.com", To: []string{"c@example.net"}, Status: queue.StatusPending, CreatedAt: time.Now()},
+		{ID: "three", From: "a@example.com", To: []string{"d@example.net"}, Status: queue.StatusPending, CreatedAt: time.Now()},
+	}
+	if err := q.EnqueueBatch(ctx, batch); err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+
+	events := readEvents(t, path)
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want 3", len(events))
+	}
+	for i, id := range []string{"one", "two", "three"} {
+		if events[i].Event != EventAccepted || events[i].MessageID != id {
+			t.Errorf("event %d = %+v, want accepted %s", i, events[i], id)
+		}
+	}
+	if got, err := storage.Get(ctx, "two"); err != nil || got == nil {
+		t.Errorf("batch message not stored: %v", err)
+	}
+}
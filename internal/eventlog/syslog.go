@@ -0,0 +1,62 @@
+package eventlog
+
+import (
+	"fmt"
+	"log/syslog"
+)
+
+// SyslogConfig configures the syslog output
+type SyslogConfig struct {
+	Network  string // udp, tcp or unix, empty for the local syslog
+	Address  string // host:port or socket path of a remote syslog
+	Tag      string // Program name of the records
+	Facility string // mail, daemon, user or local0-local7
+}
+
+var facilities = map[string]syslog.Priority{
+	"mail":   syslog.LOG_MAIL,
+	"daemon": syslog.LOG_DAEMON,
+	"user":   syslog.LOG_USER,
+	"local0": syslog.LOG_LOCAL0,
+	"local1": syslog.LOG_LOCAL1,
+	"local2": syslog.LOG_LOCAL2,
+	"local3": syslog.LOG_LOCAL3,
+	"local4": syslog.LOG_LOCAL4,
+	"local5": syslog.LOG_LOCAL5,
+	"local6": syslog.LOG_LOCAL6,
+	"local7": syslog.LOG_LOCAL7,
+}
+
+// syslogWriter sends records to syslog with the severity of their event.
+// The connection is re-established after write errors.
+type syslogWriter struct {
+	w *syslog.Writer
+}
+
+func dialSyslog(cfg SyslogConfig) (*syslogWriter, error) {
+	facility, ok := facilities[cfg.Facility]
+	if !ok {
+		facility = syslog.LOG_MAIL
+	}
+	w, err := syslog.Dial(cfg.Network, cfg.Address, facility|syslog.LOG_INFO, cfg.Tag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to syslog: %w", err)
+	}
+	return &syslogWriter{w: w}, nil
+}
+
+// write sends a record, deferred events as warnings and bounces as errors
+func (s *syslogWriter) write(event, record string) error {
+	switch event {
+	case EventDeferred:
+		return s.w.Warning(record)
+	case EventBounced:
+		return s.w.Err(record)
+	default:
+		return s.w.Info(record)
+	}
+}
+
+func (s *syslogWriter) Close() error {
+	return s.w.Close()
+}
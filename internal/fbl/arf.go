@@ -0,0 +1,197 @@
+package fbl
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// maxPartSize bounds the parts of a report that are read
+const maxPartSize = 10 << 20
+
+// ErrInvalidReport is returned for messages that are not ARF feedback reports
+var ErrInvalidReport = errors.New("not an ARF feedback report")
+
+// Report is a parsed ARF (RFC 5965) feedback report
+type Report struct {
+	FeedbackType     string // abuse, fraud, virus, other or not-spam
+	UserAgent        string
+	OriginalMailFrom string
+	OriginalRcptTo   []string
+	ArrivalDate      time.Time
+	ReportingMTA     string
+	SourceIP         string
+	ReportedDomain   string
+
+	// Headers of the reported message, nil if the report does not include it
+	Original mail.Header
+}
+
+// Parse parses an ARF feedback report: a multipart/report message with
+// report-type feedback-report, whose machine-readable part describes the
+// complaint and whose last part carries the reported message or its headers
+func Parse(data []byte) (*Report, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidReport, err)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/report" || !strings.EqualFold(params["report-type"], "feedback-report") {
+		return nil, fmt.Errorf("%w: content type is not multipart/report; report-type=feedback-report", ErrInvalidReport)
+	}
+	if params["boundary"] == "" {
+		return nil, fmt.Errorf("%w: missing multipart boundary", ErrInvalidReport)
+	}
+
+	var report *Report
+	var original mail.Header
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidReport, err)
+		}
+
+		partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		switch partType {
+		case "message/feedback-report":
+			body, err := readPart(part)
+			if err != nil {
+				return nil, err
+			}
+			if report, err = parseFeedbackReport(body); err != nil {
+				return nil, err
+			}
+		case "message/rfc822", "text/rfc822-headers":
+			body, err := readPart(part)
+			if err != nil {
+				return nil, err
+			}
+			original = parseHeaders(body)
+		}
+	}
+
+	if report == nil {
+		return nil, fmt.Errorf("%w: missing message/feedback-report part", ErrInvalidReport)
+	}
+	report.Original = original
+	return report, nil
+}
+
+// readPart reads a report part, decoding base64 content. Quoted-printable
+// parts are decoded by the multipart reader.
+func readPart(part *multipart.Part) ([]byte, error) {
+	var r io.Reader = io.LimitReader(part, maxPartSize)
+	if strings.EqualFold(strings.TrimSpace(part.Header.Get("Content-Transfer-Encoding")), "base64") {
+		r = base64.NewDecoder(base64.StdEncoding, r)
+	}
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidReport, err)
+	}
+	return body, nil
+}
+
+// parseHeaders parses a header block. Bodies and headers without a
+// terminating empty line are accepted.
+func parseHeaders(data []byte) mail.Header {
+	data = append(bytes.Clone(data), "\r\n\r\n"...)
+	h, err := textproto.NewReader(bufio.NewReader(bytes.NewReader(data))).ReadMIMEHeader()
+	if err != nil && len(h) == 0 {
+		return nil
+	}
+	return mail.Header(h)
+}
+
+// parseFeedbackReport parses the machine-readable part of a report
+func parseFeedbackReport(data []byte) (*Report, error) {
+	h := parseHeaders(data)
+	if h == nil {
+		return nil, fmt.Errorf("%w: unreadable feedback report fields", ErrInvalidReport)
+	}
+
+	r := &Report{
+		FeedbackType:     strings.ToLower(strings.TrimSpace(h.Get("Feedback-Type"))),
+		UserAgent:        strings.TrimSpace(h.Get("User-Agent")),
+		OriginalMailFrom: stripBrackets(h.Get("Original-Mail-From")),
+		ReportingMTA:     stripType(h.Get("Reporting-MTA")),
+		SourceIP:         strings.TrimSpace(h.Get("Source-IP")),
+		ReportedDomain:   strings.ToLower(strings.TrimSpace(h.Get("Reported-Domain"))),
+	}
+	if r.FeedbackType == "" {
+		return nil, fmt.Errorf("%w: missing Feedback-Type", ErrInvalidReport)
+	}
+	for _, rcpt := range h["Original-Rcpt-To"] {
+		if rcpt = stripBrackets(rcpt); rcpt != "" {
+			r.OriginalRcptTo = append(r.OriginalRcptTo, rcpt)
+		}
+	}
+
+	date := h.Get("Arrival-Date")
+	if date == "" {
+		date = h.Get("Received-Date")
+	}
+	if t, err := mail.ParseDate(date); err == nil {
+		r.ArrivalDate = t
+	}
+	return r, nil
+}
+
+// Recipients returns the recipients of the reported message: the
+// Original-Rcpt-To fields, or the To header of the reported message when
+// the report does not name them
+func (r *Report) Recipients() []string {
+	if len(r.OriginalRcptTo) > 0 {
+		return r.OriginalRcptTo
+	}
+	if r.Original == nil {
+		return nil
+	}
+	list, err := r.Original.AddressList("To")
+	if err != nil {
+		return nil
+	}
+	out := make([]string, 0, len(list))
+	for _, a := range list {
+		out = append(out, a.Address)
+	}
+	return out
+}
+
+// MailFrom returns the envelope sender of the reported message: the
+// Original-Mail-From field or the Return-Path header of the reported message
+func (r *Report) MailFrom() string {
+	if r.OriginalMailFrom != "" {
+		return r.OriginalMailFrom
+	}
+	if r.Original != nil {
+		return stripBrackets(r.Original.Get("Return-Path"))
+	}
+	return ""
+}
+
+// stripBrackets returns an address without surrounding angle brackets
+func stripBrackets(s string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(s), "<"), ">")
+}
+
+// stripType removes the "dns;" type prefix of an MTA name
+func stripType(s string) string {
+	if _, name, ok := strings.Cut(s, ";"); ok {
+		s = name
+	}
+	return strings.TrimSpace(s)
+}
@@ -0,0 +1,220 @@
+// Package fbl processes complaint feedback loop (FBL) reports: ARF messages
+// that mailbox providers send when a recipient marks a message as spam.
+package fbl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/foxzi/sendry/internal/bounce"
+	"github.com/foxzi/sendry/internal/email"
+	"github.com/foxzi/sendry/internal/metrics"
+	"github.com/foxzi/sendry/internal/reputation"
+	"github.com/foxzi/sendry/internal/suppression"
+)
+
+// CampaignHeader names the campaign of a message, so complaints about it
+// can be attributed to the campaign
+const CampaignHeader = "X-Sendry-Campaign"
+
+// FeedbackTypeNotSpam is the feedback type of reports that withdraw a complaint
+const FeedbackTypeNotSpam = "not-spam"
+
+// Report sources
+const (
+	SourceInbound = "inbound"
+	SourceAPI     = "api"
+)
+
+// Attributions name how a complaint was matched to the message it is about
+const (
+	AttributionVERP   = "verp"
+	AttributionHeader = "header"
+)
+
+// Complaint is a processed feedback report
+type Complaint struct {
+	ID                uint64    `json:"id"`
+	FeedbackType      string    `json:"feedback_type"`
+	Recipient         string    `json:"recipient,omitempty"`
+	SenderDomain      string    `json:"sender_domain,omitempty"`
+	MessageID         string    `json:"message_id,omitempty"`          // Queue message ID, from VERP
+	OriginalMessageID string    `json:"original_message_id,omitempty"` // Message-ID header of the reported message
+	CampaignID        string    `json:"campaign_id,omitempty"`
+	Attribution       string    `json:"attribution,omitempty"` // verp or header
+	ReportingMTA      string    `json:"reporting_mta,omitempty"`
+	UserAgent         string    `json:"user_agent,omitempty"`
+	SourceIP          string    `json:"source_ip,omitempty"`
+	Source            string    `json:"source"` // inbound or api
+	Suppressed        bool      `json:"suppressed"`
+	ArrivedAt         time.Time `json:"arrived_at,omitempty"`
+	ReceivedAt        time.Time `json:"received_at"`
+}
+
+// ComplaintRecorder records complaints of sender domains for reputation
+// scoring
+type ComplaintRecorder interface {
+	Add(ctx context.Context, domain string, at time.Time, counts *reputation.Counts) error
+}
+
+// Processor turns feedback reports into complaints: it stores them,
+// suppresses the complaining recipients and counts them toward the
+// reputation of the sender domain
+type Processor struct {
+	storage      *Storage
+	suppressions *suppression.Storage
+	recorder     ComplaintRecorder
+	logger       *slog.Logger
+}
+
+// NewProcessor creates a new feedback report processor
+func NewProcessor(storage *Storage, suppressions *suppression.Storage, logger *slog.Logger) *Processor {
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	return &Processor{storage: storage, suppressions: suppressions, logger: logger}
+}
+
+// SetComplaintRecorder sets the recorder of complaints, e.g. reputation storage
+func (p *Processor) SetComplaintRecorder(r ComplaintRecorder) {
+	p.recorder = r
+}
+
+// ProcessReport processes a report received by an inbound fbl rule
+func (p *Processor) ProcessReport(ctx context.Context, data []byte) error {
+	_, err := p.Process(ctx, data, SourceInbound)
+	return err
+}
+
+// Process parses and records a feedback report. It returns an error
+// wrapping ErrInvalidReport if data is not an ARF report.
+func (p *Processor) Process(ctx context.Context, data []byte, source string) (*Complaint, error) {
+	report, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	c := newComplaint(report)
+	c.Source = source
+
+	if c.FeedbackType != FeedbackTypeNotSpam {
+		for _, rcpt := range recipients(report, c) {
+			added, err := p.suppressions.Add(ctx, &suppression.Entry{
+				Email:  rcpt,
+				Reason: suppression.ReasonComplaint,
+				Detail: complaintDetail(c),
+			})
+			if err != nil {
+				p.logger.Warn("failed to suppress complaining recipient", "recipient", rcpt, "error", err)
+				continue
+			}
+			c.Suppressed = true
+			if added {
+				p.logger.Info("recipient suppressed after complaint", "recipient", rcpt, "domain", c.SenderDomain)
+			}
+		}
+
+		if p.recorder != nil && c.SenderDomain != "" {
+			if err := p.recorder.Add(ctx, c.SenderDomain, time.Now(), &reputation.Counts{Complaints: 1}); err != nil {
+				p.logger.Warn("failed to record complaint for reputation", "domain", c.SenderDomain, "error", err)
+			}
+		}
+	}
+
+	if err := p.storage.Add(ctx, c); err != nil {
+		return nil, fmt.Errorf("failed to store complaint: %w", err)
+	}
+	metrics.IncFBLComplaints(c.SenderDomain, c.FeedbackType)
+
+	p.logger.Warn("complaint received",
+		"id", c.ID,
+		"type", c.FeedbackType,
+		"domain", c.SenderDomain,
+		"recipient", c.Recipient,
+		"campaign_id", c.CampaignID,
+		"message_id", c.MessageID,
+		"reporting_mta", c.ReportingMTA,
+	)
+	return c, nil
+}
+
+// newComplaint describes a report and attributes it to the reported
+// message: by the VERP envelope sender, or by the Message-ID, campaign and
+// From headers of the reported message
+func newComplaint(r *Report) *Complaint {
+	c := &Complaint{
+		FeedbackType: r.FeedbackType,
+		ReportingMTA: r.ReportingMTA,
+		UserAgent:    r.UserAgent,
+		SourceIP:     r.SourceIP,
+		ArrivedAt:    r.ArrivalDate,
+	}
+	if rcpts := r.Recipients(); len(rcpts) > 0 {
+		c.Recipient = suppression.Normalize(rcpts[0])
+	}
+
+	mailFrom := r.MailFrom()
+	if msgID, rcpt, ok := bounce.DecodeVERP(mailFrom); ok {
+		c.MessageID = msgID
+		c.Attribution = AttributionVERP
+		if c.Recipient == "" {
+			c.Recipient = rcpt
+		}
+	}
+
+	if r.Original != nil {
+		c.OriginalMessageID = strings.TrimSpace(r.Original.Get("Message-ID"))
+		c.CampaignID = campaignID(r.Original.Get(CampaignHeader), r.Original.Get("Feedback-ID"))
+		c.SenderDomain = email.ExtractDomain(r.Original.Get("From"))
+		if c.Attribution == "" && (c.OriginalMessageID != "" || c.CampaignID != "") {
+			c.Attribution = AttributionHeader
+		}
+	}
+
+	// Fall back to the envelope sender, unless it is a VERP address of a
+	// bounce domain, and to the domain the report names
+	if c.SenderDomain == "" && c.Attribution != AttributionVERP {
+		c.SenderDomain = email.ExtractDomain(mailFrom)
+	}
+	if c.SenderDomain == "" {
+		c.SenderDomain = r.ReportedDomain
+	}
+	return c
+}
+
+// recipients returns the addresses to suppress for a complaint
+func recipients(r *Report, c *Complaint) []string {
+	if rcpts := r.Recipients(); len(rcpts) > 0 {
+		return rcpts
+	}
+	if c.Recipient != "" {
+		return []string{c.Recipient}
+	}
+	return nil
+}
+
+// complaintDetail describes the complaint behind a suppression
+func complaintDetail(c *Complaint) string {
+	detail := c.FeedbackType + " report"
+	if c.ReportingMTA != "" {
+		detail += " from " + c.ReportingMTA
+	}
+	if c.CampaignID != "" {
+		detail += ", campaign " + c.CampaignID
+	}
+	return detail
+}
+
+// campaignID returns the campaign of a message from the campaign header,
+// or the first field of a Feedback-ID header (campaign:customer:type:sender)
+func campaignID(header, feedbackID string) string {
+	if id := strings.TrimSpace(header); id != "" {
+		return id
+	}
+	id, _, _ := strings.Cut(strings.TrimSpace(feedbackID), ":")
+	return id
+}
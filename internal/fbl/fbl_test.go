@@ -0,0 +1,192 @@
+package fbl
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/foxzi/sendry/internal/reputation"
+	"github.com/foxzi/sendry/internal/suppression"
+)
+
+// arfReport builds an ARF report of a message with the given envelope
+// sender and extra headers of the reported message
+func arfReport(feedbackType, mailFrom, headers string) []byte {
+	return []byte(strings.ReplaceAll(`From: <abuse@mail.example.net>
+To: <fbl@example.com>
+Subject: FW: Newsletter
+MIME-Version: 1.0
+Content-Type: multipart/report; report-type=feedback-report;
+    boundary="part1_13d.2e68ed54_boundary"
+
+--part1_13d.2e68ed54_boundary
+Content-Type: text/plain; charset="US-ASCII"
+
+This is an email abuse report for an email message received from IP
+192.0.2.1 on Thu, 8 Mar 2005 14:00:00 EDT.
+
+--part1_13d.2e68ed54_boundary
+Content-Type: message/feedback-report
+
+Feedback-Type: `+feedbackType+`
+User-Agent: SomeGenerator/1.0
+Version: 1
+Original-Mail-From: <`+mailFrom+`>
+Original-Rcpt-To: <User@example.net>
+Arrival-Date: Thu, 8 Mar 2005 14:00:00 EDT
+Reporting-MTA: dns; mail.example.net
+Source-IP: 192.0.2.1
+
+--part1_13d.2e68ed54_boundary
+Content-Type: message/rfc822
+Content-Disposition: inline
+
+From: <news@example.com>
+Received: from mailserver.example.com by mail.example.net
+Return-Path: <`+mailFrom+`>
+To: User <user@example.net>
+Subject: Newsletter
+Message-ID: <8787KJKJ3K4J3K4J3K4J3.mail@example.com>
+`+headers+`Date: Thu, 02 Sep 2004 12:34:56 +0000
+
+Hello
+--part1_13d.2e68ed54_boundary--
+`, "\n", "\r\n"))
+}
+
+type recorder struct {
+	domains []string
+}
+
+func (r *recorder) Add(ctx context.Context, domain string, at time.Time, counts *reputation.Counts) error {
+	for i := 0; i < counts.Complaints; i++ {
+		r.domains = append(r.domains, domain)
+	}
+	return nil
+}
+
+func newTestProcessor(t *testing.T) (*Processor, *Storage, *suppression.Storage, *recorder) {
+	t.Helper()
+
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	storage, err := NewStorage(db)
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+	suppressions, err := suppression.NewStorage(db)
+	if err != nil {
+		t.Fatalf("suppression.NewStorage() error = %v", err)
+	}
+
+	rec := &recorder{}
+	p := NewProcessor(storage, suppressions, nil)
+	p.SetComplaintRecorder(rec)
+	return p, storage, suppressions, rec
+}
+
+func TestParse(t *testing.T) {
+	r, err := Parse(arfReport("abuse", "news@example.com", "Feedback-ID: spring:acme:newsletter:sendry\n"))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if r.FeedbackType != "abuse" || r.UserAgent != "SomeGenerator/1.0" || r.ReportingMTA != "mail.example.net" || r.SourceIP != "192.0.2.1" {
+		t.Errorf("Parse() = %+v", r)
+	}
+	if r.MailFrom() != "news@example.com" {
+		t.Errorf("MailFrom() = %q", r.MailFrom())
+	}
+	if rcpts := r.Recipients(); len(rcpts) != 1 || rcpts[0] != "User@example.net" {
+		t.Errorf("Recipients() = %v", rcpts)
+	}
+	if r.ArrivalDate.IsZero() {
+		t.Error("ArrivalDate is not set")
+	}
+	if r.Original == nil || r.Original.Get("Message-ID") != "<8787KJKJ3K4J3K4J3K4J3.mail@example.com>" {
+		t.Errorf("Original = %v", r.Original)
+	}
+
+	for name, data := range map[string]string{
+		"plain message":   "From: a@example.com\r\nSubject: hi\r\n\r\nbody",
+		"delivery status": "Content-Type: multipart/report; report-type=delivery-status; boundary=b\r\n\r\n--b--\r\n",
+		"no report part":  "Content-Type: multipart/report; report-type=feedback-report; boundary=b\r\n\r\n--b\r\nContent-Type: text/plain\r\n\r\nhi\r\n--b--\r\n",
+	} {
+		if _, err := Parse([]byte(data)); !errors.Is(err, ErrInvalidReport) {
+			t.Errorf("Parse(%s) error = %v, want ErrInvalidReport", name, err)
+		}
+	}
+}
+
+func TestProcessorProcess(t *testing.T) {
+	p, storage, suppressions, rec := newTestProcessor(t)
+	ctx := context.Background()
+
+	c, err := p.Process(ctx, arfReport("abuse", "news@example.com", CampaignHeader+": 17\n"), SourceAPI)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if c.ID == 0 || c.Recipient != "user@example.net" || c.SenderDomain != "example.com" || c.Source != SourceAPI {
+		t.Errorf("Process() = %+v", c)
+	}
+	if c.CampaignID != "17" || c.Attribution != AttributionHeader || c.OriginalMessageID == "" {
+		t.Errorf("attribution = %q campaign %q message %q", c.Attribution, c.CampaignID, c.OriginalMessageID)
+	}
+	if !c.Suppressed {
+		t.Error("complaining recipient is not suppressed")
+	}
+	if ok, _ := suppressions.Suppressed(ctx, "user@example.net"); !ok {
+		t.Error("recipient is not on the suppression list")
+	}
+	if len(rec.domains) != 1 || rec.domains[0] != "example.com" {
+		t.Errorf("recorded complaints = %v", rec.domains)
+	}
+
+	list, err := storage.List(ctx, Filter{CampaignID: "17"})
+	if err != nil || len(list) != 1 || list[0].ID != c.ID {
+		t.Errorf("List(campaign) = %v, %v", list, err)
+	}
+	if list, _ := storage.List(ctx, Filter{Domain: "other.com"}); len(list) != 0 {
+		t.Errorf("List(other domain) = %d complaints", len(list))
+	}
+}
+
+func TestProcessorVERPAttribution(t *testing.T) {
+	p, _, _, _ := newTestProcessor(t)
+
+	c, err := p.Process(context.Background(), arfReport("abuse", "bounce+msg-1=user=example.net@bounces.example.org", ""), SourceInbound)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if c.Attribution != AttributionVERP || c.MessageID != "msg-1" {
+		t.Errorf("attribution = %q, message %q", c.Attribution, c.MessageID)
+	}
+	// The sender domain comes from the From header, not the bounce domain
+	if c.SenderDomain != "example.com" {
+		t.Errorf("SenderDomain = %q", c.SenderDomain)
+	}
+}
+
+func TestProcessorNotSpam(t *testing.T) {
+	p, _, suppressions, rec := newTestProcessor(t)
+	ctx := context.Background()
+
+	c, err := p.Process(ctx, arfReport("not-spam", "news@example.com", ""), SourceAPI)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if c.Suppressed || len(rec.domains) != 0 {
+		t.Errorf("not-spam report suppressed = %v, recorded = %v", c.Suppressed, rec.domains)
+	}
+	if ok, _ := suppressions.Suppressed(ctx, "user@example.net"); ok {
+		t.Error("not-spam report suppressed the recipient")
+	}
+}
@@ -0,0 +1,133 @@
+package fbl
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var bucketComplaints = []byte("fbl_complaints")
+
+const (
+	// DefaultMaxComplaints is the number of complaints kept before the oldest are pruned
+	DefaultMaxComplaints = 10000
+
+	// defaultListLimit is used when a filter has no limit
+	defaultListLimit = 100
+)
+
+// Filter selects complaints
+type Filter struct {
+	Domain     string // Sender domain
+	CampaignID string
+	Recipient  string
+	Limit      int
+}
+
+// Match reports whether a complaint matches the filter
+func (f *Filter) Match(c *Complaint) bool {
+	if f.Domain != "" && !strings.EqualFold(c.SenderDomain, f.Domain) {
+		return false
+	}
+	if f.CampaignID != "" && c.CampaignID != f.CampaignID {
+		return false
+	}
+	if f.Recipient != "" && !strings.EqualFold(c.Recipient, f.Recipient) {
+		return false
+	}
+	return true
+}
+
+// Storage stores processed complaints in BoltDB
+type Storage struct {
+	db            *bolt.DB
+	maxComplaints int
+}
+
+// NewStorage creates a new complaint storage using the provided BoltDB instance
+func NewStorage(db *bolt.DB) (*Storage, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucketComplaints)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create complaint bucket: %w", err)
+	}
+	return &Storage{db: db, maxComplaints: DefaultMaxComplaints}, nil
+}
+
+// Add stores a complaint, pruning the oldest ones above the retention limit
+func (s *Storage) Add(ctx context.Context, c *Complaint) error {
+	if c.ReceivedAt.IsZero() {
+		c.ReceivedAt = time.Now()
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketComplaints)
+
+		id, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		c.ID = id
+
+		data, err := json.Marshal(c)
+		if err != nil {
+			return fmt.Errorf("failed to marshal complaint: %w", err)
+		}
+		if err := b.Put(itob(id), data); err != nil {
+			return err
+		}
+
+		if id <= uint64(s.maxComplaints) {
+			return nil
+		}
+		oldest := id - uint64(s.maxComplaints)
+		cur := b.Cursor()
+		for k, _ := cur.First(); k != nil && binary.BigEndian.Uint64(k) <= oldest; k, _ = cur.First() {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// List returns complaints matching the filter, newest first
+func (s *Storage) List(ctx context.Context, filter Filter) ([]*Complaint, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+
+	complaints := make([]*Complaint, 0)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		cur := tx.Bucket(bucketComplaints).Cursor()
+		for k, v := cur.Last(); k != nil && len(complaints) < limit; k, v = cur.Prev() {
+			var c Complaint
+			if err := json.Unmarshal(v, &c); err != nil {
+				continue
+			}
+			if !filter.Match(&c) {
+				continue
+			}
+			complaints = append(complaints, &c)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return complaints, nil
+}
+
+func itob(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return b
+}
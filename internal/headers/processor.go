@@ -2,12 +2,24 @@ package headers
 
 import (
 	"bytes"
+	"context"
 	"strings"
+	"sync"
+
+	"github.com/foxzi/sendry/internal/email"
 )
 
+// Envelope is the envelope of a message that rule conditions match on
+type Envelope struct {
+	From string
+	To   []string
+}
+
 // Processor applies header rules to email data
 type Processor struct {
-	config *Config
+	config  *Config
+	storage *Storage
+	mu      sync.RWMutex
 }
 
 // NewProcessor creates a new header processor
@@ -15,22 +27,77 @@ func NewProcessor(cfg *Config) *Processor {
 	return &Processor{config: cfg}
 }
 
-// Process applies header rules to email data for a given sender domain
+// SetConfig replaces the header rules, e.g. on a config reload
+func (p *Processor) SetConfig(cfg *Config) {
+	p.mu.Lock()
+	p.config = cfg
+	p.mu.Unlock()
+}
+
+// SetStorage sets the rule sets managed through the API, applied after the
+// rules of the config
+func (p *Processor) SetStorage(s *Storage) {
+	p.mu.Lock()
+	p.storage = s
+	p.mu.Unlock()
+}
+
+// Process applies header rules to email data for a given sender domain.
+// Rules with sender or recipient conditions do not apply.
 func (p *Processor) Process(data []byte, domain string) []byte {
-	if p.config == nil || !p.config.HasRules() {
+	return p.process(data, domain, Envelope{})
+}
+
+// ProcessMessage applies the header rules of the sender domain of a message,
+// matching rule conditions on its envelope
+func (p *Processor) ProcessMessage(data []byte, env Envelope) []byte {
+	return p.process(data, email.ExtractDomain(env.From), env)
+}
+
+// process applies the rules of the config and the stored rule set of the
+// domain
+func (p *Processor) process(data []byte, domain string, env Envelope) []byte {
+	p.mu.RLock()
+	cfg, storage := p.config, p.storage
+	p.mu.RUnlock()
+
+	rules := cfg.GetRulesForDomain(domain)
+	if storage != nil && domain != "" {
+		// A storage error leaves the config rules
+		if set, err := storage.Get(context.Background(), domain); err == nil && set != nil {
+			rules = append(rules, set.Rules...)
+		}
+	}
+	if len(rules) == 0 {
 		return data
 	}
 
-	rules := p.config.GetRulesForDomain(domain)
+	return applyRules(data, rules, &match{env: env, domain: domain})
+}
+
+// Apply applies a list of rules to email data regardless of domain. Rules
+// with sender, recipient or domain conditions do not apply.
+func Apply(data []byte, rules []Rule) []byte {
 	if len(rules) == 0 {
 		return data
 	}
+	return applyRules(data, rules, &match{})
+}
+
+// Preview applies a list of rules to a message as delivery would, e.g. to
+// try out a rule set before storing it
+func Preview(data []byte, rules []Rule, env Envelope) []byte {
+	return applyRules(data, rules, &match{env: env, domain: email.ExtractDomain(env.From)})
+}
 
-	return applyRules(data, rules)
+// match is what rule conditions are matched on
+type match struct {
+	env    Envelope
+	domain string
 }
 
 // applyRules applies a list of rules to email data
-func applyRules(data []byte, rules []Rule) []byte {
+func applyRules(data []byte, rules []Rule, m *match) []byte {
 	// Split headers and body
 	headers, body := splitHeadersBody(data)
 
@@ -39,6 +106,9 @@ func applyRules(data []byte, rules []Rule) []byte {
 
 	// Apply each rule
 	for _, rule := range rules {
+		if !m.matches(rule.When, headerList) {
+			continue
+		}
 		headerList = applyRule(headerList, rule)
 	}
 
@@ -46,6 +116,57 @@ func applyRules(data []byte, rules []Rule) []byte {
 	return buildEmail(headerList, body)
 }
 
+// matches reports whether the message meets a rule condition. Conditions
+// on headers see the changes of earlier rules.
+func (m *match) matches(c *Condition, headers []header) bool {
+	if c == nil {
+		return true
+	}
+	if c.Sender != "" && (m.env.From == "" || !matchString(c.Sender, m.env.From)) {
+		return false
+	}
+	if c.Domain != "" && (m.domain == "" || !matchString(c.Domain, m.domain)) {
+		return false
+	}
+	if c.Recipient != "" {
+		found := false
+		for _, rcpt := range m.env.To {
+			if matchString(c.Recipient, rcpt) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for name, expr := range c.Headers {
+		found := false
+		for _, h := range headers {
+			if strings.EqualFold(h.name, name) && matchString(expr, unfold(h.value)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, name := range c.Missing {
+		for _, h := range headers {
+			if strings.EqualFold(h.name, name) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// unfold joins the lines of a folded header value
+func unfold(value string) string {
+	return strings.ReplaceAll(value, "\r\n", "")
+}
+
 // header represents a single header with name and value
 type header struct {
 	name  string
@@ -116,11 +237,19 @@ func parseHeaders(data []byte) []header {
 func applyRule(headers []header, rule Rule) []header {
 	switch rule.Action {
 	case ActionRemove:
+		if rule.Pattern != "" {
+			return removeMatching(headers, rule.Headers, rule.Pattern)
+		}
 		return removeHeaders(headers, rule.Headers)
 	case ActionReplace:
+		if rule.Pattern != "" {
+			return rewriteHeader(headers, rule.Header, rule.Pattern, rule.Value)
+		}
 		return replaceHeader(headers, rule.Header, rule.Value)
 	case ActionAdd:
 		return addHeader(headers, rule.Header, rule.Value)
+	case ActionCopy:
+		return copyHeader(headers, rule.Header, rule.Target)
 	}
 	return headers
 }
@@ -172,6 +301,60 @@ func replaceHeader(headers []header, name, value string) []header {
 	return headers
 }
 
+// removeMatching removes the headers of the given names whose value matches
+// the pattern
+func removeMatching(headers []header, names []string, pattern string) []header {
+	re := compile(pattern)
+	if re == nil {
+		return headers
+	}
+
+	var result []header
+	for _, h := range headers {
+		remove := false
+		for _, name := range names {
+			if strings.EqualFold(h.name, name) && re.MatchString(unfold(h.value)) {
+				remove = true
+				break
+			}
+		}
+		if !remove {
+			result = append(result, h)
+		}
+	}
+
+	return result
+}
+
+// rewriteHeader replaces the matches of the pattern in every header of the
+// given name. The replacement expands $1 and ${name} with the groups of the
+// match. Headers are not added.
+func rewriteHeader(headers []header, name, pattern, value string) []header {
+	re := compile(pattern)
+	if re == nil {
+		return headers
+	}
+
+	for i := range headers {
+		if strings.EqualFold(headers[i].name, name) {
+			headers[i].value = re.ReplaceAllString(unfold(headers[i].value), value)
+		}
+	}
+
+	return headers
+}
+
+// copyHeader sets the target header to the value of the first source header.
+// Nothing changes if the message has no source header.
+func copyHeader(headers []header, source, target string) []header {
+	for _, h := range headers {
+		if strings.EqualFold(h.name, source) {
+			return replaceHeader(headers, target, h.value)
+		}
+	}
+	return headers
+}
+
 // addHeader adds a new header (always appends, even if exists)
 func addHeader(headers []header, name, value string) []header {
 	if name == "" {
@@ -339,3 +339,155 @@ func TestConfig_HasRules(t *testing.T) {
 		})
 	}
 }
+
+func TestProcessor_SetConfig(t *testing.T) {
+	email := "From: sender@example.com\r\n" +
+		"Subject: Test\r\n" +
+		"\r\n" +
+		"Body"
+
+	p := NewProcessor(nil)
+	p.SetConfig(&Config{
+		Global: []Rule{{Action: ActionAdd, Header: "X-Mailer", Value: "Sendry"}},
+	})
+
+	result := string(p.Process([]byte(email), "example.com"))
+	if !strings.Contains(result, "X-Mailer: Sendry") {
+		t.Errorf("rules set after creation were not applied: %q", result)
+	}
+
+	p.SetConfig(nil)
+	if result := string(p.Process([]byte(email), "example.com")); result != email {
+		t.Errorf("removed rules were still applied: %q", result)
+	}
+}
+
+func TestProcessor_Conditions(t *testing.T) {
+	email := "From: App <app@example.com>\r\n" +
+		"To: user@gmail.com\r\n" +
+		"X-Campaign: spring-2026\r\n" +
+		"Subject: Test\r\n" +
+		"\r\n" +
+		"Body"
+
+	tests := []struct {
+		name string
+		when *Condition
+		want bool
+	}{
+		{"no condition", nil, true},
+		{"sender", &Condition{Sender: `^app@`}, true},
+		{"sender mismatch", &Condition{Sender: `^noreply@`}, false},
+		{"any recipient", &Condition{Recipient: `@gmail\.com$`}, true},
+		{"recipient mismatch", &Condition{Recipient: `@yahoo\.com$`}, false},
+		{"domain", &Condition{Domain: `^example\.com$`}, true},
+		{"header value", &Condition{Headers: map[string]string{"x-campaign": `^spring-`}}, true},
+		{"header missing", &Condition{Headers: map[string]string{"X-Priority": `.`}}, false},
+		{"missing", &Condition{Missing: []string{"List-Unsubscribe"}}, true},
+		{"not missing", &Condition{Missing: []string{"x-campaign"}}, false},
+		{"all must match", &Condition{Sender: `^app@`, Recipient: `@yahoo\.com$`}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewProcessor(&Config{Global: []Rule{
+				{Action: ActionAdd, Header: "X-Matched", Value: "yes", When: tt.when},
+			}})
+			result := string(p.ProcessMessage([]byte(email), Envelope{
+				From: "app@example.com",
+				To:   []string{"other@example.org", "user@gmail.com"},
+			}))
+			if got := strings.Contains(result, "X-Matched: yes"); got != tt.want {
+				t.Errorf("rule applied = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Without an envelope rules with sender conditions do not apply
+	p := NewProcessor(&Config{Global: []Rule{
+		{Action: ActionAdd, Header: "X-Matched", Value: "yes", When: &Condition{Sender: `.`}},
+	}})
+	if strings.Contains(string(p.Process([]byte(email), "example.com")), "X-Matched") {
+		t.Error("sender condition matched without an envelope")
+	}
+}
+
+func TestProcessor_PatternAndCopy(t *testing.T) {
+	email := "From: sender@example.com\r\n" +
+		"Received: from internal.corp (10.0.0.5)\r\n" +
+		"Received: from mx.example.com (203.0.113.1)\r\n" +
+		"Subject: [internal] Report\r\n" +
+		"Message-ID: <abc@example.com>\r\n" +
+		"\r\n" +
+		"Body"
+
+	rules := []Rule{
+		{Action: ActionRemove, Headers: []string{"Received"}, Pattern: `\.corp\b`},
+		{Action: ActionReplace, Header: "Subject", Pattern: `^\[(\w+)\] `, Value: "($1) "},
+		{Action: ActionReplace, Header: "X-Absent", Pattern: `.`, Value: "x"},
+		{Action: ActionCopy, Header: "Message-ID", Target: "X-Original-Message-ID"},
+		{Action: ActionCopy, Header: "X-Absent", Target: "X-Copy"},
+	}
+	if err := ValidateRules(rules); err != nil {
+		t.Fatalf("ValidateRules() error = %v", err)
+	}
+
+	result := string(Preview([]byte(email), rules, Envelope{From: "sender@example.com"}))
+
+	if strings.Contains(result, "internal.corp") {
+		t.Error("matching Received header should be removed")
+	}
+	if !strings.Contains(result, "Received: from mx.example.com") {
+		t.Error("other Received header should be kept")
+	}
+	if !strings.Contains(result, "Subject: (internal) Report\r\n") {
+		t.Errorf("Subject should be rewritten: %q", result)
+	}
+	if strings.Contains(result, "X-Absent") || strings.Contains(result, "X-Copy") {
+		t.Error("pattern replace and copy must not add headers")
+	}
+	if !strings.Contains(result, "X-Original-Message-ID: <abc@example.com>\r\n") {
+		t.Errorf("Message-ID should be copied: %q", result)
+	}
+}
+
+func TestRule_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    Rule
+		wantErr string
+	}{
+		{"valid add", Rule{Action: ActionAdd, Header: "X-A", Value: "1"}, ""},
+		{"valid conditions", Rule{Action: ActionAdd, Header: "X-A", When: &Condition{Sender: `^a@`, Headers: map[string]string{"Subject": `(?i)urgent`}}}, ""},
+		{"unknown action", Rule{Action: "rename", Header: "X-A"}, ".action must be one of"},
+		{"remove without headers", Rule{Action: ActionRemove}, ".headers is required"},
+		{"add without header", Rule{Action: ActionAdd, Value: "1"}, ".header is required"},
+		{"copy without target", Rule{Action: ActionCopy, Header: "X-A"}, ".target are required"},
+		{"header name with colon", Rule{Action: ActionAdd, Header: "X-A: b"}, "invalid header name"},
+		{"value with line break", Rule{Action: ActionAdd, Header: "X-A", Value: "a\r\nBcc: x@example.com"}, "line breaks"},
+		{"pattern on add", Rule{Action: ActionAdd, Header: "X-A", Pattern: "a"}, "only to replace and remove"},
+		{"invalid pattern", Rule{Action: ActionReplace, Header: "X-A", Pattern: "("}, ".pattern:"},
+		{"invalid condition", Rule{Action: ActionAdd, Header: "X-A", When: &Condition{Recipient: "["}}, ".when.recipient:"},
+		{"invalid header condition", Rule{Action: ActionAdd, Header: "X-A", When: &Condition{Headers: map[string]string{"Subject": "("}}}, ".when.headers.Subject:"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rule.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	cfg := &Config{Domains: map[string][]Rule{"example.com": {{Action: ActionAdd, Header: "X-A"}, {Action: ActionRemove}}}}
+	if err := cfg.Validate(); err == nil || err.Error() != "domains.example.com[1].headers is required for remove" {
+		t.Errorf("Config.Validate() error = %v", err)
+	}
+}
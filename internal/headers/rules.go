@@ -1,5 +1,12 @@
 package headers
 
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
 // Action defines the type of header manipulation
 type Action string
 
@@ -7,14 +14,33 @@ const (
 	ActionRemove  Action = "remove"
 	ActionReplace Action = "replace"
 	ActionAdd     Action = "add"
+	ActionCopy    Action = "copy"
 )
 
 // Rule defines a header manipulation rule
 type Rule struct {
 	Action  Action   `yaml:"action" json:"action"`
 	Headers []string `yaml:"headers,omitempty" json:"headers,omitempty"` // For remove action
-	Header  string   `yaml:"header,omitempty" json:"header,omitempty"`   // For replace/add
-	Value   string   `yaml:"value,omitempty" json:"value,omitempty"`     // For replace/add
+	Header  string   `yaml:"header,omitempty" json:"header,omitempty"`   // For replace/add, source of copy
+	Value   string   `yaml:"value,omitempty" json:"value,omitempty"`     // For replace/add, $1 expands with pattern
+	Target  string   `yaml:"target,omitempty" json:"target,omitempty"`   // Header copy writes to
+
+	// Regular expression on header values. Replace rewrites the matches
+	// of every header with value, remove removes only matching headers.
+	Pattern string `yaml:"pattern,omitempty" json:"pattern,omitempty"`
+
+	// The rule applies only when all conditions match
+	When *Condition `yaml:"when,omitempty" json:"when,omitempty"`
+}
+
+// Condition limits a rule to messages matching regular expressions. Empty
+// fields match every message.
+type Condition struct {
+	Sender    string            `yaml:"sender,omitempty" json:"sender,omitempty"`       // Envelope sender
+	Recipient string            `yaml:"recipient,omitempty" json:"recipient,omitempty"` // Any envelope recipient
+	Domain    string            `yaml:"domain,omitempty" json:"domain,omitempty"`       // Sender domain
+	Headers   map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`     // Value of a header by name
+	Missing   []string          `yaml:"missing,omitempty" json:"missing,omitempty"`     // Headers the message must not have
 }
 
 // Config contains header rules configuration
@@ -62,3 +88,113 @@ func (c *Config) HasRules() bool {
 	}
 	return false
 }
+
+// Validate checks the actions, fields and regular expressions of all rules
+func (c *Config) Validate() error {
+	if c == nil {
+		return nil
+	}
+	if err := ValidateRules(c.Global); err != nil {
+		return fmt.Errorf("global%w", err)
+	}
+	for domain, rules := range c.Domains {
+		if err := ValidateRules(rules); err != nil {
+			return fmt.Errorf("domains.%s%w", domain, err)
+		}
+	}
+	return nil
+}
+
+// ValidateRules checks a list of rules. The error starts with the index of
+// the invalid rule, e.g. "[2].pattern: ...".
+func ValidateRules(rules []Rule) error {
+	for i := range rules {
+		if err := rules[i].Validate(); err != nil {
+			return fmt.Errorf("[%d]%w", i, err)
+		}
+	}
+	return nil
+}
+
+// Validate checks the action, fields and regular expressions of the rule.
+// The error names the invalid field, e.g. ".pattern: ...".
+func (r *Rule) Validate() error {
+	switch r.Action {
+	case ActionRemove:
+		if len(r.Headers) == 0 {
+			return fmt.Errorf(".headers is required for remove")
+		}
+	case ActionReplace, ActionAdd:
+		if r.Header == "" {
+			return fmt.Errorf(".header is required for %s", r.Action)
+		}
+	case ActionCopy:
+		if r.Header == "" || r.Target == "" {
+			return fmt.Errorf(".header and .target are required for copy")
+		}
+	default:
+		return fmt.Errorf(".action must be one of: add, replace, remove, copy")
+	}
+	for _, name := range append([]string{r.Header, r.Target}, r.Headers...) {
+		if strings.ContainsAny(name, ": \t\r\n") {
+			return fmt.Errorf(": invalid header name %q", name)
+		}
+	}
+	if strings.ContainsAny(r.Value, "\r\n") {
+		return fmt.Errorf(".value must not contain line breaks")
+	}
+
+	if r.Pattern != "" {
+		if r.Action != ActionReplace && r.Action != ActionRemove {
+			return fmt.Errorf(".pattern applies only to replace and remove")
+		}
+		if _, err := regexp.Compile(r.Pattern); err != nil {
+			return fmt.Errorf(".pattern: %v", err)
+		}
+	}
+
+	if r.When == nil {
+		return nil
+	}
+	for _, f := range []struct{ name, expr string }{
+		{"sender", r.When.Sender},
+		{"recipient", r.When.Recipient},
+		{"domain", r.When.Domain},
+	} {
+		if _, err := regexp.Compile(f.expr); err != nil {
+			return fmt.Errorf(".when.%s: %v", f.name, err)
+		}
+	}
+	for name, expr := range r.When.Headers {
+		if _, err := regexp.Compile(expr); err != nil {
+			return fmt.Errorf(".when.headers.%s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// patterns caches compiled regular expressions by their source
+var patterns sync.Map
+
+// compile returns the compiled regular expression, nil if it is invalid
+func compile(expr string) *regexp.Regexp {
+	if re, ok := patterns.Load(expr); ok {
+		return re.(*regexp.Regexp)
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil
+	}
+	patterns.Store(expr, re)
+	return re
+}
+
+// matchString reports whether s matches expr. An empty expression matches
+// everything, an invalid one nothing.
+func matchString(expr, s string) bool {
+	if expr == "" {
+		return true
+	}
+	re := compile(expr)
+	return re != nil && re.MatchString(s)
+}
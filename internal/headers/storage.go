@@ -0,0 +1,116 @@
+package headers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var bucketRuleSets = []byte("header_rules")
+
+// RuleSet is the list of header rules of a sender domain managed through
+// the API
+type RuleSet struct {
+	Domain    string    `json:"domain"`
+	Rules     []Rule    `json:"rules"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Storage provides rule set storage
+type Storage struct {
+	db *bolt.DB
+}
+
+// NewStorage creates a new rule set storage using the provided BoltDB instance
+func NewStorage(db *bolt.DB) (*Storage, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucketRuleSets)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create header rules bucket: %w", err)
+	}
+	return &Storage{db: db}, nil
+}
+
+// NormalizeDomain returns the domain in the form rule sets are stored under
+func NormalizeDomain(domain string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(domain), "."))
+}
+
+// Get retrieves the rule set of a domain, nil if it has none
+func (s *Storage) Get(ctx context.Context, domain string) (*RuleSet, error) {
+	var set *RuleSet
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bucketRuleSets).Get([]byte(NormalizeDomain(domain)))
+		if data == nil {
+			return nil
+		}
+		set = &RuleSet{}
+		return json.Unmarshal(data, set)
+	})
+
+	return set, err
+}
+
+// List returns all rule sets ordered by domain
+func (s *Storage) List(ctx context.Context) ([]*RuleSet, error) {
+	var result []*RuleSet
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketRuleSets).ForEach(func(k, v []byte) error {
+			var set RuleSet
+			if err := json.Unmarshal(v, &set); err != nil {
+				return nil // Skip invalid entries
+			}
+			result = append(result, &set)
+			return nil
+		})
+	})
+
+	return result, err
+}
+
+// Put creates or replaces the rule set of a domain
+func (s *Storage) Put(ctx context.Context, set *RuleSet) error {
+	set.Domain = NormalizeDomain(set.Domain)
+	if set.Domain == "" {
+		return fmt.Errorf("domain is required")
+	}
+	if err := ValidateRules(set.Rules); err != nil {
+		return fmt.Errorf("rules%w", err)
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketRuleSets)
+
+		now := time.Now()
+		set.CreatedAt = now
+		if existing := b.Get([]byte(set.Domain)); existing != nil {
+			var old RuleSet
+			if err := json.Unmarshal(existing, &old); err == nil {
+				set.CreatedAt = old.CreatedAt
+			}
+		}
+		set.UpdatedAt = now
+
+		data, err := json.Marshal(set)
+		if err != nil {
+			return fmt.Errorf("failed to marshal rule set: %w", err)
+		}
+		return b.Put([]byte(set.Domain), data)
+	})
+}
+
+// Delete removes the rule set of a domain
+func (s *Storage) Delete(ctx context.Context, domain string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketRuleSets).Delete([]byte(NormalizeDomain(domain)))
+	})
+}
@@ -0,0 +1,98 @@
+package headers
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func newTestStorage(t *testing.T) *Storage {
+	t.Helper()
+
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	storage, err := NewStorage(db)
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+	return storage
+}
+
+func TestStorageCRUD(t *testing.T) {
+	storage := newTestStorage(t)
+	ctx := context.Background()
+
+	set := &RuleSet{Domain: "Example.COM", Rules: []Rule{{Action: ActionAdd, Header: "X-A", Value: "1"}}}
+	if err := storage.Put(ctx, set); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if set.Domain != "example.com" {
+		t.Errorf("Domain = %q, want normalized example.com", set.Domain)
+	}
+	created := set.CreatedAt
+
+	// Replacing keeps the creation time
+	time.Sleep(time.Millisecond)
+	update := &RuleSet{Domain: "example.com", Rules: []Rule{{Action: ActionAdd, Header: "X-B", Value: "2"}}}
+	if err := storage.Put(ctx, update); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if !update.CreatedAt.Equal(created) {
+		t.Errorf("CreatedAt = %v, want %v", update.CreatedAt, created)
+	}
+
+	got, err := storage.Get(ctx, "EXAMPLE.com")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got == nil || len(got.Rules) != 1 || got.Rules[0].Header != "X-B" {
+		t.Fatalf("Get() = %+v", got)
+	}
+
+	if err := storage.Put(ctx, &RuleSet{Domain: "other.com", Rules: []Rule{{Action: ActionRemove}}}); err == nil {
+		t.Error("Put() stored an invalid rule")
+	}
+
+	if err := storage.Delete(ctx, "example.com"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	sets, err := storage.List(ctx)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(sets) != 0 {
+		t.Errorf("List() = %d rule sets, want 0", len(sets))
+	}
+}
+
+func TestProcessor_StoredRules(t *testing.T) {
+	storage := newTestStorage(t)
+	email := "From: sender@example.com\r\nSubject: Test\r\n\r\nBody"
+
+	p := NewProcessor(&Config{Global: []Rule{{Action: ActionAdd, Header: "X-Order", Value: "config"}}})
+	p.SetStorage(storage)
+	if err := storage.Put(context.Background(), &RuleSet{
+		Domain: "example.com",
+		Rules:  []Rule{{Action: ActionAdd, Header: "X-Order", Value: "stored"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Stored rules apply after the rules of the config, to their domain only
+	result := string(p.ProcessMessage([]byte(email), Envelope{From: "sender@example.com"}))
+	if !strings.Contains(result, "X-Order: config\r\nX-Order: stored\r\n") {
+		t.Errorf("stored rules not applied after config rules: %q", result)
+	}
+	result = string(p.ProcessMessage([]byte(email), Envelope{From: "sender@other.com"}))
+	if strings.Contains(result, "stored") {
+		t.Errorf("stored rules applied to another domain: %q", result)
+	}
+}
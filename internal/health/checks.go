@@ -0,0 +1,73 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/foxzi/sendry/internal/queue"
+)
+
+// Certificate is a served TLS certificate and its expiry
+type Certificate struct {
+	Name     string
+	NotAfter time.Time
+}
+
+// StorageCheck fails when the queue storage cannot be read
+func StorageCheck(q queue.Queue) CheckFunc {
+	return func(ctx context.Context) error {
+		_, err := q.Stats(ctx)
+		return err
+	}
+}
+
+// BacklogCheck fails when more than max messages wait for delivery,
+// pending or deferred
+func BacklogCheck(q queue.Queue, max int64) CheckFunc {
+	return func(ctx context.Context) error {
+		stats, err := q.Stats(ctx)
+		if err != nil {
+			return err
+		}
+		if backlog := stats.Pending + stats.Deferred; backlog > max {
+			return fmt.Errorf("%d messages waiting, limit %d", backlog, max)
+		}
+		return nil
+	}
+}
+
+// RunningCheck fails when a component is not running
+func RunningCheck(name string, running func() bool) CheckFunc {
+	return func(ctx context.Context) error {
+		if !running() {
+			return fmt.Errorf("%s is not running", name)
+		}
+		return nil
+	}
+}
+
+// CertificateCheck fails when there is no certificate or one has expired
+// or expires within minValidity
+func CertificateCheck(certs func() ([]Certificate, error), minValidity time.Duration) CheckFunc {
+	return func(ctx context.Context) error {
+		list, err := certs()
+		if err != nil {
+			return err
+		}
+		if len(list) == 0 {
+			return errors.New("no certificate")
+		}
+		now := time.Now()
+		for _, c := range list {
+			if !now.Before(c.NotAfter) {
+				return fmt.Errorf("certificate %s expired on %s", c.Name, c.NotAfter.Format(time.RFC3339))
+			}
+			if c.NotAfter.Sub(now) < minValidity {
+				return fmt.Errorf("certificate %s expires on %s", c.Name, c.NotAfter.Format(time.RFC3339))
+			}
+		}
+		return nil
+	}
+}
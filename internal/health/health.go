@@ -0,0 +1,154 @@
+// Package health reports whether the node is ready to take traffic: named
+// checks of its internal state, such as storage, the queue processor and
+// the TLS certificate, and a draining state while it shuts down.
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultTimeout limits a check when no timeout is set
+const defaultTimeout = 2 * time.Second
+
+// Readiness states
+const (
+	StatusReady    = "ready"
+	StatusNotReady = "not_ready"
+	StatusDraining = "draining"
+)
+
+// Check states
+const (
+	CheckOK   = "ok"
+	CheckFail = "fail"
+)
+
+// CheckFunc returns an error when the checked part is not healthy
+type CheckFunc func(ctx context.Context) error
+
+// CheckResult is the outcome of a check
+type CheckResult struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"` // ok or fail
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// Report is the readiness of the node with the results of its checks
+type Report struct {
+	Status string        `json:"status"` // ready, not_ready or draining
+	Checks []CheckResult `json:"checks"`
+}
+
+// Ready reports whether the node should take traffic
+func (r *Report) Ready() bool {
+	return r.Status == StatusReady
+}
+
+// Failure returns the first failed check as "name: error", empty when all
+// passed
+func (r *Report) Failure() string {
+	for _, c := range r.Checks {
+		if c.Status == CheckFail {
+			return c.Name + ": " + c.Error
+		}
+	}
+	return ""
+}
+
+type check struct {
+	name string
+	fn   CheckFunc
+}
+
+// Checker runs the readiness checks. A nil Checker is always ready.
+type Checker struct {
+	timeout time.Duration
+
+	mu       sync.RWMutex
+	checks   []check
+	draining atomic.Bool
+}
+
+// New creates a checker, timeout limits each check (default: 2s)
+func New(timeout time.Duration) *Checker {
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	return &Checker{timeout: timeout}
+}
+
+// Add registers a check, checks are reported in the order they were added
+func (c *Checker) Add(name string, fn CheckFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks = append(c.checks, check{name: name, fn: fn})
+}
+
+// SetDraining marks the node as draining: it reports not ready while it
+// finishes its work and shuts down
+func (c *Checker) SetDraining(draining bool) {
+	if c != nil {
+		c.draining.Store(draining)
+	}
+}
+
+// Check runs all checks concurrently and returns the readiness of the node
+func (c *Checker) Check(ctx context.Context) *Report {
+	report := &Report{Status: StatusReady, Checks: []CheckResult{}}
+	if c == nil {
+		return report
+	}
+
+	c.mu.RLock()
+	checks := append([]check(nil), c.checks...)
+	c.mu.RUnlock()
+
+	report.Checks = make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, chk := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Checks[i] = c.run(ctx, chk)
+		}()
+	}
+	wg.Wait()
+
+	for _, r := range report.Checks {
+		if r.Status == CheckFail {
+			report.Status = StatusNotReady
+		}
+	}
+	if c.draining.Load() {
+		report.Status = StatusDraining
+	}
+	return report
+}
+
+// run runs a check within the timeout
+func (c *Checker) run(ctx context.Context, chk check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	errCh := make(chan error, 1)
+	go func() { errCh <- chk.fn(ctx) }()
+
+	var err error
+	select {
+	case err = <-errCh:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := CheckResult{Name: chk.name, Status: CheckOK, Duration: time.Since(start).Round(time.Microsecond).String()}
+	if err != nil {
+		result.Status = CheckFail
+		result.Error = err.Error()
+	}
+	return result
+}
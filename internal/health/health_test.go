@@ -0,0 +1,211 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/foxzi/sendry/internal/queue"
+)
+
+// statsQueue is a queue with fixed stats
+type statsQueue struct {
+	queue.Queue
+	stats *queue.QueueStats
+	err   error
+}
+
+func (q *statsQueue) Stats(ctx context.Context) (*queue.QueueStats, error) {
+	return q.stats, q.err
+}
+
+func TestChecker(t *testing.T) {
+	ok := func(ctx context.Context) error { return nil }
+	fail := func(ctx context.Context) error { return errors.New("boom") }
+	// A check that ignores its context
+	slow := func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	}
+
+	t.Run("nil checker", func(t *testing.T) {
+		var c *Checker
+		c.SetDraining(true)
+		if report := c.Check(context.Background()); !report.Ready() {
+			t.Errorf("nil checker status = %q, want ready", report.Status)
+		}
+	})
+
+	t.Run("all pass", func(t *testing.T) {
+		c := New(time.Second)
+		c.Add("a", ok)
+		c.Add("b", ok)
+		report := c.Check(context.Background())
+		if !report.Ready() || len(report.Checks) != 2 || report.Checks[0].Name != "a" {
+			t.Errorf("report = %+v", report)
+		}
+		if report.Failure() != "" {
+			t.Errorf("Failure() = %q, want empty", report.Failure())
+		}
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		c := New(time.Second)
+		c.Add("a", ok)
+		c.Add("storage", fail)
+		report := c.Check(context.Background())
+		if report.Status != StatusNotReady {
+			t.Errorf("status = %q, want %q", report.Status, StatusNotReady)
+		}
+		if report.Failure() != "storage: boom" {
+			t.Errorf("Failure() = %q", report.Failure())
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		c := New(20 * time.Millisecond)
+		c.Add("slow", slow)
+		start := time.Now()
+		report := c.Check(context.Background())
+		if report.Status != StatusNotReady || report.Checks[0].Error != context.DeadlineExceeded.Error() {
+			t.Errorf("report = %+v", report)
+		}
+		if time.Since(start) > 500*time.Millisecond {
+			t.Errorf("Check() waited for the slow check")
+		}
+	})
+
+	t.Run("draining", func(t *testing.T) {
+		c := New(time.Second)
+		c.Add("a", ok)
+		c.SetDraining(true)
+		if report := c.Check(context.Background()); report.Status != StatusDraining {
+			t.Errorf("status = %q, want %q", report.Status, StatusDraining)
+		}
+		c.SetDraining(false)
+		if report := c.Check(context.Background()); !report.Ready() {
+			t.Errorf("status = %q, want ready", report.Status)
+		}
+	})
+}
+
+func TestBacklogCheck(t *testing.T) {
+	q := &statsQueue{stats: &queue.QueueStats{Pending: 60, Deferred: 40, Failed: 1000}}
+	if err := BacklogCheck(q, 100)(context.Background()); err != nil {
+		t.Errorf("backlog at the limit: %v", err)
+	}
+	if err := BacklogCheck(q, 99)(context.Background()); err == nil {
+		t.Error("backlog over the limit passed")
+	}
+	q.err = errors.New("closed")
+	if err := StorageCheck(q)(context.Background()); err == nil {
+		t.Error("storage check passed with a failing queue")
+	}
+}
+
+func TestCertificateCheck(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name    string
+		certs   []Certificate
+		err     error
+		wantErr bool
+	}{
+		{name: "valid", certs: []Certificate{{Name: "mail.example.com", NotAfter: now.Add(30 * 24 * time.Hour)}}},
+		{name: "expires soon", certs: []Certificate{{Name: "mail.example.com", NotAfter: now.Add(time.Hour)}}, wantErr: true},
+		{name: "expired", certs: []Certificate{{Name: "mail.example.com", NotAfter: now.Add(-time.Hour)}}, wantErr: true},
+		{name: "one of several expired", certs: []Certificate{
+			{Name: "a.example.com", NotAfter: now.Add(30 * 24 * time.Hour)},
+			{Name: "b.example.com", NotAfter: now.Add(-time.Hour)},
+		}, wantErr: true},
+		{name: "none", wantErr: true},
+		{name: "read error", err: errors.New("no such file"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := CertificateCheck(func() ([]Certificate, error) { return tt.certs, tt.err }, 24*time.Hour)
+			if err := check(context.Background()); (err != nil) != tt.wantErr {
+				t.Errorf("check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAgentLine(t *testing.T) {
+	tests := []struct {
+		report *Report
+		want   string
+	}{
+		{&Report{Status: StatusReady}, "up ready\n"},
+		{&Report{Status: StatusDraining}, "drain\n"},
+		{&Report{Status: StatusNotReady, Checks: []CheckResult{
+			{Name: "storage", Status: CheckOK},
+			{Name: "processor", Status: CheckFail, Error: "stopped\r\nnow"},
+		}}, "down #processor: stopped  now\n"},
+	}
+	for _, tt := range tests {
+		if got := AgentLine(tt.report); got != tt.want {
+			t.Errorf("AgentLine(%s) = %q, want %q", tt.report.Status, got, tt.want)
+		}
+	}
+}
+
+func TestResponder(t *testing.T) {
+	c := New(time.Second)
+	// Set from the test, read by checks of the responder
+	var failing atomic.Bool
+	c.Add("storage", func(ctx context.Context) error {
+		if failing.Load() {
+			return errors.New("disk failed")
+		}
+		return nil
+	})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewResponder("", c, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	done := make(chan error, 1)
+	go func() { done <- r.Serve(l) }()
+
+	read := func() string {
+		t.Helper()
+		conn, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		data, err := io.ReadAll(conn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	if got := read(); got != "up ready\n" {
+		t.Errorf("reply = %q, want up", got)
+	}
+	failing.Store(true)
+	if got := read(); !strings.HasPrefix(got, "down #storage: disk failed") {
+		t.Errorf("reply = %q, want down", got)
+	}
+
+	r.Close()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Serve() after Close() = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve() did not return after Close()")
+	}
+}
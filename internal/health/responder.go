@@ -0,0 +1,104 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// responderTimeout limits a check and its reply on the TCP responder
+const responderTimeout = 5 * time.Second
+
+// Responder answers every TCP connection with the readiness of the node in
+// the HAProxy agent-check format and closes it: "up ready", "drain" or
+// "down #<failed check>". Load balancers without agent checks can match
+// the "up" prefix.
+type Responder struct {
+	addr    string
+	checker *Checker
+	logger  *slog.Logger
+
+	mu       sync.Mutex
+	listener net.Listener
+	closed   bool
+}
+
+// NewResponder creates a responder listening on addr
+func NewResponder(addr string, checker *Checker, logger *slog.Logger) *Responder {
+	return &Responder{addr: addr, checker: checker, logger: logger}
+}
+
+// ListenAndServe serves connections until Close is called
+func (r *Responder) ListenAndServe() error {
+	l, err := net.Listen("tcp", r.addr)
+	if err != nil {
+		return err
+	}
+	return r.Serve(l)
+}
+
+// Serve serves connections of a listener until Close is called
+func (r *Responder) Serve(l net.Listener) error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		l.Close()
+		return nil
+	}
+	r.listener = l
+	r.mu.Unlock()
+
+	r.logger.Info("starting health responder", "addr", l.Addr().String())
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			r.mu.Lock()
+			closed := r.closed
+			r.mu.Unlock()
+			if closed || errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go r.respond(conn)
+	}
+}
+
+// respond writes the readiness line to a connection
+func (r *Responder) respond(conn net.Conn) {
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), responderTimeout)
+	defer cancel()
+
+	conn.SetWriteDeadline(time.Now().Add(responderTimeout))
+	fmt.Fprint(conn, AgentLine(r.checker.Check(ctx)))
+}
+
+// AgentLine returns the HAProxy agent-check reply of a report
+func AgentLine(report *Report) string {
+	switch report.Status {
+	case StatusReady:
+		return "up ready\n"
+	case StatusDraining:
+		return "drain\n"
+	}
+	// The description ends at the line, keep it on one
+	reason := strings.NewReplacer("\r", " ", "\n", " ").Replace(report.Failure())
+	return "down #" + reason + "\n"
+}
+
+// Close stops the responder
+func (r *Responder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	if r.listener != nil {
+		return r.listener.Close()
+	}
+	return nil
+}
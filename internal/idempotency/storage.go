@@ -0,0 +1,229 @@
+// Package idempotency remembers the responses of message submissions by
+// client supplied keys, so a retried request does not send twice.
+package idempotency
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Header carries the idempotency key of a request
+const Header = "Idempotency-Key"
+
+const (
+	// DefaultTTL is how long completed responses are kept
+	DefaultTTL = 24 * time.Hour
+
+	// lockTimeout releases keys of requests that never completed,
+	// e.g. because the server stopped while handling them
+	lockTimeout = time.Minute
+
+	// maxKeyLength limits client supplied keys
+	maxKeyLength = 255
+
+	// sweepBatch limits how many expired keys one request removes
+	sweepBatch = 100
+)
+
+var (
+	bucketKeys   = []byte("idempotency_keys")
+	bucketExpiry = []byte("idempotency_expiry")
+)
+
+// State is the outcome of claiming a key
+type State int
+
+const (
+	// Claimed means the request is new and must be handled
+	Claimed State = iota
+	// Completed means the response of an earlier request can be replayed
+	Completed
+	// InProgress means an earlier request with the key is still running
+	InProgress
+	// Mismatch means the key was used for a different request
+	Mismatch
+)
+
+// Record is the stored state of a key
+type Record struct {
+	Fingerprint string    `json:"fingerprint"`
+	Done        bool      `json:"done"`
+	Status      int       `json:"status,omitempty"`
+	ContentType string    `json:"content_type,omitempty"`
+	Body        []byte    `json:"body,omitempty"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// Storage stores idempotency keys in BoltDB
+type Storage struct {
+	db  *bolt.DB
+	ttl time.Duration
+	now func() time.Time
+}
+
+// NewStorage creates a new idempotency storage using the provided BoltDB instance
+func NewStorage(db *bolt.DB) (*Storage, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(bucketKeys); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(bucketExpiry)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create idempotency buckets: %w", err)
+	}
+	return &Storage{db: db, ttl: DefaultTTL, now: time.Now}, nil
+}
+
+// SetTTL sets how long completed responses are kept
+func (s *Storage) SetTTL(ttl time.Duration) {
+	if ttl > 0 {
+		s.ttl = ttl
+	}
+}
+
+// ValidKey reports whether a client supplied key can be stored
+func ValidKey(key string) bool {
+	if key == "" || len(key) > maxKeyLength {
+		return false
+	}
+	return !strings.ContainsFunc(key, func(r rune) bool { return r < 0x20 || r == 0x7f })
+}
+
+// Claim reserves key for a request with the given fingerprint. For
+// Completed the stored record is returned.
+func (s *Storage) Claim(ctx context.Context, key, fingerprint string) (State, *Record, error) {
+	now := s.now()
+	state := Claimed
+	var found *Record
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		keys := tx.Bucket(bucketKeys)
+		expiry := tx.Bucket(bucketExpiry)
+		if err := sweep(keys, expiry, now); err != nil {
+			return err
+		}
+
+		if data := keys.Get([]byte(key)); data != nil {
+			var rec Record
+			if err := json.Unmarshal(data, &rec); err == nil && now.Before(rec.ExpiresAt) {
+				switch {
+				case rec.Fingerprint != fingerprint:
+					state = Mismatch
+				case rec.Done:
+					state, found = Completed, &rec
+				default:
+					state = InProgress
+				}
+				return nil
+			}
+			if err := remove(keys, expiry, []byte(key), data); err != nil {
+				return err
+			}
+		}
+
+		return put(keys, expiry, []byte(key), &Record{
+			Fingerprint: fingerprint,
+			ExpiresAt:   now.Add(lockTimeout),
+		})
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return state, found, nil
+}
+
+// Complete stores the response of a claimed key. The fingerprint of the
+// claim is kept.
+func (s *Storage) Complete(ctx context.Context, key string, rec *Record) error {
+	rec.Done = true
+	rec.ExpiresAt = s.now().Add(s.ttl)
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		keys := tx.Bucket(bucketKeys)
+		expiry := tx.Bucket(bucketExpiry)
+		data := keys.Get([]byte(key))
+		if data == nil {
+			return fmt.Errorf("idempotency key not claimed")
+		}
+		var claim Record
+		if err := json.Unmarshal(data, &claim); err != nil {
+			return fmt.Errorf("failed to unmarshal idempotency record: %w", err)
+		}
+		rec.Fingerprint = claim.Fingerprint
+		if err := remove(keys, expiry, []byte(key), data); err != nil {
+			return err
+		}
+		return put(keys, expiry, []byte(key), rec)
+	})
+}
+
+// Release frees a claimed key, so the request can be retried
+func (s *Storage) Release(ctx context.Context, key string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		keys := tx.Bucket(bucketKeys)
+		data := keys.Get([]byte(key))
+		if data == nil {
+			return nil
+		}
+		return remove(keys, tx.Bucket(bucketExpiry), []byte(key), data)
+	})
+}
+
+func put(keys, expiry *bolt.Bucket, key []byte, rec *Record) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to marshal idempotency record: %w", err)
+	}
+	if err := keys.Put(key, data); err != nil {
+		return err
+	}
+	return expiry.Put(expiryKey(rec.ExpiresAt, key), nil)
+}
+
+func remove(keys, expiry *bolt.Bucket, key, data []byte) error {
+	var rec Record
+	if err := json.Unmarshal(data, &rec); err == nil {
+		if err := expiry.Delete(expiryKey(rec.ExpiresAt, key)); err != nil {
+			return err
+		}
+	}
+	return keys.Delete(key)
+}
+
+// sweep removes a batch of expired keys. The expiry index is ordered by
+// time, so expired keys sit at its start.
+func sweep(keys, expiry *bolt.Bucket, now time.Time) error {
+	c := expiry.Cursor()
+	for i, k := 0, firstKey(c); k != nil && i < sweepBatch; i, k = i+1, firstKey(c) {
+		if int64(binary.BigEndian.Uint64(k[:8])) >= now.UnixNano() {
+			return nil
+		}
+		if err := expiry.Delete(k); err != nil {
+			return err
+		}
+		if err := keys.Delete(k[8:]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func firstKey(c *bolt.Cursor) []byte {
+	k, _ := c.First()
+	return k
+}
+
+// expiryKey orders keys by expiry time
+func expiryKey(t time.Time, key []byte) []byte {
+	k := make([]byte, 8, 8+len(key))
+	binary.BigEndian.PutUint64(k, uint64(t.UnixNano()))
+	return append(k, key...)
+}
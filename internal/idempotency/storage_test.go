@@ -0,0 +1,123 @@
+package idempotency
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func newTestStorage(t *testing.T) *Storage {
+	t.Helper()
+
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	storage, err := NewStorage(db)
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+	return storage
+}
+
+func TestStorageClaimComplete(t *testing.T) {
+	storage := newTestStorage(t)
+	ctx := context.Background()
+
+	state, _, err := storage.Claim(ctx, "key-1", "hash-a")
+	if err != nil || state != Claimed {
+		t.Fatalf("Claim() = %v, %v, want Claimed", state, err)
+	}
+
+	state, _, _ = storage.Claim(ctx, "key-1", "hash-a")
+	if state != InProgress {
+		t.Errorf("Claim() of running request = %v, want InProgress", state)
+	}
+
+	if err := storage.Complete(ctx, "key-1", &Record{Status: 202, Body: []byte(`{"id":"m1"}`)}); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+
+	state, rec, err := storage.Claim(ctx, "key-1", "hash-a")
+	if err != nil || state != Completed {
+		t.Fatalf("Claim() = %v, %v, want Completed", state, err)
+	}
+	if rec.Status != 202 || string(rec.Body) != `{"id":"m1"}` {
+		t.Errorf("Claim() record = %+v", rec)
+	}
+
+	if state, _, _ := storage.Claim(ctx, "key-1", "hash-b"); state != Mismatch {
+		t.Errorf("Claim() with other request = %v, want Mismatch", state)
+	}
+}
+
+func TestStorageRelease(t *testing.T) {
+	storage := newTestStorage(t)
+	ctx := context.Background()
+
+	storage.Claim(ctx, "key-1", "hash-a")
+	if err := storage.Release(ctx, "key-1"); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	if state, _, _ := storage.Claim(ctx, "key-1", "hash-b"); state != Claimed {
+		t.Errorf("Claim() after Release() = %v, want Claimed", state)
+	}
+}
+
+func TestStorageExpiry(t *testing.T) {
+	storage := newTestStorage(t)
+	storage.SetTTL(time.Hour)
+	ctx := context.Background()
+
+	now := time.Now()
+	storage.now = func() time.Time { return now }
+
+	storage.Claim(ctx, "done", "hash-a")
+	storage.Complete(ctx, "done", &Record{Status: 202})
+	storage.Claim(ctx, "stuck", "hash-a")
+
+	// Unfinished claims are given up after the lock timeout
+	now = now.Add(2 * lockTimeout)
+	if state, _, _ := storage.Claim(ctx, "stuck", "hash-a"); state != Claimed {
+		t.Errorf("Claim() of stale claim = %v, want Claimed", state)
+	}
+	if state, _, _ := storage.Claim(ctx, "done", "hash-a"); state != Completed {
+		t.Errorf("Claim() within TTL = %v, want Completed", state)
+	}
+
+	// Expired keys are swept by later claims
+	now = now.Add(2 * time.Hour)
+	storage.Claim(ctx, "other", "hash-a")
+	storage.db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket(bucketKeys).Get([]byte("done")) != nil {
+			t.Error("expired key was not removed")
+		}
+		return nil
+	})
+	if state, _, _ := storage.Claim(ctx, "done", "hash-b"); state != Claimed {
+		t.Errorf("Claim() after TTL = %v, want Claimed", state)
+	}
+}
+
+func TestValidKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"order-42", true},
+		{"", false},
+		{strings.Repeat("a", 256), false},
+		{"bad\nkey", false},
+	}
+	for _, tt := range tests {
+		if got := ValidKey(tt.key); got != tt.want {
+			t.Errorf("ValidKey(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+// Package inbound routes mail received for local domains to HTTP webhooks,
+// maildirs, forward addresses or LMTP servers instead of relaying it.
+package inbound
+
+import (
+	"bytes"
+	"net/mail"
+	"path"
+	"strings"
+
+	"github.com/foxzi/sendry/internal/config"
+)
+
+// DomainConfigProvider provides the current configuration of a domain
+type DomainConfigProvider interface {
+	GetDomainConfig(domain string) *config.DomainConfig
+}
+
+// Router matches recipients against the inbound rules of their domain.
+// Rules are looked up on every call, so rules changed through the API take
+// effect immediately.
+type Router struct {
+	domains DomainConfigProvider
+}
+
+// NewRouter creates a new inbound router
+func NewRouter(domains DomainConfigProvider) *Router {
+	return &Router{domains: domains}
+}
+
+// Route returns the first rule matching the recipient. local is true if the
+// recipient domain has inbound rules; a nil rule then means the mailbox
+// does not exist.
+func (r *Router) Route(rcpt string) (rule *config.InboundRule, local bool) {
+	at := strings.LastIndex(rcpt, "@")
+	if at <= 0 {
+		return nil, false
+	}
+	user := strings.ToLower(rcpt[:at])
+	domain := strings.ToLower(rcpt[at+1:])
+
+	dc := r.domains.GetDomainConfig(domain)
+	if dc == nil || len(dc.Inbound) == 0 {
+		return nil, false
+	}
+
+	for i := range dc.Inbound {
+		pattern := strings.ToLower(dc.Inbound[i].Recipient)
+		if pattern == "" {
+			return &dc.Inbound[i], true
+		}
+		if ok, _ := path.Match(pattern, user); ok {
+			return &dc.Inbound[i], true
+		}
+	}
+	return nil, true
+}
+
+// LMTP reports whether mail for the recipient domain is delivered over LMTP
+func (r *Router) LMTP(rcpt string) bool {
+	at := strings.LastIndex(rcpt, "@")
+	if at <= 0 {
+		return false
+	}
+	dc := r.domains.GetDomainConfig(strings.ToLower(rcpt[at+1:]))
+	return dc != nil && dc.Delivery.IsLMTP()
+}
+
+// deliveredTo reports whether the message was already delivered to rcpt,
+// which means it is looping between forward rules
+func deliveredTo(data []byte, rcpt string) bool {
+	parsed, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return false
+	}
+	for _, v := range parsed.Header["Delivered-To"] {
+		if strings.EqualFold(strings.TrimSpace(v), rcpt) {
+			return true
+		}
+	}
+	return false
+}
+
+// withDeliveredTo prepends the Delivered-To header of a local delivery
+func withDeliveredTo(data []byte, rcpt string) []byte {
+	out := make([]byte, 0, len(data)+len(rcpt)+16)
+	out = append(out, "Delivered-To: "...)
+	out = append(out, rcpt...)
+	out = append(out, "\r\n"...)
+	return append(out, data...)
+}
@@ -0,0 +1,449 @@
+package inbound
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/foxzi/sendry/internal/bounce"
+	"github.com/foxzi/sendry/internal/config"
+	"github.com/foxzi/sendry/internal/fbl"
+	"github.com/foxzi/sendry/internal/queue"
+)
+
+type testDomains map[string]config.DomainConfig
+
+func (d testDomains) GetDomainConfig(domain string) *config.DomainConfig {
+	if dc, ok := d[domain]; ok {
+		return &dc
+	}
+	return nil
+}
+
+// testSender records the recipients it was asked to send to
+type testSender struct {
+	to  []string
+	err error
+}
+
+func (s *testSender) Send(ctx context.Context, msg *queue.Message) error {
+	s.to = append(s.to, msg.To...)
+	return s.err
+}
+
+// testQueue records enqueued messages
+type testQueue struct {
+	queue.Queue
+	enqueued []*queue.Message
+}
+
+func (q *testQueue) Enqueue(ctx context.Context, msg *queue.Message) error {
+	q.enqueued = append(q.enqueued, msg)
+	return nil
+}
+
+const testData = "From: customer@example.net\r\nTo: reply+42@reply.example.com\r\nSubject: =?UTF-8?Q?Re:_Caf=C3=A9?=\r\nMessage-ID: <abc@example.net>\r\nIn-Reply-To: <order-42@example.com>\r\nReferences: <thread@example.com> <order-42@example.com>\r\n\r\nThanks!"
+
+func newTestMessage(to ...string) *queue.Message {
+	return &queue.Message{
+		ID:        "msg-1",
+		From:      "customer@example.net",
+		To:        to,
+		Data:      []byte(testData),
+		Status:    queue.StatusSending,
+		CreatedAt: time.Now(),
+	}
+}
+
+func TestRouterRoute(t *testing.T) {
+	router := NewRouter(testDomains{
+		"reply.example.com": {Inbound: []config.InboundRule{
+			{Recipient: "reply+*", Action: config.InboundActionWebhook},
+			{Recipient: "Support", Action: config.InboundActionForward},
+		}},
+		"catchall.example.com": {Inbound: []config.InboundRule{
+			{Action: config.InboundActionMaildir},
+		}},
+		"outbound.example.com": {},
+	})
+
+	tests := []struct {
+		rcpt       string
+		wantLocal  bool
+		wantAction string
+	}{
+		{"reply+42@reply.example.com", true, config.InboundActionWebhook},
+		{"SUPPORT@Reply.Example.com", true, config.InboundActionForward},
+		{"nobody@reply.example.com", true, ""},
+		{"anyone@catchall.example.com", true, config.InboundActionMaildir},
+		{"user@outbound.example.com", false, ""},
+		{"user@example.org", false, ""},
+		{"invalid", false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rcpt, func(t *testing.T) {
+			rule, local := router.Route(tt.rcpt)
+			if local != tt.wantLocal {
+				t.Fatalf("Route() local = %v, want %v", local, tt.wantLocal)
+			}
+			action := ""
+			if rule != nil {
+				action = rule.Action
+			}
+			if action != tt.wantAction {
+				t.Errorf("Route() action = %q, want %q", action, tt.wantAction)
+			}
+		})
+	}
+}
+
+func TestSenderWebhook(t *testing.T) {
+	var (
+		payload   WebhookPayload
+		signature string
+		body      []byte
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get(SignatureHeader)
+		json.Unmarshal(body, &payload)
+	}))
+	defer srv.Close()
+
+	router := NewRouter(testDomains{"reply.example.com": {Inbound: []config.InboundRule{
+		{Recipient: "reply+*", Action: config.InboundActionWebhook, URL: srv.URL, Secret: "s3cret"},
+	}}})
+	next := &testSender{}
+	s := NewSender(router, next, nil, nil, nil)
+
+	msg := newTestMessage("reply+42@reply.example.com")
+	if err := s.Send(context.Background(), msg); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if len(next.to) != 0 {
+		t.Errorf("next sender got %v, want no remote recipients", next.to)
+	}
+
+	if signature != Sign("s3cret", body) {
+		t.Errorf("signature = %q, want HMAC of the body", signature)
+	}
+	if payload.Recipient != "reply+42@reply.example.com" || payload.Subject != "Re: Café" ||
+		payload.InReplyTo != "<order-42@example.com>" || len(payload.References) != 2 {
+		t.Errorf("payload = %+v", payload)
+	}
+	if !strings.HasPrefix(string(payload.Raw), "Delivered-To: reply+42@reply.example.com\r\n") {
+		t.Errorf("raw message lacks Delivered-To: %q", payload.Raw)
+	}
+
+	result := msg.Results["reply+42@reply.example.com"]
+	if result == nil || result.Status != queue.StatusDelivered || result.MXHost != "inbound:webhook" {
+		t.Errorf("result = %+v, want delivered by inbound:webhook", result)
+	}
+}
+
+func TestSenderWebhookErrors(t *testing.T) {
+	tests := []struct {
+		status        int
+		wantTemporary bool
+	}{
+		{http.StatusServiceUnavailable, true},
+		{http.StatusTooManyRequests, true},
+		{http.StatusNotFound, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			router := NewRouter(testDomains{"reply.example.com": {Inbound: []config.InboundRule{
+				{Action: config.InboundActionWebhook, URL: srv.URL},
+			}}})
+			s := NewSender(router, &testSender{}, nil, nil, nil)
+
+			err := s.Send(context.Background(), newTestMessage("reply@reply.example.com"))
+			var e *Error
+			if !errors.As(err, &e) {
+				t.Fatalf("Send() error = %v, want *Error", err)
+			}
+			if e.Temporary() != tt.wantTemporary {
+				t.Errorf("Temporary() = %v, want %v (%v)", e.Temporary(), tt.wantTemporary, err)
+			}
+		})
+	}
+}
+
+func TestSenderMaildir(t *testing.T) {
+	root := t.TempDir()
+	router := NewRouter(testDomains{"example.com": {Inbound: []config.InboundRule{
+		{Action: config.InboundActionMaildir, Path: filepath.Join(root, "{domain}", "{user}")},
+	}}})
+	s := NewSender(router, &testSender{}, nil, nil, nil)
+
+	if err := s.Send(context.Background(), newTestMessage("Info@example.com")); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	dir := filepath.Join(root, "example.com", "info")
+	files, _ := os.ReadDir(filepath.Join(dir, "new"))
+	if len(files) != 1 {
+		t.Fatalf("new/ holds %d files, want 1", len(files))
+	}
+	if tmp, _ := os.ReadDir(filepath.Join(dir, "tmp")); len(tmp) != 0 {
+		t.Errorf("tmp/ holds %d files, want 0", len(tmp))
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "new", files[0].Name()))
+	if !strings.HasPrefix(string(data), "Return-Path: <customer@example.net>\r\nDelivered-To: Info@example.com\r\n") {
+		t.Errorf("maildir message = %q", data)
+	}
+
+	// Quarantined messages go to the Junk folder
+	msg := newTestMessage("info@example.com")
+	msg.Quarantined = true
+	if err := s.Send(context.Background(), msg); err != nil {
+		t.Fatalf("Send() quarantined error = %v", err)
+	}
+	if junk, _ := os.ReadDir(filepath.Join(dir, ".Junk", "new")); len(junk) != 1 {
+		t.Errorf(".Junk/new/ holds %d files, want 1", len(junk))
+	}
+
+	// Path traversal through the local part is refused
+	err := s.Send(context.Background(), newTestMessage("../x@example.com"))
+	if err == nil || isTemporaryErr(err) {
+		t.Errorf("Send() error = %v, want permanent error", err)
+	}
+}
+
+func TestSenderForward(t *testing.T) {
+	router := NewRouter(testDomains{"example.com": {Inbound: []config.InboundRule{
+		{Recipient: "support", Action: config.InboundActionForward, To: []string{"helpdesk@example.org"}},
+		{Recipient: "loop", Action: config.InboundActionForward, To: []string{"loop@example.com"}},
+	}}})
+	q := &testQueue{}
+	s := NewSender(router, &testSender{}, q, nil, nil)
+
+	if err := s.Send(context.Background(), newTestMessage("support@example.com")); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if len(q.enqueued) != 1 {
+		t.Fatalf("enqueued %d messages, want 1", len(q.enqueued))
+	}
+	fwd := q.enqueued[0]
+	if fwd.From != "customer@example.net" || len(fwd.To) != 1 || fwd.To[0] != "helpdesk@example.org" {
+		t.Errorf("forwarded message = %s -> %v", fwd.From, fwd.To)
+	}
+
+	// The forwarded copy of a looping rule comes back and is refused
+	if err := s.Send(context.Background(), newTestMessage("loop@example.com")); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	looped := q.enqueued[1]
+	err := s.Send(context.Background(), looped)
+	if err == nil || isTemporaryErr(err) || !strings.Contains(err.Error(), "loop") {
+		t.Errorf("Send() error = %v, want permanent loop error", err)
+	}
+	if len(q.enqueued) != 2 {
+		t.Errorf("enqueued %d messages, want 2", len(q.enqueued))
+	}
+}
+
+func TestSenderLMTP(t *testing.T) {
+	router := NewRouter(testDomains{
+		"example.com": {
+			Inbound:  []config.InboundRule{{Action: config.InboundActionLMTP}},
+			Delivery: &config.DomainDeliveryConfig{Protocol: config.DeliveryProtocolLMTP, Address: "/run/dovecot/lmtp"},
+		},
+		"nodelivery.example.com": {Inbound: []config.InboundRule{{Action: config.InboundActionLMTP}}},
+	})
+	next := &testSender{}
+	s := NewSender(router, next, &testQueue{}, nil, nil)
+
+	// Recipients of lmtp rules are left to the next sender
+	if err := s.Send(context.Background(), newTestMessage("user@example.com")); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if len(next.to) != 1 || next.to[0] != "user@example.com" {
+		t.Errorf("next sender got %v, want user@example.com", next.to)
+	}
+
+	// Without an LMTP delivery the recipient fails instead of being relayed
+	msg := newTestMessage("user@nodelivery.example.com")
+	err := s.Send(context.Background(), msg)
+	if err == nil || isTemporaryErr(err) {
+		t.Errorf("Send() error = %v, want permanent error", err)
+	}
+	if len(next.to) != 1 {
+		t.Errorf("next sender got %v, want no new recipients", next.to)
+	}
+}
+
+// testReports records the complaint reports it processes
+type testReports struct {
+	reports [][]byte
+	err     error
+}
+
+func (r *testReports) ProcessReport(ctx context.Context, data []byte) error {
+	r.reports = append(r.reports, data)
+	return r.err
+}
+
+func TestSenderReport(t *testing.T) {
+	router := NewRouter(testDomains{"example.com": {Inbound: []config.InboundRule{
+		{Recipient: "fbl", Action: config.InboundActionFBL},
+	}}})
+	s := NewSender(router, &testSender{}, nil, nil, nil)
+
+	// Without a processor reports are refused
+	err := s.Send(context.Background(), newTestMessage("fbl@example.com"))
+	if err == nil || isTemporaryErr(err) {
+		t.Errorf("Send() without processor error = %v, want permanent error", err)
+	}
+
+	reports := &testReports{}
+	s.SetReportProcessor(reports)
+	if err := s.Send(context.Background(), newTestMessage("fbl@example.com")); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if len(reports.reports) != 1 || string(reports.reports[0]) != testData {
+		t.Errorf("processed reports = %q", reports.reports)
+	}
+
+	// Messages that are not reports fail, storage errors are retried
+	reports.err = fmt.Errorf("%w: no report part", fbl.ErrInvalidReport)
+	if err := s.Send(context.Background(), newTestMessage("fbl@example.com")); err == nil || isTemporaryErr(err) {
+		t.Errorf("Send() of an invalid report error = %v, want permanent error", err)
+	}
+	reports.err = errors.New("database closed")
+	if err := s.Send(context.Background(), newTestMessage("fbl@example.com")); err == nil || !isTemporaryErr(err) {
+		t.Errorf("Send() with storage error = %v, want temporary error", err)
+	}
+}
+
+// testBounces records the return paths bounces were sent to
+type testBounces struct {
+	rcpts []string
+	err   error
+}
+
+func (b *testBounces) ProcessBounce(ctx context.Context, rcpt string, data []byte) error {
+	b.rcpts = append(b.rcpts, rcpt)
+	return b.err
+}
+
+func TestSenderBounce(t *testing.T) {
+	router := NewRouter(testDomains{"bounces.example.com": {Inbound: []config.InboundRule{
+		{Recipient: "bounce*", Action: config.InboundActionBounce},
+	}}})
+	s := NewSender(router, &testSender{}, nil, nil, nil)
+
+	const verp = "bounce+msg-9=user=example.net@bounces.example.com"
+	if err := s.Send(context.Background(), newTestMessage(verp)); err == nil || isTemporaryErr(err) {
+		t.Errorf("Send() without processor error = %v, want permanent error", err)
+	}
+
+	bounces := &testBounces{}
+	s.SetBounceProcessor(bounces)
+	if err := s.Send(context.Background(), newTestMessage(verp)); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if len(bounces.rcpts) != 1 || bounces.rcpts[0] != verp {
+		t.Errorf("processed bounces = %q, want one to %s", bounces.rcpts, verp)
+	}
+
+	// Mail that is not a DSN, e.g. an auto-reply, is dropped, storage errors
+	// are retried
+	bounces.err = fmt.Errorf("%w: content type is not multipart/report", bounce.ErrInvalidDSN)
+	if err := s.Send(context.Background(), newTestMessage(verp)); err != nil {
+		t.Errorf("Send() of an auto-reply error = %v, want accepted", err)
+	}
+	bounces.err = errors.New("database closed")
+	if err := s.Send(context.Background(), newTestMessage(verp)); err == nil || !isTemporaryErr(err) {
+		t.Errorf("Send() with storage error = %v, want temporary error", err)
+	}
+}
+
+func TestSenderMixedRecipients(t *testing.T) {
+	root := t.TempDir()
+	router := NewRouter(testDomains{"example.com": {Inbound: []config.InboundRule{
+		{Recipient: "inbox", Action: config.InboundActionMaildir, Path: root},
+	}}})
+
+	t.Run("remote delivered", func(t *testing.T) {
+		next := &testSender{}
+		s := NewSender(router, next, nil, nil, nil)
+		msg := newTestMessage("inbox@example.com", "user@example.org", "nobody@example.com")
+
+		err := s.Send(context.Background(), msg)
+		if len(next.to) != 1 || next.to[0] != "user@example.org" {
+			t.Errorf("next sender got %v, want only the remote recipient", next.to)
+		}
+		if err == nil || isTemporaryErr(err) {
+			t.Errorf("Send() error = %v, want permanent error for the unknown mailbox", err)
+		}
+		for rcpt, want := range map[string]queue.MessageStatus{
+			"inbox@example.com":  queue.StatusDelivered,
+			"user@example.org":   queue.StatusDelivered,
+			"nobody@example.com": queue.StatusFailed,
+		} {
+			if r := msg.Results[rcpt]; r == nil || r.Status != want {
+				t.Errorf("result of %s = %+v, want %s", rcpt, r, want)
+			}
+		}
+	})
+
+	t.Run("remote deferred", func(t *testing.T) {
+		next := &testSender{err: errors.New("connection refused")}
+		s := NewSender(router, next, nil, func(error) bool { return true }, nil)
+		msg := newTestMessage("inbox@example.com", "user@example.org")
+
+		err := s.Send(context.Background(), msg)
+		if err == nil || !isTemporaryErr(err) {
+			t.Fatalf("Send() error = %v, want temporary error", err)
+		}
+		if r := msg.Results["user@example.org"]; r == nil || r.Status != queue.StatusDeferred {
+			t.Errorf("remote result = %+v, want deferred", r)
+		}
+
+		// Only the deferred recipient is retried
+		next.err = nil
+		next.to = nil
+		if err := s.Send(context.Background(), msg); err != nil {
+			t.Fatalf("retry error = %v", err)
+		}
+		if len(next.to) != 1 || next.to[0] != "user@example.org" {
+			t.Errorf("retry sent to %v", next.to)
+		}
+		files, _ := os.ReadDir(filepath.Join(root, "new"))
+		if len(files) != 2 {
+			t.Errorf("maildir holds %d messages, want 2 (one per subtest)", len(files))
+		}
+	})
+
+	t.Run("outbound only", func(t *testing.T) {
+		sendErr := errors.New("unchanged")
+		next := &testSender{err: sendErr}
+		s := NewSender(router, next, nil, nil, nil)
+		if err := s.Send(context.Background(), newTestMessage("user@example.org")); err != sendErr {
+			t.Errorf("Send() error = %v, want the next sender error", err)
+		}
+	})
+}
+
+// isTemporaryErr reports whether err is a temporary inbound error
+func isTemporaryErr(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Temporary()
+}
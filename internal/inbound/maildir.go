@@ -0,0 +1,70 @@
+package inbound
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/foxzi/sendry/internal/config"
+	"github.com/foxzi/sendry/internal/queue"
+)
+
+// maildir stores msg in the maildir of the rule. The message is written to
+// tmp and renamed into new, so mail readers never see partial files.
+// Quarantined messages go to the Junk folder of the maildir.
+func (s *Sender) maildir(msg *queue.Message, rcpt string, rule *config.InboundRule) error {
+	at := strings.LastIndex(rcpt, "@")
+	user := strings.ToLower(rcpt[:at])
+	domain := strings.ToLower(rcpt[at+1:])
+	if strings.ContainsAny(user, "/\\") || strings.HasPrefix(user, ".") ||
+		strings.ContainsAny(domain, "/\\") || strings.HasPrefix(domain, ".") {
+		return permanent("invalid mailbox name %q", rcpt)
+	}
+
+	dir := strings.NewReplacer("{domain}", domain, "{user}", user).Replace(rule.Path)
+	if msg.Quarantined {
+		dir = filepath.Join(dir, junkFolder)
+	}
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0700); err != nil {
+			return temporary("failed to create maildir: %v", err)
+		}
+	}
+
+	now := time.Now()
+	name := fmt.Sprintf("%d.M%dP%dQ%d.%s", now.Unix(), now.Nanosecond()/1000, os.Getpid(), s.seq.Add(1), s.hostname)
+	tmp := filepath.Join(dir, "tmp", name)
+
+	data := append([]byte("Return-Path: <"+msg.From+">\r\n"), withDeliveredTo(msg.Data, rcpt)...)
+	if err := writeSynced(tmp, data); err != nil {
+		os.Remove(tmp)
+		return temporary("failed to write maildir message: %v", err)
+	}
+	if err := os.Rename(tmp, filepath.Join(dir, "new", name)); err != nil {
+		os.Remove(tmp)
+		return temporary("failed to deliver maildir message: %v", err)
+	}
+	return nil
+}
+
+// junkFolder is the Maildir++ folder of quarantined messages
+const junkFolder = ".Junk"
+
+// writeSynced writes data to a new file and flushes it to disk
+func writeSynced(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
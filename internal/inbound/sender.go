@@ -0,0 +1,328 @@
+package inbound
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/foxzi/sendry/internal/bounce"
+	"github.com/foxzi/sendry/internal/config"
+	"github.com/foxzi/sendry/internal/fbl"
+	"github.com/foxzi/sendry/internal/metrics"
+	"github.com/foxzi/sendry/internal/queue"
+)
+
+// Error is a failed inbound delivery
+type Error struct {
+	Permanent bool
+	Message   string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Temporary reports whether the delivery should be retried
+func (e *Error) Temporary() bool {
+	return !e.Permanent
+}
+
+func temporary(format string, args ...any) error {
+	return &Error{Message: fmt.Sprintf(format, args...)}
+}
+
+func permanent(format string, args ...any) error {
+	return &Error{Permanent: true, Message: fmt.Sprintf(format, args...)}
+}
+
+// ReportProcessor processes complaint reports received by fbl rules
+type ReportProcessor interface {
+	ProcessReport(ctx context.Context, data []byte) error
+}
+
+// BounceProcessor processes delivery status notifications received by
+// bounce rules, rcpt is the address the bounce was sent to
+type BounceProcessor interface {
+	ProcessBounce(ctx context.Context, rcpt string, data []byte) error
+}
+
+// Sender delivers recipients with inbound rules locally and passes the
+// other recipients to the next sender
+type Sender struct {
+	router      *Router
+	next        queue.Sender
+	queue       queue.Queue
+	isTemporary queue.ErrorChecker
+	reports     ReportProcessor
+	bounces     BounceProcessor
+	client      *http.Client
+	hostname    string
+	seq         atomic.Uint64
+	logger      *slog.Logger
+}
+
+// NewSender creates a new inbound sender. isTemporary classifies errors of
+// the next sender, forwarded messages are enqueued to q.
+func NewSender(router *Router, next queue.Sender, q queue.Queue, isTemporary queue.ErrorChecker, logger *slog.Logger) *Sender {
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	if isTemporary == nil {
+		isTemporary = func(err error) bool { return true }
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "localhost"
+	}
+
+	return &Sender{
+		router:      router,
+		next:        next,
+		queue:       q,
+		isTemporary: isTemporary,
+		client:      &http.Client{Timeout: 30 * time.Second},
+		hostname:    strings.NewReplacer("/", "\\057", ":", "\\072").Replace(hostname),
+		logger:      logger,
+	}
+}
+
+// SetReportProcessor sets the processor of mail received by fbl rules
+func (s *Sender) SetReportProcessor(p ReportProcessor) {
+	s.reports = p
+}
+
+// SetBounceProcessor sets the processor of mail received by bounce rules
+func (s *Sender) SetBounceProcessor(p BounceProcessor) {
+	s.bounces = p
+}
+
+// Send delivers the pending local recipients of msg by their inbound rule
+// and sends the remaining recipients with the next sender
+func (s *Sender) Send(ctx context.Context, msg *queue.Message) error {
+	type route struct {
+		rcpt string
+		rule *config.InboundRule
+	}
+
+	// Messages without local recipients pass through unchanged, otherwise
+	// the next sender only gets the pending remote recipients, also on retry.
+	// Recipients of lmtp rules are sent by the next sender to the LMTP
+	// server of their domain.
+	var routes []route
+	for _, rcpt := range msg.To {
+		rule, ok := s.router.Route(rcpt)
+		if !ok || (rule != nil && rule.Action == config.InboundActionLMTP && s.router.LMTP(rcpt)) {
+			continue
+		}
+		routes = append(routes, route{rcpt: rcpt, rule: rule})
+	}
+	if len(routes) == 0 {
+		return s.next.Send(ctx, msg)
+	}
+
+	pending := make(map[string]bool)
+	for _, rcpt := range msg.PendingRecipients() {
+		pending[rcpt] = true
+	}
+	for _, r := range routes {
+		if pending[r.rcpt] {
+			s.deliver(ctx, msg, r.rcpt, r.rule)
+			delete(pending, r.rcpt)
+		}
+	}
+
+	var remote []string
+	for _, rcpt := range msg.To {
+		if pending[rcpt] {
+			remote = append(remote, rcpt)
+		}
+	}
+	if len(remote) > 0 {
+		s.sendRemote(ctx, msg, remote)
+	}
+	return outcome(msg)
+}
+
+// deliver delivers msg to one local recipient and records the outcome
+func (s *Sender) deliver(ctx context.Context, msg *queue.Message, rcpt string, rule *config.InboundRule) {
+	action := "reject"
+	var err error
+	switch {
+	case rule == nil:
+		err = permanent("mailbox unavailable")
+	case deliveredTo(msg.Data, rcpt):
+		action = rule.Action
+		err = permanent("mail forwarding loop for %s", rcpt)
+	default:
+		action = rule.Action
+		switch rule.Action {
+		case config.InboundActionWebhook:
+			err = s.webhook(ctx, msg, rcpt, rule)
+		case config.InboundActionMaildir:
+			err = s.maildir(msg, rcpt, rule)
+		case config.InboundActionForward:
+			err = s.forward(ctx, msg, rcpt, rule)
+		case config.InboundActionFBL:
+			err = s.report(ctx, msg)
+		case config.InboundActionBounce:
+			err = s.bounce(ctx, msg, rcpt)
+		case config.InboundActionLMTP:
+			err = permanent("no lmtp delivery configured for %s", rcpt)
+		default:
+			err = permanent("unknown inbound action %q", rule.Action)
+		}
+	}
+
+	host := "inbound:" + action
+	result := queue.RecipientResult{Status: queue.StatusDelivered, MXHost: host}
+	attempt := queue.DeliveryAttempt{Recipient: rcpt, MXHost: host, Success: err == nil}
+	if err != nil {
+		result.Status = queue.StatusDeferred
+		if e, ok := err.(*Error); ok && e.Permanent {
+			result.Status = queue.StatusFailed
+			attempt.Permanent = true
+		}
+		result.Error = err.Error()
+		attempt.Error = err.Error()
+
+		s.logger.Warn("inbound delivery failed", "message_id", msg.ID, "recipient", rcpt,
+			"action", action, "error", err, "permanent", attempt.Permanent)
+	} else {
+		s.logger.Info("inbound message delivered", "message_id", msg.ID, "recipient", rcpt, "action", action)
+	}
+
+	msg.SetResult(rcpt, result)
+	msg.RecordAttempt(attempt)
+	metrics.IncInboundMessages(action, string(result.Status))
+}
+
+// forward enqueues a copy of msg for the forward addresses of the rule
+func (s *Sender) forward(ctx context.Context, msg *queue.Message, rcpt string, rule *config.InboundRule) error {
+	now := time.Now()
+	fwd := &queue.Message{
+		ID:        uuid.New().String(),
+		From:      msg.From,
+		To:        append([]string(nil), rule.To...),
+		Data:      withDeliveredTo(msg.Data, rcpt),
+		Status:    queue.StatusPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+		ClientIP:  msg.ClientIP,
+	}
+	if err := s.queue.Enqueue(ctx, fwd); err != nil {
+		return temporary("failed to queue forwarded message: %v", err)
+	}
+
+	s.logger.Info("inbound message forwarded", "message_id", msg.ID, "recipient", rcpt,
+		"forward_id", fwd.ID, "to", fwd.To)
+	return nil
+}
+
+// report processes msg as a complaint report. Messages that are not ARF
+// reports are rejected, storage errors are retried.
+func (s *Sender) report(ctx context.Context, msg *queue.Message) error {
+	if s.reports == nil {
+		return permanent("complaint report processing is disabled")
+	}
+	if err := s.reports.ProcessReport(ctx, msg.Data); err != nil {
+		if errors.Is(err, fbl.ErrInvalidReport) {
+			return permanent("%v", err)
+		}
+		return temporary("failed to process complaint report: %v", err)
+	}
+	return nil
+}
+
+// bounce processes msg as a bounce sent to rcpt. Other mail to a return
+// path, such as auto-replies, is accepted and dropped; storage errors are
+// retried.
+func (s *Sender) bounce(ctx context.Context, msg *queue.Message, rcpt string) error {
+	if s.bounces == nil {
+		return permanent("bounce processing is disabled")
+	}
+	if err := s.bounces.ProcessBounce(ctx, rcpt, msg.Data); err != nil {
+		if errors.Is(err, bounce.ErrInvalidDSN) {
+			s.logger.Info("dropped mail to return path that is not a bounce", "message_id", msg.ID, "recipient", rcpt, "error", err)
+			return nil
+		}
+		return temporary("failed to process bounce: %v", err)
+	}
+	return nil
+}
+
+// sendRemote sends msg to the remote recipients with the next sender. The
+// outcome of senders that do not record recipient results is taken from
+// the returned error.
+func (s *Sender) sendRemote(ctx context.Context, msg *queue.Message, remote []string) {
+	start := time.Now()
+	if msg.Results == nil {
+		msg.Results = make(map[string]*queue.RecipientResult)
+	}
+	out := *msg
+	out.To = remote
+	err := s.next.Send(ctx, &out)
+	msg.Data = out.Data
+	msg.Attempts = out.Attempts
+
+	for _, rcpt := range remote {
+		if r := msg.Results[rcpt]; r != nil && !r.UpdatedAt.Before(start) {
+			continue
+		}
+		result := queue.RecipientResult{Status: queue.StatusDelivered}
+		if err != nil {
+			result.Status = queue.StatusFailed
+			if s.isTemporary(err) {
+				result.Status = queue.StatusDeferred
+			}
+			result.Error = err.Error()
+		}
+		msg.SetResult(rcpt, result)
+	}
+}
+
+// outcome summarizes the recipient results of a message. It returns a
+// temporary error if any recipient was deferred, a permanent error if all
+// undelivered recipients failed, and nil if all recipients were delivered.
+func outcome(msg *queue.Message) error {
+	var deferred, failed []string
+	for _, rcpt := range msg.UndeliveredRecipients() {
+		if r := msg.Results[rcpt]; r != nil && r.Status == queue.StatusFailed {
+			failed = append(failed, rcpt)
+		} else {
+			deferred = append(deferred, rcpt)
+		}
+	}
+
+	switch {
+	case len(deferred) > 0:
+		return &Error{Message: recipientErrors(msg, deferred)}
+	case len(failed) > 0:
+		return &Error{Permanent: true, Message: recipientErrors(msg, failed)}
+	}
+	return nil
+}
+
+// recipientErrors describes the error of the first recipient and how many
+// other recipients share the outcome
+func recipientErrors(msg *queue.Message, recipients []string) string {
+	text := recipients[0] + ": "
+	if r := msg.Results[recipients[0]]; r != nil && r.Error != "" {
+		text += r.Error
+	} else {
+		text += "not attempted"
+	}
+	if len(recipients) > 1 {
+		text += fmt.Sprintf(" (and %d more recipients)", len(recipients)-1)
+	}
+	return text
+}
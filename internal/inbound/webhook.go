@@ -0,0 +1,105 @@
+package inbound
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/foxzi/sendry/internal/config"
+	"github.com/foxzi/sendry/internal/message"
+	"github.com/foxzi/sendry/internal/queue"
+)
+
+// SignatureHeader carries the HMAC-SHA256 of the webhook body, hex encoded
+// and prefixed with "sha256=", when the rule has a secret
+const SignatureHeader = "X-Sendry-Signature"
+
+// WebhookPayload is the JSON body posted to inbound webhooks
+type WebhookPayload struct {
+	ID          string    `json:"id"`
+	From        string    `json:"from"`
+	Recipient   string    `json:"recipient"`
+	Subject     string    `json:"subject,omitempty"`
+	MessageID   string    `json:"message_id,omitempty"`
+	InReplyTo   string    `json:"in_reply_to,omitempty"`
+	References  []string  `json:"references,omitempty"`
+	ClientIP    string    `json:"client_ip,omitempty"`
+	Quarantined bool      `json:"quarantined,omitempty"` // Failed DMARC of a sender domain with a quarantine policy
+	ReceivedAt  time.Time `json:"received_at"`
+	Raw         []byte    `json:"raw"` // Message data, base64 encoded
+}
+
+// newWebhookPayload describes a message for a webhook. The threading
+// headers let the receiver match replies to the messages they answer.
+func newWebhookPayload(msg *queue.Message, rcpt string) *WebhookPayload {
+	data := withDeliveredTo(msg.Data, rcpt)
+	payload := &WebhookPayload{
+		ID:          msg.ID,
+		From:        msg.From,
+		Recipient:   rcpt,
+		ClientIP:    msg.ClientIP,
+		Quarantined: msg.Quarantined,
+		ReceivedAt:  msg.CreatedAt,
+		Raw:         data,
+	}
+
+	parsed, err := message.Parse(data)
+	if err != nil {
+		return payload
+	}
+	payload.Subject = parsed.Header.Decoded("Subject")
+	payload.MessageID = parsed.Header.Get("Message-ID")
+	payload.InReplyTo = parsed.Header.Get("In-Reply-To")
+	payload.References = strings.Fields(parsed.Header.Get("References"))
+	return payload
+}
+
+// Sign returns the signature header value of a webhook body
+func Sign(secret string, body []byte) string {
+	m := hmac.New(sha256.New, []byte(secret))
+	m.Write(body)
+	return "sha256=" + hex.EncodeToString(m.Sum(nil))
+}
+
+// webhook posts msg to the webhook of the rule. Timeouts, 408, 429 and 5xx
+// responses are retried, other client errors fail permanently.
+func (s *Sender) webhook(ctx context.Context, msg *queue.Message, rcpt string, rule *config.InboundRule) error {
+	body, err := json.Marshal(newWebhookPayload(msg, rcpt))
+	if err != nil {
+		return permanent("failed to encode webhook payload: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rule.URL, bytes.NewReader(body))
+	if err != nil {
+		return permanent("invalid webhook URL: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "sendry-inbound")
+	if rule.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(rule.Secret, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return temporary("webhook request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= 500:
+		return temporary("webhook returned %s", resp.Status)
+	case resp.StatusCode >= 400:
+		return permanent("webhook returned %s", resp.Status)
+	}
+	return temporary("webhook returned %s", resp.Status)
+}
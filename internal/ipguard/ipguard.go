@@ -0,0 +1,348 @@
+// Package ipguard throttles SMTP clients by IP address: it limits their
+// connections and commands, delays replies to clients that keep getting
+// rejected and temporarily bans clients that keep trying to relay.
+package ipguard
+
+import (
+	"errors"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/foxzi/sendry/internal/config"
+	"github.com/foxzi/sendry/internal/ipfilter"
+)
+
+// Defaults of unset config values
+const (
+	defaultMaxConnections = 20
+	defaultConnectionRate = 60
+	defaultCommandRate    = 600
+	defaultTarpitAfter    = 5
+	defaultTarpitDelay    = 5 * time.Second
+	defaultBanAfter       = 10
+	defaultWindow         = 10 * time.Minute
+	defaultBanDuration    = time.Hour
+)
+
+// ReasonRelay is the reason of bans for repeated relay attempts
+const ReasonRelay = "relay attempts"
+
+var (
+	// ErrBanned is returned for a banned client
+	ErrBanned = errors.New("client is banned")
+	// ErrTooManyConnections is returned when a client has too many open
+	// connections
+	ErrTooManyConnections = errors.New("too many connections")
+	// ErrConnectionRate is returned when a client connects too often
+	ErrConnectionRate = errors.New("connection rate exceeded")
+	// ErrCommandRate is returned when a client sends too many commands
+	ErrCommandRate = errors.New("command rate exceeded")
+)
+
+// Ban is a temporarily banned client
+type Ban struct {
+	IP        string    `json:"ip"`
+	Reason    string    `json:"reason"`
+	Attempts  int       `json:"attempts"` // Relay attempts that led to the ban
+	BannedAt  time.Time `json:"banned_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// counter counts events in fixed windows
+type counter struct {
+	start time.Time
+	n     int
+}
+
+// add counts an event and returns the count of the current window
+func (c *counter) add(now time.Time, window time.Duration) int {
+	if now.Sub(c.start) >= window {
+		c.start = now
+		c.n = 0
+	}
+	c.n++
+	return c.n
+}
+
+// count returns the count of the current window
+func (c *counter) count(now time.Time, window time.Duration) int {
+	if now.Sub(c.start) >= window {
+		return 0
+	}
+	return c.n
+}
+
+// client is the state of an IP address
+type client struct {
+	conns      int     // Open connections
+	connects   counter // New connections per minute
+	commands   counter // Commands per minute
+	rejections counter // Rejected commands per window
+	relays     counter // Relay attempts per window
+	ban        *Ban
+	lastSeen   time.Time
+}
+
+// Guard tracks the clients of all SMTP listeners. A nil Guard allows
+// everything.
+type Guard struct {
+	maxConnections int
+	connectionRate int
+	commandRate    int
+	tarpitAfter    int
+	tarpitDelay    time.Duration
+	banAfter       int
+	window         time.Duration
+	banDuration    time.Duration
+	exempt         *ipfilter.Filter
+	logger         *slog.Logger
+
+	mu        sync.Mutex
+	clients   map[string]*client
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+// New creates a guard from its config, unset values use the defaults
+func New(cfg config.IPGuardConfig, logger *slog.Logger) *Guard {
+	return &Guard{
+		maxConnections: orDefault(cfg.MaxConnections, defaultMaxConnections),
+		connectionRate: orDefault(cfg.ConnectionRate, defaultConnectionRate),
+		commandRate:    orDefault(cfg.CommandRate, defaultCommandRate),
+		tarpitAfter:    orDefault(cfg.TarpitAfter, defaultTarpitAfter),
+		tarpitDelay:    orDefault(cfg.TarpitDelay, defaultTarpitDelay),
+		banAfter:       orDefault(cfg.BanAfter, defaultBanAfter),
+		window:         orDefault(cfg.Window, defaultWindow),
+		banDuration:    orDefault(cfg.BanDuration, defaultBanDuration),
+		exempt:         ipfilter.New(cfg.Exempt, logger),
+		logger:         logger,
+		clients:        make(map[string]*client),
+		now:            time.Now,
+	}
+}
+
+func orDefault[T int | time.Duration](v, def T) T {
+	if v <= 0 {
+		return def
+	}
+	return v
+}
+
+// isExempt reports whether the client is never throttled
+func (g *Guard) isExempt(ip string) bool {
+	return ip == "" || (g.exempt.Enabled() && g.exempt.IsAllowedString(ip))
+}
+
+// get returns the state of a client, creating it. The caller holds g.mu.
+func (g *Guard) get(ip string, now time.Time) *client {
+	c := g.clients[ip]
+	if c == nil {
+		c = &client{}
+		g.clients[ip] = c
+	}
+	c.lastSeen = now
+	return c
+}
+
+// banned returns the active ban of a client, dropping an expired one. The
+// caller holds g.mu.
+func (c *client) banned(now time.Time) *Ban {
+	if c.ban != nil && !now.Before(c.ban.ExpiresAt) {
+		c.ban = nil
+	}
+	return c.ban
+}
+
+// Open counts a new connection of a client. It returns an error when the
+// client is banned or over its connection limits, otherwise Close must be
+// called when the connection ends.
+func (g *Guard) Open(ip string) error {
+	if g == nil || g.isExempt(ip) {
+		return nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	g.prune(now)
+	c := g.get(ip, now)
+	if c.banned(now) != nil {
+		return ErrBanned
+	}
+	if c.conns >= g.maxConnections {
+		return ErrTooManyConnections
+	}
+	if c.connects.add(now, time.Minute) > g.connectionRate {
+		return ErrConnectionRate
+	}
+	c.conns++
+	return nil
+}
+
+// Close ends a connection counted by Open
+func (g *Guard) Close(ip string) {
+	if g == nil || g.isExempt(ip) {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if c := g.clients[ip]; c != nil && c.conns > 0 {
+		c.conns--
+	}
+}
+
+// Command counts a command of a client. It returns ErrBanned once the
+// client is banned and ErrCommandRate when it sends too many commands.
+func (g *Guard) Command(ip string) error {
+	if g == nil || g.isExempt(ip) {
+		return nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	c := g.get(ip, now)
+	if c.banned(now) != nil {
+		return ErrBanned
+	}
+	if c.commands.add(now, time.Minute) > g.commandRate {
+		return ErrCommandRate
+	}
+	return nil
+}
+
+// Reject records a rejected command of a client
+func (g *Guard) Reject(ip string) {
+	if g == nil || g.isExempt(ip) {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	g.get(ip, now).rejections.add(now, g.window)
+}
+
+// Delay returns how long to wait before replying to a client: the tarpit
+// delay once it had too many commands rejected, zero otherwise
+func (g *Guard) Delay(ip string) time.Duration {
+	if g == nil || g.isExempt(ip) {
+		return 0
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	c := g.clients[ip]
+	if c == nil || c.rejections.count(g.now(), g.window) < g.tarpitAfter {
+		return 0
+	}
+	return g.tarpitDelay
+}
+
+// RelayAttempt records a refused relay attempt of a client and bans it when
+// it made too many. It reports whether the client is banned.
+func (g *Guard) RelayAttempt(ip string) bool {
+	if g == nil || g.isExempt(ip) {
+		return false
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	c := g.get(ip, now)
+	if c.banned(now) != nil {
+		return true
+	}
+	attempts := c.relays.add(now, g.window)
+	if attempts < g.banAfter {
+		return false
+	}
+	c.ban = &Ban{
+		IP:        ip,
+		Reason:    ReasonRelay,
+		Attempts:  attempts,
+		BannedAt:  now,
+		ExpiresAt: now.Add(g.banDuration),
+	}
+	c.relays = counter{}
+	g.logger.Warn("SMTP client banned", "ip", ip, "reason", ReasonRelay, "attempts", attempts, "until", c.ban.ExpiresAt)
+	return true
+}
+
+// Banned reports whether a client is banned
+func (g *Guard) Banned(ip string) bool {
+	if g == nil || g.isExempt(ip) {
+		return false
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	c := g.clients[ip]
+	return c != nil && c.banned(g.now()) != nil
+}
+
+// Bans returns the active bans, oldest first
+func (g *Guard) Bans() []Ban {
+	if g == nil {
+		return nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	var bans []Ban
+	for _, c := range g.clients {
+		if ban := c.banned(now); ban != nil {
+			bans = append(bans, *ban)
+		}
+	}
+	sort.Slice(bans, func(i, j int) bool {
+		if !bans[i].BannedAt.Equal(bans[j].BannedAt) {
+			return bans[i].BannedAt.Before(bans[j].BannedAt)
+		}
+		return bans[i].IP < bans[j].IP
+	})
+	return bans
+}
+
+// Unban lifts the ban of a client and forgets its relay attempts. It
+// returns the lifted ban, nil when the client was not banned.
+func (g *Guard) Unban(ip string) *Ban {
+	if g == nil {
+		return nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	c := g.clients[ip]
+	if c == nil {
+		return nil
+	}
+	ban := c.banned(g.now())
+	if ban == nil {
+		return nil
+	}
+	c.ban = nil
+	c.relays = counter{}
+	c.rejections = counter{}
+	g.logger.Info("SMTP client unbanned", "ip", ip)
+	return ban
+}
+
+// prune drops idle clients at most once a minute to bound memory. The
+// caller holds g.mu.
+func (g *Guard) prune(now time.Time) {
+	if now.Sub(g.lastPrune) < time.Minute {
+		return
+	}
+	g.lastPrune = now
+	idle := max(g.window, time.Minute)
+	for ip, c := range g.clients {
+		if c.conns == 0 && c.banned(now) == nil && now.Sub(c.lastSeen) >= idle {
+			delete(g.clients, ip)
+		}
+	}
+}
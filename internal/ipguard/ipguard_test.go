@@ -0,0 +1,233 @@
+package ipguard
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/foxzi/sendry/internal/config"
+)
+
+func newTestGuard(cfg config.IPGuardConfig) (*Guard, *time.Time) {
+	g := New(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	g.now = func() time.Time { return now }
+	return g, &now
+}
+
+func TestGuardConnections(t *testing.T) {
+	g, now := newTestGuard(config.IPGuardConfig{MaxConnections: 2, ConnectionRate: 3})
+	const ip = "192.0.2.1"
+
+	for i := 0; i < 2; i++ {
+		if err := g.Open(ip); err != nil {
+			t.Fatalf("Open() #%d error = %v", i+1, err)
+		}
+	}
+	if err := g.Open(ip); !errors.Is(err, ErrTooManyConnections) {
+		t.Errorf("Open() over max_connections error = %v, want ErrTooManyConnections", err)
+	}
+	if err := g.Open("192.0.2.2"); err != nil {
+		t.Errorf("Open() of another client error = %v", err)
+	}
+
+	g.Close(ip)
+	if err := g.Open(ip); err != nil {
+		t.Fatalf("Open() after Close() error = %v", err)
+	}
+	g.Close(ip)
+	if err := g.Open(ip); !errors.Is(err, ErrConnectionRate) {
+		t.Errorf("Open() over connection_rate error = %v, want ErrConnectionRate", err)
+	}
+
+	*now = now.Add(time.Minute)
+	if err := g.Open(ip); err != nil {
+		t.Errorf("Open() in the next minute error = %v", err)
+	}
+}
+
+func TestGuardCommandsAndTarpit(t *testing.T) {
+	g, now := newTestGuard(config.IPGuardConfig{
+		CommandRate: 3,
+		TarpitAfter: 2,
+		TarpitDelay: 2 * time.Second,
+		Window:      5 * time.Minute,
+	})
+	const ip = "192.0.2.1"
+
+	for i := 0; i < 3; i++ {
+		if err := g.Command(ip); err != nil {
+			t.Fatalf("Command() #%d error = %v", i+1, err)
+		}
+	}
+	if err := g.Command(ip); !errors.Is(err, ErrCommandRate) {
+		t.Errorf("Command() over command_rate error = %v, want ErrCommandRate", err)
+	}
+
+	g.Reject(ip)
+	if d := g.Delay(ip); d != 0 {
+		t.Errorf("Delay() after 1 rejection = %v, want 0", d)
+	}
+	g.Reject(ip)
+	if d := g.Delay(ip); d != 2*time.Second {
+		t.Errorf("Delay() after 2 rejections = %v, want 2s", d)
+	}
+	if d := g.Delay("192.0.2.2"); d != 0 {
+		t.Errorf("Delay() of another client = %v, want 0", d)
+	}
+
+	*now = now.Add(5 * time.Minute)
+	if d := g.Delay(ip); d != 0 {
+		t.Errorf("Delay() after the window = %v, want 0", d)
+	}
+}
+
+func TestGuardBans(t *testing.T) {
+	g, now := newTestGuard(config.IPGuardConfig{BanAfter: 3, BanDuration: time.Hour})
+	const ip = "192.0.2.1"
+
+	for i := 0; i < 2; i++ {
+		if g.RelayAttempt(ip) {
+			t.Fatalf("RelayAttempt() #%d banned the client", i+1)
+		}
+	}
+	if !g.RelayAttempt(ip) {
+		t.Fatal("RelayAttempt() over ban_after did not ban the client")
+	}
+	if !g.Banned(ip) {
+		t.Error("Banned() = false after ban")
+	}
+	if err := g.Open(ip); !errors.Is(err, ErrBanned) {
+		t.Errorf("Open() of banned client error = %v, want ErrBanned", err)
+	}
+	if err := g.Command(ip); !errors.Is(err, ErrBanned) {
+		t.Errorf("Command() of banned client error = %v, want ErrBanned", err)
+	}
+
+	bans := g.Bans()
+	if len(bans) != 1 {
+		t.Fatalf("Bans() = %v, want 1 ban", bans)
+	}
+	if b := bans[0]; b.IP != ip || b.Reason != ReasonRelay || b.Attempts != 3 || !b.ExpiresAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("Bans()[0] = %+v", b)
+	}
+
+	*now = now.Add(time.Hour)
+	if g.Banned(ip) || len(g.Bans()) != 0 {
+		t.Error("ban did not expire after ban_duration")
+	}
+	if g.RelayAttempt(ip) {
+		t.Error("RelayAttempt() after expiry banned the client again at once")
+	}
+}
+
+func TestGuardUnban(t *testing.T) {
+	g, _ := newTestGuard(config.IPGuardConfig{BanAfter: 1})
+	const ip = "192.0.2.1"
+
+	if g.Unban(ip) != nil {
+		t.Error("Unban() of unknown client returned a ban")
+	}
+	g.RelayAttempt(ip)
+	if ban := g.Unban(ip); ban == nil || ban.IP != ip {
+		t.Errorf("Unban() = %v, want the ban of %s", ban, ip)
+	}
+	if g.Banned(ip) {
+		t.Error("Banned() = true after Unban()")
+	}
+	if err := g.Open(ip); err != nil {
+		t.Errorf("Open() after Unban() error = %v", err)
+	}
+}
+
+func TestGuardExempt(t *testing.T) {
+	g, _ := newTestGuard(config.IPGuardConfig{MaxConnections: 1, BanAfter: 1, Exempt: []string{"10.0.0.0/8"}})
+
+	for i := 0; i < 3; i++ {
+		if err := g.Open("10.1.2.3"); err != nil {
+			t.Fatalf("Open() of exempt client error = %v", err)
+		}
+	}
+	if g.RelayAttempt("10.1.2.3") || g.Banned("10.1.2.3") {
+		t.Error("exempt client was banned")
+	}
+	if !g.RelayAttempt("192.0.2.1") {
+		t.Error("client outside exempt was not banned")
+	}
+}
+
+func TestGuardNil(t *testing.T) {
+	var g *Guard
+	if err := g.Open("192.0.2.1"); err != nil {
+		t.Errorf("nil Open() error = %v", err)
+	}
+	if err := g.Command("192.0.2.1"); err != nil {
+		t.Errorf("nil Command() error = %v", err)
+	}
+	if g.RelayAttempt("192.0.2.1") || g.Banned("192.0.2.1") || g.Delay("192.0.2.1") != 0 {
+		t.Error("nil guard throttled a client")
+	}
+}
+
+func TestListener(t *testing.T) {
+	g, _ := newTestGuard(config.IPGuardConfig{MaxConnections: 1})
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gl := g.Listener(l, true)
+	defer gl.Close()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		for {
+			conn, err := gl.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- conn
+		}
+	}()
+
+	first, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+	server := <-accepted
+
+	// A second connection is over max_connections and gets a reply
+	second, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+	second.SetReadDeadline(time.Now().Add(5 * time.Second))
+	line, err := bufio.NewReader(second).ReadString('\n')
+	if err != nil {
+		t.Fatalf("reading refusal: %v", err)
+	}
+	if !strings.HasPrefix(line, "421 4.7.0 ") {
+		t.Errorf("refusal = %q, want 421", line)
+	}
+
+	// Closing the accepted connection frees its slot
+	server.Close()
+	server.Close()
+	third, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer third.Close()
+	select {
+	case conn := <-accepted:
+		conn.Close()
+	case <-time.After(5 * time.Second):
+		t.Error("connection after Close() was not accepted")
+	}
+}
@@ -0,0 +1,81 @@
+package ipguard
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// refuseTimeout limits writing the refusal reply to a client
+const refuseTimeout = 5 * time.Second
+
+// Listener wraps a listener so that connections of banned clients and
+// clients over their connection limits are refused before they reach the
+// SMTP server. With reply a refused client gets an SMTP reply, which is only
+// possible before TLS: wrap the plain listener and pass false for implicit
+// TLS.
+func (g *Guard) Listener(l net.Listener, reply bool) net.Listener {
+	return &listener{Listener: l, guard: g, reply: reply}
+}
+
+type listener struct {
+	net.Listener
+	guard *Guard
+	reply bool
+}
+
+func (l *listener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		ip := hostIP(conn.RemoteAddr())
+		if err := l.guard.Open(ip); err != nil {
+			l.guard.logger.Warn("SMTP connection refused", "ip", ip, "reason", err)
+			l.refuse(conn, err)
+			continue
+		}
+		return &guardedConn{Conn: conn, guard: l.guard, ip: ip}, nil
+	}
+}
+
+// refuse answers and closes a refused connection
+func (l *listener) refuse(conn net.Conn, err error) {
+	defer conn.Close()
+	if !l.reply {
+		return
+	}
+	msg := "421 4.7.0 Too many connections, try again later\r\n"
+	if errors.Is(err, ErrBanned) {
+		msg = "554 5.7.1 Connection refused\r\n"
+	}
+	conn.SetWriteDeadline(time.Now().Add(refuseTimeout))
+	conn.Write([]byte(msg))
+}
+
+// guardedConn ends its count in the guard when closed
+type guardedConn struct {
+	net.Conn
+	guard *Guard
+	ip    string
+	once  sync.Once
+}
+
+func (c *guardedConn) Close() error {
+	c.once.Do(func() { c.guard.Close(c.ip) })
+	return c.Conn.Close()
+}
+
+// hostIP returns the IP address of a remote address
+func hostIP(addr net.Addr) string {
+	if tcp, ok := addr.(*net.TCPAddr); ok {
+		return tcp.IP.String()
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
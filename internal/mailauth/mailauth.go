@@ -0,0 +1,255 @@
+// Package mailauth verifies SPF, DKIM and DMARC of received mail and
+// describes the outcome in an Authentication-Results header (RFC 8601).
+package mailauth
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"net/mail"
+	"strings"
+
+	"github.com/emersion/go-msgauth/authres"
+	"github.com/emersion/go-msgauth/dkim"
+	"github.com/emersion/go-msgauth/dmarc"
+	"golang.org/x/net/publicsuffix"
+)
+
+// maxDKIMSignatures bounds the signatures verified per message
+const maxDKIMSignatures = 5
+
+// Modes of smtp.inbound_auth
+const (
+	ModeOff    = "off"    // No verification
+	ModeTag    = "tag"    // Add an Authentication-Results header
+	ModeReject = "reject" // Also apply the DMARC policy of the From domain
+)
+
+// Resolver looks up the DNS records used by the checks. net.Resolver
+// implements it.
+type Resolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+}
+
+// Verifier checks the authentication of received messages
+type Verifier struct {
+	resolver Resolver
+	authserv string // Host name of this server, the authserv-id of the header
+}
+
+// NewVerifier creates a verifier. A nil resolver uses the system resolver.
+func NewVerifier(resolver Resolver, authserv string) *Verifier {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	return &Verifier{resolver: resolver, authserv: authserv}
+}
+
+// DKIMResult is the verification of one DKIM signature
+type DKIMResult struct {
+	Domain string
+	Value  authres.ResultValue
+	Reason string
+}
+
+// Result is the authentication outcome of a message
+type Result struct {
+	SPF       authres.ResultValue
+	SPFReason string
+	MailFrom  string // Envelope sender, or postmaster@HELO for null senders
+	Helo      string
+
+	DKIM []DKIMResult
+
+	DMARC       authres.ResultValue
+	DMARCReason string
+	FromDomain  string       // Domain of the From header
+	Policy      dmarc.Policy // Policy to apply, set when DMARC fails
+
+	authserv string
+}
+
+// Verify checks SPF for the client IP and envelope, the DKIM signatures of
+// the message and the DMARC policy of its From domain
+func (v *Verifier) Verify(ctx context.Context, ip net.IP, helo, mailFrom string, data []byte) *Result {
+	r := &Result{Helo: helo, MailFrom: mailFrom, authserv: v.authserv}
+	if mailFrom == "" {
+		r.MailFrom = "postmaster@" + helo
+	}
+
+	r.SPF, r.SPFReason = CheckSPF(ctx, v.resolver, ip, helo, mailFrom)
+	r.DKIM = v.VerifyDKIM(ctx, data)
+	v.checkDMARC(ctx, r, data)
+	return r
+}
+
+// VerifyDKIM verifies the DKIM signatures of a message. A message without
+// signatures has no results.
+func (v *Verifier) VerifyDKIM(ctx context.Context, data []byte) []DKIMResult {
+	verifications, err := dkim.VerifyWithOptions(bytes.NewReader(data), &dkim.VerifyOptions{
+		LookupTXT: func(domain string) ([]string, error) {
+			return v.resolver.LookupTXT(ctx, domain)
+		},
+		MaxVerifications: maxDKIMSignatures,
+	})
+	if err != nil && !errors.Is(err, dkim.ErrTooManySignatures) && len(verifications) == 0 {
+		return []DKIMResult{{Value: authres.ResultPermError, Reason: err.Error()}}
+	}
+
+	results := make([]DKIMResult, 0, len(verifications))
+	for _, ver := range verifications {
+		res := DKIMResult{Domain: ver.Domain, Value: authres.ResultPass}
+		switch {
+		case ver.Err == nil:
+		case dkim.IsTempFail(ver.Err):
+			res.Value, res.Reason = authres.ResultTempError, ver.Err.Error()
+		case dkim.IsPermFail(ver.Err):
+			res.Value, res.Reason = authres.ResultPermError, ver.Err.Error()
+		default:
+			res.Value, res.Reason = authres.ResultFail, ver.Err.Error()
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
+// checkDMARC evaluates the DMARC policy of the From domain (RFC 7489)
+func (v *Verifier) checkDMARC(ctx context.Context, r *Result, data []byte) {
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		r.DMARC, r.DMARCReason = authres.ResultPermError, "unparsable header"
+		return
+	}
+	from, err := mail.ParseAddress(msg.Header.Get("From"))
+	if err != nil {
+		r.DMARC, r.DMARCReason = authres.ResultPermError, "invalid From header"
+		return
+	}
+	r.FromDomain = domainOf(from.Address)
+	if r.FromDomain == "" {
+		r.DMARC, r.DMARCReason = authres.ResultPermError, "invalid From header"
+		return
+	}
+
+	record, subdomain, err := v.lookupDMARC(ctx, r.FromDomain)
+	switch {
+	case errors.Is(err, dmarc.ErrNoPolicy):
+		r.DMARC = authres.ResultNone
+		return
+	case dmarc.IsTempFail(err):
+		r.DMARC, r.DMARCReason = authres.ResultTempError, err.Error()
+		return
+	case err != nil:
+		r.DMARC, r.DMARCReason = authres.ResultPermError, err.Error()
+		return
+	}
+
+	if r.SPF == authres.ResultPass && aligned(domainOf(r.MailFrom), r.FromDomain, record.SPFAlignment) {
+		r.DMARC = authres.ResultPass
+		return
+	}
+	for _, d := range r.DKIM {
+		if d.Value == authres.ResultPass && aligned(d.Domain, r.FromDomain, record.DKIMAlignment) {
+			r.DMARC = authres.ResultPass
+			return
+		}
+	}
+
+	r.DMARC, r.DMARCReason = authres.ResultFail, "no aligned SPF or DKIM pass"
+	r.Policy = record.Policy
+	if subdomain && record.SubdomainPolicy != "" {
+		r.Policy = record.SubdomainPolicy
+	}
+	// A policy applied to a sample only falls back to the next milder one
+	// for the other messages
+	if record.Percent != nil && rand.Intn(100) >= *record.Percent {
+		switch r.Policy {
+		case dmarc.PolicyReject:
+			r.Policy = dmarc.PolicyQuarantine
+		case dmarc.PolicyQuarantine:
+			r.Policy = dmarc.PolicyNone
+		}
+	}
+}
+
+// lookupDMARC returns the DMARC record of a domain or, failing that, of its
+// organizational domain. subdomain is true for the latter.
+func (v *Verifier) lookupDMARC(ctx context.Context, domain string) (*dmarc.Record, bool, error) {
+	options := &dmarc.LookupOptions{
+		LookupTXT: func(name string) ([]string, error) {
+			return v.resolver.LookupTXT(ctx, name)
+		},
+	}
+	record, err := dmarc.LookupWithOptions(domain, options)
+	if !errors.Is(err, dmarc.ErrNoPolicy) {
+		return record, false, err
+	}
+	org := organizationalDomain(domain)
+	if org == domain {
+		return nil, false, err
+	}
+	record, err = dmarc.LookupWithOptions(org, options)
+	return record, true, err
+}
+
+// aligned reports whether an authenticated domain is aligned with the From
+// domain: the same domain in strict mode, the same organizational domain in
+// relaxed mode
+func aligned(domain, from string, mode dmarc.AlignmentMode) bool {
+	domain, from = strings.ToLower(domain), strings.ToLower(from)
+	if domain == "" {
+		return false
+	}
+	if mode == dmarc.AlignmentStrict {
+		return domain == from
+	}
+	return organizationalDomain(domain) == organizationalDomain(from)
+}
+
+// organizationalDomain returns the registered domain below the public suffix
+func organizationalDomain(domain string) string {
+	org, err := publicsuffix.EffectiveTLDPlusOne(strings.ToLower(domain))
+	if err != nil {
+		return strings.ToLower(domain)
+	}
+	return org
+}
+
+// domainOf returns the lowercase domain of an address
+func domainOf(addr string) string {
+	at := strings.LastIndex(addr, "@")
+	if at < 0 || at == len(addr)-1 {
+		return ""
+	}
+	return strings.ToLower(strings.Trim(addr[at+1:], ">"))
+}
+
+// Header returns the Authentication-Results header line, CRLF included
+func (r *Result) Header() string {
+	results := []authres.Result{
+		&authres.SPFResult{Value: r.SPF, Reason: r.SPFReason, From: r.MailFrom, Helo: r.Helo},
+	}
+	if len(r.DKIM) == 0 {
+		results = append(results, &authres.DKIMResult{Value: authres.ResultNone})
+	}
+	for _, d := range r.DKIM {
+		results = append(results, &authres.DKIMResult{Value: d.Value, Reason: d.Reason, Domain: d.Domain})
+	}
+	results = append(results, &authres.DMARCResult{Value: r.DMARC, Reason: r.DMARCReason, From: r.FromDomain})
+	return "Authentication-Results: " + authres.Format(r.authserv, results) + "\r\n"
+}
+
+// Reject reports whether the From domain asks to reject the message
+func (r *Result) Reject() bool {
+	return r.DMARC == authres.ResultFail && r.Policy == dmarc.PolicyReject
+}
+
+// Quarantine reports whether the From domain asks to treat the message as
+// suspicious
+func (r *Result) Quarantine() bool {
+	return r.DMARC == authres.ResultFail && r.Policy == dmarc.PolicyQuarantine
+}
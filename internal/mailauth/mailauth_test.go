@@ -0,0 +1,116 @@
+package mailauth
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/emersion/go-msgauth/authres"
+	"github.com/emersion/go-msgauth/dmarc"
+
+	"github.com/foxzi/sendry/internal/dkim"
+)
+
+const testMessage = "From: Alice <alice@example.com>\r\nTo: bob@example.org\r\nSubject: Test\r\n\r\nHello\r\n"
+
+// newSignedResolver returns a resolver with the DKIM key of example.com and
+// the message signed with it
+func newSignedResolver(t *testing.T, dmarcRecord string) (*fakeResolver, []byte) {
+	t.Helper()
+	kp, err := dkim.GenerateKey("example.com", "sendry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := dkim.NewSigner(kp.PrivateKey, "example.com", "sendry").Sign([]byte(testMessage))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &fakeResolver{txt: map[string][]string{
+		"sendry._domainkey.example.com": {kp.DNSRecord()},
+		"example.com":                   {"v=spf1 ip4:192.0.2.0/24 -all"},
+		"_dmarc.example.com":            {dmarcRecord},
+	}}
+	return r, signed
+}
+
+func TestVerifyPass(t *testing.T) {
+	r, signed := newSignedResolver(t, "v=DMARC1; p=reject")
+	v := NewVerifier(r, "mx.example.org")
+
+	res := v.Verify(context.Background(), net.ParseIP("192.0.2.1"), "mail.example.com", "alice@example.com", signed)
+	if res.SPF != authres.ResultPass || len(res.DKIM) != 1 || res.DKIM[0].Value != authres.ResultPass || res.DMARC != authres.ResultPass {
+		t.Fatalf("Verify() = %+v, want all pass", res)
+	}
+	if res.Reject() || res.Quarantine() {
+		t.Error("passing message rejected or quarantined")
+	}
+
+	header := res.Header()
+	for _, want := range []string{"Authentication-Results: mx.example.org;", "spf=pass", "dkim=pass", "header.d=example.com", "dmarc=pass", "header.from=example.com"} {
+		if !strings.Contains(header, want) {
+			t.Errorf("Header() = %q, missing %q", header, want)
+		}
+	}
+	if !strings.HasSuffix(header, "\r\n") {
+		t.Errorf("Header() = %q, want CRLF", header)
+	}
+}
+
+func TestVerifyDMARCAlignment(t *testing.T) {
+	r, signed := newSignedResolver(t, "v=DMARC1; p=quarantine; aspf=s")
+	r.txt["bounce.example.com"] = []string{"v=spf1 ip4:192.0.2.0/24 -all"}
+	v := NewVerifier(r, "mx.example.org")
+
+	// Relaxed DKIM alignment passes although strict SPF alignment does not
+	res := v.Verify(context.Background(), net.ParseIP("192.0.2.1"), "mail.example.com", "b@bounce.example.com", signed)
+	if res.DMARC != authres.ResultPass {
+		t.Errorf("DMARC = %s, want pass by DKIM", res.DMARC)
+	}
+
+	// Without the signature only the unaligned SPF pass is left
+	res = v.Verify(context.Background(), net.ParseIP("192.0.2.1"), "mail.example.com", "b@bounce.example.com", []byte(testMessage))
+	if res.SPF != authres.ResultPass || res.DMARC != authres.ResultFail || !res.Quarantine() {
+		t.Errorf("Verify() = %+v, want DMARC failure with quarantine", res)
+	}
+}
+
+func TestVerifyForged(t *testing.T) {
+	r, signed := newSignedResolver(t, "v=DMARC1; p=reject")
+	v := NewVerifier(r, "mx.example.org")
+
+	// A tampered body breaks the signature, the IP is not in the SPF record
+	tampered := []byte(strings.Replace(string(signed), "Hello", "Pay now", 1))
+	res := v.Verify(context.Background(), net.ParseIP("203.0.113.9"), "evil.example.net", "alice@example.com", tampered)
+	if res.SPF != authres.ResultFail || res.DKIM[0].Value != authres.ResultFail || res.DMARC != authres.ResultFail {
+		t.Fatalf("Verify() = %+v, want all fail", res)
+	}
+	if !res.Reject() || res.Policy != dmarc.PolicyReject {
+		t.Errorf("Reject() = false, policy %q", res.Policy)
+	}
+}
+
+func TestVerifySubdomainPolicy(t *testing.T) {
+	r := &fakeResolver{txt: map[string][]string{
+		"_dmarc.example.com": {"v=DMARC1; p=reject; sp=quarantine"},
+	}}
+	v := NewVerifier(r, "mx.example.org")
+
+	data := []byte(strings.Replace(testMessage, "alice@example.com", "alice@news.example.com", 1))
+	res := v.Verify(context.Background(), net.ParseIP("203.0.113.9"), "client.example.net", "alice@news.example.com", data)
+	if res.FromDomain != "news.example.com" || res.DMARC != authres.ResultFail || res.Policy != dmarc.PolicyQuarantine {
+		t.Errorf("Verify() = %+v, want the sp= policy of the organizational domain", res)
+	}
+	if len(res.DKIM) != 0 || !strings.Contains(res.Header(), "dkim=none") {
+		t.Errorf("Header() = %q, want dkim=none", res.Header())
+	}
+}
+
+func TestVerifyNoDMARC(t *testing.T) {
+	v := NewVerifier(&fakeResolver{}, "mx.example.org")
+
+	res := v.Verify(context.Background(), net.ParseIP("203.0.113.9"), "client.example.net", "alice@example.com", []byte(testMessage))
+	if res.SPF != authres.ResultNone || res.DMARC != authres.ResultNone || res.Reject() || res.Quarantine() {
+		t.Errorf("Verify() = %+v, want none without policy", res)
+	}
+}
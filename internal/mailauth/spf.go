@@ -0,0 +1,426 @@
+package mailauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/emersion/go-msgauth/authres"
+)
+
+// spfMaxLookups is the limit of DNS querying mechanisms and modifiers in
+// one SPF evaluation (RFC 7208 section 4.6.4)
+const spfMaxLookups = 10
+
+// errSPFLookupLimit is returned when a record needs too many DNS lookups
+var errSPFLookupLimit = errors.New("too many DNS lookups")
+
+// spfCheck evaluates the SPF record of one domain
+type spfCheck struct {
+	resolver Resolver
+	ip       net.IP
+	sender   string // MAIL FROM, or postmaster@HELO for null senders
+	helo     string
+	lookups  int
+}
+
+// CheckSPF evaluates the SPF policy of the sender domain for a client IP
+// (RFC 7208). For the null sender the HELO domain is checked. It returns
+// the result and a reason for logs and the Authentication-Results header.
+func CheckSPF(ctx context.Context, resolver Resolver, ip net.IP, helo, sender string) (authres.ResultValue, string) {
+	if sender == "" {
+		sender = "postmaster@" + helo
+	}
+	domain := domainOf(sender)
+	if domain == "" {
+		return authres.ResultNone, "no sender domain"
+	}
+
+	c := &spfCheck{resolver: resolver, ip: ip, sender: sender, helo: helo}
+	result, err := c.checkHost(ctx, domain)
+	if err != nil {
+		return result, err.Error()
+	}
+	return result, ""
+}
+
+// checkHost evaluates the record of a domain, used for the sender domain,
+// include and redirect
+func (c *spfCheck) checkHost(ctx context.Context, domain string) (authres.ResultValue, error) {
+	record, result, err := c.record(ctx, domain)
+	if record == "" {
+		return result, err
+	}
+
+	var redirect string
+	for _, term := range strings.Fields(record)[1:] {
+		if name, value, ok := strings.Cut(term, "="); ok && !strings.ContainsAny(name, ":/") {
+			if strings.EqualFold(name, "redirect") {
+				redirect = value
+			}
+			// Other modifiers like exp= are ignored
+			continue
+		}
+
+		qualifier := authres.ResultValue(authres.ResultPass)
+		switch term[0] {
+		case '+':
+			term = term[1:]
+		case '-':
+			qualifier, term = authres.ResultFail, term[1:]
+		case '~':
+			qualifier, term = authres.ResultSoftFail, term[1:]
+		case '?':
+			qualifier, term = authres.ResultNeutral, term[1:]
+		}
+
+		match, err := c.mechanism(ctx, domain, term)
+		if err != nil {
+			if errors.Is(err, errSPFTemp) {
+				return authres.ResultTempError, err
+			}
+			return authres.ResultPermError, err
+		}
+		if match {
+			return qualifier, nil
+		}
+	}
+
+	if redirect == "" {
+		return authres.ResultNeutral, nil
+	}
+	target, err := c.expand(redirect, domain)
+	if err != nil {
+		return authres.ResultPermError, err
+	}
+	if err := c.count(); err != nil {
+		return authres.ResultPermError, err
+	}
+	result, err = c.checkHost(ctx, target)
+	if result == authres.ResultNone {
+		return authres.ResultPermError, fmt.Errorf("redirect domain %s has no SPF record", target)
+	}
+	return result, err
+}
+
+// errSPFTemp marks DNS errors that make the result a temperror
+var errSPFTemp = errors.New("temporary DNS error")
+
+// record returns the SPF record of a domain. Without a record it returns
+// the result to use instead.
+func (c *spfCheck) record(ctx context.Context, domain string) (string, authres.ResultValue, error) {
+	txts, err := c.resolver.LookupTXT(ctx, domain)
+	if err != nil {
+		if isNotFound(err) {
+			return "", authres.ResultNone, nil
+		}
+		return "", authres.ResultTempError, fmt.Errorf("%w: %v", errSPFTemp, err)
+	}
+
+	var records []string
+	for _, txt := range txts {
+		if strings.EqualFold(txt, "v=spf1") || strings.HasPrefix(strings.ToLower(txt), "v=spf1 ") {
+			records = append(records, txt)
+		}
+	}
+	switch len(records) {
+	case 0:
+		return "", authres.ResultNone, nil
+	case 1:
+		return records[0], "", nil
+	}
+	return "", authres.ResultPermError, fmt.Errorf("%s has %d SPF records", domain, len(records))
+}
+
+// mechanism reports whether a mechanism matches the client IP
+func (c *spfCheck) mechanism(ctx context.Context, domain, term string) (bool, error) {
+	name, arg, _ := strings.Cut(term, ":")
+	if i := strings.Index(name, "/"); i >= 0 {
+		// a/24 and mx/24 without a domain
+		name, arg = name[:i], name[i:]
+	}
+
+	switch strings.ToLower(name) {
+	case "all":
+		return true, nil
+
+	case "ip4", "ip6":
+		if !strings.Contains(arg, "/") {
+			if strings.EqualFold(name, "ip4") {
+				arg += "/32"
+			} else {
+				arg += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(arg)
+		if err != nil {
+			return false, fmt.Errorf("invalid %s mechanism %q", name, arg)
+		}
+		return network.Contains(c.ip), nil
+
+	case "include":
+		if err := c.count(); err != nil {
+			return false, err
+		}
+		target, err := c.expand(arg, domain)
+		if err != nil {
+			return false, err
+		}
+		result, err := c.checkHost(ctx, target)
+		switch result {
+		case authres.ResultPass:
+			return true, nil
+		case authres.ResultTempError:
+			return false, err
+		case authres.ResultPermError, authres.ResultNone:
+			if err == nil {
+				err = fmt.Errorf("included domain %s has no SPF record", target)
+			}
+			return false, err
+		}
+		return false, nil
+
+	case "a", "mx":
+		if err := c.count(); err != nil {
+			return false, err
+		}
+		host, cidr4, cidr6, err := c.hostAndCIDR(arg, domain)
+		if err != nil {
+			return false, err
+		}
+		hosts := []string{host}
+		if strings.EqualFold(name, "mx") {
+			mxs, err := c.resolver.LookupMX(ctx, host)
+			if err != nil && !isNotFound(err) {
+				return false, fmt.Errorf("%w: %v", errSPFTemp, err)
+			}
+			if len(mxs) > spfMaxLookups {
+				return false, errSPFLookupLimit
+			}
+			hosts = hosts[:0]
+			for _, mx := range mxs {
+				hosts = append(hosts, mx.Host)
+			}
+		}
+		for _, h := range hosts {
+			addrs, err := c.resolver.LookupIPAddr(ctx, h)
+			if err != nil {
+				if isNotFound(err) {
+					continue
+				}
+				return false, fmt.Errorf("%w: %v", errSPFTemp, err)
+			}
+			for _, addr := range addrs {
+				if matchCIDR(c.ip, addr.IP, cidr4, cidr6) {
+					return true, nil
+				}
+			}
+		}
+		return false, nil
+
+	case "exists":
+		if err := c.count(); err != nil {
+			return false, err
+		}
+		host, err := c.expand(arg, domain)
+		if err != nil {
+			return false, err
+		}
+		addrs, err := c.resolver.LookupIPAddr(ctx, host)
+		if err != nil && !isNotFound(err) {
+			return false, fmt.Errorf("%w: %v", errSPFTemp, err)
+		}
+		return len(addrs) > 0, nil
+
+	case "ptr":
+		// Deprecated (RFC 7208 section 5.5) and treated as not matching
+		return false, c.count()
+	}
+	return false, fmt.Errorf("unknown mechanism %q", term)
+}
+
+// count records a DNS querying term and fails over the lookup limit
+func (c *spfCheck) count() error {
+	c.lookups++
+	if c.lookups > spfMaxLookups {
+		return errSPFLookupLimit
+	}
+	return nil
+}
+
+// hostAndCIDR splits the argument of an a or mx mechanism into the domain
+// and the IPv4 and IPv6 prefix lengths
+func (c *spfCheck) hostAndCIDR(arg, domain string) (string, int, int, error) {
+	cidr4, cidr6 := 32, 128
+	host := arg
+	if i := strings.Index(arg, "/"); i >= 0 {
+		host = arg[:i]
+		spec := arg[i+1:]
+		v4, v6, dual := strings.Cut(spec, "/")
+		if dual {
+			// "/24//64" or "//64"
+			v6 = strings.TrimPrefix(v6, "/")
+		}
+		if v4 != "" {
+			n, err := strconv.Atoi(v4)
+			if err != nil || n < 0 || n > 32 {
+				return "", 0, 0, fmt.Errorf("invalid CIDR length %q", spec)
+			}
+			cidr4 = n
+		}
+		if dual && v6 != "" {
+			n, err := strconv.Atoi(v6)
+			if err != nil || n < 0 || n > 128 {
+				return "", 0, 0, fmt.Errorf("invalid CIDR length %q", spec)
+			}
+			cidr6 = n
+		}
+	}
+	if host == "" {
+		return domain, cidr4, cidr6, nil
+	}
+	host, err := c.expand(host, domain)
+	return host, cidr4, cidr6, err
+}
+
+// matchCIDR reports whether ip is in the network of addr
+func matchCIDR(ip, addr net.IP, cidr4, cidr6 int) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		addr4 := addr.To4()
+		if addr4 == nil {
+			return false
+		}
+		mask := net.CIDRMask(cidr4, 32)
+		return ip4.Mask(mask).Equal(addr4.Mask(mask))
+	}
+	if addr.To4() != nil {
+		return false
+	}
+	mask := net.CIDRMask(cidr6, 128)
+	return ip.Mask(mask).Equal(addr.Mask(mask))
+}
+
+// expand expands the macros of a domain spec (RFC 7208 section 7)
+func (c *spfCheck) expand(spec, domain string) (string, error) {
+	if !strings.Contains(spec, "%") {
+		return spec, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(spec); i++ {
+		if spec[i] != '%' {
+			b.WriteByte(spec[i])
+			continue
+		}
+		if i+1 >= len(spec) {
+			return "", fmt.Errorf("invalid macro in %q", spec)
+		}
+		i++
+		switch spec[i] {
+		case '%':
+			b.WriteByte('%')
+			continue
+		case '_':
+			b.WriteByte(' ')
+			continue
+		case '-':
+			b.WriteString("%20")
+			continue
+		case '{':
+		default:
+			return "", fmt.Errorf("invalid macro in %q", spec)
+		}
+
+		end := strings.IndexByte(spec[i:], '}')
+		if end < 2 {
+			return "", fmt.Errorf("invalid macro in %q", spec)
+		}
+		value, err := c.macro(spec[i+1:i+end], domain)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(value)
+		i += end
+	}
+	return b.String(), nil
+}
+
+// macro expands one macro, e.g. "ir" or "d2"
+func (c *spfCheck) macro(m, domain string) (string, error) {
+	local, senderDomain, _ := strings.Cut(c.sender, "@")
+
+	var value string
+	switch m[0] {
+	case 's', 'S':
+		value = c.sender
+	case 'l', 'L':
+		value = local
+	case 'o', 'O':
+		value = senderDomain
+	case 'd', 'D':
+		value = domain
+	case 'h', 'H':
+		value = c.helo
+	case 'v', 'V':
+		value = "in-addr"
+		if c.ip.To4() == nil {
+			value = "ip6"
+		}
+	case 'i', 'I':
+		if ip4 := c.ip.To4(); ip4 != nil {
+			value = ip4.String()
+		} else {
+			var parts []string
+			for _, b := range c.ip.To16() {
+				parts = append(parts, fmt.Sprintf("%x", b>>4), fmt.Sprintf("%x", b&0xf))
+			}
+			value = strings.Join(parts, ".")
+		}
+	default:
+		return "", fmt.Errorf("unsupported macro %%{%s}", m)
+	}
+
+	// Transformers: number of right-hand parts, r to reverse, delimiters
+	rest := m[1:]
+	digits := 0
+	for digits < len(rest) && rest[digits] >= '0' && rest[digits] <= '9' {
+		digits++
+	}
+	keep := 0
+	if digits > 0 {
+		keep, _ = strconv.Atoi(rest[:digits])
+		if keep == 0 {
+			return "", fmt.Errorf("invalid macro %%{%s}", m)
+		}
+	}
+	rest = rest[digits:]
+	reverse := false
+	if rest != "" && (rest[0] == 'r' || rest[0] == 'R') {
+		reverse = true
+		rest = rest[1:]
+	}
+	delims := "."
+	if rest != "" {
+		delims = rest
+	}
+
+	parts := strings.FieldsFunc(value, func(r rune) bool { return strings.ContainsRune(delims, r) })
+	if reverse {
+		for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+			parts[i], parts[j] = parts[j], parts[i]
+		}
+	}
+	if keep > 0 && keep < len(parts) {
+		parts = parts[len(parts)-keep:]
+	}
+	return strings.Join(parts, "."), nil
+}
+
+// isNotFound reports whether a DNS error means the name has no records
+func isNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
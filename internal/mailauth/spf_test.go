@@ -0,0 +1,135 @@
+package mailauth
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/emersion/go-msgauth/authres"
+)
+
+// fakeResolver serves DNS records from maps, unknown names do not exist
+type fakeResolver struct {
+	txt  map[string][]string
+	ip   map[string][]string
+	mx   map[string][]string
+	fail map[string]bool // Names that fail with a temporary error
+}
+
+func notFound(name string) error {
+	return &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func (r *fakeResolver) check(name string) error {
+	if r.fail[name] {
+		return &net.DNSError{Err: "server misbehaving", Name: name, IsTemporary: true}
+	}
+	return nil
+}
+
+func (r *fakeResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	if err := r.check(name); err != nil {
+		return nil, err
+	}
+	if txt, ok := r.txt[name]; ok {
+		return txt, nil
+	}
+	return nil, notFound(name)
+}
+
+func (r *fakeResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	if err := r.check(host); err != nil {
+		return nil, err
+	}
+	ips, ok := r.ip[host]
+	if !ok {
+		return nil, notFound(host)
+	}
+	var addrs []net.IPAddr
+	for _, ip := range ips {
+		addrs = append(addrs, net.IPAddr{IP: net.ParseIP(ip)})
+	}
+	return addrs, nil
+}
+
+func (r *fakeResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	if err := r.check(name); err != nil {
+		return nil, err
+	}
+	hosts, ok := r.mx[name]
+	if !ok {
+		return nil, notFound(name)
+	}
+	var mxs []*net.MX
+	for _, h := range hosts {
+		mxs = append(mxs, &net.MX{Host: h, Pref: 10})
+	}
+	return mxs, nil
+}
+
+func TestCheckSPF(t *testing.T) {
+	r := &fakeResolver{
+		txt: map[string][]string{
+			"example.com":                    {"some verification", "v=spf1 ip4:192.0.2.0/24 include:_spf.example.net a:web.example.com mx -all"},
+			"_spf.example.net":               {"v=spf1 ip6:2001:db8::/32 ~all"},
+			"soft.example":                   {"v=spf1 ~all"},
+			"neutral.example":                {"v=spf1 ip4:198.51.100.1"},
+			"redirect.example":               {"v=spf1 redirect=example.com"},
+			"double.example":                 {"v=spf1 -all", "v=spf1 +all"},
+			"macro.example":                  {"v=spf1 exists:%{ir}.%{l1r+-}._spf.%{d} -all"},
+			"1.2.0.192.a._spf.macro.example": {},
+			"cidr.example":                   {"v=spf1 a/24 -all"},
+			"loop.example":                   {"v=spf1 include:loop.example -all"},
+			"broken.example":                 {"v=spf1 include:missing.example -all"},
+			"temp.example":                   {"v=spf1 include:down.example -all"},
+		},
+		ip: map[string][]string{
+			"web.example.com":                {"203.0.113.5"},
+			"mx1.example.com":                {"203.0.113.25"},
+			"cidr.example":                   {"198.51.100.10"},
+			"1.2.0.192.a._spf.macro.example": {"127.0.0.2"},
+		},
+		mx:   map[string][]string{"example.com": {"mx1.example.com"}},
+		fail: map[string]bool{"down.example": true},
+	}
+
+	tests := []struct {
+		name   string
+		ip     string
+		sender string
+		want   authres.ResultValue
+	}{
+		{"ip4 range", "192.0.2.10", "alice@example.com", authres.ResultPass},
+		{"include ip6", "2001:db8::1", "alice@example.com", authres.ResultPass},
+		{"a mechanism", "203.0.113.5", "alice@example.com", authres.ResultPass},
+		{"mx mechanism", "203.0.113.25", "alice@example.com", authres.ResultPass},
+		{"not listed", "198.51.100.7", "alice@example.com", authres.ResultFail},
+		{"softfail", "198.51.100.7", "bob@soft.example", authres.ResultSoftFail},
+		{"no match without all", "192.0.2.1", "bob@neutral.example", authres.ResultNeutral},
+		{"redirect", "192.0.2.10", "bob@redirect.example", authres.ResultPass},
+		{"no record", "192.0.2.10", "bob@none.example", authres.ResultNone},
+		{"two records", "192.0.2.10", "bob@double.example", authres.ResultPermError},
+		{"macro exists", "192.0.2.1", "a-b@macro.example", authres.ResultPass},
+		{"macro no match", "192.0.2.2", "a-b@macro.example", authres.ResultFail},
+		{"a with cidr", "198.51.100.200", "bob@cidr.example", authres.ResultPass},
+		{"lookup limit", "192.0.2.1", "bob@loop.example", authres.ResultPermError},
+		{"include without record", "192.0.2.1", "bob@broken.example", authres.ResultPermError},
+		{"temporary error", "192.0.2.1", "bob@temp.example", authres.ResultTempError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, reason := CheckSPF(context.Background(), r, net.ParseIP(tt.ip), "client.example.org", tt.sender)
+			if got != tt.want {
+				t.Errorf("CheckSPF() = %s (%s), want %s", got, reason, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckSPFNullSender(t *testing.T) {
+	r := &fakeResolver{txt: map[string][]string{"client.example.org": {"v=spf1 ip4:192.0.2.1 -all"}}}
+
+	if got, _ := CheckSPF(context.Background(), r, net.ParseIP("192.0.2.1"), "client.example.org", ""); got != authres.ResultPass {
+		t.Errorf("CheckSPF() of null sender = %s, want HELO domain checked", got)
+	}
+}
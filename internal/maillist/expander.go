@@ -0,0 +1,134 @@
+package maillist
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/foxzi/sendry/internal/headers"
+	"github.com/foxzi/sendry/internal/queue"
+)
+
+// Expander expands list addresses into per-member queue messages
+type Expander struct {
+	storage *Storage
+}
+
+// NewExpander creates a new list expander
+func NewExpander(storage *Storage) *Expander {
+	return &Expander{storage: storage}
+}
+
+// Expand splits the recipients of msg into list members and regular recipients.
+// Returns nil if none of the recipients is an enabled list.
+func (e *Expander) Expand(ctx context.Context, msg *queue.Message) (*queue.ListExpansion, error) {
+	var loops []string
+	if parsed, err := mail.ReadMessage(bytes.NewReader(msg.Data)); err == nil {
+		loops = parsed.Header["X-Loop"]
+	}
+
+	expansion := &queue.ListExpansion{}
+	matched := false
+
+	for _, rcpt := range msg.To {
+		list, err := e.storage.GetByAddress(ctx, rcpt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get list: %w", err)
+		}
+		if list == nil || !list.Enabled {
+			expansion.Remaining = append(expansion.Remaining, rcpt)
+			continue
+		}
+		matched = true
+
+		// Message already went through this list, drop it to break the loop
+		if containsAddress(loops, list.Address) {
+			continue
+		}
+
+		if !list.CanPost(msg.From) {
+			expansion.Rejected = append(expansion.Rejected, rcpt)
+			continue
+		}
+
+		data := rewriteForList(msg.Data, list)
+		record := &Expansion{
+			MessageID: msg.ID,
+			ListID:    list.ID,
+			Members:   make(map[string]string, len(list.Members)),
+			CreatedAt: time.Now(),
+		}
+
+		for _, member := range list.Members {
+			now := time.Now()
+			memberMsg := &queue.Message{
+				ID:        uuid.New().String(),
+				From:      msg.From,
+				To:        []string{member},
+				Data:      data,
+				Status:    queue.StatusPending,
+				CreatedAt: now,
+				UpdatedAt: now,
+				ClientIP:  msg.ClientIP,
+				AuthUser:  msg.AuthUser,
+			}
+			record.Members[member] = memberMsg.ID
+			expansion.Messages = append(expansion.Messages, memberMsg)
+		}
+
+		if err := e.storage.SaveExpansion(ctx, record); err != nil {
+			return nil, fmt.Errorf("failed to save list expansion: %w", err)
+		}
+	}
+
+	if !matched {
+		return nil, nil
+	}
+	return expansion, nil
+}
+
+// rewriteForList adds list headers and the subject prefix to the message
+func rewriteForList(data []byte, list *List) []byte {
+	rules := []headers.Rule{
+		{Action: headers.ActionAdd, Header: "X-Loop", Value: list.Address},
+		{Action: headers.ActionReplace, Header: "List-Id", Value: listID(list)},
+		{Action: headers.ActionReplace, Header: "List-Post", Value: "<mailto:" + list.Address + ">"},
+		{Action: headers.ActionReplace, Header: "Precedence", Value: "list"},
+	}
+
+	if list.SubjectPrefix != "" {
+		subject := ""
+		if parsed, err := mail.ReadMessage(bytes.NewReader(data)); err == nil {
+			subject = parsed.Header.Get("Subject")
+		}
+		if !strings.Contains(subject, list.SubjectPrefix) {
+			subject = strings.TrimSpace(list.SubjectPrefix + " " + subject)
+		}
+		rules = append(rules, headers.Rule{Action: headers.ActionReplace, Header: "Subject", Value: subject})
+	}
+
+	return headers.Apply(data, rules)
+}
+
+// listID builds the List-Id header value (RFC 2919)
+func listID(list *List) string {
+	id := strings.Replace(list.Address, "@", ".", 1)
+	if list.Name != "" {
+		return fmt.Sprintf("%s <%s>", strings.NewReplacer("\r", "", "\n", " ").Replace(list.Name), id)
+	}
+	return "<" + id + ">"
+}
+
+func containsAddress(values []string, addr string) bool {
+	for _, v := range values {
+		if NormalizeAddress(v) == addr {
+			return true
+		}
+	}
+	return false
+}
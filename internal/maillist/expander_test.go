@@ -0,0 +1,130 @@
+package maillist
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/foxzi/sendry/internal/queue"
+)
+
+func TestExpander_Expand(t *testing.T) {
+	storage := setupTestStorage(t)
+	ctx := context.Background()
+
+	l := &List{
+		Address:       "team@example.com",
+		Name:          "Team",
+		Members:       []string{"a@example.com", "b@example.com"},
+		SubjectPrefix: "[team]",
+		Enabled:       true,
+	}
+	if err := storage.Create(ctx, l); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	msg := &queue.Message{
+		ID:   "msg-1",
+		From: "sender@remote.com",
+		To:   []string{"team@example.com", "other@example.com"},
+		Data: []byte("From: sender@remote.com\r\nTo: team@example.com\r\nSubject: Hello\r\n\r\nBody"),
+	}
+
+	expansion, err := NewExpander(storage).Expand(ctx, msg)
+	if err != nil {
+		t.Fatalf("Expand() error = %v", err)
+	}
+	if expansion == nil {
+		t.Fatal("expected expansion, got nil")
+	}
+
+	if len(expansion.Messages) != 2 {
+		t.Fatalf("expected 2 member messages, got %d", len(expansion.Messages))
+	}
+	if len(expansion.Remaining) != 1 || expansion.Remaining[0] != "other@example.com" {
+		t.Errorf("unexpected remaining recipients: %v", expansion.Remaining)
+	}
+
+	data := string(expansion.Messages[0].Data)
+	for _, want := range []string{
+		"Subject: [team] Hello",
+		"List-Id: Team <team.example.com>",
+		"X-Loop: team@example.com",
+		"Precedence: list",
+	} {
+		if !strings.Contains(data, want) {
+			t.Errorf("member message should contain %q, got:\n%s", want, data)
+		}
+	}
+
+	record, err := storage.GetExpansion(ctx, l.ID, "msg-1")
+	if err != nil || record == nil {
+		t.Fatalf("GetExpansion() = %v, %v", record, err)
+	}
+	if record.Members["a@example.com"] != expansion.Messages[0].ID {
+		t.Errorf("expansion should track member message IDs, got %v", record.Members)
+	}
+}
+
+func TestExpander_NoLists(t *testing.T) {
+	storage := setupTestStorage(t)
+
+	msg := &queue.Message{
+		ID:   "msg-1",
+		From: "sender@remote.com",
+		To:   []string{"user@example.com"},
+		Data: []byte("Subject: Hello\r\n\r\nBody"),
+	}
+
+	expansion, err := NewExpander(storage).Expand(context.Background(), msg)
+	if err != nil {
+		t.Fatalf("Expand() error = %v", err)
+	}
+	if expansion != nil {
+		t.Errorf("expected nil expansion, got %+v", expansion)
+	}
+}
+
+func TestExpander_SenderRestrictionAndLoop(t *testing.T) {
+	storage := setupTestStorage(t)
+	ctx := context.Background()
+
+	l := &List{
+		Address:      "staff@example.com",
+		Members:      []string{"a@example.com"},
+		SenderPolicy: SenderPolicyMembers,
+		Enabled:      true,
+	}
+	if err := storage.Create(ctx, l); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	expander := NewExpander(storage)
+
+	msg := &queue.Message{
+		ID:   "msg-1",
+		From: "stranger@remote.com",
+		To:   []string{"staff@example.com"},
+		Data: []byte("Subject: Hello\r\n\r\nBody"),
+	}
+	expansion, err := expander.Expand(ctx, msg)
+	if err != nil {
+		t.Fatalf("Expand() error = %v", err)
+	}
+	if len(expansion.Messages) != 0 || len(expansion.Rejected) != 1 {
+		t.Errorf("expected sender to be rejected, got %+v", expansion)
+	}
+
+	loop := &queue.Message{
+		ID:   "msg-2",
+		From: "a@example.com",
+		To:   []string{"staff@example.com"},
+		Data: []byte("X-Loop: staff@example.com\r\nSubject: Hello\r\n\r\nBody"),
+	}
+	expansion, err = expander.Expand(ctx, loop)
+	if err != nil {
+		t.Fatalf("Expand() error = %v", err)
+	}
+	if len(expansion.Messages) != 0 || len(expansion.Remaining) != 0 {
+		t.Errorf("expected looping message to be dropped, got %+v", expansion)
+	}
+}
@@ -0,0 +1,97 @@
+package maillist
+
+import (
+	"strings"
+	"time"
+)
+
+// Sender policies control who may post to a list
+const (
+	SenderPolicyAnyone  = "anyone"  // Any sender may post (default)
+	SenderPolicyMembers = "members" // Only members and allowed senders may post
+	SenderPolicyAllowed = "allowed" // Only allowed senders may post
+)
+
+// List represents an internal distribution address that expands to its members
+type List struct {
+	ID             string    `json:"id"`
+	Address        string    `json:"address"` // List address, e.g. team@example.com
+	Name           string    `json:"name,omitempty"`
+	Members        []string  `json:"members"`
+	SubjectPrefix  string    `json:"subject_prefix,omitempty"` // e.g. "[team]"
+	SenderPolicy   string    `json:"sender_policy"`
+	AllowedSenders []string  `json:"allowed_senders,omitempty"` // Addresses or @domain entries
+	Enabled        bool      `json:"enabled"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// CanPost reports whether the sender is allowed to post to the list
+func (l *List) CanPost(sender string) bool {
+	sender = NormalizeAddress(sender)
+
+	switch l.SenderPolicy {
+	case SenderPolicyMembers:
+		return l.IsMember(sender) || l.isAllowed(sender)
+	case SenderPolicyAllowed:
+		return l.isAllowed(sender)
+	default:
+		return true
+	}
+}
+
+// IsMember reports whether the address is a list member
+func (l *List) IsMember(addr string) bool {
+	addr = NormalizeAddress(addr)
+	for _, m := range l.Members {
+		if NormalizeAddress(m) == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// isAllowed checks the sender against allowed addresses and @domain entries
+func (l *List) isAllowed(sender string) bool {
+	for _, a := range l.AllowedSenders {
+		a = NormalizeAddress(a)
+		if a == sender {
+			return true
+		}
+		if strings.HasPrefix(a, "@") && strings.HasSuffix(sender, a) {
+			return true
+		}
+	}
+	return false
+}
+
+// Expansion records the per-member messages created for one list message
+type Expansion struct {
+	MessageID string            `json:"message_id"` // Original message ID
+	ListID    string            `json:"list_id"`
+	Members   map[string]string `json:"members"` // Member address -> queued message ID
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// ListFilter contains filters for listing mailing lists
+type ListFilter struct {
+	Limit  int
+	Offset int
+	Domain string
+}
+
+// NormalizeAddress lowercases and trims an email address
+func NormalizeAddress(addr string) string {
+	addr = strings.TrimSpace(addr)
+	addr = strings.Trim(addr, "<>")
+	return strings.ToLower(addr)
+}
+
+// ValidSenderPolicy reports whether the policy is known (empty means anyone)
+func ValidSenderPolicy(policy string) bool {
+	switch policy {
+	case "", SenderPolicyAnyone, SenderPolicyMembers, SenderPolicyAllowed:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,283 @@
+package maillist
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/foxzi/sendry/internal/email"
+)
+
+var (
+	bucketLists      = []byte("maillists")
+	bucketAddresses  = []byte("maillist_addresses")
+	bucketExpansions = []byte("maillist_expansions")
+)
+
+// Storage provides mailing list storage
+type Storage struct {
+	db *bolt.DB
+}
+
+// NewStorage creates a new mailing list storage using the provided BoltDB instance
+func NewStorage(db *bolt.DB) (*Storage, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{bucketLists, bucketAddresses, bucketExpansions} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create maillist buckets: %w", err)
+	}
+	return &Storage{db: db}, nil
+}
+
+// Create creates a new mailing list
+func (s *Storage) Create(ctx context.Context, l *List) error {
+	normalizeList(l)
+	if l.Address == "" {
+		return fmt.Errorf("address is required")
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		lists := tx.Bucket(bucketLists)
+		addresses := tx.Bucket(bucketAddresses)
+
+		if existing := addresses.Get([]byte(l.Address)); existing != nil {
+			return fmt.Errorf("list %q already exists", l.Address)
+		}
+
+		l.ID = uuid.New().String()
+		l.CreatedAt = time.Now()
+		l.UpdatedAt = l.CreatedAt
+
+		data, err := json.Marshal(l)
+		if err != nil {
+			return fmt.Errorf("failed to marshal list: %w", err)
+		}
+
+		if err := lists.Put([]byte(l.ID), data); err != nil {
+			return err
+		}
+		return addresses.Put([]byte(l.Address), []byte(l.ID))
+	})
+}
+
+// Get retrieves a mailing list by ID
+func (s *Storage) Get(ctx context.Context, id string) (*List, error) {
+	var l *List
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bucketLists).Get([]byte(id))
+		if data == nil {
+			return nil
+		}
+		l = &List{}
+		return json.Unmarshal(data, l)
+	})
+
+	return l, err
+}
+
+// GetByAddress retrieves a mailing list by its address
+func (s *Storage) GetByAddress(ctx context.Context, address string) (*List, error) {
+	var l *List
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		id := tx.Bucket(bucketAddresses).Get([]byte(NormalizeAddress(address)))
+		if id == nil {
+			return nil
+		}
+		data := tx.Bucket(bucketLists).Get(id)
+		if data == nil {
+			return nil
+		}
+		l = &List{}
+		return json.Unmarshal(data, l)
+	})
+
+	return l, err
+}
+
+// List returns mailing lists with optional filtering
+func (s *Storage) List(ctx context.Context, filter ListFilter) ([]*List, error) {
+	var result []*List
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucketLists).Cursor()
+
+		skipped := 0
+		count := 0
+
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			var l List
+			if err := json.Unmarshal(v, &l); err != nil {
+				continue
+			}
+
+			if filter.Domain != "" && email.ExtractDomain(l.Address) != NormalizeAddress(filter.Domain) {
+				continue
+			}
+
+			// Apply offset
+			if skipped < filter.Offset {
+				skipped++
+				continue
+			}
+
+			result = append(result, &l)
+			count++
+
+			// Apply limit
+			if filter.Limit > 0 && count >= filter.Limit {
+				break
+			}
+		}
+
+		return nil
+	})
+
+	return result, err
+}
+
+// Update updates an existing mailing list
+func (s *Storage) Update(ctx context.Context, l *List) error {
+	normalizeList(l)
+	if l.Address == "" {
+		return fmt.Errorf("address is required")
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		lists := tx.Bucket(bucketLists)
+		addresses := tx.Bucket(bucketAddresses)
+
+		existingData := lists.Get([]byte(l.ID))
+		if existingData == nil {
+			return fmt.Errorf("list not found")
+		}
+
+		var existing List
+		if err := json.Unmarshal(existingData, &existing); err != nil {
+			return err
+		}
+
+		// If address changed, update index
+		if existing.Address != l.Address {
+			if existingID := addresses.Get([]byte(l.Address)); existingID != nil {
+				return fmt.Errorf("list %q already exists", l.Address)
+			}
+			if err := addresses.Delete([]byte(existing.Address)); err != nil {
+				return err
+			}
+			if err := addresses.Put([]byte(l.Address), []byte(l.ID)); err != nil {
+				return err
+			}
+		}
+
+		l.CreatedAt = existing.CreatedAt
+		l.UpdatedAt = time.Now()
+
+		data, err := json.Marshal(l)
+		if err != nil {
+			return fmt.Errorf("failed to marshal list: %w", err)
+		}
+
+		return lists.Put([]byte(l.ID), data)
+	})
+}
+
+// Delete removes a mailing list and its expansion records
+func (s *Storage) Delete(ctx context.Context, id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		lists := tx.Bucket(bucketLists)
+
+		data := lists.Get([]byte(id))
+		if data == nil {
+			return nil // Already deleted
+		}
+
+		var l List
+		if err := json.Unmarshal(data, &l); err != nil {
+			return err
+		}
+
+		if err := tx.Bucket(bucketAddresses).Delete([]byte(l.Address)); err != nil {
+			return err
+		}
+
+		// Remove expansion records for this list
+		expansions := tx.Bucket(bucketExpansions)
+		prefix := []byte(id + ":")
+		c := expansions.Cursor()
+		var keysToDelete [][]byte
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			keysToDelete = append(keysToDelete, k)
+		}
+		for _, k := range keysToDelete {
+			if err := expansions.Delete(k); err != nil {
+				return err
+			}
+		}
+
+		return lists.Delete([]byte(id))
+	})
+}
+
+// SaveExpansion stores the per-member messages created for a list message
+func (s *Storage) SaveExpansion(ctx context.Context, exp *Expansion) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		data, err := json.Marshal(exp)
+		if err != nil {
+			return fmt.Errorf("failed to marshal expansion: %w", err)
+		}
+		return tx.Bucket(bucketExpansions).Put(expansionKey(exp.ListID, exp.MessageID), data)
+	})
+}
+
+// GetExpansion retrieves the expansion of a message for a list
+func (s *Storage) GetExpansion(ctx context.Context, listID, messageID string) (*Expansion, error) {
+	var exp *Expansion
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bucketExpansions).Get(expansionKey(listID, messageID))
+		if data == nil {
+			return nil
+		}
+		exp = &Expansion{}
+		return json.Unmarshal(data, exp)
+	})
+
+	return exp, err
+}
+
+func expansionKey(listID, messageID string) []byte {
+	return []byte(listID + ":" + messageID)
+}
+
+// normalizeList normalizes list addresses and deduplicates members
+func normalizeList(l *List) {
+	l.Address = NormalizeAddress(l.Address)
+	if l.SenderPolicy == "" {
+		l.SenderPolicy = SenderPolicyAnyone
+	}
+
+	seen := make(map[string]bool)
+	members := make([]string, 0, len(l.Members))
+	for _, m := range l.Members {
+		m = NormalizeAddress(m)
+		if m == "" || m == l.Address || seen[m] {
+			continue
+		}
+		seen[m] = true
+		members = append(members, m)
+	}
+	l.Members = members
+}
@@ -0,0 +1,118 @@
+package maillist
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func setupTestStorage(t *testing.T) *Storage {
+	t.Helper()
+
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	storage, err := NewStorage(db)
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+	return storage
+}
+
+func TestStorage_CRUD(t *testing.T) {
+	storage := setupTestStorage(t)
+	ctx := context.Background()
+
+	l := &List{
+		Address: "Team@Example.com",
+		Members: []string{"a@example.com", "A@example.com", "team@example.com", "b@example.com"},
+		Enabled: true,
+	}
+	if err := storage.Create(ctx, l); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	if l.Address != "team@example.com" {
+		t.Errorf("expected normalized address, got %q", l.Address)
+	}
+	if len(l.Members) != 2 {
+		t.Errorf("expected duplicates and self-reference removed, got %v", l.Members)
+	}
+	if l.SenderPolicy != SenderPolicyAnyone {
+		t.Errorf("expected default sender policy, got %q", l.SenderPolicy)
+	}
+
+	if err := storage.Create(ctx, &List{Address: "team@example.com"}); err == nil {
+		t.Error("expected error for duplicate address")
+	}
+
+	got, err := storage.GetByAddress(ctx, "TEAM@example.com")
+	if err != nil || got == nil || got.ID != l.ID {
+		t.Fatalf("GetByAddress() = %v, %v", got, err)
+	}
+
+	l.Address = "staff@example.com"
+	if err := storage.Update(ctx, l); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if got, _ := storage.GetByAddress(ctx, "team@example.com"); got != nil {
+		t.Error("old address index should be removed")
+	}
+
+	lists, err := storage.List(ctx, ListFilter{Domain: "example.com"})
+	if err != nil || len(lists) != 1 {
+		t.Fatalf("List() = %d items, %v", len(lists), err)
+	}
+	lists, _ = storage.List(ctx, ListFilter{Domain: "other.com"})
+	if len(lists) != 0 {
+		t.Errorf("expected no lists for other.com, got %d", len(lists))
+	}
+
+	exp := &Expansion{MessageID: "msg-1", ListID: l.ID, Members: map[string]string{"a@example.com": "child-1"}}
+	if err := storage.SaveExpansion(ctx, exp); err != nil {
+		t.Fatalf("SaveExpansion() error = %v", err)
+	}
+
+	if err := storage.Delete(ctx, l.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if got, _ := storage.Get(ctx, l.ID); got != nil {
+		t.Error("list should be deleted")
+	}
+	if got, _ := storage.GetExpansion(ctx, l.ID, "msg-1"); got != nil {
+		t.Error("expansion records should be deleted")
+	}
+}
+
+func TestList_CanPost(t *testing.T) {
+	l := &List{
+		Members:        []string{"member@example.com"},
+		AllowedSenders: []string{"boss@partner.com", "@corp.example.com"},
+	}
+
+	tests := []struct {
+		policy string
+		sender string
+		want   bool
+	}{
+		{SenderPolicyAnyone, "stranger@remote.com", true},
+		{SenderPolicyMembers, "member@example.com", true},
+		{SenderPolicyMembers, "Boss@Partner.com", true},
+		{SenderPolicyMembers, "stranger@remote.com", false},
+		{SenderPolicyAllowed, "member@example.com", false},
+		{SenderPolicyAllowed, "anyone@corp.example.com", true},
+		{SenderPolicyAllowed, "boss@partner.com", true},
+	}
+
+	for _, tt := range tests {
+		l.SenderPolicy = tt.policy
+		if got := l.CanPost(tt.sender); got != tt.want {
+			t.Errorf("CanPost(%q) with policy %q = %v, want %v", tt.sender, tt.policy, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package message
+
+import (
+	"bytes"
+	"encoding/base64"
+	"mime/quotedprintable"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// decodeBody removes a transfer encoding. Invalid base64 is decoded up to
+// the first invalid character, and a body that cannot be decoded at all is
+// kept as it is.
+func decodeBody(encoding string, body []byte) []byte {
+	switch encoding {
+	case "base64":
+		clean := make([]byte, 0, len(body))
+		for _, c := range body {
+			if isBase64(c) {
+				clean = append(clean, c)
+			}
+		}
+		// Padding is optional, a trailing partial quantum of a truncated
+		// body is dropped
+		clean = bytes.TrimRight(clean, "=")
+		if len(clean)%4 == 1 {
+			clean = clean[:len(clean)-1]
+		}
+		out := make([]byte, base64.RawStdEncoding.DecodedLen(len(clean)))
+		n, err := base64.RawStdEncoding.Decode(out, clean)
+		if err != nil && n == 0 {
+			return body
+		}
+		return out[:n]
+	case "quoted-printable":
+		return decodeQuotedPrintable(body)
+	}
+	return body
+}
+
+// decodeQuotedPrintable decodes a quoted-printable body. Unlike
+// mime/quotedprintable it never fails: an equals sign that does not start
+// an escape is kept as it is.
+func decodeQuotedPrintable(body []byte) []byte {
+	out := make([]byte, 0, len(body))
+	for len(body) > 0 {
+		line, rest := cutLine(body)
+		body = rest
+
+		content := bytes.TrimRight(line, "\r\n")
+		lineBreak := line[len(content):]
+
+		// Trailing whitespace is transport padding
+		content = bytes.TrimRight(content, " \t")
+		soft := bytes.HasSuffix(content, []byte("="))
+		if soft {
+			content = content[:len(content)-1]
+		}
+
+		for i := 0; i < len(content); i++ {
+			c := content[i]
+			if c == '=' && i+2 < len(content) && isHex(content[i+1]) && isHex(content[i+2]) {
+				out = append(out, unhex(content[i+1])<<4|unhex(content[i+2]))
+				i += 2
+				continue
+			}
+			out = append(out, c)
+		}
+		if !soft {
+			out = append(out, lineBreak...)
+		}
+	}
+	return out
+}
+
+func isHex(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'A' && c <= 'F' || c >= 'a' && c <= 'f'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case c >= 'a':
+		return c - 'a' + 10
+	case c >= 'A':
+		return c - 'A' + 10
+	}
+	return c - '0'
+}
+
+func isBase64(c byte) bool {
+	return c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '+' || c == '/' || c == '='
+}
+
+// encodeBody applies a transfer encoding for writing. Line breaks of bodies
+// without a binary-safe encoding are normalized to CRLF.
+func encodeBody(encoding string, body []byte) []byte {
+	switch encoding {
+	case "base64":
+		encoded := base64.StdEncoding.EncodeToString(body)
+		var b bytes.Buffer
+		for len(encoded) > 76 {
+			b.WriteString(encoded[:76])
+			b.WriteString("\r\n")
+			encoded = encoded[76:]
+		}
+		b.WriteString(encoded)
+		return b.Bytes()
+	case "quoted-printable":
+		var b bytes.Buffer
+		w := quotedprintable.NewWriter(&b)
+		w.Write(body)
+		w.Close()
+		return b.Bytes()
+	case "binary":
+		return body
+	}
+	return toCRLF(body)
+}
+
+// toCRLF converts bare LF line breaks to CRLF
+func toCRLF(data []byte) []byte {
+	if !bytes.Contains(data, []byte("\n")) {
+		return data
+	}
+	var b bytes.Buffer
+	b.Grow(len(data) + bytes.Count(data, []byte("\n")))
+	for i, c := range data {
+		if c == '\n' && (i == 0 || data[i-1] != '\r') {
+			b.WriteByte('\r')
+		}
+		b.WriteByte(c)
+	}
+	return b.Bytes()
+}
+
+// decodeCharset converts text in a charset to UTF-8. Unknown charsets and
+// undecodable text are returned as they are.
+func decodeCharset(charset string, data []byte) string {
+	switch strings.ToLower(strings.TrimSpace(charset)) {
+	case "", "utf-8", "utf8", "us-ascii", "ascii":
+		return string(data)
+	}
+	if isASCII(data) {
+		return string(data)
+	}
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return string(data)
+	}
+	out, err := enc.NewDecoder().Bytes(data)
+	if err != nil {
+		return string(data)
+	}
+	return string(out)
+}
+
+func isASCII(data []byte) bool {
+	for _, c := range data {
+		if c >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,203 @@
+package message
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"strings"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// Field is a header field. Value is unfolded but otherwise as on the wire,
+// encoded words included.
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Header is an ordered list of header fields. Names are kept as written and
+// matched case-insensitively.
+type Header []Field
+
+// Get returns the value of the first field with the given name
+func (h Header) Get(name string) string {
+	for _, f := range h {
+		if strings.EqualFold(f.Name, name) {
+			return f.Value
+		}
+	}
+	return ""
+}
+
+// Values returns the values of all fields with the given name
+func (h Header) Values(name string) []string {
+	var values []string
+	for _, f := range h {
+		if strings.EqualFold(f.Name, name) {
+			values = append(values, f.Value)
+		}
+	}
+	return values
+}
+
+// Has reports whether a field with the given name is present
+func (h Header) Has(name string) bool {
+	for _, f := range h {
+		if strings.EqualFold(f.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Decoded returns the value of the first field with the given name with
+// RFC 2047 encoded words decoded to UTF-8
+func (h Header) Decoded(name string) string {
+	return DecodeWords(h.Get(name))
+}
+
+// Add appends a field
+func (h *Header) Add(name, value string) {
+	*h = append(*h, Field{Name: name, Value: value})
+}
+
+// Set replaces the first field with the given name and removes the others,
+// or appends the field if there is none
+func (h *Header) Set(name, value string) {
+	out := (*h)[:0]
+	set := false
+	for _, f := range *h {
+		if !strings.EqualFold(f.Name, name) {
+			out = append(out, f)
+			continue
+		}
+		if !set {
+			out = append(out, Field{Name: f.Name, Value: value})
+			set = true
+		}
+	}
+	if !set {
+		out = append(out, Field{Name: name, Value: value})
+	}
+	*h = out
+}
+
+// Del removes all fields with the given name
+func (h *Header) Del(name string) {
+	out := (*h)[:0]
+	for _, f := range *h {
+		if !strings.EqualFold(f.Name, name) {
+			out = append(out, f)
+		}
+	}
+	*h = out
+}
+
+// wordDecoder decodes encoded words in any charset known to browsers
+var wordDecoder = &mime.WordDecoder{
+	CharsetReader: func(charset string, input io.Reader) (io.Reader, error) {
+		enc, err := htmlindex.Get(charset)
+		if err != nil {
+			return nil, err
+		}
+		return enc.NewDecoder().Reader(input), nil
+	},
+}
+
+// DecodeWords decodes RFC 2047 encoded words of a header value. Values that
+// cannot be decoded are returned as they are.
+func DecodeWords(v string) string {
+	if s, err := wordDecoder.DecodeHeader(v); err == nil {
+		return s
+	}
+	return v
+}
+
+// parseHeader parses the header section at the start of data and returns it
+// with the rest of data. Parsing stops at the empty line separating the
+// body, or at the first line that is neither a field nor a continuation,
+// which then starts the body.
+func parseHeader(data []byte) (Header, []byte) {
+	var h Header
+	rest := data
+	for len(rest) > 0 {
+		line, next := cutLine(rest)
+		trimmed := bytes.TrimRight(line, "\r\n")
+
+		if len(trimmed) == 0 {
+			return h, next
+		}
+
+		// Continuation of a folded field
+		if trimmed[0] == ' ' || trimmed[0] == '\t' {
+			if len(h) > 0 {
+				h[len(h)-1].Value += string(trimmed)
+				rest = next
+				continue
+			}
+			return h, rest
+		}
+
+		colon := bytes.IndexByte(trimmed, ':')
+		if colon <= 0 || bytes.ContainsAny(trimmed[:colon], " \t") {
+			return h, rest
+		}
+		h = append(h, Field{
+			Name:  string(trimmed[:colon]),
+			Value: strings.TrimLeft(string(trimmed[colon+1:]), " \t"),
+		})
+		rest = next
+	}
+	return h, nil
+}
+
+// cutLine returns the first line of data including its line break, and the
+// data after it
+func cutLine(data []byte) (line, rest []byte) {
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		return data[:i+1], data[i+1:]
+	}
+	return data, nil
+}
+
+// maxLineLength is the length header lines are folded at where possible
+const maxLineLength = 78
+
+// writeHeader writes header fields, folding long values at whitespace
+func writeHeader(w *bytes.Buffer, h Header) {
+	for _, f := range h {
+		line := f.Name + ": " + f.Value
+		// A field is never folded before its value
+		start := len(f.Name) + 2
+		for len(line) > maxLineLength {
+			i := foldPoint(line, start)
+			if i < 0 {
+				break
+			}
+			w.WriteString(line[:i])
+			w.WriteString("\r\n")
+			line = line[i:]
+			start = 1
+		}
+		w.WriteString(line)
+		w.WriteString("\r\n")
+	}
+}
+
+// foldPoint returns the index of the last whitespace after start a line
+// can be folded at within maxLineLength, of the first one after it for long
+// words, or -1 if the line cannot be folded
+func foldPoint(line string, start int) int {
+	if i := strings.LastIndexAny(line[:maxLineLength], " \t"); i > start {
+		return i
+	}
+	from := max(start+1, maxLineLength)
+	if from >= len(line) {
+		return -1
+	}
+	if i := strings.IndexAny(line[from:], " \t"); i >= 0 {
+		return from + i
+	}
+	return -1
+}
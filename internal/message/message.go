@@ -0,0 +1,295 @@
+// Package message parses MIME messages into a normalized model, a header
+// and a tree of parts with decoded bodies, and writes the model back in
+// wire format. Parsing is lenient: malformed structure degrades to opaque
+// parts rather than failing, so any message that reached the queue can be
+// inspected and changed.
+package message
+
+import (
+	"bytes"
+	"errors"
+	"mime"
+	"path"
+	"strings"
+)
+
+// maxDepth bounds the nesting of multipart and message/rfc822 parts that
+// are parsed, deeper parts are kept as opaque bodies
+const maxDepth = 32
+
+// ErrNoHeader is returned for data that does not start with a header field
+var ErrNoHeader = errors.New("message has no header")
+
+// Part is a MIME entity: a leaf with a decoded body, a multipart with child
+// parts or an attached message
+type Part struct {
+	Header Header
+
+	// Media type in lower case and its parameters, from Content-Type or the
+	// default of the context (text/plain, message/rfc822 in a digest)
+	MediaType string
+	Params    map[string]string
+
+	// Content of a leaf part with the transfer encoding removed, in the
+	// charset of the part. Multiparts and parsed attached messages have none.
+	Body []byte
+
+	// Children of a multipart and the text around them
+	Parts    []*Part
+	Preamble []byte
+	Epilogue []byte
+
+	// Attached message of a message/rfc822 part
+	Message *Message
+}
+
+// Message is a parsed message, the root part whose header holds the
+// message header
+type Message struct {
+	Part
+}
+
+// Parse parses a message. It fails only for data without a header; broken
+// structure, encodings and charsets further down are tolerated.
+func Parse(data []byte) (*Message, error) {
+	h, body := parseHeader(data)
+	if len(h) == 0 {
+		return nil, ErrNoHeader
+	}
+	m := &Message{}
+	m.Part = *parsePart(h, body, "text/plain", 0)
+	return m, nil
+}
+
+// parsePart parses an entity of the given header and raw body
+func parsePart(h Header, body []byte, defaultType string, depth int) *Part {
+	p := &Part{Header: h}
+
+	mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil || !strings.Contains(mediaType, "/") {
+		mediaType, params = defaultType, map[string]string{}
+	}
+	p.MediaType, p.Params = mediaType, params
+
+	switch {
+	case depth >= maxDepth:
+		p.Body = decodeBody(p.Encoding(), body)
+	case strings.HasPrefix(mediaType, "multipart/") && params["boundary"] != "":
+		childType := "text/plain"
+		if mediaType == "multipart/digest" {
+			childType = "message/rfc822"
+		}
+		preamble, parts, epilogue := splitMultipart(body, params["boundary"])
+		p.Preamble, p.Epilogue = preamble, epilogue
+		for _, raw := range parts {
+			ch, cb := parseHeader(raw)
+			p.Parts = append(p.Parts, parsePart(ch, cb, childType, depth+1))
+		}
+	case mediaType == "message/rfc822" && !p.encoded():
+		if ch, cb := parseHeader(body); len(ch) > 0 {
+			p.Message = &Message{Part: *parsePart(ch, cb, "text/plain", depth+1)}
+		} else {
+			p.Body = body
+		}
+	default:
+		p.Body = decodeBody(p.Encoding(), body)
+	}
+	return p
+}
+
+// Encoding returns the Content-Transfer-Encoding in lower case, 7bit if
+// there is none
+func (p *Part) Encoding() string {
+	if enc := strings.ToLower(strings.TrimSpace(p.Header.Get("Content-Transfer-Encoding"))); enc != "" {
+		return enc
+	}
+	return "7bit"
+}
+
+// encoded reports whether the body has a transfer encoding to remove
+func (p *Part) encoded() bool {
+	enc := p.Encoding()
+	return enc == "base64" || enc == "quoted-printable"
+}
+
+// IsMultipart reports whether the part has child parts
+func (p *Part) IsMultipart() bool {
+	return strings.HasPrefix(p.MediaType, "multipart/") && p.Params["boundary"] != ""
+}
+
+// Disposition returns the Content-Disposition in lower case and its
+// parameters, empty if there is none
+func (p *Part) Disposition() (string, map[string]string) {
+	v := p.Header.Get("Content-Disposition")
+	if v == "" {
+		return "", nil
+	}
+	disposition, params, err := mime.ParseMediaType(v)
+	if err != nil {
+		// Keep the disposition of values with broken parameters
+		disposition, _, _ = strings.Cut(v, ";")
+		return strings.ToLower(strings.TrimSpace(disposition)), nil
+	}
+	return disposition, params
+}
+
+// Filename returns the file name of the part from Content-Disposition or
+// the name parameter of Content-Type, without directories
+func (p *Part) Filename() string {
+	_, params := p.Disposition()
+	name := params["filename"]
+	if name == "" {
+		name = p.Params["name"]
+	}
+	if name == "" {
+		return ""
+	}
+	name = DecodeWords(name)
+	// Drop directories of either path style
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" {
+		return ""
+	}
+	return name
+}
+
+// IsAttachment reports whether the part is an attachment rather than
+// message text: marked as one, named, or an attached message
+func (p *Part) IsAttachment() bool {
+	if p.IsMultipart() {
+		return false
+	}
+	if disposition, _ := p.Disposition(); disposition == "attachment" {
+		return true
+	}
+	if p.Message != nil || p.MediaType == "message/rfc822" {
+		return true
+	}
+	return p.Filename() != ""
+}
+
+// Text returns the body of a leaf part converted to UTF-8. Bodies in
+// unknown charsets are returned as they are.
+func (p *Part) Text() string {
+	return decodeCharset(p.Params["charset"], p.Body)
+}
+
+// Walk calls fn for the part and all parts below it in depth-first order,
+// including the parts of attached messages. It stops at the first error
+// and returns it.
+func (p *Part) Walk(fn func(*Part) error) error {
+	if err := fn(p); err != nil {
+		return err
+	}
+	for _, child := range p.Parts {
+		if err := child.Walk(fn); err != nil {
+			return err
+		}
+	}
+	if p.Message != nil {
+		return p.Message.Walk(fn)
+	}
+	return nil
+}
+
+// TextBody returns the text/plain parts of the message that are not
+// attachments, converted to UTF-8 and joined by line breaks
+func (m *Message) TextBody() string {
+	return m.body("text/plain")
+}
+
+// HTMLBody returns the text/html parts of the message that are not
+// attachments, converted to UTF-8 and joined by line breaks
+func (m *Message) HTMLBody() string {
+	return m.body("text/html")
+}
+
+// body joins the bodies of non-attachment parts of a media type. Attached
+// messages are not searched.
+func (m *Message) body(mediaType string) string {
+	var b strings.Builder
+	var collect func(p *Part)
+	collect = func(p *Part) {
+		for _, child := range p.Parts {
+			collect(child)
+		}
+		if p.IsMultipart() || p.MediaType != mediaType || p.IsAttachment() {
+			return
+		}
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(p.Text())
+	}
+	collect(&m.Part)
+	return b.String()
+}
+
+// Attachments returns the attachments of the message, including those of
+// attached messages after the attached message itself
+func (m *Message) Attachments() []*Part {
+	var parts []*Part
+	m.Walk(func(p *Part) error {
+		if p != &m.Part && p.IsAttachment() {
+			parts = append(parts, p)
+		}
+		return nil
+	})
+	return parts
+}
+
+// splitMultipart splits a multipart body at its boundary delimiter lines.
+// A missing close delimiter ends the last part at the end of the body.
+func splitMultipart(body []byte, boundary string) (preamble []byte, parts [][]byte, epilogue []byte) {
+	delimiter := []byte("--" + boundary)
+
+	start := -1 // Start of the current part, -1 in the preamble
+	rest := body
+	offset := 0
+	for len(rest) > 0 {
+		line, next := cutLine(rest)
+		lineStart := offset
+		offset += len(line)
+		rest = next
+
+		if !bytes.HasPrefix(line, delimiter) {
+			continue
+		}
+		tail := bytes.TrimRight(line[len(delimiter):], " \t\r\n")
+		closing := bytes.Equal(tail, []byte("--"))
+		if len(tail) > 0 && !closing {
+			// A longer boundary that starts with this one
+			continue
+		}
+
+		// The line break before a delimiter belongs to the delimiter
+		end := trimLineBreak(body, lineStart)
+		if start < 0 {
+			preamble = body[:end]
+		} else {
+			parts = append(parts, body[start:end])
+		}
+		if closing {
+			return preamble, parts, body[offset:]
+		}
+		start = offset
+	}
+
+	if start < 0 {
+		// No delimiter at all, keep the body as preamble
+		return body, nil, nil
+	}
+	parts = append(parts, body[start:])
+	return preamble, parts, nil
+}
+
+// trimLineBreak returns end moved before the line break that ends at it
+func trimLineBreak(data []byte, end int) int {
+	if end > 0 && data[end-1] == '\n' {
+		end--
+		if end > 0 && data[end-1] == '\r' {
+			end--
+		}
+	}
+	return end
+}
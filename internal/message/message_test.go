@@ -0,0 +1,256 @@
+package message
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const testMessage = "From: Billing <billing@example.com>\r\n" +
+	"To: user@example.net\r\n" +
+	"Subject: =?UTF-8?Q?Invoice_f=C3=BCr_March?=\r\n" +
+	"Message-ID: <inv-1@example.com>\r\n" +
+	"X-Long: first\r\n second\r\n" +
+	"Content-Type: multipart/mixed; boundary=\"b1\"\r\n" +
+	"\r\n" +
+	"This is a multi-part message\r\n" +
+	"--b1\r\n" +
+	"Content-Type: multipart/alternative; boundary=b2\r\n" +
+	"\r\n" +
+	"--b2\r\n" +
+	"Content-Type: text/plain; charset=iso-8859-1\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"Total f=FCr March: 42 EUR, due =\r\nsoon.\r\n" +
+	"--b2\r\n" +
+	"Content-Type: text/html; charset=utf-8\r\n" +
+	"\r\n" +
+	"<p>Your invoice</p>\r\n" +
+	"--b2--\r\n" +
+	"--b1\r\n" +
+	"Content-Type: application/pdf; name=\"ignored.pdf\"\r\n" +
+	"Content-Disposition: attachment; filename*=UTF-8''Rechnung%20M%C3%A4rz.pdf\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"JVBERi0xLjQK\r\n" +
+	"--b1\r\n" +
+	"Content-Type: message/rfc822\r\n" +
+	"\r\n" +
+	"From: other@example.org\r\n" +
+	"Subject: Forwarded\r\n" +
+	"\r\n" +
+	"Forwarded text\r\n" +
+	"--b1--\r\n"
+
+func TestParse(t *testing.T) {
+	m, err := Parse([]byte(testMessage))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if got := m.Header.Decoded("Subject"); got != "Invoice für March" {
+		t.Errorf("Subject = %q", got)
+	}
+	if got := m.Header.Get("x-long"); got != "first second" {
+		t.Errorf("folded field = %q", got)
+	}
+	if m.Header[0].Name != "From" || m.Header[len(m.Header)-1].Name != "Content-Type" {
+		t.Errorf("header order = %v", m.Header)
+	}
+	if m.MediaType != "multipart/mixed" || len(m.Parts) != 3 {
+		t.Fatalf("root = %s with %d parts", m.MediaType, len(m.Parts))
+	}
+	if string(m.Preamble) != "This is a multi-part message" {
+		t.Errorf("Preamble = %q", m.Preamble)
+	}
+
+	if got := m.TextBody(); got != "Total für March: 42 EUR, due soon." {
+		t.Errorf("TextBody() = %q", got)
+	}
+	if got := m.HTMLBody(); got != "<p>Your invoice</p>" {
+		t.Errorf("HTMLBody() = %q", got)
+	}
+
+	attachments := m.Attachments()
+	if len(attachments) != 2 {
+		t.Fatalf("Attachments() = %d parts", len(attachments))
+	}
+	pdf := attachments[0]
+	if pdf.Filename() != "Rechnung März.pdf" || string(pdf.Body) != "%PDF-1.4\n" {
+		t.Errorf("attachment %q = %q", pdf.Filename(), pdf.Body)
+	}
+	forwarded := attachments[1]
+	if forwarded.Message == nil || forwarded.Message.Header.Get("Subject") != "Forwarded" {
+		t.Fatalf("attached message = %+v", forwarded)
+	}
+	if got := forwarded.Message.TextBody(); got != "Forwarded text" {
+		t.Errorf("attached message text = %q", got)
+	}
+	if strings.Contains(m.TextBody(), "Forwarded") {
+		t.Error("TextBody() includes the attached message")
+	}
+
+	var types []string
+	m.Walk(func(p *Part) error {
+		types = append(types, p.MediaType)
+		return nil
+	})
+	want := "multipart/mixed multipart/alternative text/plain text/html application/pdf message/rfc822 text/plain"
+	if got := strings.Join(types, " "); got != want {
+		t.Errorf("Walk() = %s", got)
+	}
+}
+
+func TestParseMalformed(t *testing.T) {
+	t.Run("no header", func(t *testing.T) {
+		if _, err := Parse([]byte("just some text\r\n")); !errors.Is(err, ErrNoHeader) {
+			t.Errorf("Parse() error = %v, want ErrNoHeader", err)
+		}
+	})
+
+	t.Run("bare line feeds", func(t *testing.T) {
+		m, err := Parse([]byte("Subject: hi\nContent-Type: multipart/alternative; boundary=x\n\n--x\nContent-Type: text/plain\n\nhello\n--x--\n"))
+		if err != nil {
+			t.Fatalf("Parse() error = %v", err)
+		}
+		if got := m.TextBody(); got != "hello" {
+			t.Errorf("TextBody() = %q", got)
+		}
+	})
+
+	t.Run("missing close delimiter", func(t *testing.T) {
+		m, err := Parse([]byte("Content-Type: multipart/mixed; boundary=x\r\n\r\n--x\r\n\r\none\r\n--x\r\n\r\ntwo\r\n"))
+		if err != nil {
+			t.Fatalf("Parse() error = %v", err)
+		}
+		if len(m.Parts) != 2 || string(m.Parts[1].Body) != "two\r\n" {
+			t.Errorf("parts = %d, last = %q", len(m.Parts), m.Parts[len(m.Parts)-1].Body)
+		}
+	})
+
+	t.Run("broken encodings", func(t *testing.T) {
+		data := "Content-Type: multipart/mixed; boundary=x\r\n\r\n" +
+			"--x\r\nContent-Transfer-Encoding: base64\r\n\r\naGVsbG8gd29y\r\nbGQ\r\n" +
+			"--x\r\nContent-Transfer-Encoding: quoted-printable\r\n\r\n100=% =ZZ\r\n" +
+			"--x\r\nContent-Type: text/plain; charset=no-such-charset\r\n\r\nraw\r\n" +
+			"--x\r\nContent-Type: invalid\r\n\r\ndefault type\r\n" +
+			"--x--\r\n"
+		m, err := Parse([]byte(data))
+		if err != nil {
+			t.Fatalf("Parse() error = %v", err)
+		}
+		if got := string(m.Parts[0].Body); got != "hello world" {
+			t.Errorf("unpadded base64 = %q", got)
+		}
+		if got := string(m.Parts[1].Body); got != "100=% =ZZ" {
+			t.Errorf("invalid quoted-printable = %q", got)
+		}
+		if got := m.Parts[2].Text(); got != "raw" {
+			t.Errorf("unknown charset = %q", got)
+		}
+		if m.Parts[3].MediaType != "text/plain" {
+			t.Errorf("invalid content type = %q", m.Parts[3].MediaType)
+		}
+	})
+}
+
+func TestCharsets(t *testing.T) {
+	// "Привет" in windows-1251
+	data := "Subject: =?windows-1251?B?z/Do4uXy?=\r\n" +
+		"Content-Type: text/plain; charset=windows-1251\r\n" +
+		"Content-Transfer-Encoding: base64\r\n\r\n" +
+		"z/Do4uXy\r\n"
+	m, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if got := m.Header.Decoded("Subject"); got != "Привет" {
+		t.Errorf("Subject = %q", got)
+	}
+	if got := m.TextBody(); got != "Привет" {
+		t.Errorf("TextBody() = %q", got)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	m, err := Parse([]byte(testMessage))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	// Change the message the way a footer would
+	m.Parts[0].Parts[0].Body = append(m.Parts[0].Parts[0].Body, "\r\n-- \r\nFooter"...)
+	m.Header.Set("Subject", "Changed")
+	m.Header.Del("X-Long")
+
+	out, err := Parse(m.Bytes())
+	if err != nil {
+		t.Fatalf("Parse(Bytes()) error = %v", err)
+	}
+	if out.Header.Get("Subject") != "Changed" || out.Header.Has("X-Long") {
+		t.Errorf("header = %v", out.Header)
+	}
+	if got := out.TextBody(); got != "Total für March: 42 EUR, due soon.\r\n-- \r\nFooter" {
+		t.Errorf("TextBody() = %q", got)
+	}
+	if got := out.Attachments(); len(got) != 2 || string(got[0].Body) != "%PDF-1.4\n" || got[1].Message.TextBody() != "Forwarded text" {
+		t.Errorf("attachments after round trip = %v", got)
+	}
+
+	// Writing is stable once normalized
+	if again := out.Bytes(); !bytes.Equal(again, m.Bytes()) {
+		t.Errorf("second round trip differs:\n%s\n---\n%s", again, m.Bytes())
+	}
+}
+
+func TestBuildMultipart(t *testing.T) {
+	m, err := Parse([]byte("Subject: hi\r\nContent-Type: text/plain\r\n\r\nhello\r\n"))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	text := &Part{Header: Header{{Name: "Content-Type", Value: "text/plain"}}, MediaType: "text/plain", Body: m.Body}
+	attachment := &Part{
+		Header: Header{
+			{Name: "Content-Type", Value: "application/octet-stream"},
+			{Name: "Content-Disposition", Value: "attachment; filename=data.bin"},
+			{Name: "Content-Transfer-Encoding", Value: "base64"},
+		},
+		MediaType: "application/octet-stream",
+		Body:      bytes.Repeat([]byte{0, 1, 2, 255}, 100),
+	}
+	m.Body = nil
+	m.Parts = []*Part{text, attachment}
+	m.SetContentType("multipart/mixed", nil)
+
+	out, err := Parse(m.Bytes())
+	if err != nil {
+		t.Fatalf("Parse(Bytes()) error = %v", err)
+	}
+	if !out.IsMultipart() || len(out.Parts) != 2 {
+		t.Fatalf("built message = %s with %d parts", out.MediaType, len(out.Parts))
+	}
+	if got := out.TextBody(); got != "hello\r\n" {
+		t.Errorf("TextBody() = %q", got)
+	}
+	if got := out.Parts[1]; got.Filename() != "data.bin" || !bytes.Equal(got.Body, attachment.Body) {
+		t.Errorf("attachment %q = %d bytes", got.Filename(), len(got.Body))
+	}
+}
+
+func TestHeaderFolding(t *testing.T) {
+	h := Header{{Name: "To", Value: strings.Repeat("someone@example.com, ", 10) + "last@example.com"}}
+	var b bytes.Buffer
+	writeHeader(&b, h)
+
+	for _, line := range strings.Split(strings.TrimSuffix(b.String(), "\r\n"), "\r\n") {
+		if len(line) > maxLineLength {
+			t.Errorf("line of %d characters: %q", len(line), line)
+		}
+	}
+	parsed, _ := parseHeader(append(b.Bytes(), "\r\n"...))
+	if parsed.Get("To") != h[0].Value {
+		t.Errorf("unfolded = %q", parsed.Get("To"))
+	}
+}
@@ -0,0 +1,71 @@
+package message
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"mime"
+	"strings"
+)
+
+// Bytes returns the message in wire format with CRLF line breaks. Bodies
+// are encoded with the transfer encoding of their part.
+func (m *Message) Bytes() []byte {
+	var b bytes.Buffer
+	m.Part.write(&b)
+	return b.Bytes()
+}
+
+// SetContentType sets the media type and parameters of the part and its
+// Content-Type field
+func (p *Part) SetContentType(mediaType string, params map[string]string) {
+	if params == nil {
+		params = map[string]string{}
+	}
+	p.MediaType = strings.ToLower(mediaType)
+	p.Params = params
+	p.Header.Set("Content-Type", mime.FormatMediaType(p.MediaType, params))
+}
+
+// write writes the part in wire format
+func (p *Part) write(b *bytes.Buffer) {
+	// Multiparts built in code get a boundary when they are written
+	if strings.HasPrefix(p.MediaType, "multipart/") && p.Params["boundary"] == "" && len(p.Parts) > 0 {
+		params := make(map[string]string, len(p.Params)+1)
+		for k, v := range p.Params {
+			params[k] = v
+		}
+		params["boundary"] = newBoundary()
+		p.SetContentType(p.MediaType, params)
+	}
+
+	writeHeader(b, p.Header)
+	b.WriteString("\r\n")
+
+	switch {
+	case p.IsMultipart():
+		boundary := p.Params["boundary"]
+		if len(p.Preamble) > 0 {
+			b.Write(toCRLF(p.Preamble))
+			b.WriteString("\r\n")
+		}
+		for _, child := range p.Parts {
+			b.WriteString("--" + boundary + "\r\n")
+			child.write(b)
+			b.WriteString("\r\n")
+		}
+		b.WriteString("--" + boundary + "--\r\n")
+		b.Write(toCRLF(p.Epilogue))
+	case p.Message != nil:
+		p.Message.Part.write(b)
+	default:
+		b.Write(encodeBody(p.Encoding(), p.Body))
+	}
+}
+
+// newBoundary returns a random multipart boundary
+func newBoundary() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return "sendry-" + hex.EncodeToString(buf)
+}
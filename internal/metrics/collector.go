@@ -19,6 +19,7 @@ type QueueStats struct {
 	Delivered int64
 	Failed    int64
 	Total     int64
+	DLQ       int64 // Messages in the dead letter queue
 }
 
 // QueueStatsProvider provides queue statistics for metrics
@@ -269,6 +270,7 @@ func (c *Collector) collectSystemMetrics(ctx context.Context) {
 			c.metrics.QueueSize.Set(float64(stats.Pending + stats.Deferred))
 			c.metrics.QueueActive.Set(float64(stats.Sending))
 			c.metrics.QueueDeferred.Set(float64(stats.Deferred))
+			c.metrics.DLQSize.Set(float64(stats.DLQ))
 		}
 	}
 }
@@ -2,6 +2,7 @@ package metrics
 
 import (
 	"sync"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 )
@@ -40,6 +41,53 @@ type Metrics struct {
 	// Rate limiting
 	RateLimitExceededTotal *prometheus.CounterVec
 
+	// Templates
+	TemplateRenderDurationSeconds *prometheus.HistogramVec
+	TemplateCacheEntries          prometheus.Gauge
+
+	// MX selection
+	MXSelectedTotal *prometheus.CounterVec
+	MXSkippedTotal  prometheus.Counter
+	MXDeadHosts     prometheus.Gauge
+
+	// Outbound connection pool
+	SMTPConnectionsReusedTotal prometheus.Counter
+
+	// Outbound delivery by recipient provider
+	DeliveriesTotal           *prometheus.CounterVec
+	DeliveryDurationSeconds   *prometheus.HistogramVec
+	SMTPResponsesTotal        *prometheus.CounterVec
+	TLSHandshakeFailuresTotal *prometheus.CounterVec
+
+	// DKIM signing
+	DKIMSignFailuresTotal *prometheus.CounterVec
+
+	// Dead letter queue
+	DLQSize          prometheus.Gauge
+	DLQMessagesTotal *prometheus.CounterVec
+
+	// Inbound routing
+	InboundMessagesTotal *prometheus.CounterVec
+	InboundAuthTotal     *prometheus.CounterVec
+
+	// Content filter
+	ContentFilterTotal *prometheus.CounterVec
+
+	// Content policy
+	ContentPolicyTotal *prometheus.CounterVec
+
+	// Broker consumer
+	ConsumerMessagesTotal *prometheus.CounterVec
+
+	// Sending reputation
+	DomainReputationScore *prometheus.GaugeVec
+	DNSBLListings         *prometheus.GaugeVec
+
+	// Complaint feedback loop and bounces
+	FBLComplaintsTotal   *prometheus.CounterVec
+	BouncesTotal         *prometheus.CounterVec
+	SuppressedRecipients prometheus.Counter
+
 	// System metrics
 	UptimeSeconds     prometheus.Gauge
 	Goroutines        prometheus.Gauge
@@ -175,6 +223,187 @@ func New() *Metrics {
 			[]string{"level"},
 		),
 
+		// Templates
+		TemplateRenderDurationSeconds: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "sendry_template_render_duration_seconds",
+				Help:    "Template render duration in seconds",
+				Buckets: []float64{.00001, .00005, .0001, .00025, .0005, .001, .0025, .005, .01, .05},
+			},
+			[]string{"cache"},
+		),
+		TemplateCacheEntries: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "sendry_template_cache_entries",
+				Help: "Number of compiled templates in the render cache",
+			},
+		),
+
+		// MX selection
+		MXSelectedTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "sendry_mx_selected_total",
+				Help: "Total number of MX connections used for delivery by MX choice",
+			},
+			[]string{"choice"},
+		),
+		MXSkippedTotal: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "sendry_mx_skipped_total",
+				Help: "Total number of times a dead MX host was moved behind healthy ones",
+			},
+		),
+		MXDeadHosts: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "sendry_mx_dead_hosts",
+				Help: "Number of MX hosts currently marked dead",
+			},
+		),
+
+		// Outbound connection pool
+		SMTPConnectionsReusedTotal: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "sendry_smtp_connections_reused_total",
+				Help: "Total number of outbound deliveries over a reused MX session",
+			},
+		),
+
+		// Outbound delivery by recipient provider
+		DeliveriesTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "sendry_deliveries_total",
+				Help: "Total number of recipient delivery attempts by recipient provider and result",
+			},
+			[]string{"provider", "result"},
+		),
+		DeliveryDurationSeconds: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "sendry_delivery_duration_seconds",
+				Help:    "Duration of SMTP transactions with MX hosts by recipient provider",
+				Buckets: []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60, 120},
+			},
+			[]string{"provider"},
+		),
+		SMTPResponsesTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "sendry_smtp_responses_total",
+				Help: "Total number of final SMTP replies of MX hosts by recipient provider and reply code",
+			},
+			[]string{"provider", "code"},
+		),
+		TLSHandshakeFailuresTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "sendry_tls_handshake_failures_total",
+				Help: "Total number of failed STARTTLS handshakes with MX hosts by recipient provider",
+			},
+			[]string{"provider"},
+		),
+
+		// DKIM signing
+		DKIMSignFailuresTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "sendry_dkim_sign_failures_total",
+				Help: "Total number of messages that could not be DKIM signed by signing domain",
+			},
+			[]string{"domain"},
+		),
+
+		// Dead letter queue
+		DLQSize: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "sendry_dlq_size",
+				Help: "Number of messages in the dead letter queue",
+			},
+		),
+		DLQMessagesTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "sendry_dlq_messages_total",
+				Help: "Total number of failed messages moved to the dead letter queue by sender domain",
+			},
+			[]string{"domain"},
+		),
+
+		// Inbound routing
+		InboundMessagesTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "sendry_inbound_messages_total",
+				Help: "Total number of inbound deliveries by action and status",
+			},
+			[]string{"action", "status"},
+		),
+		InboundAuthTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "sendry_inbound_auth_total",
+				Help: "Total number of SPF, DKIM and DMARC results of inbound messages",
+			},
+			[]string{"method", "result"},
+		),
+
+		// Content filter
+		ContentFilterTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "sendry_content_filter_total",
+				Help: "Total number of content filter verdicts by message source and action",
+			},
+			[]string{"source", "action"},
+		),
+
+		// Content policy
+		ContentPolicyTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "sendry_content_policy_total",
+				Help: "Total number of content policy violations by message source, reason and action",
+			},
+			[]string{"source", "reason", "action"},
+		),
+
+		// Broker consumer
+		ConsumerMessagesTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "sendry_consumer_messages_total",
+				Help: "Total number of send requests pulled from a broker by source and result",
+			},
+			[]string{"source", "result"},
+		),
+
+		// Sending reputation
+		DomainReputationScore: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "sendry_domain_reputation_score",
+				Help: "Sending reputation score (0-100) of a sender domain",
+			},
+			[]string{"domain"},
+		),
+		DNSBLListings: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "sendry_dnsbl_listings",
+				Help: "Number of DNSBLs listing a monitored outbound IP",
+			},
+			[]string{"ip"},
+		),
+
+		// Complaint feedback loop
+		FBLComplaintsTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "sendry_fbl_complaints_total",
+				Help: "Total number of complaint reports by sender domain and feedback type",
+			},
+			[]string{"domain", "type"},
+		),
+		BouncesTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "sendry_bounces_total",
+				Help: "Total number of recipients reported by received bounces, by type (hard, soft) and attribution (verp, dsn)",
+			},
+			[]string{"type", "attribution"},
+		),
+		SuppressedRecipients: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "sendry_suppressed_recipients_total",
+				Help: "Total number of recipients skipped because they are on the suppression list",
+			},
+		),
+
 		// System metrics
 		UptimeSeconds: prometheus.NewGauge(
 			prometheus.GaugeOpts{
@@ -217,6 +446,29 @@ func New() *Metrics {
 		m.APIRequestDurationSeconds,
 		m.APIErrorsTotal,
 		m.RateLimitExceededTotal,
+		m.TemplateRenderDurationSeconds,
+		m.TemplateCacheEntries,
+		m.MXSelectedTotal,
+		m.MXSkippedTotal,
+		m.MXDeadHosts,
+		m.SMTPConnectionsReusedTotal,
+		m.DeliveriesTotal,
+		m.DeliveryDurationSeconds,
+		m.SMTPResponsesTotal,
+		m.TLSHandshakeFailuresTotal,
+		m.DKIMSignFailuresTotal,
+		m.DLQSize,
+		m.DLQMessagesTotal,
+		m.InboundMessagesTotal,
+		m.InboundAuthTotal,
+		m.ContentFilterTotal,
+		m.ContentPolicyTotal,
+		m.ConsumerMessagesTotal,
+		m.DomainReputationScore,
+		m.DNSBLListings,
+		m.FBLComplaintsTotal,
+		m.BouncesTotal,
+		m.SuppressedRecipients,
 		m.UptimeSeconds,
 		m.Goroutines,
 		m.StorageUsedBytes,
@@ -332,3 +584,207 @@ func IncAPIErrors(errorType string) {
 		m.APIErrorsTotal.WithLabelValues(errorType).Inc()
 	}
 }
+
+// Template cache results used as the cache label of render metrics
+const (
+	TemplateCacheHit  = "hit"
+	TemplateCacheMiss = "miss"
+	TemplateCacheOff  = "off"
+)
+
+// ObserveTemplateRender records the duration of a template render
+func ObserveTemplateRender(cache string, d time.Duration) {
+	m := Global()
+	if m != nil {
+		m.TemplateRenderDurationSeconds.WithLabelValues(cache).Observe(d.Seconds())
+	}
+}
+
+// SetTemplateCacheEntries sets the number of cached compiled templates
+func SetTemplateCacheEntries(n int) {
+	m := Global()
+	if m != nil {
+		m.TemplateCacheEntries.Set(float64(n))
+	}
+}
+
+// MX choices used as the choice label of MX selection metrics
+const (
+	MXPrimary  = "primary"  // Highest priority MX
+	MXFallback = "fallback" // Any lower priority MX
+)
+
+// IncMXSelected increments the MX selection counter
+func IncMXSelected(choice string) {
+	m := Global()
+	if m != nil {
+		m.MXSelectedTotal.WithLabelValues(choice).Inc()
+	}
+}
+
+// IncMXSkipped increments the counter of dead MX hosts moved behind healthy ones
+func IncMXSkipped() {
+	m := Global()
+	if m != nil {
+		m.MXSkippedTotal.Inc()
+	}
+}
+
+// IncSMTPConnectionsReused increments the counter of pooled MX sessions reused
+func IncSMTPConnectionsReused() {
+	m := Global()
+	if m != nil {
+		m.SMTPConnectionsReusedTotal.Inc()
+	}
+}
+
+// Delivery results used as the result label of delivery metrics
+const (
+	DeliveryDelivered = "delivered"
+	DeliveryDeferred  = "deferred"
+	DeliveryFailed    = "failed"
+)
+
+// IncDeliveries increments the recipient delivery counter
+func IncDeliveries(provider, result string) {
+	m := Global()
+	if m != nil {
+		m.DeliveriesTotal.WithLabelValues(provider, result).Inc()
+	}
+}
+
+// ObserveDelivery records the duration of an SMTP transaction with an MX host
+func ObserveDelivery(provider string, d time.Duration) {
+	m := Global()
+	if m != nil {
+		m.DeliveryDurationSeconds.WithLabelValues(provider).Observe(d.Seconds())
+	}
+}
+
+// IncSMTPResponses increments the counter of MX replies with a reply code
+func IncSMTPResponses(provider, code string) {
+	m := Global()
+	if m != nil {
+		m.SMTPResponsesTotal.WithLabelValues(provider, code).Inc()
+	}
+}
+
+// IncTLSHandshakeFailures increments the counter of failed STARTTLS handshakes
+func IncTLSHandshakeFailures(provider string) {
+	m := Global()
+	if m != nil {
+		m.TLSHandshakeFailuresTotal.WithLabelValues(provider).Inc()
+	}
+}
+
+// IncDKIMSignFailures increments the counter of failed DKIM signatures
+func IncDKIMSignFailures(domain string) {
+	m := Global()
+	if m != nil {
+		m.DKIMSignFailuresTotal.WithLabelValues(domain).Inc()
+	}
+}
+
+// IncDLQMessages increments the counter of messages moved to the DLQ
+func IncDLQMessages(domain string) {
+	m := Global()
+	if m != nil {
+		m.DLQMessagesTotal.WithLabelValues(domain).Inc()
+	}
+}
+
+// SetMXDeadHosts sets the number of MX hosts marked dead
+func SetMXDeadHosts(n int) {
+	m := Global()
+	if m != nil {
+		m.MXDeadHosts.Set(float64(n))
+	}
+}
+
+// IncInboundMessages increments the inbound delivery counter
+func IncInboundMessages(action, status string) {
+	m := Global()
+	if m != nil {
+		m.InboundMessagesTotal.WithLabelValues(action, status).Inc()
+	}
+}
+
+// IncInboundAuth increments the counter of inbound authentication results
+func IncInboundAuth(method, result string) {
+	m := Global()
+	if m != nil {
+		m.InboundAuthTotal.WithLabelValues(method, result).Inc()
+	}
+}
+
+// IncContentFilter increments the counter of content filter verdicts
+func IncContentFilter(source, action string) {
+	m := Global()
+	if m != nil {
+		m.ContentFilterTotal.WithLabelValues(source, action).Inc()
+	}
+}
+
+// IncContentPolicy increments the counter of content policy violations
+func IncContentPolicy(source, reason, action string) {
+	m := Global()
+	if m != nil {
+		m.ContentPolicyTotal.WithLabelValues(source, reason, action).Inc()
+	}
+}
+
+// IncConsumerMessages increments the broker consumer counter
+func IncConsumerMessages(source, result string) {
+	m := Global()
+	if m != nil {
+		m.ConsumerMessagesTotal.WithLabelValues(source, result).Inc()
+	}
+}
+
+// SetDNSBLListings sets the number of DNSBLs listing a monitored IP
+func SetDNSBLListings(ip string, listings int) {
+	m := Global()
+	if m != nil {
+		m.DNSBLListings.WithLabelValues(ip).Set(float64(listings))
+	}
+}
+
+// DeleteDNSBLListings removes the listings gauge of an IP that is no longer monitored
+func DeleteDNSBLListings(ip string) {
+	m := Global()
+	if m != nil {
+		m.DNSBLListings.DeleteLabelValues(ip)
+	}
+}
+
+// SetDomainReputation sets the reputation score of a sender domain
+func SetDomainReputation(domain string, score int) {
+	m := Global()
+	if m != nil {
+		m.DomainReputationScore.WithLabelValues(domain).Set(float64(score))
+	}
+}
+
+// IncFBLComplaints increments the complaint report counter
+func IncFBLComplaints(domain, feedbackType string) {
+	m := Global()
+	if m != nil {
+		m.FBLComplaintsTotal.WithLabelValues(domain, feedbackType).Inc()
+	}
+}
+
+// IncBounces increments the received bounce counter
+func IncBounces(bounceType, attribution string) {
+	m := Global()
+	if m != nil {
+		m.BouncesTotal.WithLabelValues(bounceType, attribution).Inc()
+	}
+}
+
+// IncSuppressedRecipients increments the suppressed recipient counter
+func IncSuppressedRecipients() {
+	m := Global()
+	if m != nil {
+		m.SuppressedRecipients.Inc()
+	}
+}
@@ -2,6 +2,7 @@ package metrics
 
 import (
 	"testing"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	dto "github.com/prometheus/client_model/go"
@@ -237,4 +238,56 @@ func TestGlobalNilSafe(t *testing.T) {
 	IncSMTPTLS()
 	IncRateLimitExceeded("global")
 	IncAPIErrors("server_error")
+	ObserveTemplateRender(TemplateCacheHit, time.Millisecond)
+	SetTemplateCacheEntries(1)
+	IncMXSelected(MXPrimary)
+	IncMXSkipped()
+	SetMXDeadHosts(1)
+	IncInboundMessages("webhook", "delivered")
+	SetDomainReputation("example.com", 90)
+}
+
+func TestDeliveryMetrics(t *testing.T) {
+	m := New()
+	SetGlobal(m)
+	defer SetGlobal(nil)
+
+	IncDeliveries("google", DeliveryDelivered)
+	IncDeliveries("google", DeliveryDelivered)
+	IncDeliveries("google", DeliveryDeferred)
+	IncSMTPResponses("google", "250")
+	IncTLSHandshakeFailures("other")
+	IncDKIMSignFailures("example.com")
+	IncDLQMessages("example.com")
+	ObserveDelivery("google", 300*time.Millisecond)
+
+	counters := []struct {
+		counter *prometheus.CounterVec
+		labels  []string
+		want    float64
+	}{
+		{m.DeliveriesTotal, []string{"google", DeliveryDelivered}, 2},
+		{m.DeliveriesTotal, []string{"google", DeliveryDeferred}, 1},
+		{m.SMTPResponsesTotal, []string{"google", "250"}, 1},
+		{m.TLSHandshakeFailuresTotal, []string{"other"}, 1},
+		{m.DKIMSignFailuresTotal, []string{"example.com"}, 1},
+		{m.DLQMessagesTotal, []string{"example.com"}, 1},
+	}
+	for _, c := range counters {
+		var metric dto.Metric
+		if err := c.counter.WithLabelValues(c.labels...).Write(&metric); err != nil {
+			t.Fatalf("Failed to write metric: %v", err)
+		}
+		if metric.Counter.GetValue() != c.want {
+			t.Errorf("counter %v = %f, want %f", c.labels, metric.Counter.GetValue(), c.want)
+		}
+	}
+
+	var metric dto.Metric
+	if err := m.DeliveryDurationSeconds.WithLabelValues("google").(prometheus.Histogram).Write(&metric); err != nil {
+		t.Fatalf("Failed to write metric: %v", err)
+	}
+	if metric.Histogram.GetSampleCount() != 1 || metric.Histogram.GetSampleSum() != 0.3 {
+		t.Errorf("histogram = %d samples, sum %f", metric.Histogram.GetSampleCount(), metric.Histogram.GetSampleSum())
+	}
 }
@@ -0,0 +1,79 @@
+package metrics
+
+import "strings"
+
+// ProviderOther is the provider label of recipient domains of other providers
+const ProviderOther = "other"
+
+// providerMX maps MX host domains to mailbox providers. Domains hosted by a
+// provider, such as Google Workspace domains, are found by their MX hosts.
+var providerMX = map[string]string{
+	"google.com":          "google",
+	"googlemail.com":      "google",
+	"outlook.com":         "microsoft",
+	"yahoodns.net":        "yahoo",
+	"icloud.com":          "apple",
+	"yandex.net":          "yandex",
+	"yandex.ru":           "yandex",
+	"mail.ru":             "mailru",
+	"protonmail.ch":       "proton",
+	"zoho.com":            "zoho",
+	"zoho.eu":             "zoho",
+	"gmx.net":             "gmx",
+	"pphosted.com":        "proofpoint",
+	"mimecast.com":        "mimecast",
+	"messagingengine.com": "fastmail",
+}
+
+// providerDomains maps recipient domains to mailbox providers, used when the
+// MX host is not known
+var providerDomains = map[string]string{
+	"gmail.com":      "google",
+	"googlemail.com": "google",
+	"outlook.com":    "microsoft",
+	"hotmail.com":    "microsoft",
+	"live.com":       "microsoft",
+	"msn.com":        "microsoft",
+	"yahoo.com":      "yahoo",
+	"ymail.com":      "yahoo",
+	"aol.com":        "yahoo",
+	"icloud.com":     "apple",
+	"me.com":         "apple",
+	"mac.com":        "apple",
+	"yandex.ru":      "yandex",
+	"yandex.com":     "yandex",
+	"ya.ru":          "yandex",
+	"mail.ru":        "mailru",
+	"bk.ru":          "mailru",
+	"list.ru":        "mailru",
+	"inbox.ru":       "mailru",
+	"proton.me":      "proton",
+	"protonmail.com": "proton",
+	"zoho.com":       "zoho",
+	"gmx.com":        "gmx",
+	"gmx.net":        "gmx",
+	"gmx.de":         "gmx",
+}
+
+// Provider returns the mailbox provider of a recipient domain for the
+// provider label of delivery metrics: the provider of its MX host if mx is
+// known, else of the domain itself, else ProviderOther. The label keeps a
+// small set of values however many domains mail is sent to.
+func Provider(domain, mx string) string {
+	mx = strings.TrimSuffix(strings.ToLower(mx), ".")
+	for host := mx; host != ""; {
+		if p, ok := providerMX[host]; ok {
+			return p
+		}
+		_, parent, ok := strings.Cut(host, ".")
+		if !ok {
+			break
+		}
+		host = parent
+	}
+
+	if p, ok := providerDomains[strings.ToLower(domain)]; ok {
+		return p
+	}
+	return ProviderOther
+}
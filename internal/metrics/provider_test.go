@@ -0,0 +1,28 @@
+package metrics
+
+import "testing"
+
+func TestProvider(t *testing.T) {
+	tests := []struct {
+		domain string
+		mx     string
+		want   string
+	}{
+		{"gmail.com", "gmail-smtp-in.l.google.com", "google"},
+		{"example.com", "ASPMX.L.GOOGLE.COM.", "google"},
+		{"example.com", "example-com.mail.protection.outlook.com", "microsoft"},
+		{"yahoo.com", "mta5.am0.yahoodns.net", "yahoo"},
+		{"example.ru", "mx.yandex.net", "yandex"},
+		{"Hotmail.com", "", "microsoft"},
+		{"mail.ru", "mxs.mail.ru", "mailru"},
+		{"example.com", "mx.example.com", ProviderOther},
+		{"example.com", "", ProviderOther},
+		// MX hosts of unknown providers fall back to the domain
+		{"gmail.com", "mx.example.com", "google"},
+	}
+	for _, tt := range tests {
+		if got := Provider(tt.domain, tt.mx); got != tt.want {
+			t.Errorf("Provider(%q, %q) = %q, want %q", tt.domain, tt.mx, got, tt.want)
+		}
+	}
+}
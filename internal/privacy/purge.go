@@ -0,0 +1,147 @@
+package privacy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/foxzi/sendry/internal/archive"
+	"github.com/foxzi/sendry/internal/queue"
+	"github.com/foxzi/sendry/internal/sandbox"
+)
+
+// dlqPageSize is the number of DLQ messages read at once
+const dlqPageSize = 500
+
+// PurgeResult counts the messages deleted by a purge
+type PurgeResult struct {
+	Queue   int `json:"queue"`   // Queue messages, delivered and failed ones unless pending was set
+	DLQ     int `json:"dlq"`     // Dead letter queue messages
+	Sandbox int `json:"sandbox"` // Messages captured in sandbox mode
+	Archive int `json:"archive"` // Archived copies of delivered messages
+	Skipped int `json:"skipped"` // Queue messages waiting for or in delivery, kept
+	Total   int `json:"total"`   // Deleted messages
+}
+
+// Purger deletes the stored messages of an email address
+type Purger struct {
+	queue   queue.Storage
+	sandbox *sandbox.Storage
+	archive *archive.Storage
+}
+
+// NewPurger creates a purger of the queue and, when set, the sandbox and
+// the archive
+func NewPurger(q queue.Storage, sandboxStorage *sandbox.Storage, archiveStorage *archive.Storage) *Purger {
+	return &Purger{queue: q, sandbox: sandboxStorage, archive: archiveStorage}
+}
+
+// Purge deletes the messages sent from or to address. Queue messages that
+// wait for delivery are kept unless pending is set, as deleting them also
+// cancels their mail to other recipients; a message in delivery is always
+// kept.
+func (p *Purger) Purge(ctx context.Context, address string, pending bool) (*PurgeResult, error) {
+	address = strings.TrimSpace(address)
+	if !strings.Contains(address, "@") {
+		return nil, fmt.Errorf("invalid email address: %q", address)
+	}
+	result := &PurgeResult{}
+
+	// DLQ messages are also failed queue messages, the DLQ goes first so
+	// they leave its index too
+	if err := p.purgeDLQ(ctx, address, result); err != nil {
+		return result, err
+	}
+	if err := p.purgeQueue(ctx, address, pending, result); err != nil {
+		return result, err
+	}
+	if p.sandbox != nil {
+		n, err := p.sandbox.DeleteAddress(ctx, address)
+		if err != nil {
+			return result, fmt.Errorf("failed to purge sandbox: %w", err)
+		}
+		result.Sandbox = n
+	}
+	if p.archive != nil {
+		n, err := p.archive.DeleteAddress(ctx, address)
+		if err != nil {
+			return result, fmt.Errorf("failed to purge archive: %w", err)
+		}
+		result.Archive = n
+	}
+	result.Total = result.Queue + result.DLQ + result.Sandbox + result.Archive
+	return result, nil
+}
+
+func (p *Purger) purgeQueue(ctx context.Context, address string, pending bool, result *PurgeResult) error {
+	seen := make(map[string]bool)
+	for _, filter := range []queue.ListFilter{{Sender: address}, {Recipient: address}} {
+		msgs, err := p.queue.List(ctx, filter)
+		if err != nil {
+			return fmt.Errorf("failed to search queue: %w", err)
+		}
+		for _, msg := range msgs {
+			if seen[msg.ID] || !involves(msg, address) {
+				continue
+			}
+			seen[msg.ID] = true
+
+			switch msg.Status {
+			case queue.StatusDelivered, queue.StatusFailed:
+			case queue.StatusSending:
+				result.Skipped++
+				continue
+			default:
+				if !pending {
+					result.Skipped++
+					continue
+				}
+			}
+			if err := p.queue.Delete(ctx, msg.ID); err != nil {
+				return fmt.Errorf("failed to delete message %s: %w", msg.ID, err)
+			}
+			result.Queue++
+		}
+	}
+	return nil
+}
+
+func (p *Purger) purgeDLQ(ctx context.Context, address string, result *PurgeResult) error {
+	// Collect first, deleting while paging would skip messages
+	var ids []string
+	for offset := 0; ; offset += dlqPageSize {
+		msgs, err := p.queue.ListDLQ(ctx, dlqPageSize, offset)
+		if err != nil {
+			return fmt.Errorf("failed to search DLQ: %w", err)
+		}
+		for _, msg := range msgs {
+			if involves(msg, address) {
+				ids = append(ids, msg.ID)
+			}
+		}
+		if len(msgs) < dlqPageSize {
+			break
+		}
+	}
+
+	for _, id := range ids {
+		if err := p.queue.DeleteFromDLQ(ctx, id); err != nil {
+			return fmt.Errorf("failed to delete DLQ message %s: %w", id, err)
+		}
+		result.DLQ++
+	}
+	return nil
+}
+
+// involves reports whether a message is from or to an address
+func involves(msg *queue.Message, address string) bool {
+	if strings.EqualFold(msg.From, address) {
+		return true
+	}
+	for _, rcpt := range msg.To {
+		if strings.EqualFold(rcpt, address) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package privacy
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/foxzi/sendry/internal/archive"
+	"github.com/foxzi/sendry/internal/queue"
+	"github.com/foxzi/sendry/internal/sandbox"
+)
+
+func TestPurge(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	q, err := queue.NewBoltStorage(filepath.Join(dir, "queue.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+
+	db, err := bolt.Open(filepath.Join(dir, "sandbox.db"), 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	sb, err := sandbox.NewStorage(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ar, err := archive.NewStorage(filepath.Join(dir, "archive.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ar.Close()
+
+	const user = "user@example.com"
+	now := time.Now()
+	msgs := []*queue.Message{
+		{ID: "delivered", From: "shop@example.org", To: []string{user}, Status: queue.StatusDelivered},
+		{ID: "failed", From: "User@Example.com", To: []string{"x@example.org"}, Status: queue.StatusFailed},
+		{ID: "pending", From: "shop@example.org", To: []string{"x@example.org", user}, Status: queue.StatusPending},
+		{ID: "sending", From: "shop@example.org", To: []string{user}, Status: queue.StatusSending},
+		{ID: "similar", From: "shop@example.org", To: []string{"superuser@example.com"}, Status: queue.StatusDelivered},
+		{ID: "dlq", From: "shop@example.org", To: []string{user}, Status: queue.StatusPending},
+	}
+	for _, msg := range msgs {
+		msg.CreatedAt, msg.UpdatedAt = now, now
+		if err := q.Enqueue(ctx, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dlq, _ := q.Get(ctx, "dlq")
+	if err := q.MoveToDLQ(ctx, dlq); err != nil {
+		t.Fatal(err)
+	}
+	if err := sb.Save(ctx, &sandbox.Message{ID: "sb", From: "shop@example.org", To: []string{user}, CapturedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ar.Archive(ctx, &queue.Message{ID: "ar", From: user, To: []string{"x@example.org"}, Data: []byte("Subject: hi\r\n\r\nhi"), Status: queue.StatusDelivered, UpdatedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewPurger(q, sb, ar)
+	result, err := p.Purge(ctx, user, false)
+	if err != nil {
+		t.Fatalf("Purge() error = %v", err)
+	}
+	want := PurgeResult{Queue: 2, DLQ: 1, Sandbox: 1, Archive: 1, Skipped: 2, Total: 5}
+	if *result != want {
+		t.Errorf("Purge() = %+v, want %+v", *result, want)
+	}
+	for id, kept := range map[string]bool{"delivered": false, "failed": false, "pending": true, "sending": true, "similar": true} {
+		if msg, _ := q.Get(ctx, id); (msg != nil) != kept {
+			t.Errorf("message %s kept = %v, want %v", id, msg != nil, kept)
+		}
+	}
+	if dlq, _ := q.ListDLQ(ctx, 0, 0); len(dlq) != 0 {
+		t.Errorf("DLQ keeps %d messages", len(dlq))
+	}
+
+	// The pending message goes with pending set, the message in delivery stays
+	result, err = p.Purge(ctx, user, true)
+	if err != nil {
+		t.Fatalf("Purge() error = %v", err)
+	}
+	if result.Queue != 1 || result.Skipped != 1 || result.Total != 1 {
+		t.Errorf("Purge(pending) = %+v", *result)
+	}
+
+	if _, err := p.Purge(ctx, "user", false); err == nil {
+		t.Error("Purge() accepted an invalid address")
+	}
+}
+
+func TestPurgeQueueOnly(t *testing.T) {
+	q, err := queue.NewBoltStorage(filepath.Join(t.TempDir(), "queue.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+
+	result, err := NewPurger(q, nil, nil).Purge(context.Background(), "user@example.com", false)
+	if err != nil || result.Total != 0 {
+		t.Errorf("Purge() = %+v, %v", result, err)
+	}
+}
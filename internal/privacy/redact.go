@@ -0,0 +1,135 @@
+// Package privacy protects the personal data Sendry handles: it redacts
+// email addresses and subjects in logs and purges the stored messages of
+// an address, e.g. for a GDPR erasure request.
+package privacy
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// Address redaction modes
+const (
+	ModeMask = "mask" // j***@example.com
+	ModeHash = "hash" // 3f1c2a9b0d4e@example.com, the same for the same address
+)
+
+// Redacted replaces redacted subjects
+const Redacted = "[redacted]"
+
+// RedactConfig contains what is redacted
+type RedactConfig struct {
+	Addresses bool   // Redact email addresses in all logged values
+	Subjects  bool   // Redact the values of subject keys
+	Mode      string // Address redaction mode: mask (default) or hash
+	Salt      string // Key of hashed addresses, so they cannot be guessed
+}
+
+// addressPattern matches email addresses in text
+var addressPattern = regexp.MustCompile(`[A-Za-z0-9._%+=-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)+`)
+
+// subjectKeys are the log keys whose values are subjects
+var subjectKeys = map[string]bool{
+	"subject": true,
+}
+
+// Redactor removes personal data from logged values
+type Redactor struct {
+	cfg RedactConfig
+}
+
+// NewRedactor creates a redactor, nil when cfg redacts nothing
+func NewRedactor(cfg RedactConfig) *Redactor {
+	if !cfg.Addresses && !cfg.Subjects {
+		return nil
+	}
+	if cfg.Mode == "" {
+		cfg.Mode = ModeMask
+	}
+	return &Redactor{cfg: cfg}
+}
+
+// Address redacts an email address, keeping its domain
+func (r *Redactor) Address(addr string) string {
+	if r == nil || !r.cfg.Addresses || addr == "" {
+		return addr
+	}
+	at := strings.LastIndexByte(addr, '@')
+	if at < 0 {
+		return addr
+	}
+	local, domain := addr[:at], addr[at+1:]
+
+	if r.cfg.Mode == ModeHash {
+		mac := hmac.New(sha256.New, []byte(r.cfg.Salt))
+		mac.Write([]byte(strings.ToLower(addr)))
+		return hex.EncodeToString(mac.Sum(nil)[:6]) + "@" + domain
+	}
+	if local == "" {
+		return "***@" + domain
+	}
+	return local[:1] + "***@" + domain
+}
+
+// Addresses redacts a list of addresses
+func (r *Redactor) Addresses(addrs []string) []string {
+	if r == nil || !r.cfg.Addresses || len(addrs) == 0 {
+		return addrs
+	}
+	out := make([]string, len(addrs))
+	for i, addr := range addrs {
+		out[i] = r.Address(addr)
+	}
+	return out
+}
+
+// Text redacts the addresses in free text, e.g. an SMTP error
+func (r *Redactor) Text(s string) string {
+	if r == nil || !r.cfg.Addresses || !strings.Contains(s, "@") {
+		return s
+	}
+	return addressPattern.ReplaceAllStringFunc(s, r.Address)
+}
+
+// Subject redacts a subject
+func (r *Redactor) Subject(s string) string {
+	if r == nil || !r.cfg.Subjects || s == "" {
+		return s
+	}
+	return Redacted
+}
+
+// ReplaceAttr redacts a log attribute, it is the ReplaceAttr of
+// slog.HandlerOptions
+func (r *Redactor) ReplaceAttr(groups []string, a slog.Attr) slog.Attr {
+	if subjectKeys[strings.ToLower(a.Key)] {
+		if r.cfg.Subjects {
+			a.Value = slog.StringValue(r.Subject(a.Value.String()))
+		}
+		return a
+	}
+	if !r.cfg.Addresses {
+		return a
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		a.Value = slog.StringValue(r.Text(a.Value.String()))
+	case slog.KindAny:
+		switch v := a.Value.Any().(type) {
+		case []string:
+			out := make([]string, len(v))
+			for i, s := range v {
+				out[i] = r.Text(s)
+			}
+			a.Value = slog.AnyValue(out)
+		case error:
+			a.Value = slog.StringValue(r.Text(v.Error()))
+		}
+	}
+	return a
+}
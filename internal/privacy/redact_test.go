@@ -0,0 +1,97 @@
+package privacy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestRedactorAddress(t *testing.T) {
+	mask := NewRedactor(RedactConfig{Addresses: true})
+	tests := map[string]string{
+		"john.doe@example.com": "j***@example.com",
+		"@example.com":         "***@example.com",
+		"postmaster":           "postmaster",
+		"":                     "",
+	}
+	for in, want := range tests {
+		if got := mask.Address(in); got != want {
+			t.Errorf("Address(%q) = %q, want %q", in, got, want)
+		}
+	}
+
+	hash := NewRedactor(RedactConfig{Addresses: true, Mode: ModeHash, Salt: "s1"})
+	a := hash.Address("John@example.com")
+	if !strings.HasSuffix(a, "@example.com") || strings.Contains(a, "John") || len(a) != 12+len("@example.com") {
+		t.Errorf("hashed address = %q", a)
+	}
+	if hash.Address("john@example.com") != a {
+		t.Error("hash differs by case")
+	}
+	if NewRedactor(RedactConfig{Addresses: true, Mode: ModeHash, Salt: "s2"}).Address("john@example.com") == a {
+		t.Error("hash does not depend on the salt")
+	}
+}
+
+func TestRedactorOff(t *testing.T) {
+	if NewRedactor(RedactConfig{Mode: ModeHash}) != nil {
+		t.Error("NewRedactor() without redaction is not nil")
+	}
+	var r *Redactor
+	if r.Address("a@example.com") != "a@example.com" || r.Text("to a@example.com") != "to a@example.com" || r.Subject("Hi") != "Hi" {
+		t.Error("nil redactor changed values")
+	}
+
+	subjects := NewRedactor(RedactConfig{Subjects: true})
+	if subjects.Address("a@example.com") != "a@example.com" {
+		t.Error("subject redactor changed an address")
+	}
+	if subjects.Subject("Invoice 42") != Redacted || subjects.Subject("") != "" {
+		t.Error("Subject() did not redact")
+	}
+}
+
+func TestRedactorText(t *testing.T) {
+	r := NewRedactor(RedactConfig{Addresses: true})
+	got := r.Text("550 5.1.1 <alice@example.com>: user unknown, cc bob.smith@mail.example.org")
+	want := "550 5.1.1 <a***@example.com>: user unknown, cc b***@mail.example.org"
+	if got != want {
+		t.Errorf("Text() = %q, want %q", got, want)
+	}
+}
+
+func TestRedactorReplaceAttr(t *testing.T) {
+	r := NewRedactor(RedactConfig{Addresses: true, Subjects: true})
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{ReplaceAttr: r.ReplaceAttr}))
+
+	logger.Info("delivery to carol@example.net failed",
+		"id", "msg-1",
+		"from", "alice@example.com",
+		"to", []string{"bob@example.net", "carol@example.net"},
+		"subject", "Your invoice",
+		"error", errors.New("550 <bob@example.net> unknown"),
+		slog.Group("smtp", "rcpt", "dave@example.org"),
+	)
+
+	var rec map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "alice@") || strings.Contains(buf.String(), "bob@") ||
+		strings.Contains(buf.String(), "carol@") || strings.Contains(buf.String(), "dave@") {
+		t.Errorf("log has addresses: %s", buf.String())
+	}
+	if rec["subject"] != Redacted {
+		t.Errorf("subject = %v, want %q", rec["subject"], Redacted)
+	}
+	if rec["from"] != "a***@example.com" || rec["id"] != "msg-1" {
+		t.Errorf("from = %v, id = %v", rec["from"], rec["id"])
+	}
+	if rec["msg"] != "delivery to c***@example.net failed" {
+		t.Errorf("msg = %v", rec["msg"])
+	}
+}
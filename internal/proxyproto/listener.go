@@ -0,0 +1,146 @@
+package proxyproto
+
+import (
+	"bufio"
+	"errors"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/foxzi/sendry/internal/ipfilter"
+)
+
+// defaultHeaderTimeout limits reading the header when no timeout is set
+const defaultHeaderTimeout = 5 * time.Second
+
+// Listener reads the PROXY protocol header of connections from trusted
+// proxies and reports the client address it carries as their RemoteAddr.
+// Connections from other peers are passed on unchanged. Headers are read
+// in the background, so a slow proxy connection does not hold up others.
+type Listener struct {
+	net.Listener
+	trusted *ipfilter.Filter
+	timeout time.Duration
+	logger  *slog.Logger
+
+	accepted  chan accepted
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+type accepted struct {
+	conn net.Conn
+	err  error
+}
+
+// NewListener wraps a listener. trusted lists the IPs and CIDRs of the
+// proxies, which must send a header. A zero timeout uses the default.
+func NewListener(l net.Listener, trusted []string, timeout time.Duration, logger *slog.Logger) *Listener {
+	if timeout <= 0 {
+		timeout = defaultHeaderTimeout
+	}
+	pl := &Listener{
+		Listener: l,
+		trusted:  ipfilter.New(trusted, logger),
+		timeout:  timeout,
+		logger:   logger,
+		accepted: make(chan accepted),
+		done:     make(chan struct{}),
+	}
+	go pl.acceptLoop()
+	return pl
+}
+
+// Accept returns the next connection, with the client address of its
+// header when it came through a trusted proxy
+func (l *Listener) Accept() (net.Conn, error) {
+	select {
+	case a := <-l.accepted:
+		return a.conn, a.err
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+// Close stops accepting connections
+func (l *Listener) Close() error {
+	l.closeOnce.Do(func() { close(l.done) })
+	return l.Listener.Close()
+}
+
+func (l *Listener) acceptLoop() {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			if !l.deliver(accepted{err: err}) || errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		if !l.isTrusted(conn.RemoteAddr()) {
+			if !l.deliver(accepted{conn: conn}) {
+				conn.Close()
+				return
+			}
+			continue
+		}
+		go l.handshake(conn)
+	}
+}
+
+// deliver hands a connection or error to Accept, it returns false once the
+// listener is closed
+func (l *Listener) deliver(a accepted) bool {
+	select {
+	case l.accepted <- a:
+		return true
+	case <-l.done:
+		return false
+	}
+}
+
+// handshake reads the header of a proxy connection
+func (l *Listener) handshake(conn net.Conn) {
+	r := bufio.NewReader(conn)
+	conn.SetReadDeadline(time.Now().Add(l.timeout))
+	addr, err := ReadHeader(r)
+	conn.SetReadDeadline(time.Time{})
+	if err != nil {
+		l.logger.Warn("PROXY protocol header refused", "proxy", conn.RemoteAddr().String(), "error", err)
+		conn.Close()
+		return
+	}
+	if addr == nil {
+		addr = conn.RemoteAddr()
+	}
+	if !l.deliver(accepted{conn: &proxiedConn{Conn: conn, r: r, remote: addr}}) {
+		conn.Close()
+	}
+}
+
+func (l *Listener) isTrusted(addr net.Addr) bool {
+	if !l.trusted.Enabled() {
+		return false
+	}
+	if tcp, ok := addr.(*net.TCPAddr); ok {
+		return l.trusted.IsAllowed(tcp.IP)
+	}
+	return l.trusted.IsAllowedAddr(addr.String())
+}
+
+// proxiedConn is a connection from a proxy with the client address of its
+// header. Bytes read past the header are kept in r.
+type proxiedConn struct {
+	net.Conn
+	r      *bufio.Reader
+	remote net.Addr
+}
+
+func (c *proxiedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+func (c *proxiedConn) RemoteAddr() net.Addr {
+	return c.remote
+}
@@ -0,0 +1,135 @@
+// Package proxyproto reads the PROXY protocol header (v1 and v2) that load
+// balancers such as HAProxy send in front of a proxied connection, so that
+// the real client address is used instead of the address of the proxy.
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// v1 headers are at most 107 bytes including CRLF
+const maxV1Length = 107
+
+// v2Signature starts every v2 header
+var v2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// ErrNoHeader is returned when a connection does not start with a PROXY
+// protocol header
+var ErrNoHeader = errors.New("no PROXY protocol header")
+
+// ReadHeader reads a v1 or v2 header and returns the source address it
+// carries. It returns nil for headers without an address (v1 UNKNOWN, v2
+// LOCAL), which are sent by the proxy itself, e.g. for health checks.
+func ReadHeader(r *bufio.Reader) (net.Addr, error) {
+	b, err := r.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+	switch b[0] {
+	case 'P':
+		return readV1(r)
+	case '\r':
+		return readV2(r)
+	}
+	return nil, ErrNoHeader
+}
+
+// readV1 reads a header of the form
+// PROXY TCP4 192.0.2.1 198.51.100.1 56324 25\r\n
+func readV1(r *bufio.Reader) (net.Addr, error) {
+	var line []byte
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("invalid PROXY v1 header: %w", err)
+		}
+		line = append(line, c)
+		if c == '\n' {
+			break
+		}
+		if len(line) >= maxV1Length {
+			return nil, errors.New("invalid PROXY v1 header: too long")
+		}
+	}
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, errors.New("invalid PROXY v1 header: missing CRLF")
+	}
+
+	fields := strings.Split(string(line[:len(line)-2]), " ")
+	if fields[0] != "PROXY" || len(fields) < 2 {
+		return nil, ErrNoHeader
+	}
+	switch fields[1] {
+	case "UNKNOWN":
+		return nil, nil
+	case "TCP4", "TCP6":
+	default:
+		return nil, fmt.Errorf("invalid PROXY v1 header: unknown protocol %q", fields[1])
+	}
+	if len(fields) != 6 {
+		return nil, errors.New("invalid PROXY v1 header: wrong number of fields")
+	}
+
+	ip := net.ParseIP(fields[2])
+	if ip == nil || (fields[1] == "TCP4") != (ip.To4() != nil) {
+		return nil, fmt.Errorf("invalid PROXY v1 header: bad source address %q", fields[2])
+	}
+	port, err := strconv.ParseUint(fields[4], 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PROXY v1 header: bad source port %q", fields[4])
+	}
+	return &net.TCPAddr{IP: ip, Port: int(port)}, nil
+}
+
+// readV2 reads a binary header: the signature, version and command,
+// address family, length and the addresses followed by optional TLVs,
+// which are skipped
+func readV2(r *bufio.Reader) (net.Addr, error) {
+	head := make([]byte, 16)
+	if _, err := io.ReadFull(r, head); err != nil {
+		return nil, fmt.Errorf("invalid PROXY v2 header: %w", err)
+	}
+	if !bytes.Equal(head[:12], v2Signature) {
+		return nil, ErrNoHeader
+	}
+	if head[12]>>4 != 2 {
+		return nil, fmt.Errorf("invalid PROXY v2 header: version %d", head[12]>>4)
+	}
+	command := head[12] & 0x0f
+	family := head[13]
+	body := make([]byte, binary.BigEndian.Uint16(head[14:16]))
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, fmt.Errorf("invalid PROXY v2 header: %w", err)
+	}
+
+	switch command {
+	case 0x0: // LOCAL
+		return nil, nil
+	case 0x1: // PROXY
+	default:
+		return nil, fmt.Errorf("invalid PROXY v2 header: command %d", command)
+	}
+
+	switch family {
+	case 0x11, 0x12: // TCP and UDP over IPv4
+		if len(body) < 12 {
+			return nil, errors.New("invalid PROXY v2 header: short IPv4 addresses")
+		}
+		return &net.TCPAddr{IP: net.IP(body[0:4]).To16(), Port: int(binary.BigEndian.Uint16(body[8:10]))}, nil
+	case 0x21, 0x22: // TCP and UDP over IPv6
+		if len(body) < 36 {
+			return nil, errors.New("invalid PROXY v2 header: short IPv6 addresses")
+		}
+		return &net.TCPAddr{IP: net.IP(body[0:16]), Port: int(binary.BigEndian.Uint16(body[32:34]))}, nil
+	}
+	// Unix sockets and unspecified families carry no usable client address
+	return nil, nil
+}
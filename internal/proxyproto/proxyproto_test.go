@@ -0,0 +1,173 @@
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// v2Header builds a v2 header with a command, family and address block
+func v2Header(command, family byte, addrs []byte) []byte {
+	h := append([]byte{}, v2Signature...)
+	h = append(h, 0x20|command, family, 0, 0)
+	binary.BigEndian.PutUint16(h[14:], uint16(len(addrs)))
+	return append(h, addrs...)
+}
+
+func TestReadHeader(t *testing.T) {
+	v4 := []byte{192, 0, 2, 1, 198, 51, 100, 1, 0xdc, 0x04, 0, 25}
+	v6 := make([]byte, 36)
+	copy(v6, net.ParseIP("2001:db8::1"))
+	binary.BigEndian.PutUint16(v6[32:], 40000)
+	v4WithTLV := append(append([]byte{}, v4...), 0x04, 0, 1, 'x')
+
+	tests := []struct {
+		name    string
+		input   []byte
+		want    string // Source address, empty for none
+		wantErr bool
+	}{
+		{name: "v1 tcp4", input: []byte("PROXY TCP4 192.0.2.1 198.51.100.1 56324 25\r\nEHLO"), want: "192.0.2.1:56324"},
+		{name: "v1 tcp6", input: []byte("PROXY TCP6 2001:db8::1 2001:db8::2 40000 587\r\n"), want: "[2001:db8::1]:40000"},
+		{name: "v1 unknown", input: []byte("PROXY UNKNOWN\r\n")},
+		{name: "v1 family mismatch", input: []byte("PROXY TCP4 2001:db8::1 198.51.100.1 1 25\r\n"), wantErr: true},
+		{name: "v1 bad port", input: []byte("PROXY TCP4 192.0.2.1 198.51.100.1 99999 25\r\n"), wantErr: true},
+		{name: "v1 missing crlf", input: []byte("PROXY TCP4 192.0.2.1 198.51.100.1 1 25\n"), wantErr: true},
+		{name: "v1 too long", input: []byte("PROXY " + strings.Repeat("x", 200)), wantErr: true},
+		{name: "v2 tcp4", input: v2Header(1, 0x11, v4), want: "192.0.2.1:56324"},
+		{name: "v2 tcp4 with tlv", input: v2Header(1, 0x11, v4WithTLV), want: "192.0.2.1:56324"},
+		{name: "v2 tcp6", input: v2Header(1, 0x21, v6), want: "[2001:db8::1]:40000"},
+		{name: "v2 local", input: v2Header(0, 0x00, nil)},
+		{name: "v2 short addresses", input: v2Header(1, 0x11, v4[:8]), wantErr: true},
+		{name: "v2 truncated", input: v2Header(1, 0x11, v4)[:20], wantErr: true},
+		{name: "no header", input: []byte("EHLO client.example.com\r\n"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, err := ReadHeader(bufio.NewReader(bytes.NewReader(tt.input)))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadHeader() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			got := ""
+			if addr != nil {
+				got = addr.String()
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ReadHeader() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadHeaderKeepsData(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("PROXY TCP4 192.0.2.1 198.51.100.1 56324 25\r\nEHLO client\r\n"))
+	if _, err := ReadHeader(r); err != nil {
+		t.Fatal(err)
+	}
+	rest, _ := io.ReadAll(r)
+	if string(rest) != "EHLO client\r\n" {
+		t.Errorf("data after header = %q", rest)
+	}
+}
+
+func TestListener(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	accept := func(t *testing.T, trusted []string) (*Listener, chan net.Conn) {
+		t.Helper()
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		pl := NewListener(l, trusted, time.Second, logger)
+		t.Cleanup(func() { pl.Close() })
+		conns := make(chan net.Conn, 4)
+		go func() {
+			for {
+				conn, err := pl.Accept()
+				if err != nil {
+					return
+				}
+				conns <- conn
+			}
+		}()
+		return pl, conns
+	}
+	next := func(t *testing.T, conns chan net.Conn) net.Conn {
+		t.Helper()
+		select {
+		case conn := <-conns:
+			t.Cleanup(func() { conn.Close() })
+			return conn
+		case <-time.After(5 * time.Second):
+			t.Fatal("no connection accepted")
+			return nil
+		}
+	}
+	dial := func(t *testing.T, l net.Listener, data string) net.Conn {
+		t.Helper()
+		conn, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		if data != "" {
+			if _, err := conn.Write([]byte(data)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return conn
+	}
+
+	t.Run("trusted proxy", func(t *testing.T) {
+		pl, conns := accept(t, []string{"127.0.0.0/8"})
+
+		// A proxy that sends no header is dropped without holding up others
+		silent := dial(t, pl, "")
+		dial(t, pl, "PROXY TCP4 192.0.2.1 198.51.100.1 56324 25\r\nEHLO client\r\n")
+		conn := next(t, conns)
+		if got := conn.RemoteAddr().String(); got != "192.0.2.1:56324" {
+			t.Errorf("RemoteAddr() = %s, want the client of the header", got)
+		}
+		line, err := bufio.NewReader(conn).ReadString('\n')
+		if err != nil || line != "EHLO client\r\n" {
+			t.Errorf("data after header = %q, %v", line, err)
+		}
+
+		dial(t, pl, "EHLO client\r\n")
+		silent.SetReadDeadline(time.Now().Add(5 * time.Second))
+		if _, err := silent.Read(make([]byte, 1)); err == nil {
+			t.Error("connection without header was not closed")
+		}
+		select {
+		case conn := <-conns:
+			conn.Close()
+			t.Error("connection with invalid header was accepted")
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+
+	t.Run("untrusted peer", func(t *testing.T) {
+		pl, conns := accept(t, []string{"192.0.2.0/24"})
+		dial(t, pl, "PROXY TCP4 203.0.113.9 198.51.100.1 1 25\r\n")
+		conn := next(t, conns)
+		if ip := conn.RemoteAddr().(*net.TCPAddr).IP; !ip.IsLoopback() {
+			t.Errorf("RemoteAddr() = %s, want the peer address", ip)
+		}
+	})
+
+	t.Run("close", func(t *testing.T) {
+		pl, _ := accept(t, []string{"127.0.0.1"})
+		pl.Close()
+		if _, err := pl.Accept(); !errors.Is(err, net.ErrClosed) {
+			t.Errorf("Accept() after Close() error = %v, want net.ErrClosed", err)
+		}
+	})
+}
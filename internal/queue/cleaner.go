@@ -13,6 +13,10 @@ type CleanerConfig struct {
 	DeliveredMaxAge   time.Duration
 	DeliveredInterval time.Duration
 
+	// Shorter max ages of the delivered messages of sender domains, applied
+	// when the storage is also a Queue
+	DeliveredDomainMaxAge map[string]time.Duration
+
 	// DLQ retention
 	DLQMaxAge   time.Duration
 	DLQMaxCount int
@@ -21,7 +25,7 @@ type CleanerConfig struct {
 
 // Cleaner handles automatic cleanup of old messages
 type Cleaner struct {
-	storage *BoltStorage
+	storage CleanupStorage
 	cfg     CleanerConfig
 	logger  *slog.Logger
 	wg      sync.WaitGroup
@@ -29,7 +33,7 @@ type Cleaner struct {
 }
 
 // NewCleaner creates a new cleaner service
-func NewCleaner(storage *BoltStorage, cfg CleanerConfig, logger *slog.Logger) *Cleaner {
+func NewCleaner(storage CleanupStorage, cfg CleanerConfig, logger *slog.Logger) *Cleaner {
 	return &Cleaner{
 		storage: storage,
 		cfg:     cfg,
@@ -41,7 +45,7 @@ func NewCleaner(storage *BoltStorage, cfg CleanerConfig, logger *slog.Logger) *C
 // Start starts the cleanup goroutines
 func (c *Cleaner) Start(ctx context.Context) {
 	// Delivered messages cleanup
-	if c.cfg.DeliveredMaxAge > 0 && c.cfg.DeliveredInterval > 0 {
+	if (c.cfg.DeliveredMaxAge > 0 || len(c.cfg.DeliveredDomainMaxAge) > 0) && c.cfg.DeliveredInterval > 0 {
 		c.wg.Add(1)
 		go c.cleanupDeliveredLoop(ctx)
 	}
@@ -96,6 +100,17 @@ func (c *Cleaner) runDeliveredCleanup(ctx context.Context) {
 		return
 	}
 
+	if q, ok := c.storage.(Queue); ok {
+		for domain, maxAge := range c.cfg.DeliveredDomainMaxAge {
+			n, err := CleanupDeliveredDomain(ctx, q, domain, maxAge)
+			if err != nil {
+				c.logger.Error("failed to cleanup delivered messages", "domain", domain, "error", err)
+				return
+			}
+			deleted += n
+		}
+	}
+
 	if deleted > 0 {
 		c.logger.Info("cleaned up delivered messages", "deleted", deleted)
 	}
@@ -133,3 +148,28 @@ func (c *Cleaner) runDLQCleanup(ctx context.Context) {
 		c.logger.Info("cleaned up DLQ messages", "deleted", deleted)
 	}
 }
+
+// CleanupDeliveredDomain removes the delivered messages of a sender domain
+// older than maxAge
+func CleanupDeliveredDomain(ctx context.Context, q Queue, domain string, maxAge time.Duration) (int, error) {
+	if maxAge <= 0 {
+		return 0, nil
+	}
+	msgs, err := q.List(ctx, ListFilter{Status: StatusDelivered, SenderDomain: domain})
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := time.Now().Add(-maxAge)
+	deleted := 0
+	for _, msg := range msgs {
+		if !msg.UpdatedAt.Before(cutoff) {
+			continue
+		}
+		if err := q.Delete(ctx, msg.ID); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
@@ -0,0 +1,107 @@
+package queue
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/foxzi/sendry/internal/email"
+)
+
+// concurrencyRetryDelay is how long a message waits when a recipient domain
+// has no free delivery slot
+const concurrencyRetryDelay = 5 * time.Second
+
+// Concurrency limits the deliveries in flight per recipient domain, so
+// providers that restrict parallel connections (Gmail, Outlook) are not
+// opened more connections than they allow. A message takes a slot of each
+// of its pending recipient domains for the duration of the delivery.
+type Concurrency struct {
+	mu       sync.Mutex
+	def      int            // Limit of domains without an override (0 = unlimited)
+	limits   map[string]int // Limits by recipient domain
+	inFlight map[string]int
+}
+
+// NewConcurrency creates a tracker with a default limit per recipient
+// domain and per-domain overrides. Zero means unlimited.
+func NewConcurrency(def int, limits map[string]int) *Concurrency {
+	c := &Concurrency{inFlight: make(map[string]int)}
+	c.SetLimits(def, limits)
+	return c
+}
+
+// SetLimits replaces the limits, e.g. on a config reload. Deliveries in
+// flight keep their slots.
+func (c *Concurrency) SetLimits(def int, limits map[string]int) {
+	lower := make(map[string]int, len(limits))
+	for domain, limit := range limits {
+		lower[strings.ToLower(domain)] = limit
+	}
+	c.mu.Lock()
+	c.def = def
+	c.limits = lower
+	c.mu.Unlock()
+}
+
+// limit returns the limit of a domain. Called with the lock held.
+func (c *Concurrency) limit(domain string) int {
+	if limit, ok := c.limits[domain]; ok {
+		return limit
+	}
+	return c.def
+}
+
+// Acquire takes a slot of every domain, or none if one of them is at its
+// limit. It returns the first full domain if the slots were not taken.
+func (c *Concurrency) Acquire(domains []string) (full string, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, domain := range domains {
+		if limit := c.limit(domain); limit > 0 && c.inFlight[domain] >= limit {
+			return domain, false
+		}
+	}
+	for _, domain := range domains {
+		c.inFlight[domain]++
+	}
+	return "", true
+}
+
+// Release gives back the slots taken by Acquire
+func (c *Concurrency) Release(domains []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, domain := range domains {
+		if c.inFlight[domain] <= 1 {
+			delete(c.inFlight, domain)
+			continue
+		}
+		c.inFlight[domain]--
+	}
+}
+
+// InFlight returns the number of deliveries in flight by recipient domain
+func (c *Concurrency) InFlight() map[string]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make(map[string]int, len(c.inFlight))
+	for domain, n := range c.inFlight {
+		out[domain] = n
+	}
+	return out
+}
+
+// pendingDomains returns the distinct domains of the pending recipients
+func pendingDomains(msg *Message) []string {
+	var domains []string
+	seen := make(map[string]bool)
+	for _, rcpt := range msg.PendingRecipients() {
+		domain := email.ExtractDomain(rcpt)
+		if domain != "" && !seen[domain] {
+			seen[domain] = true
+			domains = append(domains, domain)
+		}
+	}
+	return domains
+}
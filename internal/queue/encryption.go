@@ -0,0 +1,296 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// KeySize is the size of a message encryption key, AES-256
+const KeySize = 32
+
+// encryptedMagic starts encrypted message data. Message data never starts
+// with a NUL byte, so plain data stored before encryption was enabled is
+// told apart and still read.
+var encryptedMagic = []byte("\x00SQE\x01")
+
+const keyIDSize = 4
+
+// ErrUnknownKey is returned for data encrypted with a key that is not
+// configured
+var ErrUnknownKey = errors.New("message data is encrypted with an unknown key")
+
+// Encryptor encrypts message data with AES-256-GCM. Data is sealed with the
+// current key and opened with any configured key, so keys can be rotated.
+// Encrypted data is the magic, the key ID, the nonce and the ciphertext; the
+// message ID is authenticated with it, so data cannot be moved to another
+// message.
+type Encryptor struct {
+	current [keyIDSize]byte
+	keys    map[[keyIDSize]byte]cipher.AEAD
+}
+
+// NewEncryptor creates an encryptor that seals with key and also opens data
+// sealed with the previous keys
+func NewEncryptor(key []byte, previous ...[]byte) (*Encryptor, error) {
+	e := &Encryptor{keys: make(map[[keyIDSize]byte]cipher.AEAD)}
+	for i, k := range append([][]byte{key}, previous...) {
+		if len(k) != KeySize {
+			return nil, fmt.Errorf("encryption key must be %d bytes, got %d", KeySize, len(k))
+		}
+		block, err := aes.NewCipher(k)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		id := keyID(k)
+		if i == 0 {
+			e.current = id
+		}
+		e.keys[id] = aead
+	}
+	return e, nil
+}
+
+// keyID identifies a key without revealing it
+func keyID(key []byte) [keyIDSize]byte {
+	sum := sha256.Sum256(append([]byte("sendry-queue-key\x00"), key...))
+	var id [keyIDSize]byte
+	copy(id[:], sum[:])
+	return id
+}
+
+// Seal encrypts the data of message id
+func (e *Encryptor) Seal(id string, data []byte) ([]byte, error) {
+	aead := e.keys[e.current]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to create nonce: %w", err)
+	}
+
+	out := make([]byte, 0, len(encryptedMagic)+keyIDSize+len(nonce)+len(data)+aead.Overhead())
+	out = append(out, encryptedMagic...)
+	out = append(out, e.current[:]...)
+	out = append(out, nonce...)
+	return aead.Seal(out, nonce, data, []byte(id)), nil
+}
+
+// Open decrypts the data of message id. Plain data is returned as is.
+func (e *Encryptor) Open(id string, data []byte) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return data, nil
+	}
+	rest := data[len(encryptedMagic):]
+	if len(rest) < keyIDSize {
+		return nil, errors.New("encrypted message data is truncated")
+	}
+	var kid [keyIDSize]byte
+	copy(kid[:], rest)
+	aead, ok := e.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	rest = rest[keyIDSize:]
+	if len(rest) < aead.NonceSize() {
+		return nil, errors.New("encrypted message data is truncated")
+	}
+	plain, err := aead.Open(nil, rest[:aead.NonceSize()], rest[aead.NonceSize():], []byte(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt message data: %w", err)
+	}
+	return plain, nil
+}
+
+// IsEncrypted reports whether message data is encrypted
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, encryptedMagic)
+}
+
+// ParseKey decodes a key given as base64 or hex text, or as raw bytes
+func ParseKey(s string) ([]byte, error) {
+	if len(s) == KeySize {
+		return []byte(s), nil
+	}
+	text := strings.TrimSpace(s)
+	if len(text) == 2*KeySize {
+		if key, err := hex.DecodeString(text); err == nil {
+			return key, nil
+		}
+	}
+	if key, err := base64.StdEncoding.DecodeString(text); err == nil && len(key) == KeySize {
+		return key, nil
+	}
+	return nil, fmt.Errorf("encryption key must be %d bytes as base64 or hex", KeySize)
+}
+
+// LoadEncryptor creates an encryptor from a key, or the key in keyFile,
+// and the previous keys
+func LoadEncryptor(key, keyFile string, previousKeys []string) (*Encryptor, error) {
+	if keyFile != "" {
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read encryption key: %w", err)
+		}
+		key = string(data)
+	}
+	current, err := ParseKey(key)
+	if err != nil {
+		return nil, err
+	}
+	previous := make([][]byte, 0, len(previousKeys))
+	for i, text := range previousKeys {
+		k, err := ParseKey(text)
+		if err != nil {
+			return nil, fmt.Errorf("previous key %d: %w", i+1, err)
+		}
+		previous = append(previous, k)
+	}
+	return NewEncryptor(current, previous...)
+}
+
+// EncryptedStorage encrypts the data of messages it stores and decrypts
+// the data of messages it returns. Callers keep the plain data of the
+// messages they pass in.
+type EncryptedStorage struct {
+	Storage
+	enc *Encryptor
+}
+
+// NewEncryptedStorage wraps storage to encrypt message data
+func NewEncryptedStorage(storage Storage, enc *Encryptor) *EncryptedStorage {
+	return &EncryptedStorage{Storage: storage, enc: enc}
+}
+
+// store calls write with a copy of msg that has sealed data and copies the
+// fields set by the storage back to msg
+func (s *EncryptedStorage) store(msg *Message, write func(*Message) error) error {
+	sealed, err := s.enc.Seal(msg.ID, msg.Data)
+	if err != nil {
+		return err
+	}
+	data := msg.Data
+	stored := *msg
+	stored.Data = sealed
+	err = write(&stored)
+	stored.Data = data
+	*msg = stored
+	return err
+}
+
+// open decrypts the data of messages in place
+func (s *EncryptedStorage) open(msgs ...*Message) error {
+	for _, msg := range msgs {
+		if msg == nil {
+			continue
+		}
+		data, err := s.enc.Open(msg.ID, msg.Data)
+		if err != nil {
+			return fmt.Errorf("message %s: %w", msg.ID, err)
+		}
+		msg.Data = data
+	}
+	return nil
+}
+
+// Enqueue adds a message to the queue
+func (s *EncryptedStorage) Enqueue(ctx context.Context, msg *Message) error {
+	return s.store(msg, func(m *Message) error { return s.Storage.Enqueue(ctx, m) })
+}
+
+// EnqueueBatch adds messages to the queue in a single transaction
+func (s *EncryptedStorage) EnqueueBatch(ctx context.Context, msgs []*Message) error {
+	sealed := make([]*Message, len(msgs))
+	for i, msg := range msgs {
+		if msg == nil {
+			continue
+		}
+		data, err := s.enc.Seal(msg.ID, msg.Data)
+		if err != nil {
+			return err
+		}
+		stored := *msg
+		stored.Data = data
+		sealed[i] = &stored
+	}
+	err := s.Storage.EnqueueBatch(ctx, sealed)
+	for i, msg := range msgs {
+		if msg != nil {
+			data := msg.Data
+			*msg = *sealed[i]
+			msg.Data = data
+		}
+	}
+	return err
+}
+
+// Update updates a message
+func (s *EncryptedStorage) Update(ctx context.Context, msg *Message) error {
+	return s.store(msg, func(m *Message) error { return s.Storage.Update(ctx, m) })
+}
+
+// Import stores a message as is, keeping its status and DLQ membership
+func (s *EncryptedStorage) Import(ctx context.Context, msg *Message, inDLQ bool) error {
+	return s.store(msg, func(m *Message) error { return s.Storage.Import(ctx, m, inDLQ) })
+}
+
+// MoveToDLQ moves a message to the dead letter queue
+func (s *EncryptedStorage) MoveToDLQ(ctx context.Context, msg *Message) error {
+	return s.store(msg, func(m *Message) error { return s.Storage.MoveToDLQ(ctx, m) })
+}
+
+// Dequeue gets the next message for processing
+func (s *EncryptedStorage) Dequeue(ctx context.Context) (*Message, error) {
+	msg, err := s.Storage.Dequeue(ctx)
+	if err != nil || msg == nil {
+		return msg, err
+	}
+	return msg, s.open(msg)
+}
+
+// Get retrieves a message by ID
+func (s *EncryptedStorage) Get(ctx context.Context, id string) (*Message, error) {
+	msg, err := s.Storage.Get(ctx, id)
+	if err != nil || msg == nil {
+		return msg, err
+	}
+	return msg, s.open(msg)
+}
+
+// List returns messages matching filter
+func (s *EncryptedStorage) List(ctx context.Context, filter ListFilter) ([]*Message, error) {
+	msgs, err := s.Storage.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return msgs, s.open(msgs...)
+}
+
+// ListDLQ returns messages in the dead letter queue
+func (s *EncryptedStorage) ListDLQ(ctx context.Context, limit, offset int) ([]*Message, error) {
+	msgs, err := s.Storage.ListDLQ(ctx, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return msgs, s.open(msgs...)
+}
+
+// GetFromDLQ retrieves a message from the dead letter queue
+func (s *EncryptedStorage) GetFromDLQ(ctx context.Context, id string) (*Message, error) {
+	msg, err := s.Storage.GetFromDLQ(ctx, id)
+	if err != nil || msg == nil {
+		return msg, err
+	}
+	return msg, s.open(msg)
+}
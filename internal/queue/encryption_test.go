@@ -0,0 +1,204 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, KeySize)
+}
+
+func TestEncryptorSealOpen(t *testing.T) {
+	enc, err := NewEncryptor(testKey(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []byte("Subject: secret\r\n\r\nbody")
+
+	sealed, err := enc.Seal("msg-1", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncrypted(sealed) || bytes.Contains(sealed, []byte("secret")) {
+		t.Fatalf("sealed data = %q, want ciphertext", sealed)
+	}
+	again, _ := enc.Seal("msg-1", data)
+	if bytes.Equal(sealed, again) {
+		t.Error("Seal() reused a nonce")
+	}
+
+	plain, err := enc.Open("msg-1", sealed)
+	if err != nil || !bytes.Equal(plain, data) {
+		t.Fatalf("Open() = %q, %v", plain, err)
+	}
+
+	// Data is bound to its message
+	if _, err := enc.Open("msg-2", sealed); err == nil {
+		t.Error("Open() with another message ID succeeded")
+	}
+	// Plain data stored before encryption was enabled is read as is
+	if plain, err := enc.Open("msg-1", data); err != nil || !bytes.Equal(plain, data) {
+		t.Errorf("Open(plain) = %q, %v", plain, err)
+	}
+	if _, err := enc.Open("msg-1", sealed[:len(encryptedMagic)+2]); err == nil {
+		t.Error("Open() of truncated data succeeded")
+	}
+}
+
+func TestEncryptorKeyRotation(t *testing.T) {
+	old, _ := NewEncryptor(testKey(1))
+	sealed, err := old.Seal("msg-1", []byte("body"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated, err := NewEncryptor(testKey(2), testKey(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain, err := rotated.Open("msg-1", sealed); err != nil || string(plain) != "body" {
+		t.Errorf("Open() with a previous key = %q, %v", plain, err)
+	}
+
+	other, _ := NewEncryptor(testKey(2))
+	if _, err := other.Open("msg-1", sealed); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Open() with an unknown key error = %v, want ErrUnknownKey", err)
+	}
+
+	if _, err := NewEncryptor([]byte("short")); err == nil {
+		t.Error("NewEncryptor() accepted a short key")
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	key := testKey(7)
+	for name, text := range map[string]string{
+		"base64":  base64.StdEncoding.EncodeToString(key) + "\n",
+		"hex":     hex.EncodeToString(key),
+		"raw":     string(key),
+		"trimmed": "  " + hex.EncodeToString(key) + "\n",
+	} {
+		got, err := ParseKey(text)
+		if err != nil || !bytes.Equal(got, key) {
+			t.Errorf("ParseKey(%s) = %x, %v", name, got, err)
+		}
+	}
+	if _, err := ParseKey(base64.StdEncoding.EncodeToString(key[:16])); err == nil {
+		t.Error("ParseKey() accepted a 16 byte key")
+	}
+}
+
+func TestLoadEncryptor(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "queue.key")
+	if err := os.WriteFile(file, []byte(base64.StdEncoding.EncodeToString(testKey(1))+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	enc, err := LoadEncryptor("", file, []string{hex.EncodeToString(testKey(2))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if enc.current != keyID(testKey(1)) || len(enc.keys) != 2 {
+		t.Errorf("LoadEncryptor() keys = %d, want the file key current", len(enc.keys))
+	}
+	if _, err := LoadEncryptor("", file, []string{"bad"}); err == nil {
+		t.Error("LoadEncryptor() accepted a bad previous key")
+	}
+}
+
+func TestEncryptedStorage(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "queue.db")
+	bolt, err := NewBoltStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, _ := NewEncryptor(testKey(1))
+	storage := NewEncryptedStorage(bolt, enc)
+
+	data := []byte("Subject: Invoice\r\n\r\ncustomer secret")
+	msg := &Message{
+		ID:        "enc-1",
+		From:      "sender@example.com",
+		To:        []string{"rcpt@example.net"},
+		Data:      data,
+		Status:    StatusPending,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := storage.Enqueue(ctx, msg); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(msg.Data, data) {
+		t.Errorf("caller data = %q, want it kept plain", msg.Data)
+	}
+
+	// The storage underneath keeps ciphertext only
+	raw, err := bolt.Get(ctx, "enc-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncrypted(raw.Data) || bytes.Contains(raw.Data, []byte("customer secret")) {
+		t.Fatalf("stored data = %q, want ciphertext", raw.Data)
+	}
+
+	got, err := storage.Dequeue(ctx)
+	if err != nil || got == nil || !bytes.Equal(got.Data, data) {
+		t.Fatalf("Dequeue() = %v, %v, want plain data", got, err)
+	}
+	got.Status = StatusDeferred
+	if err := storage.Update(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+	list, err := storage.List(ctx, ListFilter{})
+	if err != nil || len(list) != 1 || !bytes.Equal(list[0].Data, data) {
+		t.Fatalf("List() = %v, %v, want plain data", list, err)
+	}
+
+	if err := storage.MoveToDLQ(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+	dlq, err := storage.GetFromDLQ(ctx, "enc-1")
+	if err != nil || !bytes.Equal(dlq.Data, data) {
+		t.Fatalf("GetFromDLQ() = %v, %v, want plain data", dlq, err)
+	}
+	if dlq, err := storage.ListDLQ(ctx, 10, 0); err != nil || len(dlq) != 1 || !bytes.Equal(dlq[0].Data, data) {
+		t.Errorf("ListDLQ() = %v, %v, want plain data", dlq, err)
+	}
+
+	batch := []*Message{
+		{ID: "enc-2", From: "a@example.com", To: []string{"b@example.net"}, Data: []byte("two"), Status: StatusPending, CreatedAt: time.Now()},
+		{ID: "enc-3", From: "a@example.com", To: []string{"b@example.net"}, Data: []byte("three"), Status: StatusPending, CreatedAt: time.Now()},
+	}
+	if err := storage.EnqueueBatch(ctx, batch); err != nil {
+		t.Fatal(err)
+	}
+	if string(batch[1].Data) != "three" {
+		t.Errorf("batch data = %q, want it kept plain", batch[1].Data)
+	}
+	if raw, _ := bolt.Get(ctx, "enc-3"); !IsEncrypted(raw.Data) {
+		t.Errorf("batch message stored plain: %q", raw.Data)
+	}
+	if got, err := storage.Get(ctx, "enc-3"); err != nil || string(got.Data) != "three" {
+		t.Errorf("Get() = %v, %v", got, err)
+	}
+
+	// A stolen database does not open without the key
+	storage.Close()
+	bolt, err = NewBoltStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bolt.Close()
+	wrong, _ := NewEncryptor(testKey(2))
+	if _, err := NewEncryptedStorage(bolt, wrong).Get(ctx, "enc-2"); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Get() with another key error = %v, want ErrUnknownKey", err)
+	}
+}
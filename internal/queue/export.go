@@ -0,0 +1,115 @@
+package queue
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// exportPageSize is the number of messages read at once for an export
+const exportPageSize = 500
+
+// maxExportLine limits one line of an export file, a message with its data
+const maxExportLine = 256 << 20
+
+// ExportRecord is one line of a queue export
+type ExportRecord struct {
+	Message *Message `json:"message"`
+	DLQ     bool     `json:"dlq,omitempty"`
+}
+
+// Export writes the messages of src that match filter to w as JSON lines,
+// with their DLQ membership. filter.Limit limits the number of messages.
+func Export(ctx context.Context, src Storage, w io.Writer, filter ListFilter) (*MigrateResult, error) {
+	dlq, err := src.ListDLQ(ctx, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list DLQ: %w", err)
+	}
+	inDLQ := make(map[string]bool, len(dlq))
+	for _, msg := range dlq {
+		inDLQ[msg.ID] = true
+	}
+
+	limit := filter.Limit
+	page := filter
+	page.Offset = 0
+	page.Limit = exportPageSize
+
+	enc := json.NewEncoder(w)
+	result := &MigrateResult{}
+	for {
+		messages, err := src.List(ctx, page)
+		if err != nil {
+			return result, fmt.Errorf("failed to list messages: %w", err)
+		}
+
+		for _, msg := range messages {
+			if limit > 0 && result.Messages >= limit {
+				return result, nil
+			}
+			if err := enc.Encode(ExportRecord{Message: msg, DLQ: inDLQ[msg.ID]}); err != nil {
+				return result, err
+			}
+			result.Messages++
+			if inDLQ[msg.ID] {
+				result.DLQ++
+			}
+		}
+
+		if len(messages) < exportPageSize {
+			return result, nil
+		}
+		page.Cursor = messages[len(messages)-1].ID
+	}
+}
+
+// ImportExport stores the messages of an export read from r in dst. Messages
+// that are already queued are skipped, so an export can be imported again.
+func ImportExport(ctx context.Context, dst Storage, r io.Reader) (*MigrateResult, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxExportLine)
+
+	result := &MigrateResult{}
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		var rec ExportRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return result, fmt.Errorf("line %d: invalid record: %w", line, err)
+		}
+		if rec.Message == nil || rec.Message.ID == "" {
+			return result, fmt.Errorf("line %d: record without a message", line)
+		}
+
+		existing, err := dst.Get(ctx, rec.Message.ID)
+		if err != nil {
+			return result, fmt.Errorf("failed to check message %s: %w", rec.Message.ID, err)
+		}
+		if existing != nil {
+			result.Skipped++
+			continue
+		}
+
+		if err := dst.Import(ctx, rec.Message, rec.DLQ); err != nil {
+			return result, fmt.Errorf("failed to import message %s: %w", rec.Message.ID, err)
+		}
+		result.Messages++
+		if rec.DLQ {
+			result.DLQ++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return result, fmt.Errorf("failed to read export: %w", err)
+	}
+
+	return result, nil
+}
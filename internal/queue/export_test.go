@@ -0,0 +1,84 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportImport(t *testing.T) {
+	ctx := context.Background()
+
+	src, err := NewBoltStorage(filepath.Join(t.TempDir(), "queue.db"))
+	if err != nil {
+		t.Fatalf("NewBoltStorage() error = %v", err)
+	}
+	defer src.Close()
+
+	now := time.Now()
+	pending := &Message{ID: "pending", Data: []byte("Subject: a\r\n\r\nbody"), Status: StatusPending, CreatedAt: now, UpdatedAt: now}
+	deferred := &Message{ID: "deferred", Status: StatusDeferred, NextRetryAt: now.Add(time.Hour), CreatedAt: now, UpdatedAt: now}
+	failed := &Message{ID: "failed", Status: StatusPending, CreatedAt: now, UpdatedAt: now}
+	for _, msg := range []*Message{pending, deferred, failed} {
+		if err := src.Enqueue(ctx, msg); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+	}
+	if err := src.MoveToDLQ(ctx, failed); err != nil {
+		t.Fatalf("MoveToDLQ() error = %v", err)
+	}
+
+	// A status filter exports only matching messages
+	var buf bytes.Buffer
+	result, err := Export(ctx, src, &buf, ListFilter{Status: StatusDeferred})
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if result.Messages != 1 || strings.Count(buf.String(), "\n") != 1 || !strings.Contains(buf.String(), `"id":"deferred"`) {
+		t.Errorf("Export(deferred) = %+v:\n%s", result, buf.String())
+	}
+
+	buf.Reset()
+	if result, err = Export(ctx, src, &buf, ListFilter{}); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if result.Messages != 3 || result.DLQ != 1 {
+		t.Errorf("Export() = %+v, want 3 messages, 1 in DLQ", result)
+	}
+	export := buf.String()
+
+	dst := newTestSQLiteStorage(t)
+	result, err = ImportExport(ctx, dst, strings.NewReader(export))
+	if err != nil {
+		t.Fatalf("ImportExport() error = %v", err)
+	}
+	if result.Messages != 3 || result.DLQ != 1 || result.Skipped != 0 {
+		t.Errorf("ImportExport() = %+v, want 3 messages, 1 in DLQ", result)
+	}
+	if got, _ := dst.GetFromDLQ(ctx, "failed"); got == nil {
+		t.Error("DLQ message was not imported to DLQ")
+	}
+	got, err := dst.Dequeue(ctx)
+	if err != nil {
+		t.Fatalf("Dequeue() error = %v", err)
+	}
+	if got == nil || got.ID != "pending" || string(got.Data) != string(pending.Data) {
+		t.Errorf("Dequeue() = %v, want pending message with its data", got)
+	}
+
+	// Importing again skips the queued messages
+	result, err = ImportExport(ctx, dst, strings.NewReader(export))
+	if err != nil {
+		t.Fatalf("ImportExport() again error = %v", err)
+	}
+	if result.Messages != 0 || result.Skipped != 3 {
+		t.Errorf("ImportExport() again = %+v, want 3 skipped", result)
+	}
+
+	if _, err := ImportExport(ctx, dst, strings.NewReader("{\"message\":{}}\n")); err == nil {
+		t.Error("ImportExport() accepted a record without a message ID")
+	}
+}
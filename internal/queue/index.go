@@ -0,0 +1,171 @@
+package queue
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/foxzi/sendry/internal/email"
+)
+
+// Secondary indexes of BoltStorage used to narrow down List. Keys end with
+// the message ID, values are the message ID.
+var (
+	bucketBySender    = []byte("idx_sender_domain")    // domain + 0 + id
+	bucketByRecipient = []byte("idx_recipient_domain") // domain + 0 + id, per distinct domain
+	bucketByCreated   = []byte("idx_created")          // created_at + id
+)
+
+// makeDomainKey creates a domain index key
+func makeDomainKey(domain, id string) []byte {
+	return []byte(domain + "\x00" + id)
+}
+
+// makeCreatedKey creates a created_at index key. The sign bit is flipped so
+// times before 1970 sort first.
+func makeCreatedKey(t time.Time, id string) []byte {
+	key := make([]byte, 8, 8+len(id))
+	binary.BigEndian.PutUint64(key, createdOrder(t))
+	return append(key, id...)
+}
+
+func createdOrder(t time.Time) uint64 {
+	return uint64(t.UnixNano()) ^ 1<<63
+}
+
+// recipientDomains returns the distinct recipient domains of a message
+func recipientDomains(msg *Message) []string {
+	var domains []string
+	seen := make(map[string]bool, len(msg.To))
+	for _, rcpt := range msg.To {
+		domain := email.ExtractDomain(rcpt)
+		if domain != "" && !seen[domain] {
+			seen[domain] = true
+			domains = append(domains, domain)
+		}
+	}
+	return domains
+}
+
+// putSearchIndex adds the index entries of a message. Sender, recipients and
+// creation time never change, so entries are written once per message.
+func putSearchIndex(tx *bolt.Tx, msg *Message) error {
+	id := []byte(msg.ID)
+	if domain := email.ExtractDomain(msg.From); domain != "" {
+		if err := tx.Bucket(bucketBySender).Put(makeDomainKey(domain, msg.ID), id); err != nil {
+			return fmt.Errorf("failed to add to sender index: %w", err)
+		}
+	}
+	for _, domain := range recipientDomains(msg) {
+		if err := tx.Bucket(bucketByRecipient).Put(makeDomainKey(domain, msg.ID), id); err != nil {
+			return fmt.Errorf("failed to add to recipient index: %w", err)
+		}
+	}
+	if err := tx.Bucket(bucketByCreated).Put(makeCreatedKey(msg.CreatedAt, msg.ID), id); err != nil {
+		return fmt.Errorf("failed to add to created index: %w", err)
+	}
+	return nil
+}
+
+// deleteSearchIndex removes the index entries of a message
+func deleteSearchIndex(tx *bolt.Tx, msg *Message) error {
+	if domain := email.ExtractDomain(msg.From); domain != "" {
+		if err := tx.Bucket(bucketBySender).Delete(makeDomainKey(domain, msg.ID)); err != nil {
+			return err
+		}
+	}
+	for _, domain := range recipientDomains(msg) {
+		if err := tx.Bucket(bucketByRecipient).Delete(makeDomainKey(domain, msg.ID)); err != nil {
+			return err
+		}
+	}
+	return tx.Bucket(bucketByCreated).Delete(makeCreatedKey(msg.CreatedAt, msg.ID))
+}
+
+// deleteMessage removes a stored message and its index entries
+func deleteMessage(tx *bolt.Tx, id []byte) error {
+	msgBucket := tx.Bucket(bucketMessages)
+	if data := msgBucket.Get(id); data != nil {
+		var msg Message
+		if err := json.Unmarshal(data, &msg); err == nil {
+			if err := deleteSearchIndex(tx, &msg); err != nil {
+				return err
+			}
+		}
+	}
+	return msgBucket.Delete(id)
+}
+
+// createSearchIndex creates the index buckets, indexing all messages if
+// the database predates them
+func createSearchIndex(tx *bolt.Tx) error {
+	missing := tx.Bucket(bucketByCreated) == nil
+	for _, bucket := range [][]byte{bucketBySender, bucketByRecipient, bucketByCreated} {
+		if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+			return fmt.Errorf("failed to create bucket %s: %w", bucket, err)
+		}
+	}
+	if !missing {
+		return nil
+	}
+
+	return tx.Bucket(bucketMessages).ForEach(func(k, v []byte) error {
+		var msg Message
+		if err := json.Unmarshal(v, &msg); err != nil {
+			return nil
+		}
+		return putSearchIndex(tx, &msg)
+	})
+}
+
+// indexedIDs returns the sorted IDs of the candidates for a filter from the
+// most selective index that applies. ok is false if no index applies and
+// the whole message bucket has to be scanned.
+func indexedIDs(tx *bolt.Tx, filter ListFilter) (ids []string, ok bool) {
+	seen := make(map[string]bool)
+	collect := func(bucket []byte, from, to []byte) {
+		c := tx.Bucket(bucket).Cursor()
+		for k, v := c.Seek(from); k != nil && (to == nil || bytes.Compare(k, to) < 0); k, v = c.Next() {
+			if !seen[string(v)] {
+				seen[string(v)] = true
+				ids = append(ids, string(v))
+			}
+		}
+	}
+	domainRange := func(bucket []byte, domain string) {
+		prefix := strings.ToLower(domain) + "\x00"
+		collect(bucket, []byte(prefix), []byte(strings.ToLower(domain)+"\x01"))
+	}
+
+	switch {
+	case filter.SenderDomain != "":
+		domainRange(bucketBySender, filter.SenderDomain)
+	case filter.RecipientDomain != "":
+		domainRange(bucketByRecipient, filter.RecipientDomain)
+	case filter.Domain != "":
+		domainRange(bucketBySender, filter.Domain)
+		domainRange(bucketByRecipient, filter.Domain)
+	case !filter.Since.IsZero() || !filter.Until.IsZero():
+		from := make([]byte, 8)
+		if !filter.Since.IsZero() {
+			binary.BigEndian.PutUint64(from, createdOrder(filter.Since))
+		}
+		var to []byte
+		if !filter.Until.IsZero() {
+			to = make([]byte, 8)
+			binary.BigEndian.PutUint64(to, createdOrder(filter.Until))
+		}
+		collect(bucketByCreated, from, to)
+	default:
+		return nil, false
+	}
+
+	sort.Strings(ids)
+	return ids, true
+}
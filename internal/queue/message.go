@@ -1,7 +1,11 @@
 package queue
 
 import (
+	"fmt"
+	"strings"
 	"time"
+
+	"github.com/foxzi/sendry/internal/email"
 )
 
 // MessageStatus represents the status of a message in the queue
@@ -15,6 +19,42 @@ const (
 	StatusDeferred  MessageStatus = "deferred"
 )
 
+// Priority controls the order in which pending messages are dispatched
+type Priority string
+
+const (
+	PriorityHigh   Priority = "high"
+	PriorityNormal Priority = "normal"
+	PriorityLow    Priority = "low"
+)
+
+// PriorityHeader sets the priority of a message submitted over SMTP
+const PriorityHeader = "X-Sendry-Priority"
+
+// ParsePriority parses a priority name. An empty name means normal priority.
+func ParsePriority(s string) (Priority, error) {
+	switch p := Priority(strings.ToLower(strings.TrimSpace(s))); p {
+	case "":
+		return PriorityNormal, nil
+	case PriorityHigh, PriorityNormal, PriorityLow:
+		return p, nil
+	default:
+		return "", fmt.Errorf("invalid priority %q (must be high, normal or low)", s)
+	}
+}
+
+// rank returns the dispatch order of a priority, lower ranks go first
+func (p Priority) rank() int {
+	switch p {
+	case PriorityHigh:
+		return 0
+	case PriorityLow:
+		return 2
+	default:
+		return 1
+	}
+}
+
 // Message represents an email message in the queue
 type Message struct {
 	ID          string        `json:"id"`
@@ -25,21 +65,176 @@ type Message struct {
 	CreatedAt   time.Time     `json:"created_at"`
 	UpdatedAt   time.Time     `json:"updated_at"`
 	NextRetryAt time.Time     `json:"next_retry_at"`
+	SendAt      time.Time     `json:"send_at,omitzero"` // Not dispatched before this time
+	Priority    Priority      `json:"priority,omitempty"`
 	RetryCount  int           `json:"retry_count"`
 	LastError   string        `json:"last_error,omitempty"`
 	ClientIP    string        `json:"client_ip,omitempty"`
 	AuthUser    string        `json:"auth_user,omitempty"`
+	APIKeyID    string        `json:"api_key_id,omitempty"` // API key that submitted the message
+	APIKeyName  string        `json:"api_key_name,omitempty"`
+	DKIMSigned  bool          `json:"dkim_signed,omitempty"` // Data was DKIM-signed at enqueue time
+	SkipDKIM    bool          `json:"skip_dkim,omitempty"`   // Deliver without a DKIM signature
+	Quarantined bool          `json:"quarantined,omitempty"` // Failed DMARC of a sender domain with a quarantine policy
+	SMTPUTF8    bool          `json:"smtputf8,omitempty"`    // Submitted with SMTPUTF8, delivered only to hosts that support it
+	Released    bool          `json:"released,omitempty"`    // Released from the sandbox, delivered whatever the domain mode
+	TraceParent string        `json:"traceparent,omitempty"` // W3C trace context of the request that queued the message
+
+	// Per-recipient delivery outcomes and the log of attempts behind them
+	Results  map[string]*RecipientResult `json:"results,omitempty"`
+	Attempts []DeliveryAttempt           `json:"attempts,omitempty"`
 }
 
-// DeliveryAttempt represents a delivery attempt record
+// Schedule sets the earliest dispatch time of a pending message. A message
+// scheduled for the future is held in the deferred index until then.
+func (m *Message) Schedule(sendAt time.Time) {
+	m.SendAt = sendAt
+	if m.Status == StatusPending && sendAt.After(time.Now()) {
+		m.Status = StatusDeferred
+		m.NextRetryAt = sendAt
+	}
+}
+
+// EffectivePriority returns the message priority, normal if unset
+func (m *Message) EffectivePriority() Priority {
+	if m.Priority == "" {
+		return PriorityNormal
+	}
+	return m.Priority
+}
+
+// Scheduled reports whether the message waits for its send time
+func (m *Message) Scheduled(now time.Time) bool {
+	return m.SendAt.After(now)
+}
+
+// DeliveryAttempt represents a delivery attempt record of one recipient
+// at one MX host
 type DeliveryAttempt struct {
 	Timestamp time.Time `json:"timestamp"`
+	Recipient string    `json:"recipient,omitempty"`
 	MXHost    string    `json:"mx_host"`
 	Success   bool      `json:"success"`
+	Permanent bool      `json:"permanent,omitempty"`
 	Error     string    `json:"error,omitempty"`
 	Response  string    `json:"response,omitempty"`
 }
 
+// maxAttemptLog bounds the attempt log of a message, oldest entries are dropped
+const maxAttemptLog = 1000
+
+// RecipientResult is the delivery outcome of one recipient
+type RecipientResult struct {
+	Status     MessageStatus `json:"status"` // delivered, failed or deferred
+	MXHost     string        `json:"mx_host,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	Expired    bool          `json:"expired,omitempty"`    // Failed because the delivery budget ran out
+	Bounced    bool          `json:"bounced,omitempty"`    // Failed by a bounce received after delivery
+	Greylisted bool          `json:"greylisted,omitempty"` // Deferred by greylisting
+	UpdatedAt  time.Time     `json:"updated_at"`
+}
+
+// RecordAttempt appends an attempt to the message attempt log
+func (m *Message) RecordAttempt(a DeliveryAttempt) {
+	if a.Timestamp.IsZero() {
+		a.Timestamp = time.Now()
+	}
+	m.Attempts = append(m.Attempts, a)
+	if n := len(m.Attempts) - maxAttemptLog; n > 0 {
+		m.Attempts = append(m.Attempts[:0], m.Attempts[n:]...)
+	}
+}
+
+// SetResult sets the delivery outcome of a recipient
+func (m *Message) SetResult(recipient string, result RecipientResult) {
+	if m.Results == nil {
+		m.Results = make(map[string]*RecipientResult)
+	}
+	if result.UpdatedAt.IsZero() {
+		result.UpdatedAt = time.Now()
+	}
+	m.Results[recipient] = &result
+}
+
+// PendingRecipients returns the recipients that are neither delivered nor
+// permanently failed
+func (m *Message) PendingRecipients() []string {
+	return m.recipients(func(r *RecipientResult) bool {
+		return r == nil || r.Status == StatusDeferred
+	})
+}
+
+// DeliveredRecipients returns the recipients that were delivered
+func (m *Message) DeliveredRecipients() []string {
+	return m.recipients(func(r *RecipientResult) bool {
+		return r != nil && r.Status == StatusDelivered
+	})
+}
+
+// UndeliveredRecipients returns the recipients that were not delivered
+func (m *Message) UndeliveredRecipients() []string {
+	return m.recipients(func(r *RecipientResult) bool {
+		return r == nil || r.Status != StatusDelivered
+	})
+}
+
+func (m *Message) recipients(match func(*RecipientResult) bool) []string {
+	var out []string
+	for _, rcpt := range m.To {
+		if match(m.Results[rcpt]) {
+			out = append(out, rcpt)
+		}
+	}
+	return out
+}
+
+// ExpirePending marks the recipients still deferred as failed because the
+// delivery budget ran out. The last error of each recipient is kept.
+func (m *Message) ExpirePending(reason string) {
+	m.Expire(m.PendingRecipients(), reason)
+}
+
+// Expire marks recipients as failed because their delivery budget ran out.
+// The last error of each recipient is kept.
+func (m *Message) Expire(recipients []string, reason string) {
+	now := time.Now()
+	for _, rcpt := range recipients {
+		result := RecipientResult{Status: StatusFailed, Expired: true, UpdatedAt: now}
+		if prev := m.Results[rcpt]; prev != nil {
+			result.MXHost = prev.MXHost
+			result.Error = prev.Error
+		}
+		if result.Error == "" {
+			result.Error = m.LastError
+		}
+		result.Error = reason + ": " + result.Error
+		m.SetResult(rcpt, result)
+	}
+}
+
+// MXAttempts returns how many delivery attempts were made to an MX host
+// for any of the given recipients
+func (m *Message) MXAttempts(host string, recipients []string) int {
+	counts := make(map[string]int, len(recipients))
+	for _, rcpt := range recipients {
+		counts[rcpt] = 0
+	}
+
+	max := 0
+	for _, a := range m.Attempts {
+		if !strings.EqualFold(a.MXHost, host) {
+			continue
+		}
+		if n, ok := counts[a.Recipient]; ok {
+			counts[a.Recipient] = n + 1
+			if n+1 > max {
+				max = n + 1
+			}
+		}
+	}
+	return max
+}
+
 // QueueStats represents queue statistics
 type QueueStats struct {
 	Pending   int64 `json:"pending"`
@@ -50,9 +245,66 @@ type QueueStats struct {
 	Total     int64 `json:"total"`
 }
 
-// ListFilter represents filter options for listing messages
+// ListFilter represents filter options for listing messages. Messages are
+// listed in ID order and all set fields must match.
 type ListFilter struct {
-	Status MessageStatus
+	Status          MessageStatus
+	Sender          string    // Substring of the sender address
+	Recipient       string    // Substring of a recipient address
+	SenderDomain    string    // Domain of the sender
+	RecipientDomain string    // Domain of a recipient
+	Domain          string    // Domain of the sender or a recipient
+	Since           time.Time // Created at or after
+	Until           time.Time // Created before
+	IDPrefix        string    // Prefix of the message ID
+
+	// Cursor continues a listing after the message with this ID, the last
+	// one of the previous page
+	Cursor string
+
 	Limit  int
 	Offset int
 }
+
+// Match reports whether a message passes all fields of the filter except
+// the paging ones
+func (f ListFilter) Match(msg *Message) bool {
+	if f.Status != "" && msg.Status != f.Status {
+		return false
+	}
+	if f.Cursor != "" && msg.ID <= f.Cursor {
+		return false
+	}
+	if f.IDPrefix != "" && !strings.HasPrefix(msg.ID, f.IDPrefix) {
+		return false
+	}
+	if !f.Since.IsZero() && msg.CreatedAt.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !msg.CreatedAt.Before(f.Until) {
+		return false
+	}
+	if f.Sender != "" && !containsFold(msg.From, f.Sender) {
+		return false
+	}
+	senderDomain := email.ExtractDomain(msg.From)
+	if f.SenderDomain != "" && !strings.EqualFold(senderDomain, f.SenderDomain) {
+		return false
+	}
+
+	var rcptMatch, rcptDomainMatch, domainMatch bool
+	domainMatch = f.Domain != "" && strings.EqualFold(senderDomain, f.Domain)
+	for _, rcpt := range msg.To {
+		rcptMatch = rcptMatch || containsFold(rcpt, f.Recipient)
+		domain := email.ExtractDomain(rcpt)
+		rcptDomainMatch = rcptDomainMatch || strings.EqualFold(domain, f.RecipientDomain)
+		domainMatch = domainMatch || strings.EqualFold(domain, f.Domain)
+	}
+	return (f.Recipient == "" || rcptMatch) &&
+		(f.RecipientDomain == "" || rcptDomainMatch) &&
+		(f.Domain == "" || domainMatch)
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
@@ -0,0 +1,97 @@
+package queue
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMessageRecipientResults(t *testing.T) {
+	msg := &Message{To: []string{"a@test.com", "b@test.com", "c@test.com", "d@test.com"}}
+	msg.SetResult("a@test.com", RecipientResult{Status: StatusDelivered})
+	msg.SetResult("b@test.com", RecipientResult{Status: StatusFailed, Error: "550 User unknown"})
+	msg.SetResult("c@test.com", RecipientResult{Status: StatusDeferred, MXHost: "mx.test.com", Error: "451 Try later"})
+
+	if got := msg.PendingRecipients(); fmt.Sprint(got) != "[c@test.com d@test.com]" {
+		t.Errorf("PendingRecipients() = %v", got)
+	}
+	if got := msg.DeliveredRecipients(); fmt.Sprint(got) != "[a@test.com]" {
+		t.Errorf("DeliveredRecipients() = %v", got)
+	}
+
+	msg.LastError = "connection refused"
+	msg.ExpirePending("max retries exceeded")
+
+	if got := msg.PendingRecipients(); len(got) != 0 {
+		t.Errorf("PendingRecipients() after expiry = %v, want none", got)
+	}
+	if got := msg.UndeliveredRecipients(); fmt.Sprint(got) != "[b@test.com c@test.com d@test.com]" {
+		t.Errorf("UndeliveredRecipients() = %v", got)
+	}
+
+	c := msg.Results["c@test.com"]
+	if !c.Expired || c.MXHost != "mx.test.com" || c.Error != "max retries exceeded: 451 Try later" {
+		t.Errorf("expired result = %+v", c)
+	}
+	if d := msg.Results["d@test.com"]; d.Error != "max retries exceeded: connection refused" {
+		t.Errorf("expired result without attempt = %+v", d)
+	}
+	if msg.Results["b@test.com"].Expired {
+		t.Error("permanently failed recipient should not be marked expired")
+	}
+}
+
+func TestMessageMXAttempts(t *testing.T) {
+	msg := &Message{}
+	for i := 0; i < 3; i++ {
+		msg.RecordAttempt(DeliveryAttempt{Recipient: "a@test.com", MXHost: "mx1.test.com"})
+	}
+	msg.RecordAttempt(DeliveryAttempt{Recipient: "b@test.com", MXHost: "MX1.test.com"})
+	msg.RecordAttempt(DeliveryAttempt{Recipient: "b@test.com", MXHost: "mx2.test.com"})
+
+	if got := msg.MXAttempts("mx1.test.com", []string{"a@test.com", "b@test.com"}); got != 3 {
+		t.Errorf("MXAttempts(mx1, a+b) = %d, want 3", got)
+	}
+	if got := msg.MXAttempts("mx1.test.com", []string{"b@test.com"}); got != 1 {
+		t.Errorf("MXAttempts(mx1, b) = %d, want 1", got)
+	}
+	if got := msg.MXAttempts("mx3.test.com", []string{"a@test.com"}); got != 0 {
+		t.Errorf("MXAttempts(mx3, a) = %d, want 0", got)
+	}
+}
+
+func TestMessageAttemptLogCap(t *testing.T) {
+	msg := &Message{}
+	for i := 0; i < maxAttemptLog+10; i++ {
+		msg.RecordAttempt(DeliveryAttempt{Recipient: fmt.Sprintf("r%d@test.com", i)})
+	}
+
+	if len(msg.Attempts) != maxAttemptLog {
+		t.Fatalf("attempt log has %d entries, want %d", len(msg.Attempts), maxAttemptLog)
+	}
+	if msg.Attempts[0].Recipient != "r10@test.com" {
+		t.Errorf("oldest entry = %s, want r10@test.com", msg.Attempts[0].Recipient)
+	}
+	if msg.Attempts[0].Timestamp.IsZero() {
+		t.Error("RecordAttempt should set the timestamp")
+	}
+}
+
+func TestMessageSendAtJSON(t *testing.T) {
+	data, err := json.Marshal(&Message{ID: "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "send_at") {
+		t.Errorf("unscheduled message has send_at: %s", data)
+	}
+
+	sendAt := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	data, _ = json.Marshal(&Message{ID: "2", SendAt: sendAt})
+	var got Message
+	if err := json.Unmarshal(data, &got); err != nil || !got.SendAt.Equal(sendAt) {
+		t.Errorf("SendAt = %v, %v, want %v", got.SendAt, err, sendAt)
+	}
+}
@@ -0,0 +1,72 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+)
+
+// MigrateResult contains the outcome of a storage migration
+type MigrateResult struct {
+	Messages int `json:"messages"`
+	DLQ      int `json:"dlq"`
+	Skipped  int `json:"skipped,omitempty"` // Already queued, not imported
+}
+
+// Migrate copies all messages from src to dst, keeping their status and DLQ membership
+func Migrate(ctx context.Context, src, dst Storage) (*MigrateResult, error) {
+	return transfer(ctx, src, dst, false)
+}
+
+// Move migrates all messages from src to dst and removes them from src
+func Move(ctx context.Context, src, dst Storage) (*MigrateResult, error) {
+	return transfer(ctx, src, dst, true)
+}
+
+func transfer(ctx context.Context, src, dst Storage, remove bool) (*MigrateResult, error) {
+	dlq, err := src.ListDLQ(ctx, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list DLQ: %w", err)
+	}
+
+	inDLQ := make(map[string]bool, len(dlq))
+	for _, msg := range dlq {
+		inDLQ[msg.ID] = true
+	}
+
+	messages, err := src.List(ctx, ListFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list messages: %w", err)
+	}
+
+	result := &MigrateResult{}
+	for _, msg := range messages {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		if err := dst.Import(ctx, msg, inDLQ[msg.ID]); err != nil {
+			return result, fmt.Errorf("failed to import message %s: %w", msg.ID, err)
+		}
+
+		if remove {
+			if err := removeMessage(ctx, src, msg.ID, inDLQ[msg.ID]); err != nil {
+				return result, fmt.Errorf("failed to remove message %s: %w", msg.ID, err)
+			}
+		}
+
+		result.Messages++
+		if inDLQ[msg.ID] {
+			result.DLQ++
+		}
+	}
+
+	return result, nil
+}
+
+// removeMessage deletes a message and its DLQ entry
+func removeMessage(ctx context.Context, storage Storage, id string, inDLQ bool) error {
+	if inDLQ {
+		return storage.DeleteFromDLQ(ctx, id)
+	}
+	return storage.Delete(ctx, id)
+}
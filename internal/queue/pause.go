@@ -0,0 +1,123 @@
+package queue
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/foxzi/sendry/internal/email"
+)
+
+// pausedRetryDelay is how long a message of a paused domain waits before
+// the pause is checked again
+const pausedRetryDelay = time.Minute
+
+// Pauses holds delivery pauses. A global pause stops the processor from
+// taking messages off the queue; a domain pause holds the messages whose
+// sender or a pending recipient is in the domain. Paused messages are
+// deferred without counting as a delivery attempt.
+type Pauses struct {
+	mu         sync.RWMutex
+	global     bool
+	domains    map[string]bool // Paused at runtime through the API
+	configured map[string]bool // Paused in the domain config
+}
+
+// PauseStatus describes the current pauses
+type PauseStatus struct {
+	Global        bool     `json:"global"`
+	Domains       []string `json:"domains"`
+	ConfigDomains []string `json:"config_domains"`
+}
+
+// NewPauses creates an empty pause set
+func NewPauses() *Pauses {
+	return &Pauses{
+		domains:    make(map[string]bool),
+		configured: make(map[string]bool),
+	}
+}
+
+// Pause pauses delivery of a domain, or all delivery if domain is empty
+func (p *Pauses) Pause(domain string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if domain == "" {
+		p.global = true
+		return
+	}
+	p.domains[strings.ToLower(domain)] = true
+}
+
+// Resume resumes delivery of a domain paused at runtime, or global delivery
+// if domain is empty. Domains paused in the config stay paused.
+func (p *Pauses) Resume(domain string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if domain == "" {
+		p.global = false
+		return
+	}
+	delete(p.domains, strings.ToLower(domain))
+}
+
+// SetConfigured replaces the domains paused in the config
+func (p *Pauses) SetConfigured(domains []string) {
+	configured := make(map[string]bool, len(domains))
+	for _, d := range domains {
+		configured[strings.ToLower(d)] = true
+	}
+	p.mu.Lock()
+	p.configured = configured
+	p.mu.Unlock()
+}
+
+// Global reports whether all delivery is paused
+func (p *Pauses) Global() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.global
+}
+
+// Paused reports whether delivery of a domain is paused
+func (p *Pauses) Paused(domain string) bool {
+	domain = strings.ToLower(domain)
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.domains[domain] || p.configured[domain]
+}
+
+// Match returns the paused domain of a message, the sender domain or that
+// of a pending recipient. ok is false if delivery of the message may go on.
+func (p *Pauses) Match(msg *Message) (domain string, ok bool) {
+	if d := email.ExtractDomain(msg.From); d != "" && p.Paused(d) {
+		return d, true
+	}
+	for _, rcpt := range msg.PendingRecipients() {
+		if d := email.ExtractDomain(rcpt); d != "" && p.Paused(d) {
+			return d, true
+		}
+	}
+	return "", false
+}
+
+// Status returns the current pauses with domains in sorted order
+func (p *Pauses) Status() PauseStatus {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return PauseStatus{
+		Global:        p.global,
+		Domains:       sortedKeys(p.domains),
+		ConfigDomains: sortedKeys(p.configured),
+	}
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -2,17 +2,26 @@ package queue
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"regexp"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/foxzi/sendry/internal/email"
 	"github.com/foxzi/sendry/internal/metrics"
 	"github.com/foxzi/sendry/internal/ratelimit"
+	"github.com/foxzi/sendry/internal/retry"
+	"github.com/foxzi/sendry/internal/shaping"
+	"github.com/foxzi/sendry/internal/tracing"
 )
 
 // Sender is an interface for sending messages
@@ -25,6 +34,62 @@ type BounceGenerator interface {
 	GenerateDSN(msg *Message, errorMsg string, permanent bool) ([]byte, error)
 }
 
+// AutoResponder generates automatic replies (vacation messages) for delivered messages
+type AutoResponder interface {
+	Respond(ctx context.Context, msg *Message) ([]*Message, error)
+}
+
+// ListExpander expands mailing list recipients into per-member messages
+type ListExpander interface {
+	Expand(ctx context.Context, msg *Message) (*ListExpansion, error)
+}
+
+// Archiver keeps copies of delivered messages
+type Archiver interface {
+	Archive(ctx context.Context, msg *Message) error
+}
+
+// DeliveryRecorder records per-recipient delivery outcomes of sender
+// domains, e.g. for reputation scoring
+type DeliveryRecorder interface {
+	Delivered(sender string, recipients []string)
+	Deferred(sender string, recipients []string)
+	Failed(sender string, recipients []string)
+}
+
+// EventLogger records the delivery events of messages, e.g. for export to
+// log pipelines
+type EventLogger interface {
+	Delivered(msg *Message, recipients []string)
+	Deferred(msg *Message, recipients []string)
+	Bounced(msg *Message, recipients []string)
+}
+
+// Suppressor reports recipients that must not receive mail, e.g. after a
+// spam complaint
+type Suppressor interface {
+	Suppressed(ctx context.Context, recipient string) (bool, error)
+}
+
+// ErrSuppressed is the permanent error of messages whose pending recipients
+// are all on the suppression list
+var ErrSuppressed = errors.New("all recipients are suppressed")
+
+// ListExpansion is the result of expanding the list recipients of a message
+type ListExpansion struct {
+	Messages  []*Message // Per-member messages to enqueue
+	Remaining []string   // Recipients that are not list addresses
+	Rejected  []string   // List addresses the sender is not allowed to post to
+}
+
+// RetryScheduler decides when deferred recipients are retried
+type RetryScheduler interface {
+	// Next returns the delay before the next attempt of a recipient of
+	// domain deferred with an error of class after attempt n, false when
+	// the recipient used up its retries
+	Next(domain, class string, n int) (time.Duration, bool)
+}
+
 // DLQStorage is an interface for dead letter queue operations
 type DLQStorage interface {
 	MoveToDLQ(ctx context.Context, msg *Message) error
@@ -40,6 +105,7 @@ type Processor struct {
 	workers         int
 	retryInterval   time.Duration
 	maxRetries      int
+	maxDeliveryTime time.Duration
 	processInterval time.Duration
 	isTemporary     ErrorChecker
 	logger          *slog.Logger
@@ -47,9 +113,20 @@ type Processor struct {
 	bounceEnabled   bool
 	dlqEnabled      bool
 	rateLimiter     *ratelimit.Limiter
-
-	stopCh chan struct{}
-	wg     sync.WaitGroup
+	autoResponder   AutoResponder
+	listExpander    ListExpander
+	archiver        Archiver
+	recorder        DeliveryRecorder
+	events          EventLogger
+	shaper          *shaping.Shaper
+	suppressor      Suppressor
+	pauses          *Pauses
+	concurrency     *Concurrency
+	retries         RetryScheduler
+
+	stopCh  chan struct{}
+	wg      sync.WaitGroup
+	running atomic.Bool
 }
 
 // ProcessorConfig contains processor configuration
@@ -58,7 +135,8 @@ type ProcessorConfig struct {
 	RetryInterval   time.Duration
 	MaxRetries      int
 	ProcessInterval time.Duration
-	DLQEnabled      bool // Enable dead letter queue (if false, failed messages are deleted)
+	DLQEnabled      bool          // Enable dead letter queue (if false, failed messages are deleted)
+	MaxDeliveryTime time.Duration // Wall-clock delivery budget of a message (0 = unlimited)
 }
 
 // NewProcessor creates a new queue processor
@@ -85,6 +163,7 @@ func NewProcessor(q Queue, sender Sender, cfg ProcessorConfig, isTemp ErrorCheck
 		workers:         cfg.Workers,
 		retryInterval:   cfg.RetryInterval,
 		maxRetries:      cfg.MaxRetries,
+		maxDeliveryTime: cfg.MaxDeliveryTime,
 		processInterval: cfg.ProcessInterval,
 		isTemporary:     isTemp,
 		logger:          logger,
@@ -104,6 +183,57 @@ func (p *Processor) SetRateLimiter(rl *ratelimit.Limiter) {
 	p.rateLimiter = rl
 }
 
+// SetAutoResponder sets the auto-responder for delivered messages
+func (p *Processor) SetAutoResponder(ar AutoResponder) {
+	p.autoResponder = ar
+}
+
+// SetArchiver sets the archiver for delivered messages
+func (p *Processor) SetArchiver(a Archiver) {
+	p.archiver = a
+}
+
+// SetDeliveryRecorder sets the recorder of delivery outcomes
+func (p *Processor) SetDeliveryRecorder(r DeliveryRecorder) {
+	p.recorder = r
+}
+
+// SetEventLogger sets the logger of delivery events
+func (p *Processor) SetEventLogger(l EventLogger) {
+	p.events = l
+}
+
+// SetListExpander sets the mailing list expander
+func (p *Processor) SetListExpander(le ListExpander) {
+	p.listExpander = le
+}
+
+// SetTrafficShaper sets the shaper enforcing per-domain send schedules
+func (p *Processor) SetTrafficShaper(s *shaping.Shaper) {
+	p.shaper = s
+}
+
+// SetSuppressor sets the suppression list checked before delivery
+func (p *Processor) SetSuppressor(s Suppressor) {
+	p.suppressor = s
+}
+
+// SetPauses sets the delivery pauses honored by the workers
+func (p *Processor) SetPauses(pauses *Pauses) {
+	p.pauses = pauses
+}
+
+// SetConcurrency sets the limits of parallel deliveries per recipient domain
+func (p *Processor) SetConcurrency(c *Concurrency) {
+	p.concurrency = c
+}
+
+// SetRetryScheduler sets the retry policies of deferred recipients, which
+// replace the exponential backoff from RetryInterval
+func (p *Processor) SetRetryScheduler(s RetryScheduler) {
+	p.retries = s
+}
+
 // Start starts the processor workers
 func (p *Processor) Start(ctx context.Context) {
 	p.logger.Info("starting queue processor", "workers", p.workers)
@@ -112,16 +242,23 @@ func (p *Processor) Start(ctx context.Context) {
 		p.wg.Add(1)
 		go p.worker(ctx, i)
 	}
+	p.running.Store(true)
 }
 
 // Stop stops the processor gracefully
 func (p *Processor) Stop() {
 	p.logger.Info("stopping queue processor")
+	p.running.Store(false)
 	close(p.stopCh)
 	p.wg.Wait()
 	p.logger.Info("queue processor stopped")
 }
 
+// Running reports whether the workers have been started and not stopped
+func (p *Processor) Running() bool {
+	return p.running.Load()
+}
+
 // worker is the main processing loop
 func (p *Processor) worker(ctx context.Context, id int) {
 	defer p.wg.Done()
@@ -148,6 +285,11 @@ func (p *Processor) worker(ctx context.Context, id int) {
 
 // processOne processes a single message from the queue
 func (p *Processor) processOne(ctx context.Context, logger *slog.Logger) {
+	// Leave the queue untouched while all delivery is paused
+	if p.pauses != nil && p.pauses.Global() {
+		return
+	}
+
 	msg, err := p.queue.Dequeue(ctx)
 	if err != nil {
 		logger.Error("failed to dequeue message", "error", err)
@@ -161,9 +303,90 @@ func (p *Processor) processOne(ctx context.Context, logger *slog.Logger) {
 	logger = logger.With("message_id", msg.ID)
 	logger.Debug("processing message")
 
+	// Join the trace of the request that queued the message
+	ctx, span := tracing.Start(tracing.Extract(ctx, msg.TraceParent), "queue.process",
+		attribute.String("sendry.message.id", msg.ID),
+		attribute.Int("sendry.message.retry_count", msg.RetryCount),
+		attribute.Int("sendry.message.recipients", len(msg.PendingRecipients())),
+	)
+	defer endProcessSpan(span, msg)
+
+	// Expand mailing list recipients into per-member messages
+	if p.listExpander != nil && p.expandLists(ctx, msg, logger) {
+		return
+	}
+
+	// Hold messages of paused domains without counting an attempt
+	if p.pauses != nil {
+		if domain, paused := p.pauses.Match(msg); paused {
+			msg.Status = StatusDeferred
+			msg.LastError = "delivery paused: " + domain
+			msg.UpdatedAt = time.Now()
+			msg.NextRetryAt = time.Now().Add(pausedRetryDelay)
+
+			logger.Debug("message held by delivery pause", "domain", domain)
+
+			if err := p.queue.Update(ctx, msg); err != nil {
+				logger.Error("failed to update message status", "error", err)
+			}
+			return
+		}
+	}
+
+	// Check sender domain send schedule
+	if p.shaper != nil {
+		if domain := email.ExtractDomain(msg.From); domain != "" {
+			result, err := p.shaper.Allow(ctx, domain)
+			if err != nil {
+				logger.Error("failed to check send schedule", "error", err, "domain", domain)
+			} else if !result.Allowed {
+				msg.Status = StatusDeferred
+				msg.LastError = "send schedule limit reached: " + domain
+				msg.UpdatedAt = time.Now()
+				msg.NextRetryAt = time.Now().Add(result.RetryAfter)
+
+				logger.Info("message deferred by send schedule",
+					"domain", domain,
+					"hour_limit", result.Limit,
+					"next_retry_at", msg.NextRetryAt,
+				)
+
+				if err := p.queue.Update(ctx, msg); err != nil {
+					logger.Error("failed to update message status", "error", err)
+				}
+				return
+			}
+		}
+	}
+
+	// Fail suppressed recipients without contacting them
+	if p.suppressor != nil {
+		p.suppress(ctx, msg, logger)
+	}
+
+	// Hold the message briefly while a recipient domain has as many
+	// deliveries in flight as it allows
+	if p.concurrency != nil {
+		domains := pendingDomains(msg)
+		if domain, ok := p.concurrency.Acquire(domains); !ok {
+			msg.Status = StatusDeferred
+			msg.LastError = "recipient domain concurrency limit reached: " + domain
+			msg.UpdatedAt = time.Now()
+			msg.NextRetryAt = time.Now().Add(concurrencyRetryDelay)
+
+			logger.Debug("message deferred by recipient domain concurrency", "domain", domain)
+
+			if err := p.queue.Update(ctx, msg); err != nil {
+				logger.Error("failed to update message status", "error", err)
+			}
+			return
+		}
+		defer p.concurrency.Release(domains)
+	}
+
 	// Check recipient domain rate limits before sending
 	if p.rateLimiter != nil {
-		for _, rcpt := range msg.To {
+		for _, rcpt := range msg.PendingRecipients() {
 			domain := email.ExtractDomain(rcpt)
 			if domain == "" {
 				continue
@@ -176,6 +399,8 @@ func (p *Processor) processOne(ctx context.Context, logger *slog.Logger) {
 			}
 
 			if !result.Allowed {
+				metrics.IncRateLimitExceeded(string(result.DeniedBy))
+
 				// Rate limited - defer the message
 				msg.Status = StatusDeferred
 				msg.LastError = "recipient domain rate limit exceeded: " + domain
@@ -197,9 +422,14 @@ func (p *Processor) processOne(ctx context.Context, logger *slog.Logger) {
 	}
 
 	// Try to send
-	sendCtx, cancel := context.WithTimeout(ctx, 2*time.Minute)
-	err = p.sender.Send(sendCtx, msg)
-	cancel()
+	pending := msg.PendingRecipients()
+	if len(pending) == 0 {
+		err = ErrSuppressed
+	} else {
+		sendCtx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+		err = p.sender.Send(sendCtx, msg)
+		cancel()
+	}
 
 	if err == nil {
 		// Success
@@ -212,8 +442,17 @@ func (p *Processor) processOne(ctx context.Context, logger *slog.Logger) {
 
 		// Track metrics
 		metrics.IncMessagesSent(email.ExtractDomain(msg.From))
+		if p.recorder != nil {
+			p.recorder.Delivered(msg.From, pending)
+		}
+		if p.events != nil {
+			p.events.Delivered(msg, pending)
+		}
 
 		logger.Info("message delivered", "from", msg.From, "to", msg.To)
+
+		p.archive(ctx, msg, logger)
+		p.sendAutoReplies(ctx, msg, logger)
 		return
 	}
 
@@ -224,14 +463,31 @@ func (p *Processor) processOne(ctx context.Context, logger *slog.Logger) {
 	msg.LastError = err.Error()
 	msg.UpdatedAt = time.Now()
 
-	if p.isTemporary(err) && msg.RetryCount < p.maxRetries {
-		// Schedule retry with exponential backoff
-		backoff := p.calculateBackoff(msg.RetryCount)
+	temporary := !errors.Is(err, ErrSuppressed) && p.isTemporary(err)
+	deadline := p.deliveryDeadline(msg)
+	expired := !deadline.IsZero() && !time.Now().Before(deadline)
+
+	if temporary && p.rateLimiter != nil {
+		p.recordThrottles(msg, pending, err)
+	}
+
+	var backoff time.Duration
+	retrying := false
+	if temporary && !expired {
+		backoff, retrying = p.nextRetry(msg, err, logger)
+	}
+
+	if retrying {
+		// Schedule retry within the delivery budget
 		msg.Status = StatusDeferred
 		msg.NextRetryAt = time.Now().Add(backoff)
+		if !deadline.IsZero() && msg.NextRetryAt.After(deadline) {
+			msg.NextRetryAt = deadline
+		}
 
 		// Track metrics
 		metrics.IncMessagesDeferred(email.ExtractDomain(msg.From))
+		p.recordAttempt(msg, pending, false)
 
 		logger.Info("message deferred",
 			"retry_count", msg.RetryCount,
@@ -239,7 +495,34 @@ func (p *Processor) processOne(ctx context.Context, logger *slog.Logger) {
 			"backoff", backoff,
 		)
 	} else {
-		// Permanent failure or max retries exceeded
+		// Permanent failure or delivery budget exhausted
+		if temporary {
+			reason := "max retries exceeded"
+			if expired {
+				reason = "delivery time exceeded"
+			}
+			msg.ExpirePending(reason)
+		}
+		p.recordAttempt(msg, pending, true)
+
+		if delivered := msg.DeliveredRecipients(); len(delivered) > 0 {
+			// Some recipients got the message, only bounce the others
+			msg.Status = StatusDelivered
+			metrics.IncMessagesSent(email.ExtractDomain(msg.From))
+
+			logger.Warn("message partially delivered",
+				"delivered", delivered,
+				"failed", msg.UndeliveredRecipients(),
+			)
+
+			p.sendBounce(ctx, msg, err.Error(), logger)
+			if err := p.queue.Update(ctx, msg); err != nil {
+				logger.Error("failed to update message status", "error", err)
+			}
+			p.archive(ctx, msg, logger)
+			return
+		}
+
 		msg.Status = StatusFailed
 
 		// Track metrics
@@ -259,6 +542,7 @@ func (p *Processor) processOne(ctx context.Context, logger *slog.Logger) {
 				if err := dlq.MoveToDLQ(ctx, msg); err != nil {
 					logger.Error("failed to move message to DLQ", "error", err)
 				} else {
+					metrics.IncDLQMessages(email.ExtractDomain(msg.From))
 					logger.Info("message moved to DLQ", "id", msg.ID)
 					return // Already saved to DLQ, no need to Update
 				}
@@ -279,6 +563,113 @@ func (p *Processor) processOne(ctx context.Context, logger *slog.Logger) {
 	}
 }
 
+// endProcessSpan ends the processing span of a message with its outcome
+func endProcessSpan(span trace.Span, msg *Message) {
+	span.SetAttributes(attribute.String("sendry.message.status", string(msg.Status)))
+	if msg.Status != StatusDelivered && msg.LastError != "" {
+		span.SetAttributes(attribute.String("sendry.message.last_error", msg.LastError))
+	}
+	if msg.Status == StatusFailed {
+		span.SetStatus(codes.Error, msg.LastError)
+	}
+	span.End()
+}
+
+// recordThrottles reports the recipient domains that deferred the message
+// with a throttling response to the rate limiter, once per domain
+func (p *Processor) recordThrottles(msg *Message, pending []string, err error) {
+	seen := make(map[string]bool)
+	for _, rcpt := range pending {
+		domain := email.ExtractDomain(rcpt)
+		if domain == "" || seen[domain] {
+			continue
+		}
+
+		// Senders that do not record recipient results report one error
+		text := err.Error()
+		if r := msg.Results[rcpt]; r != nil {
+			if r.Status != StatusDeferred || r.Greylisted {
+				continue
+			}
+			text = r.Error
+		}
+
+		// Greylisting asks to come back later, not to send slower
+		if !retry.IsGreylisting(text) && isThrottleResponse(text) {
+			seen[domain] = true
+			p.rateLimiter.RecordThrottle(domain)
+		}
+	}
+}
+
+// nextRetry returns the delay before the next attempt of a deferred
+// message, false if it is not retried. With a retry scheduler every
+// deferred recipient follows the policy of its domain and error class:
+// recipients that used up their retries fail, and the message is retried
+// after the shortest delay of the others.
+func (p *Processor) nextRetry(msg *Message, err error, logger *slog.Logger) (time.Duration, bool) {
+	if p.retries == nil {
+		return p.calculateBackoff(msg.RetryCount), msg.RetryCount < p.maxRetries
+	}
+
+	var delay time.Duration
+	var exhausted []string
+	retrying := false
+	for _, rcpt := range msg.PendingRecipients() {
+		// Senders that do not record recipient results report one error
+		text := err.Error()
+		if r := msg.Results[rcpt]; r != nil && r.Error != "" {
+			text = r.Error
+		}
+
+		class := retryClass(text)
+		if r := msg.Results[rcpt]; r != nil && r.Greylisted {
+			class = retry.ClassGreylisting
+		}
+
+		d, ok := p.retries.Next(email.ExtractDomain(rcpt), class, msg.RetryCount)
+		if !ok {
+			exhausted = append(exhausted, rcpt)
+			continue
+		}
+		if !retrying || d < delay {
+			delay = d
+		}
+		retrying = true
+	}
+
+	if retrying && len(exhausted) > 0 {
+		msg.Expire(exhausted, "max retries exceeded")
+		logger.Info("recipients out of retries", "recipients", exhausted)
+	}
+	return delay, retrying
+}
+
+// suppress marks the pending recipients on the suppression list as failed.
+// Lookup errors let the recipient through.
+func (p *Processor) suppress(ctx context.Context, msg *Message, logger *slog.Logger) {
+	var suppressed []string
+	for _, rcpt := range msg.PendingRecipients() {
+		ok, err := p.suppressor.Suppressed(ctx, rcpt)
+		if err != nil {
+			logger.Error("failed to check suppression list", "error", err, "recipient", rcpt)
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		msg.SetResult(rcpt, RecipientResult{Status: StatusFailed, Error: "recipient is on the suppression list"})
+		metrics.IncSuppressedRecipients()
+		logger.Info("suppressed recipient skipped", "recipient", rcpt)
+		suppressed = append(suppressed, rcpt)
+	}
+
+	if p.events != nil {
+		p.events.Bounced(msg, suppressed)
+	}
+}
+
 // sendBounce generates and queues a bounce (NDR) message
 func (p *Processor) sendBounce(ctx context.Context, msg *Message, errorMsg string, logger *slog.Logger) {
 	if !p.bounceEnabled || p.bounceGenerator == nil {
@@ -291,6 +682,13 @@ func (p *Processor) sendBounce(ctx context.Context, msg *Message, errorMsg strin
 		return
 	}
 
+	// Only report the recipients that did not get the message
+	if undelivered := msg.UndeliveredRecipients(); len(undelivered) < len(msg.To) {
+		failed := *msg
+		failed.To = undelivered
+		msg = &failed
+	}
+
 	// Generate DSN
 	bounceData, err := p.bounceGenerator.GenerateDSN(msg, errorMsg, true)
 	if err != nil {
@@ -321,6 +719,113 @@ func (p *Processor) sendBounce(ctx context.Context, msg *Message, errorMsg strin
 	logger.Info("bounce message queued", "bounce_id", bounceMsg.ID, "original_sender", msg.From)
 }
 
+// expandLists replaces list recipients with per-member messages.
+// Returns true if no recipients are left to deliver for the original message.
+func (p *Processor) expandLists(ctx context.Context, msg *Message, logger *slog.Logger) bool {
+	expansion, err := p.listExpander.Expand(ctx, msg)
+	if err != nil {
+		logger.Error("failed to expand mailing lists", "error", err)
+		return false
+	}
+	if expansion == nil {
+		return false // No list recipients
+	}
+
+	for _, member := range expansion.Messages {
+		if err := p.queue.Enqueue(ctx, member); err != nil {
+			logger.Error("failed to enqueue list member message", "error", err, "to", member.To)
+		}
+	}
+
+	if len(expansion.Rejected) > 0 {
+		logger.Info("sender not allowed to post to list", "from", msg.From, "lists", expansion.Rejected)
+		rejected := *msg
+		rejected.To = expansion.Rejected
+		p.sendBounce(ctx, &rejected, "sender is not allowed to post to this list", logger)
+	}
+
+	logger.Info("mailing lists expanded",
+		"member_messages", len(expansion.Messages),
+		"remaining", len(expansion.Remaining),
+	)
+
+	// Persist remaining recipients so a retry does not expand the lists again
+	msg.To = expansion.Remaining
+	msg.UpdatedAt = time.Now()
+	done := len(msg.To) == 0
+	if done {
+		msg.Status = StatusDelivered
+	}
+	if err := p.queue.Update(ctx, msg); err != nil {
+		logger.Error("failed to update message status", "error", err)
+	}
+	return done
+}
+
+// recordAttempt records the outcome of a failed delivery attempt for the
+// recipients that were pending before it. After the last attempt, recipients
+// that were not delivered count as failed.
+func (p *Processor) recordAttempt(msg *Message, pending []string, last bool) {
+	if p.recorder == nil && p.events == nil {
+		return
+	}
+
+	var delivered, deferred, failed []string
+	for _, rcpt := range pending {
+		result := msg.Results[rcpt]
+		switch {
+		case result != nil && result.Status == StatusDelivered:
+			delivered = append(delivered, rcpt)
+		case last || (result != nil && result.Status == StatusFailed):
+			failed = append(failed, rcpt)
+		default:
+			deferred = append(deferred, rcpt)
+		}
+	}
+
+	if p.recorder != nil {
+		p.recorder.Delivered(msg.From, delivered)
+		p.recorder.Deferred(msg.From, deferred)
+		p.recorder.Failed(msg.From, failed)
+	}
+	if p.events != nil {
+		p.events.Delivered(msg, delivered)
+		p.events.Deferred(msg, deferred)
+		p.events.Bounced(msg, failed)
+	}
+}
+
+// archive stores a copy of a delivered message. Archive errors do not
+// affect delivery.
+func (p *Processor) archive(ctx context.Context, msg *Message, logger *slog.Logger) {
+	if p.archiver == nil {
+		return
+	}
+	if err := p.archiver.Archive(ctx, msg); err != nil {
+		logger.Error("failed to archive message", "error", err)
+	}
+}
+
+// sendAutoReplies generates and queues auto-replies for a delivered message
+func (p *Processor) sendAutoReplies(ctx context.Context, msg *Message, logger *slog.Logger) {
+	if p.autoResponder == nil || isBounceMessage(msg) {
+		return
+	}
+
+	replies, err := p.autoResponder.Respond(ctx, msg)
+	if err != nil {
+		logger.Error("failed to generate auto-reply", "error", err)
+	}
+
+	for _, reply := range replies {
+		if err := p.queue.Enqueue(ctx, reply); err != nil {
+			logger.Error("failed to enqueue auto-reply", "error", err)
+			continue
+		}
+		logger.Info("auto-reply queued", "reply_id", reply.ID, "from", reply.From, "to", reply.To)
+	}
+}
+
 // isBounceMessage checks if message is a bounce (to prevent loops)
 func isBounceMessage(msg *Message) bool {
 	// Check if message ID contains -bounce suffix
@@ -342,6 +847,19 @@ func isBounceMessage(msg *Message) bool {
 	return false
 }
 
+// deliveryDeadline returns when the delivery time budget of a message runs
+// out, counted from its creation or scheduled send time. Zero means no limit.
+func (p *Processor) deliveryDeadline(msg *Message) time.Time {
+	if p.maxDeliveryTime <= 0 {
+		return time.Time{}
+	}
+	start := msg.CreatedAt
+	if msg.SendAt.After(start) {
+		start = msg.SendAt
+	}
+	return start.Add(p.maxDeliveryTime)
+}
+
 // calculateBackoff calculates exponential backoff duration
 func (p *Processor) calculateBackoff(retryCount int) time.Duration {
 	// Exponential backoff: retry_interval * 2^(retry_count-1)
@@ -365,6 +883,61 @@ func (p *Processor) calculateBackoff(retryCount int) time.Duration {
 // smtpCodePattern matches SMTP response codes at word boundaries
 var smtpCodePattern = regexp.MustCompile(`\b(4\d{2}|5\d{2})\b`)
 
+// throttlePhrases are the parts of temporary SMTP responses that providers
+// use to ask senders to slow down
+var throttlePhrases = []string{
+	"too many", "rate limit", "ratelimit", "throttl", "try again later",
+	"connection limit", "too fast", "too quickly", "slow down", "exceeded",
+}
+
+// isThrottleResponse reports whether a delivery error is a temporary
+// 421/450/451 response asking to send slower
+func isThrottleResponse(text string) bool {
+	matches := smtpCodePattern.FindStringSubmatch(text)
+	if len(matches) < 2 {
+		return false
+	}
+	switch matches[1] {
+	case "421", "450", "451":
+	default:
+		return false
+	}
+
+	text = strings.ToLower(text)
+	for _, phrase := range throttlePhrases {
+		if strings.Contains(text, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// connectionPhrases are the parts of delivery errors without an SMTP reply
+// that mean the destination could not be reached
+var connectionPhrases = []string{
+	"connection failed", "connection refused", "connection reset", "connection aborted",
+	"timeout", "timed out", "no such host", "mx lookup failed", "no mx hosts", "broken pipe", "eof",
+}
+
+// retryClass returns the retry error class of a temporary delivery error
+func retryClass(text string) string {
+	lower := strings.ToLower(text)
+	if smtpCodePattern.MatchString(text) {
+		switch {
+		case retry.IsGreylisting(text):
+			return retry.ClassGreylisting
+		case isThrottleResponse(text):
+			return retry.ClassThrottle
+		default:
+			return retry.ClassTemporary
+		}
+	}
+	if slices.ContainsFunc(connectionPhrases, func(phrase string) bool { return strings.Contains(lower, phrase) }) {
+		return retry.ClassConnection
+	}
+	return retry.ClassTemporary
+}
+
 // classifyError classifies delivery error into category for metrics
 func classifyError(err error) string {
 	if err == nil {
@@ -6,10 +6,14 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/foxzi/sendry/internal/ratelimit"
+	"github.com/foxzi/sendry/internal/retry"
+	"github.com/foxzi/sendry/internal/shaping"
 )
 
 // mockSender implements Sender for testing
@@ -173,6 +177,248 @@ func TestProcessorRetryOnError(t *testing.T) {
 	}
 }
 
+// mockAutoResponder implements AutoResponder for testing
+type mockAutoResponder struct {
+	calls int
+}
+
+func (m *mockAutoResponder) Respond(ctx context.Context, msg *Message) ([]*Message, error) {
+	m.calls++
+	return []*Message{{
+		ID:        msg.ID + "-autoreply",
+		From:      msg.To[0],
+		To:        []string{msg.From},
+		Data:      []byte("auto reply"),
+		Status:    StatusPending,
+		CreatedAt: time.Now(),
+	}}, nil
+}
+
+func TestProcessorAutoReply(t *testing.T) {
+	tmpDir := t.TempDir()
+	storage, err := NewBoltStorage(filepath.Join(tmpDir, "queue.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storage.Close()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	processor := NewProcessor(storage, &mockSender{}, ProcessorConfig{Workers: 1}, nil, logger)
+	responder := &mockAutoResponder{}
+	processor.SetAutoResponder(responder)
+
+	msg := &Message{
+		ID:        "incoming",
+		From:      "sender@remote.com",
+		To:        []string{"user@example.com"},
+		Data:      []byte("test"),
+		Status:    StatusPending,
+		CreatedAt: time.Now(),
+	}
+	if err := storage.Enqueue(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+
+	processor.processOne(context.Background(), logger)
+
+	if responder.calls != 1 {
+		t.Fatalf("expected auto-responder to be called once, got %d", responder.calls)
+	}
+
+	reply, err := storage.Get(context.Background(), "incoming-autoreply")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply == nil || reply.Status != StatusPending {
+		t.Errorf("expected auto-reply to be queued, got %+v", reply)
+	}
+}
+
+// mockArchiver implements Archiver for testing
+type mockArchiver struct {
+	archived []string
+}
+
+func (m *mockArchiver) Archive(ctx context.Context, msg *Message) error {
+	m.archived = append(m.archived, msg.ID)
+	return errors.New("archive unavailable")
+}
+
+func TestProcessorArchive(t *testing.T) {
+	tmpDir := t.TempDir()
+	storage, err := NewBoltStorage(filepath.Join(tmpDir, "queue.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storage.Close()
+
+	sender := &mockSender{sendFunc: func(ctx context.Context, msg *Message) error {
+		if msg.ID == "rejected" {
+			return errors.New("550 mailbox unavailable")
+		}
+		return nil
+	}}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError + 4}))
+	processor := NewProcessor(storage, sender, ProcessorConfig{Workers: 1}, func(error) bool { return false }, logger)
+	archiver := &mockArchiver{}
+	processor.SetArchiver(archiver)
+
+	for _, id := range []string{"delivered", "rejected"} {
+		msg := &Message{
+			ID:        id,
+			From:      "sender@example.com",
+			To:        []string{"user@example.org"},
+			Data:      []byte("test"),
+			Status:    StatusPending,
+			CreatedAt: time.Now(),
+		}
+		if err := storage.Enqueue(context.Background(), msg); err != nil {
+			t.Fatal(err)
+		}
+		processor.processOne(context.Background(), logger)
+	}
+
+	if len(archiver.archived) != 1 || archiver.archived[0] != "delivered" {
+		t.Errorf("archived = %v, want only the delivered message", archiver.archived)
+	}
+
+	// Archive errors do not affect delivery
+	msg, err := storage.Get(context.Background(), "delivered")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Status != StatusDelivered {
+		t.Errorf("status = %s, want %s", msg.Status, StatusDelivered)
+	}
+}
+
+// mockListExpander implements ListExpander for testing
+type mockListExpander struct{}
+
+func (m *mockListExpander) Expand(ctx context.Context, msg *Message) (*ListExpansion, error) {
+	expansion := &ListExpansion{}
+	for _, rcpt := range msg.To {
+		if rcpt != "team@example.com" {
+			expansion.Remaining = append(expansion.Remaining, rcpt)
+			continue
+		}
+		for _, member := range []string{"a@example.com", "b@example.com"} {
+			expansion.Messages = append(expansion.Messages, &Message{
+				ID:        msg.ID + "-" + member,
+				From:      msg.From,
+				To:        []string{member},
+				Data:      msg.Data,
+				Status:    StatusPending,
+				CreatedAt: time.Now(),
+			})
+		}
+	}
+	return expansion, nil
+}
+
+func TestProcessorListExpansion(t *testing.T) {
+	tmpDir := t.TempDir()
+	storage, err := NewBoltStorage(filepath.Join(tmpDir, "queue.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storage.Close()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	sender := &mockSender{}
+	processor := NewProcessor(storage, sender, ProcessorConfig{Workers: 1}, nil, logger)
+	processor.SetListExpander(&mockListExpander{})
+
+	msg := &Message{
+		ID:        "list-msg",
+		From:      "sender@remote.com",
+		To:        []string{"team@example.com"},
+		Data:      []byte("test"),
+		Status:    StatusPending,
+		CreatedAt: time.Now(),
+	}
+	if err := storage.Enqueue(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+
+	processor.processOne(context.Background(), logger)
+
+	if len(sender.sent) != 0 {
+		t.Errorf("expected list message not to be sent directly, got %d sends", len(sender.sent))
+	}
+
+	original, _ := storage.Get(context.Background(), "list-msg")
+	if original == nil || original.Status != StatusDelivered {
+		t.Errorf("expected original message to be marked delivered, got %+v", original)
+	}
+
+	for _, id := range []string{"list-msg-a@example.com", "list-msg-b@example.com"} {
+		member, _ := storage.Get(context.Background(), id)
+		if member == nil || member.Status != StatusPending {
+			t.Errorf("expected member message %s to be queued, got %+v", id, member)
+		}
+	}
+}
+
+func TestProcessorTrafficShaping(t *testing.T) {
+	tmpDir := t.TempDir()
+	storage, err := NewBoltStorage(filepath.Join(tmpDir, "queue.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storage.Close()
+
+	shapingStorage, err := shaping.NewStorage(storage.DB())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// One message per hour, every hour of the week
+	err = shapingStorage.Put(context.Background(), &shaping.Schedule{
+		Domain:  "example.com",
+		Hours:   shaping.Flat(1),
+		Enabled: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	sender := &mockSender{}
+	processor := NewProcessor(storage, sender, ProcessorConfig{Workers: 1}, nil, logger)
+	processor.SetTrafficShaper(shaping.NewShaper(shapingStorage))
+
+	for _, id := range []string{"msg-1", "msg-2"} {
+		msg := &Message{
+			ID:        id,
+			From:      "sender@example.com",
+			To:        []string{"rcpt@remote.com"},
+			Data:      []byte("test"),
+			Status:    StatusPending,
+			CreatedAt: time.Now(),
+		}
+		if err := storage.Enqueue(context.Background(), msg); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond) // Keep pending order stable
+	}
+
+	processor.processOne(context.Background(), logger)
+	processor.processOne(context.Background(), logger)
+
+	if len(sender.sent) != 1 {
+		t.Fatalf("expected 1 message sent within the hourly limit, got %d", len(sender.sent))
+	}
+
+	deferred, _ := storage.Get(context.Background(), "msg-2")
+	if deferred == nil || deferred.Status != StatusDeferred {
+		t.Fatalf("expected second message to be deferred, got %+v", deferred)
+	}
+	if !deferred.NextRetryAt.After(time.Now()) || deferred.NextRetryAt.After(time.Now().Add(time.Hour)) {
+		t.Errorf("expected retry at the next hour, got %v", deferred.NextRetryAt)
+	}
+}
+
 func TestClassifyError(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -252,3 +498,689 @@ func TestCalculateBackoff(t *testing.T) {
 		}
 	}
 }
+
+// mockBounceGenerator records the messages it was asked to bounce
+type mockBounceGenerator struct {
+	bounced []*Message
+}
+
+func (m *mockBounceGenerator) GenerateDSN(msg *Message, errorMsg string, permanent bool) ([]byte, error) {
+	m.bounced = append(m.bounced, msg)
+	return []byte("bounce"), nil
+}
+
+func TestProcessorPartialDelivery(t *testing.T) {
+	storage, err := NewBoltStorage(filepath.Join(t.TempDir(), "queue.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storage.Close()
+
+	sender := &mockSender{
+		sendFunc: func(ctx context.Context, msg *Message) error {
+			msg.SetResult("a@test.com", RecipientResult{Status: StatusDelivered})
+			msg.SetResult("b@test.com", RecipientResult{Status: StatusFailed, Error: "550 5.1.1 User unknown"})
+			return errors.New("b@test.com: 550 5.1.1 User unknown")
+		},
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	isTemp := func(err error) bool { return false }
+	processor := NewProcessor(storage, sender, ProcessorConfig{Workers: 1, DLQEnabled: true}, isTemp, logger)
+	bounces := &mockBounceGenerator{}
+	processor.SetBounceGenerator(bounces)
+
+	msg := &Message{
+		ID:        "partial",
+		From:      "sender@example.com",
+		To:        []string{"a@test.com", "b@test.com"},
+		Data:      []byte("test"),
+		Status:    StatusPending,
+		CreatedAt: time.Now(),
+	}
+	if err := storage.Enqueue(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+
+	processor.processOne(context.Background(), logger)
+
+	got, err := storage.Get(context.Background(), "partial")
+	if err != nil || got == nil {
+		t.Fatalf("Get() = %v, %v", got, err)
+	}
+	if got.Status != StatusDelivered {
+		t.Errorf("status = %s, want delivered", got.Status)
+	}
+	if len(bounces.bounced) != 1 || len(bounces.bounced[0].To) != 1 || bounces.bounced[0].To[0] != "b@test.com" {
+		t.Errorf("bounced %+v, want only b@test.com", bounces.bounced)
+	}
+}
+
+// mockRecorder implements DeliveryRecorder for testing
+type mockRecorder struct {
+	delivered, deferred, failed []string
+}
+
+func (m *mockRecorder) Delivered(sender string, recipients []string) {
+	m.delivered = append(m.delivered, recipients...)
+}
+
+func (m *mockRecorder) Deferred(sender string, recipients []string) {
+	m.deferred = append(m.deferred, recipients...)
+}
+
+func (m *mockRecorder) Failed(sender string, recipients []string) {
+	m.failed = append(m.failed, recipients...)
+}
+
+func TestProcessorDeliveryRecorder(t *testing.T) {
+	storage, err := NewBoltStorage(filepath.Join(t.TempDir(), "queue.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storage.Close()
+
+	sender := &mockSender{
+		sendFunc: func(ctx context.Context, msg *Message) error {
+			switch msg.ID {
+			case "delivered":
+				return nil
+			case "deferred":
+				msg.SetResult("a@test.com", RecipientResult{Status: StatusDelivered})
+				msg.SetResult("b@test.com", RecipientResult{Status: StatusDeferred, Error: "451 try later"})
+				return errors.New("b@test.com: 451 try later")
+			}
+			return errors.New("550 relay denied")
+		},
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError + 4}))
+	isTemp := func(err error) bool { return strings.Contains(err.Error(), "451") }
+	processor := NewProcessor(storage, sender, ProcessorConfig{Workers: 1, DLQEnabled: true}, isTemp, logger)
+	recorder := &mockRecorder{}
+	processor.SetDeliveryRecorder(recorder)
+
+	for _, id := range []string{"delivered", "deferred", "rejected"} {
+		msg := &Message{
+			ID:        id,
+			From:      "sender@example.com",
+			To:        []string{"a@test.com", "b@test.com"},
+			Data:      []byte("test"),
+			Status:    StatusPending,
+			CreatedAt: time.Now(),
+		}
+		if err := storage.Enqueue(context.Background(), msg); err != nil {
+			t.Fatal(err)
+		}
+		processor.processOne(context.Background(), logger)
+	}
+
+	if len(recorder.delivered) != 3 || len(recorder.deferred) != 1 || recorder.deferred[0] != "b@test.com" || len(recorder.failed) != 2 {
+		t.Errorf("delivered = %v, deferred = %v, failed = %v", recorder.delivered, recorder.deferred, recorder.failed)
+	}
+}
+
+// mockEventLogger implements EventLogger for testing, recording
+// "event id recipient" entries
+type mockEventLogger struct {
+	events []string
+}
+
+func (m *mockEventLogger) add(event string, msg *Message, recipients []string) {
+	for _, rcpt := range recipients {
+		m.events = append(m.events, event+" "+msg.ID+" "+rcpt)
+	}
+}
+
+func (m *mockEventLogger) Delivered(msg *Message, recipients []string) {
+	m.add("delivered", msg, recipients)
+}
+
+func (m *mockEventLogger) Deferred(msg *Message, recipients []string) {
+	m.add("deferred", msg, recipients)
+}
+
+func (m *mockEventLogger) Bounced(msg *Message, recipients []string) {
+	m.add("bounced", msg, recipients)
+}
+
+func TestProcessorEventLogger(t *testing.T) {
+	storage, err := NewBoltStorage(filepath.Join(t.TempDir(), "queue.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storage.Close()
+
+	sender := &mockSender{
+		sendFunc: func(ctx context.Context, msg *Message) error {
+			switch msg.ID {
+			case "delivered":
+				return nil
+			case "deferred":
+				msg.SetResult("a@test.com", RecipientResult{Status: StatusDelivered})
+				msg.SetResult("b@test.com", RecipientResult{Status: StatusDeferred, Error: "451 try later"})
+				return errors.New("b@test.com: 451 try later")
+			}
+			return errors.New("550 relay denied")
+		},
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError + 4}))
+	isTemp := func(err error) bool { return strings.Contains(err.Error(), "451") }
+	processor := NewProcessor(storage, sender, ProcessorConfig{Workers: 1, DLQEnabled: true}, isTemp, logger)
+	processor.SetSuppressor(mockSuppressor{"complained@test.com": true})
+	events := &mockEventLogger{}
+	processor.SetEventLogger(events)
+
+	for _, id := range []string{"delivered", "deferred", "rejected"} {
+		to := []string{"a@test.com", "b@test.com"}
+		if id == "rejected" {
+			to = []string{"a@test.com", "complained@test.com"}
+		}
+		msg := &Message{
+			ID:        id,
+			From:      "sender@example.com",
+			To:        to,
+			Data:      []byte("test"),
+			Status:    StatusPending,
+			CreatedAt: time.Now(),
+		}
+		if err := storage.Enqueue(context.Background(), msg); err != nil {
+			t.Fatal(err)
+		}
+		processor.processOne(context.Background(), logger)
+	}
+
+	want := []string{
+		"delivered delivered a@test.com",
+		"delivered delivered b@test.com",
+		"delivered deferred a@test.com",
+		"deferred deferred b@test.com",
+		"bounced rejected complained@test.com",
+		"bounced rejected a@test.com",
+	}
+	if !reflect.DeepEqual(events.events, want) {
+		t.Errorf("events = %v, want %v", events.events, want)
+	}
+}
+
+type mockSuppressor map[string]bool
+
+func (m mockSuppressor) Suppressed(ctx context.Context, recipient string) (bool, error) {
+	return m[recipient], nil
+}
+
+func TestProcessorSuppression(t *testing.T) {
+	storage, err := NewBoltStorage(filepath.Join(t.TempDir(), "queue.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storage.Close()
+
+	var sentTo [][]string
+	sender := &mockSender{
+		sendFunc: func(ctx context.Context, msg *Message) error {
+			sentTo = append(sentTo, msg.PendingRecipients())
+			return nil
+		},
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError + 4}))
+	processor := NewProcessor(storage, sender, ProcessorConfig{Workers: 1, DLQEnabled: true}, nil, logger)
+	processor.SetSuppressor(mockSuppressor{"complained@test.com": true})
+
+	for id, to := range map[string][]string{
+		"partial": {"complained@test.com", "ok@test.com"},
+		"all":     {"complained@test.com"},
+	} {
+		msg := &Message{
+			ID:        id,
+			From:      "sender@example.com",
+			To:        to,
+			Data:      []byte("test"),
+			Status:    StatusPending,
+			CreatedAt: time.Now(),
+		}
+		if err := storage.Enqueue(context.Background(), msg); err != nil {
+			t.Fatal(err)
+		}
+		processor.processOne(context.Background(), logger)
+	}
+
+	// Only the recipient that is not suppressed is contacted
+	if len(sentTo) != 1 || len(sentTo[0]) != 1 || sentTo[0][0] != "ok@test.com" {
+		t.Errorf("sent to %v, want [[ok@test.com]]", sentTo)
+	}
+
+	partial, err := storage.Get(context.Background(), "partial")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if partial.Status != StatusDelivered {
+		t.Errorf("partial status = %s, want delivered", partial.Status)
+	}
+	if r := partial.Results["complained@test.com"]; r == nil || r.Status != StatusFailed {
+		t.Errorf("suppressed recipient result = %+v", r)
+	}
+
+	// A message with only suppressed recipients fails without a retry
+	all, err := storage.Get(context.Background(), "all")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if all.Status != StatusFailed || all.RetryCount != 1 || !strings.Contains(all.LastError, "suppressed") {
+		t.Errorf("all suppressed: status = %s, retries = %d, error = %q", all.Status, all.RetryCount, all.LastError)
+	}
+}
+
+func TestProcessorDeliveryTimeBudget(t *testing.T) {
+	storage, err := NewBoltStorage(filepath.Join(t.TempDir(), "queue.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storage.Close()
+
+	sender := &mockSender{
+		sendFunc: func(ctx context.Context, msg *Message) error {
+			msg.SetResult("a@test.com", RecipientResult{Status: StatusDeferred, MXHost: "mx.test.com", Error: "451 4.3.0 Try later"})
+			return errors.New("a@test.com: 451 4.3.0 Try later")
+		},
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	cfg := ProcessorConfig{Workers: 1, MaxRetries: 10, MaxDeliveryTime: time.Hour, DLQEnabled: true}
+	processor := NewProcessor(storage, sender, cfg, nil, logger)
+
+	// A fresh message is retried no later than its deadline
+	msg := &Message{
+		ID:        "budget",
+		From:      "sender@example.com",
+		To:        []string{"a@test.com"},
+		Data:      []byte("test"),
+		Status:    StatusPending,
+		CreatedAt: time.Now().Add(-59*time.Minute - 58*time.Second),
+	}
+	if err := storage.Enqueue(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	processor.processOne(context.Background(), logger)
+
+	got, err := storage.Get(context.Background(), "budget")
+	if err != nil || got == nil {
+		t.Fatalf("Get() = %v, %v", got, err)
+	}
+	if got.Status != StatusDeferred {
+		t.Fatalf("status = %s, want deferred", got.Status)
+	}
+	if deadline := got.CreatedAt.Add(time.Hour); got.NextRetryAt.After(deadline) {
+		t.Errorf("NextRetryAt = %v, want no later than %v", got.NextRetryAt, deadline)
+	}
+
+	// Once the budget is spent the recipient expires
+	got.CreatedAt = time.Now().Add(-2 * time.Hour)
+	got.NextRetryAt = time.Time{}
+	if err := storage.Update(context.Background(), got); err != nil {
+		t.Fatal(err)
+	}
+	processor.processOne(context.Background(), logger)
+
+	failed, err := storage.GetFromDLQ(context.Background(), "budget")
+	if err != nil || failed == nil {
+		t.Fatalf("GetFromDLQ() = %v, %v", failed, err)
+	}
+	r := failed.Results["a@test.com"]
+	if r == nil || r.Status != StatusFailed || !r.Expired {
+		t.Errorf("result = %+v, want expired failure", r)
+	}
+}
+
+func TestProcessorPause(t *testing.T) {
+	storage, err := NewBoltStorage(filepath.Join(t.TempDir(), "queue.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storage.Close()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	sender := &mockSender{}
+	processor := NewProcessor(storage, sender, ProcessorConfig{Workers: 1}, nil, logger)
+	pauses := NewPauses()
+	processor.SetPauses(pauses)
+
+	ctx := context.Background()
+	msg := &Message{
+		ID:        "msg-1",
+		From:      "sender@example.com",
+		To:        []string{"rcpt@throttled.com"},
+		Data:      []byte("test"),
+		Status:    StatusPending,
+		CreatedAt: time.Now(),
+	}
+	if err := storage.Enqueue(ctx, msg); err != nil {
+		t.Fatal(err)
+	}
+
+	// A global pause leaves the queue untouched
+	pauses.Pause("")
+	processor.processOne(ctx, logger)
+	if got, _ := storage.Get(ctx, "msg-1"); got.Status != StatusPending || len(sender.sent) != 0 {
+		t.Fatalf("global pause: status = %s, sent = %d", got.Status, len(sender.sent))
+	}
+	pauses.Resume("")
+
+	// A paused recipient domain defers the message without an attempt
+	pauses.Pause("THROTTLED.com")
+	processor.processOne(ctx, logger)
+	got, _ := storage.Get(ctx, "msg-1")
+	if got.Status != StatusDeferred || got.RetryCount != 0 || len(sender.sent) != 0 {
+		t.Fatalf("domain pause: status = %s, retries = %d, sent = %d", got.Status, got.RetryCount, len(sender.sent))
+	}
+	if !strings.Contains(got.LastError, "throttled.com") || !got.NextRetryAt.After(time.Now()) {
+		t.Errorf("domain pause: error = %q, next retry = %v", got.LastError, got.NextRetryAt)
+	}
+}
+
+func TestProcessorAdaptiveThrottle(t *testing.T) {
+	storage, err := NewBoltStorage(filepath.Join(t.TempDir(), "queue.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storage.Close()
+
+	limiter, err := ratelimit.NewLimiter(storage.DB(), &ratelimit.Config{
+		Adaptive: &ratelimit.AdaptiveConfig{Enabled: true, Threshold: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer limiter.Stop()
+
+	sender := &mockSender{
+		sendFunc: func(ctx context.Context, msg *Message) error {
+			msg.SetResult("a@gmail.com", RecipientResult{Status: StatusDeferred, Error: "421 4.7.28 Too many connections, try again later"})
+			msg.SetResult("b@gmail.com", RecipientResult{Status: StatusDeferred, Error: "421 4.7.28 Too many connections, try again later"})
+			msg.SetResult("c@example.org", RecipientResult{Status: StatusDeferred, Error: "450 4.2.1 Mailbox busy"})
+			return errors.New("a@gmail.com: 421 4.7.28 Too many connections (and 2 more recipients)")
+		},
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	isTemp := func(err error) bool { return true }
+	processor := NewProcessor(storage, sender, ProcessorConfig{Workers: 1, MaxRetries: 3, RetryInterval: time.Minute}, isTemp, logger)
+	processor.SetRateLimiter(limiter)
+
+	msg := &Message{
+		ID:        "throttled",
+		From:      "sender@example.com",
+		To:        []string{"a@gmail.com", "b@gmail.com", "c@example.org"},
+		Data:      []byte("test"),
+		Status:    StatusPending,
+		CreatedAt: time.Now(),
+	}
+	if err := storage.Enqueue(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+
+	processor.processOne(context.Background(), logger)
+
+	// Only the throttling domain is slowed down, once per attempt
+	rates := limiter.AdaptiveRates()
+	if len(rates) != 1 || rates[0].Domain != "gmail.com" || rates[0].Factor != 0.5 {
+		t.Fatalf("AdaptiveRates() = %+v", rates)
+	}
+}
+
+func TestProcessorGreylistingKeepsRate(t *testing.T) {
+	storage, err := NewBoltStorage(filepath.Join(t.TempDir(), "queue.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storage.Close()
+
+	limiter, err := ratelimit.NewLimiter(storage.DB(), &ratelimit.Config{
+		Adaptive: &ratelimit.AdaptiveConfig{Enabled: true, Threshold: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer limiter.Stop()
+
+	sender := &mockSender{
+		sendFunc: func(ctx context.Context, msg *Message) error {
+			msg.SetResult("a@grey.test", RecipientResult{Status: StatusDeferred, Error: "450 4.2.0 Greylisted, please try again later"})
+			msg.SetResult("b@flagged.test", RecipientResult{Status: StatusDeferred, Error: "451 4.7.1 Too many senders, try again later", Greylisted: true})
+			return errors.New("a@grey.test: 450 4.2.0 Greylisted, please try again later (and 1 more recipient)")
+		},
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	isTemp := func(err error) bool { return true }
+	processor := NewProcessor(storage, sender, ProcessorConfig{Workers: 1, MaxRetries: 3, RetryInterval: time.Minute}, isTemp, logger)
+	processor.SetRateLimiter(limiter)
+
+	msg := &Message{
+		ID:        "greylisted",
+		From:      "sender@example.com",
+		To:        []string{"a@grey.test", "b@flagged.test"},
+		Data:      []byte("test"),
+		Status:    StatusPending,
+		CreatedAt: time.Now(),
+	}
+	if err := storage.Enqueue(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+
+	processor.processOne(context.Background(), logger)
+
+	if rates := limiter.AdaptiveRates(); len(rates) != 0 {
+		t.Errorf("greylisting slowed down domains: %+v", rates)
+	}
+}
+
+func TestIsThrottleResponse(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"421 4.7.0 Too many concurrent SMTP connections", true},
+		{"450 4.2.1 The user you are trying to contact is receiving mail too quickly, rate limited", true},
+		{"421 4.7.28 Our system has detected an unusual rate of unsolicited mail, try again later", true},
+		{"451 4.7.1 Throttling failure: daily message quota exceeded", true},
+		{"450 4.2.1 Mailbox busy", false},
+		{"550 5.7.1 Too many recipients", false},
+		{"connection refused", false},
+	}
+
+	for _, tt := range tests {
+		if got := isThrottleResponse(tt.text); got != tt.want {
+			t.Errorf("isThrottleResponse(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestRetryClass(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"450 4.2.0 <a@example.com>: Recipient address rejected: Greylisted, see https://postgrey.schweikert.ch/", retry.ClassGreylisting},
+		{"451 4.7.1 Greylisting in action, please come back later", retry.ClassGreylisting},
+		{"421 4.7.0 Too many concurrent SMTP connections", retry.ClassThrottle},
+		{"450 4.2.1 Mailbox busy", retry.ClassTemporary},
+		{"connection failed to mx1.example.com:25: dial tcp 192.0.2.1:25: i/o timeout", retry.ClassConnection},
+		{"MX lookup failed for example.com: no such host", retry.ClassConnection},
+		{"something went wrong", retry.ClassTemporary},
+	}
+
+	for _, tt := range tests {
+		if got := retryClass(tt.text); got != tt.want {
+			t.Errorf("retryClass(%q) = %s, want %s", tt.text, got, tt.want)
+		}
+	}
+}
+
+// mockRetryScheduler retries with the delay of the error class, up to the
+// attempts allowed for the recipient domain
+type mockRetryScheduler struct {
+	delays     map[string]time.Duration
+	maxRetries map[string]int
+}
+
+func (m *mockRetryScheduler) Next(domain, class string, n int) (time.Duration, bool) {
+	if n >= m.maxRetries[domain] {
+		return 0, false
+	}
+	return m.delays[class], true
+}
+
+func TestProcessorRetryScheduler(t *testing.T) {
+	storage, err := NewBoltStorage(filepath.Join(t.TempDir(), "queue.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storage.Close()
+
+	sender := &mockSender{
+		sendFunc: func(ctx context.Context, msg *Message) error {
+			msg.SetResult("a@greylist.test", RecipientResult{Status: StatusDeferred, Error: "451 4.7.1 Please come back in 5 minutes", Greylisted: true})
+			msg.SetResult("b@slow.test", RecipientResult{Status: StatusDeferred, Error: "connection failed to mx.slow.test:25: i/o timeout"})
+			msg.SetResult("c@strict.test", RecipientResult{Status: StatusDeferred, Error: "450 4.2.1 Mailbox busy"})
+			return errors.New("3 recipients deferred")
+		},
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	processor := NewProcessor(storage, sender, ProcessorConfig{Workers: 1, MaxRetries: 5}, nil, logger)
+	processor.SetRetryScheduler(&mockRetryScheduler{
+		delays: map[string]time.Duration{
+			retry.ClassGreylisting: 5 * time.Minute,
+			retry.ClassConnection:  30 * time.Minute,
+			retry.ClassTemporary:   time.Hour,
+		},
+		maxRetries: map[string]int{"greylist.test": 5, "slow.test": 5, "strict.test": 1},
+	})
+
+	msg := &Message{
+		ID:        "retry",
+		From:      "sender@example.com",
+		To:        []string{"a@greylist.test", "b@slow.test", "c@strict.test"},
+		Data:      []byte("test"),
+		Status:    StatusPending,
+		CreatedAt: time.Now(),
+	}
+	if err := storage.Enqueue(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	processor.processOne(context.Background(), logger)
+
+	got, err := storage.Get(context.Background(), "retry")
+	if err != nil || got == nil {
+		t.Fatalf("Get() = %v, %v", got, err)
+	}
+	if got.Status != StatusDeferred {
+		t.Fatalf("status = %s, want deferred", got.Status)
+	}
+	// The shortest delay of the recipients left wins
+	if d := got.NextRetryAt.Sub(start); d < 5*time.Minute || d > 6*time.Minute {
+		t.Errorf("next retry in %v, want 5m", d)
+	}
+	// The recipient out of retries fails on its own
+	if r := got.Results["c@strict.test"]; r == nil || r.Status != StatusFailed || !r.Expired {
+		t.Errorf("result of c@strict.test = %+v, want expired", r)
+	}
+	if pending := got.PendingRecipients(); len(pending) != 2 {
+		t.Errorf("pending = %v, want 2 recipients", pending)
+	}
+}
+
+func TestProcessorConcurrency(t *testing.T) {
+	storage, err := NewBoltStorage(filepath.Join(t.TempDir(), "queue.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storage.Close()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	sender := &mockSender{
+		sendFunc: func(ctx context.Context, msg *Message) error {
+			if msg.ID == "first" {
+				close(started)
+				<-release
+			}
+			return nil
+		},
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	processor := NewProcessor(storage, sender, ProcessorConfig{Workers: 1}, nil, logger)
+	concurrency := NewConcurrency(0, map[string]int{"gmail.com": 1})
+	processor.SetConcurrency(concurrency)
+
+	ctx := context.Background()
+	for i, id := range []string{"first", "second"} {
+		msg := &Message{
+			ID:        id,
+			From:      "sender@example.com",
+			To:        []string{"user@gmail.com", "user@example.org"},
+			Data:      []byte("test"),
+			Status:    StatusPending,
+			CreatedAt: time.Now().Add(time.Duration(i) * time.Millisecond),
+		}
+		if err := storage.Enqueue(ctx, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		processor.processOne(ctx, logger)
+		close(done)
+	}()
+	<-started
+
+	// The second message finds gmail.com at its limit
+	processor.processOne(ctx, logger)
+	got, _ := storage.Get(ctx, "second")
+	if got.Status != StatusDeferred || got.RetryCount != 0 || !strings.Contains(got.LastError, "gmail.com") {
+		t.Fatalf("second message: status = %s, retries = %d, error = %q", got.Status, got.RetryCount, got.LastError)
+	}
+	if inFlight := concurrency.InFlight(); inFlight["gmail.com"] != 1 || inFlight["example.org"] != 1 {
+		t.Errorf("InFlight() = %v, want the first message only", inFlight)
+	}
+
+	close(release)
+	<-done
+	if inFlight := concurrency.InFlight(); len(inFlight) != 0 {
+		t.Errorf("InFlight() after delivery = %v", inFlight)
+	}
+}
+
+func TestConcurrencyAcquire(t *testing.T) {
+	c := NewConcurrency(2, map[string]int{"Gmail.com": 1, "unlimited.com": 0})
+
+	if _, ok := c.Acquire([]string{"gmail.com", "example.org"}); !ok {
+		t.Fatal("first Acquire() failed")
+	}
+
+	// All or nothing: example.org has room, but gmail.com is full
+	if full, ok := c.Acquire([]string{"example.org", "gmail.com"}); ok || full != "gmail.com" {
+		t.Fatalf("Acquire() = %q, %v, want gmail.com full", full, ok)
+	}
+	if got := c.InFlight()["example.org"]; got != 1 {
+		t.Errorf("example.org in flight = %d, want 1", got)
+	}
+
+	for i := 0; i < 5; i++ {
+		if _, ok := c.Acquire([]string{"unlimited.com"}); !ok {
+			t.Fatal("override of 0 should not limit")
+		}
+	}
+
+	c.SetLimits(2, map[string]int{"gmail.com": 2})
+	if _, ok := c.Acquire([]string{"gmail.com"}); !ok {
+		t.Error("Acquire() after raising the limit failed")
+	}
+}
@@ -2,6 +2,7 @@ package queue
 
 import (
 	"context"
+	"time"
 )
 
 // Queue defines the interface for message queue operations
@@ -31,3 +32,48 @@ type Queue interface {
 	// Close closes the storage connection
 	Close() error
 }
+
+// DLQManager defines dead letter queue operations
+type DLQManager interface {
+	DLQStorage
+
+	// ListDLQ returns messages in the dead letter queue
+	ListDLQ(ctx context.Context, limit, offset int) ([]*Message, error)
+
+	// GetFromDLQ retrieves a message from the dead letter queue
+	GetFromDLQ(ctx context.Context, id string) (*Message, error)
+
+	// RetryFromDLQ moves a message from DLQ back to pending queue for retry
+	RetryFromDLQ(ctx context.Context, id string) error
+
+	// DeleteFromDLQ permanently deletes a message from the dead letter queue
+	DeleteFromDLQ(ctx context.Context, id string) error
+
+	// DLQStats returns dead letter queue statistics
+	DLQStats(ctx context.Context) (*DLQStats, error)
+}
+
+// BatchEnqueuer can enqueue multiple messages in a single transaction
+type BatchEnqueuer interface {
+	EnqueueBatch(ctx context.Context, msgs []*Message) error
+}
+
+// CleanupStorage defines retention cleanup operations
+type CleanupStorage interface {
+	// CleanupDelivered removes delivered messages older than maxAge
+	CleanupDelivered(ctx context.Context, maxAge time.Duration) (int, error)
+
+	// CleanupDLQ removes DLQ messages by age and enforces max count (FIFO)
+	CleanupDLQ(ctx context.Context, maxAge time.Duration, maxCount int) (int, error)
+}
+
+// Storage is a complete queue backend
+type Storage interface {
+	Queue
+	DLQManager
+	BatchEnqueuer
+	CleanupStorage
+
+	// Import stores a message as is, keeping its status and DLQ membership
+	Import(ctx context.Context, msg *Message, inDLQ bool) error
+}
@@ -0,0 +1,458 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// MaxShards is the largest supported number of queue shards
+const MaxShards = 64
+
+var (
+	bucketShardMeta = []byte("shard_meta")
+	keyShardCount   = []byte("shards")
+)
+
+// ShardedStorage spreads the queue over several BoltDB files. BoltDB
+// serializes write transactions per file, so enqueue and dequeue on
+// different shards no longer contend on one lock. Messages are assigned to
+// a shard by a hash of their ID; reads spanning the queue (List, Stats, DLQ)
+// merge the shards.
+type ShardedStorage struct {
+	dir    string
+	shards []*BoltStorage
+	next   atomic.Uint32 // Rotates the shard tried first when shards tie
+}
+
+// NewShardedStorage opens n shard files in dir. If the directory holds a
+// different number of shards, messages are redistributed. An n of zero
+// opens the shards that exist.
+func NewShardedStorage(dir string, n int) (*ShardedStorage, error) {
+	if n < 0 || n > MaxShards {
+		return nil, fmt.Errorf("invalid shard count %d (must be 1-%d)", n, MaxShards)
+	}
+
+	existing, err := ShardFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		n = max(len(existing), 1)
+	}
+
+	// Open every existing file so messages in surplus shards can be moved
+	s := &ShardedStorage{dir: dir}
+	for i := 0; i < max(n, len(existing)); i++ {
+		shard, err := NewBoltStorage(shardPath(dir, i))
+		if err != nil {
+			s.Close()
+			return nil, fmt.Errorf("failed to open shard %d: %w", i, err)
+		}
+		s.shards = append(s.shards, shard)
+	}
+
+	stored, err := s.storedCount()
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+	if stored != n || len(s.shards) != n {
+		if err := s.rebalance(context.Background(), n); err != nil {
+			s.Close()
+			return nil, fmt.Errorf("failed to redistribute messages over %d shards: %w", n, err)
+		}
+	}
+
+	return s, nil
+}
+
+// ShardFiles returns the shard files in dir
+func ShardFiles(dir string) ([]string, error) {
+	var files []string
+	for i := 0; i < MaxShards; i++ {
+		path := shardPath(dir, i)
+		if _, err := os.Stat(path); err != nil {
+			if os.IsNotExist(err) {
+				break
+			}
+			return nil, err
+		}
+		files = append(files, path)
+	}
+	return files, nil
+}
+
+func shardPath(dir string, i int) string {
+	return filepath.Join(dir, fmt.Sprintf("shard-%02d.db", i))
+}
+
+// Shards returns the number of shards
+func (s *ShardedStorage) Shards() int {
+	return len(s.shards)
+}
+
+// shardIndex returns the shard of a message ID
+func shardIndex(id string, n int) int {
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	return int(h.Sum32() % uint32(n))
+}
+
+func (s *ShardedStorage) shard(id string) *BoltStorage {
+	return s.shards[shardIndex(id, len(s.shards))]
+}
+
+// storedCount returns the shard count the messages were distributed for
+func (s *ShardedStorage) storedCount() (int, error) {
+	var n int
+	err := s.shards[0].db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket(bucketShardMeta); b != nil {
+			n, _ = strconv.Atoi(string(b.Get(keyShardCount)))
+		}
+		return nil
+	})
+	return n, err
+}
+
+// rebalance moves every message to its shard for n shards, then removes
+// surplus shard files
+func (s *ShardedStorage) rebalance(ctx context.Context, n int) error {
+	for i, shard := range s.shards {
+		dlq, err := shard.ListDLQ(ctx, 0, 0)
+		if err != nil {
+			return err
+		}
+		inDLQ := make(map[string]bool, len(dlq))
+		for _, msg := range dlq {
+			inDLQ[msg.ID] = true
+		}
+
+		messages, err := shard.List(ctx, ListFilter{})
+		if err != nil {
+			return err
+		}
+		for _, msg := range messages {
+			target := shardIndex(msg.ID, n)
+			if target == i {
+				continue
+			}
+			if err := s.shards[target].Import(ctx, msg, inDLQ[msg.ID]); err != nil {
+				return err
+			}
+			if err := removeMessage(ctx, shard, msg.ID, inDLQ[msg.ID]); err != nil {
+				return err
+			}
+		}
+	}
+
+	for len(s.shards) > n {
+		last := len(s.shards) - 1
+		if err := s.shards[last].Close(); err != nil {
+			return err
+		}
+		if err := os.Remove(shardPath(s.dir, last)); err != nil {
+			return err
+		}
+		s.shards = s.shards[:last]
+	}
+
+	return s.shards[0].db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(bucketShardMeta)
+		if err != nil {
+			return err
+		}
+		return b.Put(keyShardCount, []byte(strconv.Itoa(n)))
+	})
+}
+
+// Enqueue adds a message to its shard
+func (s *ShardedStorage) Enqueue(ctx context.Context, msg *Message) error {
+	return s.shard(msg.ID).Enqueue(ctx, msg)
+}
+
+// EnqueueBatch adds messages to their shards, writing the shards in
+// parallel. Each shard is written atomically, the batch as a whole is not.
+func (s *ShardedStorage) EnqueueBatch(ctx context.Context, msgs []*Message) error {
+	groups := make(map[int][]*Message)
+	for _, msg := range msgs {
+		if msg == nil {
+			continue
+		}
+		i := shardIndex(msg.ID, len(s.shards))
+		groups[i] = append(groups[i], msg)
+	}
+
+	return s.each(groups, func(shard *BoltStorage, msgs []*Message) error {
+		return shard.EnqueueBatch(ctx, msgs)
+	})
+}
+
+// each runs fn for every shard group in parallel and returns the first error
+func (s *ShardedStorage) each(groups map[int][]*Message, fn func(*BoltStorage, []*Message) error) error {
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	for i, msgs := range groups {
+		wg.Add(1)
+		go func(shard *BoltStorage, msgs []*Message) {
+			defer wg.Done()
+			if err := fn(shard, msgs); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}(s.shards[i], msgs)
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// Dequeue gets the next message for processing. The heads of all shards are
+// compared in read transactions, which do not block each other, so deferred
+// retries and higher priorities still go first across shards. Only the
+// chosen shard is locked for writing.
+func (s *ShardedStorage) Dequeue(ctx context.Context) (*Message, error) {
+	type candidate struct {
+		shard *BoltStorage
+		rank  int
+	}
+
+	now := time.Now()
+	start := int(s.next.Add(1))
+	var candidates []candidate
+	for i := range s.shards {
+		shard := s.shards[(start+i)%len(s.shards)]
+		rank, ok, err := shard.headRank(now)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			candidates = append(candidates, candidate{shard: shard, rank: rank})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].rank < candidates[j].rank
+	})
+
+	// Another worker may have taken the head in the meantime
+	for _, c := range candidates {
+		msg, err := c.shard.Dequeue(ctx)
+		if err != nil || msg != nil {
+			return msg, err
+		}
+	}
+	return nil, nil
+}
+
+// Update updates the message status
+func (s *ShardedStorage) Update(ctx context.Context, msg *Message) error {
+	return s.shard(msg.ID).Update(ctx, msg)
+}
+
+// Get retrieves a message by ID
+func (s *ShardedStorage) Get(ctx context.Context, id string) (*Message, error) {
+	return s.shard(id).Get(ctx, id)
+}
+
+// List returns messages of all shards ordered by ID
+func (s *ShardedStorage) List(ctx context.Context, filter ListFilter) ([]*Message, error) {
+	perShard := filter
+	perShard.Offset, perShard.Limit = 0, 0
+	if filter.Limit > 0 {
+		perShard.Limit = filter.Offset + filter.Limit
+	}
+
+	var messages []*Message
+	for _, shard := range s.shards {
+		msgs, err := shard.List(ctx, perShard)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, msgs...)
+	}
+
+	sort.Slice(messages, func(i, j int) bool {
+		return messages[i].ID < messages[j].ID
+	})
+	return page(messages, filter.Limit, filter.Offset), nil
+}
+
+// page applies offset and limit to a merged list
+func page(messages []*Message, limit, offset int) []*Message {
+	if offset >= len(messages) {
+		return nil
+	}
+	messages = messages[offset:]
+	if limit > 0 && len(messages) > limit {
+		messages = messages[:limit]
+	}
+	return messages
+}
+
+// Delete removes a message from the queue
+func (s *ShardedStorage) Delete(ctx context.Context, id string) error {
+	return s.shard(id).Delete(ctx, id)
+}
+
+// Stats returns queue statistics summed over the shards
+func (s *ShardedStorage) Stats(ctx context.Context) (*QueueStats, error) {
+	total := &QueueStats{}
+	for _, shard := range s.shards {
+		stats, err := shard.Stats(ctx)
+		if err != nil {
+			return nil, err
+		}
+		total.Total += stats.Total
+		total.Pending += stats.Pending
+		total.Sending += stats.Sending
+		total.Delivered += stats.Delivered
+		total.Failed += stats.Failed
+		total.Deferred += stats.Deferred
+	}
+	return total, nil
+}
+
+// Import stores a message as is in its shard
+func (s *ShardedStorage) Import(ctx context.Context, msg *Message, inDLQ bool) error {
+	return s.shard(msg.ID).Import(ctx, msg, inDLQ)
+}
+
+// MoveToDLQ moves a failed message to the dead letter queue
+func (s *ShardedStorage) MoveToDLQ(ctx context.Context, msg *Message) error {
+	return s.shard(msg.ID).MoveToDLQ(ctx, msg)
+}
+
+// ListDLQ returns dead letter messages of all shards, oldest first
+func (s *ShardedStorage) ListDLQ(ctx context.Context, limit, offset int) ([]*Message, error) {
+	perShard := 0
+	if limit > 0 {
+		perShard = offset + limit
+	}
+
+	var messages []*Message
+	for _, shard := range s.shards {
+		msgs, err := shard.ListDLQ(ctx, perShard, 0)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, msgs...)
+	}
+
+	sort.SliceStable(messages, func(i, j int) bool {
+		if !messages[i].UpdatedAt.Equal(messages[j].UpdatedAt) {
+			return messages[i].UpdatedAt.Before(messages[j].UpdatedAt)
+		}
+		return messages[i].ID < messages[j].ID
+	})
+	return page(messages, limit, offset), nil
+}
+
+// GetFromDLQ retrieves a message from the dead letter queue
+func (s *ShardedStorage) GetFromDLQ(ctx context.Context, id string) (*Message, error) {
+	return s.shard(id).GetFromDLQ(ctx, id)
+}
+
+// RetryFromDLQ moves a message from DLQ back to pending queue for retry
+func (s *ShardedStorage) RetryFromDLQ(ctx context.Context, id string) error {
+	return s.shard(id).RetryFromDLQ(ctx, id)
+}
+
+// DeleteFromDLQ permanently deletes a message from the dead letter queue
+func (s *ShardedStorage) DeleteFromDLQ(ctx context.Context, id string) error {
+	return s.shard(id).DeleteFromDLQ(ctx, id)
+}
+
+// DLQStats returns dead letter queue statistics summed over the shards
+func (s *ShardedStorage) DLQStats(ctx context.Context) (*DLQStats, error) {
+	total := &DLQStats{}
+	for _, shard := range s.shards {
+		stats, err := shard.DLQStats(ctx)
+		if err != nil {
+			return nil, err
+		}
+		total.Total += stats.Total
+		total.TotalSize += stats.TotalSize
+		if !stats.OldestAt.IsZero() && (total.OldestAt.IsZero() || stats.OldestAt.Before(total.OldestAt)) {
+			total.OldestAt = stats.OldestAt
+		}
+	}
+	return total, nil
+}
+
+// CleanupDelivered removes delivered messages older than maxAge
+func (s *ShardedStorage) CleanupDelivered(ctx context.Context, maxAge time.Duration) (int, error) {
+	deleted := 0
+	for _, shard := range s.shards {
+		n, err := shard.CleanupDelivered(ctx, maxAge)
+		deleted += n
+		if err != nil {
+			return deleted, err
+		}
+	}
+	return deleted, nil
+}
+
+// CleanupDLQ removes DLQ messages by age and enforces max count (FIFO)
+// over all shards
+func (s *ShardedStorage) CleanupDLQ(ctx context.Context, maxAge time.Duration, maxCount int) (int, error) {
+	deleted := 0
+	for _, shard := range s.shards {
+		n, err := shard.CleanupDLQ(ctx, maxAge, 0)
+		deleted += n
+		if err != nil {
+			return deleted, err
+		}
+	}
+
+	if maxCount <= 0 {
+		return deleted, nil
+	}
+
+	stats, err := s.DLQStats(ctx)
+	if err != nil {
+		return deleted, err
+	}
+	excess := int(stats.Total) - maxCount
+	if excess <= 0 {
+		return deleted, nil
+	}
+
+	oldest, err := s.ListDLQ(ctx, excess, 0)
+	if err != nil {
+		return deleted, err
+	}
+	for _, msg := range oldest {
+		if err := s.DeleteFromDLQ(ctx, msg.ID); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// Close closes all shards
+func (s *ShardedStorage) Close() error {
+	var errs []error
+	for _, shard := range s.shards {
+		if err := shard.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
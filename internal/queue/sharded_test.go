@@ -0,0 +1,303 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestShardedStorage(t *testing.T, n int) *ShardedStorage {
+	t.Helper()
+	storage, err := NewShardedStorage(t.TempDir(), n)
+	if err != nil {
+		t.Fatalf("NewShardedStorage() error = %v", err)
+	}
+	t.Cleanup(func() { storage.Close() })
+	return storage
+}
+
+// spreadIDs returns message IDs landing on different shards
+func spreadIDs(prefix string, count, shards int) []string {
+	var ids []string
+	seen := make(map[int]bool)
+	for i := 0; len(ids) < count; i++ {
+		id := fmt.Sprintf("%s-%d", prefix, i)
+		if shard := shardIndex(id, shards); !seen[shard] || len(seen) == shards {
+			seen[shard] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func TestShardedStorageRouting(t *testing.T) {
+	storage := newTestShardedStorage(t, 4)
+	ctx := context.Background()
+
+	ids := spreadIDs("msg", 8, 4)
+	for _, id := range ids {
+		msg := &Message{ID: id, Status: StatusPending, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+		if err := storage.Enqueue(ctx, msg); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+	}
+
+	got, err := storage.Get(ctx, ids[3])
+	if err != nil || got == nil || got.ID != ids[3] {
+		t.Fatalf("Get() = %v, %v, want %s", got, err, ids[3])
+	}
+
+	got.Status = StatusDelivered
+	if err := storage.Update(ctx, got); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if err := storage.Delete(ctx, ids[5]); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if got, _ := storage.Get(ctx, ids[5]); got != nil {
+		t.Errorf("Get() after Delete() = %v, want nil", got)
+	}
+
+	stats, err := storage.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+	if stats.Total != 7 || stats.Pending != 6 || stats.Delivered != 1 {
+		t.Errorf("Stats() = %+v, want 7 total, 6 pending, 1 delivered", stats)
+	}
+
+	// List merges the shards in ID order before paging
+	list, err := storage.List(ctx, ListFilter{Status: StatusPending, Offset: 2, Limit: 3})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	all, _ := storage.List(ctx, ListFilter{Status: StatusPending})
+	if len(all) != 6 || len(list) != 3 {
+		t.Fatalf("List() returned %d and %d messages, want 6 and 3", len(all), len(list))
+	}
+	for i, msg := range list {
+		if msg.ID != all[i+2].ID {
+			t.Errorf("List()[%d] = %s, want %s", i, msg.ID, all[i+2].ID)
+		}
+	}
+}
+
+func TestShardedStoragePriority(t *testing.T) {
+	storage := newTestShardedStorage(t, 4)
+	ctx := context.Background()
+	now := time.Now()
+
+	ids := spreadIDs("prio", 4, 4)
+	msgs := []*Message{
+		{ID: ids[0], Status: StatusPending, CreatedAt: now, Priority: PriorityLow},
+		{ID: ids[1], Status: StatusPending, CreatedAt: now.Add(time.Millisecond)},
+		{ID: ids[2], Status: StatusPending, CreatedAt: now.Add(2 * time.Millisecond), Priority: PriorityHigh},
+		{ID: ids[3], Status: StatusDeferred, CreatedAt: now, NextRetryAt: now.Add(-time.Second)},
+	}
+	for _, msg := range msgs {
+		if err := storage.Import(ctx, msg, false); err != nil {
+			t.Fatalf("Import() error = %v", err)
+		}
+	}
+
+	// Retries due go first, then pending messages by priority
+	for _, want := range []string{ids[3], ids[2], ids[1], ids[0]} {
+		got, err := storage.Dequeue(ctx)
+		if err != nil {
+			t.Fatalf("Dequeue() error = %v", err)
+		}
+		if got == nil || got.ID != want {
+			t.Fatalf("Dequeue() = %v, want %s", got, want)
+		}
+	}
+	if got, _ := storage.Dequeue(ctx); got != nil {
+		t.Errorf("Dequeue() = %s, want empty queue", got.ID)
+	}
+}
+
+func TestShardedStorageDLQ(t *testing.T) {
+	storage := newTestShardedStorage(t, 3)
+	ctx := context.Background()
+
+	ids := spreadIDs("dlq", 5, 3)
+	for _, id := range ids {
+		msg := &Message{ID: id, Status: StatusPending, CreatedAt: time.Now()}
+		if err := storage.Enqueue(ctx, msg); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+		msg.Status = StatusFailed
+		if err := storage.MoveToDLQ(ctx, msg); err != nil {
+			t.Fatalf("MoveToDLQ() error = %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	stats, err := storage.DLQStats(ctx)
+	if err != nil {
+		t.Fatalf("DLQStats() error = %v", err)
+	}
+	if stats.Total != 5 || stats.OldestAt.IsZero() {
+		t.Errorf("DLQStats() = %+v, want 5 messages", stats)
+	}
+
+	list, err := storage.ListDLQ(ctx, 2, 1)
+	if err != nil {
+		t.Fatalf("ListDLQ() error = %v", err)
+	}
+	if len(list) != 2 || list[0].ID != ids[1] || list[1].ID != ids[2] {
+		t.Errorf("ListDLQ(2, 1) = %v, want %s and %s", list, ids[1], ids[2])
+	}
+
+	if err := storage.RetryFromDLQ(ctx, ids[4]); err != nil {
+		t.Fatalf("RetryFromDLQ() error = %v", err)
+	}
+
+	// The oldest messages over the limit are removed across shards
+	deleted, err := storage.CleanupDLQ(ctx, 0, 2)
+	if err != nil {
+		t.Fatalf("CleanupDLQ() error = %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("CleanupDLQ() deleted %d, want 2", deleted)
+	}
+	rest, _ := storage.ListDLQ(ctx, 0, 0)
+	if len(rest) != 2 || rest[0].ID != ids[2] || rest[1].ID != ids[3] {
+		t.Errorf("ListDLQ() after cleanup = %v, want %s and %s", rest, ids[2], ids[3])
+	}
+}
+
+func TestShardedStorageReshard(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	storage, err := NewShardedStorage(dir, 4)
+	if err != nil {
+		t.Fatalf("NewShardedStorage() error = %v", err)
+	}
+	ids := spreadIDs("reshard", 12, 4)
+	for _, id := range ids {
+		if err := storage.Enqueue(ctx, &Message{ID: id, Status: StatusPending, CreatedAt: time.Now()}); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+	}
+	failed := &Message{ID: ids[0], Status: StatusFailed}
+	if err := storage.MoveToDLQ(ctx, failed); err != nil {
+		t.Fatalf("MoveToDLQ() error = %v", err)
+	}
+	storage.Close()
+
+	for _, n := range []int{2, 5, 0} {
+		storage, err = NewShardedStorage(dir, n)
+		if err != nil {
+			t.Fatalf("NewShardedStorage(%d) error = %v", n, err)
+		}
+
+		files, _ := ShardFiles(dir)
+		if len(files) != storage.Shards() {
+			t.Errorf("%d shard files, want %d", len(files), storage.Shards())
+		}
+		for _, id := range ids {
+			if got, _ := storage.Get(ctx, id); got == nil {
+				t.Errorf("shards=%d: message %s lost", n, id)
+			}
+		}
+		if stats, _ := storage.DLQStats(ctx); stats.Total != 1 {
+			t.Errorf("shards=%d: DLQ holds %d messages, want 1", n, stats.Total)
+		}
+		storage.Close()
+	}
+
+	// Zero keeps the shard count found on disk
+	if storage.Shards() != 5 {
+		t.Errorf("Shards() = %d, want 5", storage.Shards())
+	}
+}
+
+func TestMoveIntoShardedStorage(t *testing.T) {
+	ctx := context.Background()
+	src, err := NewBoltStorage(filepath.Join(t.TempDir(), "queue.db"))
+	if err != nil {
+		t.Fatalf("NewBoltStorage() error = %v", err)
+	}
+	defer src.Close()
+
+	for i := 0; i < 6; i++ {
+		msg := &Message{ID: fmt.Sprintf("move-%d", i), Status: StatusPending, CreatedAt: time.Now()}
+		if err := src.Enqueue(ctx, msg); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+	}
+	if err := src.MoveToDLQ(ctx, &Message{ID: "move-0", Status: StatusFailed}); err != nil {
+		t.Fatalf("MoveToDLQ() error = %v", err)
+	}
+
+	dst := newTestShardedStorage(t, 3)
+	result, err := Move(ctx, src, dst)
+	if err != nil {
+		t.Fatalf("Move() error = %v", err)
+	}
+	if result.Messages != 6 || result.DLQ != 1 {
+		t.Errorf("Move() = %+v, want 6 messages, 1 DLQ", result)
+	}
+
+	if stats, _ := src.Stats(ctx); stats.Total != 0 {
+		t.Errorf("source still holds %d messages", stats.Total)
+	}
+	if stats, _ := src.DLQStats(ctx); stats.Total != 0 {
+		t.Errorf("source DLQ still holds %d messages", stats.Total)
+	}
+	if stats, _ := dst.Stats(ctx); stats.Total != 6 {
+		t.Errorf("destination holds %d messages, want 6", stats.Total)
+	}
+	if stats, _ := dst.DLQStats(ctx); stats.Total != 1 {
+		t.Errorf("destination DLQ holds %d messages, want 1", stats.Total)
+	}
+}
+
+// benchmarkQueue enqueues and dequeues from all CPUs
+func benchmarkQueue(b *testing.B, storage Storage) {
+	ctx := context.Background()
+	var seq atomic.Int64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			msg := &Message{
+				ID:        fmt.Sprintf("bench-%d", seq.Add(1)),
+				Data:      []byte("benchmark"),
+				Status:    StatusPending,
+				CreatedAt: time.Now(),
+			}
+			if err := storage.Enqueue(ctx, msg); err != nil {
+				b.Fatal(err)
+			}
+			if _, err := storage.Dequeue(ctx); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkBoltStorageEnqueueDequeue(b *testing.B) {
+	storage, err := NewBoltStorage(filepath.Join(b.TempDir(), "queue.db"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer storage.Close()
+	benchmarkQueue(b, storage)
+}
+
+func BenchmarkShardedStorageEnqueueDequeue(b *testing.B) {
+	storage, err := NewShardedStorage(b.TempDir(), 8)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer storage.Close()
+	benchmarkQueue(b, storage)
+}
+
+func TestShardedStorageListFilter(t *testing.T) {
+	testListFilter(t, newTestShardedStorage(t, 3))
+}
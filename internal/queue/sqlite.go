@@ -0,0 +1,533 @@
+package queue
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS messages (
+	id TEXT PRIMARY KEY,
+	status TEXT NOT NULL,
+	created_at INTEGER NOT NULL,
+	updated_at INTEGER NOT NULL,
+	next_retry_at INTEGER NOT NULL DEFAULT 0,
+	dlq_at INTEGER,
+	priority INTEGER NOT NULL DEFAULT 1,
+	payload BLOB NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_messages_deferred ON messages(status, next_retry_at);
+CREATE INDEX IF NOT EXISTS idx_messages_dlq ON messages(dlq_at) WHERE dlq_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_messages_created ON messages(created_at);
+`
+
+// sqlitePriorityIndex orders pending messages by priority, replacing the
+// index used before priorities were introduced
+const sqlitePriorityIndex = `
+DROP INDEX IF EXISTS idx_messages_pending;
+CREATE INDEX IF NOT EXISTS idx_messages_priority ON messages(status, priority, created_at);
+`
+
+// SQLiteStorage implements Queue interface using SQLite.
+// Unlike BoltDB, SQLite in WAL mode lets readers proceed while a writer is active.
+type SQLiteStorage struct {
+	db *sql.DB
+}
+
+// NewSQLiteStorage creates a new SQLite storage
+func NewSQLiteStorage(path string) (*SQLiteStorage, error) {
+	// Ensure directory exists
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	// Immediate transactions take the write lock up front so concurrent
+	// Dequeue calls never pick the same message
+	db, err := sql.Open("sqlite3", path+"?_journal_mode=WAL&_busy_timeout=5000&_txlock=immediate")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create schema: %w", err)
+	}
+
+	if err := migrateSQLitePriority(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate schema: %w", err)
+	}
+
+	return &SQLiteStorage{db: db}, nil
+}
+
+// migrateSQLitePriority adds the priority column to databases created
+// before priorities were introduced
+func migrateSQLitePriority(db *sql.DB) error {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('messages') WHERE name = 'priority'`).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		if _, err := db.Exec(`ALTER TABLE messages ADD COLUMN priority INTEGER NOT NULL DEFAULT 1`); err != nil {
+			return err
+		}
+	}
+
+	_, err = db.Exec(sqlitePriorityIndex)
+	return err
+}
+
+// Enqueue adds a message to the queue
+func (s *SQLiteStorage) Enqueue(ctx context.Context, msg *Message) error {
+	msg.Schedule(msg.SendAt)
+	return s.put(ctx, s.db, msg, dlqKeep)
+}
+
+// EnqueueBatch adds multiple messages to the queue in a single transaction
+func (s *SQLiteStorage) EnqueueBatch(ctx context.Context, msgs []*Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		for _, msg := range msgs {
+			if msg == nil {
+				continue
+			}
+			msg.Schedule(msg.SendAt)
+			if err := s.put(ctx, tx, msg, dlqKeep); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Dequeue gets the next message for processing
+func (s *SQLiteStorage) Dequeue(ctx context.Context) (*Message, error) {
+	var msg *Message
+
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		now := time.Now()
+
+		var m *Message
+		for {
+			// First check deferred messages that are ready for retry, then pending
+			var err error
+			m, err = scanMessage(tx.QueryRowContext(ctx,
+				`SELECT payload FROM messages WHERE status = ? AND next_retry_at <= ? ORDER BY next_retry_at LIMIT 1`,
+				string(StatusDeferred), now.UnixNano()))
+			if err != nil {
+				return err
+			}
+			if m != nil {
+				break
+			}
+
+			m, err = scanMessage(tx.QueryRowContext(ctx,
+				`SELECT payload FROM messages WHERE status = ? ORDER BY priority, created_at LIMIT 1`,
+				string(StatusPending)))
+			if err != nil {
+				return err
+			}
+			if m == nil {
+				return nil
+			}
+			if !m.Scheduled(now) {
+				break
+			}
+
+			// Not due yet, wait with the deferred messages until the send time
+			m.Status = StatusDeferred
+			m.NextRetryAt = m.SendAt
+			if err := s.put(ctx, tx, m, dlqKeep); err != nil {
+				return err
+			}
+		}
+
+		m.Status = StatusSending
+		m.UpdatedAt = now
+		if err := s.put(ctx, tx, m, dlqKeep); err != nil {
+			return err
+		}
+
+		msg = m
+		return nil
+	})
+
+	return msg, err
+}
+
+// Update updates the message status
+func (s *SQLiteStorage) Update(ctx context.Context, msg *Message) error {
+	msg.UpdatedAt = time.Now()
+	return s.put(ctx, s.db, msg, dlqKeep)
+}
+
+// Get retrieves a message by ID
+func (s *SQLiteStorage) Get(ctx context.Context, id string) (*Message, error) {
+	return scanMessage(s.db.QueryRowContext(ctx, `SELECT payload FROM messages WHERE id = ?`, id))
+}
+
+// List returns a list of messages with optional filtering. Status, ID and
+// date filters are applied in SQL, address filters on the decoded messages.
+func (s *SQLiteStorage) List(ctx context.Context, filter ListFilter) ([]*Message, error) {
+	var where []string
+	var args []interface{}
+
+	if filter.Status != "" {
+		where = append(where, `status = ?`)
+		args = append(args, string(filter.Status))
+	}
+	if filter.Cursor != "" {
+		where = append(where, `id > ?`)
+		args = append(args, filter.Cursor)
+	}
+	if filter.IDPrefix != "" {
+		where = append(where, `substr(id, 1, ?) = ?`)
+		args = append(args, len(filter.IDPrefix), filter.IDPrefix)
+	}
+	if !filter.Since.IsZero() {
+		where = append(where, `created_at >= ?`)
+		args = append(args, filter.Since.UnixNano())
+	}
+	if !filter.Until.IsZero() {
+		where = append(where, `created_at < ?`)
+		args = append(args, filter.Until.UnixNano())
+	}
+
+	query := `SELECT payload FROM messages`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, ` AND `)
+	}
+	query += ` ORDER BY id`
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []*Message
+	skipped := 0
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+
+		var msg Message
+		if err := json.Unmarshal(data, &msg); err != nil || !filter.Match(&msg) {
+			continue
+		}
+		if skipped < filter.Offset {
+			skipped++
+			continue
+		}
+		messages = append(messages, &msg)
+		if filter.Limit > 0 && len(messages) >= filter.Limit {
+			break
+		}
+	}
+
+	return messages, rows.Err()
+}
+
+// Delete removes a message from the queue
+func (s *SQLiteStorage) Delete(ctx context.Context, id string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM messages WHERE id = ?`, id)
+	return err
+}
+
+// Stats returns queue statistics
+func (s *SQLiteStorage) Stats(ctx context.Context) (*QueueStats, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT status, COUNT(*) FROM messages GROUP BY status`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := &QueueStats{}
+	for rows.Next() {
+		var status string
+		var count int64
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, err
+		}
+
+		stats.Total += count
+		switch MessageStatus(status) {
+		case StatusPending:
+			stats.Pending = count
+		case StatusSending:
+			stats.Sending = count
+		case StatusDelivered:
+			stats.Delivered = count
+		case StatusFailed:
+			stats.Failed = count
+		case StatusDeferred:
+			stats.Deferred = count
+		}
+	}
+
+	return stats, rows.Err()
+}
+
+// Close closes the database connection
+func (s *SQLiteStorage) Close() error {
+	return s.db.Close()
+}
+
+// Import stores a message as is, keeping its status and DLQ membership.
+// Messages caught in sending state are re-queued as pending.
+func (s *SQLiteStorage) Import(ctx context.Context, msg *Message, inDLQ bool) error {
+	if msg.Status == StatusSending {
+		msg.Status = StatusPending
+	}
+	mode := dlqClear
+	if inDLQ {
+		mode = dlqSet
+	}
+	return s.put(ctx, s.db, msg, mode)
+}
+
+// Dead Letter Queue methods
+
+// MoveToDLQ moves a failed message to the dead letter queue
+func (s *SQLiteStorage) MoveToDLQ(ctx context.Context, msg *Message) error {
+	msg.Status = StatusFailed
+	msg.UpdatedAt = time.Now()
+	return s.put(ctx, s.db, msg, dlqSet)
+}
+
+// ListDLQ returns messages in the dead letter queue
+func (s *SQLiteStorage) ListDLQ(ctx context.Context, limit, offset int) ([]*Message, error) {
+	if limit <= 0 {
+		limit = -1 // No limit
+	}
+	return s.queryMessages(ctx,
+		`SELECT payload FROM messages WHERE dlq_at IS NOT NULL ORDER BY dlq_at LIMIT ? OFFSET ?`,
+		limit, offset)
+}
+
+// GetFromDLQ retrieves a message from the dead letter queue
+func (s *SQLiteStorage) GetFromDLQ(ctx context.Context, id string) (*Message, error) {
+	return scanMessage(s.db.QueryRowContext(ctx,
+		`SELECT payload FROM messages WHERE id = ? AND dlq_at IS NOT NULL`, id))
+}
+
+// RetryFromDLQ moves a message from DLQ back to pending queue for retry
+func (s *SQLiteStorage) RetryFromDLQ(ctx context.Context, id string) error {
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		msg, err := scanMessage(tx.QueryRowContext(ctx, `SELECT payload FROM messages WHERE id = ?`, id))
+		if err != nil {
+			return err
+		}
+		if msg == nil {
+			return fmt.Errorf("message not found: %s", id)
+		}
+
+		// Reset message status
+		msg.Status = StatusPending
+		msg.RetryCount = 0
+		msg.LastError = ""
+		msg.UpdatedAt = time.Now()
+
+		return s.put(ctx, tx, msg, dlqClear)
+	})
+}
+
+// DeleteFromDLQ permanently deletes a message from the dead letter queue
+func (s *SQLiteStorage) DeleteFromDLQ(ctx context.Context, id string) error {
+	return s.Delete(ctx, id)
+}
+
+// DLQStats returns dead letter queue statistics
+func (s *SQLiteStorage) DLQStats(ctx context.Context) (*DLQStats, error) {
+	stats := &DLQStats{}
+
+	var oldest sql.NullInt64
+	var size sql.NullInt64
+	err := s.db.QueryRowContext(ctx,
+		`SELECT COUNT(*), MIN(dlq_at), SUM(LENGTH(payload)) FROM messages WHERE dlq_at IS NOT NULL`,
+	).Scan(&stats.Total, &oldest, &size)
+	if err != nil {
+		return nil, err
+	}
+
+	if oldest.Valid {
+		stats.OldestAt = time.Unix(0, oldest.Int64)
+	}
+	stats.TotalSize = size.Int64
+
+	return stats, nil
+}
+
+// Cleanup methods
+
+// CleanupDelivered removes delivered messages older than maxAge
+func (s *SQLiteStorage) CleanupDelivered(ctx context.Context, maxAge time.Duration) (int, error) {
+	if maxAge <= 0 {
+		return 0, nil
+	}
+
+	cutoff := time.Now().Add(-maxAge)
+	res, err := s.db.ExecContext(ctx,
+		`DELETE FROM messages WHERE status = ? AND updated_at < ?`,
+		string(StatusDelivered), cutoff.UnixNano())
+	if err != nil {
+		return 0, err
+	}
+
+	deleted, err := res.RowsAffected()
+	return int(deleted), err
+}
+
+// CleanupDLQ removes DLQ messages by age and enforces max count (FIFO)
+func (s *SQLiteStorage) CleanupDLQ(ctx context.Context, maxAge time.Duration, maxCount int) (int, error) {
+	deleted := 0
+
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		if maxAge > 0 {
+			cutoff := time.Now().Add(-maxAge)
+			res, err := tx.ExecContext(ctx,
+				`DELETE FROM messages WHERE dlq_at IS NOT NULL AND dlq_at < ?`, cutoff.UnixNano())
+			if err != nil {
+				return err
+			}
+			n, _ := res.RowsAffected()
+			deleted += int(n)
+		}
+
+		if maxCount > 0 {
+			// Delete oldest entries beyond maxCount
+			res, err := tx.ExecContext(ctx, `
+				DELETE FROM messages WHERE id IN (
+					SELECT id FROM messages WHERE dlq_at IS NOT NULL
+					ORDER BY dlq_at DESC LIMIT -1 OFFSET ?
+				)`, maxCount)
+			if err != nil {
+				return err
+			}
+			n, _ := res.RowsAffected()
+			deleted += int(n)
+		}
+
+		return nil
+	})
+
+	return deleted, err
+}
+
+// execer is implemented by *sql.DB and *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// dlqMode controls how put treats DLQ membership of an existing row
+type dlqMode int
+
+const (
+	dlqKeep  dlqMode = iota // Leave DLQ membership unchanged
+	dlqSet                  // Add to DLQ
+	dlqClear                // Remove from DLQ
+)
+
+// put inserts or replaces a message row
+func (s *SQLiteStorage) put(ctx context.Context, e execer, msg *Message, mode dlqMode) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	var dlqAt interface{}
+	if mode == dlqSet {
+		dlqAt = msg.UpdatedAt.UnixNano()
+	}
+
+	_, err = e.ExecContext(ctx, `
+		INSERT INTO messages (id, status, created_at, updated_at, next_retry_at, dlq_at, priority, payload)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			status = excluded.status,
+			created_at = excluded.created_at,
+			updated_at = excluded.updated_at,
+			next_retry_at = excluded.next_retry_at,
+			dlq_at = CASE WHEN ? THEN messages.dlq_at ELSE excluded.dlq_at END,
+			priority = excluded.priority,
+			payload = excluded.payload`,
+		msg.ID, string(msg.Status), msg.CreatedAt.UnixNano(), msg.UpdatedAt.UnixNano(),
+		msg.NextRetryAt.UnixNano(), dlqAt, msg.EffectivePriority().rank(), data, mode == dlqKeep)
+	if err != nil {
+		return fmt.Errorf("failed to store message: %w", err)
+	}
+	return nil
+}
+
+// withTx runs fn in a transaction
+func (s *SQLiteStorage) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// queryMessages runs a query returning payload rows
+func (s *SQLiteStorage) queryMessages(ctx context.Context, query string, args ...interface{}) ([]*Message, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []*Message
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+
+		var msg Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			continue
+		}
+		messages = append(messages, &msg)
+	}
+
+	return messages, rows.Err()
+}
+
+// scanMessage decodes a single payload row, returning nil if there is none
+func scanMessage(row *sql.Row) (*Message, error) {
+	var data []byte
+	if err := row.Scan(&data); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var msg Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal message: %w", err)
+	}
+	return &msg, nil
+}
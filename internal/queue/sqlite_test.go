@@ -0,0 +1,386 @@
+package queue
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestSQLiteStorage(t *testing.T) *SQLiteStorage {
+	t.Helper()
+
+	storage, err := NewSQLiteStorage(filepath.Join(t.TempDir(), "queue.sqlite"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage() error = %v", err)
+	}
+	t.Cleanup(func() { storage.Close() })
+	return storage
+}
+
+func TestSQLiteStorage(t *testing.T) {
+	storage := newTestSQLiteStorage(t)
+	ctx := context.Background()
+
+	msg := &Message{
+		ID:        "test-id-1",
+		From:      "sender@test.com",
+		To:        []string{"recipient@test.com"},
+		Data:      []byte("test email data"),
+		Status:    StatusPending,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	if err := storage.Enqueue(ctx, msg); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+
+	got, err := storage.Get(ctx, "test-id-1")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got == nil || got.From != msg.From || string(got.Data) != string(msg.Data) {
+		t.Fatalf("Get() = %+v, want %+v", got, msg)
+	}
+
+	notFound, err := storage.Get(ctx, "nonexistent")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if notFound != nil {
+		t.Error("Get() should return nil for nonexistent message")
+	}
+
+	dequeued, err := storage.Dequeue(ctx)
+	if err != nil {
+		t.Fatalf("Dequeue() error = %v", err)
+	}
+	if dequeued == nil || dequeued.ID != msg.ID {
+		t.Fatalf("Dequeue() = %v, want %s", dequeued, msg.ID)
+	}
+	if dequeued.Status != StatusSending {
+		t.Errorf("Dequeue().Status = %v, want %v", dequeued.Status, StatusSending)
+	}
+
+	// Message in sending state must not be dequeued twice
+	again, err := storage.Dequeue(ctx)
+	if err != nil {
+		t.Fatalf("Dequeue() error = %v", err)
+	}
+	if again != nil {
+		t.Errorf("Dequeue() = %s, want nil", again.ID)
+	}
+
+	dequeued.Status = StatusDelivered
+	if err := storage.Update(ctx, dequeued); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	stats, err := storage.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+	if stats.Total != 1 || stats.Delivered != 1 {
+		t.Errorf("Stats() = %+v, want 1 delivered", stats)
+	}
+
+	if err := storage.Delete(ctx, msg.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	deleted, _ := storage.Get(ctx, msg.ID)
+	if deleted != nil {
+		t.Error("Get() should return nil after Delete()")
+	}
+}
+
+func TestSQLiteStorageDeferred(t *testing.T) {
+	storage := newTestSQLiteStorage(t)
+	ctx := context.Background()
+
+	msg := &Message{
+		ID:          "deferred-1",
+		Status:      StatusDeferred,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+		NextRetryAt: time.Now().Add(time.Hour),
+	}
+	if err := storage.Enqueue(ctx, msg); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+
+	got, err := storage.Dequeue(ctx)
+	if err != nil {
+		t.Fatalf("Dequeue() error = %v", err)
+	}
+	if got != nil {
+		t.Fatalf("Dequeue() returned message before retry time")
+	}
+
+	msg.NextRetryAt = time.Now().Add(-time.Second)
+	if err := storage.Update(ctx, msg); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	got, err = storage.Dequeue(ctx)
+	if err != nil {
+		t.Fatalf("Dequeue() error = %v", err)
+	}
+	if got == nil || got.ID != msg.ID {
+		t.Fatalf("Dequeue() = %v, want %s", got, msg.ID)
+	}
+}
+
+func TestSQLiteStorageScheduled(t *testing.T) {
+	storage := newTestSQLiteStorage(t)
+	ctx := context.Background()
+	now := time.Now()
+	sendAt := now.Add(100 * time.Millisecond)
+
+	if err := storage.Enqueue(ctx, &Message{ID: "scheduled", Status: StatusPending, CreatedAt: now, UpdatedAt: now, SendAt: sendAt}); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	// Pending with a future send time, as imported from another backend
+	if err := storage.Import(ctx, &Message{ID: "imported", Status: StatusPending, CreatedAt: now, SendAt: now.Add(time.Hour)}, false); err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+
+	if got, _ := storage.Dequeue(ctx); got != nil {
+		t.Fatalf("Dequeue() returned %s before its send time", got.ID)
+	}
+	imported, _ := storage.Get(ctx, "imported")
+	if imported.Status != StatusDeferred {
+		t.Errorf("imported status = %s, want deferred", imported.Status)
+	}
+
+	time.Sleep(time.Until(sendAt))
+	got, err := storage.Dequeue(ctx)
+	if err != nil {
+		t.Fatalf("Dequeue() error = %v", err)
+	}
+	if got == nil || got.ID != "scheduled" {
+		t.Fatalf("Dequeue() = %v, want scheduled", got)
+	}
+}
+
+func TestSQLiteStoragePriority(t *testing.T) {
+	storage := newTestSQLiteStorage(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	msgs := []*Message{
+		{ID: "low", Status: StatusPending, CreatedAt: now, Priority: PriorityLow},
+		{ID: "normal", Status: StatusPending, CreatedAt: now.Add(time.Millisecond)},
+		{ID: "high", Status: StatusPending, CreatedAt: now.Add(2 * time.Millisecond), Priority: PriorityHigh},
+	}
+	if err := storage.EnqueueBatch(ctx, msgs); err != nil {
+		t.Fatalf("EnqueueBatch() error = %v", err)
+	}
+
+	for _, want := range []string{"high", "normal", "low"} {
+		got, err := storage.Dequeue(ctx)
+		if err != nil {
+			t.Fatalf("Dequeue() error = %v", err)
+		}
+		if got == nil || got.ID != want {
+			t.Fatalf("Dequeue() = %v, want %s", got, want)
+		}
+	}
+}
+
+func TestSQLiteStoragePriorityMigration(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.sqlite")
+
+	// Schema used before priorities were introduced
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	_, err = db.Exec(`
+		CREATE TABLE messages (
+			id TEXT PRIMARY KEY,
+			status TEXT NOT NULL,
+			created_at INTEGER NOT NULL,
+			updated_at INTEGER NOT NULL,
+			next_retry_at INTEGER NOT NULL DEFAULT 0,
+			dlq_at INTEGER,
+			payload BLOB NOT NULL
+		);
+		CREATE INDEX idx_messages_pending ON messages(status, created_at);
+		INSERT INTO messages (id, status, created_at, updated_at, payload)
+		VALUES ('old', 'pending', 1, 1, '{"id":"old","status":"pending"}');`)
+	db.Close()
+	if err != nil {
+		t.Fatalf("failed to create old schema: %v", err)
+	}
+
+	storage, err := NewSQLiteStorage(path)
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage() error = %v", err)
+	}
+	defer storage.Close()
+
+	ctx := context.Background()
+	if err := storage.Enqueue(ctx, &Message{ID: "high", Status: StatusPending, CreatedAt: time.Now(), Priority: PriorityHigh}); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+
+	for _, want := range []string{"high", "old"} {
+		got, err := storage.Dequeue(ctx)
+		if err != nil {
+			t.Fatalf("Dequeue() error = %v", err)
+		}
+		if got == nil || got.ID != want {
+			t.Fatalf("Dequeue() = %v, want %s", got, want)
+		}
+	}
+}
+
+func TestSQLiteStorageDLQ(t *testing.T) {
+	storage := newTestSQLiteStorage(t)
+	ctx := context.Background()
+
+	msg := &Message{ID: "dlq-1", Status: StatusPending, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	if err := storage.Enqueue(ctx, msg); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+
+	msg.LastError = "550 user unknown"
+	if err := storage.MoveToDLQ(ctx, msg); err != nil {
+		t.Fatalf("MoveToDLQ() error = %v", err)
+	}
+
+	// Updating the message must keep it in the DLQ
+	if err := storage.Update(ctx, msg); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	list, err := storage.ListDLQ(ctx, 10, 0)
+	if err != nil {
+		t.Fatalf("ListDLQ() error = %v", err)
+	}
+	if len(list) != 1 {
+		t.Fatalf("ListDLQ() returned %d messages, want 1", len(list))
+	}
+
+	stats, err := storage.DLQStats(ctx)
+	if err != nil {
+		t.Fatalf("DLQStats() error = %v", err)
+	}
+	if stats.Total != 1 || stats.OldestAt.IsZero() {
+		t.Errorf("DLQStats() = %+v", stats)
+	}
+
+	if err := storage.RetryFromDLQ(ctx, msg.ID); err != nil {
+		t.Fatalf("RetryFromDLQ() error = %v", err)
+	}
+
+	got, _ := storage.GetFromDLQ(ctx, msg.ID)
+	if got != nil {
+		t.Error("GetFromDLQ() should return nil after retry")
+	}
+
+	got, _ = storage.Get(ctx, msg.ID)
+	if got == nil || got.Status != StatusPending || got.LastError != "" {
+		t.Errorf("Get() after retry = %+v", got)
+	}
+
+	if err := storage.RetryFromDLQ(ctx, "nonexistent"); err == nil {
+		t.Error("RetryFromDLQ() should fail for nonexistent message")
+	}
+}
+
+func TestSQLiteStorageCleanup(t *testing.T) {
+	storage := newTestSQLiteStorage(t)
+	ctx := context.Background()
+
+	old := time.Now().Add(-48 * time.Hour)
+	for _, id := range []string{"old-1", "old-2"} {
+		msg := &Message{ID: id, Status: StatusDelivered, CreatedAt: old, UpdatedAt: old}
+		if err := storage.Enqueue(ctx, msg); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+	}
+	recent := &Message{ID: "recent", Status: StatusDelivered, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	if err := storage.Enqueue(ctx, recent); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+
+	deleted, err := storage.CleanupDelivered(ctx, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("CleanupDelivered() error = %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("CleanupDelivered() = %d, want 2", deleted)
+	}
+
+	for i, id := range []string{"dlq-1", "dlq-2", "dlq-3"} {
+		msg := &Message{ID: id, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+		if err := storage.Enqueue(ctx, msg); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+		msg.UpdatedAt = time.Now().Add(time.Duration(i) * time.Second)
+		if err := storage.MoveToDLQ(ctx, msg); err != nil {
+			t.Fatalf("MoveToDLQ() error = %v", err)
+		}
+	}
+
+	deleted, err = storage.CleanupDLQ(ctx, 0, 2)
+	if err != nil {
+		t.Fatalf("CleanupDLQ() error = %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("CleanupDLQ() = %d, want 1", deleted)
+	}
+	if got, _ := storage.GetFromDLQ(ctx, "dlq-1"); got != nil {
+		t.Error("CleanupDLQ() should remove the oldest entry")
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+
+	src, err := NewBoltStorage(filepath.Join(t.TempDir(), "queue.db"))
+	if err != nil {
+		t.Fatalf("NewBoltStorage() error = %v", err)
+	}
+	defer src.Close()
+
+	pending := &Message{ID: "pending", Status: StatusPending, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	failed := &Message{ID: "failed", Status: StatusPending, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	for _, msg := range []*Message{pending, failed} {
+		if err := src.Enqueue(ctx, msg); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+	}
+	if err := src.MoveToDLQ(ctx, failed); err != nil {
+		t.Fatalf("MoveToDLQ() error = %v", err)
+	}
+
+	dst := newTestSQLiteStorage(t)
+
+	result, err := Migrate(ctx, src, dst)
+	if err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+	if result.Messages != 2 || result.DLQ != 1 {
+		t.Errorf("Migrate() = %+v, want 2 messages, 1 in DLQ", result)
+	}
+
+	if got, _ := dst.GetFromDLQ(ctx, "failed"); got == nil {
+		t.Error("DLQ message was not migrated to DLQ")
+	}
+
+	got, err := dst.Dequeue(ctx)
+	if err != nil {
+		t.Fatalf("Dequeue() error = %v", err)
+	}
+	if got == nil || got.ID != "pending" {
+		t.Errorf("Dequeue() = %v, want pending message", got)
+	}
+}
+
+func TestSQLiteStorageListFilter(t *testing.T) {
+	testListFilter(t, newTestSQLiteStorage(t))
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	bolt "go.etcd.io/bbolt"
@@ -46,7 +48,10 @@ func NewBoltStorage(path string) (*BoltStorage, error) {
 				return fmt.Errorf("failed to create bucket %s: %w", bucket, err)
 			}
 		}
-		return nil
+		if err := migratePendingIndex(tx); err != nil {
+			return err
+		}
+		return createSearchIndex(tx)
 	})
 	if err != nil {
 		db.Close()
@@ -83,8 +88,11 @@ func (s *BoltStorage) EnqueueBatch(ctx context.Context, msgs []*Message) error {
 	})
 }
 
-// enqueueInTx writes a message and its pending index entry inside an existing tx.
+// enqueueInTx writes a message and its index entry inside an existing tx.
+// Messages scheduled for a future send time go to the deferred index.
 func enqueueInTx(tx *bolt.Tx, msg *Message) error {
+	msg.Schedule(msg.SendAt)
+
 	msgBucket := tx.Bucket(bucketMessages)
 	data, err := json.Marshal(msg)
 	if err != nil {
@@ -93,15 +101,54 @@ func enqueueInTx(tx *bolt.Tx, msg *Message) error {
 	if err := msgBucket.Put([]byte(msg.ID), data); err != nil {
 		return fmt.Errorf("failed to store message: %w", err)
 	}
+	if err := putSearchIndex(tx, msg); err != nil {
+		return err
+	}
+
+	if msg.Status == StatusDeferred {
+		indexKey := makeIndexKey(msg.NextRetryAt, msg.ID)
+		if err := tx.Bucket(bucketDeferred).Put(indexKey, []byte(msg.ID)); err != nil {
+			return fmt.Errorf("failed to add to deferred index: %w", err)
+		}
+		return nil
+	}
 
 	pendingBucket := tx.Bucket(bucketPending)
-	indexKey := makeIndexKey(msg.CreatedAt, msg.ID)
+	indexKey := makePendingKey(msg.EffectivePriority(), msg.CreatedAt, msg.ID)
 	if err := pendingBucket.Put(indexKey, []byte(msg.ID)); err != nil {
 		return fmt.Errorf("failed to add to pending index: %w", err)
 	}
 	return nil
 }
 
+// migratePendingIndex moves pending index entries written before priorities
+// were introduced to the normal priority range
+func migratePendingIndex(tx *bolt.Tx) error {
+	pendingBucket := tx.Bucket(bucketPending)
+
+	var oldKeys, ids [][]byte
+	c := pendingBucket.Cursor()
+	for k, v := c.First(); k != nil; k, v = c.Next() {
+		if isPendingKey(k) {
+			continue
+		}
+		oldKeys = append(oldKeys, append([]byte{}, k...))
+		ids = append(ids, append([]byte{}, v...))
+	}
+
+	for i, k := range oldKeys {
+		if err := pendingBucket.Delete(k); err != nil {
+			return err
+		}
+		newKey := append(pendingKeyPrefix(PriorityNormal), k...)
+		if err := pendingBucket.Put(newKey, ids[i]); err != nil {
+			return fmt.Errorf("failed to migrate pending index: %w", err)
+		}
+	}
+
+	return nil
+}
+
 // Dequeue gets the next message for processing
 func (s *BoltStorage) Dequeue(ctx context.Context) (*Message, error) {
 	var msg *Message
@@ -158,7 +205,8 @@ func (s *BoltStorage) Dequeue(ctx context.Context) (*Message, error) {
 			return nil
 		}
 
-		// If no deferred messages, check pending
+		// If no deferred messages, check pending. Keys are ordered by
+		// priority, so high-priority messages are dequeued first.
 		pendingBucket := tx.Bucket(bucketPending)
 		c = pendingBucket.Cursor()
 
@@ -176,6 +224,26 @@ func (s *BoltStorage) Dequeue(ctx context.Context) (*Message, error) {
 				continue
 			}
 
+			// Not due yet, wait in the deferred index until the send time
+			if m.Scheduled(now) {
+				m.Status = StatusDeferred
+				m.NextRetryAt = m.SendAt
+				data, err := json.Marshal(&m)
+				if err != nil {
+					return err
+				}
+				if err := msgBucket.Put([]byte(m.ID), data); err != nil {
+					return err
+				}
+				if err := deferredBucket.Put(makeIndexKey(m.NextRetryAt, m.ID), []byte(m.ID)); err != nil {
+					return err
+				}
+				if err := c.Delete(); err != nil {
+					return err
+				}
+				continue
+			}
+
 			// Update status to sending
 			m.Status = StatusSending
 			m.UpdatedAt = now
@@ -204,6 +272,23 @@ func (s *BoltStorage) Dequeue(ctx context.Context) (*Message, error) {
 	return msg, err
 }
 
+// headRank returns how urgent the message Dequeue would pick next is: -1
+// for a deferred message due for retry, otherwise the priority rank of the
+// first pending message. ok is false if nothing is ready.
+func (s *BoltStorage) headRank(now time.Time) (rank int, ok bool, err error) {
+	err = s.db.View(func(tx *bolt.Tx) error {
+		if k, _ := tx.Bucket(bucketDeferred).Cursor().First(); k != nil && !parseTimestampFromKey(k).After(now) {
+			rank, ok = -1, true
+			return nil
+		}
+		if k, _ := tx.Bucket(bucketPending).Cursor().First(); k != nil && isPendingKey(k) {
+			rank, ok = int(k[0]-'0'), true
+		}
+		return nil
+	})
+	return rank, ok, err
+}
+
 // Update updates the message status
 func (s *BoltStorage) Update(ctx context.Context, msg *Message) error {
 	return s.db.Update(func(tx *bolt.Tx) error {
@@ -251,39 +336,55 @@ func (s *BoltStorage) Get(ctx context.Context, id string) (*Message, error) {
 	return msg, err
 }
 
-// List returns a list of messages with optional filtering
+// List returns a list of messages with optional filtering. Domain and
+// date filters are served from the secondary indexes, other filters scan the
+// messages in ID order starting at the cursor or ID prefix.
 func (s *BoltStorage) List(ctx context.Context, filter ListFilter) ([]*Message, error) {
 	var messages []*Message
 
 	err := s.db.View(func(tx *bolt.Tx) error {
 		msgBucket := tx.Bucket(bucketMessages)
-		c := msgBucket.Cursor()
-
-		count := 0
 		skipped := 0
 
-		for k, v := c.First(); k != nil; k, v = c.Next() {
+		// add decodes and filters a candidate, it returns false once the
+		// page is full
+		add := func(data []byte) bool {
 			var msg Message
-			if err := json.Unmarshal(v, &msg); err != nil {
-				continue
-			}
-
-			// Apply status filter
-			if filter.Status != "" && msg.Status != filter.Status {
-				continue
+			if err := json.Unmarshal(data, &msg); err != nil || !filter.Match(&msg) {
+				return true
 			}
-
-			// Apply offset
 			if skipped < filter.Offset {
 				skipped++
-				continue
+				return true
 			}
-
 			messages = append(messages, &msg)
-			count++
+			return filter.Limit <= 0 || len(messages) < filter.Limit
+		}
 
-			// Apply limit
-			if filter.Limit > 0 && count >= filter.Limit {
+		if ids, ok := indexedIDs(tx, filter); ok {
+			for _, id := range ids {
+				if id <= filter.Cursor || !strings.HasPrefix(id, filter.IDPrefix) {
+					continue
+				}
+				if data := msgBucket.Get([]byte(id)); data != nil && !add(data) {
+					break
+				}
+			}
+			return nil
+		}
+
+		c := msgBucket.Cursor()
+		k, v := c.First()
+		if filter.IDPrefix > filter.Cursor {
+			k, v = c.Seek([]byte(filter.IDPrefix))
+		} else if filter.Cursor != "" {
+			k, v = c.Seek([]byte(filter.Cursor))
+		}
+		for ; k != nil; k, v = c.Next() {
+			if filter.IDPrefix != "" && string(k) > filter.IDPrefix && !strings.HasPrefix(string(k), filter.IDPrefix) {
+				break
+			}
+			if !add(v) {
 				break
 			}
 		}
@@ -306,7 +407,7 @@ func (s *BoltStorage) Delete(ctx context.Context, id string) error {
 			if err := json.Unmarshal(data, &msg); err == nil {
 				// Clean up pending index
 				pendingBucket := tx.Bucket(bucketPending)
-				pendingKey := makeIndexKey(msg.CreatedAt, msg.ID)
+				pendingKey := makePendingKey(msg.EffectivePriority(), msg.CreatedAt, msg.ID)
 				pendingBucket.Delete(pendingKey)
 
 				// Clean up deferred index
@@ -316,7 +417,7 @@ func (s *BoltStorage) Delete(ctx context.Context, id string) error {
 			}
 		}
 
-		return msgBucket.Delete([]byte(id))
+		return deleteMessage(tx, []byte(id))
 	})
 }
 
@@ -365,12 +466,40 @@ func (s *BoltStorage) DB() *bolt.DB {
 	return s.db
 }
 
+// Backup writes a consistent copy of the database file to w while the
+// database stays in use
+func (s *BoltStorage) Backup(w io.Writer) (int64, error) {
+	var n int64
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		n, err = tx.WriteTo(w)
+		return err
+	})
+	return n, err
+}
+
 // makeIndexKey creates a sortable key from timestamp and ID
 func makeIndexKey(t time.Time, id string) []byte {
 	// Format: timestamp (RFC3339Nano) + ":" + id
 	return []byte(t.Format(time.RFC3339Nano) + ":" + id)
 }
 
+// makePendingKey creates a pending index key ordered by priority, then time
+func makePendingKey(p Priority, t time.Time, id string) []byte {
+	// Format: rank + ":" + timestamp (RFC3339Nano) + ":" + id
+	return append(pendingKeyPrefix(p), makeIndexKey(t, id)...)
+}
+
+// pendingKeyPrefix returns the pending index key prefix of a priority
+func pendingKeyPrefix(p Priority) []byte {
+	return []byte{byte('0' + p.rank()), ':'}
+}
+
+// isPendingKey reports whether a pending index key carries a priority rank
+func isPendingKey(key []byte) bool {
+	return len(key) > 1 && key[1] == ':'
+}
+
 // parseTimestampFromKey extracts timestamp from index key
 func parseTimestampFromKey(key []byte) time.Time {
 	s := string(key)
@@ -384,6 +513,42 @@ func parseTimestampFromKey(key []byte) time.Time {
 	return time.Time{}
 }
 
+// Import stores a message as is, keeping its status and DLQ membership.
+// Messages caught in sending state are re-queued as pending.
+func (s *BoltStorage) Import(ctx context.Context, msg *Message, inDLQ bool) error {
+	if msg.Status == StatusSending {
+		msg.Status = StatusPending
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		data, err := json.Marshal(msg)
+		if err != nil {
+			return fmt.Errorf("failed to marshal message: %w", err)
+		}
+		if err := tx.Bucket(bucketMessages).Put([]byte(msg.ID), data); err != nil {
+			return fmt.Errorf("failed to store message: %w", err)
+		}
+		if err := putSearchIndex(tx, msg); err != nil {
+			return err
+		}
+
+		var bucket []byte
+		var indexKey []byte
+		switch {
+		case inDLQ:
+			bucket, indexKey = bucketDeadLetter, makeIndexKey(msg.UpdatedAt, msg.ID)
+		case msg.Status == StatusPending:
+			bucket, indexKey = bucketPending, makePendingKey(msg.EffectivePriority(), msg.CreatedAt, msg.ID)
+		case msg.Status == StatusDeferred:
+			bucket, indexKey = bucketDeferred, makeIndexKey(msg.NextRetryAt, msg.ID)
+		default:
+			return nil
+		}
+
+		return tx.Bucket(bucket).Put(indexKey, []byte(msg.ID))
+	})
+}
+
 // Dead Letter Queue methods
 
 // MoveToDLQ moves a failed message to the dead letter queue
@@ -534,7 +699,7 @@ func (s *BoltStorage) RetryFromDLQ(ctx context.Context, id string) error {
 		}
 
 		// Add to pending queue
-		indexKey := makeIndexKey(msg.UpdatedAt, msg.ID)
+		indexKey := makePendingKey(msg.EffectivePriority(), msg.UpdatedAt, msg.ID)
 		if err := pendingBucket.Put(indexKey, []byte(msg.ID)); err != nil {
 			return fmt.Errorf("failed to add to pending: %w", err)
 		}
@@ -547,7 +712,6 @@ func (s *BoltStorage) RetryFromDLQ(ctx context.Context, id string) error {
 func (s *BoltStorage) DeleteFromDLQ(ctx context.Context, id string) error {
 	return s.db.Update(func(tx *bolt.Tx) error {
 		dlqBucket := tx.Bucket(bucketDeadLetter)
-		msgBucket := tx.Bucket(bucketMessages)
 
 		// Remove from DLQ index
 		c := dlqBucket.Cursor()
@@ -561,7 +725,7 @@ func (s *BoltStorage) DeleteFromDLQ(ctx context.Context, id string) error {
 		}
 
 		// Delete message
-		return msgBucket.Delete([]byte(id))
+		return deleteMessage(tx, []byte(id))
 	})
 }
 
@@ -633,7 +797,7 @@ func (s *BoltStorage) CleanupDelivered(ctx context.Context, maxAge time.Duration
 
 		// Delete collected messages
 		for _, k := range toDelete {
-			if err := msgBucket.Delete(k); err != nil {
+			if err := deleteMessage(tx, k); err != nil {
 				return err
 			}
 			deleted++
@@ -651,7 +815,6 @@ func (s *BoltStorage) CleanupDLQ(ctx context.Context, maxAge time.Duration, maxC
 
 	err := s.db.Update(func(tx *bolt.Tx) error {
 		dlqBucket := tx.Bucket(bucketDeadLetter)
-		msgBucket := tx.Bucket(bucketMessages)
 
 		// Count current DLQ size and collect items to delete
 		var toDeleteByAge []struct {
@@ -691,7 +854,7 @@ func (s *BoltStorage) CleanupDLQ(ctx context.Context, maxAge time.Duration, maxC
 			if err := dlqBucket.Delete(item.indexKey); err != nil {
 				return err
 			}
-			if err := msgBucket.Delete(item.msgID); err != nil {
+			if err := deleteMessage(tx, item.msgID); err != nil {
 				return err
 			}
 			deleted++
@@ -725,7 +888,7 @@ func (s *BoltStorage) CleanupDLQ(ctx context.Context, maxAge time.Duration, maxC
 				if err := dlqBucket.Delete(item.indexKey); err != nil {
 					return err
 				}
-				if err := msgBucket.Delete(item.msgID); err != nil {
+				if err := deleteMessage(tx, item.msgID); err != nil {
 					return err
 				}
 				deleted++
@@ -3,8 +3,11 @@ package queue
 import (
 	"context"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
+
+	bolt "go.etcd.io/bbolt"
 )
 
 func TestBoltStorage(t *testing.T) {
@@ -162,6 +165,180 @@ func TestBoltStorageDeferred(t *testing.T) {
 	}
 }
 
+func TestBoltStorageScheduled(t *testing.T) {
+	storage, err := NewBoltStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewBoltStorage() error = %v", err)
+	}
+	defer storage.Close()
+
+	ctx := context.Background()
+	now := time.Now()
+	sendAt := now.Add(100 * time.Millisecond)
+
+	scheduled := &Message{ID: "scheduled", Status: StatusPending, CreatedAt: now, UpdatedAt: now, SendAt: sendAt}
+	immediate := &Message{ID: "immediate", Status: StatusPending, CreatedAt: now.Add(time.Millisecond), UpdatedAt: now}
+	if err := storage.Enqueue(ctx, scheduled); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	if err := storage.Enqueue(ctx, immediate); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+
+	stored, _ := storage.Get(ctx, "scheduled")
+	if stored.Status != StatusDeferred || !stored.NextRetryAt.Equal(sendAt) {
+		t.Errorf("scheduled message status = %s, next retry %v, want deferred until %v", stored.Status, stored.NextRetryAt, sendAt)
+	}
+
+	got, _ := storage.Dequeue(ctx)
+	if got == nil || got.ID != "immediate" {
+		t.Fatalf("Dequeue() = %v, want immediate", got)
+	}
+	if got, _ := storage.Dequeue(ctx); got != nil {
+		t.Fatalf("Dequeue() returned %s before its send time", got.ID)
+	}
+
+	time.Sleep(time.Until(sendAt))
+	got, err = storage.Dequeue(ctx)
+	if err != nil {
+		t.Fatalf("Dequeue() error = %v", err)
+	}
+	if got == nil || got.ID != "scheduled" {
+		t.Fatalf("Dequeue() = %v, want scheduled", got)
+	}
+}
+
+func TestBoltStorageScheduledPending(t *testing.T) {
+	storage, err := NewBoltStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewBoltStorage() error = %v", err)
+	}
+	defer storage.Close()
+
+	ctx := context.Background()
+
+	// A pending message with a future send time (e.g. imported from another
+	// backend) is moved to the deferred index instead of being sent
+	msg := &Message{ID: "imported", Status: StatusPending, CreatedAt: time.Now(), SendAt: time.Now().Add(time.Hour)}
+	if err := storage.Import(ctx, msg, false); err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+
+	if got, _ := storage.Dequeue(ctx); got != nil {
+		t.Fatalf("Dequeue() returned %s before its send time", got.ID)
+	}
+	stored, _ := storage.Get(ctx, "imported")
+	if stored.Status != StatusDeferred || !stored.NextRetryAt.Equal(msg.SendAt) {
+		t.Errorf("status = %s, next retry %v, want deferred until %v", stored.Status, stored.NextRetryAt, msg.SendAt)
+	}
+}
+
+func TestBoltStoragePriority(t *testing.T) {
+	storage, err := NewBoltStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewBoltStorage() error = %v", err)
+	}
+	defer storage.Close()
+
+	ctx := context.Background()
+	now := time.Now()
+
+	msgs := []*Message{
+		{ID: "low", Status: StatusPending, CreatedAt: now, Priority: PriorityLow},
+		{ID: "normal", Status: StatusPending, CreatedAt: now.Add(time.Millisecond)},
+		{ID: "high", Status: StatusPending, CreatedAt: now.Add(2 * time.Millisecond), Priority: PriorityHigh},
+		{ID: "normal-2", Status: StatusPending, CreatedAt: now.Add(3 * time.Millisecond), Priority: PriorityNormal},
+	}
+	if err := storage.EnqueueBatch(ctx, msgs); err != nil {
+		t.Fatalf("EnqueueBatch() error = %v", err)
+	}
+
+	// Deleted messages leave no index entry behind
+	if err := storage.Delete(ctx, "normal-2"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	for _, want := range []string{"high", "normal", "low"} {
+		got, err := storage.Dequeue(ctx)
+		if err != nil {
+			t.Fatalf("Dequeue() error = %v", err)
+		}
+		if got == nil || got.ID != want {
+			t.Fatalf("Dequeue() = %v, want %s", got, want)
+		}
+	}
+	if got, _ := storage.Dequeue(ctx); got != nil {
+		t.Errorf("Dequeue() = %s, want empty queue", got.ID)
+	}
+}
+
+func TestBoltStoragePendingIndexMigration(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	storage, err := NewBoltStorage(path)
+	if err != nil {
+		t.Fatalf("NewBoltStorage() error = %v", err)
+	}
+
+	ctx := context.Background()
+	now := time.Now()
+	old := &Message{ID: "old", Status: StatusPending, CreatedAt: now}
+	if err := storage.Import(ctx, old, false); err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+
+	// Rewrite the index entry in the format used before priorities
+	err = storage.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketPending)
+		if err := b.Delete(makePendingKey(PriorityNormal, now, "old")); err != nil {
+			return err
+		}
+		return b.Put(makeIndexKey(now, "old"), []byte("old"))
+	})
+	if err != nil {
+		t.Fatalf("failed to write old index key: %v", err)
+	}
+	storage.Close()
+
+	storage, err = NewBoltStorage(path)
+	if err != nil {
+		t.Fatalf("NewBoltStorage() error = %v", err)
+	}
+	defer storage.Close()
+
+	if err := storage.Enqueue(ctx, &Message{ID: "low", Status: StatusPending, CreatedAt: now.Add(-time.Second), Priority: PriorityLow}); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+
+	got, _ := storage.Dequeue(ctx)
+	if got == nil || got.ID != "old" {
+		t.Fatalf("Dequeue() = %v, want migrated message first", got)
+	}
+}
+
+func TestParsePriority(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Priority
+		wantErr bool
+	}{
+		{"", PriorityNormal, false},
+		{"high", PriorityHigh, false},
+		{" Low ", PriorityLow, false},
+		{"NORMAL", PriorityNormal, false},
+		{"urgent", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParsePriority(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParsePriority(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("ParsePriority(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
 func TestBoltStorageList(t *testing.T) {
 	tmpDir := t.TempDir()
 	dbPath := filepath.Join(tmpDir, "test.db")
@@ -228,3 +405,165 @@ func TestNewBoltStorageCreateDir(t *testing.T) {
 	}
 	storage.Close()
 }
+
+// testListFilter checks the list filters and cursor paging of a storage
+func testListFilter(t *testing.T, storage Storage) {
+	t.Helper()
+	ctx := context.Background()
+	base := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	messages := []*Message{
+		{ID: "a-1", From: "news@Shop.example", To: []string{"x@mail.test"}, CreatedAt: base},
+		{ID: "a-2", From: "news@shop.example", To: []string{"y@other.test", "z@mail.test"}, CreatedAt: base.Add(time.Hour)},
+		{ID: "b-1", From: "billing@corp.example", To: []string{"boss@shop.example"}, CreatedAt: base.Add(2 * time.Hour)},
+		{ID: "b-2", From: "", To: []string{"postmaster@corp.example"}, CreatedAt: base.Add(3 * time.Hour)},
+		{ID: "c-1", From: "billing@corp.example", To: []string{"x@mail.test"}, CreatedAt: base.Add(4 * time.Hour), Status: StatusDelivered},
+	}
+	for _, msg := range messages {
+		if msg.Status == "" {
+			msg.Status = StatusPending
+		}
+		if err := storage.Enqueue(ctx, msg); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter ListFilter
+		want   string
+	}{
+		{"all", ListFilter{}, "a-1 a-2 b-1 b-2 c-1"},
+		{"status", ListFilter{Status: StatusDelivered}, "c-1"},
+		{"sender", ListFilter{Sender: "BILLING@"}, "b-1 c-1"},
+		{"recipient", ListFilter{Recipient: "x@"}, "a-1 c-1"},
+		{"sender domain", ListFilter{SenderDomain: "shop.example"}, "a-1 a-2"},
+		{"recipient domain", ListFilter{RecipientDomain: "MAIL.test"}, "a-1 a-2 c-1"},
+		{"domain", ListFilter{Domain: "shop.example"}, "a-1 a-2 b-1"},
+		{"domain and status", ListFilter{Domain: "corp.example", Status: StatusPending}, "b-1 b-2"},
+		{"date range", ListFilter{Since: base.Add(time.Hour), Until: base.Add(3 * time.Hour)}, "a-2 b-1"},
+		{"id prefix", ListFilter{IDPrefix: "b-"}, "b-1 b-2"},
+		{"cursor", ListFilter{Cursor: "a-2", Limit: 2}, "b-1 b-2"},
+		{"cursor and prefix", ListFilter{IDPrefix: "a", Cursor: "a-1"}, "a-2"},
+		{"index and cursor", ListFilter{RecipientDomain: "mail.test", Cursor: "a-1", Limit: 1}, "a-2"},
+		{"offset", ListFilter{Domain: "corp.example", Offset: 1}, "b-2 c-1"},
+	}
+	for _, tt := range tests {
+		list, err := storage.List(ctx, tt.filter)
+		if err != nil {
+			t.Fatalf("%s: List() error = %v", tt.name, err)
+		}
+		var ids []string
+		for _, msg := range list {
+			ids = append(ids, msg.ID)
+		}
+		if got := strings.Join(ids, " "); got != tt.want {
+			t.Errorf("%s: List() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBoltStorageListFilter(t *testing.T) {
+	storage, err := NewBoltStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewBoltStorage() error = %v", err)
+	}
+	defer storage.Close()
+
+	testListFilter(t, storage)
+}
+
+func TestBoltStorageSearchIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	storage, err := NewBoltStorage(path)
+	if err != nil {
+		t.Fatalf("NewBoltStorage() error = %v", err)
+	}
+
+	ctx := context.Background()
+	msg := &Message{ID: "m1", From: "a@one.example", To: []string{"b@two.example"}, Status: StatusPending, CreatedAt: time.Now()}
+	if err := storage.Enqueue(ctx, msg); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+
+	// Drop the indexes as in a database created before they existed
+	err = storage.db.Update(func(tx *bolt.Tx) error {
+		for _, b := range [][]byte{bucketBySender, bucketByRecipient, bucketByCreated} {
+			if err := tx.DeleteBucket(b); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to drop indexes: %v", err)
+	}
+	storage.Close()
+
+	storage, err = NewBoltStorage(path)
+	if err != nil {
+		t.Fatalf("NewBoltStorage() error = %v", err)
+	}
+	defer storage.Close()
+
+	list, _ := storage.List(ctx, ListFilter{RecipientDomain: "two.example"})
+	if len(list) != 1 {
+		t.Fatalf("List() after reopening = %d messages, want the indexed message", len(list))
+	}
+
+	// Deleting a message removes its index entries
+	if err := storage.Delete(ctx, "m1"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	err = storage.db.View(func(tx *bolt.Tx) error {
+		for _, b := range [][]byte{bucketBySender, bucketByRecipient, bucketByCreated} {
+			if k, _ := tx.Bucket(b).Cursor().First(); k != nil {
+				t.Errorf("index %s keeps %q", b, k)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCleanupDeliveredDomain(t *testing.T) {
+	storage, err := NewBoltStorage(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewBoltStorage() error = %v", err)
+	}
+	defer storage.Close()
+
+	ctx := context.Background()
+	old := time.Now().Add(-48 * time.Hour)
+	msgs := []*Message{
+		{ID: "old", From: "a@example.com", Status: StatusDelivered, UpdatedAt: old},
+		{ID: "new", From: "a@example.com", Status: StatusDelivered, UpdatedAt: time.Now()},
+		{ID: "pending", From: "a@example.com", Status: StatusPending, UpdatedAt: old},
+		{ID: "other", From: "a@example.org", Status: StatusDelivered, UpdatedAt: old},
+	}
+	for _, msg := range msgs {
+		msg.To = []string{"b@example.net"}
+		msg.CreatedAt = msg.UpdatedAt
+		if err := storage.Enqueue(ctx, msg); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+	}
+
+	n, err := CleanupDeliveredDomain(ctx, storage, "example.com", 24*time.Hour)
+	if err != nil {
+		t.Fatalf("CleanupDeliveredDomain() error = %v", err)
+	}
+	if n != 1 {
+		t.Errorf("CleanupDeliveredDomain() deleted %d, want 1", n)
+	}
+	for _, id := range []string{"new", "pending", "other"} {
+		if msg, _ := storage.Get(ctx, id); msg == nil {
+			t.Errorf("message %s deleted", id)
+		}
+	}
+	if msg, _ := storage.Get(ctx, "old"); msg != nil {
+		t.Error("old message kept")
+	}
+}
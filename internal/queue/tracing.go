@@ -0,0 +1,58 @@
+package queue
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/foxzi/sendry/internal/tracing"
+)
+
+// TracedStorage traces enqueues and stores the trace context of the
+// enqueuing request on each message, so that its deliveries join the trace
+type TracedStorage struct {
+	Storage
+}
+
+// NewTracedStorage wraps storage to trace enqueues
+func NewTracedStorage(storage Storage) *TracedStorage {
+	return &TracedStorage{Storage: storage}
+}
+
+// Enqueue adds a message to the queue
+func (s *TracedStorage) Enqueue(ctx context.Context, msg *Message) error {
+	ctx, span := tracing.Start(ctx, "queue.enqueue",
+		attribute.String("sendry.message.id", msg.ID),
+		attribute.Int("sendry.message.recipients", len(msg.To)),
+	)
+	defer span.End()
+
+	if msg.TraceParent == "" {
+		msg.TraceParent = tracing.Inject(ctx)
+	}
+	if err := s.Storage.Enqueue(ctx, msg); err != nil {
+		tracing.Fail(span, err)
+		return err
+	}
+	return nil
+}
+
+// EnqueueBatch adds messages to the queue in a single transaction
+func (s *TracedStorage) EnqueueBatch(ctx context.Context, msgs []*Message) error {
+	ctx, span := tracing.Start(ctx, "queue.enqueue_batch",
+		attribute.Int("sendry.messages", len(msgs)),
+	)
+	defer span.End()
+
+	traceparent := tracing.Inject(ctx)
+	for _, msg := range msgs {
+		if msg.TraceParent == "" {
+			msg.TraceParent = traceparent
+		}
+	}
+	if err := s.Storage.EnqueueBatch(ctx, msgs); err != nil {
+		tracing.Fail(span, err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/foxzi/sendry/internal/tracing"
+)
+
+func TestTracedStorage(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(prev)
+
+	storage, err := NewBoltStorage(filepath.Join(t.TempDir(), "queue.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storage.Close()
+	traced := NewTracedStorage(storage)
+
+	// The request that submits the message
+	ctx, request := tracing.Start(context.Background(), "POST /api/v1/send")
+	msg := &Message{
+		ID:        "traced",
+		From:      "sender@example.com",
+		To:        []string{"rcpt@example.net"},
+		Data:      []byte("test"),
+		Status:    StatusPending,
+		CreatedAt: time.Now(),
+	}
+	if err := traced.Enqueue(ctx, msg); err != nil {
+		t.Fatal(err)
+	}
+	request.End()
+
+	stored, err := storage.Get(context.Background(), "traced")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.TraceParent == "" {
+		t.Fatal("trace context not stored on the message")
+	}
+
+	sender := &mockSender{
+		sendFunc: func(ctx context.Context, msg *Message) error {
+			return errors.New("550 rejected")
+		},
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError + 4}))
+	processor := NewProcessor(traced, sender, ProcessorConfig{Workers: 1}, func(error) bool { return false }, logger)
+	processor.processOne(context.Background(), logger)
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	for _, name := range []string{"queue.enqueue", "queue.process"} {
+		span, ok := spans[name]
+		if !ok {
+			t.Fatalf("no %s span", name)
+		}
+		if span.SpanContext().TraceID() != request.SpanContext().TraceID() {
+			t.Errorf("%s span is not part of the request trace", name)
+		}
+	}
+	if spans["queue.process"].Parent().SpanID() != spans["queue.enqueue"].SpanContext().SpanID() {
+		t.Error("queue.process span is not a child of queue.enqueue")
+	}
+	if spans["queue.process"].Status().Code != codes.Error {
+		t.Errorf("queue.process status = %v, want error for a failed message", spans["queue.process"].Status().Code)
+	}
+}
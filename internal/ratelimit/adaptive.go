@@ -0,0 +1,223 @@
+package ratelimit
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Defaults of adaptive throttling
+const (
+	defaultAdaptiveThreshold = 3
+	defaultAdaptiveDecrease  = 0.5
+	defaultAdaptiveMinFactor = 0.05
+	defaultAdaptiveRecovery  = 10 * time.Minute
+	defaultAdaptiveBaseRate  = 60
+)
+
+// AdaptiveConfig contains adaptive throttling settings. When a recipient
+// domain keeps answering with throttling responses (421/450 "too many
+// connections" and the like), its rate is multiplied by Decrease, down to
+// MinFactor of the configured limit. Every Recovery period without
+// throttling responses doubles the rate again until it is back to the limit.
+type AdaptiveConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Throttling responses within a recovery period that reduce the rate
+	Threshold int `yaml:"threshold,omitempty"`
+
+	// Rate multiplier applied per reduction
+	Decrease float64 `yaml:"decrease,omitempty"`
+
+	// Lowest share of the configured rate
+	MinFactor float64 `yaml:"min_factor,omitempty"`
+
+	// Time without throttling responses after which the rate doubles
+	Recovery time.Duration `yaml:"recovery,omitempty"`
+
+	// Messages per minute reduced for domains without a configured limit
+	BaseRate int `yaml:"base_rate,omitempty"`
+}
+
+// AdaptiveRate describes the reduced rate of a recipient domain
+type AdaptiveRate struct {
+	Domain            string    `json:"domain"`
+	Factor            float64   `json:"factor"`
+	Throttles         int       `json:"throttles"` // Throttling responses counted towards the next reduction
+	LastThrottle      time.Time `json:"last_throttle"`
+	MessagesPerMinute int       `json:"messages_per_minute"`
+	MessagesPerHour   int       `json:"messages_per_hour"`
+	MessagesPerDay    int       `json:"messages_per_day"`
+}
+
+// adaptiveState is the throttling state of a recipient domain
+type adaptiveState struct {
+	factor       float64   // Share of the rate as of lastThrottle
+	throttles    int       // Throttling responses since the last reduction
+	lastThrottle time.Time // Also when the recovery of factor starts
+}
+
+// adaptiveSettings returns the adaptive settings with defaults applied, or
+// nil if adaptive throttling is off
+func (l *Limiter) adaptiveSettings() *AdaptiveConfig {
+	a := l.config.Adaptive
+	if a == nil || !a.Enabled {
+		return nil
+	}
+
+	s := *a
+	if s.Threshold <= 0 {
+		s.Threshold = defaultAdaptiveThreshold
+	}
+	if s.Decrease <= 0 || s.Decrease >= 1 {
+		s.Decrease = defaultAdaptiveDecrease
+	}
+	if s.MinFactor <= 0 || s.MinFactor > 1 {
+		s.MinFactor = defaultAdaptiveMinFactor
+	}
+	if s.Recovery <= 0 {
+		s.Recovery = defaultAdaptiveRecovery
+	}
+	if s.BaseRate <= 0 {
+		s.BaseRate = defaultAdaptiveBaseRate
+	}
+	return &s
+}
+
+// current returns the factor at now, doubled for each full recovery period
+// since the last throttling response
+func (st *adaptiveState) current(s *AdaptiveConfig, now time.Time) float64 {
+	periods := int(now.Sub(st.lastThrottle) / s.Recovery)
+	if periods <= 0 {
+		return st.factor
+	}
+	return math.Min(1, st.factor*math.Pow(2, float64(periods)))
+}
+
+// RecordThrottle records a throttling response of a recipient domain.
+// Every Threshold responses within a recovery period reduce its rate.
+func (l *Limiter) RecordThrottle(recipientDomain string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	s := l.adaptiveSettings()
+	if s == nil {
+		return
+	}
+
+	now := time.Now()
+	domain := strings.ToLower(recipientDomain)
+	st, ok := l.adaptive[domain]
+	if !ok {
+		st = &adaptiveState{factor: 1}
+		l.adaptive[domain] = st
+	}
+
+	// Responses spread further apart than the recovery period start over
+	if now.Sub(st.lastThrottle) >= s.Recovery {
+		st.throttles = 0
+	}
+	st.factor = st.current(s, now)
+	st.lastThrottle = now
+	st.throttles++
+
+	if st.throttles >= s.Threshold {
+		st.factor = math.Max(s.MinFactor, st.factor*s.Decrease)
+		st.throttles = 0
+	}
+}
+
+// adaptiveLimit returns the limit of a recipient domain scaled by its
+// current factor. Domains without a limit get the base rate while reduced.
+func (l *Limiter) adaptiveLimit(domain string, limit *LimitConfig, now time.Time) *LimitConfig {
+	s := l.adaptiveSettings()
+	if s == nil {
+		return limit
+	}
+	st, ok := l.adaptive[strings.ToLower(domain)]
+	if !ok {
+		return limit
+	}
+	factor := st.current(s, now)
+	if factor >= 1 {
+		return limit
+	}
+	return scaleLimit(limit, s, factor)
+}
+
+// scaleLimit multiplies every window of a limit by factor, keeping at
+// least one message per window
+func scaleLimit(limit *LimitConfig, s *AdaptiveConfig, factor float64) *LimitConfig {
+	base := LimitConfig{MessagesPerMinute: s.BaseRate}
+	if limit != nil {
+		base = *limit
+	}
+
+	scale := func(n int) int {
+		if n <= 0 {
+			return 0
+		}
+		return max(1, int(float64(n)*factor))
+	}
+	base.MessagesPerMinute = scale(base.MessagesPerMinute)
+	base.MessagesPerHour = scale(base.MessagesPerHour)
+	base.MessagesPerDay = scale(base.MessagesPerDay)
+	return &base
+}
+
+// AdaptiveRates returns the recipient domains whose rate is currently
+// reduced or that sent throttling responses, sorted by domain
+func (l *Limiter) AdaptiveRates() []AdaptiveRate {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	s := l.adaptiveSettings()
+	if s == nil {
+		return nil
+	}
+
+	now := time.Now()
+	var rates []AdaptiveRate
+	for domain, st := range l.adaptive {
+		factor := st.current(s, now)
+		throttles := st.throttles
+		if now.Sub(st.lastThrottle) >= s.Recovery {
+			throttles = 0
+		}
+		if factor >= 1 && throttles == 0 {
+			continue
+		}
+
+		rate := AdaptiveRate{
+			Domain:       domain,
+			Factor:       factor,
+			Throttles:    throttles,
+			LastThrottle: st.lastThrottle,
+		}
+		limit := l.getRecipientDomainLimit(domain)
+		if factor < 1 {
+			limit = scaleLimit(limit, s, factor)
+		}
+		if limit != nil {
+			rate.MessagesPerMinute = limit.MessagesPerMinute
+			rate.MessagesPerHour = limit.MessagesPerHour
+			rate.MessagesPerDay = limit.MessagesPerDay
+		}
+		rates = append(rates, rate)
+	}
+
+	sort.Slice(rates, func(i, j int) bool { return rates[i].Domain < rates[j].Domain })
+	return rates
+}
+
+// cleanupAdaptive removes the state of domains that are back to their
+// full rate. Called with the lock held.
+func (l *Limiter) cleanupAdaptive(now time.Time) {
+	s := l.adaptiveSettings()
+	for domain, st := range l.adaptive {
+		if s == nil || (st.current(s, now) >= 1 && now.Sub(st.lastThrottle) >= s.Recovery) {
+			delete(l.adaptive, domain)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAdaptiveThrottle(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	limiter, err := NewLimiter(db, &Config{
+		RecipientDomains: map[string]*LimitConfig{
+			"gmail.com": {MessagesPerMinute: 8, MessagesPerHour: 100},
+		},
+		Adaptive: &AdaptiveConfig{Enabled: true, Threshold: 2, Recovery: time.Hour},
+	})
+	if err != nil {
+		t.Fatalf("failed to create limiter: %v", err)
+	}
+	defer limiter.Stop()
+
+	// Below the threshold the rate stays
+	limiter.RecordThrottle("gmail.com")
+	if rates := limiter.AdaptiveRates(); len(rates) != 1 || rates[0].Factor != 1 || rates[0].Throttles != 1 {
+		t.Fatalf("AdaptiveRates() = %+v", rates)
+	}
+
+	// Two reductions quarter the limit
+	for i := 0; i < 3; i++ {
+		limiter.RecordThrottle("Gmail.com")
+	}
+	rates := limiter.AdaptiveRates()
+	if len(rates) != 1 || rates[0].Factor != 0.25 || rates[0].MessagesPerMinute != 2 || rates[0].MessagesPerHour != 25 {
+		t.Fatalf("AdaptiveRates() = %+v", rates)
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if result, _ := limiter.AllowRecipient(ctx, "gmail.com"); !result.Allowed {
+			t.Fatalf("message %d denied", i+1)
+		}
+	}
+	if result, _ := limiter.AllowRecipient(ctx, "gmail.com"); result.Allowed {
+		t.Fatal("expected the reduced limit to deny the third message")
+	}
+
+	// Each quiet recovery period doubles the rate
+	limiter.adaptive["gmail.com"].lastThrottle = time.Now().Add(-time.Hour - time.Minute)
+	if rates := limiter.AdaptiveRates(); len(rates) != 1 || rates[0].Factor != 0.5 || rates[0].MessagesPerMinute != 4 {
+		t.Fatalf("after one recovery period AdaptiveRates() = %+v", rates)
+	}
+	limiter.adaptive["gmail.com"].lastThrottle = time.Now().Add(-3 * time.Hour)
+	if rates := limiter.AdaptiveRates(); len(rates) != 0 {
+		t.Fatalf("after full recovery AdaptiveRates() = %+v", rates)
+	}
+
+	limiter.cleanupExpiredCounters()
+	if len(limiter.adaptive) != 0 {
+		t.Errorf("recovered domains kept: %v", limiter.adaptive)
+	}
+}
+
+func TestAdaptiveThrottleUnlimitedDomain(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	limiter, err := NewLimiter(db, &Config{
+		Adaptive: &AdaptiveConfig{Enabled: true, Threshold: 1, Decrease: 0.1, MinFactor: 0.05, BaseRate: 20},
+	})
+	if err != nil {
+		t.Fatalf("failed to create limiter: %v", err)
+	}
+	defer limiter.Stop()
+
+	ctx := context.Background()
+	if result, _ := limiter.AllowRecipient(ctx, "mail.ru"); !result.Allowed {
+		t.Fatal("unlimited domain denied")
+	}
+
+	// Domains without a limit are reduced from the base rate, down to the
+	// minimum factor
+	limiter.RecordThrottle("mail.ru")
+	limiter.RecordThrottle("mail.ru")
+	rates := limiter.AdaptiveRates()
+	if len(rates) != 1 || rates[0].Factor != 0.05 || rates[0].MessagesPerMinute != 1 {
+		t.Fatalf("AdaptiveRates() = %+v", rates)
+	}
+
+	if result, _ := limiter.AllowRecipient(ctx, "mail.ru"); !result.Allowed {
+		t.Fatal("first message denied")
+	}
+	result, _ := limiter.AllowRecipient(ctx, "mail.ru")
+	if result.Allowed || result.DeniedBy != LevelRecipient {
+		t.Fatalf("second message = %+v, want denied by recipient domain", result)
+	}
+}
+
+func TestAdaptiveThrottleDisabled(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	limiter, err := NewLimiter(db, &Config{
+		DefaultRecipientDomain: &LimitConfig{MessagesPerMinute: 1},
+	})
+	if err != nil {
+		t.Fatalf("failed to create limiter: %v", err)
+	}
+	defer limiter.Stop()
+
+	for i := 0; i < 10; i++ {
+		limiter.RecordThrottle("gmail.com")
+	}
+	if rates := limiter.AdaptiveRates(); rates != nil {
+		t.Errorf("AdaptiveRates() = %+v, want nil", rates)
+	}
+	if result, _ := limiter.AllowRecipient(context.Background(), "gmail.com"); !result.Allowed {
+		t.Error("configured limit denied the first message")
+	}
+}
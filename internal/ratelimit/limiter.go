@@ -25,8 +25,24 @@ const (
 	LevelRecipient Level = "recipient_domain"
 )
 
+// Algorithm selects how counts are compared with limits
+type Algorithm string
+
+const (
+	// AlgorithmFixed counts messages in fixed windows that reset when they
+	// expire, allowing a burst of up to twice the limit around the reset
+	AlgorithmFixed Algorithm = "fixed"
+
+	// AlgorithmSliding weights the count of the previous window by how much
+	// of it still overlaps the last window length, smoothing out resets
+	AlgorithmSliding Algorithm = "sliding"
+)
+
 // Config contains rate limit configuration
 type Config struct {
+	// Default algorithm for all levels (fixed if empty)
+	Algorithm Algorithm `yaml:"algorithm,omitempty"`
+
 	// Global limits
 	Global *LimitConfig `yaml:"global,omitempty"`
 
@@ -48,29 +64,41 @@ type Config struct {
 	// Per-recipient-domain limits (overrides DefaultRecipientDomain)
 	RecipientDomains map[string]*LimitConfig `yaml:"recipient_domains,omitempty"`
 
+	// Adaptive throttling of recipient domains
+	Adaptive *AdaptiveConfig `yaml:"adaptive,omitempty"`
+
 	// Persistence settings
 	FlushInterval time.Duration `yaml:"flush_interval,omitempty"`
 }
 
 // LimitConfig contains rate limit values
 type LimitConfig struct {
-	MessagesPerHour int `yaml:"messages_per_hour" json:"messages_per_hour"`
-	MessagesPerDay  int `yaml:"messages_per_day" json:"messages_per_day"`
+	MessagesPerMinute int       `yaml:"messages_per_minute" json:"messages_per_minute"`
+	MessagesPerHour   int       `yaml:"messages_per_hour" json:"messages_per_hour"`
+	MessagesPerDay    int       `yaml:"messages_per_day" json:"messages_per_day"`
+	Algorithm         Algorithm `yaml:"algorithm,omitempty" json:"algorithm,omitempty"` // Overrides Config.Algorithm
 }
 
-// Counter tracks rate limit counters
+// Counter tracks rate limit counters. The previous window counts are kept
+// for the sliding window algorithm.
 type Counter struct {
-	HourlyCount int       `json:"hourly_count"`
-	DailyCount  int       `json:"daily_count"`
-	HourStart   time.Time `json:"hour_start"`
-	DayStart    time.Time `json:"day_start"`
+	MinuteCount     int       `json:"minute_count"`
+	HourlyCount     int       `json:"hourly_count"`
+	DailyCount      int       `json:"daily_count"`
+	MinuteStart     time.Time `json:"minute_start"`
+	HourStart       time.Time `json:"hour_start"`
+	DayStart        time.Time `json:"day_start"`
+	PrevMinuteCount int       `json:"prev_minute_count,omitempty"`
+	PrevHourlyCount int       `json:"prev_hourly_count,omitempty"`
+	PrevDailyCount  int       `json:"prev_daily_count,omitempty"`
 }
 
 // Limiter implements rate limiting with multiple levels
 type Limiter struct {
 	db       *bolt.DB
 	config   *Config
-	counters map[string]*Counter // key -> counter
+	counters map[string]*Counter       // key -> counter
+	adaptive map[string]*adaptiveState // recipient domain -> throttling state
 	mu       sync.RWMutex
 	stopCh   chan struct{}
 }
@@ -98,6 +126,7 @@ func NewLimiter(db *bolt.DB, cfg *Config) (*Limiter, error) {
 		db:       db,
 		config:   cfg,
 		counters: make(map[string]*Counter),
+		adaptive: make(map[string]*adaptiveState),
 		stopCh:   make(chan struct{}),
 	}
 
@@ -107,11 +136,27 @@ func NewLimiter(db *bolt.DB, cfg *Config) (*Limiter, error) {
 	}
 
 	// Start background persistence
-	go l.persistLoop()
+	go l.persistLoop(cfg.FlushInterval)
 
 	return l, nil
 }
 
+// SetConfig replaces the limits, e.g. on a config reload. Counters are
+// kept, so messages already counted still count against the new limits.
+func (l *Limiter) SetConfig(cfg *Config) {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// The flush interval of the running persistence loop cannot change
+	next := *cfg
+	next.FlushInterval = l.config.FlushInterval
+	l.config = &next
+}
+
 // Allow checks if the action is allowed and increments counters
 func (l *Limiter) Allow(ctx context.Context, req *Request) (*Result, error) {
 	l.mu.Lock()
@@ -132,30 +177,18 @@ func (l *Limiter) Allow(ctx context.Context, req *Request) (*Result, error) {
 		// Reset counters if time window has passed
 		l.resetExpiredCounters(counter, now)
 
-		// Check hourly limit
-		if check.limit.MessagesPerHour > 0 && counter.HourlyCount >= check.limit.MessagesPerHour {
+		if retryAfter, exceeded := l.exceeded(counter, check.limit, now); exceeded {
 			result.Allowed = false
 			result.DeniedBy = check.level
 			result.DeniedKey = check.key
-			result.RetryAfter = counter.HourStart.Add(time.Hour).Sub(now)
-			return result, nil
-		}
-
-		// Check daily limit
-		if check.limit.MessagesPerDay > 0 && counter.DailyCount >= check.limit.MessagesPerDay {
-			result.Allowed = false
-			result.DeniedBy = check.level
-			result.DeniedKey = check.key
-			result.RetryAfter = counter.DayStart.Add(24 * time.Hour).Sub(now)
+			result.RetryAfter = retryAfter
 			return result, nil
 		}
 	}
 
 	// Increment all counters if allowed
 	for _, check := range checks {
-		counter := l.counters[check.key]
-		counter.HourlyCount++
-		counter.DailyCount++
+		l.counters[check.key].increment()
 	}
 
 	return result, nil
@@ -171,41 +204,69 @@ func (l *Limiter) AllowRecipient(ctx context.Context, recipientDomain string) (*
 		Allowed: true,
 	}
 
-	// Get limit for this recipient domain
-	limit := l.getRecipientDomainLimit(recipientDomain)
+	now := time.Now()
+
+	// Get limit for this recipient domain, reduced while it throttles us
+	limit := l.adaptiveLimit(recipientDomain, l.getRecipientDomainLimit(recipientDomain), now)
 	if limit == nil {
 		// No limit configured for recipient domains
 		return result, nil
 	}
 
-	now := time.Now()
 	key := makeKey(LevelRecipient, recipientDomain)
 	counter := l.getOrCreateCounter(key, now)
 
 	// Reset counters if time window has passed
 	l.resetExpiredCounters(counter, now)
 
-	// Check hourly limit
-	if limit.MessagesPerHour > 0 && counter.HourlyCount >= limit.MessagesPerHour {
+	if retryAfter, exceeded := l.exceeded(counter, limit, now); exceeded {
 		result.Allowed = false
 		result.DeniedBy = LevelRecipient
 		result.DeniedKey = key
-		result.RetryAfter = counter.HourStart.Add(time.Hour).Sub(now)
+		result.RetryAfter = retryAfter
+		return result, nil
+	}
+
+	// Increment counter
+	counter.increment()
+
+	return result, nil
+}
+
+// AllowAPIKey checks the limit of an API key and increments its counter.
+// limit overrides the default API key limit; without either the key is
+// not limited.
+func (l *Limiter) AllowAPIKey(ctx context.Context, apiKey string, limit *LimitConfig) (*Result, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	result := &Result{
+		Allowed: true,
+	}
+
+	if limit == nil {
+		limit = l.config.DefaultAPIKey
+	}
+	if limit == nil {
 		return result, nil
 	}
 
-	// Check daily limit
-	if limit.MessagesPerDay > 0 && counter.DailyCount >= limit.MessagesPerDay {
+	now := time.Now()
+	key := makeKey(LevelAPIKey, apiKey)
+	counter := l.getOrCreateCounter(key, now)
+
+	// Reset counters if time window has passed
+	l.resetExpiredCounters(counter, now)
+
+	if retryAfter, exceeded := l.exceeded(counter, limit, now); exceeded {
 		result.Allowed = false
-		result.DeniedBy = LevelRecipient
+		result.DeniedBy = LevelAPIKey
 		result.DeniedKey = key
-		result.RetryAfter = counter.DayStart.Add(24 * time.Hour).Sub(now)
+		result.RetryAfter = retryAfter
 		return result, nil
 	}
 
-	// Increment counter
-	counter.HourlyCount++
-	counter.DailyCount++
+	counter.increment()
 
 	return result, nil
 }
@@ -228,32 +289,15 @@ func (l *Limiter) Check(ctx context.Context, req *Request) (*Result, error) {
 			continue
 		}
 
-		// Check if counters are still valid
-		hourlyCount := counter.HourlyCount
-		dailyCount := counter.DailyCount
-
-		if now.Sub(counter.HourStart) >= time.Hour {
-			hourlyCount = 0
-		}
-		if now.Sub(counter.DayStart) >= 24*time.Hour {
-			dailyCount = 0
-		}
+		// Work on a copy, expired windows are only reset by Allow
+		current := *counter
+		current.roll(now)
 
-		// Check hourly limit
-		if check.limit.MessagesPerHour > 0 && hourlyCount >= check.limit.MessagesPerHour {
+		if retryAfter, exceeded := l.exceeded(&current, check.limit, now); exceeded {
 			result.Allowed = false
 			result.DeniedBy = check.level
 			result.DeniedKey = check.key
-			result.RetryAfter = counter.HourStart.Add(time.Hour).Sub(now)
-			return result, nil
-		}
-
-		// Check daily limit
-		if check.limit.MessagesPerDay > 0 && dailyCount >= check.limit.MessagesPerDay {
-			result.Allowed = false
-			result.DeniedBy = check.level
-			result.DeniedKey = check.key
-			result.RetryAfter = counter.DayStart.Add(24 * time.Hour).Sub(now)
+			result.RetryAfter = retryAfter
 			return result, nil
 		}
 	}
@@ -266,31 +310,41 @@ func (l *Limiter) GetStats(ctx context.Context, level Level, key string) (*Stats
 	l.mu.RLock()
 	defer l.mu.RUnlock()
 
+	algorithm := l.algorithm(l.limitFor(level, key))
+
 	fullKey := makeKey(level, key)
 	counter, exists := l.counters[fullKey]
 	if !exists {
 		return &Stats{
-			Level: level,
-			Key:   key,
+			Level:     level,
+			Key:       key,
+			Algorithm: algorithm,
 		}, nil
 	}
 
+	// Reset if expired, on a copy
 	now := time.Now()
+	current := *counter
+	current.roll(now)
+
 	stats := &Stats{
 		Level:       level,
 		Key:         key,
-		HourlyCount: counter.HourlyCount,
-		DailyCount:  counter.DailyCount,
-		HourStart:   counter.HourStart,
-		DayStart:    counter.DayStart,
+		Algorithm:   algorithm,
+		MinuteCount: current.MinuteCount,
+		HourlyCount: current.HourlyCount,
+		DailyCount:  current.DailyCount,
+		MinuteStart: current.MinuteStart,
+		HourStart:   current.HourStart,
+		DayStart:    current.DayStart,
 	}
 
-	// Reset if expired
-	if now.Sub(counter.HourStart) >= time.Hour {
-		stats.HourlyCount = 0
-	}
-	if now.Sub(counter.DayStart) >= 24*time.Hour {
-		stats.DailyCount = 0
+	// Sliding windows report the weighted counts the limits are checked against
+	if algorithm == AlgorithmSliding {
+		windows := current.windows()
+		stats.MinuteCount = windows[0].slidingCount(now)
+		stats.HourlyCount = windows[1].slidingCount(now)
+		stats.DailyCount = windows[2].slidingCount(now)
 	}
 
 	return stats, nil
@@ -323,8 +377,11 @@ type Result struct {
 type Stats struct {
 	Level       Level
 	Key         string
+	Algorithm   Algorithm
+	MinuteCount int
 	HourlyCount int
 	DailyCount  int
+	MinuteStart time.Time
 	HourStart   time.Time
 	DayStart    time.Time
 }
@@ -411,12 +468,71 @@ func (l *Limiter) getRecipientDomainLimit(domain string) *LimitConfig {
 	return l.config.DefaultRecipientDomain
 }
 
+// limitFor returns the limit config that applies to a level and key
+func (l *Limiter) limitFor(level Level, key string) *LimitConfig {
+	switch level {
+	case LevelGlobal:
+		return l.config.Global
+	case LevelDomain:
+		return l.config.DefaultDomain
+	case LevelSender:
+		return l.config.DefaultSender
+	case LevelIP:
+		return l.config.DefaultIP
+	case LevelAPIKey:
+		return l.config.DefaultAPIKey
+	case LevelRecipient:
+		return l.getRecipientDomainLimit(key)
+	}
+	return nil
+}
+
+// algorithm returns the algorithm of a limit, falling back to the default
+func (l *Limiter) algorithm(limit *LimitConfig) Algorithm {
+	if limit != nil && limit.Algorithm != "" {
+		return limit.Algorithm
+	}
+	if l.config.Algorithm != "" {
+		return l.config.Algorithm
+	}
+	return AlgorithmFixed
+}
+
+// exceeded checks the minute, hour and day limits against a counter whose
+// expired windows were already reset. Returns how long to wait if a limit
+// is reached.
+func (l *Limiter) exceeded(counter *Counter, limit *LimitConfig, now time.Time) (time.Duration, bool) {
+	sliding := l.algorithm(limit) == AlgorithmSliding
+	limits := [3]int{limit.MessagesPerMinute, limit.MessagesPerHour, limit.MessagesPerDay}
+
+	for i, w := range counter.windows() {
+		max := limits[i]
+		if max <= 0 {
+			continue
+		}
+
+		if !sliding {
+			if w.count >= max {
+				return w.start.Add(w.size).Sub(now), true
+			}
+			continue
+		}
+
+		if w.slidingCount(now) >= max {
+			return w.slidingRetryAfter(max, now), true
+		}
+	}
+
+	return 0, false
+}
+
 func (l *Limiter) getOrCreateCounter(key string, now time.Time) *Counter {
 	counter, exists := l.counters[key]
 	if !exists {
 		counter = &Counter{
-			HourStart: now,
-			DayStart:  now,
+			MinuteStart: now,
+			HourStart:   now,
+			DayStart:    now,
 		}
 		l.counters[key] = counter
 	}
@@ -424,14 +540,96 @@ func (l *Limiter) getOrCreateCounter(key string, now time.Time) *Counter {
 }
 
 func (l *Limiter) resetExpiredCounters(counter *Counter, now time.Time) {
-	if now.Sub(counter.HourStart) >= time.Hour {
-		counter.HourlyCount = 0
-		counter.HourStart = now
+	counter.roll(now)
+}
+
+// window is a view of one counting window of a counter
+type window struct {
+	count int
+	prev  int
+	start time.Time
+	size  time.Duration
+}
+
+// windows returns the minute, hour and day windows of the counter
+func (c *Counter) windows() [3]window {
+	return [3]window{
+		{c.MinuteCount, c.PrevMinuteCount, c.MinuteStart, time.Minute},
+		{c.HourlyCount, c.PrevHourlyCount, c.HourStart, time.Hour},
+		{c.DailyCount, c.PrevDailyCount, c.DayStart, 24 * time.Hour},
+	}
+}
+
+// roll starts new windows for the ones that have expired. A window that
+// expired less than one window length ago keeps its count as the previous
+// count and the new window follows it directly.
+func (c *Counter) roll(now time.Time) {
+	rollWindow(&c.MinuteCount, &c.PrevMinuteCount, &c.MinuteStart, time.Minute, now)
+	rollWindow(&c.HourlyCount, &c.PrevHourlyCount, &c.HourStart, time.Hour, now)
+	rollWindow(&c.DailyCount, &c.PrevDailyCount, &c.DayStart, 24*time.Hour, now)
+}
+
+func rollWindow(count, prev *int, start *time.Time, size time.Duration, now time.Time) {
+	// Counters persisted before minute windows existed have no start
+	if start.IsZero() {
+		*start = now
+		return
+	}
+
+	elapsed := now.Sub(*start)
+	if elapsed < size {
+		return
 	}
-	if now.Sub(counter.DayStart) >= 24*time.Hour {
-		counter.DailyCount = 0
-		counter.DayStart = now
+
+	if elapsed < 2*size {
+		*prev = *count
+		*start = start.Add(size)
+	} else {
+		*prev = 0
+		*start = now
+	}
+	*count = 0
+}
+
+// increment counts a message in every window
+func (c *Counter) increment() {
+	c.MinuteCount++
+	c.HourlyCount++
+	c.DailyCount++
+}
+
+// slidingCount estimates the number of messages in the last window length
+// by weighting the previous window by its overlap
+func (w window) slidingCount(now time.Time) int {
+	return w.count + int(float64(w.prev)*w.prevWeight(now))
+}
+
+// prevWeight returns the part of the previous window still inside the last
+// window length
+func (w window) prevWeight(now time.Time) float64 {
+	weight := 1 - float64(now.Sub(w.start))/float64(w.size)
+	if weight < 0 {
+		return 0
+	}
+	return weight
+}
+
+// slidingRetryAfter returns how long until the sliding count drops below max
+func (w window) slidingRetryAfter(max int, now time.Time) time.Duration {
+	var at time.Time
+	if w.count >= max {
+		// The current window alone is full. Once it becomes the previous
+		// window, wait until its weighted count drops below max.
+		at = w.start.Add(w.size).Add(time.Duration((1 - float64(max)/float64(w.count)) * float64(w.size)))
+	} else {
+		// Wait until prev * weight < max - count
+		at = w.start.Add(time.Duration((1 - float64(max-w.count)/float64(w.prev)) * float64(w.size)))
+	}
+
+	if retry := at.Sub(now); retry > 0 {
+		return retry
 	}
+	return time.Second
 }
 
 func (l *Limiter) loadCounters() error {
@@ -476,8 +674,8 @@ func (l *Limiter) persistCounters() error {
 	})
 }
 
-func (l *Limiter) persistLoop() {
-	ticker := time.NewTicker(l.config.FlushInterval)
+func (l *Limiter) persistLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	// Clean up expired counters every hour
@@ -496,23 +694,25 @@ func (l *Limiter) persistLoop() {
 	}
 }
 
-// cleanupExpiredCounters removes counters that haven't been used for 24+ hours
-// from both in-memory map and BoltDB storage
+// cleanupExpiredCounters removes counters that haven't been used for 48+ hours
+// from both in-memory map and BoltDB storage. The sliding window algorithm
+// still uses the previous day for 24 hours after it ends.
 func (l *Limiter) cleanupExpiredCounters() {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
 	now := time.Now()
-	expireThreshold := 24 * time.Hour
+	expireThreshold := 48 * time.Hour
 	var expiredKeys []string
 
 	for key, counter := range l.counters {
-		// If both hourly and daily counters are expired (>24h old), remove the counter
+		// If both hourly and daily counters are expired (>48h old), remove the counter
 		if now.Sub(counter.HourStart) > expireThreshold && now.Sub(counter.DayStart) > expireThreshold {
 			delete(l.counters, key)
 			expiredKeys = append(expiredKeys, key)
 		}
 	}
+	l.cleanupAdaptive(now)
 
 	// Also remove from BoltDB to prevent database growth
 	if len(expiredKeys) > 0 {
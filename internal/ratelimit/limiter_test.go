@@ -162,6 +162,53 @@ func TestAllowDomainLimit(t *testing.T) {
 	}
 }
 
+func TestSetConfig(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	cfg := &Config{
+		DefaultDomain: &LimitConfig{
+			MessagesPerHour: 2,
+		},
+		FlushInterval: time.Hour,
+	}
+
+	limiter, err := NewLimiter(db, cfg)
+	if err != nil {
+		t.Fatalf("failed to create limiter: %v", err)
+	}
+	defer limiter.Stop()
+
+	ctx := context.Background()
+	req := &Request{Domain: "example.com"}
+	for i := 0; i < 2; i++ {
+		limiter.Allow(ctx, req)
+	}
+	if result, _ := limiter.Allow(ctx, req); result.Allowed {
+		t.Fatal("request 3 should be denied")
+	}
+
+	// Raised limits apply to the counts so far
+	next := &Config{
+		DefaultDomain: &LimitConfig{
+			MessagesPerHour: 3,
+		},
+	}
+	limiter.SetConfig(next)
+	if next.FlushInterval != 0 {
+		t.Errorf("SetConfig changed the caller's FlushInterval to %v", next.FlushInterval)
+	}
+	if result, _ := limiter.Allow(ctx, req); !result.Allowed {
+		t.Error("request 3 should be allowed after raising the limit")
+	}
+	if result, _ := limiter.Allow(ctx, req); result.Allowed {
+		t.Error("request 4 should be denied")
+	}
+	if limiter.config.FlushInterval != time.Hour {
+		t.Errorf("FlushInterval = %v, want 1h", limiter.config.FlushInterval)
+	}
+}
+
 func TestAllowSenderLimit(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -272,6 +319,45 @@ func TestAllowAPIKeyLimit(t *testing.T) {
 	}
 }
 
+func TestAllowAPIKeyOverride(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	cfg := &Config{
+		DefaultAPIKey: &LimitConfig{
+			MessagesPerHour: 1,
+		},
+		FlushInterval: time.Hour,
+	}
+
+	limiter, err := NewLimiter(db, cfg)
+	if err != nil {
+		t.Fatalf("failed to create limiter: %v", err)
+	}
+	defer limiter.Stop()
+
+	ctx := context.Background()
+
+	// The key limit replaces the default
+	limit := &LimitConfig{MessagesPerHour: 3}
+	for i := 0; i < 3; i++ {
+		result, _ := limiter.AllowAPIKey(ctx, "key-1", limit)
+		if !result.Allowed {
+			t.Errorf("API key request %d should be allowed", i+1)
+		}
+	}
+	result, _ := limiter.AllowAPIKey(ctx, "key-1", limit)
+	if result.Allowed || result.DeniedBy != LevelAPIKey {
+		t.Errorf("API key request 4 result = %+v, want denied by api_key", result)
+	}
+
+	// Keys without their own limit use the default
+	limiter.AllowAPIKey(ctx, "key-2", nil)
+	if result, _ := limiter.AllowAPIKey(ctx, "key-2", nil); result.Allowed {
+		t.Error("default API key limit was not applied")
+	}
+}
+
 func TestAllowDailyLimit(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -976,3 +1062,181 @@ func TestCheckWithoutIncrement(t *testing.T) {
 		t.Error("Check should report denied after limit reached")
 	}
 }
+
+func TestAllowMinuteLimit(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	cfg := &Config{
+		DefaultSender: &LimitConfig{
+			MessagesPerMinute: 3,
+			MessagesPerHour:   100,
+		},
+		FlushInterval: time.Hour,
+	}
+
+	limiter, err := NewLimiter(db, cfg)
+	if err != nil {
+		t.Fatalf("failed to create limiter: %v", err)
+	}
+	defer limiter.Stop()
+
+	ctx := context.Background()
+	req := &Request{Sender: "burst@example.com"}
+
+	for i := 0; i < 3; i++ {
+		result, _ := limiter.Allow(ctx, req)
+		if !result.Allowed {
+			t.Fatalf("request %d should be allowed", i+1)
+		}
+	}
+
+	result, _ := limiter.Allow(ctx, req)
+	if result.Allowed {
+		t.Fatal("4th request should be denied by minute limit")
+	}
+	if result.RetryAfter <= 0 || result.RetryAfter > time.Minute {
+		t.Errorf("RetryAfter = %v, want within a minute", result.RetryAfter)
+	}
+
+	// Move the minute window two minutes back
+	limiter.mu.Lock()
+	limiter.counters[makeKey(LevelSender, "burst@example.com")].MinuteStart = time.Now().Add(-2 * time.Minute)
+	limiter.mu.Unlock()
+
+	result, _ = limiter.Allow(ctx, req)
+	if !result.Allowed {
+		t.Error("request should be allowed in a new minute")
+	}
+
+	stats, _ := limiter.GetStats(ctx, LevelSender, "burst@example.com")
+	if stats.MinuteCount != 1 || stats.HourlyCount != 4 {
+		t.Errorf("stats minute=%d hourly=%d, want 1 and 4", stats.MinuteCount, stats.HourlyCount)
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	tests := []struct {
+		name      string
+		algorithm Algorithm
+		override  Algorithm
+		allowed   int
+	}{
+		// The previous minute still weighs 9 of 10 messages
+		{name: "sliding", algorithm: AlgorithmSliding, allowed: 1},
+		{name: "fixed", algorithm: AlgorithmFixed, allowed: 10},
+		{name: "level override", algorithm: AlgorithmFixed, override: AlgorithmSliding, allowed: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, cleanup := setupTestDB(t)
+			defer cleanup()
+
+			cfg := &Config{
+				Algorithm:     tt.algorithm,
+				Global:        &LimitConfig{MessagesPerMinute: 10, Algorithm: tt.override},
+				FlushInterval: time.Hour,
+			}
+
+			limiter, err := NewLimiter(db, cfg)
+			if err != nil {
+				t.Fatalf("failed to create limiter: %v", err)
+			}
+			defer limiter.Stop()
+
+			// A full minute that ended 5 seconds ago
+			now := time.Now()
+			limiter.counters[makeKey(LevelGlobal, "global")] = &Counter{
+				MinuteCount: 10,
+				MinuteStart: now.Add(-65 * time.Second),
+				HourStart:   now,
+				DayStart:    now,
+			}
+
+			ctx := context.Background()
+			allowed := 0
+			for i := 0; i < 20; i++ {
+				result, _ := limiter.Allow(ctx, &Request{})
+				if !result.Allowed {
+					if result.DeniedBy != LevelGlobal || result.RetryAfter <= 0 {
+						t.Errorf("denied result = %+v", result)
+					}
+					break
+				}
+				allowed++
+			}
+
+			if allowed != tt.allowed {
+				t.Errorf("allowed %d requests, want %d", allowed, tt.allowed)
+			}
+		})
+	}
+}
+
+func TestSlidingStats(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	cfg := &Config{
+		Algorithm:     AlgorithmSliding,
+		Global:        &LimitConfig{MessagesPerHour: 100},
+		FlushInterval: time.Hour,
+	}
+
+	limiter, err := NewLimiter(db, cfg)
+	if err != nil {
+		t.Fatalf("failed to create limiter: %v", err)
+	}
+	defer limiter.Stop()
+
+	// Half of the previous hour still overlaps the last 60 minutes
+	now := time.Now()
+	limiter.counters[makeKey(LevelGlobal, "global")] = &Counter{
+		HourlyCount: 40,
+		HourStart:   now.Add(-90*time.Minute + time.Second),
+		MinuteStart: now,
+		DayStart:    now,
+	}
+
+	stats, _ := limiter.GetStats(context.Background(), LevelGlobal, "global")
+	if stats.Algorithm != AlgorithmSliding {
+		t.Errorf("Algorithm = %s, want sliding", stats.Algorithm)
+	}
+	if stats.HourlyCount != 20 {
+		t.Errorf("HourlyCount = %d, want weighted 20", stats.HourlyCount)
+	}
+}
+
+func TestSlidingRetryAfter(t *testing.T) {
+	now := time.Now()
+
+	// 8 of 10 used in the current minute, previous minute had 10:
+	// wait until 10 * weight < 2, i.e. weight below 0.2
+	w := window{count: 8, prev: 10, start: now.Add(-30 * time.Second), size: time.Minute}
+	if got := w.slidingRetryAfter(10, now); got < 17*time.Second || got > 19*time.Second {
+		t.Errorf("slidingRetryAfter() = %v, want about 18s", got)
+	}
+
+	// Current minute full: wait for the next minute
+	w = window{count: 10, start: now.Add(-30 * time.Second), size: time.Minute}
+	if got := w.slidingRetryAfter(10, now); got < 29*time.Second || got > 31*time.Second {
+		t.Errorf("slidingRetryAfter() = %v, want about 30s", got)
+	}
+}
+
+func TestRollKeepsPreviousWindow(t *testing.T) {
+	now := time.Now()
+	start := now.Add(-90 * time.Second)
+	c := &Counter{MinuteCount: 7, MinuteStart: start, HourStart: now, DayStart: now}
+
+	c.roll(now)
+	if c.MinuteCount != 0 || c.PrevMinuteCount != 7 || !c.MinuteStart.Equal(start.Add(time.Minute)) {
+		t.Errorf("after roll = %+v, want previous count 7 and adjacent window", c)
+	}
+
+	c.roll(now.Add(3 * time.Minute))
+	if c.PrevMinuteCount != 0 {
+		t.Errorf("PrevMinuteCount = %d, want 0 after an idle window", c.PrevMinuteCount)
+	}
+}
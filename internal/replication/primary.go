@@ -0,0 +1,445 @@
+package replication
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/foxzi/sendry/internal/queue"
+)
+
+// snapshotPageSize is the number of messages read at once for a snapshot
+const snapshotPageSize = 500
+
+// finalSyncTimeout limits the sync of the last changes on shutdown
+const finalSyncTimeout = 10 * time.Second
+
+// PrimaryConfig configures the primary node
+type PrimaryConfig struct {
+	PeerURL  string        // Base URL of the standby API
+	APIKey   string        // API key of the standby
+	Client   *http.Client  // Client for the standby, with its TLS settings
+	Interval time.Duration // Heartbeat and retry interval
+}
+
+// Primary is the queue of a primary node. It stores messages in the wrapped
+// storage and streams every change to the standby in the background.
+type Primary struct {
+	queue.Storage
+
+	cfg    PrimaryConfig
+	client *http.Client
+	logger *slog.Logger
+
+	notify chan struct{}
+	done   chan struct{}
+	wg     sync.WaitGroup
+
+	mu           sync.Mutex
+	dirty        map[string]struct{} // Messages changed since the last sync
+	snapshot     bool                // The standby needs a full copy
+	connected    bool
+	lastSync     time.Time
+	lastSnapshot time.Time
+	lastError    string
+	replicated   int64
+}
+
+// NewPrimary wraps storage to replicate it to the standby. The first sync
+// sends a full copy of the queue.
+func NewPrimary(storage queue.Storage, cfg PrimaryConfig, logger *slog.Logger) *Primary {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 10 * time.Second
+	}
+	client := cfg.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	cfg.PeerURL = strings.TrimSuffix(cfg.PeerURL, "/")
+
+	return &Primary{
+		Storage:  storage,
+		cfg:      cfg,
+		client:   client,
+		logger:   logger,
+		notify:   make(chan struct{}, 1),
+		done:     make(chan struct{}),
+		dirty:    make(map[string]struct{}),
+		snapshot: true,
+	}
+}
+
+// Enqueue adds a message to the queue
+func (p *Primary) Enqueue(ctx context.Context, msg *queue.Message) error {
+	if err := p.Storage.Enqueue(ctx, msg); err != nil {
+		return err
+	}
+	p.changed(msg.ID)
+	return nil
+}
+
+// EnqueueBatch adds messages to the queue in a single transaction
+func (p *Primary) EnqueueBatch(ctx context.Context, msgs []*queue.Message) error {
+	if err := p.Storage.EnqueueBatch(ctx, msgs); err != nil {
+		return err
+	}
+	ids := make([]string, len(msgs))
+	for i, msg := range msgs {
+		ids[i] = msg.ID
+	}
+	p.changed(ids...)
+	return nil
+}
+
+// Dequeue gets the next message for processing
+func (p *Primary) Dequeue(ctx context.Context) (*queue.Message, error) {
+	msg, err := p.Storage.Dequeue(ctx)
+	if err == nil && msg != nil {
+		p.changed(msg.ID)
+	}
+	return msg, err
+}
+
+// Update updates the message status
+func (p *Primary) Update(ctx context.Context, msg *queue.Message) error {
+	if err := p.Storage.Update(ctx, msg); err != nil {
+		return err
+	}
+	p.changed(msg.ID)
+	return nil
+}
+
+// Delete removes a message from the queue
+func (p *Primary) Delete(ctx context.Context, id string) error {
+	if err := p.Storage.Delete(ctx, id); err != nil {
+		return err
+	}
+	p.changed(id)
+	return nil
+}
+
+// Import stores a message as is
+func (p *Primary) Import(ctx context.Context, msg *queue.Message, inDLQ bool) error {
+	if err := p.Storage.Import(ctx, msg, inDLQ); err != nil {
+		return err
+	}
+	p.changed(msg.ID)
+	return nil
+}
+
+// MoveToDLQ moves a message to the dead letter queue
+func (p *Primary) MoveToDLQ(ctx context.Context, msg *queue.Message) error {
+	if err := p.Storage.MoveToDLQ(ctx, msg); err != nil {
+		return err
+	}
+	p.changed(msg.ID)
+	return nil
+}
+
+// RetryFromDLQ moves a message from the dead letter queue back for retry
+func (p *Primary) RetryFromDLQ(ctx context.Context, id string) error {
+	if err := p.Storage.RetryFromDLQ(ctx, id); err != nil {
+		return err
+	}
+	p.changed(id)
+	return nil
+}
+
+// DeleteFromDLQ permanently deletes a message from the dead letter queue
+func (p *Primary) DeleteFromDLQ(ctx context.Context, id string) error {
+	if err := p.Storage.DeleteFromDLQ(ctx, id); err != nil {
+		return err
+	}
+	p.changed(id)
+	return nil
+}
+
+// CleanupDelivered removes delivered messages older than maxAge. The
+// removed messages are not known, the standby gets a full copy.
+func (p *Primary) CleanupDelivered(ctx context.Context, maxAge time.Duration) (int, error) {
+	n, err := p.Storage.CleanupDelivered(ctx, maxAge)
+	if n > 0 {
+		p.requestSnapshot()
+	}
+	return n, err
+}
+
+// CleanupDLQ removes dead letter queue messages by age and count
+func (p *Primary) CleanupDLQ(ctx context.Context, maxAge time.Duration, maxCount int) (int, error) {
+	n, err := p.Storage.CleanupDLQ(ctx, maxAge, maxCount)
+	if n > 0 {
+		p.requestSnapshot()
+	}
+	return n, err
+}
+
+// changed marks messages to be sent with the next sync
+func (p *Primary) changed(ids ...string) {
+	p.mu.Lock()
+	for _, id := range ids {
+		p.dirty[id] = struct{}{}
+	}
+	p.mu.Unlock()
+	p.wake()
+}
+
+// requestSnapshot makes the next sync send a full copy of the queue
+func (p *Primary) requestSnapshot() {
+	p.mu.Lock()
+	p.snapshot = true
+	p.mu.Unlock()
+	p.wake()
+}
+
+func (p *Primary) wake() {
+	select {
+	case p.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Start starts streaming changes to the standby
+func (p *Primary) Start(ctx context.Context) {
+	p.wg.Add(1)
+	go p.loop(ctx)
+
+	p.logger.Info("queue replication started", "role", RolePrimary, "peer", p.cfg.PeerURL)
+}
+
+// Stop stops streaming after sending the last changes
+func (p *Primary) Stop() {
+	close(p.done)
+	p.wg.Wait()
+}
+
+// loop runs until Stop rather than until ctx is cancelled, so changes made
+// while the other components shut down are still sent
+func (p *Primary) loop(ctx context.Context) {
+	defer p.wg.Done()
+
+	ctx = context.WithoutCancel(ctx)
+	ticker := time.NewTicker(p.cfg.Interval)
+	defer ticker.Stop()
+
+	failed := p.sync(ctx) != nil
+
+	for {
+		select {
+		case <-p.done:
+			p.finalSync(ctx)
+			return
+		case <-p.notify:
+			// After a failure changes wait for the next retry
+			if !failed {
+				failed = p.sync(ctx) != nil
+			}
+		case <-ticker.C:
+			failed = p.sync(ctx) != nil
+		}
+	}
+}
+
+// finalSync sends the changes made since the last sync, e.g. by deliveries
+// that finished during shutdown
+func (p *Primary) finalSync(ctx context.Context) {
+	p.mu.Lock()
+	pending := len(p.dirty) > 0 && !p.snapshot && p.connected
+	p.mu.Unlock()
+	if !pending {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, finalSyncTimeout)
+	defer cancel()
+	p.sync(ctx)
+}
+
+// sync sends the changed messages, a full copy of the queue if the standby
+// needs one, or a heartbeat
+func (p *Primary) sync(ctx context.Context) error {
+	p.mu.Lock()
+	snapshot := p.snapshot
+	ids := p.dirty
+	p.dirty = make(map[string]struct{})
+	p.snapshot = false
+	p.mu.Unlock()
+
+	applied, err := p.send(ctx, snapshot, ids)
+	if err == ErrSnapshotRequired && !snapshot {
+		// The standby restarted, it gets a full copy right away
+		snapshot = true
+		applied, err = p.send(ctx, true, nil)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err != nil {
+		// Changes made meanwhile are merged, they are sent on the next retry
+		for id := range ids {
+			p.dirty[id] = struct{}{}
+		}
+		if snapshot {
+			p.snapshot = true
+		}
+		if p.connected || p.lastError != err.Error() {
+			p.logger.Warn("queue replication failed", "peer", p.cfg.PeerURL, "error", err)
+		}
+		p.connected = false
+		p.lastError = err.Error()
+		return err
+	}
+
+	if !p.connected {
+		p.logger.Info("queue replication connected", "peer", p.cfg.PeerURL)
+	}
+	now := time.Now()
+	p.connected = true
+	p.lastSync = now
+	p.lastError = ""
+	p.replicated += int64(applied)
+	if snapshot {
+		p.lastSnapshot = now
+		p.logger.Info("queue snapshot replicated", "peer", p.cfg.PeerURL, "records", applied)
+	}
+	return nil
+}
+
+// send streams records to the standby and returns the number it applied
+func (p *Primary) send(ctx context.Context, snapshot bool, ids map[string]struct{}) (int, error) {
+	body, pw := io.Pipe()
+	written := make(chan error, 1)
+	go func() {
+		err := p.write(ctx, json.NewEncoder(pw), snapshot, ids)
+		pw.CloseWithError(err)
+		written <- err
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.cfg.PeerURL+StreamPath, body)
+	if err != nil {
+		body.Close()
+		<-written
+		return 0, err
+	}
+	req.Header.Set("Content-Type", streamMediaType)
+	if p.cfg.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.cfg.APIKey)
+	}
+
+	resp, err := p.client.Do(req)
+	body.Close()
+	if werr := <-written; werr != nil && err == nil {
+		err = werr
+	}
+	if err != nil {
+		if resp != nil {
+			resp.Body.Close()
+		}
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusConflict:
+		return 0, ErrSnapshotRequired
+	default:
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(io.LimitReader(resp.Body, 4096)).Decode(&apiErr)
+		if apiErr.Error == "" {
+			apiErr.Error = resp.Status
+		}
+		return 0, fmt.Errorf("standby: %s", apiErr.Error)
+	}
+
+	var result struct {
+		Applied int `json:"applied"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return 0, fmt.Errorf("invalid standby response: %w", err)
+	}
+	return result.Applied, nil
+}
+
+// write encodes the records of a sync
+func (p *Primary) write(ctx context.Context, enc *json.Encoder, snapshot bool, ids map[string]struct{}) error {
+	if snapshot {
+		return p.writeSnapshot(ctx, enc)
+	}
+	if len(ids) == 0 {
+		return enc.Encode(Record{Op: OpHeartbeat})
+	}
+
+	for id := range ids {
+		msg, err := p.Storage.Get(ctx, id)
+		if err != nil {
+			return fmt.Errorf("failed to read message %s: %w", id, err)
+		}
+		rec := Record{Op: OpDelete, ID: id}
+		if msg != nil {
+			rec = Record{Op: OpPut, ID: id, Message: msg, DLQ: msg.Status == queue.StatusFailed}
+		}
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSnapshot encodes a full copy of the queue
+func (p *Primary) writeSnapshot(ctx context.Context, enc *json.Encoder) error {
+	dlq, err := p.Storage.ListDLQ(ctx, 0, 0)
+	if err != nil {
+		return fmt.Errorf("failed to list dead letter queue: %w", err)
+	}
+	inDLQ := make(map[string]bool, len(dlq))
+	for _, msg := range dlq {
+		inDLQ[msg.ID] = true
+	}
+
+	if err := enc.Encode(Record{Op: OpSnapshot}); err != nil {
+		return err
+	}
+	cursor := ""
+	for {
+		msgs, err := p.Storage.List(ctx, queue.ListFilter{Cursor: cursor, Limit: snapshotPageSize})
+		if err != nil {
+			return fmt.Errorf("failed to list messages: %w", err)
+		}
+		for _, msg := range msgs {
+			if err := enc.Encode(Record{Op: OpPut, ID: msg.ID, Message: msg, DLQ: inDLQ[msg.ID]}); err != nil {
+				return err
+			}
+		}
+		if len(msgs) < snapshotPageSize {
+			break
+		}
+		cursor = msgs[len(msgs)-1].ID
+	}
+	return enc.Encode(Record{Op: OpSnapshotEnd})
+}
+
+// Status returns the replication state
+func (p *Primary) Status() Status {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return Status{
+		Role:         RolePrimary,
+		Peer:         p.cfg.PeerURL,
+		Connected:    p.connected,
+		LastSync:     timePtr(p.lastSync),
+		LastSnapshot: timePtr(p.lastSnapshot),
+		LastError:    p.lastError,
+		Pending:      len(p.dirty),
+		Replicated:   p.replicated,
+	}
+}
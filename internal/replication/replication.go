@@ -0,0 +1,74 @@
+// Package replication streams the message queue of a primary node to a
+// standby node, so queued mail survives the loss of the primary's disk.
+// Every change of a message is sent as the message's current state; the
+// standby stores it as is and does not deliver until it is promoted.
+package replication
+
+import (
+	"errors"
+	"time"
+
+	"github.com/foxzi/sendry/internal/queue"
+)
+
+// Node roles
+const (
+	RolePrimary  = "primary"
+	RoleStandby  = "standby"
+	RolePromoted = "promoted" // A standby that took over delivery
+)
+
+// Record operations
+const (
+	OpPut         = "put"          // Store the message as is
+	OpDelete      = "delete"       // Remove the message
+	OpSnapshot    = "snapshot"     // A full copy of the queue follows
+	OpSnapshotEnd = "snapshot_end" // Remove messages not in the copy
+	OpHeartbeat   = "heartbeat"    // No changes, the primary is alive
+)
+
+// StreamPath is the API path of the standby that receives the stream
+const StreamPath = "/api/v1/replication/stream"
+
+// streamMediaType is the content type of a stream, one record per line
+const streamMediaType = "application/x-ndjson"
+
+// ErrStandby is returned when a standby is asked to accept messages
+var ErrStandby = errors.New("node is a replication standby, messages are accepted by the primary")
+
+// ErrNotStandby is returned when replicating to or promoting a node that is
+// not a standby
+var ErrNotStandby = errors.New("node is not a replication standby")
+
+// ErrSnapshotRequired is returned by a standby that has to receive a full
+// copy of the queue before single changes, e.g. after it restarted
+var ErrSnapshotRequired = errors.New("standby requires a snapshot")
+
+// Record is one replicated change, streamed as a JSON line
+type Record struct {
+	Op      string         `json:"op"`
+	ID      string         `json:"id,omitempty"`
+	Message *queue.Message `json:"message,omitempty"`
+	DLQ     bool           `json:"dlq,omitempty"`
+}
+
+// Status is the replication state of a node
+type Status struct {
+	Role         string     `json:"role"`
+	Peer         string     `json:"peer,omitempty"`      // Standby URL, or address of the primary
+	Connected    bool       `json:"connected"`           // The peer answered recently
+	LastSync     *time.Time `json:"last_sync,omitempty"` // Last successful exchange
+	LastSnapshot *time.Time `json:"last_snapshot,omitempty"`
+	LastError    string     `json:"last_error,omitempty"`
+	Pending      int        `json:"pending"`    // Changed messages not yet sent (primary)
+	Replicated   int64      `json:"replicated"` // Records sent or applied since start
+	PromotedAt   *time.Time `json:"promoted_at,omitempty"`
+}
+
+// timePtr returns a pointer to a copy of t, nil for the zero time
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
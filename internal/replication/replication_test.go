@@ -0,0 +1,226 @@
+package replication
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/foxzi/sendry/internal/queue"
+)
+
+var testLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func newStorage(t *testing.T, dir string) *queue.BoltStorage {
+	t.Helper()
+	storage, err := queue.NewBoltStorage(filepath.Join(dir, "queue.db"))
+	if err != nil {
+		t.Fatalf("NewBoltStorage() error = %v", err)
+	}
+	t.Cleanup(func() { storage.Close() })
+	return storage
+}
+
+func newMessage(id string) *queue.Message {
+	now := time.Now()
+	return &queue.Message{
+		ID:        id,
+		From:      "sender@example.com",
+		To:        []string{"rcpt@example.com"},
+		Data:      []byte("Subject: test\r\n\r\nbody"),
+		Status:    queue.StatusPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// standbyServer serves the stream endpoint of a standby like the API does
+func standbyServer(t *testing.T, s *Standby) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		applied, err := s.Receive(r.Context(), r.Body, r.RemoteAddr)
+		switch {
+		case errors.Is(err, ErrSnapshotRequired):
+			w.WriteHeader(http.StatusConflict)
+		case errors.Is(err, ErrNotStandby):
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprintf(w, `{"error":%q}`, err.Error())
+		case err != nil:
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, `{"error":%q}`, err.Error())
+		default:
+			json.NewEncoder(w).Encode(map[string]int{"applied": applied})
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func ids(t *testing.T, storage queue.Storage) []string {
+	t.Helper()
+	msgs, err := storage.List(context.Background(), queue.ListFilter{})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	var ids []string
+	for _, msg := range msgs {
+		ids = append(ids, msg.ID)
+	}
+	return ids
+}
+
+func TestReplication(t *testing.T) {
+	ctx := context.Background()
+	primaryDir, standbyDir := t.TempDir(), t.TempDir()
+
+	primaryStorage := newStorage(t, primaryDir)
+	for _, id := range []string{"a", "b"} {
+		if err := primaryStorage.Enqueue(ctx, newMessage(id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	standbyStorage := newStorage(t, standbyDir)
+	if err := standbyStorage.Enqueue(ctx, newMessage("stale")); err != nil {
+		t.Fatal(err)
+	}
+
+	statePath := filepath.Join(standbyDir, "queue.db.promoted")
+	standby, err := NewStandby(standbyStorage, StandbyConfig{StatePath: statePath}, testLogger)
+	if err != nil {
+		t.Fatalf("NewStandby() error = %v", err)
+	}
+	srv := standbyServer(t, standby)
+	primary := NewPrimary(primaryStorage, PrimaryConfig{PeerURL: srv.URL}, testLogger)
+
+	// The first sync sends a full copy and removes what the primary lacks
+	if err := primary.sync(ctx); err != nil {
+		t.Fatalf("snapshot sync error = %v", err)
+	}
+	if got := fmt.Sprint(ids(t, standbyStorage)); got != "[a b]" {
+		t.Fatalf("standby after snapshot has %s, want [a b]", got)
+	}
+
+	// Changes are sent as the current state of the messages
+	if err := primary.Enqueue(ctx, newMessage("c")); err != nil {
+		t.Fatal(err)
+	}
+	if err := primary.Delete(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := primary.Get(ctx, "b")
+	if err := primary.MoveToDLQ(ctx, b); err != nil {
+		t.Fatal(err)
+	}
+	if st := primary.Status(); st.Pending != 3 {
+		t.Errorf("Pending = %d, want 3", st.Pending)
+	}
+	if err := primary.sync(ctx); err != nil {
+		t.Fatalf("sync error = %v", err)
+	}
+	if got := fmt.Sprint(ids(t, standbyStorage)); got != "[b c]" {
+		t.Fatalf("standby after changes has %s, want [b c]", got)
+	}
+	if msg, _ := standbyStorage.GetFromDLQ(ctx, "b"); msg == nil {
+		t.Error("message moved to the dead letter queue is not in the standby's")
+	}
+	if stats, _ := standbyStorage.DLQStats(ctx); stats.Total != 1 {
+		t.Errorf("standby DLQ total = %d, want 1", stats.Total)
+	}
+
+	st := primary.Status()
+	if !st.Connected || st.Pending != 0 || st.LastSnapshot == nil || st.Replicated != 5 {
+		t.Errorf("primary status = %+v", st)
+	}
+	if st := standby.Status(); st.Role != RoleStandby || !st.Connected || st.Replicated != 5 {
+		t.Errorf("standby status = %+v", st)
+	}
+
+	// A standby refuses new mail until it is promoted
+	if err := standby.Enqueue(ctx, newMessage("d")); !errors.Is(err, ErrStandby) {
+		t.Fatalf("standby Enqueue() error = %v, want ErrStandby", err)
+	}
+
+	started := false
+	standby.OnPromote(func() { started = true })
+	if err := standby.Promote(); err != nil {
+		t.Fatalf("Promote() error = %v", err)
+	}
+	if !started {
+		t.Error("OnPromote callback did not run")
+	}
+	if err := standby.Enqueue(ctx, newMessage("d")); err != nil {
+		t.Errorf("promoted Enqueue() error = %v", err)
+	}
+	if st := standby.Status(); st.Role != RolePromoted || st.PromotedAt == nil {
+		t.Errorf("promoted status = %+v", st)
+	}
+
+	// The old primary can no longer overwrite the queue
+	if err := primary.Delete(ctx, "c"); err != nil {
+		t.Fatal(err)
+	}
+	if err := primary.sync(ctx); err == nil {
+		t.Error("sync to a promoted standby succeeded")
+	}
+	if msg, _ := standbyStorage.Get(ctx, "c"); msg == nil {
+		t.Error("promoted standby applied a change of the old primary")
+	}
+	if st := primary.Status(); st.Connected || st.Pending != 1 || st.LastError == "" {
+		t.Errorf("primary status after failure = %+v", st)
+	}
+
+	// The promotion survives a restart
+	restarted, err := NewStandby(standbyStorage, StandbyConfig{StatePath: statePath}, testLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !restarted.Promoted() {
+		t.Error("restarted standby is not promoted")
+	}
+}
+
+func TestReplication_SnapshotRequired(t *testing.T) {
+	ctx := context.Background()
+	primaryStorage := newStorage(t, t.TempDir())
+	standbyDir := t.TempDir()
+	standbyStorage := newStorage(t, standbyDir)
+
+	standby, err := NewStandby(standbyStorage, StandbyConfig{StatePath: filepath.Join(standbyDir, "promoted")}, testLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	primary := NewPrimary(primaryStorage, PrimaryConfig{PeerURL: standbyServer(t, standby).URL}, testLogger)
+
+	if err := primaryStorage.Enqueue(ctx, newMessage("a")); err != nil {
+		t.Fatal(err)
+	}
+	if err := primary.sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// A restarted standby lost track of the changes, it asks for a snapshot
+	standby, err = NewStandby(standbyStorage, StandbyConfig{StatePath: filepath.Join(standbyDir, "promoted")}, testLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	primary.cfg.PeerURL = standbyServer(t, standby).URL
+	if err := primaryStorage.Enqueue(ctx, newMessage("b")); err != nil {
+		t.Fatal(err)
+	}
+	if err := primary.Enqueue(ctx, newMessage("c")); err != nil {
+		t.Fatal(err)
+	}
+	if err := primary.sync(ctx); err != nil {
+		t.Fatalf("sync error = %v", err)
+	}
+	if got := fmt.Sprint(ids(t, standbyStorage)); got != "[a b c]" {
+		t.Errorf("standby has %s, want [a b c]", got)
+	}
+}
@@ -0,0 +1,309 @@
+package replication
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/foxzi/sendry/internal/queue"
+)
+
+// StandbyConfig configures the standby node
+type StandbyConfig struct {
+	StatePath string        // File that records the promotion
+	Interval  time.Duration // Heartbeat interval of the primary
+}
+
+// Standby is the queue of a standby node. It stores the messages streamed
+// by the primary and refuses new messages until it is promoted.
+type Standby struct {
+	queue.Storage
+
+	cfg    StandbyConfig
+	logger *slog.Logger
+
+	// applyMu serializes applying records and the promotion
+	applyMu sync.Mutex
+
+	mu           sync.Mutex
+	promoted     bool
+	promotedAt   time.Time
+	onPromote    []func()
+	synced       bool // A snapshot was applied since start
+	peer         string
+	lastSync     time.Time
+	lastSnapshot time.Time
+	lastError    string
+	replicated   int64
+}
+
+// NewStandby wraps storage to receive the queue of the primary. A standby
+// that was promoted before stays promoted.
+func NewStandby(storage queue.Storage, cfg StandbyConfig, logger *slog.Logger) (*Standby, error) {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 10 * time.Second
+	}
+	s := &Standby{
+		Storage: storage,
+		cfg:     cfg,
+		logger:  logger,
+	}
+
+	data, err := os.ReadFile(cfg.StatePath)
+	switch {
+	case err == nil:
+		s.promoted = true
+		s.promotedAt, _ = time.Parse(time.RFC3339, strings.TrimSpace(string(data)))
+	case !errors.Is(err, os.ErrNotExist):
+		return nil, fmt.Errorf("failed to read replication state: %w", err)
+	}
+	return s, nil
+}
+
+// Enqueue adds a message to the queue of a promoted standby
+func (s *Standby) Enqueue(ctx context.Context, msg *queue.Message) error {
+	if !s.Promoted() {
+		return ErrStandby
+	}
+	return s.Storage.Enqueue(ctx, msg)
+}
+
+// EnqueueBatch adds messages to the queue of a promoted standby
+func (s *Standby) EnqueueBatch(ctx context.Context, msgs []*queue.Message) error {
+	if !s.Promoted() {
+		return ErrStandby
+	}
+	return s.Storage.EnqueueBatch(ctx, msgs)
+}
+
+// Promoted reports whether the standby took over delivery
+func (s *Standby) Promoted() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.promoted
+}
+
+// OnPromote registers f to run when the standby is promoted, f runs right
+// away if it already is
+func (s *Standby) OnPromote(f func()) {
+	s.mu.Lock()
+	if !s.promoted {
+		s.onPromote = append(s.onPromote, f)
+		s.mu.Unlock()
+		return
+	}
+	s.mu.Unlock()
+	f()
+}
+
+// Promote makes the standby accept and deliver messages. The messages
+// streamed so far are delivered, the primary is no longer accepted.
+func (s *Standby) Promote() error {
+	s.applyMu.Lock()
+	defer s.applyMu.Unlock()
+
+	s.mu.Lock()
+	if s.promoted {
+		s.mu.Unlock()
+		return nil
+	}
+	now := time.Now()
+	if err := os.WriteFile(s.cfg.StatePath, []byte(now.UTC().Format(time.RFC3339)+"\n"), 0600); err != nil {
+		s.mu.Unlock()
+		return fmt.Errorf("failed to save replication state: %w", err)
+	}
+	s.promoted = true
+	s.promotedAt = now
+	callbacks := s.onPromote
+	s.onPromote = nil
+	s.mu.Unlock()
+
+	s.logger.Warn("standby promoted, delivering the replicated queue")
+	for _, f := range callbacks {
+		f()
+	}
+	return nil
+}
+
+// Receive applies a stream of records sent by the primary at peer and
+// returns the number of messages stored or removed
+func (s *Standby) Receive(ctx context.Context, r io.Reader, peer string) (int, error) {
+	dec := json.NewDecoder(r)
+	var seen map[string]struct{} // Messages of a snapshot in progress
+	applied := 0
+
+	for {
+		var rec Record
+		err := dec.Decode(&rec)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return applied, s.failed(fmt.Errorf("invalid record: %w", err))
+		}
+
+		if err := s.apply(ctx, &rec, &seen); err != nil {
+			if errors.Is(err, ErrSnapshotRequired) || errors.Is(err, ErrNotStandby) {
+				return applied, err
+			}
+			return applied, s.failed(err)
+		}
+		if rec.Op == OpPut || rec.Op == OpDelete {
+			applied++
+		}
+	}
+
+	s.mu.Lock()
+	s.peer = peer
+	s.lastSync = time.Now()
+	s.lastError = ""
+	s.replicated += int64(applied)
+	s.mu.Unlock()
+	return applied, nil
+}
+
+// failed records err as the last error of the replication
+func (s *Standby) failed(err error) error {
+	s.mu.Lock()
+	s.lastError = err.Error()
+	s.mu.Unlock()
+	return err
+}
+
+// apply stores one record
+func (s *Standby) apply(ctx context.Context, rec *Record, seen *map[string]struct{}) error {
+	s.applyMu.Lock()
+	defer s.applyMu.Unlock()
+
+	s.mu.Lock()
+	promoted, synced := s.promoted, s.synced
+	s.mu.Unlock()
+	if promoted {
+		return ErrNotStandby
+	}
+	// Single changes are only meaningful on top of a full copy
+	if !synced && *seen == nil && rec.Op != OpSnapshot {
+		return ErrSnapshotRequired
+	}
+
+	switch rec.Op {
+	case OpHeartbeat:
+		return nil
+
+	case OpSnapshot:
+		*seen = make(map[string]struct{})
+		return nil
+
+	case OpPut:
+		if rec.Message == nil || rec.Message.ID == "" {
+			return errors.New("put record without a message")
+		}
+		if err := s.remove(ctx, rec.Message.ID); err != nil {
+			return err
+		}
+		if err := s.Storage.Import(ctx, rec.Message, rec.DLQ); err != nil {
+			return fmt.Errorf("failed to store message %s: %w", rec.Message.ID, err)
+		}
+		if *seen != nil {
+			(*seen)[rec.Message.ID] = struct{}{}
+		}
+		return nil
+
+	case OpDelete:
+		return s.remove(ctx, rec.ID)
+
+	case OpSnapshotEnd:
+		if *seen == nil {
+			return errors.New("snapshot end without a snapshot")
+		}
+		removed, err := s.removeUnseen(ctx, *seen)
+		if err != nil {
+			return err
+		}
+		s.logger.Info("queue snapshot applied", "messages", len(*seen), "removed", removed)
+		*seen = nil
+
+		s.mu.Lock()
+		s.synced = true
+		s.lastSnapshot = time.Now()
+		s.mu.Unlock()
+		return nil
+
+	default:
+		return fmt.Errorf("unknown record op %q", rec.Op)
+	}
+}
+
+// remove deletes a message with its queue and dead letter queue indexes
+func (s *Standby) remove(ctx context.Context, id string) error {
+	msg, err := s.Storage.GetFromDLQ(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to read message %s: %w", id, err)
+	}
+	if msg != nil {
+		err = s.Storage.DeleteFromDLQ(ctx, id)
+	} else {
+		err = s.Storage.Delete(ctx, id)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete message %s: %w", id, err)
+	}
+	return nil
+}
+
+// removeUnseen deletes the messages a snapshot did not contain
+func (s *Standby) removeUnseen(ctx context.Context, seen map[string]struct{}) (int, error) {
+	var stale []string
+	cursor := ""
+	for {
+		msgs, err := s.Storage.List(ctx, queue.ListFilter{Cursor: cursor, Limit: snapshotPageSize})
+		if err != nil {
+			return 0, fmt.Errorf("failed to list messages: %w", err)
+		}
+		for _, msg := range msgs {
+			if _, ok := seen[msg.ID]; !ok {
+				stale = append(stale, msg.ID)
+			}
+		}
+		if len(msgs) < snapshotPageSize {
+			break
+		}
+		cursor = msgs[len(msgs)-1].ID
+	}
+
+	for _, id := range stale {
+		if err := s.remove(ctx, id); err != nil {
+			return 0, err
+		}
+	}
+	return len(stale), nil
+}
+
+// Status returns the replication state
+func (s *Standby) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	role := RoleStandby
+	if s.promoted {
+		role = RolePromoted
+	}
+	return Status{
+		Role: role,
+		Peer: s.peer,
+		// The primary sends at least a heartbeat every interval
+		Connected:    !s.promoted && !s.lastSync.IsZero() && time.Since(s.lastSync) < 3*s.cfg.Interval,
+		LastSync:     timePtr(s.lastSync),
+		LastSnapshot: timePtr(s.lastSnapshot),
+		LastError:    s.lastError,
+		Replicated:   s.replicated,
+		PromotedAt:   timePtr(s.promotedAt),
+	}
+}
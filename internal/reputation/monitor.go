@@ -0,0 +1,313 @@
+package reputation
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/foxzi/sendry/internal/dkim"
+	"github.com/foxzi/sendry/internal/dnscheck"
+	"github.com/foxzi/sendry/internal/email"
+	"github.com/foxzi/sendry/internal/metrics"
+)
+
+// flushInterval is how often recorded delivery outcomes are written to storage
+const flushInterval = time.Minute
+
+// Domains provides the configured sender domains and their DKIM signers
+type Domains interface {
+	ListDomains() []string
+	GetSigner(domain string) *dkim.Signer
+}
+
+// Checker looks up the DNS-based signals
+type Checker interface {
+	// DMARC returns the DMARC record status of a domain
+	DMARC(ctx context.Context, domain string) string
+	// Listings returns the DNSBL listings of the IPs
+	Listings(ctx context.Context, ips []string) []string
+}
+
+// Config contains reputation monitor settings
+type Config struct {
+	Interval  time.Duration // How often scores are updated
+	Window    time.Duration // Signals that count toward a score
+	Retention time.Duration // How long signals and history are kept
+	IPs       []string      // Sending IPs checked against DNSBLs
+	Hostname  string        // Resolved for sending IPs when IPs is empty
+}
+
+// Monitor records delivery outcomes of sender domains and periodically
+// scores their reputation
+type Monitor struct {
+	storage *Storage
+	domains Domains
+	checker Checker
+	cfg     Config
+	logger  *slog.Logger
+
+	mu      sync.Mutex
+	pending map[string]*Counts
+
+	wg   sync.WaitGroup
+	done chan struct{}
+}
+
+// NewMonitor creates a new reputation monitor
+func NewMonitor(storage *Storage, domains Domains, cfg Config, logger *slog.Logger) *Monitor {
+	return &Monitor{
+		storage: storage,
+		domains: domains,
+		checker: dnsChecker{},
+		cfg:     cfg,
+		logger:  logger,
+		pending: make(map[string]*Counts),
+		done:    make(chan struct{}),
+	}
+}
+
+// Delivered records recipients that accepted a message
+func (m *Monitor) Delivered(sender string, recipients []string) {
+	m.record(sender, recipients, func(c *Counts, p *ProviderCounts) {
+		c.Delivered++
+		p.Delivered++
+	})
+}
+
+// Deferred records recipients whose delivery is retried later
+func (m *Monitor) Deferred(sender string, recipients []string) {
+	m.record(sender, recipients, func(c *Counts, p *ProviderCounts) {
+		c.Deferred++
+		p.Deferred++
+	})
+}
+
+// Failed records recipients that permanently failed
+func (m *Monitor) Failed(sender string, recipients []string) {
+	m.record(sender, recipients, func(c *Counts, p *ProviderCounts) {
+		c.Failed++
+		p.Failed++
+	})
+}
+
+func (m *Monitor) record(sender string, recipients []string, count func(*Counts, *ProviderCounts)) {
+	domain := email.ExtractDomain(sender)
+	if domain == "" || len(recipients) == 0 {
+		return // Bounces have no sender
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.pending[domain]
+	if !ok {
+		c = &Counts{}
+		m.pending[domain] = c
+	}
+	for _, rcpt := range recipients {
+		count(c, c.provider(Provider(email.ExtractDomain(rcpt))))
+	}
+}
+
+// Start starts the flush and scoring goroutine
+func (m *Monitor) Start(ctx context.Context) {
+	m.wg.Add(1)
+	go m.loop(ctx)
+
+	m.logger.Info("reputation monitor started",
+		"interval", m.cfg.Interval,
+		"window", m.cfg.Window,
+	)
+}
+
+// Stop stops the monitor and writes recorded outcomes to storage
+func (m *Monitor) Stop() {
+	close(m.done)
+	m.wg.Wait()
+
+	if err := m.flush(context.Background()); err != nil {
+		m.logger.Error("failed to store reputation signals", "error", err)
+	}
+}
+
+func (m *Monitor) loop(ctx context.Context) {
+	defer m.wg.Done()
+
+	flushTicker := time.NewTicker(flushInterval)
+	defer flushTicker.Stop()
+	scoreTicker := time.NewTicker(m.cfg.Interval)
+	defer scoreTicker.Stop()
+
+	// Score immediately on start
+	m.run(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-m.done:
+			return
+		case <-flushTicker.C:
+			if err := m.flush(ctx); err != nil {
+				m.logger.Error("failed to store reputation signals", "error", err)
+			}
+		case <-scoreTicker.C:
+			m.run(ctx)
+		}
+	}
+}
+
+func (m *Monitor) run(ctx context.Context) {
+	scores, err := m.Update(ctx)
+	if err != nil {
+		m.logger.Error("failed to update reputation scores", "error", err)
+		return
+	}
+
+	for _, s := range scores {
+		if s.Trend == TrendDown || s.Grade == GradePoor {
+			m.logger.Warn("sender domain reputation degrading",
+				"domain", s.Domain,
+				"score", s.Score,
+				"change", s.Change,
+				"penalties", s.Penalties,
+			)
+		}
+	}
+}
+
+// flush adds the recorded outcomes to the current hour
+func (m *Monitor) flush(ctx context.Context) error {
+	m.mu.Lock()
+	pending := m.pending
+	m.pending = make(map[string]*Counts)
+	m.mu.Unlock()
+
+	now := time.Now()
+	for domain, counts := range pending {
+		if err := m.storage.Add(ctx, domain, now, counts); err != nil {
+			return fmt.Errorf("failed to add signals of %s: %w", domain, err)
+		}
+	}
+	return nil
+}
+
+// Update scores all sender domains with signals in the window and all
+// configured domains, and prunes expired signals
+func (m *Monitor) Update(ctx context.Context) ([]*Score, error) {
+	if err := m.flush(ctx); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	domains, err := m.storage.Domains(ctx, now.Add(-m.cfg.Window))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list domains: %w", err)
+	}
+	if m.domains != nil {
+		domains = append(domains, m.domains.ListDomains()...)
+	}
+	domains = unique(domains)
+
+	listings := m.checker.Listings(ctx, m.sendingIPs(ctx))
+
+	var scores []*Score
+	for _, domain := range domains {
+		counts, err := m.storage.Sum(ctx, domain, now.Add(-m.cfg.Window))
+		if err != nil {
+			return nil, fmt.Errorf("failed to sum signals of %s: %w", domain, err)
+		}
+
+		checks := Checks{
+			DMARC:    m.checker.DMARC(ctx, domain),
+			Listings: listings,
+		}
+		if m.domains != nil {
+			checks.DKIMAligned = m.domains.GetSigner(domain) != nil
+		}
+
+		score := Compute(domain, counts, checks)
+		previous, err := m.storage.ScoreAt(ctx, domain, now.Add(-trendPeriod))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get score history of %s: %w", domain, err)
+		}
+		score.SetTrend(previous)
+		score.UpdatedAt = now
+
+		if err := m.storage.Save(ctx, score); err != nil {
+			return nil, fmt.Errorf("failed to save score of %s: %w", domain, err)
+		}
+		metrics.SetDomainReputation(domain, score.Score)
+		scores = append(scores, score)
+	}
+
+	if m.cfg.Retention > 0 {
+		if _, err := m.storage.Prune(ctx, now.Add(-m.cfg.Retention)); err != nil {
+			return scores, fmt.Errorf("failed to prune reputation history: %w", err)
+		}
+	}
+
+	return scores, nil
+}
+
+// sendingIPs returns the configured sending IPs or the IPv4 addresses of the hostname
+func (m *Monitor) sendingIPs(ctx context.Context) []string {
+	if len(m.cfg.IPs) > 0 || m.cfg.Hostname == "" {
+		return m.cfg.IPs
+	}
+
+	addrs, err := net.DefaultResolver.LookupIP(ctx, "ip4", m.cfg.Hostname)
+	if err != nil {
+		m.logger.Warn("failed to resolve sending IPs", "hostname", m.cfg.Hostname, "error", err)
+		return nil
+	}
+
+	ips := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		ips = append(ips, addr.String())
+	}
+	return ips
+}
+
+// unique sorts domains and removes duplicates and empty names
+func unique(domains []string) []string {
+	seen := make(map[string]bool, len(domains))
+	result := make([]string, 0, len(domains))
+	for _, d := range domains {
+		d = normalizeDomain(d)
+		if d == "" || seen[d] {
+			continue
+		}
+		seen[d] = true
+		result = append(result, d)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// dnsChecker looks up signals with dnscheck
+type dnsChecker struct{}
+
+func (dnsChecker) DMARC(ctx context.Context, domain string) string {
+	return dnscheck.CheckDMARC(ctx, domain).Status
+}
+
+func (dnsChecker) Listings(ctx context.Context, ips []string) []string {
+	var listings []string
+	for _, ip := range ips {
+		result, err := dnscheck.CheckIP(ctx, ip)
+		if err != nil {
+			continue
+		}
+		for _, r := range result.Results {
+			if r.Listed {
+				listings = append(listings, ip+": "+r.DNSBL.Name)
+			}
+		}
+	}
+	return listings
+}
@@ -0,0 +1,139 @@
+package reputation
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// staticChecker returns fixed DNS signals
+type staticChecker struct {
+	dmarc    string
+	listings []string
+}
+
+func (c staticChecker) DMARC(ctx context.Context, domain string) string { return c.dmarc }
+
+func (c staticChecker) Listings(ctx context.Context, ips []string) []string { return c.listings }
+
+func newTestStorage(t *testing.T) *Storage {
+	t.Helper()
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	storage, err := NewStorage(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return storage
+}
+
+func newTestMonitor(t *testing.T, storage *Storage) *Monitor {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	m := NewMonitor(storage, nil, Config{
+		Interval:  time.Hour,
+		Window:    24 * time.Hour,
+		Retention: 7 * 24 * time.Hour,
+	}, logger)
+	m.checker = staticChecker{dmarc: "ok"}
+	return m
+}
+
+func TestMonitorUpdate(t *testing.T) {
+	ctx := context.Background()
+	storage := newTestStorage(t)
+	m := newTestMonitor(t, storage)
+
+	rcpts := make([]string, 30)
+	for i := range rcpts {
+		rcpts[i] = "user@gmail.com"
+	}
+	m.Delivered("news@example.com", rcpts)
+	m.Failed("news@example.com", rcpts[:10])
+	m.Deferred("news@example.com", rcpts[:5])
+	m.Delivered("<>", rcpts) // Bounces are not scored
+
+	// A day ago the domain scored better
+	dayAgo := time.Now().Add(-25 * time.Hour)
+	if err := storage.Save(ctx, &Score{Domain: "example.com", Score: 90, UpdatedAt: dayAgo}); err != nil {
+		t.Fatal(err)
+	}
+
+	scores, err := m.Update(ctx)
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if len(scores) != 1 {
+		t.Fatalf("scores = %d, want 1", len(scores))
+	}
+
+	s := scores[0]
+	if s.Domain != "example.com" || s.Counts.Delivered != 30 || s.Counts.Failed != 10 || s.Counts.Deferred != 5 {
+		t.Errorf("score counts = %+v", s.Counts)
+	}
+	if s.Counts.Providers["google"] == nil || s.Counts.Providers["google"].Delivered != 30 {
+		t.Errorf("providers = %+v", s.Counts.Providers)
+	}
+	if s.Trend != TrendDown || s.Change != s.Score-90 {
+		t.Errorf("trend = %s (%d), want down from 90 to %d", s.Trend, s.Change, s.Score)
+	}
+
+	stored, err := storage.Get(ctx, "example.com")
+	if err != nil || stored == nil || stored.Score != s.Score {
+		t.Errorf("Get() = %+v, %v", stored, err)
+	}
+	history, err := storage.History(ctx, "example.com", dayAgo.Add(-time.Hour))
+	if err != nil || len(history) != 2 {
+		t.Errorf("History() = %+v, %v, want 2 points", history, err)
+	}
+}
+
+func TestStorageFeedbackAndPrune(t *testing.T) {
+	ctx := context.Background()
+	storage := newTestStorage(t)
+
+	now := time.Now()
+	old := now.Add(-48 * time.Hour)
+	if err := storage.Add(ctx, "Example.com", now, &Counts{Complaints: 2, Unsubscribes: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.Add(ctx, "example.com", old, &Counts{Delivered: 100}); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.Add(ctx, "stale.com", old, &Counts{Delivered: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.Save(ctx, &Score{Domain: "stale.com", UpdatedAt: old}); err != nil {
+		t.Fatal(err)
+	}
+
+	counts, err := storage.Sum(ctx, "example.com", now.Add(-time.Hour))
+	if err != nil || counts.Complaints != 2 || counts.Unsubscribes != 3 || counts.Delivered != 0 {
+		t.Errorf("Sum() = %+v, %v", counts, err)
+	}
+
+	domains, err := storage.Domains(ctx, now.Add(-72*time.Hour))
+	if err != nil || len(domains) != 2 {
+		t.Errorf("Domains() = %v, %v", domains, err)
+	}
+
+	deleted, err := storage.Prune(ctx, now.Add(-24*time.Hour))
+	if err != nil || deleted != 3 {
+		t.Errorf("Prune() = %d, %v, want 3", deleted, err)
+	}
+	if s, _ := storage.Get(ctx, "stale.com"); s != nil {
+		t.Errorf("score of pruned domain kept: %+v", s)
+	}
+	if domains, _ := storage.Domains(ctx, now.Add(-72*time.Hour)); len(domains) != 1 || domains[0] != "example.com" {
+		t.Errorf("Domains() after prune = %v", domains)
+	}
+}
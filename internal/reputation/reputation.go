@@ -0,0 +1,274 @@
+package reputation
+
+import (
+	"strings"
+	"time"
+)
+
+// Grade summarizes a score
+type Grade string
+
+// Grades
+const (
+	GradeGood Grade = "good" // 80 and above
+	GradeFair Grade = "fair" // 60 to 79
+	GradePoor Grade = "poor" // below 60
+)
+
+// Trend is the direction of a score over the last day
+type Trend string
+
+// Trends
+const (
+	TrendUp     Trend = "up"
+	TrendDown   Trend = "down"
+	TrendSteady Trend = "steady"
+)
+
+// Penalty names
+const (
+	PenaltyBounces      = "bounces"
+	PenaltyComplaints   = "complaints"
+	PenaltyUnsubscribes = "unsubscribes"
+	PenaltyDeferrals    = "deferrals"
+	PenaltyDNSBL        = "dnsbl"
+	PenaltyDMARC        = "dmarc"
+	PenaltyDKIM         = "dkim"
+)
+
+const (
+	// minVolume is the number of recipients below which rates are too noisy to score
+	minVolume = 20
+
+	// trendThreshold is the score change over a day that counts as a trend
+	trendThreshold = 5
+
+	// trendPeriod is how far back a score is compared to find the trend
+	trendPeriod = 24 * time.Hour
+)
+
+// ProviderCounts are delivery outcomes of the recipients at one mailbox provider
+type ProviderCounts struct {
+	Delivered int `json:"delivered"`
+	Deferred  int `json:"deferred"`
+	Failed    int `json:"failed"`
+}
+
+// Counts are the delivery outcomes and feedback of a sender domain.
+// Delivery outcomes are counted per recipient.
+type Counts struct {
+	Delivered    int                        `json:"delivered"`
+	Deferred     int                        `json:"deferred"`
+	Failed       int                        `json:"failed"`
+	Complaints   int                        `json:"complaints"`
+	Unsubscribes int                        `json:"unsubscribes"`
+	Providers    map[string]*ProviderCounts `json:"providers,omitempty"`
+}
+
+// Add adds other to c
+func (c *Counts) Add(other *Counts) {
+	c.Delivered += other.Delivered
+	c.Deferred += other.Deferred
+	c.Failed += other.Failed
+	c.Complaints += other.Complaints
+	c.Unsubscribes += other.Unsubscribes
+
+	for name, p := range other.Providers {
+		c.provider(name).Delivered += p.Delivered
+		c.provider(name).Deferred += p.Deferred
+		c.provider(name).Failed += p.Failed
+	}
+}
+
+// provider returns the counts of a provider, creating them if needed
+func (c *Counts) provider(name string) *ProviderCounts {
+	if c.Providers == nil {
+		c.Providers = make(map[string]*ProviderCounts)
+	}
+	p, ok := c.Providers[name]
+	if !ok {
+		p = &ProviderCounts{}
+		c.Providers[name] = p
+	}
+	return p
+}
+
+// Rates are the signal rates derived from counts
+type Rates struct {
+	Bounce        float64 `json:"bounce"`                   // Failed / (delivered + failed)
+	Complaint     float64 `json:"complaint"`                // Complaints / delivered
+	Unsubscribe   float64 `json:"unsubscribe"`              // Unsubscribes / delivered
+	Deferral      float64 `json:"deferral"`                 // Deferral rate of the worst provider
+	WorstProvider string  `json:"worst_provider,omitempty"` // Provider with the highest deferral rate
+}
+
+// Checks are the DNS-based signals of a sender domain
+type Checks struct {
+	DMARC       string   `json:"dmarc"`              // DMARC record status: ok, warning, not_found or error
+	DKIMAligned bool     `json:"dkim_aligned"`       // Mail is DKIM-signed with the sender domain
+	Listings    []string `json:"listings,omitempty"` // Sending IPs listed on DNSBLs, e.g. "192.0.2.1: Spamhaus ZEN"
+}
+
+// Score is the reputation of a sender domain
+type Score struct {
+	Domain    string         `json:"domain"`
+	Score     int            `json:"score"` // 0-100
+	Grade     Grade          `json:"grade"`
+	Trend     Trend          `json:"trend"`
+	Change    int            `json:"change"` // Score change over the last 24h
+	Counts    Counts         `json:"counts"`
+	Rates     Rates          `json:"rates"`
+	Checks    Checks         `json:"checks"`
+	Penalties map[string]int `json:"penalties,omitempty"` // Points lost per signal
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// Point is a score in the history of a domain
+type Point struct {
+	Time  time.Time `json:"time"`
+	Score int       `json:"score"`
+}
+
+// Compute scores a domain from its counts over the score window and its checks.
+// A domain starts at 100 and loses points per signal. Rates are only scored
+// once enough recipients were seen.
+func Compute(domain string, counts Counts, checks Checks) *Score {
+	s := &Score{
+		Domain:    domain,
+		Counts:    counts,
+		Checks:    checks,
+		Penalties: make(map[string]int),
+	}
+
+	if attempts := counts.Delivered + counts.Failed; attempts > 0 {
+		s.Rates.Bounce = float64(counts.Failed) / float64(attempts)
+	}
+	if counts.Delivered > 0 {
+		s.Rates.Complaint = float64(counts.Complaints) / float64(counts.Delivered)
+		s.Rates.Unsubscribe = float64(counts.Unsubscribes) / float64(counts.Delivered)
+	}
+	for name, p := range counts.Providers {
+		total := p.Delivered + p.Deferred + p.Failed
+		if total < minVolume {
+			continue
+		}
+		rate := float64(p.Deferred) / float64(total)
+		if rate > s.Rates.Deferral || (rate == s.Rates.Deferral && rate > 0 && name < s.Rates.WorstProvider) {
+			s.Rates.Deferral = rate
+			s.Rates.WorstProvider = name
+		}
+	}
+
+	if counts.Delivered+counts.Failed >= minVolume {
+		s.penalize(PenaltyBounces, scale(s.Rates.Bounce, 0.02, 0.10, 35))
+	}
+	if counts.Delivered >= minVolume {
+		// Mailbox providers start filtering at a 0.1% complaint rate and block at 0.3%
+		s.penalize(PenaltyComplaints, scale(s.Rates.Complaint, 0.001, 0.003, 30))
+		s.penalize(PenaltyUnsubscribes, scale(s.Rates.Unsubscribe, 0.005, 0.02, 10))
+	}
+	s.penalize(PenaltyDeferrals, scale(s.Rates.Deferral, 0.05, 0.30, 15))
+
+	if len(checks.Listings) > 0 {
+		s.penalize(PenaltyDNSBL, 20)
+	}
+	switch checks.DMARC {
+	case "not_found":
+		s.penalize(PenaltyDMARC, 10)
+	case "warning":
+		s.penalize(PenaltyDMARC, 5)
+	}
+	if !checks.DKIMAligned {
+		s.penalize(PenaltyDKIM, 10)
+	}
+
+	s.Score = 100
+	for _, points := range s.Penalties {
+		s.Score -= points
+	}
+	s.Score = max(s.Score, 0)
+
+	switch {
+	case s.Score >= 80:
+		s.Grade = GradeGood
+	case s.Score >= 60:
+		s.Grade = GradeFair
+	default:
+		s.Grade = GradePoor
+	}
+	s.Trend = TrendSteady
+
+	return s
+}
+
+// SetTrend sets the trend from the score of a day ago, if known
+func (s *Score) SetTrend(previous *Point) {
+	s.Trend = TrendSteady
+	s.Change = 0
+	if previous == nil {
+		return
+	}
+
+	s.Change = s.Score - previous.Score
+	switch {
+	case s.Change >= trendThreshold:
+		s.Trend = TrendUp
+	case s.Change <= -trendThreshold:
+		s.Trend = TrendDown
+	}
+}
+
+func (s *Score) penalize(name string, points int) {
+	if points > 0 {
+		s.Penalties[name] = points
+	}
+}
+
+// scale maps a rate to 0 points at low and up to maxPoints at high
+func scale(rate, low, high float64, maxPoints int) int {
+	if rate <= low {
+		return 0
+	}
+	if rate >= high {
+		return maxPoints
+	}
+	return int(float64(maxPoints)*(rate-low)/(high-low) + 0.5)
+}
+
+// providers groups recipient domains of the large mailbox providers
+var providers = map[string]string{
+	"gmail.com":      "google",
+	"googlemail.com": "google",
+	"outlook.com":    "microsoft",
+	"hotmail.com":    "microsoft",
+	"live.com":       "microsoft",
+	"msn.com":        "microsoft",
+	"yahoo.com":      "yahoo",
+	"ymail.com":      "yahoo",
+	"aol.com":        "yahoo",
+	"icloud.com":     "apple",
+	"me.com":         "apple",
+	"mac.com":        "apple",
+	"yandex.ru":      "yandex",
+	"yandex.com":     "yandex",
+	"ya.ru":          "yandex",
+	"mail.ru":        "mailru",
+	"bk.ru":          "mailru",
+	"inbox.ru":       "mailru",
+	"list.ru":        "mailru",
+}
+
+// Provider returns the mailbox provider of a recipient domain. Domains of
+// unknown providers are their own provider.
+func Provider(domain string) string {
+	domain = strings.ToLower(domain)
+	if p, ok := providers[domain]; ok {
+		return p
+	}
+	return domain
+}
+
+// normalizeDomain lowercases and trims a domain
+func normalizeDomain(domain string) string {
+	return strings.ToLower(strings.TrimSpace(domain))
+}
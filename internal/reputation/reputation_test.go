@@ -0,0 +1,136 @@
+package reputation
+
+import (
+	"testing"
+)
+
+func TestCompute(t *testing.T) {
+	clean := Checks{DMARC: "ok", DKIMAligned: true}
+
+	tests := []struct {
+		name      string
+		counts    Counts
+		checks    Checks
+		wantScore int
+		wantGrade Grade
+		penalties []string
+	}{
+		{
+			name:      "no traffic with clean checks",
+			checks:    clean,
+			wantScore: 100,
+			wantGrade: GradeGood,
+		},
+		{
+			name:      "low volume rates are not scored",
+			counts:    Counts{Delivered: 5, Failed: 5},
+			checks:    clean,
+			wantScore: 100,
+			wantGrade: GradeGood,
+		},
+		{
+			name:      "high bounce rate",
+			counts:    Counts{Delivered: 90, Failed: 10},
+			checks:    clean,
+			wantScore: 65,
+			wantGrade: GradeFair,
+			penalties: []string{PenaltyBounces},
+		},
+		{
+			name:      "complaints above blocking threshold",
+			counts:    Counts{Delivered: 1000, Complaints: 5},
+			checks:    clean,
+			wantScore: 70,
+			wantGrade: GradeFair,
+			penalties: []string{PenaltyComplaints},
+		},
+		{
+			name: "deferrals at one provider",
+			counts: Counts{Delivered: 100, Deferred: 50, Providers: map[string]*ProviderCounts{
+				"google":      {Delivered: 50, Deferred: 50},
+				"example.org": {Delivered: 50},
+			}},
+			checks:    clean,
+			wantScore: 85,
+			wantGrade: GradeGood,
+			penalties: []string{PenaltyDeferrals},
+		},
+		{
+			name:      "listed without DMARC and DKIM",
+			checks:    Checks{DMARC: "not_found", Listings: []string{"192.0.2.1: Spamhaus ZEN"}},
+			wantScore: 60,
+			wantGrade: GradeFair,
+			penalties: []string{PenaltyDNSBL, PenaltyDMARC, PenaltyDKIM},
+		},
+		{
+			name:      "score does not go below zero",
+			counts:    Counts{Delivered: 100, Failed: 100, Complaints: 10, Unsubscribes: 10},
+			checks:    Checks{DMARC: "not_found", Listings: []string{"192.0.2.1: Spamhaus ZEN"}},
+			wantScore: 0,
+			wantGrade: GradePoor,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Compute("example.com", tt.counts, tt.checks)
+			if s.Score != tt.wantScore || s.Grade != tt.wantGrade {
+				t.Errorf("score = %d (%s), want %d (%s), penalties %v", s.Score, s.Grade, tt.wantScore, tt.wantGrade, s.Penalties)
+			}
+			for _, p := range tt.penalties {
+				if s.Penalties[p] == 0 {
+					t.Errorf("missing penalty %s in %v", p, s.Penalties)
+				}
+			}
+			if tt.penalties != nil && len(s.Penalties) != len(tt.penalties) {
+				t.Errorf("penalties = %v, want %v", s.Penalties, tt.penalties)
+			}
+		})
+	}
+}
+
+func TestComputeWorstProvider(t *testing.T) {
+	s := Compute("example.com", Counts{Providers: map[string]*ProviderCounts{
+		"google":    {Delivered: 90, Deferred: 10},
+		"microsoft": {Delivered: 60, Deferred: 40},
+		"tiny.org":  {Deferred: 5},
+	}}, Checks{})
+
+	if s.Rates.WorstProvider != "microsoft" || s.Rates.Deferral != 0.4 {
+		t.Errorf("worst provider = %s (%v), want microsoft (0.4)", s.Rates.WorstProvider, s.Rates.Deferral)
+	}
+}
+
+func TestSetTrend(t *testing.T) {
+	tests := []struct {
+		previous *Point
+		want     Trend
+	}{
+		{nil, TrendSteady},
+		{&Point{Score: 90}, TrendDown},
+		{&Point{Score: 82}, TrendSteady},
+		{&Point{Score: 60}, TrendUp},
+	}
+
+	for _, tt := range tests {
+		s := &Score{Score: 80}
+		s.SetTrend(tt.previous)
+		if s.Trend != tt.want {
+			t.Errorf("SetTrend(%+v) = %s, want %s", tt.previous, s.Trend, tt.want)
+		}
+	}
+}
+
+func TestProvider(t *testing.T) {
+	tests := map[string]string{
+		"gmail.com":      "google",
+		"GoogleMail.com": "google",
+		"hotmail.com":    "microsoft",
+		"example.org":    "example.org",
+	}
+	for domain, want := range tests {
+		if got := Provider(domain); got != want {
+			t.Errorf("Provider(%q) = %q, want %q", domain, got, want)
+		}
+	}
+}
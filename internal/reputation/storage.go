@@ -0,0 +1,288 @@
+package reputation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	bucketSignals = []byte("reputation_signals")
+	bucketScores  = []byte("reputation_scores")
+	bucketHistory = []byte("reputation_history")
+)
+
+// hourLayout is the hour slot of signal and history keys
+const hourLayout = "2006-01-02T15"
+
+// Storage provides reputation signal and score storage
+type Storage struct {
+	db *bolt.DB
+}
+
+// NewStorage creates a new reputation storage using the provided BoltDB instance
+func NewStorage(db *bolt.DB) (*Storage, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{bucketSignals, bucketScores, bucketHistory} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create reputation buckets: %w", err)
+	}
+	return &Storage{db: db}, nil
+}
+
+// hourKey returns the key of a domain in an hour slot
+func hourKey(domain string, t time.Time) []byte {
+	return []byte(domain + "\x00" + t.UTC().Format(hourLayout))
+}
+
+// domainPrefix returns the key prefix of a domain
+func domainPrefix(domain string) []byte {
+	return []byte(domain + "\x00")
+}
+
+// Add adds counts to the hour slot of a domain
+func (s *Storage) Add(ctx context.Context, domain string, at time.Time, counts *Counts) error {
+	domain = normalizeDomain(domain)
+	if domain == "" {
+		return fmt.Errorf("domain is required")
+	}
+	key := hourKey(domain, at)
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketSignals)
+
+		var total Counts
+		if data := b.Get(key); data != nil {
+			if err := json.Unmarshal(data, &total); err != nil {
+				return fmt.Errorf("failed to unmarshal signals: %w", err)
+			}
+		}
+		total.Add(counts)
+
+		data, err := json.Marshal(&total)
+		if err != nil {
+			return fmt.Errorf("failed to marshal signals: %w", err)
+		}
+		return b.Put(key, data)
+	})
+}
+
+// Sum returns the counts of a domain since the given time
+func (s *Storage) Sum(ctx context.Context, domain string, since time.Time) (Counts, error) {
+	var total Counts
+	prefix := domainPrefix(normalizeDomain(domain))
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucketSignals).Cursor()
+		for k, v := c.Seek(hourKey(normalizeDomain(domain), since)); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var counts Counts
+			if err := json.Unmarshal(v, &counts); err != nil {
+				continue // Skip invalid entries
+			}
+			total.Add(&counts)
+		}
+		return nil
+	})
+
+	return total, err
+}
+
+// Domains returns the domains with signals since the given time
+func (s *Storage) Domains(ctx context.Context, since time.Time) ([]string, error) {
+	var domains []string
+	from := since.UTC().Format(hourLayout)
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketSignals).ForEach(func(k, v []byte) error {
+			domain, hour, ok := bytes.Cut(k, []byte{0})
+			if !ok || string(hour) < from {
+				return nil
+			}
+			if n := len(domains); n == 0 || domains[n-1] != string(domain) {
+				domains = append(domains, string(domain))
+			}
+			return nil
+		})
+	})
+
+	return domains, err
+}
+
+// Save stores the current score of a domain and adds it to the domain history
+func (s *Storage) Save(ctx context.Context, score *Score) error {
+	data, err := json.Marshal(score)
+	if err != nil {
+		return fmt.Errorf("failed to marshal score: %w", err)
+	}
+	point, err := json.Marshal(&Point{Time: score.UpdatedAt, Score: score.Score})
+	if err != nil {
+		return fmt.Errorf("failed to marshal score point: %w", err)
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(bucketScores).Put([]byte(score.Domain), data); err != nil {
+			return err
+		}
+		return tx.Bucket(bucketHistory).Put(hourKey(score.Domain, score.UpdatedAt), point)
+	})
+}
+
+// Get returns the current score of a domain, nil if it was never scored
+func (s *Storage) Get(ctx context.Context, domain string) (*Score, error) {
+	var score *Score
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bucketScores).Get([]byte(normalizeDomain(domain)))
+		if data == nil {
+			return nil
+		}
+		score = &Score{}
+		return json.Unmarshal(data, score)
+	})
+
+	return score, err
+}
+
+// List returns the current scores of all domains ordered by domain
+func (s *Storage) List(ctx context.Context) ([]*Score, error) {
+	var scores []*Score
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketScores).ForEach(func(k, v []byte) error {
+			var score Score
+			if err := json.Unmarshal(v, &score); err != nil {
+				return nil // Skip invalid entries
+			}
+			scores = append(scores, &score)
+			return nil
+		})
+	})
+
+	return scores, err
+}
+
+// History returns the scores of a domain since the given time, oldest first
+func (s *Storage) History(ctx context.Context, domain string, since time.Time) ([]Point, error) {
+	var points []Point
+	domain = normalizeDomain(domain)
+	prefix := domainPrefix(domain)
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucketHistory).Cursor()
+		for k, v := c.Seek(hourKey(domain, since)); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var p Point
+			if err := json.Unmarshal(v, &p); err != nil {
+				continue // Skip invalid entries
+			}
+			points = append(points, p)
+		}
+		return nil
+	})
+
+	return points, err
+}
+
+// ScoreAt returns the latest history point of a domain at or before the given
+// time, or the oldest point if all are newer. Returns nil without history.
+func (s *Storage) ScoreAt(ctx context.Context, domain string, at time.Time) (*Point, error) {
+	var point *Point
+	domain = normalizeDomain(domain)
+	prefix := domainPrefix(domain)
+	target := hourKey(domain, at)
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucketHistory).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			if point != nil && bytes.Compare(k, target) > 0 {
+				break
+			}
+			var p Point
+			if err := json.Unmarshal(v, &p); err != nil {
+				continue // Skip invalid entries
+			}
+			point = &p
+		}
+		return nil
+	})
+
+	return point, err
+}
+
+// Prune deletes signals and history older than the given time, and the
+// scores of domains without history left
+func (s *Storage) Prune(ctx context.Context, before time.Time) (int, error) {
+	cutoff := []byte(before.UTC().Format(hourLayout))
+	deleted := 0
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		n, err := pruneHours(tx.Bucket(bucketSignals), cutoff, nil)
+		if err != nil {
+			return err
+		}
+		deleted += n
+
+		active := make(map[string]bool)
+		n, err = pruneHours(tx.Bucket(bucketHistory), cutoff, active)
+		if err != nil {
+			return err
+		}
+		deleted += n
+
+		scores := tx.Bucket(bucketScores)
+		var orphaned [][]byte
+		err = scores.ForEach(func(k, v []byte) error {
+			if !active[string(k)] {
+				orphaned = append(orphaned, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range orphaned {
+			if err := scores.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	return deleted, err
+}
+
+// pruneHours deletes the hour slots of a bucket before cutoff. Domains with
+// slots left are added to active if it is not nil.
+func pruneHours(b *bolt.Bucket, cutoff []byte, active map[string]bool) (int, error) {
+	var stale [][]byte
+	err := b.ForEach(func(k, v []byte) error {
+		domain, hour, ok := bytes.Cut(k, []byte{0})
+		if ok && bytes.Compare(hour, cutoff) >= 0 {
+			if active != nil {
+				active[string(domain)] = true
+			}
+			return nil
+		}
+		stale = append(stale, append([]byte(nil), k...))
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, k := range stale {
+		if err := b.Delete(k); err != nil {
+			return 0, err
+		}
+	}
+	return len(stale), nil
+}
@@ -0,0 +1,48 @@
+package retry
+
+import (
+	"regexp"
+	"strings"
+)
+
+// temporaryReplyPattern matches a 4xx SMTP reply with an optional enhanced
+// status code, e.g. "451 4.7.1"
+var temporaryReplyPattern = regexp.MustCompile(`\b(4\d{2})(?:[ -](4\.\d{1,3}\.\d{1,3}))?\b`)
+
+// greylistPhrases name greylisting in a reply
+var greylistPhrases = []string{"greylist", "graylist", "grey-list", "gray-list", "grey list", "gray list"}
+
+// laterPhrases ask to come back later, which 451 4.7.x replies without a
+// greylisting name use for greylisting
+var laterPhrases = []string{"come back later", "try again later", "retry later", "please retry", "try later"}
+
+// rateLimitPhrases mark replies of providers asking to send slower
+var rateLimitPhrases = []string{"too many", "rate limit", "rate-limit", "ratelimit", "throttl", "quota", "exceeded", "too fast"}
+
+// IsGreylisting reports whether a delivery error is a greylisting reply:
+// a 4xx reply naming greylisting, such as "450 4.2.0 Greylisted" or
+// "451 4.7.1 Greylisting in action", or a 451 4.7.x reply asking to come
+// back later that does not ask to send slower
+func IsGreylisting(text string) bool {
+	m := temporaryReplyPattern.FindStringSubmatch(text)
+	if m == nil {
+		return false
+	}
+
+	lower := strings.ToLower(text)
+	if containsAny(lower, greylistPhrases) {
+		return true
+	}
+	return m[1] == "451" && strings.HasPrefix(m[2], "4.7.") &&
+		containsAny(lower, laterPhrases) && !containsAny(lower, rateLimitPhrases)
+}
+
+// containsAny reports whether s contains one of phrases
+func containsAny(s string, phrases []string) bool {
+	for _, phrase := range phrases {
+		if strings.Contains(s, phrase) {
+			return true
+		}
+	}
+	return false
+}
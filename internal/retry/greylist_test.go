@@ -0,0 +1,62 @@
+package retry
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIsGreylisting(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"451 4.7.1 Greylisted, please try again in 300 seconds", true},
+		{"450 4.2.0 <user@example.com>: Recipient address rejected: Greylisted", true},
+		{"451 4.7.1 Service unavailable - try again later", true},
+		{"421 4.7.0 Temporary graylisting in effect", true},
+		{"451 4.7.1 Too many messages, slow down and try again later", false},
+		{"451 4.7.1 Separate delivery refused, please try again later", true},
+		{"451 4.7.1 Rate limit reached, try again later", false},
+		{"451 4.3.0 Mail server temporarily rejected message", false},
+		{"550 5.7.1 Greylisting is not a reason for this", false},
+		{"dial tcp: connection refused", false},
+	}
+	for _, tt := range tests {
+		if got := IsGreylisting(tt.text); got != tt.want {
+			t.Errorf("IsGreylisting(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestSchedulerGreylistRetry(t *testing.T) {
+	domains := map[string]*Config{
+		"gmail.com": {Classes: map[string]Policy{ClassGreylisting: {Interval: 20 * time.Minute}}},
+	}
+	s := NewScheduler(Policy{Interval: 30 * time.Minute, MaxRetries: 5}, nil, func(d string) *Config { return domains[d] })
+	s.random = func() float64 { return 0.5 }
+
+	// Only the first retry of greylisted deliveries is sooner
+	if d, _ := s.Next("example.com", ClassGreylisting, 1); d != DefaultGreylistRetry {
+		t.Errorf("Next(greylisting, 1) = %v, want %v", d, DefaultGreylistRetry)
+	}
+	if d, _ := s.Next("example.com", ClassGreylisting, 2); d != time.Hour {
+		t.Errorf("Next(greylisting, 2) = %v, want 1h", d)
+	}
+	if d, _ := s.Next("example.com", ClassTemporary, 1); d != 30*time.Minute {
+		t.Errorf("Next(temporary, 1) = %v, want 30m", d)
+	}
+	// A greylisting class policy wins
+	if d, _ := s.Next("gmail.com", ClassGreylisting, 1); d != 20*time.Minute {
+		t.Errorf("Next(gmail.com greylisting, 1) = %v, want 20m", d)
+	}
+
+	s.SetGreylistRetry(15 * time.Minute)
+	if d, _ := s.Next("example.com", ClassGreylisting, 1); d != 15*time.Minute {
+		t.Errorf("Next(greylisting, 1) = %v, want 15m", d)
+	}
+	// A regular first retry that is already sooner is kept
+	s.SetConfig(Policy{Interval: 2 * time.Minute, MaxRetries: 5}, nil)
+	if d, _ := s.Next("example.com", ClassGreylisting, 1); d != 2*time.Minute {
+		t.Errorf("Next(greylisting, 1) = %v, want 2m", d)
+	}
+}
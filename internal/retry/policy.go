@@ -0,0 +1,263 @@
+// Package retry schedules the retries of deferred deliveries: exponential
+// backoff with jitter or custom step lists, overridable per recipient
+// domain and per class of the delivery error.
+package retry
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Strategies of a retry policy
+const (
+	StrategyExponential = "exponential"
+	StrategySteps       = "steps"
+)
+
+// Error classes of deferred deliveries
+const (
+	ClassGreylisting = "greylisting" // 4xx reply with a greylisting hint
+	ClassThrottle    = "throttle"    // 4xx reply asking to send slower
+	ClassConnection  = "connection"  // Connection, timeout or DNS failure without a reply
+	ClassTemporary   = "temporary"   // Other 4xx replies
+)
+
+// Classes lists the error classes a policy can be set for
+var Classes = []string{ClassGreylisting, ClassThrottle, ClassConnection, ClassTemporary}
+
+// Defaults of exponential policies
+const (
+	DefaultMultiplier  = 2
+	DefaultMaxInterval = time.Hour
+)
+
+// Policy is a retry schedule. Zero fields are taken from the default
+// policy (queue.retry_interval and queue.max_retries).
+type Policy struct {
+	Strategy    string          `yaml:"strategy,omitempty"`     // exponential (default) or steps
+	First       time.Duration   `yaml:"first,omitempty"`        // Delay of the first retry, replaces interval or the first step
+	Interval    time.Duration   `yaml:"interval,omitempty"`     // First delay of exponential
+	Multiplier  float64         `yaml:"multiplier,omitempty"`   // Growth of exponential delays (default 2)
+	MaxInterval time.Duration   `yaml:"max_interval,omitempty"` // Cap of exponential delays (default 1h)
+	Jitter      float64         `yaml:"jitter,omitempty"`       // Random spread of each delay, 0-1 (0.2 = ±20%)
+	Steps       []time.Duration `yaml:"steps,omitempty"`        // Delay of each retry for steps, the last one repeats
+	MaxRetries  int             `yaml:"max_retries,omitempty"`  // Attempts before a recipient fails
+}
+
+// Config is a retry policy with overrides per error class
+type Config struct {
+	Policy  *Policy           `yaml:"policy,omitempty" json:"policy,omitempty"`
+	Classes map[string]Policy `yaml:"classes,omitempty" json:"classes,omitempty"`
+}
+
+// Validate checks the policies of a config
+func (c *Config) Validate() error {
+	if c == nil {
+		return nil
+	}
+	if c.Policy != nil {
+		if err := c.Policy.Validate(); err != nil {
+			return fmt.Errorf("policy: %w", err)
+		}
+	}
+	for class, p := range c.Classes {
+		if !validClass(class) {
+			return fmt.Errorf("classes: unknown error class %q (must be one of %v)", class, Classes)
+		}
+		if err := p.Validate(); err != nil {
+			return fmt.Errorf("classes.%s: %w", class, err)
+		}
+	}
+	return nil
+}
+
+// Validate checks a policy
+func (p *Policy) Validate() error {
+	switch p.Strategy {
+	case "", StrategyExponential:
+		if len(p.Steps) > 0 {
+			return fmt.Errorf("steps require strategy steps")
+		}
+	case StrategySteps:
+		if len(p.Steps) == 0 {
+			return fmt.Errorf("strategy steps requires steps")
+		}
+		for _, step := range p.Steps {
+			if step <= 0 {
+				return fmt.Errorf("steps must be positive")
+			}
+		}
+	default:
+		return fmt.Errorf("unknown strategy %q (must be exponential or steps)", p.Strategy)
+	}
+	if p.First < 0 || p.Interval < 0 || p.MaxInterval < 0 {
+		return fmt.Errorf("first, interval and max_interval must not be negative")
+	}
+	if p.Multiplier != 0 && p.Multiplier < 1 {
+		return fmt.Errorf("multiplier must be at least 1")
+	}
+	if p.Jitter < 0 || p.Jitter > 1 {
+		return fmt.Errorf("jitter must be between 0 and 1")
+	}
+	if p.MaxRetries < 0 {
+		return fmt.Errorf("max_retries must not be negative")
+	}
+	return nil
+}
+
+// withDefaults returns the policy with zero fields taken from def
+func (p Policy) withDefaults(def Policy) Policy {
+	if p.Strategy == "" {
+		p.Strategy = StrategyExponential
+	}
+	if p.Interval == 0 {
+		p.Interval = def.Interval
+	}
+	if p.Multiplier == 0 {
+		p.Multiplier = def.Multiplier
+	}
+	if p.Multiplier == 0 {
+		p.Multiplier = DefaultMultiplier
+	}
+	if p.MaxInterval == 0 {
+		p.MaxInterval = def.MaxInterval
+	}
+	if p.MaxInterval == 0 {
+		p.MaxInterval = DefaultMaxInterval
+	}
+	if p.MaxRetries == 0 {
+		p.MaxRetries = def.MaxRetries
+	}
+	return p
+}
+
+// Delay returns the delay before the retry that follows attempt n
+// (1-based), without jitter
+func (p *Policy) Delay(n int) time.Duration {
+	if n < 1 {
+		n = 1
+	}
+	if n == 1 && p.First > 0 {
+		return p.First
+	}
+	if p.Strategy == StrategySteps && len(p.Steps) > 0 {
+		return p.Steps[min(n, len(p.Steps))-1]
+	}
+
+	delay := float64(p.Interval) * math.Pow(p.Multiplier, float64(n-1))
+	if p.MaxInterval > 0 && delay > float64(p.MaxInterval) {
+		return p.MaxInterval
+	}
+	return time.Duration(delay)
+}
+
+// jittered spreads a delay by the jitter of the policy, r is a random
+// number in [0, 1)
+func (p *Policy) jittered(delay time.Duration, r float64) time.Duration {
+	if p.Jitter <= 0 {
+		return delay
+	}
+	return time.Duration(float64(delay) * (1 + p.Jitter*(2*r-1)))
+}
+
+// Schedule returns the delays of all retries of the policy, without jitter
+func (p *Policy) Schedule() []time.Duration {
+	schedule := make([]time.Duration, 0, max(p.MaxRetries-1, 0))
+	for n := 1; n < p.MaxRetries; n++ {
+		schedule = append(schedule, p.Delay(n))
+	}
+	return schedule
+}
+
+func validClass(class string) bool {
+	for _, c := range Classes {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// policyJSON is the API form of a policy, with durations as strings like
+// "5m"
+type policyJSON struct {
+	Strategy    string   `json:"strategy,omitempty"`
+	First       string   `json:"first,omitempty"`
+	Interval    string   `json:"interval,omitempty"`
+	Multiplier  float64  `json:"multiplier,omitempty"`
+	MaxInterval string   `json:"max_interval,omitempty"`
+	Jitter      float64  `json:"jitter,omitempty"`
+	Steps       []string `json:"steps,omitempty"`
+	MaxRetries  int      `json:"max_retries,omitempty"`
+}
+
+// MarshalJSON encodes durations as strings like "5m0s"
+func (p Policy) MarshalJSON() ([]byte, error) {
+	v := policyJSON{
+		Strategy:    p.Strategy,
+		First:       durationString(p.First),
+		Interval:    durationString(p.Interval),
+		Multiplier:  p.Multiplier,
+		MaxInterval: durationString(p.MaxInterval),
+		Jitter:      p.Jitter,
+		MaxRetries:  p.MaxRetries,
+	}
+	for _, step := range p.Steps {
+		v.Steps = append(v.Steps, step.String())
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON decodes durations from strings like "5m"
+func (p *Policy) UnmarshalJSON(data []byte) error {
+	var v policyJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	out := Policy{
+		Strategy:   v.Strategy,
+		Multiplier: v.Multiplier,
+		Jitter:     v.Jitter,
+		MaxRetries: v.MaxRetries,
+	}
+	var err error
+	if out.First, err = parseDuration("first", v.First); err != nil {
+		return err
+	}
+	if out.Interval, err = parseDuration("interval", v.Interval); err != nil {
+		return err
+	}
+	if out.MaxInterval, err = parseDuration("max_interval", v.MaxInterval); err != nil {
+		return err
+	}
+	for _, s := range v.Steps {
+		step, err := parseDuration("steps", s)
+		if err != nil {
+			return err
+		}
+		out.Steps = append(out.Steps, step)
+	}
+	*p = out
+	return nil
+}
+
+func durationString(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.String()
+}
+
+func parseDuration(field, s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", field, err)
+	}
+	return d, nil
+}
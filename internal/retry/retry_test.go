@@ -0,0 +1,133 @@
+package retry
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPolicyDelay(t *testing.T) {
+	exp := Policy{Interval: 5 * time.Minute}.withDefaults(Policy{MaxRetries: 5})
+	steps := Policy{Strategy: StrategySteps, Steps: []time.Duration{time.Minute, 10 * time.Minute}}
+
+	tests := []struct {
+		name   string
+		policy Policy
+		n      int
+		want   time.Duration
+	}{
+		{"exponential first", exp, 1, 5 * time.Minute},
+		{"exponential third", exp, 3, 20 * time.Minute},
+		{"exponential capped", exp, 10, time.Hour},
+		{"steps first", steps, 1, time.Minute},
+		{"steps last repeats", steps, 5, 10 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := tt.policy.Delay(tt.n); got != tt.want {
+			t.Errorf("%s: Delay(%d) = %v, want %v", tt.name, tt.n, got, tt.want)
+		}
+	}
+
+	if got := exp.Schedule(); !reflect.DeepEqual(got, []time.Duration{5 * time.Minute, 10 * time.Minute, 20 * time.Minute, 40 * time.Minute}) {
+		t.Errorf("Schedule() = %v", got)
+	}
+}
+
+func TestPolicyJitter(t *testing.T) {
+	p := Policy{Jitter: 0.2}
+	if got := p.jittered(10*time.Minute, 0); got != 8*time.Minute {
+		t.Errorf("jittered(r=0) = %v, want 8m", got)
+	}
+	if got := p.jittered(10*time.Minute, 0.5); got != 10*time.Minute {
+		t.Errorf("jittered(r=0.5) = %v, want 10m", got)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  *Config
+		wantErr bool
+	}{
+		{name: "not set"},
+		{name: "exponential", config: &Config{Policy: &Policy{Interval: time.Minute, Multiplier: 3, Jitter: 0.1, MaxRetries: 8}}},
+		{name: "class steps", config: &Config{Classes: map[string]Policy{ClassGreylisting: {Strategy: StrategySteps, Steps: []time.Duration{5 * time.Minute}}}}},
+		{name: "unknown strategy", config: &Config{Policy: &Policy{Strategy: "linear"}}, wantErr: true},
+		{name: "steps without strategy", config: &Config{Policy: &Policy{Steps: []time.Duration{time.Minute}}}, wantErr: true},
+		{name: "steps strategy without steps", config: &Config{Policy: &Policy{Strategy: StrategySteps}}, wantErr: true},
+		{name: "jitter above 1", config: &Config{Policy: &Policy{Jitter: 1.5}}, wantErr: true},
+		{name: "multiplier below 1", config: &Config{Policy: &Policy{Multiplier: 0.5}}, wantErr: true},
+		{name: "unknown class", config: &Config{Classes: map[string]Policy{"dns": {}}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		if err := tt.config.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestSchedulerPrecedence(t *testing.T) {
+	global := &Config{
+		Policy:  &Policy{Interval: 10 * time.Minute},
+		Classes: map[string]Policy{ClassGreylisting: {Strategy: StrategySteps, Steps: []time.Duration{5 * time.Minute}}},
+	}
+	domains := map[string]*Config{
+		"gmail.com":   {Classes: map[string]Policy{ClassConnection: {Interval: 2 * time.Minute, MaxRetries: 3}}},
+		"example.org": {Policy: &Policy{Interval: time.Minute}},
+	}
+	s := NewScheduler(Policy{Interval: 5 * time.Minute, MaxRetries: 5}, global, func(d string) *Config { return domains[d] })
+	s.random = func() float64 { return 0.5 }
+
+	tests := []struct {
+		domain, class string
+		want          time.Duration
+		wantRetries   int
+	}{
+		{"gmail.com", ClassConnection, 2 * time.Minute, 3},  // Domain class
+		{"gmail.com", ClassTemporary, 10 * time.Minute, 5},  // Global policy
+		{"GMAIL.com", ClassGreylisting, 5 * time.Minute, 5}, // Global class
+		{"example.org", ClassGreylisting, time.Minute, 5},   // Domain policy
+		{"other.net", ClassTemporary, 10 * time.Minute, 5},
+	}
+	for _, tt := range tests {
+		p := s.Policy(tt.domain, tt.class)
+		if got := p.Delay(1); got != tt.want || p.MaxRetries != tt.wantRetries {
+			t.Errorf("Policy(%s, %s) = delay %v, max retries %d; want %v, %d", tt.domain, tt.class, got, p.MaxRetries, tt.want, tt.wantRetries)
+		}
+	}
+
+	if d, ok := s.Next("gmail.com", ClassConnection, 2); !ok || d != 4*time.Minute {
+		t.Errorf("Next(attempt 2) = %v, %v; want 4m, true", d, ok)
+	}
+	if _, ok := s.Next("gmail.com", ClassConnection, 3); ok {
+		t.Error("Next(attempt 3) retried past max_retries")
+	}
+
+	// Without a global config the default policy applies
+	s.SetConfig(Policy{Interval: time.Minute, MaxRetries: 2}, nil)
+	if p := s.Policy("other.net", ClassTemporary); p.Delay(1) != time.Minute || p.MaxRetries != 2 {
+		t.Errorf("default policy = %+v", p)
+	}
+}
+
+func TestPolicyJSON(t *testing.T) {
+	data := []byte(`{"strategy":"steps","steps":["1m","5m"],"jitter":0.1,"max_retries":4}`)
+	var p Policy
+	if err := json.Unmarshal(data, &p); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	want := Policy{Strategy: StrategySteps, Steps: []time.Duration{time.Minute, 5 * time.Minute}, Jitter: 0.1, MaxRetries: 4}
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("Unmarshal() = %+v, want %+v", p, want)
+	}
+
+	out, err := json.Marshal(Policy{Interval: 90 * time.Second})
+	if err != nil || string(out) != `{"interval":"1m30s"}` {
+		t.Errorf("Marshal() = %s, %v", out, err)
+	}
+
+	if err := json.Unmarshal([]byte(`{"interval":"soon"}`), &p); err == nil {
+		t.Error("Unmarshal() accepted an invalid duration")
+	}
+}
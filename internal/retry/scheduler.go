@@ -0,0 +1,118 @@
+package retry
+
+import (
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultGreylistRetry is the delay of the first retry of a greylisted
+// delivery. Greylisting servers accept the retry of a message after a few
+// minutes, usually five.
+const DefaultGreylistRetry = 5 * time.Minute
+
+// DomainLookup returns the retry config of a recipient domain, nil if it
+// has none
+type DomainLookup func(domain string) *Config
+
+// Scheduler resolves the retry policy of deferred recipients. The most
+// specific policy wins: the error class policy of the recipient domain,
+// the policy of the recipient domain, the global error class policy, the
+// global policy. Zero fields of the resolved policy are taken from the
+// default policy. Greylisted deliveries without a greylisting class policy
+// are retried first after the greylist retry delay if that is sooner.
+type Scheduler struct {
+	mu            sync.RWMutex
+	def           Policy
+	global        *Config
+	greylistRetry time.Duration
+	domains       DomainLookup
+	random        func() float64
+}
+
+// NewScheduler creates a scheduler with the default policy def, the global
+// retry config and the lookup of per-domain configs
+func NewScheduler(def Policy, global *Config, domains DomainLookup) *Scheduler {
+	s := &Scheduler{domains: domains, random: rand.Float64, greylistRetry: DefaultGreylistRetry}
+	s.SetConfig(def, global)
+	return s
+}
+
+// SetConfig replaces the default policy and the global config, e.g. on a
+// config reload
+func (s *Scheduler) SetConfig(def Policy, global *Config) {
+	def = def.withDefaults(Policy{})
+	s.mu.Lock()
+	s.def = def
+	s.global = global
+	s.mu.Unlock()
+}
+
+// SetGreylistRetry sets the delay of the first retry of greylisted
+// deliveries, DefaultGreylistRetry if zero
+func (s *Scheduler) SetGreylistRetry(d time.Duration) {
+	if d <= 0 {
+		d = DefaultGreylistRetry
+	}
+	s.mu.Lock()
+	s.greylistRetry = d
+	s.mu.Unlock()
+}
+
+// Policy returns the policy of a recipient domain for an error class
+func (s *Scheduler) Policy(domain, class string) Policy {
+	s.mu.RLock()
+	def, global, greylistRetry := s.def, s.global, s.greylistRetry
+	s.mu.RUnlock()
+
+	var p Policy
+	var domainPolicy bool
+	if s.domains != nil && domain != "" {
+		if dc := s.domains(strings.ToLower(domain)); dc != nil {
+			if cp, ok := dc.Classes[class]; ok {
+				return cp.withDefaults(def)
+			}
+			if dc.Policy != nil {
+				// A domain policy also replaces global class policies
+				p = dc.Policy.withDefaults(def)
+				domainPolicy = true
+			}
+		}
+	}
+	if !domainPolicy {
+		switch cp, ok := global.class(class); {
+		case ok:
+			return cp.withDefaults(def)
+		case global != nil && global.Policy != nil:
+			p = global.Policy.withDefaults(def)
+		default:
+			p = def
+		}
+	}
+
+	if class == ClassGreylisting && p.First == 0 && greylistRetry < p.Delay(1) {
+		p.First = greylistRetry
+	}
+	return p
+}
+
+// class returns the policy of an error class of a config
+func (c *Config) class(class string) (Policy, bool) {
+	if c == nil {
+		return Policy{}, false
+	}
+	p, ok := c.Classes[class]
+	return p, ok
+}
+
+// Next returns the delay before the next attempt of a recipient of domain
+// deferred with an error of class after attempt n (1-based). It returns
+// false when the recipient used up its retries.
+func (s *Scheduler) Next(domain, class string, n int) (time.Duration, bool) {
+	p := s.Policy(domain, class)
+	if n >= p.MaxRetries {
+		return 0, false
+	}
+	return p.jittered(p.Delay(n), s.random()), true
+}
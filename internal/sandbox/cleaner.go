@@ -0,0 +1,105 @@
+package sandbox
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// CleanerConfig contains sandbox retention settings
+type CleanerConfig struct {
+	MaxAge   time.Duration
+	MaxCount int
+	Interval time.Duration
+
+	// Shorter max ages of the messages of sending domains
+	DomainMaxAge map[string]time.Duration
+}
+
+// Cleaner removes captured messages beyond the retention limits
+type Cleaner struct {
+	storage *Storage
+	cfg     CleanerConfig
+	logger  *slog.Logger
+	wg      sync.WaitGroup
+	done    chan struct{}
+}
+
+// NewCleaner creates a new sandbox cleaner
+func NewCleaner(storage *Storage, cfg CleanerConfig, logger *slog.Logger) *Cleaner {
+	return &Cleaner{
+		storage: storage,
+		cfg:     cfg,
+		logger:  logger,
+		done:    make(chan struct{}),
+	}
+}
+
+// Start starts the cleanup goroutine
+func (c *Cleaner) Start(ctx context.Context) {
+	if (c.cfg.MaxAge <= 0 && c.cfg.MaxCount <= 0 && len(c.cfg.DomainMaxAge) == 0) || c.cfg.Interval <= 0 {
+		return
+	}
+
+	c.wg.Add(1)
+	go c.loop(ctx)
+
+	c.logger.Info("sandbox cleaner started",
+		"max_age", c.cfg.MaxAge,
+		"max_count", c.cfg.MaxCount,
+		"domains", len(c.cfg.DomainMaxAge),
+		"interval", c.cfg.Interval,
+	)
+}
+
+// Stop stops the cleaner and waits for the goroutine to finish
+func (c *Cleaner) Stop() {
+	close(c.done)
+	c.wg.Wait()
+}
+
+func (c *Cleaner) loop(ctx context.Context) {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(c.cfg.Interval)
+	defer ticker.Stop()
+
+	// Run cleanup immediately on start
+	c.run(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.done:
+			return
+		case <-ticker.C:
+			c.run(ctx)
+		}
+	}
+}
+
+func (c *Cleaner) run(ctx context.Context) {
+	deleted, err := c.storage.Cleanup(ctx, c.cfg.MaxAge, c.cfg.MaxCount)
+	if err != nil {
+		c.logger.Error("failed to cleanup sandbox", "error", err)
+		return
+	}
+
+	for domain, maxAge := range c.cfg.DomainMaxAge {
+		if maxAge <= 0 {
+			continue
+		}
+		n, err := c.storage.Clear(ctx, domain, maxAge)
+		if err != nil {
+			c.logger.Error("failed to cleanup sandbox", "domain", domain, "error", err)
+			return
+		}
+		deleted += n
+	}
+
+	if deleted > 0 {
+		c.logger.Info("cleaned up sandbox messages", "deleted", deleted)
+	}
+}
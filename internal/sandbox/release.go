@@ -0,0 +1,45 @@
+package sandbox
+
+import (
+	"errors"
+	"time"
+
+	"github.com/foxzi/sendry/internal/queue"
+)
+
+// ErrDelivered is returned when releasing a bcc copy, the message was
+// delivered to its recipients when it was captured
+var ErrDelivered = errors.New("message was delivered when captured")
+
+// Releasable reports whether the message can be released for delivery
+func (m *Message) Releasable() bool {
+	return m.Mode != "bcc"
+}
+
+// Release returns a queue message that delivers a captured message to its
+// original recipients. It is delivered even while the domain is still in
+// sandbox or redirect mode.
+func (m *Message) Release() (*queue.Message, error) {
+	if !m.Releasable() {
+		return nil, ErrDelivered
+	}
+
+	recipients := m.To
+	if len(m.OriginalTo) > 0 {
+		recipients = m.OriginalTo
+	}
+
+	now := time.Now()
+	return &queue.Message{
+		ID:         m.ID + "-release-" + now.Format("20060102150405"),
+		From:       m.From,
+		To:         recipients,
+		Data:       m.Data,
+		Status:     queue.StatusPending,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		ClientIP:   m.ClientIP,
+		DKIMSigned: m.DKIMSigned,
+		Released:   true,
+	}, nil
+}
@@ -0,0 +1,58 @@
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/foxzi/sendry/internal/queue"
+)
+
+func TestMessageRelease(t *testing.T) {
+	msg := &Message{
+		ID:         "captured",
+		From:       "app@example.com",
+		To:         []string{"qa@example.com"},
+		OriginalTo: []string{"user@example.org"},
+		Data:       []byte("Subject: Hi\r\n\r\nHi\r\n"),
+		Mode:       "redirect",
+		DKIMSigned: true,
+	}
+
+	released, err := msg.Release()
+	if err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	if !released.Released || !released.DKIMSigned || released.Status != queue.StatusPending {
+		t.Errorf("Release() = %+v, want a pending released message keeping its signature", released)
+	}
+	if len(released.To) != 1 || released.To[0] != "user@example.org" {
+		t.Errorf("released to %v, want the original recipient", released.To)
+	}
+
+	msg.Mode = "bcc"
+	if _, err := msg.Release(); !errors.Is(err, ErrDelivered) {
+		t.Errorf("Release() of bcc copy error = %v, want ErrDelivered", err)
+	}
+}
+
+func TestSenderReleasedMessage(t *testing.T) {
+	mock := &mockSender{}
+	sender := NewSender(mock, &mockDomainProvider{
+		modes: map[string]string{"sandbox.com": "sandbox"},
+	}, nil, nil)
+
+	msg := &queue.Message{
+		ID:       "released",
+		From:     "sender@sandbox.com",
+		To:       []string{"recipient@example.com"},
+		Data:     []byte("Subject: Test\r\n\r\ntest message"),
+		Released: true,
+	}
+	if err := sender.Send(context.Background(), msg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(mock.sentMessages) != 1 {
+		t.Errorf("released message sent %d times, want delivery despite sandbox mode", len(mock.sentMessages))
+	}
+}
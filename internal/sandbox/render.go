@@ -0,0 +1,49 @@
+package sandbox
+
+import (
+	"github.com/foxzi/sendry/internal/message"
+)
+
+// Rendered is a captured message prepared for inspection
+type Rendered struct {
+	Headers     message.Header // Header fields in order, encoded words decoded
+	Text        string         // text/plain body
+	HTML        string         // text/html body, sanitized
+	Attachments []Attachment
+}
+
+// Attachment describes an attachment of a captured message
+type Attachment struct {
+	Filename    string `json:"filename,omitempty"`
+	ContentType string `json:"content_type"`
+	Size        int    `json:"size"` // Decoded size in bytes
+}
+
+// Render parses a captured message into its headers, text and HTML bodies
+// and attachments. Data that is not a message is shown as text.
+func Render(data []byte) *Rendered {
+	m, err := message.Parse(data)
+	if err != nil {
+		return &Rendered{Text: string(data)}
+	}
+
+	r := &Rendered{
+		Text: m.TextBody(),
+		HTML: SanitizeHTML(m.HTMLBody()),
+	}
+	for _, f := range m.Header {
+		r.Headers = append(r.Headers, message.Field{Name: f.Name, Value: message.DecodeWords(f.Value)})
+	}
+	for _, p := range m.Attachments() {
+		size := len(p.Body)
+		if p.Message != nil {
+			size = len(p.Message.Bytes())
+		}
+		r.Attachments = append(r.Attachments, Attachment{
+			Filename:    p.Filename(),
+			ContentType: p.MediaType,
+			Size:        size,
+		})
+	}
+	return r
+}
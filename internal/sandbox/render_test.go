@@ -0,0 +1,132 @@
+package sandbox
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitizeHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "formatting kept",
+			in:   `<p class="lead" align="center"><b>Hello</b> <i>world</i></p>`,
+			want: `<p class="lead" align="center"><b>Hello</b> <i>world</i></p>`,
+		},
+		{
+			name: "script removed with content",
+			in:   `<p>Hi</p><script>alert(1)</script><p>there</p>`,
+			want: `<p>Hi</p><p>there</p>`,
+		},
+		{
+			name: "event handlers removed",
+			in:   `<div onclick="steal()" style="color: red">text</div>`,
+			want: `<div style="color: red">text</div>`,
+		},
+		{
+			name: "javascript link removed",
+			in:   `<a href="javascript:alert(1)">click</a>`,
+			want: `<a target="_blank" rel="noopener noreferrer">click</a>`,
+		},
+		{
+			name: "web link opens in new window",
+			in:   `<a href="https://example.com/?a=1&amp;b=2">site</a>`,
+			want: `<a href="https://example.com/?a=1&amp;b=2" target="_blank" rel="noopener noreferrer">site</a>`,
+		},
+		{
+			name: "remote image not loaded",
+			in:   `<img src="https://tracker.example.com/open.gif" alt="logo">`,
+			want: `<img alt="logo">`,
+		},
+		{
+			name: "embedded image kept",
+			in:   `<img src="data:image/png;base64,AAAA">`,
+			want: `<img src="data:image/png;base64,AAAA">`,
+		},
+		{
+			name: "forms and frames removed",
+			in:   `<form action="https://evil.example"><input name="pw"><button>Go</button></form><iframe src="x">y</iframe>`,
+			want: ``,
+		},
+		{
+			name: "remote style removed",
+			in:   `<style>body{background:url(https://tracker.example.com/x)}</style><td style="background-image: url(x)">a</td>`,
+			want: `<style></style><td>a</td>`,
+		},
+		{
+			name: "comments dropped",
+			in:   `<!DOCTYPE html><!-- hidden --><span>a</span>`,
+			want: `<span>a</span>`,
+		},
+		{
+			name: "text escaped",
+			in:   `1 &lt; 2`,
+			want: `1 &lt; 2`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeHTML(tt.in); got != tt.want {
+				t.Errorf("SanitizeHTML() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	data := strings.Join([]string{
+		"From: =?utf-8?q?J=C3=BCrgen?= <sender@example.com>",
+		"To: recipient@example.com",
+		"Subject: =?utf-8?q?Gr=C3=BC=C3=9Fe?=",
+		"MIME-Version: 1.0",
+		`Content-Type: multipart/mixed; boundary="outer"`,
+		"",
+		"--outer",
+		`Content-Type: multipart/alternative; boundary="inner"`,
+		"",
+		"--inner",
+		"Content-Type: text/plain; charset=utf-8",
+		"",
+		"Plain body",
+		"--inner",
+		"Content-Type: text/html; charset=utf-8",
+		"",
+		`<p onclick="x()">HTML body</p><script>bad()</script>`,
+		"--inner--",
+		"--outer",
+		"Content-Type: text/csv",
+		`Content-Disposition: attachment; filename="report.csv"`,
+		"",
+		"a,b",
+		"--outer--",
+		"",
+	}, "\r\n")
+
+	r := Render([]byte(data))
+
+	if len(r.Headers) != 5 {
+		t.Fatalf("got %d headers, want 5", len(r.Headers))
+	}
+	if r.Headers[0].Value != "Jürgen <sender@example.com>" || r.Headers[2].Value != "Grüße" {
+		t.Errorf("headers not decoded: %+v", r.Headers)
+	}
+	if strings.TrimSpace(r.Text) != "Plain body" {
+		t.Errorf("Text = %q", r.Text)
+	}
+	if r.HTML != "<p>HTML body</p>" {
+		t.Errorf("HTML = %q, want sanitized body", r.HTML)
+	}
+	if len(r.Attachments) != 1 || r.Attachments[0].Filename != "report.csv" || r.Attachments[0].ContentType != "text/csv" {
+		t.Errorf("Attachments = %+v", r.Attachments)
+	}
+}
+
+func TestRenderNotAMessage(t *testing.T) {
+	r := Render([]byte("not a message"))
+	if r.Text != "not a message" || len(r.Headers) != 0 {
+		t.Errorf("Render() = %+v, want the data as text", r)
+	}
+}
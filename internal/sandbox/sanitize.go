@@ -0,0 +1,160 @@
+package sandbox
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// droppedElements are removed together with their content
+var droppedElements = map[string]bool{
+	"script": true, "noscript": true, "template": true, "title": true,
+	"iframe": true, "frame": true, "frameset": true, "object": true, "embed": true, "applet": true,
+	"svg": true, "math": true, "textarea": true, "select": true, "button": true,
+}
+
+// allowedElements are kept, other elements are removed but their content is
+// kept
+var allowedElements = map[string]bool{
+	"a": true, "abbr": true, "address": true, "article": true, "b": true, "big": true,
+	"blockquote": true, "br": true, "caption": true, "center": true, "cite": true, "code": true,
+	"col": true, "colgroup": true, "dd": true, "del": true, "div": true, "dl": true, "dt": true,
+	"em": true, "figcaption": true, "figure": true, "font": true, "footer": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"header": true, "hr": true, "i": true, "img": true, "ins": true, "kbd": true, "label": true,
+	"li": true, "main": true, "mark": true, "nav": true, "ol": true, "p": true, "pre": true,
+	"q": true, "s": true, "section": true, "small": true, "span": true, "strike": true,
+	"strong": true, "style": true, "sub": true, "sup": true, "table": true, "tbody": true,
+	"td": true, "tfoot": true, "th": true, "thead": true, "tr": true, "tt": true, "u": true,
+	"ul": true, "wbr": true,
+}
+
+// allowedAttributes are kept on allowed elements, href and src are checked
+// separately
+var allowedAttributes = map[string]bool{
+	"align": true, "alt": true, "bgcolor": true, "border": true, "cellpadding": true,
+	"cellspacing": true, "class": true, "color": true, "colspan": true, "dir": true,
+	"face": true, "height": true, "lang": true, "rowspan": true, "size": true, "style": true,
+	"title": true, "valign": true, "width": true,
+}
+
+// unsafeCSS matches CSS that loads resources or runs code
+var unsafeCSS = regexp.MustCompile(`(?i)url\s*\(|@import|expression\s*\(|behavior\s*:|-moz-binding`)
+
+// SanitizeHTML removes active content from the HTML body of a message so it
+// can be shown in a browser. Scripts, frames, forms, event handlers,
+// javascript: links and remote resources such as images and style sheets
+// are removed; formatting, tables and inline styles are kept. Links open in
+// a new window.
+func SanitizeHTML(s string) string {
+	if s == "" {
+		return ""
+	}
+
+	var b strings.Builder
+	z := html.NewTokenizer(strings.NewReader(s))
+	skip, skipDepth := "", 0
+	inStyle := false
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			return b.String()
+		}
+		tok := z.Token()
+
+		// Inside a dropped element only its nesting is followed
+		if skip != "" {
+			switch {
+			case tt == html.StartTagToken && tok.Data == skip:
+				skipDepth++
+			case tt == html.EndTagToken && tok.Data == skip:
+				if skipDepth--; skipDepth == 0 {
+					skip = ""
+				}
+			}
+			continue
+		}
+
+		switch tt {
+		case html.TextToken:
+			if inStyle {
+				// Style sheets are raw text, a safe one is written as it is
+				if !unsafeCSS.MatchString(tok.Data) {
+					b.WriteString(tok.Data)
+				}
+				continue
+			}
+			b.WriteString(html.EscapeString(tok.Data))
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if droppedElements[tok.Data] {
+				if tt == html.StartTagToken {
+					skip, skipDepth = tok.Data, 1
+				}
+				continue
+			}
+			if !allowedElements[tok.Data] {
+				continue
+			}
+			if tok.Data == "style" && tt == html.StartTagToken {
+				inStyle = true
+			}
+			writeStartTag(&b, tok)
+		case html.EndTagToken:
+			if !allowedElements[tok.Data] {
+				continue
+			}
+			if tok.Data == "style" {
+				inStyle = false
+			}
+			b.WriteString("</" + tok.Data + ">")
+		}
+		// Comments and doctypes are dropped
+	}
+}
+
+// writeStartTag writes a start tag with its safe attributes
+func writeStartTag(b *strings.Builder, tok html.Token) {
+	b.WriteString("<" + tok.Data)
+	for _, attr := range tok.Attr {
+		if attr.Namespace != "" {
+			continue
+		}
+		key := strings.ToLower(attr.Key)
+		switch {
+		case key == "href" && tok.Data == "a":
+			if !safeLink(attr.Val) {
+				continue
+			}
+		case key == "src" && tok.Data == "img":
+			if !safeImage(attr.Val) {
+				continue
+			}
+		case key == "style":
+			if unsafeCSS.MatchString(attr.Val) {
+				continue
+			}
+		case !allowedAttributes[key]:
+			continue
+		}
+		b.WriteString(" " + key + `="` + html.EscapeString(attr.Val) + `"`)
+	}
+	if tok.Data == "a" {
+		b.WriteString(` target="_blank" rel="noopener noreferrer"`)
+	}
+	b.WriteString(">")
+}
+
+// safeLink reports whether a link is a web, mail or in-page link
+func safeLink(v string) bool {
+	v = strings.ToLower(strings.TrimSpace(v))
+	return strings.HasPrefix(v, "http://") || strings.HasPrefix(v, "https://") ||
+		strings.HasPrefix(v, "mailto:") || strings.HasPrefix(v, "#")
+}
+
+// safeImage reports whether an image source is embedded in the message.
+// Remote images are not loaded, they would report the view to the sender.
+func safeImage(v string) bool {
+	v = strings.ToLower(strings.TrimSpace(v))
+	return strings.HasPrefix(v, "data:image/") && !strings.HasPrefix(v, "data:image/svg")
+}
@@ -10,6 +10,8 @@ import (
 	"sync"
 	"time"
 
+	"github.com/foxzi/sendry/internal/config"
+	"github.com/foxzi/sendry/internal/dkim"
 	"github.com/foxzi/sendry/internal/email"
 	"github.com/foxzi/sendry/internal/headers"
 	"github.com/foxzi/sendry/internal/queue"
@@ -22,22 +24,30 @@ type DomainModeProvider interface {
 	GetBCCAddresses(domain string) []string
 }
 
+// DKIMProvider provides DKIM signers for email addresses
+type DKIMProvider interface {
+	GetSignerForEmail(email string) *dkim.Signer
+}
+
 // RealSender is the interface for the actual SMTP sender
 type RealSender interface {
 	Send(ctx context.Context, msg *queue.Message) error
 }
 
-// Sender wraps a real sender and intercepts messages based on domain mode
+// Sender wraps a real sender and intercepts messages based on domain mode and
+// the overrides of API keys and senders
 type Sender struct {
 	realSender       RealSender
 	domainProvider   DomainModeProvider
 	storage          *Storage
 	logger           *slog.Logger
 	headerProcessor  *headers.Processor
+	dkimProvider     DKIMProvider
 
 	mu               sync.RWMutex
 	simulateErrors   bool
 	errorProbability float64 // 0.0 to 1.0
+	overrides        []config.SandboxOverride
 }
 
 // NewSender creates a new sandbox sender
@@ -75,8 +85,52 @@ func (s *Sender) SetHeaderProcessor(p *headers.Processor) {
 	s.headerProcessor = p
 }
 
-// Send routes the message based on domain mode
+// SetOverrides replaces the modes of API keys and senders, e.g. on a config
+// reload
+func (s *Sender) SetOverrides(overrides []config.SandboxOverride) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.overrides = overrides
+}
+
+// SetDKIMProvider sets the DKIM signers of captured messages, so they are
+// stored signed as they would be delivered
+func (s *Sender) SetDKIMProvider(p DKIMProvider) {
+	s.dkimProvider = p
+}
+
+// sign returns the message data signed with the DKIM key of the sender
+// domain and whether it is signed
+func (s *Sender) sign(msg *queue.Message) ([]byte, bool) {
+	if msg.DKIMSigned {
+		return msg.Data, true
+	}
+	if msg.SkipDKIM || s.dkimProvider == nil {
+		return msg.Data, false
+	}
+	signer := s.dkimProvider.GetSignerForEmail(msg.From)
+	if signer == nil {
+		return msg.Data, false
+	}
+	signed, err := signer.Sign(msg.Data)
+	if err != nil {
+		s.logger.Warn("sandbox: DKIM signing failed, capturing unsigned",
+			"domain", signer.Domain(),
+			"error", err,
+		)
+		return msg.Data, false
+	}
+	return signed, true
+}
+
+// Send routes the message by the override of its API key or sender, or by
+// the mode of its sender domain
 func (s *Sender) Send(ctx context.Context, msg *queue.Message) error {
+	// Released messages got the header rules when they were captured
+	if msg.Released {
+		return s.realSender.Send(ctx, msg)
+	}
+
 	// Extract sender domain
 	domain := email.ExtractDomain(msg.From)
 	if domain == "" {
@@ -86,27 +140,84 @@ func (s *Sender) Send(ctx context.Context, msg *queue.Message) error {
 
 	// Apply header rules if configured
 	if s.headerProcessor != nil {
-		msg.Data = s.headerProcessor.Process(msg.Data, domain)
+		msg.Data = s.headerProcessor.ProcessMessage(msg.Data, headers.Envelope{From: msg.From, To: msg.To})
 	}
 
-	mode := "production"
-	if s.domainProvider != nil {
-		mode = s.domainProvider.GetDomainMode(domain)
-	}
+	rt := s.resolve(msg, domain)
 
-	switch mode {
+	switch rt.mode {
 	case "sandbox":
 		return s.handleSandbox(ctx, msg, domain)
 	case "redirect":
-		return s.handleRedirect(ctx, msg, domain)
+		return s.handleRedirect(ctx, msg, domain, rt.redirectTo)
 	case "bcc":
-		return s.handleBCC(ctx, msg, domain)
+		return s.handleBCC(ctx, msg, domain, rt.bccTo)
 	default:
 		// Production mode - send normally
 		return s.realSender.Send(ctx, msg)
 	}
 }
 
+// route is the mode of a message with its redirect and BCC addresses
+type route struct {
+	mode       string
+	redirectTo []string
+	bccTo      []string
+}
+
+// resolve returns the route of a message. The override of the API key that
+// submitted the message comes first, then the override of its sender
+// address, then the override of its sender domain written as @domain, then
+// the mode of the sender domain.
+func (s *Sender) resolve(msg *queue.Message, domain string) route {
+	s.mu.RLock()
+	overrides := s.overrides
+	s.mu.RUnlock()
+
+	var bySender, byDomain *config.SandboxOverride
+	for i := range overrides {
+		o := &overrides[i]
+		switch {
+		case o.APIKey != "":
+			if (msg.APIKeyID != "" && o.APIKey == msg.APIKeyID) || (msg.APIKeyName != "" && o.APIKey == msg.APIKeyName) {
+				return overrideRoute(o)
+			}
+		case strings.HasPrefix(o.Sender, "@"):
+			if byDomain == nil && strings.EqualFold(o.Sender[1:], domain) {
+				byDomain = o
+			}
+		default:
+			if bySender == nil && strings.EqualFold(o.Sender, msg.From) {
+				bySender = o
+			}
+		}
+	}
+	if bySender != nil {
+		return overrideRoute(bySender)
+	}
+	if byDomain != nil {
+		return overrideRoute(byDomain)
+	}
+
+	if s.domainProvider == nil {
+		return route{mode: "production"}
+	}
+	mode := s.domainProvider.GetDomainMode(domain)
+	rt := route{mode: mode}
+	switch mode {
+	case "redirect":
+		rt.redirectTo = s.domainProvider.GetRedirectAddresses(domain)
+	case "bcc":
+		rt.bccTo = s.domainProvider.GetBCCAddresses(domain)
+	}
+	return rt
+}
+
+// overrideRoute returns the route set by an override
+func overrideRoute(o *config.SandboxOverride) route {
+	return route{mode: o.Mode, redirectTo: o.RedirectTo, bccTo: o.BCCTo}
+}
+
 // handleSandbox stores the message instead of sending
 func (s *Sender) handleSandbox(ctx context.Context, msg *queue.Message, domain string) error {
 	s.logger.Info("sandbox: capturing message",
@@ -122,6 +233,8 @@ func (s *Sender) handleSandbox(ctx context.Context, msg *queue.Message, domain s
 	errorProbability := s.errorProbability
 	s.mu.RUnlock()
 
+	data, signed := s.sign(msg)
+
 	if simulateErrors && rand.Float64() < errorProbability {
 		errorTypes := []string{
 			"550 User not found",
@@ -136,12 +249,15 @@ func (s *Sender) handleSandbox(ctx context.Context, msg *queue.Message, domain s
 			From:         msg.From,
 			To:           msg.To,
 			Subject:      extractSubject(msg.Data),
-			Data:         msg.Data,
+			Data:         data,
 			Domain:       domain,
 			Mode:         "sandbox",
 			CapturedAt:   time.Now(),
 			ClientIP:     msg.ClientIP,
 			SimulatedErr: errMsg,
+			DKIMSigned:   signed,
+			APIKeyID:     msg.APIKeyID,
+			APIKeyName:   msg.APIKeyName,
 		}
 
 		if err := s.storage.Save(ctx, sandboxMsg); err != nil {
@@ -162,11 +278,14 @@ func (s *Sender) handleSandbox(ctx context.Context, msg *queue.Message, domain s
 		From:       msg.From,
 		To:         msg.To,
 		Subject:    extractSubject(msg.Data),
-		Data:       msg.Data,
+		Data:       data,
 		Domain:     domain,
 		Mode:       "sandbox",
 		CapturedAt: time.Now(),
 		ClientIP:   msg.ClientIP,
+		DKIMSigned: signed,
+		APIKeyID:   msg.APIKeyID,
+		APIKeyName: msg.APIKeyName,
 	}
 
 	if err := s.storage.Save(ctx, sandboxMsg); err != nil {
@@ -183,8 +302,7 @@ func (s *Sender) handleSandbox(ctx context.Context, msg *queue.Message, domain s
 }
 
 // handleRedirect redirects the message to configured addresses
-func (s *Sender) handleRedirect(ctx context.Context, msg *queue.Message, domain string) error {
-	redirectTo := s.domainProvider.GetRedirectAddresses(domain)
+func (s *Sender) handleRedirect(ctx context.Context, msg *queue.Message, domain string, redirectTo []string) error {
 	if len(redirectTo) == 0 {
 		s.logger.Warn("redirect: no redirect addresses configured, using sandbox",
 			"domain", domain,
@@ -212,6 +330,8 @@ func (s *Sender) handleRedirect(ctx context.Context, msg *queue.Message, domain
 		Mode:       "redirect",
 		CapturedAt: time.Now(),
 		ClientIP:   msg.ClientIP,
+		APIKeyID:   msg.APIKeyID,
+		APIKeyName: msg.APIKeyName,
 	}
 
 	if err := s.storage.Save(ctx, sandboxMsg); err != nil {
@@ -233,8 +353,7 @@ func (s *Sender) handleRedirect(ctx context.Context, msg *queue.Message, domain
 }
 
 // handleBCC sends to original recipients and BCC addresses
-func (s *Sender) handleBCC(ctx context.Context, msg *queue.Message, domain string) error {
-	bccTo := s.domainProvider.GetBCCAddresses(domain)
+func (s *Sender) handleBCC(ctx context.Context, msg *queue.Message, domain string, bccTo []string) error {
 	if len(bccTo) == 0 {
 		s.logger.Debug("bcc: no BCC addresses configured, sending normally",
 			"domain", domain,
@@ -262,6 +381,8 @@ func (s *Sender) handleBCC(ctx context.Context, msg *queue.Message, domain strin
 		Mode:       "bcc",
 		CapturedAt: time.Now(),
 		ClientIP:   msg.ClientIP,
+		APIKeyID:   msg.APIKeyID,
+		APIKeyName: msg.APIKeyName,
 	}
 
 	if err := s.storage.Save(ctx, sandboxMsg); err != nil {
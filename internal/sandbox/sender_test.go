@@ -1,13 +1,18 @@
 package sandbox
 
 import (
+	"bytes"
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
 	"path/filepath"
 	"testing"
 	"time"
 
 	bolt "go.etcd.io/bbolt"
 
+	"github.com/foxzi/sendry/internal/config"
+	"github.com/foxzi/sendry/internal/dkim"
 	"github.com/foxzi/sendry/internal/queue"
 )
 
@@ -152,6 +157,59 @@ func TestSenderSandboxMode(t *testing.T) {
 	}
 }
 
+// mockDKIMProvider signs messages of one domain
+type mockDKIMProvider struct {
+	signer *dkim.Signer
+}
+
+func (m *mockDKIMProvider) GetSignerForEmail(addr string) *dkim.Signer {
+	return m.signer
+}
+
+func TestSenderSandboxModeSignsDKIM(t *testing.T) {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	defer db.Close()
+
+	storage, err := NewStorage(db)
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	sender := NewSender(&mockSender{}, &mockDomainProvider{
+		modes: map[string]string{"sandbox.com": "sandbox"},
+	}, storage, nil)
+	sender.SetDKIMProvider(&mockDKIMProvider{signer: dkim.NewSigner(key, "sandbox.com", "s1")})
+
+	msg := &queue.Message{
+		ID:   "test-signed",
+		From: "sender@sandbox.com",
+		To:   []string{"recipient@example.com"},
+		Data: []byte("From: sender@sandbox.com\r\nSubject: Test\r\n\r\ntest message\r\n"),
+	}
+	if err := sender.Send(context.Background(), msg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	stored, err := storage.Get(context.Background(), "test-signed")
+	if err != nil || stored == nil {
+		t.Fatalf("failed to get stored message: %v", err)
+	}
+	if !stored.DKIMSigned || !bytes.HasPrefix(stored.Data, []byte("DKIM-Signature:")) {
+		t.Errorf("captured message not signed: signed=%v data=%q", stored.DKIMSigned, stored.Data)
+	}
+	if bytes.Contains(msg.Data, []byte("DKIM-Signature:")) {
+		t.Error("queued message data was modified")
+	}
+}
+
 func TestSenderRedirectMode(t *testing.T) {
 	tmpDir := t.TempDir()
 	dbPath := filepath.Join(tmpDir, "test.db")
@@ -274,6 +332,115 @@ func TestSenderBCCMode(t *testing.T) {
 	}
 }
 
+func TestSenderOverrides(t *testing.T) {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	defer db.Close()
+
+	storage, err := NewStorage(db)
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+
+	mock := &mockSender{}
+	sender := NewSender(mock, &mockDomainProvider{
+		modes: map[string]string{"staging.com": "sandbox"},
+	}, storage, nil)
+	sender.SetOverrides([]config.SandboxOverride{
+		{Sender: "@prod.com", Mode: "bcc", BCCTo: []string{"archive@testing.com"}},
+		{Sender: "QA@prod.com", Mode: "redirect", RedirectTo: []string{"qa-inbox@testing.com"}},
+		{APIKey: "staging-key", Mode: "sandbox"},
+		{APIKey: "key-id-2", Mode: "production"},
+	})
+
+	tests := []struct {
+		name     string
+		msg      *queue.Message
+		wantMode string // Mode of the capture, empty if delivered as is
+		wantTo   []string
+	}{
+		{
+			name:     "API key by name wins over sender",
+			msg:      &queue.Message{ID: "m1", From: "qa@prod.com", APIKeyName: "staging-key"},
+			wantMode: "sandbox",
+		},
+		{
+			name:   "API key by ID wins over domain mode",
+			msg:    &queue.Message{ID: "m2", From: "app@staging.com", APIKeyID: "key-id-2"},
+			wantTo: []string{"user@example.com"},
+		},
+		{
+			name:     "sender address wins over @domain",
+			msg:      &queue.Message{ID: "m3", From: "qa@prod.com", APIKeyName: "other-key"},
+			wantMode: "redirect",
+			wantTo:   []string{"qa-inbox@testing.com"},
+		},
+		{
+			name:     "@domain",
+			msg:      &queue.Message{ID: "m4", From: "app@PROD.com"},
+			wantMode: "bcc",
+			wantTo:   []string{"user@example.com", "archive@testing.com"},
+		},
+		{
+			name:     "domain mode without override",
+			msg:      &queue.Message{ID: "m5", From: "app@staging.com"},
+			wantMode: "sandbox",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock.sentMessages = nil
+			tt.msg.To = []string{"user@example.com"}
+			tt.msg.Data = []byte("Subject: Test\r\n\r\ntest message")
+
+			if err := sender.Send(context.Background(), tt.msg); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+
+			var to []string
+			for _, sent := range mock.sentMessages {
+				to = append(to, sent.To...)
+			}
+			if len(to) != len(tt.wantTo) {
+				t.Fatalf("delivered to %v, want %v", to, tt.wantTo)
+			}
+			for i := range to {
+				if to[i] != tt.wantTo[i] {
+					t.Fatalf("delivered to %v, want %v", to, tt.wantTo)
+				}
+			}
+
+			stored, err := storage.Get(context.Background(), tt.msg.ID)
+			if err != nil {
+				t.Fatalf("failed to get stored message: %v", err)
+			}
+			switch {
+			case tt.wantMode == "" && stored != nil:
+				t.Errorf("captured in %s mode, want delivered as is", stored.Mode)
+			case tt.wantMode != "" && (stored == nil || stored.Mode != tt.wantMode):
+				t.Errorf("capture = %+v, want mode %s", stored, tt.wantMode)
+			case stored != nil && (stored.APIKeyID != tt.msg.APIKeyID || stored.APIKeyName != tt.msg.APIKeyName):
+				t.Errorf("capture records API key %q/%q, want %q/%q",
+					stored.APIKeyID, stored.APIKeyName, tt.msg.APIKeyID, tt.msg.APIKeyName)
+			}
+		})
+	}
+
+	// Overrides are replaced on a config reload
+	sender.SetOverrides(nil)
+	mock.sentMessages = nil
+	msg := &queue.Message{ID: "m6", From: "qa@prod.com", To: []string{"user@example.com"}, Data: []byte("test")}
+	if err := sender.Send(context.Background(), msg); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if len(mock.sentMessages) != 1 || mock.sentMessages[0].To[0] != "user@example.com" {
+		t.Errorf("without overrides sent %v, want the original message", mock.sentMessages)
+	}
+}
+
 func TestExtractSubject(t *testing.T) {
 	tests := []struct {
 		data     []byte
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	bolt "go.etcd.io/bbolt"
@@ -24,6 +25,9 @@ type Message struct {
 	CapturedAt   time.Time `json:"captured_at"`
 	ClientIP     string    `json:"client_ip,omitempty"`
 	SimulatedErr string    `json:"simulated_error,omitempty"` // For error simulation
+	DKIMSigned   bool      `json:"dkim_signed,omitempty"`     // Data is signed with the DKIM key of the domain
+	APIKeyID     string    `json:"api_key_id,omitempty"`      // API key that submitted the message
+	APIKeyName   string    `json:"api_key_name,omitempty"`
 }
 
 // Storage provides sandbox message storage
@@ -92,8 +96,30 @@ type ListFilter struct {
 	Domain string
 	Mode   string
 	From   string
+	Search string // Case-insensitive text in the sender, recipients or subject
 	Limit  int
 	Offset int
+
+	// Releasable skips bcc copies, which cannot be released
+	Releasable bool
+	// WithData returns complete messages instead of their summary
+	WithData bool
+}
+
+// matches reports whether a message matches the search text of the filter
+func (f ListFilter) matches(msg *Message) bool {
+	if f.Search == "" {
+		return true
+	}
+	search := strings.ToLower(f.Search)
+	fields := append([]string{msg.From, msg.Subject}, msg.To...)
+	fields = append(fields, msg.OriginalTo...)
+	for _, field := range fields {
+		if strings.Contains(strings.ToLower(field), search) {
+			return true
+		}
+	}
+	return false
 }
 
 // List returns messages matching the filter
@@ -124,6 +150,12 @@ func (s *Storage) List(ctx context.Context, filter ListFilter) ([]*Message, erro
 			if filter.From != "" && msg.From != filter.From {
 				continue
 			}
+			if filter.Releasable && !msg.Releasable() {
+				continue
+			}
+			if !filter.matches(&msg) {
+				continue
+			}
 
 			// Apply offset
 			if skipped < filter.Offset {
@@ -142,6 +174,12 @@ func (s *Storage) List(ctx context.Context, filter ListFilter) ([]*Message, erro
 				CapturedAt: msg.CapturedAt,
 				ClientIP:   msg.ClientIP,
 			})
+			if filter.WithData {
+				last := messages[len(messages)-1]
+				last.Data = msg.Data
+				last.SimulatedErr = msg.SimulatedErr
+				last.DKIMSigned = msg.DKIMSigned
+			}
 			count++
 
 			// Apply limit
@@ -218,6 +256,92 @@ func (s *Storage) Clear(ctx context.Context, domain string, olderThan time.Durat
 	return count, err
 }
 
+// DeleteAddress removes the messages sent from or to an address, including
+// the original recipients of redirected messages
+func (s *Storage) DeleteAddress(ctx context.Context, address string) (int, error) {
+	var count int
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketSandbox)
+		c := bucket.Cursor()
+
+		var keysToDelete [][]byte
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			var msg Message
+			if err := json.Unmarshal(v, &msg); err != nil {
+				continue
+			}
+			if msg.involves(address) {
+				keysToDelete = append(keysToDelete, k)
+			}
+		}
+
+		for _, k := range keysToDelete {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+
+	return count, err
+}
+
+// involves reports whether a message is from or to an address
+func (m *Message) involves(address string) bool {
+	if strings.EqualFold(m.From, address) {
+		return true
+	}
+	for _, addr := range append(append([]string{}, m.To...), m.OriginalTo...) {
+		if strings.EqualFold(addr, address) {
+			return true
+		}
+	}
+	return false
+}
+
+// Cleanup deletes messages captured more than maxAge ago and the oldest
+// messages beyond maxCount. Zero values disable a limit.
+func (s *Storage) Cleanup(ctx context.Context, maxAge time.Duration, maxCount int) (int, error) {
+	var count int
+	cutoff := time.Now().Add(-maxAge)
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketSandbox)
+
+		// Keys sort by capture time, so the oldest messages come first
+		var keys [][]byte
+		var times []time.Time
+		c := bucket.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			var msg Message
+			if err := json.Unmarshal(v, &msg); err != nil {
+				continue
+			}
+			keys = append(keys, k)
+			times = append(times, msg.CapturedAt)
+		}
+
+		excess := 0
+		if maxCount > 0 && len(keys) > maxCount {
+			excess = len(keys) - maxCount
+		}
+		for i, k := range keys {
+			if i >= excess && (maxAge <= 0 || !times[i].Before(cutoff)) {
+				continue
+			}
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+
+	return count, err
+}
+
 // Stats returns sandbox statistics
 type Stats struct {
 	Total     int64            `json:"total"`
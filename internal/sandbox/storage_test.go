@@ -4,6 +4,7 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -337,6 +338,151 @@ func TestStorageNewStorageCreatesBucket(t *testing.T) {
 	}
 }
 
+func TestStorageListSearch(t *testing.T) {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	defer db.Close()
+
+	storage, err := NewStorage(db)
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+
+	ctx := context.Background()
+	messages := []*Message{
+		{ID: "welcome", From: "news@example.com", To: []string{"alice@example.org"}, Subject: "Welcome aboard"},
+		{ID: "invoice", From: "billing@example.com", To: []string{"bob@example.org"}, Subject: "Your invoice"},
+		{ID: "redirected", From: "news@example.com", To: []string{"qa@example.com"}, OriginalTo: []string{"carol@example.org"}, Subject: "Digest"},
+	}
+	for i, msg := range messages {
+		msg.Domain = "example.com"
+		msg.Mode = "sandbox"
+		msg.CapturedAt = time.Now().Add(time.Duration(i) * time.Second)
+		if err := storage.Save(ctx, msg); err != nil {
+			t.Fatalf("failed to save message: %v", err)
+		}
+	}
+
+	tests := []struct {
+		search string
+		want   []string
+	}{
+		{"INVOICE", []string{"invoice"}},
+		{"news@", []string{"redirected", "welcome"}},
+		{"alice", []string{"welcome"}},
+		{"carol@example.org", []string{"redirected"}},
+		{"nothing", nil},
+	}
+	for _, tt := range tests {
+		found, err := storage.List(ctx, ListFilter{Search: tt.search})
+		if err != nil {
+			t.Fatalf("failed to list messages: %v", err)
+		}
+		var ids []string
+		for _, msg := range found {
+			ids = append(ids, msg.ID)
+		}
+		if strings.Join(ids, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("search %q found %v, want %v", tt.search, ids, tt.want)
+		}
+	}
+}
+
+func TestStorageCleanup(t *testing.T) {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	defer db.Close()
+
+	storage, err := NewStorage(db)
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+
+	ctx := context.Background()
+	now := time.Now()
+	ages := map[string]time.Duration{
+		"old":    48 * time.Hour,
+		"day":    20 * time.Hour,
+		"hour":   time.Hour,
+		"recent": time.Minute,
+	}
+	for id, age := range ages {
+		msg := &Message{ID: id, From: "sender@example.com", Domain: "example.com", Mode: "sandbox", CapturedAt: now.Add(-age)}
+		if err := storage.Save(ctx, msg); err != nil {
+			t.Fatalf("failed to save message: %v", err)
+		}
+	}
+
+	// Without limits nothing is removed
+	if n, err := storage.Cleanup(ctx, 0, 0); err != nil || n != 0 {
+		t.Fatalf("Cleanup(0, 0) = %d, %v, want 0", n, err)
+	}
+
+	// Age removes the old message, count the oldest of the rest
+	n, err := storage.Cleanup(ctx, 24*time.Hour, 2)
+	if err != nil {
+		t.Fatalf("Cleanup() error = %v", err)
+	}
+	if n != 2 {
+		t.Errorf("Cleanup() removed %d messages, want 2", n)
+	}
+
+	remaining, err := storage.List(ctx, ListFilter{})
+	if err != nil {
+		t.Fatalf("failed to list messages: %v", err)
+	}
+	if len(remaining) != 2 || remaining[0].ID != "recent" || remaining[1].ID != "hour" {
+		t.Errorf("remaining messages = %v, want recent and hour", remaining)
+	}
+}
+
+func TestStorageDeleteAddress(t *testing.T) {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	defer db.Close()
+
+	storage, err := NewStorage(db)
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	ctx := context.Background()
+
+	msgs := []*Message{
+		{ID: "from", From: "User@example.com", To: []string{"other@example.org"}},
+		{ID: "to", From: "shop@example.org", To: []string{"a@example.org", "user@example.com"}},
+		{ID: "redirected", From: "shop@example.org", To: []string{"test@example.org"}, OriginalTo: []string{"user@example.com"}},
+		{ID: "similar", From: "shop@example.org", To: []string{"superuser@example.com"}},
+	}
+	for _, msg := range msgs {
+		msg.CapturedAt = time.Now()
+		if err := storage.Save(ctx, msg); err != nil {
+			t.Fatalf("failed to save message: %v", err)
+		}
+	}
+
+	n, err := storage.DeleteAddress(ctx, "user@example.com")
+	if err != nil {
+		t.Fatalf("DeleteAddress() error = %v", err)
+	}
+	if n != 3 {
+		t.Errorf("DeleteAddress() deleted %d messages, want 3", n)
+	}
+
+	remaining, err := storage.List(ctx, ListFilter{})
+	if err != nil {
+		t.Fatalf("failed to list messages: %v", err)
+	}
+	if len(remaining) != 1 || remaining[0].ID != "similar" {
+		t.Errorf("remaining messages = %v, want similar", remaining)
+	}
+}
+
 func TestMain(m *testing.M) {
 	os.Exit(m.Run())
 }
@@ -0,0 +1,290 @@
+// Package secrets resolves config values that reference a secret backend
+// instead of holding the secret: "vault:<path>#<field>" reads a field of a
+// HashiCorp Vault secret and "sops:<file>#<key>" a key of a SOPS encrypted
+// file. Values of *_file keys, such as DKIM and TLS keys, are written to
+// private files whose paths replace the references.
+package secrets
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Reference schemes
+const (
+	SchemeVault = "vault"
+	SchemeSOPS  = "sops"
+)
+
+// fileKeySuffix marks keys whose value is a file path
+const fileKeySuffix = "_file"
+
+// Config contains the secret backends
+type Config struct {
+	Vault           VaultConfig   `yaml:"vault"`
+	SOPS            SOPSConfig    `yaml:"sops"`
+	Dir             string        `yaml:"dir"`              // Directory of secrets of *_file keys (default: <tmp>/sendry-secrets)
+	RefreshInterval time.Duration `yaml:"refresh_interval"` // Re-read secrets and reload the config when they change (0 = on reload only)
+}
+
+var configType = reflect.TypeOf(Config{})
+
+// IsReference reports whether a config value references a secret
+func IsReference(s string) bool {
+	return strings.HasPrefix(s, SchemeVault+":") || strings.HasPrefix(s, SchemeSOPS+":")
+}
+
+// Resolver replaces secret references in a config with their values. It
+// caches what it reads, so a resolver is used for one load of the config.
+type Resolver struct {
+	cfg   Config
+	vault *vaultClient
+
+	sources  map[string]map[string]string // Fields of each secret or file
+	resolved map[string]string            // Reference -> value
+}
+
+// NewResolver creates a resolver for the backends of cfg
+func NewResolver(cfg Config) *Resolver {
+	if cfg.Dir == "" {
+		cfg.Dir = filepath.Join(os.TempDir(), "sendry-secrets")
+	}
+	return &Resolver{
+		cfg:      cfg,
+		sources:  make(map[string]map[string]string),
+		resolved: make(map[string]string),
+	}
+}
+
+// Resolve replaces the references in the strings of v, a pointer to a
+// struct, including those in nested structs, lists and maps. The secrets
+// section itself is left as is.
+func (r *Resolver) Resolve(ctx context.Context, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("resolve needs a pointer, got %T", v)
+	}
+	return r.walk(ctx, rv.Elem(), "", false)
+}
+
+// Digest identifies the resolved secrets, it changes when one does
+func (r *Resolver) Digest() string {
+	if len(r.resolved) == 0 {
+		return ""
+	}
+	refs := make([]string, 0, len(r.resolved))
+	for ref := range r.resolved {
+		refs = append(refs, ref)
+	}
+	slices.Sort(refs)
+
+	h := sha256.New()
+	for _, ref := range refs {
+		fmt.Fprintf(h, "%s\x00%s\x00", ref, r.resolved[ref])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// walk resolves the references under v. file is set for strings of *_file
+// keys, which get the path of a file with the secret.
+func (r *Resolver) walk(ctx context.Context, v reflect.Value, path string, file bool) error {
+	switch v.Kind() {
+	case reflect.String:
+		ref := v.String()
+		if !IsReference(ref) {
+			return nil
+		}
+		value, err := r.lookup(ctx, ref)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if file {
+			if value, err = r.writeFile(ref, value); err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+		}
+		v.SetString(value)
+	case reflect.Pointer:
+		if !v.IsNil() {
+			return r.walk(ctx, v.Elem(), path, file)
+		}
+	case reflect.Struct:
+		if v.Type() == configType {
+			return nil
+		}
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name := yamlKey(field)
+			if name == "-" {
+				continue
+			}
+			fieldPath := joinPath(path, name)
+			isFile := field.Type.Kind() == reflect.String && strings.HasSuffix(name, fileKeySuffix)
+			if err := r.walk(ctx, v.Field(i), fieldPath, isFile); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := r.walk(ctx, v.Index(i), fmt.Sprintf("%s[%d]", path, i), false); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		// Map values cannot be set in place, resolve a copy and store it
+		for _, key := range v.MapKeys() {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			elem.Set(v.MapIndex(key))
+			if err := r.walk(ctx, elem, joinPath(path, fmt.Sprint(key.Interface())), false); err != nil {
+				return err
+			}
+			v.SetMapIndex(key, elem)
+		}
+	}
+	return nil
+}
+
+// lookup returns the value of a reference
+func (r *Resolver) lookup(ctx context.Context, ref string) (string, error) {
+	if value, ok := r.resolved[ref]; ok {
+		return value, nil
+	}
+
+	scheme, rest, _ := strings.Cut(ref, ":")
+	source, field, _ := strings.Cut(rest, "#")
+	if source == "" {
+		return "", fmt.Errorf("%s reference without a path", scheme)
+	}
+
+	var value string
+	var err error
+	switch scheme {
+	case SchemeVault:
+		value, err = r.vaultValue(ctx, source, field)
+	case SchemeSOPS:
+		value, err = r.sopsValue(ctx, source, field)
+	}
+	if err != nil {
+		return "", err
+	}
+	r.resolved[ref] = value
+	return value, nil
+}
+
+// vaultValue returns a field of a Vault secret. The field may be left out
+// when the secret has only one.
+func (r *Resolver) vaultValue(ctx context.Context, path, field string) (string, error) {
+	key := SchemeVault + ":" + path
+	fields, ok := r.sources[key]
+	if !ok {
+		if r.vault == nil {
+			client, err := newVaultClient(r.cfg.Vault)
+			if err != nil {
+				return "", err
+			}
+			r.vault = client
+		}
+		var err error
+		if fields, err = r.vault.read(ctx, path); err != nil {
+			return "", err
+		}
+		r.sources[key] = fields
+	}
+	return pickField(fields, field, "vault secret "+path)
+}
+
+// sopsValue returns a key of a SOPS encrypted file, or the whole file
+// without a key
+func (r *Resolver) sopsValue(ctx context.Context, file, field string) (string, error) {
+	if field == "" {
+		return decryptSOPSFile(ctx, r.cfg.SOPS, file)
+	}
+	key := SchemeSOPS + ":" + file
+	fields, ok := r.sources[key]
+	if !ok {
+		var err error
+		if fields, err = decryptSOPSFields(ctx, r.cfg.SOPS, file); err != nil {
+			return "", err
+		}
+		r.sources[key] = fields
+	}
+	return pickField(fields, field, "sops file "+file)
+}
+
+// pickField returns a field of a secret, the only one when field is empty
+func pickField(fields map[string]string, field, source string) (string, error) {
+	if field == "" {
+		if len(fields) != 1 {
+			return "", fmt.Errorf("%s has %d fields, name one with #field", source, len(fields))
+		}
+		for _, value := range fields {
+			return value, nil
+		}
+	}
+	value, ok := fields[field]
+	if !ok {
+		return "", fmt.Errorf("%s has no field %q", source, field)
+	}
+	return value, nil
+}
+
+// writeFile writes the secret of a *_file key to a private file named after
+// its reference and returns its path. The file is only rewritten when the
+// secret changed, so readers that watch its modification time reload it on
+// rotation only.
+func (r *Resolver) writeFile(ref, value string) (string, error) {
+	if err := os.MkdirAll(r.cfg.Dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create secrets directory: %w", err)
+	}
+	sum := sha256.Sum256([]byte(ref))
+	path := filepath.Join(r.cfg.Dir, hex.EncodeToString(sum[:8])+".secret")
+
+	if current, err := os.ReadFile(path); err == nil && string(current) == value {
+		return path, nil
+	}
+
+	tmp, err := os.CreateTemp(r.cfg.Dir, ".secret-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to write secret file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(value); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to write secret file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write secret file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", fmt.Errorf("failed to write secret file: %w", err)
+	}
+	return path, nil
+}
+
+// yamlKey returns the YAML key of a field
+func yamlKey(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	if name == "" {
+		name = strings.ToLower(field.Name)
+	}
+	return name
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
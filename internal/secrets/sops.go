@@ -0,0 +1,92 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultSOPSBinary  = "sops"
+	defaultSOPSTimeout = 30 * time.Second
+)
+
+// SOPSConfig contains the SOPS backend. Files are decrypted by the sops
+// binary, which reads the age, PGP or cloud KMS keys of its environment.
+type SOPSConfig struct {
+	Binary  string        `yaml:"binary"`  // Path of the sops binary (default: sops in PATH)
+	Timeout time.Duration `yaml:"timeout"` // Time limit of a decryption (default: 30s)
+}
+
+// decryptSOPSFile returns the decrypted content of a file
+func decryptSOPSFile(ctx context.Context, cfg SOPSConfig, file string) (string, error) {
+	out, err := runSOPS(ctx, cfg, "--decrypt", file)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// decryptSOPSFields returns the keys of a decrypted YAML, JSON, INI or
+// dotenv file. Keys of nested objects are joined with dots and list items
+// are numbered, e.g. smtp.users.0.password.
+func decryptSOPSFields(ctx context.Context, cfg SOPSConfig, file string) (map[string]string, error) {
+	out, err := runSOPS(ctx, cfg, "--decrypt", "--output-type", "json", file)
+	if err != nil {
+		return nil, err
+	}
+	var doc any
+	if err := json.Unmarshal(out, &doc); err != nil {
+		return nil, fmt.Errorf("sops file %s: %w", file, err)
+	}
+	fields := make(map[string]string)
+	flatten(fields, "", doc)
+	return fields, nil
+}
+
+func runSOPS(ctx context.Context, cfg SOPSConfig, args ...string) ([]byte, error) {
+	binary := cfg.Binary
+	if binary == "" {
+		binary = defaultSOPSBinary
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultSOPSTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, binary, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		file := args[len(args)-1]
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("sops file %s: %w: %s", file, err, msg)
+		}
+		return nil, fmt.Errorf("sops file %s: %w", file, err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// flatten adds the scalar values of a JSON document to fields
+func flatten(fields map[string]string, prefix string, v any) {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			flatten(fields, joinPath(prefix, key), value)
+		}
+	case []any:
+		for i, value := range v {
+			flatten(fields, joinPath(prefix, strconv.Itoa(i)), value)
+		}
+	default:
+		fields[prefix] = stringValue(v)
+	}
+}
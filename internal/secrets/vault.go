@@ -0,0 +1,204 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	defaultVaultTimeout  = 10 * time.Second
+	defaultVaultAuthPath = "kubernetes"
+	defaultJWTFile       = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+)
+
+// VaultConfig contains the HashiCorp Vault backend. Secrets are read from
+// the KV engine, version 1 or 2, with a token or by Kubernetes login.
+type VaultConfig struct {
+	Address   string        `yaml:"address"`    // e.g. https://vault.example.com:8200 (default: VAULT_ADDR)
+	Token     string        `yaml:"token"`      // default: VAULT_TOKEN
+	TokenFile string        `yaml:"token_file"` // File with the token, e.g. written by Vault Agent
+	Namespace string        `yaml:"namespace"`  // Vault Enterprise namespace
+	CAFile    string        `yaml:"ca_file"`    // CA of the Vault server certificate (default: system roots)
+	Timeout   time.Duration `yaml:"timeout"`    // Time limit of a request (default: 10s)
+
+	// Kubernetes auth: log in with the service account token as this role
+	Role     string `yaml:"role"`
+	AuthPath string `yaml:"auth_path"` // Mount of the auth method (default: kubernetes)
+	JWTFile  string `yaml:"jwt_file"`  // Service account token (default: the mounted one)
+}
+
+// vaultClient reads secrets over the Vault HTTP API
+type vaultClient struct {
+	cfg   VaultConfig
+	http  *http.Client
+	token string
+}
+
+func newVaultClient(cfg VaultConfig) (*vaultClient, error) {
+	if cfg.Address == "" {
+		cfg.Address = os.Getenv("VAULT_ADDR")
+	}
+	if cfg.Address == "" {
+		return nil, errors.New("vault address is not set")
+	}
+	cfg.Address = strings.TrimRight(cfg.Address, "/")
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultVaultTimeout
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read vault CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in vault CA %s", cfg.CAFile)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+
+	c := &vaultClient{cfg: cfg, http: &http.Client{Timeout: cfg.Timeout, Transport: transport}}
+	switch {
+	case cfg.Role != "":
+		// Logged in on the first read
+	case cfg.Token != "":
+		c.token = cfg.Token
+	case cfg.TokenFile != "":
+		data, err := os.ReadFile(cfg.TokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read vault token: %w", err)
+		}
+		c.token = strings.TrimSpace(string(data))
+	default:
+		c.token = os.Getenv("VAULT_TOKEN")
+	}
+	if c.token == "" && cfg.Role == "" {
+		return nil, errors.New("vault token is not set")
+	}
+	return c, nil
+}
+
+// read returns the fields of a secret. The path is the API path of the
+// secret, e.g. secret/data/sendry for KV version 2.
+func (c *vaultClient) read(ctx context.Context, path string) (map[string]string, error) {
+	if c.token == "" {
+		if err := c.login(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	var resp struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/v1/"+strings.TrimLeft(path, "/"), nil, &resp); err != nil {
+		return nil, fmt.Errorf("vault secret %s: %w", path, err)
+	}
+
+	// KV version 2 nests the fields under data next to metadata
+	data := resp.Data
+	if inner, ok := data["data"].(map[string]any); ok {
+		if _, ok := data["metadata"]; ok {
+			data = inner
+		}
+	}
+
+	fields := make(map[string]string, len(data))
+	for name, value := range data {
+		fields[name] = stringValue(value)
+	}
+	return fields, nil
+}
+
+// login gets a token with the Kubernetes auth method
+func (c *vaultClient) login(ctx context.Context) error {
+	jwtFile := c.cfg.JWTFile
+	if jwtFile == "" {
+		jwtFile = defaultJWTFile
+	}
+	jwt, err := os.ReadFile(jwtFile)
+	if err != nil {
+		return fmt.Errorf("failed to read service account token: %w", err)
+	}
+	authPath := c.cfg.AuthPath
+	if authPath == "" {
+		authPath = defaultVaultAuthPath
+	}
+
+	body, _ := json.Marshal(map[string]string{"role": c.cfg.Role, "jwt": strings.TrimSpace(string(jwt))})
+	var resp struct {
+		Auth struct {
+			ClientToken string `json:"client_token"`
+		} `json:"auth"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/v1/auth/"+strings.Trim(authPath, "/")+"/login", body, &resp); err != nil {
+		return fmt.Errorf("vault login: %w", err)
+	}
+	if resp.Auth.ClientToken == "" {
+		return errors.New("vault login returned no token")
+	}
+	c.token = resp.Auth.ClientToken
+	return nil
+}
+
+// do sends a request and decodes its JSON response
+func (c *vaultClient) do(ctx context.Context, method, path string, body []byte, out any) error {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.cfg.Address+path, reader)
+	if err != nil {
+		return err
+	}
+	if c.token != "" {
+		req.Header.Set("X-Vault-Token", c.token)
+	}
+	if c.cfg.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", c.cfg.Namespace)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var vaultErr struct {
+			Errors []string `json:"errors"`
+		}
+		if json.Unmarshal(data, &vaultErr) == nil && len(vaultErr.Errors) > 0 {
+			return fmt.Errorf("status %d: %s", resp.StatusCode, strings.Join(vaultErr.Errors, "; "))
+		}
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return json.Unmarshal(data, out)
+}
+
+// stringValue returns a secret field as text, other than strings as JSON
+func stringValue(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
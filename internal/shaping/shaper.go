@@ -0,0 +1,89 @@
+package shaping
+
+import (
+	"context"
+	"time"
+)
+
+// slotFormat identifies an hour slot in the schedule timezone
+const slotFormat = "2006-01-02T15"
+
+// Result is the outcome of a schedule check
+type Result struct {
+	Allowed    bool
+	Limit      int           // Limit of the current hour (-1 = unlimited)
+	Sent       int           // Messages sent in the current hour
+	RetryAfter time.Duration // When to retry if not allowed
+}
+
+// Shaper enforces per-domain send schedules
+type Shaper struct {
+	storage *Storage
+	now     func() time.Time
+}
+
+// NewShaper creates a new traffic shaper
+func NewShaper(storage *Storage) *Shaper {
+	return &Shaper{
+		storage: storage,
+		now:     time.Now,
+	}
+}
+
+// Allow reports whether the domain may send a message now and counts it if so.
+// Domains without an enabled schedule are always allowed.
+func (s *Shaper) Allow(ctx context.Context, domain string) (*Result, error) {
+	sched, err := s.storage.Get(ctx, domain)
+	if err != nil {
+		return nil, err
+	}
+	if sched == nil || !sched.Enabled {
+		return &Result{Allowed: true, Limit: Unlimited}, nil
+	}
+
+	now := s.now().In(sched.Location())
+	limit := sched.Limit(now)
+	if limit == Unlimited {
+		return &Result{Allowed: true, Limit: Unlimited}, nil
+	}
+
+	sent, allowed, err := s.storage.Take(ctx, sched.Domain, now.Format(slotFormat), limit)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Allowed: allowed, Limit: limit, Sent: sent}
+	if !allowed {
+		result.RetryAfter = time.Hour
+		if next := sched.NextOpen(now); !next.IsZero() {
+			result.RetryAfter = next.Sub(now)
+		}
+	}
+
+	return result, nil
+}
+
+// Status returns the current hour limit and sent count for the domain without counting a message
+func (s *Shaper) Status(ctx context.Context, domain string) (*Result, error) {
+	sched, err := s.storage.Get(ctx, domain)
+	if err != nil {
+		return nil, err
+	}
+	if sched == nil || !sched.Enabled {
+		return &Result{Allowed: true, Limit: Unlimited}, nil
+	}
+
+	now := s.now().In(sched.Location())
+	limit := sched.Limit(now)
+
+	sent, err := s.storage.Sent(ctx, sched.Domain, now.Format(slotFormat))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{
+		Allowed: limit == Unlimited || sent < limit,
+		Limit:   limit,
+		Sent:    sent,
+	}, nil
+}
@@ -0,0 +1,82 @@
+package shaping
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestShaperAllow(t *testing.T) {
+	storage := newTestStorage(t)
+	ctx := context.Background()
+
+	sched := &Schedule{Domain: "example.com", Hours: Flat(Paused), Enabled: true}
+	sched.Hours[time.Monday][10] = 2
+	sched.Hours[time.Monday][11] = Unlimited
+	if err := storage.Put(ctx, sched); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2026, 1, 5, 10, 20, 0, 0, time.UTC) // Monday
+	shaper := NewShaper(storage)
+	shaper.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		result, err := shaper.Allow(ctx, "example.com")
+		if err != nil {
+			t.Fatalf("Allow() error = %v", err)
+		}
+		if !result.Allowed {
+			t.Fatalf("Allow() #%d should be allowed", i+1)
+		}
+	}
+
+	result, err := shaper.Allow(ctx, "example.com")
+	if err != nil {
+		t.Fatalf("Allow() error = %v", err)
+	}
+	if result.Allowed {
+		t.Fatal("Allow() should deny once the hourly limit is reached")
+	}
+	if result.RetryAfter != 40*time.Minute {
+		t.Errorf("RetryAfter = %v, want 40m", result.RetryAfter)
+	}
+
+	status, err := shaper.Status(ctx, "example.com")
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if status.Limit != 2 || status.Sent != 2 || status.Allowed {
+		t.Errorf("Status() = %+v", status)
+	}
+
+	// Unlimited hour
+	now = now.Add(time.Hour)
+	if result, _ := shaper.Allow(ctx, "example.com"); !result.Allowed {
+		t.Error("Allow() should allow during an unlimited hour")
+	}
+
+	// Paused hour waits for next Monday 10:00
+	now = time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)
+	result, _ = shaper.Allow(ctx, "example.com")
+	if result.Allowed {
+		t.Error("Allow() should deny during a paused hour")
+	}
+	if result.RetryAfter != 7*24*time.Hour-2*time.Hour {
+		t.Errorf("RetryAfter = %v, want %v", result.RetryAfter, 7*24*time.Hour-2*time.Hour)
+	}
+
+	// Domains without a schedule are not shaped
+	if result, _ := shaper.Allow(ctx, "other.com"); !result.Allowed {
+		t.Error("Allow() should allow domains without a schedule")
+	}
+
+	// Disabled schedules are not enforced
+	sched.Enabled = false
+	if err := storage.Put(ctx, sched); err != nil {
+		t.Fatal(err)
+	}
+	if result, _ := shaper.Allow(ctx, "example.com"); !result.Allowed {
+		t.Error("Allow() should allow when the schedule is disabled")
+	}
+}
@@ -0,0 +1,95 @@
+package shaping
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Hour limit values with special meaning
+const (
+	Unlimited = -1 // No cap for the hour, only rate limits apply
+	Paused    = 0  // No messages are sent during the hour
+)
+
+// Schedule is a weekly send curve for a sender domain.
+// Hours is indexed by weekday (Sunday = 0) and hour of day in the schedule timezone,
+// each value is the maximum number of messages sent in that hour.
+type Schedule struct {
+	Domain    string     `json:"domain"`
+	Timezone  string     `json:"timezone,omitempty"` // IANA name, default UTC
+	Hours     [7][24]int `json:"hours"`
+	Enabled   bool       `json:"enabled"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// Location returns the schedule timezone, UTC if unset or unknown
+func (s *Schedule) Location() *time.Location {
+	if s.Timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// Limit returns the hourly limit in effect at t
+func (s *Schedule) Limit(t time.Time) int {
+	t = t.In(s.Location())
+	return s.Hours[t.Weekday()][t.Hour()]
+}
+
+// NextOpen returns the start of the first hour after t that allows sending.
+// Returns zero time if every hour of the week is paused.
+func (s *Schedule) NextOpen(t time.Time) time.Time {
+	t = t.In(s.Location())
+	next := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location()).Add(time.Hour)
+
+	for i := 0; i < 7*24; i++ {
+		if s.Limit(next) != Paused {
+			return next
+		}
+		next = next.Add(time.Hour)
+	}
+
+	return time.Time{}
+}
+
+// Validate checks the timezone and hour limits
+func (s *Schedule) Validate() error {
+	if s.Timezone != "" {
+		if _, err := time.LoadLocation(s.Timezone); err != nil {
+			return fmt.Errorf("invalid timezone: %s", s.Timezone)
+		}
+	}
+
+	for day := range s.Hours {
+		for hour, limit := range s.Hours[day] {
+			if limit < Unlimited {
+				return fmt.Errorf("invalid limit %d for %s %02d:00 (must be -1 or greater)",
+					limit, time.Weekday(day), hour)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Flat returns a schedule with the same limit for every hour of the week
+func Flat(limit int) [7][24]int {
+	var hours [7][24]int
+	for day := range hours {
+		for hour := range hours[day] {
+			hours[day][hour] = limit
+		}
+	}
+	return hours
+}
+
+// NormalizeDomain lowercases and trims a domain name
+func NormalizeDomain(domain string) string {
+	return strings.ToLower(strings.TrimSpace(domain))
+}
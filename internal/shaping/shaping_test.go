@@ -0,0 +1,81 @@
+package shaping
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleLimit(t *testing.T) {
+	sched := &Schedule{Timezone: "Europe/Moscow", Hours: Flat(Unlimited)}
+	sched.Hours[time.Monday][9] = 100
+
+	// 06:30 UTC is 09:30 in Moscow
+	at := time.Date(2026, 1, 5, 6, 30, 0, 0, time.UTC) // Monday
+	if got := sched.Limit(at); got != 100 {
+		t.Errorf("Limit() = %d, want 100", got)
+	}
+
+	if got := sched.Limit(at.Add(time.Hour)); got != Unlimited {
+		t.Errorf("Limit() = %d, want %d", got, Unlimited)
+	}
+}
+
+func TestScheduleNextOpen(t *testing.T) {
+	sched := &Schedule{Hours: Flat(Paused)}
+	for hour := 9; hour < 18; hour++ {
+		sched.Hours[time.Monday][hour] = 50
+	}
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want time.Time
+	}{
+		{
+			name: "overnight waits for business hours",
+			at:   time.Date(2026, 1, 5, 2, 15, 0, 0, time.UTC), // Monday 02:15
+			want: time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "inside window opens at next hour",
+			at:   time.Date(2026, 1, 5, 10, 59, 0, 0, time.UTC),
+			want: time.Date(2026, 1, 5, 11, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "after window wraps to next week",
+			at:   time.Date(2026, 1, 5, 18, 0, 0, 0, time.UTC),
+			want: time.Date(2026, 1, 12, 9, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sched.NextOpen(tt.at); !got.Equal(tt.want) {
+				t.Errorf("NextOpen() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	closed := &Schedule{Hours: Flat(Paused)}
+	if got := closed.NextOpen(time.Now()); !got.IsZero() {
+		t.Errorf("NextOpen() = %v, want zero time for a fully paused schedule", got)
+	}
+}
+
+func TestScheduleValidate(t *testing.T) {
+	valid := &Schedule{Timezone: "America/New_York", Hours: Flat(10)}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+
+	badTZ := &Schedule{Timezone: "Mars/Olympus", Hours: Flat(10)}
+	if err := badTZ.Validate(); err == nil {
+		t.Error("Validate() should reject unknown timezone")
+	}
+
+	badLimit := &Schedule{Hours: Flat(10)}
+	badLimit.Hours[3][12] = -5
+	if err := badLimit.Validate(); err == nil {
+		t.Error("Validate() should reject limits below -1")
+	}
+}
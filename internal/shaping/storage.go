@@ -0,0 +1,179 @@
+package shaping
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	bucketSchedules = []byte("shaping_schedules")
+	bucketCounters  = []byte("shaping_counters")
+)
+
+// counter tracks messages sent by a domain in one hour slot
+type counter struct {
+	Slot  string `json:"slot"` // Hour in the schedule timezone, e.g. 2026-01-02T15
+	Count int    `json:"count"`
+}
+
+// Storage provides send schedule storage
+type Storage struct {
+	db *bolt.DB
+}
+
+// NewStorage creates a new schedule storage using the provided BoltDB instance
+func NewStorage(db *bolt.DB) (*Storage, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{bucketSchedules, bucketCounters} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create shaping buckets: %w", err)
+	}
+	return &Storage{db: db}, nil
+}
+
+// Get retrieves the schedule of a domain
+func (s *Storage) Get(ctx context.Context, domain string) (*Schedule, error) {
+	var sched *Schedule
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bucketSchedules).Get([]byte(NormalizeDomain(domain)))
+		if data == nil {
+			return nil
+		}
+		sched = &Schedule{}
+		return json.Unmarshal(data, sched)
+	})
+
+	return sched, err
+}
+
+// List returns all schedules ordered by domain
+func (s *Storage) List(ctx context.Context) ([]*Schedule, error) {
+	var result []*Schedule
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketSchedules).ForEach(func(k, v []byte) error {
+			var sched Schedule
+			if err := json.Unmarshal(v, &sched); err != nil {
+				return nil // Skip invalid entries
+			}
+			result = append(result, &sched)
+			return nil
+		})
+	})
+
+	return result, err
+}
+
+// Put creates or replaces the schedule of a domain
+func (s *Storage) Put(ctx context.Context, sched *Schedule) error {
+	sched.Domain = NormalizeDomain(sched.Domain)
+	if sched.Domain == "" {
+		return fmt.Errorf("domain is required")
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketSchedules)
+
+		now := time.Now()
+		sched.CreatedAt = now
+		if existing := b.Get([]byte(sched.Domain)); existing != nil {
+			var old Schedule
+			if err := json.Unmarshal(existing, &old); err == nil {
+				sched.CreatedAt = old.CreatedAt
+			}
+		}
+		sched.UpdatedAt = now
+
+		data, err := json.Marshal(sched)
+		if err != nil {
+			return fmt.Errorf("failed to marshal schedule: %w", err)
+		}
+		return b.Put([]byte(sched.Domain), data)
+	})
+}
+
+// Delete removes the schedule and hour counter of a domain
+func (s *Storage) Delete(ctx context.Context, domain string) error {
+	key := []byte(NormalizeDomain(domain))
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(bucketCounters).Delete(key); err != nil {
+			return err
+		}
+		return tx.Bucket(bucketSchedules).Delete(key)
+	})
+}
+
+// Take counts one message for the domain in the given hour slot if the limit allows it.
+// Returns the number of messages sent in the slot including this one.
+func (s *Storage) Take(ctx context.Context, domain, slot string, limit int) (int, bool, error) {
+	key := []byte(NormalizeDomain(domain))
+	sent := 0
+	allowed := false
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketCounters)
+
+		var c counter
+		if data := b.Get(key); data != nil {
+			if err := json.Unmarshal(data, &c); err != nil {
+				return err
+			}
+		}
+
+		// New hour starts a new count
+		if c.Slot != slot {
+			c = counter{Slot: slot}
+		}
+
+		sent = c.Count
+		if limit >= 0 && c.Count >= limit {
+			return nil
+		}
+
+		c.Count++
+		sent = c.Count
+		allowed = true
+
+		data, err := json.Marshal(c)
+		if err != nil {
+			return err
+		}
+		return b.Put(key, data)
+	})
+
+	return sent, allowed, err
+}
+
+// Sent returns the number of messages sent by the domain in the given hour slot
+func (s *Storage) Sent(ctx context.Context, domain, slot string) (int, error) {
+	sent := 0
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bucketCounters).Get([]byte(NormalizeDomain(domain)))
+		if data == nil {
+			return nil
+		}
+		var c counter
+		if err := json.Unmarshal(data, &c); err != nil {
+			return err
+		}
+		if c.Slot == slot {
+			sent = c.Count
+		}
+		return nil
+	})
+
+	return sent, err
+}
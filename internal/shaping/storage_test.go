@@ -0,0 +1,115 @@
+package shaping
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func newTestStorage(t *testing.T) *Storage {
+	t.Helper()
+
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	storage, err := NewStorage(db)
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+	return storage
+}
+
+func TestStorageCRUD(t *testing.T) {
+	storage := newTestStorage(t)
+	ctx := context.Background()
+
+	sched := &Schedule{Domain: "Example.COM", Hours: Flat(100), Enabled: true}
+	if err := storage.Put(ctx, sched); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if sched.Domain != "example.com" {
+		t.Errorf("Domain = %q, want normalized example.com", sched.Domain)
+	}
+	created := sched.CreatedAt
+
+	got, err := storage.Get(ctx, "example.com")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got == nil || got.Hours[2][5] != 100 {
+		t.Fatalf("Get() = %+v", got)
+	}
+
+	// Replacing keeps the creation time
+	time.Sleep(time.Millisecond)
+	update := &Schedule{Domain: "example.com", Hours: Flat(50), Enabled: true}
+	if err := storage.Put(ctx, update); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if !update.CreatedAt.Equal(created) {
+		t.Errorf("CreatedAt = %v, want %v", update.CreatedAt, created)
+	}
+
+	if err := storage.Put(ctx, &Schedule{Domain: "other.com"}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	list, err := storage.List(ctx)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(list) != 2 {
+		t.Errorf("List() returned %d schedules, want 2", len(list))
+	}
+
+	if err := storage.Delete(ctx, "example.com"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	got, _ = storage.Get(ctx, "example.com")
+	if got != nil {
+		t.Error("Get() should return nil after Delete()")
+	}
+
+	if err := storage.Put(ctx, &Schedule{}); err == nil {
+		t.Error("Put() should require a domain")
+	}
+}
+
+func TestStorageTake(t *testing.T) {
+	storage := newTestStorage(t)
+	ctx := context.Background()
+
+	for i := 1; i <= 2; i++ {
+		sent, ok, err := storage.Take(ctx, "example.com", "2026-01-05T10", 2)
+		if err != nil {
+			t.Fatalf("Take() error = %v", err)
+		}
+		if !ok || sent != i {
+			t.Errorf("Take() = %d, %v, want %d, true", sent, ok, i)
+		}
+	}
+
+	sent, ok, _ := storage.Take(ctx, "example.com", "2026-01-05T10", 2)
+	if ok || sent != 2 {
+		t.Errorf("Take() over limit = %d, %v, want 2, false", sent, ok)
+	}
+
+	// Next hour resets the count
+	sent, ok, _ = storage.Take(ctx, "example.com", "2026-01-05T11", 2)
+	if !ok || sent != 1 {
+		t.Errorf("Take() in new hour = %d, %v, want 1, true", sent, ok)
+	}
+
+	if n, _ := storage.Sent(ctx, "example.com", "2026-01-05T11"); n != 1 {
+		t.Errorf("Sent() = %d, want 1", n)
+	}
+	if n, _ := storage.Sent(ctx, "example.com", "2026-01-05T10"); n != 0 {
+		t.Errorf("Sent() for a past hour = %d, want 0", n)
+	}
+}
@@ -0,0 +1,20 @@
+package smtp
+
+import (
+	"errors"
+
+	"github.com/emersion/go-smtp"
+
+	"github.com/foxzi/sendry/internal/addrpolicy"
+)
+
+// addressPolicyError returns the SMTP error of an address refused by the
+// allow and deny lists
+func addressPolicyError(err error) *smtp.SMTPError {
+	e := &smtp.SMTPError{Code: 550, EnhancedCode: smtp.EnhancedCode{5, 7, 1}, Message: "Sender address rejected by policy"}
+	var v *addrpolicy.Violation
+	if errors.As(err, &v) && v.Recipient {
+		e.Message = "Recipient address rejected by policy"
+	}
+	return e
+}
@@ -7,11 +7,18 @@ import (
 	"time"
 
 	"github.com/emersion/go-smtp"
+	"github.com/foxzi/sendry/internal/addrpolicy"
 	"github.com/foxzi/sendry/internal/config"
+	"github.com/foxzi/sendry/internal/contentfilter"
+	"github.com/foxzi/sendry/internal/contentpolicy"
+	"github.com/foxzi/sendry/internal/inbound"
 	"github.com/foxzi/sendry/internal/ipfilter"
+	"github.com/foxzi/sendry/internal/ipguard"
+	"github.com/foxzi/sendry/internal/mailauth"
 	"github.com/foxzi/sendry/internal/metrics"
 	"github.com/foxzi/sendry/internal/queue"
 	"github.com/foxzi/sendry/internal/ratelimit"
+	"github.com/foxzi/sendry/internal/smtpauth"
 )
 
 // authFailure tracks failed auth attempts
@@ -29,6 +36,12 @@ type Backend struct {
 	rateLimiter *ratelimit.Limiter
 	serverType  string
 
+	// Verifies AUTH credentials, smtp.auth.users unless set
+	authenticator *smtpauth.Authenticator
+
+	// Identities of TLS client certificates, nil when disabled
+	certificates *smtpauth.Certificates
+
 	// Auth brute force protection
 	authFailures map[string]*authFailure
 	authMu       sync.RWMutex
@@ -43,9 +56,35 @@ type Backend struct {
 
 	// Anti-relay protection: only allow sending from configured domains
 	allowedDomains map[string]bool
+	allowedMu      sync.RWMutex
 
 	// IP filtering
 	ipFilter *ipfilter.Filter
+
+	// Per client IP throttling, tarpitting and bans, shared by the listeners
+	guard *ipguard.Guard
+
+	// Inbound routing of mail for local domains
+	inbound *inbound.Router
+
+	// SPF/DKIM/DMARC verification of inbound mail
+	verifier    *mailauth.Verifier
+	inboundAuth string
+
+	// Reject BODY=8BITMIME when 8BITMIME is not advertised
+	reject8BitMIME bool
+
+	// Refuse addresses with UTF-8 local parts
+	rejectEAI bool
+
+	// External content filter of received messages
+	filter *contentfilter.Checker
+
+	// Recipient and attachment limits of received messages
+	policy *contentpolicy.Enforcer
+
+	// Allow and deny lists of senders and recipients
+	addrPolicy *addrpolicy.Enforcer
 }
 
 // NewBackend creates a new SMTP backend
@@ -67,6 +106,7 @@ func NewBackend(q queue.Queue, auth *config.AuthConfig, logger *slog.Logger) *Ba
 	b := &Backend{
 		queue:             q,
 		auth:              auth,
+		authenticator:     smtpauth.New(auth, nil, nil),
 		logger:            logger,
 		serverType:        "smtp",
 		authFailures:      make(map[string]*authFailure),
@@ -126,6 +166,16 @@ func (b *Backend) SetServerType(serverType string) {
 	b.serverType = serverType
 }
 
+// SetReject8BitMIME makes sessions reject MAIL FROM with BODY=8BITMIME
+func (b *Backend) SetReject8BitMIME(reject bool) {
+	b.reject8BitMIME = reject
+}
+
+// SetRejectEAI makes sessions refuse internationalized addresses
+func (b *Backend) SetRejectEAI(reject bool) {
+	b.rejectEAI = reject
+}
+
 // SetRateLimiter sets the rate limiter for the backend
 func (b *Backend) SetRateLimiter(rl *ratelimit.Limiter) {
 	b.rateLimiter = rl
@@ -133,15 +183,23 @@ func (b *Backend) SetRateLimiter(rl *ratelimit.Limiter) {
 
 // SetAllowedDomains sets the list of domains allowed for sending (anti-relay protection)
 func (b *Backend) SetAllowedDomains(domains []string) {
-	b.allowedDomains = make(map[string]bool, len(domains))
+	allowed := make(map[string]bool, len(domains))
 	for _, d := range domains {
-		b.allowedDomains[d] = true
+		allowed[d] = true
 	}
+
+	b.allowedMu.Lock()
+	b.allowedDomains = allowed
+	b.allowedMu.Unlock()
+
 	b.logger.Info("allowed domains configured", "count", len(domains), "domains", domains)
 }
 
 // IsDomainAllowed checks if the sender domain is allowed
 func (b *Backend) IsDomainAllowed(domain string) bool {
+	b.allowedMu.RLock()
+	defer b.allowedMu.RUnlock()
+
 	// If no allowed domains configured, allow all (backwards compatibility)
 	if len(b.allowedDomains) == 0 {
 		return true
@@ -149,11 +207,58 @@ func (b *Backend) IsDomainAllowed(domain string) bool {
 	return b.allowedDomains[domain]
 }
 
+// SetInboundRouter enables inbound routing. Mail for recipients with an
+// inbound rule is accepted from any sender without authentication.
+func (b *Backend) SetInboundRouter(r *inbound.Router) {
+	b.inbound = r
+}
+
+// SetInboundAuth enables SPF, DKIM and DMARC verification of mail for
+// inbound routes in sessions without authentication. mode is
+// mailauth.ModeTag or mailauth.ModeReject.
+func (b *Backend) SetInboundAuth(v *mailauth.Verifier, mode string) {
+	b.verifier = v
+	b.inboundAuth = mode
+}
+
+// SetContentFilter passes received messages to an external content filter
+func (b *Backend) SetContentFilter(c *contentfilter.Checker) {
+	b.filter = c
+}
+
+// SetContentPolicy enforces recipient and attachment limits on received
+// messages
+func (b *Backend) SetContentPolicy(e *contentpolicy.Enforcer) {
+	b.policy = e
+}
+
+// SetAddressPolicy enforces allow and deny lists of senders and recipients
+// of relayed messages
+func (b *Backend) SetAddressPolicy(e *addrpolicy.Enforcer) {
+	b.addrPolicy = e
+}
+
+// SetAuthenticator sets the verifier of AUTH credentials
+func (b *Backend) SetAuthenticator(a *smtpauth.Authenticator) {
+	b.authenticator = a
+}
+
+// SetCertificates enables authentication by verified TLS client
+// certificate
+func (b *Backend) SetCertificates(c *smtpauth.Certificates) {
+	b.certificates = c
+}
+
 // SetIPFilter sets the IP filter for connection filtering
 func (b *Backend) SetIPFilter(filter *ipfilter.Filter) {
 	b.ipFilter = filter
 }
 
+// SetIPGuard sets the throttling of client IPs
+func (b *Backend) SetIPGuard(guard *ipguard.Guard) {
+	b.guard = guard
+}
+
 // CheckRateLimit checks if the request is within rate limits
 func (b *Backend) CheckRateLimit(ctx context.Context, req *ratelimit.Request) error {
 	if b.rateLimiter == nil {
@@ -255,5 +360,9 @@ func (b *Backend) NewSession(c *smtp.Conn) (smtp.Session, error) {
 		}
 	}
 
-	return NewSession(b, c), nil
+	s := NewSession(b, c)
+	if err := s.throttle(); err != nil {
+		return nil, err
+	}
+	return s, nil
 }
@@ -0,0 +1,306 @@
+package smtp
+
+import (
+	"bytes"
+	"crypto/tls"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// maxCommandLine limits how much of a line is held back while looking for
+// a complete command, longer lines are passed on unchanged
+const maxCommandLine = 4096
+
+// capabilities customizes what a listener says beyond the go-smtp settings:
+// the greeting banner and the EHLO extension list. go-smtp writes both with
+// fixed text, so they are rewritten on the connection. Rewriting needs the
+// plain text of the session, so with STARTTLS the TLS upgrade is done here.
+type capabilities struct {
+	banner     string      // Greeting text after the 220 code
+	no8BitMIME bool        // Do not advertise 8BITMIME
+	noChunking bool        // Do not advertise CHUNKING
+	size       int         // Advertised SIZE value (0 = unchanged)
+	startTLS   *tls.Config // STARTTLS handled by the connection
+}
+
+// active reports whether any go-smtp response needs to be rewritten
+func (c *capabilities) active() bool {
+	return c.banner != "" || c.no8BitMIME || c.noChunking || c.size > 0
+}
+
+// capListener wraps accepted connections in a capConn
+type capListener struct {
+	net.Listener
+	caps *capabilities
+}
+
+func (l *capListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &capConn{Conn: conn, caps: l.caps, transport: conn}, nil
+}
+
+// capConn rewrites the greeting and EHLO responses of a go-smtp connection
+// and runs STARTTLS for it. It follows the client commands to tell them
+// apart from message data.
+type capConn struct {
+	net.Conn // Raw connection for addresses and deadlines
+	caps     *capabilities
+
+	mu        sync.Mutex
+	transport net.Conn // Raw or TLS connection
+
+	// Read side
+	raw     []byte // Received and not yet scanned
+	in      []byte // Scanned and ready for go-smtp
+	readErr error
+	midLine bool // raw starts inside a line that was passed on
+	chunk   int  // BDAT payload bytes still to pass on
+	inData  bool // DATA payload until the terminating dot
+	pending func() error
+
+	// Write side
+	out     []byte   // Partial response line
+	ehlo    [][]byte // Collected EHLO response lines
+	greeted bool
+	authed  bool
+	tls     bool
+}
+
+func (c *capConn) conn() net.Conn {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.transport
+}
+
+// Read passes client lines to go-smtp. STARTTLS is answered here once all
+// commands before it are handled.
+func (c *capConn) Read(p []byte) (int, error) {
+	for len(c.in) == 0 {
+		if c.pending != nil {
+			action := c.pending
+			c.pending = nil
+			if err := action(); err != nil {
+				return 0, err
+			}
+			c.scan()
+			continue
+		}
+		if c.readErr != nil {
+			return 0, c.readErr
+		}
+
+		buf := make([]byte, 4096)
+		n, err := c.conn().Read(buf)
+		c.raw = append(c.raw, buf[:n]...)
+		if err != nil {
+			// Pass on what is left before reporting the error
+			c.readErr = err
+			c.in = append(c.in, c.raw...)
+			c.raw = nil
+			continue
+		}
+		c.scan()
+	}
+
+	n := copy(p, c.in)
+	c.in = c.in[n:]
+	return n, nil
+}
+
+// scan moves complete client lines from raw to in
+func (c *capConn) scan() {
+	for len(c.raw) > 0 && c.pending == nil {
+		if c.chunk > 0 {
+			n := min(c.chunk, len(c.raw))
+			c.in = append(c.in, c.raw[:n]...)
+			c.raw = c.raw[n:]
+			c.chunk -= n
+			continue
+		}
+
+		i := bytes.IndexByte(c.raw, '\n')
+		if i < 0 {
+			if len(c.raw) >= maxCommandLine {
+				c.in = append(c.in, c.raw...)
+				c.raw = nil
+				c.midLine = true
+			}
+			return
+		}
+
+		line := c.raw[:i+1]
+		c.raw = c.raw[i+1:]
+		if c.midLine {
+			c.midLine = false
+		} else if c.command(line) {
+			continue
+		}
+		c.in = append(c.in, line...)
+	}
+}
+
+// command inspects a client line and reports whether it is handled here
+// instead of by go-smtp
+func (c *capConn) command(line []byte) bool {
+	text := strings.TrimRight(string(line), "\r\n")
+	if c.inData {
+		if text == "." {
+			c.inData = false
+		}
+		return false
+	}
+
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return false
+	}
+	switch strings.ToUpper(fields[0]) {
+	case "BDAT":
+		if len(fields) > 1 {
+			if size, err := strconv.Atoi(fields[1]); err == nil && size > 0 {
+				c.chunk = size
+			}
+		}
+	case "STARTTLS":
+		if c.caps.startTLS == nil {
+			return false
+		}
+		c.pending = c.startTLS
+		return true
+	}
+	return false
+}
+
+// startTLS upgrades the connection to TLS. Commands the client pipelined
+// after STARTTLS are discarded (RFC 3207).
+func (c *capConn) startTLS() error {
+	c.raw = nil
+	switch {
+	case c.tls:
+		return c.reply("502 5.5.1 Already running in TLS")
+	case c.authed:
+		return c.reply("503 5.5.1 STARTTLS is not allowed after AUTH")
+	}
+
+	if err := c.reply("220 2.0.0 Ready to start TLS"); err != nil {
+		return err
+	}
+	tlsConn := tls.Server(c.Conn, c.caps.startTLS)
+	if err := tlsConn.Handshake(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.transport = tlsConn
+	c.mu.Unlock()
+	c.tls = true
+	return nil
+}
+
+// connTLSState returns the TLS state of a connection, also when its TLS is
+// run by a capConn, which go-smtp does not see
+func connTLSState(conn net.Conn) (tls.ConnectionState, bool) {
+	if c, ok := conn.(*capConn); ok {
+		conn = c.conn()
+	}
+	if tc, ok := conn.(*tls.Conn); ok {
+		return tc.ConnectionState(), true
+	}
+	return tls.ConnectionState{}, false
+}
+
+// reply writes a response of the connection itself
+func (c *capConn) reply(text string) error {
+	_, err := c.conn().Write([]byte(text + "\r\n"))
+	return err
+}
+
+// Write rewrites go-smtp responses line by line
+func (c *capConn) Write(p []byte) (int, error) {
+	c.out = append(c.out, p...)
+
+	var send []byte
+	for {
+		i := bytes.IndexByte(c.out, '\n')
+		if i < 0 {
+			break
+		}
+		line := c.out[:i+1]
+		c.out = c.out[i+1:]
+		send = append(send, c.response(line)...)
+	}
+
+	if len(send) > 0 {
+		if _, err := c.conn().Write(send); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// response returns what to send for a go-smtp response line
+func (c *capConn) response(line []byte) []byte {
+	if !c.greeted {
+		c.greeted = true
+		if c.caps.banner != "" && bytes.HasPrefix(line, []byte("220 ")) {
+			return []byte("220 " + c.caps.banner + "\r\n")
+		}
+		return line
+	}
+
+	switch {
+	case c.ehlo != nil || bytes.HasPrefix(line, []byte("250-Hello ")):
+		c.ehlo = append(c.ehlo, line)
+		if bytes.HasPrefix(line, []byte("250 ")) {
+			resp := c.rewriteEHLO(c.ehlo)
+			c.ehlo = nil
+			return resp
+		}
+		return nil
+	case bytes.HasPrefix(line, []byte("354 ")):
+		c.inData = true
+	case bytes.HasPrefix(line, []byte("235 ")):
+		c.authed = true
+	}
+	return line
+}
+
+// rewriteEHLO applies the capabilities to a multiline EHLO response
+func (c *capConn) rewriteEHLO(lines [][]byte) []byte {
+	var args []string
+	for _, line := range lines {
+		arg := strings.TrimRight(string(line[4:]), "\r\n")
+		keyword := strings.ToUpper(strings.SplitN(arg, " ", 2)[0])
+		switch {
+		case keyword == "8BITMIME" && c.caps.no8BitMIME,
+			keyword == "CHUNKING" && c.caps.noChunking:
+			continue
+		case keyword == "SIZE" && c.caps.size > 0:
+			arg = "SIZE " + strconv.Itoa(c.caps.size)
+		}
+		args = append(args, arg)
+	}
+	if c.caps.startTLS != nil && !c.tls {
+		args = append(args, "STARTTLS")
+	}
+
+	var resp []byte
+	for i, arg := range args {
+		sep := "-"
+		if i == len(args)-1 {
+			sep = " "
+		}
+		resp = append(resp, "250"+sep+arg+"\r\n"...)
+	}
+	return resp
+}
+
+// Close closes the connection, with a TLS close notification after STARTTLS
+func (c *capConn) Close() error {
+	return c.conn().Close()
+}
@@ -0,0 +1,279 @@
+package smtp
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/foxzi/sendry/internal/config"
+	"github.com/foxzi/sendry/internal/queue"
+)
+
+// recordQueue records enqueued messages
+type recordQueue struct {
+	queue.Queue
+	mu       sync.Mutex
+	enqueued []*queue.Message
+}
+
+func (q *recordQueue) Enqueue(ctx context.Context, msg *queue.Message) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.enqueued = append(q.enqueued, msg)
+	return nil
+}
+
+func testTLSConfig(t *testing.T) *tls.Config {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+}
+
+// startCapServer serves an SMTP server with custom capabilities on a local port
+func startCapServer(t *testing.T, cfg *config.SMTPConfig, tlsConfig *tls.Config) (string, *recordQueue) {
+	t.Helper()
+	q := &recordQueue{}
+	s := NewServerWithOptions(ServerOptions{
+		Config:     cfg,
+		Queue:      q,
+		Logger:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+		TLSConfig:  tlsConfig,
+		ServerType: "smtp",
+	})
+	if s.caps == nil {
+		t.Fatal("capabilities not active")
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.server.Serve(&capListener{Listener: l, caps: s.caps})
+	t.Cleanup(func() { s.Close() })
+	return l.Addr().String(), q
+}
+
+// ehlo sends EHLO and returns the advertised extensions
+func ehlo(t *testing.T, c *textproto.Conn) []string {
+	t.Helper()
+	return strings.Split(cmd(t, c, 250, "EHLO client.example.com"), "\n")[1:]
+}
+
+// cmd sends a command and reads a response with the expected code
+func cmd(t *testing.T, c *textproto.Conn, code int, format string, args ...any) string {
+	t.Helper()
+	if err := c.PrintfLine(format, args...); err != nil {
+		t.Fatal(err)
+	}
+	_, msg, err := c.ReadResponse(code)
+	if err != nil {
+		t.Fatalf("%s: %v", format, err)
+	}
+	return msg
+}
+
+func TestServerCapabilities(t *testing.T) {
+	cfg := &config.SMTPConfig{
+		Domain:          "mx.example.com",
+		MaxMessageBytes: 10 * 1024 * 1024,
+		Banner:          "mx.example.com ESMTP Gateway ready",
+		Extensions: config.SMTPExtensionsConfig{
+			Disable8BitMIME: true,
+			DisableChunking: true,
+			SMTPUTF8:        true,
+			Size:            5242880,
+		},
+		Auth: config.AuthConfig{Users: map[string]string{"app": "secret"}},
+	}
+	addr, q := startCapServer(t, cfg, testTLSConfig(t))
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	c := textproto.NewConn(conn)
+
+	_, banner, err := c.ReadResponse(220)
+	if err != nil || banner != cfg.Banner {
+		t.Fatalf("banner = %q, %v", banner, err)
+	}
+
+	exts := strings.Join(ehlo(t, c), ",")
+	for _, want := range []string{"SIZE 5242880", "SMTPUTF8", "STARTTLS", "PIPELINING"} {
+		if !strings.Contains(exts, want) {
+			t.Errorf("extensions %s lack %s", exts, want)
+		}
+	}
+	for _, hidden := range []string{"8BITMIME", "CHUNKING"} {
+		if strings.Contains(exts, hidden) {
+			t.Errorf("extensions %s advertise %s", exts, hidden)
+		}
+	}
+
+	// Commands pipelined after STARTTLS are discarded
+	if err := c.PrintfLine("STARTTLS\r\nRSET"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := c.ReadResponse(220); err != nil {
+		t.Fatalf("STARTTLS error = %v", err)
+	}
+	tlsConn := tls.Client(conn, &tls.Config{InsecureSkipVerify: true})
+	if err := tlsConn.Handshake(); err != nil {
+		t.Fatalf("handshake error = %v", err)
+	}
+	c = textproto.NewConn(tlsConn)
+
+	exts = strings.Join(ehlo(t, c), ",")
+	if strings.Contains(exts, "STARTTLS") || !strings.Contains(exts, "AUTH PLAIN") {
+		t.Errorf("extensions after STARTTLS = %s", exts)
+	}
+	cmd(t, c, 502, "STARTTLS")
+	cmd(t, c, 555, "MAIL FROM:<a@example.com> BODY=8BITMIME")
+
+	// Message lines that look like commands are data
+	cmd(t, c, 235, "AUTH PLAIN AGFwcABzZWNyZXQ=")
+	cmd(t, c, 250, "MAIL FROM:<a@example.com>")
+	cmd(t, c, 250, "RCPT TO:<b@example.org>")
+	cmd(t, c, 354, "DATA")
+	if err := c.PrintfLine("Subject: test\r\n\r\nSTARTTLS\r\nBDAT 10\r\n."); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := c.ReadResponse(250); err != nil {
+		t.Fatalf("DATA error = %v", err)
+	}
+	cmd(t, c, 221, "QUIT")
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.enqueued) != 1 || !strings.HasSuffix(string(q.enqueued[0].Data), "STARTTLS\r\nBDAT 10\r\n") {
+		t.Errorf("enqueued = %v", q.enqueued)
+	}
+}
+
+func TestServerChunkingSMTPUTF8(t *testing.T) {
+	cfg := &config.SMTPConfig{
+		Domain:          "mx.example.com",
+		MaxMessageBytes: 10 * 1024 * 1024,
+		Banner:          "mx.example.com ESMTP",
+		Extensions:      config.SMTPExtensionsConfig{SMTPUTF8: true},
+		Auth:            config.AuthConfig{Users: map[string]string{"app": "secret"}},
+	}
+	addr, q := startCapServer(t, cfg, nil)
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	c := textproto.NewConn(conn)
+	if _, _, err := c.ReadResponse(220); err != nil {
+		t.Fatal(err)
+	}
+
+	exts := strings.Join(ehlo(t, c), ",")
+	for _, want := range []string{"CHUNKING", "SMTPUTF8"} {
+		if !strings.Contains(exts, want) {
+			t.Errorf("extensions %s lack %s", exts, want)
+		}
+	}
+
+	cmd(t, c, 235, "AUTH PLAIN AGFwcABzZWNyZXQ=")
+	cmd(t, c, 250, "MAIL FROM:<отправитель@example.com> SMTPUTF8")
+	cmd(t, c, 250, "RCPT TO:<b@example.org>")
+
+	// Chunk content that looks like a command is data
+	first := "Subject: test\r\n\r\nSTARTTLS\r\n"
+	second := "end\r\n"
+	if _, err := io.WriteString(conn, "BDAT "+strconv.Itoa(len(first))+"\r\n"+first); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := c.ReadResponse(250); err != nil {
+		t.Fatalf("BDAT error = %v", err)
+	}
+	if _, err := io.WriteString(conn, "BDAT "+strconv.Itoa(len(second))+" LAST\r\n"+second); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := c.ReadResponse(250); err != nil {
+		t.Fatalf("BDAT LAST error = %v", err)
+	}
+	cmd(t, c, 221, "QUIT")
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.enqueued) != 1 {
+		t.Fatalf("enqueued %d messages, want 1", len(q.enqueued))
+	}
+	msg := q.enqueued[0]
+	if string(msg.Data) != first+second {
+		t.Errorf("data = %q", msg.Data)
+	}
+	if !msg.SMTPUTF8 || msg.From != "отправитель@example.com" {
+		t.Errorf("message from %q SMTPUTF8=%t, want the SMTPUTF8 flag", msg.From, msg.SMTPUTF8)
+	}
+}
+
+func TestCapConnRewriteEHLO(t *testing.T) {
+	c := &capConn{caps: &capabilities{noChunking: true, size: 1000}, greeted: true}
+	resp := c.response([]byte("250-Hello client\r\n"))
+	for _, line := range []string{"250-PIPELINING\r\n", "250-CHUNKING\r\n"} {
+		resp = append(resp, c.response([]byte(line))...)
+	}
+	resp = append(resp, c.response([]byte("250 SIZE 10485760\r\n"))...)
+
+	want := "250-Hello client\r\n250-PIPELINING\r\n250 SIZE 1000\r\n"
+	if string(resp) != want {
+		t.Errorf("EHLO response = %q, want %q", resp, want)
+	}
+
+	// Responses after EHLO are unchanged
+	if got := c.response([]byte("250 2.0.0 Roger\r\n")); string(got) != "250 2.0.0 Roger\r\n" {
+		t.Errorf("response = %q", got)
+	}
+}
+
+func TestConnTLSState(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	if _, ok := connTLSState(server); ok {
+		t.Error("plain connection has a TLS state")
+	}
+	if _, ok := connTLSState(&capConn{Conn: server, transport: server}); ok {
+		t.Error("capConn before STARTTLS has a TLS state")
+	}
+	tlsConn := tls.Server(server, &tls.Config{})
+	if _, ok := connTLSState(tlsConn); !ok {
+		t.Error("TLS connection has no TLS state")
+	}
+	if _, ok := connTLSState(&capConn{Conn: server, transport: tlsConn}); !ok {
+		t.Error("capConn after STARTTLS has no TLS state")
+	}
+}
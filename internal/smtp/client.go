@@ -3,21 +3,32 @@ package smtp
 import (
 	"bytes"
 	"context"
-	"crypto/tls"
 	"errors"
 	"fmt"
 	"log/slog"
 	"net"
-	"net/smtp"
 	"regexp"
 	"strings"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/foxzi/sendry/internal/bounce"
+	"github.com/foxzi/sendry/internal/config"
 	"github.com/foxzi/sendry/internal/dkim"
 	"github.com/foxzi/sendry/internal/dns"
+	"github.com/foxzi/sendry/internal/email"
+	"github.com/foxzi/sendry/internal/metrics"
 	"github.com/foxzi/sendry/internal/queue"
+	"github.com/foxzi/sendry/internal/retry"
+	"github.com/foxzi/sendry/internal/tracing"
 )
 
+// DefaultMXFallbackDelay is the head start an MX host gets before the next
+// one is dialed in parallel
+const DefaultMXFallbackDelay = 3 * time.Second
+
 // DKIMProvider provides DKIM signers for email addresses
 type DKIMProvider interface {
 	GetSignerForEmail(email string) *dkim.Signer
@@ -25,22 +36,41 @@ type DKIMProvider interface {
 
 // DeliveryError represents a delivery error with type information
 type DeliveryError struct {
-	Temporary bool
-	Message   string
+	Temporary  bool
+	Greylisted bool   // Temporary reply of a greylisting server
+	Code       string // SMTP reply code, empty for errors without a reply
+	Message    string
 }
 
 func (e *DeliveryError) Error() string {
 	return e.Message
 }
 
+// DomainConfigProvider provides the current configuration of a domain
+type DomainConfigProvider interface {
+	GetDomainConfig(domain string) *config.DomainConfig
+}
+
+// mxResolver looks up the MX records of a domain
+type mxResolver interface {
+	LookupMX(ctx context.Context, domain string) ([]dns.MXRecord, error)
+}
+
 // Client sends emails to external MX servers
 type Client struct {
-	resolver     *dns.Resolver
-	timeout      time.Duration
-	hostname     string
-	logger       *slog.Logger
-	dkimSigner   *dkim.Signer   // Legacy single signer (deprecated)
-	dkimProvider DKIMProvider   // Multi-domain DKIM provider
+	resolver         mxResolver
+	timeout          time.Duration
+	hostname         string
+	logger           *slog.Logger
+	dkimSigner       *dkim.Signer // Legacy single signer (deprecated)
+	dkimProvider     DKIMProvider // Multi-domain DKIM provider
+	health           *MXHealth    // Negative cache of unreachable MX hosts
+	fallbackDelay    time.Duration
+	maxAttemptsPerMX int                  // Attempts per MX host and message (0 = unlimited)
+	pool             *ConnPool            // Reused MX sessions (nil = one connection per message)
+	domains          DomainConfigProvider // Per-domain delivery settings (nil = MX lookup only)
+	port             string
+	dial             func(ctx context.Context, network, addr string) (net.Conn, error)
 }
 
 // NewClient creates a new SMTP client
@@ -48,14 +78,57 @@ func NewClient(resolver *dns.Resolver, hostname string, timeout time.Duration, l
 	if timeout == 0 {
 		timeout = 30 * time.Second
 	}
+	dialer := &net.Dialer{
+		Timeout: timeout,
+	}
 	return &Client{
-		resolver: resolver,
-		timeout:  timeout,
-		hostname: hostname,
-		logger:   logger,
+		resolver:      resolver,
+		timeout:       timeout,
+		hostname:      hostname,
+		logger:        logger,
+		health:        NewMXHealth(DefaultMXDeadTTL),
+		fallbackDelay: DefaultMXFallbackDelay,
+		port:          "25",
+		dial:          dialer.DialContext,
 	}
 }
 
+// SetMXHealth sets the tracker of unreachable MX hosts
+func (c *Client) SetMXHealth(health *MXHealth) {
+	c.health = health
+}
+
+// SetMXFallbackDelay sets how long an MX host is given to accept a connection
+// before the next MX host is dialed in parallel
+func (c *Client) SetMXFallbackDelay(d time.Duration) {
+	if d <= 0 {
+		d = DefaultMXFallbackDelay
+	}
+	c.fallbackDelay = d
+}
+
+// SetMaxAttemptsPerMX limits how many times one message is tried at the
+// same MX host across retries. Zero means unlimited.
+func (c *Client) SetMaxAttemptsPerMX(n int) {
+	c.maxAttemptsPerMX = n
+}
+
+// SetConnPool enables reuse of MX sessions across messages
+func (c *Client) SetConnPool(pool *ConnPool) {
+	c.pool = pool
+}
+
+// SetDomainConfigs sets the provider of per-domain delivery settings, mail
+// for domains with an LMTP delivery is sent to their LMTP server
+func (c *Client) SetDomainConfigs(domains DomainConfigProvider) {
+	c.domains = domains
+}
+
+// MXHealth returns the tracker of unreachable MX hosts
+func (c *Client) MXHealth() *MXHealth {
+	return c.health
+}
+
 // SetDKIMSigner sets the DKIM signer for outgoing messages (deprecated, use SetDKIMProvider)
 func (c *Client) SetDKIMSigner(signer *dkim.Signer) {
 	c.dkimSigner = signer
@@ -79,194 +152,652 @@ func (c *Client) getDKIMSigner(from string) *dkim.Signer {
 	return c.dkimSigner
 }
 
-// Send sends a message to all recipients
+// signMessage returns the message data signed with the DKIM key of the
+// sender domain. Messages signed at enqueue time, flagged to skip signing
+// or without a key for the sender are returned as they are.
+func (c *Client) signMessage(msg *queue.Message) []byte {
+	if msg.DKIMSigned || msg.SkipDKIM {
+		return msg.Data
+	}
+	signer := c.getDKIMSigner(msg.From)
+	if signer == nil {
+		return msg.Data
+	}
+	signed, err := signer.Sign(msg.Data)
+	if err != nil {
+		c.logger.Warn("DKIM signing failed, sending unsigned",
+			"domain", signer.Domain(),
+			"error", err,
+		)
+		return msg.Data
+	}
+	c.logger.Debug("DKIM signed",
+		"domain", signer.Domain(),
+		"selector", signer.Selector(),
+	)
+	return signed
+}
+
+// Send sends a message to all pending recipients. The outcome of every
+// recipient is stored in msg.Results and every MX attempt in msg.Attempts,
+// so a retry only sends to recipients that were deferred.
 func (c *Client) Send(ctx context.Context, msg *queue.Message) error {
+	if len(msg.To) == 0 {
+		return &DeliveryError{
+			Temporary: false,
+			Message:   "no valid recipients",
+		}
+	}
+
 	// Group recipients by domain
 	byDomain := make(map[string][]string)
-	for _, to := range msg.To {
+	for _, to := range msg.PendingRecipients() {
 		domain := dns.ExtractDomain(to)
 		if domain == "" {
 			c.logger.Warn("skipping recipient with invalid domain", "recipient", to)
+			msg.SetResult(to, queue.RecipientResult{
+				Status: queue.StatusFailed,
+				Error:  "invalid recipient domain",
+			})
 			continue
 		}
 		byDomain[domain] = append(byDomain[domain], to)
 	}
 
-	// Check if we have any valid recipients
-	if len(byDomain) == 0 {
-		return &DeliveryError{
-			Temporary: false,
-			Message:   "no valid recipients",
+	data := c.signMessage(msg)
+	rp := c.returnPath(msg)
+	for domain, recipients := range byDomain {
+		if rp == nil || !rp.VERP {
+			c.sendToDomain(ctx, msg, data, envelopeSender(msg, rp, ""), domain, recipients)
+			continue
+		}
+		// VERP gives every recipient its own envelope sender, and so its
+		// own transaction
+		for _, rcpt := range recipients {
+			c.sendToDomain(ctx, msg, data, envelopeSender(msg, rp, rcpt), domain, []string{rcpt})
 		}
 	}
 
-	var lastErr error
-	var permanentErr bool
+	return deliveryOutcome(msg)
+}
 
-	for domain, recipients := range byDomain {
-		err := c.sendToDomain(ctx, domain, msg.From, recipients, msg.Data)
-		if err != nil {
-			lastErr = err
-			if de, ok := err.(*DeliveryError); ok && !de.Temporary {
-				permanentErr = true
-			}
+// returnPath returns the return path settings of the sender domain, nil if
+// the sender is the envelope sender. Bounces with the null sender keep it.
+func (c *Client) returnPath(msg *queue.Message) *config.DomainReturnPathConfig {
+	if c.domains == nil || msg.From == "" || msg.From == "<>" {
+		return nil
+	}
+	if dc := c.domains.GetDomainConfig(email.ExtractDomain(msg.From)); dc != nil {
+		return dc.ReturnPath
+	}
+	return nil
+}
+
+// envelopeSender returns the MAIL FROM address of a transaction: the
+// sender, the return path address, or with VERP the return path address
+// encoded with the message ID and the recipient
+func envelopeSender(msg *queue.Message, rp *config.DomainReturnPathConfig, rcpt string) string {
+	switch {
+	case rp == nil:
+		return msg.From
+	case rp.VERP && rcpt != "":
+		return bounce.EncodeVERP(rp.Address, msg.ID, rcpt)
+	default:
+		return rp.Address
+	}
+}
+
+// deliveryOutcome summarizes the recipient results of a message. It returns
+// a temporary error if any recipient was deferred, a permanent error if all
+// undelivered recipients failed, and nil if all recipients were delivered.
+// The temporary error is greylisted if every deferred recipient was.
+func deliveryOutcome(msg *queue.Message) error {
+	var deferred, failed []string
+	greylisted := true
+	for _, rcpt := range msg.UndeliveredRecipients() {
+		r := msg.Results[rcpt]
+		if r != nil && r.Status == queue.StatusFailed {
+			failed = append(failed, rcpt)
+		} else {
+			deferred = append(deferred, rcpt)
+			greylisted = greylisted && r != nil && r.Greylisted
 		}
 	}
 
-	if lastErr != nil {
-		if permanentErr {
-			return &DeliveryError{
-				Temporary: false,
-				Message:   lastErr.Error(),
-			}
+	switch {
+	case len(deferred) > 0:
+		return &DeliveryError{
+			Temporary:  true,
+			Greylisted: greylisted,
+			Message:    recipientErrors(msg, deferred),
 		}
+	case len(failed) > 0:
 		return &DeliveryError{
-			Temporary: true,
-			Message:   lastErr.Error(),
+			Temporary: false,
+			Message:   recipientErrors(msg, failed),
 		}
 	}
-
 	return nil
 }
 
-// sendToDomain sends to all recipients in a single domain
-func (c *Client) sendToDomain(ctx context.Context, domain string, from string, to []string, data []byte) error {
+// recipientErrors describes the error of the first recipient and how many
+// other recipients share the outcome
+func recipientErrors(msg *queue.Message, recipients []string) string {
+	text := recipients[0] + ": "
+	if r := msg.Results[recipients[0]]; r != nil && r.Error != "" {
+		text += r.Error
+	} else {
+		text += "not attempted"
+	}
+	if len(recipients) > 1 {
+		text += fmt.Sprintf(" (and %d more recipients)", len(recipients)-1)
+	}
+	return text
+}
+
+// sendToDomain sends to all recipients in a single domain and records the
+// outcome of each recipient. A recipient fails permanently when its MX host
+// rejects it or the message, or when every MX host rejected the session
+// permanently. Otherwise undelivered recipients are deferred. Domains with
+// an LMTP delivery are sent to their LMTP server instead. from is the
+// envelope sender.
+func (c *Client) sendToDomain(ctx context.Context, msg *queue.Message, data []byte, from, domain string, recipients []string) {
+	if d := c.lmtpDelivery(domain); d != nil {
+		c.sendToLMTP(ctx, msg, data, from, d, recipients)
+		return
+	}
+
+	provider := metrics.Provider(domain, "")
+	defer func() { countResults(msg, provider, recipients) }()
+
 	// Lookup MX records
 	mxRecords, err := c.resolver.LookupMX(ctx, domain)
 	if err != nil {
-		return &DeliveryError{
+		setResults(msg, recipients, "", &DeliveryError{
 			Temporary: true,
 			Message:   fmt.Sprintf("MX lookup failed for %s: %v", domain, err),
+		})
+		return
+	}
+	if len(mxRecords) == 0 {
+		setResults(msg, recipients, "", &DeliveryError{
+			Temporary: true,
+			Message:   fmt.Sprintf("no MX hosts available for %s", domain),
+		})
+		return
+	}
+	primary := mxRecords[0].Host
+	provider = metrics.Provider(domain, primary)
+
+	// Try MX hosts in order of priority, known-dead hosts last, skipping
+	// hosts that used up their attempts for this message
+	candidates := c.withinBudget(msg, c.health.Order(mxRecords), recipients)
+	if len(candidates) == 0 {
+		for _, rcpt := range recipients {
+			msg.SetResult(rcpt, queue.RecipientResult{
+				Status:  queue.StatusFailed,
+				Expired: true,
+				Error:   fmt.Sprintf("delivery budget exhausted: %d attempts to each MX host of %s", c.maxAttemptsPerMX, domain),
+			})
 		}
+		return
 	}
 
-	// Try each MX host in order of priority
-	var lastErr error
-	for _, mx := range mxRecords {
-		err := c.sendToMX(ctx, mx.Host, from, to, data)
-		if err == nil {
-			return nil
+	pending := recipients
+	var lastErr *DeliveryError
+	tried, rejected := 0, 0
+	for len(candidates) > 0 && len(pending) > 0 {
+		sess, idx, failed, err := c.dialMX(ctx, candidates)
+		for i, dialErr := range failed {
+			recordAttempts(msg, pending, candidates[i].Host, dialErr)
+			lastErr = dialErr
+			tried++
+		}
+		if err != nil {
+			c.logger.Warn("no MX host accepted connection",
+				"domain", domain,
+				"error", err,
+			)
+			if lastErr == nil {
+				lastErr = err
+			}
+			break
 		}
 
-		c.logger.Warn("delivery to MX failed",
-			"mx", mx.Host,
-			"domain", domain,
-			"error", err,
+		mx := candidates[idx].Host
+		if mx == primary {
+			metrics.IncMXSelected(metrics.MXPrimary)
+		} else {
+			metrics.IncMXSelected(metrics.MXFallback)
+		}
+
+		txCtx, span := tracing.Start(ctx, "smtp.transaction",
+			attribute.String("smtp.mx", mx),
+			attribute.String("smtp.domain", domain),
+			attribute.Int("smtp.recipients", len(pending)),
 		)
-		lastErr = err
+		start := time.Now()
+		tx := c.sendToMX(txCtx, sess, from, pending, data, needsSMTPUTF8(msg, pending))
+		metrics.ObserveDelivery(provider, time.Since(start))
+		countReplies(provider, tx, len(pending))
+		endTransactionSpan(span, tx)
+		pending = applyTransaction(msg, mx, pending, tx)
+		tried++
+
+		if tx.err != nil {
+			c.logger.Warn("delivery to MX failed",
+				"mx", mx,
+				"domain", domain,
+				"error", tx.err,
+				"greylisted", tx.err.Greylisted,
+			)
+			lastErr = tx.err
+			if !tx.err.Temporary {
+				rejected++
+			}
+		}
+
+		// Hosts before the connected one failed or were slower to connect
+		candidates = candidates[idx+1:]
+	}
 
-		// If permanent error, don't try other MX
-		if de, ok := err.(*DeliveryError); ok && !de.Temporary {
-			return de
+	if len(pending) == 0 {
+		return
+	}
+	if lastErr == nil {
+		lastErr = &DeliveryError{
+			Temporary: true,
+			Message:   fmt.Sprintf("no MX hosts available for %s", domain),
 		}
 	}
 
-	if lastErr != nil {
-		return lastErr
+	// Only fail if every MX host rejected the session permanently
+	if tried > 0 && rejected == tried {
+		setResults(msg, pending, "", &DeliveryError{Temporary: false, Message: lastErr.Message})
+		return
 	}
+	setResults(msg, pending, "", &DeliveryError{Temporary: true, Greylisted: lastErr.Greylisted, Message: lastErr.Message})
+}
 
-	return &DeliveryError{
-		Temporary: true,
-		Message:   fmt.Sprintf("no MX hosts available for %s", domain),
+// withinBudget drops MX hosts that reached the attempt limit for the recipients
+func (c *Client) withinBudget(msg *queue.Message, records []dns.MXRecord, recipients []string) []dns.MXRecord {
+	if c.maxAttemptsPerMX <= 0 {
+		return records
 	}
+	var out []dns.MXRecord
+	for _, mx := range records {
+		if msg.MXAttempts(mx.Host, recipients) < c.maxAttemptsPerMX {
+			out = append(out, mx)
+		}
+	}
+	return out
 }
 
-// sendToMX sends to a specific MX host
-func (c *Client) sendToMX(ctx context.Context, mx string, from string, to []string, data []byte) error {
-	addr := net.JoinHostPort(mx, "25")
+// transaction is the outcome of one SMTP transaction with an MX host
+type transaction struct {
+	rejected map[string]*DeliveryError // Recipients refused at RCPT TO
+	err      *DeliveryError            // Failure that applies to all other recipients
+	final    bool                      // err came after DATA, other MX hosts are not tried
+}
 
-	// Create connection with timeout
-	dialer := &net.Dialer{
-		Timeout: c.timeout,
+// countReplies counts the final replies of a transaction by reply code: the
+// refusals of recipients, the error of the transaction, or its acceptance
+func countReplies(provider string, tx transaction, recipients int) {
+	for _, de := range tx.rejected {
+		if de.Code != "" {
+			metrics.IncSMTPResponses(provider, de.Code)
+		}
 	}
+	switch {
+	case tx.err != nil:
+		if tx.err.Code != "" {
+			metrics.IncSMTPResponses(provider, tx.err.Code)
+		}
+	case len(tx.rejected) < recipients:
+		metrics.IncSMTPResponses(provider, "250")
+	}
+}
 
-	conn, err := dialer.DialContext(ctx, "tcp", addr)
-	if err != nil {
-		return &DeliveryError{
-			Temporary: true,
-			Message:   fmt.Sprintf("connection failed to %s: %v", addr, err),
+// countResults counts the delivery results of recipients after an attempt
+func countResults(msg *queue.Message, provider string, recipients []string) {
+	for _, rcpt := range recipients {
+		result := metrics.DeliveryDeferred
+		if r := msg.Results[rcpt]; r != nil {
+			switch r.Status {
+			case queue.StatusDelivered:
+				result = metrics.DeliveryDelivered
+			case queue.StatusFailed:
+				result = metrics.DeliveryFailed
+			}
 		}
+		metrics.IncDeliveries(provider, result)
 	}
-	defer conn.Close()
+}
 
-	// Set deadline
-	deadline, ok := ctx.Deadline()
-	if ok {
-		conn.SetDeadline(deadline)
-	} else {
-		conn.SetDeadline(time.Now().Add(c.timeout))
+// endTransactionSpan ends the span of an SMTP transaction with its outcome
+func endTransactionSpan(span trace.Span, tx transaction) {
+	span.SetAttributes(attribute.Int("smtp.rejected", len(tx.rejected)))
+	if tx.err != nil {
+		span.SetAttributes(
+			attribute.Bool("smtp.temporary", tx.err.Temporary),
+			attribute.Bool("smtp.greylisted", tx.err.Greylisted),
+		)
+		tracing.Fail(span, tx.err)
 	}
+	span.End()
+}
 
-	// Create SMTP client
-	client, err := smtp.NewClient(conn, mx)
-	if err != nil {
-		return &DeliveryError{
-			Temporary: true,
-			Message:   fmt.Sprintf("SMTP client creation failed: %v", err),
+// applyTransaction records the transaction outcome of each recipient and
+// returns the recipients that may still be tried at another MX host
+func applyTransaction(msg *queue.Message, mx string, recipients []string, tx transaction) []string {
+	now := time.Now()
+	var unresolved []string
+	for _, rcpt := range recipients {
+		attempt := queue.DeliveryAttempt{Timestamp: now, Recipient: rcpt, MXHost: mx}
+		switch de := tx.rejected[rcpt]; {
+		case de != nil:
+			attempt.Error = de.Message
+			attempt.Permanent = !de.Temporary
+			setResults(msg, []string{rcpt}, mx, de)
+		case tx.err == nil:
+			attempt.Success = true
+			msg.SetResult(rcpt, queue.RecipientResult{Status: queue.StatusDelivered, MXHost: mx, UpdatedAt: now})
+		default:
+			attempt.Error = tx.err.Message
+			attempt.Permanent = !tx.err.Temporary
+			if tx.final {
+				setResults(msg, []string{rcpt}, mx, tx.err)
+			} else {
+				unresolved = append(unresolved, rcpt)
+			}
 		}
+		msg.RecordAttempt(attempt)
 	}
-	defer client.Close()
+	return unresolved
+}
 
-	// Send HELO
-	if err := client.Hello(c.hostname); err != nil {
-		return c.categorizeError(err, "HELO")
+// recordAttempts logs a failed attempt of the recipients at an MX host
+func recordAttempts(msg *queue.Message, recipients []string, mx string, de *DeliveryError) {
+	now := time.Now()
+	for _, rcpt := range recipients {
+		msg.RecordAttempt(queue.DeliveryAttempt{
+			Timestamp: now,
+			Recipient: rcpt,
+			MXHost:    mx,
+			Permanent: !de.Temporary,
+			Error:     de.Message,
+		})
+	}
+}
+
+// setResults sets a failed or deferred result for the recipients
+func setResults(msg *queue.Message, recipients []string, mx string, de *DeliveryError) {
+	status := queue.StatusDeferred
+	if !de.Temporary {
+		status = queue.StatusFailed
+	}
+	for _, rcpt := range recipients {
+		msg.SetResult(rcpt, queue.RecipientResult{
+			Status:     status,
+			MXHost:     mx,
+			Error:      de.Message,
+			Greylisted: de.Temporary && de.Greylisted,
+		})
 	}
+}
+
+// dialResult is the outcome of dialing one MX candidate
+type dialResult struct {
+	idx  int
+	sess *mxSession
+	err  error
+}
+
+// dialMX connects to the first MX host that accepts a connection. Hosts are
+// dialed in order; the next host is dialed when the previous one fails or
+// has not connected within the fallback delay, so a dead primary does not
+// hold up delivery for the full connect timeout. Only the connection is
+// raced, the SMTP transaction runs on the returned connection alone. With
+// a connection pool an idle session to a host is used instead of dialing it.
+// Returns the session, the index of its host in records and the errors of
+// hosts that failed to connect by index.
+func (c *Client) dialMX(ctx context.Context, records []dns.MXRecord) (*mxSession, int, map[int]*DeliveryError, *DeliveryError) {
+	results := make(chan dialResult, len(records))
+	started, pending := 0, 0
+	start := func() {
+		i := started
+		started++
+		pending++
+		go func() {
+			sess, err := c.connect(ctx, records[i].Host)
+			results <- dialResult{idx: i, sess: sess, err: err}
+		}()
+	}
+
+	start()
+	timer := time.NewTimer(c.fallbackDelay)
+	defer timer.Stop()
+
+	failed := make(map[int]*DeliveryError)
+	var lastErr *DeliveryError
+	for pending > 0 {
+		select {
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				c.health.MarkAlive(records[r.idx].Host)
+				if pending > 0 {
+					go c.drainDials(ctx, records, results, pending)
+				}
+				return r.sess, r.idx, failed, nil
+			}
+
+			c.dialFailed(ctx, records[r.idx].Host, r.err)
+			de := &DeliveryError{
+				Temporary: true,
+				Message:   fmt.Sprintf("connection failed to %s: %v", net.JoinHostPort(records[r.idx].Host, c.port), r.err),
+			}
+			failed[r.idx] = de
+			lastErr = de
+			if started < len(records) {
+				start()
+				timer.Reset(c.fallbackDelay)
+			}
+
+		case <-timer.C:
+			if started < len(records) {
+				c.logger.Debug("MX slow to connect, dialing next host",
+					"mx", records[started-1].Host,
+					"next", records[started].Host,
+				)
+				start()
+				timer.Reset(c.fallbackDelay)
+			}
 
-	// Try STARTTLS (opportunistic)
-	if ok, _ := client.Extension("STARTTLS"); ok {
-		tlsConfig := &tls.Config{
-			ServerName: mx,
-			MinVersion: tls.VersionTLS12,
+		case <-ctx.Done():
+			go c.drainDials(ctx, records, results, pending)
+			return nil, -1, failed, &DeliveryError{
+				Temporary: true,
+				Message:   fmt.Sprintf("connection aborted: %v", ctx.Err()),
+			}
 		}
-		if err := client.StartTLS(tlsConfig); err != nil {
-			c.logger.Warn("STARTTLS failed, continuing without encryption",
-				"mx", mx,
-				"error", err,
-			)
-		} else {
-			c.logger.Debug("STARTTLS successful", "mx", mx)
+	}
+
+	return nil, -1, failed, lastErr
+}
+
+// drainDials waits for dials that lost the race. Their connections are
+// closed, or returned to the pool if they were taken from it, but their
+// outcome still updates MX health.
+func (c *Client) drainDials(ctx context.Context, records []dns.MXRecord, results <-chan dialResult, pending int) {
+	for ; pending > 0; pending-- {
+		r := <-results
+		if r.err == nil {
+			if r.sess.client == nil || c.pool == nil || !c.pool.put(r.sess) {
+				r.sess.quit()
+			}
+			c.health.MarkAlive(records[r.idx].Host)
+			continue
 		}
+		c.dialFailed(ctx, records[r.idx].Host, r.err)
 	}
+}
+
+// connect returns a session to an MX host, an idle one from the pool if
+// possible or else a new connection. Idle sessions the host has closed in
+// the meantime are dropped.
+func (c *Client) connect(ctx context.Context, host string) (*mxSession, error) {
+	ctx, span := tracing.Start(ctx, "smtp.connect", attribute.String("smtp.mx", host))
+	defer span.End()
 
-	// Sign message with DKIM if signer is configured for this sender
-	messageData := data
-	if signer := c.getDKIMSigner(from); signer != nil {
-		signed, err := signer.Sign(data)
+	sess, err := c.openConnection(ctx, host)
+	if err != nil {
+		tracing.Fail(span, err)
+		return nil, err
+	}
+	span.SetAttributes(attribute.Bool("smtp.reused", sess.client != nil))
+	return sess, nil
+}
+
+// openConnection takes a pooled session with the MX host or dials a new one
+func (c *Client) openConnection(ctx context.Context, host string) (*mxSession, error) {
+	addr := net.JoinHostPort(host, c.port)
+	if c.pool == nil {
+		conn, err := c.dial(ctx, "tcp", addr)
 		if err != nil {
-			c.logger.Warn("DKIM signing failed, sending unsigned",
-				"domain", signer.Domain(),
-				"error", err,
-			)
-		} else {
-			messageData = signed
-			c.logger.Debug("DKIM signed",
-				"domain", signer.Domain(),
-				"selector", signer.Selector(),
-			)
+			return nil, err
 		}
+		return &mxSession{host: host, conn: conn}, nil
 	}
 
-	// Send MAIL FROM
-	if err := client.Mail(from); err != nil {
-		return c.categorizeError(err, "MAIL FROM")
+	for {
+		sess, err := c.pool.acquire(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		if sess == nil {
+			break
+		}
+		if err := sess.reset(c.timeout); err != nil {
+			c.logger.Debug("pooled MX session closed by host", "mx", host, "error", err)
+			sess.Close()
+			continue
+		}
+		metrics.IncSMTPConnectionsReused()
+		return sess, nil
 	}
 
-	// Send RCPT TO for each recipient
-	for _, recipient := range to {
-		if err := client.Rcpt(recipient); err != nil {
-			return c.categorizeError(err, fmt.Sprintf("RCPT TO %s", recipient))
+	conn, err := c.dial(ctx, "tcp", addr)
+	if err != nil {
+		c.pool.release(host)
+		return nil, err
+	}
+	return &mxSession{host: host, conn: conn, pool: c.pool}, nil
+}
+
+// dialFailed marks an MX host dead unless the dial was aborted by the caller
+func (c *Client) dialFailed(ctx context.Context, host string, err error) {
+	if ctx.Err() != nil {
+		return
+	}
+	c.health.MarkDead(host)
+	c.logger.Warn("MX host unreachable, skipping it for a while",
+		"mx", host,
+		"error", err,
+	)
+}
+
+// sendToMX sends over a session with a specific MX host. Recipients refused
+// at RCPT TO are reported individually, the message is sent to the accepted
+// ones with BDAT if the host supports CHUNKING, otherwise with DATA. After a
+// clean transaction the session goes back to the pool, if any, otherwise it
+// is ended with QUIT.
+func (c *Client) sendToMX(ctx context.Context, sess *mxSession, from string, to []string, data []byte, smtputf8 bool) transaction {
+	mx := sess.host
+	clean := false
+	defer func() {
+		if clean && c.pool != nil && c.pool.put(sess) {
+			return
+		}
+		if clean {
+			// Log error but don't fail - message was already accepted
+			if err := sess.quit(); err != nil {
+				c.logger.Warn("QUIT command failed", "error", err, "mx", mx)
+			}
+			return
 		}
+		sess.Close()
+	}()
+
+	// Set deadline
+	deadline, ok := ctx.Deadline()
+	if ok {
+		sess.conn.SetDeadline(deadline)
+	} else {
+		sess.conn.SetDeadline(time.Now().Add(c.timeout))
+	}
+
+	// New connections start with the greeting, EHLO and STARTTLS
+	if sess.client == nil {
+		if de := c.openSession(sess); de != nil {
+			return transaction{err: de}
+		}
+	}
+
+	// Send MAIL FROM and RCPT TO for each recipient
+	tx := c.sendEnvelope(sess, from, to, smtputf8)
+	if tx.err != nil {
+		return tx
+	}
+	if len(tx.rejected) == len(to) {
+		clean = true
+		return tx
 	}
 
-	// Send DATA
-	wc, err := client.Data()
+	if sess.chunking {
+		if err := sess.sendChunks(data); err != nil {
+			if isReply(err) {
+				tx.err = c.categorizeError(err, "BDAT")
+				tx.final = !tx.err.Temporary
+			} else {
+				tx.err = &DeliveryError{
+					Temporary: true,
+					Message:   fmt.Sprintf("failed to write message data: %v", err),
+				}
+			}
+			return tx
+		}
+	} else if tx.err = c.sendData(sess, data); tx.err != nil {
+		tx.final = !tx.err.Temporary
+		return tx
+	}
+	sess.messages++
+	clean = true
+
+	c.logger.Info("message delivered",
+		"mx", mx,
+		"from", from,
+		"to", to,
+		"rejected", len(tx.rejected),
+		"pipelining", sess.pipelining,
+		"chunking", sess.chunking,
+		"session_messages", sess.messages,
+	)
+
+	return tx
+}
+
+// sendData sends the message with DATA
+func (c *Client) sendData(sess *mxSession, data []byte) *DeliveryError {
+	wc, err := sess.client.Data()
 	if err != nil {
 		return c.categorizeError(err, "DATA")
 	}
 
-	_, err = bytes.NewReader(messageData).WriteTo(wc)
+	_, err = bytes.NewReader(data).WriteTo(wc)
 	if err != nil {
 		wc.Close()
+		// Nothing was refused, a write error is never final
 		return &DeliveryError{
 			Temporary: true,
 			Message:   fmt.Sprintf("failed to write message data: %v", err),
@@ -276,19 +807,23 @@ func (c *Client) sendToMX(ctx context.Context, mx string, from string, to []stri
 	if err := wc.Close(); err != nil {
 		return c.categorizeError(err, "DATA close")
 	}
+	return nil
+}
 
-	// Quit (log error but don't fail - message was already accepted)
-	if err := client.Quit(); err != nil {
-		c.logger.Warn("QUIT command failed", "error", err, "mx", mx)
+// needsSMTPUTF8 reports whether sending a message to recipients requires
+// SMTPUTF8: it was submitted with SMTPUTF8 or its envelope has addresses
+// with non-ASCII local parts. Internationalized domains alone are sent in
+// A-label form.
+func needsSMTPUTF8(msg *queue.Message, recipients []string) bool {
+	if msg.SMTPUTF8 || email.IsInternational(msg.From) {
+		return true
 	}
-
-	c.logger.Info("message delivered",
-		"mx", mx,
-		"from", from,
-		"to", to,
-	)
-
-	return nil
+	for _, rcpt := range recipients {
+		if email.IsInternational(rcpt) {
+			return true
+		}
+	}
+	return false
 }
 
 // smtpCodePattern matches SMTP response codes at word boundaries
@@ -307,14 +842,17 @@ func (c *Client) categorizeError(err error, stage string) *DeliveryError {
 		if strings.HasPrefix(code, "5") {
 			return &DeliveryError{
 				Temporary: false,
+				Code:      code,
 				Message:   msg,
 			}
 		}
 		// 4xx codes are temporary errors
 		if strings.HasPrefix(code, "4") {
 			return &DeliveryError{
-				Temporary: true,
-				Message:   msg,
+				Temporary:  true,
+				Greylisted: retry.IsGreylisting(errStr),
+				Code:       code,
+				Message:    msg,
 			}
 		}
 	}
@@ -332,5 +870,20 @@ func IsTemporaryError(err error) bool {
 	if errors.As(err, &de) {
 		return de.Temporary
 	}
+	// Errors of other senders, such as inbound delivery
+	var te interface{ Temporary() bool }
+	if errors.As(err, &te) {
+		return te.Temporary()
+	}
 	return true // Assume temporary if unknown
 }
+
+// IsGreylisted checks if the error is a greylisting reply, which is
+// accepted when the message is retried after a few minutes
+func IsGreylisted(err error) bool {
+	var de *DeliveryError
+	if errors.As(err, &de) {
+		return de.Temporary && de.Greylisted
+	}
+	return false
+}
@@ -1,15 +1,28 @@
 package smtp
 
 import (
+	"context"
 	"errors"
+	"io"
 	"log/slog"
+	"net"
+	"net/textproto"
 	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/foxzi/sendry/internal/config"
 	"github.com/foxzi/sendry/internal/dkim"
 	"github.com/foxzi/sendry/internal/dns"
 	"github.com/foxzi/sendry/internal/email"
+	"github.com/foxzi/sendry/internal/metrics"
+	"github.com/foxzi/sendry/internal/queue"
 )
 
 func TestNewClient(t *testing.T) {
@@ -96,10 +109,11 @@ func TestCategorizeError(t *testing.T) {
 	client := NewClient(resolver, "mail.example.com", 30*time.Second, logger)
 
 	tests := []struct {
-		name          string
-		err           error
-		stage         string
-		wantTemporary bool
+		name           string
+		err            error
+		stage          string
+		wantTemporary  bool
+		wantGreylisted bool
 	}{
 		{
 			name:          "550 user not found",
@@ -143,6 +157,13 @@ func TestCategorizeError(t *testing.T) {
 			stage:         "RCPT TO",
 			wantTemporary: true,
 		},
+		{
+			name:           "451 greylisted",
+			err:            errors.New("451 4.7.1 Greylisted, please try again in 300 seconds"),
+			stage:          "RCPT TO",
+			wantTemporary:  true,
+			wantGreylisted: true,
+		},
 		{
 			name:          "connection timeout",
 			err:           errors.New("i/o timeout"),
@@ -163,6 +184,12 @@ func TestCategorizeError(t *testing.T) {
 			if result.Temporary != tc.wantTemporary {
 				t.Errorf("categorizeError() temporary = %v, want %v", result.Temporary, tc.wantTemporary)
 			}
+			if result.Greylisted != tc.wantGreylisted {
+				t.Errorf("categorizeError() greylisted = %v, want %v", result.Greylisted, tc.wantGreylisted)
+			}
+			if IsGreylisted(result) != tc.wantGreylisted {
+				t.Errorf("IsGreylisted() = %v, want %v", IsGreylisted(result), tc.wantGreylisted)
+			}
 			if result.Message == "" {
 				t.Error("expected non-empty message")
 			}
@@ -224,6 +251,37 @@ func TestGetDKIMSignerPriority(t *testing.T) {
 	}
 }
 
+func TestSignMessage(t *testing.T) {
+	resolver := dns.NewResolver(0)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	client := NewClient(resolver, "mail.example.com", 30*time.Second, logger)
+
+	kp, err := dkim.GenerateKey("example.com", "sendry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetDKIMProvider(&mockDKIMProvider{
+		signers: map[string]*dkim.Signer{"example.com": dkim.NewSigner(kp.PrivateKey, "example.com", "sendry")},
+	})
+
+	msg := &queue.Message{
+		From: "user@example.com",
+		Data: []byte("From: user@example.com\r\nSubject: Test\r\n\r\nBody\r\n"),
+	}
+	if got := client.signMessage(msg); dkim.SignatureHeader(got) == "" {
+		t.Error("message not signed at delivery")
+	}
+
+	// Messages signed at enqueue time or flagged to skip are sent as queued
+	for _, flag := range []*bool{&msg.DKIMSigned, &msg.SkipDKIM} {
+		*flag = true
+		if got := client.signMessage(msg); string(got) != string(msg.Data) {
+			t.Errorf("message with flags signed = %v, skip = %v was signed", msg.DKIMSigned, msg.SkipDKIM)
+		}
+		*flag = false
+	}
+}
+
 func TestExtractIP(t *testing.T) {
 	tests := []struct {
 		addr     string
@@ -245,3 +303,426 @@ func TestExtractIP(t *testing.T) {
 		})
 	}
 }
+
+// fakeMX is a scripted SMTP server
+type fakeMX struct {
+	greeting string            // Greeting reply, 220 if empty
+	rcpt     map[string]string // RCPT TO reply by recipient, 250 if missing
+	data     string            // Reply to the message, 250 if empty
+	ext      []string          // EHLO keywords
+	batched  *atomic.Bool      // Set when RCPT TO arrived together with MAIL FROM
+	mail     *atomic.Value     // Last MAIL FROM command
+	chunks   *atomic.Int32     // Number of BDAT chunks received
+	body     *atomic.Value     // Message content received with BDAT
+}
+
+func (f fakeMX) run(conn net.Conn) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+
+	greeting := f.greeting
+	if greeting == "" {
+		greeting = "220 mx.example.com ESMTP"
+	}
+	tp.PrintfLine("%s", greeting)
+	if !strings.HasPrefix(greeting, "2") {
+		return
+	}
+
+	var body []byte
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			lines := append([]string{"mx.example.com"}, f.ext...)
+			for i, line := range lines {
+				sep := "-"
+				if i == len(lines)-1 {
+					sep = " "
+				}
+				tp.PrintfLine("250%s%s", sep, line)
+			}
+		case strings.HasPrefix(cmd, "MAIL FROM"):
+			if f.batched != nil && tp.R.Buffered() > 0 {
+				f.batched.Store(true)
+			}
+			if f.mail != nil {
+				f.mail.Store(line)
+			}
+			tp.PrintfLine("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO"):
+			addr := strings.Trim(line[len("RCPT TO:"):], "<> ")
+			reply, ok := f.rcpt[addr]
+			if !ok {
+				reply = "250 OK"
+			}
+			tp.PrintfLine("%s", reply)
+		case cmd == "DATA":
+			tp.PrintfLine("354 Go ahead")
+			tp.ReadDotLines()
+			reply := f.data
+			if reply == "" {
+				reply = "250 Queued"
+			}
+			tp.PrintfLine("%s", reply)
+		case strings.HasPrefix(cmd, "BDAT "):
+			fields := strings.Fields(cmd)
+			size, _ := strconv.Atoi(fields[1])
+			chunk := make([]byte, size)
+			if _, err := io.ReadFull(tp.R, chunk); err != nil {
+				return
+			}
+			body = append(body, chunk...)
+			if f.chunks != nil {
+				f.chunks.Add(1)
+			}
+			if len(fields) < 3 || fields[2] != "LAST" {
+				tp.PrintfLine("250 %d octets received", size)
+				continue
+			}
+			if f.body != nil {
+				f.body.Store(string(body))
+			}
+			body = nil
+			reply := f.data
+			if reply == "" {
+				reply = "250 Queued"
+			}
+			tp.PrintfLine("%s", reply)
+		case cmd == "RSET", cmd == "NOOP":
+			tp.PrintfLine("250 OK")
+		case cmd == "QUIT":
+			tp.PrintfLine("221 Bye")
+			return
+		default:
+			tp.PrintfLine("502 Not implemented")
+		}
+	}
+}
+
+// staticResolver returns the same MX records for every domain
+type staticResolver []dns.MXRecord
+
+func (r staticResolver) LookupMX(ctx context.Context, domain string) ([]dns.MXRecord, error) {
+	return r, nil
+}
+
+func newDeliveryClient(d *fakeDialer) *Client {
+	c := newFailoverClient(d, time.Hour)
+	c.resolver = staticResolver(failoverRecords)
+	return c
+}
+
+func newDeliveryMessage(to ...string) *queue.Message {
+	return &queue.Message{
+		ID:   "msg-1",
+		From: "sender@example.org",
+		To:   to,
+		Data: []byte("Subject: Test\r\n\r\nHello\r\n"),
+	}
+}
+
+func TestSendPerRecipientResults(t *testing.T) {
+	d := &fakeDialer{serve: map[string]fakeMX{
+		"mx1.example.com": {rcpt: map[string]string{
+			"bob@example.com":   "550 5.1.1 User unknown",
+			"carol@example.com": "452 4.2.2 Mailbox full",
+		}},
+	}}
+	c := newDeliveryClient(d)
+	msg := newDeliveryMessage("alice@example.com", "bob@example.com", "carol@example.com")
+
+	err := c.Send(context.Background(), msg)
+	if err == nil || !IsTemporaryError(err) {
+		t.Fatalf("Send() error = %v, want temporary error for the deferred recipient", err)
+	}
+
+	want := map[string]queue.MessageStatus{
+		"alice@example.com": queue.StatusDelivered,
+		"bob@example.com":   queue.StatusFailed,
+		"carol@example.com": queue.StatusDeferred,
+	}
+	for rcpt, status := range want {
+		r := msg.Results[rcpt]
+		if r == nil || r.Status != status || r.MXHost != "mx1.example.com" {
+			t.Errorf("result of %s = %+v, want %s at mx1", rcpt, r, status)
+		}
+	}
+	if len(msg.Attempts) != 3 {
+		t.Errorf("recorded %d attempts, want 3", len(msg.Attempts))
+	}
+
+	// A retry only sends to the deferred recipient
+	d.serve["mx1.example.com"] = fakeMX{}
+	if err := c.Send(context.Background(), msg); err == nil || IsTemporaryError(err) {
+		t.Fatalf("Send() retry error = %v, want permanent error for the failed recipient", err)
+	}
+	if r := msg.Results["carol@example.com"]; r.Status != queue.StatusDelivered {
+		t.Errorf("carol after retry = %+v, want delivered", r)
+	}
+	if len(msg.Attempts) != 4 {
+		t.Errorf("recorded %d attempts, want 4", len(msg.Attempts))
+	}
+	if got := msg.UndeliveredRecipients(); len(got) != 1 || got[0] != "bob@example.com" {
+		t.Errorf("UndeliveredRecipients() = %v, want bob", got)
+	}
+}
+
+func TestSendDeliveryMetrics(t *testing.T) {
+	m := metrics.New()
+	metrics.SetGlobal(m)
+	defer metrics.SetGlobal(nil)
+
+	d := &fakeDialer{serve: map[string]fakeMX{
+		"mx1.example.com": {rcpt: map[string]string{
+			"bob@example.com":   "550 5.1.1 User unknown",
+			"carol@example.com": "452 4.2.2 Mailbox full",
+		}},
+	}}
+	c := newDeliveryClient(d)
+	msg := newDeliveryMessage("alice@example.com", "bob@example.com", "carol@example.com")
+	c.Send(context.Background(), msg)
+
+	value := func(vec interface {
+		GetMetricWithLabelValues(...string) (prometheus.Counter, error)
+	}, labels ...string) float64 {
+		counter, err := vec.GetMetricWithLabelValues(labels...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var metric dto.Metric
+		counter.Write(&metric)
+		return metric.Counter.GetValue()
+	}
+
+	for result, want := range map[string]float64{
+		metrics.DeliveryDelivered: 1,
+		metrics.DeliveryFailed:    1,
+		metrics.DeliveryDeferred:  1,
+	} {
+		if got := value(m.DeliveriesTotal, metrics.ProviderOther, result); got != want {
+			t.Errorf("deliveries %s = %v, want %v", result, got, want)
+		}
+	}
+	for _, code := range []string{"250", "550", "452"} {
+		if got := value(m.SMTPResponsesTotal, metrics.ProviderOther, code); got != 1 {
+			t.Errorf("responses %s = %v, want 1", code, got)
+		}
+	}
+
+	var metric dto.Metric
+	m.DeliveryDurationSeconds.WithLabelValues(metrics.ProviderOther).(prometheus.Histogram).Write(&metric)
+	if metric.Histogram.GetSampleCount() != 1 {
+		t.Errorf("delivery duration samples = %d, want 1", metric.Histogram.GetSampleCount())
+	}
+}
+
+func TestSendAllMXRejectPermanently(t *testing.T) {
+	d := &fakeDialer{serve: map[string]fakeMX{
+		"mx1.example.com": {greeting: "554 5.7.1 No service"},
+		"mx2.example.com": {greeting: "554 5.7.1 No service"},
+	}}
+	c := newDeliveryClient(d)
+	msg := newDeliveryMessage("alice@example.com")
+
+	err := c.Send(context.Background(), msg)
+	if err == nil || IsTemporaryError(err) {
+		t.Fatalf("Send() error = %v, want permanent error", err)
+	}
+	if r := msg.Results["alice@example.com"]; r.Status != queue.StatusFailed {
+		t.Errorf("result = %+v, want failed", r)
+	}
+	if len(msg.Attempts) != 2 {
+		t.Errorf("recorded %d attempts, want one per MX", len(msg.Attempts))
+	}
+}
+
+func TestSendSomeMXTemporarilyFailed(t *testing.T) {
+	d := &fakeDialer{
+		refuse: map[string]bool{"mx2.example.com": true},
+		serve: map[string]fakeMX{
+			"mx1.example.com": {greeting: "554 5.7.1 No service"},
+		},
+	}
+	c := newDeliveryClient(d)
+	msg := newDeliveryMessage("alice@example.com")
+
+	err := c.Send(context.Background(), msg)
+	if err == nil || !IsTemporaryError(err) {
+		t.Fatalf("Send() error = %v, want temporary error", err)
+	}
+	if r := msg.Results["alice@example.com"]; r.Status != queue.StatusDeferred {
+		t.Errorf("result = %+v, want deferred", r)
+	}
+}
+
+func TestSendDataRejectedIsFinal(t *testing.T) {
+	d := &fakeDialer{serve: map[string]fakeMX{
+		"mx1.example.com": {data: "554 5.6.0 Message rejected"},
+		"mx2.example.com": {},
+	}}
+	c := newDeliveryClient(d)
+	msg := newDeliveryMessage("alice@example.com")
+
+	if err := c.Send(context.Background(), msg); err == nil || IsTemporaryError(err) {
+		t.Fatalf("Send() error = %v, want permanent error", err)
+	}
+	if hosts := d.dialedHosts(); len(hosts) != 1 {
+		t.Errorf("dialed %v, want no fallback after the message was rejected", hosts)
+	}
+}
+
+func TestSendAttemptBudget(t *testing.T) {
+	d := &fakeDialer{refuse: map[string]bool{"mx1.example.com": true, "mx2.example.com": true}}
+	c := newDeliveryClient(d)
+	c.SetMaxAttemptsPerMX(2)
+	msg := newDeliveryMessage("alice@example.com")
+
+	for i := 0; i < 2; i++ {
+		c.MXHealth().MarkAlive("mx1.example.com")
+		c.MXHealth().MarkAlive("mx2.example.com")
+		if err := c.Send(context.Background(), msg); !IsTemporaryError(err) {
+			t.Fatalf("attempt %d error = %v, want temporary", i+1, err)
+		}
+	}
+
+	err := c.Send(context.Background(), msg)
+	if err == nil || IsTemporaryError(err) {
+		t.Fatalf("Send() error = %v, want permanent error after the budget", err)
+	}
+	r := msg.Results["alice@example.com"]
+	if r.Status != queue.StatusFailed || !r.Expired {
+		t.Errorf("result = %+v, want expired failure", r)
+	}
+	if got := msg.MXAttempts("mx1.example.com", msg.To); got != 2 {
+		t.Errorf("MXAttempts(mx1) = %d, want 2", got)
+	}
+}
+
+func TestSendChunking(t *testing.T) {
+	var chunks atomic.Int32
+	var body atomic.Value
+	d := &fakeDialer{serve: map[string]fakeMX{
+		"mx1.example.com": {ext: []string{"CHUNKING", "PIPELINING"}, chunks: &chunks, body: &body},
+	}}
+	c := newDeliveryClient(d)
+
+	msg := newDeliveryMessage("alice@example.com")
+	content := strings.Repeat("line\n", bdatChunkSize/5+10)
+	msg.Data = []byte("Subject: Large\n\n" + content)
+
+	if err := c.Send(context.Background(), msg); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if got := chunks.Load(); got != 2 {
+		t.Errorf("sent %d BDAT chunks, want 2", got)
+	}
+	want := strings.ReplaceAll(string(msg.Data), "\n", "\r\n")
+	if got, _ := body.Load().(string); got != want {
+		t.Errorf("received %d bytes, want the message with CRLF line endings (%d bytes)", len(got), len(want))
+	}
+}
+
+func TestSendChunkingRejected(t *testing.T) {
+	d := &fakeDialer{serve: map[string]fakeMX{
+		"mx1.example.com": {ext: []string{"CHUNKING"}, data: "554 5.6.0 Message rejected"},
+		"mx2.example.com": {ext: []string{"CHUNKING"}},
+	}}
+	c := newDeliveryClient(d)
+	msg := newDeliveryMessage("alice@example.com")
+
+	if err := c.Send(context.Background(), msg); err == nil || IsTemporaryError(err) {
+		t.Fatalf("Send() error = %v, want permanent error", err)
+	}
+	if hosts := d.dialedHosts(); len(hosts) != 1 {
+		t.Errorf("dialed %v, want no fallback after the message was rejected", hosts)
+	}
+}
+
+func TestSendSMTPUTF8(t *testing.T) {
+	for _, pipelining := range []bool{false, true} {
+		ext := []string{"SMTPUTF8"}
+		if pipelining {
+			ext = append(ext, "PIPELINING")
+		}
+		var mail atomic.Value
+		d := &fakeDialer{serve: map[string]fakeMX{
+			"mx1.example.com": {ext: ext, mail: &mail},
+		}}
+		c := newDeliveryClient(d)
+
+		msg := newDeliveryMessage("пользователь@example.com")
+		if err := c.Send(context.Background(), msg); err != nil {
+			t.Fatalf("pipelining=%t: Send() error = %v", pipelining, err)
+		}
+		if got, _ := mail.Load().(string); !strings.HasSuffix(got, " SMTPUTF8") {
+			t.Errorf("pipelining=%t: MAIL FROM = %q, want the SMTPUTF8 parameter", pipelining, got)
+		}
+	}
+
+	// Hosts without SMTPUTF8 cannot take the message
+	d := &fakeDialer{serve: map[string]fakeMX{
+		"mx1.example.com": {},
+		"mx2.example.com": {ext: []string{"PIPELINING"}},
+	}}
+	c := newDeliveryClient(d)
+	msg := newDeliveryMessage("alice@example.com")
+	msg.SMTPUTF8 = true
+
+	err := c.Send(context.Background(), msg)
+	if err == nil || IsTemporaryError(err) {
+		t.Fatalf("Send() error = %v, want permanent error", err)
+	}
+	if r := msg.Results["alice@example.com"]; r == nil || !strings.Contains(r.Error, "SMTPUTF8") {
+		t.Errorf("result = %+v, want an SMTPUTF8 error", r)
+	}
+}
+
+func TestSendIDNDomain(t *testing.T) {
+	// Without SMTPUTF8 the host only takes the A-label form of the domain
+	d := &fakeDialer{serve: map[string]fakeMX{
+		"mx1.example.com": {rcpt: map[string]string{"user@пример.рф": "553 5.6.7 Not ASCII"}},
+	}}
+	c := newDeliveryClient(d)
+	msg := newDeliveryMessage("user@пример.рф")
+
+	if err := c.Send(context.Background(), msg); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if r := msg.Results["user@пример.рф"]; r == nil || r.Status != queue.StatusDelivered {
+		t.Errorf("result = %+v, want delivered", r)
+	}
+}
+
+func TestSendReturnPath(t *testing.T) {
+	tests := []struct {
+		name string
+		rp   *config.DomainReturnPathConfig
+		want string
+	}{
+		{"sender", nil, "MAIL FROM:<sender@example.org>"},
+		{"fixed", &config.DomainReturnPathConfig{Address: "bounce@bounces.example.org"}, "MAIL FROM:<bounce@bounces.example.org>"},
+		{"verp", &config.DomainReturnPathConfig{Address: "bounce@bounces.example.org", VERP: true}, "MAIL FROM:<bounce+msg-1=alice=example.com@bounces.example.org>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mail atomic.Value
+			d := &fakeDialer{serve: map[string]fakeMX{
+				"mx1.example.com": {mail: &mail},
+			}}
+			c := newDeliveryClient(d)
+			c.SetDomainConfigs(lmtpDomains{"example.org": {ReturnPath: tt.rp}})
+
+			if err := c.Send(context.Background(), newDeliveryMessage("alice@example.com")); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+			if got, _ := mail.Load().(string); !strings.HasPrefix(got, tt.want) {
+				t.Errorf("MAIL FROM = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
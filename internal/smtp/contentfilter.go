@@ -0,0 +1,42 @@
+package smtp
+
+import (
+	"context"
+
+	"github.com/emersion/go-smtp"
+
+	"github.com/foxzi/sendry/internal/contentfilter"
+)
+
+// filterContent passes a received message to the content filter. It
+// returns the data to queue, nil if the filter discarded the message, or
+// the SMTP error of a refusal.
+func (s *Session) filterContent(ctx context.Context, env *contentfilter.Envelope, data []byte) ([]byte, error) {
+	v := s.backend.filter.Check(ctx, env, data)
+	switch v.Action {
+	case contentfilter.ActionModify:
+		return v.Data, nil
+	case contentfilter.ActionDiscard:
+		return nil, nil
+	case contentfilter.ActionReject:
+		return nil, filterError(v, 550, smtp.EnhancedCode{5, 7, 1}, "Message rejected by content filter")
+	case contentfilter.ActionTempfail:
+		return nil, filterError(v, 451, smtp.EnhancedCode{4, 7, 1}, "Message deferred by content filter")
+	}
+	return data, nil
+}
+
+// filterError returns the SMTP error of a refusal. The reply of the filter
+// is used if its code is of the class of the default code.
+func filterError(v *contentfilter.Verdict, code int, enhanced smtp.EnhancedCode, message string) *smtp.SMTPError {
+	if v.Code/100 == code/100 {
+		code = v.Code
+		if v.EnhancedCode[0] == code/100 {
+			enhanced = smtp.EnhancedCode(v.EnhancedCode)
+		}
+	}
+	if v.Message != "" {
+		message = v.Message
+	}
+	return &smtp.SMTPError{Code: code, EnhancedCode: enhanced, Message: message}
+}
@@ -0,0 +1,27 @@
+package smtp
+
+import (
+	"errors"
+
+	"github.com/emersion/go-smtp"
+
+	"github.com/foxzi/sendry/internal/contentpolicy"
+)
+
+// policyError returns the SMTP error of a message refused by the content
+// policy
+func policyError(err error) *smtp.SMTPError {
+	var v *contentpolicy.Violation
+	if !errors.As(err, &v) {
+		return &smtp.SMTPError{Code: 451, EnhancedCode: smtp.EnhancedCode{4, 3, 0}, Message: "Content policy check failed"}
+	}
+
+	e := &smtp.SMTPError{Code: 550, EnhancedCode: smtp.EnhancedCode{5, 7, 1}, Message: "Message refused by content policy: " + v.Message}
+	switch v.Reason {
+	case contentpolicy.ReasonRecipients:
+		e.EnhancedCode = smtp.EnhancedCode{5, 5, 3}
+	case contentpolicy.ReasonAttachmentSize:
+		e.Code, e.EnhancedCode = 552, smtp.EnhancedCode{5, 3, 4}
+	}
+	return e
+}
@@ -0,0 +1,54 @@
+package smtp
+
+import (
+	"context"
+	"net"
+
+	"github.com/emersion/go-smtp"
+
+	"github.com/foxzi/sendry/internal/mailauth"
+	"github.com/foxzi/sendry/internal/metrics"
+)
+
+// verifyInbound checks SPF, DKIM and DMARC of a received message and
+// prepends the Authentication-Results header. In reject mode a message
+// failing DMARC of a domain with a reject policy is refused, one with a
+// quarantine policy is accepted and reported as quarantined.
+func (s *Session) verifyInbound(ctx context.Context, ip net.IP, helo string, data []byte) ([]byte, bool, error) {
+	res := s.backend.verifier.Verify(ctx, ip, helo, s.from, data)
+
+	metrics.IncInboundAuth("spf", string(res.SPF))
+	for _, d := range res.DKIM {
+		metrics.IncInboundAuth("dkim", string(d.Value))
+	}
+	if len(res.DKIM) == 0 {
+		metrics.IncInboundAuth("dkim", "none")
+	}
+	metrics.IncInboundAuth("dmarc", string(res.DMARC))
+
+	s.logger.Info("inbound authentication",
+		"from", s.from,
+		"header_from", res.FromDomain,
+		"spf", res.SPF,
+		"dkim", len(res.DKIM),
+		"dmarc", res.DMARC,
+		"policy", res.Policy,
+	)
+
+	quarantined := false
+	if s.backend.inboundAuth == mailauth.ModeReject {
+		if res.Reject() {
+			s.logger.Warn("message rejected by DMARC policy", "from", s.from, "header_from", res.FromDomain)
+			return nil, false, &smtp.SMTPError{
+				Code:         550,
+				EnhancedCode: smtp.EnhancedCode{5, 7, 1},
+				Message:      "Message rejected due to DMARC policy of " + res.FromDomain,
+			}
+		}
+		quarantined = res.Quarantine()
+	}
+
+	out := make([]byte, 0, len(data)+256)
+	out = append(out, res.Header()...)
+	return append(out, data...), quarantined, nil
+}
@@ -0,0 +1,46 @@
+package smtp
+
+import (
+	"errors"
+	"time"
+
+	"github.com/emersion/go-smtp"
+
+	"github.com/foxzi/sendry/internal/ipguard"
+)
+
+// throttle delays the reply to a tarpitted client and counts the command
+// against the command rate of its IP
+func (s *Session) throttle() error {
+	guard := s.backend.guard
+	if d := guard.Delay(s.ip); d > 0 {
+		s.logger.Debug("tarpitting client", "delay", d)
+		time.Sleep(d)
+	}
+
+	err := guard.Command(s.ip)
+	if errors.Is(err, ipguard.ErrBanned) {
+		return &smtp.SMTPError{Code: 421, EnhancedCode: smtp.EnhancedCode{4, 7, 1}, Message: "Too many relay attempts, try again later"}
+	}
+	if err != nil {
+		s.logger.Warn("client throttled", "reason", err)
+		return &smtp.SMTPError{Code: 421, EnhancedCode: smtp.EnhancedCode{4, 7, 0}, Message: "Too many commands, slow down"}
+	}
+	return nil
+}
+
+// recordRejection counts a refused command of the client, slowing down
+// clients that keep getting refused
+func (s *Session) recordRejection(err *error) {
+	if *err != nil {
+		s.backend.guard.Reject(s.ip)
+	}
+}
+
+// recordRelayAttempt counts a refused relay attempt of the client, banning
+// clients that keep trying
+func (s *Session) recordRelayAttempt() {
+	if s.backend.guard.RelayAttempt(s.ip) {
+		s.logger.Warn("client banned for relay attempts")
+	}
+}
@@ -0,0 +1,262 @@
+package smtp
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/textproto"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/foxzi/sendry/internal/config"
+	"github.com/foxzi/sendry/internal/email"
+	"github.com/foxzi/sendry/internal/queue"
+)
+
+// lmtpSession is an LMTP session (RFC 2033) with a local delivery agent such
+// as Dovecot. Unlike SMTP the server replies to the message once for every
+// accepted recipient.
+type lmtpSession struct {
+	conn net.Conn
+	text *textproto.Conn
+	ext  map[string]bool
+}
+
+// cmd sends a command and reads its reply
+func (s *lmtpSession) cmd(expectCode int, format string, args ...any) error {
+	id, err := s.text.Cmd(format, args...)
+	if err != nil {
+		return err
+	}
+	s.text.StartResponse(id)
+	defer s.text.EndResponse(id)
+	_, _, err = s.text.ReadResponse(expectCode)
+	return err
+}
+
+// lhlo says LHLO and reads the extensions of the server
+func (s *lmtpSession) lhlo(hostname string) error {
+	id, err := s.text.Cmd("LHLO %s", hostname)
+	if err != nil {
+		return err
+	}
+	s.text.StartResponse(id)
+	defer s.text.EndResponse(id)
+	_, msg, err := s.text.ReadResponse(250)
+	if err != nil {
+		return err
+	}
+
+	s.ext = make(map[string]bool)
+	lines := strings.Split(msg, "\n")
+	for _, line := range lines[1:] {
+		if keyword, _, _ := strings.Cut(line, " "); keyword != "" {
+			s.ext[strings.ToUpper(keyword)] = true
+		}
+	}
+	return nil
+}
+
+// startTLS upgrades the session with STARTTLS and says LHLO again
+func (s *lmtpSession) startTLS(hostname string, tlsConfig *tls.Config) error {
+	if err := s.cmd(220, "STARTTLS"); err != nil {
+		return err
+	}
+	conn := tls.Client(s.conn, tlsConfig)
+	if err := conn.Handshake(); err != nil {
+		return err
+	}
+	s.conn = conn
+	s.text = textproto.NewConn(conn)
+	return s.lhlo(hostname)
+}
+
+// lmtpTLSConfig returns the TLS configuration of an LMTP delivery
+func lmtpTLSConfig(d *config.DomainDeliveryConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         d.TLS.ServerName,
+		InsecureSkipVerify: d.TLS.InsecureSkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+	if tlsConfig.ServerName == "" {
+		if network, address := d.Network(); network == "tcp" {
+			tlsConfig.ServerName, _, _ = net.SplitHostPort(address)
+		} else {
+			tlsConfig.ServerName = "localhost"
+		}
+	}
+	if d.TLS.CAFile != "" {
+		pem, err := os.ReadFile(d.TLS.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in CA file %s", d.TLS.CAFile)
+		}
+	}
+	return tlsConfig, nil
+}
+
+// lmtpDelivery returns the LMTP delivery of a recipient domain, nil if the
+// domain is delivered to its MX hosts
+func (c *Client) lmtpDelivery(domain string) *config.DomainDeliveryConfig {
+	if c.domains == nil {
+		return nil
+	}
+	if dc := c.domains.GetDomainConfig(domain); dc != nil && dc.Delivery.IsLMTP() {
+		return dc.Delivery
+	}
+	return nil
+}
+
+// sendToLMTP delivers to the recipients of a domain over LMTP with the
+// envelope sender from and records the outcome of each recipient
+func (c *Client) sendToLMTP(ctx context.Context, msg *queue.Message, data []byte, from string, d *config.DomainDeliveryConfig, recipients []string) {
+	host := "lmtp:" + d.Address
+	tx := c.deliverLMTP(ctx, d, from, recipients, data, needsSMTPUTF8(msg, recipients))
+	if pending := applyTransaction(msg, host, recipients, tx); len(pending) > 0 {
+		setResults(msg, pending, host, tx.err)
+	}
+
+	if tx.err != nil {
+		c.logger.Warn("delivery to LMTP failed",
+			"lmtp", d.Address,
+			"error", tx.err,
+		)
+		return
+	}
+	c.logger.Info("message delivered",
+		"lmtp", d.Address,
+		"from", from,
+		"to", recipients,
+		"rejected", len(tx.rejected),
+	)
+}
+
+// deliverLMTP runs one LMTP transaction. Recipients refused at RCPT TO or
+// after the message are reported individually.
+func (c *Client) deliverLMTP(ctx context.Context, d *config.DomainDeliveryConfig, from string, to []string, data []byte, smtputf8 bool) transaction {
+	tx := transaction{rejected: make(map[string]*DeliveryError)}
+
+	network, address := d.Network()
+	conn, err := c.dial(ctx, network, address)
+	if err != nil {
+		return transaction{err: &DeliveryError{
+			Temporary: true,
+			Message:   fmt.Sprintf("connection to LMTP %s failed: %v", d.Address, err),
+		}}
+	}
+	s := &lmtpSession{conn: conn, text: textproto.NewConn(conn)}
+	defer func() { s.conn.Close() }()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	} else {
+		conn.SetDeadline(time.Now().Add(c.timeout))
+	}
+
+	if _, _, err := s.text.ReadResponse(220); err != nil {
+		return transaction{err: c.categorizeError(err, "greeting")}
+	}
+	if err := s.lhlo(c.hostname); err != nil {
+		return transaction{err: c.categorizeError(err, "LHLO")}
+	}
+
+	if d.TLS != nil && d.TLS.Enabled {
+		if !s.ext["STARTTLS"] {
+			return transaction{err: &DeliveryError{
+				Temporary: true,
+				Message:   fmt.Sprintf("LMTP %s does not support STARTTLS", d.Address),
+			}}
+		}
+		tlsConfig, err := lmtpTLSConfig(d)
+		if err != nil {
+			return transaction{err: &DeliveryError{Temporary: true, Message: err.Error()}}
+		}
+		if err := s.startTLS(c.hostname, tlsConfig); err != nil {
+			return transaction{err: c.categorizeError(err, "STARTTLS")}
+		}
+	}
+
+	if smtputf8 && !s.ext["SMTPUTF8"] {
+		return transaction{err: &DeliveryError{
+			Message: "MAIL FROM failed: host does not support SMTPUTF8, required by the internationalized addresses of the message",
+		}}
+	}
+
+	for _, addr := range append([]string{from}, to...) {
+		if strings.ContainsAny(addr, "\r\n") {
+			return transaction{err: &DeliveryError{Message: "MAIL FROM failed: address contains a line break"}}
+		}
+	}
+
+	mail := "MAIL FROM:<" + email.ASCIIAddress(from) + ">"
+	if s.ext["8BITMIME"] {
+		mail += " BODY=8BITMIME"
+	}
+	if smtputf8 {
+		mail += " SMTPUTF8"
+	}
+	if err := s.cmd(250, "%s", mail); err != nil {
+		return transaction{err: c.categorizeError(err, "MAIL FROM")}
+	}
+
+	var accepted []string
+	for _, recipient := range to {
+		if err := s.cmd(25, "RCPT TO:<%s>", email.ASCIIAddress(recipient)); err != nil {
+			tx.rejected[recipient] = c.categorizeError(err, fmt.Sprintf("RCPT TO %s", recipient))
+			continue
+		}
+		accepted = append(accepted, recipient)
+	}
+	if len(accepted) == 0 {
+		s.cmd(221, "QUIT")
+		return tx
+	}
+
+	if err := s.cmd(354, "DATA"); err != nil {
+		tx.err = c.categorizeError(err, "DATA")
+		tx.final = !tx.err.Temporary
+		return tx
+	}
+	w := s.text.DotWriter()
+	if _, err := w.Write(data); err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		// Nothing was refused, a write error is never final
+		tx.err = &DeliveryError{
+			Temporary: true,
+			Message:   fmt.Sprintf("failed to write message data: %v", err),
+		}
+		return tx
+	}
+
+	// One reply per accepted recipient, in RCPT TO order
+	for i, recipient := range accepted {
+		_, _, err := s.text.ReadResponse(250)
+		if err == nil {
+			continue
+		}
+		var protoErr *textproto.Error
+		if !errors.As(err, &protoErr) {
+			// The outcome of the remaining recipients is unknown
+			for _, rest := range accepted[i:] {
+				tx.rejected[rest] = &DeliveryError{
+					Temporary: true,
+					Message:   fmt.Sprintf("failed to read LMTP reply: %v", err),
+				}
+			}
+			return tx
+		}
+		tx.rejected[recipient] = c.categorizeError(err, fmt.Sprintf("DATA %s", recipient))
+	}
+
+	s.cmd(221, "QUIT")
+	return tx
+}
@@ -0,0 +1,173 @@
+package smtp
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/foxzi/sendry/internal/config"
+	"github.com/foxzi/sendry/internal/dns"
+	"github.com/foxzi/sendry/internal/queue"
+)
+
+// lmtpDomains provides fixed domain configs
+type lmtpDomains map[string]config.DomainConfig
+
+func (d lmtpDomains) GetDomainConfig(domain string) *config.DomainConfig {
+	if dc, ok := d[domain]; ok {
+		return &dc
+	}
+	return nil
+}
+
+// fakeLMTP is a scripted LMTP server
+type fakeLMTP struct {
+	ext   []string          // LHLO keywords
+	rcpt  map[string]string // RCPT TO reply by recipient, 250 if missing
+	data  map[string]string // Reply to the message by recipient, 250 if missing
+	hello *atomic.Value     // First command of the session
+}
+
+func (f fakeLMTP) run(conn net.Conn) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 lmtp.example.com LMTP ready")
+
+	var accepted []string
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(cmd, "LHLO"):
+			if f.hello != nil {
+				f.hello.CompareAndSwap(nil, line)
+			}
+			lines := append([]string{"lmtp.example.com"}, f.ext...)
+			for i, line := range lines {
+				sep := "-"
+				if i == len(lines)-1 {
+					sep = " "
+				}
+				tp.PrintfLine("250%s%s", sep, line)
+			}
+		case strings.HasPrefix(cmd, "MAIL FROM"):
+			accepted = nil
+			tp.PrintfLine("250 2.1.0 OK")
+		case strings.HasPrefix(cmd, "RCPT TO"):
+			addr := strings.Trim(line[len("RCPT TO:"):], "<> ")
+			reply, ok := f.rcpt[addr]
+			if !ok {
+				reply = "250 2.1.5 OK"
+				accepted = append(accepted, addr)
+			}
+			tp.PrintfLine("%s", reply)
+		case cmd == "DATA":
+			tp.PrintfLine("354 Go ahead")
+			tp.ReadDotLines()
+			for _, addr := range accepted {
+				reply, ok := f.data[addr]
+				if !ok {
+					reply = "250 2.0.0 <" + addr + "> Saved"
+				}
+				tp.PrintfLine("%s", reply)
+			}
+		case cmd == "QUIT":
+			tp.PrintfLine("221 Bye")
+			return
+		default:
+			tp.PrintfLine("502 Not implemented")
+		}
+	}
+}
+
+// serveLMTP runs a fake LMTP server on a unix socket and returns its path
+func serveLMTP(t *testing.T, f fakeLMTP) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "lmtp.sock")
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Skipf("unix sockets not available: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.run(conn)
+		}
+	}()
+	return path
+}
+
+func newLMTPClient(delivery *config.DomainDeliveryConfig) *Client {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	c := NewClient(dns.NewResolver(0), "mail.example.com", time.Second, logger)
+	c.SetDomainConfigs(lmtpDomains{"example.com": {Delivery: delivery}})
+	return c
+}
+
+func TestSendLMTP(t *testing.T) {
+	hello := &atomic.Value{}
+	path := serveLMTP(t, fakeLMTP{
+		ext:   []string{"PIPELINING", "8BITMIME"},
+		rcpt:  map[string]string{"bob@example.com": "550 5.1.1 User unknown"},
+		data:  map[string]string{"carol@example.com": "452 4.2.2 Mailbox full"},
+		hello: hello,
+	})
+	c := newLMTPClient(&config.DomainDeliveryConfig{Protocol: config.DeliveryProtocolLMTP, Address: path})
+	msg := newDeliveryMessage("alice@example.com", "bob@example.com", "carol@example.com")
+
+	err := c.Send(context.Background(), msg)
+	if err == nil || !IsTemporaryError(err) {
+		t.Fatalf("Send() error = %v, want temporary error for the deferred recipient", err)
+	}
+	if got, _ := hello.Load().(string); got != "LHLO mail.example.com" {
+		t.Errorf("session started with %q, want LHLO", got)
+	}
+
+	// Every recipient gets its own outcome, also after the message
+	want := map[string]queue.MessageStatus{
+		"alice@example.com": queue.StatusDelivered,
+		"bob@example.com":   queue.StatusFailed,
+		"carol@example.com": queue.StatusDeferred,
+	}
+	for rcpt, status := range want {
+		r := msg.Results[rcpt]
+		if r == nil || r.Status != status || r.MXHost != "lmtp:"+path {
+			t.Errorf("result of %s = %+v, want %s at lmtp:%s", rcpt, r, status, path)
+		}
+	}
+	if len(msg.Attempts) != 3 {
+		t.Errorf("recorded %d attempts, want 3", len(msg.Attempts))
+	}
+}
+
+func TestSendLMTPRequiresTLS(t *testing.T) {
+	path := serveLMTP(t, fakeLMTP{})
+	c := newLMTPClient(&config.DomainDeliveryConfig{
+		Protocol: config.DeliveryProtocolLMTP,
+		Address:  "unix:" + path,
+		TLS:      &config.DeliveryTLSConfig{Enabled: true},
+	})
+	msg := newDeliveryMessage("alice@example.com")
+
+	err := c.Send(context.Background(), msg)
+	if err == nil || !IsTemporaryError(err) || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Fatalf("Send() error = %v, want temporary STARTTLS error", err)
+	}
+	if r := msg.Results["alice@example.com"]; r == nil || r.Status != queue.StatusDeferred {
+		t.Errorf("result = %+v, want deferred", r)
+	}
+}
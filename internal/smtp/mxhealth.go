@@ -0,0 +1,105 @@
+package smtp
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/foxzi/sendry/internal/dns"
+	"github.com/foxzi/sendry/internal/metrics"
+)
+
+// DefaultMXDeadTTL is how long an unreachable MX host is skipped
+const DefaultMXDeadTTL = 5 * time.Minute
+
+// MXHealth tracks MX hosts that recently refused or timed out connections.
+// Dead hosts are moved behind healthy ones until their entry expires, so
+// deliveries do not wait for a known-dead primary MX to time out.
+type MXHealth struct {
+	mu   sync.Mutex
+	dead map[string]time.Time // host -> dead until
+	ttl  time.Duration
+	now  func() time.Time
+}
+
+// NewMXHealth creates an MX health tracker. A non-positive ttl uses
+// DefaultMXDeadTTL.
+func NewMXHealth(ttl time.Duration) *MXHealth {
+	if ttl <= 0 {
+		ttl = DefaultMXDeadTTL
+	}
+	return &MXHealth{
+		dead: make(map[string]time.Time),
+		ttl:  ttl,
+		now:  time.Now,
+	}
+}
+
+// MarkDead records a failed connection to an MX host
+func (h *MXHealth) MarkDead(host string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.dead[strings.ToLower(host)] = h.now().Add(h.ttl)
+	metrics.SetMXDeadHosts(len(h.dead))
+}
+
+// MarkAlive clears the dead state of an MX host
+func (h *MXHealth) MarkAlive(host string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	host = strings.ToLower(host)
+	if _, ok := h.dead[host]; ok {
+		delete(h.dead, host)
+		metrics.SetMXDeadHosts(len(h.dead))
+	}
+}
+
+// IsDead reports whether the host failed within the dead TTL
+func (h *MXHealth) IsDead(host string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.isDead(strings.ToLower(host))
+}
+
+func (h *MXHealth) isDead(host string) bool {
+	until, ok := h.dead[host]
+	if !ok {
+		return false
+	}
+	if h.now().Before(until) {
+		return true
+	}
+	delete(h.dead, host)
+	metrics.SetMXDeadHosts(len(h.dead))
+	return false
+}
+
+// DeadHosts returns the number of hosts currently marked dead
+func (h *MXHealth) DeadHosts() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for host := range h.dead {
+		h.isDead(host)
+	}
+	return len(h.dead)
+}
+
+// Order returns the MX records with healthy hosts first, keeping the
+// priority order within healthy and dead hosts. Dead hosts stay at the
+// end as a last resort.
+func (h *MXHealth) Order(records []dns.MXRecord) []dns.MXRecord {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	healthy := make([]dns.MXRecord, 0, len(records))
+	var dead []dns.MXRecord
+	for _, mx := range records {
+		if h.isDead(strings.ToLower(mx.Host)) {
+			dead = append(dead, mx)
+			metrics.IncMXSkipped()
+			continue
+		}
+		healthy = append(healthy, mx)
+	}
+	return append(healthy, dead...)
+}
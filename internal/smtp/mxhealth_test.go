@@ -0,0 +1,218 @@
+package smtp
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/foxzi/sendry/internal/dns"
+)
+
+func TestMXHealthOrder(t *testing.T) {
+	h := NewMXHealth(time.Minute)
+	records := []dns.MXRecord{
+		{Host: "mx1.example.com", Priority: 10},
+		{Host: "mx2.example.com", Priority: 20},
+		{Host: "mx3.example.com", Priority: 30},
+	}
+
+	h.MarkDead("MX1.example.com")
+
+	got := h.Order(records)
+	want := []string{"mx2.example.com", "mx3.example.com", "mx1.example.com"}
+	for i, mx := range got {
+		if mx.Host != want[i] {
+			t.Fatalf("Order() = %v, want %v", got, want)
+		}
+	}
+	if h.DeadHosts() != 1 {
+		t.Errorf("DeadHosts() = %d, want 1", h.DeadHosts())
+	}
+
+	h.MarkAlive("mx1.example.com")
+	if got := h.Order(records); got[0].Host != "mx1.example.com" {
+		t.Errorf("Order() after MarkAlive = %v, want mx1 first", got)
+	}
+}
+
+func TestMXHealthExpiry(t *testing.T) {
+	h := NewMXHealth(time.Minute)
+	now := time.Now()
+	h.now = func() time.Time { return now }
+
+	h.MarkDead("mx1.example.com")
+	if !h.IsDead("mx1.example.com") {
+		t.Fatal("host should be dead")
+	}
+
+	now = now.Add(2 * time.Minute)
+	if h.IsDead("mx1.example.com") {
+		t.Error("host should be alive after the dead TTL")
+	}
+	if h.DeadHosts() != 0 {
+		t.Errorf("DeadHosts() = %d, want 0", h.DeadHosts())
+	}
+}
+
+// fakeDialer simulates MX hosts that accept, refuse or hang
+type fakeDialer struct {
+	mu      sync.Mutex
+	refuse  map[string]bool
+	hang    map[string]chan struct{} // closed to fail the hanging dial
+	serve   map[string]fakeMX        // scripted SMTP servers by host
+	dialed  []string
+	servers []net.Conn
+}
+
+func (d *fakeDialer) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, _, _ := net.SplitHostPort(addr)
+
+	d.mu.Lock()
+	d.dialed = append(d.dialed, host)
+	release := d.hang[host]
+	d.mu.Unlock()
+
+	if release != nil {
+		select {
+		case <-release:
+			return nil, errors.New("i/o timeout")
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if d.refuse[host] {
+		return nil, errors.New("connection refused")
+	}
+
+	client, server := net.Pipe()
+	d.mu.Lock()
+	d.servers = append(d.servers, server)
+	d.mu.Unlock()
+	if mx, ok := d.serve[host]; ok {
+		go mx.run(server)
+	}
+	return client, nil
+}
+
+func (d *fakeDialer) dialedHosts() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.dialed...)
+}
+
+func newFailoverClient(d *fakeDialer, fallbackDelay time.Duration) *Client {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	c := NewClient(dns.NewResolver(0), "mail.example.com", time.Second, logger)
+	c.dial = d.dial
+	c.SetMXFallbackDelay(fallbackDelay)
+	return c
+}
+
+var failoverRecords = []dns.MXRecord{
+	{Host: "mx1.example.com", Priority: 10},
+	{Host: "mx2.example.com", Priority: 20},
+}
+
+func TestDialMXRefusedPrimary(t *testing.T) {
+	d := &fakeDialer{refuse: map[string]bool{"mx1.example.com": true}}
+	c := newFailoverClient(d, time.Hour)
+
+	start := time.Now()
+	conn, idx, _, err := c.dialMX(context.Background(), failoverRecords)
+	if err != nil {
+		t.Fatalf("dialMX() error = %v", err)
+	}
+	conn.Close()
+
+	if idx != 1 {
+		t.Errorf("dialMX() connected to index %d, want 1", idx)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("refused primary should fail over without waiting for the fallback delay")
+	}
+	if !c.MXHealth().IsDead("mx1.example.com") {
+		t.Error("refused primary should be marked dead")
+	}
+}
+
+func TestDialMXSlowPrimary(t *testing.T) {
+	release := make(chan struct{})
+	d := &fakeDialer{hang: map[string]chan struct{}{"mx1.example.com": release}}
+	c := newFailoverClient(d, 20*time.Millisecond)
+
+	conn, idx, _, err := c.dialMX(context.Background(), failoverRecords)
+	if err != nil {
+		t.Fatalf("dialMX() error = %v", err)
+	}
+	conn.Close()
+
+	if idx != 1 {
+		t.Errorf("dialMX() connected to index %d, want 1", idx)
+	}
+
+	// The primary dial keeps running and marks the host dead when it times out
+	close(release)
+	deadline := time.Now().Add(time.Second)
+	for !c.MXHealth().IsDead("mx1.example.com") {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out primary should be marked dead")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestDialMXHealthyPrimary(t *testing.T) {
+	d := &fakeDialer{}
+	c := newFailoverClient(d, time.Hour)
+
+	conn, idx, _, err := c.dialMX(context.Background(), failoverRecords)
+	if err != nil {
+		t.Fatalf("dialMX() error = %v", err)
+	}
+	conn.Close()
+
+	if idx != 0 {
+		t.Errorf("dialMX() connected to index %d, want 0", idx)
+	}
+	if hosts := d.dialedHosts(); len(hosts) != 1 {
+		t.Errorf("dialed %v, want only the primary", hosts)
+	}
+}
+
+func TestDialMXAllDead(t *testing.T) {
+	d := &fakeDialer{refuse: map[string]bool{"mx1.example.com": true, "mx2.example.com": true}}
+	c := newFailoverClient(d, time.Hour)
+
+	_, _, _, err := c.dialMX(context.Background(), failoverRecords)
+	if err == nil {
+		t.Fatal("dialMX() expected error")
+	}
+	if !IsTemporaryError(err) {
+		t.Error("connection failures should be temporary")
+	}
+	if c.MXHealth().DeadHosts() != 2 {
+		t.Errorf("DeadHosts() = %d, want 2", c.MXHealth().DeadHosts())
+	}
+}
+
+func TestDialMXCancelled(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	d := &fakeDialer{hang: map[string]chan struct{}{"mx1.example.com": release, "mx2.example.com": release}}
+	c := newFailoverClient(d, time.Hour)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if _, _, _, err := c.dialMX(ctx, failoverRecords); err == nil {
+		t.Fatal("dialMX() expected error")
+	}
+	if c.MXHealth().IsDead("mx1.example.com") {
+		t.Error("aborted dial should not mark the host dead")
+	}
+}
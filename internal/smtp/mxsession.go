@@ -0,0 +1,232 @@
+package smtp
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/foxzi/sendry/internal/email"
+	"github.com/foxzi/sendry/internal/metrics"
+)
+
+// quitTimeout bounds how long closing an idle session may take
+const quitTimeout = 5 * time.Second
+
+// mxSession is an SMTP session with an MX host. Sessions of a pooled client
+// are returned to the pool after a message and reused by the next one.
+type mxSession struct {
+	host       string
+	conn       net.Conn
+	client     *smtp.Client // Nil until the session is opened
+	pool       *ConnPool    // Nil if connections are not pooled
+	pipelining bool         // The host advertised PIPELINING (RFC 2920)
+	chunking   bool         // The host advertised CHUNKING (RFC 3030)
+	messages   int          // Messages sent in this session
+	idleSince  time.Time    // When the session was returned to the pool
+}
+
+// Close closes the connection and gives back its pool slot
+func (s *mxSession) Close() error {
+	var err error
+	if s.client != nil {
+		err = s.client.Close()
+	} else {
+		err = s.conn.Close()
+	}
+	if s.pool != nil {
+		s.pool.release(s.host)
+	}
+	return err
+}
+
+// quit ends the session with QUIT and closes it
+func (s *mxSession) quit() error {
+	var err error
+	if s.client != nil {
+		s.conn.SetDeadline(time.Now().Add(quitTimeout))
+		err = s.client.Quit()
+	}
+	s.Close()
+	return err
+}
+
+// reset makes sure an idle session is still usable and clears any state
+// left by the previous message
+func (s *mxSession) reset(timeout time.Duration) error {
+	s.conn.SetDeadline(time.Now().Add(timeout))
+	return s.client.Reset()
+}
+
+// openSession reads the greeting, says EHLO and starts TLS if offered
+func (c *Client) openSession(s *mxSession) *DeliveryError {
+	client, err := smtp.NewClient(s.conn, s.host)
+	if err != nil {
+		return c.categorizeError(err, "greeting")
+	}
+	s.client = client
+
+	// Send HELO
+	if err := client.Hello(c.hostname); err != nil {
+		return c.categorizeError(err, "HELO")
+	}
+
+	// Try STARTTLS (opportunistic)
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		tlsConfig := &tls.Config{
+			ServerName: s.host,
+			MinVersion: tls.VersionTLS12,
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			metrics.IncTLSHandshakeFailures(metrics.Provider("", s.host))
+			c.logger.Warn("STARTTLS failed, continuing without encryption",
+				"mx", s.host,
+				"error", err,
+			)
+		} else {
+			c.logger.Debug("STARTTLS successful", "mx", s.host)
+		}
+	}
+
+	s.pipelining, _ = client.Extension("PIPELINING")
+	s.chunking, _ = client.Extension("CHUNKING")
+	return nil
+}
+
+// sendEnvelope sends MAIL FROM and RCPT TO. Recipients refused at RCPT TO
+// are reported individually. If the host supports PIPELINING all commands
+// are written at once and the replies are read afterwards, saving a round
+// trip per recipient. A message that needs SMTPUTF8 fails permanently at a
+// host without it (RFC 6531).
+func (c *Client) sendEnvelope(s *mxSession, from string, to []string, smtputf8 bool) transaction {
+	tx := transaction{rejected: make(map[string]*DeliveryError)}
+
+	if ok, _ := s.client.Extension("SMTPUTF8"); smtputf8 && !ok {
+		return transaction{err: &DeliveryError{
+			Message: "MAIL FROM failed: host does not support SMTPUTF8, required by the internationalized addresses of the message",
+		}}
+	}
+
+	// Internationalized domains are sent in A-label form, results stay
+	// keyed by the addresses of the message
+	from = email.ASCIIAddress(from)
+	wire := make([]string, len(to))
+	for i, recipient := range to {
+		wire[i] = email.ASCIIAddress(recipient)
+	}
+
+	if !s.pipelining {
+		if err := s.client.Mail(from); err != nil {
+			return transaction{err: c.categorizeError(err, "MAIL FROM")}
+		}
+		for i, recipient := range to {
+			if err := s.client.Rcpt(wire[i]); err != nil {
+				tx.rejected[recipient] = c.categorizeError(err, fmt.Sprintf("RCPT TO %s", recipient))
+			}
+		}
+		return tx
+	}
+
+	for _, addr := range append([]string{from}, wire...) {
+		if strings.ContainsAny(addr, "\r\n") {
+			return transaction{err: &DeliveryError{Message: "MAIL FROM failed: address contains a line break"}}
+		}
+	}
+
+	mail := "MAIL FROM:<" + from + ">"
+	if ok, _ := s.client.Extension("8BITMIME"); ok {
+		mail += " BODY=8BITMIME"
+	}
+	if ok, _ := s.client.Extension("SMTPUTF8"); ok {
+		mail += " SMTPUTF8"
+	}
+
+	w := s.client.Text.W
+	w.WriteString(mail + "\r\n")
+	for _, recipient := range wire {
+		w.WriteString("RCPT TO:<" + recipient + ">\r\n")
+	}
+	if err := w.Flush(); err != nil {
+		return transaction{err: c.categorizeError(err, "MAIL FROM")}
+	}
+
+	// Every command gets a reply, also when MAIL FROM was refused
+	_, _, mailErr := s.client.Text.ReadResponse(250)
+	if mailErr != nil && !isReply(mailErr) {
+		return transaction{err: c.categorizeError(mailErr, "MAIL FROM")}
+	}
+	for _, recipient := range to {
+		_, _, err := s.client.Text.ReadResponse(25)
+		if err != nil && !isReply(err) {
+			return transaction{err: c.categorizeError(err, fmt.Sprintf("RCPT TO %s", recipient))}
+		}
+		if mailErr == nil && err != nil {
+			tx.rejected[recipient] = c.categorizeError(err, fmt.Sprintf("RCPT TO %s", recipient))
+		}
+	}
+	if mailErr != nil {
+		return transaction{err: c.categorizeError(mailErr, "MAIL FROM")}
+	}
+	return tx
+}
+
+// bdatChunkSize is the largest BDAT chunk sent to an MX host
+const bdatChunkSize = 1 << 20
+
+// sendChunks sends the message with BDAT (RFC 3030), waiting for the reply
+// to each chunk. The content is sent as is, without dot-stuffing, so bare
+// line feeds are turned into CRLF as DATA does.
+func (s *mxSession) sendChunks(data []byte) error {
+	data = toCRLF(data)
+	w := s.client.Text.W
+	for {
+		n := min(len(data), bdatChunkSize)
+		last := n == len(data)
+
+		cmd := "BDAT " + strconv.Itoa(n)
+		if last {
+			cmd += " LAST"
+		}
+		w.WriteString(cmd + "\r\n")
+		w.Write(data[:n])
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		if _, _, err := s.client.Text.ReadResponse(250); err != nil {
+			return err
+		}
+
+		data = data[n:]
+		if last {
+			return nil
+		}
+	}
+}
+
+// toCRLF turns bare line feeds into CRLF
+func toCRLF(data []byte) []byte {
+	bare := bytes.Count(data, []byte("\n")) - bytes.Count(data, []byte("\r\n"))
+	if bare == 0 {
+		return data
+	}
+	out := make([]byte, 0, len(data)+bare)
+	for i, b := range data {
+		if b == '\n' && (i == 0 || data[i-1] != '\r') {
+			out = append(out, '\r')
+		}
+		out = append(out, b)
+	}
+	return out
+}
+
+// isReply reports whether err is an SMTP reply rather than a connection error
+func isReply(err error) bool {
+	var reply *textproto.Error
+	return errors.As(err, &reply)
+}
@@ -0,0 +1,199 @@
+package smtp
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Defaults of the outbound connection pool
+const (
+	DefaultPoolIdleTimeout = 30 * time.Second
+	DefaultPoolMaxMessages = 100
+)
+
+// ConnPool keeps SMTP sessions to MX hosts open after a message was sent
+// so later messages to the same host skip the connect, EHLO and STARTTLS
+// round trips. It also limits how many connections are open to one host;
+// a host at its limit is treated like a host that is slow to connect.
+type ConnPool struct {
+	mu          sync.Mutex
+	hosts       map[string]*poolHost
+	maxPerHost  int           // Open connections per MX host (0 = unlimited)
+	idleTimeout time.Duration // Idle sessions are closed after this long
+	maxMessages int           // Messages per session before it is closed (0 = unlimited)
+	closed      bool
+	stopCh      chan struct{}
+}
+
+// poolHost holds the connections of one MX host
+type poolHost struct {
+	open int           // Open connections, idle or in use
+	idle []*mxSession  // Idle sessions, most recently used last
+	wake chan struct{} // Closed when a connection is released or returned
+}
+
+// PoolStats describes the connections of one MX host
+type PoolStats struct {
+	Open int
+	Idle int
+}
+
+// NewConnPool creates a connection pool and starts closing idle sessions
+func NewConnPool(maxPerHost int, idleTimeout time.Duration, maxMessages int) *ConnPool {
+	if idleTimeout <= 0 {
+		idleTimeout = DefaultPoolIdleTimeout
+	}
+	p := &ConnPool{
+		hosts:       make(map[string]*poolHost),
+		maxPerHost:  maxPerHost,
+		idleTimeout: idleTimeout,
+		maxMessages: maxMessages,
+		stopCh:      make(chan struct{}),
+	}
+	go p.reapLoop()
+	return p
+}
+
+// host returns the state of an MX host. Called with the lock held.
+func (p *ConnPool) host(name string) *poolHost {
+	h, ok := p.hosts[name]
+	if !ok {
+		h = &poolHost{wake: make(chan struct{})}
+		p.hosts[name] = h
+	}
+	return h
+}
+
+// signal wakes up callers waiting for a connection. Called with the lock held.
+func (h *poolHost) signal() {
+	close(h.wake)
+	h.wake = make(chan struct{})
+}
+
+// acquire returns an idle session to host, or nil if the caller may open a
+// new connection. In the latter case the caller owns a connection slot and
+// must give it back with release or put. acquire waits while the host is at
+// its connection limit.
+func (p *ConnPool) acquire(ctx context.Context, host string) (*mxSession, error) {
+	for {
+		p.mu.Lock()
+		h := p.host(host)
+		if n := len(h.idle); n > 0 {
+			s := h.idle[n-1]
+			h.idle = h.idle[:n-1]
+			p.mu.Unlock()
+			return s, nil
+		}
+		if p.maxPerHost <= 0 || h.open < p.maxPerHost {
+			h.open++
+			p.mu.Unlock()
+			return nil, nil
+		}
+		wake := h.wake
+		p.mu.Unlock()
+
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// release gives back the connection slot of a closed or failed connection
+func (p *ConnPool) release(host string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	h := p.host(host)
+	if h.open > 0 {
+		h.open--
+	}
+	h.signal()
+	if h.open == 0 && len(h.idle) == 0 {
+		delete(p.hosts, host)
+	}
+}
+
+// put returns a session for reuse. It returns false if the session has to
+// be closed instead because the pool is closed or the session sent its
+// maximum number of messages; the slot stays taken until release.
+func (p *ConnPool) put(s *mxSession) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed || (p.maxMessages > 0 && s.messages >= p.maxMessages) {
+		return false
+	}
+	s.idleSince = time.Now()
+	h := p.host(s.host)
+	h.idle = append(h.idle, s)
+	h.signal()
+	return true
+}
+
+// Stats returns the open and idle connections by MX host
+func (p *ConnPool) Stats() map[string]PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := make(map[string]PoolStats, len(p.hosts))
+	for name, h := range p.hosts {
+		stats[name] = PoolStats{Open: h.open, Idle: len(h.idle)}
+	}
+	return stats
+}
+
+// Close closes all idle sessions. Sessions in use are closed when their
+// message is sent.
+func (p *ConnPool) Close() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	close(p.stopCh)
+	var idle []*mxSession
+	for _, h := range p.hosts {
+		idle = append(idle, h.idle...)
+		h.idle = nil
+	}
+	p.mu.Unlock()
+
+	for _, s := range idle {
+		s.quit()
+	}
+}
+
+func (p *ConnPool) reapLoop() {
+	ticker := time.NewTicker(p.idleTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stopCh:
+			return
+		case <-ticker.C:
+			p.reap(time.Now())
+		}
+	}
+}
+
+// reap closes sessions idle for longer than the idle timeout
+func (p *ConnPool) reap(now time.Time) {
+	p.mu.Lock()
+	var expired []*mxSession
+	for _, h := range p.hosts {
+		// Idle sessions are ordered by last use
+		n := 0
+		for n < len(h.idle) && now.Sub(h.idle[n].idleSince) >= p.idleTimeout {
+			n++
+		}
+		expired = append(expired, h.idle[:n]...)
+		h.idle = append([]*mxSession(nil), h.idle[n:]...)
+	}
+	p.mu.Unlock()
+
+	for _, s := range expired {
+		s.quit()
+	}
+}
@@ -0,0 +1,183 @@
+package smtp
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/foxzi/sendry/internal/queue"
+)
+
+func TestSendPipelining(t *testing.T) {
+	var batched atomic.Bool
+	d := &fakeDialer{serve: map[string]fakeMX{
+		"mx1.example.com": {
+			ext:     []string{"PIPELINING", "8BITMIME"},
+			batched: &batched,
+			rcpt:    map[string]string{"bob@example.com": "550 5.1.1 User unknown"},
+		},
+	}}
+	c := newDeliveryClient(d)
+	msg := newDeliveryMessage("alice@example.com", "bob@example.com", "carol@example.com")
+
+	if err := c.Send(context.Background(), msg); err == nil || IsTemporaryError(err) {
+		t.Fatalf("Send() error = %v, want permanent error for the rejected recipient", err)
+	}
+	if !batched.Load() {
+		t.Error("RCPT TO was not pipelined with MAIL FROM")
+	}
+
+	want := map[string]queue.MessageStatus{
+		"alice@example.com": queue.StatusDelivered,
+		"bob@example.com":   queue.StatusFailed,
+		"carol@example.com": queue.StatusDelivered,
+	}
+	for rcpt, status := range want {
+		if r := msg.Results[rcpt]; r == nil || r.Status != status {
+			t.Errorf("result of %s = %+v, want %s", rcpt, r, status)
+		}
+	}
+}
+
+func TestSendPipeliningMailRejected(t *testing.T) {
+	d := &fakeDialer{serve: map[string]fakeMX{
+		"mx1.example.com": {ext: []string{"PIPELINING"}},
+		"mx2.example.com": {ext: []string{"PIPELINING"}},
+	}}
+	c := newDeliveryClient(d)
+	msg := newDeliveryMessage("alice@example.com")
+	msg.From = "bad\r\naddress@example.org"
+
+	if err := c.Send(context.Background(), msg); err == nil || IsTemporaryError(err) {
+		t.Fatalf("Send() error = %v, want permanent error", err)
+	}
+}
+
+func TestConnPoolReuse(t *testing.T) {
+	d := &fakeDialer{serve: map[string]fakeMX{
+		"mx1.example.com": {ext: []string{"PIPELINING"}},
+	}}
+	c := newDeliveryClient(d)
+	pool := NewConnPool(2, time.Minute, 0)
+	c.SetConnPool(pool)
+
+	for i := 0; i < 3; i++ {
+		if err := c.Send(context.Background(), newDeliveryMessage("alice@example.com")); err != nil {
+			t.Fatalf("Send() %d error = %v", i+1, err)
+		}
+	}
+	if hosts := d.dialedHosts(); len(hosts) != 1 {
+		t.Errorf("dialed %v, want one connection for all messages", hosts)
+	}
+	if got := pool.Stats()["mx1.example.com"]; got.Open != 1 || got.Idle != 1 {
+		t.Errorf("Stats() = %+v, want one idle connection", got)
+	}
+
+	pool.Close()
+	if got := pool.Stats(); len(got) != 0 {
+		t.Errorf("Stats() after Close() = %+v", got)
+	}
+}
+
+func TestConnPoolStaleSession(t *testing.T) {
+	d := &fakeDialer{serve: map[string]fakeMX{"mx1.example.com": {}}}
+	c := newDeliveryClient(d)
+	pool := NewConnPool(0, time.Minute, 0)
+	defer pool.Close()
+	c.SetConnPool(pool)
+
+	if err := c.Send(context.Background(), newDeliveryMessage("alice@example.com")); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	// The host drops the idle connection
+	d.mu.Lock()
+	d.servers[0].Close()
+	d.mu.Unlock()
+
+	msg := newDeliveryMessage("alice@example.com")
+	if err := c.Send(context.Background(), msg); err != nil {
+		t.Fatalf("Send() over a new connection error = %v", err)
+	}
+	if hosts := d.dialedHosts(); len(hosts) != 2 {
+		t.Errorf("dialed %v, want a new connection after the stale one", hosts)
+	}
+	if len(msg.Attempts) != 1 {
+		t.Errorf("recorded %d attempts, want the stale session not to count", len(msg.Attempts))
+	}
+}
+
+func TestConnPoolMaxMessages(t *testing.T) {
+	d := &fakeDialer{serve: map[string]fakeMX{"mx1.example.com": {}}}
+	c := newDeliveryClient(d)
+	pool := NewConnPool(0, time.Minute, 2)
+	defer pool.Close()
+	c.SetConnPool(pool)
+
+	for i := 0; i < 3; i++ {
+		if err := c.Send(context.Background(), newDeliveryMessage("alice@example.com")); err != nil {
+			t.Fatalf("Send() %d error = %v", i+1, err)
+		}
+	}
+	if hosts := d.dialedHosts(); len(hosts) != 2 {
+		t.Errorf("dialed %v, want a new connection after two messages", hosts)
+	}
+}
+
+func TestConnPoolLimit(t *testing.T) {
+	pool := NewConnPool(1, time.Minute, 0)
+	defer pool.Close()
+
+	if s, err := pool.acquire(context.Background(), "mx1.example.com"); s != nil || err != nil {
+		t.Fatalf("acquire() = %v, %v, want a free slot", s, err)
+	}
+
+	// The host is at its limit until the slot is released
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := pool.acquire(ctx, "mx1.example.com"); err == nil {
+		t.Fatal("acquire() over the limit did not wait")
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := pool.acquire(context.Background(), "mx1.example.com")
+		done <- err
+	}()
+	pool.release("mx1.example.com")
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("acquire() after release error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("acquire() not woken by release")
+	}
+
+	// Other hosts have their own limit
+	if s, err := pool.acquire(context.Background(), "mx2.example.com"); s != nil || err != nil {
+		t.Errorf("acquire(mx2) = %v, %v", s, err)
+	}
+}
+
+func TestConnPoolReap(t *testing.T) {
+	d := &fakeDialer{serve: map[string]fakeMX{"mx1.example.com": {}}}
+	c := newDeliveryClient(d)
+	pool := NewConnPool(0, time.Minute, 0)
+	defer pool.Close()
+	c.SetConnPool(pool)
+
+	if err := c.Send(context.Background(), newDeliveryMessage("alice@example.com")); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	pool.reap(time.Now())
+	if got := pool.Stats()["mx1.example.com"]; got.Idle != 1 {
+		t.Errorf("Stats() = %+v, want the recent session kept", got)
+	}
+	pool.reap(time.Now().Add(time.Minute))
+	if got := pool.Stats(); len(got) != 0 {
+		t.Errorf("Stats() after idle timeout = %+v", got)
+	}
+}
@@ -4,13 +4,22 @@ import (
 	"context"
 	"crypto/tls"
 	"log/slog"
+	"net"
 
 	"github.com/emersion/go-smtp"
 
+	"github.com/foxzi/sendry/internal/addrpolicy"
 	"github.com/foxzi/sendry/internal/config"
+	"github.com/foxzi/sendry/internal/contentfilter"
+	"github.com/foxzi/sendry/internal/contentpolicy"
+	"github.com/foxzi/sendry/internal/inbound"
 	"github.com/foxzi/sendry/internal/ipfilter"
+	"github.com/foxzi/sendry/internal/ipguard"
+	"github.com/foxzi/sendry/internal/mailauth"
+	"github.com/foxzi/sendry/internal/proxyproto"
 	"github.com/foxzi/sendry/internal/queue"
 	"github.com/foxzi/sendry/internal/ratelimit"
+	"github.com/foxzi/sendry/internal/smtpauth"
 )
 
 // Server wraps go-smtp server with configuration
@@ -20,6 +29,9 @@ type Server struct {
 	addr      string
 	tlsConfig *tls.Config
 	implicit  bool // true for SMTPS (implicit TLS on port 465)
+	caps      *capabilities
+	guard     *ipguard.Guard
+	proxy     *config.SMTPProxyProtocolConfig // PROXY protocol from trusted proxies, nil when off
 	logger    *slog.Logger
 }
 
@@ -29,12 +41,19 @@ type ServerOptions struct {
 	Queue          queue.Queue
 	Logger         *slog.Logger
 	TLSConfig      *tls.Config
-	Implicit       bool     // true for SMTPS (implicit TLS)
+	Implicit       bool // true for SMTPS (implicit TLS)
 	Addr           string
 	RateLimiter    *ratelimit.Limiter
-	ServerType     string   // smtp, submission, smtps - for metrics
-	AllowedDomains []string // Domains allowed for sending (anti-relay protection)
-	AllowedIPs     []string // IPs/CIDRs allowed to connect
+	ServerType     string          // smtp, submission, smtps - for metrics
+	AllowedDomains []string        // Domains allowed for sending (anti-relay protection)
+	AllowedIPs     []string        // IPs/CIDRs allowed to connect
+	InboundRouter  *inbound.Router // Accepts mail for inbound routes without relay checks
+	ContentFilter  *contentfilter.Checker
+	ContentPolicy  *contentpolicy.Enforcer
+	AddressPolicy  *addrpolicy.Enforcer
+	Authenticator  *smtpauth.Authenticator // Verifies AUTH credentials, smtp.auth.users when nil
+	Certificates   *smtpauth.Certificates  // Authenticates verified client certificates, TLSConfig must request them
+	IPGuard        *ipguard.Guard          // Throttles and bans client IPs, shared by the listeners
 }
 
 // NewServer creates a new SMTP server
@@ -62,6 +81,32 @@ func NewServerWithOptions(opts ServerOptions) *Server {
 		opts.Logger.Info("SMTP IP filtering enabled", "allowed_networks", filter.Count())
 	}
 
+	if opts.InboundRouter != nil {
+		backend.SetInboundRouter(opts.InboundRouter)
+		if mode := opts.Config.InboundAuth; mode == mailauth.ModeTag || mode == mailauth.ModeReject {
+			backend.SetInboundAuth(mailauth.NewVerifier(nil, opts.Config.Domain), mode)
+		}
+	}
+
+	if opts.ContentFilter != nil {
+		backend.SetContentFilter(opts.ContentFilter)
+	}
+	if opts.ContentPolicy != nil {
+		backend.SetContentPolicy(opts.ContentPolicy)
+	}
+	if opts.AddressPolicy != nil {
+		backend.SetAddressPolicy(opts.AddressPolicy)
+	}
+	if opts.Authenticator != nil {
+		backend.SetAuthenticator(opts.Authenticator)
+	}
+	if opts.Certificates != nil {
+		backend.SetCertificates(opts.Certificates)
+	}
+	if opts.IPGuard != nil {
+		backend.SetIPGuard(opts.IPGuard)
+	}
+
 	// Set server type for metrics
 	serverType := opts.ServerType
 	if serverType == "" {
@@ -73,12 +118,20 @@ func NewServerWithOptions(opts ServerOptions) *Server {
 	}
 	backend.SetServerType(serverType)
 
+	ext := opts.Config.Extensions
+	backend.SetReject8BitMIME(ext.Disable8BitMIME)
+	backend.SetRejectEAI(opts.Config.RejectEAI)
+
 	srv := smtp.NewServer(backend)
 	srv.Domain = opts.Config.Domain
 	srv.MaxMessageBytes = int64(opts.Config.MaxMessageBytes)
 	srv.MaxRecipients = opts.Config.MaxRecipients
 	srv.ReadTimeout = opts.Config.ReadTimeout
 	srv.WriteTimeout = opts.Config.WriteTimeout
+	srv.EnableSMTPUTF8 = ext.SMTPUTF8
+	if n := opts.Config.MaxLineLength.For(serverType); n > 0 {
+		srv.MaxLineLength = n
+	}
 
 	// Configure TLS
 	if opts.TLSConfig != nil {
@@ -94,12 +147,39 @@ func NewServerWithOptions(opts ServerOptions) *Server {
 		srv.AllowInsecureAuth = true
 	}
 
+	// A custom banner or extension list is written by the connection, which
+	// then also runs STARTTLS. go-smtp only sees plain connections: it must
+	// not offer STARTTLS itself and cannot tell that implicit TLS is secure.
+	caps := &capabilities{
+		banner:     opts.Config.Banner,
+		no8BitMIME: ext.Disable8BitMIME,
+		noChunking: ext.DisableChunking,
+		size:       ext.Size,
+	}
+	if caps.active() {
+		if opts.TLSConfig != nil && !opts.Implicit {
+			caps.startTLS = opts.TLSConfig
+		}
+		srv.TLSConfig = nil
+		srv.AllowInsecureAuth = true
+	} else {
+		caps = nil
+	}
+
+	var proxy *config.SMTPProxyProtocolConfig
+	if opts.Config.ProxyProtocol.Enabled(serverType) {
+		proxy = &opts.Config.ProxyProtocol
+	}
+
 	return &Server{
 		server:    srv,
 		backend:   backend,
 		addr:      opts.Addr,
 		tlsConfig: opts.TLSConfig,
 		implicit:  opts.Implicit,
+		caps:      caps,
+		guard:     opts.IPGuard,
+		proxy:     proxy,
 		logger:    opts.Logger,
 	}
 }
@@ -107,6 +187,9 @@ func NewServerWithOptions(opts ServerOptions) *Server {
 // ListenAndServe starts the SMTP server
 func (s *Server) ListenAndServe() error {
 	s.server.Addr = s.addr
+	if s.caps != nil || s.guard != nil || s.proxy != nil {
+		return s.listenAndServeWrapped()
+	}
 	if s.implicit && s.tlsConfig != nil {
 		s.logger.Info("starting SMTPS server (implicit TLS)", "addr", s.addr)
 		return s.server.ListenAndServeTLS()
@@ -115,6 +198,41 @@ func (s *Server) ListenAndServe() error {
 	return s.server.ListenAndServe()
 }
 
+// listenAndServeWrapped serves connections through the PROXY protocol
+// reader, which replaces the address of a proxy with the client address,
+// the IP guard, which refuses clients before TLS, and connections that
+// rewrite the banner and EHLO extensions
+func (s *Server) listenAndServeWrapped() error {
+	l, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	if s.proxy != nil {
+		l = proxyproto.NewListener(l, s.proxy.TrustedProxies, s.proxy.HeaderTimeout, s.logger)
+		s.logger.Info("PROXY protocol enabled", "addr", s.addr, "trusted_proxies", s.proxy.TrustedProxies)
+	}
+	implicit := s.implicit && s.tlsConfig != nil
+	if s.guard != nil {
+		l = s.guard.Listener(l, !implicit)
+	}
+	if implicit {
+		l = tls.NewListener(l, s.tlsConfig)
+		s.logger.Info("starting SMTPS server (implicit TLS)", "addr", s.addr, "custom_capabilities", s.caps != nil, "ip_guard", s.guard != nil)
+	} else {
+		s.logger.Info("starting SMTP server", "addr", s.addr, "custom_capabilities", s.caps != nil, "ip_guard", s.guard != nil)
+	}
+	if s.caps != nil {
+		l = &capListener{Listener: l, caps: s.caps}
+	}
+	return s.server.Serve(l)
+}
+
+// SetAllowedDomains replaces the domains allowed for sending, e.g. on a
+// config reload
+func (s *Server) SetAllowedDomains(domains []string) {
+	s.backend.SetAllowedDomains(domains)
+}
+
 // Shutdown gracefully shuts down the server
 func (s *Server) Shutdown(ctx context.Context) error {
 	s.logger.Info("shutting down SMTP server")
@@ -1,32 +1,47 @@
 package smtp
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
 	"errors"
 	"io"
 	"log/slog"
 	"net"
+	"net/mail"
 	"time"
 
 	"github.com/emersion/go-sasl"
 	"github.com/emersion/go-smtp"
 	"github.com/google/uuid"
 
+	"github.com/foxzi/sendry/internal/addrpolicy"
+	"github.com/foxzi/sendry/internal/config"
+	"github.com/foxzi/sendry/internal/contentfilter"
+	"github.com/foxzi/sendry/internal/contentpolicy"
 	"github.com/foxzi/sendry/internal/email"
+	"github.com/foxzi/sendry/internal/headers"
 	"github.com/foxzi/sendry/internal/metrics"
 	"github.com/foxzi/sendry/internal/queue"
 	"github.com/foxzi/sendry/internal/ratelimit"
+	"github.com/foxzi/sendry/internal/smtpauth"
 )
 
 // Session implements smtp.Session and smtp.AuthSession for go-smtp
 type Session struct {
-	backend    *Backend
-	conn       *smtp.Conn
-	from       string
-	to         []string
-	authUser   string
-	logger     *slog.Logger
-	serverType string
+	backend     *Backend
+	conn        *smtp.Conn
+	ip          string // Client IP address
+	from        string
+	to          []string
+	authUser    string
+	senders     []string // Authorized senders of the user, empty for any
+	relayErr    error    // Relay check result, deferred to RCPT for inbound routes
+	relayDenied bool     // relayErr is a refused relay attempt, not an address policy refusal
+	inbound     bool     // A recipient has an inbound route
+	smtputf8    bool     // MAIL FROM had the SMTPUTF8 parameter
+	logger      *slog.Logger
+	serverType  string
 }
 
 // NewSession creates a new SMTP session
@@ -34,6 +49,7 @@ func NewSession(b *Backend, c *smtp.Conn) *Session {
 	s := &Session{
 		backend:    b,
 		conn:       c,
+		ip:         extractIP(c.Conn().RemoteAddr().String()),
 		logger:     b.logger.With("remote_addr", c.Conn().RemoteAddr().String()),
 		serverType: b.serverType,
 	}
@@ -60,44 +76,153 @@ func (s *Session) Auth(mech string) (sasl.Server, error) {
 			return errors.New("identity must be empty or match username")
 		}
 
-		// Get client IP for brute force protection
-		clientIP := extractIP(s.conn.Conn().RemoteAddr().String())
+		return s.authenticate(username, password, s.ip)
+	}), nil
+}
 
-		// Check if IP is blocked due to too many failures
-		if s.backend.CheckAuthBlocked(clientIP) {
-			s.logger.Warn("authentication blocked", "ip", clientIP, "reason", "too many failures")
-			metrics.IncSMTPAuthFailed()
-			return &smtp.SMTPError{
-				Code:    454,
-				Message: "Too many authentication failures, try again later",
-			}
+// authenticate checks the credentials of AUTH PLAIN with brute force
+// protection of the client IP
+func (s *Session) authenticate(username, password, clientIP string) error {
+	if err := s.throttle(); err != nil {
+		return err
+	}
+
+	// Check if IP is blocked due to too many failures
+	if s.backend.CheckAuthBlocked(clientIP) {
+		s.logger.Warn("authentication blocked", "ip", clientIP, "reason", "too many failures")
+		metrics.IncSMTPAuthFailed()
+		return &smtp.SMTPError{
+			Code:    454,
+			Message: "Too many authentication failures, try again later",
 		}
+	}
+
+	// Check credentials
+	if !s.backend.authenticator.Configured() {
+		return errors.New("authentication not configured")
+	}
 
-		// Check credentials
-		if s.backend.auth == nil || s.backend.auth.Users == nil {
-			return errors.New("authentication not configured")
+	identity, err := s.backend.authenticator.Authenticate(context.Background(), username, password, clientIP)
+	if errors.Is(err, smtpauth.ErrInvalidCredentials) {
+		s.logger.Warn("authentication failed", "username", username, "ip", clientIP)
+		metrics.IncSMTPAuthFailed()
+		s.backend.RecordAuthFailure(clientIP)
+		s.backend.guard.Reject(clientIP)
+		return smtp.ErrAuthFailed
+	}
+	if err != nil {
+		s.logger.Error("authentication error", "username", username, "ip", clientIP, "error", err)
+		return &smtp.SMTPError{
+			Code:         454,
+			EnhancedCode: smtp.EnhancedCode{4, 7, 0},
+			Message:      "Temporary authentication failure",
 		}
+	}
+
+	// Clear failure record on success
+	s.backend.ClearAuthFailure(clientIP)
+
+	s.authUser = identity.Username
+	s.senders = identity.Senders
+	s.logger.Info("authentication successful", "username", username, "source", identity.Source)
+	metrics.IncSMTPAuthSuccess()
+	return nil
+}
+
+// authenticateCertificate authenticates a client that has not used AUTH by
+// its verified TLS client certificate
+func (s *Session) authenticateCertificate(state tls.ConnectionState) {
+	if s.authUser != "" || s.backend.certificates == nil {
+		return
+	}
+	// Only certificates verified against the client CA are trusted
+	if len(state.VerifiedChains) == 0 || len(state.PeerCertificates) == 0 {
+		return
+	}
+	cert := state.PeerCertificates[0]
+	identity := s.backend.certificates.Identify(cert)
+	if identity == nil {
+		s.logger.Debug("client certificate not mapped to a user", "common_name", cert.Subject.CommonName)
+		return
+	}
+
+	s.authUser = identity.Username
+	s.senders = identity.Senders
+	s.logger.Info("authentication successful", "username", identity.Username, "source", identity.Source)
+	metrics.IncSMTPAuthSuccess()
+}
+
+// Mail handles MAIL FROM command
+func (s *Session) Mail(from string, opts *smtp.MailOptions) (err error) {
+	if err := s.throttle(); err != nil {
+		return err
+	}
+	defer s.recordRejection(&err)
 
-		expectedPassword, ok := s.backend.auth.Users[username]
-		if !ok || expectedPassword != password {
-			s.logger.Warn("authentication failed", "username", username, "ip", clientIP)
-			metrics.IncSMTPAuthFailed()
-			s.backend.RecordAuthFailure(clientIP)
-			return smtp.ErrAuthFailed
+	if s.conn != nil {
+		if state, ok := connTLSState(s.conn.Conn()); ok {
+			s.authenticateCertificate(state)
 		}
+	}
+
+	if s.backend.reject8BitMIME && opts != nil && opts.Body == smtp.Body8BitMIME {
+		return &smtp.SMTPError{
+			Code:         555,
+			EnhancedCode: smtp.EnhancedCode{5, 5, 4},
+			Message:      "BODY=8BITMIME is not supported",
+		}
+	}
+
+	if err := s.checkEAI(from); err != nil {
+		return err
+	}
 
-		// Clear failure record on success
-		s.backend.ClearAuthFailure(clientIP)
+	// With inbound routing any sender may deliver to inbound routes, the
+	// relay checks then apply to the other recipients
+	relayErr := s.checkRelay(from)
+	relayDenied := relayErr != nil
+	if relayErr != nil && s.backend.inbound == nil {
+		s.logger.Warn("relay denied", "from", from, "error", relayErr)
+		s.recordRelayAttempt()
+		return relayErr
+	}
 
-		s.authUser = username
-		s.logger.Info("authentication successful", "username", username)
-		metrics.IncSMTPAuthSuccess()
+	if err := s.checkSenders(from, nil); err != nil {
+		return err
+	}
+
+	// Address lists apply to relayed mail, not to inbound routes
+	if err := s.backend.addrPolicy.CheckSender(addrpolicy.SourceSMTP, from); err != nil {
+		if s.backend.inbound == nil {
+			return addressPolicyError(err)
+		}
+		if relayErr == nil {
+			relayErr = addressPolicyError(err)
+		}
+	}
+
+	s.from = from
+	s.relayErr = relayErr
+	s.relayDenied = relayDenied
+	s.smtputf8 = opts != nil && opts.UTF8
+	s.logger.Debug("MAIL FROM", "from", from)
+	return nil
+}
+
+// checkEAI refuses internationalized addresses when they are not accepted
+func (s *Session) checkEAI(addr string) error {
+	if !s.backend.rejectEAI || !email.IsInternational(addr) {
 		return nil
-	}), nil
+	}
+	return &smtp.SMTPError{
+		Code:         553,
+		EnhancedCode: smtp.EnhancedCode{5, 6, 7},
+		Message:      "Internationalized addresses are not accepted",
+	}
 }
 
-// Mail handles MAIL FROM command
-func (s *Session) Mail(from string, opts *smtp.MailOptions) error {
+// checkRelay checks whether the client may relay mail from the sender
+func (s *Session) checkRelay(from string) error {
 	// Check if authentication is required
 	if s.backend.auth != nil && s.backend.auth.Required && s.authUser == "" {
 		return &smtp.SMTPError{
@@ -109,7 +234,6 @@ func (s *Session) Mail(from string, opts *smtp.MailOptions) error {
 	// Check if sender domain is allowed (anti-relay protection)
 	senderDomain := email.ExtractDomain(from)
 	if senderDomain != "" && !s.backend.IsDomainAllowed(senderDomain) {
-		s.logger.Warn("sender domain not allowed", "from", from, "domain", senderDomain)
 		return &smtp.SMTPError{
 			Code:         550,
 			EnhancedCode: smtp.EnhancedCode{5, 7, 1},
@@ -117,13 +241,70 @@ func (s *Session) Mail(from string, opts *smtp.MailOptions) error {
 		}
 	}
 
-	s.from = from
-	s.logger.Debug("MAIL FROM", "from", from)
 	return nil
 }
 
+// checkSenders refuses the envelope sender or a From header address the
+// authenticated user is bound to by smtp.auth.senders, the credential store
+// or the auth hook
+func (s *Session) checkSenders(from string, data []byte) error {
+	if s.authUser == "" {
+		return nil
+	}
+	addr := email.UnauthorizedSender(s.senders, from, data)
+	if addr == "" {
+		return nil
+	}
+	s.logger.Warn("sender not authorized", "from", addr, "username", s.authUser)
+	return &smtp.SMTPError{
+		Code:         550,
+		EnhancedCode: smtp.EnhancedCode{5, 7, 1},
+		Message:      "Sender address not authorized for this user",
+	}
+}
+
 // Rcpt handles RCPT TO command
-func (s *Session) Rcpt(to string, opts *smtp.RcptOptions) error {
+func (s *Session) Rcpt(to string, opts *smtp.RcptOptions) (err error) {
+	if err := s.throttle(); err != nil {
+		return err
+	}
+	defer s.recordRejection(&err)
+
+	if err := s.checkEAI(to); err != nil {
+		return err
+	}
+	if err := s.backend.policy.CheckRecipients(contentpolicy.SourceSMTP, s.from, len(s.to)+1); err != nil {
+		return policyError(err)
+	}
+
+	if s.backend.inbound != nil {
+		if rule, local := s.backend.inbound.Route(to); local {
+			if rule == nil {
+				s.logger.Info("inbound recipient rejected", "from", s.from, "to", to)
+				return &smtp.SMTPError{
+					Code:         550,
+					EnhancedCode: smtp.EnhancedCode{5, 1, 1},
+					Message:      "Mailbox unavailable",
+				}
+			}
+			s.to = append(s.to, to)
+			s.inbound = true
+			s.logger.Debug("RCPT TO", "to", to, "inbound", rule.Action)
+			return nil
+		}
+		if s.relayErr != nil {
+			s.logger.Warn("relay denied", "from", s.from, "to", to, "error", s.relayErr)
+			if s.relayDenied {
+				s.recordRelayAttempt()
+			}
+			return s.relayErr
+		}
+	}
+
+	if err := s.backend.addrPolicy.CheckRecipient(addrpolicy.SourceSMTP, s.from, to); err != nil {
+		return addressPolicyError(err)
+	}
+
 	s.to = append(s.to, to)
 	s.logger.Debug("RCPT TO", "to", to)
 	return nil
@@ -131,6 +312,10 @@ func (s *Session) Rcpt(to string, opts *smtp.RcptOptions) error {
 
 // Data handles DATA command
 func (s *Session) Data(r io.Reader) error {
+	if err := s.throttle(); err != nil {
+		return err
+	}
+
 	// Check rate limits before processing
 	ctx := context.Background()
 	if err := s.checkRateLimits(ctx); err != nil {
@@ -145,17 +330,66 @@ func (s *Session) Data(r io.Reader) error {
 		}
 	}
 
+	if err := s.checkSenders("", data); err != nil {
+		return err
+	}
+
+	data, priority, err := extractPriority(data)
+	if err != nil {
+		s.logger.Warn("ignoring invalid priority header", "from", s.from, "error", err)
+	}
+
+	// Mail received from other servers for inbound routes is checked for
+	// SPF, DKIM and DMARC
+	quarantined := false
+	if s.inbound && s.authUser == "" && s.backend.verifier != nil {
+		ip := net.ParseIP(extractIP(s.conn.Conn().RemoteAddr().String()))
+		data, quarantined, err = s.verifyInbound(ctx, ip, s.conn.Hostname(), data)
+		if err != nil {
+			return err
+		}
+	}
+
+	data, err = s.backend.policy.CheckContent(contentpolicy.SourceSMTP, s.from, data)
+	if err != nil {
+		return policyError(err)
+	}
+
+	id := uuid.New().String()
+	if s.backend.filter.Applies(config.ContentFilterSourceSMTP) {
+		env := &contentfilter.Envelope{
+			ID:       id,
+			Source:   config.ContentFilterSourceSMTP,
+			From:     s.from,
+			To:       s.to,
+			ClientIP: s.conn.Conn().RemoteAddr().String(),
+			Helo:     s.conn.Hostname(),
+			AuthUser: s.authUser,
+		}
+		data, err = s.filterContent(ctx, env, data)
+		if err != nil {
+			return err
+		}
+		if data == nil {
+			s.logger.Info("message discarded by content filter", "id", id, "from", s.from, "to", s.to)
+			return nil
+		}
+	}
+
 	// Create message
 	msg := &queue.Message{
-		ID:        uuid.New().String(),
-		From:      s.from,
-		To:        s.to,
-		Data:      data,
-		Status:    queue.StatusPending,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-		AuthUser:  s.authUser,
-		ClientIP:  s.conn.Conn().RemoteAddr().String(),
+		ID:          id,
+		From:        s.from,
+		To:          s.to,
+		Data:        data,
+		Status:      queue.StatusPending,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+		AuthUser:    s.authUser,
+		ClientIP:    s.conn.Conn().RemoteAddr().String(),
+		Priority:    priority,
+		Quarantined: quarantined,
+		SMTPUTF8:    s.smtputf8,
 	}
 
 	// Enqueue message
@@ -172,9 +406,40 @@ func (s *Session) Data(r io.Reader) error {
 		"from", s.from,
 		"to", s.to,
 		"size", len(data),
+		"priority", priority,
 	)
 
-	return nil
+	// Report the queue ID so clients can look up the delivery status
+	return &smtp.SMTPError{
+		Code:         250,
+		EnhancedCode: smtp.EnhancedCode{2, 0, 0},
+		Message:      "OK: queued as " + msg.ID,
+	}
+}
+
+// extractPriority reads the X-Sendry-Priority header and removes it from the
+// message, so the instruction is not passed on to recipients. Messages
+// without the header or with an invalid value get normal priority.
+func extractPriority(data []byte) ([]byte, queue.Priority, error) {
+	parsed, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return data, queue.PriorityNormal, nil
+	}
+
+	value := parsed.Header.Get(queue.PriorityHeader)
+	if value == "" {
+		return data, queue.PriorityNormal, nil
+	}
+
+	data = headers.Apply(data, []headers.Rule{
+		{Action: headers.ActionRemove, Headers: []string{queue.PriorityHeader}},
+	})
+
+	priority, err := queue.ParsePriority(value)
+	if err != nil {
+		return data, queue.PriorityNormal, err
+	}
+	return data, priority, nil
 }
 
 // checkRateLimits checks if the message is within rate limits
@@ -201,6 +466,10 @@ func extractIP(addr string) string {
 func (s *Session) Reset() {
 	s.from = ""
 	s.to = nil
+	s.relayErr = nil
+	s.relayDenied = false
+	s.inbound = false
+	s.smtputf8 = false
 }
 
 // Logout handles session logout
@@ -0,0 +1,601 @@
+package smtp
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-smtp"
+
+	"github.com/foxzi/sendry/internal/addrpolicy"
+	"github.com/foxzi/sendry/internal/config"
+	"github.com/foxzi/sendry/internal/contentfilter"
+	"github.com/foxzi/sendry/internal/contentpolicy"
+	"github.com/foxzi/sendry/internal/inbound"
+	"github.com/foxzi/sendry/internal/ipguard"
+	"github.com/foxzi/sendry/internal/mailauth"
+	"github.com/foxzi/sendry/internal/queue"
+	"github.com/foxzi/sendry/internal/smtpauth"
+	sendryTLS "github.com/foxzi/sendry/internal/tls"
+)
+
+func TestExtractPriority(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    queue.Priority
+		wantErr bool
+	}{
+		{
+			name: "no header",
+			data: "From: a@example.com\r\nSubject: Hi\r\n\r\nBody",
+			want: queue.PriorityNormal,
+		},
+		{
+			name: "high",
+			data: "From: a@example.com\r\nX-Sendry-Priority: high\r\nSubject: Hi\r\n\r\nBody",
+			want: queue.PriorityHigh,
+		},
+		{
+			name: "case insensitive",
+			data: "From: a@example.com\r\nx-sendry-priority: Low\r\n\r\nBody",
+			want: queue.PriorityLow,
+		},
+		{
+			name:    "invalid",
+			data:    "From: a@example.com\r\nX-Sendry-Priority: urgent\r\n\r\nBody",
+			want:    queue.PriorityNormal,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, got, err := extractPriority([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("extractPriority() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("extractPriority() = %q, want %q", got, tt.want)
+			}
+			if strings.Contains(strings.ToLower(string(data)), "x-sendry-priority") {
+				t.Errorf("priority header not removed: %q", data)
+			}
+			if !strings.HasSuffix(string(data), "Body") {
+				t.Errorf("body lost: %q", data)
+			}
+		})
+	}
+}
+
+type inboundDomains map[string]config.DomainConfig
+
+func (d inboundDomains) GetDomainConfig(domain string) *config.DomainConfig {
+	if dc, ok := d[domain]; ok {
+		return &dc
+	}
+	return nil
+}
+
+func TestSessionInboundRouting(t *testing.T) {
+	newSession := func(router *inbound.Router) *Session {
+		b := NewBackend(nil, &config.AuthConfig{Required: true}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+		t.Cleanup(b.Stop)
+		b.SetAllowedDomains([]string{"example.com"})
+		if router != nil {
+			b.SetInboundRouter(router)
+		}
+		return &Session{backend: b, logger: b.logger}
+	}
+
+	router := inbound.NewRouter(inboundDomains{"example.com": {Inbound: []config.InboundRule{
+		{Recipient: "reply+*", Action: config.InboundActionWebhook, URL: "https://app.example.com/hook"},
+	}}})
+
+	t.Run("without inbound routing", func(t *testing.T) {
+		s := newSession(nil)
+		if err := s.Mail("customer@example.net", nil); err == nil {
+			t.Fatal("Mail() accepted an unauthenticated sender")
+		}
+	})
+
+	t.Run("inbound recipients", func(t *testing.T) {
+		s := newSession(router)
+		if err := s.Mail("customer@example.net", nil); err != nil {
+			t.Fatalf("Mail() error = %v", err)
+		}
+		if err := s.Rcpt("reply+42@example.com", nil); err != nil {
+			t.Errorf("Rcpt() inbound route error = %v", err)
+		}
+
+		var smtpErr *smtp.SMTPError
+		if err := s.Rcpt("nobody@example.com", nil); !errors.As(err, &smtpErr) || smtpErr.Code != 550 {
+			t.Errorf("Rcpt() unknown mailbox error = %v, want 550", err)
+		}
+		if err := s.Rcpt("user@example.org", nil); !errors.As(err, &smtpErr) || smtpErr.Code != 530 {
+			t.Errorf("Rcpt() relay error = %v, want 530", err)
+		}
+		if len(s.to) != 1 || s.to[0] != "reply+42@example.com" {
+			t.Errorf("recipients = %v, want only the inbound route", s.to)
+		}
+	})
+
+	t.Run("authenticated relay", func(t *testing.T) {
+		s := newSession(router)
+		s.authUser = "app"
+		if err := s.Mail("noreply@example.com", nil); err != nil {
+			t.Fatalf("Mail() error = %v", err)
+		}
+		if err := s.Rcpt("user@example.org", nil); err != nil {
+			t.Errorf("Rcpt() error = %v", err)
+		}
+	})
+}
+
+// dnsRecords serves TXT records for inbound authentication, other lookups
+// find nothing
+type dnsRecords map[string][]string
+
+func (d dnsRecords) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	if txt, ok := d[name]; ok {
+		return txt, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func (d dnsRecords) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+func (d dnsRecords) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func TestSessionVerifyInbound(t *testing.T) {
+	records := dnsRecords{
+		"example.com":        {"v=spf1 ip4:192.0.2.0/24 -all"},
+		"_dmarc.example.com": {"v=DMARC1; p=reject"},
+		"_dmarc.example.net": {"v=DMARC1; p=quarantine"},
+		"_dmarc.example.org": {"v=DMARC1; p=none"},
+		"mail.example.net":   {"v=spf1 -all"},
+	}
+	newSession := func(mode string) *Session {
+		b := NewBackend(nil, &config.AuthConfig{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+		t.Cleanup(b.Stop)
+		b.SetInboundAuth(mailauth.NewVerifier(records, "mx.example.com"), mode)
+		return &Session{backend: b, logger: b.logger}
+	}
+	message := func(from string) []byte {
+		return []byte("From: " + from + "\r\nTo: reply+1@example.com\r\nSubject: Hi\r\n\r\nHello\r\n")
+	}
+	forged := net.ParseIP("203.0.113.9")
+
+	t.Run("pass", func(t *testing.T) {
+		s := newSession(mailauth.ModeReject)
+		s.from = "alice@example.com"
+		data, quarantined, err := s.verifyInbound(context.Background(), net.ParseIP("192.0.2.1"), "mail.example.com", message("alice@example.com"))
+		if err != nil || quarantined {
+			t.Fatalf("verifyInbound() = %v, %v", quarantined, err)
+		}
+		if !strings.HasPrefix(string(data), "Authentication-Results: mx.example.com; spf=pass") || !strings.Contains(string(data), "dmarc=pass") {
+			t.Errorf("header = %q", data)
+		}
+	})
+
+	t.Run("reject policy", func(t *testing.T) {
+		s := newSession(mailauth.ModeReject)
+		s.from = "alice@example.com"
+		var smtpErr *smtp.SMTPError
+		if _, _, err := s.verifyInbound(context.Background(), forged, "evil.example", message("alice@example.com")); !errors.As(err, &smtpErr) || smtpErr.Code != 550 {
+			t.Errorf("verifyInbound() error = %v, want 550", err)
+		}
+	})
+
+	t.Run("quarantine policy", func(t *testing.T) {
+		s := newSession(mailauth.ModeReject)
+		s.from = "bob@mail.example.net"
+		data, quarantined, err := s.verifyInbound(context.Background(), forged, "evil.example", message("bob@example.net"))
+		if err != nil || !quarantined || !strings.Contains(string(data), "dmarc=fail") {
+			t.Errorf("verifyInbound() = %v, %v, want quarantined", quarantined, err)
+		}
+	})
+
+	t.Run("none policy", func(t *testing.T) {
+		s := newSession(mailauth.ModeReject)
+		s.from = "carol@example.org"
+		if _, quarantined, err := s.verifyInbound(context.Background(), forged, "evil.example", message("carol@example.org")); err != nil || quarantined {
+			t.Errorf("verifyInbound() = %v, %v, want accepted", quarantined, err)
+		}
+	})
+
+	t.Run("tag mode", func(t *testing.T) {
+		s := newSession(mailauth.ModeTag)
+		s.from = "alice@example.com"
+		data, quarantined, err := s.verifyInbound(context.Background(), forged, "evil.example", message("alice@example.com"))
+		if err != nil || quarantined {
+			t.Fatalf("verifyInbound() = %v, %v, want only tagged", quarantined, err)
+		}
+		if !strings.Contains(string(data), "spf=fail") || !strings.Contains(string(data), "dmarc=fail") {
+			t.Errorf("header = %q", data)
+		}
+	})
+}
+
+// verdictFilter returns the same verdict for all messages
+type verdictFilter contentfilter.Verdict
+
+func (f verdictFilter) Check(ctx context.Context, env *contentfilter.Envelope, data []byte) (*contentfilter.Verdict, error) {
+	v := contentfilter.Verdict(f)
+	return &v, nil
+}
+
+func TestSessionFilterContent(t *testing.T) {
+	env := &contentfilter.Envelope{ID: "msg-1", Source: config.ContentFilterSourceSMTP, From: "alice@example.com", To: []string{"bob@example.net"}}
+	data := []byte("Subject: Hi\r\n\r\nHello\r\n")
+	tests := []struct {
+		name    string
+		verdict contentfilter.Verdict
+		data    string
+		code    int
+		enh     smtp.EnhancedCode
+		message string
+	}{
+		{name: "accept", verdict: contentfilter.Verdict{Action: contentfilter.ActionAccept}, data: string(data)},
+		{name: "modify", verdict: contentfilter.Verdict{Action: contentfilter.ActionModify, Data: []byte("X-Spam: Yes\r\n\r\n")}, data: "X-Spam: Yes\r\n\r\n"},
+		{name: "discard", verdict: contentfilter.Verdict{Action: contentfilter.ActionDiscard}},
+		{
+			name:    "reject",
+			verdict: contentfilter.Verdict{Action: contentfilter.ActionReject},
+			code:    550, enh: smtp.EnhancedCode{5, 7, 1}, message: "Message rejected by content filter",
+		},
+		{
+			name:    "reject with filter reply",
+			verdict: contentfilter.Verdict{Action: contentfilter.ActionReject, Code: 554, EnhancedCode: [3]int{5, 7, 9}, Message: "Spam"},
+			code:    554, enh: smtp.EnhancedCode{5, 7, 9}, message: "Spam",
+		},
+		{
+			name:    "tempfail ignores a permanent code",
+			verdict: contentfilter.Verdict{Action: contentfilter.ActionTempfail, Code: 550},
+			code:    451, enh: smtp.EnhancedCode{4, 7, 1}, message: "Message deferred by content filter",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBackend(nil, &config.AuthConfig{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+			defer b.Stop()
+			b.SetContentFilter(contentfilter.NewChecker(verdictFilter(tt.verdict), time.Second, false,
+				map[string]bool{config.ContentFilterSourceSMTP: true}, nil))
+			s := &Session{backend: b, logger: b.logger}
+
+			got, err := s.filterContent(context.Background(), env, data)
+			if tt.code == 0 {
+				if err != nil || string(got) != tt.data {
+					t.Errorf("filterContent() = %q, %v, want %q", got, err, tt.data)
+				}
+				return
+			}
+			var smtpErr *smtp.SMTPError
+			if !errors.As(err, &smtpErr) || smtpErr.Code != tt.code || smtpErr.EnhancedCode != tt.enh || smtpErr.Message != tt.message {
+				t.Errorf("filterContent() error = %+v, want %d %v %s", err, tt.code, tt.enh, tt.message)
+			}
+		})
+	}
+}
+
+func TestSessionContentPolicy(t *testing.T) {
+	b := NewBackend(nil, &config.AuthConfig{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	defer b.Stop()
+	b.SetContentPolicy(contentpolicy.New(config.ContentPolicyConfig{MaxRecipients: 1}, nil, nil))
+	s := &Session{backend: b, logger: b.logger, from: "alice@example.com"}
+
+	if err := s.Rcpt("bob@example.net", nil); err != nil {
+		t.Fatalf("Rcpt() error = %v", err)
+	}
+	var smtpErr *smtp.SMTPError
+	if err := s.Rcpt("carol@example.net", nil); !errors.As(err, &smtpErr) || smtpErr.Code != 550 || smtpErr.EnhancedCode != (smtp.EnhancedCode{5, 5, 3}) {
+		t.Errorf("Rcpt() over the limit error = %v, want 550 5.5.3", err)
+	}
+	if len(s.to) != 1 {
+		t.Errorf("recipients = %v, want one", s.to)
+	}
+
+	tests := []struct {
+		reason contentpolicy.Reason
+		code   int
+		enh    smtp.EnhancedCode
+	}{
+		{contentpolicy.ReasonAttachmentSize, 552, smtp.EnhancedCode{5, 3, 4}},
+		{contentpolicy.ReasonBanned, 550, smtp.EnhancedCode{5, 7, 1}},
+	}
+	for _, tt := range tests {
+		err := policyError(&contentpolicy.Violation{Reason: tt.reason, Message: "attachment \"a.exe\" is not allowed"})
+		if err.Code != tt.code || err.EnhancedCode != tt.enh || !strings.Contains(err.Message, "a.exe") {
+			t.Errorf("policyError(%s) = %+v, want %d %v", tt.reason, err, tt.code, tt.enh)
+		}
+	}
+}
+
+func TestSessionRejectEAI(t *testing.T) {
+	b := NewBackend(nil, &config.AuthConfig{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	defer b.Stop()
+	b.SetRejectEAI(true)
+	s := &Session{backend: b, logger: b.logger}
+
+	var smtpErr *smtp.SMTPError
+	if err := s.Mail("отправитель@example.com", nil); !errors.As(err, &smtpErr) || smtpErr.Code != 553 {
+		t.Errorf("Mail() error = %v, want 553", err)
+	}
+	if err := s.Mail("sender@пример.рф", nil); err != nil {
+		t.Fatalf("Mail() IDN domain error = %v", err)
+	}
+	if err := s.Rcpt("получатель@example.org", nil); !errors.As(err, &smtpErr) || smtpErr.Code != 553 {
+		t.Errorf("Rcpt() error = %v, want 553", err)
+	}
+	if err := s.Rcpt("user@example.org", nil); err != nil {
+		t.Errorf("Rcpt() error = %v", err)
+	}
+}
+
+func TestSessionAddressPolicy(t *testing.T) {
+	policy := addrpolicy.New(config.AddressPolicyConfig{
+		DenySenders:    []string{"spammer@example.net"},
+		DenyRecipients: []string{"*.blocked.example"},
+	}, nil, nil)
+	newSession := func(router *inbound.Router) *Session {
+		b := NewBackend(nil, &config.AuthConfig{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+		t.Cleanup(b.Stop)
+		b.SetAddressPolicy(policy)
+		if router != nil {
+			b.SetInboundRouter(router)
+		}
+		return &Session{backend: b, logger: b.logger}
+	}
+
+	s := newSession(nil)
+	var smtpErr *smtp.SMTPError
+	if err := s.Mail("spammer@example.net", nil); !errors.As(err, &smtpErr) || smtpErr.Code != 550 || smtpErr.EnhancedCode != (smtp.EnhancedCode{5, 7, 1}) {
+		t.Errorf("Mail() denied sender error = %v, want 550 5.7.1", err)
+	}
+	if err := s.Mail("news@example.com", nil); err != nil {
+		t.Fatalf("Mail() error = %v", err)
+	}
+	if err := s.Rcpt("user@mx.blocked.example", nil); !errors.As(err, &smtpErr) || smtpErr.Code != 550 || !strings.Contains(smtpErr.Message, "Recipient") {
+		t.Errorf("Rcpt() denied recipient error = %v, want 550 recipient rejected", err)
+	}
+	if err := s.Rcpt("user@example.org", nil); err != nil {
+		t.Errorf("Rcpt() error = %v", err)
+	}
+
+	// Mail for inbound routes is not checked, relayed recipients are
+	router := inbound.NewRouter(inboundDomains{"example.com": {Inbound: []config.InboundRule{
+		{Recipient: "support", Action: config.InboundActionWebhook, URL: "https://app.example.com/hook"},
+	}}})
+	s = newSession(router)
+	if err := s.Mail("spammer@example.net", nil); err != nil {
+		t.Fatalf("Mail() with inbound routing error = %v", err)
+	}
+	if err := s.Rcpt("support@example.com", nil); err != nil {
+		t.Errorf("Rcpt() inbound route error = %v", err)
+	}
+	if err := s.Rcpt("user@example.org", nil); !errors.As(err, &smtpErr) || smtpErr.Code != 550 {
+		t.Errorf("Rcpt() relayed recipient of denied sender error = %v, want 550", err)
+	}
+}
+
+func TestSessionIPGuard(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	guard := ipguard.New(config.IPGuardConfig{BanAfter: 2, TarpitAfter: 1, TarpitDelay: time.Millisecond}, logger)
+	b := NewBackend(nil, &config.AuthConfig{Required: true}, logger)
+	t.Cleanup(b.Stop)
+	b.SetIPGuard(guard)
+
+	s := &Session{backend: b, logger: b.logger, ip: "192.0.2.1"}
+	var smtpErr *smtp.SMTPError
+	for i := 0; i < 2; i++ {
+		if err := s.Mail("user@example.com", nil); !errors.As(err, &smtpErr) || smtpErr.Code != 530 {
+			t.Fatalf("Mail() relay attempt #%d error = %v, want 530", i+1, err)
+		}
+	}
+	if d := guard.Delay(s.ip); d != time.Millisecond {
+		t.Errorf("Delay() after rejected commands = %v, want the tarpit delay", d)
+	}
+	if !guard.Banned(s.ip) {
+		t.Fatal("client not banned after ban_after relay attempts")
+	}
+	if err := s.Mail("user@example.com", nil); !errors.As(err, &smtpErr) || smtpErr.Code != 421 {
+		t.Errorf("Mail() of banned client error = %v, want 421", err)
+	}
+
+	other := &Session{backend: b, logger: b.logger, ip: "192.0.2.2"}
+	if err := other.Mail("user@example.com", nil); !errors.As(err, &smtpErr) || smtpErr.Code != 530 {
+		t.Errorf("Mail() of another client error = %v, want 530", err)
+	}
+}
+
+func TestSessionAuthorizedSenders(t *testing.T) {
+	auth := &config.AuthConfig{
+		Users:   map[string]string{"acme": "secret", "ops": "secret"},
+		Senders: map[string][]string{"acme": {"acme.example", "*.acme.example"}},
+	}
+	b := NewBackend(nil, auth, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	defer b.Stop()
+	s := &Session{backend: b, logger: b.logger}
+	if err := s.authenticate("acme", "secret", "192.0.2.1"); err != nil {
+		t.Fatalf("authenticate() error = %v", err)
+	}
+
+	var smtpErr *smtp.SMTPError
+	if err := s.Mail("billing@globex.example", nil); !errors.As(err, &smtpErr) || smtpErr.Code != 550 || smtpErr.EnhancedCode != (smtp.EnhancedCode{5, 7, 1}) {
+		t.Errorf("Mail() foreign domain error = %v, want 550 5.7.1", err)
+	}
+	for _, from := range []string{"billing@acme.example", "news@mail.acme.example", ""} {
+		if err := s.Mail(from, nil); err != nil {
+			t.Errorf("Mail(%q) error = %v", from, err)
+		}
+	}
+	if err := s.checkSenders("", []byte("From: CEO <ceo@globex.example>\r\n\r\nHi\r\n")); !errors.As(err, &smtpErr) || smtpErr.Code != 550 {
+		t.Errorf("checkSenders() foreign From header error = %v, want 550", err)
+	}
+
+	// Users without senders may use any address
+	s = &Session{backend: b, logger: b.logger}
+	if err := s.authenticate("ops", "secret", "192.0.2.1"); err != nil {
+		t.Fatalf("authenticate() error = %v", err)
+	}
+	if err := s.Mail("billing@globex.example", nil); err != nil {
+		t.Errorf("Mail() unrestricted user error = %v", err)
+	}
+}
+
+func TestSessionAuthenticate(t *testing.T) {
+	hash, err := smtpauth.HashPassword("stored-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := &config.AuthConfig{Users: map[string]string{"app": hash}, MaxFailures: 2}
+	b := NewBackend(nil, auth, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	defer b.Stop()
+
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req smtpauth.HookRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		switch req.Username {
+		case "partner":
+			if req.Password == "partner-secret" {
+				json.NewEncoder(w).Encode(smtpauth.HookResponse{Senders: []string{"partner.example"}})
+				return
+			}
+			w.WriteHeader(http.StatusUnauthorized)
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer hook.Close()
+	b.SetAuthenticator(smtpauth.New(auth, nil, smtpauth.NewHook(config.AuthHookConfig{URL: hook.URL})))
+
+	s := &Session{backend: b, logger: b.logger}
+	if err := s.authenticate("app", "stored-secret", "192.0.2.1"); err != nil || s.authUser != "app" {
+		t.Errorf("authenticate(hashed config user) = %v, user %q", err, s.authUser)
+	}
+
+	s = &Session{backend: b, logger: b.logger}
+	if err := s.authenticate("partner", "partner-secret", "192.0.2.2"); err != nil || len(s.senders) != 1 {
+		t.Errorf("authenticate(hook user) = %v, senders %v", err, s.senders)
+	}
+
+	var smtpErr *smtp.SMTPError
+	if err := s.authenticate("unknown", "x", "192.0.2.3"); !errors.As(err, &smtpErr) || smtpErr.Code != 454 {
+		t.Errorf("authenticate() with failing hook = %v, want 454", err)
+	}
+	if b.CheckAuthBlocked("192.0.2.3") {
+		t.Error("hook failure counted as an auth failure")
+	}
+
+	for range 2 {
+		if err := s.authenticate("partner", "wrong", "192.0.2.4"); err != smtp.ErrAuthFailed {
+			t.Errorf("authenticate(wrong password) = %v, want auth failed", err)
+		}
+	}
+	if err := s.authenticate("partner", "partner-secret", "192.0.2.4"); !errors.As(err, &smtpErr) || smtpErr.Code != 454 {
+		t.Errorf("authenticate() after failures = %v, want 454 blocked", err)
+	}
+}
+
+// clientCertificate returns a self-signed client certificate
+func clientCertificate(t *testing.T, cn string) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestSessionAuthenticateCertificate(t *testing.T) {
+	billing := clientCertificate(t, "billing")
+	pinned := clientCertificate(t, "reports")
+	other := clientCertificate(t, "reports")
+	verified := func(cert *x509.Certificate) tls.ConnectionState {
+		return tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}, VerifiedChains: [][]*x509.Certificate{{cert}}}
+	}
+
+	b := NewBackend(nil, &config.AuthConfig{Required: true, Users: map[string]string{"ops": "secret"}}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	defer b.Stop()
+	b.SetCertificates(smtpauth.NewCertificates([]config.SMTPCertClient{
+		{CommonName: "billing", Senders: []string{"billing.example"}},
+		{Fingerprint: sendryTLS.Fingerprint(pinned), User: "reports-bot"},
+	}))
+
+	tests := []struct {
+		name  string
+		state tls.ConnectionState
+		want  string
+	}{
+		{"common name", verified(billing), "billing"},
+		{"fingerprint", verified(pinned), "reports-bot"},
+		{"unlisted certificate", verified(other), ""},
+		{"unverified certificate", tls.ConnectionState{PeerCertificates: []*x509.Certificate{billing}}, ""},
+		{"no certificate", tls.ConnectionState{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Session{backend: b, logger: b.logger}
+			s.authenticateCertificate(tt.state)
+			if s.authUser != tt.want {
+				t.Errorf("authUser = %q, want %q", s.authUser, tt.want)
+			}
+		})
+	}
+
+	// Senders of the certificate apply
+	s := &Session{backend: b, logger: b.logger}
+	s.authenticateCertificate(verified(billing))
+	var smtpErr *smtp.SMTPError
+	if err := s.Mail("ceo@globex.example", nil); !errors.As(err, &smtpErr) || smtpErr.Code != 550 {
+		t.Errorf("Mail() foreign domain error = %v, want 550", err)
+	}
+	if err := s.Mail("invoices@billing.example", nil); err != nil {
+		t.Errorf("Mail() error = %v", err)
+	}
+
+	// A user authenticated with AUTH keeps its identity
+	s = &Session{backend: b, logger: b.logger}
+	if err := s.authenticate("ops", "secret", "192.0.2.1"); err != nil {
+		t.Fatal(err)
+	}
+	s.authenticateCertificate(verified(billing))
+	if s.authUser != "ops" || s.senders != nil {
+		t.Errorf("authUser = %q, senders = %v, want AUTH identity", s.authUser, s.senders)
+	}
+}
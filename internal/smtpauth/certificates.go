@@ -0,0 +1,49 @@
+package smtpauth
+
+import (
+	"crypto/x509"
+
+	"github.com/foxzi/sendry/internal/config"
+	sendryTLS "github.com/foxzi/sendry/internal/tls"
+)
+
+// SourceCertificate is the source of an identity authenticated by its TLS
+// client certificate
+const SourceCertificate = "certificate"
+
+// Certificates maps verified TLS client certificates to identities
+type Certificates struct {
+	byFingerprint map[string]*Identity
+	byCommonName  map[string]*Identity
+}
+
+// NewCertificates creates a mapping of smtp.client_certs.clients
+func NewCertificates(clients []config.SMTPCertClient) *Certificates {
+	c := &Certificates{
+		byFingerprint: make(map[string]*Identity),
+		byCommonName:  make(map[string]*Identity),
+	}
+	for _, client := range clients {
+		user := client.User
+		if user == "" {
+			user = client.CommonName
+		}
+		identity := &Identity{Username: user, Senders: client.Senders, Source: SourceCertificate}
+		if client.Fingerprint != "" {
+			c.byFingerprint[sendryTLS.NormalizeFingerprint(client.Fingerprint)] = identity
+		} else {
+			c.byCommonName[client.CommonName] = identity
+		}
+	}
+	return c
+}
+
+// Identify returns the identity of a client certificate verified against
+// the client CA, or nil when it is not listed. A fingerprint entry takes
+// precedence over a common name entry.
+func (c *Certificates) Identify(cert *x509.Certificate) *Identity {
+	if identity, ok := c.byFingerprint[sendryTLS.Fingerprint(cert)]; ok {
+		return identity
+	}
+	return c.byCommonName[cert.Subject.CommonName]
+}
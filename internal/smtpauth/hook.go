@@ -0,0 +1,86 @@
+package smtpauth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/foxzi/sendry/internal/config"
+)
+
+// defaultHookTimeout limits a hook call when smtp.auth.hook.timeout is not set
+const defaultHookTimeout = 5 * time.Second
+
+// HookRequest is the JSON body posted to the verification hook
+type HookRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	ClientIP string `json:"client_ip"`
+}
+
+// HookResponse is the optional JSON body of an accepting hook
+type HookResponse struct {
+	Senders []string `json:"senders,omitempty"` // Authorized senders, empty for any
+}
+
+// Hook verifies credentials with an external HTTP endpoint: 2xx accepts
+// them, 401 and 403 refuse them, anything else is a failure
+type Hook struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+// NewHook creates a hook from its config
+func NewHook(cfg config.AuthHookConfig) *Hook {
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultHookTimeout
+	}
+	return &Hook{url: cfg.URL, headers: cfg.Headers, client: &http.Client{Timeout: timeout}}
+}
+
+// Verify posts the credentials and returns the senders of an accepted user.
+// It returns ErrInvalidCredentials when the hook refuses them.
+func (h *Hook) Verify(ctx context.Context, username, password, clientIP string) ([]string, error) {
+	body, err := json.Marshal(&HookRequest{Username: username, Password: password, ClientIP: clientIP})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "sendry-smtp-auth")
+	for name, value := range h.headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("auth hook failed: %w", err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return nil, ErrInvalidCredentials
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return nil, fmt.Errorf("auth hook returned %s", resp.Status)
+	}
+
+	var r HookResponse
+	if len(bytes.TrimSpace(data)) > 0 {
+		if err := json.Unmarshal(data, &r); err != nil {
+			return nil, fmt.Errorf("invalid auth hook response: %w", err)
+		}
+	}
+	return r.Senders, nil
+}
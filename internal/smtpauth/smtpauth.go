@@ -0,0 +1,167 @@
+// Package smtpauth verifies SMTP AUTH credentials against the users of the
+// config, the credential store and an external HTTP hook.
+package smtpauth
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/foxzi/sendry/internal/config"
+)
+
+// ErrInvalidCredentials is returned for an unknown user, a wrong password
+// or a disabled user
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// Sources of an identity
+const (
+	SourceConfig = "config"
+	SourceStore  = "store"
+	SourceHook   = "hook"
+)
+
+// Identity is an authenticated SMTP user
+type Identity struct {
+	Username string
+	Senders  []string // Authorized senders, empty for any
+	Source   string   // config, store or hook
+}
+
+// Authenticator checks credentials against smtp.auth.users first, then the
+// credential store, then the hook
+type Authenticator struct {
+	mu    sync.RWMutex
+	cfg   *config.AuthConfig
+	store *Storage
+	hook  *Hook
+}
+
+// New creates an authenticator. store and hook may be nil.
+func New(cfg *config.AuthConfig, store *Storage, hook *Hook) *Authenticator {
+	return &Authenticator{cfg: cfg, store: store, hook: hook}
+}
+
+// SetConfig replaces the users and senders of the config, e.g. on a
+// config reload
+func (a *Authenticator) SetConfig(cfg *config.AuthConfig) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.cfg = cfg
+}
+
+func (a *Authenticator) config() *config.AuthConfig {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.cfg
+}
+
+// Configured reports whether there is any source of users
+func (a *Authenticator) Configured() bool {
+	cfg := a.config()
+	return (cfg != nil && len(cfg.Users) > 0) || a.store != nil || a.hook != nil
+}
+
+// Authenticate verifies a username and password. It returns
+// ErrInvalidCredentials when they are wrong and another error when a
+// source failed, which the client should retry later.
+func (a *Authenticator) Authenticate(ctx context.Context, username, password, clientIP string) (*Identity, error) {
+	if cfg := a.config(); cfg != nil {
+		if stored, ok := cfg.Users[username]; ok {
+			if !CheckPassword(stored, password) {
+				return nil, ErrInvalidCredentials
+			}
+			return &Identity{Username: username, Senders: cfg.Senders[username], Source: SourceConfig}, nil
+		}
+	}
+
+	if a.store != nil {
+		user, err := a.store.Verify(ctx, username, password)
+		switch {
+		case err == nil:
+			return &Identity{Username: username, Senders: user.Senders, Source: SourceStore}, nil
+		case !errors.Is(err, ErrNotFound):
+			return nil, err
+		}
+	}
+
+	if a.hook != nil {
+		senders, err := a.hook.Verify(ctx, username, password, clientIP)
+		if err != nil {
+			return nil, err
+		}
+		return &Identity{Username: username, Senders: senders, Source: SourceHook}, nil
+	}
+	return nil, ErrInvalidCredentials
+}
+
+// HashPassword returns the bcrypt hash of a password
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
+// IsHash reports whether a stored password is a bcrypt or argon2id hash
+// rather than a plaintext password
+func IsHash(stored string) bool {
+	return isBcrypt(stored) || strings.HasPrefix(stored, "$argon2id$")
+}
+
+func isBcrypt(stored string) bool {
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if strings.HasPrefix(stored, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckPassword compares a password with a stored bcrypt hash, argon2id
+// hash in PHC format or plaintext password
+func CheckPassword(stored, password string) bool {
+	switch {
+	case isBcrypt(stored):
+		return bcrypt.CompareHashAndPassword([]byte(stored), []byte(password)) == nil
+	case strings.HasPrefix(stored, "$argon2id$"):
+		return checkArgon2id(stored, password)
+	}
+	return subtle.ConstantTimeCompare([]byte(stored), []byte(password)) == 1
+}
+
+// checkArgon2id compares a password with a hash of the form
+// $argon2id$v=19$m=65536,t=3,p=4$salt$hash
+func checkArgon2id(stored, password string) bool {
+	parts := strings.Split(stored, "$")
+	if len(parts) != 6 {
+		return false
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false
+	}
+	var memory, iterations uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &threads); err != nil || threads == 0 {
+		return false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false
+	}
+	want, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(want) == 0 {
+		return false
+	}
+	got := argon2.IDKey([]byte(password), salt, iterations, memory, threads, uint32(len(want)))
+	return subtle.ConstantTimeCompare(got, want) == 1
+}
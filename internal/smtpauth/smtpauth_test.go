@@ -0,0 +1,108 @@
+package smtpauth
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/crypto/argon2"
+
+	"github.com/foxzi/sendry/internal/config"
+)
+
+func TestCheckPassword(t *testing.T) {
+	bcryptHash, err := HashPassword("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	salt := []byte("0123456789abcdef")
+	argonHash := fmt.Sprintf("$argon2id$v=%d$m=65536,t=1,p=2$%s$%s", argon2.Version,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(argon2.IDKey([]byte("secret"), salt, 1, 65536, 2, 32)))
+
+	for _, stored := range []string{bcryptHash, argonHash, "secret"} {
+		if !CheckPassword(stored, "secret") {
+			t.Errorf("CheckPassword(%q, secret) = false", stored)
+		}
+		if CheckPassword(stored, "wrong") {
+			t.Errorf("CheckPassword(%q, wrong) = true", stored)
+		}
+	}
+	if !IsHash(bcryptHash) || !IsHash(argonHash) || IsHash("secret") {
+		t.Error("IsHash() does not tell hashes from plaintext")
+	}
+	if CheckPassword("$argon2id$v=19$m=x$salt$hash", "secret") {
+		t.Error("CheckPassword() accepted a malformed argon2id hash")
+	}
+}
+
+func TestAuthenticator(t *testing.T) {
+	storage := newTestStorage(t)
+	ctx := context.Background()
+	if _, err := storage.Create(ctx, "stored", "stored-secret", []string{"stored.example"}); err != nil {
+		t.Fatal(err)
+	}
+	// A stored user shadowed by a config user is never checked
+	if _, err := storage.Create(ctx, "app", "store-password", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	hookCalls := 0
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hookCalls++
+		if r.Header.Get("Authorization") != "Bearer hook-token" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer hook.Close()
+
+	cfg := &config.AuthConfig{
+		Users:   map[string]string{"app": "app-secret"},
+		Senders: map[string][]string{"app": {"app.example"}},
+	}
+	a := New(cfg, storage, NewHook(config.AuthHookConfig{URL: hook.URL, Headers: map[string]string{"Authorization": "Bearer hook-token"}}))
+
+	tests := []struct {
+		username, password string
+		source             string
+		err                error
+	}{
+		{"app", "app-secret", SourceConfig, nil},
+		{"app", "store-password", "", ErrInvalidCredentials},
+		{"stored", "stored-secret", SourceStore, nil},
+		{"stored", "wrong", "", ErrInvalidCredentials},
+		{"other", "x", "", ErrInvalidCredentials},
+	}
+	for _, tt := range tests {
+		id, err := a.Authenticate(ctx, tt.username, tt.password, "192.0.2.1")
+		if !errors.Is(err, tt.err) {
+			t.Errorf("Authenticate(%s, %s) error = %v, want %v", tt.username, tt.password, err, tt.err)
+			continue
+		}
+		if err == nil && (id.Source != tt.source || len(id.Senders) != 1) {
+			t.Errorf("Authenticate(%s) = %+v, want source %s with senders", tt.username, id, tt.source)
+		}
+	}
+	if hookCalls != 1 {
+		t.Errorf("hook calls = %d, want 1 for the unknown user", hookCalls)
+	}
+
+	// A reload replaces the config users, e.g. a rotated password
+	a.SetConfig(&config.AuthConfig{Users: map[string]string{"app": "rotated-secret"}})
+	if _, err := a.Authenticate(ctx, "app", "app-secret", "192.0.2.1"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("old password after SetConfig() error = %v, want ErrInvalidCredentials", err)
+	}
+	if id, err := a.Authenticate(ctx, "app", "rotated-secret", "192.0.2.1"); err != nil || id.Source != SourceConfig {
+		t.Errorf("new password after SetConfig() = %+v, %v", id, err)
+	}
+
+	if New(&config.AuthConfig{}, nil, nil).Configured() {
+		t.Error("Configured() without users = true")
+	}
+}
@@ -0,0 +1,260 @@
+package smtpauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/foxzi/sendry/internal/email"
+)
+
+var bucketUsers = []byte("smtp_users")
+
+// minPasswordLength is the shortest password of a stored user
+const minPasswordLength = 8
+
+var (
+	// ErrNotFound is returned when a user does not exist
+	ErrNotFound = errors.New("smtp user not found")
+	// ErrExists is returned when creating a user that exists
+	ErrExists = errors.New("smtp user already exists")
+)
+
+// User is an SMTP user of the credential store. The password hash is never
+// returned.
+type User struct {
+	Username          string    `json:"username"`
+	Senders           []string  `json:"senders,omitempty"` // Domains, *.domains and addresses the user may send as, empty for any
+	Disabled          bool      `json:"disabled"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+	PasswordChangedAt time.Time `json:"password_changed_at"`
+}
+
+// record is the stored form of a user
+type record struct {
+	User
+	Hash string `json:"hash"`
+}
+
+// Update changes a stored user, nil fields are kept
+type Update struct {
+	Password *string
+	Disabled *bool
+	Senders  *[]string
+}
+
+// Storage stores SMTP users with bcrypt password hashes in BoltDB
+type Storage struct {
+	db  *bolt.DB
+	now func() time.Time
+}
+
+// NewStorage creates a new SMTP user storage using the provided BoltDB
+// instance
+func NewStorage(db *bolt.DB) (*Storage, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucketUsers)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create smtp users bucket: %w", err)
+	}
+	return &Storage{db: db, now: time.Now}, nil
+}
+
+// checkUsername checks that a username can be sent in AUTH PLAIN
+func checkUsername(username string) error {
+	if username == "" {
+		return fmt.Errorf("username is required")
+	}
+	if len(username) > 255 {
+		return fmt.Errorf("username is too long")
+	}
+	for _, c := range username {
+		if unicode.IsSpace(c) || unicode.IsControl(c) {
+			return fmt.Errorf("username must not contain spaces or control characters")
+		}
+	}
+	return nil
+}
+
+// hashNewPassword checks and hashes the password of a stored user
+func hashNewPassword(password string) (string, error) {
+	if len(password) < minPasswordLength {
+		return "", fmt.Errorf("password must be at least %d characters", minPasswordLength)
+	}
+	return HashPassword(password)
+}
+
+// Create stores a new user
+func (s *Storage) Create(ctx context.Context, username, password string, senders []string) (*User, error) {
+	username = strings.TrimSpace(username)
+	if err := checkUsername(username); err != nil {
+		return nil, err
+	}
+	senders, err := email.NormalizeSenders(senders)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := hashNewPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	rec := &record{
+		User: User{
+			Username:          username,
+			Senders:           senders,
+			CreatedAt:         now,
+			UpdatedAt:         now,
+			PasswordChangedAt: now,
+		},
+		Hash: hash,
+	}
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(bucketUsers).Get([]byte(username)) != nil {
+			return ErrExists
+		}
+		return putRecord(tx, rec)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &rec.User, nil
+}
+
+// Get returns a user
+func (s *Storage) Get(ctx context.Context, username string) (*User, error) {
+	var rec *record
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		rec, err = getRecord(tx, username)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &rec.User, nil
+}
+
+// List returns all users sorted by username
+func (s *Storage) List(ctx context.Context) ([]*User, error) {
+	var users []*User
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketUsers).ForEach(func(k, v []byte) error {
+			var rec record
+			if err := json.Unmarshal(v, &rec); err != nil {
+				return nil // Skip corrupted entries
+			}
+			users = append(users, &rec.User)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Username < users[j].Username })
+	return users, nil
+}
+
+// Update rotates the password, disables or enables a user or changes its
+// senders
+func (s *Storage) Update(ctx context.Context, username string, u Update) (*User, error) {
+	var hash string
+	if u.Password != nil {
+		var err error
+		if hash, err = hashNewPassword(*u.Password); err != nil {
+			return nil, err
+		}
+	}
+	var senders []string
+	if u.Senders != nil {
+		var err error
+		if senders, err = email.NormalizeSenders(*u.Senders); err != nil {
+			return nil, err
+		}
+	}
+
+	var rec *record
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		var err error
+		if rec, err = getRecord(tx, username); err != nil {
+			return err
+		}
+		now := s.now()
+		if u.Password != nil {
+			rec.Hash = hash
+			rec.PasswordChangedAt = now
+		}
+		if u.Disabled != nil {
+			rec.Disabled = *u.Disabled
+		}
+		if u.Senders != nil {
+			rec.Senders = senders
+		}
+		rec.UpdatedAt = now
+		return putRecord(tx, rec)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &rec.User, nil
+}
+
+// Delete removes a user
+func (s *Storage) Delete(ctx context.Context, username string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketUsers)
+		if b.Get([]byte(username)) == nil {
+			return ErrNotFound
+		}
+		return b.Delete([]byte(username))
+	})
+}
+
+// Verify checks the password of a user. It returns ErrNotFound for unknown
+// users and ErrInvalidCredentials for a wrong password or a disabled user.
+func (s *Storage) Verify(ctx context.Context, username, password string) (*User, error) {
+	var rec *record
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		rec, err = getRecord(tx, username)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if rec.Disabled || !CheckPassword(rec.Hash, password) {
+		return nil, ErrInvalidCredentials
+	}
+	return &rec.User, nil
+}
+
+func getRecord(tx *bolt.Tx, username string) (*record, error) {
+	data := tx.Bucket(bucketUsers).Get([]byte(username))
+	if data == nil {
+		return nil, ErrNotFound
+	}
+	var rec record
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("failed to decode smtp user: %w", err)
+	}
+	return &rec, nil
+}
+
+func putRecord(tx *bolt.Tx, rec *record) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(bucketUsers).Put([]byte(rec.Username), data)
+}
@@ -0,0 +1,98 @@
+package smtpauth
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func newTestStorage(t *testing.T) *Storage {
+	t.Helper()
+
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	storage, err := NewStorage(db)
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+	return storage
+}
+
+func TestStorageUsers(t *testing.T) {
+	storage := newTestStorage(t)
+	ctx := context.Background()
+
+	user, err := storage.Create(ctx, "billing", "first-secret", []string{"Billing.Example"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if len(user.Senders) != 1 || user.Senders[0] != "billing.example" {
+		t.Errorf("Senders = %v, want normalized", user.Senders)
+	}
+	if _, err := storage.Create(ctx, "billing", "other-secret", nil); !errors.Is(err, ErrExists) {
+		t.Errorf("Create(duplicate) error = %v, want ErrExists", err)
+	}
+	for _, bad := range []struct{ name, password string }{{"", "long-enough"}, {"a b", "long-enough"}, {"short", "abc"}} {
+		if _, err := storage.Create(ctx, bad.name, bad.password, nil); err == nil {
+			t.Errorf("Create(%q, %q) succeeded", bad.name, bad.password)
+		}
+	}
+
+	if _, err := storage.Verify(ctx, "billing", "first-secret"); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+	if _, err := storage.Verify(ctx, "billing", "wrong"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Verify(wrong password) error = %v, want ErrInvalidCredentials", err)
+	}
+	if _, err := storage.Verify(ctx, "nobody", "x"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Verify(unknown) error = %v, want ErrNotFound", err)
+	}
+
+	// Rotation replaces the password
+	password := "second-secret"
+	if _, err := storage.Update(ctx, "billing", Update{Password: &password}); err != nil {
+		t.Fatalf("Update(password) error = %v", err)
+	}
+	if _, err := storage.Verify(ctx, "billing", "first-secret"); err == nil {
+		t.Error("old password still accepted after rotation")
+	}
+	if _, err := storage.Verify(ctx, "billing", password); err != nil {
+		t.Errorf("Verify(new password) error = %v", err)
+	}
+
+	disabled := true
+	if u, err := storage.Update(ctx, "billing", Update{Disabled: &disabled}); err != nil || !u.Disabled {
+		t.Fatalf("Update(disabled) = %+v, %v", u, err)
+	}
+	if _, err := storage.Verify(ctx, "billing", password); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Verify(disabled) error = %v, want ErrInvalidCredentials", err)
+	}
+
+	if _, err := storage.Create(ctx, "alerts", "alerts-secret", nil); err != nil {
+		t.Fatal(err)
+	}
+	users, err := storage.List(ctx)
+	if err != nil || len(users) != 2 || users[0].Username != "alerts" {
+		t.Errorf("List() = %v, %v, want alerts and billing", users, err)
+	}
+
+	if err := storage.Delete(ctx, "billing"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := storage.Get(ctx, "billing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(deleted) error = %v, want ErrNotFound", err)
+	}
+	if err := storage.Delete(ctx, "billing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Delete(deleted) error = %v, want ErrNotFound", err)
+	}
+	if _, err := storage.Update(ctx, "billing", Update{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Update(deleted) error = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,224 @@
+package stats
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/foxzi/sendry/internal/email"
+	"github.com/foxzi/sendry/internal/queue"
+)
+
+// flushInterval is how often counts and queue sizes are written to storage
+const flushInterval = time.Minute
+
+// QueueStatsProvider provides the current queue sizes
+type QueueStatsProvider interface {
+	Stats(ctx context.Context) (*queue.QueueStats, error)
+}
+
+// Config contains statistics history settings
+type Config struct {
+	MinuteRetention time.Duration // How long minute points are kept
+	Retention       time.Duration // How long hour points are kept
+}
+
+// Recorder counts delivery events and writes them to storage every minute
+// with the queue sizes. It implements the delivery event hooks of the queue.
+type Recorder struct {
+	storage *Storage
+	queue   QueueStatsProvider
+	cfg     Config
+	logger  *slog.Logger
+
+	mu      sync.Mutex
+	pending Point
+	usage   map[string]map[string]*Usage // By report dimension and name
+
+	wg   sync.WaitGroup
+	done chan struct{}
+	now  func() time.Time
+}
+
+// NewRecorder creates a new statistics recorder
+func NewRecorder(storage *Storage, q QueueStatsProvider, cfg Config, logger *slog.Logger) *Recorder {
+	return &Recorder{
+		storage: storage,
+		queue:   q,
+		cfg:     cfg,
+		logger:  logger,
+		done:    make(chan struct{}),
+		now:     time.Now,
+	}
+}
+
+// Accepted counts a message added to the queue
+func (r *Recorder) Accepted(msg *queue.Message) {
+	r.count(msg, func(c *Counts) { c.Accepted++ }, func(u *Usage) { u.Accepted++ })
+}
+
+// Delivered counts the recipients of a message delivered by an attempt
+func (r *Recorder) Delivered(msg *queue.Message, recipients []string) {
+	// Delivery time is counted from creation or the scheduled send time
+	start := msg.CreatedAt
+	if msg.SendAt.After(start) {
+		start = msg.SendAt
+	}
+	var latency int64
+	if !start.IsZero() {
+		latency = max(r.now().Sub(start).Milliseconds(), 0)
+	}
+
+	r.count(msg, func(c *Counts) { c.Delivered += len(recipients) }, func(u *Usage) {
+		u.Delivered += len(recipients)
+		u.LatencyMillis += latency * int64(len(recipients))
+		for _, rcpt := range recipients {
+			u.recipient(email.ExtractDomain(rcpt)).Delivered++
+		}
+	})
+}
+
+// Deferred counts the recipients of a message deferred by an attempt
+func (r *Recorder) Deferred(msg *queue.Message, recipients []string) {
+	r.count(msg, func(c *Counts) { c.Deferred += len(recipients) }, func(u *Usage) {
+		u.Deferred += len(recipients)
+		for _, rcpt := range recipients {
+			u.recipient(email.ExtractDomain(rcpt)).Deferred++
+		}
+	})
+}
+
+// Bounced counts the recipients of a message that failed for good
+func (r *Recorder) Bounced(msg *queue.Message, recipients []string) {
+	r.count(msg, func(c *Counts) { c.Bounced += len(recipients) }, func(u *Usage) {
+		u.Bounced += len(recipients)
+		for _, rcpt := range recipients {
+			u.recipient(email.ExtractDomain(rcpt)).Bounced++
+		}
+	})
+}
+
+func (r *Recorder) count(msg *queue.Message, add func(*Counts), use func(*Usage)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	add(&r.pending.Totals)
+	// Bounces have no sender, they count toward the totals only
+	domain := email.ExtractDomain(msg.From)
+	if domain == "" {
+		return
+	}
+	add(r.pending.domain(domain))
+
+	use(r.use(ByDomain, domain))
+	key := msg.APIKeyID
+	if key == "" {
+		key = NoKey
+	}
+	u := r.use(ByKey, key)
+	if msg.APIKeyName != "" {
+		u.Label = msg.APIKeyName
+	}
+	use(u)
+}
+
+// use returns the pending usage of a name, added if missing
+func (r *Recorder) use(by, name string) *Usage {
+	if r.usage == nil {
+		r.usage = make(map[string]map[string]*Usage)
+	}
+	names, ok := r.usage[by]
+	if !ok {
+		names = make(map[string]*Usage)
+		r.usage[by] = names
+	}
+	u, ok := names[name]
+	if !ok {
+		u = &Usage{}
+		names[name] = u
+	}
+	return u
+}
+
+// Start starts the flush goroutine
+func (r *Recorder) Start(ctx context.Context) {
+	r.wg.Add(1)
+	go r.loop(ctx)
+
+	r.logger.Info("stats history started",
+		"minute_retention", r.cfg.MinuteRetention,
+		"retention", r.cfg.Retention,
+	)
+}
+
+// Stop stops the recorder and writes recorded counts to storage
+func (r *Recorder) Stop() {
+	close(r.done)
+	r.wg.Wait()
+
+	if err := r.Flush(context.Background()); err != nil {
+		r.logger.Error("failed to store stats", "error", err)
+	}
+}
+
+func (r *Recorder) loop(ctx context.Context) {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.done:
+			return
+		case <-ticker.C:
+			if err := r.Flush(ctx); err != nil {
+				r.logger.Error("failed to store stats", "error", err)
+			}
+		}
+	}
+}
+
+// Flush writes the recorded counts and the current queue sizes to the
+// current minute, the recorded usage to the current hour, and prunes
+// expired points
+func (r *Recorder) Flush(ctx context.Context) error {
+	now := r.now()
+
+	r.mu.Lock()
+	p := r.pending
+	r.pending = Point{}
+	usage := r.usage
+	r.usage = nil
+	r.mu.Unlock()
+
+	p.Time = now.Truncate(time.Minute)
+	if r.queue != nil {
+		sizes, err := r.queue.Stats(ctx)
+		if err != nil {
+			// Keep the counts, the sizes are taken again next minute
+			r.logger.Warn("failed to get queue sizes for stats", "error", err)
+		} else {
+			p.Queue = sizes
+		}
+	}
+
+	if err := r.storage.Add(ctx, &p); err != nil {
+		return err
+	}
+	for by, names := range usage {
+		if err := r.storage.AddUsage(ctx, by, p.Time, names); err != nil {
+			return err
+		}
+	}
+
+	if r.cfg.MinuteRetention > 0 && r.cfg.Retention > 0 {
+		if _, err := r.storage.Prune(ctx, now.Add(-r.cfg.MinuteRetention), now.Add(-r.cfg.Retention)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
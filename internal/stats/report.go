@@ -0,0 +1,138 @@
+package stats
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Report dimensions
+const (
+	ByDomain = "domain" // Sending domains
+	ByKey    = "key"    // API keys
+)
+
+// NoKey is the name of messages submitted without an API key, e.g. over
+// SMTP, in key reports
+const NoKey = "-"
+
+// Usage are the counts of a sending domain or API key in an hour
+type Usage struct {
+	Label         string                      `json:"label,omitempty"` // API key name
+	Accepted      int                         `json:"accepted"`
+	Delivered     int                         `json:"delivered"`
+	Deferred      int                         `json:"deferred"`
+	Bounced       int                         `json:"bounced"`
+	LatencyMillis int64                       `json:"latency_ms"` // Sum of the delivery times of delivered recipients
+	Recipients    map[string]*RecipientCounts `json:"recipients,omitempty"`
+}
+
+// RecipientCounts are the delivery outcomes of the recipients at a domain
+type RecipientCounts struct {
+	Delivered int `json:"delivered"`
+	Deferred  int `json:"deferred"`
+	Bounced   int `json:"bounced"`
+}
+
+// Add adds other to u
+func (u *Usage) Add(other *Usage) {
+	if other.Label != "" {
+		u.Label = other.Label
+	}
+	u.Accepted += other.Accepted
+	u.Delivered += other.Delivered
+	u.Deferred += other.Deferred
+	u.Bounced += other.Bounced
+	u.LatencyMillis += other.LatencyMillis
+	for domain, c := range other.Recipients {
+		r := u.recipient(domain)
+		r.Delivered += c.Delivered
+		r.Deferred += c.Deferred
+		r.Bounced += c.Bounced
+	}
+}
+
+// recipient returns the counts of a recipient domain, added if missing
+func (u *Usage) recipient(domain string) *RecipientCounts {
+	if u.Recipients == nil {
+		u.Recipients = make(map[string]*RecipientCounts)
+	}
+	c, ok := u.Recipients[domain]
+	if !ok {
+		c = &RecipientCounts{}
+		u.Recipients[domain] = c
+	}
+	return c
+}
+
+// ReportRow is the usage of a sending domain or API key over a report period
+type ReportRow struct {
+	Name                string            `json:"name"`               // Sending domain or API key ID
+	KeyName             string            `json:"key_name,omitempty"` // Name of the API key
+	Sent                int               `json:"sent"`               // Messages accepted
+	Delivered           int               `json:"delivered"`          // Recipients delivered
+	Deferred            int               `json:"deferred"`           // Recipient delivery attempts deferred
+	Bounced             int               `json:"bounced"`            // Recipients that failed for good
+	AvgDeliverySeconds  float64           `json:"avg_delivery_seconds"`
+	TopRecipientDomains []RecipientDomain `json:"top_recipient_domains"`
+}
+
+// RecipientDomain are the delivery outcomes of a recipient domain
+type RecipientDomain struct {
+	Domain    string `json:"domain"`
+	Delivered int    `json:"delivered"`
+	Deferred  int    `json:"deferred"`
+	Bounced   int    `json:"bounced"`
+}
+
+// NewReportRow returns the report row of the usage of a name, with its top
+// recipient domains by number of recipients
+func NewReportRow(name string, u *Usage, top int) ReportRow {
+	row := ReportRow{
+		Name:                name,
+		KeyName:             u.Label,
+		Sent:                u.Accepted,
+		Delivered:           u.Delivered,
+		Deferred:            u.Deferred,
+		Bounced:             u.Bounced,
+		TopRecipientDomains: []RecipientDomain{},
+	}
+	if u.Delivered > 0 {
+		avg := time.Duration(u.LatencyMillis/int64(u.Delivered)) * time.Millisecond
+		row.AvgDeliverySeconds = avg.Round(10 * time.Millisecond).Seconds()
+	}
+
+	for domain, c := range u.Recipients {
+		row.TopRecipientDomains = append(row.TopRecipientDomains, RecipientDomain{
+			Domain:    domain,
+			Delivered: c.Delivered,
+			Deferred:  c.Deferred,
+			Bounced:   c.Bounced,
+		})
+	}
+	sort.Slice(row.TopRecipientDomains, func(i, j int) bool {
+		a, b := row.TopRecipientDomains[i], row.TopRecipientDomains[j]
+		if ta, tb := a.Delivered+a.Deferred+a.Bounced, b.Delivered+b.Deferred+b.Bounced; ta != tb {
+			return ta > tb
+		}
+		return a.Domain < b.Domain
+	})
+	if top > 0 && len(row.TopRecipientDomains) > top {
+		row.TopRecipientDomains = row.TopRecipientDomains[:top]
+	}
+	return row
+}
+
+// usageKey returns the key of the usage of a name in an hour
+func usageKey(by, name string, hour time.Time) []byte {
+	return []byte(by + "\x00" + name + "\x00" + hour.UTC().Format(keyLayout))
+}
+
+// parseUsageKey returns the name and hour of a usage key
+func parseUsageKey(key []byte) (name, hour string, ok bool) {
+	parts := strings.Split(string(key), "\x00")
+	if len(parts) != 3 {
+		return "", "", false
+	}
+	return parts[1], parts[2], true
+}
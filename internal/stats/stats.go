@@ -0,0 +1,118 @@
+// Package stats keeps a history of queue sizes and delivery counts for
+// dashboards: points per minute for recent periods and per hour for longer
+// ones.
+package stats
+
+import (
+	"time"
+
+	"github.com/foxzi/sendry/internal/queue"
+)
+
+// MaxPoints is the most points a history returns, longer ranges get a
+// longer step
+const MaxPoints = 1000
+
+// steps are the steps picked for a range, shortest first
+var steps = []time.Duration{
+	time.Minute,
+	5 * time.Minute,
+	15 * time.Minute,
+	time.Hour,
+	6 * time.Hour,
+	24 * time.Hour,
+}
+
+// Counts are the delivery counts of a period. Accepted counts messages,
+// the others count recipients.
+type Counts struct {
+	Accepted  int `json:"accepted"`
+	Delivered int `json:"delivered"`
+	Deferred  int `json:"deferred"`
+	Bounced   int `json:"bounced"`
+}
+
+// Add adds other to c
+func (c *Counts) Add(other *Counts) {
+	c.Accepted += other.Accepted
+	c.Delivered += other.Delivered
+	c.Deferred += other.Deferred
+	c.Bounced += other.Bounced
+}
+
+// Point is the statistics of a period
+type Point struct {
+	Time    time.Time          `json:"time"`              // Start of the period
+	Queue   *queue.QueueStats  `json:"queue,omitempty"`   // Queue sizes at the end of the period
+	Totals  Counts             `json:"totals"`            // Counts of all messages
+	Domains map[string]*Counts `json:"domains,omitempty"` // Counts per sender domain
+}
+
+// Add adds the counts of other to p and takes its queue sizes, other is
+// the later point
+func (p *Point) Add(other *Point) {
+	if other.Queue != nil {
+		sizes := *other.Queue
+		p.Queue = &sizes
+	}
+	p.Totals.Add(&other.Totals)
+	for domain, c := range other.Domains {
+		p.domain(domain).Add(c)
+	}
+}
+
+// domain returns the counts of a sender domain, added if missing
+func (p *Point) domain(name string) *Counts {
+	if p.Domains == nil {
+		p.Domains = make(map[string]*Counts)
+	}
+	c, ok := p.Domains[name]
+	if !ok {
+		c = &Counts{}
+		p.Domains[name] = c
+	}
+	return c
+}
+
+// Step returns the shortest step that splits a range into at most
+// MaxPoints points
+func Step(rng time.Duration) time.Duration {
+	for _, step := range steps {
+		if rng/step <= MaxPoints {
+			return step
+		}
+	}
+	return steps[len(steps)-1]
+}
+
+// Downsample merges points, oldest first, into periods of step. Periods
+// start at multiples of step since the zero time, so days start at
+// midnight UTC.
+func Downsample(points []Point, step time.Duration) []Point {
+	var result []Point
+	for i := range points {
+		start := points[i].Time.Truncate(step)
+		if n := len(result); n > 0 && result[n-1].Time.Equal(start) {
+			result[n-1].Add(&points[i])
+			continue
+		}
+		p := Point{Time: start}
+		p.Add(&points[i])
+		result = append(result, p)
+	}
+	return result
+}
+
+// ForDomain returns points with the counts of a sender domain as totals
+// and without the other domains. Queue sizes are kept, they are not known
+// per domain.
+func ForDomain(points []Point, domain string) []Point {
+	result := make([]Point, len(points))
+	for i, p := range points {
+		result[i] = Point{Time: p.Time, Queue: p.Queue}
+		if c, ok := p.Domains[domain]; ok {
+			result[i].Totals = *c
+		}
+	}
+	return result
+}
@@ -0,0 +1,186 @@
+package stats
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/foxzi/sendry/internal/queue"
+)
+
+func newTestStorage(t *testing.T) *Storage {
+	t.Helper()
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	s, err := NewStorage(db)
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+	return s
+}
+
+func TestStep(t *testing.T) {
+	tests := []struct {
+		rng  time.Duration
+		want time.Duration
+	}{
+		{time.Hour, time.Minute},
+		{24 * time.Hour, 5 * time.Minute},
+		{7 * 24 * time.Hour, 15 * time.Minute},
+		{30 * 24 * time.Hour, time.Hour},
+		{90 * 24 * time.Hour, 6 * time.Hour},
+		{2000 * 24 * time.Hour, 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := Step(tt.rng); got != tt.want {
+			t.Errorf("Step(%v) = %v, want %v", tt.rng, got, tt.want)
+		}
+	}
+}
+
+func TestDownsample(t *testing.T) {
+	base := time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)
+	points := []Point{
+		{Time: base, Queue: &queue.QueueStats{Pending: 5}, Totals: Counts{Accepted: 2}, Domains: map[string]*Counts{"a.com": {Accepted: 2}}},
+		{Time: base.Add(2 * time.Minute), Queue: &queue.QueueStats{Pending: 3}, Totals: Counts{Delivered: 4}, Domains: map[string]*Counts{"a.com": {Delivered: 1}, "b.com": {Delivered: 3}}},
+		{Time: base.Add(7 * time.Minute), Totals: Counts{Bounced: 1}},
+	}
+
+	got := Downsample(points, 5*time.Minute)
+	if len(got) != 2 {
+		t.Fatalf("Downsample() = %d points, want 2", len(got))
+	}
+	first := got[0]
+	if !first.Time.Equal(base) || first.Totals != (Counts{Accepted: 2, Delivered: 4}) {
+		t.Errorf("first point = %+v", first)
+	}
+	if first.Queue == nil || first.Queue.Pending != 3 {
+		t.Errorf("first point queue = %+v, want the last sizes", first.Queue)
+	}
+	if *first.Domains["a.com"] != (Counts{Accepted: 2, Delivered: 1}) || first.Domains["b.com"].Delivered != 3 {
+		t.Errorf("first point domains = %+v", first.Domains)
+	}
+	if !got[1].Time.Equal(base.Add(5*time.Minute)) || got[1].Queue != nil || got[1].Totals.Bounced != 1 {
+		t.Errorf("second point = %+v", got[1])
+	}
+	if points[0].Domains["a.com"].Delivered != 0 {
+		t.Error("Downsample() changed its input")
+	}
+
+	byDomain := ForDomain(got, "b.com")
+	if byDomain[0].Totals.Delivered != 3 || byDomain[0].Domains != nil || byDomain[0].Queue == nil || byDomain[1].Totals != (Counts{}) {
+		t.Errorf("ForDomain() = %+v", byDomain)
+	}
+}
+
+type fakeQueue struct {
+	stats *queue.QueueStats
+	err   error
+}
+
+func (f *fakeQueue) Stats(ctx context.Context) (*queue.QueueStats, error) {
+	return f.stats, f.err
+}
+
+func TestRecorder(t *testing.T) {
+	storage := newTestStorage(t)
+	q := &fakeQueue{stats: &queue.QueueStats{Pending: 7, Total: 7}}
+	r := NewRecorder(storage, q, Config{MinuteRetention: time.Hour, Retention: 24 * time.Hour}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	now := time.Now().Truncate(time.Minute).Add(30 * time.Second)
+	r.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	msg := &queue.Message{ID: "m1", From: "news@Example.com", To: []string{"a@gmail.com", "b@gmail.com"}}
+	r.Accepted(msg)
+	r.Delivered(msg, []string{"a@gmail.com"})
+	r.Deferred(msg, []string{"b@gmail.com"})
+	r.Bounced(&queue.Message{ID: "dsn", To: []string{"x@example.org"}}, []string{"x@example.org"})
+	if err := r.Flush(ctx); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	// A failed size query keeps the counts and the earlier sizes
+	q.err = errors.New("busy")
+	r.Accepted(msg)
+	if err := r.Flush(ctx); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	points, err := storage.Minutes(ctx, now.Add(-time.Hour), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 1 {
+		t.Fatalf("Minutes() = %d points, want 1", len(points))
+	}
+	p := points[0]
+	want := Counts{Accepted: 2, Delivered: 1, Deferred: 1, Bounced: 1}
+	if p.Totals != want || !p.Time.Equal(now.Truncate(time.Minute)) {
+		t.Errorf("point = %+v, want totals %+v", p, want)
+	}
+	if c := p.Domains["example.com"]; c == nil || *c != (Counts{Accepted: 2, Delivered: 1, Deferred: 1}) || len(p.Domains) != 1 {
+		t.Errorf("domains = %+v", p.Domains)
+	}
+	if p.Queue == nil || p.Queue.Pending != 7 {
+		t.Errorf("queue = %+v", p.Queue)
+	}
+}
+
+func TestRecorderUsage(t *testing.T) {
+	storage := newTestStorage(t)
+	r := NewRecorder(storage, nil, Config{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	now := time.Date(2026, 5, 3, 12, 30, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	msg := &queue.Message{
+		ID:         "m1",
+		From:       "news@example.com",
+		To:         []string{"a@gmail.com", "b@gmail.com", "c@yahoo.com"},
+		CreatedAt:  now.Add(-time.Minute),
+		APIKeyID:   "k1",
+		APIKeyName: "billing",
+	}
+	r.Accepted(msg)
+	r.Delivered(msg, []string{"a@gmail.com", "b@gmail.com"})
+	r.Bounced(msg, []string{"c@yahoo.com"})
+	r.Accepted(&queue.Message{ID: "m2", From: "alerts@example.com", To: []string{"d@gmail.com"}})
+	r.Bounced(&queue.Message{ID: "dsn", To: []string{"x@example.org"}}, []string{"x@example.org"})
+	if err := r.Flush(ctx); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	rows, err := storage.Report(ctx, ByDomain, now.Add(-time.Hour), now, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("domain report = %+v, want example.com only", rows)
+	}
+	row := rows[0]
+	if row.Name != "example.com" || row.Sent != 2 || row.Delivered != 2 || row.Bounced != 1 || row.AvgDeliverySeconds != 60 {
+		t.Errorf("domain row = %+v", row)
+	}
+	if len(row.TopRecipientDomains) != 2 || row.TopRecipientDomains[0] != (RecipientDomain{Domain: "gmail.com", Delivered: 2}) {
+		t.Errorf("recipient domains = %+v", row.TopRecipientDomains)
+	}
+
+	rows, err = storage.Report(ctx, ByKey, now.Add(-time.Hour), now, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Ties are ordered by name
+	if len(rows) != 2 || rows[0].Name != NoKey || rows[0].Sent != 1 || rows[1].Name != "k1" || rows[1].KeyName != "billing" || rows[1].Delivered != 2 {
+		t.Errorf("key report = %+v", rows)
+	}
+}
@@ -0,0 +1,282 @@
+package stats
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	bucketMinutes = []byte("stats_minutes")
+	bucketHours   = []byte("stats_hours")
+	bucketUsage   = []byte("stats_usage")
+)
+
+// keyLayout is the period of point keys, keys sort by time
+const keyLayout = "2006-01-02T15:04"
+
+// Storage provides statistics history storage
+type Storage struct {
+	db *bolt.DB
+}
+
+// NewStorage creates a new statistics storage using the provided BoltDB instance
+func NewStorage(db *bolt.DB) (*Storage, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{bucketMinutes, bucketHours, bucketUsage} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stats buckets: %w", err)
+	}
+	return &Storage{db: db}, nil
+}
+
+// timeKey returns the key of the period starting at t
+func timeKey(t time.Time) []byte {
+	return []byte(t.UTC().Format(keyLayout))
+}
+
+// Add adds a point to its minute and to its hour
+func (s *Storage) Add(ctx context.Context, p *Point) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := merge(tx.Bucket(bucketMinutes), p.Time.Truncate(time.Minute), p); err != nil {
+			return err
+		}
+		return merge(tx.Bucket(bucketHours), p.Time.Truncate(time.Hour), p)
+	})
+}
+
+// merge adds a point to the point of a period
+func merge(b *bolt.Bucket, start time.Time, p *Point) error {
+	key := timeKey(start)
+	total := Point{Time: start.UTC()}
+	if data := b.Get(key); data != nil {
+		if err := json.Unmarshal(data, &total); err != nil {
+			return fmt.Errorf("failed to unmarshal stats point: %w", err)
+		}
+	}
+	total.Add(p)
+
+	data, err := json.Marshal(&total)
+	if err != nil {
+		return fmt.Errorf("failed to marshal stats point: %w", err)
+	}
+	return b.Put(key, data)
+}
+
+// Minutes returns the minute points from from to to, oldest first
+func (s *Storage) Minutes(ctx context.Context, from, to time.Time) ([]Point, error) {
+	return s.points(bucketMinutes, from.Truncate(time.Minute), to)
+}
+
+// Hours returns the hour points from from to to, oldest first
+func (s *Storage) Hours(ctx context.Context, from, to time.Time) ([]Point, error) {
+	return s.points(bucketHours, from.Truncate(time.Hour), to)
+}
+
+func (s *Storage) points(bucket []byte, from, to time.Time) ([]Point, error) {
+	var points []Point
+	end := timeKey(to)
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucket).Cursor()
+		for k, v := c.Seek(timeKey(from)); k != nil && bytes.Compare(k, end) <= 0; k, v = c.Next() {
+			var p Point
+			if err := json.Unmarshal(v, &p); err != nil {
+				continue // Skip invalid entries
+			}
+			points = append(points, p)
+		}
+		return nil
+	})
+
+	return points, err
+}
+
+// History returns the points from from to to merged into periods of step,
+// oldest first, and the step used. Minute points are used for steps below
+// an hour when they cover from; otherwise hour points are, with a step of
+// at least an hour.
+func (s *Storage) History(ctx context.Context, from, to time.Time, step time.Duration) ([]Point, time.Duration, error) {
+	if step < time.Hour {
+		oldest, err := s.oldest(bucketMinutes)
+		if err != nil {
+			return nil, 0, err
+		}
+		if oldest != nil && bytes.Compare(oldest, timeKey(from.Truncate(time.Minute))) <= 0 {
+			points, err := s.Minutes(ctx, from, to)
+			if err != nil {
+				return nil, 0, err
+			}
+			return Downsample(points, step), step, nil
+		}
+		step = time.Hour
+	}
+
+	points, err := s.Hours(ctx, from, to)
+	if err != nil {
+		return nil, 0, err
+	}
+	return Downsample(points, step), step, nil
+}
+
+// oldest returns the key of the oldest point of a bucket, nil if it is empty
+func (s *Storage) oldest(bucket []byte) ([]byte, error) {
+	var key []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if k, _ := tx.Bucket(bucket).Cursor().First(); k != nil {
+			key = append([]byte(nil), k...)
+		}
+		return nil
+	})
+	return key, err
+}
+
+// AddUsage adds the usage of sending domains or API keys, by, to an hour
+func (s *Storage) AddUsage(ctx context.Context, by string, hour time.Time, usage map[string]*Usage) error {
+	if len(usage) == 0 {
+		return nil
+	}
+	hour = hour.Truncate(time.Hour)
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketUsage)
+		for name, u := range usage {
+			key := usageKey(by, name, hour)
+
+			var total Usage
+			if data := b.Get(key); data != nil {
+				if err := json.Unmarshal(data, &total); err != nil {
+					return fmt.Errorf("failed to unmarshal usage: %w", err)
+				}
+			}
+			total.Add(u)
+
+			data, err := json.Marshal(&total)
+			if err != nil {
+				return fmt.Errorf("failed to marshal usage: %w", err)
+			}
+			if err := b.Put(key, data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Report returns the usage of sending domains or API keys, by, in the hours
+// from from up to to, ordered by messages sent. top limits the recipient
+// domains of a row, 0 keeps all.
+func (s *Storage) Report(ctx context.Context, by string, from, to time.Time, top int) ([]ReportRow, error) {
+	first := from.Truncate(time.Hour).UTC().Format(keyLayout)
+	end := to.UTC().Format(keyLayout)
+	prefix := []byte(by + "\x00")
+	totals := make(map[string]*Usage)
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucketUsage).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			name, hour, ok := parseUsageKey(k)
+			if !ok || hour < first || hour >= end {
+				continue
+			}
+			var u Usage
+			if err := json.Unmarshal(v, &u); err != nil {
+				continue // Skip invalid entries
+			}
+			total, ok := totals[name]
+			if !ok {
+				total = &Usage{}
+				totals[name] = total
+			}
+			total.Add(&u)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]ReportRow, 0, len(totals))
+	for name, u := range totals {
+		rows = append(rows, NewReportRow(name, u, top))
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Sent != rows[j].Sent {
+			return rows[i].Sent > rows[j].Sent
+		}
+		return rows[i].Name < rows[j].Name
+	})
+	return rows, nil
+}
+
+// Prune deletes minute points older than minutesBefore, and hour points
+// and usage older than hoursBefore
+func (s *Storage) Prune(ctx context.Context, minutesBefore, hoursBefore time.Time) (int, error) {
+	deleted := 0
+	hourCutoff := timeKey(hoursBefore.Truncate(time.Hour))
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		n, err := prune(tx.Bucket(bucketMinutes), timeKey(minutesBefore))
+		if err != nil {
+			return err
+		}
+		deleted += n
+
+		n, err = prune(tx.Bucket(bucketHours), hourCutoff)
+		if err != nil {
+			return err
+		}
+		deleted += n
+
+		n, err = pruneUsage(tx.Bucket(bucketUsage), string(hourCutoff))
+		deleted += n
+		return err
+	})
+
+	return deleted, err
+}
+
+// pruneUsage deletes the usage of hours before cutoff
+func pruneUsage(b *bolt.Bucket, cutoff string) (int, error) {
+	var stale [][]byte
+	err := b.ForEach(func(k, v []byte) error {
+		if _, hour, ok := parseUsageKey(k); !ok || hour < cutoff {
+			stale = append(stale, append([]byte(nil), k...))
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, k := range stale {
+		if err := b.Delete(k); err != nil {
+			return 0, err
+		}
+	}
+	return len(stale), nil
+}
+
+// prune deletes the points of a bucket before cutoff
+func prune(b *bolt.Bucket, cutoff []byte) (int, error) {
+	deleted := 0
+	c := b.Cursor()
+	for k, _ := c.First(); k != nil && bytes.Compare(k, cutoff) < 0; k, _ = c.First() {
+		if err := c.Delete(); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
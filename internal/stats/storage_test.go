@@ -0,0 +1,181 @@
+package stats
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/foxzi/sendry/internal/queue"
+)
+
+func TestStorageAdd(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	base := time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	for i, m := range []time.Duration{0, 0, 59} {
+		p := &Point{Time: base.Add(m * time.Minute), Queue: &queue.QueueStats{Pending: int64(i)}, Totals: Counts{Accepted: 1}}
+		if err := s.Add(ctx, p); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+
+	minutes, err := s.Minutes(ctx, base, base.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(minutes) != 2 || minutes[0].Totals.Accepted != 2 || minutes[0].Queue.Pending != 1 || minutes[1].Totals.Accepted != 1 {
+		t.Errorf("Minutes() = %+v, want the first minute merged", minutes)
+	}
+
+	hours, err := s.Hours(ctx, base, base.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hours) != 1 || !hours[0].Time.Equal(base) || hours[0].Totals.Accepted != 3 || hours[0].Queue.Pending != 2 {
+		t.Errorf("Hours() = %+v, want one hour with all counts and the last sizes", hours)
+	}
+}
+
+func TestStorageHistory(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	now := time.Date(2026, 5, 3, 12, 0, 0, 0, time.UTC)
+
+	// Two days of hours, minutes only for the last hour
+	for h := 48; h > 0; h-- {
+		if err := s.Add(ctx, &Point{Time: now.Add(-time.Duration(h) * time.Hour), Totals: Counts{Delivered: 1}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.Prune(ctx, now, now.Add(-72*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	for m := 60; m > 0; m-- {
+		if err := s.Add(ctx, &Point{Time: now.Add(-time.Duration(m) * time.Minute), Totals: Counts{Delivered: 1}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	points, step, err := s.History(ctx, now.Add(-time.Hour), now, 5*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if step != 5*time.Minute || len(points) != 12 || points[0].Totals.Delivered != 5 {
+		t.Errorf("History(1h, 5m) = %d points, step %v", len(points), step)
+	}
+
+	// Minutes do not cover a day, hours are used
+	points, step, err = s.History(ctx, now.Add(-24*time.Hour), now, 5*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if step != time.Hour || len(points) != 24 {
+		t.Errorf("History(24h, 5m) = %d points, step %v, want 24 hours", len(points), step)
+	}
+
+	points, step, err = s.History(ctx, now.Add(-48*time.Hour), now, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := 0
+	for _, p := range points {
+		total += p.Totals.Delivered
+	}
+	// Days start at midnight, so two days from noon span three
+	if step != 24*time.Hour || len(points) != 3 || total != 108 {
+		t.Errorf("History(48h, 1d) = %+v, step %v", points, step)
+	}
+}
+
+func TestStoragePrune(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	now := time.Date(2026, 5, 3, 12, 30, 0, 0, time.UTC)
+
+	for _, age := range []time.Duration{3 * time.Hour, 90 * time.Minute, time.Minute} {
+		if err := s.Add(ctx, &Point{Time: now.Add(-age), Totals: Counts{Accepted: 1}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deleted, err := s.Prune(ctx, now.Add(-time.Hour), now.Add(-2*time.Hour))
+	if err != nil {
+		t.Fatalf("Prune() error = %v", err)
+	}
+	// Two minutes and the hour of 09:30
+	if deleted != 3 {
+		t.Errorf("Prune() deleted %d, want 3", deleted)
+	}
+	minutes, _ := s.Minutes(ctx, now.Add(-24*time.Hour), now)
+	hours, _ := s.Hours(ctx, now.Add(-24*time.Hour), now)
+	if len(minutes) != 1 || len(hours) != 2 {
+		t.Errorf("kept %d minutes and %d hours, want 1 and 2", len(minutes), len(hours))
+	}
+}
+
+func TestStorageReport(t *testing.T) {
+	s := newTestStorage(t)
+	ctx := context.Background()
+	now := time.Date(2026, 5, 3, 12, 30, 0, 0, time.UTC)
+
+	add := func(at time.Time, usage map[string]*Usage) {
+		t.Helper()
+		if err := s.AddUsage(ctx, ByDomain, at, usage); err != nil {
+			t.Fatalf("AddUsage() error = %v", err)
+		}
+	}
+	add(now, map[string]*Usage{
+		"a.com": {Accepted: 1, Delivered: 1, LatencyMillis: 1000, Recipients: map[string]*RecipientCounts{"gmail.com": {Delivered: 1}}},
+		"b.com": {Accepted: 3, Deferred: 2, Recipients: map[string]*RecipientCounts{"yahoo.com": {Deferred: 2}}},
+	})
+	add(now.Add(-10*time.Minute), map[string]*Usage{
+		"a.com": {Accepted: 1, Delivered: 1, LatencyMillis: 2000, Recipients: map[string]*RecipientCounts{"gmail.com": {Delivered: 1}}},
+	})
+	add(now.Add(-3*time.Hour), map[string]*Usage{"a.com": {Accepted: 5}})
+	if err := s.AddUsage(ctx, ByKey, now, map[string]*Usage{"k1": {Accepted: 9}}); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := s.Report(ctx, ByDomain, now.Add(-time.Hour), now, 0)
+	if err != nil {
+		t.Fatalf("Report() error = %v", err)
+	}
+	if len(rows) != 2 || rows[0].Name != "b.com" || rows[1].Name != "a.com" {
+		t.Fatalf("Report() = %+v, want b.com then a.com", rows)
+	}
+	a := rows[1]
+	if a.Sent != 2 || a.Delivered != 2 || a.AvgDeliverySeconds != 1.5 || len(a.TopRecipientDomains) != 1 || a.TopRecipientDomains[0].Delivered != 2 {
+		t.Errorf("a.com = %+v", a)
+	}
+
+	// Older hours are included by a longer period, other dimensions never
+	rows, _ = s.Report(ctx, ByDomain, now.Add(-24*time.Hour), now, 0)
+	if len(rows) != 2 || rows[0].Name != "a.com" || rows[0].Sent != 7 {
+		t.Errorf("Report(24h) = %+v", rows)
+	}
+
+	if _, err := s.Prune(ctx, now, now.Add(-2*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	rows, _ = s.Report(ctx, ByDomain, now.Add(-24*time.Hour), now, 0)
+	if len(rows) != 2 || rows[1].Sent != 2 {
+		t.Errorf("Report() after Prune() = %+v", rows)
+	}
+}
+
+func TestNewReportRow(t *testing.T) {
+	u := &Usage{Label: "billing", Accepted: 3, Delivered: 3, LatencyMillis: 1234, Recipients: map[string]*RecipientCounts{
+		"a.com": {Delivered: 1},
+		"b.com": {Delivered: 1, Bounced: 2},
+		"c.com": {Delivered: 1},
+	}}
+
+	row := NewReportRow("k1", u, 2)
+	if row.KeyName != "billing" || row.AvgDeliverySeconds != 0.41 {
+		t.Errorf("row = %+v", row)
+	}
+	if len(row.TopRecipientDomains) != 2 || row.TopRecipientDomains[0].Domain != "b.com" || row.TopRecipientDomains[1].Domain != "a.com" {
+		t.Errorf("top recipient domains = %+v", row.TopRecipientDomains)
+	}
+}
@@ -0,0 +1,169 @@
+// Package suppression keeps the recipients that must not receive mail, such
+// as recipients that reported a message as spam.
+package suppression
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var bucketSuppressions = []byte("suppressions")
+
+// Suppression reasons
+const (
+	ReasonComplaint = "complaint"
+	ReasonBounce    = "bounce"
+	ReasonManual    = "manual"
+)
+
+// defaultListLimit is used when a filter has no limit
+const defaultListLimit = 100
+
+// ErrNotFound is returned when an address is not on the suppression list
+var ErrNotFound = errors.New("address is not suppressed")
+
+// Entry is a suppressed recipient address
+type Entry struct {
+	Email     string    `json:"email"`
+	Reason    string    `json:"reason"`           // complaint, bounce or manual
+	Detail    string    `json:"detail,omitempty"` // e.g. the complaint that caused it
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Filter selects suppression list entries
+type Filter struct {
+	Reason string
+	Limit  int
+	Offset int
+}
+
+// Storage stores the suppression list in BoltDB
+type Storage struct {
+	db *bolt.DB
+}
+
+// NewStorage creates a new suppression storage using the provided BoltDB instance
+func NewStorage(db *bolt.DB) (*Storage, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucketSuppressions)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create suppression bucket: %w", err)
+	}
+	return &Storage{db: db}, nil
+}
+
+// Normalize returns the form addresses are stored and looked up in
+func Normalize(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// Add puts an address on the suppression list. An address that is already
+// suppressed keeps its original entry. It returns true if the address was
+// added.
+func (s *Storage) Add(ctx context.Context, e *Entry) (bool, error) {
+	e.Email = Normalize(e.Email)
+	if e.Email == "" || !strings.Contains(e.Email, "@") {
+		return false, fmt.Errorf("invalid email address: %q", e.Email)
+	}
+	if e.Reason == "" {
+		e.Reason = ReasonManual
+	}
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+
+	added := false
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketSuppressions)
+		if b.Get([]byte(e.Email)) != nil {
+			return nil
+		}
+
+		data, err := json.Marshal(e)
+		if err != nil {
+			return fmt.Errorf("failed to marshal suppression: %w", err)
+		}
+		added = true
+		return b.Put([]byte(e.Email), data)
+	})
+	return added, err
+}
+
+// Get returns the suppression entry of an address
+func (s *Storage) Get(ctx context.Context, email string) (*Entry, error) {
+	var e *Entry
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bucketSuppressions).Get([]byte(Normalize(email)))
+		if data == nil {
+			return ErrNotFound
+		}
+		e = &Entry{}
+		return json.Unmarshal(data, e)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Suppressed reports whether an address is on the suppression list
+func (s *Storage) Suppressed(ctx context.Context, email string) (bool, error) {
+	found := false
+	err := s.db.View(func(tx *bolt.Tx) error {
+		found = tx.Bucket(bucketSuppressions).Get([]byte(Normalize(email))) != nil
+		return nil
+	})
+	return found, err
+}
+
+// Remove takes an address off the suppression list
+func (s *Storage) Remove(ctx context.Context, email string) error {
+	key := []byte(Normalize(email))
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketSuppressions)
+		if b.Get(key) == nil {
+			return ErrNotFound
+		}
+		return b.Delete(key)
+	})
+}
+
+// List returns the entries matching the filter ordered by address, and the
+// total number of matching entries
+func (s *Storage) List(ctx context.Context, filter Filter) ([]*Entry, int, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+
+	entries := make([]*Entry, 0)
+	total := 0
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketSuppressions).ForEach(func(k, v []byte) error {
+			var e Entry
+			if err := json.Unmarshal(v, &e); err != nil {
+				return nil // Skip invalid entries
+			}
+			if filter.Reason != "" && e.Reason != filter.Reason {
+				return nil
+			}
+			if total >= filter.Offset && len(entries) < limit {
+				entries = append(entries, &e)
+			}
+			total++
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return entries, total, nil
+}
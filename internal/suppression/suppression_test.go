@@ -0,0 +1,100 @@
+package suppression
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func newTestStorage(t *testing.T) *Storage {
+	t.Helper()
+
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	storage, err := NewStorage(db)
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+	return storage
+}
+
+func TestStorageAddSuppressed(t *testing.T) {
+	storage := newTestStorage(t)
+	ctx := context.Background()
+
+	added, err := storage.Add(ctx, &Entry{Email: " User@Example.COM ", Reason: ReasonComplaint, Detail: "complaint 1"})
+	if err != nil || !added {
+		t.Fatalf("Add() = %v, %v", added, err)
+	}
+
+	// The first entry is kept
+	added, err = storage.Add(ctx, &Entry{Email: "user@example.com"})
+	if err != nil || added {
+		t.Errorf("Add() of a suppressed address = %v, %v", added, err)
+	}
+
+	ok, err := storage.Suppressed(ctx, "USER@example.com")
+	if err != nil || !ok {
+		t.Errorf("Suppressed() = %v, %v", ok, err)
+	}
+	if ok, _ := storage.Suppressed(ctx, "other@example.com"); ok {
+		t.Error("Suppressed() of an unknown address = true")
+	}
+
+	e, err := storage.Get(ctx, "user@example.com")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if e.Reason != ReasonComplaint || e.Detail != "complaint 1" || e.CreatedAt.IsZero() {
+		t.Errorf("Get() = %+v", e)
+	}
+
+	if _, err := storage.Add(ctx, &Entry{Email: "invalid"}); err == nil {
+		t.Error("Add() of an invalid address succeeded")
+	}
+}
+
+func TestStorageListRemove(t *testing.T) {
+	storage := newTestStorage(t)
+	ctx := context.Background()
+
+	for _, e := range []*Entry{
+		{Email: "c@example.com", Reason: ReasonComplaint},
+		{Email: "a@example.com", Reason: ReasonManual},
+		{Email: "b@example.com", Reason: ReasonComplaint},
+	} {
+		if _, err := storage.Add(ctx, e); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+
+	entries, total, err := storage.List(ctx, Filter{Reason: ReasonComplaint})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if total != 2 || len(entries) != 2 || entries[0].Email != "b@example.com" {
+		t.Errorf("List(complaint) = %d entries, total %d", len(entries), total)
+	}
+
+	entries, total, _ = storage.List(ctx, Filter{Limit: 1, Offset: 1})
+	if total != 3 || len(entries) != 1 || entries[0].Email != "b@example.com" {
+		t.Errorf("List(limit 1, offset 1) = %+v, total %d", entries, total)
+	}
+
+	if err := storage.Remove(ctx, "A@example.com"); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if err := storage.Remove(ctx, "a@example.com"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Remove() of a removed address error = %v, want ErrNotFound", err)
+	}
+	if ok, _ := storage.Suppressed(ctx, "a@example.com"); ok {
+		t.Error("removed address is still suppressed")
+	}
+}
@@ -0,0 +1,111 @@
+package template
+
+import (
+	htmlTemplate "html/template"
+	"sync"
+	"sync/atomic"
+	textTemplate "text/template"
+
+	"github.com/foxzi/sendry/internal/metrics"
+)
+
+// DefaultCacheSize is the default number of compiled templates kept in memory
+const DefaultCacheSize = 1000
+
+// compiled holds the parsed parts of one template version
+type compiled struct {
+	version int
+	subject *textTemplate.Template
+	html    *htmlTemplate.Template
+	text    *textTemplate.Template
+}
+
+// Cache keeps compiled templates keyed by template ID and version, so
+// repeated sends of the same template skip parsing. A template update
+// bumps its version, which makes the cached entry stale.
+type Cache struct {
+	mu         sync.RWMutex
+	entries    map[string]*compiled
+	maxEntries int
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// CacheStats contains cache counters
+type CacheStats struct {
+	Entries int   `json:"entries"`
+	Hits    int64 `json:"hits"`
+	Misses  int64 `json:"misses"`
+}
+
+// NewCache creates a compiled template cache holding up to maxEntries
+// templates. A non-positive size uses DefaultCacheSize.
+func NewCache(maxEntries int) *Cache {
+	if maxEntries <= 0 {
+		maxEntries = DefaultCacheSize
+	}
+	return &Cache{
+		entries:    make(map[string]*compiled),
+		maxEntries: maxEntries,
+	}
+}
+
+// get returns the compiled template if the cached version matches
+func (c *Cache) get(id string, version int) *compiled {
+	c.mu.RLock()
+	entry, ok := c.entries[id]
+	c.mu.RUnlock()
+
+	if !ok || entry.version != version {
+		c.misses.Add(1)
+		return nil
+	}
+	c.hits.Add(1)
+	return entry
+}
+
+// put stores a compiled template, evicting an arbitrary entry when full
+func (c *Cache) put(id string, entry *compiled) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[id]; !ok && len(c.entries) >= c.maxEntries {
+		for key := range c.entries {
+			delete(c.entries, key)
+			break
+		}
+	}
+	c.entries[id] = entry
+	metrics.SetTemplateCacheEntries(len(c.entries))
+}
+
+// Invalidate drops the compiled template with the given ID
+func (c *Cache) Invalidate(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, id)
+	metrics.SetTemplateCacheEntries(len(c.entries))
+}
+
+// Clear drops all compiled templates, e.g. after a partial they may
+// include has changed
+func (c *Cache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]*compiled)
+	metrics.SetTemplateCacheEntries(0)
+}
+
+// Stats returns cache counters
+func (c *Cache) Stats() CacheStats {
+	c.mu.RLock()
+	entries := len(c.entries)
+	c.mu.RUnlock()
+
+	return CacheStats{
+		Entries: entries,
+		Hits:    c.hits.Load(),
+		Misses:  c.misses.Load(),
+	}
+}
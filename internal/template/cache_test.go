@@ -0,0 +1,117 @@
+package template
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestCachedEngine_Render(t *testing.T) {
+	cache := NewCache(0)
+	engine := NewCachedEngine(cache)
+
+	tmpl := &Template{
+		ID:      "t1",
+		Version: 1,
+		Subject: "Hello {{.Name}}",
+		HTML:    "<p>{{.Name}}</p>",
+	}
+
+	for _, name := range []string{"John", "Jane"} {
+		result, err := engine.Render(tmpl, map[string]interface{}{"Name": name})
+		if err != nil {
+			t.Fatalf("Render() error = %v", err)
+		}
+		if result.Subject != "Hello "+name || result.HTML != "<p>"+name+"</p>" {
+			t.Errorf("Render() = %+v", result)
+		}
+	}
+
+	if stats := cache.Stats(); stats.Entries != 1 || stats.Hits != 1 || stats.Misses != 1 {
+		t.Errorf("Stats() = %+v, want 1 entry, 1 hit, 1 miss", stats)
+	}
+
+	// A new version is compiled again
+	updated := &Template{ID: "t1", Version: 2, Subject: "Hi {{.Name}}"}
+	result, err := engine.Render(updated, map[string]interface{}{"Name": "John"})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if result.Subject != "Hi John" || result.HTML != "" {
+		t.Errorf("Render() after update = %+v, want new version", result)
+	}
+	if stats := cache.Stats(); stats.Misses != 2 {
+		t.Errorf("Misses = %d, want 2", stats.Misses)
+	}
+
+	cache.Invalidate("t1")
+	if stats := cache.Stats(); stats.Entries != 0 {
+		t.Errorf("Entries after Invalidate() = %d, want 0", stats.Entries)
+	}
+}
+
+func TestCachedEngine_NoID(t *testing.T) {
+	cache := NewCache(0)
+	engine := NewCachedEngine(cache)
+
+	// Inline templates without an ID are not cached
+	if _, err := engine.Render(&Template{Subject: "Hello"}, nil); err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if stats := cache.Stats(); stats.Entries != 0 || stats.Misses != 0 {
+		t.Errorf("Stats() = %+v, want empty", stats)
+	}
+}
+
+func TestCachedEngine_InvalidTemplate(t *testing.T) {
+	cache := NewCache(0)
+	engine := NewCachedEngine(cache)
+
+	if _, err := engine.Render(&Template{ID: "bad", Subject: "Hello {{.Name"}, nil); err == nil {
+		t.Fatal("Render() expected error for invalid syntax")
+	}
+	if stats := cache.Stats(); stats.Entries != 0 {
+		t.Errorf("Entries = %d, invalid template must not be cached", stats.Entries)
+	}
+}
+
+func TestCache_Eviction(t *testing.T) {
+	cache := NewCache(2)
+	engine := NewCachedEngine(cache)
+
+	for i := 0; i < 5; i++ {
+		tmpl := &Template{ID: fmt.Sprintf("t%d", i), Version: 1, Subject: "Hello"}
+		if _, err := engine.Render(tmpl, nil); err != nil {
+			t.Fatalf("Render() error = %v", err)
+		}
+	}
+
+	if stats := cache.Stats(); stats.Entries != 2 {
+		t.Errorf("Entries = %d, want 2", stats.Entries)
+	}
+}
+
+func TestCachedEngine_Concurrent(t *testing.T) {
+	engine := NewCachedEngine(NewCache(0))
+	tmpl := &Template{ID: "t1", Version: 1, Subject: "Order {{.ID}}", HTML: "<b>{{.ID}}</b>"}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				result, err := engine.Render(tmpl, map[string]interface{}{"ID": i})
+				if err != nil {
+					t.Errorf("Render() error = %v", err)
+					return
+				}
+				if want := fmt.Sprintf("Order %d", i); result.Subject != want {
+					t.Errorf("Subject = %q, want %q", result.Subject, want)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
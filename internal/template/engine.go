@@ -2,33 +2,84 @@ package template
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	htmlTemplate "html/template"
+	"io"
 	textTemplate "text/template"
+	"time"
+
+	"github.com/foxzi/sendry/internal/metrics"
 )
 
 // Engine renders templates with data
-type Engine struct{}
+type Engine struct {
+	cache    *Cache
+	partials PartialSource
+}
+
+// PartialSource looks up the partials included by templates
+type PartialSource interface {
+	GetPartialByName(ctx context.Context, name string) (*Partial, error)
+}
 
 // NewEngine creates a new template engine
 func NewEngine() *Engine {
 	return &Engine{}
 }
 
+// NewCachedEngine creates a template engine that keeps compiled templates
+// in the given cache. Templates without an ID are always parsed.
+func NewCachedEngine(cache *Cache) *Engine {
+	return &Engine{cache: cache}
+}
+
+// SetPartials makes {{template "name"}} actions include the partials of
+// the given source. Compiled templates must be dropped from the cache
+// when a partial changes.
+func (e *Engine) SetPartials(src PartialSource) {
+	e.partials = src
+}
+
+// Cache returns the compiled template cache, or nil if caching is off
+func (e *Engine) Cache() *Cache {
+	return e.cache
+}
+
 // Render renders a template with provided data
 func (e *Engine) Render(tmpl *Template, data map[string]interface{}) (*RenderResult, error) {
+	start := time.Now()
+	cacheResult := metrics.TemplateCacheOff
+
+	var c *compiled
+	if e.cache != nil && tmpl.ID != "" {
+		c = e.cache.get(tmpl.ID, tmpl.Version)
+		cacheResult = metrics.TemplateCacheHit
+	}
+	if c == nil {
+		var err error
+		c, err = e.compile(tmpl)
+		if err != nil {
+			return nil, err
+		}
+		if e.cache != nil && tmpl.ID != "" {
+			e.cache.put(tmpl.ID, c)
+			cacheResult = metrics.TemplateCacheMiss
+		}
+	}
+
 	result := &RenderResult{}
 
 	// Render subject (text template)
-	subject, err := e.renderText("subject", tmpl.Subject, data)
+	subject, err := execute(c.subject, data)
 	if err != nil {
 		return nil, fmt.Errorf("failed to render subject: %w", err)
 	}
 	result.Subject = subject
 
 	// Render HTML (html template with auto-escaping)
-	if tmpl.HTML != "" {
-		html, err := e.renderHTML("html", tmpl.HTML, data)
+	if c.html != nil {
+		html, err := execute(c.html, data)
 		if err != nil {
 			return nil, fmt.Errorf("failed to render html: %w", err)
 		}
@@ -36,14 +87,15 @@ func (e *Engine) Render(tmpl *Template, data map[string]interface{}) (*RenderRes
 	}
 
 	// Render plain text
-	if tmpl.Text != "" {
-		text, err := e.renderText("text", tmpl.Text, data)
+	if c.text != nil {
+		text, err := execute(c.text, data)
 		if err != nil {
 			return nil, fmt.Errorf("failed to render text: %w", err)
 		}
 		result.Text = text
 	}
 
+	metrics.ObserveTemplateRender(cacheResult, time.Since(start))
 	return result, nil
 }
 
@@ -73,23 +125,107 @@ func (e *Engine) Validate(tmpl *Template) error {
 	return nil
 }
 
-func (e *Engine) renderText(name, tmplStr string, data map[string]interface{}) (string, error) {
-	t, err := textTemplate.New(name).Parse(tmplStr)
-	if err != nil {
-		return "", err
+// ValidatePartial checks if partial syntax is valid
+func (e *Engine) ValidatePartial(p *Partial) error {
+	if _, err := textTemplate.New(p.Name).Parse(p.Content); err != nil {
+		return fmt.Errorf("invalid partial template: %w", err)
 	}
-	var buf bytes.Buffer
-	if err := t.Execute(&buf, data); err != nil {
-		return "", err
+	if _, err := htmlTemplate.New(p.Name).Parse(p.Content); err != nil {
+		return fmt.Errorf("invalid partial template: %w", err)
 	}
-	return buf.String(), nil
+	return nil
 }
 
-func (e *Engine) renderHTML(name, tmplStr string, data map[string]interface{}) (string, error) {
-	t, err := htmlTemplate.New(name).Parse(tmplStr)
+// compile parses all parts of a template along with the partials they
+// include. Partials are parsed first, so blocks of a layout partial can be
+// overridden by {{define}} in the template.
+func (e *Engine) compile(tmpl *Template) (*compiled, error) {
+	c := &compiled{version: tmpl.Version}
+
+	partials, err := e.resolvePartials(tmpl.Subject, tmpl.HTML, tmpl.Text)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	if c.subject, err = parseText("subject", tmpl.Subject, partials); err != nil {
+		return nil, fmt.Errorf("failed to render subject: %w", err)
+	}
+	if tmpl.HTML != "" {
+		if c.html, err = parseHTML("html", tmpl.HTML, partials); err != nil {
+			return nil, fmt.Errorf("failed to render html: %w", err)
+		}
+	}
+	if tmpl.Text != "" {
+		if c.text, err = parseText("text", tmpl.Text, partials); err != nil {
+			return nil, fmt.Errorf("failed to render text: %w", err)
+		}
+	}
+
+	return c, nil
+}
+
+// resolvePartials returns the partials included by the sources, directly
+// or through other partials
+func (e *Engine) resolvePartials(srcs ...string) ([]*Partial, error) {
+	if e.partials == nil {
+		return nil, nil
+	}
+
+	var pending []string
+	for _, src := range srcs {
+		pending = append(pending, References(src)...)
+	}
+
+	var partials []*Partial
+	seen := map[string]bool{}
+	for len(pending) > 0 {
+		name := pending[0]
+		pending = pending[1:]
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		p, err := e.partials.GetPartialByName(context.Background(), name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load partial %q: %w", name, err)
+		}
+		if p == nil {
+			return nil, fmt.Errorf("partial %q not found", name)
+		}
+		partials = append(partials, p)
+		pending = append(pending, References(p.Content)...)
+	}
+	return partials, nil
+}
+
+func parseText(name, src string, partials []*Partial) (*textTemplate.Template, error) {
+	t := textTemplate.New(name)
+	for _, p := range partials {
+		if _, err := t.New(p.Name).Parse(p.Content); err != nil {
+			return nil, fmt.Errorf("partial %q: %w", p.Name, err)
+		}
 	}
+	return t.Parse(src)
+}
+
+func parseHTML(name, src string, partials []*Partial) (*htmlTemplate.Template, error) {
+	t := htmlTemplate.New(name)
+	for _, p := range partials {
+		if _, err := t.New(p.Name).Parse(p.Content); err != nil {
+			return nil, fmt.Errorf("partial %q: %w", p.Name, err)
+		}
+	}
+	return t.Parse(src)
+}
+
+// executor is implemented by text and html templates
+type executor interface {
+	Execute(w io.Writer, data any) error
+}
+
+// execute runs a parsed template. Parsed templates are safe for concurrent use.
+func execute(t executor, data map[string]interface{}) (string, error) {
 	var buf bytes.Buffer
 	if err := t.Execute(&buf, data); err != nil {
 		return "", err
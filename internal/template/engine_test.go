@@ -1,6 +1,7 @@
 package template
 
 import (
+	"fmt"
 	"testing"
 )
 
@@ -153,3 +154,91 @@ func TestEngine_Render(t *testing.T) {
 		})
 	}
 }
+
+// benchTemplate is a typical transactional email
+var benchTemplate = &Template{
+	ID:      "welcome",
+	Version: 3,
+	Subject: "Welcome to {{.Company}}, {{.Name}}!",
+	Text: `Hello {{.Name}},
+
+Thank you for signing up. Your account {{.Email}} is ready.
+{{range .Items}}- {{.}}
+{{end}}
+{{.Company}} team`,
+	HTML: `<html><body>
+<h1>Hello {{.Name}}</h1>
+<p>Thank you for signing up. Your account <b>{{.Email}}</b> is ready.</p>
+<ul>{{range .Items}}<li>{{.}}</li>{{end}}</ul>
+{{if .Promo}}<p>Use code <b>{{.Promo}}</b> for 10% off.</p>{{end}}
+<p>{{.Company}} team</p>
+</body></html>`,
+}
+
+var benchData = map[string]interface{}{
+	"Name":    "John",
+	"Email":   "john@example.com",
+	"Company": "Example",
+	"Items":   []string{"Profile", "Settings", "Billing"},
+	"Promo":   "WELCOME10",
+}
+
+func benchmarkRender(b *testing.B, engine *Engine) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := engine.Render(benchTemplate, benchData); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "renders/s")
+}
+
+// BenchmarkEngine_Render parses the template on every render
+func BenchmarkEngine_Render(b *testing.B) {
+	benchmarkRender(b, NewEngine())
+}
+
+// BenchmarkEngine_RenderCached renders a compiled template from the cache
+func BenchmarkEngine_RenderCached(b *testing.B) {
+	benchmarkRender(b, NewCachedEngine(NewCache(0)))
+}
+
+// BenchmarkEngine_RenderCachedParallel renders from the cache on all CPUs,
+// as concurrent API template sends do
+func BenchmarkEngine_RenderCachedParallel(b *testing.B) {
+	engine := NewCachedEngine(NewCache(0))
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := engine.Render(benchTemplate, benchData); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "renders/s")
+}
+
+// BenchmarkEngine_RenderCachedMany renders from a cache holding many
+// templates, as a server sending 10k distinct templates would
+func BenchmarkEngine_RenderCachedMany(b *testing.B) {
+	engine := NewCachedEngine(NewCache(10000))
+
+	templates := make([]*Template, 10000)
+	for i := range templates {
+		tmpl := *benchTemplate
+		tmpl.ID = fmt.Sprintf("t%d", i)
+		templates[i] = &tmpl
+		engine.Render(&tmpl, benchData)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := engine.Render(templates[i%len(templates)], benchData); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "renders/s")
+}
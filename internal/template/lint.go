@@ -0,0 +1,254 @@
+package template
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	textTemplate "text/template"
+	"text/template/parse"
+)
+
+// Warning codes
+const (
+	WarningSyntax            = "syntax"             // Template does not parse, e.g. an unclosed action
+	WarningUndefinedVariable = "undefined_variable" // Variable not in the declared variables
+	WarningEmptyURL          = "empty_url"          // Empty href or src
+	WarningBrokenURL         = "broken_url"         // href or src that is not a valid URL
+	WarningUndefinedPartial  = "undefined_partial"  // {{template}} of a partial that does not exist
+)
+
+// Warning is a problem found by Lint
+type Warning struct {
+	Field   string `json:"field"` // subject, html or text
+	Line    int    `json:"line"`
+	Code    string `json:"code"`
+	Value   string `json:"value,omitempty"` // Variable name or URL
+	Message string `json:"message"`
+}
+
+var (
+	lintTagRe    = regexp.MustCompile(`<[a-zA-Z][^<>]*>`)
+	lintAttrRe   = regexp.MustCompile(`(?i)\s(href|src)\s*=\s*("[^"]*"|'[^']*'|[^\s"'>]+)`)
+	lintActionRe = regexp.MustCompile(`\{\{.*?\}\}`)
+	lintLineRe   = regexp.MustCompile(`^template: [^:]*:(\d+):`)
+)
+
+// Lint checks a template for syntax errors, variables missing from the
+// declared Variables and empty or broken link and image URLs. Variables
+// are only checked if the template declares any.
+func Lint(tmpl *Template) []Warning {
+	var declared map[string]bool
+	if len(tmpl.Variables) > 0 {
+		declared = make(map[string]bool, len(tmpl.Variables))
+		for _, v := range tmpl.Variables {
+			declared[v.Name] = true
+		}
+	}
+
+	var warnings []Warning
+	warnings = append(warnings, LintSource("subject", tmpl.Subject, declared)...)
+	warnings = append(warnings, LintSource("html", tmpl.HTML, declared)...)
+	warnings = append(warnings, LintURLs("html", tmpl.HTML)...)
+	warnings = append(warnings, LintSource("text", tmpl.Text, declared)...)
+	return warnings
+}
+
+// LintPartials reports the partials a template includes that do not exist
+// in the source
+func LintPartials(ctx context.Context, tmpl *Template, src PartialSource) ([]Warning, error) {
+	var warnings []Warning
+	fields := []struct{ name, src string }{{"subject", tmpl.Subject}, {"html", tmpl.HTML}, {"text", tmpl.Text}}
+	for _, f := range fields {
+		for _, name := range References(f.src) {
+			p, err := src.GetPartialByName(ctx, name)
+			if err != nil {
+				return nil, err
+			}
+			if p != nil {
+				continue
+			}
+			line := 0
+			if i := strings.Index(f.src, `"`+name+`"`); i >= 0 {
+				line = lineAt(f.src, i)
+			}
+			warnings = append(warnings, Warning{
+				Field:   f.name,
+				Line:    line,
+				Code:    WarningUndefinedPartial,
+				Value:   name,
+				Message: "partial " + name + " does not exist",
+			})
+		}
+	}
+	return warnings, nil
+}
+
+// LintSource checks the syntax of a template part and, if declared is not
+// nil, reports the top-level variables it uses that are not declared
+func LintSource(field, src string, declared map[string]bool) []Warning {
+	if src == "" {
+		return nil
+	}
+	t, err := textTemplate.New(field).Parse(src)
+	if err != nil {
+		msg := err.Error()
+		line := 0
+		if m := lintLineRe.FindStringSubmatch(msg); m != nil {
+			line, _ = strconv.Atoi(m[1])
+		}
+		return []Warning{{Field: field, Line: line, Code: WarningSyntax, Message: msg}}
+	}
+	if declared == nil || t.Tree == nil {
+		return nil
+	}
+
+	used := map[string]int{} // Variable -> offset of its first use
+	collectFields(t.Tree.Root, true, used)
+
+	names := make([]string, 0, len(used))
+	for name := range used {
+		if !declared[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool { return used[names[i]] < used[names[j]] })
+
+	var warnings []Warning
+	for _, name := range names {
+		warnings = append(warnings, Warning{
+			Field:   field,
+			Line:    lineAt(src, used[name]),
+			Code:    WarningUndefinedVariable,
+			Value:   name,
+			Message: "variable " + name + " is not declared",
+		})
+	}
+	return warnings
+}
+
+// collectFields records the data fields used by a node. root is false
+// inside range and with, where dot is no longer the template data.
+func collectFields(node parse.Node, root bool, used map[string]int) {
+	use := func(name string, pos parse.Pos) {
+		if _, ok := used[name]; !ok {
+			used[name] = int(pos)
+		}
+	}
+
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Nodes {
+			collectFields(c, root, used)
+		}
+	case *parse.ActionNode:
+		collectFields(n.Pipe, root, used)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Cmds {
+			collectFields(c, root, used)
+		}
+	case *parse.CommandNode:
+		for _, a := range n.Args {
+			collectFields(a, root, used)
+		}
+	case *parse.ChainNode:
+		collectFields(n.Node, root, used)
+	case *parse.FieldNode:
+		if root {
+			use(n.Ident[0], n.Pos)
+		}
+	case *parse.VariableNode:
+		// $ is the template data everywhere
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			use(n.Ident[1], n.Pos)
+		}
+	case *parse.IfNode:
+		collectFields(n.Pipe, root, used)
+		collectFields(n.List, root, used)
+		collectFields(n.ElseList, root, used)
+	case *parse.RangeNode:
+		collectFields(n.Pipe, root, used)
+		collectFields(n.List, false, used)
+		collectFields(n.ElseList, root, used)
+	case *parse.WithNode:
+		collectFields(n.Pipe, root, used)
+		collectFields(n.List, false, used)
+		collectFields(n.ElseList, root, used)
+	case *parse.TemplateNode:
+		collectFields(n.Pipe, root, used)
+	}
+}
+
+// LintURLs reports empty and broken href and src URLs in HTML. URLs set
+// entirely by a template action are not checked.
+func LintURLs(field, src string) []Warning {
+	var warnings []Warning
+	for _, tag := range lintTagRe.FindAllStringIndex(src, -1) {
+		for _, m := range lintAttrRe.FindAllStringSubmatchIndex(src[tag[0]:tag[1]], -1) {
+			attr := strings.ToLower(src[tag[0]+m[2] : tag[0]+m[3]])
+			value := strings.Trim(src[tag[0]+m[4]:tag[0]+m[5]], `"'`)
+			code, reason := checkURL(value)
+			if code == "" {
+				continue
+			}
+			warnings = append(warnings, Warning{
+				Field:   field,
+				Line:    lineAt(src, tag[0]+m[0]),
+				Code:    code,
+				Value:   value,
+				Message: attr + " " + reason,
+			})
+		}
+	}
+	return warnings
+}
+
+// checkURL returns the warning code and reason of an empty or broken URL
+func checkURL(raw string) (string, string) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return WarningEmptyURL, "is empty"
+	}
+	// Template actions are replaced by a placeholder
+	masked := lintActionRe.ReplaceAllString(raw, "x")
+	if masked == "x" {
+		return "", ""
+	}
+	if strings.ContainsAny(masked, " \t\r\n") {
+		return WarningBrokenURL, "contains whitespace"
+	}
+	u, err := url.Parse(masked)
+	if err != nil {
+		return WarningBrokenURL, "is not a valid URL"
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		if u.Host == "" {
+			return WarningBrokenURL, "has no host"
+		}
+	case "mailto", "tel":
+		if u.Opaque == "" {
+			return WarningBrokenURL, "has no address"
+		}
+	case "":
+		if strings.HasPrefix(strings.ToLower(masked), "www.") {
+			return WarningBrokenURL, "has no scheme"
+		}
+	}
+	return "", ""
+}
+
+func lineAt(src string, offset int) int {
+	if offset > len(src) {
+		offset = len(src)
+	}
+	return strings.Count(src[:offset], "\n") + 1
+}
@@ -0,0 +1,56 @@
+package template
+
+import (
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	tmpl := &Template{
+		Subject: "Order {{.OrderID}} for {{.Customer.Name}}",
+		HTML: "<p>Hi {{.Name}}</p>\n" +
+			"{{range .Items}}<li>{{.Title}} {{$.Currency}}</li>{{end}}\n" +
+			"{{with .Coupon}}{{.Code}}{{end}}\n" +
+			`<a href="">empty</a> <a href='https://'>no host</a>` + "\n" +
+			`<img src="www.example.com/logo.png"> <a href="mailto:">mail</a>` + "\n" +
+			`<a href="{{.Link}}">ok</a> <a href="https://example.com/o/{{.OrderID}}">ok</a> <a href="#top">ok</a>`,
+		Text: "Hi {{.Name}}",
+		Variables: []VariableInfo{
+			{Name: "OrderID"}, {Name: "Customer"}, {Name: "Name"}, {Name: "Items"}, {Name: "Link"},
+		},
+	}
+
+	type key struct{ field, code, value string }
+	got := map[key]int{}
+	for _, w := range Lint(tmpl) {
+		got[key{w.Field, w.Code, w.Value}] = w.Line
+	}
+
+	want := map[key]int{
+		{"html", WarningUndefinedVariable, "Currency"}:         2,
+		{"html", WarningUndefinedVariable, "Coupon"}:           3,
+		{"html", WarningEmptyURL, ""}:                          4,
+		{"html", WarningBrokenURL, "https://"}:                 4,
+		{"html", WarningBrokenURL, "www.example.com/logo.png"}: 5,
+		{"html", WarningBrokenURL, "mailto:"}:                  5,
+	}
+	for k, line := range want {
+		if got[k] != line {
+			t.Errorf("warning %+v at line %d, want line %d", k, got[k], line)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("Lint() = %v, want %d warnings", got, len(want))
+	}
+}
+
+func TestLintSyntax(t *testing.T) {
+	warnings := Lint(&Template{Subject: "Hello", HTML: "<p>\n{{if .Name}}Hi {{.Name</p>"})
+	if len(warnings) != 1 || warnings[0].Code != WarningSyntax || warnings[0].Field != "html" || warnings[0].Line != 2 {
+		t.Errorf("Lint() = %+v, want a syntax error on html line 2", warnings)
+	}
+
+	// Without declared variables only syntax and URLs are checked
+	if warnings := Lint(&Template{Subject: "{{.A}}", HTML: "{{.B}}"}); len(warnings) != 0 {
+		t.Errorf("Lint() = %+v, want no warnings", warnings)
+	}
+}
@@ -0,0 +1,331 @@
+package template
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template/parse"
+	"time"
+
+	"github.com/google/uuid"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	bucketPartials     = []byte("template_partials")
+	bucketPartialNames = []byte("template_partial_names")
+)
+
+// partialNameRe restricts partial names to what can be written in a
+// {{template "name"}} action without escaping
+var partialNameRe = regexp.MustCompile(`^[A-Za-z0-9_.\-/]+$`)
+
+// reservedPartialNames are the names of the template parts
+var reservedPartialNames = map[string]bool{"subject": true, "html": true, "text": true}
+
+// Dependent is a template or partial that includes a partial
+type Dependent struct {
+	Type string `json:"type"` // template or partial
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// PartialInUseError is returned when deleting or renaming a partial that
+// templates or other partials include
+type PartialInUseError struct {
+	Name   string
+	UsedBy []Dependent
+}
+
+func (e *PartialInUseError) Error() string {
+	names := make([]string, len(e.UsedBy))
+	for i, d := range e.UsedBy {
+		names[i] = d.Type + " " + d.Name
+	}
+	return fmt.Sprintf("partial %q is used by %s", e.Name, strings.Join(names, ", "))
+}
+
+// ValidatePartialName checks that a name can be used for a partial
+func ValidatePartialName(name string) error {
+	if name == "" {
+		return fmt.Errorf("partial name is required")
+	}
+	if !partialNameRe.MatchString(name) {
+		return fmt.Errorf("partial name %q may only contain letters, digits, '_', '.', '-' and '/'", name)
+	}
+	if reservedPartialNames[name] {
+		return fmt.Errorf("partial name %q is reserved", name)
+	}
+	return nil
+}
+
+// CreatePartial creates a new partial
+func (s *Storage) CreatePartial(ctx context.Context, p *Partial) error {
+	if err := ValidatePartialName(p.Name); err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		partials := tx.Bucket(bucketPartials)
+		names := tx.Bucket(bucketPartialNames)
+
+		if existing := names.Get([]byte(p.Name)); existing != nil {
+			return fmt.Errorf("partial with name %q already exists", p.Name)
+		}
+
+		p.ID = uuid.New().String()
+		p.Version = 1
+		p.CreatedAt = time.Now()
+		p.UpdatedAt = p.CreatedAt
+
+		data, err := json.Marshal(p)
+		if err != nil {
+			return fmt.Errorf("failed to marshal partial: %w", err)
+		}
+		if err := partials.Put([]byte(p.ID), data); err != nil {
+			return err
+		}
+		return names.Put([]byte(p.Name), []byte(p.ID))
+	})
+}
+
+// GetPartial retrieves a partial by ID
+func (s *Storage) GetPartial(ctx context.Context, id string) (*Partial, error) {
+	var p *Partial
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bucketPartials).Get([]byte(id))
+		if data == nil {
+			return nil
+		}
+		p = &Partial{}
+		return json.Unmarshal(data, p)
+	})
+
+	return p, err
+}
+
+// GetPartialByName retrieves a partial by name
+func (s *Storage) GetPartialByName(ctx context.Context, name string) (*Partial, error) {
+	var p *Partial
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		id := tx.Bucket(bucketPartialNames).Get([]byte(name))
+		if id == nil {
+			return nil
+		}
+		data := tx.Bucket(bucketPartials).Get(id)
+		if data == nil {
+			return nil
+		}
+		p = &Partial{}
+		return json.Unmarshal(data, p)
+	})
+
+	return p, err
+}
+
+// ListPartials returns all partials sorted by name
+func (s *Storage) ListPartials(ctx context.Context) ([]*Partial, error) {
+	var partials []*Partial
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketPartials).ForEach(func(k, v []byte) error {
+			var p Partial
+			if err := json.Unmarshal(v, &p); err != nil {
+				return nil
+			}
+			partials = append(partials, &p)
+			return nil
+		})
+	})
+
+	sort.Slice(partials, func(i, j int) bool { return partials[i].Name < partials[j].Name })
+	return partials, err
+}
+
+// UpdatePartial updates an existing partial. Renaming a partial that is
+// in use returns a *PartialInUseError.
+func (s *Storage) UpdatePartial(ctx context.Context, p *Partial) error {
+	if err := ValidatePartialName(p.Name); err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		partials := tx.Bucket(bucketPartials)
+		names := tx.Bucket(bucketPartialNames)
+
+		existingData := partials.Get([]byte(p.ID))
+		if existingData == nil {
+			return fmt.Errorf("partial not found")
+		}
+		var existing Partial
+		if err := json.Unmarshal(existingData, &existing); err != nil {
+			return err
+		}
+
+		if existing.Name != p.Name {
+			if existingID := names.Get([]byte(p.Name)); existingID != nil {
+				return fmt.Errorf("partial with name %q already exists", p.Name)
+			}
+			if usedBy := dependents(tx, existing.Name, existing.ID); len(usedBy) > 0 {
+				return &PartialInUseError{Name: existing.Name, UsedBy: usedBy}
+			}
+			if err := names.Delete([]byte(existing.Name)); err != nil {
+				return err
+			}
+			if err := names.Put([]byte(p.Name), []byte(p.ID)); err != nil {
+				return err
+			}
+		}
+
+		p.Version = existing.Version + 1
+		p.CreatedAt = existing.CreatedAt
+		p.UpdatedAt = time.Now()
+
+		data, err := json.Marshal(p)
+		if err != nil {
+			return fmt.Errorf("failed to marshal partial: %w", err)
+		}
+		return partials.Put([]byte(p.ID), data)
+	})
+}
+
+// DeletePartial removes a partial by ID. A partial that is in use is
+// only removed if force is set, otherwise a *PartialInUseError is
+// returned.
+func (s *Storage) DeletePartial(ctx context.Context, id string, force bool) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		partials := tx.Bucket(bucketPartials)
+
+		data := partials.Get([]byte(id))
+		if data == nil {
+			return nil // Already deleted
+		}
+		var p Partial
+		if err := json.Unmarshal(data, &p); err != nil {
+			return err
+		}
+
+		if !force {
+			if usedBy := dependents(tx, p.Name, p.ID); len(usedBy) > 0 {
+				return &PartialInUseError{Name: p.Name, UsedBy: usedBy}
+			}
+		}
+
+		if err := tx.Bucket(bucketPartialNames).Delete([]byte(p.Name)); err != nil {
+			return err
+		}
+		return partials.Delete([]byte(id))
+	})
+}
+
+// PartialDependents returns the templates and partials that include the
+// partial with the given name
+func (s *Storage) PartialDependents(ctx context.Context, name string) ([]Dependent, error) {
+	var usedBy []Dependent
+	err := s.db.View(func(tx *bolt.Tx) error {
+		usedBy = dependents(tx, name, "")
+		return nil
+	})
+	return usedBy, err
+}
+
+// dependents finds the templates and partials other than selfID that
+// include the named partial
+func dependents(tx *bolt.Tx, name, selfID string) []Dependent {
+	var usedBy []Dependent
+
+	_ = tx.Bucket(bucketTemplates).ForEach(func(k, v []byte) error {
+		var tmpl Template
+		if err := json.Unmarshal(v, &tmpl); err != nil {
+			return nil
+		}
+		if includes(name, tmpl.Subject, tmpl.HTML, tmpl.Text) {
+			usedBy = append(usedBy, Dependent{Type: "template", ID: tmpl.ID, Name: tmpl.Name})
+		}
+		return nil
+	})
+
+	_ = tx.Bucket(bucketPartials).ForEach(func(k, v []byte) error {
+		var p Partial
+		if err := json.Unmarshal(v, &p); err != nil || p.ID == selfID {
+			return nil
+		}
+		if includes(name, p.Content) {
+			usedBy = append(usedBy, Dependent{Type: "partial", ID: p.ID, Name: p.Name})
+		}
+		return nil
+	})
+
+	return usedBy
+}
+
+// includes reports whether any of the sources includes the named partial
+func includes(name string, srcs ...string) bool {
+	for _, src := range srcs {
+		for _, ref := range References(src) {
+			if ref == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// References returns the names of the templates included by a template
+// source with {{template "name"}}, other than those it defines itself.
+// Sources that do not parse have no references.
+func References(src string) []string {
+	if !strings.Contains(src, "template") {
+		return nil
+	}
+
+	trees := map[string]*parse.Tree{}
+	t := parse.New("")
+	t.Mode = parse.SkipFuncCheck
+	if _, err := t.Parse(src, "", "", trees); err != nil {
+		return nil
+	}
+
+	refs := map[string]bool{}
+	for _, tree := range trees {
+		collectReferences(tree.Root, refs)
+	}
+
+	var names []string
+	for name := range refs {
+		if _, defined := trees[name]; !defined {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// collectReferences records the names of the templates a node includes
+func collectReferences(node parse.Node, refs map[string]bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Nodes {
+			collectReferences(c, refs)
+		}
+	case *parse.IfNode:
+		collectReferences(n.List, refs)
+		collectReferences(n.ElseList, refs)
+	case *parse.RangeNode:
+		collectReferences(n.List, refs)
+		collectReferences(n.ElseList, refs)
+	case *parse.WithNode:
+		collectReferences(n.List, refs)
+		collectReferences(n.ElseList, refs)
+	case *parse.TemplateNode:
+		refs[n.Name] = true
+	}
+}
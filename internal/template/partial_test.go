@@ -0,0 +1,160 @@
+package template
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestReferences(t *testing.T) {
+	cases := []struct {
+		src  string
+		want string
+	}{
+		{`<p>{{.Name}}</p>`, ""},
+		{`{{template "header" .}}<p>Hi</p>{{template "footer"}}`, "footer,header"},
+		{`{{if .VIP}}{{template "vip" .}}{{else}}{{template "regular" .}}{{end}}`, "regular,vip"},
+		{`{{range .Items}}{{template "item" .}}{{end}}`, "item"},
+		{`{{define "row"}}<tr></tr>{{end}}{{template "row"}}{{template "layout" .}}`, "layout"},
+		{`{{block "content" .}}default{{end}}`, ""},
+		{`{{template "header" .`, ""},
+	}
+
+	for _, tc := range cases {
+		if got := strings.Join(References(tc.src), ","); got != tc.want {
+			t.Errorf("References(%q) = %q, want %q", tc.src, got, tc.want)
+		}
+	}
+}
+
+func TestStorage_Partials(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	storage, err := NewStorage(db)
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+	ctx := context.Background()
+
+	for _, name := range []string{"", "html", `bad"name`} {
+		if err := storage.CreatePartial(ctx, &Partial{Name: name, Content: "x"}); err == nil {
+			t.Errorf("CreatePartial(%q) error = nil", name)
+		}
+	}
+
+	footer := &Partial{Name: "footer", Content: "<p>Bye</p>"}
+	if err := storage.CreatePartial(ctx, footer); err != nil {
+		t.Fatalf("CreatePartial() error = %v", err)
+	}
+	layout := &Partial{Name: "layout", Content: `<body>{{block "content" .}}{{end}}{{template "footer"}}</body>`}
+	if err := storage.CreatePartial(ctx, layout); err != nil {
+		t.Fatalf("CreatePartial() error = %v", err)
+	}
+	if err := storage.CreatePartial(ctx, &Partial{Name: "footer", Content: "x"}); err == nil {
+		t.Error("CreatePartial() with duplicate name error = nil")
+	}
+	tmpl := &Template{Name: "welcome", Subject: "Hi", HTML: `{{template "layout" .}}`}
+	if err := storage.Create(ctx, tmpl); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	usedBy, err := storage.PartialDependents(ctx, "footer")
+	if err != nil || len(usedBy) != 1 || usedBy[0].Type != "partial" || usedBy[0].Name != "layout" {
+		t.Errorf("PartialDependents(footer) = %+v, %v", usedBy, err)
+	}
+
+	// In use by the template: delete and rename are blocked
+	var inUse *PartialInUseError
+	if err := storage.DeletePartial(ctx, layout.ID, false); !errors.As(err, &inUse) || inUse.UsedBy[0].Name != "welcome" {
+		t.Fatalf("DeletePartial() error = %v, want PartialInUseError", err)
+	}
+	layout.Name = "base"
+	if err := storage.UpdatePartial(ctx, layout); !errors.As(err, &inUse) {
+		t.Fatalf("UpdatePartial() rename error = %v, want PartialInUseError", err)
+	}
+	layout.Name = "layout"
+	layout.Content = `<body>{{block "content" .}}{{end}}</body>`
+	if err := storage.UpdatePartial(ctx, layout); err != nil || layout.Version != 2 {
+		t.Fatalf("UpdatePartial() error = %v, version = %d", err, layout.Version)
+	}
+
+	// footer is no longer included by layout
+	if err := storage.DeletePartial(ctx, footer.ID, false); err != nil {
+		t.Fatalf("DeletePartial(footer) error = %v", err)
+	}
+	if err := storage.DeletePartial(ctx, layout.ID, true); err != nil {
+		t.Fatalf("DeletePartial(force) error = %v", err)
+	}
+	partials, err := storage.ListPartials(ctx)
+	if err != nil || len(partials) != 0 {
+		t.Errorf("ListPartials() = %d partials, %v", len(partials), err)
+	}
+	if p, _ := storage.GetPartialByName(ctx, "layout"); p != nil {
+		t.Error("GetPartialByName() found a deleted partial")
+	}
+}
+
+func TestEngine_RenderPartials(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	storage, err := NewStorage(db)
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+	ctx := context.Background()
+
+	partials := []*Partial{
+		{Name: "footer", Content: `<footer>{{.Company}}</footer>`},
+		{Name: "layout", Content: `<body>{{block "content" .}}Default{{end}}{{template "footer" .}}</body>`},
+		{Name: "signature", Content: `-- {{.Company}}`},
+	}
+	for _, p := range partials {
+		if err := storage.CreatePartial(ctx, p); err != nil {
+			t.Fatalf("CreatePartial() error = %v", err)
+		}
+	}
+
+	engine := NewCachedEngine(NewCache(10))
+	engine.SetPartials(storage)
+	tmpl := &Template{
+		ID:      "t1",
+		Version: 1,
+		Subject: "Hello",
+		HTML:    `{{template "layout" .}}{{define "content"}}<p>Hi {{.Name}}</p>{{end}}`,
+		Text:    "Hi {{.Name}}\n{{template \"signature\" .}}",
+	}
+
+	result, err := engine.Render(tmpl, map[string]interface{}{"Name": "Bob", "Company": "A&B"})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if want := `<body><p>Hi Bob</p><footer>A&amp;B</footer></body>`; result.HTML != want {
+		t.Errorf("HTML = %q, want %q", result.HTML, want)
+	}
+	if want := "Hi Bob\n-- A&B"; result.Text != want {
+		t.Errorf("Text = %q, want %q", result.Text, want)
+	}
+
+	// Partial changes apply once the cache is cleared
+	partials[0].Content = `<footer>Bye</footer>`
+	if err := storage.UpdatePartial(ctx, partials[0]); err != nil {
+		t.Fatalf("UpdatePartial() error = %v", err)
+	}
+	engine.Cache().Clear()
+	result, err = engine.Render(tmpl, map[string]interface{}{"Name": "Bob"})
+	if err != nil || !strings.Contains(result.HTML, "<footer>Bye</footer>") {
+		t.Errorf("Render() after partial update = %+v, %v", result, err)
+	}
+
+	missing := &Template{Subject: "Hi", HTML: `{{template "nope" .}}`}
+	if _, err := engine.Render(missing, nil); err == nil || !strings.Contains(err.Error(), `partial "nope" not found`) {
+		t.Errorf("Render() with missing partial error = %v", err)
+	}
+	warnings, err := LintPartials(ctx, missing, storage)
+	if err != nil || len(warnings) != 1 || warnings[0].Code != WarningUndefinedPartial || warnings[0].Value != "nope" {
+		t.Errorf("LintPartials() = %+v, %v", warnings, err)
+	}
+}
@@ -30,6 +30,12 @@ func NewStorage(db *bolt.DB) (*Storage, error) {
 		if _, err := tx.CreateBucketIfNotExists(bucketTemplateNames); err != nil {
 			return err
 		}
+		if _, err := tx.CreateBucketIfNotExists(bucketPartials); err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucketIfNotExists(bucketPartialNames); err != nil {
+			return err
+		}
 		return nil
 	})
 	if err != nil {
@@ -18,6 +18,18 @@ type Template struct {
 	UpdatedAt   time.Time      `json:"updated_at"`
 }
 
+// Partial is a reusable template block, such as a header, footer or
+// layout, included in templates with {{template "name" .}}
+type Partial struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Content     string    `json:"content"`
+	Version     int       `json:"version"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
 // VariableInfo documents a template variable
 type VariableInfo struct {
 	Name        string `json:"name"`
@@ -4,43 +4,82 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"slices"
+	"sync"
 	"time"
 
 	"golang.org/x/crypto/acme/autocert"
 )
 
+// ErrDomainNotManaged is returned when renewing a domain that is not in the
+// ACME domains list
+var ErrDomainNotManaged = errors.New("domain not in ACME domains list")
+
 // ACMEManager manages automatic TLS certificates from Let's Encrypt
 type ACMEManager struct {
-	manager *autocert.Manager
+	email   string
+	cache   autocert.DirCache
 	domains []string
+
+	mu      sync.RWMutex
+	manager *autocert.Manager // Replaced after a forced renewal
+
+	renewMu sync.Mutex // Serializes forced renewals
 }
 
 // NewACMEManager creates a new ACME manager
 func NewACMEManager(email string, domains []string, cacheDir string) *ACMEManager {
-	m := &autocert.Manager{
-		Prompt:     autocert.AcceptTOS,
-		Email:      email,
-		HostPolicy: autocert.HostWhitelist(domains...),
-		Cache:      autocert.DirCache(cacheDir),
+	a := &ACMEManager{
+		email:   email,
+		cache:   autocert.DirCache(cacheDir),
+		domains: domains,
 	}
+	a.manager = a.newManager(a.cache)
+	return a
+}
 
-	return &ACMEManager{
-		manager: m,
-		domains: domains,
+func (a *ACMEManager) newManager(cache autocert.Cache) *autocert.Manager {
+	return &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Email:      a.email,
+		HostPolicy: autocert.HostWhitelist(a.domains...),
+		Cache:      cache,
 	}
 }
 
+func (a *ACMEManager) current() *autocert.Manager {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.manager
+}
+
 // CertificateInfo contains information about a certificate
 type CertificateInfo struct {
 	Domain    string
+	Issuer    string
+	DNSNames  []string
 	NotBefore time.Time
 	NotAfter  time.Time
 	DaysLeft  int
 	IsNew     bool
 }
 
+// newCertificateInfo returns the information of a parsed certificate
+func newCertificateInfo(domain string, leaf *x509.Certificate) CertificateInfo {
+	return CertificateInfo{
+		Domain:    domain,
+		Issuer:    issuerName(leaf),
+		DNSNames:  leaf.DNSNames,
+		NotBefore: leaf.NotBefore,
+		NotAfter:  leaf.NotAfter,
+		DaysLeft:  DaysLeft(leaf.NotAfter),
+	}
+}
+
 // EnsureCertificates obtains/validates certificates for all configured domains at startup
 // The HTTP challenge server must be running before calling this method
 // Returns info about each certificate
@@ -61,7 +100,7 @@ func (a *ACMEManager) EnsureCertificates(ctx context.Context) ([]CertificateInfo
 
 		// GetCertificate will fetch from cache or obtain new certificate from Let's Encrypt
 		// If certificate is about to expire, autocert will automatically renew it
-		cert, err := a.manager.GetCertificate(hello)
+		cert, err := a.current().GetCertificate(hello)
 		if err != nil {
 			return results, fmt.Errorf("failed to obtain certificate for %s: %w", domain, err)
 		}
@@ -79,14 +118,8 @@ func (a *ACMEManager) EnsureCertificates(ctx context.Context) ([]CertificateInfo
 			}
 
 			if leaf != nil {
-				daysLeft := int(time.Until(leaf.NotAfter).Hours() / 24)
-				info := CertificateInfo{
-					Domain:    domain,
-					NotBefore: leaf.NotBefore,
-					NotAfter:  leaf.NotAfter,
-					DaysLeft:  daysLeft,
-					IsNew:     daysLeft > 85, // Let's Encrypt certs are valid for 90 days
-				}
+				info := newCertificateInfo(domain, leaf)
+				info.IsNew = info.DaysLeft > 85 // Let's Encrypt certs are valid for 90 days
 				results = append(results, info)
 			}
 		}
@@ -103,28 +136,27 @@ func (a *ACMEManager) Domains() []string {
 // TLSConfig returns TLS configuration for use with servers
 func (a *ACMEManager) TLSConfig() *tls.Config {
 	return &tls.Config{
-		GetCertificate: a.manager.GetCertificate,
-		MinVersion:     tls.VersionTLS12,
+		GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return a.current().GetCertificate(hello)
+		},
+		MinVersion: tls.VersionTLS12,
 	}
 }
 
 // HTTPHandler returns HTTP handler for HTTP-01 ACME challenge
 func (a *ACMEManager) HTTPHandler(fallback http.Handler) http.Handler {
-	return a.manager.HTTPHandler(fallback)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a.current().HTTPHandler(fallback).ServeHTTP(w, r)
+	})
 }
 
 // GetCachedCertificates reads certificates from cache without contacting Let's Encrypt
 func (a *ACMEManager) GetCachedCertificates() ([]CertificateInfo, error) {
 	var results []CertificateInfo
 
-	cache, ok := a.manager.Cache.(autocert.DirCache)
-	if !ok {
-		return nil, fmt.Errorf("cache is not a directory cache")
-	}
-
 	for _, domain := range a.domains {
 		// Try to get certificate from cache
-		data, err := cache.Get(context.Background(), domain)
+		data, err := a.cache.Get(context.Background(), domain)
 		if err != nil {
 			// Certificate not in cache
 			continue
@@ -142,15 +174,7 @@ func (a *ACMEManager) GetCachedCertificates() ([]CertificateInfo, error) {
 				continue
 			}
 
-			daysLeft := int(time.Until(leaf.NotAfter).Hours() / 24)
-			info := CertificateInfo{
-				Domain:    domain,
-				NotBefore: leaf.NotBefore,
-				NotAfter:  leaf.NotAfter,
-				DaysLeft:  daysLeft,
-				IsNew:     false,
-			}
-			results = append(results, info)
+			results = append(results, newCertificateInfo(domain, leaf))
 		}
 	}
 
@@ -179,6 +203,107 @@ func (a *ACMEManager) HasValidCachedCertificates() (bool, []CertificateInfo) {
 	return true, certs
 }
 
+// Renew obtains a new certificate for a domain even if the current one is
+// still valid. The HTTP-01 challenge must be reachable through HTTPHandler.
+// The certificate is stored in the cache and used for new connections; on
+// failure the current certificate is kept.
+func (a *ACMEManager) Renew(ctx context.Context, domain string) (*CertificateInfo, error) {
+	if !slices.Contains(a.domains, domain) {
+		return nil, ErrDomainNotManaged
+	}
+
+	a.renewMu.Lock()
+	defer a.renewMu.Unlock()
+
+	// A manager that does not see the cached certificate orders a new one
+	if _, err := getCertificate(ctx, a.newManager(renewCache{DirCache: a.cache, domain: domain}), domain); err != nil {
+		return nil, fmt.Errorf("failed to renew certificate for %s: %w", domain, err)
+	}
+
+	// Serve the new certificate from the cache
+	m := a.newManager(a.cache)
+	cert, err := getCertificate(ctx, m, domain)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load renewed certificate for %s: %w", domain, err)
+	}
+	leaf := cert.Leaf
+	if leaf == nil {
+		if leaf, err = parseCertificate(cert.Certificate[0]); err != nil {
+			return nil, fmt.Errorf("failed to parse certificate for %s: %w", domain, err)
+		}
+	}
+
+	a.mu.Lock()
+	a.manager = m
+	a.mu.Unlock()
+
+	info := newCertificateInfo(domain, leaf)
+	info.IsNew = true
+	return &info, nil
+}
+
+// StartChallengeServer serves HTTP-01 challenges on addr until the returned
+// stop function is called, for renewals in on-demand mode
+func (a *ACMEManager) StartChallengeServer(addr string) (func(), error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start ACME challenge server: %w", err)
+	}
+	srv := &http.Server{
+		Handler: a.HTTPHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "ACME challenge server", http.StatusNotFound)
+		})),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go srv.Serve(ln)
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+	}, nil
+}
+
+// getCertificate fetches the certificate of a domain from a manager,
+// giving up when ctx is done
+func getCertificate(ctx context.Context, m *autocert.Manager, domain string) (*tls.Certificate, error) {
+	type result struct {
+		cert *tls.Certificate
+		err  error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		cert, err := m.GetCertificate(&tls.ClientHelloInfo{ServerName: domain})
+		ch <- result{cert, err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case r := <-ch:
+		if r.err == nil && (r.cert == nil || len(r.cert.Certificate) == 0) {
+			return nil, errors.New("empty certificate")
+		}
+		return r.cert, r.err
+	}
+}
+
+// renewCache hides the cached certificates of one domain, so that the
+// manager using it orders new ones. Everything else, including the account
+// key, comes from the directory cache and new certificates are stored there.
+type renewCache struct {
+	autocert.DirCache
+	domain string
+}
+
+func (c renewCache) Get(ctx context.Context, key string) ([]byte, error) {
+	// autocert stores ECDSA certificates as <domain> and RSA as <domain>+rsa
+	if key == c.domain || key == c.domain+"+rsa" {
+		return nil, autocert.ErrCacheMiss
+	}
+	return c.DirCache.Get(ctx, key)
+}
+
 // parseCertificate parses a DER-encoded certificate
 func parseCertificate(der []byte) (*x509.Certificate, error) {
 	return x509.ParseCertificate(der)
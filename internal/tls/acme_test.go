@@ -0,0 +1,74 @@
+package tls
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+func TestACMEManager_CachedCertificates(t *testing.T) {
+	dir := t.TempDir()
+	certPEM, keyPEM, err := generateTestCertificate()
+	if err != nil {
+		t.Fatalf("generateTestCertificate() error = %v", err)
+	}
+	// autocert caches the private key followed by the certificate chain
+	if err := os.WriteFile(filepath.Join(dir, "localhost"), append(keyPEM, certPEM...), 0600); err != nil {
+		t.Fatalf("write cache: %v", err)
+	}
+
+	a := NewACMEManager("admin@example.com", []string{"localhost", "mail.example.com"}, dir)
+	certs, err := a.GetCachedCertificates()
+	if err != nil {
+		t.Fatalf("GetCachedCertificates() error = %v", err)
+	}
+	if len(certs) != 1 {
+		t.Fatalf("GetCachedCertificates() = %d certificates, want 1", len(certs))
+	}
+	c := certs[0]
+	if c.Domain != "localhost" || c.Issuer != "localhost" || len(c.DNSNames) != 1 || c.DaysLeft != 0 {
+		t.Errorf("GetCachedCertificates()[0] = %+v", c)
+	}
+}
+
+func TestRenewCache(t *testing.T) {
+	dir := t.TempDir()
+	cache := autocert.DirCache(dir)
+	ctx := context.Background()
+	for _, key := range []string{"example.com", "example.com+rsa", "acme_account+key", "other.com"} {
+		if err := cache.Put(ctx, key, []byte(key)); err != nil {
+			t.Fatalf("Put(%q) error = %v", key, err)
+		}
+	}
+
+	c := renewCache{DirCache: cache, domain: "example.com"}
+	for _, key := range []string{"example.com", "example.com+rsa"} {
+		if _, err := c.Get(ctx, key); !errors.Is(err, autocert.ErrCacheMiss) {
+			t.Errorf("Get(%q) error = %v, want cache miss", key, err)
+		}
+	}
+	for _, key := range []string{"acme_account+key", "other.com"} {
+		if data, err := c.Get(ctx, key); err != nil || string(data) != key {
+			t.Errorf("Get(%q) = %q, %v", key, data, err)
+		}
+	}
+
+	// New certificates are stored in the directory cache
+	if err := c.Put(ctx, "example.com", []byte("renewed")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if data, _ := cache.Get(ctx, "example.com"); string(data) != "renewed" {
+		t.Errorf("cached certificate = %q, want renewed", data)
+	}
+}
+
+func TestACMEManager_RenewUnknownDomain(t *testing.T) {
+	a := NewACMEManager("", []string{"mail.example.com"}, t.TempDir())
+	if _, err := a.Renew(context.Background(), "other.example.com"); !errors.Is(err, ErrDomainNotManaged) {
+		t.Errorf("Renew() error = %v, want ErrDomainNotManaged", err)
+	}
+}
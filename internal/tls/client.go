@@ -0,0 +1,85 @@
+package tls
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// RequireClientCertificate returns a copy of base that requires clients to
+// present a certificate signed by the CA bundle in caFile. When fingerprints
+// is not empty, only certificates with one of these SHA-256 fingerprints are
+// accepted.
+func RequireClientCertificate(base *tls.Config, caFile string, fingerprints []string) (*tls.Config, error) {
+	pool, err := loadClientCAs(caFile)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := base.Clone()
+	cfg.ClientCAs = pool
+	cfg.ClientAuth = tls.RequireAndVerifyClientCert
+
+	if len(fingerprints) > 0 {
+		allowed := make(map[string]bool, len(fingerprints))
+		for _, fp := range fingerprints {
+			allowed[NormalizeFingerprint(fp)] = true
+		}
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			if len(cs.PeerCertificates) == 0 {
+				return fmt.Errorf("client certificate required")
+			}
+			fp := Fingerprint(cs.PeerCertificates[0])
+			if !allowed[fp] {
+				return fmt.Errorf("client certificate %s is not allowed", fp)
+			}
+			return nil
+		}
+	}
+
+	return cfg, nil
+}
+
+// RequestClientCertificate returns a copy of base that asks clients for a
+// certificate and verifies it against the CA bundle in caFile. Clients
+// without a certificate are still accepted.
+func RequestClientCertificate(base *tls.Config, caFile string) (*tls.Config, error) {
+	pool, err := loadClientCAs(caFile)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := base.Clone()
+	cfg.ClientCAs = pool
+	cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	return cfg, nil
+}
+
+func loadClientCAs(caFile string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA file: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in client CA file %s", caFile)
+	}
+	return pool, nil
+}
+
+// Fingerprint returns the lowercase hex SHA-256 fingerprint of a certificate
+func Fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// NormalizeFingerprint converts a fingerprint written as "AB:CD:..." or
+// "abcd..." to the form returned by Fingerprint
+func NormalizeFingerprint(fp string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(fp), ":", ""))
+}
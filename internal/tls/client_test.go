@@ -0,0 +1,212 @@
+package tls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCA issues certificates for client authentication tests
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{
+		cert: cert,
+		key:  key,
+		pem:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+}
+
+func (ca *testCA) issue(t *testing.T, cn string, usage x509.ExtKeyUsage) tls.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		DNSNames:     []string{cn},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+// handshake connects a client with the given certificates to server and
+// returns the error of the server side of the handshake
+func handshake(t *testing.T, server *tls.Config, roots *x509.CertPool, certs []tls.Certificate) error {
+	t.Helper()
+
+	serverConn, clientConn := net.Pipe()
+	defer serverConn.Close()
+	defer clientConn.Close()
+
+	go func() {
+		client := tls.Client(clientConn, &tls.Config{
+			RootCAs:      roots,
+			ServerName:   "localhost",
+			Certificates: certs,
+		})
+		client.Handshake()
+		// Read the server's reply so a rejection after the client finished is seen
+		client.Read(make([]byte, 1))
+	}()
+
+	conn := tls.Server(serverConn, server)
+	return conn.Handshake()
+}
+
+func TestRequireClientCertificate(t *testing.T) {
+	serverCA := newTestCA(t)
+	clientCA := newTestCA(t)
+	otherCA := newTestCA(t)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(serverCA.cert)
+	base := &tls.Config{
+		Certificates: []tls.Certificate{serverCA.issue(t, "localhost", x509.ExtKeyUsageServerAuth)},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	caFile := filepath.Join(t.TempDir(), "clients.pem")
+	if err := os.WriteFile(caFile, clientCA.pem, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	web := clientCA.issue(t, "sendry-web", x509.ExtKeyUsageClientAuth)
+	deploy := clientCA.issue(t, "deploy", x509.ExtKeyUsageClientAuth)
+	stranger := otherCA.issue(t, "sendry-web", x509.ExtKeyUsageClientAuth)
+
+	t.Run("any certificate of the CA", func(t *testing.T) {
+		cfg, err := RequireClientCertificate(base, caFile, nil)
+		if err != nil {
+			t.Fatalf("RequireClientCertificate() error = %v", err)
+		}
+		if base.ClientCAs != nil {
+			t.Error("base config was modified")
+		}
+		if err := handshake(t, cfg, roots, []tls.Certificate{web}); err != nil {
+			t.Errorf("CA signed certificate rejected: %v", err)
+		}
+		if err := handshake(t, cfg, roots, nil); err == nil {
+			t.Error("connection without certificate accepted")
+		}
+		if err := handshake(t, cfg, roots, []tls.Certificate{stranger}); err == nil {
+			t.Error("certificate of another CA accepted")
+		}
+	})
+
+	t.Run("fingerprint allowlist", func(t *testing.T) {
+		fp := strings.ToUpper(Fingerprint(web.Leaf))
+		var colons []string
+		for i := 0; i < len(fp); i += 2 {
+			colons = append(colons, fp[i:i+2])
+		}
+
+		cfg, err := RequireClientCertificate(base, caFile, []string{strings.Join(colons, ":")})
+		if err != nil {
+			t.Fatalf("RequireClientCertificate() error = %v", err)
+		}
+		if err := handshake(t, cfg, roots, []tls.Certificate{web}); err != nil {
+			t.Errorf("allowed certificate rejected: %v", err)
+		}
+		if err := handshake(t, cfg, roots, []tls.Certificate{deploy}); err == nil {
+			t.Error("certificate outside the allowlist accepted")
+		}
+	})
+
+	t.Run("invalid CA file", func(t *testing.T) {
+		if _, err := RequireClientCertificate(base, filepath.Join(t.TempDir(), "missing.pem"), nil); err == nil {
+			t.Error("expected error for missing CA file")
+		}
+		empty := filepath.Join(t.TempDir(), "empty.pem")
+		if err := os.WriteFile(empty, []byte("not a certificate"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := RequireClientCertificate(base, empty, nil); err == nil {
+			t.Error("expected error for CA file without certificates")
+		}
+	})
+}
+
+func TestRequestClientCertificate(t *testing.T) {
+	serverCA := newTestCA(t)
+	clientCA := newTestCA(t)
+	otherCA := newTestCA(t)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(serverCA.cert)
+	base := &tls.Config{
+		Certificates: []tls.Certificate{serverCA.issue(t, "localhost", x509.ExtKeyUsageServerAuth)},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	caFile := filepath.Join(t.TempDir(), "clients.pem")
+	if err := os.WriteFile(caFile, clientCA.pem, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := RequestClientCertificate(base, caFile)
+	if err != nil {
+		t.Fatalf("RequestClientCertificate() error = %v", err)
+	}
+	if err := handshake(t, cfg, roots, []tls.Certificate{clientCA.issue(t, "billing", x509.ExtKeyUsageClientAuth)}); err != nil {
+		t.Errorf("CA signed certificate rejected: %v", err)
+	}
+	if err := handshake(t, cfg, roots, nil); err != nil {
+		t.Errorf("connection without certificate rejected: %v", err)
+	}
+	if err := handshake(t, cfg, roots, []tls.Certificate{otherCA.issue(t, "billing", x509.ExtKeyUsageClientAuth)}); err == nil {
+		t.Error("certificate of another CA accepted")
+	}
+}
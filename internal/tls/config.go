@@ -5,6 +5,7 @@ import (
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
+	"math"
 	"os"
 	"time"
 )
@@ -49,14 +50,27 @@ func GetCertificateInfo(certFile string) (*ManualCertificateInfo, error) {
 		return nil, fmt.Errorf("failed to parse certificate: %w", err)
 	}
 
-	daysLeft := int(time.Until(cert.NotAfter).Hours() / 24)
-
 	return &ManualCertificateInfo{
 		Subject:   cert.Subject.CommonName,
-		Issuer:    cert.Issuer.CommonName,
+		Issuer:    issuerName(cert),
 		NotBefore: cert.NotBefore,
 		NotAfter:  cert.NotAfter,
-		DaysLeft:  daysLeft,
+		DaysLeft:  DaysLeft(cert.NotAfter),
 		DNSNames:  cert.DNSNames,
 	}, nil
 }
+
+// DaysLeft returns the whole days until a certificate expires, negative
+// once it has expired
+func DaysLeft(notAfter time.Time) int {
+	return int(math.Floor(time.Until(notAfter).Hours() / 24))
+}
+
+// issuerName returns the common name of the issuer, or the full name if it
+// has none
+func issuerName(cert *x509.Certificate) string {
+	if cert.Issuer.CommonName != "" {
+		return cert.Issuer.CommonName
+	}
+	return cert.Issuer.String()
+}
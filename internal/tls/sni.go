@@ -0,0 +1,208 @@
+package tls
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/acme"
+)
+
+// sniCheckInterval is how often the files of a certificate in use are
+// checked for changes
+const sniCheckInterval = 30 * time.Second
+
+// CertificateLookup returns the certificate and key files configured for a
+// domain, empty if the domain has none
+type CertificateLookup func(domain string) (certFile, keyFile string)
+
+// SNIStore selects the certificate of a TLS handshake by the server name
+// the client asks for (SNI). A name uses the certificate of its domain or
+// of the closest parent domain that has one, other names get the default
+// certificate. Certificates are loaded on first use and reloaded when their
+// files change on disk.
+type SNIStore struct {
+	lookup        CertificateLookup
+	logger        *slog.Logger
+	checkInterval time.Duration
+
+	fallback *tls.Config // ACME, serves names without a domain certificate
+	defaults *certFiles  // Default certificate when there is no fallback
+
+	mu    sync.Mutex
+	certs map[certFiles]*fileCertificate
+}
+
+type certFiles struct {
+	cert, key string
+}
+
+// fileCertificate is a certificate loaded from files, with the modification
+// times it was loaded at
+type fileCertificate struct {
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	certMod time.Time
+	keyMod  time.Time
+	checked time.Time
+}
+
+// NewSNIStore creates a store of the domain certificates returned by lookup.
+// lookup may be nil.
+func NewSNIStore(lookup CertificateLookup, logger *slog.Logger) *SNIStore {
+	if lookup == nil {
+		lookup = func(string) (string, string) { return "", "" }
+	}
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	return &SNIStore{
+		lookup:        lookup,
+		logger:        logger,
+		checkInterval: sniCheckInterval,
+		certs:         make(map[certFiles]*fileCertificate),
+	}
+}
+
+// SetDefault loads the certificate served to clients that ask for a name
+// without a domain certificate or send no name at all
+func (s *SNIStore) SetDefault(certFile, keyFile string) error {
+	files := certFiles{cert: certFile, key: keyFile}
+	if _, err := s.load(files); err != nil {
+		return err
+	}
+	s.defaults = &files
+	return nil
+}
+
+// SetFallback serves names without a domain certificate from another
+// configuration, e.g. the one of the ACME manager
+func (s *SNIStore) SetFallback(cfg *tls.Config) {
+	s.fallback = cfg
+}
+
+// TLSConfig returns the TLS configuration of the listeners
+func (s *SNIStore) TLSConfig() *tls.Config {
+	var cfg *tls.Config
+	if s.fallback != nil {
+		// Keeps the ALPN protocols of the tls-alpn-01 challenge
+		cfg = s.fallback.Clone()
+	} else {
+		cfg = &tls.Config{}
+	}
+	cfg.Certificates = nil
+	cfg.GetCertificate = s.GetCertificate
+	if cfg.MinVersion < tls.VersionTLS12 {
+		cfg.MinVersion = tls.VersionTLS12
+	}
+	return cfg
+}
+
+// GetCertificate returns the certificate of a handshake
+func (s *SNIStore) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	// ACME tls-alpn-01 challenges are answered by the ACME manager
+	if s.fallback != nil && slices.Contains(hello.SupportedProtos, acme.ALPNProto) {
+		return s.fallback.GetCertificate(hello)
+	}
+
+	name := strings.TrimSuffix(strings.ToLower(hello.ServerName), ".")
+	for domain := name; domain != ""; {
+		if cert := s.domainCertificate(hello, name, domain); cert != nil {
+			return cert, nil
+		}
+		_, parent, ok := strings.Cut(domain, ".")
+		if !ok || !strings.Contains(parent, ".") {
+			break
+		}
+		domain = parent
+	}
+
+	if s.fallback != nil {
+		return s.fallback.GetCertificate(hello)
+	}
+	if s.defaults != nil {
+		return s.load(*s.defaults)
+	}
+	return nil, fmt.Errorf("no certificate for server name %q", hello.ServerName)
+}
+
+// domainCertificate returns the certificate configured for domain, nil if
+// there is none, it cannot be loaded or, for a parent domain, it does not
+// cover the name
+func (s *SNIStore) domainCertificate(hello *tls.ClientHelloInfo, name, domain string) *tls.Certificate {
+	certFile, keyFile := s.lookup(domain)
+	if certFile == "" || keyFile == "" {
+		return nil
+	}
+	cert, err := s.load(certFiles{cert: certFile, key: keyFile})
+	if err != nil {
+		s.logger.Error("failed to load domain certificate", "domain", domain, "error", err)
+		return nil
+	}
+	if domain != name && hello.SupportsCertificate(cert) != nil {
+		return nil
+	}
+	return cert
+}
+
+// load returns the certificate of files, reloading it if the files changed
+// since it was loaded. A certificate that fails to reload, e.g. while its
+// files are being replaced, is served until the files can be loaded.
+func (s *SNIStore) load(files certFiles) (*tls.Certificate, error) {
+	s.mu.Lock()
+	fc, ok := s.certs[files]
+	if !ok {
+		fc = &fileCertificate{}
+		s.certs[files] = fc
+	}
+	s.mu.Unlock()
+
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	now := time.Now()
+	if fc.cert != nil && now.Sub(fc.checked) < s.checkInterval {
+		return fc.cert, nil
+	}
+	fc.checked = now
+
+	certMod, keyMod, err := modTimes(files)
+	if err == nil && fc.cert != nil && certMod.Equal(fc.certMod) && keyMod.Equal(fc.keyMod) {
+		return fc.cert, nil
+	}
+	if err == nil {
+		var cert tls.Certificate
+		if cert, err = tls.LoadX509KeyPair(files.cert, files.key); err == nil {
+			if fc.cert != nil {
+				s.logger.Info("reloaded TLS certificate", "cert_file", files.cert)
+			}
+			fc.cert, fc.certMod, fc.keyMod = &cert, certMod, keyMod
+			return fc.cert, nil
+		}
+	}
+
+	if fc.cert == nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	s.logger.Warn("failed to reload TLS certificate, serving the previous one", "cert_file", files.cert, "error", err)
+	return fc.cert, nil
+}
+
+// modTimes returns the modification times of the certificate and key files
+func modTimes(files certFiles) (certMod, keyMod time.Time, err error) {
+	info, err := os.Stat(files.cert)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	certMod = info.ModTime()
+	if info, err = os.Stat(files.key); err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return certMod, info.ModTime(), nil
+}
@@ -0,0 +1,132 @@
+package tls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCertificate writes cert to cert and key files in dir
+func writeCertificate(t *testing.T, dir, name string, cert tls.Certificate) (certFile, keyFile string) {
+	t.Helper()
+
+	key, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile = filepath.Join(dir, name+".crt")
+	keyFile = filepath.Join(dir, name+".key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: key}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+// servedName connects to server asking for name and returns the common name
+// of the certificate served, empty if the handshake failed
+func servedName(t *testing.T, server *tls.Config, name string) string {
+	t.Helper()
+
+	serverConn, clientConn := net.Pipe()
+	defer serverConn.Close()
+	defer clientConn.Close()
+
+	go tls.Server(serverConn, server).Handshake()
+
+	client := tls.Client(clientConn, &tls.Config{ServerName: name, InsecureSkipVerify: true})
+	if err := client.Handshake(); err != nil {
+		return ""
+	}
+	return client.ConnectionState().PeerCertificates[0].Subject.CommonName
+}
+
+func TestSNIStore_GetCertificate(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+
+	files := map[string][2]string{}
+	certFile, keyFile := writeCertificate(t, dir, "com", ca.issue(t, "example.com", x509.ExtKeyUsageServerAuth))
+	files["example.com"] = [2]string{certFile, keyFile}
+	// The certificate of example.org also covers its subdomains
+	certFile, keyFile = writeCertificate(t, dir, "org", ca.issue(t, "*.example.org", x509.ExtKeyUsageServerAuth))
+	files["example.org"] = [2]string{certFile, keyFile}
+
+	store := NewSNIStore(func(domain string) (string, string) {
+		f := files[domain]
+		return f[0], f[1]
+	}, nil)
+	cfg := store.TLSConfig()
+
+	if name := servedName(t, cfg, "other.test"); name != "" {
+		t.Fatalf("without a default certificate only domains are served, got %q", name)
+	}
+
+	defCert, defKey := writeCertificate(t, dir, "default", ca.issue(t, "mail.example.net", x509.ExtKeyUsageServerAuth))
+	if err := store.SetDefault(defCert, defKey); err != nil {
+		t.Fatalf("SetDefault() error = %v", err)
+	}
+
+	tests := []struct {
+		serverName string
+		want       string
+	}{
+		{"example.com", "example.com"},
+		{"EXAMPLE.COM", "example.com"},
+		{"mx.example.org", "*.example.org"},      // Parent domain certificate covers the name
+		{"mail.example.com", "mail.example.net"}, // Parent domain certificate does not
+		{"other.test", "mail.example.net"},
+		{"", "mail.example.net"},
+	}
+	for _, tt := range tests {
+		if got := servedName(t, cfg, tt.serverName); got != tt.want {
+			t.Errorf("server name %q: served %q, want %q", tt.serverName, got, tt.want)
+		}
+	}
+}
+
+func TestSNIStore_Reload(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+
+	certFile, keyFile := writeCertificate(t, dir, "domain", ca.issue(t, "example.com", x509.ExtKeyUsageServerAuth))
+	store := NewSNIStore(func(domain string) (string, string) {
+		if domain == "example.com" {
+			return certFile, keyFile
+		}
+		return "", ""
+	}, nil)
+	store.checkInterval = 0
+	cfg := store.TLSConfig()
+
+	if name := servedName(t, cfg, "example.com"); name != "example.com" {
+		t.Fatalf("served %q, want example.com", name)
+	}
+
+	// A replaced certificate is served from the next handshake
+	writeCertificate(t, dir, "domain", ca.issue(t, "renewed.example.com", x509.ExtKeyUsageServerAuth))
+	future := time.Now().Add(time.Minute)
+	for _, f := range []string{certFile, keyFile} {
+		if err := os.Chtimes(f, future, future); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if name := servedName(t, cfg, "example.com"); name != "renewed.example.com" {
+		t.Errorf("after replacing the files served %q, want renewed.example.com", name)
+	}
+
+	// A broken replacement keeps the loaded certificate
+	if err := os.WriteFile(certFile, []byte("invalid"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if name := servedName(t, cfg, "example.com"); name != "renewed.example.com" {
+		t.Errorf("after breaking the files served %q, want renewed.example.com", name)
+	}
+}
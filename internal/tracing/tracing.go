@@ -0,0 +1,99 @@
+// Package tracing sets up OpenTelemetry tracing of the path of a message:
+// API request, enqueue, queue processing, MX lookup and SMTP delivery. The
+// trace context travels with the queued message, so every delivery attempt
+// joins the trace of the request that submitted it.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation scope of sendry spans
+const tracerName = "github.com/foxzi/sendry"
+
+// propagator carries trace contexts in W3C traceparent headers
+var propagator = propagation.TraceContext{}
+
+// Config configures the export of spans
+type Config struct {
+	Endpoint    string            // OTLP/HTTP endpoint URL, e.g. http://localhost:4318
+	Headers     map[string]string // Headers of export requests, e.g. for authentication
+	ServiceName string            // service.name of the spans
+	SampleRatio float64           // Share of new traces recorded, 0-1
+}
+
+// Setup exports spans to an OTLP collector (Jaeger, Tempo, the OpenTelemetry
+// Collector) and installs the tracer provider. Without Setup spans are not
+// recorded. The returned function flushes and stops the export.
+func Setup(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid tracing endpoint %q", cfg.Endpoint)
+	}
+
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpointURL(cfg.Endpoint)}
+	if len(cfg.Headers) > 0 {
+		opts = append(opts, otlptracehttp.WithHeaders(cfg.Headers))
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", cfg.ServiceName))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagator)
+	return provider.Shutdown, nil
+}
+
+// Start starts a span as a child of the span in ctx
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// Fail marks a span as failed with err
+func Fail(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// Inject returns the W3C traceparent of the span in ctx, empty if there is
+// no recorded span
+func Inject(ctx context.Context) string {
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(ctx, carrier)
+	return carrier.Get("traceparent")
+}
+
+// TraceID returns the trace ID of a W3C traceparent, empty if it is invalid
+func TraceID(traceparent string) string {
+	sc := trace.SpanContextFromContext(Extract(context.Background(), traceparent))
+	if !sc.TraceID().IsValid() {
+		return ""
+	}
+	return sc.TraceID().String()
+}
+
+// Extract returns ctx with the remote span of a W3C traceparent, ctx if
+// traceparent is empty or invalid
+func Extract(ctx context.Context, traceparent string) context.Context {
+	if traceparent == "" {
+		return ctx
+	}
+	return propagator.Extract(ctx, propagation.MapCarrier{"traceparent": traceparent})
+}
@@ -0,0 +1,86 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// record installs a tracer provider that keeps the ended spans
+func record(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	return recorder
+}
+
+func TestInjectExtract(t *testing.T) {
+	if got := Inject(context.Background()); got != "" {
+		t.Errorf("Inject(no span) = %q, want empty", got)
+	}
+
+	record(t)
+	ctx, span := Start(context.Background(), "parent")
+	defer span.End()
+
+	traceparent := Inject(ctx)
+	if traceparent == "" {
+		t.Fatal("Inject() returned no traceparent")
+	}
+
+	_, child := Start(Extract(context.Background(), traceparent), "child")
+	defer child.End()
+	if child.SpanContext().TraceID() != span.SpanContext().TraceID() {
+		t.Error("span of extracted context is not part of the trace")
+	}
+
+	if ctx := Extract(context.Background(), "invalid"); Inject(ctx) != "" {
+		t.Error("Extract(invalid) returned a span context")
+	}
+
+	if got := TraceID(traceparent); got != span.SpanContext().TraceID().String() {
+		t.Errorf("TraceID() = %q, want %s", got, span.SpanContext().TraceID())
+	}
+	if got := TraceID(""); got != "" {
+		t.Errorf("TraceID(empty) = %q, want empty", got)
+	}
+}
+
+func TestHTTPMiddleware(t *testing.T) {
+	recorder := record(t)
+
+	r := chi.NewRouter()
+	r.Use(HTTPMiddleware)
+	r.Get("/api/v1/status/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/status/abc", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "GET /api/v1/status/{id}" {
+		t.Errorf("span name = %q", span.Name())
+	}
+	if span.Parent().TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("span trace = %s, want the client trace", span.Parent().TraceID())
+	}
+	if span.Status().Code != codes.Error {
+		t.Errorf("span status = %v, want error", span.Status().Code)
+	}
+}
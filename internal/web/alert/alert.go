@@ -0,0 +1,252 @@
+// Package alert evaluates alert rules on server health samples and notifies
+// email, Slack and Telegram channels when alerts fire and resolve.
+package alert
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/foxzi/sendry/internal/web/config"
+	"github.com/foxzi/sendry/internal/web/models"
+	"github.com/foxzi/sendry/internal/web/repository"
+	"github.com/foxzi/sendry/internal/web/sendry"
+)
+
+// bounceWindow is the period of the bounce rate of bounce_rate rules
+const bounceWindow = time.Hour
+
+// bounceMinItems is the number of campaign emails with a final outcome a
+// server needs in the bounce window before its bounce rate is checked
+const bounceMinItems = 20
+
+// Engine evaluates the alert rules and sends notifications
+type Engine struct {
+	cfg       config.AlertsConfig
+	alerts    *repository.AlertRepository
+	jobs      *repository.JobRepository
+	notifiers map[string]Notifier
+	logger    *slog.Logger
+}
+
+// New creates an alert engine
+func New(cfg config.AlertsConfig, db *sql.DB, sendryMgr *sendry.Manager, logger *slog.Logger) *Engine {
+	e := &Engine{
+		cfg:       cfg,
+		alerts:    repository.NewAlertRepository(db),
+		jobs:      repository.NewJobRepository(db),
+		notifiers: make(map[string]Notifier),
+		logger:    logger.With("component", "alerts"),
+	}
+	client := defaultHTTPClient()
+	for _, c := range cfg.Channels {
+		e.notifiers[c.Name] = newNotifier(c, sendryMgr, client)
+	}
+	return e
+}
+
+// Config returns the alert settings
+func (e *Engine) Config() config.AlertsConfig {
+	return e.cfg
+}
+
+// Evaluate checks the rules on the health samples of the servers, opening
+// alerts for conditions newly met and resolving those no longer met
+func (e *Engine) Evaluate(ctx context.Context, samples []*models.ServerHealth) {
+	if !e.cfg.Enabled {
+		return
+	}
+
+	firing, err := e.alerts.Firing()
+	if err != nil {
+		e.logger.Error("failed to load firing alerts", "error", err)
+		return
+	}
+	open := make(map[string]*models.Alert, len(firing))
+	for i := range firing {
+		open[firing[i].Rule+"\x00"+firing[i].Server] = &firing[i]
+	}
+
+	now := time.Now()
+	for _, rule := range e.cfg.Rules {
+		for _, h := range samples {
+			if len(rule.Servers) > 0 && !slices.Contains(rule.Servers, h.Server) {
+				continue
+			}
+			message, met := e.check(rule, h, now)
+			key := rule.Name + "\x00" + h.Server
+			a := open[key]
+			delete(open, key)
+
+			switch {
+			case met && a == nil:
+				a = &models.Alert{Rule: rule.Name, Type: rule.Type, Server: h.Server, Message: message, StartedAt: now}
+				if err := e.alerts.Open(a); err != nil {
+					e.logger.Error("failed to open alert", "rule", rule.Name, "server", h.Server, "error", err)
+					continue
+				}
+				e.logger.Warn("alert firing", "rule", rule.Name, "server", h.Server, "message", message)
+				e.notify(ctx, rule, a, false, now)
+			case met:
+				if message != a.Message {
+					a.Message = message
+					e.alerts.Update(a.ID, message)
+				}
+				if a.NotifiedAt == nil || now.Sub(*a.NotifiedAt) >= e.cfg.Repeat {
+					e.notify(ctx, rule, a, false, now)
+				}
+			case a != nil:
+				e.resolve(ctx, rule, a, now)
+			}
+		}
+	}
+
+	// Alerts of rules or servers removed from the configuration
+	for _, a := range open {
+		if err := e.alerts.Resolve(a.ID, now); err != nil {
+			e.logger.Error("failed to resolve alert", "alert_id", a.ID, "error", err)
+		}
+	}
+}
+
+// check returns the alert message and whether the rule condition is met
+func (e *Engine) check(rule config.AlertRule, h *models.ServerHealth, now time.Time) (string, bool) {
+	if rule.Type == config.AlertServerDown {
+		if h.Online {
+			return "", false
+		}
+		if h.Error != "" {
+			return "Server unreachable: " + h.Error, true
+		}
+		return "Server unreachable", true
+	}
+	// The other checks need data from the server
+	if !h.Online {
+		return "", false
+	}
+
+	switch rule.Type {
+	case config.AlertDLQSize:
+		if float64(h.DLQSize) > rule.Threshold {
+			return fmt.Sprintf("Dead letter queue has %d messages (threshold %g)", h.DLQSize, rule.Threshold), true
+		}
+	case config.AlertQueueAge:
+		if age := time.Duration(h.OldestQueued) * time.Second; age > rule.Age {
+			return fmt.Sprintf("Oldest queued message is %s old (threshold %s)", age, rule.Age), true
+		}
+	case config.AlertCertExpiry:
+		if h.CertExpiresAt == nil {
+			return "", false
+		}
+		days := h.CertExpiresAt.Sub(now).Hours() / 24
+		if days < 0 {
+			return fmt.Sprintf("TLS certificate of %s has expired", h.CertDomain), true
+		}
+		if days < rule.Threshold {
+			return fmt.Sprintf("TLS certificate of %s expires in %d days", h.CertDomain, int(days)), true
+		}
+	case config.AlertDNSBL:
+		if len(h.DNSBLListings) > 0 {
+			return "Outbound IPs listed on DNSBLs: " + strings.Join(h.DNSBLListings, ", "), true
+		}
+	case config.AlertBounceRate:
+		completed, bounced, err := e.jobs.ServerOutcomes(h.Server, now.Add(-bounceWindow))
+		if err != nil {
+			e.logger.Error("failed to count server outcomes", "server", h.Server, "error", err)
+			return "", false
+		}
+		if completed < bounceMinItems {
+			return "", false
+		}
+		if rate := float64(bounced) * 100 / float64(completed); rate > rule.Threshold {
+			return fmt.Sprintf("Bounce rate %.1f%% in the last hour, %d of %d emails (threshold %g%%)",
+				rate, bounced, completed, rule.Threshold), true
+		}
+	}
+	return "", false
+}
+
+// resolve closes an alert and notifies its channels if they were told it fired
+func (e *Engine) resolve(ctx context.Context, rule config.AlertRule, a *models.Alert, now time.Time) {
+	if err := e.alerts.Resolve(a.ID, now); err != nil {
+		e.logger.Error("failed to resolve alert", "alert_id", a.ID, "error", err)
+		return
+	}
+	e.logger.Info("alert resolved", "rule", a.Rule, "server", a.Server)
+	if a.NotifiedAt != nil {
+		e.notify(ctx, rule, a, true, now)
+	}
+}
+
+// notify sends the alert to the rule channels unless a silence covers it
+func (e *Engine) notify(ctx context.Context, rule config.AlertRule, a *models.Alert, resolved bool, now time.Time) {
+	silenced, err := e.alerts.Silenced(a.Rule, a.Server, now)
+	if err != nil {
+		e.logger.Error("failed to check alert silences", "error", err)
+	}
+	if silenced {
+		return
+	}
+
+	n := formatNotification(a, resolved, now)
+	sent := false
+	for _, name := range e.channels(rule) {
+		if err := e.notifiers[name].Notify(ctx, n); err != nil {
+			e.logger.Error("failed to send alert notification", "channel", name, "rule", a.Rule, "error", err)
+			continue
+		}
+		sent = true
+	}
+	if sent && !resolved {
+		if err := e.alerts.SetNotified(a.ID, now); err != nil {
+			e.logger.Error("failed to record alert notification", "alert_id", a.ID, "error", err)
+		}
+		a.NotifiedAt = &now
+	}
+}
+
+// channels returns the channel names of a rule, all channels if none are set
+func (e *Engine) channels(rule config.AlertRule) []string {
+	if len(rule.Channels) > 0 {
+		return rule.Channels
+	}
+	names := make([]string, 0, len(e.cfg.Channels))
+	for _, c := range e.cfg.Channels {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+// Test sends a test notification to a channel
+func (e *Engine) Test(ctx context.Context, channel string) error {
+	n, ok := e.notifiers[channel]
+	if !ok {
+		return fmt.Errorf("channel %q not found", channel)
+	}
+	return n.Notify(ctx, Notification{
+		Subject: "[Sendry] Test alert",
+		Text:    "[Sendry] Test alert\n\nAlert notifications to this channel work.",
+	})
+}
+
+// formatNotification builds the notification of an alert
+func formatNotification(a *models.Alert, resolved bool, now time.Time) Notification {
+	state := "FIRING"
+	if resolved {
+		state = "RESOLVED"
+	}
+	subject := fmt.Sprintf("[Sendry %s] %s on %s", state, a.Rule, a.Server)
+
+	var b strings.Builder
+	b.WriteString(subject + "\n\n")
+	b.WriteString(a.Message + "\n")
+	b.WriteString("Since: " + a.StartedAt.UTC().Format("2006-01-02 15:04 UTC") + "\n")
+	if resolved {
+		b.WriteString("Resolved: " + now.UTC().Format("2006-01-02 15:04 UTC") + "\n")
+	}
+	return Notification{Subject: subject, Text: b.String()}
+}
@@ -0,0 +1,216 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/foxzi/sendry/internal/web/config"
+	"github.com/foxzi/sendry/internal/web/db"
+	"github.com/foxzi/sendry/internal/web/models"
+	"github.com/foxzi/sendry/internal/web/sendry"
+)
+
+type fakeNotifier struct {
+	sent []Notification
+	err  error
+}
+
+func (f *fakeNotifier) Notify(ctx context.Context, n Notification) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.sent = append(f.sent, n)
+	return nil
+}
+
+func newTestEngine(t *testing.T, cfg config.AlertsConfig) (*Engine, *fakeNotifier) {
+	t.Helper()
+	database, err := db.New(filepath.Join(t.TempDir(), "web.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	e := New(cfg, database.DB, sendry.NewManager(nil), slog.New(slog.DiscardHandler))
+	n := &fakeNotifier{}
+	e.notifiers = map[string]Notifier{"ops": n}
+	return e, n
+}
+
+func TestEngineEvaluate(t *testing.T) {
+	e, n := newTestEngine(t, config.AlertsConfig{
+		Enabled: true,
+		Repeat:  time.Hour,
+		Rules: []config.AlertRule{
+			{Name: "down", Type: config.AlertServerDown},
+			{Name: "dlq", Type: config.AlertDLQSize, Threshold: 100, Servers: []string{"mta-1"}},
+		},
+		Channels: []config.AlertChannel{{Name: "ops", Type: config.AlertChannelSlack}},
+	})
+	ctx := context.Background()
+
+	healthy := []*models.ServerHealth{{Server: "mta-1", Online: true, DLQSize: 10}, {Server: "mta-2", Online: true, DLQSize: 500}}
+	e.Evaluate(ctx, healthy)
+	if len(n.sent) != 0 {
+		t.Fatalf("notifications for healthy servers: %v", n.sent)
+	}
+
+	failing := []*models.ServerHealth{{Server: "mta-1", Online: true, DLQSize: 150}, {Server: "mta-2", Error: "connection refused"}}
+	e.Evaluate(ctx, failing)
+	if len(n.sent) != 2 {
+		t.Fatalf("got %d notifications, want 2", len(n.sent))
+	}
+	if n.sent[0].Subject != "[Sendry FIRING] down on mta-2" || !strings.Contains(n.sent[0].Text, "connection refused") {
+		t.Errorf("first notification = %+v", n.sent[0])
+	}
+
+	// Still failing within the repeat interval: no new notifications
+	e.Evaluate(ctx, failing)
+	if len(n.sent) != 2 {
+		t.Fatalf("got %d notifications after repeat check, want 2", len(n.sent))
+	}
+	firing, _ := e.alerts.Firing()
+	if len(firing) != 2 {
+		t.Fatalf("Firing() = %d alerts, want 2", len(firing))
+	}
+
+	e.Evaluate(ctx, healthy)
+	if len(n.sent) != 4 || !strings.HasPrefix(n.sent[2].Subject, "[Sendry RESOLVED]") {
+		t.Fatalf("resolve notifications = %+v", n.sent[2:])
+	}
+	if firing, _ := e.alerts.Firing(); len(firing) != 0 {
+		t.Errorf("Firing() after recovery = %d alerts, want 0", len(firing))
+	}
+}
+
+func TestEngineEvaluate_Silenced(t *testing.T) {
+	e, n := newTestEngine(t, config.AlertsConfig{
+		Enabled:  true,
+		Repeat:   time.Hour,
+		Rules:    []config.AlertRule{{Name: "down", Type: config.AlertServerDown}},
+		Channels: []config.AlertChannel{{Name: "ops", Type: config.AlertChannelSlack}},
+	})
+	ctx := context.Background()
+
+	now := time.Now()
+	silence := &models.AlertSilence{Server: "mta-1", StartsAt: now.Add(-time.Minute), EndsAt: now.Add(time.Hour)}
+	if err := e.alerts.CreateSilence(silence); err != nil {
+		t.Fatalf("CreateSilence() error = %v", err)
+	}
+
+	down := []*models.ServerHealth{{Server: "mta-1"}}
+	e.Evaluate(ctx, down)
+	if len(n.sent) != 0 {
+		t.Fatalf("notifications while silenced: %v", n.sent)
+	}
+	if firing, _ := e.alerts.Firing(); len(firing) != 1 {
+		t.Fatalf("Firing() = %d alerts, want the silenced alert recorded", len(firing))
+	}
+
+	// The silence ends while the server is still down
+	e.alerts.DeleteSilence(silence.ID)
+	e.Evaluate(ctx, down)
+	if len(n.sent) != 1 {
+		t.Fatalf("got %d notifications after the silence, want 1", len(n.sent))
+	}
+}
+
+func TestEngineEvaluate_FailedNotification(t *testing.T) {
+	e, n := newTestEngine(t, config.AlertsConfig{
+		Enabled:  true,
+		Repeat:   time.Hour,
+		Rules:    []config.AlertRule{{Name: "down", Type: config.AlertServerDown}},
+		Channels: []config.AlertChannel{{Name: "ops", Type: config.AlertChannelSlack}},
+	})
+	ctx := context.Background()
+
+	n.err = errors.New("webhook down")
+	e.Evaluate(ctx, []*models.ServerHealth{{Server: "mta-1"}})
+
+	// Not notified yet, so the next check retries
+	n.err = nil
+	e.Evaluate(ctx, []*models.ServerHealth{{Server: "mta-1"}})
+	if len(n.sent) != 1 {
+		t.Fatalf("got %d notifications, want the retry", len(n.sent))
+	}
+}
+
+func TestEngineCheck(t *testing.T) {
+	e, _ := newTestEngine(t, config.AlertsConfig{Enabled: true})
+	now := time.Now()
+	expires := now.Add(5 * 24 * time.Hour)
+
+	tests := []struct {
+		name string
+		rule config.AlertRule
+		h    *models.ServerHealth
+		want bool
+	}{
+		{"queue age over", config.AlertRule{Type: config.AlertQueueAge, Age: time.Hour}, &models.ServerHealth{Online: true, OldestQueued: 7200}, true},
+		{"queue age under", config.AlertRule{Type: config.AlertQueueAge, Age: time.Hour}, &models.ServerHealth{Online: true, OldestQueued: 60}, false},
+		{"cert expiring", config.AlertRule{Type: config.AlertCertExpiry, Threshold: 14}, &models.ServerHealth{Online: true, CertDomain: "mx", CertExpiresAt: &expires}, true},
+		{"cert valid", config.AlertRule{Type: config.AlertCertExpiry, Threshold: 3}, &models.ServerHealth{Online: true, CertExpiresAt: &expires}, false},
+		{"offline server skips data checks", config.AlertRule{Type: config.AlertDLQSize, Threshold: 1}, &models.ServerHealth{DLQSize: 50}, false},
+		{"ip listed", config.AlertRule{Type: config.AlertDNSBL}, &models.ServerHealth{Online: true, DNSBLListings: []string{"192.0.2.1: SpamCop"}}, true},
+		{"ips clean", config.AlertRule{Type: config.AlertDNSBL}, &models.ServerHealth{Online: true}, false},
+		{"bounce rate without traffic", config.AlertRule{Type: config.AlertBounceRate, Threshold: 5}, &models.ServerHealth{Server: "mta-1", Online: true}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, got := e.check(tt.rule, tt.h, now); got != tt.want {
+				t.Errorf("check() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebhookNotifiers(t *testing.T) {
+	var got []map[string]string
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		got = append(got, body)
+		paths = append(paths, r.URL.Path)
+		if strings.Contains(r.URL.Path, "bad") {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	n := Notification{Subject: "s", Text: "hello"}
+
+	slack := &slackNotifier{url: srv.URL + "/hook", client: srv.Client()}
+	if err := slack.Notify(ctx, n); err != nil {
+		t.Fatalf("slack Notify() error = %v", err)
+	}
+	tg := &telegramNotifier{apiURL: srv.URL, token: "123:abc", chatID: "-100", client: srv.Client()}
+	if err := tg.Notify(ctx, n); err != nil {
+		t.Fatalf("telegram Notify() error = %v", err)
+	}
+	if got[0]["text"] != "hello" || got[1]["chat_id"] != "-100" || paths[1] != "/bot123:abc/sendMessage" {
+		t.Errorf("requests = %v %v", got, paths)
+	}
+
+	bad := &slackNotifier{url: srv.URL + "/bad", client: srv.Client()}
+	if err := bad.Notify(ctx, n); err == nil {
+		t.Error("Notify() on 403 error = nil")
+	}
+
+	down := &slackNotifier{url: "http://127.0.0.1:1/secret-token", client: srv.Client()}
+	if err := down.Notify(ctx, n); err == nil || strings.Contains(err.Error(), "secret-token") {
+		t.Errorf("Notify() error = %v, want error without the URL", err)
+	}
+}
@@ -0,0 +1,122 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/foxzi/sendry/internal/web/config"
+	"github.com/foxzi/sendry/internal/web/sendry"
+	sendryclient "github.com/foxzi/sendry/pkg/client"
+)
+
+// telegramAPI is the base URL of the Telegram Bot API
+const telegramAPI = "https://api.telegram.org"
+
+// Notification is an alert message sent to a channel
+type Notification struct {
+	Subject string // One line summary
+	Text    string // Summary followed by details
+}
+
+// Notifier delivers notifications to a channel
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// newNotifier creates the notifier of a channel
+func newNotifier(c config.AlertChannel, sendryMgr *sendry.Manager, httpClient *http.Client) Notifier {
+	switch c.Type {
+	case config.AlertChannelEmail:
+		return &emailNotifier{sendry: sendryMgr, server: c.Server, from: c.From, to: c.To}
+	case config.AlertChannelSlack:
+		return &slackNotifier{url: c.WebhookURL, client: httpClient}
+	case config.AlertChannelTelegram:
+		return &telegramNotifier{apiURL: telegramAPI, token: c.BotToken, chatID: c.ChatID, client: httpClient}
+	}
+	return nil
+}
+
+// emailNotifier sends notifications as email through a Sendry server
+type emailNotifier struct {
+	sendry *sendry.Manager
+	server string
+	from   string
+	to     []string
+}
+
+func (e *emailNotifier) Notify(ctx context.Context, n Notification) error {
+	client, err := e.sendry.GetClient(e.server)
+	if err != nil {
+		return err
+	}
+	_, err = client.Send(ctx, &sendryclient.SendRequest{
+		From:    e.from,
+		To:      e.to,
+		Subject: n.Subject,
+		Body:    n.Text,
+	})
+	return err
+}
+
+// slackNotifier posts notifications to a Slack incoming webhook
+type slackNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func (s *slackNotifier) Notify(ctx context.Context, n Notification) error {
+	return postJSON(ctx, s.client, s.url, map[string]string{"text": n.Text})
+}
+
+// telegramNotifier sends notifications as Telegram bot messages
+type telegramNotifier struct {
+	apiURL string
+	token  string
+	chatID string
+	client *http.Client
+}
+
+func (t *telegramNotifier) Notify(ctx context.Context, n Notification) error {
+	endpoint := t.apiURL + "/bot" + url.PathEscape(t.token) + "/sendMessage"
+	return postJSON(ctx, t.client, endpoint, map[string]string{"chat_id": t.chatID, "text": n.Text})
+}
+
+// postJSON posts a JSON body and fails on a non-2xx response
+func postJSON(ctx context.Context, client *http.Client, endpoint string, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		// The URL holds the webhook secret or bot token, keep it out of logs
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return fmt.Errorf("do request: %w", urlErr.Err)
+		}
+		return fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// defaultHTTPClient is the client of webhook notifications
+func defaultHTTPClient() *http.Client {
+	return &http.Client{Timeout: 10 * time.Second}
+}
@@ -5,6 +5,7 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"fmt"
+	"strings"
 	"sync"
 
 	"github.com/coreos/go-oidc/v3/oidc"
@@ -101,41 +102,23 @@ func (p *OIDCProvider) Exchange(ctx context.Context, state, code string) (*UserI
 	}
 
 	// Extract claims
-	var claims struct {
-		Email         string   `json:"email"`
-		EmailVerified bool     `json:"email_verified"`
-		Name          string   `json:"name"`
-		Groups        []string `json:"groups"`
-	}
-
+	var claims map[string]any
 	if err := idToken.Claims(&claims); err != nil {
 		return nil, fmt.Errorf("failed to parse claims: %w", err)
 	}
 
+	info := userInfoFromClaims(claims, p.config.GroupsClaim)
+	if info.Email == "" {
+		return nil, fmt.Errorf("no email in id_token")
+	}
+
 	// Check allowed groups
-	if len(p.config.AllowedGroups) > 0 {
-		allowed := false
-		for _, allowedGroup := range p.config.AllowedGroups {
-			for _, userGroup := range claims.Groups {
-				if userGroup == allowedGroup {
-					allowed = true
-					break
-				}
-			}
-			if allowed {
-				break
-			}
-		}
-		if !allowed {
-			return nil, fmt.Errorf("user not in allowed groups")
-		}
+	if len(p.config.AllowedGroups) > 0 && !hasAnyGroup(info.Groups, p.config.AllowedGroups) {
+		return nil, fmt.Errorf("user not in allowed groups")
 	}
 
-	return &UserInfo{
-		Email:  claims.Email,
-		Name:   claims.Name,
-		Groups: claims.Groups,
-	}, nil
+	info.Role = MapRole(p.config, info.Groups)
+	return info, nil
 }
 
 // UserInfo represents user information from OIDC
@@ -143,6 +126,87 @@ type UserInfo struct {
 	Email  string
 	Name   string
 	Groups []string
+	Role   string // Role mapped from the groups, empty without group_roles
+}
+
+// userInfoFromClaims reads the user from ID token claims. Providers without
+// an email claim (Azure AD) fall back to preferred_username or upn when it
+// is an address.
+func userInfoFromClaims(claims map[string]any, groupsClaim string) *UserInfo {
+	info := &UserInfo{}
+	info.Email, _ = claims["email"].(string)
+	for _, key := range []string{"preferred_username", "upn"} {
+		if v, _ := claims[key].(string); info.Email == "" && strings.Contains(v, "@") {
+			info.Email = v
+		}
+	}
+	info.Name, _ = claims["name"].(string)
+
+	// Walk a dotted path to nested claims
+	var v any = claims
+	for _, key := range strings.Split(groupsClaim, ".") {
+		m, ok := v.(map[string]any)
+		if !ok {
+			v = nil
+			break
+		}
+		v = m[key]
+	}
+	switch groups := v.(type) {
+	case []any:
+		for _, g := range groups {
+			if s, ok := g.(string); ok {
+				info.Groups = append(info.Groups, s)
+			}
+		}
+	case string:
+		info.Groups = []string{groups}
+	}
+	return info
+}
+
+// MapRole returns the role of a user in groups by the group_roles mapping:
+// admin if any group maps to admin, else user if any maps to user, else
+// the default role. It returns "" when no mapping is configured.
+func MapRole(cfg *config.OIDCConfig, groups []string) string {
+	if len(cfg.GroupRoles) == 0 {
+		return ""
+	}
+	role := ""
+	for _, g := range groups {
+		switch groupRole(cfg.GroupRoles, g) {
+		case "admin":
+			return "admin"
+		case "user":
+			role = "user"
+		}
+	}
+	if role == "" {
+		role = cfg.DefaultRole
+	}
+	return role
+}
+
+// groupRole looks up a group, also without the leading slash of Keycloak
+// group paths
+func groupRole(roles map[string]string, group string) string {
+	if role, ok := roles[group]; ok {
+		return role
+	}
+	return roles[strings.TrimPrefix(group, "/")]
+}
+
+// hasAnyGroup reports whether groups contain any of wanted, comparing
+// Keycloak group paths without the leading slash too
+func hasAnyGroup(groups, wanted []string) bool {
+	for _, g := range groups {
+		for _, w := range wanted {
+			if g == w || strings.TrimPrefix(g, "/") == w {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 func generateState() (string, error) {
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/foxzi/sendry/internal/web/config"
@@ -130,3 +131,85 @@ func TestUserInfo(t *testing.T) {
 		t.Errorf("UserInfo.Groups length = %v, want %v", len(info.Groups), 2)
 	}
 }
+
+func TestUserInfoFromClaims(t *testing.T) {
+	tests := []struct {
+		name        string
+		claims      map[string]any
+		groupsClaim string
+		wantEmail   string
+		wantGroups  []string
+	}{
+		{
+			name:        "groups claim",
+			claims:      map[string]any{"email": "ann@example.com", "groups": []any{"sendry-admins", "staff"}},
+			groupsClaim: "groups",
+			wantEmail:   "ann@example.com",
+			wantGroups:  []string{"sendry-admins", "staff"},
+		},
+		{
+			name: "nested Keycloak roles",
+			claims: map[string]any{
+				"email":        "ann@example.com",
+				"realm_access": map[string]any{"roles": []any{"sendry-admin"}},
+			},
+			groupsClaim: "realm_access.roles",
+			wantEmail:   "ann@example.com",
+			wantGroups:  []string{"sendry-admin"},
+		},
+		{
+			name:        "Azure AD without email",
+			claims:      map[string]any{"preferred_username": "ann@corp.example.com", "roles": "Sender"},
+			groupsClaim: "roles",
+			wantEmail:   "ann@corp.example.com",
+			wantGroups:  []string{"Sender"},
+		},
+		{
+			name:        "username is not an address",
+			claims:      map[string]any{"preferred_username": "ann", "groups": []any{"staff"}},
+			groupsClaim: "realm_access.roles",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := userInfoFromClaims(tt.claims, tt.groupsClaim)
+			if info.Email != tt.wantEmail {
+				t.Errorf("Email = %q, want %q", info.Email, tt.wantEmail)
+			}
+			if strings.Join(info.Groups, ",") != strings.Join(tt.wantGroups, ",") {
+				t.Errorf("Groups = %v, want %v", info.Groups, tt.wantGroups)
+			}
+		})
+	}
+}
+
+func TestMapRole(t *testing.T) {
+	cfg := &config.OIDCConfig{
+		GroupRoles:  map[string]string{"sendry-admins": "admin", "marketing": "user"},
+		DefaultRole: "user",
+	}
+
+	tests := []struct {
+		groups []string
+		want   string
+	}{
+		{[]string{"marketing", "sendry-admins"}, "admin"},
+		{[]string{"/sendry-admins"}, "admin"},
+		{[]string{"marketing"}, "user"},
+		{nil, "user"},
+	}
+	for _, tt := range tests {
+		if got := MapRole(cfg, tt.groups); got != tt.want {
+			t.Errorf("MapRole(%v) = %q, want %q", tt.groups, got, tt.want)
+		}
+	}
+
+	if got := MapRole(&config.OIDCConfig{}, []string{"sendry-admins"}); got != "" {
+		t.Errorf("MapRole() without mapping = %q, want empty", got)
+	}
+
+	if !hasAnyGroup([]string{"/staff"}, []string{"staff"}) || hasAnyGroup([]string{"staff"}, []string{"admins"}) {
+		t.Error("hasAnyGroup() mismatch")
+	}
+}
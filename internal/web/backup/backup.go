@@ -0,0 +1,286 @@
+// Package backup creates, prunes and restores compressed snapshots of the
+// sendry-web SQLite database.
+package backup
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+const (
+	filePrefix = "sendry-web-"
+	fileSuffix = ".db.gz"
+	timeLayout = "20060102-150405"
+)
+
+// File is a backup archive in the backup directory
+type File struct {
+	Name    string
+	Path    string
+	Size    int64
+	Created time.Time
+}
+
+// FileName returns the archive name of a backup taken at t
+func FileName(t time.Time) string {
+	return filePrefix + t.UTC().Format(timeLayout) + fileSuffix
+}
+
+// Create writes a gzip-compressed snapshot of the database to dir. The
+// snapshot is taken with the SQLite online backup API, so the database
+// stays usable while it runs.
+func Create(ctx context.Context, db *sql.DB, dir string, now time.Time) (*File, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	name := FileName(now)
+	path := filepath.Join(dir, name)
+
+	tmp, err := os.CreateTemp(dir, ".snapshot-*.db")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create snapshot file: %w", err)
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	if err := snapshot(ctx, db, tmp.Name()); err != nil {
+		return nil, fmt.Errorf("failed to snapshot database: %w", err)
+	}
+
+	if err := compress(tmp.Name(), path); err != nil {
+		os.Remove(path)
+		return nil, fmt.Errorf("failed to compress snapshot: %w", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	return &File{Name: name, Path: path, Size: info.Size(), Created: now}, nil
+}
+
+// snapshot copies the main database of db into a new SQLite file
+func snapshot(ctx context.Context, db *sql.DB, dest string) error {
+	destDB, err := sql.Open("sqlite3", dest)
+	if err != nil {
+		return err
+	}
+	defer destDB.Close()
+
+	destConn, err := destDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer destConn.Close()
+
+	srcConn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer srcConn.Close()
+
+	return destConn.Raw(func(dc any) error {
+		return srcConn.Raw(func(sc any) error {
+			d, ok := dc.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("unexpected destination driver %T", dc)
+			}
+			s, ok := sc.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("unexpected source driver %T", sc)
+			}
+
+			b, err := d.Backup("main", s, "main")
+			if err != nil {
+				return err
+			}
+			if _, err := b.Step(-1); err != nil {
+				b.Finish()
+				return err
+			}
+			return b.Finish()
+		})
+	})
+}
+
+func compress(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(out)
+	if _, err := io.Copy(gz, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// List returns the backups in dir, newest first
+func List(dir string) ([]File, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var files []File
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, filePrefix) || !strings.HasSuffix(name, fileSuffix) {
+			continue
+		}
+		created, err := time.Parse(timeLayout, strings.TrimSuffix(strings.TrimPrefix(name, filePrefix), fileSuffix))
+		if err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, File{
+			Name:    name,
+			Path:    filepath.Join(dir, name),
+			Size:    info.Size(),
+			Created: created,
+		})
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Created.After(files[j].Created)
+	})
+	return files, nil
+}
+
+// Prune deletes all but the newest keep backups in dir and returns the
+// names of the deleted files. A keep of zero keeps everything.
+func Prune(dir string, keep int) ([]string, error) {
+	if keep <= 0 {
+		return nil, nil
+	}
+
+	files, err := List(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var deleted []string
+	for i := keep; i < len(files); i++ {
+		if err := os.Remove(files[i].Path); err != nil {
+			return deleted, err
+		}
+		deleted = append(deleted, files[i].Name)
+	}
+	return deleted, nil
+}
+
+// Restore replaces the database at dbPath with a backup. The archive may be
+// gzip-compressed or a plain SQLite file. The current database, if any, is
+// kept next to it with a ".before-restore-<time>" suffix, whose path is
+// returned. sendry-web must not be running while the database is restored.
+func Restore(archive, dbPath string) (string, error) {
+	tmp := dbPath + ".restore"
+	if err := extract(archive, tmp); err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("failed to extract backup: %w", err)
+	}
+
+	if err := checkIntegrity(tmp); err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("backup is not a valid database: %w", err)
+	}
+
+	var previous string
+	if _, err := os.Stat(dbPath); err == nil {
+		previous = dbPath + ".before-restore-" + time.Now().UTC().Format(timeLayout)
+		if err := os.Rename(dbPath, previous); err != nil {
+			os.Remove(tmp)
+			return "", fmt.Errorf("failed to move current database aside: %w", err)
+		}
+		// WAL files belong to the old database
+		for _, suffix := range []string{"-wal", "-shm"} {
+			if _, err := os.Stat(dbPath + suffix); err == nil {
+				os.Rename(dbPath+suffix, previous+suffix)
+			}
+		}
+	}
+
+	if err := os.Rename(tmp, dbPath); err != nil {
+		return previous, fmt.Errorf("failed to install restored database: %w", err)
+	}
+	return previous, nil
+}
+
+func extract(archive, dst string) error {
+	in, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	var src io.Reader = bufio.NewReader(in)
+	if magic, _ := src.(*bufio.Reader).Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(src)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		src = gz
+	}
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, src); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func checkIntegrity(path string) error {
+	db, err := sql.Open("sqlite3", path+"?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var result string
+	if err := db.QueryRow("PRAGMA integrity_check").Scan(&result); err != nil {
+		return err
+	}
+	if result != "ok" {
+		return fmt.Errorf("integrity check failed: %s", result)
+	}
+	return nil
+}
@@ -0,0 +1,274 @@
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/foxzi/sendry/internal/web/config"
+	"github.com/foxzi/sendry/internal/web/db"
+)
+
+func newTestDB(t *testing.T, path string) *db.DB {
+	t.Helper()
+	database, err := db.New(path)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	return database
+}
+
+func countRows(t *testing.T, path string) int {
+	t.Helper()
+	database, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+
+	var n int
+	if err := database.QueryRow("SELECT COUNT(*) FROM notes").Scan(&n); err != nil {
+		t.Fatalf("failed to count rows: %v", err)
+	}
+	return n
+}
+
+func TestCreateAndRestore(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "web.db")
+	backupDir := filepath.Join(dir, "backups")
+
+	database := newTestDB(t, dbPath)
+	if _, err := database.Exec("CREATE TABLE notes (body TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.Exec("INSERT INTO notes (body) VALUES ('one'), ('two')"); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2026, 3, 14, 3, 0, 0, 0, time.UTC)
+	file, err := Create(context.Background(), database.DB, backupDir, now)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if file.Name != "sendry-web-20260314-030000.db.gz" {
+		t.Errorf("file.Name = %q", file.Name)
+	}
+	if file.Size == 0 {
+		t.Error("file.Size = 0")
+	}
+
+	// Changes after the backup are discarded by the restore
+	if _, err := database.Exec("INSERT INTO notes (body) VALUES ('three')"); err != nil {
+		t.Fatal(err)
+	}
+	database.Close()
+
+	previous, err := Restore(file.Path, dbPath)
+	if err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if !strings.HasPrefix(previous, dbPath+".before-restore-") {
+		t.Errorf("previous = %q", previous)
+	}
+
+	if n := countRows(t, dbPath); n != 2 {
+		t.Errorf("restored rows = %d, want 2", n)
+	}
+	if n := countRows(t, previous); n != 3 {
+		t.Errorf("previous rows = %d, want 3", n)
+	}
+}
+
+func TestRestoreRejectsInvalidBackup(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "web.db")
+	if err := os.WriteFile(dbPath, []byte("current"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	archive := filepath.Join(dir, "garbage.db")
+	if err := os.WriteFile(archive, []byte(strings.Repeat("not a database ", 100)), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Restore(archive, dbPath); err == nil {
+		t.Fatal("Restore() expected error for invalid backup")
+	}
+
+	data, err := os.ReadFile(dbPath)
+	if err != nil || string(data) != "current" {
+		t.Errorf("current database was modified: %q, %v", data, err)
+	}
+}
+
+func TestListAndPrune(t *testing.T) {
+	dir := t.TempDir()
+	base := time.Date(2026, 3, 1, 3, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		name := FileName(base.AddDate(0, 0, i))
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Unrelated files are ignored
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("x"), 0600)
+
+	files, err := List(dir)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(files) != 5 {
+		t.Fatalf("len(files) = %d, want 5", len(files))
+	}
+	if files[0].Name != FileName(base.AddDate(0, 0, 4)) {
+		t.Errorf("newest = %q", files[0].Name)
+	}
+
+	deleted, err := Prune(dir, 2)
+	if err != nil {
+		t.Fatalf("Prune() error = %v", err)
+	}
+	if len(deleted) != 3 {
+		t.Errorf("deleted = %v, want 3 files", deleted)
+	}
+
+	files, _ = List(dir)
+	if len(files) != 2 || files[1].Name != FileName(base.AddDate(0, 0, 3)) {
+		t.Errorf("remaining = %v", files)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "notes.txt")); err != nil {
+		t.Error("unrelated file was pruned")
+	}
+}
+
+func TestManagerRun(t *testing.T) {
+	dir := t.TempDir()
+	database := newTestDB(t, filepath.Join(dir, "web.db"))
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	m := NewManager(config.BackupConfig{Dir: filepath.Join(dir, "backups"), Time: "03:00", Keep: 1}, database.DB, logger)
+
+	if last, err := m.LastResult(); err != nil || last != nil {
+		t.Fatalf("LastResult() = %v, %v; want nil", last, err)
+	}
+
+	result := m.Run(context.Background())
+	if result.Error != "" {
+		t.Fatalf("Run() error = %s", result.Error)
+	}
+
+	last, err := m.LastResult()
+	if err != nil || last == nil {
+		t.Fatalf("LastResult() = %v, %v", last, err)
+	}
+	if last.File != result.File || last.Size != result.Size {
+		t.Errorf("LastResult() = %+v, want %+v", last, result)
+	}
+
+	files, _ := m.List()
+	if len(files) != 1 {
+		t.Errorf("len(files) = %d, want 1", len(files))
+	}
+}
+
+func TestNextRun(t *testing.T) {
+	tests := []struct {
+		now  time.Time
+		at   string
+		want time.Time
+	}{
+		{time.Date(2026, 3, 1, 1, 0, 0, 0, time.UTC), "03:00", time.Date(2026, 3, 1, 3, 0, 0, 0, time.UTC)},
+		{time.Date(2026, 3, 1, 3, 0, 0, 0, time.UTC), "03:00", time.Date(2026, 3, 2, 3, 0, 0, 0, time.UTC)},
+		{time.Date(2026, 3, 31, 23, 30, 0, 0, time.UTC), "23:15", time.Date(2026, 4, 1, 23, 15, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		if got := nextRun(tt.now, tt.at); !got.Equal(tt.want) {
+			t.Errorf("nextRun(%v, %q) = %v, want %v", tt.now, tt.at, got, tt.want)
+		}
+	}
+}
+
+func TestS3Upload(t *testing.T) {
+	var gotPath, gotAuth, gotHash, gotDate string
+	var gotBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			t.Errorf("method = %s, want PUT", r.Method)
+		}
+		gotPath = r.URL.Path
+		gotAuth = r.Header.Get("Authorization")
+		gotHash = r.Header.Get("X-Amz-Content-Sha256")
+		gotDate = r.Header.Get("X-Amz-Date")
+		gotBody, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	file := filepath.Join(t.TempDir(), "sendry-web-20260301-030000.db.gz")
+	if err := os.WriteFile(file, []byte("backup"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	u := NewS3Uploader(config.BackupS3Config{
+		Endpoint:  srv.URL,
+		Region:    "eu-west-1",
+		Bucket:    "backups",
+		Prefix:    "sendry/",
+		AccessKey: "AKID",
+		SecretKey: "secret",
+	})
+	u.now = func() time.Time { return time.Date(2026, 3, 1, 3, 0, 5, 0, time.UTC) }
+
+	key, err := u.Upload(context.Background(), file)
+	if err != nil {
+		t.Fatalf("Upload() error = %v", err)
+	}
+	if key != "sendry/sendry-web-20260301-030000.db.gz" {
+		t.Errorf("key = %q", key)
+	}
+	if gotPath != "/backups/"+key {
+		t.Errorf("path = %q", gotPath)
+	}
+	if string(gotBody) != "backup" {
+		t.Errorf("body = %q", gotBody)
+	}
+	if gotHash != sha256Hex("backup") {
+		t.Errorf("X-Amz-Content-Sha256 = %q", gotHash)
+	}
+	if gotDate != "20260301T030005Z" {
+		t.Errorf("X-Amz-Date = %q", gotDate)
+	}
+	wantPrefix := "AWS4-HMAC-SHA256 Credential=AKID/20260301/eu-west-1/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature="
+	if !strings.HasPrefix(gotAuth, wantPrefix) || len(gotAuth) != len(wantPrefix)+64 {
+		t.Errorf("Authorization = %q", gotAuth)
+	}
+}
+
+func TestS3UploadError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "<Error><Code>AccessDenied</Code></Error>", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	file := filepath.Join(t.TempDir(), "backup.db.gz")
+	os.WriteFile(file, []byte("backup"), 0600)
+
+	u := NewS3Uploader(config.BackupS3Config{Endpoint: srv.URL, Region: "us-east-1", Bucket: "b", AccessKey: "a", SecretKey: "s"})
+	_, err := u.Upload(context.Background(), file)
+	if err == nil || !strings.Contains(err.Error(), "AccessDenied") {
+		t.Errorf("Upload() error = %v, want AccessDenied", err)
+	}
+}
@@ -0,0 +1,189 @@
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/foxzi/sendry/internal/web/config"
+	"github.com/foxzi/sendry/internal/web/repository"
+)
+
+// statusKey is the settings key holding the result of the last backup
+const statusKey = "backup.last_result"
+
+// Result describes a backup run
+type Result struct {
+	File      string        `json:"file,omitempty"`
+	Size      int64         `json:"size,omitempty"`
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"duration"`
+	Uploaded  string        `json:"uploaded,omitempty"` // S3 key
+	Pruned    []string      `json:"pruned,omitempty"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// Manager runs database backups on demand and on a daily schedule
+type Manager struct {
+	cfg      config.BackupConfig
+	db       *sql.DB
+	settings *repository.SettingsRepository
+	uploader *S3Uploader
+	logger   *slog.Logger
+
+	mu     sync.Mutex // One backup at a time
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewManager creates a backup manager
+func NewManager(cfg config.BackupConfig, db *sql.DB, logger *slog.Logger) *Manager {
+	m := &Manager{
+		cfg:      cfg,
+		db:       db,
+		settings: repository.NewSettingsRepository(db),
+		logger:   logger.With("component", "backup"),
+	}
+	if cfg.S3.Enabled {
+		m.uploader = NewS3Uploader(cfg.S3)
+	}
+	return m
+}
+
+// Config returns the backup configuration
+func (m *Manager) Config() config.BackupConfig {
+	return m.cfg
+}
+
+// UploadTarget returns where backups are uploaded, empty if upload is off
+func (m *Manager) UploadTarget() string {
+	if m.uploader == nil {
+		return ""
+	}
+	return m.uploader.Target()
+}
+
+// Run creates a backup, uploads it if configured, prunes old backups and
+// records the result
+func (m *Manager) Run(ctx context.Context) *Result {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := &Result{StartedAt: time.Now()}
+	if err := m.run(ctx, result); err != nil {
+		result.Error = err.Error()
+		m.logger.Error("backup failed", "error", err)
+	} else {
+		m.logger.Info("backup completed", "file", result.File, "size", result.Size, "uploaded", result.Uploaded)
+	}
+	result.Duration = time.Since(result.StartedAt)
+
+	if data, err := json.Marshal(result); err == nil {
+		if err := m.settings.SetSetting(statusKey, string(data)); err != nil {
+			m.logger.Error("failed to save backup status", "error", err)
+		}
+	}
+	return result
+}
+
+func (m *Manager) run(ctx context.Context, result *Result) error {
+	file, err := Create(ctx, m.db, m.cfg.Dir, result.StartedAt)
+	if err != nil {
+		return err
+	}
+	result.File = file.Name
+	result.Size = file.Size
+
+	if m.uploader != nil {
+		key, err := m.uploader.Upload(ctx, file.Path)
+		if err != nil {
+			return err
+		}
+		result.Uploaded = key
+	}
+
+	pruned, err := Prune(m.cfg.Dir, m.cfg.Keep)
+	result.Pruned = pruned
+	if err != nil {
+		return fmt.Errorf("failed to prune old backups: %w", err)
+	}
+	return nil
+}
+
+// LastResult returns the result of the last backup, nil if none ran yet
+func (m *Manager) LastResult() (*Result, error) {
+	data, err := m.settings.GetSetting(statusKey)
+	if err != nil || data == "" {
+		return nil, err
+	}
+	var result Result
+	if err := json.Unmarshal([]byte(data), &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// List returns the local backups, newest first
+func (m *Manager) List() ([]File, error) {
+	return List(m.cfg.Dir)
+}
+
+// NextRun returns the next scheduled backup time after now
+func (m *Manager) NextRun(now time.Time) time.Time {
+	return nextRun(now, m.cfg.Time)
+}
+
+// Start runs backups daily at the configured time if backups are enabled
+func (m *Manager) Start() {
+	if !m.cfg.Enabled {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+
+	m.wg.Add(1)
+	go m.loop(ctx)
+	m.logger.Info("backup scheduler started", "time", m.cfg.Time, "dir", m.cfg.Dir, "keep", m.cfg.Keep)
+}
+
+// Stop stops the scheduler and waits for a running backup
+func (m *Manager) Stop() {
+	if m.cancel == nil {
+		return
+	}
+	m.cancel()
+	m.wg.Wait()
+}
+
+func (m *Manager) loop(ctx context.Context) {
+	defer m.wg.Done()
+
+	for {
+		timer := time.NewTimer(time.Until(m.NextRun(time.Now())))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			m.Run(ctx)
+		}
+	}
+}
+
+// nextRun returns the first time after now at the HH:MM clock time
+func nextRun(now time.Time, at string) time.Time {
+	clock, err := time.Parse("15:04", at)
+	if err != nil {
+		clock = time.Date(0, 1, 1, 3, 0, 0, 0, time.UTC)
+	}
+	next := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
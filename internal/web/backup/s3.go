@@ -0,0 +1,155 @@
+package backup
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/foxzi/sendry/internal/web/config"
+)
+
+// S3Uploader uploads backups to S3-compatible storage using AWS Signature
+// Version 4
+type S3Uploader struct {
+	cfg    config.BackupS3Config
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewS3Uploader creates an uploader for the configured bucket
+func NewS3Uploader(cfg config.BackupS3Config) *S3Uploader {
+	return &S3Uploader{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Minute},
+		now:    time.Now,
+	}
+}
+
+// Target returns the bucket and key prefix backups are uploaded to
+func (u *S3Uploader) Target() string {
+	return "s3://" + u.cfg.Bucket + "/" + strings.TrimPrefix(u.cfg.Prefix, "/")
+}
+
+// Upload stores a file under the configured prefix and returns its key
+func (u *S3Uploader) Upload(ctx context.Context, file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	// The payload hash is part of the signature
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	payloadHash := hex.EncodeToString(h.Sum(nil))
+
+	key := strings.TrimPrefix(u.cfg.Prefix, "/") + path.Base(file)
+	objectURL, err := u.objectURL(key)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, objectURL.String(), f)
+	if err != nil {
+		return "", err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/gzip")
+	u.sign(req, payloadHash)
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("S3 upload failed: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return key, nil
+}
+
+// objectURL returns the URL of a key. AWS uses virtual-hosted buckets,
+// custom endpoints such as MinIO use path-style URLs.
+func (u *S3Uploader) objectURL(key string) (*url.URL, error) {
+	if u.cfg.Endpoint == "" {
+		return url.Parse(fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", u.cfg.Bucket, u.cfg.Region, escapePath(key)))
+	}
+	return url.Parse(strings.TrimSuffix(u.cfg.Endpoint, "/") + "/" + u.cfg.Bucket + "/" + escapePath(key))
+}
+
+// sign adds the SigV4 authorization headers to the request
+func (u *S3Uploader) sign(req *http.Request, payloadHash string) {
+	t := u.now().UTC()
+	amzDate := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		"host:" + req.URL.Host,
+		"x-amz-content-sha256:" + payloadHash,
+		"x-amz-date:" + amzDate,
+		"",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + u.cfg.Region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex(canonicalRequest),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+u.cfg.SecretKey), date)
+	key = hmacSHA256(key, u.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		u.cfg.AccessKey, scope, signedHeaders, signature))
+}
+
+// escapePath URI-encodes each segment of an object key
+func escapePath(key string) string {
+	segments := strings.Split(key, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	m := hmac.New(sha256.New, key)
+	m.Write([]byte(data))
+	return m.Sum(nil)
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,218 @@
+// Package bundle exports the full definition of one domain as a portable
+// file and reads it back, so a brand can be moved between sendry-web
+// instances without re-creating it by hand.
+package bundle
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/foxzi/sendry/internal/web/crypto"
+	"github.com/foxzi/sendry/internal/web/dnssync"
+	"github.com/foxzi/sendry/internal/web/models"
+)
+
+const (
+	// Format identifies the bundle layout, bumped on incompatible changes
+	Format = 1
+
+	// MinPassphrase is the shortest passphrase accepted for DKIM keys
+	MinPassphrase = 8
+
+	kdfIterations = 600000
+	saltSize      = 16
+)
+
+// ErrPassphrase is returned when the DKIM key cannot be decrypted
+var ErrPassphrase = errors.New("wrong passphrase or damaged DKIM key")
+
+// Bundle is the portable definition of a domain
+type Bundle struct {
+	Format     int         `json:"format"`
+	ExportedAt time.Time   `json:"exported_at"`
+	Domain     Domain      `json:"domain"`
+	DKIM       *DKIM       `json:"dkim,omitempty"`
+	Templates  []Template  `json:"templates,omitempty"`
+	DNS        []DNSRecord `json:"dns,omitempty"`
+}
+
+// Domain is the domain configuration
+type Domain struct {
+	Domain              string   `json:"domain"`
+	Mode                string   `json:"mode"`
+	DefaultFrom         string   `json:"default_from,omitempty"`
+	DKIMEnabled         bool     `json:"dkim_enabled"`
+	DKIMSelector        string   `json:"dkim_selector,omitempty"`
+	RateLimitHour       int      `json:"rate_limit_hour"`
+	RateLimitDay        int      `json:"rate_limit_day"`
+	RateLimitRecipients int      `json:"rate_limit_recipients"`
+	RedirectTo          []string `json:"redirect_to,omitempty"`
+	BCCTo               []string `json:"bcc_to,omitempty"`
+}
+
+// DKIM is the DKIM key of the domain. The private key is encrypted with a
+// key derived from the export passphrase.
+type DKIM struct {
+	Selector   string `json:"selector"`
+	DNSRecord  string `json:"dns_record"`
+	Salt       string `json:"salt"`
+	PrivateKey string `json:"private_key"`
+}
+
+// Template is a template sent from the domain
+type Template struct {
+	Name          string `json:"name"`
+	Description   string `json:"description"`
+	Subject       string `json:"subject"`
+	HTML          string `json:"html"`
+	Text          string `json:"text"`
+	Variables     string `json:"variables"`
+	Folder        string `json:"folder"`
+	BlockExternal bool   `json:"block_external,omitempty"`
+}
+
+// DNSRecord is a record the domain needs
+type DNSRecord struct {
+	Kind  string `json:"kind"`
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// New builds the bundle of a domain. A linked DKIM key is encrypted with
+// passphrase, which must then be at least MinPassphrase characters.
+func New(domain *models.Domain, templates []models.Template, spfInclude, passphrase string, now time.Time) (*Bundle, error) {
+	b := &Bundle{
+		Format:     Format,
+		ExportedAt: now.UTC(),
+		Domain: Domain{
+			Domain:              domain.Domain,
+			Mode:                domain.Mode,
+			DefaultFrom:         domain.DefaultFrom,
+			DKIMEnabled:         domain.DKIMEnabled,
+			DKIMSelector:        domain.DKIMSelector,
+			RateLimitHour:       domain.RateLimitHour,
+			RateLimitDay:        domain.RateLimitDay,
+			RateLimitRecipients: domain.RateLimitRecipients,
+			RedirectTo:          domain.RedirectTo,
+			BCCTo:               domain.BCCTo,
+		},
+	}
+
+	if key := domain.DKIMKey; key != nil {
+		if len(passphrase) < MinPassphrase {
+			return nil, fmt.Errorf("passphrase must be at least %d characters", MinPassphrase)
+		}
+		salt := make([]byte, saltSize)
+		if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+			return nil, fmt.Errorf("salt: %w", err)
+		}
+		c, err := passphraseCipher(passphrase, salt)
+		if err != nil {
+			return nil, err
+		}
+		sealed, err := c.Encrypt(key.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt DKIM key: %w", err)
+		}
+		b.DKIM = &DKIM{
+			Selector:   key.Selector,
+			DNSRecord:  key.DNSRecord,
+			Salt:       base64.StdEncoding.EncodeToString(salt),
+			PrivateKey: sealed,
+		}
+	}
+
+	for _, t := range templates {
+		b.Templates = append(b.Templates, Template{
+			Name:          t.Name,
+			Description:   t.Description,
+			Subject:       t.Subject,
+			HTML:          t.HTML,
+			Text:          t.Text,
+			Variables:     t.Variables,
+			Folder:        t.Folder,
+			BlockExternal: t.BlockExternal,
+		})
+	}
+
+	for _, e := range dnssync.BuildRecommended(domain, spfInclude) {
+		if e.Action == dnssync.ActionSkip {
+			continue
+		}
+		b.DNS = append(b.DNS, DNSRecord{Kind: string(e.Kind), Type: e.Type, Name: e.Name, Value: e.Expected})
+	}
+
+	return b, nil
+}
+
+// Read parses and checks a bundle
+func Read(r io.Reader) (*Bundle, error) {
+	var b Bundle
+	if err := json.NewDecoder(r).Decode(&b); err != nil {
+		return nil, fmt.Errorf("invalid bundle: %w", err)
+	}
+	if b.Format != Format {
+		return nil, fmt.Errorf("unsupported bundle format %d", b.Format)
+	}
+	if b.Domain.Domain == "" {
+		return nil, errors.New("bundle has no domain")
+	}
+	if b.DKIM != nil && (b.DKIM.Selector == "" || b.DKIM.PrivateKey == "") {
+		return nil, errors.New("bundle has an incomplete DKIM key")
+	}
+	for _, t := range b.Templates {
+		if t.Name == "" || t.Subject == "" {
+			return nil, errors.New("bundle has a template without name or subject")
+		}
+	}
+	return &b, nil
+}
+
+// Write encodes the bundle as indented JSON
+func (b *Bundle) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(b)
+}
+
+// FileName returns the download name of the bundle
+func (b *Bundle) FileName() string {
+	return b.Domain.Domain + ".sendry-bundle.json"
+}
+
+// PrivateKey decrypts the DKIM private key with the export passphrase
+func (b *Bundle) PrivateKey(passphrase string) (string, error) {
+	if b.DKIM == nil {
+		return "", nil
+	}
+	salt, err := base64.StdEncoding.DecodeString(b.DKIM.Salt)
+	if err != nil || len(salt) != saltSize {
+		return "", ErrPassphrase
+	}
+	c, err := passphraseCipher(passphrase, salt)
+	if err != nil {
+		return "", err
+	}
+	key, err := c.Decrypt(b.DKIM.PrivateKey)
+	if err != nil {
+		return "", ErrPassphrase
+	}
+	return key, nil
+}
+
+// passphraseCipher derives an AES-256 cipher from a passphrase
+func passphraseCipher(passphrase string, salt []byte) (*crypto.Cipher, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, kdfIterations, 32)
+	if err != nil {
+		return nil, fmt.Errorf("derive key: %w", err)
+	}
+	return crypto.NewCipher(key)
+}
@@ -1,7 +1,11 @@
 package config
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
 	"time"
 
@@ -14,6 +18,14 @@ type Config struct {
 	Auth     AuthConfig     `yaml:"auth"`
 	Sendry   SendryConfig   `yaml:"sendry"`
 	Logging  LoggingConfig  `yaml:"logging"`
+	Backup   BackupConfig   `yaml:"backup"`
+
+	ContentPolicy ContentPolicyConfig `yaml:"content_policy"`
+	Tracking      TrackingConfig      `yaml:"tracking"`
+	TemplateSync  TemplateSyncConfig  `yaml:"template_sync"`
+	Health        HealthConfig        `yaml:"health"`
+	Alerts        AlertsConfig        `yaml:"alerts"`
+	DNSPublishing DNSPublishingConfig `yaml:"dns_publishing"`
 }
 
 type ServerConfig struct {
@@ -35,6 +47,173 @@ type DatabaseConfig struct {
 	Path string `yaml:"path"`
 }
 
+// BackupConfig contains scheduled database backup settings
+type BackupConfig struct {
+	Enabled bool           `yaml:"enabled"`
+	Dir     string         `yaml:"dir"`  // Default: /var/lib/sendry-web/backups
+	Time    string         `yaml:"time"` // Daily backup time, HH:MM local time (default: 03:00)
+	Keep    int            `yaml:"keep"` // Number of backups to keep (default: 7)
+	S3      BackupS3Config `yaml:"s3"`
+}
+
+// BackupS3Config contains settings for uploading backups to S3-compatible storage
+type BackupS3Config struct {
+	Enabled   bool   `yaml:"enabled"`
+	Endpoint  string `yaml:"endpoint"` // Empty for AWS, e.g. https://minio.example.com for others
+	Region    string `yaml:"region"`   // Default: us-east-1
+	Bucket    string `yaml:"bucket"`
+	Prefix    string `yaml:"prefix"` // Key prefix, e.g. "sendry-web/"
+	AccessKey string `yaml:"access_key"`
+	SecretKey string `yaml:"secret_key"`
+}
+
+// ContentPolicyConfig restricts external resources in template HTML
+type ContentPolicyConfig struct {
+	BlockExternal bool     `yaml:"block_external"` // Apply to all templates, otherwise only to templates that opt in
+	AllowedHosts  []string `yaml:"allowed_hosts"`  // Approved asset hosts, "*.example.com" for subdomains
+}
+
+// TrackingConfig contains open and click tracking settings for campaigns
+type TrackingConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	BaseURL string `yaml:"base_url"` // Public URL of the tracking endpoints (default: server.public_url)
+}
+
+// TemplateSyncConfig contains template drift detection settings
+type TemplateSyncConfig struct {
+	Enabled  bool          `yaml:"enabled"`
+	Interval time.Duration `yaml:"interval"` // Time between checks (default: 15m)
+}
+
+// HealthConfig contains server health dashboard settings
+type HealthConfig struct {
+	Enabled    bool             `yaml:"enabled"`   // Record samples for the trend charts
+	Interval   time.Duration    `yaml:"interval"`  // Time between samples (default: 1m)
+	Retention  time.Duration    `yaml:"retention"` // Age of the oldest kept sample (default: 168h)
+	Thresholds HealthThresholds `yaml:"thresholds"`
+}
+
+// HealthThresholds raise dashboard warnings when exceeded. A negative value
+// disables a check.
+type HealthThresholds struct {
+	QueueSize      int           `yaml:"queue_size"`       // Pending and retrying messages (default: 1000)
+	QueueAge       time.Duration `yaml:"queue_age"`        // Age of the oldest queued message (default: 1h)
+	DLQSize        int           `yaml:"dlq_size"`         // Messages in the dead letter queue (default: 100)
+	RateLimitUsage int           `yaml:"rate_limit_usage"` // Percent of the global rate limit used (default: 80)
+	CertExpiryDays int           `yaml:"cert_expiry_days"` // Days until a TLS certificate expires (default: 14)
+}
+
+// Alert rule types
+const (
+	AlertServerDown = "server_down"  // Server does not answer its health check
+	AlertDLQSize    = "dlq_size"     // Messages in the dead letter queue above threshold
+	AlertQueueAge   = "queue_age"    // Oldest queued message older than age, needs metrics_url
+	AlertBounceRate = "bounce_rate"  // Percent of campaign emails bounced in the last hour above threshold
+	AlertCertExpiry = "cert_expiry"  // A TLS certificate expires in less than threshold days
+	AlertDNSBL      = "dnsbl_listed" // A monitored outbound IP is listed on a DNSBL, needs dnsbl_monitor on the server
+)
+
+// Alert channel types
+const (
+	AlertChannelEmail    = "email"
+	AlertChannelSlack    = "slack"
+	AlertChannelTelegram = "telegram"
+)
+
+// AlertsConfig contains alert rules and notification channels. Rules are
+// evaluated on each server health sample, so alerts need health.enabled.
+type AlertsConfig struct {
+	Enabled  bool           `yaml:"enabled"`
+	Repeat   time.Duration  `yaml:"repeat"` // Notify again while an alert is firing (default: 4h)
+	Rules    []AlertRule    `yaml:"rules"`
+	Channels []AlertChannel `yaml:"channels"`
+}
+
+// AlertRule raises an alert for each server matching its condition
+type AlertRule struct {
+	Name      string        `yaml:"name"`
+	Type      string        `yaml:"type"`
+	Threshold float64       `yaml:"threshold"` // Messages, percent or days by type
+	Age       time.Duration `yaml:"age"`       // queue_age rules
+	Servers   []string      `yaml:"servers"`   // Empty for all servers
+	Channels  []string      `yaml:"channels"`  // Empty for all channels
+}
+
+// AlertChannel delivers alert notifications
+type AlertChannel struct {
+	Name string `yaml:"name"`
+	Type string `yaml:"type"`
+
+	// Email through a Sendry server
+	Server string   `yaml:"server"`
+	From   string   `yaml:"from"`
+	To     []string `yaml:"to"`
+
+	// Slack incoming webhook
+	WebhookURL string `yaml:"webhook_url"`
+
+	// Telegram bot
+	BotToken string `yaml:"bot_token"`
+	ChatID   string `yaml:"chat_id"`
+}
+
+// DNS providers for record publishing
+const (
+	DNSProviderCloudflare = "cloudflare"
+	DNSProviderRoute53    = "route53"
+	DNSProviderRFC2136    = "rfc2136"
+	DNSProviderNamedot    = "namedot"
+)
+
+// DNSPublishingConfig contains settings for publishing the SPF, DKIM, DMARC
+// and MX records of domains through a DNS provider API
+type DNSPublishingConfig struct {
+	Enabled        bool          `yaml:"enabled"`
+	Auto           bool          `yaml:"auto"`            // Publish when a domain or DKIM key is created or changed
+	Provider       string        `yaml:"provider"`        // cloudflare, route53, rfc2136 or namedot
+	MXHost         string        `yaml:"mx_host"`         // Publish an MX record pointing to this host; empty skips MX
+	TTL            int           `yaml:"ttl"`             // TTL of published records in seconds (default: 300)
+	Resolver       string        `yaml:"resolver"`        // host:port used to verify propagation; empty for the system resolver
+	VerifyInterval time.Duration `yaml:"verify_interval"` // Time between propagation checks (default: 1m)
+	VerifyTimeout  time.Duration `yaml:"verify_timeout"`  // Records not visible after this are failed (default: 24h)
+
+	Cloudflare DNSCloudflareConfig `yaml:"cloudflare"`
+	Route53    DNSRoute53Config    `yaml:"route53"`
+	RFC2136    DNSRFC2136Config    `yaml:"rfc2136"`
+	Namedot    DNSNamedotConfig    `yaml:"namedot"`
+}
+
+// DNSCloudflareConfig contains Cloudflare credentials: a scoped API token,
+// or the legacy account email and global API key
+type DNSCloudflareConfig struct {
+	APIToken string `yaml:"api_token"`
+	Email    string `yaml:"email"`
+	APIKey   string `yaml:"api_key"`
+}
+
+// DNSRoute53Config contains AWS credentials for Route 53
+type DNSRoute53Config struct {
+	AccessKeyID     string `yaml:"access_key_id"`
+	SecretAccessKey string `yaml:"secret_access_key"`
+	SessionToken    string `yaml:"session_token"` // Optional, for temporary credentials
+}
+
+// DNSRFC2136Config contains the primary name server and TSIG key for
+// dynamic DNS updates
+type DNSRFC2136Config struct {
+	Server        string `yaml:"server"`         // host:port, port defaults to 53
+	Zone          string `yaml:"zone"`           // Optional; detected with SOA queries when empty
+	TSIGKey       string `yaml:"tsig_key"`       // TSIG key name; empty sends unsigned updates
+	TSIGAlgorithm string `yaml:"tsig_algorithm"` // hmac-sha256 (default) or hmac-sha512
+	TSIGSecret    string `yaml:"tsig_secret"`    // Base64 secret
+}
+
+// DNSNamedotConfig contains the namedot API address and token
+type DNSNamedotConfig struct {
+	URL   string `yaml:"url"`
+	Token string `yaml:"token"`
+}
+
 type AuthConfig struct {
 	LocalEnabled  bool          `yaml:"local_enabled"`
 	SessionSecret string        `yaml:"session_secret"`
@@ -53,6 +232,15 @@ type OIDCConfig struct {
 	RedirectURL   string   `yaml:"redirect_url"`
 	Scopes        []string `yaml:"scopes"`
 	AllowedGroups []string `yaml:"allowed_groups"`
+
+	// GroupsClaim is the ID token claim holding the groups of the user, a
+	// dotted path for nested claims such as realm_access.roles
+	GroupsClaim string `yaml:"groups_claim"`
+	// GroupRoles maps groups to the role of the user (admin or user). When
+	// set, the role is updated on every login: admin if any group of the
+	// user maps to admin, DefaultRole if none is mapped.
+	GroupRoles  map[string]string `yaml:"group_roles"`
+	DefaultRole string            `yaml:"default_role"`
 }
 
 type SendryConfig struct {
@@ -65,6 +253,50 @@ type SendryServer struct {
 	BaseURL string `yaml:"base_url"`
 	APIKey  string `yaml:"api_key"`
 	Env     string `yaml:"env"`
+
+	MetricsURL string `yaml:"metrics_url"` // Prometheus endpoint for the health dashboard, e.g. http://mta:9090/metrics
+
+	TLS SendryTLSConfig `yaml:"tls"`
+}
+
+// SendryTLSConfig contains TLS settings for the API connection of a server
+type SendryTLSConfig struct {
+	CAFile   string `yaml:"ca_file"`   // CA bundle of the server certificate (default: system roots)
+	CertFile string `yaml:"cert_file"` // Client certificate for servers that require mTLS
+	KeyFile  string `yaml:"key_file"`
+}
+
+// Enabled reports whether any TLS setting is configured
+func (t SendryTLSConfig) Enabled() bool {
+	return t.CAFile != "" || t.CertFile != "" || t.KeyFile != ""
+}
+
+// ClientConfig loads the CA bundle and client certificate
+func (t SendryTLSConfig) ClientConfig() (*tls.Config, error) {
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		return nil, fmt.Errorf("cert_file and key_file must be set together")
+	}
+
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if t.CAFile != "" {
+		data, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", t.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+	if t.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
 }
 
 type MultiSendConfig struct {
@@ -126,9 +358,66 @@ func setDefaults(cfg *Config) {
 	if len(cfg.Auth.OIDC.Scopes) == 0 {
 		cfg.Auth.OIDC.Scopes = []string{"openid", "profile", "email"}
 	}
+	if cfg.Auth.OIDC.GroupsClaim == "" {
+		cfg.Auth.OIDC.GroupsClaim = "groups"
+	}
+	if cfg.Auth.OIDC.DefaultRole == "" {
+		cfg.Auth.OIDC.DefaultRole = "user"
+	}
 	if cfg.Sendry.MultiSend.Strategy == "" {
 		cfg.Sendry.MultiSend.Strategy = "round_robin"
 	}
+	if cfg.Backup.Dir == "" {
+		cfg.Backup.Dir = "/var/lib/sendry-web/backups"
+	}
+	if cfg.Backup.Time == "" {
+		cfg.Backup.Time = "03:00"
+	}
+	if cfg.Backup.Keep == 0 {
+		cfg.Backup.Keep = 7
+	}
+	if cfg.Backup.S3.Region == "" {
+		cfg.Backup.S3.Region = "us-east-1"
+	}
+	if cfg.Tracking.BaseURL == "" {
+		cfg.Tracking.BaseURL = cfg.Server.PublicURL
+	}
+	if cfg.TemplateSync.Interval == 0 {
+		cfg.TemplateSync.Interval = 15 * time.Minute
+	}
+	if cfg.Health.Interval == 0 {
+		cfg.Health.Interval = time.Minute
+	}
+	if cfg.Health.Retention == 0 {
+		cfg.Health.Retention = 7 * 24 * time.Hour
+	}
+	if cfg.Health.Thresholds.QueueSize == 0 {
+		cfg.Health.Thresholds.QueueSize = 1000
+	}
+	if cfg.Health.Thresholds.QueueAge == 0 {
+		cfg.Health.Thresholds.QueueAge = time.Hour
+	}
+	if cfg.Health.Thresholds.DLQSize == 0 {
+		cfg.Health.Thresholds.DLQSize = 100
+	}
+	if cfg.Health.Thresholds.RateLimitUsage == 0 {
+		cfg.Health.Thresholds.RateLimitUsage = 80
+	}
+	if cfg.Health.Thresholds.CertExpiryDays == 0 {
+		cfg.Health.Thresholds.CertExpiryDays = 14
+	}
+	if cfg.Alerts.Repeat == 0 {
+		cfg.Alerts.Repeat = 4 * time.Hour
+	}
+	if cfg.DNSPublishing.TTL == 0 {
+		cfg.DNSPublishing.TTL = 300
+	}
+	if cfg.DNSPublishing.VerifyInterval == 0 {
+		cfg.DNSPublishing.VerifyInterval = time.Minute
+	}
+	if cfg.DNSPublishing.VerifyTimeout == 0 {
+		cfg.DNSPublishing.VerifyTimeout = 24 * time.Hour
+	}
 }
 
 func validate(cfg *Config) error {
@@ -151,6 +440,165 @@ func validate(cfg *Config) error {
 		if cfg.Auth.OIDC.IssuerURL == "" {
 			return fmt.Errorf("auth.oidc.issuer_url is required when OIDC is enabled")
 		}
+		for group, role := range cfg.Auth.OIDC.GroupRoles {
+			if role != "admin" && role != "user" {
+				return fmt.Errorf("auth.oidc.group_roles: role of %q must be admin or user", group)
+			}
+		}
+		if r := cfg.Auth.OIDC.DefaultRole; r != "admin" && r != "user" {
+			return fmt.Errorf("auth.oidc.default_role must be admin or user")
+		}
+	}
+	if _, err := time.Parse("15:04", cfg.Backup.Time); err != nil {
+		return fmt.Errorf("backup.time must be HH:MM: %s", cfg.Backup.Time)
+	}
+	if cfg.Backup.Keep < 0 {
+		return fmt.Errorf("backup.keep must not be negative")
+	}
+	if cfg.Backup.S3.Enabled {
+		if cfg.Backup.S3.Bucket == "" {
+			return fmt.Errorf("backup.s3.bucket is required when S3 upload is enabled")
+		}
+		if cfg.Backup.S3.AccessKey == "" || cfg.Backup.S3.SecretKey == "" {
+			return fmt.Errorf("backup.s3.access_key and secret_key are required when S3 upload is enabled")
+		}
+	}
+	if cfg.Tracking.Enabled {
+		u, err := url.Parse(cfg.Tracking.BaseURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("tracking.base_url (or server.public_url) must be an absolute http(s) URL when tracking is enabled")
+		}
+	}
+	for _, s := range cfg.Sendry.Servers {
+		if !s.TLS.Enabled() {
+			continue
+		}
+		if _, err := s.TLS.ClientConfig(); err != nil {
+			return fmt.Errorf("sendry.servers %q tls: %w", s.Name, err)
+		}
+	}
+	if cfg.Alerts.Enabled {
+		if err := validateAlerts(cfg); err != nil {
+			return err
+		}
+	}
+	if cfg.DNSPublishing.Enabled {
+		if err := validateDNSPublishing(&cfg.DNSPublishing); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateDNSPublishing(c *DNSPublishingConfig) error {
+	if c.TTL < 0 || c.VerifyInterval < 0 || c.VerifyTimeout < 0 {
+		return fmt.Errorf("dns_publishing: ttl, verify_interval and verify_timeout must not be negative")
+	}
+	if c.Resolver != "" {
+		if _, _, err := net.SplitHostPort(c.Resolver); err != nil {
+			return fmt.Errorf("dns_publishing.resolver must be host:port: %s", c.Resolver)
+		}
+	}
+
+	switch c.Provider {
+	case DNSProviderCloudflare:
+		if c.Cloudflare.APIToken == "" && (c.Cloudflare.Email == "" || c.Cloudflare.APIKey == "") {
+			return fmt.Errorf("dns_publishing.cloudflare: api_token, or email and api_key, are required")
+		}
+	case DNSProviderRoute53:
+		if c.Route53.AccessKeyID == "" || c.Route53.SecretAccessKey == "" {
+			return fmt.Errorf("dns_publishing.route53: access_key_id and secret_access_key are required")
+		}
+	case DNSProviderRFC2136:
+		if c.RFC2136.Server == "" {
+			return fmt.Errorf("dns_publishing.rfc2136.server is required")
+		}
+		if c.RFC2136.TSIGKey != "" && c.RFC2136.TSIGSecret == "" {
+			return fmt.Errorf("dns_publishing.rfc2136.tsig_secret is required with tsig_key")
+		}
+		switch c.RFC2136.TSIGAlgorithm {
+		case "", "hmac-sha256", "hmac-sha512":
+		default:
+			return fmt.Errorf("dns_publishing.rfc2136.tsig_algorithm must be hmac-sha256 or hmac-sha512")
+		}
+	case DNSProviderNamedot:
+		if c.Namedot.URL == "" || c.Namedot.Token == "" {
+			return fmt.Errorf("dns_publishing.namedot: url and token are required")
+		}
+	default:
+		return fmt.Errorf("dns_publishing.provider must be cloudflare, route53, rfc2136 or namedot: %q", c.Provider)
+	}
+	return nil
+}
+
+func validateAlerts(cfg *Config) error {
+	if !cfg.Health.Enabled {
+		return fmt.Errorf("alerts need health.enabled")
+	}
+
+	servers := make(map[string]bool)
+	for _, s := range cfg.Sendry.Servers {
+		servers[s.Name] = true
+	}
+
+	channels := make(map[string]bool)
+	for _, c := range cfg.Alerts.Channels {
+		if c.Name == "" || channels[c.Name] {
+			return fmt.Errorf("alerts.channels: name must be set and unique: %q", c.Name)
+		}
+		channels[c.Name] = true
+
+		switch c.Type {
+		case AlertChannelEmail:
+			if !servers[c.Server] {
+				return fmt.Errorf("alerts.channels %q: unknown server %q", c.Name, c.Server)
+			}
+			if c.From == "" || len(c.To) == 0 {
+				return fmt.Errorf("alerts.channels %q: from and to are required", c.Name)
+			}
+		case AlertChannelSlack:
+			if c.WebhookURL == "" {
+				return fmt.Errorf("alerts.channels %q: webhook_url is required", c.Name)
+			}
+		case AlertChannelTelegram:
+			if c.BotToken == "" || c.ChatID == "" {
+				return fmt.Errorf("alerts.channels %q: bot_token and chat_id are required", c.Name)
+			}
+		default:
+			return fmt.Errorf("alerts.channels %q: unknown type %q", c.Name, c.Type)
+		}
+	}
+
+	rules := make(map[string]bool)
+	for _, r := range cfg.Alerts.Rules {
+		if r.Name == "" || rules[r.Name] {
+			return fmt.Errorf("alerts.rules: name must be set and unique: %q", r.Name)
+		}
+		rules[r.Name] = true
+
+		switch r.Type {
+		case AlertServerDown, AlertDNSBL:
+		case AlertQueueAge:
+			if r.Age <= 0 {
+				return fmt.Errorf("alerts.rules %q: age is required", r.Name)
+			}
+		case AlertDLQSize, AlertBounceRate, AlertCertExpiry:
+			if r.Threshold <= 0 {
+				return fmt.Errorf("alerts.rules %q: threshold is required", r.Name)
+			}
+		default:
+			return fmt.Errorf("alerts.rules %q: unknown type %q", r.Name, r.Type)
+		}
+		for _, s := range r.Servers {
+			if !servers[s] {
+				return fmt.Errorf("alerts.rules %q: unknown server %q", r.Name, s)
+			}
+		}
+		for _, c := range r.Channels {
+			if !channels[c] {
+				return fmt.Errorf("alerts.rules %q: unknown channel %q", r.Name, c)
+			}
+		}
 	}
 	return nil
 }
@@ -38,6 +38,7 @@ func New(path string) (*DB, error) {
 func (db *DB) Migrate() error {
 	migrations := []string{
 		migrationUsers,
+		migrationOrganizations,
 		migrationSessions,
 		migrationTemplates,
 		migrationTemplateVersions,
@@ -61,6 +62,15 @@ func (db *DB) Migrate() error {
 		migrationMediaFiles,
 		migrationTemplateBlockRefs,
 		migrationUserSMTPServers,
+		migrationDeploymentEvents,
+		migrationFreezeWindows,
+		migrationTrackingEvents,
+		migrationRecipientSegments,
+		migrationRecipientImports,
+		migrationServerHealth,
+		migrationAlerts,
+		migrationDNSRecords,
+		migrationMTASTSPolicies,
 	}
 
 	for _, m := range migrations {
@@ -95,11 +105,34 @@ func (db *DB) Migrate() error {
 		"ALTER TABLE templates ADD COLUMN container_radius_top INTEGER NOT NULL DEFAULT 0",
 		"ALTER TABLE templates ADD COLUMN container_radius_bottom INTEGER NOT NULL DEFAULT 0",
 		"ALTER TABLE template_block_refs ADD COLUMN condition TEXT NOT NULL DEFAULT ''",
+		"ALTER TABLE templates ADD COLUMN block_external INTEGER NOT NULL DEFAULT 0",
+		"ALTER TABLE send_jobs ADD COLUMN freeze_override INTEGER NOT NULL DEFAULT 0",
+		"ALTER TABLE template_deployments ADD COLUMN content_hash TEXT NOT NULL DEFAULT ''",
+		"ALTER TABLE template_deployments ADD COLUMN drift TEXT NOT NULL DEFAULT ''",
+		"ALTER TABLE template_deployments ADD COLUMN drift_checked_at TIMESTAMP",
+		"ALTER TABLE send_jobs ADD COLUMN segment_id TEXT",
+		"ALTER TABLE send_jobs ADD COLUMN throttle TEXT",
+		"ALTER TABLE send_job_items ADD COLUMN failed_at TIMESTAMP",
+		"ALTER TABLE send_job_items ADD COLUMN mx_host TEXT NOT NULL DEFAULT ''",
+		"ALTER TABLE send_job_items ADD COLUMN status_checked_at TIMESTAMP",
+		"ALTER TABLE sessions ADD COLUMN auth_method TEXT NOT NULL DEFAULT 'local'",
+		"ALTER TABLE sessions ADD COLUMN ip TEXT NOT NULL DEFAULT ''",
+		"ALTER TABLE sessions ADD COLUMN user_agent TEXT NOT NULL DEFAULT ''",
+		"ALTER TABLE sessions ADD COLUMN last_seen_at TIMESTAMP",
+		"ALTER TABLE api_keys ADD COLUMN user_id TEXT REFERENCES users(id) ON DELETE CASCADE",
+		"ALTER TABLE sessions ADD COLUMN org_id TEXT REFERENCES organizations(id) ON DELETE SET NULL",
+	}
+	for _, table := range OrgTables {
+		alterMigrations = append(alterMigrations, "ALTER TABLE "+table+" ADD COLUMN org_id TEXT REFERENCES organizations(id)")
 	}
 	for _, m := range alterMigrations {
 		db.Exec(m) // Ignore errors (column may already exist)
 	}
 
+	if err := db.migrateDefaultOrganization(); err != nil {
+		return fmt.Errorf("default organization: %w", err)
+	}
+
 	if err := db.dropColumnIfExists("templates", "container_background"); err != nil {
 		return fmt.Errorf("drop container_background: %w", err)
 	}
@@ -113,6 +146,46 @@ func (db *DB) Migrate() error {
 	return nil
 }
 
+// OrgTables are the tables whose rows belong to an organization
+var OrgTables = []string{"templates", "campaigns", "recipient_lists", "dkim_keys", "domains", "api_keys"}
+
+// DefaultOrganizationID is the organization created for databases without
+// one. It takes over the existing objects and users.
+const DefaultOrganizationID = "default"
+
+func (db *DB) migrateDefaultOrganization() error {
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM organizations").Scan(&n); err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("INSERT INTO organizations (id, name) VALUES (?, 'Default')", DefaultOrganizationID); err != nil {
+		return err
+	}
+	for _, table := range OrgTables {
+		if _, err := tx.Exec("UPDATE "+table+" SET org_id = ? WHERE org_id IS NULL", DefaultOrganizationID); err != nil {
+			return fmt.Errorf("%s: %w", table, err)
+		}
+	}
+	if _, err := tx.Exec(`
+		INSERT OR IGNORE INTO organization_members (org_id, user_id, role)
+		SELECT ?, id, CASE WHEN role = 'admin' THEN 'admin' ELSE 'member' END FROM users`,
+		DefaultOrganizationID,
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
 func (db *DB) dropColumnIfExists(table, column string) error {
 	if !db.columnExists(table, column) {
 		return nil
@@ -272,6 +345,7 @@ CREATE TABLE IF NOT EXISTS templates (
     page_background TEXT NOT NULL DEFAULT '',
     container_radius_top INTEGER NOT NULL DEFAULT 0,
     container_radius_bottom INTEGER NOT NULL DEFAULT 0,
+    block_external INTEGER NOT NULL DEFAULT 0,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
@@ -597,3 +671,180 @@ CREATE TABLE IF NOT EXISTS user_smtp_servers (
 );
 CREATE INDEX IF NOT EXISTS idx_user_smtp_servers_user ON user_smtp_servers(user_id);
 `
+
+const migrationDeploymentEvents = `
+CREATE TABLE IF NOT EXISTS deployment_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    correlation_id TEXT NOT NULL,
+    entity_type TEXT NOT NULL,
+    entity_id TEXT NOT NULL,
+    entity_name TEXT,
+    server_name TEXT NOT NULL,
+    status TEXT NOT NULL,
+    error TEXT,
+    user_email TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_deployment_events_correlation ON deployment_events(correlation_id);
+CREATE INDEX IF NOT EXISTS idx_deployment_events_entity ON deployment_events(entity_type, entity_id);
+`
+
+const migrationFreezeWindows = `
+CREATE TABLE IF NOT EXISTS freeze_windows (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    domain TEXT NOT NULL DEFAULT '',
+    reason TEXT,
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP NOT NULL,
+    created_by TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_freeze_windows_ends ON freeze_windows(ends_at);
+`
+
+const migrationTrackingEvents = `
+CREATE TABLE IF NOT EXISTS tracking_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    item_id TEXT NOT NULL REFERENCES send_job_items(id) ON DELETE CASCADE,
+    job_id TEXT NOT NULL REFERENCES send_jobs(id) ON DELETE CASCADE,
+    campaign_id TEXT NOT NULL,
+    type TEXT NOT NULL,
+    url TEXT NOT NULL DEFAULT '',
+    ip_address TEXT,
+    user_agent TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_tracking_events_job ON tracking_events(job_id, type);
+CREATE INDEX IF NOT EXISTS idx_tracking_events_campaign ON tracking_events(campaign_id, type);
+`
+
+const migrationRecipientSegments = `
+CREATE TABLE IF NOT EXISTS recipient_segments (
+    id TEXT PRIMARY KEY,
+    list_id TEXT NOT NULL REFERENCES recipient_lists(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    rules JSON NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_recipient_segments_list ON recipient_segments(list_id);
+`
+
+const migrationRecipientImports = `
+CREATE TABLE IF NOT EXISTS recipient_imports (
+    id TEXT PRIMARY KEY,
+    list_id TEXT NOT NULL REFERENCES recipient_lists(id) ON DELETE CASCADE,
+    file_name TEXT NOT NULL DEFAULT '',
+    columns JSON NOT NULL,
+    data JSON,
+    status TEXT NOT NULL DEFAULT 'pending',
+    total INTEGER DEFAULT 0,
+    imported INTEGER DEFAULT 0,
+    updated INTEGER DEFAULT 0,
+    rejected JSON,
+    created_by TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_recipient_imports_list ON recipient_imports(list_id);
+`
+
+const migrationServerHealth = `
+CREATE TABLE IF NOT EXISTS server_health_samples (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    server TEXT NOT NULL,
+    online INTEGER NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    queue_pending INTEGER NOT NULL DEFAULT 0,
+    queue_retrying INTEGER NOT NULL DEFAULT 0,
+    dlq_size INTEGER NOT NULL DEFAULT 0,
+    oldest_queued INTEGER NOT NULL DEFAULT 0,
+    rate_limit_usage REAL NOT NULL DEFAULT 0,
+    sent_total REAL NOT NULL DEFAULT 0,
+    failed_total REAL NOT NULL DEFAULT 0,
+    bounced_total REAL NOT NULL DEFAULT 0,
+    cert_domain TEXT NOT NULL DEFAULT '',
+    cert_expires_at TIMESTAMP,
+    warnings JSON,
+    sampled_at TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_server_health_samples_server ON server_health_samples(server, sampled_at);
+`
+
+const migrationAlerts = `
+CREATE TABLE IF NOT EXISTS alerts (
+    id TEXT PRIMARY KEY,
+    rule TEXT NOT NULL,
+    type TEXT NOT NULL,
+    server TEXT NOT NULL,
+    message TEXT NOT NULL DEFAULT '',
+    state TEXT NOT NULL DEFAULT 'firing',
+    started_at TIMESTAMP NOT NULL,
+    resolved_at TIMESTAMP,
+    notified_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_alerts_state ON alerts(state, rule, server);
+CREATE INDEX IF NOT EXISTS idx_alerts_started ON alerts(started_at);
+
+CREATE TABLE IF NOT EXISTS alert_silences (
+    id TEXT PRIMARY KEY,
+    rule TEXT NOT NULL DEFAULT '',
+    server TEXT NOT NULL DEFAULT '',
+    reason TEXT,
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP NOT NULL,
+    created_by TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_alert_silences_ends ON alert_silences(ends_at);
+`
+
+const migrationDNSRecords = `
+CREATE TABLE IF NOT EXISTS dns_records (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    domain_id TEXT NOT NULL REFERENCES domains(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL,
+    type TEXT NOT NULL,
+    name TEXT NOT NULL,
+    value TEXT NOT NULL,
+    provider TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'published',
+    error TEXT,
+    published_at TIMESTAMP,
+    checked_at TIMESTAMP,
+    verified_at TIMESTAMP,
+    UNIQUE(domain_id, kind)
+);
+CREATE INDEX IF NOT EXISTS idx_dns_records_status ON dns_records(status);
+`
+
+const migrationMTASTSPolicies = `
+CREATE TABLE IF NOT EXISTS mta_sts_policies (
+    domain_id TEXT PRIMARY KEY REFERENCES domains(id) ON DELETE CASCADE,
+    mode TEXT NOT NULL,
+    mx TEXT NOT NULL DEFAULT '[]',
+    max_age INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+`
+
+const migrationOrganizations = `
+CREATE TABLE IF NOT EXISTS organizations (
+    id TEXT PRIMARY KEY,
+    name TEXT UNIQUE NOT NULL,
+    servers TEXT NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS organization_members (
+    org_id TEXT NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role TEXT NOT NULL DEFAULT 'member',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (org_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS idx_organization_members_user_id ON organization_members(user_id);
+`
@@ -73,11 +73,12 @@ type cfZone struct {
 }
 
 type cfRecord struct {
-	ID      string `json:"id"`
-	Type    string `json:"type"`
-	Name    string `json:"name"`
-	Content string `json:"content"`
-	TTL     int    `json:"ttl"`
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Name     string `json:"name"`
+	Content  string `json:"content"`
+	TTL      int    `json:"ttl"`
+	Priority *int   `json:"priority,omitempty"` // MX only
 }
 
 func (p *CloudflareProvider) setAuth(req *http.Request) {
@@ -192,11 +193,15 @@ func (p *CloudflareProvider) ListRecords(ctx context.Context, zoneID, name, reco
 
 	records := make([]Record, 0, len(result.Result))
 	for _, r := range result.Result {
+		content := r.Content
+		if r.Priority != nil && strings.EqualFold(r.Type, "MX") {
+			content = fmt.Sprintf("%d %s", *r.Priority, r.Content)
+		}
 		records = append(records, Record{
 			ID:      r.ID,
 			Type:    r.Type,
 			Name:    r.Name,
-			Content: r.Content,
+			Content: content,
 			TTL:     r.TTL,
 		})
 	}
@@ -205,24 +210,30 @@ func (p *CloudflareProvider) ListRecords(ctx context.Context, zoneID, name, reco
 
 // CreateRecord creates a DNS record.
 func (p *CloudflareProvider) CreateRecord(ctx context.Context, zoneID string, r Record) error {
-	payload := cfRecord{
-		Type:    r.Type,
-		Name:    r.Name,
-		Content: r.Content,
-		TTL:     ttlOrAuto(r.TTL),
-	}
-	return p.do(ctx, http.MethodPost, "/zones/"+zoneID+"/dns_records", payload, nil)
+	return p.do(ctx, http.MethodPost, "/zones/"+zoneID+"/dns_records", newCFRecord(r), nil)
 }
 
 // UpdateRecord updates an existing DNS record.
 func (p *CloudflareProvider) UpdateRecord(ctx context.Context, zoneID, recordID string, r Record) error {
+	return p.do(ctx, http.MethodPut, "/zones/"+zoneID+"/dns_records/"+recordID, newCFRecord(r), nil)
+}
+
+// newCFRecord converts a record to the API payload. Cloudflare keeps the
+// MX preference in a separate field.
+func newCFRecord(r Record) cfRecord {
 	payload := cfRecord{
 		Type:    r.Type,
 		Name:    r.Name,
 		Content: r.Content,
 		TTL:     ttlOrAuto(r.TTL),
 	}
-	return p.do(ctx, http.MethodPut, "/zones/"+zoneID+"/dns_records/"+recordID, payload, nil)
+	if strings.EqualFold(r.Type, "MX") {
+		if pref, host, ok := SplitMX(r.Content); ok {
+			payload.Priority = &pref
+			payload.Content = host
+		}
+	}
+	return payload
 }
 
 func ttlOrAuto(t int) int {
@@ -66,3 +66,40 @@ func TestCloudflare_AuthHeaders_GlobalKey(t *testing.T) {
 		t.Errorf("X-Auth-Key = %q", gotKey)
 	}
 }
+
+func TestCloudflare_MXPriority(t *testing.T) {
+	var body map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			_ = json.NewEncoder(w).Encode(map[string]any{"success": true})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"success": true,
+			"result": []map[string]any{
+				{"id": "r1", "type": "MX", "name": "example.com", "content": "mx.example.net", "priority": 10, "ttl": 300},
+			},
+		})
+	}))
+	defer srv.Close()
+
+	p := NewCloudflare("tok")
+	p.BaseURL = srv.URL
+
+	recs, err := p.ListRecords(context.Background(), "z1", "example.com", "MX")
+	if err != nil {
+		t.Fatalf("ListRecords error = %v", err)
+	}
+	if len(recs) != 1 || recs[0].Content != "10 mx.example.net" {
+		t.Errorf("records = %+v, want content 10 mx.example.net", recs)
+	}
+
+	err = p.CreateRecord(context.Background(), "z1", Record{Type: "MX", Name: "example.com", Content: "20 mx2.example.net"})
+	if err != nil {
+		t.Fatalf("CreateRecord error = %v", err)
+	}
+	if body["content"] != "mx2.example.net" || body["priority"] != float64(20) {
+		t.Errorf("body = %v, want content mx2.example.net and priority 20", body)
+	}
+}
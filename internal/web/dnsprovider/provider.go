@@ -3,6 +3,8 @@ package dnsprovider
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"strings"
 )
 
 // Record represents a generic DNS record used for comparison and upsert.
@@ -11,8 +13,8 @@ type Record struct {
 	ID      string
 	Type    string // e.g. TXT
 	Name    string // FQDN without trailing dot
-	Content string
-	TTL     int // 0 or 1 means provider default/auto
+	Content string // MX content is "<preference> <host>"
+	TTL     int    // 0 or 1 means provider default/auto
 }
 
 // Zone represents a DNS zone.
@@ -37,3 +39,17 @@ type Provider interface {
 
 // ErrZoneNotFound is returned when no matching zone is found.
 var ErrZoneNotFound = fmt.Errorf("zone not found")
+
+// SplitMX splits MX record content "<preference> <host>" into its parts.
+// The host is returned without trailing dot.
+func SplitMX(content string) (int, string, bool) {
+	fields := strings.Fields(content)
+	if len(fields) != 2 {
+		return 0, "", false
+	}
+	pref, err := strconv.Atoi(fields[0])
+	if err != nil || pref < 0 || pref > 65535 {
+		return 0, "", false
+	}
+	return pref, strings.TrimSuffix(strings.ToLower(fields[1]), "."), true
+}
@@ -0,0 +1,425 @@
+package dnsprovider
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	// opcodeUpdate is the DNS UPDATE opcode (RFC 2136)
+	opcodeUpdate = 5
+	// classNone marks the deletion of a specific RR in an update
+	classNone = dnsmessage.Class(254)
+	// classAny is the class of TSIG records
+	classAny = dnsmessage.Class(255)
+	// typeTSIG is the TSIG record type (RFC 8945)
+	typeTSIG = dnsmessage.Type(250)
+	// tsigFudge is the allowed clock skew of signed messages in seconds
+	tsigFudge = 300
+)
+
+// RFC2136Provider implements Provider with DNS UPDATE messages (RFC 2136)
+// sent over TCP to the primary name server of the zone, e.g. BIND, Knot or
+// PowerDNS. Messages are signed with TSIG when a key is configured.
+//
+// DNS has no record IDs, so the IDs returned by ListRecords are the record
+// contents and UpdateRecord deletes exactly that value before adding the new
+// one. Other values at the same name are left untouched.
+type RFC2136Provider struct {
+	Server       string // host:port of the primary name server
+	Zone         string // Optional zone apex; detected with SOA queries when empty
+	KeyName      string // TSIG key name; empty disables signing
+	KeyAlgorithm string // hmac-sha256 (default) or hmac-sha512
+	KeySecret    string // Base64 TSIG secret
+	Timeout      time.Duration
+
+	now func() time.Time
+}
+
+// NewRFC2136 creates a provider for the given name server. The port
+// defaults to 53.
+func NewRFC2136(server, keyName, keyAlgorithm, keySecret string) *RFC2136Provider {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+	return &RFC2136Provider{
+		Server:       server,
+		KeyName:      keyName,
+		KeyAlgorithm: keyAlgorithm,
+		KeySecret:    keySecret,
+		Timeout:      10 * time.Second,
+		now:          time.Now,
+	}
+}
+
+func (p *RFC2136Provider) Name() string { return "rfc2136" }
+
+// ResolveZone returns the configured zone, or walks the FQDN parent labels
+// and asks the server for their SOA records.
+func (p *RFC2136Provider) ResolveZone(ctx context.Context, fqdn string) (*Zone, error) {
+	fqdn = strings.TrimSuffix(strings.ToLower(fqdn), ".")
+
+	if p.Zone != "" {
+		zone := strings.TrimSuffix(strings.ToLower(p.Zone), ".")
+		if fqdn == zone || strings.HasSuffix(fqdn, "."+zone) {
+			return &Zone{ID: zone, Name: zone}, nil
+		}
+		return nil, ErrZoneNotFound
+	}
+
+	labels := strings.Split(fqdn, ".")
+	for i := 0; i < len(labels)-1; i++ {
+		candidate := strings.Join(labels[i:], ".")
+		resp, err := p.query(ctx, candidate, dnsmessage.TypeSOA)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range resp.Answers {
+			if a.Header.Type == dnsmessage.TypeSOA && strings.EqualFold(strings.TrimSuffix(a.Header.Name.String(), "."), candidate) {
+				return &Zone{ID: candidate, Name: candidate}, nil
+			}
+		}
+	}
+	return nil, ErrZoneNotFound
+}
+
+// ListRecords queries the server for the records of the exact name and type.
+// Only TXT and MX records are supported.
+func (p *RFC2136Provider) ListRecords(ctx context.Context, zoneID, name, recordType string) ([]Record, error) {
+	t, err := rfc2136Type(recordType)
+	if err != nil {
+		return nil, err
+	}
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+
+	resp, err := p.query(ctx, name, t)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []Record
+	for _, a := range resp.Answers {
+		var content string
+		switch body := a.Body.(type) {
+		case *dnsmessage.TXTResource:
+			content = strings.Join(body.TXT, "")
+		case *dnsmessage.MXResource:
+			content = fmt.Sprintf("%d %s", body.Pref, strings.TrimSuffix(strings.ToLower(body.MX.String()), "."))
+		default:
+			continue // CNAME and other records of a different type
+		}
+		records = append(records, Record{
+			ID:      content,
+			Type:    strings.ToUpper(recordType),
+			Name:    name,
+			Content: content,
+			TTL:     int(a.Header.TTL),
+		})
+	}
+	return records, nil
+}
+
+// CreateRecord adds a record.
+func (p *RFC2136Provider) CreateRecord(ctx context.Context, zoneID string, r Record) error {
+	return p.update(ctx, zoneID, "", r)
+}
+
+// UpdateRecord deletes the record with content recordID and adds r.
+func (p *RFC2136Provider) UpdateRecord(ctx context.Context, zoneID, recordID string, r Record) error {
+	return p.update(ctx, zoneID, recordID, r)
+}
+
+func (p *RFC2136Provider) update(ctx context.Context, zoneID, oldContent string, r Record) error {
+	id, err := messageID()
+	if err != nil {
+		return err
+	}
+	zone, err := dnsmessage.NewName(fqdnDot(zoneID))
+	if err != nil {
+		return fmt.Errorf("invalid zone: %w", err)
+	}
+
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: id, OpCode: opcodeUpdate})
+	// The zone section of an update uses the question layout
+	if err := b.StartQuestions(); err != nil {
+		return err
+	}
+	if err := b.Question(dnsmessage.Question{Name: zone, Type: dnsmessage.TypeSOA, Class: dnsmessage.ClassINET}); err != nil {
+		return err
+	}
+	// The update section uses the authority layout
+	if err := b.StartAuthorities(); err != nil {
+		return err
+	}
+	if oldContent != "" {
+		if err := addUpdateRR(&b, r.Name, r.Type, oldContent, classNone, 0); err != nil {
+			return err
+		}
+	}
+	ttl := r.TTL
+	if ttl <= 1 {
+		ttl = 300
+	}
+	if err := addUpdateRR(&b, r.Name, r.Type, r.Content, dnsmessage.ClassINET, uint32(ttl)); err != nil {
+		return err
+	}
+
+	msg, err := b.Finish()
+	if err != nil {
+		return fmt.Errorf("build update: %w", err)
+	}
+	_, err = p.exchange(ctx, msg)
+	return err
+}
+
+// addUpdateRR adds a TXT or MX record to the update section
+func addUpdateRR(b *dnsmessage.Builder, name, recordType, content string, class dnsmessage.Class, ttl uint32) error {
+	n, err := dnsmessage.NewName(fqdnDot(name))
+	if err != nil {
+		return fmt.Errorf("invalid name: %w", err)
+	}
+
+	switch strings.ToUpper(recordType) {
+	case "TXT":
+		h := dnsmessage.ResourceHeader{Name: n, Type: dnsmessage.TypeTXT, Class: class, TTL: ttl}
+		return b.TXTResource(h, dnsmessage.TXTResource{TXT: splitTXT(content)})
+	case "MX":
+		pref, host, ok := SplitMX(content)
+		if !ok {
+			return fmt.Errorf("invalid MX content %q", content)
+		}
+		mx, err := dnsmessage.NewName(fqdnDot(host))
+		if err != nil {
+			return fmt.Errorf("invalid MX host: %w", err)
+		}
+		h := dnsmessage.ResourceHeader{Name: n, Type: dnsmessage.TypeMX, Class: class, TTL: ttl}
+		return b.MXResource(h, dnsmessage.MXResource{Pref: uint16(pref), MX: mx})
+	}
+	return fmt.Errorf("unsupported record type %q", recordType)
+}
+
+// query sends a query for the name and type
+func (p *RFC2136Provider) query(ctx context.Context, name string, t dnsmessage.Type) (*dnsmessage.Message, error) {
+	id, err := messageID()
+	if err != nil {
+		return nil, err
+	}
+	n, err := dnsmessage.NewName(fqdnDot(name))
+	if err != nil {
+		return nil, fmt.Errorf("invalid name: %w", err)
+	}
+
+	q := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: id},
+		Questions: []dnsmessage.Question{{Name: n, Type: t, Class: dnsmessage.ClassINET}},
+	}
+	msg, err := q.Pack()
+	if err != nil {
+		return nil, fmt.Errorf("build query: %w", err)
+	}
+	return p.exchange(ctx, msg)
+}
+
+// exchange signs the message, sends it over TCP and returns the response.
+// NXDOMAIN is not an error for queries; it is returned as an empty answer.
+func (p *RFC2136Provider) exchange(ctx context.Context, msg []byte) (*dnsmessage.Message, error) {
+	if p.KeyName != "" {
+		signed, err := p.sign(msg)
+		if err != nil {
+			return nil, err
+		}
+		msg = signed
+	}
+
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", p.Server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	// Messages over TCP are prefixed with their length
+	frame := make([]byte, 2, 2+len(msg))
+	binary.BigEndian.PutUint16(frame, uint16(len(msg)))
+	if _, err := conn.Write(append(frame, msg...)); err != nil {
+		return nil, err
+	}
+
+	var size [2]byte
+	if _, err := io.ReadFull(conn, size[:]); err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+	raw := make([]byte, binary.BigEndian.Uint16(size[:]))
+	if _, err := io.ReadFull(conn, raw); err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+
+	var resp dnsmessage.Message
+	if err := resp.Unpack(raw); err != nil {
+		return nil, fmt.Errorf("parse response: %w", err)
+	}
+	if resp.ID != binary.BigEndian.Uint16(msg) {
+		return nil, fmt.Errorf("response id mismatch")
+	}
+	switch resp.RCode {
+	case dnsmessage.RCodeSuccess, dnsmessage.RCodeNameError:
+		return &resp, nil
+	}
+	return nil, fmt.Errorf("rfc2136 server error: %s", rcodeName(resp.RCode))
+}
+
+// sign appends a TSIG record (RFC 8945) to the message. The response
+// signature is not verified.
+func (p *RFC2136Provider) sign(msg []byte) ([]byte, error) {
+	alg, newHash, err := tsigAlgorithm(p.KeyAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := base64.StdEncoding.DecodeString(p.KeySecret)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tsig secret: %w", err)
+	}
+	keyName, err := wireName(p.KeyName)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tsig key name: %w", err)
+	}
+	algName, _ := wireName(alg)
+
+	var signedAt [6]byte
+	now := uint64(p.now().Unix())
+	binary.BigEndian.PutUint16(signedAt[0:2], uint16(now>>32))
+	binary.BigEndian.PutUint32(signedAt[2:6], uint32(now))
+
+	// The MAC covers the message and the TSIG variables
+	mac := hmac.New(newHash, secret)
+	mac.Write(msg)
+	mac.Write(keyName)
+	mac.Write([]byte{0, byte(classAny), 0, 0, 0, 0}) // Class ANY, TTL 0
+	mac.Write(algName)
+	mac.Write(signedAt[:])
+	mac.Write([]byte{tsigFudge >> 8, tsigFudge & 0xff, 0, 0, 0, 0}) // Fudge, error, other length
+	sum := mac.Sum(nil)
+
+	rdata := append([]byte(nil), algName...)
+	rdata = append(rdata, signedAt[:]...)
+	rdata = binary.BigEndian.AppendUint16(rdata, tsigFudge)
+	rdata = binary.BigEndian.AppendUint16(rdata, uint16(len(sum)))
+	rdata = append(rdata, sum...)
+	rdata = append(rdata, msg[0], msg[1]) // Original ID
+	rdata = append(rdata, 0, 0, 0, 0)     // Error, other length
+
+	out := append([]byte(nil), msg...)
+	out = append(out, keyName...)
+	out = binary.BigEndian.AppendUint16(out, uint16(typeTSIG))
+	out = binary.BigEndian.AppendUint16(out, uint16(classAny))
+	out = binary.BigEndian.AppendUint32(out, 0)
+	out = binary.BigEndian.AppendUint16(out, uint16(len(rdata)))
+	out = append(out, rdata...)
+
+	binary.BigEndian.PutUint16(out[10:12], binary.BigEndian.Uint16(out[10:12])+1)
+	return out, nil
+}
+
+// tsigAlgorithm returns the algorithm name and hash of a TSIG algorithm
+func tsigAlgorithm(name string) (string, func() hash.Hash, error) {
+	switch strings.TrimSuffix(strings.ToLower(name), ".") {
+	case "", "hmac-sha256":
+		return "hmac-sha256", sha256.New, nil
+	case "hmac-sha512":
+		return "hmac-sha512", sha512.New, nil
+	}
+	return "", nil, fmt.Errorf("unsupported tsig algorithm %q", name)
+}
+
+// wireName returns the uncompressed, lowercase wire format of a name
+func wireName(name string) ([]byte, error) {
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+	var out []byte
+	if name != "" {
+		for _, label := range strings.Split(name, ".") {
+			if label == "" || len(label) > 63 {
+				return nil, fmt.Errorf("invalid label in %q", name)
+			}
+			out = append(out, byte(len(label)))
+			out = append(out, label...)
+		}
+	}
+	return append(out, 0), nil
+}
+
+// splitTXT splits a TXT value into character strings of at most 255 bytes
+func splitTXT(s string) []string {
+	var parts []string
+	for len(s) > 255 {
+		parts = append(parts, s[:255])
+		s = s[255:]
+	}
+	return append(parts, s)
+}
+
+func rfc2136Type(recordType string) (dnsmessage.Type, error) {
+	switch strings.ToUpper(recordType) {
+	case "TXT":
+		return dnsmessage.TypeTXT, nil
+	case "MX":
+		return dnsmessage.TypeMX, nil
+	}
+	return 0, fmt.Errorf("unsupported record type %q", recordType)
+}
+
+// rcodeNames are the names of the response codes of updates
+var rcodeNames = map[dnsmessage.RCode]string{
+	1:  "FORMERR",
+	2:  "SERVFAIL",
+	3:  "NXDOMAIN",
+	4:  "NOTIMP",
+	5:  "REFUSED",
+	6:  "YXDOMAIN",
+	7:  "YXRRSET",
+	8:  "NXRRSET",
+	9:  "NOTAUTH",
+	10: "NOTZONE",
+}
+
+func rcodeName(rc dnsmessage.RCode) string {
+	if s, ok := rcodeNames[rc]; ok {
+		return s
+	}
+	return "RCODE " + strconv.Itoa(int(rc))
+}
+
+func fqdnDot(name string) string {
+	return strings.TrimSuffix(name, ".") + "."
+}
+
+func messageID() (uint16, error) {
+	var b [2]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint16(b[:]), nil
+}
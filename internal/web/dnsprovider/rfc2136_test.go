@@ -0,0 +1,168 @@
+package dnsprovider
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// startDNSServer serves DNS over TCP, answering each request with handle
+func startDNSServer(t *testing.T, handle func(raw []byte, req *dnsmessage.Message) dnsmessage.Message) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			var size [2]byte
+			if _, err := io.ReadFull(conn, size[:]); err != nil {
+				conn.Close()
+				continue
+			}
+			raw := make([]byte, binary.BigEndian.Uint16(size[:]))
+			if _, err := io.ReadFull(conn, raw); err != nil {
+				conn.Close()
+				continue
+			}
+			var req dnsmessage.Message
+			if err := req.Unpack(raw); err != nil {
+				conn.Close()
+				continue
+			}
+			resp := handle(raw, &req)
+			resp.ID = req.ID
+			resp.Response = true
+			out, _ := resp.Pack()
+			frame := binary.BigEndian.AppendUint16(nil, uint16(len(out)))
+			_, _ = conn.Write(append(frame, out...))
+			conn.Close()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestRFC2136_ResolveZoneAndList(t *testing.T) {
+	zone := dnsmessage.MustNewName("example.com.")
+	addr := startDNSServer(t, func(raw []byte, req *dnsmessage.Message) dnsmessage.Message {
+		q := req.Questions[0]
+		var resp dnsmessage.Message
+		switch {
+		case q.Type == dnsmessage.TypeSOA && q.Name == zone:
+			resp.Answers = []dnsmessage.Resource{{
+				Header: dnsmessage.ResourceHeader{Name: zone, Type: dnsmessage.TypeSOA, Class: dnsmessage.ClassINET},
+				Body:   &dnsmessage.SOAResource{NS: dnsmessage.MustNewName("ns.example.com."), MBox: dnsmessage.MustNewName("hostmaster.example.com.")},
+			}}
+		case q.Type == dnsmessage.TypeTXT && q.Name == zone:
+			resp.Answers = []dnsmessage.Resource{{
+				Header: dnsmessage.ResourceHeader{Name: zone, Type: dnsmessage.TypeTXT, Class: dnsmessage.ClassINET, TTL: 600},
+				Body:   &dnsmessage.TXTResource{TXT: []string{"v=spf1 ", "-all"}},
+			}}
+		default:
+			resp.RCode = dnsmessage.RCodeNameError
+		}
+		return resp
+	})
+
+	p := NewRFC2136(addr, "", "", "")
+	z, err := p.ResolveZone(context.Background(), "_dmarc.example.com")
+	if err != nil {
+		t.Fatalf("ResolveZone error = %v", err)
+	}
+	if z.Name != "example.com" {
+		t.Errorf("zone = %q, want example.com", z.Name)
+	}
+
+	recs, err := p.ListRecords(context.Background(), z.ID, "example.com", "TXT")
+	if err != nil {
+		t.Fatalf("ListRecords error = %v", err)
+	}
+	if len(recs) != 1 || recs[0].Content != "v=spf1 -all" || recs[0].TTL != 600 {
+		t.Errorf("records = %+v", recs)
+	}
+}
+
+func TestRFC2136_UpdateSigned(t *testing.T) {
+	secret := base64.StdEncoding.EncodeToString([]byte("0123456789abcdef"))
+	var updates []dnsmessage.Resource
+	var tsigOK bool
+
+	addr := startDNSServer(t, func(raw []byte, req *dnsmessage.Message) dnsmessage.Message {
+		updates = req.Authorities
+		tsigOK = verifyTestTSIG(t, raw, "key.example.com", []byte("0123456789abcdef"))
+		return dnsmessage.Message{}
+	})
+
+	p := NewRFC2136(addr, "key.example.com", "hmac-sha256", secret)
+	p.now = func() time.Time { return time.Unix(1767225600, 0) }
+
+	err := p.UpdateRecord(context.Background(), "example.com", "v=spf1 -all", Record{Type: "TXT", Name: "example.com", Content: "v=spf1 a mx ~all"})
+	if err != nil {
+		t.Fatalf("UpdateRecord error = %v", err)
+	}
+	if !tsigOK {
+		t.Error("TSIG signature does not verify")
+	}
+	if len(updates) != 2 {
+		t.Fatalf("updates = %d, want delete and add", len(updates))
+	}
+	if updates[0].Header.Class != classNone || updates[0].Body.(*dnsmessage.TXTResource).TXT[0] != "v=spf1 -all" {
+		t.Errorf("first update = %+v, want delete of the old value", updates[0])
+	}
+	if updates[1].Header.Class != dnsmessage.ClassINET || updates[1].Header.TTL != 300 {
+		t.Errorf("second update = %+v, want add with default TTL", updates[1].Header)
+	}
+}
+
+func TestRFC2136_ServerError(t *testing.T) {
+	addr := startDNSServer(t, func(raw []byte, req *dnsmessage.Message) dnsmessage.Message {
+		return dnsmessage.Message{Header: dnsmessage.Header{RCode: dnsmessage.RCode(9)}}
+	})
+
+	p := NewRFC2136(addr, "", "", "")
+	err := p.CreateRecord(context.Background(), "example.com", Record{Type: "MX", Name: "example.com", Content: "10 mx.example.com"})
+	if err == nil || err.Error() != "rfc2136 server error: NOTAUTH" {
+		t.Errorf("error = %v, want NOTAUTH", err)
+	}
+}
+
+// verifyTestTSIG checks the hmac-sha256 TSIG record at the end of a message
+func verifyTestTSIG(t *testing.T, raw []byte, keyName string, secret []byte) bool {
+	t.Helper()
+	key, _ := wireName(keyName)
+	alg, _ := wireName("hmac-sha256")
+	rdataLen := len(alg) + 6 + 2 + 2 + sha256.Size + 6
+	rrLen := len(key) + 10 + rdataLen
+	if len(raw) < 12+rrLen {
+		return false
+	}
+
+	unsigned := append([]byte(nil), raw[:len(raw)-rrLen]...)
+	binary.BigEndian.PutUint16(unsigned[10:12], binary.BigEndian.Uint16(unsigned[10:12])-1)
+	rdata := raw[len(raw)-rdataLen:]
+	timers := rdata[len(alg) : len(alg)+8]
+	gotMAC := rdata[len(alg)+10 : len(alg)+10+sha256.Size]
+
+	m := hmac.New(sha256.New, secret)
+	m.Write(unsigned)
+	m.Write(key)
+	m.Write([]byte{0, 255, 0, 0, 0, 0})
+	m.Write(alg)
+	m.Write(timers)
+	m.Write([]byte{0, 0, 0, 0})
+	return hmac.Equal(m.Sum(nil), gotMAC)
+}
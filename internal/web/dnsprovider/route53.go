@@ -0,0 +1,336 @@
+package dnsprovider
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// route53Region is the signing region of the global Route 53 API.
+const route53Region = "us-east-1"
+
+// Route53Provider implements Provider using the AWS Route 53 REST API with
+// AWS Signature Version 4.
+//
+// Route 53 stores records as record sets (name + type) with several values.
+// Record IDs returned by ListRecords are the stored values, so UpdateRecord
+// replaces one value and CreateRecord adds one, leaving the other values of
+// the record set (e.g. other TXT records at the zone apex) untouched.
+type Route53Provider struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string // Optional, for temporary credentials
+	BaseURL         string
+	Client          *http.Client
+
+	now func() time.Time
+}
+
+// NewRoute53 creates a provider with an IAM access key. The key needs
+// route53:ListHostedZonesByName, route53:ListResourceRecordSets and
+// route53:ChangeResourceRecordSets.
+func NewRoute53(accessKeyID, secretAccessKey string) *Route53Provider {
+	return &Route53Provider{
+		AccessKeyID:     accessKeyID,
+		SecretAccessKey: secretAccessKey,
+		BaseURL:         "https://route53.amazonaws.com",
+		Client:          &http.Client{Timeout: 30 * time.Second},
+		now:             time.Now,
+	}
+}
+
+func (p *Route53Provider) Name() string { return "route53" }
+
+type r53HostedZone struct {
+	ID   string `xml:"Id"`
+	Name string `xml:"Name"`
+}
+
+type r53ResourceRecord struct {
+	Value string `xml:"Value"`
+}
+
+type r53RecordSet struct {
+	Name            string              `xml:"Name"`
+	Type            string              `xml:"Type"`
+	TTL             int                 `xml:"TTL,omitempty"`
+	ResourceRecords []r53ResourceRecord `xml:"ResourceRecords>ResourceRecord"`
+}
+
+type r53Change struct {
+	Action string       `xml:"Action"`
+	Set    r53RecordSet `xml:"ResourceRecordSet"`
+}
+
+type r53ChangeRequest struct {
+	XMLName xml.Name    `xml:"https://route53.amazonaws.com/doc/2013-04-01/ ChangeResourceRecordSetsRequest"`
+	Changes []r53Change `xml:"ChangeBatch>Changes>Change"`
+}
+
+func (p *Route53Provider) do(ctx context.Context, method, path string, body any, out any) error {
+	var payload []byte
+	if body != nil {
+		b, err := xml.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("marshal body: %w", err)
+		}
+		payload = append([]byte(xml.Header), b...)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, p.BaseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/xml")
+	}
+	p.sign(req, payload)
+
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("route53 api error (%d): %s", resp.StatusCode, truncate(string(raw), 400))
+	}
+
+	if out != nil {
+		if err := xml.Unmarshal(raw, out); err != nil {
+			return fmt.Errorf("parse response: %w: %s", err, truncate(string(raw), 400))
+		}
+	}
+	return nil
+}
+
+// sign adds the SigV4 authorization headers to the request
+func (p *Route53Provider) sign(req *http.Request, payload []byte) {
+	t := p.now().UTC()
+	amzDate := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+	payloadHash := sha256Hex(payload)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	headers := []string{"host:" + req.URL.Host, "x-amz-date:" + amzDate}
+	signedHeaders := "host;x-amz-date"
+	if p.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", p.SessionToken)
+		headers = append(headers, "x-amz-security-token:"+p.SessionToken)
+		signedHeaders += ";x-amz-security-token"
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		strings.Join(headers, "\n") + "\n",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + route53Region + "/route53/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+p.SecretAccessKey), date)
+	key = hmacSHA256(key, route53Region)
+	key = hmacSHA256(key, "route53")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		p.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// canonicalQuery returns the query string sorted by key with SigV4 escaping
+func canonicalQuery(q url.Values) string {
+	// url.Values.Encode sorts by key; SigV4 wants %20 rather than +
+	return strings.ReplaceAll(q.Encode(), "+", "%20")
+}
+
+// ResolveZone walks the FQDN parent labels to locate the hosted zone.
+func (p *Route53Provider) ResolveZone(ctx context.Context, fqdn string) (*Zone, error) {
+	fqdn = strings.TrimSuffix(strings.ToLower(fqdn), ".")
+	labels := strings.Split(fqdn, ".")
+
+	for i := 0; i < len(labels)-1; i++ {
+		candidate := strings.Join(labels[i:], ".")
+		var result struct {
+			HostedZones []r53HostedZone `xml:"HostedZones>HostedZone"`
+		}
+		q := url.Values{}
+		q.Set("dnsname", candidate)
+		q.Set("maxitems", "1")
+		if err := p.do(ctx, http.MethodGet, "/2013-04-01/hostedzonesbyname?"+q.Encode(), nil, &result); err != nil {
+			return nil, err
+		}
+		// The list starts at dnsname, so the first zone may be a later one
+		if len(result.HostedZones) > 0 && strings.TrimSuffix(result.HostedZones[0].Name, ".") == candidate {
+			z := result.HostedZones[0]
+			return &Zone{ID: strings.TrimPrefix(z.ID, "/hostedzone/"), Name: candidate}, nil
+		}
+	}
+	return nil, ErrZoneNotFound
+}
+
+// recordSet returns the record set of a name and type, nil if there is none
+func (p *Route53Provider) recordSet(ctx context.Context, zoneID, name, recordType string) (*r53RecordSet, error) {
+	var result struct {
+		RecordSets []r53RecordSet `xml:"ResourceRecordSets>ResourceRecordSet"`
+	}
+	q := url.Values{}
+	q.Set("name", name)
+	q.Set("type", recordType)
+	q.Set("maxitems", "1")
+	if err := p.do(ctx, http.MethodGet, "/2013-04-01/hostedzone/"+zoneID+"/rrset?"+q.Encode(), nil, &result); err != nil {
+		return nil, err
+	}
+	// The list starts at name and type, so the first set may be a later one
+	if len(result.RecordSets) == 0 {
+		return nil, nil
+	}
+	rs := result.RecordSets[0]
+	if !strings.EqualFold(strings.TrimSuffix(rs.Name, "."), strings.TrimSuffix(name, ".")) || !strings.EqualFold(rs.Type, recordType) {
+		return nil, nil
+	}
+	return &rs, nil
+}
+
+// ListRecords returns the values of the record set of the exact name and type.
+func (p *Route53Provider) ListRecords(ctx context.Context, zoneID, name, recordType string) ([]Record, error) {
+	rs, err := p.recordSet(ctx, zoneID, name, recordType)
+	if err != nil || rs == nil {
+		return nil, err
+	}
+
+	records := make([]Record, 0, len(rs.ResourceRecords))
+	for _, rr := range rs.ResourceRecords {
+		records = append(records, Record{
+			ID:      rr.Value,
+			Type:    rs.Type,
+			Name:    strings.TrimSuffix(strings.ToLower(rs.Name), "."),
+			Content: r53Content(rr.Value, rs.Type),
+			TTL:     rs.TTL,
+		})
+	}
+	return records, nil
+}
+
+// CreateRecord adds a value to the record set, creating it if needed.
+func (p *Route53Provider) CreateRecord(ctx context.Context, zoneID string, r Record) error {
+	return p.upsert(ctx, zoneID, r, "")
+}
+
+// UpdateRecord replaces the value identified by recordID in the record set.
+func (p *Route53Provider) UpdateRecord(ctx context.Context, zoneID, recordID string, r Record) error {
+	return p.upsert(ctx, zoneID, r, recordID)
+}
+
+func (p *Route53Provider) upsert(ctx context.Context, zoneID string, r Record, replace string) error {
+	rs, err := p.recordSet(ctx, zoneID, r.Name, r.Type)
+	if err != nil {
+		return err
+	}
+
+	set := r53RecordSet{
+		Name: strings.TrimSuffix(strings.ToLower(r.Name), ".") + ".",
+		Type: r.Type,
+		TTL:  r.TTL,
+	}
+	if rs != nil {
+		if set.TTL <= 0 {
+			set.TTL = rs.TTL
+		}
+		for _, rr := range rs.ResourceRecords {
+			if replace == "" || rr.Value != replace {
+				set.ResourceRecords = append(set.ResourceRecords, rr)
+			}
+		}
+	}
+	if set.TTL <= 0 {
+		set.TTL = 300
+	}
+	set.ResourceRecords = append(set.ResourceRecords, r53ResourceRecord{Value: r53Value(r.Content, r.Type)})
+
+	change := r53ChangeRequest{Changes: []r53Change{{Action: "UPSERT", Set: set}}}
+	return p.do(ctx, http.MethodPost, "/2013-04-01/hostedzone/"+zoneID+"/rrset", change, nil)
+}
+
+// r53Value returns a record value in Route 53 format. TXT values are quoted
+// strings of at most 255 characters each.
+func r53Value(content, recordType string) string {
+	if !strings.EqualFold(recordType, "TXT") {
+		return content
+	}
+	escaped := strings.ReplaceAll(content, "\\", "\\\\")
+	escaped = strings.ReplaceAll(escaped, "\"", "\\\"")
+	var parts []string
+	for len(escaped) > 255 {
+		cut := 255
+		// Do not split an escape sequence
+		if escaped[cut-1] == '\\' {
+			cut--
+		}
+		parts = append(parts, "\""+escaped[:cut]+"\"")
+		escaped = escaped[cut:]
+	}
+	parts = append(parts, "\""+escaped+"\"")
+	return strings.Join(parts, " ")
+}
+
+// r53Content returns the content of a Route 53 record value. The quoted
+// strings of a TXT value are joined.
+func r53Content(value, recordType string) string {
+	switch strings.ToUpper(recordType) {
+	case "TXT":
+		var b strings.Builder
+		inQuote, escaped := false, false
+		for i := 0; i < len(value); i++ {
+			c := value[i]
+			switch {
+			case escaped:
+				b.WriteByte(c)
+				escaped = false
+			case c == '\\' && inQuote:
+				escaped = true
+			case c == '"':
+				inQuote = !inQuote
+			case inQuote:
+				b.WriteByte(c)
+			}
+		}
+		return b.String()
+	case "MX":
+		return strings.TrimSuffix(value, ".")
+	}
+	return value
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	m := hmac.New(sha256.New, key)
+	m.Write([]byte(data))
+	return m.Sum(nil)
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
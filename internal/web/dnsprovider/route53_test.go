@@ -0,0 +1,95 @@
+package dnsprovider
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestRoute53(url string) *Route53Provider {
+	p := NewRoute53("AKIDEXAMPLE", "secret")
+	p.BaseURL = url
+	p.now = func() time.Time { return time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC) }
+	return p
+}
+
+func TestRoute53_ResolveZone(t *testing.T) {
+	var gotAuth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		// The list starts at the requested name, so later zones are returned too
+		_, _ = io.WriteString(w, `<ListHostedZonesByNameResponse><HostedZones><HostedZone>
+			<Id>/hostedzone/Z123</Id><Name>example.com.</Name></HostedZone></HostedZones></ListHostedZonesByNameResponse>`)
+	}))
+	defer srv.Close()
+
+	p := newTestRoute53(srv.URL)
+	z, err := p.ResolveZone(context.Background(), "_dmarc.example.com")
+	if err != nil {
+		t.Fatalf("ResolveZone error = %v", err)
+	}
+	if z.ID != "Z123" || z.Name != "example.com" {
+		t.Errorf("zone = %+v, want Z123 example.com", z)
+	}
+	if !strings.HasPrefix(gotAuth, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20260102/us-east-1/route53/aws4_request, SignedHeaders=host;x-amz-date, Signature=") {
+		t.Errorf("Authorization = %q", gotAuth)
+	}
+}
+
+func TestRoute53_UpsertKeepsOtherValues(t *testing.T) {
+	var change string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			b, _ := io.ReadAll(r.Body)
+			change = string(b)
+			_, _ = io.WriteString(w, `<ChangeResourceRecordSetsResponse/>`)
+			return
+		}
+		_, _ = io.WriteString(w, `<ListResourceRecordSetsResponse><ResourceRecordSets><ResourceRecordSet>
+			<Name>example.com.</Name><Type>TXT</Type><TTL>600</TTL><ResourceRecords>
+			<ResourceRecord><Value>"google-site-verification=abc"</Value></ResourceRecord>
+			<ResourceRecord><Value>"v=spf1 -all"</Value></ResourceRecord>
+			</ResourceRecords></ResourceRecordSet></ResourceRecordSets></ListResourceRecordSetsResponse>`)
+	}))
+	defer srv.Close()
+
+	p := newTestRoute53(srv.URL)
+	recs, err := p.ListRecords(context.Background(), "Z123", "example.com", "TXT")
+	if err != nil {
+		t.Fatalf("ListRecords error = %v", err)
+	}
+	if len(recs) != 2 || recs[1].Content != "v=spf1 -all" {
+		t.Fatalf("records = %+v", recs)
+	}
+
+	err = p.UpdateRecord(context.Background(), "Z123", recs[1].ID, Record{Type: "TXT", Name: "example.com", Content: "v=spf1 a mx ~all"})
+	if err != nil {
+		t.Fatalf("UpdateRecord error = %v", err)
+	}
+	for _, want := range []string{"<Action>UPSERT</Action>", "<TTL>600</TTL>", "google-site-verification=abc", "v=spf1 a mx ~all"} {
+		if !strings.Contains(change, want) {
+			t.Errorf("change does not contain %q: %s", want, change)
+		}
+	}
+	if strings.Contains(change, "v=spf1 -all") {
+		t.Errorf("change still contains the replaced value: %s", change)
+	}
+}
+
+func TestRoute53_TXTValue(t *testing.T) {
+	long := strings.Repeat("a", 300)
+	v := r53Value(long, "TXT")
+	if v != `"`+long[:255]+`" "`+long[255:]+`"` {
+		t.Errorf("r53Value = %q", v)
+	}
+	if got := r53Content(v, "TXT"); got != long {
+		t.Errorf("r53Content = %q, want joined value", got)
+	}
+	if got := r53Content(`"say \"hi\""`, "TXT"); got != `say "hi"` {
+		t.Errorf("r53Content = %q, want unescaped value", got)
+	}
+}
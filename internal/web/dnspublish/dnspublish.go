@@ -0,0 +1,410 @@
+// Package dnspublish publishes the recommended DNS records of domains
+// through a DNS provider API and verifies that they propagated before a
+// domain is reported ready.
+package dnspublish
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/foxzi/sendry/internal/web/config"
+	"github.com/foxzi/sendry/internal/web/dnsprovider"
+	"github.com/foxzi/sendry/internal/web/dnssync"
+	"github.com/foxzi/sendry/internal/web/models"
+	"github.com/foxzi/sendry/internal/web/mtasts"
+	"github.com/foxzi/sendry/internal/web/repository"
+)
+
+// lookupTimeout bounds the propagation check of one record
+const lookupTimeout = 10 * time.Second
+
+// LookupFunc returns the values of the records of a type at a name. TXT
+// values are the joined strings of each record, MX values are
+// "<preference> <host>".
+type LookupFunc func(ctx context.Context, recordType, name string) ([]string, error)
+
+// NewProvider creates the DNS provider selected in the configuration
+func NewProvider(cfg config.DNSPublishingConfig) (dnsprovider.Provider, error) {
+	switch cfg.Provider {
+	case config.DNSProviderCloudflare:
+		if cfg.Cloudflare.APIToken != "" {
+			return dnsprovider.NewCloudflare(cfg.Cloudflare.APIToken), nil
+		}
+		return dnsprovider.NewCloudflareGlobalKey(cfg.Cloudflare.Email, cfg.Cloudflare.APIKey), nil
+	case config.DNSProviderRoute53:
+		p := dnsprovider.NewRoute53(cfg.Route53.AccessKeyID, cfg.Route53.SecretAccessKey)
+		p.SessionToken = cfg.Route53.SessionToken
+		return p, nil
+	case config.DNSProviderRFC2136:
+		p := dnsprovider.NewRFC2136(cfg.RFC2136.Server, cfg.RFC2136.TSIGKey, cfg.RFC2136.TSIGAlgorithm, cfg.RFC2136.TSIGSecret)
+		p.Zone = cfg.RFC2136.Zone
+		return p, nil
+	case config.DNSProviderNamedot:
+		return dnsprovider.NewNamedot(cfg.Namedot.URL, cfg.Namedot.Token), nil
+	}
+	return nil, fmt.Errorf("unsupported dns provider %q", cfg.Provider)
+}
+
+// Publisher publishes domain records and tracks their propagation
+type Publisher struct {
+	cfg      config.DNSPublishingConfig
+	provider dnsprovider.Provider
+	records  *repository.DNSRecordRepository
+	domains  *repository.DomainRepository
+	dkim     *repository.DKIMRepository
+	settings *repository.SettingsRepository
+	mtaSTS   *repository.MTASTSRepository
+	lookup   LookupFunc
+	logger   *slog.Logger
+
+	mu     sync.Mutex // Serializes publishing and checks
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New creates a publisher. The provider is nil when publishing is disabled
+// or the provider settings are invalid; Publish then returns an error.
+func New(cfg config.DNSPublishingConfig, db *sql.DB, logger *slog.Logger) *Publisher {
+	p := &Publisher{
+		cfg:      cfg,
+		records:  repository.NewDNSRecordRepository(db),
+		domains:  repository.NewDomainRepository(db),
+		dkim:     repository.NewDKIMRepository(db),
+		settings: repository.NewSettingsRepository(db),
+		mtaSTS:   repository.NewMTASTSRepository(db),
+		lookup:   NewLookup(cfg.Resolver),
+		logger:   logger.With("component", "dns_publishing"),
+	}
+	if cfg.Enabled {
+		provider, err := NewProvider(cfg)
+		if err != nil {
+			p.logger.Error("failed to create dns provider", "error", err)
+		}
+		p.provider = provider
+	}
+	return p
+}
+
+// Enabled returns true if records can be published
+func (p *Publisher) Enabled() bool {
+	return p.cfg.Enabled && p.provider != nil
+}
+
+// Auto returns true if records are published when domains and DKIM keys change
+func (p *Publisher) Auto() bool {
+	return p.Enabled() && p.cfg.Auto
+}
+
+// Config returns the publishing settings
+func (p *Publisher) Config() config.DNSPublishingConfig {
+	return p.cfg
+}
+
+// Records returns the published records of a domain
+func (p *Publisher) Records(domainID string) ([]models.DNSRecord, error) {
+	return p.records.ListByDomain(domainID)
+}
+
+// Publish creates or updates the recommended records of a domain with the
+// provider. Records the provider accepted are checked for propagation by
+// the verify loop; provider errors are stored on the failed records.
+func (p *Publisher) Publish(ctx context.Context, domainID string) ([]models.DNSRecord, error) {
+	if !p.Enabled() {
+		return nil, fmt.Errorf("dns publishing is not enabled")
+	}
+
+	domain, err := p.domains.GetByID(domainID)
+	if err != nil {
+		return nil, err
+	}
+	if domain == nil {
+		return nil, fmt.Errorf("domain not found")
+	}
+	if domain.DKIMKeyID != "" {
+		if key, err := p.dkim.GetByID(domain.DKIMKeyID); err == nil {
+			domain.DKIMKey = key
+		}
+	}
+
+	vars, err := p.settings.GetGlobalVariablesMap()
+	if err != nil {
+		return nil, fmt.Errorf("load global variables: %w", err)
+	}
+	entries := dnssync.BuildRecommended(domain, vars["spf_include"])
+	if p.cfg.MXHost != "" {
+		entries = append(entries, dnssync.MXEntry(domain.Domain, p.cfg.MXHost))
+	}
+	policy, err := p.mtaSTS.Get(domain.ID)
+	if err != nil {
+		return nil, fmt.Errorf("load mta-sts policy: %w", err)
+	}
+	if policy != nil {
+		sts := mtasts.Policy{Mode: policy.Mode, MX: policy.MX, MaxAge: policy.MaxAge}
+		entries = append(entries, dnssync.MTASTSEntry(domain.Domain, sts.TXTRecord()))
+	}
+
+	if err := p.apply(ctx, domain, entries); err != nil {
+		return nil, err
+	}
+	p.logger.Info("published dns records", "domain", domain.Domain, "provider", p.provider.Name())
+	return p.records.ListByDomain(domain.ID)
+}
+
+// PublishDKIM publishes the DNS record of a DKIM key. The record is tracked
+// on the domain of the same name if there is one.
+func (p *Publisher) PublishDKIM(ctx context.Context, key *models.DKIMKey) error {
+	if !p.Enabled() {
+		return fmt.Errorf("dns publishing is not enabled")
+	}
+
+	domain, err := p.domains.GetByDomain(key.Domain)
+	if err != nil {
+		return err
+	}
+	entries := []dnssync.PlanEntry{{
+		Kind:     dnssync.RecordDKIM,
+		Type:     "TXT",
+		Name:     fmt.Sprintf("%s._domainkey.%s", key.Selector, strings.ToLower(key.Domain)),
+		Expected: key.DNSRecord,
+	}}
+	if err := p.apply(ctx, domain, entries); err != nil {
+		return err
+	}
+	p.logger.Info("published dkim record", "domain", key.Domain, "selector", key.Selector, "provider", p.provider.Name())
+	return nil
+}
+
+// apply creates or updates the records with the provider and stores their
+// state on the domain, if any
+func (p *Publisher) apply(ctx context.Context, domain *models.Domain, entries []dnssync.PlanEntry) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	syncer := &dnssync.Syncer{Provider: p.provider, TTL: p.cfg.TTL}
+	results, err := syncer.Apply(ctx, entries)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, res := range results {
+		if res.Action == dnssync.ActionSkip && res.Name == "" {
+			continue // Not applicable, e.g. DKIM without a key
+		}
+		if res.Error != "" {
+			p.logger.Warn("failed to publish dns record", "name", res.Name, "kind", res.Kind, "error", res.Error)
+		}
+		if domain == nil {
+			continue
+		}
+		rec := &models.DNSRecord{
+			DomainID:    domain.ID,
+			Kind:        string(res.Kind),
+			Type:        res.Type,
+			Name:        res.Name,
+			Value:       res.Expected,
+			Provider:    p.provider.Name(),
+			Status:      models.DNSRecordPublished,
+			PublishedAt: &now,
+		}
+		if res.Error != "" {
+			rec.Status = models.DNSRecordFailed
+			rec.Error = res.Error
+		}
+		if err := p.records.Upsert(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Verify checks the propagation of all records of a domain
+func (p *Publisher) Verify(ctx context.Context, domainID string) ([]models.DNSRecord, error) {
+	records, err := p.records.ListByDomain(domainID)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	for i := range records {
+		if err := p.check(ctx, &records[i], now); err != nil {
+			return nil, err
+		}
+	}
+	return records, nil
+}
+
+// Run checks the propagation of all records waiting for it
+func (p *Publisher) Run(ctx context.Context) {
+	records, err := p.records.ListByStatus(models.DNSRecordPublished)
+	if err != nil {
+		p.logger.Error("failed to list published dns records", "error", err)
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	for i := range records {
+		if ctx.Err() != nil {
+			return
+		}
+		rec := &records[i]
+		if err := p.check(ctx, rec, now); err != nil {
+			p.logger.Error("failed to store dns record status", "name", rec.Name, "error", err)
+			continue
+		}
+		switch rec.Status {
+		case models.DNSRecordPropagated:
+			p.logger.Info("dns record propagated", "name", rec.Name, "kind", rec.Kind)
+		case models.DNSRecordFailed:
+			p.logger.Warn("dns record did not propagate", "name", rec.Name, "kind", rec.Kind, "error", rec.Error)
+		}
+	}
+}
+
+// check looks up a record and stores its state. Records that were never
+// accepted by the provider stay failed until they are visible.
+func (p *Publisher) check(ctx context.Context, rec *models.DNSRecord, now time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, lookupTimeout)
+	defer cancel()
+
+	values, lookupErr := p.lookup(ctx, rec.Type, rec.Name)
+	visible := lookupErr == nil && containsValue(rec.Type, values, rec.Value)
+
+	switch {
+	case visible:
+		if rec.VerifiedAt == nil {
+			rec.VerifiedAt = &now
+		}
+		rec.Status = models.DNSRecordPropagated
+		rec.Error = ""
+	case rec.Status == models.DNSRecordFailed:
+		// Keep the provider error
+	case rec.PublishedAt != nil && p.cfg.VerifyTimeout > 0 && now.Sub(*rec.PublishedAt) > p.cfg.VerifyTimeout:
+		rec.Status = models.DNSRecordFailed
+		rec.Error = fmt.Sprintf("not visible after %s", p.cfg.VerifyTimeout)
+	default:
+		// Published, or propagated before and now missing
+		rec.Status = models.DNSRecordPublished
+		rec.VerifiedAt = nil
+		rec.Error = ""
+		if lookupErr != nil {
+			rec.Error = lookupErr.Error()
+		}
+	}
+	rec.CheckedAt = &now
+	return p.records.SetStatus(rec.ID, rec.Status, rec.Error, now, rec.VerifiedAt)
+}
+
+// containsValue returns true if one of the values matches the expected value
+func containsValue(recordType string, values []string, expected string) bool {
+	want := normalize(recordType, expected)
+	for _, v := range values {
+		if normalize(recordType, v) == want {
+			return true
+		}
+	}
+	return false
+}
+
+func normalize(recordType, value string) string {
+	if strings.EqualFold(recordType, "MX") {
+		if pref, host, ok := dnsprovider.SplitMX(value); ok {
+			return fmt.Sprintf("%d %s", pref, host)
+		}
+	}
+	return dnssync.NormalizeTXT(value)
+}
+
+// NewLookup returns a lookup using the given resolver address, or the system
+// resolver when it is empty
+func NewLookup(resolver string) LookupFunc {
+	r := net.DefaultResolver
+	if resolver != "" {
+		r = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, resolver)
+			},
+		}
+	}
+
+	return func(ctx context.Context, recordType, name string) ([]string, error) {
+		switch strings.ToUpper(recordType) {
+		case "TXT":
+			values, err := r.LookupTXT(ctx, name)
+			if isNotFound(err) {
+				return nil, nil
+			}
+			return values, err
+		case "MX":
+			mxs, err := r.LookupMX(ctx, name)
+			if isNotFound(err) {
+				return nil, nil
+			}
+			values := make([]string, 0, len(mxs))
+			for _, mx := range mxs {
+				values = append(values, fmt.Sprintf("%d %s", mx.Pref, mx.Host))
+			}
+			return values, err
+		}
+		return nil, fmt.Errorf("unsupported record type %q", recordType)
+	}
+}
+
+func isNotFound(err error) bool {
+	dnsErr, ok := err.(*net.DNSError)
+	return ok && dnsErr.IsNotFound
+}
+
+// Start starts the propagation check loop
+func (p *Publisher) Start() {
+	if !p.Enabled() {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+
+	p.wg.Add(1)
+	go p.loop(ctx)
+	p.logger.Info("dns publishing started", "provider", p.provider.Name(), "verify_interval", p.cfg.VerifyInterval)
+}
+
+// Stop stops the loop and waits for a running check
+func (p *Publisher) Stop() {
+	if p.cancel == nil {
+		return
+	}
+	p.cancel()
+	p.wg.Wait()
+}
+
+func (p *Publisher) loop(ctx context.Context) {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.cfg.VerifyInterval)
+	defer ticker.Stop()
+
+	p.Run(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.Run(ctx)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package dnspublish
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/foxzi/sendry/internal/web/config"
+	"github.com/foxzi/sendry/internal/web/db"
+	"github.com/foxzi/sendry/internal/web/dnsprovider"
+	"github.com/foxzi/sendry/internal/web/models"
+)
+
+type fakeProvider struct {
+	records []dnsprovider.Record
+}
+
+func (f *fakeProvider) Name() string { return "fake" }
+
+func (f *fakeProvider) ResolveZone(_ context.Context, fqdn string) (*dnsprovider.Zone, error) {
+	return &dnsprovider.Zone{ID: "z1", Name: "example.com"}, nil
+}
+
+func (f *fakeProvider) ListRecords(_ context.Context, _, name, recordType string) ([]dnsprovider.Record, error) {
+	var out []dnsprovider.Record
+	for _, r := range f.records {
+		if r.Name == name && r.Type == recordType {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeProvider) CreateRecord(_ context.Context, _ string, r dnsprovider.Record) error {
+	f.records = append(f.records, r)
+	return nil
+}
+
+func (f *fakeProvider) UpdateRecord(_ context.Context, _, _ string, r dnsprovider.Record) error {
+	return nil
+}
+
+func TestPublishAndVerify(t *testing.T) {
+	database, err := db.New(filepath.Join(t.TempDir(), "web.db"))
+	if err != nil {
+		t.Fatalf("db.New() error = %v", err)
+	}
+	defer database.Close()
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+	if _, err := database.Exec(`INSERT INTO domains (id, domain) VALUES ('d1', 'example.com')`); err != nil {
+		t.Fatalf("insert domain: %v", err)
+	}
+	if _, err := database.Exec(`INSERT INTO mta_sts_policies (domain_id, mode, mx, max_age) VALUES ('d1', 'testing', '["mx.example.net"]', 86400)`); err != nil {
+		t.Fatalf("insert mta-sts policy: %v", err)
+	}
+
+	cfg := config.DNSPublishingConfig{Enabled: true, Provider: "fake", MXHost: "mx.example.net", TTL: 300, VerifyTimeout: time.Hour}
+	p := New(cfg, database.DB, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	fp := &fakeProvider{}
+	p.provider = fp
+
+	// The resolver sees what the provider has, except the MX record
+	p.lookup = func(_ context.Context, recordType, name string) ([]string, error) {
+		var values []string
+		for _, r := range fp.records {
+			if r.Name == name && r.Type == recordType && r.Type != "MX" {
+				values = append(values, r.Content)
+			}
+		}
+		return values, nil
+	}
+
+	records, err := p.Publish(context.Background(), "d1")
+	if err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	// SPF, DMARC, MX and MTA-STS; DKIM is skipped without a key
+	if len(records) != 4 || len(fp.records) != 4 {
+		t.Fatalf("Publish() = %d records, provider has %d, want 4", len(records), len(fp.records))
+	}
+	for _, r := range records {
+		if r.Kind == "MTA-STS" && (r.Name != "_mta-sts.example.com" || !strings.HasPrefix(r.Value, "v=STSv1; id=")) {
+			t.Errorf("MTA-STS record = %s %q", r.Name, r.Value)
+		}
+		if r.Status != models.DNSRecordPublished || r.Provider != "fake" {
+			t.Errorf("record %s = %s/%s, want published by fake", r.Kind, r.Status, r.Provider)
+		}
+	}
+
+	p.Run(context.Background())
+	records, _ = p.Records("d1")
+	for _, r := range records {
+		want := models.DNSRecordPropagated
+		if r.Kind == "MX" {
+			want = models.DNSRecordPublished
+		}
+		if r.Status != want || r.CheckedAt == nil {
+			t.Errorf("record %s = %s, want %s", r.Kind, r.Status, want)
+		}
+	}
+	if models.DNSRecordsReady(records) {
+		t.Error("DNSRecordsReady() = true with a pending MX record")
+	}
+
+	// Records not visible within the timeout fail
+	if _, err := database.Exec(`UPDATE dns_records SET published_at = ? WHERE kind = 'MX'`, time.Now().Add(-2*time.Hour).UTC()); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	records, err = p.Verify(context.Background(), "d1")
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	for _, r := range records {
+		if r.Kind == "MX" && (r.Status != models.DNSRecordFailed || r.Error == "") {
+			t.Errorf("MX record = %s %q, want failed", r.Status, r.Error)
+		}
+	}
+}
@@ -11,9 +11,11 @@ import (
 type RecordType string
 
 const (
-	RecordSPF   RecordType = "SPF"
-	RecordDKIM  RecordType = "DKIM"
-	RecordDMARC RecordType = "DMARC"
+	RecordSPF    RecordType = "SPF"
+	RecordDKIM   RecordType = "DKIM"
+	RecordDMARC  RecordType = "DMARC"
+	RecordMX     RecordType = "MX"
+	RecordMTASTS RecordType = "MTA-STS"
 )
 
 // Action describes what should happen with a record.
@@ -90,6 +92,30 @@ func BuildRecommended(domain *models.Domain, spfInclude string) []PlanEntry {
 	return entries
 }
 
+// MXEntry returns the MX record entry that points the domain to host with
+// preference 10.
+func MXEntry(domain, host string) PlanEntry {
+	d := strings.ToLower(strings.TrimSpace(domain))
+	return PlanEntry{
+		Kind:     RecordMX,
+		Type:     "MX",
+		Name:     d,
+		Expected: "10 " + strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), "."),
+	}
+}
+
+// MTASTSEntry returns the _mta-sts TXT record entry announcing the MTA-STS
+// policy of the domain.
+func MTASTSEntry(domain, value string) PlanEntry {
+	d := strings.ToLower(strings.TrimSpace(domain))
+	return PlanEntry{
+		Kind:     RecordMTASTS,
+		Type:     "TXT",
+		Name:     "_mta-sts." + d,
+		Expected: value,
+	}
+}
+
 // NormalizeTXT canonicalizes a TXT record value for comparison:
 // trims surrounding whitespace and quotes and collapses inner whitespace.
 func NormalizeTXT(s string) string {
@@ -10,6 +10,7 @@ import (
 // Syncer reconciles recommended DNS records with a DNS provider.
 type Syncer struct {
 	Provider dnsprovider.Provider
+	TTL      int // TTL of created and updated records; 0 means provider default
 }
 
 // SyncResult describes what was planned or done for one record.
@@ -59,7 +60,7 @@ func (s *Syncer) Plan(ctx context.Context, entries []PlanEntry) ([]SyncResult, e
 			continue
 		}
 
-		current := pickMatchingTXT(records, e.Expected)
+		_, current := pickRecord(e, records)
 		res.Current = current
 		res.Action, res.Reason = DecideAction(e.Expected, current)
 		results = append(results, res)
@@ -103,7 +104,7 @@ func (s *Syncer) Apply(ctx context.Context, entries []PlanEntry) ([]SyncResult,
 			continue
 		}
 
-		existingID, current := pickMatchingRecord(records, e.Expected)
+		existingID, current := pickRecord(e, records)
 		res.Current = current
 		res.Action, res.Reason = DecideAction(e.Expected, current)
 
@@ -113,6 +114,7 @@ func (s *Syncer) Apply(ctx context.Context, entries []PlanEntry) ([]SyncResult,
 				Type:    e.Type,
 				Name:    e.Name,
 				Content: e.Expected,
+				TTL:     s.TTL,
 			})
 			if err != nil {
 				res.Error = err.Error()
@@ -124,6 +126,7 @@ func (s *Syncer) Apply(ctx context.Context, entries []PlanEntry) ([]SyncResult,
 				Type:    e.Type,
 				Name:    e.Name,
 				Content: e.Expected,
+				TTL:     s.TTL,
 			})
 			if err != nil {
 				res.Error = err.Error()
@@ -136,17 +139,33 @@ func (s *Syncer) Apply(ctx context.Context, entries []PlanEntry) ([]SyncResult,
 	return results, nil
 }
 
-// pickMatchingTXT returns the content of the most relevant existing TXT:
-// if any record matches expected normalized form, return it; otherwise
-// pick one starting with the same prefix as expected (e.g. v=spf1, v=DMARC1);
-// if none match by prefix, return the first record's content (or empty).
-func pickMatchingTXT(records []dnsprovider.Record, expected string) string {
-	_, content := pickMatchingRecord(records, expected)
-	return content
+// pickRecord returns the existing record to compare with the entry. A domain
+// may have several MX records, so only an exact MX match counts; otherwise
+// the MX record is added next to the existing ones.
+func pickRecord(e PlanEntry, records []dnsprovider.Record) (string, string) {
+	if e.Kind != RecordMX {
+		return pickMatchingRecord(records, e.Expected)
+	}
+	for _, r := range records {
+		if normalizeMX(r.Content) == normalizeMX(e.Expected) {
+			return r.ID, r.Content
+		}
+	}
+	return "", ""
+}
+
+// normalizeMX canonicalizes MX content "<preference> <host>"
+func normalizeMX(s string) string {
+	if pref, host, ok := dnsprovider.SplitMX(s); ok {
+		return fmt.Sprintf("%d %s", pref, host)
+	}
+	return NormalizeTXT(s)
 }
 
-// pickMatchingRecord is like pickMatchingTXT but also returns the record ID
-// so it can be used for updates.
+// pickMatchingRecord returns the ID and content of the most relevant
+// existing TXT: if any record matches expected normalized form, return it;
+// otherwise pick one starting with the same prefix as expected (e.g. v=spf1,
+// v=DMARC1); if none match by prefix, return the first record (or empty).
 func pickMatchingRecord(records []dnsprovider.Record, expected string) (string, string) {
 	if len(records) == 0 {
 		return "", ""
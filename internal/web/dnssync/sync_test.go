@@ -149,3 +149,34 @@ func TestSyncer_Apply_CreatesAndUpdates(t *testing.T) {
 		t.Errorf("applied = %d, want 2 (SPF create + DMARC update)", appliedCount)
 	}
 }
+
+func TestSyncer_Apply_MXAddsNextToExisting(t *testing.T) {
+	fp := &fakeProvider{
+		zoneID:   "zone1",
+		zoneName: "example.com",
+		records: map[string][]dnsprovider.Record{
+			"example.com|MX": {
+				{ID: "r-mx", Type: "MX", Name: "example.com", Content: "20 backup.example.net"},
+			},
+		},
+	}
+
+	s := &Syncer{Provider: fp, TTL: 600}
+	results, err := s.Apply(context.Background(), []PlanEntry{MXEntry("example.com", "MX.example.com.")})
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+
+	if len(fp.updateCalls) != 0 {
+		t.Errorf("expected no update calls, got %d", len(fp.updateCalls))
+	}
+	if len(fp.createCalls) != 1 || fp.createCalls[0].Content != "10 mx.example.com" || fp.createCalls[0].TTL != 600 {
+		t.Fatalf("create calls = %+v, want 10 mx.example.com with TTL 600", fp.createCalls)
+	}
+
+	// A second run finds the record
+	results, _ = s.Apply(context.Background(), []PlanEntry{MXEntry("example.com", "mx.example.com")})
+	if results[0].Action != ActionNoop {
+		t.Errorf("action = %s, want noop", results[0].Action)
+	}
+}
@@ -0,0 +1,190 @@
+// Package drift detects templates changed or deleted on Sendry servers
+// after they were deployed from sendry-web.
+package drift
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/foxzi/sendry/internal/web/config"
+	"github.com/foxzi/sendry/internal/web/models"
+	"github.com/foxzi/sendry/internal/web/repository"
+	"github.com/foxzi/sendry/internal/web/sendry"
+	sendryclient "github.com/foxzi/sendry/pkg/client"
+)
+
+// pageSize is the number of templates fetched per request, the Sendry API maximum
+const pageSize = 1000
+
+// Hash returns the content hash of a template as deployed to a server
+func Hash(subject, html, text string) string {
+	h := sha256.New()
+	h.Write([]byte(subject))
+	h.Write([]byte{0})
+	h.Write([]byte(html))
+	h.Write([]byte{0})
+	h.Write([]byte(text))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Result describes a drift check of one server
+type Result struct {
+	Server   string
+	Checked  int
+	Modified int
+	Deleted  int
+}
+
+// Checker periodically compares deployed templates with the templates on
+// each Sendry server
+type Checker struct {
+	cfg       config.TemplateSyncConfig
+	templates *repository.TemplateRepository
+	sendry    *sendry.Manager
+	logger    *slog.Logger
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New creates a drift checker
+func New(cfg config.TemplateSyncConfig, db *sql.DB, sendryMgr *sendry.Manager, logger *slog.Logger) *Checker {
+	return &Checker{
+		cfg:       cfg,
+		templates: repository.NewTemplateRepository(db),
+		sendry:    sendryMgr,
+		logger:    logger.With("component", "template_sync"),
+	}
+}
+
+// Run checks all configured servers. Servers that cannot be reached are
+// logged and skipped, keeping their last known drift state.
+func (c *Checker) Run(ctx context.Context) []Result {
+	var results []Result
+	for _, server := range c.sendry.GetServers() {
+		result, err := c.CheckServer(ctx, server.Name)
+		if err != nil {
+			c.logger.Warn("template drift check failed", "server", server.Name, "error", err)
+			continue
+		}
+		if result.Modified > 0 || result.Deleted > 0 {
+			c.logger.Warn("template drift detected", "server", server.Name,
+				"modified", result.Modified, "deleted", result.Deleted)
+		}
+		results = append(results, *result)
+	}
+	return results
+}
+
+// CheckServer compares the templates deployed to a server with the
+// templates on it and records the drift of each deployment
+func (c *Checker) CheckServer(ctx context.Context, serverName string) (*Result, error) {
+	deployments, err := c.templates.ListServerDeployments(serverName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deployments: %w", err)
+	}
+	result := &Result{Server: serverName}
+	if len(deployments) == 0 {
+		return result, nil
+	}
+
+	remote, err := c.fetch(ctx, serverName)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, d := range deployments {
+		if d.RemoteID == "" {
+			continue
+		}
+		result.Checked++
+
+		var state, baseline string
+		t, ok := remote[d.RemoteID]
+		switch {
+		case !ok:
+			state = models.DriftDeleted
+			result.Deleted++
+		case d.ContentHash == "":
+			// Deployed before hashes were recorded, take the server content
+			// as the baseline
+			baseline = Hash(t.Subject, t.HTML, t.Text)
+		case Hash(t.Subject, t.HTML, t.Text) != d.ContentHash:
+			state = models.DriftModified
+			result.Modified++
+		}
+
+		if err := c.templates.SetDeploymentDrift(d.ID, state, baseline); err != nil {
+			return nil, fmt.Errorf("failed to save drift: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// fetch returns all templates on a server by ID
+func (c *Checker) fetch(ctx context.Context, serverName string) (map[string]*sendryclient.Template, error) {
+	client, err := c.sendry.GetClient(serverName)
+	if err != nil {
+		return nil, err
+	}
+
+	templates := make(map[string]*sendryclient.Template)
+	for offset := 0; ; offset += pageSize {
+		resp, err := client.ListTemplates(ctx, "", pageSize, offset)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list templates: %w", err)
+		}
+		for _, t := range resp.Templates {
+			templates[t.ID] = t
+		}
+		if len(resp.Templates) < pageSize {
+			return templates, nil
+		}
+	}
+}
+
+// Start runs drift checks at the configured interval if enabled
+func (c *Checker) Start() {
+	if !c.cfg.Enabled {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+
+	c.wg.Add(1)
+	go c.loop(ctx)
+	c.logger.Info("template drift checker started", "interval", c.cfg.Interval)
+}
+
+// Stop stops the checker and waits for a running check
+func (c *Checker) Stop() {
+	if c.cancel == nil {
+		return
+	}
+	c.cancel()
+	c.wg.Wait()
+}
+
+func (c *Checker) loop(ctx context.Context) {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(c.cfg.Interval)
+	defer ticker.Stop()
+
+	c.Run(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.Run(ctx)
+		}
+	}
+}
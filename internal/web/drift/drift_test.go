@@ -0,0 +1,115 @@
+package drift
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/foxzi/sendry/internal/web/config"
+	"github.com/foxzi/sendry/internal/web/db"
+	"github.com/foxzi/sendry/internal/web/models"
+	"github.com/foxzi/sendry/internal/web/repository"
+	"github.com/foxzi/sendry/internal/web/sendry"
+	sendryclient "github.com/foxzi/sendry/pkg/client"
+)
+
+func TestCheckerRun(t *testing.T) {
+	database, err := db.New(filepath.Join(t.TempDir(), "web.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.Close()
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	remote := []*sendryclient.Template{
+		{ID: "r-sync", Subject: "Hi", HTML: "<p>Hi</p>"},
+		{ID: "r-modified", Subject: "Hi", HTML: "<p>Edited on the server</p>"},
+		{ID: "r-legacy", Subject: "Old", Text: "Old"},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(sendryclient.TemplateListResponse{Templates: remote, Total: len(remote)})
+	}))
+	defer srv.Close()
+
+	servers := []config.SendryServer{
+		{Name: "prod", BaseURL: srv.URL},
+		{Name: "down", BaseURL: "http://127.0.0.1:1"},
+	}
+	repo := repository.NewTemplateRepository(database.DB)
+
+	ids := map[string]string{}
+	for _, d := range []struct{ name, server, remoteID, hash string }{
+		{"sync", "prod", "r-sync", Hash("Hi", "<p>Hi</p>", "")},
+		{"modified", "prod", "r-modified", Hash("Hi", "<p>Hi</p>", "")},
+		{"deleted", "prod", "r-deleted", Hash("Bye", "", "Bye")},
+		{"legacy", "prod", "r-legacy", ""},
+		{"unreachable", "down", "r-down", Hash("Hi", "", "")},
+	} {
+		tmpl := &models.Template{Name: d.name, Subject: "Hi", HTML: "<p>Hi</p>"}
+		if err := repo.Create(tmpl, ""); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		ids[d.name] = tmpl.ID
+		deployment := &models.TemplateDeployment{
+			TemplateID: tmpl.ID, ServerName: d.server, RemoteID: d.remoteID, DeployedVersion: 1, ContentHash: d.hash,
+		}
+		if err := repo.SaveDeployment(deployment); err != nil {
+			t.Fatalf("SaveDeployment() error = %v", err)
+		}
+	}
+
+	checker := New(config.TemplateSyncConfig{}, database.DB, sendry.NewManager(servers), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	results := checker.Run(context.Background())
+	if len(results) != 1 || results[0].Server != "prod" || results[0].Checked != 4 ||
+		results[0].Modified != 1 || results[0].Deleted != 1 {
+		t.Fatalf("Run() = %+v, want prod with 4 checked, 1 modified, 1 deleted", results)
+	}
+
+	want := map[string]string{
+		"sync":        "",
+		"modified":    models.DriftModified,
+		"deleted":     models.DriftDeleted,
+		"legacy":      "",
+		"unreachable": "",
+	}
+	for name, drift := range want {
+		server := "prod"
+		if name == "unreachable" {
+			server = "down"
+		}
+		d, err := repo.GetDeployment(ids[name], server)
+		if err != nil || d == nil {
+			t.Fatalf("GetDeployment(%s) = %v, %v", name, d, err)
+		}
+		if d.Drift != drift {
+			t.Errorf("%s drift = %q, want %q", name, d.Drift, drift)
+		}
+		if checked := d.DriftCheckedAt != nil; checked != (name != "unreachable") {
+			t.Errorf("%s drift checked = %v", name, checked)
+		}
+		if name == "legacy" && d.ContentHash != Hash("Old", "", "Old") {
+			t.Errorf("legacy hash = %q, want the server content hash", d.ContentHash)
+		}
+	}
+
+	// A deploy records the new content as in sync
+	if err := repo.SaveDeployment(&models.TemplateDeployment{
+		TemplateID: ids["modified"], ServerName: "prod", RemoteID: "r-modified", DeployedVersion: 1,
+		ContentHash: Hash("Hi", "<p>Edited on the server</p>", ""),
+	}); err != nil {
+		t.Fatalf("SaveDeployment() error = %v", err)
+	}
+	if d, _ := repo.GetDeployment(ids["modified"], "prod"); d.Drift != "" {
+		t.Errorf("drift after redeploy = %q, want none", d.Drift)
+	}
+	if results := checker.Run(context.Background()); results[0].Modified != 0 {
+		t.Errorf("Run() after redeploy = %+v, want no modified templates", results)
+	}
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/foxzi/sendry/internal/web/alert"
+	"github.com/foxzi/sendry/internal/web/middleware"
+	"github.com/foxzi/sendry/internal/web/models"
+)
+
+// alertHistoryLimit is the number of alerts shown in the alert history
+const alertHistoryLimit = 50
+
+// SetAlertEngine sets the alert engine
+func (h *Handlers) SetAlertEngine(e *alert.Engine) {
+	h.alertEngine = e
+}
+
+// Alerts shows alert rules, channels, firing alerts, silences and history
+func (h *Handlers) Alerts(w http.ResponseWriter, r *http.Request) {
+	h.renderAlerts(w, r, "", "")
+}
+
+func (h *Handlers) renderAlerts(w http.ResponseWriter, r *http.Request, errMsg, success string) {
+	if h.alertEngine == nil {
+		h.error(w, http.StatusNotFound, "Alerts are not available")
+		return
+	}
+
+	now := time.Now()
+	firing, err := h.alerts.Firing()
+	if err != nil {
+		h.logger.Error("failed to list firing alerts", "error", err)
+		h.error(w, http.StatusInternalServerError, "Failed to load alerts")
+		return
+	}
+	history, err := h.alerts.List(alertHistoryLimit)
+	if err != nil {
+		h.logger.Error("failed to list alerts", "error", err)
+	}
+	silences, err := h.alerts.ListSilences(now)
+	if err != nil {
+		h.logger.Error("failed to list alert silences", "error", err)
+	}
+
+	data := map[string]any{
+		"Title":    "Alerts",
+		"Active":   "settings",
+		"User":     h.getUserFromContext(r),
+		"Config":   h.alertEngine.Config(),
+		"Health":   h.cfg.Health.Enabled,
+		"Servers":  h.sendry.GetServers(),
+		"Firing":   firing,
+		"History":  history,
+		"Silences": silences,
+		"Now":      now,
+		"Error":    errMsg,
+		"Success":  success,
+	}
+
+	h.render(w, "settings_alerts", data)
+}
+
+// AlertSilenceCreate creates an alert silence
+func (h *Handlers) AlertSilenceCreate(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		h.error(w, http.StatusBadRequest, "Invalid form data")
+		return
+	}
+
+	s := &models.AlertSilence{
+		Rule:      strings.TrimSpace(r.FormValue("rule")),
+		Server:    strings.TrimSpace(r.FormValue("server")),
+		Reason:    strings.TrimSpace(r.FormValue("reason")),
+		CreatedBy: middleware.GetUserEmail(r),
+	}
+
+	var err error
+	if s.StartsAt, err = time.Parse(freezeTimeLayout, r.FormValue("starts_at")); err != nil {
+		h.renderAlerts(w, r, "Invalid start time", "")
+		return
+	}
+	if s.EndsAt, err = time.Parse(freezeTimeLayout, r.FormValue("ends_at")); err != nil {
+		h.renderAlerts(w, r, "Invalid end time", "")
+		return
+	}
+	if !s.EndsAt.After(s.StartsAt) {
+		h.renderAlerts(w, r, "End time must be after start time", "")
+		return
+	}
+
+	if err := h.alerts.CreateSilence(s); err != nil {
+		h.logger.Error("failed to create alert silence", "error", err)
+		h.error(w, http.StatusInternalServerError, "Failed to create alert silence")
+		return
+	}
+
+	h.settings.LogAction(r, middleware.GetUserID(r), middleware.GetUserEmail(r),
+		"create", "alert_silence", s.ID, silenceDetails(s))
+	http.Redirect(w, r, "/settings/alerts", http.StatusSeeOther)
+}
+
+// AlertSilenceDelete deletes an alert silence
+func (h *Handlers) AlertSilenceDelete(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	s, err := h.alerts.GetSilence(id)
+	if err != nil || s == nil {
+		h.error(w, http.StatusNotFound, "Alert silence not found")
+		return
+	}
+
+	if err := h.alerts.DeleteSilence(id); err != nil {
+		h.logger.Error("failed to delete alert silence", "error", err)
+		h.error(w, http.StatusInternalServerError, "Failed to delete alert silence")
+		return
+	}
+
+	h.settings.LogAction(r, middleware.GetUserID(r), middleware.GetUserEmail(r),
+		"delete", "alert_silence", id, silenceDetails(s))
+	http.Redirect(w, r, "/settings/alerts", http.StatusSeeOther)
+}
+
+// AlertTest sends a test notification to a channel
+func (h *Handlers) AlertTest(w http.ResponseWriter, r *http.Request) {
+	if h.alertEngine == nil {
+		h.error(w, http.StatusNotFound, "Alerts are not available")
+		return
+	}
+
+	channel := r.FormValue("channel")
+	if err := h.alertEngine.Test(r.Context(), channel); err != nil {
+		h.renderAlerts(w, r, "Test notification to "+channel+" failed: "+err.Error(), "")
+		return
+	}
+	h.renderAlerts(w, r, "", "Test notification sent to "+channel)
+}
+
+// silenceDetails returns the audit log details of an alert silence
+func silenceDetails(s *models.AlertSilence) string {
+	details := map[string]string{
+		"rule":      s.Rule,
+		"server":    s.Server,
+		"starts_at": s.StartsAt.UTC().Format(time.RFC3339),
+		"ends_at":   s.EndsAt.UTC().Format(time.RFC3339),
+	}
+	b, _ := json.Marshal(details)
+	return string(b)
+}
@@ -4,9 +4,11 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/foxzi/sendry/internal/web/middleware"
+	"github.com/foxzi/sendry/internal/web/models"
 	"github.com/foxzi/sendry/internal/web/router"
 )
 
@@ -40,6 +42,25 @@ func (h *Handlers) APISend(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// The sender domain and template must belong to the key's organization
+	if domain, err := h.domains.GetByDomain(extractDomainFromEmail(req.From)); err == nil && domain != nil && !orgOwns(r, domain.OrgID) {
+		h.apiError(w, http.StatusBadRequest, "Domain not configured", "DOMAIN_NOT_FOUND")
+		return
+	}
+	if req.TemplateID != "" || req.TemplateName != "" {
+		var t *models.Template
+		var err error
+		if req.TemplateID != "" {
+			t, err = h.templates.GetByID(req.TemplateID)
+		} else {
+			t, err = h.templates.GetByName(req.TemplateName)
+		}
+		if err == nil && t != nil && !orgOwns(r, t.OrgID) {
+			h.apiError(w, http.StatusNotFound, "Template not found", "TEMPLATE_NOT_FOUND")
+			return
+		}
+	}
+
 	// Get client IP
 	clientIP := r.RemoteAddr
 	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
@@ -110,6 +131,124 @@ func (h *Handlers) APIGetStatus(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// APIListJobs handles GET /api/v1/jobs
+func (h *Handlers) APIListJobs(w http.ResponseWriter, r *http.Request) {
+	limit, offset := apiPage(r)
+	jobs, total, err := h.jobs.List(models.JobListFilter{
+		CampaignID: r.URL.Query().Get("campaign_id"),
+		OrgID:      middleware.GetOrgID(r),
+		Status:     r.URL.Query().Get("status"),
+		Limit:      limit,
+		Offset:     offset,
+	})
+	if err != nil {
+		h.logger.Error("failed to list jobs", "error", err)
+		h.apiError(w, http.StatusInternalServerError, "Failed to list jobs", "INTERNAL_ERROR")
+		return
+	}
+	h.apiJSON(w, http.StatusOK, map[string]any{"jobs": jobs, "total": total})
+}
+
+// APIGetJob handles GET /api/v1/jobs/{id}
+func (h *Handlers) APIGetJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.apiJob(w, r)
+	if !ok {
+		return
+	}
+	h.apiJobResponse(w, http.StatusOK, job)
+}
+
+// APIJobPause handles POST /api/v1/jobs/{id}/pause
+func (h *Handlers) APIJobPause(w http.ResponseWriter, r *http.Request) {
+	h.apiJobControl(w, r, "pause")
+}
+
+// APIJobResume handles POST /api/v1/jobs/{id}/resume
+func (h *Handlers) APIJobResume(w http.ResponseWriter, r *http.Request) {
+	h.apiJobControl(w, r, "resume")
+}
+
+// APIJobCancel handles POST /api/v1/jobs/{id}/cancel
+func (h *Handlers) APIJobCancel(w http.ResponseWriter, r *http.Request) {
+	h.apiJobControl(w, r, "cancel")
+}
+
+func (h *Handlers) apiJobControl(w http.ResponseWriter, r *http.Request, name string) {
+	job, ok := h.apiJob(w, r)
+	if !ok {
+		return
+	}
+	if err := h.applyJobAction(r, job, name); err != nil {
+		h.apiActionError(w, err)
+		return
+	}
+	if job, ok = h.apiJob(w, r); ok {
+		h.apiJobResponse(w, http.StatusOK, job)
+	}
+}
+
+// apiJob loads the job of the request, writing an error if it fails
+func (h *Handlers) apiJob(w http.ResponseWriter, r *http.Request) (*models.SendJob, bool) {
+	id := r.PathValue("id")
+	job, err := h.jobs.GetByID(id)
+	if err != nil {
+		h.logger.Error("failed to get job", "id", id, "error", err)
+		h.apiError(w, http.StatusInternalServerError, "Failed to get job", "INTERNAL_ERROR")
+		return nil, false
+	}
+	if job == nil {
+		h.apiError(w, http.StatusNotFound, "Job not found", "NOT_FOUND")
+		return nil, false
+	}
+	return job, true
+}
+
+func (h *Handlers) apiJobResponse(w http.ResponseWriter, status int, job *models.SendJob) {
+	stats, err := h.jobs.GetStats(job.ID)
+	if err != nil {
+		h.logger.Error("failed to get job stats", "id", job.ID, "error", err)
+		h.apiError(w, http.StatusInternalServerError, "Failed to get job stats", "INTERNAL_ERROR")
+		return
+	}
+	h.apiJSON(w, status, map[string]any{
+		"id":           job.ID,
+		"campaign_id":  job.CampaignID,
+		"status":       job.Status,
+		"scheduled_at": job.ScheduledAt,
+		"started_at":   job.StartedAt,
+		"completed_at": job.CompletedAt,
+		"stats":        stats,
+	})
+}
+
+// decodeAPIJSON decodes the JSON body of a request, writing an error if it
+// fails
+func (h *Handlers) decodeAPIJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		h.apiError(w, http.StatusBadRequest, "Invalid request body", "INVALID_JSON")
+		return false
+	}
+	return true
+}
+
+// apiPage returns the limit and offset query parameters of a list request
+func apiPage(r *http.Request) (limit, offset int) {
+	limit, _ = strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	offset, _ = strconv.Atoi(r.URL.Query().Get("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
+
+// apiActionError sends the error response of a failed action
+func (h *Handlers) apiActionError(w http.ResponseWriter, err *actionError) {
+	h.apiError(w, err.status, err.message, err.code)
+}
+
 // apiJSON sends a JSON response
 func (h *Handlers) apiJSON(w http.ResponseWriter, status int, data any) {
 	w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/foxzi/sendry/internal/web/config"
+	"github.com/foxzi/sendry/internal/web/sendry"
+)
+
+// apiCall calls an API handler with a JSON body and decodes the response
+func apiCall(t *testing.T, handler http.HandlerFunc, method, body string, values map[string]string, resp any) int {
+	t.Helper()
+	req := httptest.NewRequest(method, "/api/v1/test", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range values {
+		req.SetPathValue(k, v)
+	}
+	w := httptest.NewRecorder()
+	handler(w, req)
+	if resp != nil && w.Body.Len() > 0 {
+		if err := json.Unmarshal(w.Body.Bytes(), resp); err != nil {
+			t.Fatalf("invalid response %q: %v", w.Body.String(), err)
+		}
+	}
+	return w.Code
+}
+
+func TestAPITemplates(t *testing.T) {
+	h, _, cleanup := newDomainViewTestHandlers(t)
+	defer cleanup()
+
+	var deployed int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deployed++
+		json.NewEncoder(w).Encode(map[string]any{"id": "remote-1"})
+	}))
+	defer srv.Close()
+	h.cfg.Sendry.Servers = []config.SendryServer{{Name: "prod", BaseURL: srv.URL}}
+	h.sendry = sendry.NewManager(h.cfg.Sendry.Servers)
+
+	var errResp map[string]any
+	if code := apiCall(t, h.APICreateTemplate, "POST", `{"name":"Welcome"}`, nil, &errResp); code != http.StatusBadRequest || errResp["code"] != "INVALID_REQUEST" {
+		t.Errorf("create without subject = %d %v, want 400 INVALID_REQUEST", code, errResp)
+	}
+	if code := apiCall(t, h.APICreateTemplate, "POST", `{`, nil, nil); code != http.StatusBadRequest {
+		t.Errorf("create with invalid JSON = %d, want 400", code)
+	}
+
+	var created struct {
+		ID             string `json:"id"`
+		CurrentVersion int    `json:"current_version"`
+	}
+	if code := apiCall(t, h.APICreateTemplate, "POST", `{"name":"Welcome","subject":"Hi v1","html":"<p>v1</p>"}`, nil, &created); code != http.StatusCreated || created.ID == "" {
+		t.Fatalf("create = %d %+v", code, created)
+	}
+	id := map[string]string{"id": created.ID}
+
+	var updated struct {
+		Subject        string `json:"subject"`
+		CurrentVersion int    `json:"current_version"`
+	}
+	if code := apiCall(t, h.APIUpdateTemplate, "PUT", `{"name":"Welcome","subject":"Hi v2","html":"<p>v2</p>"}`, id, &updated); code != http.StatusOK || updated.CurrentVersion != 2 || updated.Subject != "Hi v2" {
+		t.Errorf("update = %d %+v, want version 2", code, updated)
+	}
+
+	var deploy struct {
+		Version int               `json:"version"`
+		Results []APIDeployResult `json:"results"`
+	}
+	if code := apiCall(t, h.APIDeployTemplate, "POST", `{"servers":["prod","missing"],"version":1}`, id, &deploy); code != http.StatusOK {
+		t.Fatalf("deploy = %d %+v", code, deploy)
+	}
+	if deploy.Version != 1 || len(deploy.Results) != 2 || deploy.Results[0].Status != "deployed" ||
+		deploy.Results[0].RemoteID != "remote-1" || deploy.Results[1].Status != "failed" || deployed != 1 {
+		t.Errorf("deploy = %+v, deployed %d", deploy, deployed)
+	}
+
+	var got struct {
+		Deployments []struct {
+			ServerName      string `json:"server_name"`
+			DeployedVersion int    `json:"deployed_version"`
+		} `json:"deployments"`
+	}
+	if code := apiCall(t, h.APIGetTemplate, "GET", "", id, &got); code != http.StatusOK || len(got.Deployments) != 1 || got.Deployments[0].DeployedVersion != 1 {
+		t.Errorf("get = %d %+v, want the prod deployment of version 1", code, got)
+	}
+
+	if code := apiCall(t, h.APIDeleteTemplate, "DELETE", "", id, nil); code != http.StatusNoContent {
+		t.Errorf("delete = %d, want 204", code)
+	}
+	if code := apiCall(t, h.APIGetTemplate, "GET", "", id, nil); code != http.StatusNotFound {
+		t.Errorf("get deleted = %d, want 404", code)
+	}
+}
+
+func TestAPICampaignSend(t *testing.T) {
+	h, _, cleanup := newDomainViewTestHandlers(t)
+	defer cleanup()
+
+	var list struct {
+		ID         string `json:"id"`
+		SourceType string `json:"source_type"`
+	}
+	if code := apiCall(t, h.APICreateRecipientList, "POST", `{"name":"Customers"}`, nil, &list); code != http.StatusCreated || list.SourceType != "api" {
+		t.Fatalf("create list = %d %+v", code, list)
+	}
+	listID := map[string]string{"id": list.ID}
+
+	var added APIAddRecipientsResponse
+	body := `{"recipients":[
+		{"email":"a@example.com","name":"A","variables":"{\"plan\":\"pro\"}","tags":"vip, new"},
+		{"email":"b@example.com"},
+		{"email":"not-an-email"}]}`
+	if code := apiCall(t, h.APIAddRecipients, "POST", body, listID, &added); code != http.StatusOK ||
+		added.Imported != 2 || added.Updated != 0 || len(added.Rejected) != 1 || added.Rejected[0].Row != 3 {
+		t.Fatalf("add recipients = %d %+v", code, added)
+	}
+	if code := apiCall(t, h.APIAddRecipients, "POST", `{"recipients":[{"email":"A@example.com","name":"Ann"}]}`, listID, &added); code != http.StatusOK ||
+		added.Imported != 0 || added.Updated != 1 {
+		t.Errorf("re-add recipient = %d %+v, want 1 updated", code, added)
+	}
+
+	var recipients struct {
+		Recipients []struct {
+			Email string `json:"email"`
+			Name  string `json:"name"`
+			Tags  string `json:"tags"`
+		} `json:"recipients"`
+		Total int `json:"total"`
+	}
+	if code := apiCall(t, h.APIListRecipients, "GET", "", listID, &recipients); code != http.StatusOK || recipients.Total != 2 {
+		t.Fatalf("list recipients = %d %+v", code, recipients)
+	}
+	for _, rec := range recipients.Recipients {
+		if rec.Email == "a@example.com" && (rec.Name != "Ann" || rec.Tags != `["vip","new"]`) {
+			t.Errorf("updated recipient = %+v", rec)
+		}
+	}
+
+	var tmpl, campaign struct {
+		ID string `json:"id"`
+	}
+	apiCall(t, h.APICreateTemplate, "POST", `{"name":"Promo","subject":"Sale","html":"<p>Sale</p>"}`, nil, &tmpl)
+	if code := apiCall(t, h.APICreateCampaign, "POST", `{"name":"Spring","from_email":"news@example.com"}`, nil, &campaign); code != http.StatusCreated {
+		t.Fatalf("create campaign = %d", code)
+	}
+	campaignID := map[string]string{"id": campaign.ID}
+
+	sendBody := `{"recipient_list_id":"` + list.ID + `","servers":["prod"],"throttle":{"rate_per_minute":100}}`
+	var errResp map[string]any
+	if code := apiCall(t, h.APISendCampaign, "POST", sendBody, campaignID, &errResp); code != http.StatusBadRequest || errResp["code"] != "NO_VARIANTS" {
+		t.Errorf("send without variants = %d %v, want 400 NO_VARIANTS", code, errResp)
+	}
+	if code := apiCall(t, h.APICreateVariant, "POST", `{"name":"A","template_id":"missing"}`, campaignID, nil); code != http.StatusBadRequest {
+		t.Errorf("variant of a missing template = %d, want 400", code)
+	}
+	if code := apiCall(t, h.APICreateVariant, "POST", `{"name":"A","template_id":"`+tmpl.ID+`"}`, campaignID, nil); code != http.StatusCreated {
+		t.Fatalf("create variant = %d", code)
+	}
+	bad := `{"recipient_list_id":"` + list.ID + `","servers":["prod"],"throttle":{"rate_per_minute":-1}}`
+	if code := apiCall(t, h.APISendCampaign, "POST", bad, campaignID, nil); code != http.StatusBadRequest {
+		t.Errorf("send with negative rate = %d, want 400", code)
+	}
+
+	var job struct {
+		ID     string `json:"id"`
+		Status string `json:"status"`
+		Stats  struct {
+			Total int `json:"total"`
+		} `json:"stats"`
+	}
+	if code := apiCall(t, h.APISendCampaign, "POST", sendBody, campaignID, &job); code != http.StatusCreated {
+		t.Fatalf("send = %d %+v", code, job)
+	}
+	if job.ID == "" || job.Status != "running" || job.Stats.Total != 2 {
+		t.Errorf("job = %+v, want running with 2 items", job)
+	}
+	stored, err := h.jobs.GetByID(job.ID)
+	if err != nil || stored.Throttle != `{"rate_per_minute":100}` {
+		t.Errorf("stored job = %+v, %v, want the throttle", stored, err)
+	}
+}
+
+func TestAPIDomainsAndDKIM(t *testing.T) {
+	h, _, cleanup := newDomainViewTestHandlers(t)
+	defer cleanup()
+
+	if code := apiCall(t, h.APICreateDomain, "POST", `{"domain":"example.com","mode":"staging"}`, nil, nil); code != http.StatusBadRequest {
+		t.Errorf("create with unknown mode = %d, want 400", code)
+	}
+
+	var key struct {
+		ID         string `json:"id"`
+		PrivateKey string `json:"private_key"`
+		DNSRecord  string `json:"dns_record"`
+	}
+	if code := apiCall(t, h.APICreateDKIM, "POST", `{"domain":"example.com","selector":"mail"}`, nil, &key); code != http.StatusCreated {
+		t.Fatalf("create DKIM key = %d", code)
+	}
+	if key.PrivateKey != "" || !strings.HasPrefix(key.DNSRecord, "v=DKIM1; k=rsa; p=") {
+		t.Errorf("DKIM key = %+v, want a DNS record and no private key", key)
+	}
+	if code := apiCall(t, h.APICreateDKIM, "POST", `{"domain":"example.com","selector":"mail"}`, nil, nil); code != http.StatusConflict {
+		t.Errorf("duplicate DKIM key = %d, want 409", code)
+	}
+
+	var domain struct {
+		ID           string   `json:"id"`
+		Mode         string   `json:"mode"`
+		DKIMSelector string   `json:"dkim_selector"`
+		BCCTo        []string `json:"bcc_to"`
+	}
+	body := `{"domain":"example.com","dkim_enabled":true,"dkim_key_id":"` + key.ID + `","redirect_to":["qa@example.com"]}`
+	if code := apiCall(t, h.APICreateDomain, "POST", body, nil, &domain); code != http.StatusCreated {
+		t.Fatalf("create domain = %d", code)
+	}
+	if domain.Mode != "production" || domain.DKIMSelector != "mail" {
+		t.Errorf("domain = %+v, want production with selector mail", domain)
+	}
+	if code := apiCall(t, h.APICreateDomain, "POST", `{"domain":"example.com"}`, nil, nil); code != http.StatusConflict {
+		t.Errorf("duplicate domain = %d, want 409", code)
+	}
+
+	id := map[string]string{"id": domain.ID}
+	domain.DKIMSelector = ""
+	if code := apiCall(t, h.APIUpdateDomain, "PUT", `{"mode":"bcc","bcc_to":["archive@example.com"]}`, id, &domain); code != http.StatusOK ||
+		domain.Mode != "bcc" || len(domain.BCCTo) != 1 || domain.DKIMSelector != "" {
+		t.Errorf("update = %d %+v", code, domain)
+	}
+
+	var domains struct {
+		Total int `json:"total"`
+	}
+	if code := apiCall(t, h.APIListDomains, "GET", "", nil, &domains); code != http.StatusOK || domains.Total != 1 {
+		t.Errorf("list = %d %+v", code, domains)
+	}
+	if code := apiCall(t, h.APIDeleteDomain, "DELETE", "", id, nil); code != http.StatusNoContent {
+		t.Errorf("delete = %d, want 204", code)
+	}
+}
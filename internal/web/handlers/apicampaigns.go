@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/foxzi/sendry/internal/web/middleware"
+	"github.com/foxzi/sendry/internal/web/models"
+)
+
+// APICampaignRequest is the body of campaign create and update requests
+type APICampaignRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	FromEmail   string `json:"from_email"`
+	FromName    string `json:"from_name"`
+	ReplyTo     string `json:"reply_to"`
+	Variables   string `json:"variables"` // JSON
+	Tags        string `json:"tags"`      // JSON array
+}
+
+// APIVariantRequest is the body of variant create requests
+type APIVariantRequest struct {
+	Name            string `json:"name"`
+	TemplateID      string `json:"template_id"`
+	SubjectOverride string `json:"subject_override"`
+	Weight          int    `json:"weight"` // 100 if 0
+}
+
+// APICampaignSendRequest is the body of campaign send requests
+type APICampaignSendRequest struct {
+	RecipientListID string              `json:"recipient_list_id"`
+	SegmentID       string              `json:"segment_id,omitempty"`
+	Servers         []string            `json:"servers"`
+	Strategy        string              `json:"strategy,omitempty"`
+	DryRun          bool                `json:"dry_run,omitempty"`
+	DryRunLimit     int                 `json:"dry_run_limit,omitempty"`
+	Throttle        *models.JobThrottle `json:"throttle,omitempty"`
+	ScheduledAt     *time.Time          `json:"scheduled_at,omitempty"`
+}
+
+// APIListCampaigns handles GET /api/v1/campaigns
+func (h *Handlers) APIListCampaigns(w http.ResponseWriter, r *http.Request) {
+	limit, offset := apiPage(r)
+	campaigns, total, err := h.campaigns.List(models.CampaignListFilter{
+		OrgID:  middleware.GetOrgID(r),
+		Search: r.URL.Query().Get("search"),
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		h.logger.Error("failed to list campaigns", "error", err)
+		h.apiError(w, http.StatusInternalServerError, "Failed to list campaigns", "INTERNAL_ERROR")
+		return
+	}
+	h.apiJSON(w, http.StatusOK, map[string]any{"campaigns": campaigns, "total": total})
+}
+
+// APICreateCampaign handles POST /api/v1/campaigns
+func (h *Handlers) APICreateCampaign(w http.ResponseWriter, r *http.Request) {
+	var req APICampaignRequest
+	if !h.decodeAPIJSON(w, r, &req) {
+		return
+	}
+
+	c := &models.Campaign{}
+	req.apply(c)
+	if c.Name == "" || c.FromEmail == "" {
+		h.apiError(w, http.StatusBadRequest, "Name and from_email are required", "INVALID_REQUEST")
+		return
+	}
+
+	c.OrgID = middleware.GetOrgID(r)
+	if err := h.campaigns.Create(c); err != nil {
+		h.logger.Error("failed to create campaign", "error", err)
+		h.apiError(w, http.StatusInternalServerError, "Failed to create campaign", "INTERNAL_ERROR")
+		return
+	}
+
+	h.settings.LogAction(r, middleware.GetUserID(r), requestActor(r),
+		"create", "campaign", c.ID, auditJSON(map[string]any{"name": c.Name}))
+	h.apiJSON(w, http.StatusCreated, c)
+}
+
+// APIGetCampaign handles GET /api/v1/campaigns/{id}
+func (h *Handlers) APIGetCampaign(w http.ResponseWriter, r *http.Request) {
+	c, ok := h.apiCampaign(w, r)
+	if !ok {
+		return
+	}
+
+	variants, err := h.campaigns.GetVariants(c.ID)
+	if err != nil {
+		h.logger.Error("failed to get variants", "campaign_id", c.ID, "error", err)
+	}
+	h.apiJSON(w, http.StatusOK, map[string]any{"campaign": c, "variants": variants})
+}
+
+// APIUpdateCampaign handles PUT /api/v1/campaigns/{id}
+func (h *Handlers) APIUpdateCampaign(w http.ResponseWriter, r *http.Request) {
+	c, ok := h.apiCampaign(w, r)
+	if !ok {
+		return
+	}
+
+	var req APICampaignRequest
+	if !h.decodeAPIJSON(w, r, &req) {
+		return
+	}
+	req.apply(c)
+	if c.Name == "" || c.FromEmail == "" {
+		h.apiError(w, http.StatusBadRequest, "Name and from_email are required", "INVALID_REQUEST")
+		return
+	}
+
+	if err := h.campaigns.Update(c); err != nil {
+		h.logger.Error("failed to update campaign", "error", err)
+		h.apiError(w, http.StatusInternalServerError, "Failed to update campaign", "INTERNAL_ERROR")
+		return
+	}
+
+	h.settings.LogAction(r, middleware.GetUserID(r), requestActor(r),
+		"update", "campaign", c.ID, auditJSON(map[string]any{"name": c.Name}))
+	h.apiJSON(w, http.StatusOK, c)
+}
+
+// APIDeleteCampaign handles DELETE /api/v1/campaigns/{id}
+func (h *Handlers) APIDeleteCampaign(w http.ResponseWriter, r *http.Request) {
+	c, ok := h.apiCampaign(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.campaigns.Delete(c.ID); err != nil {
+		h.logger.Error("failed to delete campaign", "error", err)
+		h.apiError(w, http.StatusInternalServerError, "Failed to delete campaign", "INTERNAL_ERROR")
+		return
+	}
+
+	h.settings.LogAction(r, middleware.GetUserID(r), requestActor(r),
+		"delete", "campaign", c.ID, "")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// APICreateVariant handles POST /api/v1/campaigns/{id}/variants
+func (h *Handlers) APICreateVariant(w http.ResponseWriter, r *http.Request) {
+	c, ok := h.apiCampaign(w, r)
+	if !ok {
+		return
+	}
+
+	var req APIVariantRequest
+	if !h.decodeAPIJSON(w, r, &req) {
+		return
+	}
+	if req.Name == "" || req.TemplateID == "" {
+		h.apiError(w, http.StatusBadRequest, "Name and template_id are required", "INVALID_REQUEST")
+		return
+	}
+	if t, err := h.templates.GetByID(req.TemplateID); err != nil || t == nil || !orgOwns(r, t.OrgID) {
+		h.apiError(w, http.StatusBadRequest, "Template not found", "INVALID_REQUEST")
+		return
+	}
+	if req.Weight <= 0 {
+		req.Weight = 100
+	}
+
+	v := &models.CampaignVariant{
+		CampaignID:      c.ID,
+		Name:            req.Name,
+		TemplateID:      req.TemplateID,
+		SubjectOverride: req.SubjectOverride,
+		Weight:          req.Weight,
+	}
+	if err := h.campaigns.AddVariant(v); err != nil {
+		h.logger.Error("failed to add variant", "error", err)
+		h.apiError(w, http.StatusInternalServerError, "Failed to add variant", "INTERNAL_ERROR")
+		return
+	}
+	h.apiJSON(w, http.StatusCreated, v)
+}
+
+// APIDeleteVariant handles DELETE /api/v1/campaigns/{id}/variants/{variantId}
+func (h *Handlers) APIDeleteVariant(w http.ResponseWriter, r *http.Request) {
+	v, err := h.campaigns.GetVariant(r.PathValue("variantId"))
+	if err != nil || v == nil || v.CampaignID != r.PathValue("id") {
+		h.apiError(w, http.StatusNotFound, "Variant not found", "NOT_FOUND")
+		return
+	}
+
+	if err := h.campaigns.DeleteVariant(v.ID); err != nil {
+		h.logger.Error("failed to delete variant", "error", err)
+		h.apiError(w, http.StatusInternalServerError, "Failed to delete variant", "INTERNAL_ERROR")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// APISendCampaign handles POST /api/v1/campaigns/{id}/send
+func (h *Handlers) APISendCampaign(w http.ResponseWriter, r *http.Request) {
+	c, ok := h.apiCampaign(w, r)
+	if !ok {
+		return
+	}
+
+	var req APICampaignSendRequest
+	if !h.decodeAPIJSON(w, r, &req) {
+		return
+	}
+
+	job, aerr := h.sendCampaign(r, c, &req)
+	if aerr != nil {
+		h.apiActionError(w, aerr)
+		return
+	}
+
+	h.apiJobResponse(w, http.StatusCreated, job)
+}
+
+// sendCampaign starts the send job of a campaign for a send request of the
+// JSON API
+func (h *Handlers) sendCampaign(r *http.Request, c *models.Campaign, req *APICampaignSendRequest) (*models.SendJob, *actionError) {
+	var throttle string
+	if !req.Throttle.IsZero() {
+		if err := req.Throttle.Validate(); err != nil {
+			return nil, &actionError{http.StatusBadRequest, "INVALID_REQUEST", "Invalid sending rate: " + err.Error()}
+		}
+		data, _ := json.Marshal(req.Throttle)
+		throttle = string(data)
+	}
+
+	return h.startCampaign(r, c, campaignSend{
+		RecipientListID: req.RecipientListID,
+		SegmentID:       req.SegmentID,
+		Servers:         req.Servers,
+		Strategy:        req.Strategy,
+		DryRun:          req.DryRun,
+		DryRunLimit:     req.DryRunLimit,
+		Throttle:        throttle,
+		ScheduledAt:     req.ScheduledAt,
+	})
+}
+
+// apiCampaign loads the campaign of the request, writing an error if it fails
+func (h *Handlers) apiCampaign(w http.ResponseWriter, r *http.Request) (*models.Campaign, bool) {
+	id := r.PathValue("id")
+	c, err := h.campaigns.GetByID(id)
+	if err != nil {
+		h.logger.Error("failed to get campaign", "id", id, "error", err)
+		h.apiError(w, http.StatusInternalServerError, "Failed to get campaign", "INTERNAL_ERROR")
+		return nil, false
+	}
+	if c == nil {
+		h.apiError(w, http.StatusNotFound, "Campaign not found", "NOT_FOUND")
+		return nil, false
+	}
+	return c, true
+}
+
+// apply copies the fields of the request to a campaign
+func (req *APICampaignRequest) apply(c *models.Campaign) {
+	c.Name = req.Name
+	c.Description = req.Description
+	c.FromEmail = req.FromEmail
+	c.FromName = req.FromName
+	c.ReplyTo = req.ReplyTo
+	c.Variables = req.Variables
+	c.Tags = req.Tags
+}
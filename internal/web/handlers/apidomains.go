@@ -0,0 +1,399 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/foxzi/sendry/internal/web/middleware"
+	"github.com/foxzi/sendry/internal/web/models"
+)
+
+// domainModes are the modes a domain can be in
+var domainModes = []string{"production", "sandbox", "redirect", "bcc"}
+
+// APIDomainRequest is the body of domain create and update requests
+type APIDomainRequest struct {
+	Domain              string   `json:"domain"` // Create only
+	Mode                string   `json:"mode"`   // production if empty
+	DefaultFrom         string   `json:"default_from,omitempty"`
+	DKIMEnabled         bool     `json:"dkim_enabled"`
+	DKIMSelector        string   `json:"dkim_selector,omitempty"` // mail if empty
+	DKIMKeyID           string   `json:"dkim_key_id,omitempty"`
+	RateLimitHour       int      `json:"rate_limit_hour"`
+	RateLimitDay        int      `json:"rate_limit_day"`
+	RateLimitRecipients int      `json:"rate_limit_recipients"`
+	RedirectTo          []string `json:"redirect_to,omitempty"`
+	BCCTo               []string `json:"bcc_to,omitempty"`
+	Servers             []string `json:"servers,omitempty"` // Servers to deploy a new domain to
+}
+
+// APIDKIMRequest is the body of DKIM key create requests
+type APIDKIMRequest struct {
+	Domain   string   `json:"domain"`
+	Selector string   `json:"selector"`
+	Servers  []string `json:"servers,omitempty"` // Servers to deploy the new key to
+}
+
+// APIListDomains handles GET /api/v1/domains
+func (h *Handlers) APIListDomains(w http.ResponseWriter, r *http.Request) {
+	domains, err := h.domains.List(models.DomainFilter{
+		OrgID:  middleware.GetOrgID(r),
+		Search: r.URL.Query().Get("search"),
+		Mode:   r.URL.Query().Get("mode"),
+	})
+	if err != nil {
+		h.logger.Error("failed to list domains", "error", err)
+		h.apiError(w, http.StatusInternalServerError, "Failed to list domains", "INTERNAL_ERROR")
+		return
+	}
+	h.apiJSON(w, http.StatusOK, map[string]any{"domains": domains, "total": len(domains)})
+}
+
+// APICreateDomain handles POST /api/v1/domains
+func (h *Handlers) APICreateDomain(w http.ResponseWriter, r *http.Request) {
+	var req APIDomainRequest
+	if !h.decodeAPIJSON(w, r, &req) {
+		return
+	}
+
+	domainName := strings.TrimSpace(req.Domain)
+	if domainName == "" {
+		h.apiError(w, http.StatusBadRequest, "Domain name is required", "INVALID_REQUEST")
+		return
+	}
+	if existing, _ := h.domains.GetByDomain(domainName); existing != nil {
+		h.apiError(w, http.StatusConflict, "Domain already exists", "CONFLICT")
+		return
+	}
+
+	domain := &models.Domain{Domain: domainName}
+	if !h.apiApplyDomain(w, &req, domain) {
+		return
+	}
+
+	domain.OrgID = middleware.GetOrgID(r)
+	if err := h.domains.Create(domain); err != nil {
+		h.logger.Error("failed to create domain", "error", err)
+		h.apiError(w, http.StatusInternalServerError, "Failed to create domain", "INTERNAL_ERROR")
+		return
+	}
+	h.settings.LogAction(r, middleware.GetUserID(r), requestActor(r),
+		"create", "domain", domain.ID, auditJSON(map[string]any{"new": domainAudit(domain)}))
+
+	if len(req.Servers) > 0 {
+		h.apiDeployDomain(r, domain, req.Servers)
+	}
+	h.autoPublishDNS(r, domain)
+
+	if created, err := h.domains.GetByID(domain.ID); err == nil && created != nil {
+		domain = created
+	}
+	h.apiJSON(w, http.StatusCreated, domain)
+}
+
+// APIGetDomain handles GET /api/v1/domains/{id}
+func (h *Handlers) APIGetDomain(w http.ResponseWriter, r *http.Request) {
+	if domain, ok := h.apiDomain(w, r); ok {
+		h.apiJSON(w, http.StatusOK, domain)
+	}
+}
+
+// APIUpdateDomain handles PUT /api/v1/domains/{id}. The domain is not
+// redeployed; deployments of the old settings become outdated.
+func (h *Handlers) APIUpdateDomain(w http.ResponseWriter, r *http.Request) {
+	domain, ok := h.apiDomain(w, r)
+	if !ok {
+		return
+	}
+
+	var req APIDomainRequest
+	if !h.decodeAPIJSON(w, r, &req) {
+		return
+	}
+
+	before := domainAudit(domain)
+	if !h.apiApplyDomain(w, &req, domain) {
+		return
+	}
+
+	if err := h.domains.Update(domain.ID, domain); err != nil {
+		h.logger.Error("failed to update domain", "error", err)
+		h.apiError(w, http.StatusInternalServerError, "Failed to update domain", "INTERNAL_ERROR")
+		return
+	}
+	h.settings.LogAction(r, middleware.GetUserID(r), requestActor(r),
+		"update", "domain", domain.ID, auditJSON(map[string]any{"old": before, "new": domainAudit(domain)}))
+
+	h.autoPublishDNS(r, domain)
+	h.apiJSON(w, http.StatusOK, domain)
+}
+
+// APIDeleteDomain handles DELETE /api/v1/domains/{id}, removing the domain
+// from the servers it is deployed to
+func (h *Handlers) APIDeleteDomain(w http.ResponseWriter, r *http.Request) {
+	domain, ok := h.apiDomain(w, r)
+	if !ok {
+		return
+	}
+
+	for _, d := range domain.Deployments {
+		client, err := h.sendry.GetClient(d.ServerName)
+		if err == nil {
+			_ = client.DeleteDomain(r.Context(), domain.Domain)
+		}
+	}
+
+	if err := h.domains.Delete(domain.ID); err != nil {
+		h.logger.Error("failed to delete domain", "error", err)
+		h.apiError(w, http.StatusInternalServerError, "Failed to delete domain", "INTERNAL_ERROR")
+		return
+	}
+	h.settings.LogAction(r, middleware.GetUserID(r), requestActor(r),
+		"delete", "domain", domain.ID, auditJSON(map[string]any{"old": domainAudit(domain)}))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// APIDeployDomain handles POST /api/v1/domains/{id}/deploy
+func (h *Handlers) APIDeployDomain(w http.ResponseWriter, r *http.Request) {
+	domain, ok := h.apiDomain(w, r)
+	if !ok {
+		return
+	}
+
+	var req APIDeployRequest
+	if !h.decodeAPIJSON(w, r, &req) {
+		return
+	}
+	if len(req.Servers) == 0 {
+		h.apiError(w, http.StatusBadRequest, "At least one server is required", "INVALID_REQUEST")
+		return
+	}
+	if !orgServersAllowed(r, req.Servers...) {
+		h.apiError(w, http.StatusNotFound, "Server not found", "NOT_FOUND")
+		return
+	}
+
+	results := h.apiDeployDomain(r, domain, req.Servers)
+	h.apiJSON(w, deployStatus(results), map[string]any{"results": results})
+}
+
+// APIListDKIM handles GET /api/v1/dkim
+func (h *Handlers) APIListDKIM(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.dkim.List(middleware.GetOrgID(r))
+	if err != nil {
+		h.logger.Error("failed to list DKIM keys", "error", err)
+		h.apiError(w, http.StatusInternalServerError, "Failed to list DKIM keys", "INTERNAL_ERROR")
+		return
+	}
+	h.apiJSON(w, http.StatusOK, map[string]any{"keys": keys, "total": len(keys)})
+}
+
+// APICreateDKIM handles POST /api/v1/dkim, generating a new key
+func (h *Handlers) APICreateDKIM(w http.ResponseWriter, r *http.Request) {
+	var req APIDKIMRequest
+	if !h.decodeAPIJSON(w, r, &req) {
+		return
+	}
+
+	domain := strings.TrimSpace(req.Domain)
+	selector := strings.TrimSpace(req.Selector)
+	if domain == "" || selector == "" {
+		h.apiError(w, http.StatusBadRequest, "Domain and selector are required", "INVALID_REQUEST")
+		return
+	}
+	if existing, _ := h.dkim.GetByDomainSelector(domain, selector); existing != nil {
+		h.apiError(w, http.StatusConflict, "DKIM key for this domain and selector already exists", "CONFLICT")
+		return
+	}
+
+	key, err := newDKIMKey(domain, selector)
+	if err != nil {
+		h.logger.Error("failed to generate DKIM key", "error", err)
+		h.apiError(w, http.StatusInternalServerError, "Failed to generate key", "INTERNAL_ERROR")
+		return
+	}
+	key.OrgID = middleware.GetOrgID(r)
+	if err := h.dkim.Create(key); err != nil {
+		h.logger.Error("failed to create DKIM key", "error", err)
+		h.apiError(w, http.StatusInternalServerError, "Failed to save DKIM key", "INTERNAL_ERROR")
+		return
+	}
+	h.settings.LogAction(r, middleware.GetUserID(r), requestActor(r),
+		"create", "dkim_key", key.ID, auditJSON(map[string]any{"domain": key.Domain, "selector": key.Selector}))
+
+	if len(req.Servers) > 0 {
+		r = h.withDeployCorrelation(r)
+		h.logDeployAction(r, deployEntityDKIM, key.ID, req.Servers)
+		h.deployDKIMKey(r, key, req.Servers)
+	}
+	h.autoPublishDKIM(r, key)
+
+	if created, err := h.dkim.GetByID(key.ID); err == nil && created != nil {
+		key = created
+	}
+	key.PrivateKey = ""
+	h.apiJSON(w, http.StatusCreated, key)
+}
+
+// APIGetDKIM handles GET /api/v1/dkim/{id}. The private key is not returned.
+func (h *Handlers) APIGetDKIM(w http.ResponseWriter, r *http.Request) {
+	if key, ok := h.apiDKIMKey(w, r); ok {
+		key.PrivateKey = ""
+		h.apiJSON(w, http.StatusOK, key)
+	}
+}
+
+// APIDeleteDKIM handles DELETE /api/v1/dkim/{id}, removing the key from the
+// servers it is deployed to
+func (h *Handlers) APIDeleteDKIM(w http.ResponseWriter, r *http.Request) {
+	key, ok := h.apiDKIMKey(w, r)
+	if !ok {
+		return
+	}
+
+	for _, d := range key.Deployments {
+		client, err := h.sendry.GetClient(d.ServerName)
+		if err == nil {
+			_ = client.DeleteDKIM(r.Context(), key.Domain, key.Selector)
+		}
+	}
+
+	if err := h.dkim.Delete(key.ID); err != nil {
+		h.logger.Error("failed to delete DKIM key", "error", err)
+		h.apiError(w, http.StatusInternalServerError, "Failed to delete DKIM key", "INTERNAL_ERROR")
+		return
+	}
+	h.settings.LogAction(r, middleware.GetUserID(r), requestActor(r),
+		"delete", "dkim_key", key.ID, auditJSON(map[string]any{"domain": key.Domain, "selector": key.Selector}))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// APIDeployDKIM handles POST /api/v1/dkim/{id}/deploy
+func (h *Handlers) APIDeployDKIM(w http.ResponseWriter, r *http.Request) {
+	key, ok := h.apiDKIMKey(w, r)
+	if !ok {
+		return
+	}
+
+	var req APIDeployRequest
+	if !h.decodeAPIJSON(w, r, &req) {
+		return
+	}
+	if len(req.Servers) == 0 {
+		h.apiError(w, http.StatusBadRequest, "At least one server is required", "INVALID_REQUEST")
+		return
+	}
+	if !orgServersAllowed(r, req.Servers...) {
+		h.apiError(w, http.StatusNotFound, "Server not found", "NOT_FOUND")
+		return
+	}
+
+	r = h.withDeployCorrelation(r)
+	h.logDeployAction(r, deployEntityDKIM, key.ID, req.Servers)
+	failures := h.deployDKIMKey(r, key, req.Servers)
+
+	results := make([]APIDeployResult, 0, len(req.Servers))
+	for _, server := range req.Servers {
+		result := APIDeployResult{Server: server, Status: "deployed"}
+		if msg, failed := failures[server]; failed {
+			result.Status, result.Error = "failed", msg
+		}
+		results = append(results, result)
+	}
+	h.apiJSON(w, deployStatus(results), map[string]any{"results": results})
+}
+
+// apiDeployDomain deploys a domain to servers under one correlation ID
+func (h *Handlers) apiDeployDomain(r *http.Request, domain *models.Domain, servers []string) []APIDeployResult {
+	r = h.withDeployCorrelation(r)
+	h.logDeployAction(r, deployEntityDomain, domain.ID, servers)
+
+	results := make([]APIDeployResult, 0, len(servers))
+	for _, server := range servers {
+		result := APIDeployResult{Server: server, Status: "deployed"}
+		if err := h.deployDomainToServer(r, domain, server); err != nil {
+			result.Status, result.Error = "failed", err.Error()
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// apiApplyDomain validates a domain request and copies its settings to a
+// domain, writing an error if they are invalid
+func (h *Handlers) apiApplyDomain(w http.ResponseWriter, req *APIDomainRequest, domain *models.Domain) bool {
+	mode := req.Mode
+	if mode == "" {
+		mode = "production"
+	}
+	if !slices.Contains(domainModes, mode) {
+		h.apiError(w, http.StatusBadRequest, "Unknown mode: "+mode, "INVALID_REQUEST")
+		return false
+	}
+	if req.RateLimitHour < 0 || req.RateLimitDay < 0 || req.RateLimitRecipients < 0 {
+		h.apiError(w, http.StatusBadRequest, "Rate limits must not be negative", "INVALID_REQUEST")
+		return false
+	}
+	if req.DKIMEnabled && req.DKIMKeyID != "" {
+		if key, err := h.dkim.GetByID(req.DKIMKeyID); err != nil || key == nil {
+			h.apiError(w, http.StatusBadRequest, "DKIM key not found", "INVALID_REQUEST")
+			return false
+		}
+	}
+
+	domain.Mode = mode
+	domain.DefaultFrom = req.DefaultFrom
+	domain.DKIMEnabled = req.DKIMEnabled
+	domain.DKIMSelector, domain.DKIMKeyID = "", ""
+	if req.DKIMEnabled {
+		domain.DKIMSelector = req.DKIMSelector
+		if domain.DKIMSelector == "" {
+			domain.DKIMSelector = "mail"
+		}
+		domain.DKIMKeyID = req.DKIMKeyID
+	}
+	domain.RateLimitHour = req.RateLimitHour
+	domain.RateLimitDay = req.RateLimitDay
+	domain.RateLimitRecipients = req.RateLimitRecipients
+	domain.RedirectTo, domain.BCCTo = nil, nil
+	switch mode {
+	case "redirect":
+		domain.RedirectTo = req.RedirectTo
+	case "bcc":
+		domain.BCCTo = req.BCCTo
+	}
+	return true
+}
+
+// apiDomain loads the domain of the request, writing an error if it fails
+func (h *Handlers) apiDomain(w http.ResponseWriter, r *http.Request) (*models.Domain, bool) {
+	id := r.PathValue("id")
+	domain, err := h.domains.GetByID(id)
+	if err != nil {
+		h.logger.Error("failed to get domain", "id", id, "error", err)
+		h.apiError(w, http.StatusInternalServerError, "Failed to get domain", "INTERNAL_ERROR")
+		return nil, false
+	}
+	if domain == nil {
+		h.apiError(w, http.StatusNotFound, "Domain not found", "NOT_FOUND")
+		return nil, false
+	}
+	return domain, true
+}
+
+// apiDKIMKey loads the DKIM key of the request, writing an error if it fails
+func (h *Handlers) apiDKIMKey(w http.ResponseWriter, r *http.Request) (*models.DKIMKey, bool) {
+	id := r.PathValue("id")
+	key, err := h.dkim.GetByID(id)
+	if err != nil {
+		h.logger.Error("failed to get DKIM key", "id", id, "error", err)
+		h.apiError(w, http.StatusInternalServerError, "Failed to get DKIM key", "INTERNAL_ERROR")
+		return nil, false
+	}
+	if key == nil {
+		h.apiError(w, http.StatusNotFound, "DKIM key not found", "NOT_FOUND")
+		return nil, false
+	}
+	return key, true
+}
@@ -2,10 +2,12 @@ package handlers
 
 import (
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/foxzi/sendry/internal/web/middleware"
 	"github.com/foxzi/sendry/internal/web/models"
 	"github.com/foxzi/sendry/internal/web/repository"
 )
@@ -22,6 +24,7 @@ func (h *Handlers) APIKeysList(w http.ResponseWriter, r *http.Request) {
 	offset := (page - 1) * limit
 
 	filter := models.APIKeyFilter{
+		OrgID:  middleware.GetOrgID(r),
 		Search: search,
 		Limit:  limit,
 		Offset: offset,
@@ -40,19 +43,20 @@ func (h *Handlers) APIKeysList(w http.ResponseWriter, r *http.Request) {
 	newKey := r.URL.Query().Get("new_key")
 
 	// Get available domains for selection
-	domains, _ := h.domains.List(models.DomainFilter{})
+	domains, _ := h.domains.List(models.DomainFilter{OrgID: middleware.GetOrgID(r)})
 
 	data := map[string]any{
-		"Title":      "API Keys",
-		"Active":     "settings",
-		"User":       h.getUserFromContext(r),
-		"APIKeys":    keys,
-		"Total":      total,
-		"Page":       page,
-		"TotalPages": totalPages,
-		"Search":     search,
-		"NewKey":     newKey,
-		"Domains":    domains,
+		"Title":       "API Keys",
+		"Active":      "settings",
+		"User":        h.getUserFromContext(r),
+		"APIKeys":     keys,
+		"Total":       total,
+		"Page":        page,
+		"TotalPages":  totalPages,
+		"Search":      search,
+		"NewKey":      newKey,
+		"Domains":     domains,
+		"Permissions": models.APIKeyPermissions,
 	}
 
 	h.render(w, "apikeys_list", data)
@@ -99,11 +103,12 @@ func (h *Handlers) APIKeyCreate(w http.ResponseWriter, r *http.Request) {
 	opts := repository.APIKeyCreateOptions{
 		Name:            name,
 		CreatedBy:       user["Email"].(string),
-		Permissions:     []string{"send"},
+		Permissions:     formPermissions(r),
 		AllowedDomains:  allowedDomains,
 		ExpiresAt:       expiresAt,
 		RateLimitMinute: rateLimitMinute,
 		RateLimitHour:   rateLimitHour,
+		OrgID:           middleware.GetOrgID(r),
 	}
 
 	result, err := h.apiKeys.Create(opts)
@@ -119,6 +124,17 @@ func (h *Handlers) APIKeyCreate(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/settings/api-keys?new_key="+result.Key, http.StatusSeeOther)
 }
 
+// formPermissions returns the known permissions checked in an API key form
+func formPermissions(r *http.Request) []string {
+	var permissions []string
+	for _, p := range r.Form["permissions"] {
+		if slices.Contains(models.APIKeyPermissions, p) && !slices.Contains(permissions, p) {
+			permissions = append(permissions, p)
+		}
+	}
+	return permissions
+}
+
 // APIKeyDelete deletes an API key
 func (h *Handlers) APIKeyDelete(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
@@ -198,7 +214,13 @@ func (h *Handlers) APIKeyEdit(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	if err := h.apiKeys.Update(id, name, allowedDomains, rateLimitMinute, rateLimitHour); err != nil {
+	permissions := formPermissions(r)
+	if len(permissions) == 0 {
+		h.error(w, http.StatusBadRequest, "At least one permission is required")
+		return
+	}
+
+	if err := h.apiKeys.Update(id, name, permissions, allowedDomains, rateLimitMinute, rateLimitHour); err != nil {
 		h.logger.Error("failed to update API key", "id", id, "error", err)
 		h.error(w, http.StatusInternalServerError, "Failed to update API key")
 		return
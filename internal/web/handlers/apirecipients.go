@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/foxzi/sendry/internal/web/importer"
+	"github.com/foxzi/sendry/internal/web/middleware"
+	"github.com/foxzi/sendry/internal/web/models"
+)
+
+// APIRecipientListRequest is the body of recipient list create and update requests
+type APIRecipientListRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// APIRecipient is a recipient to add to a list
+type APIRecipient struct {
+	Email     string `json:"email"`
+	Name      string `json:"name,omitempty"`
+	Variables string `json:"variables,omitempty"` // JSON object
+	Tags      string `json:"tags,omitempty"`      // JSON array or comma separated
+}
+
+// APIAddRecipientsRequest is the body of add recipients requests
+type APIAddRecipientsRequest struct {
+	Recipients []APIRecipient `json:"recipients"`
+}
+
+// APIAddRecipientsResponse reports added recipients
+type APIAddRecipientsResponse struct {
+	Imported int                  `json:"imported"` // New recipients
+	Updated  int                  `json:"updated"`  // Existing recipients of the list
+	Rejected []models.RejectedRow `json:"rejected,omitempty"`
+}
+
+// apiRecipientColumns maps the fields of APIRecipient for importer.Build
+var apiRecipientColumns = []importer.Column{
+	{Field: importer.FieldEmail},
+	{Field: importer.FieldName},
+	{Field: importer.FieldVariables},
+	{Field: importer.FieldTags},
+}
+
+// APIListRecipientLists handles GET /api/v1/lists
+func (h *Handlers) APIListRecipientLists(w http.ResponseWriter, r *http.Request) {
+	limit, offset := apiPage(r)
+	lists, total, err := h.recipients.ListLists(models.RecipientListFilter{
+		OrgID:  middleware.GetOrgID(r),
+		Search: r.URL.Query().Get("search"),
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		h.logger.Error("failed to list recipient lists", "error", err)
+		h.apiError(w, http.StatusInternalServerError, "Failed to list recipient lists", "INTERNAL_ERROR")
+		return
+	}
+	h.apiJSON(w, http.StatusOK, map[string]any{"lists": lists, "total": total})
+}
+
+// APICreateRecipientList handles POST /api/v1/lists
+func (h *Handlers) APICreateRecipientList(w http.ResponseWriter, r *http.Request) {
+	var req APIRecipientListRequest
+	if !h.decodeAPIJSON(w, r, &req) {
+		return
+	}
+	if req.Name == "" {
+		h.apiError(w, http.StatusBadRequest, "Name is required", "INVALID_REQUEST")
+		return
+	}
+
+	list := &models.RecipientList{
+		Name:        req.Name,
+		Description: req.Description,
+		SourceType:  "api",
+	}
+	list.OrgID = middleware.GetOrgID(r)
+	if err := h.recipients.CreateList(list); err != nil {
+		h.logger.Error("failed to create recipient list", "error", err)
+		h.apiError(w, http.StatusInternalServerError, "Failed to create recipient list", "INTERNAL_ERROR")
+		return
+	}
+
+	h.settings.LogAction(r, middleware.GetUserID(r), requestActor(r),
+		"create", "recipient_list", list.ID, auditJSON(map[string]any{"name": list.Name}))
+	h.apiJSON(w, http.StatusCreated, list)
+}
+
+// APIGetRecipientList handles GET /api/v1/lists/{id}
+func (h *Handlers) APIGetRecipientList(w http.ResponseWriter, r *http.Request) {
+	if list, ok := h.apiRecipientList(w, r); ok {
+		h.apiJSON(w, http.StatusOK, list)
+	}
+}
+
+// APIUpdateRecipientList handles PUT /api/v1/lists/{id}
+func (h *Handlers) APIUpdateRecipientList(w http.ResponseWriter, r *http.Request) {
+	list, ok := h.apiRecipientList(w, r)
+	if !ok {
+		return
+	}
+
+	var req APIRecipientListRequest
+	if !h.decodeAPIJSON(w, r, &req) {
+		return
+	}
+	if req.Name == "" {
+		h.apiError(w, http.StatusBadRequest, "Name is required", "INVALID_REQUEST")
+		return
+	}
+
+	list.Name = req.Name
+	list.Description = req.Description
+	if err := h.recipients.UpdateList(list); err != nil {
+		h.logger.Error("failed to update recipient list", "error", err)
+		h.apiError(w, http.StatusInternalServerError, "Failed to update recipient list", "INTERNAL_ERROR")
+		return
+	}
+
+	h.settings.LogAction(r, middleware.GetUserID(r), requestActor(r),
+		"update", "recipient_list", list.ID, auditJSON(map[string]any{"name": list.Name}))
+	h.apiJSON(w, http.StatusOK, list)
+}
+
+// APIDeleteRecipientList handles DELETE /api/v1/lists/{id}
+func (h *Handlers) APIDeleteRecipientList(w http.ResponseWriter, r *http.Request) {
+	list, ok := h.apiRecipientList(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.recipients.DeleteList(list.ID); err != nil {
+		h.logger.Error("failed to delete recipient list", "error", err)
+		h.apiError(w, http.StatusInternalServerError, "Failed to delete recipient list", "INTERNAL_ERROR")
+		return
+	}
+
+	h.settings.LogAction(r, middleware.GetUserID(r), requestActor(r),
+		"delete", "recipient_list", list.ID, "")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// APIListRecipients handles GET /api/v1/lists/{id}/recipients
+func (h *Handlers) APIListRecipients(w http.ResponseWriter, r *http.Request) {
+	list, ok := h.apiRecipientList(w, r)
+	if !ok {
+		return
+	}
+
+	limit, offset := apiPage(r)
+	recipients, total, err := h.recipients.ListRecipients(models.RecipientFilter{
+		ListID: list.ID,
+		Search: r.URL.Query().Get("search"),
+		Status: r.URL.Query().Get("status"),
+		Tag:    r.URL.Query().Get("tag"),
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		h.logger.Error("failed to list recipients", "error", err)
+		h.apiError(w, http.StatusInternalServerError, "Failed to list recipients", "INTERNAL_ERROR")
+		return
+	}
+	h.apiJSON(w, http.StatusOK, map[string]any{"recipients": recipients, "total": total})
+}
+
+// APIAddRecipients handles POST /api/v1/lists/{id}/recipients. Recipients
+// are validated like imported rows; recipients already in the list keep
+// their status and get the new name, tags and variables.
+func (h *Handlers) APIAddRecipients(w http.ResponseWriter, r *http.Request) {
+	list, ok := h.apiRecipientList(w, r)
+	if !ok {
+		return
+	}
+
+	var req APIAddRecipientsRequest
+	if !h.decodeAPIJSON(w, r, &req) {
+		return
+	}
+	if len(req.Recipients) == 0 {
+		h.apiError(w, http.StatusBadRequest, "At least one recipient is required", "INVALID_REQUEST")
+		return
+	}
+
+	values := make([][]string, len(req.Recipients))
+	for i, rec := range req.Recipients {
+		values[i] = []string{rec.Email, rec.Name, rec.Variables, rec.Tags}
+	}
+	rows, rejected := importer.Build(values, apiRecipientColumns)
+
+	existing, err := h.recipients.ListEmails(list.ID)
+	if err != nil {
+		h.logger.Error("failed to load list emails", "error", err)
+		h.apiError(w, http.StatusInternalServerError, "Failed to add recipients", "INTERNAL_ERROR")
+		return
+	}
+
+	resp := APIAddRecipientsResponse{Rejected: rejected}
+	recipients := make([]models.Recipient, 0, len(rows))
+	for _, row := range rows {
+		if stored, ok := existing[strings.ToLower(row.Recipient.Email)]; ok {
+			// Keep the stored case so the existing recipient is updated
+			row.Recipient.Email = stored
+			resp.Updated++
+		} else {
+			resp.Imported++
+		}
+		recipients = append(recipients, row.Recipient)
+	}
+
+	if len(recipients) > 0 {
+		if err := h.recipients.ImportRecipients(list.ID, recipients); err != nil {
+			h.logger.Error("failed to add recipients", "list_id", list.ID, "error", err)
+			h.apiError(w, http.StatusInternalServerError, "Failed to add recipients", "INTERNAL_ERROR")
+			return
+		}
+	}
+
+	h.settings.LogAction(r, middleware.GetUserID(r), requestActor(r),
+		"import", "recipient_list", list.ID, auditJSON(map[string]any{
+			"imported": resp.Imported, "updated": resp.Updated, "rejected": len(resp.Rejected),
+		}))
+	h.apiJSON(w, http.StatusOK, resp)
+}
+
+// APIDeleteRecipient handles DELETE /api/v1/lists/{id}/recipients/{recipientId}
+func (h *Handlers) APIDeleteRecipient(w http.ResponseWriter, r *http.Request) {
+	rec, err := h.recipients.GetRecipient(r.PathValue("recipientId"))
+	if err != nil || rec == nil || rec.ListID != r.PathValue("id") {
+		h.apiError(w, http.StatusNotFound, "Recipient not found", "NOT_FOUND")
+		return
+	}
+
+	if err := h.recipients.DeleteRecipient(rec.ID, rec.ListID); err != nil {
+		h.logger.Error("failed to delete recipient", "error", err)
+		h.apiError(w, http.StatusInternalServerError, "Failed to delete recipient", "INTERNAL_ERROR")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// apiRecipientList loads the recipient list of the request, writing an
+// error if it fails
+func (h *Handlers) apiRecipientList(w http.ResponseWriter, r *http.Request) (*models.RecipientList, bool) {
+	id := r.PathValue("id")
+	list, err := h.recipients.GetListByID(id)
+	if err != nil {
+		h.logger.Error("failed to get recipient list", "id", id, "error", err)
+		h.apiError(w, http.StatusInternalServerError, "Failed to get recipient list", "INTERNAL_ERROR")
+		return nil, false
+	}
+	if list == nil {
+		h.apiError(w, http.StatusNotFound, "Recipient list not found", "NOT_FOUND")
+		return nil, false
+	}
+	return list, true
+}
@@ -0,0 +1,281 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/foxzi/sendry/internal/web/middleware"
+	"github.com/foxzi/sendry/internal/web/models"
+	emailtpl "github.com/foxzi/sendry/internal/web/template"
+)
+
+// APITemplateRequest is the body of template create and update requests
+type APITemplateRequest struct {
+	Name          string `json:"name"`
+	Description   string `json:"description"`
+	Subject       string `json:"subject"`
+	HTML          string `json:"html"`
+	Text          string `json:"text"`
+	Variables     string `json:"variables"` // JSON
+	Folder        string `json:"folder"`
+	BlockExternal bool   `json:"block_external"`
+	ChangeNote    string `json:"change_note,omitempty"` // Note of the new version on update
+}
+
+// APIDeployRequest is the body of deploy requests
+type APIDeployRequest struct {
+	Servers []string `json:"servers"`
+	Version int      `json:"version,omitempty"` // Template version, the current one if 0
+}
+
+// APIDeployResult is the outcome of deploying to one server
+type APIDeployResult struct {
+	Server   string `json:"server"`
+	Status   string `json:"status"` // deployed, failed
+	RemoteID string `json:"remote_id,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// APIListTemplates handles GET /api/v1/templates
+func (h *Handlers) APIListTemplates(w http.ResponseWriter, r *http.Request) {
+	limit, offset := apiPage(r)
+	templates, total, err := h.templates.List(models.TemplateListFilter{
+		OrgID:  middleware.GetOrgID(r),
+		Search: r.URL.Query().Get("search"),
+		Folder: r.URL.Query().Get("folder"),
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		h.logger.Error("failed to list templates", "error", err)
+		h.apiError(w, http.StatusInternalServerError, "Failed to list templates", "INTERNAL_ERROR")
+		return
+	}
+	h.apiJSON(w, http.StatusOK, map[string]any{"templates": templates, "total": total})
+}
+
+// APICreateTemplate handles POST /api/v1/templates
+func (h *Handlers) APICreateTemplate(w http.ResponseWriter, r *http.Request) {
+	var req APITemplateRequest
+	if !h.decodeAPIJSON(w, r, &req) {
+		return
+	}
+
+	t := &models.Template{}
+	req.apply(t)
+	if t.Name == "" || t.Subject == "" {
+		h.apiError(w, http.StatusBadRequest, "Name and subject are required", "INVALID_REQUEST")
+		return
+	}
+	if !h.apiCheckPolicy(w, t) {
+		return
+	}
+
+	t.OrgID = middleware.GetOrgID(r)
+	if err := h.templates.Create(t, requestActor(r)); err != nil {
+		h.logger.Error("failed to create template", "error", err)
+		h.apiError(w, http.StatusInternalServerError, "Failed to create template", "INTERNAL_ERROR")
+		return
+	}
+
+	h.settings.LogAction(r, middleware.GetUserID(r), requestActor(r),
+		"create", "template", t.ID, auditJSON(map[string]any{"name": t.Name}))
+	h.apiJSON(w, http.StatusCreated, t)
+}
+
+// APIGetTemplate handles GET /api/v1/templates/{id}
+func (h *Handlers) APIGetTemplate(w http.ResponseWriter, r *http.Request) {
+	t, ok := h.apiTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	deployments, err := h.templates.GetDeployments(t.ID)
+	if err != nil {
+		h.logger.Error("failed to get deployments", "template_id", t.ID, "error", err)
+	}
+	h.apiJSON(w, http.StatusOK, map[string]any{"template": t, "deployments": deployments})
+}
+
+// APIUpdateTemplate handles PUT /api/v1/templates/{id}, saving a new version
+func (h *Handlers) APIUpdateTemplate(w http.ResponseWriter, r *http.Request) {
+	t, ok := h.apiTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	var req APITemplateRequest
+	if !h.decodeAPIJSON(w, r, &req) {
+		return
+	}
+	req.apply(t)
+	if t.Name == "" || t.Subject == "" {
+		h.apiError(w, http.StatusBadRequest, "Name and subject are required", "INVALID_REQUEST")
+		return
+	}
+	if !h.apiCheckPolicy(w, t) {
+		return
+	}
+
+	changeNote := req.ChangeNote
+	if changeNote == "" {
+		changeNote = "Updated template"
+	}
+	if err := h.templates.Update(t, changeNote, requestActor(r)); err != nil {
+		h.logger.Error("failed to update template", "error", err)
+		h.apiError(w, http.StatusInternalServerError, "Failed to update template", "INTERNAL_ERROR")
+		return
+	}
+
+	h.settings.LogAction(r, middleware.GetUserID(r), requestActor(r),
+		"update", "template", t.ID, auditJSON(map[string]any{"name": t.Name}))
+
+	// Reload for the new version number
+	if t, ok = h.apiTemplate(w, r); ok {
+		h.apiJSON(w, http.StatusOK, t)
+	}
+}
+
+// APIDeleteTemplate handles DELETE /api/v1/templates/{id}
+func (h *Handlers) APIDeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	t, ok := h.apiTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.templates.Delete(t.ID); err != nil {
+		h.logger.Error("failed to delete template", "error", err)
+		h.apiError(w, http.StatusInternalServerError, "Failed to delete template", "INTERNAL_ERROR")
+		return
+	}
+
+	h.settings.LogAction(r, middleware.GetUserID(r), requestActor(r),
+		"delete", "template", t.ID, "")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// APIDeployTemplate handles POST /api/v1/templates/{id}/deploy
+func (h *Handlers) APIDeployTemplate(w http.ResponseWriter, r *http.Request) {
+	t, ok := h.apiTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	var req APIDeployRequest
+	if !h.decodeAPIJSON(w, r, &req) {
+		return
+	}
+	if len(req.Servers) == 0 {
+		h.apiError(w, http.StatusBadRequest, "At least one server is required", "INVALID_REQUEST")
+		return
+	}
+	if !orgServersAllowed(r, req.Servers...) {
+		h.apiError(w, http.StatusNotFound, "Server not found", "NOT_FOUND")
+		return
+	}
+
+	// Deploy the current version unless another one is selected
+	version := t.CurrentVersion
+	if req.Version != 0 && req.Version != t.CurrentVersion {
+		v, err := h.templates.GetVersion(t.ID, req.Version)
+		if err != nil || v == nil {
+			h.apiError(w, http.StatusNotFound, "Template version not found", "NOT_FOUND")
+			return
+		}
+		version = v.Version
+		t.Subject, t.HTML, t.Text, t.Variables = v.Subject, v.HTML, v.Text, v.Variables
+	}
+
+	r = h.withDeployCorrelation(r)
+	h.logDeployAction(r, deployEntityTemplate, t.ID, req.Servers)
+
+	if violations := h.policyViolations(t); len(violations) > 0 {
+		for _, server := range req.Servers {
+			h.recordDeploy(r, deployEntityTemplate, t.ID, t.Name, server, policyError(violations))
+		}
+		h.apiPolicyViolations(w, violations)
+		return
+	}
+
+	results := h.deployTemplateTo(r, t, version, req.Servers)
+	h.apiJSON(w, deployStatus(results), map[string]any{"version": version, "results": results})
+}
+
+// deployTemplateTo deploys a version of a template to each of the servers,
+// carrying on after a server fails
+func (h *Handlers) deployTemplateTo(r *http.Request, t *models.Template, version int, servers []string) []APIDeployResult {
+	results := make([]APIDeployResult, 0, len(servers))
+	for _, server := range servers {
+		result := APIDeployResult{Server: server, Status: "deployed"}
+		client, err := h.sendry.GetClient(server)
+		if err == nil {
+			var deployment *models.TemplateDeployment
+			if deployment, err = h.deployTemplate(r, client, t, version, server); err == nil {
+				result.RemoteID = deployment.RemoteID
+			}
+		}
+		if err != nil {
+			result.Status, result.Error = "failed", err.Error()
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// apiTemplate loads the template of the request, writing an error if it fails
+func (h *Handlers) apiTemplate(w http.ResponseWriter, r *http.Request) (*models.Template, bool) {
+	id := r.PathValue("id")
+	t, err := h.templates.GetByID(id)
+	if err != nil {
+		h.logger.Error("failed to get template", "id", id, "error", err)
+		h.apiError(w, http.StatusInternalServerError, "Failed to get template", "INTERNAL_ERROR")
+		return nil, false
+	}
+	if t == nil {
+		h.apiError(w, http.StatusNotFound, "Template not found", "NOT_FOUND")
+		return nil, false
+	}
+	return t, true
+}
+
+// apiCheckPolicy checks a template against its content policy, writing the
+// violations if it breaks it
+func (h *Handlers) apiCheckPolicy(w http.ResponseWriter, t *models.Template) bool {
+	if violations := h.policyViolations(t); len(violations) > 0 {
+		h.logger.Warn("template blocked by content policy", "template_id", t.ID, "name", t.Name,
+			"violations", len(violations))
+		h.apiPolicyViolations(w, violations)
+		return false
+	}
+	return true
+}
+
+func (h *Handlers) apiPolicyViolations(w http.ResponseWriter, violations []emailtpl.Violation) {
+	h.apiJSON(w, http.StatusUnprocessableEntity, map[string]any{
+		"error":      policyError(violations).Error(),
+		"code":       "POLICY_VIOLATION",
+		"violations": violations,
+	})
+}
+
+// apply copies the fields of the request to a template
+func (req *APITemplateRequest) apply(t *models.Template) {
+	t.Name = req.Name
+	t.Description = req.Description
+	t.Subject = req.Subject
+	t.HTML = req.HTML
+	t.Text = req.Text
+	t.Variables = req.Variables
+	t.Folder = req.Folder
+	t.BlockExternal = req.BlockExternal
+}
+
+// deployStatus is the response status of a deploy to several servers: OK
+// if any server succeeded, Bad Gateway if all failed
+func deployStatus(results []APIDeployResult) int {
+	for _, r := range results {
+		if r.Status == "deployed" {
+			return http.StatusOK
+		}
+	}
+	return http.StatusBadGateway
+}
@@ -1,13 +1,16 @@
 package handlers
 
 import (
+	"database/sql"
 	"net/http"
 	"time"
 
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 
+	"github.com/foxzi/sendry/internal/web/auth"
 	"github.com/foxzi/sendry/internal/web/middleware"
+	"github.com/foxzi/sendry/internal/web/models"
 )
 
 // LoginPage renders the login page
@@ -22,6 +25,11 @@ func (h *Handlers) LoginPage(w http.ResponseWriter, r *http.Request) {
 
 // Login handles login form submission
 func (h *Handlers) Login(w http.ResponseWriter, r *http.Request) {
+	if !h.cfg.Auth.LocalEnabled {
+		h.renderLoginError(w, "Password login is disabled")
+		return
+	}
+
 	if err := r.ParseForm(); err != nil {
 		h.renderLoginError(w, "Invalid form data")
 		return
@@ -49,7 +57,7 @@ func (h *Handlers) Login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Create session
-	h.createSession(w, userID, email)
+	h.createSession(w, r, userID, email, sessionLocal)
 	h.settings.LogAction(r, userID, email, "login", "user", userID, "")
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
@@ -160,37 +168,72 @@ func (h *Handlers) OIDCCallback(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Find or create user
-	var userID string
-	err = h.db.QueryRow("SELECT id FROM users WHERE email = ?", userInfo.Email).Scan(&userID)
+	userID, err := h.oidcUser(userInfo)
 	if err != nil {
-		// Create new user from OIDC
+		h.logger.Error("failed to create OIDC user", "error", err)
+		h.renderLoginError(w, "Failed to create user")
+		return
+	}
+
+	// Create session
+	h.createSession(w, r, userID, userInfo.Email, sessionOIDC)
+	h.settings.LogAction(r, userID, userInfo.Email, "login", "user", userID, "oidc")
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// oidcUser finds or creates the user of an OIDC login. With group_roles the
+// role follows the groups of the provider on every login.
+func (h *Handlers) oidcUser(info *auth.UserInfo) (string, error) {
+	var userID, role string
+	err := h.db.QueryRow("SELECT id, role FROM users WHERE email = ?", info.Email).Scan(&userID, &role)
+	if err == sql.ErrNoRows {
 		userID = uuid.New().String()
+		role = info.Role
+		if role == "" {
+			role = string(models.RoleUser)
+		}
+		// An empty password hash never matches, so OIDC users cannot log
+		// in with a password
 		_, err = h.db.Exec(
-			"INSERT INTO users (id, email, name, created_at, updated_at) VALUES (?, ?, ?, datetime('now'), datetime('now'))",
-			userID, userInfo.Email, userInfo.Name,
+			"INSERT INTO users (id, email, password_hash, name, role, created_at, updated_at) VALUES (?, ?, '', ?, ?, datetime('now'), datetime('now'))",
+			userID, info.Email, info.Name, role,
 		)
 		if err != nil {
-			h.logger.Error("failed to create OIDC user", "error", err)
-			h.renderLoginError(w, "Failed to create user")
-			return
+			return "", err
+		}
+		if err := h.orgs.JoinSole(userID); err != nil {
+			h.logger.Error("failed to add OIDC user to organization", "email", info.Email, "error", err)
 		}
-		h.logger.Info("created OIDC user", "email", userInfo.Email)
+		h.logger.Info("created OIDC user", "email", info.Email, "role", role)
+		return userID, nil
+	}
+	if err != nil {
+		return "", err
 	}
 
-	// Create session
-	h.createSession(w, userID, userInfo.Email)
-	h.settings.LogAction(r, userID, userInfo.Email, "login", "user", userID, "oidc")
-	http.Redirect(w, r, "/", http.StatusSeeOther)
+	if info.Role != "" && info.Role != role {
+		if _, err := h.db.Exec("UPDATE users SET role = ?, updated_at = datetime('now') WHERE id = ?", info.Role, userID); err != nil {
+			return "", err
+		}
+		h.logger.Info("updated OIDC user role", "email", info.Email, "old", role, "new", info.Role)
+	}
+	return userID, nil
 }
 
-func (h *Handlers) createSession(w http.ResponseWriter, userID, email string) {
+// Session authentication methods
+const (
+	sessionLocal = "local"
+	sessionOIDC  = "oidc"
+)
+
+func (h *Handlers) createSession(w http.ResponseWriter, r *http.Request, userID, email, method string) {
 	sessionID := uuid.New().String()
 	expiresAt := time.Now().Add(h.cfg.Auth.SessionTTL)
 
+	// The client is shown in the session list of the profile page
 	_, err := h.db.Exec(
-		"INSERT INTO sessions (id, user_id, expires_at) VALUES (?, ?, ?)",
-		sessionID, userID, expiresAt,
+		"INSERT INTO sessions (id, user_id, expires_at, auth_method, ip, user_agent, last_seen_at) VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)",
+		sessionID, userID, expiresAt, method, middleware.ClientIP(r), truncateUserAgent(r.UserAgent()),
 	)
 	if err != nil {
 		h.logger.Error("failed to create session", "error", err, "email", email)
@@ -218,3 +261,11 @@ func (h *Handlers) renderLoginError(w http.ResponseWriter, message string) {
 	}
 	h.render(w, "login", data)
 }
+
+// truncateUserAgent limits the stored User-Agent of a session
+func truncateUserAgent(ua string) string {
+	if len(ua) > 256 {
+		return ua[:256]
+	}
+	return ua
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/foxzi/sendry/internal/web/auth"
+	"github.com/foxzi/sendry/internal/web/models"
+)
+
+func TestLoginLocalDisabled(t *testing.T) {
+	h, _, cleanup := newDomainViewTestHandlers(t)
+	defer cleanup()
+	h.cfg.Auth.SessionTTL = time.Hour
+
+	if _, err := h.settings.CreateUser("ann@example.com", "Ann", "secret-password", models.RoleUser); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+
+	login := func() *httptest.ResponseRecorder {
+		form := url.Values{"email": {"ann@example.com"}, "password": {"secret-password"}}
+		req := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		h.Login(w, req)
+		return w
+	}
+
+	h.cfg.Auth.LocalEnabled = true
+	if w := login(); w.Code != http.StatusSeeOther {
+		t.Fatalf("login status = %d, want 303", w.Code)
+	}
+
+	h.cfg.Auth.LocalEnabled = false
+	w := login()
+	if w.Code == http.StatusSeeOther || !strings.Contains(w.Body.String(), "Password login is disabled") {
+		t.Errorf("login with local disabled = %d, body: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestOIDCUserRole(t *testing.T) {
+	h, database, cleanup := newDomainViewTestHandlers(t)
+	defer cleanup()
+
+	role := func(id string) string {
+		var r string
+		if err := database.QueryRow("SELECT role FROM users WHERE id = ?", id).Scan(&r); err != nil {
+			t.Fatalf("role query error = %v", err)
+		}
+		return r
+	}
+
+	// Without a mapping new users are users and roles are kept
+	id, err := h.oidcUser(&auth.UserInfo{Email: "ann@example.com", Name: "Ann"})
+	if err != nil || role(id) != "user" {
+		t.Fatalf("oidcUser() = %q, %v, role %q", id, err, role(id))
+	}
+	if _, err := database.Exec("UPDATE users SET role = 'admin' WHERE id = ?", id); err != nil {
+		t.Fatal(err)
+	}
+	again, err := h.oidcUser(&auth.UserInfo{Email: "ann@example.com"})
+	if err != nil || again != id || role(id) != "admin" {
+		t.Errorf("second login = %q, %v, role %q, want the same admin user", again, err, role(id))
+	}
+
+	// A mapped role follows the groups
+	if _, err := h.oidcUser(&auth.UserInfo{Email: "ann@example.com", Role: "user"}); err != nil || role(id) != "user" {
+		t.Errorf("mapped login role = %q, %v, want user", role(id), err)
+	}
+	bob, err := h.oidcUser(&auth.UserInfo{Email: "bob@example.com", Role: "admin"})
+	if err != nil || role(bob) != "admin" {
+		t.Errorf("new mapped user role = %q, %v, want admin", role(bob), err)
+	}
+}
@@ -39,6 +39,8 @@ func (h *Handlers) BuilderPage(w http.ResponseWriter, r *http.Request) {
 		"Folders":    folders,
 		"Wrapper":    wrapper,
 		"Categories": categories,
+
+		"GlobalContentPolicy": h.cfg.ContentPolicy.BlockExternal,
 	}
 
 	if id := r.PathValue("id"); id != "" {
@@ -116,6 +118,7 @@ func (h *Handlers) BuilderUpdate(w http.ResponseWriter, r *http.Request) {
 	t.ContainerPaddingV = cs.PaddingV
 	t.ContainerPaddingH = cs.PaddingH
 	t.PageBackground = cs.PageBG
+	t.BlockExternal = r.FormValue("block_external") == "on"
 
 	blockRefs := parseBlockRefs(r.FormValue("block_refs"))
 	t.UseBlocks = len(blockRefs) > 0
@@ -140,6 +143,11 @@ func (h *Handlers) BuilderUpdate(w http.ResponseWriter, r *http.Request) {
 	t.HTML = html
 	t.Text = text
 
+	if violations := h.policyViolations(t); len(violations) > 0 {
+		h.renderPolicyViolations(w, r, t, "save", violations)
+		return
+	}
+
 	user := h.getUserFromContext(r)
 	if err := h.templates.Update(t, "Edited via builder", user["Email"].(string)); err != nil {
 		h.logger.Error("failed to update template via builder", "error", err)
@@ -200,9 +208,16 @@ func (h *Handlers) BuilderCreate(w http.ResponseWriter, r *http.Request) {
 		ContainerPaddingV:    cs.PaddingV,
 		ContainerPaddingH:    cs.PaddingH,
 		PageBackground:       cs.PageBG,
+		BlockExternal:        r.FormValue("block_external") == "on",
+	}
+
+	if violations := h.policyViolations(t); len(violations) > 0 {
+		h.renderPolicyViolations(w, r, t, "save", violations)
+		return
 	}
 
 	user := h.getUserFromContext(r)
+	t.OrgID = middleware.GetOrgID(r)
 	if err := h.templates.Create(t, user["Email"].(string)); err != nil {
 		h.logger.Error("failed to create template from builder", "error", err)
 		h.error(w, http.StatusInternalServerError, "Failed to create template")
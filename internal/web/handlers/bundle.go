@@ -0,0 +1,275 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/foxzi/sendry/internal/web/bundle"
+	"github.com/foxzi/sendry/internal/web/middleware"
+	"github.com/foxzi/sendry/internal/web/models"
+)
+
+// maxBundleSize limits uploaded domain bundles
+const maxBundleSize = 10 << 20
+
+// bundleTemplate is a bundled template with its import state
+type bundleTemplate struct {
+	bundle.Template
+	Exists     bool
+	Violations int
+}
+
+// DomainBundleExport downloads a domain with its DKIM key, templates and
+// DNS records as a bundle
+func (h *Handlers) DomainBundleExport(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	if err := r.ParseForm(); err != nil {
+		h.error(w, http.StatusBadRequest, "Invalid form data")
+		return
+	}
+
+	domain, err := h.domains.GetByID(id)
+	if err != nil || domain == nil {
+		h.error(w, http.StatusNotFound, "Domain not found")
+		return
+	}
+	if domain.DKIMKeyID != "" {
+		domain.DKIMKey, _ = h.dkim.GetByID(domain.DKIMKeyID)
+	}
+
+	templates, err := h.templates.GetTemplatesBySenderDomain(domain.Domain)
+	if err != nil {
+		h.logger.Error("failed to load domain templates", "error", err)
+		h.error(w, http.StatusInternalServerError, "Failed to load templates")
+		return
+	}
+
+	globalVars, err := h.settings.GetGlobalVariablesMap()
+	if err != nil {
+		h.logger.Error("failed to load global variables", "error", err)
+	}
+
+	b, err := bundle.New(domain, templates, globalVars["spf_include"], r.FormValue("passphrase"), time.Now())
+	if err != nil {
+		h.error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	h.settings.LogAction(r, middleware.GetUserID(r), middleware.GetUserEmail(r),
+		"export", "domain", domain.ID, auditJSON(map[string]any{
+			"domain":    domain.Domain,
+			"dkim":      b.DKIM != nil,
+			"templates": len(b.Templates),
+		}))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", "attachment; filename=\""+sanitizeFilename(b.FileName())+"\"")
+	if err := b.Write(w); err != nil {
+		h.logger.Error("failed to encode domain bundle", "error", err)
+	}
+}
+
+// DomainBundleImportPage shows the bundle upload form
+func (h *Handlers) DomainBundleImportPage(w http.ResponseWriter, r *http.Request) {
+	data := map[string]any{
+		"Title":  "Import Domain Bundle",
+		"Active": "domains",
+		"User":   h.getUserFromContext(r),
+	}
+	h.render(w, "domain_bundle_import", data)
+}
+
+// DomainBundlePreview shows what importing an uploaded bundle will create
+func (h *Handlers) DomainBundlePreview(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(maxBundleSize); err != nil {
+		h.error(w, http.StatusBadRequest, "Invalid form data")
+		return
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		h.error(w, http.StatusBadRequest, "No bundle file provided")
+		return
+	}
+	defer file.Close()
+
+	b, err := bundle.Read(file)
+	if err != nil {
+		h.error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	passphrase := r.FormValue("passphrase")
+	if _, err := b.PrivateKey(passphrase); err != nil {
+		h.error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	existing, _ := h.domains.GetByDomain(b.Domain.Domain)
+
+	var existingKey *models.DKIMKey
+	if b.DKIM != nil {
+		existingKey, _ = h.dkim.GetByDomainSelector(b.Domain.Domain, b.DKIM.Selector)
+	}
+
+	templates := make([]bundleTemplate, 0, len(b.Templates))
+	for _, t := range b.Templates {
+		found, _ := h.templates.GetByName(t.Name)
+		templates = append(templates, bundleTemplate{
+			Template:   t,
+			Exists:     found != nil,
+			Violations: len(h.policyViolations(bundledTemplate(t))),
+		})
+	}
+
+	var encoded bytes.Buffer
+	if err := b.Write(&encoded); err != nil {
+		h.error(w, http.StatusInternalServerError, "Failed to encode bundle")
+		return
+	}
+
+	data := map[string]any{
+		"Title":          "Import Domain: " + b.Domain.Domain,
+		"Active":         "domains",
+		"User":           h.getUserFromContext(r),
+		"Bundle":         b,
+		"BundleJSON":     encoded.String(),
+		"Passphrase":     passphrase,
+		"ExistingDomain": existing,
+		"ExistingKey":    existingKey,
+		"Templates":      templates,
+		"Servers":        h.getServersStatus(r),
+	}
+	h.render(w, "domain_bundle_preview", data)
+}
+
+// DomainBundleImport creates the domain, DKIM key and selected templates
+// of a previewed bundle and deploys the domain to the selected servers
+func (h *Handlers) DomainBundleImport(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 2*maxBundleSize)
+	if err := r.ParseForm(); err != nil {
+		h.error(w, http.StatusBadRequest, "Invalid form data")
+		return
+	}
+
+	b, err := bundle.Read(strings.NewReader(r.FormValue("bundle")))
+	if err != nil {
+		h.error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	privateKey, err := b.PrivateKey(r.FormValue("passphrase"))
+	if err != nil {
+		h.error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if existing, _ := h.domains.GetByDomain(b.Domain.Domain); existing != nil {
+		h.error(w, http.StatusConflict, "Domain already exists")
+		return
+	}
+
+	domain := &models.Domain{
+		Domain:              b.Domain.Domain,
+		Mode:                b.Domain.Mode,
+		DefaultFrom:         b.Domain.DefaultFrom,
+		DKIMEnabled:         b.Domain.DKIMEnabled,
+		DKIMSelector:        b.Domain.DKIMSelector,
+		RateLimitHour:       b.Domain.RateLimitHour,
+		RateLimitDay:        b.Domain.RateLimitDay,
+		RateLimitRecipients: b.Domain.RateLimitRecipients,
+		RedirectTo:          b.Domain.RedirectTo,
+		BCCTo:               b.Domain.BCCTo,
+	}
+
+	// An existing key for the same selector is reused
+	if b.DKIM != nil {
+		key, _ := h.dkim.GetByDomainSelector(b.Domain.Domain, b.DKIM.Selector)
+		if key == nil {
+			key = &models.DKIMKey{
+				Domain:     b.Domain.Domain,
+				Selector:   b.DKIM.Selector,
+				PrivateKey: privateKey,
+				DNSRecord:  b.DKIM.DNSRecord,
+			}
+			key.OrgID = middleware.GetOrgID(r)
+			if err := h.dkim.Create(key); err != nil {
+				h.logger.Error("failed to create DKIM key", "error", err)
+				h.error(w, http.StatusInternalServerError, "Failed to import DKIM key")
+				return
+			}
+		}
+		domain.DKIMKeyID = key.ID
+	}
+
+	domain.OrgID = middleware.GetOrgID(r)
+	if err := h.domains.Create(domain); err != nil {
+		h.logger.Error("failed to create domain", "error", err)
+		h.error(w, http.StatusInternalServerError, "Failed to import domain")
+		return
+	}
+
+	selected := make(map[string]bool)
+	for _, name := range r.Form["templates"] {
+		selected[name] = true
+	}
+	userEmail := middleware.GetUserEmail(r)
+	var imported, skipped []string
+	for _, bt := range b.Templates {
+		if !selected[bt.Name] {
+			continue
+		}
+		t := bundledTemplate(bt)
+		if found, _ := h.templates.GetByName(t.Name); found != nil || len(h.policyViolations(t)) > 0 {
+			skipped = append(skipped, t.Name)
+			continue
+		}
+		t.OrgID = middleware.GetOrgID(r)
+		if err := h.templates.Create(t, userEmail); err != nil {
+			h.logger.Error("failed to import template", "name", t.Name, "error", err)
+			skipped = append(skipped, t.Name)
+			continue
+		}
+		imported = append(imported, t.Name)
+	}
+	if len(skipped) > 0 {
+		h.logger.Warn("bundle templates skipped", "domain", domain.Domain, "templates", skipped)
+	}
+
+	h.logger.Info("domain bundle imported", "id", domain.ID, "domain", domain.Domain, "user", userEmail)
+	h.settings.LogAction(r, middleware.GetUserID(r), userEmail,
+		"import", "domain", domain.ID, auditJSON(map[string]any{
+			"domain":    domain.Domain,
+			"source":    "bundle",
+			"dkim":      domain.DKIMKeyID != "",
+			"templates": imported,
+			"skipped":   skipped,
+		}))
+
+	if servers := r.Form["servers"]; len(servers) > 0 && orgServersAllowed(r, servers...) {
+		r = h.withDeployCorrelation(r)
+		h.logDeployAction(r, deployEntityDomain, domain.ID, servers)
+		for _, srvName := range servers {
+			h.deployDomainToServer(r, domain, srvName)
+		}
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/domains/%s", domain.ID), http.StatusSeeOther)
+}
+
+// bundledTemplate returns the template to create for a bundled template
+func bundledTemplate(t bundle.Template) *models.Template {
+	return &models.Template{
+		Name:          t.Name,
+		Description:   t.Description,
+		Subject:       t.Subject,
+		HTML:          t.HTML,
+		Text:          t.Text,
+		Variables:     t.Variables,
+		Folder:        t.Folder,
+		BlockExternal: t.BlockExternal,
+	}
+}
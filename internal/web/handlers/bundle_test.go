@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/foxzi/sendry/internal/web/models"
+	"github.com/foxzi/sendry/internal/web/repository"
+)
+
+func TestDomainBundleExportImport(t *testing.T) {
+	src, srcDB, cleanup := newDomainViewTestHandlers(t)
+	defer cleanup()
+
+	key := &models.DKIMKey{Domain: "brand.example", Selector: "s1", PrivateKey: "PRIVATE-KEY", DNSRecord: "v=DKIM1; k=rsa; p=PUBLIC"}
+	if err := repository.NewDKIMRepository(srcDB.DB).Create(key); err != nil {
+		t.Fatal(err)
+	}
+	domain := &models.Domain{Domain: "brand.example", Mode: "production", DKIMEnabled: true, DKIMSelector: "s1", DKIMKeyID: key.ID, RateLimitDay: 500}
+	if err := repository.NewDomainRepository(srcDB.DB).Create(domain); err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &models.Template{Name: "Welcome", Subject: "Hi", HTML: "<p>Hi</p>"}
+	if err := repository.NewTemplateRepository(srcDB.DB).Create(tmpl, "test@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	campaigns := repository.NewCampaignRepository(srcDB.DB)
+	campaign := &models.Campaign{Name: "Onboarding", FromEmail: "hello@brand.example"}
+	if err := campaigns.Create(campaign); err != nil {
+		t.Fatal(err)
+	}
+	if err := campaigns.AddVariant(&models.CampaignVariant{CampaignID: campaign.ID, Name: "A", TemplateID: tmpl.ID, Weight: 100}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A DKIM key is only exported with a passphrase
+	req := httptest.NewRequest(http.MethodPost, "/domains/"+domain.ID+"/bundle", nil)
+	req.SetPathValue("id", domain.ID)
+	w := httptest.NewRecorder()
+	src.DomainBundleExport(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("export without passphrase status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	form := url.Values{"passphrase": {"bundle-secret"}}
+	req = httptest.NewRequest(http.MethodPost, "/domains/"+domain.ID+"/bundle", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetPathValue("id", domain.ID)
+	w = httptest.NewRecorder()
+	src.DomainBundleExport(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("export status = %d, body = %s", w.Code, w.Body.String())
+	}
+	exported := w.Body.String()
+	if strings.Contains(exported, "PRIVATE-KEY") {
+		t.Fatal("bundle contains the plain DKIM private key")
+	}
+
+	dst, dstDB, cleanup := newDomainViewTestHandlers(t)
+	defer cleanup()
+
+	// Preview decrypts the key and lists what will be created
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, _ := mw.CreateFormFile("file", "brand.example.sendry-bundle.json")
+	fw.Write([]byte(exported))
+	mw.WriteField("passphrase", "bundle-secret")
+	mw.Close()
+	req = httptest.NewRequest(http.MethodPost, "/domains/bundle/preview", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w = httptest.NewRecorder()
+	dst.DomainBundlePreview(w, req)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Welcome") {
+		t.Fatalf("preview status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	form = url.Values{"bundle": {exported}, "passphrase": {"wrong-secret"}, "templates": {"Welcome"}}
+	req = httptest.NewRequest(http.MethodPost, "/domains/bundle/import", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	dst.DomainBundleImport(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("import with wrong passphrase status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	form.Set("passphrase", "bundle-secret")
+	req = httptest.NewRequest(http.MethodPost, "/domains/bundle/import", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	dst.DomainBundleImport(w, req)
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("import status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	got, err := repository.NewDomainRepository(dstDB.DB).GetByDomain("brand.example")
+	if err != nil || got == nil {
+		t.Fatalf("imported domain = %v, %v", got, err)
+	}
+	if got.RateLimitDay != 500 || !got.DKIMEnabled || got.DKIMKeyID == "" {
+		t.Errorf("imported domain = %+v", got)
+	}
+	gotKey, _ := repository.NewDKIMRepository(dstDB.DB).GetByID(got.DKIMKeyID)
+	if gotKey == nil || gotKey.PrivateKey != "PRIVATE-KEY" || gotKey.Selector != "s1" {
+		t.Errorf("imported DKIM key = %+v", gotKey)
+	}
+	if tmpl, _ := repository.NewTemplateRepository(dstDB.DB).GetByName("Welcome"); tmpl == nil {
+		t.Error("template was not imported")
+	}
+
+	// The domain cannot be imported twice
+	req = httptest.NewRequest(http.MethodPost, "/domains/bundle/import", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	dst.DomainBundleImport(w, req)
+	if w.Code != http.StatusConflict {
+		t.Errorf("second import status = %d, want %d", w.Code, http.StatusConflict)
+	}
+}
@@ -2,12 +2,15 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/foxzi/sendry/internal/web/middleware"
 	"github.com/foxzi/sendry/internal/web/models"
+	"github.com/foxzi/sendry/internal/web/worker"
 )
 
 func (h *Handlers) CampaignList(w http.ResponseWriter, r *http.Request) {
@@ -20,6 +23,7 @@ func (h *Handlers) CampaignList(w http.ResponseWriter, r *http.Request) {
 	offset := (page - 1) * limit
 
 	filter := models.CampaignListFilter{
+		OrgID:  middleware.GetOrgID(r),
 		Search: search,
 		Limit:  limit,
 		Offset: offset,
@@ -79,6 +83,7 @@ func (h *Handlers) CampaignCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	c.OrgID = middleware.GetOrgID(r)
 	if err := h.campaigns.Create(c); err != nil {
 		h.logger.Error("failed to create campaign", "error", err)
 		h.error(w, http.StatusInternalServerError, "Failed to create campaign")
@@ -110,7 +115,17 @@ func (h *Handlers) CampaignView(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get recipient lists for send page
-	recipientLists, _, _ := h.recipients.ListLists(models.RecipientListFilter{Limit: 100})
+	recipientLists, _, _ := h.recipients.ListLists(models.RecipientListFilter{OrgID: middleware.GetOrgID(r), Limit: 100})
+
+	engagement, err := h.tracking.CampaignStats(id)
+	if err != nil {
+		h.logger.Error("failed to get engagement stats", "error", err)
+	}
+
+	deliverability, err := h.jobs.CampaignDeliverability(id)
+	if err != nil {
+		h.logger.Error("failed to get deliverability report", "error", err)
+	}
 
 	data := map[string]any{
 		"Title":          c.Name,
@@ -119,12 +134,40 @@ func (h *Handlers) CampaignView(w http.ResponseWriter, r *http.Request) {
 		"Campaign":       c,
 		"Variants":       variants,
 		"RecipientLists": recipientLists,
-		"Servers":        h.cfg.Sendry.Servers,
+		"Servers":        h.orgServers(r),
+		"Tracking":       h.cfg.Tracking.Enabled,
+		"Engagement":     engagement,
+		"Deliverability": deliverability,
 	}
 
 	h.render(w, "campaign_view", data)
 }
 
+// CampaignDeliverability returns the delivery report of a campaign as JSON
+func (h *Handlers) CampaignDeliverability(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	c, err := h.campaigns.GetByID(id)
+	if err != nil || c == nil {
+		h.json(w, http.StatusNotFound, map[string]any{"error": "campaign not found"})
+		return
+	}
+
+	report, err := h.jobs.CampaignDeliverability(id)
+	if err != nil {
+		h.logger.Error("failed to get deliverability report", "error", err)
+		h.json(w, http.StatusInternalServerError, map[string]any{"error": "failed to get deliverability report"})
+		return
+	}
+
+	h.json(w, http.StatusOK, map[string]any{
+		"campaign_id":   id,
+		"report":        report,
+		"delivery_rate": report.DeliveryRate(),
+		"bounce_rate":   report.BounceRate(),
+	})
+}
+
 func (h *Handlers) CampaignEdit(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
 
@@ -248,7 +291,7 @@ func (h *Handlers) CampaignVariants(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get templates for selection
-	templateList, _, _ := h.templates.List(models.TemplateListFilter{Limit: 100})
+	templateList, _, _ := h.templates.List(models.TemplateListFilter{OrgID: middleware.GetOrgID(r), Limit: 100})
 
 	data := map[string]any{
 		"Title":     c.Name + " - Variants",
@@ -287,6 +330,10 @@ func (h *Handlers) CampaignVariantCreate(w http.ResponseWriter, r *http.Request)
 		h.error(w, http.StatusBadRequest, "Name and template are required")
 		return
 	}
+	if t, err := h.templates.GetByID(v.TemplateID); err != nil || t == nil || !orgOwns(r, t.OrgID) {
+		h.error(w, http.StatusBadRequest, "Template not found")
+		return
+	}
 
 	if err := h.campaigns.AddVariant(v); err != nil {
 		h.logger.Error("failed to add variant", "error", err)
@@ -320,7 +367,16 @@ func (h *Handlers) CampaignSendPage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	variants, _ := h.campaigns.GetVariants(id)
-	recipientLists, _, _ := h.recipients.ListLists(models.RecipientListFilter{Limit: 100})
+	recipientLists, _, _ := h.recipients.ListLists(models.RecipientListFilter{OrgID: middleware.GetOrgID(r), Limit: 100})
+	segments, err := h.recipients.ListAllSegments(middleware.GetOrgID(r))
+	if err != nil {
+		h.logger.Error("failed to list segments", "error", err)
+	}
+
+	freeze, err := h.activeFreeze(c)
+	if err != nil {
+		h.logger.Error("failed to check freeze windows", "error", err)
+	}
 
 	data := map[string]any{
 		"Title":          "Send " + c.Name,
@@ -329,7 +385,9 @@ func (h *Handlers) CampaignSendPage(w http.ResponseWriter, r *http.Request) {
 		"Campaign":       c,
 		"Variants":       variants,
 		"RecipientLists": recipientLists,
-		"Servers":        h.cfg.Sendry.Servers,
+		"Segments":       segments,
+		"Servers":        h.orgServers(r),
+		"Freeze":         freeze,
 	}
 
 	h.render(w, "campaign_send", data)
@@ -349,35 +407,110 @@ func (h *Handlers) CampaignSend(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	recipientListID := r.FormValue("recipient_list_id")
-	if recipientListID == "" {
-		h.error(w, http.StatusBadRequest, "Recipient list is required")
+	// Handle dry-run mode
+	dryRun := r.FormValue("dry_run") == "on"
+	dryRunLimit := 0
+	if dryRun {
+		dryRunLimit, _ = strconv.Atoi(r.FormValue("dry_run_limit"))
+	}
+
+	throttle, err := throttleFromForm(r)
+	if err != nil {
+		h.error(w, http.StatusBadRequest, "Invalid sending rate: "+err.Error())
 		return
 	}
 
-	servers := r.Form["servers"]
-	if len(servers) == 0 {
-		h.error(w, http.StatusBadRequest, "At least one server is required")
+	send := campaignSend{
+		RecipientListID: r.FormValue("recipient_list_id"),
+		SegmentID:       r.FormValue("segment_id"),
+		Servers:         r.Form["servers"],
+		Strategy:        r.FormValue("strategy"),
+		DryRun:          dryRun,
+		DryRunLimit:     dryRunLimit,
+		Throttle:        throttle,
+		FreezeOverride:  r.FormValue("freeze_override") == "on" && middleware.IsAdmin(r),
+	}
+
+	// Handle scheduled_at
+	if scheduledAt := r.FormValue("scheduled_at"); scheduledAt != "" {
+		t, err := time.Parse("2006-01-02T15:04", scheduledAt)
+		if err == nil {
+			send.ScheduledAt = &t
+		}
+	}
+
+	job, aerr := h.startCampaign(r, c, send)
+	if aerr != nil {
+		h.error(w, aerr.status, aerr.message)
 		return
 	}
 
-	strategy := r.FormValue("strategy")
+	http.Redirect(w, r, "/jobs/"+job.ID, http.StatusSeeOther)
+}
+
+// campaignSend selects the recipients, servers and pace of a campaign send
+type campaignSend struct {
+	RecipientListID string
+	SegmentID       string // Optional subset of the list
+	Servers         []string
+	Strategy        string
+	DryRun          bool
+	DryRunLimit     int
+	Throttle        string // JSON JobThrottle, empty for no limits
+	ScheduledAt     *time.Time
+	FreezeOverride  bool // Start during a freeze window, admins only
+}
+
+// startCampaign creates the send job of a campaign with an item for every
+// active recipient; the job starts right away unless it is scheduled
+func (h *Handlers) startCampaign(r *http.Request, c *models.Campaign, send campaignSend) (*models.SendJob, *actionError) {
+	if send.RecipientListID == "" {
+		return nil, &actionError{http.StatusBadRequest, "INVALID_REQUEST", "Recipient list is required"}
+	}
+	if list, err := h.recipients.GetListByID(send.RecipientListID); err != nil || list == nil || !orgOwns(r, list.OrgID) {
+		return nil, &actionError{http.StatusNotFound, "NOT_FOUND", "Recipient list not found"}
+	}
+
+	// An optional segment selects a subset of the list
+	var rules *models.SegmentRules
+	if send.SegmentID != "" {
+		segment, err := h.recipients.GetSegment(send.SegmentID)
+		if err != nil || segment == nil {
+			return nil, &actionError{http.StatusBadRequest, "INVALID_REQUEST", "Segment not found"}
+		}
+		if segment.ListID != send.RecipientListID {
+			return nil, &actionError{http.StatusBadRequest, "INVALID_REQUEST", "Segment does not belong to the recipient list"}
+		}
+		if rules, err = models.ParseSegmentRules(segment.Rules); err != nil {
+			return nil, &actionError{http.StatusBadRequest, "INVALID_REQUEST", "Invalid segment rules: " + err.Error()}
+		}
+	}
+
+	servers := send.Servers
+	if len(servers) == 0 {
+		return nil, &actionError{http.StatusBadRequest, "INVALID_REQUEST", "At least one server is required"}
+	}
+	if !orgServersAllowed(r, servers...) {
+		return nil, &actionError{http.StatusNotFound, "NOT_FOUND", "Server not found"}
+	}
+
+	strategy := send.Strategy
 	if strategy == "" {
-		strategy = "round-robin"
+		strategy = models.StrategyRoundRobin
+	}
+	if !models.ValidStrategy(strategy) {
+		return nil, &actionError{http.StatusBadRequest, "INVALID_REQUEST", "Unknown distribution strategy: " + strategy}
 	}
 
 	// Get variants
-	variants, err := h.campaigns.GetVariants(id)
+	variants, err := h.campaigns.GetVariants(c.ID)
 	if err != nil || len(variants) == 0 {
-		h.error(w, http.StatusBadRequest, "Campaign has no variants configured")
-		return
+		return nil, &actionError{http.StatusBadRequest, "NO_VARIANTS", "Campaign has no variants configured"}
 	}
 
-	// Handle dry-run mode
-	dryRun := r.FormValue("dry_run") == "on"
 	dryRunLimit := 0
-	if dryRun {
-		dryRunLimit, _ = strconv.Atoi(r.FormValue("dry_run_limit"))
+	if send.DryRun {
+		dryRunLimit = send.DryRunLimit
 		if dryRunLimit <= 0 {
 			dryRunLimit = 10 // default
 		}
@@ -388,44 +521,58 @@ func (h *Handlers) CampaignSend(w http.ResponseWriter, r *http.Request) {
 
 	// Create job
 	job := &models.SendJob{
-		CampaignID:      id,
-		RecipientListID: recipientListID,
+		CampaignID:      c.ID,
+		RecipientListID: send.RecipientListID,
+		SegmentID:       send.SegmentID,
 		Servers:         string(serversJSON),
 		Strategy:        strategy,
-		DryRun:          dryRun,
+		DryRun:          send.DryRun,
 		DryRunLimit:     dryRunLimit,
+		Throttle:        send.Throttle,
+	}
+	if send.ScheduledAt != nil {
+		job.ScheduledAt = send.ScheduledAt
+		job.Status = "scheduled"
 	}
 
-	// Handle scheduled_at
-	if scheduledAt := r.FormValue("scheduled_at"); scheduledAt != "" {
-		t, err := time.Parse("2006-01-02T15:04", scheduledAt)
-		if err == nil {
-			job.ScheduledAt = &t
-			job.Status = "scheduled"
+	// Jobs cannot start during a freeze window unless an admin overrides
+	// it; scheduled jobs are held by the worker until the window ends
+	var overridden *models.FreezeWindow
+	if job.ScheduledAt == nil {
+		f, err := h.activeFreeze(c)
+		if err != nil {
+			h.logger.Error("failed to check freeze windows", "error", err)
+			return nil, &actionError{http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to check freeze windows"}
+		}
+		if f != nil {
+			if !send.FreezeOverride {
+				return nil, &actionError{http.StatusConflict, "FROZEN", freezeMessage(f)}
+			}
+			job.FreezeOverride = true
+			overridden = f
 		}
 	}
 
 	if err := h.jobs.Create(job); err != nil {
 		h.logger.Error("failed to create job", "error", err)
-		h.error(w, http.StatusInternalServerError, "Failed to create job")
-		return
+		return nil, &actionError{http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to create job"}
 	}
 
 	// Get recipients
 	recipientLimit := 100000 // Get all active recipients
-	if dryRun && dryRunLimit > 0 {
+	if send.DryRun && dryRunLimit > 0 {
 		recipientLimit = dryRunLimit
 	}
 
 	recipients, _, err := h.recipients.ListRecipients(models.RecipientFilter{
-		ListID: recipientListID,
+		ListID: send.RecipientListID,
 		Status: "active",
+		Rules:  rules,
 		Limit:  recipientLimit,
 	})
 	if err != nil {
 		h.logger.Error("failed to get recipients", "error", err)
-		h.error(w, http.StatusInternalServerError, "Failed to get recipients")
-		return
+		return nil, &actionError{http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to get recipients"}
 	}
 
 	// Create job items
@@ -452,19 +599,60 @@ func (h *Handlers) CampaignSend(w http.ResponseWriter, r *http.Request) {
 
 	if err := h.jobs.CreateItems(items); err != nil {
 		h.logger.Error("failed to create job items", "error", err)
-		h.error(w, http.StatusInternalServerError, "Failed to create job items")
-		return
+		return nil, &actionError{http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to create job items"}
 	}
 
 	// Start job if not scheduled
 	if job.ScheduledAt == nil {
 		h.jobs.UpdateStatus(job.ID, "running")
+		job.Status = "running"
 		// TODO: Start background worker to process items
 	}
 
-	h.settings.LogAction(r, middleware.GetUserID(r), middleware.GetUserEmail(r),
-		"send", "campaign", id, `{"job_id":"`+job.ID+`","recipients":`+strconv.Itoa(len(recipients))+`}`)
-	http.Redirect(w, r, "/jobs/"+job.ID, http.StatusSeeOther)
+	h.settings.LogAction(r, middleware.GetUserID(r), requestActor(r),
+		"send", "campaign", c.ID, auditJSON(map[string]any{"job_id": job.ID, "segment_id": send.SegmentID, "recipients": len(recipients), "throttle": job.Throttle}))
+	if overridden != nil {
+		h.logFreezeOverride(r, job.ID, overridden)
+	}
+	return job, nil
+}
+
+// throttleFromForm reads the sending rate, ramp-up and send window of the
+// campaign send form as a JSON job throttle, empty if nothing is limited
+func throttleFromForm(r *http.Request) (string, error) {
+	t := &models.JobThrottle{}
+	if v := strings.TrimSpace(r.FormValue("rate_per_minute")); v != "" {
+		rate, err := strconv.Atoi(v)
+		if err != nil {
+			return "", fmt.Errorf("rate must be a number")
+		}
+		t.RatePerMinute = rate
+	}
+
+	steps, err := models.ParseRampUp(r.FormValue("ramp_up"))
+	if err != nil {
+		return "", err
+	}
+	t.RampUp = steps
+
+	start, end := r.FormValue("window_start"), r.FormValue("window_end")
+	if start != "" || end != "" {
+		t.Window = &models.SendWindow{
+			Start:          start,
+			End:            end,
+			Timezone:       strings.TrimSpace(r.FormValue("window_timezone")),
+			RecipientLocal: r.FormValue("window_recipient_local") == "on",
+		}
+	}
+
+	if err := t.Validate(); err != nil {
+		return "", err
+	}
+	if t.IsZero() {
+		return "", nil
+	}
+	data, _ := json.Marshal(t)
+	return string(data), nil
 }
 
 func (h *Handlers) CampaignJobs(w http.ResponseWriter, r *http.Request) {
@@ -486,10 +674,7 @@ func (h *Handlers) CampaignJobs(w http.ResponseWriter, r *http.Request) {
 	jobsWithStats := make([]map[string]any, len(jobs))
 	for i, job := range jobs {
 		stats, _ := h.jobs.GetStats(job.ID)
-		progress := 0
-		if stats.Total > 0 {
-			progress = (stats.Sent + stats.Failed) * 100 / stats.Total
-		}
+		progress := worker.JobProgress(stats)
 		jobsWithStats[i] = map[string]any{
 			"Job":      job,
 			"Stats":    stats,
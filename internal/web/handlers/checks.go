@@ -3,6 +3,9 @@ package handlers
 import (
 	"fmt"
 	"net/http"
+	"strings"
+
+	"github.com/foxzi/sendry/internal/web/middleware"
 )
 
 // DNSCheck handles DNS check page
@@ -46,6 +49,10 @@ func (h *Handlers) DNSCheck(w http.ResponseWriter, r *http.Request) {
 
 // IPCheck handles IP DNSBL check page
 func (h *Handlers) IPCheck(w http.ResponseWriter, r *http.Request) {
+	h.renderIPCheck(w, r, "")
+}
+
+func (h *Handlers) renderIPCheck(w http.ResponseWriter, r *http.Request, errMsg string) {
 	serverName := r.PathValue("server")
 
 	client, err := h.sendry.GetClient(serverName)
@@ -62,6 +69,7 @@ func (h *Handlers) IPCheck(w http.ResponseWriter, r *http.Request) {
 		"User":       h.getUserFromContext(r),
 		"ServerName": serverName,
 		"IP":         ip,
+		"Error":      errMsg,
 	}
 
 	// If IP is provided, perform the check
@@ -75,5 +83,89 @@ func (h *Handlers) IPCheck(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Monitored IPs are only shown when DNSBL monitoring is enabled on the server
+	monitored, err := client.ListMonitoredIPs(r.Context())
+	if err != nil {
+		h.logger.Error("failed to list monitored IPs", "error", err, "server", serverName)
+	}
+	data["Monitored"] = monitored
+
 	h.render(w, "ip_check", data)
 }
+
+// IPMonitorAdd registers an outbound IP for DNSBL monitoring
+func (h *Handlers) IPMonitorAdd(w http.ResponseWriter, r *http.Request) {
+	serverName := r.PathValue("server")
+
+	client, err := h.sendry.GetClient(serverName)
+	if err != nil {
+		h.error(w, http.StatusNotFound, "Server not found")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		h.error(w, http.StatusBadRequest, "Invalid form data")
+		return
+	}
+
+	ip := strings.TrimSpace(r.FormValue("ip"))
+	note := strings.TrimSpace(r.FormValue("note"))
+	added, err := client.AddMonitoredIP(r.Context(), ip, note)
+	if err != nil {
+		h.logger.Error("failed to add monitored IP", "error", err, "ip", ip)
+		h.renderIPCheck(w, r, "Failed to add monitored IP: "+err.Error())
+		return
+	}
+
+	h.settings.LogAction(r, middleware.GetUserID(r), middleware.GetUserEmail(r),
+		"create", "monitored_ip", added.IP, auditJSON(map[string]any{"server": serverName, "note": note}))
+
+	// Check the new IP right away instead of waiting for the next scheduled run
+	if _, err := client.CheckMonitoredIP(r.Context(), added.IP); err != nil {
+		h.logger.Warn("failed to check monitored IP", "error", err, "ip", added.IP)
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/servers/%s/ip-check", serverName), http.StatusSeeOther)
+}
+
+// IPMonitorCheck checks a monitored IP against DNSBLs now
+func (h *Handlers) IPMonitorCheck(w http.ResponseWriter, r *http.Request) {
+	serverName := r.PathValue("server")
+	ip := r.PathValue("ip")
+
+	client, err := h.sendry.GetClient(serverName)
+	if err != nil {
+		h.error(w, http.StatusNotFound, "Server not found")
+		return
+	}
+
+	if _, err := client.CheckMonitoredIP(r.Context(), ip); err != nil {
+		h.logger.Error("failed to check monitored IP", "error", err, "ip", ip)
+		h.renderIPCheck(w, r, "Failed to check "+ip+": "+err.Error())
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/servers/%s/ip-check", serverName), http.StatusSeeOther)
+}
+
+// IPMonitorRemove stops DNSBL monitoring of an outbound IP
+func (h *Handlers) IPMonitorRemove(w http.ResponseWriter, r *http.Request) {
+	serverName := r.PathValue("server")
+	ip := r.PathValue("ip")
+
+	client, err := h.sendry.GetClient(serverName)
+	if err != nil {
+		h.error(w, http.StatusNotFound, "Server not found")
+		return
+	}
+
+	if err := client.RemoveMonitoredIP(r.Context(), ip); err != nil {
+		h.logger.Error("failed to remove monitored IP", "error", err, "ip", ip)
+		h.renderIPCheck(w, r, "Failed to remove "+ip+": "+err.Error())
+		return
+	}
+
+	h.settings.LogAction(r, middleware.GetUserID(r), middleware.GetUserEmail(r),
+		"delete", "monitored_ip", ip, auditJSON(map[string]any{"server": serverName}))
+
+	http.Redirect(w, r, fmt.Sprintf("/servers/%s/ip-check", serverName), http.StatusSeeOther)
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/foxzi/sendry/internal/web/middleware"
+)
+
+// ServerComplaints shows the spam complaints and the suppression list of a server
+func (h *Handlers) ServerComplaints(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	client, err := h.sendry.GetClient(name)
+	if err != nil {
+		h.error(w, http.StatusNotFound, "Server not found")
+		return
+	}
+
+	campaignID := strings.TrimSpace(r.URL.Query().Get("campaign_id"))
+	data := map[string]any{
+		"Title":      name + " - Complaints",
+		"Active":     "servers",
+		"User":       h.getUserFromContext(r),
+		"ServerName": name,
+		"CampaignID": campaignID,
+	}
+
+	complaints, err := client.ListComplaints(r.Context(), campaignID, 100)
+	if err != nil {
+		// Servers without fbl.enabled have no complaint API
+		h.logger.Warn("failed to list complaints", "error", err, "server", name)
+		data["Error"] = errMsg(err)
+	} else {
+		data["Complaints"] = complaints.Complaints
+	}
+
+	suppressions, err := client.ListSuppressions(r.Context(), 100, 0)
+	if err != nil {
+		h.logger.Warn("failed to list suppressions", "error", err, "server", name)
+		data["SuppressionError"] = errMsg(err)
+	} else {
+		data["Suppressions"] = suppressions.Suppressions
+		data["SuppressionTotal"] = suppressions.Total
+	}
+
+	h.render(w, "server_complaints", data)
+}
+
+// ServerSuppressionRemove takes a recipient off the suppression list of a server
+func (h *Handlers) ServerSuppressionRemove(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	client, err := h.sendry.GetClient(name)
+	if err != nil {
+		h.error(w, http.StatusNotFound, "Server not found")
+		return
+	}
+
+	email := strings.TrimSpace(r.FormValue("email"))
+	if email == "" {
+		h.error(w, http.StatusBadRequest, "Email is required")
+		return
+	}
+
+	if err := client.RemoveSuppression(r.Context(), email); err != nil {
+		h.logger.Error("failed to remove suppression", "error", err, "server", name, "email", email)
+		h.error(w, http.StatusInternalServerError, fmt.Sprintf("Failed to remove suppression: %v", err))
+		return
+	}
+
+	h.settings.LogAction(r, middleware.GetUserID(r), middleware.GetUserEmail(r),
+		"delete", "suppression", email, auditJSON(map[string]any{"server": name}))
+
+	http.Redirect(w, r, "/servers/"+name+"/complaints", http.StatusSeeOther)
+}
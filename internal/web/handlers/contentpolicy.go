@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/foxzi/sendry/internal/web/models"
+	emailtpl "github.com/foxzi/sendry/internal/web/template"
+)
+
+// contentPolicy returns the content policy that applies to a template, nil
+// if it may reference external resources. The public URLs of sendry-web
+// are always approved, uploaded media is served from them.
+func (h *Handlers) contentPolicy(t *models.Template) *emailtpl.Policy {
+	cfg := h.cfg.ContentPolicy
+	if !cfg.BlockExternal && !t.BlockExternal {
+		return nil
+	}
+
+	hosts := append([]string{}, cfg.AllowedHosts...)
+	for _, u := range []string{h.cfg.Server.PublicURL, h.cfg.Server.PublicUploadURL} {
+		if u != "" {
+			hosts = append(hosts, u)
+		}
+	}
+	return &emailtpl.Policy{AllowedHosts: hosts}
+}
+
+// policyViolations checks the template HTML against its content policy
+func (h *Handlers) policyViolations(t *models.Template) []emailtpl.Violation {
+	policy := h.contentPolicy(t)
+	if policy == nil {
+		return nil
+	}
+	return policy.Check(t.HTML)
+}
+
+// renderPolicyViolations responds with the violation report of a template
+// that breaks its content policy. action is what was blocked, such as
+// "save" or "deploy".
+func (h *Handlers) renderPolicyViolations(w http.ResponseWriter, r *http.Request, t *models.Template, action string, violations []emailtpl.Violation) {
+	h.logger.Warn("template blocked by content policy", "template_id", t.ID, "name", t.Name,
+		"action", action, "violations", len(violations))
+
+	data := map[string]any{
+		"Title":        "Content Policy Violation",
+		"Active":       "templates",
+		"User":         h.getUserFromContext(r),
+		"Template":     t,
+		"Action":       action,
+		"Violations":   violations,
+		"AllowedHosts": h.contentPolicy(t).AllowedHosts,
+		"GlobalPolicy": h.cfg.ContentPolicy.BlockExternal,
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	if err := h.views.Render(w, "template_policy", data); err != nil {
+		h.logger.Error("failed to render template", "name", "template_policy", "error", err)
+	}
+}
+
+// policyError describes content policy violations as a deploy error
+func policyError(violations []emailtpl.Violation) error {
+	return fmt.Errorf("content policy: %d external resource(s) not allowed", len(violations))
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/foxzi/sendry/internal/web/models"
+	"github.com/foxzi/sendry/internal/web/repository"
+)
+
+func postTemplate(h *Handlers, form url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/templates", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	h.TemplateCreate(w, req)
+	return w
+}
+
+func TestTemplateCreateContentPolicy(t *testing.T) {
+	external := `<p>Hi</p><img src="https://tracker.example.net/pixel.gif">`
+	approved := `<img src="https://assets.example.com/logo.png"><img src="cid:banner">`
+
+	tests := []struct {
+		name       string
+		global     bool
+		optIn      bool
+		html       string
+		wantStatus int
+	}{
+		{"no policy allows external", false, false, external, http.StatusSeeOther},
+		{"global policy blocks external", true, false, external, http.StatusUnprocessableEntity},
+		{"template policy blocks external", false, true, external, http.StatusUnprocessableEntity},
+		{"approved and embedded resources", true, true, approved, http.StatusSeeOther},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, database, cleanup := newDomainViewTestHandlers(t)
+			defer cleanup()
+			h.cfg.ContentPolicy.BlockExternal = tt.global
+			h.cfg.ContentPolicy.AllowedHosts = []string{"assets.example.com"}
+
+			form := url.Values{
+				"name":    {"Template " + string(rune('A'+i))},
+				"subject": {"Hello"},
+				"html":    {tt.html},
+			}
+			if tt.optIn {
+				form.Set("block_external", "on")
+			}
+
+			w := postTemplate(h, form)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+
+			list, total, err := repository.NewTemplateRepository(database.DB).List(models.TemplateListFilter{})
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			if tt.wantStatus == http.StatusUnprocessableEntity {
+				if total != 0 {
+					t.Errorf("template was saved despite violations")
+				}
+				body := w.Body.String()
+				if !strings.Contains(body, "https://tracker.example.net/pixel.gif") || !strings.Contains(body, "tracker.example.net is not an approved asset host") {
+					t.Errorf("violation report does not list the resource, body: %s", body)
+				}
+				return
+			}
+			if total != 1 || list[0].BlockExternal != tt.optIn {
+				t.Errorf("saved templates = %+v, want one with BlockExternal=%v", list, tt.optIn)
+			}
+		})
+	}
+}
+
+func TestContentPolicyApprovesPublicURLs(t *testing.T) {
+	h, _, cleanup := newDomainViewTestHandlers(t)
+	defer cleanup()
+	h.cfg.Server.PublicUploadURL = "https://media.example.com"
+
+	tmpl := &models.Template{
+		BlockExternal: true,
+		HTML:          `<img src="https://media.example.com/uploads/a.png"><img src="https://other.example.com/b.png">`,
+	}
+	violations := h.policyViolations(tmpl)
+	if len(violations) != 1 || violations[0].URL != "https://other.example.com/b.png" {
+		t.Errorf("violations = %+v, want only other.example.com", violations)
+	}
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/foxzi/sendry/internal/web/middleware"
+	"github.com/foxzi/sendry/internal/web/models"
+	sendryclient "github.com/foxzi/sendry/pkg/client"
+)
+
+// Entity types recorded in the deployment history
+const (
+	deployEntityDomain   = "domain"
+	deployEntityDKIM     = "dkim"
+	deployEntityTemplate = "template"
+)
+
+// deployTraceTimeout bounds how long the trace page waits for server audit logs
+const deployTraceTimeout = 10 * time.Second
+
+// withDeployCorrelation starts a deploy action. Server API requests made with
+// the returned request carry a new correlation ID, which links the deployment
+// history to the server logs and audit records.
+func (h *Handlers) withDeployCorrelation(r *http.Request) *http.Request {
+	return r.WithContext(sendryclient.WithCorrelationID(r.Context(), uuid.New().String()))
+}
+
+// recordDeploy stores the outcome of deploying an entity to one server
+func (h *Handlers) recordDeploy(r *http.Request, entityType, entityID, entityName, serverName string, deployErr error) {
+	correlationID := sendryclient.CorrelationID(r.Context())
+	if correlationID == "" {
+		return
+	}
+
+	event := &models.DeploymentEvent{
+		CorrelationID: correlationID,
+		EntityType:    entityType,
+		EntityID:      entityID,
+		EntityName:    entityName,
+		ServerName:    serverName,
+		Status:        "deployed",
+		UserEmail:     requestActor(r),
+	}
+	if deployErr != nil {
+		event.Status = "failed"
+		event.Error = deployErr.Error()
+		h.logger.Error("deployment failed", "correlation_id", correlationID,
+			"entity_type", entityType, "entity", entityName, "server", serverName, "error", deployErr)
+	}
+
+	if err := h.deployments.AddEvent(event); err != nil {
+		h.logger.Error("failed to record deployment event", "correlation_id", correlationID, "error", err)
+	}
+}
+
+// logDeployAction writes the web audit log entry of a deploy action
+func (h *Handlers) logDeployAction(r *http.Request, entityType, entityID string, servers []string) {
+	h.settings.LogAction(r, middleware.GetUserID(r), requestActor(r),
+		"deploy", entityType, entityID, auditJSON(map[string]any{
+			"servers":        servers,
+			"correlation_id": sendryclient.CorrelationID(r.Context()),
+		}))
+}
+
+// Deployments shows the deployment history
+func (h *Handlers) Deployments(w http.ResponseWriter, r *http.Request) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit := 50
+
+	filter := models.DeploymentFilter{
+		EntityType: r.URL.Query().Get("entity_type"),
+		EntityID:   r.URL.Query().Get("entity_id"),
+		Limit:      limit,
+		Offset:     (page - 1) * limit,
+	}
+
+	runs, total, err := h.deployments.ListRuns(filter)
+	if err != nil {
+		h.logger.Error("failed to list deployments", "error", err)
+		h.error(w, http.StatusInternalServerError, "Failed to load deployments")
+		return
+	}
+
+	data := map[string]any{
+		"Title":      "Deployments",
+		"Active":     "settings",
+		"User":       h.getUserFromContext(r),
+		"Runs":       runs,
+		"Page":       page,
+		"TotalPages": (total + limit - 1) / limit,
+		"Total":      total,
+		"Filter":     filter,
+	}
+
+	h.render(w, "deployments", data)
+}
+
+// deployServerTrace is one server's part of a deploy action
+type deployServerTrace struct {
+	Name   string
+	Events []models.DeploymentEvent
+	Audit  []sendryclient.AuditEntry
+	Error  string
+}
+
+// DeploymentView shows a deploy action together with the audit records
+// of every server it touched
+func (h *Handlers) DeploymentView(w http.ResponseWriter, r *http.Request) {
+	correlationID := r.PathValue("id")
+
+	events, err := h.deployments.GetEvents(correlationID)
+	if err != nil {
+		h.logger.Error("failed to load deployment", "correlation_id", correlationID, "error", err)
+		h.error(w, http.StatusInternalServerError, "Failed to load deployment")
+		return
+	}
+	if len(events) == 0 {
+		h.error(w, http.StatusNotFound, "Deployment not found")
+		return
+	}
+
+	// Group events by server, keeping the order servers were deployed in
+	var traces []*deployServerTrace
+	byServer := make(map[string]*deployServerTrace)
+	failed := 0
+	for _, e := range events {
+		trace, ok := byServer[e.ServerName]
+		if !ok {
+			trace = &deployServerTrace{Name: e.ServerName}
+			byServer[e.ServerName] = trace
+			traces = append(traces, trace)
+		}
+		trace.Events = append(trace.Events, e)
+		if e.Status == "failed" {
+			failed++
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), deployTraceTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, trace := range traces {
+		client, err := h.sendry.GetClient(trace.Name)
+		if err != nil {
+			trace.Error = "Server is not configured"
+			continue
+		}
+
+		wg.Add(1)
+		go func(trace *deployServerTrace, client *sendryclient.Client) {
+			defer wg.Done()
+			resp, err := client.GetAuditLog(ctx, correlationID, 100)
+			if err != nil {
+				trace.Error = err.Error()
+				return
+			}
+			// Show server records in the order they happened
+			slices.Reverse(resp.Entries)
+			trace.Audit = resp.Entries
+		}(trace, client)
+	}
+	wg.Wait()
+
+	data := map[string]any{
+		"Title":         "Deployment " + correlationID,
+		"Active":        "settings",
+		"User":          h.getUserFromContext(r),
+		"CorrelationID": correlationID,
+		"Run":           events[0],
+		"Servers":       traces,
+		"Failed":        failed,
+	}
+
+	h.render(w, "deployment_view", data)
+}
@@ -10,8 +10,9 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/foxzi/sendry/internal/web/middleware"
 	"github.com/foxzi/sendry/internal/web/models"
-	"github.com/foxzi/sendry/internal/web/sendry"
+	sendryclient "github.com/foxzi/sendry/pkg/client"
 )
 
 // DKIMList shows all DKIM keys for a server
@@ -24,7 +25,7 @@ func (h *Handlers) DKIMList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	keys, err := h.dkim.List()
+	keys, err := h.dkim.List(middleware.GetOrgID(r))
 	if err != nil {
 		h.logger.Error("failed to list DKIM keys", "error", err)
 		h.error(w, http.StatusInternalServerError, "Failed to load DKIM keys")
@@ -138,11 +139,14 @@ func (h *Handlers) DKIMCreate(w http.ResponseWriter, r *http.Request) {
 		DNSRecord:  dnsRecord,
 	}
 
+	key.OrgID = middleware.GetOrgID(r)
 	if err := h.dkim.Create(key); err != nil {
 		h.logger.Error("failed to create DKIM key", "error", err)
 		h.error(w, http.StatusInternalServerError, "Failed to save DKIM key")
 		return
 	}
+	h.settings.LogAction(r, middleware.GetUserID(r), middleware.GetUserEmail(r),
+		"create", "dkim_key", key.ID, auditJSON(map[string]any{"domain": key.Domain, "selector": key.Selector}))
 
 	// Auto-deploy to current server if requested
 	if autoDeploy {
@@ -194,7 +198,7 @@ func (h *Handlers) DKIMView(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get all servers for deployment
-	servers := h.getServersStatus()
+	servers := h.getServersStatus(r)
 
 	// Filter out current server for "other servers" list
 	var otherServers []map[string]any
@@ -224,11 +228,19 @@ func (h *Handlers) DKIMDelete(w http.ResponseWriter, r *http.Request) {
 	serverName := r.PathValue("server")
 	id := r.PathValue("id")
 
+	key, err := h.dkim.GetByID(id)
+	if err != nil || key == nil {
+		h.error(w, http.StatusNotFound, "DKIM key not found")
+		return
+	}
+
 	if err := h.dkim.Delete(id); err != nil {
 		h.logger.Error("failed to delete DKIM key", "error", err)
 		h.error(w, http.StatusInternalServerError, "Failed to delete DKIM key")
 		return
 	}
+	h.settings.LogAction(r, middleware.GetUserID(r), middleware.GetUserEmail(r),
+		"delete", "dkim_key", id, auditJSON(map[string]any{"domain": key.Domain, "selector": key.Selector}))
 
 	http.Redirect(w, r, fmt.Sprintf("/servers/%s/dkim", serverName), http.StatusSeeOther)
 }
@@ -254,6 +266,14 @@ func (h *Handlers) DKIMDeploy(w http.ResponseWriter, r *http.Request) {
 		h.error(w, http.StatusBadRequest, "No servers selected")
 		return
 	}
+	if !orgServersAllowed(r, servers...) {
+		h.error(w, http.StatusNotFound, "Server not found")
+		return
+	}
+
+	r = h.withDeployCorrelation(r)
+	h.logDeployAction(r, deployEntityDKIM, key.ID, servers)
+	dnsName := key.Selector + "._domainkey." + key.Domain
 
 	var deployErrors []string
 	for _, srvName := range servers {
@@ -261,6 +281,7 @@ func (h *Handlers) DKIMDeploy(w http.ResponseWriter, r *http.Request) {
 		if err != nil {
 			deployErrors = append(deployErrors, fmt.Sprintf("%s: %v", srvName, err))
 			h.dkim.CreateDeployment(key.ID, srvName, "failed", err.Error())
+			h.recordDeploy(r, deployEntityDKIM, key.ID, dnsName, srvName, err)
 			continue
 		}
 
@@ -273,10 +294,12 @@ func (h *Handlers) DKIMDeploy(w http.ResponseWriter, r *http.Request) {
 			h.updateDomainDKIM(r.Context(), client, key.Domain, key.Selector, resp.KeyFile)
 			h.dkim.CreateDeployment(key.ID, srvName, "deployed", "")
 		}
+		h.recordDeploy(r, deployEntityDKIM, key.ID, dnsName, srvName, err)
 	}
 
 	if len(deployErrors) > 0 {
-		h.logger.Error("some deployments failed", "errors", deployErrors)
+		h.logger.Error("some deployments failed", "errors", deployErrors,
+			"correlation_id", sendryclient.CorrelationID(r.Context()))
 	}
 
 	http.Redirect(w, r, fmt.Sprintf("/servers/%s/dkim/%s", serverName, id), http.StatusSeeOther)
@@ -303,6 +326,8 @@ func (h *Handlers) DKIMDeploymentDelete(w http.ResponseWriter, r *http.Request)
 	if err := h.dkim.DeleteDeployment(key.ID, serverName); err != nil {
 		h.logger.Error("failed to delete deployment", "error", err)
 	}
+	h.settings.LogAction(r, middleware.GetUserID(r), middleware.GetUserEmail(r),
+		"undeploy", "dkim_key", key.ID, auditJSON(map[string]any{"server": serverName, "domain": key.Domain, "selector": key.Selector}))
 
 	http.Redirect(w, r, fmt.Sprintf("/servers/%s/dkim/%s", serverName, id), http.StatusSeeOther)
 }
@@ -313,7 +338,7 @@ func (h *Handlers) DKIMDeploymentDelete(w http.ResponseWriter, r *http.Request)
 
 // CentralDKIMList shows all DKIM keys (central management)
 func (h *Handlers) CentralDKIMList(w http.ResponseWriter, r *http.Request) {
-	keys, err := h.dkim.List()
+	keys, err := h.dkim.List(middleware.GetOrgID(r))
 	if err != nil {
 		h.logger.Error("failed to list DKIM keys", "error", err)
 		h.error(w, http.StatusInternalServerError, "Failed to load DKIM keys")
@@ -331,7 +356,7 @@ func (h *Handlers) CentralDKIMList(w http.ResponseWriter, r *http.Request) {
 		"Active":  "dkim",
 		"User":    h.getUserFromContext(r),
 		"Keys":    keys,
-		"Servers": h.getServersStatus(),
+		"Servers": h.getServersStatus(r),
 	}
 
 	h.render(w, "central_dkim_list", data)
@@ -343,7 +368,7 @@ func (h *Handlers) CentralDKIMNew(w http.ResponseWriter, r *http.Request) {
 		"Title":   "New DKIM Key",
 		"Active":  "dkim",
 		"User":    h.getUserFromContext(r),
-		"Servers": h.getServersStatus(),
+		"Servers": h.getServersStatus(r),
 	}
 
 	h.render(w, "central_dkim_new", data)
@@ -364,6 +389,10 @@ func (h *Handlers) CentralDKIMCreate(w http.ResponseWriter, r *http.Request) {
 		h.error(w, http.StatusBadRequest, "Domain and selector are required")
 		return
 	}
+	if !orgServersAllowed(r, deployServers...) {
+		h.error(w, http.StatusNotFound, "Server not found")
+		return
+	}
 
 	// Check if key already exists
 	existing, _ := h.dkim.GetByDomainSelector(domain, selector)
@@ -372,69 +401,26 @@ func (h *Handlers) CentralDKIMCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Generate RSA key
-	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	key, err := newDKIMKey(domain, selector)
 	if err != nil {
-		h.logger.Error("failed to generate RSA key", "error", err)
+		h.logger.Error("failed to generate DKIM key", "error", err)
 		h.error(w, http.StatusInternalServerError, "Failed to generate key")
 		return
 	}
 
-	// Encode private key to PEM
-	privateKeyPEM := pem.EncodeToMemory(&pem.Block{
-		Type:  "RSA PRIVATE KEY",
-		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
-	})
-
-	// Generate public key for DNS record
-	publicKeyDER, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
-	if err != nil {
-		h.logger.Error("failed to marshal public key", "error", err)
-		h.error(w, http.StatusInternalServerError, "Failed to generate public key")
-		return
-	}
-	publicKeyPEM := pem.EncodeToMemory(&pem.Block{
-		Type:  "PUBLIC KEY",
-		Bytes: publicKeyDER,
-	})
-
-	// Create DNS record value
-	pubKeyBase64 := strings.ReplaceAll(string(publicKeyPEM), "-----BEGIN PUBLIC KEY-----", "")
-	pubKeyBase64 = strings.ReplaceAll(pubKeyBase64, "-----END PUBLIC KEY-----", "")
-	pubKeyBase64 = strings.ReplaceAll(pubKeyBase64, "\n", "")
-	dnsRecord := fmt.Sprintf("v=DKIM1; k=rsa; p=%s", pubKeyBase64)
-
-	// Save to database
-	key := &models.DKIMKey{
-		Domain:     domain,
-		Selector:   selector,
-		PrivateKey: string(privateKeyPEM),
-		DNSRecord:  dnsRecord,
-	}
-
+	key.OrgID = middleware.GetOrgID(r)
 	if err := h.dkim.Create(key); err != nil {
 		h.logger.Error("failed to create DKIM key", "error", err)
 		h.error(w, http.StatusInternalServerError, "Failed to save DKIM key")
 		return
 	}
+	h.settings.LogAction(r, middleware.GetUserID(r), middleware.GetUserEmail(r),
+		"create", "dkim_key", key.ID, auditJSON(map[string]any{"domain": key.Domain, "selector": key.Selector}))
 
 	// Deploy to selected servers
-	for _, srvName := range deployServers {
-		client, err := h.sendry.GetClient(srvName)
-		if err != nil {
-			h.dkim.CreateDeployment(key.ID, srvName, "failed", err.Error())
-			continue
-		}
+	h.deployDKIMKey(r, key, deployServers)
 
-		resp, err := client.UploadDKIM(r.Context(), key.Domain, key.Selector, key.PrivateKey)
-		if err != nil {
-			h.dkim.CreateDeployment(key.ID, srvName, "failed", err.Error())
-		} else {
-			// Update domain config with DKIM settings
-			h.updateDomainDKIM(r.Context(), client, key.Domain, key.Selector, resp.KeyFile)
-			h.dkim.CreateDeployment(key.ID, srvName, "deployed", "")
-		}
-	}
+	h.autoPublishDKIM(r, key)
 
 	http.Redirect(w, r, fmt.Sprintf("/dkim/%s", key.ID), http.StatusSeeOther)
 }
@@ -455,7 +441,7 @@ func (h *Handlers) CentralDKIMView(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get all servers
-	servers := h.getServersStatus()
+	servers := h.getServersStatus(r)
 
 	// Mark which servers have this key deployed
 	deployedMap := make(map[string]models.DKIMDeployment)
@@ -499,6 +485,8 @@ func (h *Handlers) CentralDKIMDelete(w http.ResponseWriter, r *http.Request) {
 		h.error(w, http.StatusInternalServerError, "Failed to delete DKIM key")
 		return
 	}
+	h.settings.LogAction(r, middleware.GetUserID(r), middleware.GetUserEmail(r),
+		"delete", "dkim_key", id, auditJSON(map[string]any{"domain": key.Domain, "selector": key.Selector}))
 
 	http.Redirect(w, r, "/dkim", http.StatusSeeOther)
 }
@@ -523,32 +511,87 @@ func (h *Handlers) CentralDKIMDeploy(w http.ResponseWriter, r *http.Request) {
 		h.error(w, http.StatusBadRequest, "No servers selected")
 		return
 	}
+	if !orgServersAllowed(r, servers...) {
+		h.error(w, http.StatusNotFound, "Server not found")
+		return
+	}
 
-	var deployErrors []string
+	r = h.withDeployCorrelation(r)
+	h.logDeployAction(r, deployEntityDKIM, key.ID, servers)
+	h.deployDKIMKey(r, key, servers)
+
+	http.Redirect(w, r, fmt.Sprintf("/dkim/%s", id), http.StatusSeeOther)
+}
+
+// deployDKIMKey uploads a DKIM key to servers and enables it in their
+// domain config, recording the deployment to each server. It returns the
+// failures by server.
+func (h *Handlers) deployDKIMKey(r *http.Request, key *models.DKIMKey, servers []string) map[string]string {
+	dnsName := key.Selector + "._domainkey." + key.Domain
+
+	deployErrors := make(map[string]string)
 	for _, srvName := range servers {
 		client, err := h.sendry.GetClient(srvName)
 		if err != nil {
-			deployErrors = append(deployErrors, fmt.Sprintf("%s: %v", srvName, err))
+			deployErrors[srvName] = err.Error()
 			h.dkim.CreateDeployment(key.ID, srvName, "failed", err.Error())
+			h.recordDeploy(r, deployEntityDKIM, key.ID, dnsName, srvName, err)
 			continue
 		}
 
 		resp, err := client.UploadDKIM(r.Context(), key.Domain, key.Selector, key.PrivateKey)
 		if err != nil {
-			deployErrors = append(deployErrors, fmt.Sprintf("%s: %v", srvName, err))
+			deployErrors[srvName] = err.Error()
 			h.dkim.CreateDeployment(key.ID, srvName, "failed", err.Error())
 		} else {
 			// Update domain config with DKIM settings
 			h.updateDomainDKIM(r.Context(), client, key.Domain, key.Selector, resp.KeyFile)
 			h.dkim.CreateDeployment(key.ID, srvName, "deployed", "")
 		}
+		h.recordDeploy(r, deployEntityDKIM, key.ID, dnsName, srvName, err)
 	}
 
 	if len(deployErrors) > 0 {
-		h.logger.Error("some deployments failed", "errors", deployErrors)
+		h.logger.Error("some deployments failed", "errors", deployErrors,
+			"correlation_id", sendryclient.CorrelationID(r.Context()))
 	}
+	return deployErrors
+}
 
-	http.Redirect(w, r, fmt.Sprintf("/dkim/%s", id), http.StatusSeeOther)
+// newDKIMKey generates a 2048 bit RSA DKIM key with its DNS record
+func newDKIMKey(domain, selector string) (*models.DKIMKey, error) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+
+	// Encode private key to PEM
+	privateKeyPEM := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
+	})
+
+	// Generate public key for DNS record
+	publicKeyDER, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	publicKeyPEM := pem.EncodeToMemory(&pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: publicKeyDER,
+	})
+
+	// Create DNS record value
+	pubKeyBase64 := strings.ReplaceAll(string(publicKeyPEM), "-----BEGIN PUBLIC KEY-----", "")
+	pubKeyBase64 = strings.ReplaceAll(pubKeyBase64, "-----END PUBLIC KEY-----", "")
+	pubKeyBase64 = strings.ReplaceAll(pubKeyBase64, "\n", "")
+
+	return &models.DKIMKey{
+		Domain:     domain,
+		Selector:   selector,
+		PrivateKey: string(privateKeyPEM),
+		DNSRecord:  fmt.Sprintf("v=DKIM1; k=rsa; p=%s", pubKeyBase64),
+	}, nil
 }
 
 // CentralDKIMDeploymentDelete removes a deployment from a specific server (central management)
@@ -572,12 +615,14 @@ func (h *Handlers) CentralDKIMDeploymentDelete(w http.ResponseWriter, r *http.Re
 	if err := h.dkim.DeleteDeployment(key.ID, serverName); err != nil {
 		h.logger.Error("failed to delete deployment", "error", err)
 	}
+	h.settings.LogAction(r, middleware.GetUserID(r), middleware.GetUserEmail(r),
+		"undeploy", "dkim_key", key.ID, auditJSON(map[string]any{"server": serverName, "domain": key.Domain, "selector": key.Selector}))
 
 	http.Redirect(w, r, fmt.Sprintf("/dkim/%s", id), http.StatusSeeOther)
 }
 
 // updateDomainDKIM updates domain configuration with DKIM settings after key upload
-func (h *Handlers) updateDomainDKIM(ctx context.Context, client *sendry.Client, domain, selector, keyFile string) {
+func (h *Handlers) updateDomainDKIM(ctx context.Context, client *sendryclient.Client, domain, selector, keyFile string) {
 	h.logger.Info("updateDomainDKIM called", "domain", domain, "selector", selector, "keyFile", keyFile)
 
 	// Get current domain config or create new
@@ -592,9 +637,9 @@ func (h *Handlers) updateDomainDKIM(ctx context.Context, client *sendry.Client,
 	}
 
 	// Update domain with DKIM config
-	resp, err := client.UpdateDomain(ctx, domain, &sendry.DomainUpdateRequest{
+	resp, err := client.UpdateDomain(ctx, domain, &sendryclient.DomainUpdateRequest{
 		Mode: mode,
-		DKIM: &sendry.DKIMConfig{
+		DKIM: &sendryclient.DKIMConfig{
 			Enabled:  true,
 			Selector: selector,
 			KeyFile:  keyFile,
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/foxzi/sendry/internal/web/dnspublish"
+	"github.com/foxzi/sendry/internal/web/middleware"
+	"github.com/foxzi/sendry/internal/web/models"
+)
+
+// SetDNSPublisher sets the DNS record publisher
+func (h *Handlers) SetDNSPublisher(p *dnspublish.Publisher) {
+	h.dnsPublisher = p
+}
+
+// CentralDomainsDNSPublish publishes the DNS records of a domain through
+// the configured DNS provider
+func (h *Handlers) CentralDomainsDNSPublish(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	if h.dnsPublisher == nil || !h.dnsPublisher.Enabled() {
+		h.error(w, http.StatusNotFound, "DNS publishing is not enabled")
+		return
+	}
+	domain, err := h.domains.GetByID(id)
+	if err != nil || domain == nil {
+		h.error(w, http.StatusNotFound, "Domain not found")
+		return
+	}
+
+	records, err := h.dnsPublisher.Publish(r.Context(), id)
+	if err != nil {
+		h.logger.Error("failed to publish dns records", "domain", domain.Domain, "error", err)
+		h.error(w, http.StatusInternalServerError, "Failed to publish DNS records: "+err.Error())
+		return
+	}
+	h.logDNSPublish(r, domain, records)
+
+	http.Redirect(w, r, fmt.Sprintf("/domains/%s#dns-publishing", id), http.StatusSeeOther)
+}
+
+// CentralDomainsDNSVerify checks the propagation of the published DNS
+// records of a domain now
+func (h *Handlers) CentralDomainsDNSVerify(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	if h.dnsPublisher == nil || !h.dnsPublisher.Enabled() {
+		h.error(w, http.StatusNotFound, "DNS publishing is not enabled")
+		return
+	}
+	domain, err := h.domains.GetByID(id)
+	if err != nil || domain == nil {
+		h.error(w, http.StatusNotFound, "Domain not found")
+		return
+	}
+
+	if _, err := h.dnsPublisher.Verify(r.Context(), id); err != nil {
+		h.logger.Error("failed to verify dns records", "domain", domain.Domain, "error", err)
+		h.error(w, http.StatusInternalServerError, "Failed to verify DNS records")
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/domains/%s#dns-publishing", id), http.StatusSeeOther)
+}
+
+// autoPublishDNS publishes the DNS records of a domain when automatic
+// publishing is enabled. Failures are logged and stored on the records;
+// they do not fail the change that triggered the publishing.
+func (h *Handlers) autoPublishDNS(r *http.Request, domain *models.Domain) {
+	if h.dnsPublisher == nil || !h.dnsPublisher.Auto() {
+		return
+	}
+	records, err := h.dnsPublisher.Publish(context.WithoutCancel(r.Context()), domain.ID)
+	if err != nil {
+		h.logger.Error("failed to publish dns records", "domain", domain.Domain, "error", err)
+		return
+	}
+	h.logDNSPublish(r, domain, records)
+}
+
+// autoPublishDKIM publishes the DNS record of a new DKIM key when automatic
+// publishing is enabled
+func (h *Handlers) autoPublishDKIM(r *http.Request, key *models.DKIMKey) {
+	if h.dnsPublisher == nil || !h.dnsPublisher.Auto() {
+		return
+	}
+	if err := h.dnsPublisher.PublishDKIM(context.WithoutCancel(r.Context()), key); err != nil {
+		h.logger.Error("failed to publish dkim record", "domain", key.Domain, "selector", key.Selector, "error", err)
+		return
+	}
+	h.settings.LogAction(r, middleware.GetUserID(r), requestActor(r),
+		"publish", "dns_records", key.ID, auditJSON(map[string]any{
+			"domain":   key.Domain,
+			"selector": key.Selector,
+			"provider": h.dnsPublisher.Config().Provider,
+		}))
+}
+
+func (h *Handlers) logDNSPublish(r *http.Request, domain *models.Domain, records []models.DNSRecord) {
+	published := make(map[string]string, len(records))
+	for _, rec := range records {
+		published[rec.Kind] = rec.Status
+	}
+	h.settings.LogAction(r, middleware.GetUserID(r), requestActor(r),
+		"publish", "dns_records", domain.ID, auditJSON(map[string]any{
+			"domain":   domain.Domain,
+			"provider": h.dnsPublisher.Config().Provider,
+			"records":  published,
+		}))
+}
@@ -5,9 +5,12 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/foxzi/sendry/internal/web/middleware"
 	"github.com/foxzi/sendry/internal/web/models"
-	"github.com/foxzi/sendry/internal/web/sendry"
+	"github.com/foxzi/sendry/internal/web/mtasts"
+	sendryclient "github.com/foxzi/sendry/pkg/client"
 )
 
 // DomainsList shows domains for a server
@@ -28,7 +31,15 @@ func (h *Handlers) DomainsList(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get DKIM keys for linking
-	dkimKeys, _ := h.dkim.List()
+	dkimKeys, _ := h.dkim.List(middleware.GetOrgID(r))
+
+	// Reputation badges, only available with reputation scoring enabled
+	reputation := make(map[string]*sendryclient.ReputationScore)
+	if scores, err := client.ListReputation(r.Context()); err == nil {
+		for i := range scores.Domains {
+			reputation[scores.Domains[i].Domain] = &scores.Domains[i]
+		}
+	}
 
 	data := map[string]any{
 		"Title":      fmt.Sprintf("%s - Domains", serverName),
@@ -37,6 +48,7 @@ func (h *Handlers) DomainsList(w http.ResponseWriter, r *http.Request) {
 		"ServerName": serverName,
 		"Domains":    domains.Domains,
 		"DKIMKeys":   dkimKeys,
+		"Reputation": reputation,
 	}
 
 	h.render(w, "domains_list", data)
@@ -47,7 +59,7 @@ func (h *Handlers) DomainsNew(w http.ResponseWriter, r *http.Request) {
 	serverName := r.PathValue("server")
 
 	// Get DKIM keys for selection
-	dkimKeys, _ := h.dkim.List()
+	dkimKeys, _ := h.dkim.List(middleware.GetOrgID(r))
 
 	data := map[string]any{
 		"Title":      "New Domain",
@@ -82,7 +94,7 @@ func (h *Handlers) DomainsCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	req := &sendry.DomainCreateRequest{
+	req := &sendryclient.DomainCreateRequest{
 		Domain:      domain,
 		Mode:        r.FormValue("mode"),
 		DefaultFrom: r.FormValue("default_from"),
@@ -94,7 +106,7 @@ func (h *Handlers) DomainsCreate(w http.ResponseWriter, r *http.Request) {
 		if selector == "" {
 			selector = "mail"
 		}
-		req.DKIM = &sendry.DKIMConfig{
+		req.DKIM = &sendryclient.DKIMConfig{
 			Enabled:  true,
 			Selector: selector,
 		}
@@ -119,7 +131,7 @@ func (h *Handlers) DomainsCreate(w http.ResponseWriter, r *http.Request) {
 	msgsPerDay, _ := strconv.Atoi(r.FormValue("rate_limit_day"))
 	recipientsPerMsg, _ := strconv.Atoi(r.FormValue("rate_limit_recipients"))
 	if msgsPerHour > 0 || msgsPerDay > 0 || recipientsPerMsg > 0 {
-		req.RateLimit = &sendry.RateLimitCfg{
+		req.RateLimit = &sendryclient.RateLimitCfg{
 			MessagesPerHour:      msgsPerHour,
 			MessagesPerDay:       msgsPerDay,
 			RecipientsPerMessage: recipientsPerMsg,
@@ -153,6 +165,8 @@ func (h *Handlers) DomainsCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.settings.LogAction(r, middleware.GetUserID(r), middleware.GetUserEmail(r),
+		"create", "server_domain", domain, auditJSON(map[string]any{"server": serverName, "new": req}))
 	http.Redirect(w, r, "/servers/"+serverName+"/domains", http.StatusSeeOther)
 }
 
@@ -175,7 +189,13 @@ func (h *Handlers) DomainsView(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get DKIM keys
-	dkimKeys, _ := h.dkim.List()
+	dkimKeys, _ := h.dkim.List(middleware.GetOrgID(r))
+
+	// Scheduled DNS checks, only available with DNS monitoring enabled
+	dnsHistory, err := client.GetDNSHistory(r.Context(), domainName, 7*24)
+	if err != nil {
+		h.logger.Warn("failed to get dns check history", "server", serverName, "domain", domainName, "error", err)
+	}
 
 	data := map[string]any{
 		"Title":      fmt.Sprintf("Domain: %s", domainName),
@@ -185,6 +205,8 @@ func (h *Handlers) DomainsView(w http.ResponseWriter, r *http.Request) {
 		"Domain":     domain,
 		"DKIMKeys":   dkimKeys,
 		"Modes":      []string{"production", "sandbox", "redirect", "bcc"},
+		"DNSHistory": dnsHistory,
+		"DNSChanges": dnsRegressions(dnsHistory),
 	}
 
 	h.render(w, "domain_view", data)
@@ -209,7 +231,7 @@ func (h *Handlers) DomainsEdit(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get DKIM keys
-	dkimKeys, _ := h.dkim.List()
+	dkimKeys, _ := h.dkim.List(middleware.GetOrgID(r))
 
 	data := map[string]any{
 		"Title":      fmt.Sprintf("Edit Domain: %s", domainName),
@@ -240,7 +262,7 @@ func (h *Handlers) DomainsUpdate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	req := &sendry.DomainUpdateRequest{
+	req := &sendryclient.DomainUpdateRequest{
 		Mode:        r.FormValue("mode"),
 		DefaultFrom: r.FormValue("default_from"),
 	}
@@ -251,7 +273,7 @@ func (h *Handlers) DomainsUpdate(w http.ResponseWriter, r *http.Request) {
 		if selector == "" {
 			selector = "mail"
 		}
-		req.DKIM = &sendry.DKIMConfig{
+		req.DKIM = &sendryclient.DKIMConfig{
 			Enabled:  true,
 			Selector: selector,
 		}
@@ -270,7 +292,7 @@ func (h *Handlers) DomainsUpdate(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 	} else {
-		req.DKIM = &sendry.DKIMConfig{Enabled: false}
+		req.DKIM = &sendryclient.DKIMConfig{Enabled: false}
 	}
 
 	// Parse rate limits
@@ -278,7 +300,7 @@ func (h *Handlers) DomainsUpdate(w http.ResponseWriter, r *http.Request) {
 	msgsPerDay, _ := strconv.Atoi(r.FormValue("rate_limit_day"))
 	recipientsPerMsg, _ := strconv.Atoi(r.FormValue("rate_limit_recipients"))
 	if msgsPerHour > 0 || msgsPerDay > 0 || recipientsPerMsg > 0 {
-		req.RateLimit = &sendry.RateLimitCfg{
+		req.RateLimit = &sendryclient.RateLimitCfg{
 			MessagesPerHour:      msgsPerHour,
 			MessagesPerDay:       msgsPerDay,
 			RecipientsPerMessage: recipientsPerMsg,
@@ -305,6 +327,8 @@ func (h *Handlers) DomainsUpdate(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	before, _ := client.GetDomain(r.Context(), domainName)
+
 	_, err = client.UpdateDomain(r.Context(), domainName, req)
 	if err != nil {
 		h.logger.Error("failed to update domain", "error", err)
@@ -312,6 +336,9 @@ func (h *Handlers) DomainsUpdate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.settings.LogAction(r, middleware.GetUserID(r), middleware.GetUserEmail(r),
+		"update", "server_domain", domainName, auditJSON(map[string]any{"server": serverName, "old": before, "new": req}))
+
 	http.Redirect(w, r, "/servers/"+serverName+"/domains/"+domainName, http.StatusSeeOther)
 }
 
@@ -326,6 +353,8 @@ func (h *Handlers) DomainsDelete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before, _ := client.GetDomain(r.Context(), domainName)
+
 	err = client.DeleteDomain(r.Context(), domainName)
 	if err != nil {
 		h.logger.Error("failed to delete domain", "error", err)
@@ -333,6 +362,9 @@ func (h *Handlers) DomainsDelete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.settings.LogAction(r, middleware.GetUserID(r), middleware.GetUserEmail(r),
+		"delete", "server_domain", domainName, auditJSON(map[string]any{"server": serverName, "old": before}))
+
 	http.Redirect(w, r, "/servers/"+serverName+"/domains", http.StatusSeeOther)
 }
 
@@ -342,7 +374,7 @@ func (h *Handlers) DomainsDelete(w http.ResponseWriter, r *http.Request) {
 
 // CentralDomainsList shows all locally stored domains
 func (h *Handlers) CentralDomainsList(w http.ResponseWriter, r *http.Request) {
-	domains, err := h.domains.List(models.DomainFilter{})
+	domains, err := h.domains.List(models.DomainFilter{OrgID: middleware.GetOrgID(r)})
 	if err != nil {
 		h.logger.Error("failed to list domains", "error", err)
 		h.error(w, http.StatusInternalServerError, "Failed to load domains")
@@ -360,7 +392,7 @@ func (h *Handlers) CentralDomainsList(w http.ResponseWriter, r *http.Request) {
 		"Active":  "domains",
 		"User":    h.getUserFromContext(r),
 		"Domains": domains,
-		"Servers": h.getServersStatus(),
+		"Servers": h.getServersStatus(r),
 	}
 
 	h.render(w, "central_domains_list", data)
@@ -368,13 +400,13 @@ func (h *Handlers) CentralDomainsList(w http.ResponseWriter, r *http.Request) {
 
 // CentralDomainsNew shows the new domain form
 func (h *Handlers) CentralDomainsNew(w http.ResponseWriter, r *http.Request) {
-	dkimKeys, _ := h.dkim.List()
+	dkimKeys, _ := h.dkim.List(middleware.GetOrgID(r))
 
 	data := map[string]any{
 		"Title":    "New Domain",
 		"Active":   "domains",
 		"User":     h.getUserFromContext(r),
-		"Servers":  h.getServersStatus(),
+		"Servers":  h.getServersStatus(r),
 		"DKIMKeys": dkimKeys,
 		"Modes":    []string{"production", "sandbox", "redirect", "bcc"},
 	}
@@ -437,11 +469,14 @@ func (h *Handlers) CentralDomainsCreate(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
+	domain.OrgID = middleware.GetOrgID(r)
 	if err := h.domains.Create(domain); err != nil {
 		h.logger.Error("failed to create domain", "error", err)
 		h.error(w, http.StatusInternalServerError, "Failed to create domain")
 		return
 	}
+	h.settings.LogAction(r, middleware.GetUserID(r), middleware.GetUserEmail(r),
+		"create", "domain", domain.ID, auditJSON(map[string]any{"new": domainAudit(domain)}))
 
 	// Deploy to selected servers
 	deployServers := r.Form["servers"]
@@ -449,6 +484,8 @@ func (h *Handlers) CentralDomainsCreate(w http.ResponseWriter, r *http.Request)
 		h.deployDomainToServer(r, domain, srvName)
 	}
 
+	h.autoPublishDNS(r, domain)
+
 	http.Redirect(w, r, fmt.Sprintf("/domains/%s", domain.ID), http.StatusSeeOther)
 }
 
@@ -473,7 +510,7 @@ func (h *Handlers) CentralDomainsView(w http.ResponseWriter, r *http.Request) {
 		domain.DKIMKey = dkimKey
 	}
 
-	servers := h.getServersStatus()
+	servers := h.getServersStatus(r)
 	deployedMap := make(map[string]models.DomainDeployment)
 	for _, d := range domain.Deployments {
 		deployedMap[d.ServerName] = d
@@ -511,6 +548,47 @@ func (h *Handlers) CentralDomainsView(w http.ResponseWriter, r *http.Request) {
 		"SPFInclude":    spfInclude,
 	}
 
+	if h.dnsPublisher != nil && h.dnsPublisher.Enabled() {
+		records, err := h.dnsPublisher.Records(domain.ID)
+		if err != nil {
+			h.logger.Error("failed to load dns records", "error", err)
+		}
+		data["DNSPublishing"] = h.dnsPublisher.Config()
+		data["DNSRecords"] = records
+		data["DNSReady"] = models.DNSRecordsReady(records)
+	}
+
+	policy, err := h.mtaSTS.Get(domain.ID)
+	if err != nil {
+		h.logger.Error("failed to load mta-sts policy", "error", err)
+	}
+	if policy != nil {
+		sts := mtastsPolicy(policy)
+		data["MTASTS"] = policy
+		data["MTASTSText"] = sts.Text()
+		data["MTASTSRecord"] = sts.TXTRecord()
+		data["MTASTSForm"] = map[string]any{
+			"Mode":   policy.Mode,
+			"MX":     strings.Join(policy.MX, "\n"),
+			"MaxAge": policy.MaxAge,
+		}
+
+		if r.URL.Query().Get("check") == "true" || r.URL.Query().Get("mta_sts") == "check" {
+			data["MTASTSCheck"] = mtasts.NewValidator().Validate(r.Context(), domain.Domain, sts)
+		}
+	} else {
+		mx := ""
+		if h.dnsPublisher != nil {
+			mx = h.dnsPublisher.Config().MXHost
+		}
+		data["MTASTSForm"] = map[string]any{
+			"Mode":   mtasts.ModeTesting,
+			"MX":     mx,
+			"MaxAge": mtasts.DefaultMaxAge,
+		}
+	}
+	data["MTASTSURL"] = mtasts.PolicyURL(domain.Domain)
+
 	// Run DNS check if requested
 	if r.URL.Query().Get("check") == "true" {
 		selector := domain.DKIMSelector
@@ -548,7 +626,7 @@ func (h *Handlers) CentralDomainsEdit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	dkimKeys, _ := h.dkim.List()
+	dkimKeys, _ := h.dkim.List(middleware.GetOrgID(r))
 
 	data := map[string]any{
 		"Title":    fmt.Sprintf("Edit Domain: %s", domain.Domain),
@@ -577,6 +655,7 @@ func (h *Handlers) CentralDomainsUpdate(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	before := domainAudit(domain)
 	domain.Mode = r.FormValue("mode")
 	domain.DefaultFrom = r.FormValue("default_from")
 
@@ -620,6 +699,10 @@ func (h *Handlers) CentralDomainsUpdate(w http.ResponseWriter, r *http.Request)
 		h.error(w, http.StatusInternalServerError, "Failed to update domain")
 		return
 	}
+	h.settings.LogAction(r, middleware.GetUserID(r), middleware.GetUserEmail(r),
+		"update", "domain", id, auditJSON(map[string]any{"old": before, "new": domainAudit(domain)}))
+
+	h.autoPublishDNS(r, domain)
 
 	http.Redirect(w, r, fmt.Sprintf("/domains/%s", id), http.StatusSeeOther)
 }
@@ -647,10 +730,28 @@ func (h *Handlers) CentralDomainsDelete(w http.ResponseWriter, r *http.Request)
 		h.error(w, http.StatusInternalServerError, "Failed to delete domain")
 		return
 	}
+	h.settings.LogAction(r, middleware.GetUserID(r), middleware.GetUserEmail(r),
+		"delete", "domain", id, auditJSON(map[string]any{"old": domainAudit(domain)}))
 
 	http.Redirect(w, r, "/domains", http.StatusSeeOther)
 }
 
+// domainAudit returns the settings of a domain recorded in the audit log
+func domainAudit(d *models.Domain) map[string]any {
+	return map[string]any{
+		"domain":                d.Domain,
+		"mode":                  d.Mode,
+		"default_from":          d.DefaultFrom,
+		"dkim_enabled":          d.DKIMEnabled,
+		"dkim_selector":         d.DKIMSelector,
+		"rate_limit_hour":       d.RateLimitHour,
+		"rate_limit_day":        d.RateLimitDay,
+		"rate_limit_recipients": d.RateLimitRecipients,
+		"redirect_to":           d.RedirectTo,
+		"bcc_to":                d.BCCTo,
+	}
+}
+
 // CentralDomainsDeploy deploys a domain to selected servers
 func (h *Handlers) CentralDomainsDeploy(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
@@ -671,7 +772,13 @@ func (h *Handlers) CentralDomainsDeploy(w http.ResponseWriter, r *http.Request)
 		h.error(w, http.StatusBadRequest, "No servers selected")
 		return
 	}
+	if !orgServersAllowed(r, servers...) {
+		h.error(w, http.StatusNotFound, "Server not found")
+		return
+	}
 
+	r = h.withDeployCorrelation(r)
+	h.logDeployAction(r, deployEntityDomain, domain.ID, servers)
 	for _, srvName := range servers {
 		h.deployDomainToServer(r, domain, srvName)
 	}
@@ -691,9 +798,18 @@ func (h *Handlers) CentralDomainsSync(w http.ResponseWriter, r *http.Request) {
 
 	// Find all outdated deployments
 	currentHash := domain.ConfigHash()
+	var outdated []string
 	for _, d := range domain.Deployments {
 		if d.ConfigHash != currentHash && d.Status != "failed" {
-			h.deployDomainToServer(r, domain, d.ServerName)
+			outdated = append(outdated, d.ServerName)
+		}
+	}
+
+	if len(outdated) > 0 {
+		r = h.withDeployCorrelation(r)
+		h.logDeployAction(r, deployEntityDomain, domain.ID, outdated)
+		for _, srvName := range outdated {
+			h.deployDomainToServer(r, domain, srvName)
 		}
 	}
 
@@ -709,6 +825,10 @@ func (h *Handlers) CentralDomainsImport(w http.ResponseWriter, r *http.Request)
 
 	serverName := r.FormValue("server")
 	domainName := r.FormValue("domain")
+	if !orgServersAllowed(r, serverName) {
+		h.error(w, http.StatusNotFound, "Server not found")
+		return
+	}
 
 	client, err := h.sendry.GetClient(serverName)
 	if err != nil {
@@ -755,6 +875,7 @@ func (h *Handlers) CentralDomainsImport(w http.ResponseWriter, r *http.Request)
 	domain.RedirectTo = serverDomain.RedirectTo
 	domain.BCCTo = serverDomain.BCCTo
 
+	domain.OrgID = middleware.GetOrgID(r)
 	if err := h.domains.Create(domain); err != nil {
 		h.logger.Error("failed to create domain", "error", err)
 		h.error(w, http.StatusInternalServerError, "Failed to import domain")
@@ -767,16 +888,17 @@ func (h *Handlers) CentralDomainsImport(w http.ResponseWriter, r *http.Request)
 	http.Redirect(w, r, fmt.Sprintf("/domains/%s", domain.ID), http.StatusSeeOther)
 }
 
-// Helper to deploy domain to a server
-func (h *Handlers) deployDomainToServer(r *http.Request, domain *models.Domain, serverName string) {
+// Helper to deploy domain to a server, returning the deploy error
+func (h *Handlers) deployDomainToServer(r *http.Request, domain *models.Domain, serverName string) error {
 	client, err := h.sendry.GetClient(serverName)
 	if err != nil {
 		h.domains.CreateDeployment(domain.ID, serverName, "failed", domain.ConfigHash(), err.Error())
-		return
+		h.recordDeploy(r, deployEntityDomain, domain.ID, domain.Domain, serverName, err)
+		return err
 	}
 
 	// Build request
-	req := &sendry.DomainCreateRequest{
+	req := &sendryclient.DomainCreateRequest{
 		Domain:      domain.Domain,
 		Mode:        domain.Mode,
 		DefaultFrom: domain.DefaultFrom,
@@ -789,10 +911,11 @@ func (h *Handlers) deployDomainToServer(r *http.Request, domain *models.Domain,
 		} else {
 			dkimResp, err := client.UploadDKIM(r.Context(), key.Domain, key.Selector, key.PrivateKey)
 			if err != nil {
-				h.logger.Error("failed to deploy DKIM key", "domain", domain.Domain, "error", err)
+				h.logger.Error("failed to deploy DKIM key", "domain", domain.Domain, "error", err,
+					"correlation_id", sendryclient.CorrelationID(r.Context()))
 			} else {
 				h.dkim.CreateDeployment(key.ID, serverName, "deployed", "")
-				req.DKIM = &sendry.DKIMConfig{
+				req.DKIM = &sendryclient.DKIMConfig{
 					Enabled:  true,
 					Selector: key.Selector,
 					KeyFile:  dkimResp.KeyFile,
@@ -802,7 +925,7 @@ func (h *Handlers) deployDomainToServer(r *http.Request, domain *models.Domain,
 	}
 
 	if domain.RateLimitHour > 0 || domain.RateLimitDay > 0 || domain.RateLimitRecipients > 0 {
-		req.RateLimit = &sendry.RateLimitCfg{
+		req.RateLimit = &sendryclient.RateLimitCfg{
 			MessagesPerHour:      domain.RateLimitHour,
 			MessagesPerDay:       domain.RateLimitDay,
 			RecipientsPerMessage: domain.RateLimitRecipients,
@@ -813,7 +936,7 @@ func (h *Handlers) deployDomainToServer(r *http.Request, domain *models.Domain,
 	req.BCCTo = domain.BCCTo
 
 	// Try to update first, then create
-	updateReq := &sendry.DomainUpdateRequest{
+	updateReq := &sendryclient.DomainUpdateRequest{
 		Mode:        req.Mode,
 		DefaultFrom: req.DefaultFrom,
 		DKIM:        req.DKIM,
@@ -833,6 +956,8 @@ func (h *Handlers) deployDomainToServer(r *http.Request, domain *models.Domain,
 	} else {
 		h.domains.CreateDeployment(domain.ID, serverName, "deployed", domain.ConfigHash(), "")
 	}
+	h.recordDeploy(r, deployEntityDomain, domain.ID, domain.Domain, serverName, err)
+	return err
 }
 
 // Helper to parse newline-separated addresses
@@ -847,3 +972,24 @@ func parseAddresses(s string) []string {
 	}
 	return result
 }
+
+// dnsChange is a DNS record regression with the time it was detected
+type dnsChange struct {
+	sendryclient.DNSRegression
+	At time.Time
+}
+
+// dnsRegressions returns the regressions of the DNS check history, newest first
+func dnsRegressions(history *sendryclient.DNSHistoryResponse) []dnsChange {
+	if history == nil {
+		return nil
+	}
+	var changes []dnsChange
+	for i := len(history.History) - 1; i >= 0; i-- {
+		run := history.History[i]
+		for _, reg := range run.Regressions {
+			changes = append(changes, dnsChange{DNSRegression: reg, At: run.CheckedAt})
+		}
+	}
+	return changes
+}
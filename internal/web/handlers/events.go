@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/foxzi/sendry/internal/web/models"
+	"github.com/foxzi/sendry/internal/web/worker"
+)
+
+// eventKeepAlive is how often an idle event stream sends a comment so
+// proxies do not close it
+const eventKeepAlive = 30 * time.Second
+
+// eventStream writes server-sent events
+type eventStream struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+// newEventStream starts a server-sent event response
+func newEventStream(w http.ResponseWriter) *eventStream {
+	rc := http.NewResponseController(w)
+	// The stream outlives the server write timeout
+	rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	rc.Flush()
+
+	return &eventStream{w: w, rc: rc}
+}
+
+// Send writes one event, each line of data as a data field
+func (s *eventStream) Send(event string, data []byte) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "event: %s\n", event)
+	for _, line := range bytes.Split(bytes.TrimRight(data, "\n"), []byte("\n")) {
+		buf.WriteString("data: ")
+		buf.Write(bytes.TrimRight(line, "\r"))
+		buf.WriteByte('\n')
+	}
+	buf.WriteByte('\n')
+
+	if _, err := s.w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+// KeepAlive writes a comment line
+func (s *eventStream) KeepAlive() error {
+	if _, err := s.w.Write([]byte(": keep-alive\n\n")); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+// SetJobProgress sets the hub streaming live job progress to the job view
+// and dashboard
+func (h *Handlers) SetJobProgress(p *worker.Progress) {
+	h.progress = p
+}
+
+// JobEvents streams progress of one job to the job view
+func (h *Handlers) JobEvents(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	if h.progress == nil {
+		h.error(w, http.StatusNotFound, "Live updates are not available")
+		return
+	}
+
+	job, err := h.jobs.GetByID(id)
+	if err != nil || job == nil {
+		h.error(w, http.StatusNotFound, "Job not found")
+		return
+	}
+
+	events, unsubscribe := h.progress.Subscribe(id)
+	defer unsubscribe()
+
+	h.streamEvents(w, r, events, func(stream *eventStream, ev worker.ProgressEvent) error {
+		data := map[string]any{
+			"Job":      ev.Job,
+			"Stats":    ev.Stats,
+			"Progress": ev.Progress,
+		}
+		if err := h.sendFragment(stream, "progress", "job_view", "job_progress", data); err != nil {
+			return err
+		}
+		if ev.ItemsChanged == 0 {
+			return nil
+		}
+
+		items, _, err := h.jobs.ListItems(models.JobItemFilter{JobID: id, Limit: 50})
+		if err != nil {
+			h.logger.Error("failed to list job items", "job_id", id, "error", err)
+			return nil
+		}
+		return h.sendFragment(stream, "items", "job_view", "job_recent_items", map[string]any{"Items": items})
+	})
+}
+
+// JobsEvents streams progress of all jobs to the dashboard
+func (h *Handlers) JobsEvents(w http.ResponseWriter, r *http.Request) {
+	if h.progress == nil {
+		h.error(w, http.StatusNotFound, "Live updates are not available")
+		return
+	}
+
+	events, unsubscribe := h.progress.Subscribe("")
+	defer unsubscribe()
+
+	h.streamEvents(w, r, events, func(stream *eventStream, ev worker.ProgressEvent) error {
+		row := jobRow(ev.Job, ev.Progress)
+		if err := h.sendFragment(stream, "job-"+ev.Job.ID, "dashboard", "dashboard_job_row", row); err != nil {
+			return err
+		}
+
+		var activeJobs int
+		h.db.QueryRow("SELECT COUNT(*) FROM send_jobs WHERE status = 'running'").Scan(&activeJobs)
+		return stream.Send("active-jobs", []byte(fmt.Sprint(activeJobs)))
+	})
+}
+
+// streamEvents sends progress events until the client disconnects
+func (h *Handlers) streamEvents(w http.ResponseWriter, r *http.Request, events <-chan worker.ProgressEvent, send func(*eventStream, worker.ProgressEvent) error) {
+	stream := newEventStream(w)
+
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		var err error
+		select {
+		case <-r.Context().Done():
+			return
+		case ev := <-events:
+			err = send(stream, ev)
+		case <-keepAlive.C:
+			err = stream.KeepAlive()
+		}
+		if err != nil {
+			h.logger.Debug("event stream closed", "path", r.URL.Path, "error", err)
+			return
+		}
+	}
+}
+
+// sendFragment renders a page fragment and sends it as an event
+func (h *Handlers) sendFragment(stream *eventStream, event, page, name string, data any) error {
+	var buf bytes.Buffer
+	if err := h.views.RenderFragment(&buf, page, name, data); err != nil {
+		h.logger.Error("failed to render fragment", "name", name, "error", err)
+		return nil
+	}
+	return stream.Send(event, buf.Bytes())
+}
+
+// publishJob notifies live viewers about a job status change
+func (h *Handlers) publishJob(id string) {
+	if err := h.progress.PublishJob(h.jobs, id, 0); err != nil {
+		h.logger.Debug("failed to publish job progress", "job_id", id, "error", err)
+	}
+}
+
+// jobRow is the dashboard row of a job
+func jobRow(job models.SendJob, progress int) map[string]any {
+	return map[string]any{
+		"ID":           job.ID,
+		"CampaignName": job.CampaignName,
+		"Status":       job.Status,
+		"Progress":     progress,
+		"CreatedAt":    job.CreatedAt,
+	}
+}
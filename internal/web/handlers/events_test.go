@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/foxzi/sendry/internal/web/models"
+	"github.com/foxzi/sendry/internal/web/views"
+)
+
+func TestEventStreamSend(t *testing.T) {
+	w := httptest.NewRecorder()
+	stream := newEventStream(w)
+
+	if err := stream.Send("progress", []byte("<div>\n  42%\r\n</div>\n")); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if err := stream.KeepAlive(); err != nil {
+		t.Fatalf("KeepAlive() error = %v", err)
+	}
+
+	if ct := w.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	want := "event: progress\ndata: <div>\ndata:   42%\ndata: </div>\n\n: keep-alive\n\n"
+	if got := w.Body.String(); got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
+
+func TestJobFragments(t *testing.T) {
+	v, err := views.New()
+	if err != nil {
+		t.Fatalf("views.New() error = %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	data := map[string]any{
+		"Job":      models.SendJob{ID: "job-1", Status: "running"},
+		"Stats":    models.JobStats{Total: 10, Sent: 4},
+		"Progress": 40,
+	}
+	if err := v.RenderFragment(w, "job_view", "job_progress", data); err != nil {
+		t.Fatalf("RenderFragment(job_progress) error = %v", err)
+	}
+	if body := w.Body.String(); !strings.Contains(body, "40% complete") || strings.Contains(body, "<html") {
+		t.Errorf("job_progress fragment = %q", body)
+	}
+
+	w = httptest.NewRecorder()
+	row := jobRow(models.SendJob{ID: "job-1", CampaignName: "Spring", Status: "running", CreatedAt: time.Now()}, 40)
+	if err := v.RenderFragment(w, "dashboard", "dashboard_job_row", row); err != nil {
+		t.Fatalf("RenderFragment(dashboard_job_row) error = %v", err)
+	}
+	if body := w.Body.String(); !strings.Contains(body, `sse-swap="job-job-1"`) || !strings.Contains(body, "Spring") {
+		t.Errorf("dashboard_job_row fragment = %q", body)
+	}
+}
@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/foxzi/sendry/internal/web/middleware"
+	"github.com/foxzi/sendry/internal/web/models"
+)
+
+// freezeTimeLayout is the layout of datetime-local form inputs
+const freezeTimeLayout = "2006-01-02T15:04"
+
+// FreezeWindows lists freeze windows
+func (h *Handlers) FreezeWindows(w http.ResponseWriter, r *http.Request) {
+	h.renderFreezeWindows(w, r, "")
+}
+
+func (h *Handlers) renderFreezeWindows(w http.ResponseWriter, r *http.Request, errMsg string) {
+	now := time.Now()
+	windows, err := h.freezes.List(now)
+	if err != nil {
+		h.logger.Error("failed to list freeze windows", "error", err)
+		h.error(w, http.StatusInternalServerError, "Failed to load freeze windows")
+		return
+	}
+
+	data := map[string]any{
+		"Title":   "Freeze Windows",
+		"Active":  "settings",
+		"User":    h.getUserFromContext(r),
+		"Windows": windows,
+		"Now":     now,
+		"Error":   errMsg,
+	}
+
+	h.render(w, "settings_freeze", data)
+}
+
+// FreezeWindowCreate creates a freeze window
+func (h *Handlers) FreezeWindowCreate(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		h.error(w, http.StatusBadRequest, "Invalid form data")
+		return
+	}
+
+	f := &models.FreezeWindow{
+		Name:      strings.TrimSpace(r.FormValue("name")),
+		Domain:    strings.TrimSpace(r.FormValue("domain")),
+		Reason:    strings.TrimSpace(r.FormValue("reason")),
+		CreatedBy: middleware.GetUserEmail(r),
+	}
+	if msg := parseFreezeWindow(f, r.FormValue("starts_at"), r.FormValue("ends_at")); msg != "" {
+		h.renderFreezeWindows(w, r, msg)
+		return
+	}
+
+	if err := h.freezes.Create(f); err != nil {
+		h.logger.Error("failed to create freeze window", "error", err)
+		h.error(w, http.StatusInternalServerError, "Failed to create freeze window")
+		return
+	}
+
+	h.settings.LogAction(r, middleware.GetUserID(r), middleware.GetUserEmail(r),
+		"create", "freeze_window", f.ID, freezeDetails(f, nil))
+	http.Redirect(w, r, "/settings/freeze", http.StatusSeeOther)
+}
+
+// FreezeWindowDelete deletes a freeze window
+func (h *Handlers) FreezeWindowDelete(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	f, err := h.freezes.GetByID(id)
+	if err != nil || f == nil {
+		h.error(w, http.StatusNotFound, "Freeze window not found")
+		return
+	}
+
+	if err := h.freezes.Delete(id); err != nil {
+		h.logger.Error("failed to delete freeze window", "error", err)
+		h.error(w, http.StatusInternalServerError, "Failed to delete freeze window")
+		return
+	}
+
+	h.settings.LogAction(r, middleware.GetUserID(r), middleware.GetUserEmail(r),
+		"delete", "freeze_window", id, freezeDetails(f, nil))
+	http.Redirect(w, r, "/settings/freeze", http.StatusSeeOther)
+}
+
+// JobFreezeOverride lets a job held by a freeze window send anyway
+func (h *Handlers) JobFreezeOverride(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	job, err := h.jobs.GetByID(id)
+	if err != nil || job == nil {
+		h.error(w, http.StatusNotFound, "Job not found")
+		return
+	}
+
+	if !jobPending(job.Status) {
+		h.error(w, http.StatusBadRequest, "Cannot override freeze for job in status: "+job.Status)
+		return
+	}
+
+	f, err := h.jobFreeze(job)
+	if err != nil {
+		h.logger.Error("failed to check freeze windows", "error", err)
+		h.error(w, http.StatusInternalServerError, "Failed to check freeze windows")
+		return
+	}
+	if f == nil {
+		h.error(w, http.StatusBadRequest, "Job is not held by a freeze window")
+		return
+	}
+
+	if err := h.jobs.SetFreezeOverride(id); err != nil {
+		h.logger.Error("failed to override freeze", "error", err)
+		h.error(w, http.StatusInternalServerError, "Failed to override freeze")
+		return
+	}
+
+	h.logFreezeOverride(r, job.ID, f)
+	h.publishJob(id)
+	http.Redirect(w, r, "/jobs/"+id, http.StatusSeeOther)
+}
+
+// activeFreeze returns the freeze window covering the campaign now, or nil
+func (h *Handlers) activeFreeze(c *models.Campaign) (*models.FreezeWindow, error) {
+	return h.freezes.Active(c.SenderDomain(), time.Now())
+}
+
+// jobFreeze returns the freeze window that keeps the job from sending, or
+// nil if the job may send
+func (h *Handlers) jobFreeze(job *models.SendJob) (*models.FreezeWindow, error) {
+	if job.FreezeOverride {
+		return nil, nil
+	}
+
+	c, err := h.campaigns.GetByID(job.CampaignID)
+	if err != nil || c == nil {
+		return nil, err
+	}
+	return h.activeFreeze(c)
+}
+
+// checkJobFreeze writes an error and returns false if a freeze window
+// keeps the job from starting
+func (h *Handlers) checkJobFreeze(w http.ResponseWriter, job *models.SendJob) bool {
+	f, err := h.jobFreeze(job)
+	if err != nil {
+		h.logger.Error("failed to check freeze windows", "error", err)
+		h.error(w, http.StatusInternalServerError, "Failed to check freeze windows")
+		return false
+	}
+	if f != nil {
+		h.error(w, http.StatusConflict, freezeMessage(f)+"; an admin can override the freeze on the job page")
+		return false
+	}
+	return true
+}
+
+// logFreezeOverride records a freeze override in the audit log
+func (h *Handlers) logFreezeOverride(r *http.Request, jobID string, f *models.FreezeWindow) {
+	h.logger.Warn("freeze window overridden", "job_id", jobID, "freeze", f.Name, "user", middleware.GetUserEmail(r))
+	h.settings.LogAction(r, middleware.GetUserID(r), middleware.GetUserEmail(r),
+		"freeze_override", "job", jobID, freezeDetails(f, map[string]string{"freeze_window_id": f.ID}))
+}
+
+// jobPending returns true for job statuses that still have mail to send
+func jobPending(status string) bool {
+	return status == "scheduled" || status == "running" || status == "paused"
+}
+
+// freezeMessage describes why sending is frozen
+func freezeMessage(f *models.FreezeWindow) string {
+	msg := "Sending is frozen for " + f.Scope() + " until " + f.EndsAt.UTC().Format("2006-01-02 15:04 UTC") + " (" + f.Name
+	if f.Reason != "" {
+		msg += ": " + f.Reason
+	}
+	return msg + ")"
+}
+
+// parseFreezeWindow validates the form fields of a freeze window and
+// returns an error message, or "" if valid
+func parseFreezeWindow(f *models.FreezeWindow, startsAt, endsAt string) string {
+	if f.Name == "" {
+		return "Name is required"
+	}
+	if strings.ContainsAny(f.Domain, "@ /") {
+		return "Domain must be a domain name, not an address"
+	}
+
+	var err error
+	if f.StartsAt, err = time.Parse(freezeTimeLayout, startsAt); err != nil {
+		return "Invalid start time"
+	}
+	if f.EndsAt, err = time.Parse(freezeTimeLayout, endsAt); err != nil {
+		return "Invalid end time"
+	}
+	if !f.EndsAt.After(f.StartsAt) {
+		return "End time must be after start time"
+	}
+	return ""
+}
+
+// freezeDetails returns the audit log details of a freeze window
+func freezeDetails(f *models.FreezeWindow, extra map[string]string) string {
+	details := map[string]string{
+		"name":      f.Name,
+		"scope":     f.Scope(),
+		"starts_at": f.StartsAt.UTC().Format(time.RFC3339),
+		"ends_at":   f.EndsAt.UTC().Format(time.RFC3339),
+	}
+	for k, v := range extra {
+		details[k] = v
+	}
+	b, _ := json.Marshal(details)
+	return string(b)
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/foxzi/sendry/internal/web/models"
+)
+
+func TestParseFreezeWindow(t *testing.T) {
+	tests := []struct {
+		name     string
+		window   models.FreezeWindow
+		startsAt string
+		endsAt   string
+		wantErr  string
+	}{
+		{
+			name:     "valid",
+			window:   models.FreezeWindow{Name: "Black Friday", Domain: "example.com"},
+			startsAt: "2026-11-27T00:00",
+			endsAt:   "2026-11-30T23:59",
+		},
+		{
+			name:     "missing name",
+			startsAt: "2026-11-27T00:00",
+			endsAt:   "2026-11-30T23:59",
+			wantErr:  "Name",
+		},
+		{
+			name:     "address instead of domain",
+			window:   models.FreezeWindow{Name: "Freeze", Domain: "news@example.com"},
+			startsAt: "2026-11-27T00:00",
+			endsAt:   "2026-11-30T23:59",
+			wantErr:  "Domain",
+		},
+		{
+			name:     "invalid start",
+			window:   models.FreezeWindow{Name: "Freeze"},
+			startsAt: "tomorrow",
+			endsAt:   "2026-11-30T23:59",
+			wantErr:  "start",
+		},
+		{
+			name:     "ends before start",
+			window:   models.FreezeWindow{Name: "Freeze"},
+			startsAt: "2026-11-30T00:00",
+			endsAt:   "2026-11-27T00:00",
+			wantErr:  "after",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := tt.window
+			msg := parseFreezeWindow(&f, tt.startsAt, tt.endsAt)
+			if tt.wantErr == "" {
+				if msg != "" {
+					t.Fatalf("parseFreezeWindow() = %q, want valid", msg)
+				}
+				if want := time.Date(2026, 11, 27, 0, 0, 0, 0, time.UTC); !f.StartsAt.Equal(want) {
+					t.Errorf("StartsAt = %v, want %v", f.StartsAt, want)
+				}
+				return
+			}
+			if !strings.Contains(msg, tt.wantErr) {
+				t.Errorf("parseFreezeWindow() = %q, want error about %q", msg, tt.wantErr)
+			}
+		})
+	}
+}
@@ -6,38 +6,60 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/foxzi/sendry/internal/web/alert"
 	"github.com/foxzi/sendry/internal/web/auth"
+	"github.com/foxzi/sendry/internal/web/backup"
 	"github.com/foxzi/sendry/internal/web/config"
 	"github.com/foxzi/sendry/internal/web/crypto"
 	"github.com/foxzi/sendry/internal/web/db"
+	"github.com/foxzi/sendry/internal/web/dnspublish"
+	"github.com/foxzi/sendry/internal/web/health"
 	"github.com/foxzi/sendry/internal/web/middleware"
+	"github.com/foxzi/sendry/internal/web/models"
 	"github.com/foxzi/sendry/internal/web/repository"
 	"github.com/foxzi/sendry/internal/web/router"
 	"github.com/foxzi/sendry/internal/web/sendry"
+	"github.com/foxzi/sendry/internal/web/tracking"
 	"github.com/foxzi/sendry/internal/web/views"
+	"github.com/foxzi/sendry/internal/web/worker"
 )
 
 type Handlers struct {
-	cfg        *config.Config
-	db         *db.DB
-	logger     *slog.Logger
-	views      *views.Engine
-	sendry     *sendry.Manager
-	oidc       *auth.OIDCProvider
-	templates  *repository.TemplateRepository
-	recipients *repository.RecipientRepository
-	campaigns  *repository.CampaignRepository
-	jobs       *repository.JobRepository
-	settings   *repository.SettingsRepository
-	dkim       *repository.DKIMRepository
-	domains    *repository.DomainRepository
-	sends      *repository.SendRepository
-	apiKeys    *repository.APIKeyRepository
-	blocks     *repository.BlockRepository
-	media      *repository.MediaRepository
-	userSMTP   *repository.UserSMTPRepository
-	cipher     *crypto.Cipher
-	router     *router.EmailRouter
+	cfg         *config.Config
+	db          *db.DB
+	logger      *slog.Logger
+	views       *views.Engine
+	sendry      *sendry.Manager
+	oidc        *auth.OIDCProvider
+	templates   *repository.TemplateRepository
+	recipients  *repository.RecipientRepository
+	campaigns   *repository.CampaignRepository
+	jobs        *repository.JobRepository
+	settings    *repository.SettingsRepository
+	dkim        *repository.DKIMRepository
+	domains     *repository.DomainRepository
+	sends       *repository.SendRepository
+	apiKeys     *repository.APIKeyRepository
+	sessions    *repository.SessionRepository
+	orgs        *repository.OrganizationRepository
+	blocks      *repository.BlockRepository
+	media       *repository.MediaRepository
+	userSMTP    *repository.UserSMTPRepository
+	deployments *repository.DeploymentRepository
+	freezes     *repository.FreezeRepository
+	tracking    *repository.TrackingRepository
+	tracker     *tracking.Tracker
+	cipher      *crypto.Cipher
+	router      *router.EmailRouter
+	progress    *worker.Progress
+	backups     *backup.Manager
+	health      *health.Monitor
+	alertEngine *alert.Engine
+	alerts      *repository.AlertRepository
+
+	healthSamples *repository.HealthRepository
+	dnsPublisher  *dnspublish.Publisher
+	mtaSTS        *repository.MTASTSRepository
 }
 
 func New(cfg *config.Config, db *db.DB, logger *slog.Logger, v *views.Engine, oidcProvider *auth.OIDCProvider) *Handlers {
@@ -76,26 +98,36 @@ func New(cfg *config.Config, db *db.DB, logger *slog.Logger, v *views.Engine, oi
 	})
 
 	return &Handlers{
-		cfg:        cfg,
-		db:         db,
-		logger:     logger,
-		views:      v,
-		sendry:     sendryMgr,
-		oidc:       oidcProvider,
-		templates:  templates,
-		recipients: repository.NewRecipientRepository(db.DB),
-		campaigns:  repository.NewCampaignRepository(db.DB),
-		jobs:       repository.NewJobRepository(db.DB),
-		settings:   settings,
-		dkim:       repository.NewDKIMRepository(db.DB),
-		domains:    domains,
-		sends:      sends,
-		apiKeys:    apiKeys,
-		blocks:     repository.NewBlockRepository(db.DB),
-		media:      repository.NewMediaRepository(db.DB),
-		userSMTP:   repository.NewUserSMTPRepository(db.DB),
-		cipher:     ciph,
-		router:     emailRouter,
+		cfg:         cfg,
+		db:          db,
+		logger:      logger,
+		views:       v,
+		sendry:      sendryMgr,
+		oidc:        oidcProvider,
+		templates:   templates,
+		recipients:  repository.NewRecipientRepository(db.DB),
+		campaigns:   repository.NewCampaignRepository(db.DB),
+		jobs:        repository.NewJobRepository(db.DB),
+		settings:    settings,
+		dkim:        repository.NewDKIMRepository(db.DB),
+		domains:     domains,
+		sends:       sends,
+		apiKeys:     apiKeys,
+		sessions:    repository.NewSessionRepository(db.DB),
+		orgs:        repository.NewOrganizationRepository(db.DB),
+		blocks:      repository.NewBlockRepository(db.DB),
+		media:       repository.NewMediaRepository(db.DB),
+		userSMTP:    repository.NewUserSMTPRepository(db.DB),
+		deployments: repository.NewDeploymentRepository(db.DB),
+		freezes:     repository.NewFreezeRepository(db.DB),
+		tracking:    repository.NewTrackingRepository(db.DB),
+		alerts:      repository.NewAlertRepository(db.DB),
+		tracker:     tracking.New(cfg.Tracking.BaseURL, cfg.Auth.SessionSecret),
+		cipher:      ciph,
+		router:      emailRouter,
+
+		healthSamples: repository.NewHealthRepository(db.DB),
+		mtaSTS:        repository.NewMTASTSRepository(db.DB),
 	}
 }
 
@@ -108,11 +140,24 @@ func (h *Handlers) Health(w http.ResponseWriter, r *http.Request) {
 // Dashboard
 func (h *Handlers) Dashboard(w http.ResponseWriter, r *http.Request) {
 	// Get stats from DB
+	orgID := middleware.GetOrgID(r)
 	var templates, campaigns, recipients, activeJobs int
-	h.db.QueryRow("SELECT COUNT(*) FROM templates").Scan(&templates)
-	h.db.QueryRow("SELECT COUNT(*) FROM campaigns").Scan(&campaigns)
-	h.db.QueryRow("SELECT COUNT(*) FROM recipients").Scan(&recipients)
-	h.db.QueryRow("SELECT COUNT(*) FROM send_jobs WHERE status = 'running'").Scan(&activeJobs)
+	h.db.QueryRow("SELECT COUNT(*) FROM templates WHERE ? IN ('', org_id)", orgID).Scan(&templates)
+	h.db.QueryRow("SELECT COUNT(*) FROM campaigns WHERE ? IN ('', org_id)", orgID).Scan(&campaigns)
+	h.db.QueryRow(`SELECT COUNT(*) FROM recipients
+		WHERE list_id IN (SELECT id FROM recipient_lists WHERE ? IN ('', org_id))`, orgID).Scan(&recipients)
+	h.db.QueryRow(`SELECT COUNT(*) FROM send_jobs
+		WHERE status = 'running' AND campaign_id IN (SELECT id FROM campaigns WHERE ? IN ('', org_id))`, orgID).Scan(&activeJobs)
+
+	jobs, _, err := h.jobs.List(models.JobListFilter{OrgID: orgID, Limit: 5})
+	if err != nil {
+		h.logger.Error("failed to list recent jobs", "error", err)
+	}
+	recentJobs := make([]map[string]any, len(jobs))
+	for i, job := range jobs {
+		stats, _ := h.jobs.GetStats(job.ID)
+		recentJobs[i] = jobRow(job, worker.JobProgress(stats))
+	}
 
 	data := map[string]any{
 		"Title":  "Dashboard",
@@ -124,8 +169,9 @@ func (h *Handlers) Dashboard(w http.ResponseWriter, r *http.Request) {
 			"Recipients": recipients,
 			"ActiveJobs": activeJobs,
 		},
-		"Servers":    h.getServersStatus(),
-		"RecentJobs": []any{},
+		"Servers":    h.getServersStatus(r),
+		"RecentJobs": recentJobs,
+		"Live":       h.progress != nil,
 	}
 
 	h.render(w, "dashboard", data)
@@ -162,17 +208,75 @@ func (h *Handlers) getUserFromContext(r *http.Request) map[string]any {
 		email = "unknown"
 	}
 	role := middleware.GetUserRole(r)
+
+	// Users in several organizations get a switcher
+	var orgs []models.Organization
+	if role == "admin" {
+		orgs, _ = h.orgs.List()
+	} else if userID := middleware.GetUserID(r); userID != "" {
+		orgs, _ = h.orgs.ListForUser(userID)
+	}
+	if len(orgs) < 2 {
+		orgs = nil
+	}
+
 	return map[string]any{
 		"Email":   email,
 		"Role":    role,
 		"IsAdmin": role == "admin",
+		"OrgID":   middleware.GetOrgID(r),
+		"OrgRole": middleware.GetOrgRole(r),
+		"Orgs":    orgs,
+	}
+}
+
+// orgServers returns the configured Sendry servers the current
+// organization may use
+func (h *Handlers) orgServers(r *http.Request) []config.SendryServer {
+	servers := make([]config.SendryServer, 0, len(h.cfg.Sendry.Servers))
+	for _, s := range h.cfg.Sendry.Servers {
+		if middleware.ServerAllowed(r, s.Name) {
+			servers = append(servers, s)
+		}
 	}
+	return servers
+}
+
+// orgServersAllowed reports whether the current organization may use all
+// of the servers
+func orgServersAllowed(r *http.Request, servers ...string) bool {
+	for _, name := range servers {
+		if !middleware.ServerAllowed(r, name) {
+			return false
+		}
+	}
+	return true
+}
+
+// orgOwns reports whether an object of the organization is visible in the
+// current organization. Objects without an organization are shared.
+func orgOwns(r *http.Request, orgID string) bool {
+	current := middleware.GetOrgID(r)
+	return current == "" || orgID == "" || orgID == current
+}
+
+// orgServersStatus returns health status of the servers the current
+// organization may use
+func (h *Handlers) orgServersStatus(r *http.Request) []*sendry.ServerStatus {
+	statuses := h.sendry.GetAllStatus(r.Context())
+	allowed := make([]*sendry.ServerStatus, 0, len(statuses))
+	for _, s := range statuses {
+		if middleware.ServerAllowed(r, s.Name) {
+			allowed = append(allowed, s)
+		}
+	}
+	return allowed
 }
 
 // Get servers status from config (quick, no API calls)
-func (h *Handlers) getServersStatus() []map[string]any {
+func (h *Handlers) getServersStatus(r *http.Request) []map[string]any {
 	servers := make([]map[string]any, 0, len(h.cfg.Sendry.Servers))
-	for _, s := range h.cfg.Sendry.Servers {
+	for _, s := range h.orgServers(r) {
 		servers = append(servers, map[string]any{
 			"Name":      s.Name,
 			"Env":       s.Env,
@@ -204,7 +308,7 @@ func sanitizeFilename(s string) string {
 
 // NavStats returns queue stats for the navbar badge
 func (h *Handlers) NavStats(w http.ResponseWriter, r *http.Request) {
-	statuses := h.sendry.GetAllStatus(r.Context())
+	statuses := h.orgServersStatus(r)
 	totalQueue := 0
 	for _, s := range statuses {
 		totalQueue += s.QueueSize
@@ -216,7 +320,7 @@ func (h *Handlers) NavStats(w http.ResponseWriter, r *http.Request) {
 
 // Get servers status with actual health checks (slow, makes API calls)
 func (h *Handlers) getServersStatusLive(r *http.Request) []map[string]any {
-	statuses := h.sendry.GetAllStatus(r.Context())
+	statuses := h.orgServersStatus(r)
 	servers := make([]map[string]any, 0, len(statuses))
 	for _, s := range statuses {
 		servers = append(servers, map[string]any{
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/foxzi/sendry/internal/web/middleware"
+	"github.com/foxzi/sendry/internal/web/models"
+	emailtpl "github.com/foxzi/sendry/internal/web/template"
+)
+
+// Headless actions run the services of the HTTP handlers for the sendry-web
+// command line. They act as an administrator outside any organization and
+// are recorded in the audit log and deployment history under the actor.
+
+// TemplateSyncResult is the outcome of deploying a template file
+type TemplateSyncResult struct {
+	TemplateID string            `json:"template_id"`
+	Name       string            `json:"name"`
+	Version    int               `json:"version"`
+	Change     string            `json:"change"` // created, updated, unchanged
+	Results    []APIDeployResult `json:"results,omitempty"`
+}
+
+// PolicyViolationError is returned when a template breaks its content policy
+type PolicyViolationError struct {
+	Violations []emailtpl.Violation
+}
+
+func (e *PolicyViolationError) Error() string {
+	var b strings.Builder
+	b.WriteString(policyError(e.Violations).Error())
+	for _, v := range e.Violations {
+		fmt.Fprintf(&b, "\n  line %d: <%s %s> %s: %s", v.Line, v.Tag, v.Attr, v.URL, v.Reason)
+	}
+	return b.String()
+}
+
+// headlessRequest returns a request that carries the actor as the
+// authenticated user, for the services that take the request of a handler
+func headlessRequest(ctx context.Context, actor string) *http.Request {
+	r, _ := http.NewRequestWithContext(middleware.WithUserEmail(ctx, actor), http.MethodPost, "/", nil)
+	return r
+}
+
+// SyncTemplate saves the template of an export file under its name, creating
+// it or adding a version if its content changed, and deploys the current
+// version to the servers
+func (h *Handlers) SyncTemplate(ctx context.Context, actor string, data *TemplateExportData, changeNote string, servers []string) (*TemplateSyncResult, error) {
+	if data.Name == "" || data.Subject == "" {
+		return nil, errors.New("name and subject are required")
+	}
+	for _, server := range servers {
+		if _, err := h.sendry.GetClient(server); err != nil {
+			return nil, fmt.Errorf("server not found: %s", server)
+		}
+	}
+	r := headlessRequest(ctx, actor)
+
+	existing, err := h.templates.GetByName(data.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template: %w", err)
+	}
+
+	var t *models.Template
+	change := "unchanged"
+	if existing == nil {
+		t = &models.Template{
+			Name:          data.Name,
+			Description:   data.Description,
+			Subject:       data.Subject,
+			HTML:          data.HTML,
+			Text:          data.Text,
+			Variables:     data.Variables,
+			Folder:        data.Folder,
+			BlockExternal: data.BlockExternal,
+		}
+		if violations := h.policyViolations(t); len(violations) > 0 {
+			return nil, &PolicyViolationError{Violations: violations}
+		}
+		if err := h.templates.Create(t, actor); err != nil {
+			return nil, fmt.Errorf("failed to create template: %w", err)
+		}
+		change = "created"
+		h.settings.LogAction(r, "", actor, "create", "template", t.ID,
+			auditJSON(map[string]any{"name": t.Name, "source": "cli"}))
+	} else {
+		// GetByName leaves out the layout settings, which Update writes back
+		if t, err = h.templates.GetByID(existing.ID); err != nil || t == nil {
+			return nil, fmt.Errorf("failed to get template: %w", err)
+		}
+		if templateFileChanged(t, data) {
+			t.Description, t.Subject, t.HTML, t.Text = data.Description, data.Subject, data.HTML, data.Text
+			t.Variables, t.BlockExternal = data.Variables, data.BlockExternal
+			if data.Folder != "" {
+				t.Folder = data.Folder
+			}
+			if violations := h.policyViolations(t); len(violations) > 0 {
+				return nil, &PolicyViolationError{Violations: violations}
+			}
+			if changeNote == "" {
+				changeNote = "Updated template"
+			}
+			if err := h.templates.Update(t, changeNote, actor); err != nil {
+				return nil, fmt.Errorf("failed to update template: %w", err)
+			}
+			change = "updated"
+			h.settings.LogAction(r, "", actor, "update", "template", t.ID,
+				auditJSON(map[string]any{"name": t.Name, "source": "cli"}))
+		}
+	}
+
+	result := &TemplateSyncResult{TemplateID: t.ID, Name: t.Name, Version: t.CurrentVersion, Change: change}
+	if len(servers) > 0 {
+		r = h.withDeployCorrelation(r)
+		h.logDeployAction(r, deployEntityTemplate, t.ID, servers)
+		result.Results = h.deployTemplateTo(r, t, t.CurrentVersion, servers)
+	}
+	return result, nil
+}
+
+// templateFileChanged reports whether an export file differs from the
+// current version of a template
+func templateFileChanged(t *models.Template, data *TemplateExportData) bool {
+	return t.Description != data.Description || t.Subject != data.Subject || t.HTML != data.HTML ||
+		t.Text != data.Text || t.Variables != data.Variables || t.BlockExternal != data.BlockExternal ||
+		(data.Folder != "" && t.Folder != data.Folder)
+}
+
+// StartCampaign starts the send job of a campaign like
+// POST /api/v1/campaigns/{id}/send
+func (h *Handlers) StartCampaign(ctx context.Context, actor, campaignID string, req *APICampaignSendRequest) (*models.SendJob, error) {
+	c, err := h.campaigns.GetByID(campaignID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get campaign: %w", err)
+	}
+	if c == nil {
+		return nil, fmt.Errorf("campaign not found: %s", campaignID)
+	}
+
+	job, aerr := h.sendCampaign(headlessRequest(ctx, actor), c, req)
+	if aerr != nil {
+		return nil, aerr
+	}
+	return job, nil
+}
+
+// JobProgress returns the status and item counts of a send job
+func (h *Handlers) JobProgress(jobID string) (*models.SendJob, models.JobStats, error) {
+	job, err := h.jobs.GetByID(jobID)
+	if err != nil || job == nil {
+		return nil, models.JobStats{}, fmt.Errorf("job not found: %s", jobID)
+	}
+	stats, err := h.jobs.GetStats(jobID)
+	return job, stats, err
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/foxzi/sendry/internal/web/config"
+	"github.com/foxzi/sendry/internal/web/sendry"
+	sendryclient "github.com/foxzi/sendry/pkg/client"
+)
+
+func TestSyncTemplate(t *testing.T) {
+	h, _, cleanup := newDomainViewTestHandlers(t)
+	defer cleanup()
+
+	var deployed []sendryclient.TemplateCreateRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req sendryclient.TemplateCreateRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		deployed = append(deployed, req)
+		json.NewEncoder(w).Encode(map[string]any{"id": "remote-1", "name": req.Name})
+	}))
+	defer srv.Close()
+	h.cfg.Sendry.Servers = []config.SendryServer{{Name: "prod", BaseURL: srv.URL}}
+	h.sendry = sendry.NewManager(h.cfg.Sendry.Servers)
+
+	ctx := context.Background()
+	file := &TemplateExportData{Name: "Welcome", Subject: "Hello", HTML: "<p>v1</p>"}
+
+	res, err := h.SyncTemplate(ctx, "ci", file, "", []string{"prod"})
+	if err != nil {
+		t.Fatalf("SyncTemplate() error = %v", err)
+	}
+	if res.Change != "created" || res.Version != 1 || len(res.Results) != 1 || res.Results[0].Status != "deployed" {
+		t.Errorf("first sync = %+v", res)
+	}
+	if len(deployed) != 1 || deployed[0].HTML != "<p>v1</p>" {
+		t.Errorf("deployed = %+v", deployed)
+	}
+
+	// The same file only redeploys
+	res, err = h.SyncTemplate(ctx, "ci", file, "", []string{"prod"})
+	if err != nil || res.Change != "unchanged" || res.Version != 1 || len(deployed) != 2 {
+		t.Errorf("second sync = %+v, %v", res, err)
+	}
+
+	file.HTML = "<p>v2</p>"
+	res, err = h.SyncTemplate(ctx, "ci", file, "ship v2", nil)
+	if err != nil || res.Change != "updated" || res.Version != 2 || len(res.Results) != 0 || len(deployed) != 2 {
+		t.Fatalf("update without servers = %+v, %v", res, err)
+	}
+	v2, err := h.templates.GetVersion(res.TemplateID, 2)
+	if err != nil || v2 == nil || v2.ChangeNote != "ship v2" || v2.CreatedBy != "ci" {
+		t.Errorf("version 2 = %+v, %v", v2, err)
+	}
+
+	if _, err := h.SyncTemplate(ctx, "ci", file, "", []string{"missing"}); err == nil {
+		t.Error("sync to an unknown server succeeded")
+	}
+	if _, err := h.SyncTemplate(ctx, "ci", &TemplateExportData{Name: "No subject"}, "", nil); err == nil {
+		t.Error("sync without a subject succeeded")
+	}
+
+	blocked := &TemplateExportData{Name: "Tracked", Subject: "Hi", HTML: `<img src="https://tracker.example.net/p.gif">`, BlockExternal: true}
+	var policyErr *PolicyViolationError
+	if _, err := h.SyncTemplate(ctx, "ci", blocked, "", nil); !errors.As(err, &policyErr) {
+		t.Errorf("sync breaking the policy = %v, want PolicyViolationError", err)
+	}
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/foxzi/sendry/internal/web/health"
+	"github.com/foxzi/sendry/internal/web/middleware"
+	sendryclient "github.com/foxzi/sendry/pkg/client"
+)
+
+// healthPeriods are the trend chart periods of the server health dashboard
+var healthPeriods = map[string]time.Duration{
+	"1h":  time.Hour,
+	"24h": 24 * time.Hour,
+	"7d":  7 * 24 * time.Hour,
+}
+
+// SetHealthMonitor sets the server health monitor
+func (h *Handlers) SetHealthMonitor(m *health.Monitor) {
+	h.health = m
+}
+
+// ServerHealth shows the health dashboard of a server with a live sample
+// and trend charts of the recorded samples
+func (h *Handlers) ServerHealth(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if _, err := h.sendry.GetServerByName(name); err != nil || h.health == nil {
+		h.error(w, http.StatusNotFound, "Server not found")
+		return
+	}
+
+	current := h.health.Collect(r.Context(), name)
+	cfg := h.health.Config()
+
+	data := map[string]any{
+		"Title":      name + " Health",
+		"Active":     "servers",
+		"User":       h.getUserFromContext(r),
+		"ServerName": name,
+		"Health":     current,
+		"Config":     cfg,
+		"Queued":     current.QueuePending + current.QueueRetrying,
+		"RateLimit":  int(current.RateLimitUsage * 100),
+	}
+	if current.CertExpiresAt != nil {
+		data["CertDays"] = int(time.Until(*current.CertExpiresAt).Hours() / 24)
+	}
+
+	// Not available on servers without TLS certificates
+	if client, err := h.sendry.GetClient(name); err == nil {
+		if certs, err := client.ListTLSCertificates(r.Context()); err == nil {
+			data["Certificates"] = tlsCertificateRows(certs.Certificates, cfg.Thresholds.CertExpiryDays)
+		}
+	}
+
+	h.render(w, "server_health", data)
+}
+
+// ServerTLSRenew forces the renewal of an ACME certificate of a server
+func (h *Handlers) ServerTLSRenew(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	domain := r.PathValue("domain")
+
+	client, err := h.sendry.GetClient(name)
+	if err != nil {
+		h.error(w, http.StatusNotFound, "Server not found")
+		return
+	}
+
+	cert, err := client.RenewTLSCertificate(r.Context(), domain)
+	if err != nil {
+		h.logger.Error("failed to renew TLS certificate", "error", err, "server", name, "domain", domain)
+		h.error(w, http.StatusInternalServerError, fmt.Sprintf("Failed to renew certificate: %v", err))
+		return
+	}
+
+	h.settings.LogAction(r, middleware.GetUserID(r), middleware.GetUserEmail(r),
+		"renew", "tls_certificate", domain, auditJSON(map[string]any{
+			"server":    name,
+			"not_after": cert.NotAfter,
+		}))
+
+	http.Redirect(w, r, "/servers/"+name+"/health#tls", http.StatusSeeOther)
+}
+
+// tlsCertificateRow is a certificate of the TLS card with its status badge
+type tlsCertificateRow struct {
+	sendryclient.TLSCertificate
+	Badge  string
+	Status string
+}
+
+// tlsCertificateRows returns the rows of the TLS card, certificates expiring
+// in less than expiryDays are marked as warnings
+func tlsCertificateRows(certs []sendryclient.TLSCertificate, expiryDays int) []tlsCertificateRow {
+	rows := make([]tlsCertificateRow, 0, len(certs))
+	for _, c := range certs {
+		row := tlsCertificateRow{TLSCertificate: c, Badge: "running"}
+		switch {
+		case c.Error != "":
+			row.Badge, row.Status = "failed", c.Error
+		case c.DaysLeft == nil:
+			row.Badge, row.Status = "pending", "Unknown"
+		case *c.DaysLeft < 0:
+			row.Badge, row.Status = "failed", "Expired"
+		case *c.DaysLeft < expiryDays:
+			row.Badge, row.Status = "warning", fmt.Sprintf("%d days left", *c.DaysLeft)
+		default:
+			row.Status = fmt.Sprintf("%d days left", *c.DaysLeft)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// ServerHealthData returns the recorded health samples of a server as JSON
+func (h *Handlers) ServerHealthData(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if _, err := h.sendry.GetServerByName(name); err != nil || h.health == nil {
+		h.jsonError(w, "Server not found", http.StatusNotFound)
+		return
+	}
+
+	period := r.URL.Query().Get("period")
+	window, ok := healthPeriods[period]
+	if !ok {
+		period, window = "24h", healthPeriods["24h"]
+	}
+
+	samples, err := h.healthSamples.List(name, time.Now().Add(-window))
+	if err != nil {
+		h.logger.Error("failed to list server health", "server", name, "error", err)
+		h.jsonError(w, "Failed to get server health", http.StatusInternalServerError)
+		return
+	}
+
+	h.json(w, http.StatusOK, map[string]any{
+		"server":  name,
+		"period":  period,
+		"samples": samples,
+	})
+}
+
+// serverWarnings returns the warnings of the last recorded sample of each
+// server, empty if samples are not recorded
+func (h *Handlers) serverWarnings() map[string][]string {
+	warnings := make(map[string][]string)
+	if h.health == nil || !h.health.Config().Enabled {
+		return warnings
+	}
+	latest, err := h.healthSamples.Latest()
+	if err != nil {
+		h.logger.Error("failed to load server health", "error", err)
+		return warnings
+	}
+	// Skip samples left from a stopped monitor
+	stale := time.Now().Add(-3 * h.health.Config().Interval)
+	for name, s := range latest {
+		if s.SampledAt.After(stale) {
+			warnings[name] = s.Warnings
+		}
+	}
+	return warnings
+}
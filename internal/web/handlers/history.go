@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/foxzi/sendry/internal/web/models"
+)
+
+// historyLimit is the maximum number of job items, API sends and tracking
+// events in a recipient history
+const historyLimit = 200
+
+// historyMTALookups is the number of newest messages whose delivery status
+// is fetched from their Sendry server
+const historyMTALookups = 20
+
+// historyTimeout limits the Sendry requests of a recipient history
+const historyTimeout = 10 * time.Second
+
+// RecipientHistory shows the send history and status timeline of an email
+// address across campaigns and API sends. With Accept: application/json
+// it returns the history as JSON.
+func (h *Handlers) RecipientHistory(w http.ResponseWriter, r *http.Request) {
+	email := strings.TrimSpace(r.URL.Query().Get("email"))
+	wantJSON := strings.Contains(r.Header.Get("Accept"), "application/json")
+
+	var history *models.RecipientHistory
+	if email != "" {
+		var err error
+		history, err = h.recipientHistory(r.Context(), email)
+		if err != nil {
+			h.logger.Error("failed to load recipient history", "error", err)
+			h.error(w, http.StatusInternalServerError, "Failed to load recipient history")
+			return
+		}
+	}
+
+	if wantJSON {
+		if history == nil {
+			h.json(w, http.StatusBadRequest, map[string]any{"error": "email is required"})
+			return
+		}
+		h.json(w, http.StatusOK, history)
+		return
+	}
+
+	data := map[string]any{
+		"Title":   "Recipient History",
+		"Active":  "recipients",
+		"User":    h.getUserFromContext(r),
+		"Email":   email,
+		"History": history,
+	}
+
+	h.render(w, "recipient_history", data)
+}
+
+// recipientHistory collects the messages, list memberships, opens and
+// clicks and suppressions of an email address and builds its timeline
+func (h *Handlers) recipientHistory(ctx context.Context, email string) (*models.RecipientHistory, error) {
+	history := &models.RecipientHistory{Email: email}
+
+	var err error
+	if history.Lists, err = h.recipients.Memberships(email); err != nil {
+		return nil, err
+	}
+	items, err := h.jobs.ItemsByEmail(email, historyLimit)
+	if err != nil {
+		return nil, err
+	}
+	sends, err := h.sends.ListByRecipient(email, historyLimit)
+	if err != nil {
+		return nil, err
+	}
+	events, err := h.tracking.ListByEmail(email, historyLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	history.Messages = append(items, sends...)
+	sort.SliceStable(history.Messages, func(i, j int) bool {
+		return history.Messages[i].CreatedAt.After(history.Messages[j].CreatedAt)
+	})
+
+	ctx, cancel := context.WithTimeout(ctx, historyTimeout)
+	defer cancel()
+	h.fetchMTAStatuses(ctx, history.Messages)
+	history.Suppressions = h.fetchSuppressions(ctx, email)
+
+	history.Timeline = historyTimeline(history, events)
+	return history, nil
+}
+
+// fetchMTAStatuses sets the delivery status of the newest messages that
+// reached a Sendry server. Lookup failures are kept on the message.
+func (h *Handlers) fetchMTAStatuses(ctx context.Context, messages []models.HistoryMessage) {
+	var wg sync.WaitGroup
+	lookups := 0
+	for i := range messages {
+		m := &messages[i]
+		if m.SendryMsgID == "" || m.ServerName == "" {
+			continue
+		}
+		if lookups == historyMTALookups {
+			break
+		}
+		lookups++
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client, err := h.sendry.GetClient(m.ServerName)
+			if err != nil {
+				m.MTA = &models.MTAStatus{Error: err.Error()}
+				return
+			}
+			status, err := client.GetStatus(ctx, m.SendryMsgID)
+			if err != nil {
+				m.MTA = &models.MTAStatus{Error: err.Error()}
+				return
+			}
+			m.MTA = &models.MTAStatus{
+				Status:     status.Status,
+				RetryCount: status.RetryCount,
+				LastError:  status.LastError,
+				UpdatedAt:  status.UpdatedAt,
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// fetchSuppressions returns the suppressions of an email on all servers.
+// Servers that cannot be reached are skipped.
+func (h *Handlers) fetchSuppressions(ctx context.Context, email string) []models.HistorySuppression {
+	suppressions := []models.HistorySuppression{}
+	for _, server := range h.sendry.GetServers() {
+		client, err := h.sendry.GetClient(server.Name)
+		if err != nil {
+			continue
+		}
+		s, err := client.GetSuppression(ctx, email)
+		if err != nil {
+			h.logger.Warn("failed to get suppression", "error", err, "server", server.Name)
+			continue
+		}
+		if s != nil {
+			suppressions = append(suppressions, models.HistorySuppression{
+				Server: server.Name, Reason: s.Reason, Detail: s.Detail, CreatedAt: s.CreatedAt,
+			})
+		}
+	}
+	return suppressions
+}
+
+// historyTimeline merges the list memberships, message statuses, opens and
+// clicks and suppressions of a history into events, newest first
+func historyTimeline(history *models.RecipientHistory, events []models.TrackingEvent) []models.HistoryEvent {
+	timeline := []models.HistoryEvent{}
+	add := func(t time.Time, typ, messageID, title, detail string) {
+		if !t.IsZero() {
+			timeline = append(timeline, models.HistoryEvent{Time: t, Type: typ, MessageID: messageID, Title: title, Detail: detail})
+		}
+	}
+
+	for _, l := range history.Lists {
+		detail := ""
+		if l.Status != "active" {
+			detail = "Now " + l.Status
+		}
+		add(l.CreatedAt, "added", "", "Added to list "+l.ListName, detail)
+	}
+
+	titles := make(map[string]string, len(history.Messages))
+	for _, m := range history.Messages {
+		title := historyMessageTitle(m)
+		titles[m.ID] = title
+
+		add(m.CreatedAt, "created", m.ID, title, "")
+		if m.QueuedAt != nil {
+			add(*m.QueuedAt, "queued", m.ID, "Queued on "+m.ServerName, title)
+		}
+		switch {
+		case m.Status == "failed":
+			t := m.CreatedAt
+			if m.SentAt != nil {
+				t = *m.SentAt
+			} else if m.QueuedAt != nil {
+				t = *m.QueuedAt
+			}
+			add(t, "failed", m.ID, "Failed: "+title, m.Error)
+		case m.SentAt != nil:
+			add(*m.SentAt, "sent", m.ID, "Sent via "+m.ServerName, title)
+		}
+		if m.MTA != nil && m.MTA.Status != "" {
+			add(m.MTA.UpdatedAt, "delivery", m.ID, "Server status: "+m.MTA.Status, m.MTA.LastError)
+		}
+	}
+
+	for _, e := range events {
+		switch e.Type {
+		case models.TrackingOpen:
+			add(e.CreatedAt, "open", e.ItemID, "Opened", titles[e.ItemID])
+		case models.TrackingClick:
+			add(e.CreatedAt, "click", e.ItemID, "Clicked "+e.URL, titles[e.ItemID])
+		}
+	}
+
+	for _, s := range history.Suppressions {
+		add(s.CreatedAt, "suppressed", "", "Suppressed on "+s.Server+" ("+s.Reason+")", s.Detail)
+	}
+
+	sort.SliceStable(timeline, func(i, j int) bool { return timeline[i].Time.After(timeline[j].Time) })
+	return timeline
+}
+
+// historyMessageTitle names a message by its campaign and variant or its
+// API send subject
+func historyMessageTitle(m models.HistoryMessage) string {
+	if m.Source == models.HistorySourceAPI {
+		if m.Subject == "" {
+			return "API send"
+		}
+		return "API send: " + m.Subject
+	}
+	title := "Campaign " + m.CampaignName
+	if m.VariantName != "" {
+		title += " (" + m.VariantName + ")"
+	}
+	return title
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/foxzi/sendry/internal/web/config"
+	"github.com/foxzi/sendry/internal/web/models"
+	"github.com/foxzi/sendry/internal/web/repository"
+	"github.com/foxzi/sendry/internal/web/sendry"
+	sendryclient "github.com/foxzi/sendry/pkg/client"
+)
+
+func TestRecipientHistory(t *testing.T) {
+	h, database, cleanup := newDomainViewTestHandlers(t)
+	defer cleanup()
+
+	delivered := time.Now().Add(time.Minute).UTC().Truncate(time.Second)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/status/msg-1":
+			json.NewEncoder(w).Encode(sendryclient.StatusResponse{ID: "msg-1", Status: "delivered", UpdatedAt: delivered})
+		case "/api/v1/status/msg-2":
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(sendryclient.ErrorResponse{Error: "Message not found"})
+		case "/api/v1/suppressions/anna@example.com":
+			json.NewEncoder(w).Encode(sendryclient.Suppression{Email: "anna@example.com", Reason: "complaint", CreatedAt: delivered})
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	h.cfg.Sendry.Servers = []config.SendryServer{{Name: "prod", BaseURL: srv.URL}}
+	h.sendry = sendry.NewManager(h.cfg.Sendry.Servers)
+
+	recipients := repository.NewRecipientRepository(database.DB)
+	list := &models.RecipientList{Name: "Customers", SourceType: "manual"}
+	recipients.CreateList(list)
+	rec := &models.Recipient{ListID: list.ID, Email: "Anna@example.com", Status: "unsubscribed"}
+	if err := recipients.AddRecipient(rec); err != nil {
+		t.Fatalf("AddRecipient() error = %v", err)
+	}
+
+	tmpl := &models.Template{Name: "Invoice", Subject: "Invoice", HTML: "<p>Hi</p>"}
+	repository.NewTemplateRepository(database.DB).Create(tmpl, "")
+	campaigns := repository.NewCampaignRepository(database.DB)
+	campaign := &models.Campaign{Name: "Spring Sale", FromEmail: "news@example.com"}
+	campaigns.Create(campaign)
+	variant := &models.CampaignVariant{CampaignID: campaign.ID, Name: "A", TemplateID: tmpl.ID, Weight: 100}
+	campaigns.AddVariant(variant)
+
+	job := &models.SendJob{CampaignID: campaign.ID, RecipientListID: list.ID, Status: "running"}
+	if err := h.jobs.Create(job); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	item := &models.SendJobItem{JobID: job.ID, RecipientID: rec.ID, VariantID: variant.ID, ServerName: "prod"}
+	if err := h.jobs.CreateItem(item); err != nil {
+		t.Fatalf("CreateItem() error = %v", err)
+	}
+	h.jobs.UpdateItemStatus(item.ID, "sent", "msg-1", "")
+	if _, err := h.tracking.Record(&models.TrackingEvent{ItemID: item.ID, Type: models.TrackingClick, URL: "https://example.com/sale"}); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+
+	send := &models.Send{FromAddress: "billing@example.com", ToAddresses: `["other@example.com"]`, BCCAddresses: `["anna@EXAMPLE.com"]`,
+		Subject: "Your invoice", SenderDomain: "example.com", ServerName: "prod", ServerMsgID: "msg-2", Status: models.SendStatusSent}
+	if err := h.sends.Create(send); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	h.sends.Create(&models.Send{FromAddress: "billing@example.com", ToAddresses: `["other@example.com"]`, SenderDomain: "example.com", ServerName: "prod", Status: models.SendStatusSent})
+
+	req := httptest.NewRequest(http.MethodGet, "/recipients/history?email=anna@example.com", nil)
+	req.Header.Set("Accept", "application/json")
+	w := httptest.NewRecorder()
+	h.RecipientHistory(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body: %s", w.Code, w.Body.String())
+	}
+
+	var history models.RecipientHistory
+	if err := json.NewDecoder(w.Body).Decode(&history); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(history.Lists) != 1 || history.Lists[0].Status != "unsubscribed" {
+		t.Errorf("lists = %+v", history.Lists)
+	}
+	if len(history.Messages) != 2 {
+		t.Fatalf("messages = %+v, want the job item and the API send", history.Messages)
+	}
+	byID := map[string]models.HistoryMessage{}
+	for _, m := range history.Messages {
+		byID[m.ID] = m
+	}
+	if m := byID[item.ID]; m.CampaignName != "Spring Sale" || m.Clicks != 1 || m.MTA == nil || m.MTA.Status != "delivered" {
+		t.Errorf("job item = %+v", m)
+	}
+	if m := byID[send.ID]; m.Subject != "Your invoice" || m.MTA == nil || !strings.Contains(m.MTA.Error, "Message not found") {
+		t.Errorf("API send = %+v", m)
+	}
+	if len(history.Suppressions) != 1 || history.Suppressions[0].Reason != "complaint" {
+		t.Errorf("suppressions = %+v", history.Suppressions)
+	}
+
+	types := map[string]bool{}
+	for i, e := range history.Timeline {
+		types[e.Type] = true
+		if i > 0 && e.Time.After(history.Timeline[i-1].Time) {
+			t.Errorf("timeline is not sorted newest first: %+v", history.Timeline)
+		}
+	}
+	for _, typ := range []string{"added", "created", "sent", "delivery", "click", "suppressed"} {
+		if !types[typ] {
+			t.Errorf("timeline has no %s event: %+v", typ, history.Timeline)
+		}
+	}
+
+	// The page renders the same history
+	req = httptest.NewRequest(http.MethodGet, "/recipients/history?email=anna@example.com", nil)
+	w = httptest.NewRecorder()
+	h.RecipientHistory(w, req)
+	body := w.Body.String()
+	if !strings.Contains(body, "Spring Sale") || !strings.Contains(body, "Your invoice") || !strings.Contains(body, "Suppressed on prod (complaint)") {
+		t.Errorf("page does not show the history, body: %s", body)
+	}
+}
@@ -3,9 +3,12 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
+	"slices"
 	"strconv"
 
+	"github.com/foxzi/sendry/internal/web/middleware"
 	"github.com/foxzi/sendry/internal/web/models"
+	"github.com/foxzi/sendry/internal/web/worker"
 )
 
 func (h *Handlers) JobList(w http.ResponseWriter, r *http.Request) {
@@ -18,6 +21,7 @@ func (h *Handlers) JobList(w http.ResponseWriter, r *http.Request) {
 	offset := (page - 1) * limit
 
 	filter := models.JobListFilter{
+		OrgID:  middleware.GetOrgID(r),
 		Status: status,
 		Limit:  limit,
 		Offset: offset,
@@ -34,10 +38,7 @@ func (h *Handlers) JobList(w http.ResponseWriter, r *http.Request) {
 	jobsWithStats := make([]map[string]any, len(jobs))
 	for i, job := range jobs {
 		stats, _ := h.jobs.GetStats(job.ID)
-		progress := 0
-		if stats.Total > 0 {
-			progress = (stats.Sent + stats.Failed) * 100 / stats.Total
-		}
+		progress := worker.JobProgress(stats)
 		jobsWithStats[i] = map[string]any{
 			"Job":      job,
 			"Stats":    stats,
@@ -76,10 +77,7 @@ func (h *Handlers) JobView(w http.ResponseWriter, r *http.Request) {
 	}
 
 	stats, _ := h.jobs.GetStats(id)
-	progress := 0
-	if stats.Total > 0 {
-		progress = (stats.Sent + stats.Failed) * 100 / stats.Total
-	}
+	progress := worker.JobProgress(stats)
 
 	// Get recent items
 	items, _, _ := h.jobs.ListItems(models.JobItemFilter{
@@ -91,6 +89,23 @@ func (h *Handlers) JobView(w http.ResponseWriter, r *http.Request) {
 	var servers []string
 	json.Unmarshal([]byte(job.Servers), &servers)
 
+	var freeze *models.FreezeWindow
+	if jobPending(job.Status) {
+		if freeze, err = h.jobFreeze(job); err != nil {
+			h.logger.Error("failed to check freeze windows", "error", err)
+		}
+	}
+
+	engagement, err := h.tracking.JobStats(id)
+	if err != nil {
+		h.logger.Error("failed to get engagement stats", "error", err)
+	}
+
+	throttle, err := models.ParseJobThrottle(job.Throttle)
+	if err != nil {
+		h.logger.Error("invalid job throttle", "job_id", job.ID, "error", err)
+	}
+
 	data := map[string]any{
 		"Title":    "Job: " + job.ID[:8],
 		"Active":   "jobs",
@@ -100,6 +115,12 @@ func (h *Handlers) JobView(w http.ResponseWriter, r *http.Request) {
 		"Progress": progress,
 		"Items":    items,
 		"Servers":  servers,
+		"Live":     h.progress != nil,
+		"Freeze":   freeze,
+		"Throttle": throttle.String(),
+
+		"Tracking":   h.cfg.Tracking.Enabled,
+		"Engagement": engagement,
 	}
 
 	h.render(w, "job_view", data)
@@ -152,30 +173,75 @@ func (h *Handlers) JobItems(w http.ResponseWriter, r *http.Request) {
 	h.render(w, "job_items", data)
 }
 
-func (h *Handlers) JobPause(w http.ResponseWriter, r *http.Request) {
-	id := r.PathValue("id")
+// jobAction is an operator control on a send job
+type jobAction struct {
+	status string   // Status the job moves to
+	from   []string // Statuses the action applies to
+	err    string   // Message when the job is in another status
+}
 
-	job, err := h.jobs.GetByID(id)
-	if err != nil || job == nil {
-		h.error(w, http.StatusNotFound, "Job not found")
-		return
+var jobActions = map[string]jobAction{
+	"pause":  {status: "paused", from: []string{"running"}, err: "Can only pause running jobs"},
+	"resume": {status: "running", from: []string{"paused"}, err: "Can only resume paused jobs"},
+	"cancel": {status: "cancelled", from: []string{"running", "paused", "scheduled"}, err: "Cannot cancel job in status"},
+}
+
+// actionError is a failure of an action of the web UI or the JSON API with
+// its HTTP status
+type actionError struct {
+	status  int
+	code    string // API error code
+	message string
+}
+
+func (e *actionError) Error() string { return e.message }
+
+// applyJobAction pauses, resumes or cancels a job. Pausing stops the worker
+// from picking pending items, cancelling marks them cancelled; items already
+// handed to a Sendry server are tracked to their final status either way.
+func (h *Handlers) applyJobAction(r *http.Request, job *models.SendJob, name string) *actionError {
+	action := jobActions[name]
+	if !slices.Contains(action.from, job.Status) {
+		msg := action.err
+		if name == "cancel" {
+			msg += ": " + job.Status
+		}
+		return &actionError{http.StatusBadRequest, "INVALID_STATE", msg}
 	}
 
-	if job.Status != "running" {
-		h.error(w, http.StatusBadRequest, "Can only pause running jobs")
-		return
+	if name == "resume" {
+		f, err := h.jobFreeze(job)
+		if err != nil {
+			h.logger.Error("failed to check freeze windows", "error", err)
+			return &actionError{http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to check freeze windows"}
+		}
+		if f != nil {
+			return &actionError{http.StatusConflict, "FROZEN", freezeMessage(f) + "; an admin can override the freeze on the job page"}
+		}
 	}
 
-	if err := h.jobs.UpdateStatus(id, "paused"); err != nil {
-		h.logger.Error("failed to pause job", "error", err)
-		h.error(w, http.StatusInternalServerError, "Failed to pause job")
-		return
+	if err := h.jobs.UpdateStatus(job.ID, action.status); err != nil {
+		h.logger.Error("failed to "+name+" job", "job_id", job.ID, "error", err)
+		return &actionError{http.StatusInternalServerError, "INTERNAL_ERROR", "Failed to " + name + " job"}
 	}
+	h.publishJob(job.ID)
 
-	http.Redirect(w, r, "/jobs/"+id, http.StatusSeeOther)
+	stats, _ := h.jobs.GetStats(job.ID)
+	h.settings.LogAction(r, middleware.GetUserID(r), requestActor(r),
+		name, "job", job.ID, auditJSON(map[string]any{"from": job.Status, "sent": stats.Sent, "failed": stats.Failed, "cancelled": stats.Cancelled}))
+	return nil
 }
 
-func (h *Handlers) JobResume(w http.ResponseWriter, r *http.Request) {
+// jobActor returns the user or API key changing a job, for the audit log
+func requestActor(r *http.Request) string {
+	if key := middleware.GetAPIKeyFromContext(r); key != nil && key.UserID == "" {
+		return "api-key:" + key.Name
+	}
+	return middleware.GetUserEmail(r)
+}
+
+// jobControl handles the pause, resume and cancel buttons of the job page
+func (h *Handlers) jobControl(w http.ResponseWriter, r *http.Request, name string) {
 	id := r.PathValue("id")
 
 	job, err := h.jobs.GetByID(id)
@@ -184,41 +250,24 @@ func (h *Handlers) JobResume(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if job.Status != "paused" {
-		h.error(w, http.StatusBadRequest, "Can only resume paused jobs")
-		return
-	}
-
-	if err := h.jobs.UpdateStatus(id, "running"); err != nil {
-		h.logger.Error("failed to resume job", "error", err)
-		h.error(w, http.StatusInternalServerError, "Failed to resume job")
+	if err := h.applyJobAction(r, job, name); err != nil {
+		h.error(w, err.status, err.message)
 		return
 	}
 
 	http.Redirect(w, r, "/jobs/"+id, http.StatusSeeOther)
 }
 
-func (h *Handlers) JobCancel(w http.ResponseWriter, r *http.Request) {
-	id := r.PathValue("id")
-
-	job, err := h.jobs.GetByID(id)
-	if err != nil || job == nil {
-		h.error(w, http.StatusNotFound, "Job not found")
-		return
-	}
-
-	if job.Status != "running" && job.Status != "paused" && job.Status != "scheduled" {
-		h.error(w, http.StatusBadRequest, "Cannot cancel job in status: "+job.Status)
-		return
-	}
+func (h *Handlers) JobPause(w http.ResponseWriter, r *http.Request) {
+	h.jobControl(w, r, "pause")
+}
 
-	if err := h.jobs.UpdateStatus(id, "cancelled"); err != nil {
-		h.logger.Error("failed to cancel job", "error", err)
-		h.error(w, http.StatusInternalServerError, "Failed to cancel job")
-		return
-	}
+func (h *Handlers) JobResume(w http.ResponseWriter, r *http.Request) {
+	h.jobControl(w, r, "resume")
+}
 
-	http.Redirect(w, r, "/jobs/"+id, http.StatusSeeOther)
+func (h *Handlers) JobCancel(w http.ResponseWriter, r *http.Request) {
+	h.jobControl(w, r, "cancel")
 }
 
 func (h *Handlers) JobRetry(w http.ResponseWriter, r *http.Request) {
@@ -233,11 +282,16 @@ func (h *Handlers) JobRetry(w http.ResponseWriter, r *http.Request) {
 	// TODO: Implement retry logic - reset failed items to pending
 	// For now, just update status back to running
 
+	if !h.checkJobFreeze(w, job) {
+		return
+	}
+
 	if err := h.jobs.UpdateStatus(id, "running"); err != nil {
 		h.logger.Error("failed to retry job", "error", err)
 		h.error(w, http.StatusInternalServerError, "Failed to retry job")
 		return
 	}
+	h.publishJob(id)
 
 	http.Redirect(w, r, "/jobs/"+id, http.StatusSeeOther)
 }
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/foxzi/sendry/internal/web/middleware"
+	"github.com/foxzi/sendry/internal/web/models"
+	"github.com/foxzi/sendry/internal/web/mtasts"
+)
+
+// MTASTSPolicy serves the MTA-STS policy of the domain named by the
+// mta-sts.<domain> host the request was sent to (public)
+func (h *Handlers) MTASTSPolicy(w http.ResponseWriter, r *http.Request) {
+	host := r.Host
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	domain, ok := strings.CutPrefix(strings.TrimSuffix(strings.ToLower(host), "."), "mta-sts.")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	policy, err := h.mtaSTS.GetByDomain(domain)
+	if err != nil {
+		h.logger.Error("failed to load mta-sts policy", "domain", domain, "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if policy == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte(mtastsPolicy(policy).Text()))
+}
+
+// CentralDomainsMTASTSSave creates or updates the MTA-STS policy of a domain
+func (h *Handlers) CentralDomainsMTASTSSave(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	if err := r.ParseForm(); err != nil {
+		h.error(w, http.StatusBadRequest, "Invalid form data")
+		return
+	}
+
+	domain, err := h.domains.GetByID(id)
+	if err != nil || domain == nil {
+		h.error(w, http.StatusNotFound, "Domain not found")
+		return
+	}
+
+	maxAge, err := strconv.Atoi(strings.TrimSpace(r.FormValue("max_age")))
+	if err != nil {
+		h.error(w, http.StatusBadRequest, "Invalid max_age")
+		return
+	}
+	policy := &models.MTASTSPolicy{
+		DomainID: domain.ID,
+		Mode:     r.FormValue("mode"),
+		MX:       mtasts.ParseMX(r.FormValue("mx")),
+		MaxAge:   maxAge,
+	}
+	sts := mtastsPolicy(policy)
+	if err := sts.Validate(); err != nil {
+		h.error(w, http.StatusBadRequest, "Invalid MTA-STS policy: "+err.Error())
+		return
+	}
+
+	if err := h.mtaSTS.Save(policy); err != nil {
+		h.logger.Error("failed to save mta-sts policy", "domain", domain.Domain, "error", err)
+		h.error(w, http.StatusInternalServerError, "Failed to save MTA-STS policy")
+		return
+	}
+
+	h.settings.LogAction(r, middleware.GetUserID(r), middleware.GetUserEmail(r),
+		"update", "mta_sts", domain.ID, auditJSON(map[string]any{
+			"domain":  domain.Domain,
+			"mode":    policy.Mode,
+			"mx":      policy.MX,
+			"max_age": policy.MaxAge,
+			"id":      sts.ID(),
+		}))
+
+	// The policy id changed, publish the new _mta-sts record
+	h.autoPublishDNS(r, domain)
+
+	http.Redirect(w, r, fmt.Sprintf("/domains/%s#mta-sts", id), http.StatusSeeOther)
+}
+
+// CentralDomainsMTASTSDelete removes the MTA-STS policy of a domain
+func (h *Handlers) CentralDomainsMTASTSDelete(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	domain, err := h.domains.GetByID(id)
+	if err != nil || domain == nil {
+		h.error(w, http.StatusNotFound, "Domain not found")
+		return
+	}
+
+	if err := h.mtaSTS.Delete(domain.ID); err != nil {
+		h.logger.Error("failed to delete mta-sts policy", "domain", domain.Domain, "error", err)
+		h.error(w, http.StatusInternalServerError, "Failed to delete MTA-STS policy")
+		return
+	}
+
+	h.settings.LogAction(r, middleware.GetUserID(r), middleware.GetUserEmail(r),
+		"delete", "mta_sts", domain.ID, auditJSON(map[string]any{
+			"domain": domain.Domain,
+		}))
+
+	http.Redirect(w, r, fmt.Sprintf("/domains/%s#mta-sts", id), http.StatusSeeOther)
+}
+
+// CentralDomainsMTASTSDownload downloads the policy file of a domain, for
+// hosting it on another web server
+func (h *Handlers) CentralDomainsMTASTSDownload(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	policy, err := h.mtaSTS.Get(id)
+	if err != nil {
+		h.logger.Error("failed to load mta-sts policy", "error", err)
+		h.error(w, http.StatusInternalServerError, "Failed to load MTA-STS policy")
+		return
+	}
+	if policy == nil {
+		h.error(w, http.StatusNotFound, "MTA-STS policy not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", "attachment; filename=\"mta-sts.txt\"")
+	w.Write([]byte(mtastsPolicy(policy).Text()))
+}
+
+// mtastsPolicy returns the policy of a stored MTA-STS policy
+func mtastsPolicy(p *models.MTASTSPolicy) *mtasts.Policy {
+	return &mtasts.Policy{Mode: p.Mode, MX: p.MX, MaxAge: p.MaxAge}
+}
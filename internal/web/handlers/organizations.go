@@ -0,0 +1,282 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/foxzi/sendry/internal/web/middleware"
+	"github.com/foxzi/sendry/internal/web/models"
+	"github.com/foxzi/sendry/internal/web/repository"
+)
+
+// OrganizationList shows the organizations page
+func (h *Handlers) OrganizationList(w http.ResponseWriter, r *http.Request) {
+	h.renderOrganizations(w, r, "")
+}
+
+func (h *Handlers) renderOrganizations(w http.ResponseWriter, r *http.Request, errMsg string) {
+	orgs, err := h.orgs.List()
+	if err != nil {
+		h.logger.Error("failed to list organizations", "error", err)
+		h.error(w, http.StatusInternalServerError, "Failed to load organizations")
+		return
+	}
+
+	data := map[string]any{
+		"Title":         "Organizations",
+		"Active":        "settings",
+		"User":          h.getUserFromContext(r),
+		"Organizations": orgs,
+		"Servers":       h.cfg.Sendry.Servers,
+		"Error":         errMsg,
+	}
+
+	h.render(w, "settings_organizations", data)
+}
+
+// organizationForm reads the name and allowed servers of an organization
+func (h *Handlers) organizationForm(r *http.Request, o *models.Organization) string {
+	o.Name = strings.TrimSpace(r.FormValue("name"))
+	if o.Name == "" {
+		return "Name is required"
+	}
+	o.Servers = nil
+	for _, name := range r.Form["servers"] {
+		if _, err := h.sendry.GetServerByName(name); err != nil {
+			return "Unknown server: " + name
+		}
+		o.Servers = append(o.Servers, name)
+	}
+	return ""
+}
+
+// OrganizationCreate creates an organization
+func (h *Handlers) OrganizationCreate(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		h.error(w, http.StatusBadRequest, "Invalid form data")
+		return
+	}
+
+	o := &models.Organization{}
+	if msg := h.organizationForm(r, o); msg != "" {
+		h.renderOrganizations(w, r, msg)
+		return
+	}
+	if existing, _ := h.orgs.GetByName(o.Name); existing != nil {
+		h.renderOrganizations(w, r, "Organization "+o.Name+" already exists")
+		return
+	}
+
+	if err := h.orgs.Create(o); err != nil {
+		h.logger.Error("failed to create organization", "error", err)
+		h.error(w, http.StatusInternalServerError, "Failed to create organization")
+		return
+	}
+
+	h.settings.LogAction(r, middleware.GetUserID(r), middleware.GetUserEmail(r),
+		"create", "organization", o.ID, auditJSON(map[string]any{"name": o.Name, "servers": o.Servers}))
+	http.Redirect(w, r, "/organizations/"+o.ID+"/members", http.StatusSeeOther)
+}
+
+// OrganizationUpdate changes the name and allowed servers of an organization
+func (h *Handlers) OrganizationUpdate(w http.ResponseWriter, r *http.Request) {
+	o, err := h.orgs.GetByID(r.PathValue("id"))
+	if err != nil || o == nil {
+		h.error(w, http.StatusNotFound, "Organization not found")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		h.error(w, http.StatusBadRequest, "Invalid form data")
+		return
+	}
+
+	if msg := h.organizationForm(r, o); msg != "" {
+		h.renderOrganizations(w, r, msg)
+		return
+	}
+	if existing, _ := h.orgs.GetByName(o.Name); existing != nil && existing.ID != o.ID {
+		h.renderOrganizations(w, r, "Organization "+o.Name+" already exists")
+		return
+	}
+
+	if err := h.orgs.Update(o); err != nil {
+		h.logger.Error("failed to update organization", "error", err)
+		h.error(w, http.StatusInternalServerError, "Failed to update organization")
+		return
+	}
+
+	h.settings.LogAction(r, middleware.GetUserID(r), middleware.GetUserEmail(r),
+		"update", "organization", o.ID, auditJSON(map[string]any{"name": o.Name, "servers": o.Servers}))
+	http.Redirect(w, r, "/settings/organizations", http.StatusSeeOther)
+}
+
+// OrganizationDelete deletes an organization that owns no objects
+func (h *Handlers) OrganizationDelete(w http.ResponseWriter, r *http.Request) {
+	o, err := h.orgs.GetByID(r.PathValue("id"))
+	if err != nil || o == nil {
+		h.error(w, http.StatusNotFound, "Organization not found")
+		return
+	}
+
+	if err := h.orgs.Delete(o.ID); err != nil {
+		if errors.Is(err, repository.ErrOrganizationInUse) {
+			h.renderOrganizations(w, r, "Cannot delete "+o.Name+": "+err.Error())
+			return
+		}
+		h.logger.Error("failed to delete organization", "error", err)
+		h.error(w, http.StatusInternalServerError, "Failed to delete organization")
+		return
+	}
+
+	h.settings.LogAction(r, middleware.GetUserID(r), middleware.GetUserEmail(r),
+		"delete", "organization", o.ID, auditJSON(map[string]any{"name": o.Name}))
+	http.Redirect(w, r, "/settings/organizations", http.StatusSeeOther)
+}
+
+// manageableOrganization returns the organization of the request path if
+// the user may manage its members: admins manage all organizations,
+// organization admins their own
+func (h *Handlers) manageableOrganization(w http.ResponseWriter, r *http.Request) *models.Organization {
+	o, err := h.orgs.GetByID(r.PathValue("id"))
+	if err != nil || o == nil {
+		h.error(w, http.StatusNotFound, "Organization not found")
+		return nil
+	}
+	if !middleware.IsAdmin(r) {
+		role, err := h.orgs.MemberRole(o.ID, middleware.GetUserID(r))
+		if err != nil || role != models.OrgRoleAdmin {
+			h.error(w, http.StatusNotFound, "Organization not found")
+			return nil
+		}
+	}
+	return o
+}
+
+// OrganizationMembers shows the members of an organization
+func (h *Handlers) OrganizationMembers(w http.ResponseWriter, r *http.Request) {
+	o := h.manageableOrganization(w, r)
+	if o == nil {
+		return
+	}
+	h.renderOrganizationMembers(w, r, o, "")
+}
+
+func (h *Handlers) renderOrganizationMembers(w http.ResponseWriter, r *http.Request, o *models.Organization, errMsg string) {
+	members, err := h.orgs.ListMembers(o.ID)
+	if err != nil {
+		h.logger.Error("failed to list members", "error", err)
+		h.error(w, http.StatusInternalServerError, "Failed to load members")
+		return
+	}
+
+	data := map[string]any{
+		"Title":        o.Name + " - Members",
+		"Active":       "settings",
+		"User":         h.getUserFromContext(r),
+		"Organization": o,
+		"Members":      members,
+		"Roles":        models.OrgRoles,
+		"Error":        errMsg,
+	}
+
+	h.render(w, "organization_members", data)
+}
+
+// OrganizationMemberSet adds a user to an organization by email or changes
+// the role of a member
+func (h *Handlers) OrganizationMemberSet(w http.ResponseWriter, r *http.Request) {
+	o := h.manageableOrganization(w, r)
+	if o == nil {
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		h.error(w, http.StatusBadRequest, "Invalid form data")
+		return
+	}
+
+	email := strings.TrimSpace(r.FormValue("email"))
+	role := r.FormValue("role")
+	if !models.ValidOrgRole(role) {
+		h.renderOrganizationMembers(w, r, o, "Unknown role: "+role)
+		return
+	}
+
+	users, err := h.settings.ListUsers()
+	if err != nil {
+		h.logger.Error("failed to list users", "error", err)
+		h.error(w, http.StatusInternalServerError, "Failed to load users")
+		return
+	}
+	i := slices.IndexFunc(users, func(u models.User) bool { return strings.EqualFold(u.Email, email) })
+	if i < 0 {
+		h.renderOrganizationMembers(w, r, o, "No user with email "+email)
+		return
+	}
+	user := users[i]
+
+	if err := h.orgs.SetMember(o.ID, user.ID, models.OrgRole(role)); err != nil {
+		h.logger.Error("failed to set member", "error", err)
+		h.error(w, http.StatusInternalServerError, "Failed to save member")
+		return
+	}
+
+	h.settings.LogAction(r, middleware.GetUserID(r), middleware.GetUserEmail(r),
+		"set_member", "organization", o.ID, auditJSON(map[string]any{"email": user.Email, "role": role}))
+	http.Redirect(w, r, "/organizations/"+o.ID+"/members", http.StatusSeeOther)
+}
+
+// OrganizationMemberRemove removes a user from an organization
+func (h *Handlers) OrganizationMemberRemove(w http.ResponseWriter, r *http.Request) {
+	o := h.manageableOrganization(w, r)
+	if o == nil {
+		return
+	}
+
+	userID := r.PathValue("user")
+	target, _ := h.settings.GetUserByID(userID)
+	if target == nil {
+		h.error(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	if err := h.orgs.RemoveMember(o.ID, userID); err != nil {
+		h.logger.Error("failed to remove member", "error", err)
+		h.error(w, http.StatusInternalServerError, "Failed to remove member")
+		return
+	}
+
+	h.settings.LogAction(r, middleware.GetUserID(r), middleware.GetUserEmail(r),
+		"remove_member", "organization", o.ID, auditJSON(map[string]any{"email": target.Email}))
+	http.Redirect(w, r, "/organizations/"+o.ID+"/members", http.StatusSeeOther)
+}
+
+// OrganizationSwitch selects the organization the current session works in
+func (h *Handlers) OrganizationSwitch(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		h.error(w, http.StatusBadRequest, "Invalid form data")
+		return
+	}
+
+	o, err := h.orgs.GetByID(r.FormValue("org_id"))
+	if err != nil || o == nil {
+		h.error(w, http.StatusNotFound, "Organization not found")
+		return
+	}
+	if !middleware.IsAdmin(r) {
+		role, err := h.orgs.MemberRole(o.ID, middleware.GetUserID(r))
+		if err != nil || role == "" {
+			h.error(w, http.StatusNotFound, "Organization not found")
+			return
+		}
+	}
+
+	if err := h.sessions.SetOrganization(middleware.GetSessionID(r), o.ID); err != nil {
+		h.logger.Error("failed to switch organization", "error", err)
+		h.error(w, http.StatusInternalServerError, "Failed to switch organization")
+		return
+	}
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/foxzi/sendry/internal/web/db"
+	"github.com/foxzi/sendry/internal/web/middleware"
+	"github.com/foxzi/sendry/internal/web/models"
+	"github.com/foxzi/sendry/internal/web/repository"
+)
+
+func TestOrganizationScoping(t *testing.T) {
+	h, database, cleanup := newDomainViewTestHandlers(t)
+	defer cleanup()
+	h.cfg.Auth.SessionTTL = time.Hour
+	h.cfg.Auth.LocalEnabled = true
+
+	// Ann joins the default organization as its only one, then becomes a
+	// viewer of Globex, which may only use mta-1
+	ann, err := h.settings.CreateUser("ann@example.com", "Ann", "secret-password", models.RoleUser)
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	globex := &models.Organization{Name: "Globex", Servers: []string{"mta-1"}}
+	initech := &models.Organization{Name: "Initech"}
+	for _, o := range []*models.Organization{globex, initech} {
+		if err := h.orgs.Create(o); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+	if err := h.orgs.SetMember(globex.ID, ann.ID, models.OrgRoleViewer); err != nil {
+		t.Fatal(err)
+	}
+
+	own := &models.Template{Name: "default-welcome", Subject: "Hi", OrgID: db.DefaultOrganizationID}
+	other := &models.Template{Name: "globex-welcome", Subject: "Hi", OrgID: globex.ID}
+	for _, tmpl := range []*models.Template{own, other} {
+		if err := h.templates.Create(tmpl, "test"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	form := url.Values{"email": {"ann@example.com"}, "password": {"secret-password"}}
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	h.Login(w, req)
+	var cookie *http.Cookie
+	for _, c := range w.Result().Cookies() {
+		if c.Name == "session" {
+			cookie = c
+		}
+	}
+	if cookie == nil {
+		t.Fatalf("login set no session cookie, status %d", w.Code)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /templates", h.TemplateList)
+	mux.HandleFunc("POST /templates", h.TemplateCreate)
+	mux.HandleFunc("GET /templates/{id}", h.TemplateView)
+	mux.HandleFunc("GET /servers/{name}", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("POST /organizations/switch", h.OrganizationSwitch)
+	web := middleware.Auth(h.cfg, database, testLogger())(middleware.Organization(database, testLogger())(mux))
+	call := func(method, target string, form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.AddCookie(cookie)
+		w := httptest.NewRecorder()
+		web.ServeHTTP(w, req)
+		return w
+	}
+
+	// The default organization is selected first
+	w = call(http.MethodGet, "/templates", nil)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), own.Name) || strings.Contains(w.Body.String(), other.Name) {
+		t.Errorf("template list status = %d, want only the default organization's templates", w.Code)
+	}
+	if w := call(http.MethodGet, "/templates/"+other.ID, nil); w.Code != http.StatusNotFound {
+		t.Errorf("other organization's template status = %d, want 404", w.Code)
+	}
+	if w := call(http.MethodGet, "/servers/mta-2", nil); w.Code != http.StatusOK {
+		t.Errorf("server status = %d, want 200 without restriction", w.Code)
+	}
+
+	// Organizations the user is not a member of cannot be selected
+	if w := call(http.MethodPost, "/organizations/switch", url.Values{"org_id": {initech.ID}}); w.Code != http.StatusNotFound {
+		t.Errorf("switch to foreign organization status = %d, want 404", w.Code)
+	}
+	if w := call(http.MethodPost, "/organizations/switch", url.Values{"org_id": {globex.ID}}); w.Code != http.StatusSeeOther {
+		t.Fatalf("switch status = %d, want 303", w.Code)
+	}
+
+	w = call(http.MethodGet, "/templates", nil)
+	if !strings.Contains(w.Body.String(), other.Name) || strings.Contains(w.Body.String(), own.Name) {
+		t.Error("template list after switch does not show Globex templates only")
+	}
+	if w := call(http.MethodGet, "/servers/mta-2", nil); w.Code != http.StatusNotFound {
+		t.Errorf("disallowed server status = %d, want 404", w.Code)
+	}
+	if w := call(http.MethodGet, "/servers/mta-1", nil); w.Code != http.StatusOK {
+		t.Errorf("allowed server status = %d, want 200", w.Code)
+	}
+	if w := call(http.MethodPost, "/templates", url.Values{"name": {"x"}, "subject": {"y"}}); w.Code != http.StatusForbidden {
+		t.Errorf("viewer create status = %d, want 403", w.Code)
+	}
+
+	// API keys act in the organization they were created in
+	key, err := h.apiKeys.Create(repository.APIKeyCreateOptions{
+		Name: "globex", OrgID: globex.ID, Permissions: []string{models.PermissionTemplate},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiMux := http.NewServeMux()
+	apiMux.HandleFunc("GET /api/v1/templates/{id}", h.APIGetTemplate)
+	api := middleware.APIAuth(h.apiKeys, testLogger())(middleware.Organization(database, testLogger())(apiMux))
+	for id, want := range map[string]int{other.ID: http.StatusOK, own.ID: http.StatusNotFound} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/templates/"+id, nil)
+		req.Header.Set("Authorization", "Bearer "+key.Key)
+		w := httptest.NewRecorder()
+		api.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Errorf("API template %s status = %d, want %d", id, w.Code, want)
+		}
+	}
+
+	// A corrupt server list denies access instead of allowing every server
+	if _, err := database.Exec("UPDATE organizations SET servers = 'mta-1' WHERE id = ?", globex.ID); err != nil {
+		t.Fatal(err)
+	}
+	if w := call(http.MethodGet, "/servers/mta-2", nil); w.Code != http.StatusInternalServerError {
+		t.Errorf("server of a corrupt list status = %d, want 500", w.Code)
+	}
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/foxzi/sendry/internal/web/middleware"
+	"github.com/foxzi/sendry/internal/web/models"
+	"github.com/foxzi/sendry/internal/web/repository"
+)
+
+// Profile shows the sessions and personal API tokens of the current user
+func (h *Handlers) Profile(w http.ResponseWriter, r *http.Request) {
+	h.renderProfile(w, r, "")
+}
+
+func (h *Handlers) renderProfile(w http.ResponseWriter, r *http.Request, newToken string) {
+	userID := middleware.GetUserID(r)
+
+	sessions, err := h.sessions.ListByUser(userID, middleware.GetSessionID(r))
+	if err != nil {
+		h.logger.Error("failed to list sessions", "error", err)
+		h.error(w, http.StatusInternalServerError, "Failed to load sessions")
+		return
+	}
+
+	tokens, _, err := h.apiKeys.List(models.APIKeyFilter{UserID: userID})
+	if err != nil {
+		h.logger.Error("failed to list personal tokens", "error", err)
+		h.error(w, http.StatusInternalServerError, "Failed to load API tokens")
+		return
+	}
+
+	data := map[string]any{
+		"Title":       "Profile",
+		"Active":      "profile",
+		"User":        h.getUserFromContext(r),
+		"Sessions":    sessions,
+		"Tokens":      tokens,
+		"NewToken":    newToken,
+		"Permissions": models.APIKeyPermissions,
+	}
+
+	h.render(w, "profile", data)
+}
+
+// ProfileSessionRevoke signs out one session of the current user
+func (h *Handlers) ProfileSessionRevoke(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	found, err := h.sessions.Delete(middleware.GetUserID(r), id)
+	if err != nil {
+		h.logger.Error("failed to revoke session", "error", err)
+		h.error(w, http.StatusInternalServerError, "Failed to revoke session")
+		return
+	}
+	if !found {
+		h.error(w, http.StatusNotFound, "Session not found")
+		return
+	}
+
+	h.settings.LogAction(r, middleware.GetUserID(r), middleware.GetUserEmail(r),
+		"revoke", "session", id, "")
+
+	// Revoking the current session signs the user out
+	if id == repository.SessionHandle(middleware.GetSessionID(r)) {
+		http.Redirect(w, r, "/auth/login", http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, "/profile", http.StatusSeeOther)
+}
+
+// ProfileSessionsRevokeOthers signs out all other sessions of the current
+// user
+func (h *Handlers) ProfileSessionsRevokeOthers(w http.ResponseWriter, r *http.Request) {
+	n, err := h.sessions.DeleteOthers(middleware.GetUserID(r), middleware.GetSessionID(r))
+	if err != nil {
+		h.logger.Error("failed to revoke sessions", "error", err)
+		h.error(w, http.StatusInternalServerError, "Failed to revoke sessions")
+		return
+	}
+
+	h.settings.LogAction(r, middleware.GetUserID(r), middleware.GetUserEmail(r),
+		"revoke_others", "session", "", auditJSON(map[string]any{"sessions": n}))
+	http.Redirect(w, r, "/profile", http.StatusSeeOther)
+}
+
+// ProfileTokenCreate creates a personal API token of the current user. The
+// token is shown once on the profile page.
+func (h *Handlers) ProfileTokenCreate(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		h.error(w, http.StatusBadRequest, "Invalid form data")
+		return
+	}
+
+	name := r.FormValue("name")
+	if name == "" {
+		h.error(w, http.StatusBadRequest, "Name is required")
+		return
+	}
+	if middleware.GetOrgID(r) == "" {
+		h.error(w, http.StatusBadRequest, "Tokens act in an organization, select one first")
+		return
+	}
+
+	permissions := formPermissions(r)
+	if len(permissions) == 0 {
+		h.error(w, http.StatusBadRequest, "At least one scope is required")
+		return
+	}
+
+	var expiresAt *time.Time
+	if v := r.FormValue("expires_days"); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil || days < 0 {
+			h.error(w, http.StatusBadRequest, "Invalid expiry")
+			return
+		}
+		if days > 0 {
+			t := time.Now().AddDate(0, 0, days)
+			expiresAt = &t
+		}
+	}
+
+	result, err := h.apiKeys.Create(repository.APIKeyCreateOptions{
+		Name:        name,
+		CreatedBy:   middleware.GetUserEmail(r),
+		UserID:      middleware.GetUserID(r),
+		OrgID:       middleware.GetOrgID(r),
+		Permissions: permissions,
+		ExpiresAt:   expiresAt,
+	})
+	if err != nil {
+		h.logger.Error("failed to create personal token", "error", err)
+		h.error(w, http.StatusInternalServerError, "Failed to create API token")
+		return
+	}
+
+	h.settings.LogAction(r, middleware.GetUserID(r), middleware.GetUserEmail(r),
+		"create", "api_token", result.ID, auditJSON(map[string]any{"name": name, "permissions": permissions, "expires_at": expiresAt}))
+	h.renderProfile(w, r, result.Key)
+}
+
+// ProfileTokenDelete deletes a personal API token of the current user
+func (h *Handlers) ProfileTokenDelete(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	token, err := h.apiKeys.GetByID(id)
+	if err != nil || token == nil || token.UserID != middleware.GetUserID(r) {
+		h.error(w, http.StatusNotFound, "API token not found")
+		return
+	}
+
+	if err := h.apiKeys.Delete(id); err != nil {
+		h.logger.Error("failed to delete personal token", "id", id, "error", err)
+		h.error(w, http.StatusInternalServerError, "Failed to delete API token")
+		return
+	}
+
+	h.settings.LogAction(r, middleware.GetUserID(r), middleware.GetUserEmail(r),
+		"delete", "api_token", id, auditJSON(map[string]any{"name": token.Name}))
+	http.Redirect(w, r, "/profile", http.StatusSeeOther)
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/foxzi/sendry/internal/web/middleware"
+	"github.com/foxzi/sendry/internal/web/models"
+	"github.com/foxzi/sendry/internal/web/repository"
+)
+
+func TestProfileSessionsAndTokens(t *testing.T) {
+	h, database, cleanup := newDomainViewTestHandlers(t)
+	defer cleanup()
+	h.cfg.Auth.SessionTTL = time.Hour
+	h.cfg.Auth.LocalEnabled = true
+
+	user, err := h.settings.CreateUser("ann@example.com", "Ann", "secret-password", models.RoleUser)
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	userID := user.ID
+
+	login := func(agent string) *http.Cookie {
+		form := url.Values{"email": {"ann@example.com"}, "password": {"secret-password"}}
+		req := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("User-Agent", agent)
+		w := httptest.NewRecorder()
+		h.Login(w, req)
+		for _, c := range w.Result().Cookies() {
+			if c.Name == "session" {
+				return c
+			}
+		}
+		t.Fatalf("login set no session cookie, status %d", w.Code)
+		return nil
+	}
+	laptop, phone := login("laptop"), login("phone")
+
+	authed := func(fn http.HandlerFunc) http.Handler {
+		return middleware.Auth(h.cfg, database, testLogger())(middleware.Organization(database, testLogger())(fn))
+	}
+	call := func(fn http.HandlerFunc, method, target string, cookie *http.Cookie, form url.Values, pathValues map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.AddCookie(cookie)
+		for k, v := range pathValues {
+			req.SetPathValue(k, v)
+		}
+		w := httptest.NewRecorder()
+		authed(fn).ServeHTTP(w, req)
+		return w
+	}
+
+	sessions, err := h.sessions.ListByUser(userID, laptop.Value)
+	if err != nil || len(sessions) != 2 {
+		t.Fatalf("ListByUser() = %d sessions, %v, want 2", len(sessions), err)
+	}
+	var phoneHandle string
+	for _, s := range sessions {
+		if s.UserAgent == "phone" {
+			phoneHandle = s.ID
+		}
+		if s.Current != (s.UserAgent == "laptop") {
+			t.Errorf("session %q current = %v", s.UserAgent, s.Current)
+		}
+		if strings.Contains(laptop.Value+phone.Value, s.ID) {
+			t.Errorf("session handle %q exposes the session cookie", s.ID)
+		}
+	}
+
+	w := call(h.Profile, http.MethodGet, "/profile", laptop, nil, nil)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "phone") {
+		t.Fatalf("profile status = %d, want the session list", w.Code)
+	}
+
+	// Revoking the phone session signs it out
+	w = call(h.ProfileSessionRevoke, http.MethodPost, "/profile/sessions/x/revoke", laptop, nil, map[string]string{"id": phoneHandle})
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/profile" {
+		t.Fatalf("revoke status = %d, location %q", w.Code, w.Header().Get("Location"))
+	}
+	if w := call(h.Profile, http.MethodGet, "/profile", phone, nil, nil); w.Header().Get("Location") != "/auth/login" {
+		t.Errorf("revoked session status = %d, want redirect to login", w.Code)
+	}
+
+	// Signing out other devices keeps the current session
+	tablet := login("tablet")
+	call(h.ProfileSessionsRevokeOthers, http.MethodPost, "/profile/sessions/revoke-others", laptop, nil, nil)
+	if w := call(h.Profile, http.MethodGet, "/profile", tablet, nil, nil); w.Code != http.StatusSeeOther {
+		t.Errorf("other session status = %d, want redirect", w.Code)
+	}
+	if w := call(h.Profile, http.MethodGet, "/profile", laptop, nil, nil); w.Code != http.StatusOK {
+		t.Errorf("current session status = %d, want 200", w.Code)
+	}
+
+	// A personal token is shown once and acts as its owner
+	form := url.Values{"name": {"ci"}, "permissions": {"template", "bogus"}, "expires_days": {"30"}}
+	w = call(h.ProfileTokenCreate, http.MethodPost, "/profile/tokens", laptop, form, nil)
+	key := regexp.MustCompile(`sk_[0-9a-f]+`).FindString(w.Body.String())
+	if w.Code != http.StatusOK || key == "" {
+		t.Fatalf("token create status = %d, no token in page", w.Code)
+	}
+
+	tokens, _, err := h.apiKeys.List(models.APIKeyFilter{UserID: userID})
+	if err != nil || len(tokens) != 1 {
+		t.Fatalf("List(user) = %d tokens, %v, want 1", len(tokens), err)
+	}
+	if tokens[0].Permissions != `["template"]` || tokens[0].ExpiresAt == nil {
+		t.Errorf("token permissions = %s, expires %v", tokens[0].Permissions, tokens[0].ExpiresAt)
+	}
+	if keys, _, _ := h.apiKeys.List(models.APIKeyFilter{}); len(keys) != 0 {
+		t.Errorf("API key list has %d personal tokens, want 0", len(keys))
+	}
+
+	var gotUser, gotActor string
+	api := middleware.APIAuth(h.apiKeys, testLogger())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUser, gotActor = middleware.GetUserID(r), requestActor(r)
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/templates", nil)
+	req.Header.Set("Authorization", "Bearer "+key)
+	api.ServeHTTP(httptest.NewRecorder(), req)
+	if gotUser != userID || gotActor != "ann@example.com" {
+		t.Errorf("token request user = %q, actor %q", gotUser, gotActor)
+	}
+
+	// Tokens of other users are not found
+	bob, _ := h.settings.CreateUser("bob@example.com", "Bob", "secret-password", models.RoleUser)
+	other, err := h.apiKeys.Create(repository.APIKeyCreateOptions{Name: "bob", UserID: bob.ID, Permissions: []string{"send"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w = call(h.ProfileTokenDelete, http.MethodPost, "/profile/tokens/x/delete", laptop, nil, map[string]string{"id": other.ID})
+	if w.Code != http.StatusNotFound {
+		t.Errorf("delete other token status = %d, want 404", w.Code)
+	}
+	w = call(h.ProfileTokenDelete, http.MethodPost, "/profile/tokens/x/delete", laptop, nil, map[string]string{"id": tokens[0].ID})
+	if got, _ := h.apiKeys.GetByID(tokens[0].ID); w.Code != http.StatusSeeOther || got != nil {
+		t.Errorf("delete own token status = %d, token %v", w.Code, got)
+	}
+}
@@ -0,0 +1,283 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/foxzi/sendry/internal/web/importer"
+	"github.com/foxzi/sendry/internal/web/middleware"
+	"github.com/foxzi/sendry/internal/web/models"
+)
+
+// maxImportSize is the maximum size of an uploaded recipient file
+const maxImportSize = 10 << 20
+
+// importSampleRows is the number of rows shown on the mapping page
+const importSampleRows = 5
+
+// Rejection reasons of rows checked against the database
+const (
+	reasonInList    = "already in list"
+	reasonOtherList = "in list "
+)
+
+// importColumn is a file column on the mapping page
+type importColumn struct {
+	Index   int
+	Name    string
+	Samples []string
+	Mapping importer.Column
+}
+
+// RecipientImport reads an uploaded CSV or XLSX file and shows the column
+// mapping of the import
+func (h *Handlers) RecipientImport(w http.ResponseWriter, r *http.Request) {
+	list, ok := h.segmentList(w, r)
+	if !ok {
+		return
+	}
+
+	if err := r.ParseMultipartForm(maxImportSize); err != nil {
+		h.error(w, http.StatusBadRequest, "Failed to parse form")
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		h.error(w, http.StatusBadRequest, "No file uploaded")
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxImportSize+1))
+	if err != nil {
+		h.error(w, http.StatusBadRequest, "Failed to read file")
+		return
+	}
+	if len(data) > maxImportSize {
+		h.error(w, http.StatusBadRequest, "File is larger than 10 MB")
+		return
+	}
+
+	sheet, err := importer.Parse(header.Filename, data)
+	if err != nil {
+		h.error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	imp := &models.RecipientImport{
+		ListID:    list.ID,
+		FileName:  header.Filename,
+		Columns:   sheet.Columns,
+		Rows:      sheet.Rows,
+		CreatedBy: middleware.GetUserID(r),
+	}
+	if err := h.recipients.CreateImport(imp); err != nil {
+		h.logger.Error("failed to create import", "error", err)
+		h.error(w, http.StatusInternalServerError, "Failed to import file")
+		return
+	}
+
+	http.Redirect(w, r, "/recipients/"+list.ID+"/imports/"+imp.ID, http.StatusSeeOther)
+}
+
+// RecipientImportView shows the column mapping of a pending import or the
+// report of a completed one
+func (h *Handlers) RecipientImportView(w http.ResponseWriter, r *http.Request) {
+	list, imp, ok := h.recipientImport(w, r)
+	if !ok {
+		return
+	}
+
+	data := map[string]any{
+		"Title":  "Import to " + list.Name,
+		"Active": "recipients",
+		"User":   h.getUserFromContext(r),
+		"List":   list,
+		"Import": imp,
+	}
+
+	if imp.Status == models.RecipientImportCompleted {
+		shown := imp.Rejected
+		if len(shown) > 100 {
+			shown = shown[:100]
+		}
+		data["Title"] = "Import Results"
+		data["RejectedShown"] = shown
+		h.render(w, "recipient_import_result", data)
+		return
+	}
+
+	mapping := importer.GuessMapping(imp.Columns)
+	columns := make([]importColumn, len(imp.Columns))
+	for i, name := range imp.Columns {
+		columns[i] = importColumn{Index: i, Name: name, Mapping: mapping[i]}
+		for _, row := range imp.Rows {
+			if len(columns[i].Samples) == importSampleRows {
+				break
+			}
+			columns[i].Samples = append(columns[i].Samples, row[i])
+		}
+	}
+	data["Columns"] = columns
+	data["Fields"] = importer.Fields
+
+	h.render(w, "recipient_import_mapping", data)
+}
+
+// RecipientImportRun imports the rows of a pending import with the column
+// mapping of the form. Rows with invalid or duplicate emails are rejected;
+// emails already in the list are updated or rejected, and emails in other
+// lists are rejected if requested.
+func (h *Handlers) RecipientImportRun(w http.ResponseWriter, r *http.Request) {
+	list, imp, ok := h.recipientImport(w, r)
+	if !ok {
+		return
+	}
+	if imp.Status != models.RecipientImportPending {
+		h.error(w, http.StatusConflict, "Import is already completed")
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		h.error(w, http.StatusBadRequest, "Invalid form data")
+		return
+	}
+
+	mapping := make([]importer.Column, len(imp.Columns))
+	for i := range mapping {
+		n := strconv.Itoa(i)
+		mapping[i] = importer.Column{
+			Field:    r.FormValue("field_" + n),
+			Variable: strings.TrimSpace(r.FormValue("variable_" + n)),
+		}
+		if mapping[i].Field != importer.FieldVariable {
+			mapping[i].Variable = ""
+		}
+	}
+	if err := importer.ValidateMapping(imp.Columns, mapping); err != nil {
+		h.error(w, http.StatusBadRequest, "Invalid column mapping: "+err.Error())
+		return
+	}
+	updateExisting := r.FormValue("existing") != "skip"
+	rejectOtherLists := r.FormValue("other_lists") == "reject"
+
+	rows, rejected := importer.Build(imp.Rows, mapping)
+
+	existing, err := h.recipients.ListEmails(list.ID)
+	if err != nil {
+		h.logger.Error("failed to load list emails", "error", err)
+		h.error(w, http.StatusInternalServerError, "Failed to import recipients")
+		return
+	}
+	var otherLists map[string]string
+	if rejectOtherLists {
+		emails := make(map[string]bool, len(rows))
+		for _, row := range rows {
+			emails[strings.ToLower(row.Recipient.Email)] = true
+		}
+		if otherLists, err = h.recipients.OtherListEmails(list.ID, emails); err != nil {
+			h.logger.Error("failed to check other lists", "error", err)
+			h.error(w, http.StatusInternalServerError, "Failed to import recipients")
+			return
+		}
+	}
+
+	var recipients []models.Recipient
+	imp.Imported, imp.Updated = 0, 0
+	for _, row := range rows {
+		key := strings.ToLower(row.Recipient.Email)
+		reason := ""
+		if other, ok := otherLists[key]; ok {
+			reason = reasonOtherList + other
+		}
+		stored, inList := existing[key]
+		if inList && !updateExisting {
+			reason = reasonInList
+		}
+		if reason != "" {
+			rejected = append(rejected, models.RejectedRow{Row: row.Row, Email: row.Recipient.Email, Reason: reason, Values: row.Values})
+			continue
+		}
+
+		if inList {
+			// Keep the stored case so the existing recipient is updated
+			row.Recipient.Email = stored
+			imp.Updated++
+		} else {
+			imp.Imported++
+		}
+		recipients = append(recipients, row.Recipient)
+	}
+	sort.Slice(rejected, func(i, j int) bool { return rejected[i].Row < rejected[j].Row })
+	imp.Rejected = rejected
+
+	if err := h.recipients.ImportRecipients(list.ID, recipients); err != nil {
+		h.logger.Error("failed to import recipients", "error", err)
+		h.error(w, http.StatusInternalServerError, "Failed to import recipients")
+		return
+	}
+	if err := h.recipients.CompleteImport(imp); err != nil {
+		h.logger.Error("failed to complete import", "error", err)
+		h.error(w, http.StatusInternalServerError, "Failed to save import report")
+		return
+	}
+
+	list.SourceType = "csv"
+	h.recipients.UpdateList(list)
+
+	h.logger.Info("recipients imported", "list_id", list.ID, "imported", imp.Imported, "updated", imp.Updated, "rejected", len(imp.Rejected))
+	http.Redirect(w, r, "/recipients/"+list.ID+"/imports/"+imp.ID, http.StatusSeeOther)
+}
+
+// RecipientImportRejected downloads the rejected rows of an import as CSV
+// with the original columns and the row number and reason
+func (h *Handlers) RecipientImportRejected(w http.ResponseWriter, r *http.Request) {
+	list, imp, ok := h.recipientImport(w, r)
+	if !ok {
+		return
+	}
+
+	filename := fmt.Sprintf("%s-rejected.csv", sanitizeFilename(list.Name))
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+
+	writer := csv.NewWriter(w)
+	defer writer.Flush()
+
+	writer.Write(append(append([]string{}, imp.Columns...), "row", "reason"))
+	for _, row := range imp.Rejected {
+		record := make([]string, len(imp.Columns), len(imp.Columns)+2)
+		copy(record, row.Values)
+		if err := writer.Write(append(record, strconv.Itoa(row.Row), row.Reason)); err != nil {
+			h.logger.Error("failed to write CSV row", "error", err)
+			return
+		}
+	}
+}
+
+// recipientImport returns the recipient list and import of the request,
+// writing the error response if there is none
+func (h *Handlers) recipientImport(w http.ResponseWriter, r *http.Request) (*models.RecipientList, *models.RecipientImport, bool) {
+	list, ok := h.segmentList(w, r)
+	if !ok {
+		return nil, nil, false
+	}
+
+	imp, err := h.recipients.GetImport(r.PathValue("importId"))
+	if err != nil {
+		h.logger.Error("failed to get import", "error", err)
+		h.error(w, http.StatusInternalServerError, "Failed to load import")
+		return nil, nil, false
+	}
+	if imp == nil || imp.ListID != list.ID {
+		h.error(w, http.StatusNotFound, "Import not found")
+		return nil, nil, false
+	}
+	return list, imp, true
+}
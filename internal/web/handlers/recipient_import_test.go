@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/foxzi/sendry/internal/web/models"
+	"github.com/foxzi/sendry/internal/web/repository"
+)
+
+func TestRecipientImportMappingAndDedup(t *testing.T) {
+	h, database, cleanup := newDomainViewTestHandlers(t)
+	defer cleanup()
+
+	recipients := repository.NewRecipientRepository(database.DB)
+	list := &models.RecipientList{Name: "Customers", SourceType: "manual"}
+	other := &models.RecipientList{Name: "Partners", SourceType: "manual"}
+	for _, l := range []*models.RecipientList{list, other} {
+		if err := recipients.CreateList(l); err != nil {
+			t.Fatalf("CreateList() error = %v", err)
+		}
+	}
+	recipients.AddRecipient(&models.Recipient{ListID: list.ID, Email: "Anna@example.com", Name: "Anna", Variables: `{"city": "Berlin"}`})
+	recipients.AddRecipient(&models.Recipient{ListID: other.ID, Email: "carl@example.com"})
+
+	// Upload
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, _ := mw.CreateFormFile("file", "customers.csv")
+	fw.Write([]byte("Mail;Plan;Notes\nanna@example.com;pro;x\nben@example.com;free;y\nbroken;free;z\nben@example.com;pro;w\ncarl@example.com;pro;v\n"))
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/recipients/"+list.ID+"/import", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.SetPathValue("id", list.ID)
+	w := httptest.NewRecorder()
+	h.RecipientImport(w, req)
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("upload status = %d, body: %s", w.Code, w.Body.String())
+	}
+	location := w.Header().Get("Location")
+	importID := location[strings.LastIndex(location, "/")+1:]
+
+	get := func(handler http.HandlerFunc) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, location, nil)
+		req.SetPathValue("id", list.ID)
+		req.SetPathValue("importId", importID)
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+
+	// The mapping page guesses the email column and variables from headers
+	page := get(h.RecipientImportView).Body.String()
+	if !strings.Contains(page, `name="field_0"`) || !strings.Contains(page, `value="notes"`) || !strings.Contains(page, "anna@example.com, ben@example.com") {
+		t.Errorf("mapping page does not show the columns, body: %s", page)
+	}
+
+	form := url.Values{
+		"field_0": {"email"}, "field_1": {"variable"}, "variable_1": {"plan"}, "field_2": {""},
+		"existing": {"update"}, "other_lists": {"reject"},
+	}
+	req = httptest.NewRequest(http.MethodPost, location, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetPathValue("id", list.ID)
+	req.SetPathValue("importId", importID)
+	w = httptest.NewRecorder()
+	h.RecipientImportRun(w, req)
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("import status = %d, body: %s", w.Code, w.Body.String())
+	}
+
+	imp, err := recipients.GetImport(importID)
+	if err != nil || imp.Status != models.RecipientImportCompleted || imp.Imported != 1 || imp.Updated != 1 || len(imp.Rejected) != 3 || imp.Rows != nil {
+		t.Fatalf("import = %+v, %v", imp, err)
+	}
+	items, _, _ := recipients.ListRecipients(models.RecipientFilter{ListID: list.ID, Limit: 10})
+	if len(items) != 2 {
+		t.Fatalf("list has %d recipients, want 2", len(items))
+	}
+	for _, rec := range items {
+		if rec.Email == "Anna@example.com" && (rec.Name != "Anna" || !strings.Contains(rec.Variables, `"city":"Berlin"`) || !strings.Contains(rec.Variables, `"plan":"pro"`)) {
+			t.Errorf("updated recipient = %+v, want name and variables kept and merged", rec)
+		}
+	}
+
+	if page := get(h.RecipientImportView).Body.String(); !strings.Contains(page, "in list Partners") || !strings.Contains(page, "duplicate in file") {
+		t.Errorf("report does not show the rejected rows, body: %s", page)
+	}
+
+	csv := get(h.RecipientImportRejected).Body.String()
+	want := "Mail,Plan,Notes,row,reason\nbroken,free,z,3,invalid email\nben@example.com,pro,w,4,duplicate in file\ncarl@example.com,pro,v,5,in list Partners\n"
+	if csv != want {
+		t.Errorf("rejected CSV = %q, want %q", csv, want)
+	}
+
+	// A completed import cannot run again
+	req = httptest.NewRequest(http.MethodPost, location, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetPathValue("id", list.ID)
+	req.SetPathValue("importId", importID)
+	w = httptest.NewRecorder()
+	h.RecipientImportRun(w, req)
+	if w.Code != http.StatusConflict {
+		t.Errorf("second run status = %d, want 409", w.Code)
+	}
+}
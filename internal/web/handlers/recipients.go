@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/foxzi/sendry/internal/web/middleware"
 	"github.com/foxzi/sendry/internal/web/models"
 )
 
@@ -19,6 +20,7 @@ func (h *Handlers) RecipientListList(w http.ResponseWriter, r *http.Request) {
 	offset := (page - 1) * limit
 
 	filter := models.RecipientListFilter{
+		OrgID:  middleware.GetOrgID(r),
 		Search: search,
 		Limit:  limit,
 		Offset: offset,
@@ -74,6 +76,7 @@ func (h *Handlers) RecipientListCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	list.OrgID = middleware.GetOrgID(r)
 	if err := h.recipients.CreateList(list); err != nil {
 		h.logger.Error("failed to create recipient list", "error", err)
 		h.error(w, http.StatusInternalServerError, "Failed to create recipient list")
@@ -117,6 +120,26 @@ func (h *Handlers) RecipientListView(w http.ResponseWriter, r *http.Request) {
 		Offset: offset,
 	}
 
+	segments, err := h.recipients.ListSegments(id)
+	if err != nil {
+		h.logger.Error("failed to list segments", "error", err)
+	}
+
+	// Show only the recipients of a segment
+	segmentID := r.URL.Query().Get("segment")
+	var segment *models.Segment
+	for i := range segments {
+		if segments[i].ID == segmentID {
+			segment = &segments[i]
+		}
+	}
+	if segment != nil {
+		filter.Rules, err = models.ParseSegmentRules(segment.Rules)
+		if err != nil {
+			h.logger.Error("invalid segment rules", "segment_id", segment.ID, "error", err)
+		}
+	}
+
 	recipients, total, err := h.recipients.ListRecipients(filter)
 	if err != nil {
 		h.logger.Error("failed to list recipients", "error", err)
@@ -139,6 +162,8 @@ func (h *Handlers) RecipientListView(w http.ResponseWriter, r *http.Request) {
 		"Status":     status,
 		"Tag":        tag,
 		"Tags":       tags,
+		"Segments":   segments,
+		"Segment":    segment,
 	}
 
 	h.render(w, "recipient_list_view", data)
@@ -225,50 +250,6 @@ func (h *Handlers) RecipientImportPage(w http.ResponseWriter, r *http.Request) {
 	h.render(w, "recipient_import", data)
 }
 
-func (h *Handlers) RecipientImport(w http.ResponseWriter, r *http.Request) {
-	id := r.PathValue("id")
-
-	list, err := h.recipients.GetListByID(id)
-	if err != nil || list == nil {
-		h.error(w, http.StatusNotFound, "Recipient list not found")
-		return
-	}
-
-	// Parse multipart form (max 10MB)
-	if err := r.ParseMultipartForm(10 << 20); err != nil {
-		h.error(w, http.StatusBadRequest, "Failed to parse form")
-		return
-	}
-
-	file, _, err := r.FormFile("file")
-	if err != nil {
-		h.error(w, http.StatusBadRequest, "No file uploaded")
-		return
-	}
-	defer file.Close()
-
-	// Update source type to CSV
-	list.SourceType = "csv"
-	h.recipients.UpdateList(list)
-
-	result, err := h.recipients.ImportCSV(id, file)
-	if err != nil {
-		h.logger.Error("failed to import CSV", "error", err)
-		h.error(w, http.StatusInternalServerError, "Failed to import: "+err.Error())
-		return
-	}
-
-	data := map[string]any{
-		"Title":  "Import Results",
-		"Active": "recipients",
-		"User":   h.getUserFromContext(r),
-		"List":   list,
-		"Result": result,
-	}
-
-	h.render(w, "recipient_import_result", data)
-}
-
 func (h *Handlers) RecipientsList(w http.ResponseWriter, r *http.Request) {
 	// This endpoint returns just the recipients table for HTMX updates
 	id := r.PathValue("id")
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"net/http"
+)
+
+// ServerReputation shows the reputation scores of the sender domains of a server
+func (h *Handlers) ServerReputation(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	client, err := h.sendry.GetClient(name)
+	if err != nil {
+		h.error(w, http.StatusNotFound, "Server not found")
+		return
+	}
+
+	data := map[string]any{
+		"Title":      name + " - Reputation",
+		"Active":     "servers",
+		"User":       h.getUserFromContext(r),
+		"ServerName": name,
+	}
+
+	resp, err := client.ListReputation(r.Context())
+	if err != nil {
+		// Servers without reputation.enabled have no reputation API
+		h.logger.Warn("failed to list reputation scores", "error", err, "server", name)
+		data["Error"] = errMsg(err)
+	} else {
+		data["Scores"] = resp.Domains
+	}
+
+	h.render(w, "server_reputation", data)
+}
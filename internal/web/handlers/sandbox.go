@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/foxzi/sendry/internal/web/middleware"
+	sendryclient "github.com/foxzi/sendry/pkg/client"
+)
+
+// sandboxPageSize is the number of messages on a page of the sandbox inbox
+const sandboxPageSize = 50
+
+// ServerSandboxInbox lists the messages captured by a server in sandbox,
+// redirect and bcc mode
+func (h *Handlers) ServerSandboxInbox(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	client, err := h.sendry.GetClient(name)
+	if err != nil {
+		h.error(w, http.StatusNotFound, "Server not found")
+		return
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	filter := sendryclient.SandboxFilter{
+		Domain: strings.TrimSpace(r.URL.Query().Get("domain")),
+		Mode:   r.URL.Query().Get("mode"),
+		Search: strings.TrimSpace(r.URL.Query().Get("q")),
+		// One more than shown tells whether there is a next page
+		Limit:  sandboxPageSize + 1,
+		Offset: (page - 1) * sandboxPageSize,
+	}
+
+	data := map[string]any{
+		"Title":      name + " - Sandbox Inbox",
+		"Active":     "servers",
+		"User":       h.getUserFromContext(r),
+		"ServerName": name,
+		"Filter":     filter,
+		"Page":       page,
+		"Modes":      []string{"sandbox", "redirect", "bcc"},
+		"Cleared":    r.URL.Query().Get("cleared"),
+		"Released":   r.URL.Query().Get("released"),
+		"Skipped":    r.URL.Query().Get("skipped"),
+	}
+
+	resp, err := client.ListSandboxMessages(r.Context(), filter)
+	if err != nil {
+		h.logger.Warn("failed to list sandbox messages", "error", err, "server", name)
+		data["Error"] = errMsg(err)
+	} else {
+		messages := resp.Messages
+		if len(messages) > sandboxPageSize {
+			messages = messages[:sandboxPageSize]
+			data["HasNext"] = true
+		}
+		data["Messages"] = messages
+	}
+
+	if stats, err := client.GetSandboxStats(r.Context()); err == nil {
+		data["Stats"] = stats
+	}
+
+	h.render(w, "server_sandbox_inbox", data)
+}
+
+// ServerSandboxMessage shows a captured message with its headers, bodies,
+// raw source and DKIM verification
+func (h *Handlers) ServerSandboxMessage(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	id := r.PathValue("id")
+
+	client, err := h.sendry.GetClient(name)
+	if err != nil {
+		h.error(w, http.StatusNotFound, "Server not found")
+		return
+	}
+
+	msg, err := client.GetSandboxMessage(r.Context(), id)
+	if err != nil {
+		h.error(w, http.StatusNotFound, "Message not found: "+err.Error())
+		return
+	}
+
+	data := map[string]any{
+		"Title":      name + " - Sandbox Message",
+		"Active":     "servers",
+		"User":       h.getUserFromContext(r),
+		"ServerName": name,
+		"Message":    msg,
+		// Servers without a DKIM verifier return no results at all
+		"DKIMVerified": msg.DKIM != nil,
+	}
+
+	raw, err := client.GetSandboxRaw(r.Context(), id)
+	if err != nil {
+		h.logger.Warn("failed to get sandbox message source", "error", err, "server", name, "id", id)
+		data["RawError"] = errMsg(err)
+	} else {
+		data["Raw"] = string(raw)
+	}
+
+	h.render(w, "server_sandbox_message", data)
+}
+
+// ServerSandboxRaw downloads the source of a captured message
+func (h *Handlers) ServerSandboxRaw(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	id := r.PathValue("id")
+
+	client, err := h.sendry.GetClient(name)
+	if err != nil {
+		h.error(w, http.StatusNotFound, "Server not found")
+		return
+	}
+
+	raw, err := client.GetSandboxRaw(r.Context(), id)
+	if err != nil {
+		h.error(w, http.StatusNotFound, "Message not found: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "message/rfc822")
+	w.Header().Set("Content-Disposition", "attachment; filename=\""+sanitizeFilename(id)+".eml\"")
+	w.Write(raw)
+}
+
+// ServerSandboxMessageDelete deletes a captured message
+func (h *Handlers) ServerSandboxMessageDelete(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	id := r.PathValue("id")
+
+	client, err := h.sendry.GetClient(name)
+	if err != nil {
+		h.error(w, http.StatusNotFound, "Server not found")
+		return
+	}
+
+	if err := client.DeleteSandboxMessage(r.Context(), id); err != nil {
+		h.logger.Error("failed to delete sandbox message", "server", name, "id", id, "error", err)
+	} else {
+		h.settings.LogAction(r, middleware.GetUserID(r), middleware.GetUserEmail(r),
+			"delete", "sandbox_message", id, auditJSON(map[string]any{"server": name}))
+	}
+
+	http.Redirect(w, r, "/servers/"+name+"/sandbox/messages", http.StatusSeeOther)
+}
+
+// ServerSandboxClear deletes the captured messages of a domain, or of all
+// domains, older than the given age
+func (h *Handlers) ServerSandboxClear(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	client, err := h.sendry.GetClient(name)
+	if err != nil {
+		h.error(w, http.StatusNotFound, "Server not found")
+		return
+	}
+
+	domain := strings.TrimSpace(r.FormValue("domain"))
+	olderThan := strings.TrimSpace(r.FormValue("older_than"))
+	if olderThan != "" {
+		if _, err := time.ParseDuration(olderThan); err != nil {
+			h.error(w, http.StatusBadRequest, "Invalid age, use a duration such as 24h")
+			return
+		}
+	}
+
+	resp, err := client.ClearSandbox(r.Context(), domain, olderThan)
+	if err != nil {
+		h.logger.Error("failed to clear sandbox", "server", name, "error", err)
+		h.error(w, http.StatusInternalServerError, fmt.Sprintf("Failed to clear sandbox: %v", err))
+		return
+	}
+
+	h.settings.LogAction(r, middleware.GetUserID(r), middleware.GetUserEmail(r),
+		"purge", "sandbox", name, auditJSON(map[string]any{
+			"domain":     domain,
+			"older_than": olderThan,
+			"cleared":    resp.Cleared,
+		}))
+
+	http.Redirect(w, r, "/servers/"+name+"/sandbox/messages?cleared="+strconv.Itoa(resp.Cleared), http.StatusSeeOther)
+}
+
+// ServerSandboxMessageRelease queues a captured message for delivery to its
+// original recipients
+func (h *Handlers) ServerSandboxMessageRelease(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	id := r.PathValue("id")
+
+	client, err := h.sendry.GetClient(name)
+	if err != nil {
+		h.error(w, http.StatusNotFound, "Server not found")
+		return
+	}
+
+	resp, err := client.ReleaseSandboxMessage(r.Context(), id)
+	if err != nil {
+		h.logger.Error("failed to release sandbox message", "server", name, "id", id, "error", err)
+		h.error(w, http.StatusInternalServerError, fmt.Sprintf("Failed to release message: %v", err))
+		return
+	}
+
+	h.settings.LogAction(r, middleware.GetUserID(r), middleware.GetUserEmail(r),
+		"release", "sandbox_message", id, auditJSON(map[string]any{
+			"server":     name,
+			"message_id": resp.MessageID,
+		}))
+
+	http.Redirect(w, r, "/servers/"+name+"/sandbox/messages?released=1", http.StatusSeeOther)
+}
+
+// ServerSandboxRelease queues the selected captured messages, or those of a
+// domain, for delivery to their original recipients
+func (h *Handlers) ServerSandboxRelease(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	client, err := h.sendry.GetClient(name)
+	if err != nil {
+		h.error(w, http.StatusNotFound, "Server not found")
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		h.error(w, http.StatusBadRequest, "Invalid form data")
+		return
+	}
+
+	req := &sendryclient.SandboxReleaseRequest{
+		IDs:     r.Form["ids"],
+		Domain:  strings.TrimSpace(r.FormValue("domain")),
+		Confirm: r.FormValue("confirm") == "1",
+	}
+	if len(req.IDs) == 0 && req.Domain == "" {
+		h.error(w, http.StatusBadRequest, "Select messages or enter a domain to release")
+		return
+	}
+	if !req.Confirm {
+		h.error(w, http.StatusBadRequest, "Confirm that the messages are delivered to their real recipients")
+		return
+	}
+
+	resp, err := client.ReleaseSandbox(r.Context(), req)
+	if err != nil {
+		h.logger.Error("failed to release sandbox messages", "server", name, "error", err)
+		h.error(w, http.StatusInternalServerError, fmt.Sprintf("Failed to release messages: %v", err))
+		return
+	}
+
+	h.settings.LogAction(r, middleware.GetUserID(r), middleware.GetUserEmail(r),
+		"release", "sandbox", name, auditJSON(map[string]any{
+			"domain":   req.Domain,
+			"ids":      req.IDs,
+			"released": len(resp.Released),
+			"skipped":  len(resp.Skipped),
+		}))
+
+	http.Redirect(w, r, fmt.Sprintf("/servers/%s/sandbox/messages?released=%d&skipped=%d",
+		name, len(resp.Released), len(resp.Skipped)), http.StatusSeeOther)
+}
@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/foxzi/sendry/internal/web/models"
+)
+
+// segmentFormRows is the number of empty condition rows in the segment form
+const segmentFormRows = 2
+
+func (h *Handlers) SegmentNew(w http.ResponseWriter, r *http.Request) {
+	list, ok := h.segmentList(w, r)
+	if !ok {
+		return
+	}
+	h.renderSegmentForm(w, r, list, &models.Segment{}, nil)
+}
+
+func (h *Handlers) SegmentCreate(w http.ResponseWriter, r *http.Request) {
+	list, ok := h.segmentList(w, r)
+	if !ok {
+		return
+	}
+
+	s := &models.Segment{ListID: list.ID}
+	if !h.segmentFromForm(w, r, s) {
+		return
+	}
+	if err := h.recipients.CreateSegment(s); err != nil {
+		h.logger.Error("failed to create segment", "error", err)
+		h.error(w, http.StatusInternalServerError, "Failed to create segment")
+		return
+	}
+
+	http.Redirect(w, r, "/recipients/"+list.ID, http.StatusSeeOther)
+}
+
+func (h *Handlers) SegmentEdit(w http.ResponseWriter, r *http.Request) {
+	list, s, ok := h.segment(w, r)
+	if !ok {
+		return
+	}
+
+	rules, err := models.ParseSegmentRules(s.Rules)
+	if err != nil {
+		h.logger.Error("invalid segment rules", "segment_id", s.ID, "error", err)
+	}
+	h.renderSegmentForm(w, r, list, s, rules)
+}
+
+func (h *Handlers) SegmentUpdate(w http.ResponseWriter, r *http.Request) {
+	list, s, ok := h.segment(w, r)
+	if !ok {
+		return
+	}
+
+	if !h.segmentFromForm(w, r, s) {
+		return
+	}
+	if err := h.recipients.UpdateSegment(s); err != nil {
+		h.logger.Error("failed to update segment", "error", err)
+		h.error(w, http.StatusInternalServerError, "Failed to update segment")
+		return
+	}
+
+	http.Redirect(w, r, "/recipients/"+list.ID, http.StatusSeeOther)
+}
+
+func (h *Handlers) SegmentDelete(w http.ResponseWriter, r *http.Request) {
+	list, s, ok := h.segment(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.recipients.DeleteSegment(s.ID); err != nil {
+		h.logger.Error("failed to delete segment", "error", err)
+		h.error(w, http.StatusInternalServerError, "Failed to delete segment")
+		return
+	}
+
+	http.Redirect(w, r, "/recipients/"+list.ID, http.StatusSeeOther)
+}
+
+// SegmentCount returns the number of active recipients matching the rules
+// of the segment form, for the live count while editing
+func (h *Handlers) SegmentCount(w http.ResponseWriter, r *http.Request) {
+	list, ok := h.segmentList(w, r)
+	if !ok {
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		h.json(w, http.StatusBadRequest, map[string]any{"error": "Invalid form data"})
+		return
+	}
+	rules, err := segmentRulesFromForm(r)
+	if err != nil {
+		h.json(w, http.StatusOK, map[string]any{"error": err.Error()})
+		return
+	}
+
+	count, err := h.recipients.CountSegment(list.ID, rules)
+	if err != nil {
+		h.logger.Error("failed to count segment", "error", err)
+		h.json(w, http.StatusInternalServerError, map[string]any{"error": "Failed to count recipients"})
+		return
+	}
+	h.json(w, http.StatusOK, map[string]any{"count": count, "active": list.ActiveCount})
+}
+
+func (h *Handlers) renderSegmentForm(w http.ResponseWriter, r *http.Request, list *models.RecipientList, s *models.Segment, rules *models.SegmentRules) {
+	var rows []models.SegmentCondition
+	match := "all"
+	if rules != nil {
+		rows = rules.Conditions
+		if rules.Match != "" {
+			match = rules.Match
+		}
+	}
+	for i := range rows {
+		if len(rows[i].Values) > 0 {
+			rows[i].Value = strings.Join(rows[i].Values, ", ")
+		}
+	}
+	rows = append(rows, make([]models.SegmentCondition, segmentFormRows)...)
+
+	title := "New Segment"
+	if s.ID != "" {
+		title = "Edit Segment: " + s.Name
+	}
+
+	data := map[string]any{
+		"Title":      title,
+		"Active":     "recipients",
+		"User":       h.getUserFromContext(r),
+		"List":       list,
+		"Segment":    s,
+		"Match":      match,
+		"Conditions": rows,
+		"Ops":        models.SegmentOps,
+	}
+
+	h.render(w, "segment_form", data)
+}
+
+// segmentList returns the recipient list of the request, writing the error
+// response if there is none
+func (h *Handlers) segmentList(w http.ResponseWriter, r *http.Request) (*models.RecipientList, bool) {
+	list, err := h.recipients.GetListByID(r.PathValue("id"))
+	if err != nil {
+		h.logger.Error("failed to get recipient list", "error", err)
+		h.error(w, http.StatusInternalServerError, "Failed to load recipient list")
+		return nil, false
+	}
+	if list == nil {
+		h.error(w, http.StatusNotFound, "Recipient list not found")
+		return nil, false
+	}
+	return list, true
+}
+
+// segment returns the recipient list and segment of the request, writing
+// the error response if there is none
+func (h *Handlers) segment(w http.ResponseWriter, r *http.Request) (*models.RecipientList, *models.Segment, bool) {
+	list, ok := h.segmentList(w, r)
+	if !ok {
+		return nil, nil, false
+	}
+
+	s, err := h.recipients.GetSegment(r.PathValue("segmentId"))
+	if err != nil {
+		h.logger.Error("failed to get segment", "error", err)
+		h.error(w, http.StatusInternalServerError, "Failed to load segment")
+		return nil, nil, false
+	}
+	if s == nil || s.ListID != list.ID {
+		h.error(w, http.StatusNotFound, "Segment not found")
+		return nil, nil, false
+	}
+	return list, s, true
+}
+
+// segmentFromForm sets the name, description and rules of a segment from
+// the submitted form. It writes the error response and returns false if
+// the form is invalid.
+func (h *Handlers) segmentFromForm(w http.ResponseWriter, r *http.Request, s *models.Segment) bool {
+	if err := r.ParseForm(); err != nil {
+		h.error(w, http.StatusBadRequest, "Invalid form data")
+		return false
+	}
+
+	s.Name = strings.TrimSpace(r.FormValue("name"))
+	s.Description = r.FormValue("description")
+	if s.Name == "" {
+		h.error(w, http.StatusBadRequest, "Name is required")
+		return false
+	}
+
+	rules, err := segmentRulesFromForm(r)
+	if err != nil {
+		h.error(w, http.StatusBadRequest, "Invalid segment rules: "+err.Error())
+		return false
+	}
+	data, _ := json.Marshal(rules)
+	s.Rules = string(data)
+	return true
+}
+
+// segmentRulesFromForm reads the condition rows of the segment form. Rows
+// without a field are skipped; in and not_in take comma-separated values.
+func segmentRulesFromForm(r *http.Request) (*models.SegmentRules, error) {
+	fields, ops, values := r.Form["field"], r.Form["op"], r.Form["value"]
+
+	rules := &models.SegmentRules{Match: r.FormValue("match")}
+	for i, field := range fields {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		c := models.SegmentCondition{Field: field}
+		if i < len(ops) {
+			c.Op = ops[i]
+		}
+		value := ""
+		if i < len(values) {
+			value = strings.TrimSpace(values[i])
+		}
+
+		switch c.Op {
+		case models.SegmentOpIn, models.SegmentOpNotIn:
+			for _, v := range strings.Split(value, ",") {
+				if v = strings.TrimSpace(v); v != "" {
+					c.Values = append(c.Values, v)
+				}
+			}
+		case models.SegmentOpExists, models.SegmentOpNotExists:
+		default:
+			c.Value = value
+		}
+		rules.Conditions = append(rules.Conditions, c)
+	}
+
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/foxzi/sendry/internal/web/models"
+	"github.com/foxzi/sendry/internal/web/repository"
+)
+
+func TestSegmentsAndCampaignSend(t *testing.T) {
+	h, database, cleanup := newDomainViewTestHandlers(t)
+	defer cleanup()
+
+	recipients := repository.NewRecipientRepository(database.DB)
+	list := &models.RecipientList{Name: "Customers", SourceType: "manual"}
+	if err := recipients.CreateList(list); err != nil {
+		t.Fatalf("CreateList() error = %v", err)
+	}
+	for email, vars := range map[string]string{
+		"anna@example.de":  `{"country": "DE", "plan": "pro"}`,
+		"ben@example.de":   `{"country": "DE", "plan": "free"}`,
+		"carl@example.com": `{"country": "US", "plan": "pro"}`,
+	} {
+		if err := recipients.AddRecipient(&models.Recipient{ListID: list.ID, Email: email, Variables: vars}); err != nil {
+			t.Fatalf("AddRecipient() error = %v", err)
+		}
+	}
+
+	post := func(handler http.HandlerFunc, path string, form url.Values, values map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		for k, v := range values {
+			req.SetPathValue(k, v)
+		}
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+	rules := url.Values{
+		"match": {"all"},
+		"field": {"country", "plan", ""},
+		"op":    {"eq", "in", "eq"},
+		"value": {"DE", "pro, team", ""},
+	}
+
+	// Live count of the form rules
+	w := post(h.SegmentCount, "/recipients/"+list.ID+"/segments/count", rules, map[string]string{"id": list.ID})
+	var count struct {
+		Count  int    `json:"count"`
+		Active int    `json:"active"`
+		Error  string `json:"error"`
+	}
+	json.NewDecoder(w.Body).Decode(&count)
+	if count.Count != 1 || count.Active != 3 {
+		t.Errorf("count = %+v, want 1 of 3", count)
+	}
+	w = post(h.SegmentCount, "/recipients/"+list.ID+"/segments/count", url.Values{"field": {"orders"}, "op": {"gt"}, "value": {"x"}}, map[string]string{"id": list.ID})
+	json.NewDecoder(w.Body).Decode(&count)
+	if !strings.Contains(count.Error, "needs a number") {
+		t.Errorf("count error = %q, want invalid number", count.Error)
+	}
+
+	form := url.Values{"name": {"German pros"}}
+	for k, v := range rules {
+		form[k] = v
+	}
+	if w := post(h.SegmentCreate, "/recipients/"+list.ID+"/segments", form, map[string]string{"id": list.ID}); w.Code != http.StatusSeeOther {
+		t.Fatalf("create segment status = %d, body: %s", w.Code, w.Body.String())
+	}
+	segments, err := recipients.ListSegments(list.ID)
+	if err != nil || len(segments) != 1 || segments[0].Count != 1 {
+		t.Fatalf("segments = %+v, %v", segments, err)
+	}
+	segment := segments[0]
+
+	req := httptest.NewRequest(http.MethodGet, "/recipients/"+list.ID+"?segment="+segment.ID, nil)
+	req.SetPathValue("id", list.ID)
+	rec := httptest.NewRecorder()
+	h.RecipientListView(rec, req)
+	body := rec.Body.String()
+	if !strings.Contains(body, `country == &#34;DE&#34; and plan in [pro, team]`) || !strings.Contains(body, "Recipients (1) in German pros") {
+		t.Errorf("list view does not show the segment, body: %s", body)
+	}
+
+	// A campaign send with the segment creates items only for its recipients
+	tmpl := &models.Template{Name: "Welcome", Subject: "Hi", HTML: "<p>Hi</p>"}
+	if err := repository.NewTemplateRepository(database.DB).Create(tmpl, ""); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	campaigns := repository.NewCampaignRepository(database.DB)
+	campaign := &models.Campaign{Name: "Launch", FromEmail: "news@example.com"}
+	if err := campaigns.Create(campaign); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := campaigns.AddVariant(&models.CampaignVariant{CampaignID: campaign.ID, Name: "A", TemplateID: tmpl.ID, Weight: 100}); err != nil {
+		t.Fatalf("AddVariant() error = %v", err)
+	}
+
+	send := url.Values{"recipient_list_id": {list.ID}, "segment_id": {segment.ID}, "servers": {"prod"}}
+	w = post(h.CampaignSend, "/campaigns/"+campaign.ID+"/send", send, map[string]string{"id": campaign.ID})
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("send status = %d, body: %s", w.Code, w.Body.String())
+	}
+	jobID := strings.TrimPrefix(w.Header().Get("Location"), "/jobs/")
+	items, err := h.jobs.GetPendingItems(jobID, 10)
+	if err != nil || len(items) != 1 || items[0].Email != "anna@example.de" {
+		t.Errorf("job items = %+v, %v", items, err)
+	}
+	job, err := h.jobs.GetByID(jobID)
+	if err != nil || job.SegmentID != segment.ID || job.SegmentName != "German pros" {
+		t.Errorf("job = %+v, %v", job, err)
+	}
+
+	// The segment must belong to the list
+	other := &models.RecipientList{Name: "Other", SourceType: "manual"}
+	recipients.CreateList(other)
+	send.Set("recipient_list_id", other.ID)
+	if w := post(h.CampaignSend, "/campaigns/"+campaign.ID+"/send", send, map[string]string{"id": campaign.ID}); w.Code != http.StatusBadRequest {
+		t.Errorf("send with segment of another list status = %d, want 400", w.Code)
+	}
+}
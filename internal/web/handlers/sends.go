@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/foxzi/sendry/internal/web/middleware"
 	"github.com/foxzi/sendry/internal/web/models"
 	"github.com/foxzi/sendry/internal/web/repository"
 )
@@ -24,6 +25,7 @@ func (h *Handlers) SendsList(w http.ResponseWriter, r *http.Request) {
 	offset := (page - 1) * limit
 
 	filter := models.SendFilter{
+		OrgID:        middleware.GetOrgID(r),
 		Search:       search,
 		Status:       status,
 		SenderDomain: domain,
@@ -40,7 +42,7 @@ func (h *Handlers) SendsList(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get stats
-	stats, _ := h.sends.GetStats(models.SendFilter{})
+	stats, _ := h.sends.GetStats(models.SendFilter{OrgID: middleware.GetOrgID(r)})
 
 	// Get unique domains and servers for filters
 	domains, _ := h.sends.GetDomains()
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/foxzi/sendry/internal/web/middleware"
+	sendryclient "github.com/foxzi/sendry/pkg/client"
+)
+
+// apiKeyScopes lists the scopes offered when creating a server API key
+var apiKeyScopes = []string{"send", "read", "admin"}
+
+// ServerAPIKeys shows the API keys of a server
+func (h *Handlers) ServerAPIKeys(w http.ResponseWriter, r *http.Request) {
+	h.renderServerAPIKeys(w, r, r.PathValue("name"), nil)
+}
+
+// renderServerAPIKeys renders the API keys page, with the token of a key
+// that was just created
+func (h *Handlers) renderServerAPIKeys(w http.ResponseWriter, r *http.Request, name string, created *sendryclient.APIKeyCreateResponse) {
+	client, err := h.sendry.GetClient(name)
+	if err != nil {
+		h.error(w, http.StatusNotFound, "Server not found")
+		return
+	}
+
+	keys, err := client.ListAPIKeys(r.Context())
+	if err != nil {
+		h.logger.Error("failed to list API keys", "error", err, "server", name)
+		h.error(w, http.StatusInternalServerError, "Failed to load API keys")
+		return
+	}
+
+	data := map[string]any{
+		"Title":      "API Keys",
+		"Active":     "servers",
+		"User":       h.getUserFromContext(r),
+		"ServerName": name,
+		"Keys":       keys.Keys,
+		"Scopes":     apiKeyScopes,
+		"Created":    created,
+	}
+
+	h.render(w, "server_apikeys", data)
+}
+
+// ServerAPIKeyCreate creates an API key on a server and shows its token once
+func (h *Handlers) ServerAPIKeyCreate(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	if err := r.ParseForm(); err != nil {
+		h.error(w, http.StatusBadRequest, "Invalid form data")
+		return
+	}
+
+	client, err := h.sendry.GetClient(name)
+	if err != nil {
+		h.error(w, http.StatusNotFound, "Server not found")
+		return
+	}
+
+	req := &sendryclient.APIKeyCreateRequest{
+		Name:    strings.TrimSpace(r.FormValue("name")),
+		Scopes:  r.Form["scopes"],
+		Senders: strings.FieldsFunc(r.FormValue("senders"), func(c rune) bool { return c == ',' || unicode.IsSpace(c) }),
+	}
+	if req.Name == "" {
+		h.error(w, http.StatusBadRequest, "Name is required")
+		return
+	}
+
+	var limit sendryclient.APIKeyLimit
+	for field, value := range map[string]*int{
+		"per_minute": &limit.MessagesPerMinute,
+		"per_hour":   &limit.MessagesPerHour,
+		"per_day":    &limit.MessagesPerDay,
+	} {
+		text := strings.TrimSpace(r.FormValue(field))
+		if text == "" {
+			continue
+		}
+		n, err := strconv.Atoi(text)
+		if err != nil || n < 0 {
+			h.error(w, http.StatusBadRequest, fmt.Sprintf("Invalid rate limit: %s", text))
+			return
+		}
+		*value = n
+	}
+	if limit != (sendryclient.APIKeyLimit{}) {
+		req.RateLimit = &limit
+	}
+
+	created, err := client.CreateAPIKey(r.Context(), req)
+	if err != nil {
+		h.logger.Error("failed to create API key", "error", err, "server", name)
+		h.error(w, http.StatusInternalServerError, fmt.Sprintf("Failed to create API key: %v", err))
+		return
+	}
+
+	h.settings.LogAction(r, middleware.GetUserID(r), middleware.GetUserEmail(r),
+		"create", "server_api_key", created.ID,
+		auditJSON(map[string]any{"server": name, "name": created.Name, "scopes": created.Scopes, "senders": created.Senders}))
+
+	h.renderServerAPIKeys(w, r, name, created)
+}
+
+// ServerAPIKeyRevoke revokes an API key on a server
+func (h *Handlers) ServerAPIKeyRevoke(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	id := r.PathValue("id")
+
+	client, err := h.sendry.GetClient(name)
+	if err != nil {
+		h.error(w, http.StatusNotFound, "Server not found")
+		return
+	}
+
+	if err := client.RevokeAPIKey(r.Context(), id); err != nil {
+		h.logger.Error("failed to revoke API key", "error", err, "server", name, "id", id)
+		h.error(w, http.StatusInternalServerError, fmt.Sprintf("Failed to revoke API key: %v", err))
+		return
+	}
+
+	h.settings.LogAction(r, middleware.GetUserID(r), middleware.GetUserEmail(r),
+		"revoke", "server_api_key", id, auditJSON(map[string]any{"server": name}))
+
+	http.Redirect(w, r, "/servers/"+name+"/apikeys", http.StatusSeeOther)
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	sendryclient "github.com/foxzi/sendry/pkg/client"
+)
+
+// ServerAudit shows the management API audit log of a server
+func (h *Handlers) ServerAudit(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	client, err := h.sendry.GetClient(name)
+	if err != nil {
+		h.error(w, http.StatusNotFound, "Server not found")
+		return
+	}
+
+	filter := sendryclient.AuditLogFilter{
+		Actor: strings.TrimSpace(r.URL.Query().Get("actor")),
+		Path:  strings.TrimSpace(r.URL.Query().Get("path")),
+		Limit: 100,
+	}
+
+	data := map[string]any{
+		"Title":      name + " - Audit Log",
+		"Active":     "servers",
+		"User":       h.getUserFromContext(r),
+		"ServerName": name,
+		"Filter":     filter,
+	}
+
+	resp, err := client.ListAuditLog(r.Context(), filter)
+	if err != nil {
+		h.logger.Warn("failed to list audit log", "error", err, "server", name)
+		data["Error"] = errMsg(err)
+	} else {
+		data["Entries"] = resp.Entries
+	}
+
+	h.render(w, "server_audit", data)
+}
@@ -4,12 +4,13 @@ import (
 	"net/http"
 	"sync"
 
-	"github.com/foxzi/sendry/internal/web/sendry"
+	"github.com/foxzi/sendry/internal/web/middleware"
+	sendryclient "github.com/foxzi/sendry/pkg/client"
 )
 
 // QueueOverview shows combined queue and DLQ from all servers
 func (h *Handlers) QueueOverview(w http.ResponseWriter, r *http.Request) {
-	servers := h.sendry.GetServers()
+	servers := h.orgServers(r)
 
 	type serverStats struct {
 		Name      string
@@ -113,7 +114,8 @@ func (h *Handlers) QueueOverview(w http.ResponseWriter, r *http.Request) {
 
 // ServerList shows all configured Sendry servers
 func (h *Handlers) ServerList(w http.ResponseWriter, r *http.Request) {
-	statuses := h.sendry.GetAllStatus(r.Context())
+	statuses := h.orgServersStatus(r)
+	warnings := h.serverWarnings()
 	servers := make([]map[string]any, 0, len(statuses))
 	for _, s := range statuses {
 		servers = append(servers, map[string]any{
@@ -124,6 +126,7 @@ func (h *Handlers) ServerList(w http.ResponseWriter, r *http.Request) {
 			"Version":   s.Version,
 			"QueueSize": s.QueueSize,
 			"Error":     s.Error,
+			"Warnings":  warnings[s.Name],
 		})
 	}
 
@@ -309,6 +312,9 @@ func (h *Handlers) QueueMessageDelete(w http.ResponseWriter, r *http.Request) {
 
 	if err := client.DeleteFromQueue(r.Context(), id); err != nil {
 		h.logger.Error("failed to delete queue message", "server", name, "id", id, "error", err)
+	} else {
+		h.settings.LogAction(r, middleware.GetUserID(r), middleware.GetUserEmail(r),
+			"delete", "queue_message", id, auditJSON(map[string]any{"server": name}))
 	}
 
 	http.Redirect(w, r, "/servers/"+name+"/queue", http.StatusSeeOther)
@@ -327,6 +333,9 @@ func (h *Handlers) DLQMessageRetry(w http.ResponseWriter, r *http.Request) {
 
 	if err := client.RetryDLQ(r.Context(), id); err != nil {
 		h.logger.Error("failed to retry DLQ message", "server", name, "id", id, "error", err)
+	} else {
+		h.settings.LogAction(r, middleware.GetUserID(r), middleware.GetUserEmail(r),
+			"retry", "dlq_message", id, auditJSON(map[string]any{"server": name}))
 	}
 
 	http.Redirect(w, r, "/servers/"+name+"/dlq", http.StatusSeeOther)
@@ -345,6 +354,9 @@ func (h *Handlers) DLQMessageDelete(w http.ResponseWriter, r *http.Request) {
 
 	if err := client.DeleteFromDLQ(r.Context(), id); err != nil {
 		h.logger.Error("failed to delete DLQ message", "server", name, "id", id, "error", err)
+	} else {
+		h.settings.LogAction(r, middleware.GetUserID(r), middleware.GetUserEmail(r),
+			"delete", "dlq_message", id, auditJSON(map[string]any{"server": name}))
 	}
 
 	http.Redirect(w, r, "/servers/"+name+"/dlq", http.StatusSeeOther)
@@ -365,6 +377,8 @@ func (h *Handlers) QueuePurge(w http.ResponseWriter, r *http.Request) {
 		h.logger.Error("failed to purge queue", "server", name, "error", err)
 	} else {
 		h.logger.Info("queue purged", "server", name, "deleted", deleted)
+		h.settings.LogAction(r, middleware.GetUserID(r), middleware.GetUserEmail(r),
+			"purge", "queue", name, auditJSON(map[string]any{"deleted": deleted}))
 	}
 
 	http.Redirect(w, r, "/servers/"+name+"/queue", http.StatusSeeOther)
@@ -385,6 +399,8 @@ func (h *Handlers) DLQPurge(w http.ResponseWriter, r *http.Request) {
 		h.logger.Error("failed to purge DLQ", "server", name, "error", err)
 	} else {
 		h.logger.Info("DLQ purged", "server", name, "deleted", deleted)
+		h.settings.LogAction(r, middleware.GetUserID(r), middleware.GetUserEmail(r),
+			"purge", "dlq", name, auditJSON(map[string]any{"deleted": deleted}))
 	}
 
 	http.Redirect(w, r, "/servers/"+name+"/dlq", http.StatusSeeOther)
@@ -439,7 +455,7 @@ func (h *Handlers) ServerSandbox(w http.ResponseWriter, r *http.Request) {
 	name := r.PathValue("name")
 
 	// Get all servers for selection
-	servers := h.getServersStatus()
+	servers := h.getServersStatus(r)
 
 	data := map[string]any{
 		"Title":      "Send Test Email",
@@ -470,7 +486,7 @@ func (h *Handlers) ServerSandbox(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		req := &sendry.SendRequest{
+		req := &sendryclient.SendRequest{
 			From:    r.FormValue("from"),
 			To:      []string{r.FormValue("to")},
 			Subject: r.FormValue("subject"),
@@ -4,10 +4,12 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/foxzi/sendry/internal/web/backup"
 	"github.com/foxzi/sendry/internal/web/middleware"
 	"github.com/foxzi/sendry/internal/web/models"
-	"github.com/foxzi/sendry/internal/web/sendry"
+	sendryclient "github.com/foxzi/sendry/pkg/client"
 )
 
 // Settings shows settings overview
@@ -117,7 +119,7 @@ func (h *Handlers) AuditLog(w http.ResponseWriter, r *http.Request) {
 
 // SettingsTestEmail provides test email sending interface
 func (h *Handlers) SettingsTestEmail(w http.ResponseWriter, r *http.Request) {
-	servers := h.getServersStatus()
+	servers := h.getServersStatus(r)
 
 	data := map[string]any{
 		"Title":   "Send Test Email",
@@ -147,7 +149,7 @@ func (h *Handlers) SettingsTestEmail(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		req := &sendry.SendRequest{
+		req := &sendryclient.SendRequest{
 			From:    r.FormValue("from"),
 			To:      []string{r.FormValue("to")},
 			Subject: r.FormValue("subject"),
@@ -173,7 +175,7 @@ func (h *Handlers) SettingsTestEmail(w http.ResponseWriter, r *http.Request) {
 // Monitoring shows system monitoring overview
 func (h *Handlers) Monitoring(w http.ResponseWriter, r *http.Request) {
 	// Gather live stats from all servers
-	statuses := h.sendry.GetAllStatus(r.Context())
+	statuses := h.orgServersStatus(r)
 	servers := make([]map[string]any, 0, len(statuses))
 
 	for _, s := range statuses {
@@ -267,7 +269,7 @@ func (h *Handlers) MonitoringAPIStats(w http.ResponseWriter, r *http.Request) {
 
 // MonitoringAPIServers returns JSON data for server status
 func (h *Handlers) MonitoringAPIServers(w http.ResponseWriter, r *http.Request) {
-	statuses := h.sendry.GetAllStatus(r.Context())
+	statuses := h.orgServersStatus(r)
 	servers := make([]models.ServerStats, 0, len(statuses))
 
 	for _, s := range statuses {
@@ -301,3 +303,60 @@ func (h *Handlers) jsonError(w http.ResponseWriter, message string, status int)
 	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(map[string]string{"error": message})
 }
+
+// SetBackupManager sets the manager of database backups
+func (h *Handlers) SetBackupManager(m *backup.Manager) {
+	h.backups = m
+}
+
+// Backups shows backup settings, the last backup result and local backups
+func (h *Handlers) Backups(w http.ResponseWriter, r *http.Request) {
+	if h.backups == nil {
+		h.error(w, http.StatusNotFound, "Backups are not available")
+		return
+	}
+
+	last, err := h.backups.LastResult()
+	if err != nil {
+		h.logger.Error("failed to load last backup result", "error", err)
+	}
+	files, err := h.backups.List()
+	if err != nil {
+		h.logger.Error("failed to list backups", "error", err)
+	}
+
+	cfg := h.backups.Config()
+	data := map[string]any{
+		"Title":        "Backups",
+		"Active":       "settings",
+		"User":         h.getUserFromContext(r),
+		"Config":       cfg,
+		"UploadTarget": h.backups.UploadTarget(),
+		"Last":         last,
+		"Files":        files,
+	}
+	if cfg.Enabled {
+		data["NextRun"] = h.backups.NextRun(time.Now())
+	}
+
+	h.render(w, "settings_backups", data)
+}
+
+// BackupRun creates a backup now
+func (h *Handlers) BackupRun(w http.ResponseWriter, r *http.Request) {
+	if h.backups == nil {
+		h.error(w, http.StatusNotFound, "Backups are not available")
+		return
+	}
+
+	result := h.backups.Run(r.Context())
+
+	details := ""
+	if result.Error != "" {
+		details = `{"error":` + strconv.Quote(result.Error) + `}`
+	}
+	h.settings.LogAction(r, middleware.GetUserID(r), middleware.GetUserEmail(r),
+		"backup", "database", result.File, details)
+
+	http.Redirect(w, r, "/settings/backups", http.StatusSeeOther)
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/foxzi/sendry/internal/web/middleware"
+	sendryclient "github.com/foxzi/sendry/pkg/client"
+)
+
+// shapingRow is one weekday row of the send schedule grid
+type shapingRow struct {
+	Day   string
+	Index int      // Weekday index used by the API (Sunday = 0)
+	Cells []string // Hourly limits, empty = unlimited
+}
+
+// shapingDays lists weekdays in grid order (Monday first)
+var shapingDays = []time.Weekday{
+	time.Monday, time.Tuesday, time.Wednesday, time.Thursday,
+	time.Friday, time.Saturday, time.Sunday,
+}
+
+// DomainsShaping shows the send schedule grid for a domain
+func (h *Handlers) DomainsShaping(w http.ResponseWriter, r *http.Request) {
+	serverName := r.PathValue("server")
+	domainName := r.PathValue("domain")
+
+	client, err := h.sendry.GetClient(serverName)
+	if err != nil {
+		h.error(w, http.StatusNotFound, "Server not found")
+		return
+	}
+
+	schedules, err := client.ListShapingSchedules(r.Context())
+	if err != nil {
+		h.logger.Error("failed to list send schedules", "error", err, "server", serverName)
+		h.error(w, http.StatusInternalServerError, "Failed to load send schedule")
+		return
+	}
+
+	var schedule *sendryclient.ShapingSchedule
+	for i := range schedules.Schedules {
+		if schedules.Schedules[i].Domain == strings.ToLower(domainName) {
+			schedule = &schedules.Schedules[i]
+			break
+		}
+	}
+
+	hours := make([][]int, 7)
+	for day := range hours {
+		hours[day] = make([]int, 24)
+		for hour := range hours[day] {
+			hours[day][hour] = -1
+		}
+	}
+	if schedule != nil && len(schedule.Hours) == 7 {
+		hours = schedule.Hours
+	}
+
+	rows := make([]shapingRow, 0, len(shapingDays))
+	for _, day := range shapingDays {
+		row := shapingRow{Day: day.String(), Index: int(day), Cells: make([]string, 24)}
+		for hour, limit := range hours[day] {
+			if limit >= 0 && hour < 24 {
+				row.Cells[hour] = strconv.Itoa(limit)
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	hourLabels := make([]string, 24)
+	for hour := range hourLabels {
+		hourLabels[hour] = fmt.Sprintf("%02d", hour)
+	}
+
+	data := map[string]any{
+		"Title":      fmt.Sprintf("Send Schedule: %s", domainName),
+		"Active":     "servers",
+		"User":       h.getUserFromContext(r),
+		"ServerName": serverName,
+		"DomainName": domainName,
+		"Schedule":   schedule,
+		"Rows":       rows,
+		"Hours":      hourLabels,
+	}
+
+	h.render(w, "domain_shaping", data)
+}
+
+// DomainsShapingUpdate saves the send schedule grid for a domain
+func (h *Handlers) DomainsShapingUpdate(w http.ResponseWriter, r *http.Request) {
+	serverName := r.PathValue("server")
+	domainName := r.PathValue("domain")
+
+	if err := r.ParseForm(); err != nil {
+		h.error(w, http.StatusBadRequest, "Invalid form data")
+		return
+	}
+
+	client, err := h.sendry.GetClient(serverName)
+	if err != nil {
+		h.error(w, http.StatusNotFound, "Server not found")
+		return
+	}
+
+	enabled := r.FormValue("enabled") == "on"
+	req := &sendryclient.ShapingScheduleRequest{
+		Timezone: strings.TrimSpace(r.FormValue("timezone")),
+		Hours:    make([][]int, 7),
+		Enabled:  &enabled,
+	}
+
+	for day := range req.Hours {
+		req.Hours[day] = make([]int, 24)
+		for hour := range req.Hours[day] {
+			value := strings.TrimSpace(r.FormValue(fmt.Sprintf("h_%d_%d", day, hour)))
+			if value == "" {
+				req.Hours[day][hour] = -1 // Unlimited
+				continue
+			}
+
+			limit, err := strconv.Atoi(value)
+			if err != nil || limit < 0 {
+				h.error(w, http.StatusBadRequest, fmt.Sprintf("Invalid limit for %s %02d:00: %s", time.Weekday(day), hour, value))
+				return
+			}
+			req.Hours[day][hour] = limit
+		}
+	}
+
+	if _, err := client.PutShapingSchedule(r.Context(), domainName, req); err != nil {
+		h.logger.Error("failed to save send schedule", "error", err)
+		h.error(w, http.StatusInternalServerError, fmt.Sprintf("Failed to save send schedule: %v", err))
+		return
+	}
+	h.settings.LogAction(r, middleware.GetUserID(r), middleware.GetUserEmail(r),
+		"update", "send_schedule", domainName, auditJSON(map[string]any{"server": serverName, "timezone": req.Timezone, "enabled": enabled}))
+
+	http.Redirect(w, r, "/servers/"+serverName+"/domains/"+domainName+"/shaping", http.StatusSeeOther)
+}
+
+// DomainsShapingDelete removes the send schedule of a domain
+func (h *Handlers) DomainsShapingDelete(w http.ResponseWriter, r *http.Request) {
+	serverName := r.PathValue("server")
+	domainName := r.PathValue("domain")
+
+	client, err := h.sendry.GetClient(serverName)
+	if err != nil {
+		h.error(w, http.StatusNotFound, "Server not found")
+		return
+	}
+
+	if err := client.DeleteShapingSchedule(r.Context(), domainName); err != nil {
+		h.logger.Error("failed to delete send schedule", "error", err)
+		h.error(w, http.StatusInternalServerError, fmt.Sprintf("Failed to delete send schedule: %v", err))
+		return
+	}
+	h.settings.LogAction(r, middleware.GetUserID(r), middleware.GetUserEmail(r),
+		"delete", "send_schedule", domainName, auditJSON(map[string]any{"server": serverName}))
+
+	http.Redirect(w, r, "/servers/"+serverName+"/domains/"+domainName, http.StatusSeeOther)
+}
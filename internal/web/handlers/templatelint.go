@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/foxzi/sendry/internal/web/models"
+	emailtpl "github.com/foxzi/sendry/internal/web/template"
+)
+
+// builtinVariables are set by the worker for every recipient
+var builtinVariables = []string{"email", "recipient_email", "name", "recipient_name"}
+
+// templateLint checks a template for syntax errors, undefined variables and
+// broken links. Variables are declared by the keys of the template
+// Variables, the global variables and the built-in recipient variables.
+// They are not checked if the template declares no Variables.
+func (h *Handlers) templateLint(t *models.Template) []emailtpl.LintWarning {
+	return emailtpl.Lint(t.Subject, t.HTML, t.Text, h.declaredVariables(t))
+}
+
+// declaredVariables returns the variables a template may use, nil if it
+// declares none
+func (h *Handlers) declaredVariables(t *models.Template) map[string]bool {
+	if strings.TrimSpace(t.Variables) == "" {
+		return nil
+	}
+	var vars map[string]any
+	if err := json.Unmarshal([]byte(t.Variables), &vars); err != nil || len(vars) == 0 {
+		return nil
+	}
+
+	declared := make(map[string]bool, len(vars)+len(builtinVariables))
+	for k := range vars {
+		declared[k] = true
+	}
+	for _, k := range builtinVariables {
+		declared[k] = true
+	}
+	globalVars, err := h.settings.GetGlobalVariablesMap()
+	if err != nil {
+		h.logger.Error("failed to get global variables", "error", err)
+	}
+	for k := range globalVars {
+		declared[k] = true
+	}
+	return declared
+}
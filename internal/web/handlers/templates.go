@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"regexp"
@@ -9,11 +10,12 @@ import (
 	"strings"
 	"time"
 
+	"github.com/foxzi/sendry/internal/web/drift"
 	"github.com/foxzi/sendry/internal/web/middleware"
 	"github.com/foxzi/sendry/internal/web/models"
-	"github.com/foxzi/sendry/internal/web/sendry"
 	smtpclient "github.com/foxzi/sendry/internal/web/smtp"
 	emailtpl "github.com/foxzi/sendry/internal/web/template"
+	sendryclient "github.com/foxzi/sendry/pkg/client"
 )
 
 func (h *Handlers) TemplateList(w http.ResponseWriter, r *http.Request) {
@@ -28,6 +30,7 @@ func (h *Handlers) TemplateList(w http.ResponseWriter, r *http.Request) {
 	offset := (page - 1) * limit
 
 	filter := models.TemplateListFilter{
+		OrgID:  middleware.GetOrgID(r),
 		Search: search,
 		Folder: folder,
 		Limit:  limit,
@@ -78,6 +81,8 @@ func (h *Handlers) TemplateCreate(w http.ResponseWriter, r *http.Request) {
 		Text:        r.FormValue("text"),
 		Variables:   r.FormValue("variables"),
 		Folder:      r.FormValue("folder"),
+
+		BlockExternal: r.FormValue("block_external") == "on",
 	}
 
 	// Validate required fields
@@ -86,12 +91,18 @@ func (h *Handlers) TemplateCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if violations := h.policyViolations(t); len(violations) > 0 {
+		h.renderPolicyViolations(w, r, t, "save", violations)
+		return
+	}
+
 	blockRefs := parseBlockRefs(r.FormValue("block_refs"))
 	if len(blockRefs) > 0 {
 		t.UseBlocks = true
 	}
 
 	user := h.getUserFromContext(r)
+	t.OrgID = middleware.GetOrgID(r)
 	if err := h.templates.Create(t, user["Email"].(string)); err != nil {
 		h.logger.Error("failed to create template", "error", err)
 		h.error(w, http.StatusInternalServerError, "Failed to create template")
@@ -171,13 +182,17 @@ func (h *Handlers) TemplateView(w http.ResponseWriter, r *http.Request) {
 
 	// Get available servers from config
 	servers := make([]map[string]any, 0)
-	for _, s := range h.cfg.Sendry.Servers {
+	for _, s := range h.orgServers(r) {
 		deployed := false
 		deployedVersion := 0
+		driftState := ""
+		var driftCheckedAt *time.Time
 		for _, d := range deployments {
 			if d.ServerName == s.Name {
 				deployed = true
 				deployedVersion = d.DeployedVersion
+				driftState = d.Drift
+				driftCheckedAt = d.DriftCheckedAt
 				break
 			}
 		}
@@ -187,6 +202,8 @@ func (h *Handlers) TemplateView(w http.ResponseWriter, r *http.Request) {
 			"Deployed":        deployed,
 			"DeployedVersion": deployedVersion,
 			"OutOfSync":       deployed && deployedVersion < t.CurrentVersion,
+			"Drift":           driftState,
+			"DriftCheckedAt":  driftCheckedAt,
 		})
 	}
 
@@ -199,6 +216,9 @@ func (h *Handlers) TemplateView(w http.ResponseWriter, r *http.Request) {
 		"Deployments":    deployments,
 		"Servers":        servers,
 		"VariablesShape": string(skeleton),
+		"ContentPolicy":  h.contentPolicy(t) != nil,
+		"Violations":     h.policyViolations(t),
+		"Lint":           h.templateLint(t),
 	}
 
 	h.render(w, "template_view", data)
@@ -225,6 +245,14 @@ func (h *Handlers) TemplateUpdate(w http.ResponseWriter, r *http.Request) {
 	t.Text = r.FormValue("text")
 	t.Variables = r.FormValue("variables")
 	t.Folder = r.FormValue("folder")
+	if r.Form.Has("block_external") {
+		t.BlockExternal = r.FormValue("block_external") == "on"
+	}
+
+	if violations := h.policyViolations(t); len(violations) > 0 {
+		h.renderPolicyViolations(w, r, t, "save", violations)
+		return
+	}
 
 	rawRefs := strings.TrimSpace(r.FormValue("block_refs"))
 	blockRefs := parseBlockRefs(rawRefs)
@@ -286,12 +314,24 @@ func (h *Handlers) TemplateVersions(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	deployments, err := h.templates.GetDeployments(id)
+	if err != nil {
+		h.logger.Error("failed to get deployments", "error", err)
+	}
+	// Servers each version is live on
+	liveOn := make(map[int][]string)
+	for _, d := range deployments {
+		liveOn[d.DeployedVersion] = append(liveOn[d.DeployedVersion], d.ServerName)
+	}
+
 	data := map[string]any{
 		"Title":    t.Name + " - Versions",
 		"Active":   "templates",
 		"User":     h.getUserFromContext(r),
 		"Template": t,
 		"Versions": versions,
+		"LiveOn":   liveOn,
+		"Servers":  h.orgServers(r),
 	}
 
 	h.render(w, "template_versions", data)
@@ -310,6 +350,10 @@ func (h *Handlers) TemplateDeploy(w http.ResponseWriter, r *http.Request) {
 		h.error(w, http.StatusBadRequest, "Server name is required")
 		return
 	}
+	if !orgServersAllowed(r, serverName) {
+		h.error(w, http.StatusNotFound, "Server not found")
+		return
+	}
 
 	t, err := h.templates.GetByID(id)
 	if err != nil || t == nil {
@@ -317,6 +361,21 @@ func (h *Handlers) TemplateDeploy(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Deploy the current version unless another one is selected
+	version := t.CurrentVersion
+	if v := r.FormValue("version"); v != "" {
+		version, err = strconv.Atoi(v)
+		if err != nil || version < 1 {
+			h.error(w, http.StatusBadRequest, "Invalid version")
+			return
+		}
+	}
+	if version != t.CurrentVersion {
+		if !h.loadTemplateVersion(w, t, version) {
+			return
+		}
+	}
+
 	// Get Sendry client for this server
 	client, err := h.sendry.GetClient(serverName)
 	if err != nil {
@@ -324,12 +383,32 @@ func (h *Handlers) TemplateDeploy(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	r = h.withDeployCorrelation(r)
+	h.logDeployAction(r, deployEntityTemplate, id, []string{serverName})
+
+	if violations := h.policyViolations(t); len(violations) > 0 {
+		h.recordDeploy(r, deployEntityTemplate, id, t.Name, serverName, policyError(violations))
+		h.renderPolicyViolations(w, r, t, "deploy", violations)
+		return
+	}
+
+	if _, err := h.deployTemplate(r, client, t, version, serverName); err != nil {
+		h.error(w, http.StatusInternalServerError, "Failed to deploy template: "+err.Error())
+		return
+	}
+
+	http.Redirect(w, r, "/templates/"+id, http.StatusSeeOther)
+}
+
+// deployTemplate creates or updates a version of a template on a server and
+// records the deployment
+func (h *Handlers) deployTemplate(r *http.Request, client *sendryclient.Client, t *models.Template, version int, serverName string) (*models.TemplateDeployment, error) {
 	// Check if template was already deployed to this server
-	existingDeployment, _ := h.templates.GetDeployment(id, serverName)
+	existingDeployment, _ := h.templates.GetDeployment(t.ID, serverName)
 
 	// Build template request for Sendry API
 	// Convert {{variable}} to {{.variable}} for Go templates compatibility
-	req := &sendry.TemplateCreateRequest{
+	req := &sendryclient.TemplateCreateRequest{
 		Name:        t.Name,
 		Description: t.Description,
 		Subject:     convertToGoTemplate(t.Subject),
@@ -340,45 +419,103 @@ func (h *Handlers) TemplateDeploy(w http.ResponseWriter, r *http.Request) {
 	var remoteID string
 	ctx := r.Context()
 
-	if existingDeployment != nil && existingDeployment.RemoteID != "" {
+	// A template deleted on the server is created again
+	if existingDeployment != nil && existingDeployment.RemoteID != "" && existingDeployment.Drift != models.DriftDeleted {
 		// Update existing template on Sendry
 		resp, err := client.UpdateTemplate(ctx, existingDeployment.RemoteID, req)
 		if err != nil {
-			h.logger.Error("failed to update template on Sendry", "server", serverName, "error", err)
-			h.error(w, http.StatusInternalServerError, "Failed to deploy template: "+err.Error())
-			return
+			h.recordDeploy(r, deployEntityTemplate, t.ID, t.Name, serverName, err)
+			return nil, err
 		}
 		remoteID = resp.ID
 	} else {
 		// Create new template on Sendry
 		resp, err := client.CreateTemplate(ctx, req)
 		if err != nil {
-			h.logger.Error("failed to create template on Sendry", "server", serverName, "error", err)
-			h.error(w, http.StatusInternalServerError, "Failed to deploy template: "+err.Error())
-			return
+			h.recordDeploy(r, deployEntityTemplate, t.ID, t.Name, serverName, err)
+			return nil, err
 		}
 		remoteID = resp.ID
 	}
 
 	// Save deployment record
 	deployment := &models.TemplateDeployment{
-		TemplateID:      id,
+		TemplateID:      t.ID,
 		ServerName:      serverName,
 		RemoteID:        remoteID,
-		DeployedVersion: t.CurrentVersion,
+		DeployedVersion: version,
+		ContentHash:     drift.Hash(req.Subject, req.HTML, req.Text),
 	}
 
 	if err := h.templates.SaveDeployment(deployment); err != nil {
 		h.logger.Error("failed to save deployment", "error", err)
-		h.error(w, http.StatusInternalServerError, "Failed to save deployment record")
+		return nil, errors.New("failed to save deployment record")
+	}
+	h.recordDeploy(r, deployEntityTemplate, t.ID, t.Name, serverName, nil)
+
+	h.logger.Info("template deployed", "template_id", t.ID, "server", serverName, "remote_id", remoteID,
+		"version", version, "correlation_id", sendryclient.CorrelationID(r.Context()))
+	return deployment, nil
+}
+
+// TemplateRestore creates a new version of a template with the content of
+// an old version
+func (h *Handlers) TemplateRestore(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	version, err := strconv.Atoi(r.PathValue("version"))
+	if err != nil || version < 1 {
+		h.error(w, http.StatusBadRequest, "Invalid version")
+		return
+	}
+
+	t, err := h.templates.GetByID(id)
+	if err != nil || t == nil {
+		h.error(w, http.StatusNotFound, "Template not found")
+		return
+	}
+	if !h.loadTemplateVersion(w, t, version) {
+		return
+	}
+
+	if violations := h.policyViolations(t); len(violations) > 0 {
+		h.renderPolicyViolations(w, r, t, "restore", violations)
 		return
 	}
 
-	h.logger.Info("template deployed", "template_id", id, "server", serverName, "remote_id", remoteID, "version", t.CurrentVersion)
 	user := h.getUserFromContext(r)
+	changeNote := fmt.Sprintf("Restored from v%d", version)
+	if err := h.templates.Update(t, changeNote, user["Email"].(string)); err != nil {
+		h.logger.Error("failed to restore template version", "template_id", id, "version", version, "error", err)
+		h.error(w, http.StatusInternalServerError, "Failed to restore version")
+		return
+	}
+
 	h.settings.LogAction(r, middleware.GetUserID(r), user["Email"].(string),
-		"deploy", "template", id, `{"server":"`+serverName+`"}`)
-	http.Redirect(w, r, "/templates/"+id, http.StatusSeeOther)
+		"restore", "template", id, auditJSON(map[string]any{"name": t.Name, "version": version, "new_version": t.CurrentVersion}))
+	http.Redirect(w, r, "/templates/"+id+"/versions", http.StatusSeeOther)
+}
+
+// loadTemplateVersion replaces the content of t with that of one of its
+// versions. It writes the error response and returns false if the version
+// cannot be loaded.
+func (h *Handlers) loadTemplateVersion(w http.ResponseWriter, t *models.Template, version int) bool {
+	v, err := h.templates.GetVersion(t.ID, version)
+	if err != nil {
+		h.logger.Error("failed to get version", "template_id", t.ID, "version", version, "error", err)
+		h.error(w, http.StatusInternalServerError, "Failed to load version")
+		return false
+	}
+	if v == nil {
+		h.error(w, http.StatusNotFound, "Version not found")
+		return false
+	}
+
+	t.Subject = v.Subject
+	t.HTML = v.HTML
+	t.Text = v.Text
+	t.Variables = v.Variables
+	return true
 }
 
 func (h *Handlers) TemplateDiff(w http.ResponseWriter, r *http.Request) {
@@ -494,6 +631,8 @@ type TemplateExportData struct {
 	Folder      string `json:"folder"`
 	ExportedAt  string `json:"exported_at"`
 	Version     int    `json:"version"`
+
+	BlockExternal bool `json:"block_external,omitempty"`
 }
 
 func (h *Handlers) TemplateExport(w http.ResponseWriter, r *http.Request) {
@@ -506,15 +645,16 @@ func (h *Handlers) TemplateExport(w http.ResponseWriter, r *http.Request) {
 	}
 
 	export := TemplateExportData{
-		Name:        t.Name,
-		Description: t.Description,
-		Subject:     t.Subject,
-		HTML:        t.HTML,
-		Text:        t.Text,
-		Variables:   t.Variables,
-		Folder:      t.Folder,
-		ExportedAt:  time.Now().UTC().Format(time.RFC3339),
-		Version:     t.CurrentVersion,
+		Name:          t.Name,
+		Description:   t.Description,
+		Subject:       t.Subject,
+		HTML:          t.HTML,
+		Text:          t.Text,
+		Variables:     t.Variables,
+		Folder:        t.Folder,
+		BlockExternal: t.BlockExternal,
+		ExportedAt:    time.Now().UTC().Format(time.RFC3339),
+		Version:       t.CurrentVersion,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -597,9 +737,17 @@ func (h *Handlers) TemplateImport(w http.ResponseWriter, r *http.Request) {
 		Text:        importData.Text,
 		Variables:   importData.Variables,
 		Folder:      importData.Folder,
+
+		BlockExternal: importData.BlockExternal,
+	}
+
+	if violations := h.policyViolations(t); len(violations) > 0 {
+		h.renderPolicyViolations(w, r, t, "save", violations)
+		return
 	}
 
 	user := h.getUserFromContext(r)
+	t.OrgID = middleware.GetOrgID(r)
 	if err := h.templates.Create(t, user["Email"].(string)); err != nil {
 		h.logger.Error("failed to import template", "error", err)
 		h.error(w, http.StatusInternalServerError, "Failed to import template")
@@ -623,7 +771,7 @@ func (h *Handlers) TemplateTestPage(w http.ResponseWriter, r *http.Request) {
 
 	// Get available servers from config
 	servers := make([]map[string]any, 0)
-	for _, s := range h.cfg.Sendry.Servers {
+	for _, s := range h.orgServers(r) {
 		servers = append(servers, map[string]any{
 			"Name": s.Name,
 			"Env":  s.Env,
@@ -693,6 +841,10 @@ func (h *Handlers) TemplateTest(w http.ResponseWriter, r *http.Request) {
 		h.error(w, http.StatusBadRequest, "Server and from are required for Sendry transport")
 		return
 	}
+	if !orgServersAllowed(r, serverName) {
+		h.error(w, http.StatusNotFound, "Server not found")
+		return
+	}
 	client, err := h.sendry.GetClient(serverName)
 	if err != nil {
 		h.error(w, http.StatusBadRequest, "Server not found: "+serverName)
@@ -717,7 +869,7 @@ func (h *Handlers) TemplateTest(w http.ResponseWriter, r *http.Request) {
 	text := renderTemplateVars(t.Text, globalVars)
 	html = makeAbsoluteURLs(html, h.cfg.Server.PublicURL, h.cfg.Server.PublicUploadURL)
 
-	req := &sendry.SendRequest{
+	req := &sendryclient.SendRequest{
 		From:    from,
 		To:      []string{to},
 		Subject: "[TEST] " + subject,
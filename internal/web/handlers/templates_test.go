@@ -1,6 +1,19 @@
 package handlers
 
-import "testing"
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/foxzi/sendry/internal/web/config"
+	"github.com/foxzi/sendry/internal/web/models"
+	"github.com/foxzi/sendry/internal/web/repository"
+	"github.com/foxzi/sendry/internal/web/sendry"
+	sendryclient "github.com/foxzi/sendry/pkg/client"
+)
 
 func TestConvertToGoTemplate(t *testing.T) {
 	tests := []struct {
@@ -89,3 +102,177 @@ func TestConvertToGoTemplate(t *testing.T) {
 		})
 	}
 }
+
+func TestTemplateViewLintWarnings(t *testing.T) {
+	h, database, cleanup := newDomainViewTestHandlers(t)
+	defer cleanup()
+
+	if err := repository.NewSettingsRepository(database.DB).SetVariable("company", "Acme", ""); err != nil {
+		t.Fatalf("SetVariable() error = %v", err)
+	}
+	tmpl := &models.Template{
+		Name:      "Lint",
+		Subject:   "Hi {{name}} from {{company}}",
+		HTML:      `<p>{{greeting}} {{coupon}}</p><a href="">Shop</a>`,
+		Variables: `{"greeting": "Hello"}`,
+	}
+	if err := repository.NewTemplateRepository(database.DB).Create(tmpl, ""); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/templates/"+tmpl.ID, nil)
+	req.SetPathValue("id", tmpl.ID)
+	w := httptest.NewRecorder()
+	h.TemplateView(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body: %s", w.Code, w.Body.String())
+	}
+
+	body := w.Body.String()
+	if !strings.Contains(body, "Lint Warnings (2)") {
+		t.Errorf("want 2 lint warnings, body: %s", body)
+	}
+	if !strings.Contains(body, "variable coupon is not declared in Variables") || !strings.Contains(body, "href is empty") {
+		t.Errorf("lint warnings not listed, body: %s", body)
+	}
+	for _, declared := range []string{"variable name ", "variable company ", "variable greeting "} {
+		if strings.Contains(body, declared) {
+			t.Errorf("declared %q reported as undefined", declared)
+		}
+	}
+}
+
+func TestTemplateRestoreAndDeployVersion(t *testing.T) {
+	h, database, cleanup := newDomainViewTestHandlers(t)
+	defer cleanup()
+
+	var deployed []sendryclient.TemplateCreateRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req sendryclient.TemplateCreateRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		deployed = append(deployed, req)
+		json.NewEncoder(w).Encode(map[string]any{"id": "remote-1", "name": req.Name})
+	}))
+	defer srv.Close()
+	h.cfg.Sendry.Servers = []config.SendryServer{{Name: "prod", BaseURL: srv.URL}}
+	h.sendry = sendry.NewManager(h.cfg.Sendry.Servers)
+
+	repo := repository.NewTemplateRepository(database.DB)
+	tmpl := &models.Template{Name: "Welcome", Subject: "Hello v1", HTML: "<p>v1</p>"}
+	if err := repo.Create(tmpl, ""); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	tmpl.Subject, tmpl.HTML = "Hello v2", "<p>v2</p>"
+	if err := repo.Update(tmpl, "second", ""); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	post := func(handler http.HandlerFunc, path string, form url.Values, values map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		for k, v := range values {
+			req.SetPathValue(k, v)
+		}
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+
+	// Deploy v1 while v2 is current
+	w := post(h.TemplateDeploy, "/templates/"+tmpl.ID+"/deploy", url.Values{"server": {"prod"}, "version": {"1"}}, map[string]string{"id": tmpl.ID})
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("deploy status = %d, body: %s", w.Code, w.Body.String())
+	}
+	if len(deployed) != 1 || deployed[0].Subject != "Hello v1" || deployed[0].HTML != "<p>v1</p>" {
+		t.Errorf("deployed = %+v, want v1 content", deployed)
+	}
+	d, err := repo.GetDeployment(tmpl.ID, "prod")
+	if err != nil || d == nil || d.DeployedVersion != 1 {
+		t.Fatalf("deployment = %+v, %v, want version 1", d, err)
+	}
+
+	w = post(h.TemplateDeploy, "/templates/"+tmpl.ID+"/deploy", url.Values{"server": {"prod"}, "version": {"7"}}, map[string]string{"id": tmpl.ID})
+	if w.Code != http.StatusNotFound {
+		t.Errorf("deploy of missing version status = %d, want 404", w.Code)
+	}
+
+	// Restore v1 as v3
+	w = post(h.TemplateRestore, "/templates/"+tmpl.ID+"/versions/1/restore", nil, map[string]string{"id": tmpl.ID, "version": "1"})
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("restore status = %d, body: %s", w.Code, w.Body.String())
+	}
+	got, err := repo.GetByID(tmpl.ID)
+	if err != nil || got.CurrentVersion != 3 || got.Subject != "Hello v1" || got.HTML != "<p>v1</p>" {
+		t.Fatalf("template after restore = %+v, %v", got, err)
+	}
+	v3, err := repo.GetVersion(tmpl.ID, 3)
+	if err != nil || v3 == nil || v3.ChangeNote != "Restored from v1" || v3.Subject != "Hello v1" {
+		t.Errorf("version 3 = %+v, %v", v3, err)
+	}
+
+	// Versions page shows where each version is live
+	req := httptest.NewRequest(http.MethodGet, "/templates/"+tmpl.ID+"/versions", nil)
+	req.SetPathValue("id", tmpl.ID)
+	rec := httptest.NewRecorder()
+	h.TemplateVersions(rec, req)
+	body := rec.Body.String()
+	if rec.Code != http.StatusOK || !strings.Contains(body, `<span class="badge badge-success">prod</span>`) ||
+		!strings.Contains(body, "/versions/1/restore") || strings.Contains(body, "/versions/3/restore") {
+		t.Errorf("versions page status = %d, body: %s", rec.Code, body)
+	}
+}
+
+func TestTemplateDriftResync(t *testing.T) {
+	h, database, cleanup := newDomainViewTestHandlers(t)
+	defer cleanup()
+
+	var methods []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		json.NewEncoder(w).Encode(map[string]any{"id": "remote-2"})
+	}))
+	defer srv.Close()
+	h.cfg.Sendry.Servers = []config.SendryServer{{Name: "prod", BaseURL: srv.URL}}
+	h.sendry = sendry.NewManager(h.cfg.Sendry.Servers)
+
+	repo := repository.NewTemplateRepository(database.DB)
+	tmpl := &models.Template{Name: "Welcome", Subject: "Hello", HTML: "<p>Hi</p>"}
+	if err := repo.Create(tmpl, ""); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	d := &models.TemplateDeployment{TemplateID: tmpl.ID, ServerName: "prod", RemoteID: "remote-1", DeployedVersion: 1}
+	if err := repo.SaveDeployment(d); err != nil {
+		t.Fatalf("SaveDeployment() error = %v", err)
+	}
+	d, _ = repo.GetDeployment(tmpl.ID, "prod")
+	if err := repo.SetDeploymentDrift(d.ID, models.DriftDeleted, ""); err != nil {
+		t.Fatalf("SetDeploymentDrift() error = %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/templates/"+tmpl.ID, nil)
+	req.SetPathValue("id", tmpl.ID)
+	w := httptest.NewRecorder()
+	h.TemplateView(w, req)
+	body := w.Body.String()
+	if !strings.Contains(body, "Deleted remotely") || !strings.Contains(body, ">Resync</button>") {
+		t.Errorf("template view does not show drift, body: %s", body)
+	}
+
+	// Resync of a template deleted on the server creates it again
+	form := url.Values{"server": {"prod"}, "version": {"1"}}
+	req = httptest.NewRequest(http.MethodPost, "/templates/"+tmpl.ID+"/deploy", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetPathValue("id", tmpl.ID)
+	w = httptest.NewRecorder()
+	h.TemplateDeploy(w, req)
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("resync status = %d, body: %s", w.Code, w.Body.String())
+	}
+	if len(methods) != 1 || methods[0] != http.MethodPost {
+		t.Errorf("requests = %v, want a create", methods)
+	}
+	d, _ = repo.GetDeployment(tmpl.ID, "prod")
+	if d.RemoteID != "remote-2" || d.Drift != "" || d.ContentHash == "" {
+		t.Errorf("deployment after resync = %+v", d)
+	}
+}
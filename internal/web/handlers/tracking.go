@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/foxzi/sendry/internal/web/middleware"
+	"github.com/foxzi/sendry/internal/web/models"
+	"github.com/foxzi/sendry/internal/web/tracking"
+)
+
+// TrackOpen records the open of a campaign email and returns the tracking
+// pixel. The pixel is returned for invalid links too.
+func (h *Handlers) TrackOpen(w http.ResponseWriter, r *http.Request) {
+	itemID := r.PathValue("item")
+	if h.tracker.VerifyOpen(itemID, r.PathValue("sig")) {
+		h.recordTracking(r, itemID, models.TrackingOpen, "")
+	}
+
+	w.Header().Set("Content-Type", "image/gif")
+	w.Header().Set("Cache-Control", "no-store, no-cache, must-revalidate, max-age=0")
+	w.Header().Set("Pragma", "no-cache")
+	w.Write(tracking.Pixel)
+}
+
+// TrackClick records the click of a link in a campaign email and redirects
+// to the link target
+func (h *Handlers) TrackClick(w http.ResponseWriter, r *http.Request) {
+	itemID := r.PathValue("item")
+	target := r.URL.Query().Get("u")
+	if !tracking.Trackable(target) || !h.tracker.VerifyClick(itemID, target, r.PathValue("sig")) {
+		http.NotFound(w, r)
+		return
+	}
+
+	h.recordTracking(r, itemID, models.TrackingClick, target)
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+func (h *Handlers) recordTracking(r *http.Request, itemID, eventType, target string) {
+	ok, err := h.tracking.Record(&models.TrackingEvent{
+		ItemID:    itemID,
+		Type:      eventType,
+		URL:       target,
+		IPAddress: middleware.ClientIP(r),
+		UserAgent: r.UserAgent(),
+	})
+	if err != nil {
+		h.logger.Error("failed to record tracking event", "item_id", itemID, "type", eventType, "error", err)
+	} else if !ok {
+		h.logger.Debug("tracking event for unknown item", "item_id", itemID, "type", eventType)
+	}
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/foxzi/sendry/internal/web/models"
+	"github.com/foxzi/sendry/internal/web/tracking"
+)
+
+func TestTrackingEndpoints(t *testing.T) {
+	h, database, cleanup := newDomainViewTestHandlers(t)
+	defer cleanup()
+
+	seed := []string{
+		`INSERT INTO recipient_lists (id, name, source_type) VALUES ('list', 'List', 'manual')`,
+		`INSERT INTO recipients (id, list_id, email) VALUES ('r1', 'list', 'a@example.com')`,
+		`INSERT INTO campaigns (id, name, from_email) VALUES ('camp', 'Sale', 'news@example.com')`,
+		`INSERT INTO send_jobs (id, campaign_id, recipient_list_id) VALUES ('job1', 'camp', 'list')`,
+		`INSERT INTO send_job_items (id, job_id, recipient_id, status) VALUES ('item1', 'job1', 'r1', 'sent')`,
+	}
+	for _, q := range seed {
+		if _, err := database.Exec(q); err != nil {
+			t.Fatalf("seed failed: %v", err)
+		}
+	}
+
+	call := func(handler http.HandlerFunc, rawURL string) *httptest.ResponseRecorder {
+		u, _ := url.Parse(rawURL)
+		parts := strings.Split(strings.TrimPrefix(u.Path, "/t/"), "/")
+		req := httptest.NewRequest(http.MethodGet, u.RequestURI(), nil)
+		req.SetPathValue("item", parts[1])
+		req.SetPathValue("sig", parts[2])
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+
+	target := "https://shop.example.com/sale?a=1&b=2"
+	click := h.tracker.ClickURL("item1", target)
+	w := call(h.TrackClick, click)
+	if w.Code != http.StatusFound || w.Header().Get("Location") != target {
+		t.Fatalf("TrackClick() = %d, Location %q, want redirect to %s", w.Code, w.Header().Get("Location"), target)
+	}
+
+	// A signature does not cover other targets
+	w = call(h.TrackClick, strings.Replace(click, url.QueryEscape(target), url.QueryEscape("https://evil.example.net/"), 1))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("TrackClick(forged) = %d, want 404", w.Code)
+	}
+
+	w = call(h.TrackOpen, h.tracker.OpenURL("item1"))
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/gif" || w.Body.Len() != len(tracking.Pixel) {
+		t.Errorf("TrackOpen() = %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	w = call(h.TrackOpen, "/t/o/item1/forged")
+	if w.Code != http.StatusOK {
+		t.Errorf("TrackOpen(forged) = %d, want the pixel", w.Code)
+	}
+
+	stats, err := h.tracking.JobStats("job1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Opens != 1 || stats.Clicks != 1 || len(stats.Links) != 1 || stats.Links[0].URL != target {
+		t.Errorf("JobStats() = %+v, want one open and one click", stats)
+	}
+
+	h.cfg.Tracking.Enabled = true
+	w = httptest.NewRecorder()
+	err = h.views.Render(w, "campaign_view", map[string]any{
+		"Campaign":   models.Campaign{ID: "camp", Name: "Sale"},
+		"Tracking":   true,
+		"Engagement": stats,
+	})
+	if err != nil {
+		t.Fatalf("Render(campaign_view) error = %v", err)
+	}
+	if body := w.Body.String(); !strings.Contains(body, "Engagement") || !strings.Contains(body, "100.00%") {
+		t.Errorf("campaign_view does not show engagement stats")
+	}
+}
@@ -0,0 +1,235 @@
+// Package health samples the queue, rate limit and certificate state of
+// Sendry servers for the server health dashboard.
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/foxzi/sendry/internal/web/alert"
+	"github.com/foxzi/sendry/internal/web/config"
+	"github.com/foxzi/sendry/internal/web/models"
+	"github.com/foxzi/sendry/internal/web/repository"
+	"github.com/foxzi/sendry/internal/web/sendry"
+)
+
+// requestTimeout bounds the requests of a sample to one server
+const requestTimeout = 10 * time.Second
+
+// Monitor samples the health of all configured servers
+type Monitor struct {
+	cfg     config.HealthConfig
+	samples *repository.HealthRepository
+	sendry  *sendry.Manager
+	logger  *slog.Logger
+	alerts  *alert.Engine
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New creates a health monitor
+func New(cfg config.HealthConfig, db *sql.DB, sendryMgr *sendry.Manager, logger *slog.Logger) *Monitor {
+	return &Monitor{
+		cfg:     cfg,
+		samples: repository.NewHealthRepository(db),
+		sendry:  sendryMgr,
+		logger:  logger.With("component", "health"),
+	}
+}
+
+// SetAlerts sets the alert engine that evaluates each round of samples
+func (m *Monitor) SetAlerts(e *alert.Engine) {
+	m.alerts = e
+}
+
+// Config returns the health settings
+func (m *Monitor) Config() config.HealthConfig {
+	return m.cfg
+}
+
+// Collect samples a server. Data that cannot be read is left empty; the
+// server is offline only if its health endpoint does not answer.
+func (m *Monitor) Collect(ctx context.Context, server string) *models.ServerHealth {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	now := time.Now()
+	h := &models.ServerHealth{Server: server, SampledAt: now}
+
+	client, err := m.sendry.GetClient(server)
+	if err != nil {
+		h.Error = err.Error()
+		h.Warnings = Check(h, m.cfg.Thresholds, now)
+		return h
+	}
+
+	health, err := client.Health(ctx)
+	if err != nil {
+		h.Error = err.Error()
+		h.Warnings = Check(h, m.cfg.Thresholds, now)
+		return h
+	}
+	h.Online = health.Status == "ok"
+	if health.Queue != nil {
+		h.QueuePending = health.Queue.Pending
+		h.QueueRetrying = health.Queue.Deferred
+	}
+
+	if dlq, err := client.GetDLQ(ctx); err == nil && dlq.Stats != nil {
+		h.DLQSize = dlq.Stats.Total
+	}
+
+	// Not available when rate limiting is disabled on the server
+	if stats, err := client.GetRateLimitStats(ctx, "global", "global"); err == nil {
+		h.RateLimitUsage = stats.Utilization()
+	}
+
+	if certs, err := client.ListTLSCertificates(ctx); err == nil {
+		for _, c := range certs.Certificates {
+			if c.NotAfter != nil && (h.CertExpiresAt == nil || c.NotAfter.Before(*h.CertExpiresAt)) {
+				h.CertDomain = c.Domain
+				h.CertExpiresAt = c.NotAfter
+			}
+		}
+	}
+
+	// Not available when DNSBL monitoring is disabled on the server
+	if monitored, err := client.ListMonitoredIPs(ctx); err == nil && monitored != nil {
+		for _, ip := range monitored.IPs {
+			if ip.Latest == nil {
+				continue
+			}
+			for _, l := range ip.Latest.Listings {
+				h.DNSBLListings = append(h.DNSBLListings, ip.IP+": "+l.DNSBL.Name)
+			}
+		}
+	}
+
+	metrics, err := client.Metrics(ctx)
+	if err != nil {
+		m.logger.Debug("failed to read server metrics", "server", server, "error", err)
+	}
+	h.OldestQueued = int(metrics["sendry_queue_oldest_seconds"])
+	h.SentTotal = metrics["sendry_messages_sent_total"]
+	h.FailedTotal = metrics["sendry_messages_failed_total"]
+	h.BouncedTotal = metrics["sendry_messages_bounced_total"]
+
+	h.Warnings = Check(h, m.cfg.Thresholds, now)
+	return h
+}
+
+// Check returns the warnings for the thresholds a sample exceeds
+func Check(h *models.ServerHealth, t config.HealthThresholds, now time.Time) []string {
+	if !h.Online {
+		if h.Error != "" {
+			return []string{"Server unreachable: " + h.Error}
+		}
+		return []string{"Server unreachable"}
+	}
+
+	var warnings []string
+	if queued := h.QueuePending + h.QueueRetrying; t.QueueSize > 0 && queued > t.QueueSize {
+		warnings = append(warnings, fmt.Sprintf("Queue has %d messages (threshold %d)", queued, t.QueueSize))
+	}
+	if age := time.Duration(h.OldestQueued) * time.Second; t.QueueAge > 0 && age > t.QueueAge {
+		warnings = append(warnings, fmt.Sprintf("Oldest queued message is %s old (threshold %s)", age, t.QueueAge))
+	}
+	if t.DLQSize > 0 && h.DLQSize > t.DLQSize {
+		warnings = append(warnings, fmt.Sprintf("Dead letter queue has %d messages (threshold %d)", h.DLQSize, t.DLQSize))
+	}
+	if usage := int(h.RateLimitUsage * 100); t.RateLimitUsage > 0 && usage >= t.RateLimitUsage {
+		warnings = append(warnings, fmt.Sprintf("Global rate limit %d%% used (threshold %d%%)", usage, t.RateLimitUsage))
+	}
+	if h.CertExpiresAt != nil && t.CertExpiryDays > 0 {
+		days := int(h.CertExpiresAt.Sub(now).Hours() / 24)
+		switch {
+		case days < 0:
+			warnings = append(warnings, fmt.Sprintf("TLS certificate of %s has expired", h.CertDomain))
+		case days < t.CertExpiryDays:
+			warnings = append(warnings, fmt.Sprintf("TLS certificate of %s expires in %d days", h.CertDomain, days))
+		}
+	}
+	for _, l := range h.DNSBLListings {
+		warnings = append(warnings, "IP listed on DNSBL: "+l)
+	}
+	return warnings
+}
+
+// Run samples all configured servers, evaluates the alert rules and deletes
+// samples older than the retention period
+func (m *Monitor) Run(ctx context.Context) {
+	servers := m.sendry.GetServers()
+	samples := make([]*models.ServerHealth, len(servers))
+
+	var wg sync.WaitGroup
+	for i, server := range servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			samples[i] = m.Collect(ctx, server.Name)
+		}()
+	}
+	wg.Wait()
+
+	for _, h := range samples {
+		if len(h.Warnings) > 0 {
+			m.logger.Warn("server health warning", "server", h.Server, "warnings", h.Warnings)
+		}
+		if err := m.samples.Record(h); err != nil {
+			m.logger.Error("failed to record server health", "server", h.Server, "error", err)
+		}
+	}
+
+	if m.alerts != nil {
+		m.alerts.Evaluate(ctx, samples)
+	}
+
+	if _, err := m.samples.Prune(time.Now().Add(-m.cfg.Retention)); err != nil {
+		m.logger.Error("failed to prune server health", "error", err)
+	}
+}
+
+// Start samples the servers at the configured interval if enabled
+func (m *Monitor) Start() {
+	if !m.cfg.Enabled {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+
+	m.wg.Add(1)
+	go m.loop(ctx)
+	m.logger.Info("server health monitor started", "interval", m.cfg.Interval)
+}
+
+// Stop stops the monitor and waits for a running sample
+func (m *Monitor) Stop() {
+	if m.cancel == nil {
+		return
+	}
+	m.cancel()
+	m.wg.Wait()
+}
+
+func (m *Monitor) loop(ctx context.Context) {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+
+	m.Run(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.Run(ctx)
+		}
+	}
+}
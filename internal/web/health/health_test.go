@@ -0,0 +1,49 @@
+package health
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/foxzi/sendry/internal/web/config"
+	"github.com/foxzi/sendry/internal/web/models"
+)
+
+func TestCheck(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	thresholds := config.HealthThresholds{QueueSize: 100, QueueAge: time.Hour, DLQSize: 10, RateLimitUsage: 80, CertExpiryDays: 14}
+
+	healthy := &models.ServerHealth{Online: true, QueuePending: 60, QueueRetrying: 40, DLQSize: 10, RateLimitUsage: 0.79}
+	if w := Check(healthy, thresholds, now); len(w) != 0 {
+		t.Errorf("Check() at the thresholds = %v, want none", w)
+	}
+
+	expires := now.Add(5 * 24 * time.Hour)
+	busy := &models.ServerHealth{
+		Online: true, QueuePending: 101, OldestQueued: 7200, DLQSize: 11, RateLimitUsage: 0.8,
+		CertDomain: "mail.example.com", CertExpiresAt: &expires,
+	}
+	w := Check(busy, thresholds, now)
+	if len(w) != 5 {
+		t.Fatalf("Check() = %v, want 5 warnings", w)
+	}
+	if !strings.Contains(w[4], "mail.example.com expires in 5 days") {
+		t.Errorf("certificate warning = %q", w[4])
+	}
+
+	// Negative thresholds disable the checks
+	off := config.HealthThresholds{QueueSize: -1, QueueAge: -1, DLQSize: -1, RateLimitUsage: -1, CertExpiryDays: -1}
+	if w := Check(busy, off, now); len(w) != 0 {
+		t.Errorf("Check() with checks disabled = %v", w)
+	}
+
+	listed := &models.ServerHealth{Online: true, DNSBLListings: []string{"192.0.2.1: SpamCop"}}
+	if w := Check(listed, off, now); len(w) != 1 || !strings.Contains(w[0], "192.0.2.1: SpamCop") {
+		t.Errorf("Check(listed) = %v, want the listing", w)
+	}
+
+	offline := &models.ServerHealth{Error: "connection refused", QueuePending: 1000}
+	if w := Check(offline, thresholds, now); len(w) != 1 || !strings.Contains(w[0], "unreachable") {
+		t.Errorf("Check(offline) = %v, want only unreachable", w)
+	}
+}
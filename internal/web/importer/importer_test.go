@@ -0,0 +1,141 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestParseCSV(t *testing.T) {
+	data := "\xef\xbb\xbfEmail;First Name;Tags\n\nanna@example.com;Anna;a, b\nben@example.com\n"
+	sheet, err := Parse("list.csv", []byte(data))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if want := []string{"Email", "First Name", "Tags"}; !reflect.DeepEqual(sheet.Columns, want) {
+		t.Errorf("Columns = %q, want %q", sheet.Columns, want)
+	}
+	want := [][]string{{"anna@example.com", "Anna", "a, b"}, {"ben@example.com", "", ""}}
+	if !reflect.DeepEqual(sheet.Rows, want) {
+		t.Errorf("Rows = %q, want %q", sheet.Rows, want)
+	}
+
+	if _, err := Parse("empty.csv", []byte("\n\n")); err == nil {
+		t.Error("Parse() of an empty file should fail")
+	}
+}
+
+func TestParseXLSX(t *testing.T) {
+	parts := map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"
+			xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+			<sheets><sheet name="Data" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+			<Relationship Id="rId1" Type="worksheet" Target="worksheets/sheet1.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+			<si><t>email</t></si><si><t>name</t></si><si><r><t>Anna </t></r><r><t>Schmidt</t></r></si></sst>`,
+		"xl/worksheets/sheet1.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+			<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="C1" t="inlineStr"><is><t>orders</t></is></c></row>
+			<row r="2"><c r="A2" t="str"><v>anna@example.com</v></c><c r="B2" t="s"><v>2</v></c><c r="C2"><v>12</v></c></row>
+			<row r="4"><c r="A4" t="inlineStr"><is><t>ben@example.com</t></is></c><c r="C4"><v>3</v></c></row>
+		</sheetData></worksheet>`,
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range parts {
+		f, _ := zw.Create(name)
+		f.Write([]byte(content))
+	}
+	zw.Close()
+
+	sheet, err := Parse("list.xlsx", buf.Bytes())
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if want := []string{"email", "name", "orders"}; !reflect.DeepEqual(sheet.Columns, want) {
+		t.Errorf("Columns = %q, want %q", sheet.Columns, want)
+	}
+	want := [][]string{{"anna@example.com", "Anna Schmidt", "12"}, {"ben@example.com", "", "3"}}
+	if !reflect.DeepEqual(sheet.Rows, want) {
+		t.Errorf("Rows = %q, want %q", sheet.Rows, want)
+	}
+}
+
+func TestGuessMapping(t *testing.T) {
+	got := GuessMapping([]string{"E-Mail", "Full Name", "tags", "status", "First Name", "2nd plan", "!!"})
+	want := []Column{
+		{Field: FieldEmail},
+		{Field: FieldName},
+		{Field: FieldTags},
+		{Field: FieldIgnore},
+		{Field: FieldVariable, Variable: "first_name"},
+		{Field: FieldVariable, Variable: "_2nd_plan"},
+		{Field: FieldVariable, Variable: "column_7"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GuessMapping() = %+v, want %+v", got, want)
+	}
+}
+
+func TestValidateMapping(t *testing.T) {
+	columns := []string{"a", "b"}
+	tests := []struct {
+		name    string
+		mapping []Column
+		wantErr bool
+	}{
+		{"valid", []Column{{Field: FieldEmail}, {Field: FieldVariable, Variable: "plan"}}, false},
+		{"no email", []Column{{Field: FieldName}, {Field: FieldIgnore}}, true},
+		{"two emails", []Column{{Field: FieldEmail}, {Field: FieldEmail}}, true},
+		{"invalid variable", []Column{{Field: FieldEmail}, {Field: FieldVariable, Variable: "a b"}}, true},
+		{"unknown field", []Column{{Field: FieldEmail}, {Field: "status"}}, true},
+		{"column count", []Column{{Field: FieldEmail}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateMapping(columns, tt.mapping); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateMapping() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBuild(t *testing.T) {
+	mapping := []Column{
+		{Field: FieldEmail},
+		{Field: FieldName},
+		{Field: FieldTags},
+		{Field: FieldVariable, Variable: "plan"},
+		{Field: FieldVariables},
+	}
+	rows := [][]string{
+		{"anna@example.com", "Anna", "vip; beta", "pro", `{"city": "Berlin", "plan": "free"}`},
+		{"", "Nobody", "", "", ""},
+		{"not-an-email", "", "", "", ""},
+		{"Anna <anna@example.com>", "", "", "", ""},
+		{"ANNA@example.com", "", "", "", ""},
+		{"ben@example.com", "", `["x"]`, "", ""},
+	}
+
+	got, rejected := Build(rows, mapping)
+	if len(got) != 2 {
+		t.Fatalf("Build() returned %d rows, want 2", len(got))
+	}
+	anna := got[0].Recipient
+	if anna.Name != "Anna" || anna.Tags != `["vip","beta"]` || anna.Variables != `{"city":"Berlin","plan":"pro"}` {
+		t.Errorf("row 1 = %+v", anna)
+	}
+	if got[1].Row != 6 || got[1].Recipient.Tags != `["x"]` || got[1].Recipient.Variables != "" {
+		t.Errorf("row 6 = %+v", got[1])
+	}
+
+	reasons := map[int]string{}
+	for _, r := range rejected {
+		reasons[r.Row] = r.Reason
+	}
+	want := map[int]string{2: ReasonMissingEmail, 3: ReasonInvalidEmail, 4: ReasonInvalidEmail, 5: ReasonDuplicate}
+	if !reflect.DeepEqual(reasons, want) {
+		t.Errorf("rejected = %v, want %v", reasons, want)
+	}
+}
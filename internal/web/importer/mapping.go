@@ -0,0 +1,232 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/foxzi/sendry/internal/web/models"
+)
+
+// Recipient fields a column can be mapped to
+const (
+	FieldIgnore    = ""
+	FieldEmail     = "email"
+	FieldName      = "name"
+	FieldTags      = "tags"      // Comma or semicolon separated, or a JSON array
+	FieldVariable  = "variable"  // A single variable named by Column.Variable
+	FieldVariables = "variables" // A JSON object of variables, as exported
+)
+
+// Fields lists the fields in the order shown in the UI
+var Fields = []string{FieldIgnore, FieldEmail, FieldName, FieldTags, FieldVariable, FieldVariables}
+
+// Column maps a file column to a recipient field
+type Column struct {
+	Field    string `json:"field"`
+	Variable string `json:"variable,omitempty"` // Variable key for FieldVariable
+}
+
+// Rejection reasons of rows
+const (
+	ReasonMissingEmail = "missing email"
+	ReasonInvalidEmail = "invalid email"
+	ReasonDuplicate    = "duplicate in file"
+)
+
+var (
+	variableKeyRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	nonKeyRe      = regexp.MustCompile(`[^a-z0-9_]+`)
+)
+
+// GuessMapping maps columns by their header: common email and name
+// headers, tags and variables as exported, status to nothing, and any
+// other column to a variable named after the header
+func GuessMapping(columns []string) []Column {
+	mapping := make([]Column, len(columns))
+	hasEmail, hasName := false, false
+	for i, c := range columns {
+		switch strings.ToLower(c) {
+		case "email", "e-mail", "email_address", "email address", "mail":
+			if !hasEmail {
+				mapping[i] = Column{Field: FieldEmail}
+				hasEmail = true
+				continue
+			}
+		case "name", "full_name", "fullname", "full name":
+			if !hasName {
+				mapping[i] = Column{Field: FieldName}
+				hasName = true
+				continue
+			}
+		case "tags":
+			mapping[i] = Column{Field: FieldTags}
+			continue
+		case "variables":
+			mapping[i] = Column{Field: FieldVariables}
+			continue
+		case "status":
+			continue
+		}
+		mapping[i] = Column{Field: FieldVariable, Variable: VariableKey(c, i)}
+	}
+	return mapping
+}
+
+// VariableKey derives a variable key from a column header, e.g.
+// "First Name" becomes first_name
+func VariableKey(header string, index int) string {
+	key := strings.Trim(nonKeyRe.ReplaceAllString(strings.ToLower(header), "_"), "_")
+	if key == "" {
+		return "column_" + strconv.Itoa(index+1)
+	}
+	if key[0] >= '0' && key[0] <= '9' {
+		key = "_" + key
+	}
+	return key
+}
+
+// ValidateMapping checks that one column is the email, at most one is the
+// name and variable keys are valid and unique
+func ValidateMapping(columns []string, mapping []Column) error {
+	if len(mapping) != len(columns) {
+		return fmt.Errorf("mapping has %d columns, file has %d", len(mapping), len(columns))
+	}
+
+	emails, names := 0, 0
+	keys := make(map[string]bool)
+	for i, m := range mapping {
+		switch m.Field {
+		case FieldIgnore, FieldTags, FieldVariables:
+		case FieldEmail:
+			emails++
+		case FieldName:
+			names++
+		case FieldVariable:
+			if !variableKeyRe.MatchString(m.Variable) {
+				return fmt.Errorf("column %q: invalid variable name %q", columns[i], m.Variable)
+			}
+			if keys[m.Variable] {
+				return fmt.Errorf("variable %q is mapped twice", m.Variable)
+			}
+			keys[m.Variable] = true
+		default:
+			return fmt.Errorf("column %q: unknown field %q", columns[i], m.Field)
+		}
+	}
+	if emails != 1 {
+		return fmt.Errorf("exactly one column must be mapped to email")
+	}
+	if names > 1 {
+		return fmt.Errorf("at most one column can be mapped to name")
+	}
+	return nil
+}
+
+// Row is a recipient read from a data row
+type Row struct {
+	Row       int // 1-based data row
+	Values    []string
+	Recipient models.Recipient
+}
+
+// Build maps the data rows to recipients. Rows without an email, with an
+// invalid email or repeating an email of an earlier row are rejected.
+// Emails are compared ignoring case.
+func Build(rows [][]string, mapping []Column) ([]Row, []models.RejectedRow) {
+	var result []Row
+	var rejected []models.RejectedRow
+	seen := make(map[string]bool)
+
+	for n, values := range rows {
+		row := Row{Row: n + 1, Values: values}
+		rec := &row.Recipient
+		var tags []string
+		vars := make(map[string]any)
+
+		for i, m := range mapping {
+			if i >= len(values) {
+				break
+			}
+			v := strings.TrimSpace(values[i])
+			switch m.Field {
+			case FieldEmail:
+				rec.Email = v
+			case FieldName:
+				rec.Name = v
+			case FieldTags:
+				tags = append(tags, splitTags(v)...)
+			case FieldVariable:
+				if v != "" {
+					vars[m.Variable] = v
+				}
+			case FieldVariables:
+				var obj map[string]any
+				if json.Unmarshal([]byte(v), &obj) == nil {
+					for k, val := range obj {
+						if _, ok := vars[k]; !ok {
+							vars[k] = val
+						}
+					}
+				}
+			}
+		}
+
+		reason := ""
+		key := strings.ToLower(rec.Email)
+		switch {
+		case rec.Email == "":
+			reason = ReasonMissingEmail
+		case !validEmail(rec.Email):
+			reason = ReasonInvalidEmail
+		case seen[key]:
+			reason = ReasonDuplicate
+		}
+		if reason != "" {
+			rejected = append(rejected, models.RejectedRow{Row: row.Row, Email: rec.Email, Reason: reason, Values: values})
+			continue
+		}
+		seen[key] = true
+
+		if len(vars) > 0 {
+			data, _ := json.Marshal(vars)
+			rec.Variables = string(data)
+		}
+		if len(tags) > 0 {
+			data, _ := json.Marshal(tags)
+			rec.Tags = string(data)
+		}
+		result = append(result, row)
+	}
+	return result, rejected
+}
+
+// validEmail accepts a bare address with a dot in the domain
+func validEmail(s string) bool {
+	addr, err := mail.ParseAddress(s)
+	if err != nil || addr.Address != s {
+		return false
+	}
+	domain := s[strings.LastIndex(s, "@")+1:]
+	return strings.Contains(domain, ".") && !strings.HasSuffix(domain, ".")
+}
+
+// splitTags reads a JSON array or a comma or semicolon separated list
+func splitTags(s string) []string {
+	if s == "" {
+		return nil
+	}
+	var tags []string
+	if strings.HasPrefix(s, "[") && json.Unmarshal([]byte(s), &tags) == nil {
+		return tags
+	}
+	for _, t := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ';' }) {
+		if t = strings.TrimSpace(t); t != "" {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,282 @@
+// Package importer reads recipient files and maps their columns to
+// recipient fields.
+package importer
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// MaxRows is the maximum number of data rows in an imported file
+const MaxRows = 100000
+
+// maxXLSXPart limits the uncompressed size of a part of an XLSX file
+const maxXLSXPart = 200 << 20
+
+// Sheet is the content of an imported file: the header row and data rows
+type Sheet struct {
+	Columns []string
+	Rows    [][]string
+}
+
+// Parse reads a CSV or XLSX file. XLSX files are detected by content, so
+// the file name is only used for error messages. The first row is the
+// header; empty rows are skipped.
+func Parse(name string, data []byte) (*Sheet, error) {
+	var records [][]string
+	var err error
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		records, err = readXLSX(data)
+	} else {
+		records, err = readCSV(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+
+	sheet := &Sheet{}
+	for _, record := range records {
+		if isEmpty(record) {
+			continue
+		}
+		if sheet.Columns == nil {
+			sheet.Columns = headerColumns(record)
+			continue
+		}
+		if len(sheet.Rows) == MaxRows {
+			return nil, fmt.Errorf("%s has more than %d rows", name, MaxRows)
+		}
+		sheet.Rows = append(sheet.Rows, padRow(record, len(sheet.Columns)))
+	}
+	if sheet.Columns == nil {
+		return nil, fmt.Errorf("%s is empty", name)
+	}
+	return sheet, nil
+}
+
+// readCSV reads CSV data separated by commas, semicolons or tabs, as
+// exported by spreadsheet applications in different locales
+func readCSV(data []byte) ([][]string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comma = sniffDelimiter(data)
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	return r.ReadAll()
+}
+
+// sniffDelimiter returns the delimiter used most often in the first line
+func sniffDelimiter(data []byte) rune {
+	line, _ := bufio.NewReader(bytes.NewReader(data)).ReadString('\n')
+	best, bestCount := ',', 0
+	for _, d := range []rune{',', ';', '\t'} {
+		if n := strings.Count(line, string(d)); n > bestCount {
+			best, bestCount = d, n
+		}
+	}
+	return best
+}
+
+// headerColumns trims the header names and names empty columns by position
+func headerColumns(record []string) []string {
+	columns := make([]string, len(record))
+	for i, c := range record {
+		columns[i] = strings.TrimSpace(c)
+		if columns[i] == "" {
+			columns[i] = "column_" + strconv.Itoa(i+1)
+		}
+	}
+	return columns
+}
+
+func padRow(record []string, n int) []string {
+	row := make([]string, n)
+	copy(row, record)
+	return row
+}
+
+func isEmpty(record []string) bool {
+	for _, v := range record {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// XLSX parts, see ECMA-376 part 1
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		RID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxText `xml:"si"`
+}
+
+// xlsxText is a string item, plain (t) or with formatting runs (r/t)
+type xlsxText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.T
+	}
+	var b strings.Builder
+	for _, r := range t.Runs {
+		b.WriteString(r.T)
+	}
+	return b.String()
+}
+
+type xlsxSheet struct {
+	Rows []struct {
+		Cells []struct {
+			Ref    string   `xml:"r,attr"`
+			Type   string   `xml:"t,attr"`
+			Value  string   `xml:"v"`
+			Inline xlsxText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// readXLSX reads the cell values of the first worksheet of an XLSX file.
+// Formula cells give their cached value; dates are kept as serial numbers.
+func readXLSX(data []byte) ([][]string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid XLSX file: %w", err)
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	var workbook xlsxWorkbook
+	if err := decodePart(files, "xl/workbook.xml", &workbook); err != nil {
+		return nil, err
+	}
+	if len(workbook.Sheets) == 0 {
+		return nil, fmt.Errorf("XLSX file has no worksheets")
+	}
+	var rels xlsxRelationships
+	if err := decodePart(files, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+	sheetPath := ""
+	for _, rel := range rels.Relationships {
+		if rel.ID == workbook.Sheets[0].RID {
+			sheetPath = rel.Target
+		}
+	}
+	if sheetPath == "" {
+		return nil, fmt.Errorf("XLSX worksheet not found")
+	}
+	if strings.HasPrefix(sheetPath, "/") {
+		sheetPath = strings.TrimPrefix(sheetPath, "/")
+	} else {
+		sheetPath = path.Join("xl", sheetPath)
+	}
+
+	var shared xlsxSharedStrings
+	if _, ok := files["xl/sharedStrings.xml"]; ok {
+		if err := decodePart(files, "xl/sharedStrings.xml", &shared); err != nil {
+			return nil, err
+		}
+	}
+
+	var sheet xlsxSheet
+	if err := decodePart(files, sheetPath, &sheet); err != nil {
+		return nil, err
+	}
+
+	records := make([][]string, 0, len(sheet.Rows))
+	for _, row := range sheet.Rows {
+		var record []string
+		for i, c := range row.Cells {
+			col := i
+			if c.Ref != "" {
+				col = columnIndex(c.Ref)
+			}
+			if col < 0 || col > 16383 {
+				return nil, fmt.Errorf("invalid cell reference %q", c.Ref)
+			}
+			for len(record) <= col {
+				record = append(record, "")
+			}
+
+			switch c.Type {
+			case "s":
+				n, err := strconv.Atoi(c.Value)
+				if err != nil || n < 0 || n >= len(shared.Items) {
+					return nil, fmt.Errorf("invalid shared string in cell %s", c.Ref)
+				}
+				record[col] = shared.Items[n].String()
+			case "inlineStr":
+				record[col] = c.Inline.String()
+			case "b":
+				record[col] = map[string]string{"1": "TRUE", "0": "FALSE"}[c.Value]
+			default:
+				record[col] = c.Value
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func decodePart(files map[string]*zip.File, name string, v any) error {
+	f, ok := files[name]
+	if !ok {
+		return fmt.Errorf("XLSX part %s not found", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	if err := xml.NewDecoder(io.LimitReader(rc, maxXLSXPart)).Decode(v); err != nil {
+		return fmt.Errorf("invalid XLSX part %s: %w", name, err)
+	}
+	return nil
+}
+
+// columnIndex returns the zero-based column of a cell reference like "AB12"
+func columnIndex(ref string) int {
+	col := 0
+	n := 0
+	for _, ch := range ref {
+		if ch < 'A' || ch > 'Z' {
+			break
+		}
+		col = col*26 + int(ch-'A'+1)
+		n++
+	}
+	if n == 0 {
+		return -1
+	}
+	return col - 1
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/foxzi/sendry/internal/web/db"
 	"github.com/foxzi/sendry/internal/web/models"
 	"github.com/foxzi/sendry/internal/web/repository"
+	sendryclient "github.com/foxzi/sendry/pkg/client"
 )
 
 // RateLimiter provides in-memory rate limiting
@@ -106,6 +107,7 @@ const ctxKeyAPIKey ctxKey = "api_key"
 const ctxKeyUserEmail ctxKey = "user_email"
 const ctxKeyUserID ctxKey = "user_id"
 const ctxKeyUserRole ctxKey = "user_role"
+const ctxKeySessionID ctxKey = "session_id"
 
 // GetUserEmail returns the authenticated user's email from request context
 func GetUserEmail(r *http.Request) string {
@@ -131,6 +133,21 @@ func GetUserRole(r *http.Request) string {
 	return ""
 }
 
+// GetSessionID returns the ID of the authenticated user's session from
+// request context
+func GetSessionID(r *http.Request) string {
+	if id, ok := r.Context().Value(ctxKeySessionID).(string); ok {
+		return id
+	}
+	return ""
+}
+
+// WithUserEmail returns a context carrying email as the authenticated user,
+// for actions run outside an HTTP request such as the command line
+func WithUserEmail(ctx context.Context, email string) context.Context {
+	return context.WithValue(ctx, ctxKeyUserEmail, email)
+}
+
 // IsAdmin returns true if the authenticated user has admin role
 func IsAdmin(r *http.Request) bool {
 	return GetUserRole(r) == "admin"
@@ -204,10 +221,21 @@ func Auth(cfg *config.Config, database *db.DB, logger *slog.Logger) func(http.Ha
 				return
 			}
 
+			// Record activity for the session list, at most once a minute
+			if _, err := database.Exec(
+				"UPDATE sessions SET last_seen_at = CURRENT_TIMESTAMP WHERE id = ? AND (last_seen_at IS NULL OR last_seen_at < datetime('now', '-1 minute'))",
+				cookie.Value,
+			); err != nil {
+				logger.Warn("failed to update session last seen", "error", err)
+			}
+
 			// Session valid, add user info to context
 			ctx := context.WithValue(r.Context(), ctxKeyUserEmail, email)
 			ctx = context.WithValue(ctx, ctxKeyUserID, userID)
 			ctx = context.WithValue(ctx, ctxKeyUserRole, role)
+			ctx = context.WithValue(ctx, ctxKeySessionID, cookie.Value)
+			// Name the user in the audit log of servers it changes
+			ctx = sendryclient.WithActor(ctx, email)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
@@ -248,6 +276,12 @@ func (w *responseWriter) WriteHeader(status int) {
 	w.ResponseWriter.WriteHeader(status)
 }
 
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to
+// flush event streams
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
 // APIAuth middleware authenticates API requests using API keys
 func APIAuth(apiKeys *repository.APIKeyRepository, logger *slog.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -288,7 +322,7 @@ func APIAuth(apiKeys *repository.APIKeyRepository, logger *slog.Logger) func(htt
 			}
 
 			// Check expiration
-			if apiKey.ExpiresAt != nil && time.Now().After(*apiKey.ExpiresAt) {
+			if apiKey.IsExpired() {
 				sendAPIError(w, http.StatusUnauthorized, "API key expired")
 				return
 			}
@@ -310,11 +344,32 @@ func APIAuth(apiKeys *repository.APIKeyRepository, logger *slog.Logger) func(htt
 
 			// Add to context
 			ctx := context.WithValue(r.Context(), ctxKeyAPIKey, apiKey)
+			// A personal token acts as its owner
+			if apiKey.UserID != "" {
+				ctx = context.WithValue(ctx, ctxKeyUserID, apiKey.UserID)
+				ctx = context.WithValue(ctx, ctxKeyUserEmail, apiKey.UserEmail)
+				ctx = sendryclient.WithActor(ctx, apiKey.UserEmail)
+			}
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
+// RequireAPIPermission allows requests whose API key has any of the given
+// permissions
+func RequireAPIPermission(permissions ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			apiKey := GetAPIKeyFromContext(r)
+			if apiKey == nil || !apiKey.HasPermission(permissions...) {
+				sendAPIError(w, http.StatusForbidden, "API key lacks the "+strings.Join(permissions, " or ")+" permission")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 // GetAPIKeyFromContext returns the API key from request context
 func GetAPIKeyFromContext(r *http.Request) *models.APIKey {
 	if key, ok := r.Context().Value(ctxKeyAPIKey).(*models.APIKey); ok {
@@ -392,6 +447,11 @@ func IPFilter(allowedIPs []string, logger *slog.Logger) func(http.Handler) http.
 	}
 }
 
+// ClientIP returns the client IP of a request, handling proxies
+func ClientIP(r *http.Request) string {
+	return getClientIP(r)
+}
+
 // getClientIP extracts client IP from request, handling proxies
 func getClientIP(r *http.Request) string {
 	// Check X-Forwarded-For header (first IP in chain)
@@ -5,6 +5,8 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+
+	"github.com/foxzi/sendry/internal/web/models"
 )
 
 func TestGetUserEmail(t *testing.T) {
@@ -51,6 +53,35 @@ func TestGetAPIKeyFromContext_Nil(t *testing.T) {
 	}
 }
 
+func TestRequireAPIPermission(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	handler := RequireAPIPermission(models.PermissionSend, models.PermissionCampaign)(next)
+
+	tests := []struct {
+		name string
+		key  *models.APIKey
+		want int
+	}{
+		{"no key", nil, http.StatusForbidden},
+		{"granted", &models.APIKey{Permissions: `["campaign"]`}, http.StatusNoContent},
+		{"missing", &models.APIKey{Permissions: `["template","dkim"]`}, http.StatusForbidden},
+		{"invalid", &models.APIKey{Permissions: `send`}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/api/v1/jobs/1", nil)
+		if tt.key != nil {
+			r = r.WithContext(context.WithValue(r.Context(), ctxKeyAPIKey, tt.key))
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.want)
+		}
+	}
+}
+
 func TestMethodOverride(t *testing.T) {
 	tests := []struct {
 		name       string
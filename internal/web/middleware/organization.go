@@ -0,0 +1,188 @@
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/foxzi/sendry/internal/web/db"
+	"github.com/foxzi/sendry/internal/web/models"
+)
+
+const ctxKeyOrgID ctxKey = "org_id"
+const ctxKeyOrgRole ctxKey = "org_role"
+const ctxKeyOrgServers ctxKey = "org_servers"
+
+// GetOrgID returns the ID of the current organization from request context
+func GetOrgID(r *http.Request) string {
+	if id, ok := r.Context().Value(ctxKeyOrgID).(string); ok {
+		return id
+	}
+	return ""
+}
+
+// GetOrgRole returns the role of the user in the current organization from
+// request context
+func GetOrgRole(r *http.Request) models.OrgRole {
+	if role, ok := r.Context().Value(ctxKeyOrgRole).(models.OrgRole); ok {
+		return role
+	}
+	return ""
+}
+
+// ServerAllowed returns true if the current organization may use the Sendry
+// server
+func ServerAllowed(r *http.Request, name string) bool {
+	servers, _ := r.Context().Value(ctxKeyOrgServers).([]string)
+	return len(servers) == 0 || slices.Contains(servers, name)
+}
+
+// orgResources are the URL path prefixes followed by the ID of an object
+// that belongs to an organization, with the query returning its
+// organization
+var orgResources = []struct {
+	prefix string
+	query  string
+}{
+	{"/templates/", "SELECT COALESCE(org_id, '') FROM templates WHERE id = ?"},
+	{"/campaigns/", "SELECT COALESCE(org_id, '') FROM campaigns WHERE id = ?"},
+	{"/recipients/", "SELECT COALESCE(org_id, '') FROM recipient_lists WHERE id = ?"},
+	{"/dkim/", "SELECT COALESCE(org_id, '') FROM dkim_keys WHERE id = ?"},
+	{"/domains/", "SELECT COALESCE(org_id, '') FROM domains WHERE id = ?"},
+	{"/jobs/", "SELECT COALESCE(c.org_id, '') FROM send_jobs j JOIN campaigns c ON c.id = j.campaign_id WHERE j.id = ?"},
+	{"/sends/", "SELECT COALESCE(k.org_id, '') FROM sends s LEFT JOIN api_keys k ON k.id = s.api_key_id WHERE s.id = ?"},
+	{"/api/v1/templates/", "SELECT COALESCE(org_id, '') FROM templates WHERE id = ?"},
+	{"/api/v1/campaigns/", "SELECT COALESCE(org_id, '') FROM campaigns WHERE id = ?"},
+	{"/api/v1/lists/", "SELECT COALESCE(org_id, '') FROM recipient_lists WHERE id = ?"},
+	{"/api/v1/dkim/", "SELECT COALESCE(org_id, '') FROM dkim_keys WHERE id = ?"},
+	{"/api/v1/domains/", "SELECT COALESCE(org_id, '') FROM domains WHERE id = ?"},
+	{"/api/v1/jobs/", "SELECT COALESCE(c.org_id, '') FROM send_jobs j JOIN campaigns c ON c.id = j.campaign_id WHERE j.id = ?"},
+	{"/api/v1/send/", "SELECT COALESCE(k.org_id, '') FROM sends s LEFT JOIN api_keys k ON k.id = s.api_key_id WHERE s.id = ?"},
+}
+
+// pathSegment returns the path segment that follows the prefix
+func pathSegment(path, prefix string) string {
+	rest, ok := strings.CutPrefix(path, prefix)
+	if !ok {
+		return ""
+	}
+	segment, _, _ := strings.Cut(rest, "/")
+	return segment
+}
+
+// Organization middleware resolves the current organization of a request
+// signed in by Auth or APIAuth and keeps it to the objects and Sendry
+// servers of that organization. Users choose their organization for the
+// session; API keys act in the organization they were created in.
+func Organization(database *db.DB, logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			api := strings.HasPrefix(r.URL.Path, "/api/")
+			deny := func(status int, message string) {
+				if api {
+					sendAPIError(w, status, message)
+					return
+				}
+				http.Error(w, message, status)
+			}
+
+			var orgID, servers string
+			var role models.OrgRole
+			var err error
+			if key := GetAPIKeyFromContext(r); key != nil {
+				orgID, role = key.OrgID, models.OrgRoleMember
+				err = database.QueryRow("SELECT servers FROM organizations WHERE id = ?", orgID).Scan(&servers)
+			} else {
+				var memberRole string
+				err = database.QueryRow(`
+					SELECT o.id, o.servers, COALESCE(m.role, '')
+					FROM organizations o
+					LEFT JOIN organization_members m ON m.org_id = o.id AND m.user_id = ?
+					WHERE m.user_id IS NOT NULL OR ? = 'admin'
+					ORDER BY o.id = COALESCE((SELECT org_id FROM sessions WHERE id = ?), '') DESC,
+					         m.user_id IS NULL, o.name
+					LIMIT 1`,
+					GetUserID(r), GetUserRole(r), GetSessionID(r),
+				).Scan(&orgID, &servers, &memberRole)
+				role = models.OrgRole(memberRole)
+				if IsAdmin(r) {
+					role = models.OrgRoleAdmin
+				}
+			}
+
+			switch {
+			case err == sql.ErrNoRows && !api && IsAdmin(r):
+				// Admins work without an organization until one exists
+			case err == sql.ErrNoRows:
+				if !api && strings.HasPrefix(r.URL.Path, "/profile") {
+					break
+				}
+				deny(http.StatusForbidden, "Not a member of any organization")
+				return
+			case err != nil:
+				logger.Error("failed to resolve organization", "error", err)
+				deny(http.StatusInternalServerError, "Internal Server Error")
+				return
+			}
+
+			// An unreadable server list must not lift the restriction
+			var allowed []string
+			if servers != "" {
+				if err := json.Unmarshal([]byte(servers), &allowed); err != nil {
+					logger.Error("invalid organization servers", "org_id", orgID, "error", err)
+					deny(http.StatusInternalServerError, "Internal Server Error")
+					return
+				}
+			}
+			ctx := context.WithValue(r.Context(), ctxKeyOrgID, orgID)
+			ctx = context.WithValue(ctx, ctxKeyOrgRole, role)
+			ctx = context.WithValue(ctx, ctxKeyOrgServers, allowed)
+			r = r.WithContext(ctx)
+
+			// Servers outside the organization do not exist for it
+			path := r.URL.Path
+			if name := pathSegment(path, "/servers/"); name != "" {
+				if !ServerAllowed(r, name) {
+					deny(http.StatusNotFound, "Not Found")
+					return
+				}
+				path = strings.TrimPrefix(path, "/servers/"+name)
+			}
+
+			// Nor do objects of other organizations
+			if orgID != "" {
+				for _, res := range orgResources {
+					id := pathSegment(path, res.prefix)
+					if id == "" {
+						continue
+					}
+					var owner string
+					err := database.QueryRow(res.query, id).Scan(&owner)
+					if err == nil && owner != "" && owner != orgID {
+						deny(http.StatusNotFound, "Not Found")
+						return
+					}
+					if err != nil && err != sql.ErrNoRows {
+						logger.Error("failed to check object organization", "path", r.URL.Path, "error", err)
+						deny(http.StatusInternalServerError, "Internal Server Error")
+						return
+					}
+					break
+				}
+			}
+
+			// Viewers only read
+			if role == models.OrgRoleViewer && r.Method != http.MethodGet && r.Method != http.MethodHead &&
+				!strings.HasPrefix(r.URL.Path, "/profile") && r.URL.Path != "/organizations/switch" {
+				deny(http.StatusForbidden, "Read-only access")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
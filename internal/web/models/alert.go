@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// Alert states
+const (
+	AlertFiring   = "firing"
+	AlertResolved = "resolved"
+)
+
+// Alert is a rule condition met on a server, from the first sample that
+// met it until the first sample that did not
+type Alert struct {
+	ID         string     `json:"id"`
+	Rule       string     `json:"rule"`
+	Type       string     `json:"type"`
+	Server     string     `json:"server"`
+	Message    string     `json:"message"`
+	State      string     `json:"state"`
+	StartedAt  time.Time  `json:"started_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	NotifiedAt *time.Time `json:"notified_at,omitempty"` // Last notification sent
+}
+
+// AlertSilence suppresses the notifications of matching alerts for a period
+type AlertSilence struct {
+	ID        string    `json:"id"`
+	Rule      string    `json:"rule"`   // Empty for all rules
+	Server    string    `json:"server"` // Empty for all servers
+	Reason    string    `json:"reason"`
+	StartsAt  time.Time `json:"starts_at"`
+	EndsAt    time.Time `json:"ends_at"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ActiveAt returns true if the silence covers the given time
+func (s *AlertSilence) ActiveAt(t time.Time) bool {
+	return !t.Before(s.StartsAt) && t.Before(s.EndsAt)
+}
+
+// Matches returns true if the silence applies to the rule and server
+func (s *AlertSilence) Matches(rule, server string) bool {
+	return (s.Rule == "" || s.Rule == rule) && (s.Server == "" || s.Server == server)
+}
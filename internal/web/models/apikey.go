@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"slices"
+	"time"
+)
 
 // APIKey represents an API key for authentication
 type APIKey struct {
@@ -13,12 +17,20 @@ type APIKey struct {
 	RateLimitMinute int        `json:"rate_limit_minute"`       // Max requests per minute (0 = unlimited)
 	RateLimitHour   int        `json:"rate_limit_hour"`         // Max requests per hour (0 = unlimited)
 	CreatedBy       string     `json:"created_by,omitempty"`
+	UserID          string     `json:"user_id,omitempty"`       // Owner of a personal token, empty for API keys
+	UserEmail       string     `json:"user_email,omitempty"`    // joined field
+	OrgID           string     `json:"org_id,omitempty"`        // Organization the key acts in
 	CreatedAt       time.Time  `json:"created_at"`
 	LastUsedAt      *time.Time `json:"last_used_at,omitempty"`
 	ExpiresAt       *time.Time `json:"expires_at,omitempty"`
 	Active          bool       `json:"active"`
 }
 
+// IsExpired returns true if the API key has an expiry in the past
+func (k *APIKey) IsExpired() bool {
+	return k.ExpiresAt != nil && time.Now().After(*k.ExpiresAt)
+}
+
 // CanSendFromDomain checks if the API key is allowed to send from the given domain
 func (k *APIKey) CanSendFromDomain(domain string) bool {
 	if len(k.AllowedDomains) == 0 {
@@ -32,16 +44,53 @@ func (k *APIKey) CanSendFromDomain(domain string) bool {
 	return false
 }
 
+// HasPermission checks if the API key has any of the given permissions
+func (k *APIKey) HasPermission(permissions ...string) bool {
+	granted := k.PermissionList()
+	for _, p := range permissions {
+		if slices.Contains(granted, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// PermissionList returns the permissions of the API key
+func (k *APIKey) PermissionList() []string {
+	var permissions []string
+	if err := json.Unmarshal([]byte(k.Permissions), &permissions); err != nil {
+		return nil
+	}
+	return permissions
+}
+
 // APIKeyPermission constants
 const (
-	PermissionSend     = "send"
-	PermissionTemplate = "template"
-	PermissionStatus   = "status"
+	PermissionSend       = "send"       // Send mail and read send status
+	PermissionTemplate   = "template"   // Manage and deploy templates
+	PermissionStatus     = "status"     // Read send and job status
+	PermissionCampaign   = "campaign"   // Manage campaigns and their send jobs
+	PermissionRecipients = "recipients" // Manage recipient lists
+	PermissionDomain     = "domain"     // Manage and deploy domains
+	PermissionDKIM       = "dkim"       // Manage and deploy DKIM keys
 )
 
+// APIKeyPermissions are the permissions that can be granted to an API key
+var APIKeyPermissions = []string{
+	PermissionSend,
+	PermissionTemplate,
+	PermissionStatus,
+	PermissionCampaign,
+	PermissionRecipients,
+	PermissionDomain,
+	PermissionDKIM,
+}
+
 // APIKeyFilter for listing API keys
 type APIKeyFilter struct {
 	Active bool
+	UserID string // Personal tokens of the user; API keys without an owner if empty
+	OrgID  string // Keys of the organization; all if empty
 	Search string
 	Limit  int
 	Offset int
@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 // Campaign represents an email campaign
 type Campaign struct {
@@ -12,10 +15,19 @@ type Campaign struct {
 	ReplyTo     string    `json:"reply_to"`
 	Variables   string    `json:"variables"` // JSON
 	Tags        string    `json:"tags"`      // JSON array
+	OrgID       string    `json:"org_id,omitempty"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
 
+// SenderDomain returns the domain of the campaign sender address
+func (c *Campaign) SenderDomain() string {
+	if i := strings.LastIndex(c.FromEmail, "@"); i >= 0 {
+		return strings.ToLower(strings.TrimSuffix(c.FromEmail[i+1:], ">"))
+	}
+	return ""
+}
+
 // CampaignVariant represents a template variant for A/B testing
 type CampaignVariant struct {
 	ID              string    `json:"id"`
@@ -37,6 +49,7 @@ type CampaignWithStats struct {
 
 // CampaignListFilter for filtering campaigns
 type CampaignListFilter struct {
+	OrgID  string // Campaigns of the organization; all if empty
 	Search string
 	Limit  int
 	Offset int
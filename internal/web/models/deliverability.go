@@ -0,0 +1,49 @@
+package models
+
+// DeliverabilityReport rolls up the delivery outcome of the job items of a
+// campaign as reported by the Sendry servers
+type DeliverabilityReport struct {
+	Total     int `json:"total"`
+	Queued    int `json:"queued"`    // Waiting for a final outcome on the server
+	Delivered int `json:"delivered"` // Items with status sent
+	Bounced   int `json:"bounced"`
+	Failed    int `json:"failed"`
+
+	Reasons []BounceReason         `json:"reasons"` // Most frequent first
+	Domains []DomainDeliverability `json:"domains"` // Recipient domains, most emails first
+}
+
+// BounceReason counts the bounced and failed items with the same error
+type BounceReason struct {
+	Error string `json:"error"`
+	Count int    `json:"count"`
+}
+
+// DomainDeliverability holds the outcome of the items of a recipient domain
+type DomainDeliverability struct {
+	Domain    string `json:"domain"`
+	Total     int    `json:"total"`
+	Delivered int    `json:"delivered"`
+	Bounced   int    `json:"bounced"`
+	Failed    int    `json:"failed"`
+}
+
+// Completed returns the number of items with a final outcome
+func (r DeliverabilityReport) Completed() int {
+	return r.Delivered + r.Bounced + r.Failed
+}
+
+// DeliveryRate returns the share of completed items that were delivered
+func (r DeliverabilityReport) DeliveryRate() float64 {
+	return rate(r.Delivered, r.Completed())
+}
+
+// BounceRate returns the share of completed items that bounced
+func (r DeliverabilityReport) BounceRate() float64 {
+	return rate(r.Bounced, r.Completed())
+}
+
+// BounceRate returns the share of the domain's completed items that bounced
+func (d DomainDeliverability) BounceRate() float64 {
+	return rate(d.Bounced, d.Delivered+d.Bounced+d.Failed)
+}
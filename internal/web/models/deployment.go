@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// DeploymentEvent records the outcome of deploying an entity to one server.
+// Events of a single deploy action share a correlation ID, which is also
+// sent to the servers and stored in their audit logs.
+type DeploymentEvent struct {
+	ID            int64     `json:"id"`
+	CorrelationID string    `json:"correlation_id"`
+	EntityType    string    `json:"entity_type"` // domain, dkim, template
+	EntityID      string    `json:"entity_id"`
+	EntityName    string    `json:"entity_name"`
+	ServerName    string    `json:"server_name"`
+	Status        string    `json:"status"` // deployed, failed
+	Error         string    `json:"error,omitempty"`
+	UserEmail     string    `json:"user_email"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// DeploymentRun summarizes all events of one deploy action
+type DeploymentRun struct {
+	CorrelationID string    `json:"correlation_id"`
+	EntityType    string    `json:"entity_type"`
+	EntityID      string    `json:"entity_id"`
+	EntityName    string    `json:"entity_name"`
+	UserEmail     string    `json:"user_email"`
+	StartedAt     time.Time `json:"started_at"`
+	Servers       int       `json:"servers"`
+	Failed        int       `json:"failed"`
+}
+
+// DeploymentFilter for filtering deployment history
+type DeploymentFilter struct {
+	EntityType string
+	EntityID   string
+	Limit      int
+	Offset     int
+}
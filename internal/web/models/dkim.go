@@ -9,6 +9,7 @@ type DKIMKey struct {
 	Selector   string    `json:"selector"`
 	PrivateKey string    `json:"private_key,omitempty"` // Hidden in list views
 	DNSRecord  string    `json:"dns_record"`
+	OrgID      string    `json:"org_id,omitempty"`
 	CreatedAt  time.Time `json:"created_at"`
 	UpdatedAt  time.Time `json:"updated_at"`
 
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// DNS record publishing states
+const (
+	DNSRecordPublished  = "published"  // Written to the DNS provider, not yet visible
+	DNSRecordPropagated = "propagated" // Visible to the resolver with the expected value
+	DNSRecordFailed     = "failed"     // Publishing failed or not visible within the timeout
+)
+
+// DNSRecord is a DNS record of a domain published through a DNS provider
+type DNSRecord struct {
+	ID          int64      `json:"id"`
+	DomainID    string     `json:"domain_id"`
+	Kind        string     `json:"kind"` // SPF, DKIM, DMARC, MX, MTA-STS
+	Type        string     `json:"type"` // DNS record type, e.g. TXT
+	Name        string     `json:"name"`
+	Value       string     `json:"value"`
+	Provider    string     `json:"provider"`
+	Status      string     `json:"status"`
+	Error       string     `json:"error,omitempty"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+	CheckedAt   *time.Time `json:"checked_at,omitempty"`  // Last propagation check
+	VerifiedAt  *time.Time `json:"verified_at,omitempty"` // Propagation confirmed
+}
+
+// DNSRecordsReady returns true if there are records and all of them propagated
+func DNSRecordsReady(records []DNSRecord) bool {
+	if len(records) == 0 {
+		return false
+	}
+	for _, r := range records {
+		if r.Status != DNSRecordPropagated {
+			return false
+		}
+	}
+	return true
+}
@@ -21,6 +21,7 @@ type Domain struct {
 	RateLimitRecipients int       `json:"rate_limit_recipients"`
 	RedirectTo          []string  `json:"redirect_to,omitempty"`
 	BCCTo               []string  `json:"bcc_to,omitempty"`
+	OrgID               string    `json:"org_id,omitempty"`
 	CreatedAt           time.Time `json:"created_at"`
 	UpdatedAt           time.Time `json:"updated_at"`
 
@@ -55,6 +56,7 @@ type DomainListItem struct {
 
 // DomainFilter for listing domains
 type DomainFilter struct {
+	OrgID  string // Domains of the organization; all if empty
 	Search string
 	Mode   string
 	Limit  int
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// FreezeWindow is a period during which campaign sending is frozen, for
+// all domains or for one sender domain
+type FreezeWindow struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Domain    string    `json:"domain"` // empty for organization-wide
+	Reason    string    `json:"reason"`
+	StartsAt  time.Time `json:"starts_at"`
+	EndsAt    time.Time `json:"ends_at"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ActiveAt returns true if the window covers the given time
+func (f *FreezeWindow) ActiveAt(t time.Time) bool {
+	return !t.Before(f.StartsAt) && t.Before(f.EndsAt)
+}
+
+// Covers returns true if the window applies to the sender domain
+func (f *FreezeWindow) Covers(domain string) bool {
+	return f.Domain == "" || strings.EqualFold(f.Domain, domain)
+}
+
+// Scope returns the domain of the window or "all domains"
+func (f *FreezeWindow) Scope() string {
+	if f.Domain == "" {
+		return "all domains"
+	}
+	return f.Domain
+}
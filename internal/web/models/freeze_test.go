@@ -0,0 +1,34 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFreezeWindow(t *testing.T) {
+	start := time.Date(2026, 11, 27, 0, 0, 0, 0, time.UTC)
+	f := &FreezeWindow{Domain: "example.com", StartsAt: start, EndsAt: start.Add(72 * time.Hour)}
+
+	if !f.ActiveAt(start) || f.ActiveAt(f.EndsAt) || f.ActiveAt(start.Add(-time.Second)) {
+		t.Error("ActiveAt() should include the start and exclude the end")
+	}
+	if !f.Covers("Example.com") || f.Covers("example.org") {
+		t.Error("Covers() should match only the window domain")
+	}
+	if org := (&FreezeWindow{}); !org.Covers("example.org") || org.Scope() != "all domains" {
+		t.Error("window without domain should cover all domains")
+	}
+}
+
+func TestCampaignSenderDomain(t *testing.T) {
+	for from, want := range map[string]string{
+		"news@Example.com":   "example.com",
+		"<news@example.org>": "example.org",
+		"invalid":            "",
+	} {
+		c := &Campaign{FromEmail: from}
+		if got := c.SenderDomain(); got != want {
+			t.Errorf("SenderDomain(%q) = %q, want %q", from, got, want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package models
+
+import "time"
+
+// RecipientHistory is everything sent to one email address through
+// campaigns and the send API, with its list memberships and suppressions
+type RecipientHistory struct {
+	Email        string               `json:"email"`
+	Lists        []HistoryMembership  `json:"lists"`
+	Messages     []HistoryMessage     `json:"messages"` // Newest first
+	Suppressions []HistorySuppression `json:"suppressions"`
+	Timeline     []HistoryEvent       `json:"timeline"` // Newest first
+}
+
+// HistoryMembership is a recipient list containing the address
+type HistoryMembership struct {
+	ListID    string    `json:"list_id"`
+	ListName  string    `json:"list_name"`
+	Status    string    `json:"status"` // active, unsubscribed, bounced
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// History message sources
+const (
+	HistorySourceCampaign = "campaign"
+	HistorySourceAPI      = "api"
+)
+
+// HistoryMessage is a campaign job item or an API send to the address
+type HistoryMessage struct {
+	Source       string     `json:"source"` // campaign or api
+	ID           string     `json:"id"`     // Job item or send ID
+	JobID        string     `json:"job_id,omitempty"`
+	CampaignID   string     `json:"campaign_id,omitempty"`
+	CampaignName string     `json:"campaign_name,omitempty"`
+	VariantName  string     `json:"variant_name,omitempty"`
+	Subject      string     `json:"subject,omitempty"` // API sends only
+	ServerName   string     `json:"server_name"`
+	SendryMsgID  string     `json:"sendry_msg_id,omitempty"`
+	Status       string     `json:"status"`
+	Error        string     `json:"error,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	QueuedAt     *time.Time `json:"queued_at,omitempty"`
+	SentAt       *time.Time `json:"sent_at,omitempty"`
+	Opens        int        `json:"opens"`
+	Clicks       int        `json:"clicks"`
+	MTA          *MTAStatus `json:"mta,omitempty"` // Current status on the Sendry server
+}
+
+// MTAStatus is the delivery status of a message on its Sendry server
+type MTAStatus struct {
+	Status     string    `json:"status,omitempty"`
+	RetryCount int       `json:"retry_count,omitempty"`
+	LastError  string    `json:"last_error,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at,omitempty"`
+	Error      string    `json:"error,omitempty"` // Lookup failure
+}
+
+// HistorySuppression is a suppression of the address on a Sendry server
+type HistorySuppression struct {
+	Server    string    `json:"server"`
+	Reason    string    `json:"reason"`
+	Detail    string    `json:"detail,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// HistoryEvent is an entry of the recipient timeline
+type HistoryEvent struct {
+	Time      time.Time `json:"time"`
+	Type      string    `json:"type"` // added, created, queued, sent, failed, delivery, open, click, suppressed
+	MessageID string    `json:"message_id,omitempty"`
+	Title     string    `json:"title"`
+	Detail    string    `json:"detail,omitempty"`
+}
@@ -9,19 +9,41 @@ type SendJob struct {
 	CampaignName    string     `json:"campaign_name,omitempty"` // joined field
 	RecipientListID string     `json:"recipient_list_id"`
 	ListName        string     `json:"list_name,omitempty"` // joined field
-	Status          string     `json:"status"`              // draft, scheduled, running, paused, completed, failed, cancelled
+	SegmentID       string     `json:"segment_id,omitempty"`
+	SegmentName     string     `json:"segment_name,omitempty"` // joined field
+	Status          string     `json:"status"`                 // draft, scheduled, running, paused, completed, failed, cancelled
 	ScheduledAt     *time.Time `json:"scheduled_at,omitempty"`
 	StartedAt       *time.Time `json:"started_at,omitempty"`
 	CompletedAt     *time.Time `json:"completed_at,omitempty"`
 	Servers         string     `json:"servers"`  // JSON array of server names
-	Strategy        string     `json:"strategy"` // round-robin, random, weighted
+	Strategy        string     `json:"strategy"` // round-robin, random, weighted, failover, least-loaded
 	Stats           string     `json:"stats"`    // JSON with stats
 	DryRun          bool       `json:"dry_run"`
 	DryRunLimit     int        `json:"dry_run_limit"`
+	FreezeOverride  bool       `json:"freeze_override"`    // sends during freeze windows
+	Throttle        string     `json:"throttle,omitempty"` // JSON JobThrottle, empty for no limits
 	CreatedAt       time.Time  `json:"created_at"`
 	UpdatedAt       time.Time  `json:"updated_at"`
 }
 
+// Strategies distributing the items of a send job over its servers
+const (
+	StrategyRoundRobin  = "round-robin"
+	StrategyRandom      = "random"
+	StrategyWeighted    = "weighted"     // Smooth weighted round-robin by sendry.multi_send.weights
+	StrategyFailover    = "failover"     // First server, the next ones only when it fails
+	StrategyLeastLoaded = "least-loaded" // Server with the smallest queue
+)
+
+// ValidStrategy reports whether s is a known job strategy
+func ValidStrategy(s string) bool {
+	switch s {
+	case StrategyRoundRobin, StrategyRandom, StrategyWeighted, StrategyFailover, StrategyLeastLoaded:
+		return true
+	}
+	return false
+}
+
 // SendJobItem represents a single email in a send job
 type SendJobItem struct {
 	ID                 string     `json:"id"`
@@ -33,26 +55,39 @@ type SendJobItem struct {
 	VariantID          string     `json:"variant_id"`
 	VariantName        string     `json:"variant_name,omitempty"` // joined field
 	ServerName         string     `json:"server_name"`
-	Status             string     `json:"status"` // pending, queued, sent, failed
+	Status             string     `json:"status"` // pending, queued, sent, bounced, failed, cancelled
 	SendryMsgID        string     `json:"sendry_msg_id"`
-	Error              string     `json:"error"`
+	Error              string     `json:"error"`   // Failure, bounce or retry reason
+	MXHost             string     `json:"mx_host"` // MX host that accepted or rejected the email
 	QueuedAt           *time.Time `json:"queued_at,omitempty"`
-	SentAt             *time.Time `json:"sent_at,omitempty"`
+	SentAt             *time.Time `json:"sent_at,omitempty"` // Delivered to the recipient server
+	FailedAt           *time.Time `json:"failed_at,omitempty"`
 	CreatedAt          time.Time  `json:"created_at"`
 }
 
 // JobStats holds aggregated job statistics
 type JobStats struct {
-	Total   int `json:"total"`
-	Pending int `json:"pending"`
-	Queued  int `json:"queued"`
-	Sent    int `json:"sent"`
-	Failed  int `json:"failed"`
+	Total     int `json:"total"`
+	Pending   int `json:"pending"`
+	Queued    int `json:"queued"`
+	Sent      int `json:"sent"`
+	Failed    int `json:"failed"`
+	Bounced   int `json:"bounced"`   // Rejected permanently by the recipient server
+	Cancelled int `json:"cancelled"` // Pending items of a cancelled job
+}
+
+// ItemDelivery is the delivery state of a queued job item on its server
+type ItemDelivery struct {
+	Status string    // queued, sent, bounced or failed
+	MXHost string    // MX host of the final attempt
+	Error  string    // Bounce, failure or retry reason
+	At     time.Time // Time of the final outcome
 }
 
 // JobListFilter for filtering jobs
 type JobListFilter struct {
 	CampaignID string
+	OrgID      string
 	Status     string
 	Limit      int
 	Offset     int
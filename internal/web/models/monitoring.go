@@ -45,3 +45,24 @@ type ServerStats struct {
 	DLQSize   int    `json:"dlq_size"`
 	Error     string `json:"error,omitempty"`
 }
+
+// ServerHealth is a health sample of a Sendry server for the server
+// dashboard. Message totals are the server's cumulative counters.
+type ServerHealth struct {
+	Server         string     `json:"server"`
+	Online         bool       `json:"online"`
+	Error          string     `json:"error,omitempty"`
+	QueuePending   int        `json:"queue_pending"`
+	QueueRetrying  int        `json:"queue_retrying"`
+	DLQSize        int        `json:"dlq_size"`
+	OldestQueued   int        `json:"oldest_queued"`    // Age of the oldest queued message in seconds, from metrics
+	RateLimitUsage float64    `json:"rate_limit_usage"` // Highest share of the global limits used, 0-1
+	SentTotal      float64    `json:"sent_total"`
+	FailedTotal    float64    `json:"failed_total"`
+	BouncedTotal   float64    `json:"bounced_total"`
+	CertDomain     string     `json:"cert_domain,omitempty"` // Certificate expiring first
+	CertExpiresAt  *time.Time `json:"cert_expires_at,omitempty"`
+	DNSBLListings  []string   `json:"dnsbl_listings,omitempty"` // Monitored IPs listed on DNSBLs, e.g. "192.0.2.1: SpamCop"; not stored
+	Warnings       []string   `json:"warnings,omitempty"`
+	SampledAt      time.Time  `json:"sampled_at"`
+}
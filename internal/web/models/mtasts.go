@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// MTASTSPolicy is the MTA-STS policy of a domain, served at
+// https://mta-sts.<domain>/.well-known/mta-sts.txt
+type MTASTSPolicy struct {
+	DomainID  string    `json:"domain_id"`
+	Mode      string    `json:"mode"` // enforce, testing, none
+	MX        []string  `json:"mx"`
+	MaxAge    int       `json:"max_age"` // Seconds
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+// Organization owns templates, campaigns, recipient lists, DKIM keys,
+// domains and API keys. Users see the objects of the organizations they are
+// members of.
+type Organization struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Servers     []string  `json:"servers"` // Sendry servers the organization may use (empty = all)
+	CreatedAt   time.Time `json:"created_at"`
+	MemberCount int       `json:"member_count"` // joined field
+}
+
+// AllowsServer returns true if the organization may use the Sendry server
+func (o *Organization) AllowsServer(name string) bool {
+	return len(o.Servers) == 0 || slices.Contains(o.Servers, name)
+}
+
+// OrgRole is the role of a user in an organization
+type OrgRole string
+
+const (
+	OrgRoleAdmin  OrgRole = "admin"  // Manage members, plus member access
+	OrgRoleMember OrgRole = "member" // Create, change and send
+	OrgRoleViewer OrgRole = "viewer" // Read only
+)
+
+// OrgRoles are the roles a member can have
+var OrgRoles = []OrgRole{OrgRoleAdmin, OrgRoleMember, OrgRoleViewer}
+
+// ValidOrgRole returns true if the role is known
+func ValidOrgRole(role string) bool {
+	return slices.Contains(OrgRoles, OrgRole(role))
+}
+
+// OrganizationMember is a user's membership in an organization
+type OrganizationMember struct {
+	OrgID     string    `json:"org_id"`
+	UserID    string    `json:"user_id"`
+	Email     string    `json:"email"` // joined field
+	Name      string    `json:"name"`  // joined field
+	Role      OrgRole   `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -10,6 +10,7 @@ type RecipientList struct {
 	SourceType  string    `json:"source_type"` // manual, csv, api
 	TotalCount  int       `json:"total_count"`
 	ActiveCount int       `json:"active_count"`
+	OrgID       string    `json:"org_id,omitempty"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
@@ -28,6 +29,7 @@ type Recipient struct {
 
 // RecipientListFilter for filtering recipient lists
 type RecipientListFilter struct {
+	OrgID  string // Lists of the organization; all if empty
 	Search string
 	Limit  int
 	Offset int
@@ -39,14 +41,39 @@ type RecipientFilter struct {
 	Search string
 	Status string
 	Tag    string
+	Rules  *SegmentRules // Segment rules, nil for all recipients
 	Limit  int
 	Offset int
 }
 
-// RecipientImportResult holds the result of an import operation
-type RecipientImportResult struct {
-	Total    int      `json:"total"`
-	Imported int      `json:"imported"`
-	Skipped  int      `json:"skipped"`
-	Errors   []string `json:"errors,omitempty"`
+// RecipientImport is an uploaded recipient file. It holds the file rows
+// until the columns are mapped and the import runs, then the import report.
+type RecipientImport struct {
+	ID          string        `json:"id"`
+	ListID      string        `json:"list_id"`
+	FileName    string        `json:"file_name"`
+	Columns     []string      `json:"columns"`
+	Rows        [][]string    `json:"-"`      // Cleared once imported
+	Status      string        `json:"status"` // pending, completed
+	Total       int           `json:"total"`
+	Imported    int           `json:"imported"` // New recipients
+	Updated     int           `json:"updated"`  // Existing recipients of the list
+	Rejected    []RejectedRow `json:"rejected,omitempty"`
+	CreatedBy   string        `json:"created_by"`
+	CreatedAt   time.Time     `json:"created_at"`
+	CompletedAt *time.Time    `json:"completed_at,omitempty"`
+}
+
+// Recipient import statuses
+const (
+	RecipientImportPending   = "pending"
+	RecipientImportCompleted = "completed"
+)
+
+// RejectedRow is a row of an imported file that was not imported
+type RejectedRow struct {
+	Row    int      `json:"row"` // 1-based data row, not counting the header
+	Email  string   `json:"email"`
+	Reason string   `json:"reason"`
+	Values []string `json:"values"`
 }
@@ -0,0 +1,159 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Segment is a saved subset of a recipient list selected by rules
+type Segment struct {
+	ID          string    `json:"id"`
+	ListID      string    `json:"list_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Rules       string    `json:"rules"` // JSON SegmentRules
+	Count       int       `json:"count"` // Active recipients matching the rules, computed
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Summary returns the rules in a readable form, e.g.
+// country == "DE" and plan in [pro]
+func (s Segment) Summary() string {
+	rules, err := ParseSegmentRules(s.Rules)
+	if err != nil {
+		return s.Rules
+	}
+	parts := make([]string, len(rules.Conditions))
+	for i, c := range rules.Conditions {
+		parts[i] = c.String()
+	}
+	join := " and "
+	if rules.Match == "any" {
+		join = " or "
+	}
+	return strings.Join(parts, join)
+}
+
+// Segment rule operators
+const (
+	SegmentOpEq        = "eq"
+	SegmentOpNe        = "ne"
+	SegmentOpIn        = "in"
+	SegmentOpNotIn     = "not_in"
+	SegmentOpContains  = "contains"
+	SegmentOpGt        = "gt"
+	SegmentOpLt        = "lt"
+	SegmentOpExists    = "exists"
+	SegmentOpNotExists = "not_exists"
+)
+
+// SegmentOps lists the operators in the order shown in the UI
+var SegmentOps = []string{
+	SegmentOpEq, SegmentOpNe, SegmentOpIn, SegmentOpNotIn, SegmentOpContains,
+	SegmentOpGt, SegmentOpLt, SegmentOpExists, SegmentOpNotExists,
+}
+
+// Segment fields that are not recipient variables
+const (
+	SegmentFieldEmail = "email"
+	SegmentFieldName  = "name"
+	SegmentFieldTag   = "tag"
+)
+
+// SegmentRules selects recipients whose conditions all match, or any
+// condition matches if Match is "any"
+type SegmentRules struct {
+	Match      string             `json:"match,omitempty"` // all (default) or any
+	Conditions []SegmentCondition `json:"conditions"`
+}
+
+// SegmentCondition compares a recipient field or variable with a value.
+// Field is email, name, tag or a variable key; nested variables are
+// separated by dots (address.country).
+type SegmentCondition struct {
+	Field  string   `json:"field"`
+	Op     string   `json:"op"`
+	Value  string   `json:"value,omitempty"`
+	Values []string `json:"values,omitempty"` // For in and not_in
+}
+
+var segmentFieldRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)*$`)
+
+// ParseSegmentRules parses and validates JSON segment rules
+func ParseSegmentRules(data string) (*SegmentRules, error) {
+	var rules SegmentRules
+	if err := json.Unmarshal([]byte(data), &rules); err != nil {
+		return nil, fmt.Errorf("invalid segment rules: %w", err)
+	}
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+	return &rules, nil
+}
+
+// Validate checks the match mode, fields, operators and values
+func (s *SegmentRules) Validate() error {
+	if s.Match != "" && s.Match != "all" && s.Match != "any" {
+		return fmt.Errorf("match must be all or any")
+	}
+	if len(s.Conditions) == 0 {
+		return fmt.Errorf("at least one condition is required")
+	}
+	for i, c := range s.Conditions {
+		if err := c.validate(); err != nil {
+			return fmt.Errorf("condition %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+func (c *SegmentCondition) validate() error {
+	if !segmentFieldRe.MatchString(c.Field) {
+		return fmt.Errorf("invalid field %q", c.Field)
+	}
+
+	switch c.Op {
+	case SegmentOpEq, SegmentOpNe, SegmentOpContains:
+	case SegmentOpIn, SegmentOpNotIn:
+		if len(c.Values) == 0 {
+			return fmt.Errorf("%s needs at least one value", c.Op)
+		}
+	case SegmentOpGt, SegmentOpLt:
+		if _, err := strconv.ParseFloat(c.Value, 64); err != nil {
+			return fmt.Errorf("%s needs a number, got %q", c.Op, c.Value)
+		}
+	case SegmentOpExists, SegmentOpNotExists:
+	default:
+		return fmt.Errorf("unknown operator %q", c.Op)
+	}
+
+	if c.Field == SegmentFieldTag {
+		switch c.Op {
+		case SegmentOpEq, SegmentOpNe, SegmentOpIn, SegmentOpNotIn:
+		default:
+			return fmt.Errorf("tag supports only eq, ne, in and not_in")
+		}
+	}
+	return nil
+}
+
+// String returns the condition in a readable form, e.g. plan in [pro, team]
+func (c SegmentCondition) String() string {
+	switch c.Op {
+	case SegmentOpIn, SegmentOpNotIn:
+		return fmt.Sprintf("%s %s [%s]", c.Field, strings.ReplaceAll(c.Op, "_", " "), strings.Join(c.Values, ", "))
+	case SegmentOpExists:
+		return c.Field + " exists"
+	case SegmentOpNotExists:
+		return c.Field + " does not exist"
+	}
+	symbols := map[string]string{
+		SegmentOpEq: "==", SegmentOpNe: "!=", SegmentOpGt: ">", SegmentOpLt: "<", SegmentOpContains: "contains",
+	}
+	return fmt.Sprintf("%s %s %q", c.Field, symbols[c.Op], c.Value)
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseSegmentRules(t *testing.T) {
+	invalid := map[string]string{
+		`{"conditions": []}`: "at least one condition",
+		`{"match": "some", "conditions": [{"field": "plan", "op": "eq"}]}`:           "match must be",
+		`{"conditions": [{"field": "plan'; --", "op": "eq", "value": "x"}]}`:         "invalid field",
+		`{"conditions": [{"field": "plan", "op": "like", "value": "x"}]}`:            "unknown operator",
+		`{"conditions": [{"field": "plan", "op": "in"}]}`:                            "needs at least one value",
+		`{"conditions": [{"field": "orders", "op": "gt", "value": "many"}]}`:         "needs a number",
+		`{"conditions": [{"field": "tag", "op": "contains", "value": "v"}]}`:         "tag supports only",
+		`{"conditions": [{"field": "plan", "op": "eq"}, {"field": "", "op": "eq"}]}`: "condition 2",
+	}
+	for rules, want := range invalid {
+		if _, err := ParseSegmentRules(rules); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ParseSegmentRules(%s) error = %v, want %q", rules, err, want)
+		}
+	}
+
+	rules, err := ParseSegmentRules(`{"conditions": [{"field": "country", "op": "eq", "value": "DE"}, {"field": "plan", "op": "in", "values": ["pro", "team"]}]}`)
+	if err != nil {
+		t.Fatalf("ParseSegmentRules() error = %v", err)
+	}
+	if len(rules.Conditions) != 2 {
+		t.Fatalf("conditions = %+v", rules.Conditions)
+	}
+	s := Segment{Rules: `{"match": "any", "conditions": [{"field": "country", "op": "eq", "value": "DE"}, {"field": "plan", "op": "in", "values": ["pro", "team"]}]}`}
+	if want := `country == "DE" or plan in [pro, team]`; s.Summary() != want {
+		t.Errorf("Summary() = %q, want %q", s.Summary(), want)
+	}
+}
@@ -32,6 +32,7 @@ const (
 // SendFilter for listing sends
 type SendFilter struct {
 	APIKeyID     string
+	OrgID        string // Sends of the organization's API keys
 	Status       string
 	SenderDomain string
 	ServerName   string
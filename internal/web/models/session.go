@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// Session is a signed-in browser session of a web user
+type Session struct {
+	ID         string     `json:"id"` // handle derived from the session cookie, which is never shown
+	AuthMethod string     `json:"auth_method"`
+	IP         string     `json:"ip"`
+	UserAgent  string     `json:"user_agent"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt *time.Time `json:"last_seen_at,omitempty"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	Current    bool       `json:"current"` // session of the request
+}
@@ -11,6 +11,7 @@ type Template struct {
 	Text                string    `json:"text"`
 	Variables           string    `json:"variables"` // JSON
 	Folder              string    `json:"folder"`
+	OrgID               string    `json:"org_id,omitempty"`
 	CurrentVersion      int       `json:"current_version"`
 	UseBlocks           bool      `json:"use_blocks"`
 	ContainerRadius      int       `json:"container_radius"`
@@ -21,6 +22,7 @@ type Template struct {
 	ContainerPaddingV    int       `json:"container_padding_v"`
 	ContainerPaddingH    int       `json:"container_padding_h"`
 	PageBackground       string    `json:"page_background"` // CSS colour around the email container; empty -> "#F5F5F5"
+	BlockExternal        bool      `json:"block_external"`  // Forbid externally hosted resources in HTML
 	CreatedAt            time.Time `json:"created_at"`
 	UpdatedAt           time.Time `json:"updated_at"`
 
@@ -51,13 +53,22 @@ type TemplateVersion struct {
 	CreatedAt  time.Time `json:"created_at"`
 }
 
+// Template drift states, empty if the server has the deployed content
+const (
+	DriftModified = "modified" // Changed on the server after the deploy
+	DriftDeleted  = "deleted"  // Deleted on the server
+)
+
 type TemplateDeployment struct {
-	ID              int64     `json:"id"`
-	TemplateID      string    `json:"template_id"`
-	ServerName      string    `json:"server_name"`
-	RemoteID        string    `json:"remote_id"`
-	DeployedVersion int       `json:"deployed_version"`
-	DeployedAt      time.Time `json:"deployed_at"`
+	ID              int64      `json:"id"`
+	TemplateID      string     `json:"template_id"`
+	ServerName      string     `json:"server_name"`
+	RemoteID        string     `json:"remote_id"`
+	DeployedVersion int        `json:"deployed_version"`
+	DeployedAt      time.Time  `json:"deployed_at"`
+	ContentHash     string     `json:"content_hash"` // Hash of the deployed content
+	Drift           string     `json:"drift"`        // modified, deleted or empty
+	DriftCheckedAt  *time.Time `json:"drift_checked_at,omitempty"`
 }
 
 // TemplateWithStatus includes deployment status info
@@ -70,6 +81,7 @@ type TemplateWithStatus struct {
 
 // TemplateListFilter for filtering template list
 type TemplateListFilter struct {
+	OrgID  string // Templates of the organization; all if empty
 	Search string
 	Folder string
 	Limit  int
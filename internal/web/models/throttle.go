@@ -0,0 +1,209 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JobThrottle limits how fast a send job sends and when
+type JobThrottle struct {
+	RatePerMinute int         `json:"rate_per_minute,omitempty"` // 0 for no limit
+	RampUp        []RampStep  `json:"ramp_up,omitempty"`         // Lower rates at the start of the job
+	Window        *SendWindow `json:"window,omitempty"`          // Time of day items are sent
+}
+
+// RampStep limits the rate during the first Minutes of a job
+type RampStep struct {
+	Minutes       int `json:"minutes"`
+	RatePerMinute int `json:"rate_per_minute"`
+}
+
+// SendWindow is the time of day a job sends, e.g. 09:00-18:00. End before
+// Start spans midnight.
+type SendWindow struct {
+	Start          string `json:"start"`                     // HH:MM
+	End            string `json:"end"`                       // HH:MM
+	Timezone       string `json:"timezone,omitempty"`        // IANA name, server local time if empty
+	RecipientLocal bool   `json:"recipient_local,omitempty"` // Use the timezone variable of each recipient
+}
+
+// RecipientTimezoneVar is the recipient variable holding the IANA timezone
+// used by recipient-local send windows
+const RecipientTimezoneVar = "timezone"
+
+// ParseJobThrottle parses and validates a JSON job throttle. An empty
+// string is no throttle.
+func ParseJobThrottle(data string) (*JobThrottle, error) {
+	if data == "" {
+		return nil, nil
+	}
+	var t JobThrottle
+	if err := json.Unmarshal([]byte(data), &t); err != nil {
+		return nil, fmt.Errorf("invalid throttle: %w", err)
+	}
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Validate checks the rates, ramp-up steps and window, and sorts the steps
+func (t *JobThrottle) Validate() error {
+	if t.RatePerMinute < 0 {
+		return fmt.Errorf("rate must not be negative")
+	}
+	for _, s := range t.RampUp {
+		if s.Minutes <= 0 || s.RatePerMinute <= 0 {
+			return fmt.Errorf("ramp-up steps need positive minutes and rate")
+		}
+	}
+	sort.Slice(t.RampUp, func(i, j int) bool { return t.RampUp[i].Minutes < t.RampUp[j].Minutes })
+	for i := 1; i < len(t.RampUp); i++ {
+		if t.RampUp[i].Minutes == t.RampUp[i-1].Minutes {
+			return fmt.Errorf("ramp-up has two steps for %d minutes", t.RampUp[i].Minutes)
+		}
+	}
+
+	if w := t.Window; w != nil {
+		if _, err := parseClock(w.Start); err != nil {
+			return fmt.Errorf("window start: %w", err)
+		}
+		if _, err := parseClock(w.End); err != nil {
+			return fmt.Errorf("window end: %w", err)
+		}
+		if w.Start == w.End {
+			return fmt.Errorf("window start and end must differ")
+		}
+		if _, err := time.LoadLocation(w.Timezone); err != nil {
+			return fmt.Errorf("unknown timezone %q", w.Timezone)
+		}
+	}
+	return nil
+}
+
+// IsZero reports whether the throttle has no limits
+func (t *JobThrottle) IsZero() bool {
+	return t == nil || (t.RatePerMinute == 0 && len(t.RampUp) == 0 && t.Window == nil)
+}
+
+// RateAt returns the rate limit a job has been running for elapsed time:
+// the rate of the first ramp-up step not yet over, or the job rate.
+// 0 means no limit.
+func (t *JobThrottle) RateAt(elapsed time.Duration) int {
+	if t == nil {
+		return 0
+	}
+	for _, s := range t.RampUp {
+		if elapsed < time.Duration(s.Minutes)*time.Minute {
+			return s.RatePerMinute
+		}
+	}
+	return t.RatePerMinute
+}
+
+// Location returns the window timezone, or the server local time
+func (w *SendWindow) Location() *time.Location {
+	if w.Timezone == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(w.Timezone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// Open reports whether t falls into the window in the given timezone
+func (w *SendWindow) Open(t time.Time, loc *time.Location) bool {
+	start, err1 := parseClock(w.Start)
+	end, err2 := parseClock(w.End)
+	if err1 != nil || err2 != nil {
+		return true
+	}
+	t = t.In(loc)
+	now := t.Hour()*60 + t.Minute()
+	if start < end {
+		return now >= start && now < end
+	}
+	return now >= start || now < end
+}
+
+// RecipientLocation returns the timezone of a recipient from its variables
+// for recipient-local windows, or the window timezone
+func (w *SendWindow) RecipientLocation(variables string) *time.Location {
+	if w.RecipientLocal && variables != "" {
+		var vars map[string]any
+		if json.Unmarshal([]byte(variables), &vars) == nil {
+			if name, ok := vars[RecipientTimezoneVar].(string); ok && name != "" {
+				if loc, err := time.LoadLocation(name); err == nil {
+					return loc
+				}
+			}
+		}
+	}
+	return w.Location()
+}
+
+// String returns the throttle in a readable form, e.g.
+// 500/min, ramp-up 50/min for 30m, 09:00-18:00 Europe/Berlin
+func (t *JobThrottle) String() string {
+	if t.IsZero() {
+		return ""
+	}
+	var parts []string
+	if t.RatePerMinute > 0 {
+		parts = append(parts, fmt.Sprintf("%d/min", t.RatePerMinute))
+	}
+	if len(t.RampUp) > 0 {
+		steps := make([]string, len(t.RampUp))
+		for i, s := range t.RampUp {
+			steps[i] = fmt.Sprintf("%d/min for %dm", s.RatePerMinute, s.Minutes)
+		}
+		parts = append(parts, "ramp-up "+strings.Join(steps, ", "))
+	}
+	if w := t.Window; w != nil {
+		tz := w.Timezone
+		if tz == "" {
+			tz = "server time"
+		}
+		if w.RecipientLocal {
+			tz = "recipient time, default " + tz
+		}
+		parts = append(parts, fmt.Sprintf("%s-%s %s", w.Start, w.End, tz))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ParseRampUp parses ramp-up steps written as minutes:rate pairs, e.g.
+// "30:50, 60:200" sends 50 per minute for the first 30 minutes and 200 per
+// minute until the job has run for an hour
+func ParseRampUp(s string) ([]RampStep, error) {
+	var steps []RampStep
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		minutes, rate, ok := strings.Cut(part, ":")
+		m, err1 := strconv.Atoi(strings.TrimSpace(minutes))
+		r, err2 := strconv.Atoi(strings.TrimSpace(rate))
+		if !ok || err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("invalid ramp-up step %q, want minutes:rate", part)
+		}
+		steps = append(steps, RampStep{Minutes: m, RatePerMinute: r})
+	}
+	return steps, nil
+}
+
+// parseClock parses HH:MM into minutes since midnight
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, want HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestJobThrottleRateAt(t *testing.T) {
+	steps, err := ParseRampUp("60:200, 30:50")
+	if err != nil {
+		t.Fatalf("ParseRampUp() error = %v", err)
+	}
+	th := &JobThrottle{RatePerMinute: 500, RampUp: steps}
+	if err := th.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	for elapsed, want := range map[time.Duration]int{
+		0:                50,
+		29 * time.Minute: 50,
+		30 * time.Minute: 200,
+		2 * time.Hour:    500,
+	} {
+		if got := th.RateAt(elapsed); got != want {
+			t.Errorf("RateAt(%v) = %d, want %d", elapsed, got, want)
+		}
+	}
+
+	if _, err := ParseRampUp("30"); err == nil {
+		t.Error("ParseRampUp() should reject a step without rate")
+	}
+	if err := (&JobThrottle{RampUp: []RampStep{{30, 10}, {30, 20}}}).Validate(); err == nil {
+		t.Error("Validate() should reject duplicate ramp-up steps")
+	}
+}
+
+func TestSendWindowOpen(t *testing.T) {
+	day := &SendWindow{Start: "09:00", End: "18:00"}
+	night := &SendWindow{Start: "22:00", End: "06:00"}
+
+	at := func(h, m int) time.Time { return time.Date(2026, 10, 16, h, m, 0, 0, time.UTC) }
+	if !day.Open(at(9, 0), time.UTC) || day.Open(at(18, 0), time.UTC) || day.Open(at(8, 59), time.UTC) {
+		t.Error("day window should include the start and exclude the end")
+	}
+	if !night.Open(at(23, 0), time.UTC) || !night.Open(at(5, 59), time.UTC) || night.Open(at(12, 0), time.UTC) {
+		t.Error("window ending before its start should span midnight")
+	}
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("timezone data not available")
+	}
+	local := &SendWindow{Start: "09:00", End: "18:00", Timezone: "UTC", RecipientLocal: true}
+	loc := local.RecipientLocation(`{"timezone":"Europe/Berlin"}`)
+	if loc.String() != berlin.String() {
+		t.Errorf("RecipientLocation() = %v, want Europe/Berlin", loc)
+	}
+	if !local.Open(at(7, 30), loc) || local.Open(at(7, 30), local.RecipientLocation(`{}`)) {
+		t.Error("recipient-local window should use the recipient timezone")
+	}
+}
@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// Tracking event types
+const (
+	TrackingOpen  = "open"
+	TrackingClick = "click"
+)
+
+// TrackingEvent is an open or click of a campaign email
+type TrackingEvent struct {
+	ID         int64     `json:"id"`
+	ItemID     string    `json:"item_id"`
+	JobID      string    `json:"job_id"`
+	CampaignID string    `json:"campaign_id"`
+	Type       string    `json:"type"` // open, click
+	URL        string    `json:"url,omitempty"`
+	IPAddress  string    `json:"ip_address"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// EngagementStats holds the opens and clicks of a job or campaign
+type EngagementStats struct {
+	Delivered    int         `json:"delivered"` // Items sent or queued
+	Opens        int         `json:"opens"`
+	UniqueOpens  int         `json:"unique_opens"`
+	Clicks       int         `json:"clicks"`
+	UniqueClicks int         `json:"unique_clicks"`
+	Links        []LinkStats `json:"links"` // Most clicked links first
+}
+
+// LinkStats holds the clicks of a link
+type LinkStats struct {
+	URL          string `json:"url"`
+	Clicks       int    `json:"clicks"`
+	UniqueClicks int    `json:"unique_clicks"`
+}
+
+// OpenRate returns the share of delivered emails that were opened
+func (s EngagementStats) OpenRate() float64 {
+	return rate(s.UniqueOpens, s.Delivered)
+}
+
+// ClickRate returns the share of delivered emails with a clicked link
+func (s EngagementStats) ClickRate() float64 {
+	return rate(s.UniqueClicks, s.Delivered)
+}
+
+func rate(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
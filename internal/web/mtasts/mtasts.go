@@ -0,0 +1,218 @@
+// Package mtasts generates MTA-STS policies (RFC 8461) for domains and
+// validates the policy a domain publishes.
+package mtasts
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Policy modes
+const (
+	ModeEnforce = "enforce"
+	ModeTesting = "testing"
+	ModeNone    = "none"
+)
+
+const (
+	// DefaultMaxAge is the default policy lifetime, one week
+	DefaultMaxAge = 604800
+
+	// MaxMaxAge is the longest lifetime RFC 8461 allows, about one year
+	MaxMaxAge = 31557600
+
+	// WellKnownPath is the path of the policy file on the policy host
+	WellKnownPath = "/.well-known/mta-sts.txt"
+)
+
+// Policy is an MTA-STS policy
+type Policy struct {
+	Mode   string
+	MX     []string // Host names or "*.example.com" patterns
+	MaxAge int      // Seconds
+}
+
+// Validate checks that the policy can be published
+func (p *Policy) Validate() error {
+	switch p.Mode {
+	case ModeEnforce, ModeTesting, ModeNone:
+	default:
+		return fmt.Errorf("invalid mode %q", p.Mode)
+	}
+	if p.MaxAge < 0 || p.MaxAge > MaxMaxAge {
+		return fmt.Errorf("max_age must be between 0 and %d", MaxMaxAge)
+	}
+	if p.Mode != ModeNone && len(p.MX) == 0 {
+		return errors.New("at least one mx is required")
+	}
+	for _, mx := range p.MX {
+		if !validMX(mx) {
+			return fmt.Errorf("invalid mx %q", mx)
+		}
+	}
+	return nil
+}
+
+// Text returns the policy file content
+func (p *Policy) Text() string {
+	var b strings.Builder
+	b.WriteString("version: STSv1\r\n")
+	b.WriteString("mode: " + p.Mode + "\r\n")
+	for _, mx := range p.MX {
+		b.WriteString("mx: " + mx + "\r\n")
+	}
+	b.WriteString("max_age: " + strconv.Itoa(p.MaxAge) + "\r\n")
+	return b.String()
+}
+
+// ID returns the policy id, derived from the policy content so it changes
+// whenever the policy does and senders fetch the new policy
+func (p *Policy) ID() string {
+	sum := sha256.Sum256([]byte(p.Text()))
+	return hex.EncodeToString(sum[:10])
+}
+
+// TXTRecord returns the value of the _mta-sts TXT record
+func (p *Policy) TXTRecord() string {
+	return "v=STSv1; id=" + p.ID()
+}
+
+// Equal returns true if both policies have the same mode, lifetime and MX
+// patterns in any order
+func (p *Policy) Equal(o *Policy) bool {
+	if p.Mode != o.Mode || p.MaxAge != o.MaxAge || len(p.MX) != len(o.MX) {
+		return false
+	}
+	seen := make(map[string]bool, len(p.MX))
+	for _, mx := range p.MX {
+		seen[strings.ToLower(mx)] = true
+	}
+	for _, mx := range o.MX {
+		if !seen[strings.ToLower(mx)] {
+			return false
+		}
+	}
+	return true
+}
+
+// Matches returns true if an MX host is covered by the policy
+func (p *Policy) Matches(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for _, mx := range p.MX {
+		mx = strings.ToLower(mx)
+		if suffix, ok := strings.CutPrefix(mx, "*."); ok {
+			// A wildcard matches exactly one label
+			label, rest, found := strings.Cut(host, ".")
+			if found && label != "" && rest == suffix {
+				return true
+			}
+			continue
+		}
+		if host == mx {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse parses a policy file. Unknown keys are ignored as RFC 8461 requires.
+func Parse(text string) (*Policy, error) {
+	p := &Policy{}
+	var version, maxAge string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid line %q", line)
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "version":
+			version = value
+		case "mode":
+			p.Mode = value
+		case "mx":
+			p.MX = append(p.MX, value)
+		case "max_age":
+			maxAge = value
+		}
+	}
+
+	if version != "STSv1" {
+		return nil, fmt.Errorf("unsupported version %q", version)
+	}
+	if maxAge == "" {
+		return nil, errors.New("max_age is missing")
+	}
+	n, err := strconv.Atoi(maxAge)
+	if err != nil {
+		return nil, fmt.Errorf("invalid max_age %q", maxAge)
+	}
+	p.MaxAge = n
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// ParseTXT returns the policy id of an _mta-sts TXT record
+func ParseTXT(record string) (string, error) {
+	var version, id string
+	for _, field := range strings.Split(record, ";") {
+		key, value, _ := strings.Cut(strings.TrimSpace(field), "=")
+		switch strings.TrimSpace(key) {
+		case "v":
+			version = strings.TrimSpace(value)
+		case "id":
+			id = strings.TrimSpace(value)
+		}
+	}
+	if version != "STSv1" {
+		return "", errors.New("not an MTA-STS record")
+	}
+	if id == "" || len(id) > 32 || strings.IndexFunc(id, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	}) >= 0 {
+		return "", fmt.Errorf("invalid policy id %q", id)
+	}
+	return id, nil
+}
+
+// ParseMX splits a list of MX patterns separated by newlines, commas or
+// spaces
+func ParseMX(s string) []string {
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == '\n' || r == '\r' || r == ',' || r == ' ' || r == '\t'
+	})
+	mx := make([]string, 0, len(fields))
+	for _, f := range fields {
+		mx = append(mx, strings.TrimSuffix(strings.ToLower(f), "."))
+	}
+	return mx
+}
+
+// validMX checks a host name or a "*." pattern
+func validMX(mx string) bool {
+	mx = strings.TrimPrefix(mx, "*.")
+	if mx == "" || len(mx) > 253 || !strings.Contains(mx, ".") {
+		return false
+	}
+	for _, label := range strings.Split(mx, ".") {
+		if label == "" || len(label) > 63 {
+			return false
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
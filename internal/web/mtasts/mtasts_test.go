@@ -0,0 +1,155 @@
+package mtasts
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPolicy_TextAndParse(t *testing.T) {
+	p := &Policy{Mode: ModeEnforce, MX: []string{"mx1.example.com", "*.mail.example.com"}, MaxAge: DefaultMaxAge}
+	if err := p.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	want := "version: STSv1\r\nmode: enforce\r\nmx: mx1.example.com\r\nmx: *.mail.example.com\r\nmax_age: 604800\r\n"
+	if got := p.Text(); got != want {
+		t.Errorf("Text() = %q, want %q", got, want)
+	}
+
+	parsed, err := Parse(p.Text())
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if !parsed.Equal(p) {
+		t.Errorf("Parse() = %+v, want %+v", parsed, p)
+	}
+
+	if id := p.ID(); len(id) != 20 {
+		t.Errorf("ID() = %q, want 20 characters", id)
+	}
+	if _, err := ParseTXT(p.TXTRecord()); err != nil {
+		t.Errorf("ParseTXT(%q) error = %v", p.TXTRecord(), err)
+	}
+
+	changed := &Policy{Mode: ModeTesting, MX: p.MX, MaxAge: p.MaxAge}
+	if changed.ID() == p.ID() {
+		t.Error("ID() did not change with the policy")
+	}
+}
+
+func TestPolicy_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"version", "version: STSv2\nmode: enforce\nmx: mx.example.com\nmax_age: 86400\n"},
+		{"mode", "version: STSv1\nmode: strict\nmx: mx.example.com\nmax_age: 86400\n"},
+		{"no mx", "version: STSv1\nmode: enforce\nmax_age: 86400\n"},
+		{"max_age", "version: STSv1\nmode: enforce\nmx: mx.example.com\nmax_age: 99999999\n"},
+		{"missing max_age", "version: STSv1\nmode: enforce\nmx: mx.example.com\n"},
+		{"bad mx", "version: STSv1\nmode: enforce\nmx: mx_1\nmax_age: 86400\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.text); err == nil {
+				t.Error("Parse() error = nil, want error")
+			}
+		})
+	}
+
+	if _, err := Parse("version: STSv1\nmode: none\nmax_age: 86400\n"); err != nil {
+		t.Errorf("Parse() mode none without mx error = %v", err)
+	}
+}
+
+func TestPolicy_Matches(t *testing.T) {
+	p := &Policy{Mode: ModeEnforce, MX: []string{"mx.example.com", "*.mail.example.com"}}
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"mx.example.com", true},
+		{"MX.example.com.", true},
+		{"a.mail.example.com", true},
+		{"mail.example.com", false},
+		{"a.b.mail.example.com", false},
+		{"mx2.example.com", false},
+	}
+	for _, tt := range tests {
+		if got := p.Matches(tt.host); got != tt.want {
+			t.Errorf("Matches(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestValidator_Validate(t *testing.T) {
+	expected := &Policy{Mode: ModeEnforce, MX: []string{"mx.example.com"}, MaxAge: DefaultMaxAge}
+	served := expected.Text()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != WellKnownPath {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(served))
+	}))
+	defer srv.Close()
+
+	txt := expected.TXTRecord()
+	mx := []*net.MX{{Host: "mx.example.com.", Pref: 10}}
+	v := &Validator{
+		Client: srv.Client(),
+		LookupTXT: func(_ context.Context, name string) ([]string, error) {
+			if name != "_mta-sts.example.com" {
+				t.Errorf("LookupTXT(%q)", name)
+			}
+			return []string{txt}, nil
+		},
+		LookupMX: func(context.Context, string) ([]*net.MX, error) {
+			return mx, nil
+		},
+		PolicyURL: func(string) string { return srv.URL + WellKnownPath },
+	}
+
+	results := v.Validate(context.Background(), "example.com", expected)
+	if len(results) != 3 {
+		t.Fatalf("Validate() returned %d results, want 3", len(results))
+	}
+	for _, r := range results {
+		if r.Status != StatusOK {
+			t.Errorf("%s: status = %s (%s), want ok", r.Type, r.Status, r.Message)
+		}
+	}
+
+	// A stale id, a different served policy and an uncovered MX host
+	txt = "v=STSv1; id=old1"
+	served = strings.Replace(served, "mode: enforce", "mode: testing", 1)
+	mx = append(mx, &net.MX{Host: "backup.example.net.", Pref: 20})
+
+	results = v.Validate(context.Background(), "example.com", expected)
+	want := []string{StatusWarning, StatusWarning, StatusWarning}
+	for i, r := range results {
+		if r.Status != want[i] {
+			t.Errorf("%s: status = %s (%s), want %s", r.Type, r.Status, r.Message, want[i])
+		}
+	}
+	if !strings.Contains(results[2].Message, "backup.example.net") {
+		t.Errorf("MX message = %q, want uncovered host", results[2].Message)
+	}
+
+	// Without a served policy an uncovered MX host fails in enforce mode
+	served = expected.Text()
+	v.PolicyURL = func(string) string { return srv.URL + "/missing" }
+	results = v.Validate(context.Background(), "example.com", expected)
+	if results[1].Status != StatusError {
+		t.Errorf("missing policy status = %s, want error", results[1].Status)
+	}
+	if results[2].Status != StatusError {
+		t.Errorf("enforce MX coverage status = %s, want error", results[2].Status)
+	}
+}
@@ -0,0 +1,233 @@
+package mtasts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxPolicySize limits fetched policy files, RFC 8461 suggests 64 KiB
+const maxPolicySize = 64 << 10
+
+// Check statuses, the same as the DNS checks of the domain page
+const (
+	StatusOK       = "ok"
+	StatusWarning  = "warning"
+	StatusError    = "error"
+	StatusNotFound = "not_found"
+)
+
+// CheckResult is the result of one validation step
+type CheckResult struct {
+	Type    string
+	Status  string
+	Value   string
+	Message string
+}
+
+// Validator checks the MTA-STS policy a domain publishes against the
+// expected policy
+type Validator struct {
+	Client    *http.Client
+	LookupTXT func(ctx context.Context, name string) ([]string, error)
+	LookupMX  func(ctx context.Context, name string) ([]*net.MX, error)
+
+	// PolicyURL returns the policy URL of a domain, for tests
+	PolicyURL func(domain string) string
+}
+
+// NewValidator creates a validator using the system resolver
+func NewValidator() *Validator {
+	return &Validator{
+		Client: &http.Client{
+			Timeout: 15 * time.Second,
+			// RFC 8461: senders must not follow redirects
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		LookupTXT: net.DefaultResolver.LookupTXT,
+		LookupMX:  net.DefaultResolver.LookupMX,
+	}
+}
+
+// PolicyURL returns the URL senders fetch the policy of a domain from
+func PolicyURL(domain string) string {
+	return "https://mta-sts." + strings.ToLower(domain) + WellKnownPath
+}
+
+// Validate checks the _mta-sts TXT record, the hosted policy file and that
+// the MX hosts of the domain are covered by the policy
+func (v *Validator) Validate(ctx context.Context, domain string, expected *Policy) []CheckResult {
+	results := []CheckResult{v.checkTXT(ctx, domain, expected)}
+
+	published, res := v.checkPolicy(ctx, domain, expected)
+	results = append(results, res)
+
+	// Check the MX hosts against what senders see, if they can fetch it
+	policy := expected
+	if published != nil {
+		policy = published
+	}
+	return append(results, v.checkMX(ctx, domain, policy))
+}
+
+func (v *Validator) checkTXT(ctx context.Context, domain string, expected *Policy) CheckResult {
+	result := CheckResult{Type: "MTA-STS Record"}
+
+	txts, err := v.LookupTXT(ctx, "_mta-sts."+domain)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			result.Status = StatusNotFound
+			result.Message = fmt.Sprintf("No MTA-STS record at _mta-sts.%s, publish %q", domain, expected.TXTRecord())
+			return result
+		}
+		result.Status = StatusError
+		result.Message = fmt.Sprintf("Lookup failed: %v", err)
+		return result
+	}
+
+	var records []string
+	for _, txt := range txts {
+		if strings.HasPrefix(txt, "v=STSv1") {
+			records = append(records, txt)
+		}
+	}
+	switch len(records) {
+	case 0:
+		result.Status = StatusNotFound
+		result.Message = fmt.Sprintf("No MTA-STS record at _mta-sts.%s, publish %q", domain, expected.TXTRecord())
+		return result
+	case 1:
+	default:
+		result.Status = StatusError
+		result.Value = strings.Join(records, " | ")
+		result.Message = "Several MTA-STS records, senders ignore all of them"
+		return result
+	}
+
+	result.Value = records[0]
+	id, err := ParseTXT(records[0])
+	if err != nil {
+		result.Status = StatusError
+		result.Message = err.Error()
+		return result
+	}
+	if id != expected.ID() {
+		result.Status = StatusWarning
+		result.Message = fmt.Sprintf("Policy id differs from %q, senders may keep a cached policy", expected.ID())
+		return result
+	}
+	result.Status = StatusOK
+	result.Message = "MTA-STS record matches the policy"
+	return result
+}
+
+func (v *Validator) checkPolicy(ctx context.Context, domain string, expected *Policy) (*Policy, CheckResult) {
+	url := PolicyURL(domain)
+	if v.PolicyURL != nil {
+		url = v.PolicyURL(domain)
+	}
+	result := CheckResult{Type: "MTA-STS Policy", Value: url}
+
+	text, err := v.fetch(ctx, url)
+	if err != nil {
+		result.Status = StatusError
+		result.Message = "Fetch failed: " + err.Error()
+		return nil, result
+	}
+	published, err := Parse(text)
+	if err != nil {
+		result.Status = StatusError
+		result.Message = "Invalid policy: " + err.Error()
+		return nil, result
+	}
+	if !published.Equal(expected) {
+		result.Status = StatusWarning
+		result.Message = fmt.Sprintf("Published policy differs: mode %s, mx %s, max_age %d",
+			published.Mode, strings.Join(published.MX, ", "), published.MaxAge)
+		return published, result
+	}
+	result.Status = StatusOK
+	result.Message = "Policy is served and matches"
+	return published, result
+}
+
+func (v *Validator) fetch(ctx context.Context, url string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := v.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != "text/plain" {
+		return "", fmt.Errorf("served as %q, senders require text/plain", resp.Header.Get("Content-Type"))
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPolicySize+1))
+	if err != nil {
+		return "", err
+	}
+	if len(body) > maxPolicySize {
+		return "", errors.New("policy file is larger than 64 KiB")
+	}
+	return string(body), nil
+}
+
+func (v *Validator) checkMX(ctx context.Context, domain string, policy *Policy) CheckResult {
+	result := CheckResult{Type: "MTA-STS MX Coverage"}
+
+	mxs, err := v.LookupMX(ctx, domain)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			result.Status = StatusNotFound
+			result.Message = "No MX records found"
+			return result
+		}
+		result.Status = StatusError
+		result.Message = fmt.Sprintf("Lookup failed: %v", err)
+		return result
+	}
+	if len(mxs) == 0 {
+		result.Status = StatusNotFound
+		result.Message = "No MX records found"
+		return result
+	}
+
+	var hosts, uncovered []string
+	for _, mx := range mxs {
+		host := strings.TrimSuffix(mx.Host, ".")
+		hosts = append(hosts, host)
+		if !policy.Matches(host) {
+			uncovered = append(uncovered, host)
+		}
+	}
+	result.Value = strings.Join(hosts, ", ")
+
+	switch {
+	case len(uncovered) == 0:
+		result.Status = StatusOK
+		result.Message = "All MX hosts are covered by the policy"
+	case policy.Mode == ModeEnforce:
+		result.Status = StatusError
+		result.Message = "Not in the policy, senders will not deliver to: " + strings.Join(uncovered, ", ")
+	default:
+		result.Status = StatusWarning
+		result.Message = "Not in the policy: " + strings.Join(uncovered, ", ")
+	}
+	return result
+}
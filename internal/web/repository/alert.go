@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/foxzi/sendry/internal/web/models"
+)
+
+type AlertRepository struct {
+	db *sql.DB
+}
+
+func NewAlertRepository(db *sql.DB) *AlertRepository {
+	return &AlertRepository{db: db}
+}
+
+const alertColumns = "id, rule, type, server, message, state, started_at, resolved_at, notified_at"
+
+// Open records a new firing alert
+func (r *AlertRepository) Open(a *models.Alert) error {
+	a.ID = uuid.New().String()
+	a.State = models.AlertFiring
+
+	_, err := r.db.Exec(`
+		INSERT INTO alerts (id, rule, type, server, message, state, started_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		a.ID, a.Rule, a.Type, a.Server, a.Message, a.State, a.StartedAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to open alert: %w", err)
+	}
+	return nil
+}
+
+// Update stores the current message of a firing alert
+func (r *AlertRepository) Update(id, message string) error {
+	_, err := r.db.Exec("UPDATE alerts SET message = ? WHERE id = ?", message, id)
+	return err
+}
+
+// Resolve marks an alert resolved
+func (r *AlertRepository) Resolve(id string, at time.Time) error {
+	_, err := r.db.Exec("UPDATE alerts SET state = ?, resolved_at = ? WHERE id = ?",
+		models.AlertResolved, at.UTC(), id)
+	return err
+}
+
+// SetNotified records that a notification of the alert was sent
+func (r *AlertRepository) SetNotified(id string, at time.Time) error {
+	_, err := r.db.Exec("UPDATE alerts SET notified_at = ? WHERE id = ?", at.UTC(), id)
+	return err
+}
+
+// Firing returns the firing alerts, oldest first
+func (r *AlertRepository) Firing() ([]models.Alert, error) {
+	return r.query(`SELECT `+alertColumns+` FROM alerts WHERE state = ? ORDER BY started_at`, models.AlertFiring)
+}
+
+// List returns the latest alerts, newest first
+func (r *AlertRepository) List(limit int) ([]models.Alert, error) {
+	return r.query(`SELECT `+alertColumns+` FROM alerts ORDER BY started_at DESC LIMIT ?`, limit)
+}
+
+func (r *AlertRepository) query(query string, args ...any) ([]models.Alert, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	alerts := []models.Alert{}
+	for rows.Next() {
+		var a models.Alert
+		var resolved, notified sql.NullTime
+		if err := rows.Scan(&a.ID, &a.Rule, &a.Type, &a.Server, &a.Message, &a.State, &a.StartedAt, &resolved, &notified); err != nil {
+			return nil, err
+		}
+		if resolved.Valid {
+			a.ResolvedAt = &resolved.Time
+		}
+		if notified.Valid {
+			a.NotifiedAt = &notified.Time
+		}
+		alerts = append(alerts, a)
+	}
+	return alerts, rows.Err()
+}
+
+// CreateSilence creates an alert silence
+func (r *AlertRepository) CreateSilence(s *models.AlertSilence) error {
+	s.ID = uuid.New().String()
+	s.CreatedAt = time.Now()
+
+	_, err := r.db.Exec(`
+		INSERT INTO alert_silences (id, rule, server, reason, starts_at, ends_at, created_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		s.ID, s.Rule, s.Server, s.Reason, s.StartsAt.UTC(), s.EndsAt.UTC(), s.CreatedBy, s.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create alert silence: %w", err)
+	}
+	return nil
+}
+
+// GetSilence returns an alert silence by ID
+func (r *AlertRepository) GetSilence(id string) (*models.AlertSilence, error) {
+	s := &models.AlertSilence{}
+	err := r.db.QueryRow(`
+		SELECT id, rule, server, COALESCE(reason, ''), starts_at, ends_at, COALESCE(created_by, ''), created_at
+		FROM alert_silences WHERE id = ?`, id,
+	).Scan(&s.ID, &s.Rule, &s.Server, &s.Reason, &s.StartsAt, &s.EndsAt, &s.CreatedBy, &s.CreatedAt)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// ListSilences returns the silences that end after since, ordered by start time
+func (r *AlertRepository) ListSilences(since time.Time) ([]models.AlertSilence, error) {
+	rows, err := r.db.Query(`
+		SELECT id, rule, server, COALESCE(reason, ''), starts_at, ends_at, COALESCE(created_by, ''), created_at
+		FROM alert_silences ORDER BY starts_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	silences := []models.AlertSilence{}
+	for rows.Next() {
+		var s models.AlertSilence
+		if err := rows.Scan(&s.ID, &s.Rule, &s.Server, &s.Reason, &s.StartsAt, &s.EndsAt, &s.CreatedBy, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		if !s.EndsAt.After(since) {
+			continue
+		}
+		silences = append(silences, s)
+	}
+	return silences, rows.Err()
+}
+
+// Silenced returns true if a silence covers the rule and server at the given time
+func (r *AlertRepository) Silenced(rule, server string, at time.Time) (bool, error) {
+	silences, err := r.ListSilences(at)
+	if err != nil {
+		return false, err
+	}
+	for i := range silences {
+		if silences[i].ActiveAt(at) && silences[i].Matches(rule, server) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// DeleteSilence deletes an alert silence
+func (r *AlertRepository) DeleteSilence(id string) error {
+	_, err := r.db.Exec("DELETE FROM alert_silences WHERE id = ?", id)
+	return err
+}
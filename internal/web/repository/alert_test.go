@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/foxzi/sendry/internal/web/models"
+)
+
+func TestAlertRepository(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewAlertRepository(db)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	a := &models.Alert{Rule: "dlq", Type: "dlq_size", Server: "mta-1", Message: "DLQ has 120 messages", StartedAt: now.Add(-time.Hour)}
+	if err := repo.Open(a); err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	b := &models.Alert{Rule: "down", Type: "server_down", Server: "mta-2", Message: "Server unreachable", StartedAt: now}
+	if err := repo.Open(b); err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	if err := repo.SetNotified(a.ID, now); err != nil {
+		t.Fatalf("SetNotified() error = %v", err)
+	}
+	if err := repo.Update(a.ID, "DLQ has 130 messages"); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if err := repo.Resolve(b.ID, now); err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+
+	firing, err := repo.Firing()
+	if err != nil || len(firing) != 1 {
+		t.Fatalf("Firing() = %d alerts, %v, want 1", len(firing), err)
+	}
+	if got := firing[0]; got.ID != a.ID || got.Message != "DLQ has 130 messages" || got.NotifiedAt == nil || got.ResolvedAt != nil {
+		t.Errorf("Firing()[0] = %+v", got)
+	}
+
+	list, err := repo.List(10)
+	if err != nil || len(list) != 2 {
+		t.Fatalf("List() = %d alerts, %v, want 2", len(list), err)
+	}
+	if list[0].ID != b.ID || list[0].State != models.AlertResolved || list[0].ResolvedAt == nil {
+		t.Errorf("List()[0] = %+v, want the resolved alert first", list[0])
+	}
+}
+
+func TestAlertRepository_Silences(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewAlertRepository(db)
+
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	silences := []*models.AlertSilence{
+		{Rule: "dlq", Server: "mta-1", Reason: "Reprocessing", StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour)},
+		{Server: "mta-2", Reason: "Maintenance", StartsAt: now.Add(2 * time.Hour), EndsAt: now.Add(4 * time.Hour)},
+		{Rule: "down", StartsAt: now.Add(-48 * time.Hour), EndsAt: now.Add(-24 * time.Hour)},
+	}
+	for _, s := range silences {
+		if err := repo.CreateSilence(s); err != nil {
+			t.Fatalf("CreateSilence() error = %v", err)
+		}
+	}
+
+	list, err := repo.ListSilences(now)
+	if err != nil || len(list) != 2 {
+		t.Fatalf("ListSilences() = %d silences, %v, want 2", len(list), err)
+	}
+
+	tests := []struct {
+		rule, server string
+		at           time.Time
+		want         bool
+	}{
+		{"dlq", "mta-1", now, true},
+		{"dlq", "mta-2", now, false},
+		{"down", "mta-1", now, false},
+		{"down", "mta-2", now.Add(3 * time.Hour), true},
+		{"down", "mta-1", now.Add(-36 * time.Hour), true},
+		{"dlq", "mta-1", now.Add(2 * time.Hour), false},
+	}
+	for _, tt := range tests {
+		got, err := repo.Silenced(tt.rule, tt.server, tt.at)
+		if err != nil {
+			t.Fatalf("Silenced() error = %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("Silenced(%s, %s, %s) = %v, want %v", tt.rule, tt.server, tt.at, got, tt.want)
+		}
+	}
+
+	if err := repo.DeleteSilence(silences[0].ID); err != nil {
+		t.Fatalf("DeleteSilence() error = %v", err)
+	}
+	if s, err := repo.GetSilence(silences[0].ID); err != nil || s != nil {
+		t.Errorf("GetSilence() after delete = %+v, %v", s, err)
+	}
+}
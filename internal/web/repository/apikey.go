@@ -25,6 +25,8 @@ func NewAPIKeyRepository(db *sql.DB) *APIKeyRepository {
 type APIKeyCreateOptions struct {
 	Name            string
 	CreatedBy       string
+	UserID          string // Owner of a personal token, empty for API keys
+	OrgID           string
 	Permissions     []string
 	AllowedDomains  []string
 	ExpiresAt       *time.Time
@@ -49,13 +51,7 @@ func (r *APIKeyRepository) Create(opts APIKeyCreateOptions) (*models.APIKeyCreat
 	keyPrefix := key[:11] // "sk_" + first 8 chars
 
 	// Serialize permissions
-	permJSON := `["send"]`
-	if len(opts.Permissions) > 0 {
-		permJSON = `["` + opts.Permissions[0] + `"]`
-		for _, p := range opts.Permissions[1:] {
-			permJSON = permJSON[:len(permJSON)-1] + `","` + p + `"]`
-		}
-	}
+	permJSON := permissionsJSON(opts.Permissions)
 
 	// Serialize allowed domains
 	domainsJSON := "[]"
@@ -74,17 +70,19 @@ func (r *APIKeyRepository) Create(opts APIKeyCreateOptions) (*models.APIKeyCreat
 		RateLimitMinute: opts.RateLimitMinute,
 		RateLimitHour:   opts.RateLimitHour,
 		CreatedBy:       opts.CreatedBy,
+		UserID:          opts.UserID,
+		OrgID:           opts.OrgID,
 		CreatedAt:       time.Now(),
 		ExpiresAt:       opts.ExpiresAt,
 		Active:          true,
 	}
 
 	_, err := r.db.Exec(`
-		INSERT INTO api_keys (id, name, key_hash, key_prefix, permissions, allowed_domains, rate_limit_minute, rate_limit_hour, created_by, created_at, expires_at, active)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		INSERT INTO api_keys (id, name, key_hash, key_prefix, permissions, allowed_domains, rate_limit_minute, rate_limit_hour, created_by, user_id, org_id, created_at, expires_at, active)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		apiKey.ID, apiKey.Name, apiKey.KeyHash, apiKey.KeyPrefix, apiKey.Permissions, domainsJSON,
 		apiKey.RateLimitMinute, apiKey.RateLimitHour,
-		apiKey.CreatedBy, apiKey.CreatedAt, apiKey.ExpiresAt, 1,
+		apiKey.CreatedBy, nullString(apiKey.UserID), nullString(apiKey.OrgID), apiKey.CreatedAt, apiKey.ExpiresAt, 1,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create API key: %w", err)
@@ -98,59 +96,32 @@ func (r *APIKeyRepository) Create(opts APIKeyCreateOptions) (*models.APIKeyCreat
 
 // GetByID returns an API key by ID
 func (r *APIKeyRepository) GetByID(id string) (*models.APIKey, error) {
-	k := &models.APIKey{}
-	var expiresAt, lastUsedAt sql.NullTime
-	var rateLimitMinute, rateLimitHour sql.NullInt64
-	var domainsJSON sql.NullString
-
-	err := r.db.QueryRow(`
-		SELECT id, name, key_hash, key_prefix, permissions, COALESCE(allowed_domains, '[]'),
-		       COALESCE(rate_limit_minute, 0), COALESCE(rate_limit_hour, 0),
-		       created_by, created_at, last_used_at, expires_at, active
-		FROM api_keys WHERE id = ?`, id,
-	).Scan(&k.ID, &k.Name, &k.KeyHash, &k.KeyPrefix, &k.Permissions, &domainsJSON,
-		&rateLimitMinute, &rateLimitHour,
-		&k.CreatedBy, &k.CreatedAt, &lastUsedAt, &expiresAt, &k.Active)
-
-	if err == sql.ErrNoRows {
-		return nil, nil
-	}
-	if err != nil {
-		return nil, err
-	}
-
-	k.RateLimitMinute = int(rateLimitMinute.Int64)
-	k.RateLimitHour = int(rateLimitHour.Int64)
-
-	if domainsJSON.Valid && domainsJSON.String != "" && domainsJSON.String != "[]" {
-		json.Unmarshal([]byte(domainsJSON.String), &k.AllowedDomains)
-	}
-
-	if expiresAt.Valid {
-		k.ExpiresAt = &expiresAt.Time
-	}
-	if lastUsedAt.Valid {
-		k.LastUsedAt = &lastUsedAt.Time
-	}
-
-	return k, nil
+	return r.getBy("k.id = ?", id)
 }
 
 // GetByHash returns an API key by its hash (for authentication)
 func (r *APIKeyRepository) GetByHash(keyHash string) (*models.APIKey, error) {
+	return r.getBy("k.key_hash = ?", keyHash)
+}
+
+func (r *APIKeyRepository) getBy(where string, arg any) (*models.APIKey, error) {
 	k := &models.APIKey{}
 	var expiresAt, lastUsedAt sql.NullTime
 	var rateLimitMinute, rateLimitHour sql.NullInt64
 	var domainsJSON sql.NullString
 
 	err := r.db.QueryRow(`
-		SELECT id, name, key_hash, key_prefix, permissions, COALESCE(allowed_domains, '[]'),
-		       COALESCE(rate_limit_minute, 0), COALESCE(rate_limit_hour, 0),
-		       created_by, created_at, last_used_at, expires_at, active
-		FROM api_keys WHERE key_hash = ?`, keyHash,
+		SELECT k.id, k.name, k.key_hash, k.key_prefix, k.permissions, COALESCE(k.allowed_domains, '[]'),
+		       COALESCE(k.rate_limit_minute, 0), COALESCE(k.rate_limit_hour, 0),
+		       k.created_by, k.created_at, k.last_used_at, k.expires_at, k.active,
+		       COALESCE(k.user_id, ''), COALESCE(u.email, ''), COALESCE(k.org_id, '')
+		FROM api_keys k
+		LEFT JOIN users u ON u.id = k.user_id
+		WHERE `+where, arg,
 	).Scan(&k.ID, &k.Name, &k.KeyHash, &k.KeyPrefix, &k.Permissions, &domainsJSON,
 		&rateLimitMinute, &rateLimitHour,
-		&k.CreatedBy, &k.CreatedAt, &lastUsedAt, &expiresAt, &k.Active)
+		&k.CreatedBy, &k.CreatedAt, &lastUsedAt, &expiresAt, &k.Active,
+		&k.UserID, &k.UserEmail, &k.OrgID)
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -178,13 +149,26 @@ func (r *APIKeyRepository) GetByHash(keyHash string) (*models.APIKey, error) {
 
 // List returns all API keys with optional filtering
 func (r *APIKeyRepository) List(filter models.APIKeyFilter) ([]models.APIKeyWithStats, int, error) {
-	countQuery := "SELECT COUNT(*) FROM api_keys WHERE 1=1"
+	countQuery := "SELECT COUNT(*) FROM api_keys k WHERE 1=1"
+	where := ""
 	args := []any{}
 
+	// Personal tokens of a user, or the API keys without an owner
+	if filter.UserID != "" {
+		where += " AND k.user_id = ?"
+		args = append(args, filter.UserID)
+	} else {
+		where += " AND k.user_id IS NULL"
+	}
+	if filter.OrgID != "" {
+		where += " AND k.org_id = ?"
+		args = append(args, filter.OrgID)
+	}
 	if filter.Search != "" {
-		countQuery += " AND name LIKE ?"
+		where += " AND k.name LIKE ?"
 		args = append(args, "%"+filter.Search+"%")
 	}
+	countQuery += where
 
 	var total int
 	if err := r.db.QueryRow(countQuery, args...).Scan(&total); err != nil {
@@ -202,11 +186,7 @@ func (r *APIKeyRepository) List(filter models.APIKeyFilter) ([]models.APIKeyWith
 			FROM sends
 			GROUP BY api_key_id
 		) s ON k.id = s.api_key_id
-		WHERE 1=1`
-
-	if filter.Search != "" {
-		query += " AND k.name LIKE ?"
-	}
+		WHERE 1=1` + where
 
 	query += " ORDER BY k.created_at DESC"
 
@@ -317,7 +297,7 @@ func (r *APIKeyRepository) Delete(id string) error {
 }
 
 // Update updates an API key's settings
-func (r *APIKeyRepository) Update(id string, name string, allowedDomains []string, rateLimitMinute, rateLimitHour int) error {
+func (r *APIKeyRepository) Update(id string, name string, permissions, allowedDomains []string, rateLimitMinute, rateLimitHour int) error {
 	domainsJSON := "[]"
 	if len(allowedDomains) > 0 {
 		domainsBytes, _ := json.Marshal(allowedDomains)
@@ -325,8 +305,8 @@ func (r *APIKeyRepository) Update(id string, name string, allowedDomains []strin
 	}
 
 	result, err := r.db.Exec(`
-		UPDATE api_keys SET name = ?, allowed_domains = ?, rate_limit_minute = ?, rate_limit_hour = ?
-		WHERE id = ?`, name, domainsJSON, rateLimitMinute, rateLimitHour, id)
+		UPDATE api_keys SET name = ?, permissions = ?, allowed_domains = ?, rate_limit_minute = ?, rate_limit_hour = ?
+		WHERE id = ?`, name, permissionsJSON(permissions), domainsJSON, rateLimitMinute, rateLimitHour, id)
 	if err != nil {
 		return err
 	}
@@ -338,6 +318,15 @@ func (r *APIKeyRepository) Update(id string, name string, allowedDomains []strin
 	return nil
 }
 
+// permissionsJSON serializes the permissions of an API key, send if none
+func permissionsJSON(permissions []string) string {
+	if len(permissions) == 0 {
+		permissions = []string{models.PermissionSend}
+	}
+	data, _ := json.Marshal(permissions)
+	return string(data)
+}
+
 // HashKey computes SHA256 hash of an API key
 func HashKey(key string) string {
 	hash := sha256.Sum256([]byte(key))
@@ -162,7 +162,7 @@ func TestAPIKeyRepository_Update(t *testing.T) {
 	}
 
 	// Update
-	err = repo.Update(result.ID, "Updated Name", []string{"new.com", "other.com"}, 20, 200)
+	err = repo.Update(result.ID, "Updated Name", []string{"send", "template"}, []string{"new.com", "other.com"}, 20, 200)
 	if err != nil {
 		t.Fatalf("failed to update API key: %v", err)
 	}
@@ -176,6 +176,9 @@ func TestAPIKeyRepository_Update(t *testing.T) {
 	if key.Name != "Updated Name" {
 		t.Errorf("expected name 'Updated Name', got '%s'", key.Name)
 	}
+	if !key.HasPermission("template") || key.HasPermission("domain") {
+		t.Errorf("unexpected permissions %s", key.Permissions)
+	}
 	if len(key.AllowedDomains) != 2 {
 		t.Errorf("expected 2 allowed domains, got %d", len(key.AllowedDomains))
 	}
@@ -24,9 +24,9 @@ func (r *CampaignRepository) Create(c *models.Campaign) error {
 	c.UpdatedAt = c.CreatedAt
 
 	_, err := r.db.Exec(`
-		INSERT INTO campaigns (id, name, description, from_email, from_name, reply_to, variables, tags, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		c.ID, c.Name, c.Description, c.FromEmail, c.FromName, c.ReplyTo, c.Variables, c.Tags, c.CreatedAt, c.UpdatedAt,
+		INSERT INTO campaigns (id, name, description, from_email, from_name, reply_to, variables, tags, org_id, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		c.ID, c.Name, c.Description, c.FromEmail, c.FromName, c.ReplyTo, c.Variables, c.Tags, nullString(c.OrgID), c.CreatedAt, c.UpdatedAt,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to create campaign: %w", err)
@@ -38,9 +38,9 @@ func (r *CampaignRepository) Create(c *models.Campaign) error {
 func (r *CampaignRepository) GetByID(id string) (*models.Campaign, error) {
 	c := &models.Campaign{}
 	err := r.db.QueryRow(`
-		SELECT id, name, description, from_email, from_name, reply_to, variables, tags, created_at, updated_at
+		SELECT id, name, description, from_email, from_name, reply_to, variables, tags, COALESCE(org_id, ''), created_at, updated_at
 		FROM campaigns WHERE id = ?`, id,
-	).Scan(&c.ID, &c.Name, &c.Description, &c.FromEmail, &c.FromName, &c.ReplyTo, &c.Variables, &c.Tags, &c.CreatedAt, &c.UpdatedAt)
+	).Scan(&c.ID, &c.Name, &c.Description, &c.FromEmail, &c.FromName, &c.ReplyTo, &c.Variables, &c.Tags, &c.OrgID, &c.CreatedAt, &c.UpdatedAt)
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -57,6 +57,10 @@ func (r *CampaignRepository) List(filter models.CampaignListFilter) ([]models.Ca
 	countQuery := "SELECT COUNT(*) FROM campaigns WHERE 1=1"
 	args := []any{}
 
+	if filter.OrgID != "" {
+		countQuery += " AND org_id = ?"
+		args = append(args, filter.OrgID)
+	}
 	if filter.Search != "" {
 		countQuery += " AND (name LIKE ? OR description LIKE ?)"
 		args = append(args, "%"+filter.Search+"%", "%"+filter.Search+"%")
@@ -69,13 +73,17 @@ func (r *CampaignRepository) List(filter models.CampaignListFilter) ([]models.Ca
 
 	// Get campaigns with stats
 	query := `
-		SELECT c.id, c.name, c.description, c.from_email, c.from_name, c.reply_to, c.variables, c.tags, c.created_at, c.updated_at,
+		SELECT c.id, c.name, c.description, c.from_email, c.from_name, c.reply_to, c.variables, c.tags, COALESCE(c.org_id, ''), c.created_at, c.updated_at,
 			COALESCE((SELECT COUNT(*) FROM campaign_variants WHERE campaign_id = c.id), 0) as variant_count,
 			COALESCE((SELECT COUNT(*) FROM send_jobs WHERE campaign_id = c.id), 0) as job_count
 		FROM campaigns c
 		WHERE 1=1`
 
 	args = []any{}
+	if filter.OrgID != "" {
+		query += " AND c.org_id = ?"
+		args = append(args, filter.OrgID)
+	}
 	if filter.Search != "" {
 		query += " AND (c.name LIKE ? OR c.description LIKE ?)"
 		args = append(args, "%"+filter.Search+"%", "%"+filter.Search+"%")
@@ -103,7 +111,7 @@ func (r *CampaignRepository) List(filter models.CampaignListFilter) ([]models.Ca
 		var c models.CampaignWithStats
 		err := rows.Scan(
 			&c.ID, &c.Name, &c.Description, &c.FromEmail, &c.FromName, &c.ReplyTo,
-			&c.Variables, &c.Tags, &c.CreatedAt, &c.UpdatedAt,
+			&c.Variables, &c.Tags, &c.OrgID, &c.CreatedAt, &c.UpdatedAt,
 			&c.VariantCount, &c.JobCount,
 		)
 		if err != nil {
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/foxzi/sendry/internal/web/models"
+)
+
+type DeploymentRepository struct {
+	db *sql.DB
+}
+
+func NewDeploymentRepository(db *sql.DB) *DeploymentRepository {
+	return &DeploymentRepository{db: db}
+}
+
+// AddEvent records the outcome of a deployment to one server
+func (r *DeploymentRepository) AddEvent(e *models.DeploymentEvent) error {
+	e.CreatedAt = time.Now()
+	result, err := r.db.Exec(`
+		INSERT INTO deployment_events (correlation_id, entity_type, entity_id, entity_name, server_name, status, error, user_email, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.CorrelationID, e.EntityType, e.EntityID, e.EntityName, e.ServerName, e.Status, e.Error, e.UserEmail, e.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	e.ID, _ = result.LastInsertId()
+	return nil
+}
+
+// GetEvents returns all events of a deploy action in the order they happened
+func (r *DeploymentRepository) GetEvents(correlationID string) ([]models.DeploymentEvent, error) {
+	rows, err := r.db.Query(`
+		SELECT id, correlation_id, entity_type, entity_id, COALESCE(entity_name, ''), server_name,
+			status, COALESCE(error, ''), COALESCE(user_email, ''), created_at
+		FROM deployment_events WHERE correlation_id = ? ORDER BY id`, correlationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.DeploymentEvent
+	for rows.Next() {
+		var e models.DeploymentEvent
+		if err := rows.Scan(&e.ID, &e.CorrelationID, &e.EntityType, &e.EntityID, &e.EntityName, &e.ServerName,
+			&e.Status, &e.Error, &e.UserEmail, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// ListRuns returns deploy actions, newest first
+func (r *DeploymentRepository) ListRuns(filter models.DeploymentFilter) ([]models.DeploymentRun, int, error) {
+	where := ""
+	args := []any{}
+	if filter.EntityType != "" {
+		where += " AND entity_type = ?"
+		args = append(args, filter.EntityType)
+	}
+	if filter.EntityID != "" {
+		where += " AND entity_id = ?"
+		args = append(args, filter.EntityID)
+	}
+
+	var total int
+	if err := r.db.QueryRow("SELECT COUNT(DISTINCT correlation_id) FROM deployment_events WHERE 1=1"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	// The first event of each run describes it
+	query := `
+		SELECT e.correlation_id, e.entity_type, e.entity_id, COALESCE(e.entity_name, ''), COALESCE(e.user_email, ''), e.created_at,
+			(SELECT COUNT(DISTINCT x.server_name) FROM deployment_events x WHERE x.correlation_id = e.correlation_id),
+			(SELECT COUNT(*) FROM deployment_events x WHERE x.correlation_id = e.correlation_id AND x.status = 'failed')
+		FROM deployment_events e
+		WHERE e.id IN (SELECT MIN(id) FROM deployment_events GROUP BY correlation_id)` + where + `
+		ORDER BY e.id DESC`
+
+	if filter.Limit > 0 {
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, filter.Limit, filter.Offset)
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	runs := []models.DeploymentRun{}
+	for rows.Next() {
+		var run models.DeploymentRun
+		if err := rows.Scan(&run.CorrelationID, &run.EntityType, &run.EntityID, &run.EntityName, &run.UserEmail,
+			&run.StartedAt, &run.Servers, &run.Failed); err != nil {
+			return nil, 0, err
+		}
+		runs = append(runs, run)
+	}
+
+	return runs, total, rows.Err()
+}
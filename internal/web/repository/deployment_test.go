@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/foxzi/sendry/internal/web/models"
+)
+
+func TestDeploymentRepository_Events(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewDeploymentRepository(db)
+
+	events := []*models.DeploymentEvent{
+		{CorrelationID: "run-1", EntityType: "domain", EntityID: "d1", EntityName: "example.com", ServerName: "mta1", Status: "deployed", UserEmail: "admin@example.com"},
+		{CorrelationID: "run-1", EntityType: "domain", EntityID: "d1", EntityName: "example.com", ServerName: "mta2", Status: "failed", Error: "API error: connection refused", UserEmail: "admin@example.com"},
+		{CorrelationID: "run-2", EntityType: "template", EntityID: "t1", EntityName: "Welcome", ServerName: "mta1", Status: "deployed"},
+	}
+	for _, e := range events {
+		if err := repo.AddEvent(e); err != nil {
+			t.Fatalf("AddEvent() error = %v", err)
+		}
+		if e.ID == 0 {
+			t.Error("AddEvent() did not set ID")
+		}
+	}
+
+	got, err := repo.GetEvents("run-1")
+	if err != nil {
+		t.Fatalf("GetEvents() error = %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("GetEvents() returned %d events, want 2", len(got))
+	}
+	if got[0].ServerName != "mta1" || got[1].Error != "API error: connection refused" {
+		t.Errorf("GetEvents() = %+v", got)
+	}
+
+	none, err := repo.GetEvents("missing")
+	if err != nil || len(none) != 0 {
+		t.Errorf("GetEvents(missing) = %v, %v", none, err)
+	}
+}
+
+func TestDeploymentRepository_ListRuns(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewDeploymentRepository(db)
+
+	repo.AddEvent(&models.DeploymentEvent{CorrelationID: "run-1", EntityType: "domain", EntityID: "d1", ServerName: "mta1", Status: "deployed"})
+	repo.AddEvent(&models.DeploymentEvent{CorrelationID: "run-1", EntityType: "domain", EntityID: "d1", ServerName: "mta2", Status: "failed"})
+	repo.AddEvent(&models.DeploymentEvent{CorrelationID: "run-2", EntityType: "dkim", EntityID: "k1", ServerName: "mta1", Status: "deployed"})
+	repo.AddEvent(&models.DeploymentEvent{CorrelationID: "run-3", EntityType: "domain", EntityID: "d1", ServerName: "mta1", Status: "deployed"})
+
+	runs, total, err := repo.ListRuns(models.DeploymentFilter{})
+	if err != nil {
+		t.Fatalf("ListRuns() error = %v", err)
+	}
+	if total != 3 || len(runs) != 3 {
+		t.Fatalf("ListRuns() = %d runs, total %d, want 3", len(runs), total)
+	}
+	if runs[0].CorrelationID != "run-3" {
+		t.Errorf("ListRuns() first = %s, want newest run-3", runs[0].CorrelationID)
+	}
+	if runs[2].Servers != 2 || runs[2].Failed != 1 {
+		t.Errorf("run-1 servers = %d failed = %d, want 2 and 1", runs[2].Servers, runs[2].Failed)
+	}
+
+	runs, total, err = repo.ListRuns(models.DeploymentFilter{EntityType: "domain", EntityID: "d1", Limit: 1})
+	if err != nil {
+		t.Fatalf("ListRuns() error = %v", err)
+	}
+	if total != 2 || len(runs) != 1 || runs[0].CorrelationID != "run-3" {
+		t.Errorf("ListRuns(domain d1) = %+v, total %d", runs, total)
+	}
+}
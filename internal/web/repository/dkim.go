@@ -24,9 +24,9 @@ func (r *DKIMRepository) Create(key *models.DKIMKey) error {
 	key.UpdatedAt = key.CreatedAt
 
 	_, err := r.db.Exec(`
-		INSERT INTO dkim_keys (id, domain, selector, private_key, dns_record, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?)`,
-		key.ID, key.Domain, key.Selector, key.PrivateKey, key.DNSRecord, key.CreatedAt, key.UpdatedAt,
+		INSERT INTO dkim_keys (id, domain, selector, private_key, dns_record, org_id, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		key.ID, key.Domain, key.Selector, key.PrivateKey, key.DNSRecord, nullString(key.OrgID), key.CreatedAt, key.UpdatedAt,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to create DKIM key: %w", err)
@@ -38,9 +38,9 @@ func (r *DKIMRepository) Create(key *models.DKIMKey) error {
 func (r *DKIMRepository) GetByID(id string) (*models.DKIMKey, error) {
 	key := &models.DKIMKey{}
 	err := r.db.QueryRow(`
-		SELECT id, domain, selector, private_key, dns_record, created_at, updated_at
+		SELECT id, domain, selector, private_key, dns_record, COALESCE(org_id, ''), created_at, updated_at
 		FROM dkim_keys WHERE id = ?`, id,
-	).Scan(&key.ID, &key.Domain, &key.Selector, &key.PrivateKey, &key.DNSRecord, &key.CreatedAt, &key.UpdatedAt)
+	).Scan(&key.ID, &key.Domain, &key.Selector, &key.PrivateKey, &key.DNSRecord, &key.OrgID, &key.CreatedAt, &key.UpdatedAt)
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -63,9 +63,9 @@ func (r *DKIMRepository) GetByID(id string) (*models.DKIMKey, error) {
 func (r *DKIMRepository) GetByDomainSelector(domain, selector string) (*models.DKIMKey, error) {
 	key := &models.DKIMKey{}
 	err := r.db.QueryRow(`
-		SELECT id, domain, selector, private_key, dns_record, created_at, updated_at
+		SELECT id, domain, selector, private_key, dns_record, COALESCE(org_id, ''), created_at, updated_at
 		FROM dkim_keys WHERE domain = ? AND selector = ?`, domain, selector,
-	).Scan(&key.ID, &key.Domain, &key.Selector, &key.PrivateKey, &key.DNSRecord, &key.CreatedAt, &key.UpdatedAt)
+	).Scan(&key.ID, &key.Domain, &key.Selector, &key.PrivateKey, &key.DNSRecord, &key.OrgID, &key.CreatedAt, &key.UpdatedAt)
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -76,15 +76,17 @@ func (r *DKIMRepository) GetByDomainSelector(domain, selector string) (*models.D
 	return key, nil
 }
 
-// List returns all DKIM keys with deployment counts
-func (r *DKIMRepository) List() ([]models.DKIMKeyListItem, error) {
+// List returns the DKIM keys of an organization, or all keys if orgID is
+// empty, with deployment counts
+func (r *DKIMRepository) List(orgID string) ([]models.DKIMKeyListItem, error) {
 	rows, err := r.db.Query(`
 		SELECT k.id, k.domain, k.selector, k.dns_record, k.created_at,
 			COUNT(d.id) as deployment_count
 		FROM dkim_keys k
 		LEFT JOIN dkim_deployments d ON k.id = d.dkim_key_id
+		WHERE ? = '' OR k.org_id = ?
 		GROUP BY k.id
-		ORDER BY k.domain, k.selector`)
+		ORDER BY k.domain, k.selector`, orgID, orgID)
 	if err != nil {
 		return nil, err
 	}
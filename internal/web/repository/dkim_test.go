@@ -160,7 +160,7 @@ func TestDKIMRepository_List(t *testing.T) {
 		}
 	}
 
-	list, err := repo.List()
+	list, err := repo.List("")
 	if err != nil {
 		t.Fatalf("List() error = %v", err)
 	}
@@ -372,7 +372,7 @@ func TestDKIMRepository_ListWithDeploymentCount(t *testing.T) {
 	repo.CreateDeployment(key1.ID, "server1", "deployed", "")
 	repo.CreateDeployment(key1.ID, "server2", "deployed", "")
 
-	list, err := repo.List()
+	list, err := repo.List("")
 	if err != nil {
 		t.Fatalf("List() error = %v", err)
 	}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/foxzi/sendry/internal/web/models"
+)
+
+type DNSRecordRepository struct {
+	db *sql.DB
+}
+
+func NewDNSRecordRepository(db *sql.DB) *DNSRecordRepository {
+	return &DNSRecordRepository{db: db}
+}
+
+const dnsRecordColumns = "id, domain_id, kind, type, name, value, provider, status, COALESCE(error, ''), published_at, checked_at, verified_at"
+
+// Upsert stores the record of a domain, replacing the previous record of
+// the same kind
+func (r *DNSRecordRepository) Upsert(rec *models.DNSRecord) error {
+	err := r.db.QueryRow(`
+		INSERT INTO dns_records (domain_id, kind, type, name, value, provider, status, error, published_at, checked_at, verified_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(domain_id, kind) DO UPDATE SET
+			type = excluded.type,
+			name = excluded.name,
+			value = excluded.value,
+			provider = excluded.provider,
+			status = excluded.status,
+			error = excluded.error,
+			published_at = excluded.published_at,
+			checked_at = excluded.checked_at,
+			verified_at = excluded.verified_at
+		RETURNING id`,
+		rec.DomainID, rec.Kind, rec.Type, rec.Name, rec.Value, rec.Provider, rec.Status, rec.Error,
+		utcPtr(rec.PublishedAt), utcPtr(rec.CheckedAt), utcPtr(rec.VerifiedAt),
+	).Scan(&rec.ID)
+	if err != nil {
+		return fmt.Errorf("failed to save dns record: %w", err)
+	}
+	return nil
+}
+
+// SetStatus stores the result of a propagation check
+func (r *DNSRecordRepository) SetStatus(id int64, status, errMsg string, checkedAt time.Time, verifiedAt *time.Time) error {
+	_, err := r.db.Exec("UPDATE dns_records SET status = ?, error = ?, checked_at = ?, verified_at = ? WHERE id = ?",
+		status, errMsg, checkedAt.UTC(), utcPtr(verifiedAt), id)
+	return err
+}
+
+// ListByDomain returns the records of a domain
+func (r *DNSRecordRepository) ListByDomain(domainID string) ([]models.DNSRecord, error) {
+	return r.query(`SELECT `+dnsRecordColumns+` FROM dns_records WHERE domain_id = ? ORDER BY kind`, domainID)
+}
+
+// ListByStatus returns the records in a state, oldest publication first
+func (r *DNSRecordRepository) ListByStatus(status string) ([]models.DNSRecord, error) {
+	return r.query(`SELECT `+dnsRecordColumns+` FROM dns_records WHERE status = ? ORDER BY published_at`, status)
+}
+
+func (r *DNSRecordRepository) query(query string, args ...any) ([]models.DNSRecord, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []models.DNSRecord{}
+	for rows.Next() {
+		var rec models.DNSRecord
+		var published, checked, verified sql.NullTime
+		if err := rows.Scan(&rec.ID, &rec.DomainID, &rec.Kind, &rec.Type, &rec.Name, &rec.Value, &rec.Provider,
+			&rec.Status, &rec.Error, &published, &checked, &verified); err != nil {
+			return nil, err
+		}
+		if published.Valid {
+			rec.PublishedAt = &published.Time
+		}
+		if checked.Valid {
+			rec.CheckedAt = &checked.Time
+		}
+		if verified.Valid {
+			rec.VerifiedAt = &verified.Time
+		}
+		records = append(records, rec)
+	}
+	return records, rows.Err()
+}
+
+func utcPtr(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
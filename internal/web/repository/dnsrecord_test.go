@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/foxzi/sendry/internal/web/models"
+)
+
+func TestDNSRecordRepository(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewDNSRecordRepository(db)
+
+	if _, err := db.Exec(`INSERT INTO domains (id, domain) VALUES ('d1', 'example.com')`); err != nil {
+		t.Fatalf("insert domain: %v", err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	spf := &models.DNSRecord{DomainID: "d1", Kind: "SPF", Type: "TXT", Name: "example.com", Value: "v=spf1 -all",
+		Provider: "cloudflare", Status: models.DNSRecordPublished, PublishedAt: &now}
+	if err := repo.Upsert(spf); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	dmarc := &models.DNSRecord{DomainID: "d1", Kind: "DMARC", Type: "TXT", Name: "_dmarc.example.com", Value: "v=DMARC1; p=none",
+		Provider: "cloudflare", Status: models.DNSRecordFailed, Error: "zone not found", PublishedAt: &now}
+	if err := repo.Upsert(dmarc); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+
+	// Publishing again replaces the record of the same kind
+	republished := &models.DNSRecord{DomainID: "d1", Kind: "SPF", Type: "TXT", Name: "example.com", Value: "v=spf1 a mx ~all",
+		Provider: "cloudflare", Status: models.DNSRecordPublished, PublishedAt: &now}
+	if err := repo.Upsert(republished); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	if republished.ID != spf.ID {
+		t.Errorf("Upsert() id = %d, want %d", republished.ID, spf.ID)
+	}
+
+	pending, err := repo.ListByStatus(models.DNSRecordPublished)
+	if err != nil || len(pending) != 1 || pending[0].Value != "v=spf1 a mx ~all" {
+		t.Fatalf("ListByStatus() = %+v, %v", pending, err)
+	}
+
+	if err := repo.SetStatus(spf.ID, models.DNSRecordPropagated, "", now, &now); err != nil {
+		t.Fatalf("SetStatus() error = %v", err)
+	}
+
+	records, err := repo.ListByDomain("d1")
+	if err != nil || len(records) != 2 {
+		t.Fatalf("ListByDomain() = %d records, %v, want 2", len(records), err)
+	}
+	if records[0].Kind != "DMARC" || records[0].Error != "zone not found" {
+		t.Errorf("ListByDomain()[0] = %+v", records[0])
+	}
+	if records[1].Status != models.DNSRecordPropagated || records[1].VerifiedAt == nil || records[1].CheckedAt == nil {
+		t.Errorf("ListByDomain()[1] = %+v", records[1])
+	}
+	if models.DNSRecordsReady(records) {
+		t.Error("DNSRecordsReady() = true with a failed record")
+	}
+
+	// Records are deleted with the domain
+	if _, err := db.Exec(`DELETE FROM domains WHERE id = 'd1'`); err != nil {
+		t.Fatalf("delete domain: %v", err)
+	}
+	if records, _ := repo.ListByDomain("d1"); len(records) != 0 {
+		t.Errorf("ListByDomain() after delete = %d records, want 0", len(records))
+	}
+}
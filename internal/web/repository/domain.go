@@ -29,12 +29,12 @@ func (r *DomainRepository) Create(domain *models.Domain) error {
 
 	_, err := r.db.Exec(`
 		INSERT INTO domains (id, domain, mode, default_from, dkim_enabled, dkim_selector, dkim_key_id,
-			rate_limit_hour, rate_limit_day, rate_limit_recipients, redirect_to, bcc_to, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			rate_limit_hour, rate_limit_day, rate_limit_recipients, redirect_to, bcc_to, org_id, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		domain.ID, domain.Domain, domain.Mode, domain.DefaultFrom,
 		domain.DKIMEnabled, domain.DKIMSelector, nullString(domain.DKIMKeyID),
 		domain.RateLimitHour, domain.RateLimitDay, domain.RateLimitRecipients,
-		string(redirectJSON), string(bccJSON), domain.CreatedAt, domain.UpdatedAt,
+		string(redirectJSON), string(bccJSON), nullString(domain.OrgID), domain.CreatedAt, domain.UpdatedAt,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to create domain: %w", err)
@@ -50,12 +50,12 @@ func (r *DomainRepository) GetByID(id string) (*models.Domain, error) {
 
 	err := r.db.QueryRow(`
 		SELECT id, domain, mode, COALESCE(default_from, ''), dkim_enabled, COALESCE(dkim_selector, ''), dkim_key_id,
-			rate_limit_hour, rate_limit_day, rate_limit_recipients, redirect_to, bcc_to, created_at, updated_at
+			rate_limit_hour, rate_limit_day, rate_limit_recipients, redirect_to, bcc_to, COALESCE(org_id, ''), created_at, updated_at
 		FROM domains WHERE id = ?`, id,
 	).Scan(&domain.ID, &domain.Domain, &domain.Mode, &domain.DefaultFrom,
 		&domain.DKIMEnabled, &domain.DKIMSelector, &dkimKeyID,
 		&domain.RateLimitHour, &domain.RateLimitDay, &domain.RateLimitRecipients,
-		&redirectJSON, &bccJSON, &domain.CreatedAt, &domain.UpdatedAt)
+		&redirectJSON, &bccJSON, &domain.OrgID, &domain.CreatedAt, &domain.UpdatedAt)
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -93,12 +93,12 @@ func (r *DomainRepository) GetByDomain(domainName string) (*models.Domain, error
 
 	err := r.db.QueryRow(`
 		SELECT id, domain, mode, COALESCE(default_from, ''), dkim_enabled, COALESCE(dkim_selector, ''), dkim_key_id,
-			rate_limit_hour, rate_limit_day, rate_limit_recipients, redirect_to, bcc_to, created_at, updated_at
+			rate_limit_hour, rate_limit_day, rate_limit_recipients, redirect_to, bcc_to, COALESCE(org_id, ''), created_at, updated_at
 		FROM domains WHERE domain = ?`, domainName,
 	).Scan(&domain.ID, &domain.Domain, &domain.Mode, &domain.DefaultFrom,
 		&domain.DKIMEnabled, &domain.DKIMSelector, &dkimKeyID,
 		&domain.RateLimitHour, &domain.RateLimitDay, &domain.RateLimitRecipients,
-		&redirectJSON, &bccJSON, &domain.CreatedAt, &domain.UpdatedAt)
+		&redirectJSON, &bccJSON, &domain.OrgID, &domain.CreatedAt, &domain.UpdatedAt)
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -140,6 +140,10 @@ func (r *DomainRepository) List(filter models.DomainFilter) ([]models.DomainList
 
 	args := []interface{}{}
 
+	if filter.OrgID != "" {
+		query += " AND d.org_id = ?"
+		args = append(args, filter.OrgID)
+	}
 	if filter.Search != "" {
 		query += " AND d.domain LIKE ?"
 		args = append(args, "%"+filter.Search+"%")
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/foxzi/sendry/internal/web/models"
+)
+
+type FreezeRepository struct {
+	db *sql.DB
+}
+
+func NewFreezeRepository(db *sql.DB) *FreezeRepository {
+	return &FreezeRepository{db: db}
+}
+
+// Create creates a new freeze window
+func (r *FreezeRepository) Create(f *models.FreezeWindow) error {
+	f.ID = uuid.New().String()
+	f.Domain = strings.ToLower(strings.TrimSpace(f.Domain))
+	f.CreatedAt = time.Now()
+
+	_, err := r.db.Exec(`
+		INSERT INTO freeze_windows (id, name, domain, reason, starts_at, ends_at, created_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		f.ID, f.Name, f.Domain, f.Reason, f.StartsAt.UTC(), f.EndsAt.UTC(), f.CreatedBy, f.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create freeze window: %w", err)
+	}
+	return nil
+}
+
+// GetByID returns a freeze window by ID
+func (r *FreezeRepository) GetByID(id string) (*models.FreezeWindow, error) {
+	f := &models.FreezeWindow{}
+	err := r.db.QueryRow(`
+		SELECT id, name, domain, COALESCE(reason, ''), starts_at, ends_at, COALESCE(created_by, ''), created_at
+		FROM freeze_windows WHERE id = ?`, id,
+	).Scan(&f.ID, &f.Name, &f.Domain, &f.Reason, &f.StartsAt, &f.EndsAt, &f.CreatedBy, &f.CreatedAt)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// List returns freeze windows ordered by start time. Windows that ended
+// before since are skipped unless since is zero.
+func (r *FreezeRepository) List(since time.Time) ([]models.FreezeWindow, error) {
+	rows, err := r.db.Query(`
+		SELECT id, name, domain, COALESCE(reason, ''), starts_at, ends_at, COALESCE(created_by, ''), created_at
+		FROM freeze_windows ORDER BY starts_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	windows := []models.FreezeWindow{}
+	for rows.Next() {
+		var f models.FreezeWindow
+		if err := rows.Scan(&f.ID, &f.Name, &f.Domain, &f.Reason, &f.StartsAt, &f.EndsAt, &f.CreatedBy, &f.CreatedAt); err != nil {
+			return nil, err
+		}
+		if !since.IsZero() && !f.EndsAt.After(since) {
+			continue
+		}
+		windows = append(windows, f)
+	}
+	return windows, rows.Err()
+}
+
+// Active returns the freeze window covering the sender domain at the given
+// time, or nil if sending is not frozen. Of several matching windows the
+// one ending last is returned.
+func (r *FreezeRepository) Active(domain string, at time.Time) (*models.FreezeWindow, error) {
+	windows, err := r.List(at)
+	if err != nil {
+		return nil, err
+	}
+
+	var active *models.FreezeWindow
+	for i := range windows {
+		f := &windows[i]
+		if !f.ActiveAt(at) || !f.Covers(domain) {
+			continue
+		}
+		if active == nil || f.EndsAt.After(active.EndsAt) {
+			active = f
+		}
+	}
+	return active, nil
+}
+
+// Delete deletes a freeze window
+func (r *FreezeRepository) Delete(id string) error {
+	_, err := r.db.Exec("DELETE FROM freeze_windows WHERE id = ?", id)
+	return err
+}
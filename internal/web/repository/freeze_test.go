@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/foxzi/sendry/internal/web/models"
+)
+
+func TestFreezeRepository_Active(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewFreezeRepository(db)
+
+	now := time.Date(2026, 11, 27, 12, 0, 0, 0, time.UTC)
+	windows := []*models.FreezeWindow{
+		{Name: "Black Friday", StartsAt: now.Add(-time.Hour), EndsAt: now.Add(48 * time.Hour)},
+		{Name: "Legal quiet period", Domain: " Example.COM ", StartsAt: now.Add(-time.Hour), EndsAt: now.Add(96 * time.Hour)},
+		{Name: "Past", Domain: "other.com", StartsAt: now.Add(-48 * time.Hour), EndsAt: now.Add(-24 * time.Hour)},
+		{Name: "Upcoming", Domain: "other.com", StartsAt: now.Add(24 * time.Hour), EndsAt: now.Add(72 * time.Hour)},
+	}
+	for _, f := range windows {
+		if err := repo.Create(f); err != nil {
+			t.Fatalf("Create(%s) error = %v", f.Name, err)
+		}
+	}
+
+	if got := windows[1].Domain; got != "example.com" {
+		t.Errorf("Create() domain = %q, want normalized example.com", got)
+	}
+
+	tests := []struct {
+		domain string
+		at     time.Time
+		want   string
+	}{
+		{"example.com", now, "Legal quiet period"},
+		{"other.com", now, "Black Friday"},
+		{"other.com", now.Add(60 * time.Hour), "Upcoming"},
+		{"example.com", now.Add(100 * time.Hour), ""},
+		{"other.com", now.Add(-36 * time.Hour), "Past"},
+	}
+	for _, tt := range tests {
+		got, err := repo.Active(tt.domain, tt.at)
+		if err != nil {
+			t.Fatalf("Active() error = %v", err)
+		}
+		name := ""
+		if got != nil {
+			name = got.Name
+		}
+		if name != tt.want {
+			t.Errorf("Active(%s, %s) = %q, want %q", tt.domain, tt.at, name, tt.want)
+		}
+	}
+
+	// List skips windows that have ended
+	list, err := repo.List(now)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(list) != 3 {
+		t.Errorf("List() returned %d windows, want 3", len(list))
+	}
+	all, _ := repo.List(time.Time{})
+	if len(all) != 4 {
+		t.Errorf("List(zero) returned %d windows, want 4", len(all))
+	}
+
+	if err := repo.Delete(windows[0].ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if got, _ := repo.GetByID(windows[0].ID); got != nil {
+		t.Error("GetByID() after delete should return nil")
+	}
+	if got, _ := repo.Active("other.com", now); got != nil {
+		t.Errorf("Active() after delete = %q, want nil", got.Name)
+	}
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/foxzi/sendry/internal/web/models"
+)
+
+type HealthRepository struct {
+	db *sql.DB
+}
+
+func NewHealthRepository(db *sql.DB) *HealthRepository {
+	return &HealthRepository{db: db}
+}
+
+const healthColumns = `server, online, error, queue_pending, queue_retrying, dlq_size, oldest_queued,
+	rate_limit_usage, sent_total, failed_total, bounced_total, cert_domain, cert_expires_at, warnings, sampled_at`
+
+// Record stores a server health sample
+func (r *HealthRepository) Record(h *models.ServerHealth) error {
+	warnings, _ := json.Marshal(h.Warnings)
+	var certExpires any
+	if h.CertExpiresAt != nil {
+		certExpires = h.CertExpiresAt.UTC()
+	}
+
+	_, err := r.db.Exec(`INSERT INTO server_health_samples (`+healthColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		h.Server, h.Online, h.Error, h.QueuePending, h.QueueRetrying, h.DLQSize, h.OldestQueued,
+		h.RateLimitUsage, h.SentTotal, h.FailedTotal, h.BouncedTotal, h.CertDomain, certExpires,
+		string(warnings), h.SampledAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record server health: %w", err)
+	}
+	return nil
+}
+
+// List returns the samples of a server taken since the given time, oldest first
+func (r *HealthRepository) List(server string, since time.Time) ([]models.ServerHealth, error) {
+	rows, err := r.db.Query(`SELECT `+healthColumns+` FROM server_health_samples
+		WHERE server = ? AND sampled_at >= ? ORDER BY sampled_at`, server, since.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	samples := []models.ServerHealth{}
+	for rows.Next() {
+		h, err := scanHealth(rows)
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, *h)
+	}
+	return samples, rows.Err()
+}
+
+// Latest returns the last sample of each server by server name
+func (r *HealthRepository) Latest() (map[string]*models.ServerHealth, error) {
+	rows, err := r.db.Query(`SELECT ` + healthColumns + ` FROM server_health_samples s
+		WHERE id = (SELECT MAX(id) FROM server_health_samples WHERE server = s.server)`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	latest := make(map[string]*models.ServerHealth)
+	for rows.Next() {
+		h, err := scanHealth(rows)
+		if err != nil {
+			return nil, err
+		}
+		latest[h.Server] = h
+	}
+	return latest, rows.Err()
+}
+
+// Prune deletes the samples taken before the given time
+func (r *HealthRepository) Prune(before time.Time) (int64, error) {
+	result, err := r.db.Exec("DELETE FROM server_health_samples WHERE sampled_at < ?", before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune server health: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+func scanHealth(rows *sql.Rows) (*models.ServerHealth, error) {
+	var h models.ServerHealth
+	var certExpires sql.NullTime
+	var warnings sql.NullString
+	err := rows.Scan(&h.Server, &h.Online, &h.Error, &h.QueuePending, &h.QueueRetrying, &h.DLQSize, &h.OldestQueued,
+		&h.RateLimitUsage, &h.SentTotal, &h.FailedTotal, &h.BouncedTotal, &h.CertDomain, &certExpires,
+		&warnings, &h.SampledAt)
+	if err != nil {
+		return nil, err
+	}
+	if certExpires.Valid {
+		h.CertExpiresAt = &certExpires.Time
+	}
+	if warnings.Valid {
+		json.Unmarshal([]byte(warnings.String), &h.Warnings)
+	}
+	return &h, nil
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/foxzi/sendry/internal/web/models"
+)
+
+func TestHealthRepository(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewHealthRepository(db)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	expires := now.Add(10 * 24 * time.Hour)
+	samples := []*models.ServerHealth{
+		{Server: "mta-1", Online: true, QueuePending: 5, SampledAt: now.Add(-2 * time.Hour)},
+		{Server: "mta-1", Online: true, QueuePending: 7, RateLimitUsage: 0.5, CertDomain: "mail.example.com",
+			CertExpiresAt: &expires, Warnings: []string{"TLS certificate of mail.example.com expires in 9 days"}, SampledAt: now},
+		{Server: "mta-2", Error: "connection refused", Warnings: []string{"Server unreachable"}, SampledAt: now.Add(-time.Minute)},
+	}
+	for _, s := range samples {
+		if err := repo.Record(s); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+
+	list, err := repo.List("mta-1", now.Add(-time.Hour))
+	if err != nil || len(list) != 1 {
+		t.Fatalf("List() = %d samples, %v, want 1", len(list), err)
+	}
+	got := list[0]
+	if got.QueuePending != 7 || got.RateLimitUsage != 0.5 || got.CertExpiresAt == nil || !got.CertExpiresAt.Equal(expires) || len(got.Warnings) != 1 {
+		t.Errorf("List()[0] = %+v", got)
+	}
+
+	latest, err := repo.Latest()
+	if err != nil {
+		t.Fatalf("Latest() error = %v", err)
+	}
+	if len(latest) != 2 || latest["mta-1"].QueuePending != 7 || latest["mta-2"].Online || latest["mta-2"].Error != "connection refused" {
+		t.Errorf("Latest() = %+v", latest)
+	}
+
+	n, err := repo.Prune(now.Add(-time.Hour))
+	if err != nil || n != 1 {
+		t.Errorf("Prune() = %d, %v, want 1 sample deleted", n, err)
+	}
+}
@@ -26,9 +26,9 @@ func (r *JobRepository) Create(job *models.SendJob) error {
 	job.UpdatedAt = job.CreatedAt
 
 	_, err := r.db.Exec(`
-		INSERT INTO send_jobs (id, campaign_id, recipient_list_id, status, scheduled_at, servers, strategy, stats, dry_run, dry_run_limit, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		job.ID, job.CampaignID, job.RecipientListID, job.Status, job.ScheduledAt, job.Servers, job.Strategy, job.Stats, job.DryRun, job.DryRunLimit, job.CreatedAt, job.UpdatedAt,
+		INSERT INTO send_jobs (id, campaign_id, recipient_list_id, segment_id, status, scheduled_at, servers, strategy, stats, dry_run, dry_run_limit, freeze_override, throttle, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		job.ID, job.CampaignID, job.RecipientListID, nullString(job.SegmentID), job.Status, job.ScheduledAt, job.Servers, job.Strategy, job.Stats, job.DryRun, job.DryRunLimit, job.FreezeOverride, nullString(job.Throttle), job.CreatedAt, job.UpdatedAt,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to create job: %w", err)
@@ -40,19 +40,21 @@ func (r *JobRepository) Create(job *models.SendJob) error {
 func (r *JobRepository) GetByID(id string) (*models.SendJob, error) {
 	job := &models.SendJob{}
 	var scheduledAt, startedAt, completedAt sql.NullTime
-	var campaignName, listName sql.NullString
+	var campaignName, listName, segmentID, segmentName sql.NullString
 
 	err := r.db.QueryRow(`
-		SELECT j.id, j.campaign_id, c.name, j.recipient_list_id, rl.name, j.status,
+		SELECT j.id, j.campaign_id, c.name, j.recipient_list_id, rl.name, j.segment_id, rs.name, j.status,
 			j.scheduled_at, j.started_at, j.completed_at, j.servers, j.strategy, j.stats,
-			COALESCE(j.dry_run, 0), COALESCE(j.dry_run_limit, 0), j.created_at, j.updated_at
+			COALESCE(j.dry_run, 0), COALESCE(j.dry_run_limit, 0), COALESCE(j.freeze_override, 0), COALESCE(j.throttle, ''),
+			j.created_at, j.updated_at
 		FROM send_jobs j
 		LEFT JOIN campaigns c ON j.campaign_id = c.id
 		LEFT JOIN recipient_lists rl ON j.recipient_list_id = rl.id
+		LEFT JOIN recipient_segments rs ON j.segment_id = rs.id
 		WHERE j.id = ?`, id,
-	).Scan(&job.ID, &job.CampaignID, &campaignName, &job.RecipientListID, &listName, &job.Status,
+	).Scan(&job.ID, &job.CampaignID, &campaignName, &job.RecipientListID, &listName, &segmentID, &segmentName, &job.Status,
 		&scheduledAt, &startedAt, &completedAt, &job.Servers, &job.Strategy, &job.Stats,
-		&job.DryRun, &job.DryRunLimit, &job.CreatedAt, &job.UpdatedAt)
+		&job.DryRun, &job.DryRunLimit, &job.FreezeOverride, &job.Throttle, &job.CreatedAt, &job.UpdatedAt)
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -67,6 +69,8 @@ func (r *JobRepository) GetByID(id string) (*models.SendJob, error) {
 	if listName.Valid {
 		job.ListName = listName.String
 	}
+	job.SegmentID = segmentID.String
+	job.SegmentName = segmentName.String
 	if scheduledAt.Valid {
 		job.ScheduledAt = &scheduledAt.Time
 	}
@@ -94,6 +98,10 @@ func (r *JobRepository) List(filter models.JobListFilter) ([]models.SendJob, int
 		countQuery += " AND status = ?"
 		args = append(args, filter.Status)
 	}
+	if filter.OrgID != "" {
+		countQuery += " AND campaign_id IN (SELECT id FROM campaigns WHERE org_id = ?)"
+		args = append(args, filter.OrgID)
+	}
 
 	var total int
 	if err := r.db.QueryRow(countQuery, args...).Scan(&total); err != nil {
@@ -104,7 +112,7 @@ func (r *JobRepository) List(filter models.JobListFilter) ([]models.SendJob, int
 	query := `
 		SELECT j.id, j.campaign_id, c.name, j.recipient_list_id, rl.name, j.status,
 			j.scheduled_at, j.started_at, j.completed_at, j.servers, j.strategy, j.stats,
-			COALESCE(j.dry_run, 0), COALESCE(j.dry_run_limit, 0), j.created_at, j.updated_at
+			COALESCE(j.dry_run, 0), COALESCE(j.dry_run_limit, 0), COALESCE(j.freeze_override, 0), j.created_at, j.updated_at
 		FROM send_jobs j
 		LEFT JOIN campaigns c ON j.campaign_id = c.id
 		LEFT JOIN recipient_lists rl ON j.recipient_list_id = rl.id
@@ -119,6 +127,10 @@ func (r *JobRepository) List(filter models.JobListFilter) ([]models.SendJob, int
 		query += " AND j.status = ?"
 		args = append(args, filter.Status)
 	}
+	if filter.OrgID != "" {
+		query += " AND c.org_id = ?"
+		args = append(args, filter.OrgID)
+	}
 
 	query += " ORDER BY j.created_at DESC"
 
@@ -145,7 +157,7 @@ func (r *JobRepository) List(filter models.JobListFilter) ([]models.SendJob, int
 
 		err := rows.Scan(&job.ID, &job.CampaignID, &campaignName, &job.RecipientListID, &listName, &job.Status,
 			&scheduledAt, &startedAt, &completedAt, &job.Servers, &job.Strategy, &job.Stats,
-			&job.DryRun, &job.DryRunLimit, &job.CreatedAt, &job.UpdatedAt)
+			&job.DryRun, &job.DryRunLimit, &job.FreezeOverride, &job.CreatedAt, &job.UpdatedAt)
 		if err != nil {
 			return nil, 0, err
 		}
@@ -172,7 +184,8 @@ func (r *JobRepository) List(filter models.JobListFilter) ([]models.SendJob, int
 	return jobs, total, nil
 }
 
-// UpdateStatus updates job status
+// UpdateStatus updates job status. Cancelling a job cancels its pending
+// items, and a job that stops running keeps the stats it stopped with.
 func (r *JobRepository) UpdateStatus(id, status string) error {
 	now := time.Now()
 	var startedAt, completedAt *time.Time
@@ -184,11 +197,55 @@ func (r *JobRepository) UpdateStatus(id, status string) error {
 		completedAt = &now
 	}
 
-	_, err := r.db.Exec(`
-		UPDATE send_jobs SET status = ?, started_at = COALESCE(?, started_at), completed_at = ?, updated_at = ?
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Resuming a paused job keeps its original start time
+	_, err = tx.Exec(`
+		UPDATE send_jobs SET status = ?, started_at = COALESCE(started_at, ?), completed_at = ?, updated_at = ?
 		WHERE id = ?`,
 		status, startedAt, completedAt, now, id,
 	)
+	if err != nil {
+		return err
+	}
+
+	if status == "cancelled" {
+		if _, err := tx.Exec(`UPDATE send_job_items SET status = 'cancelled' WHERE job_id = ? AND status = 'pending'`, id); err != nil {
+			return fmt.Errorf("failed to cancel items: %w", err)
+		}
+	}
+
+	if status != "running" {
+		stats, err := jobStats(tx, id)
+		if err != nil {
+			return err
+		}
+		statsJSON, _ := json.Marshal(stats)
+		if _, err := tx.Exec("UPDATE send_jobs SET stats = ? WHERE id = ?", string(statsJSON), id); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Status returns the current status of a job, empty if it does not exist
+func (r *JobRepository) Status(id string) (string, error) {
+	var status string
+	err := r.db.QueryRow("SELECT status FROM send_jobs WHERE id = ?", id).Scan(&status)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return status, err
+}
+
+// SetFreezeOverride lets a job send during freeze windows
+func (r *JobRepository) SetFreezeOverride(id string) error {
+	_, err := r.db.Exec("UPDATE send_jobs SET freeze_override = 1, updated_at = ? WHERE id = ?", time.Now(), id)
 	return err
 }
 
@@ -270,7 +327,7 @@ func (r *JobRepository) ListItems(filter models.JobItemFilter) ([]models.SendJob
 	// Get items
 	query := `
 		SELECT i.id, i.job_id, i.recipient_id, r.email, i.variant_id, COALESCE(v.name, ''), i.server_name, i.status,
-			i.sendry_msg_id, i.error, i.queued_at, i.sent_at, i.created_at
+			i.sendry_msg_id, i.error, COALESCE(i.mx_host, ''), i.queued_at, i.sent_at, i.failed_at, i.created_at
 		FROM send_job_items i
 		LEFT JOIN recipients r ON i.recipient_id = r.id
 		LEFT JOIN campaign_variants v ON i.variant_id = v.id
@@ -303,10 +360,10 @@ func (r *JobRepository) ListItems(filter models.JobItemFilter) ([]models.SendJob
 	for rows.Next() {
 		var item models.SendJobItem
 		var email, variantName sql.NullString
-		var queuedAt, sentAt sql.NullTime
+		var queuedAt, sentAt, failedAt sql.NullTime
 
 		err := rows.Scan(&item.ID, &item.JobID, &item.RecipientID, &email, &item.VariantID, &variantName,
-			&item.ServerName, &item.Status, &item.SendryMsgID, &item.Error, &queuedAt, &sentAt, &item.CreatedAt)
+			&item.ServerName, &item.Status, &item.SendryMsgID, &item.Error, &item.MXHost, &queuedAt, &sentAt, &failedAt, &item.CreatedAt)
 		if err != nil {
 			return nil, 0, err
 		}
@@ -323,6 +380,9 @@ func (r *JobRepository) ListItems(filter models.JobItemFilter) ([]models.SendJob
 		if sentAt.Valid {
 			item.SentAt = &sentAt.Time
 		}
+		if failedAt.Valid {
+			item.FailedAt = &failedAt.Time
+		}
 
 		items = append(items, item)
 	}
@@ -351,28 +411,139 @@ func (r *JobRepository) UpdateItemStatus(id, status, sendryMsgID, errorMsg strin
 	return err
 }
 
+// UpdateItemDelivery records the delivery state of a queued item reported
+// by its server. Items still queued keep the retry reason and are checked
+// again after the other queued items.
+func (r *JobRepository) UpdateItemDelivery(id string, d models.ItemDelivery) error {
+	var sentAt, failedAt *time.Time
+	switch d.Status {
+	case "sent":
+		sentAt = &d.At
+	case "bounced", "failed":
+		failedAt = &d.At
+	}
+
+	_, err := r.db.Exec(`
+		UPDATE send_job_items SET status = ?, error = ?, mx_host = ?,
+			sent_at = COALESCE(?, sent_at), failed_at = COALESCE(?, failed_at), status_checked_at = ?
+		WHERE id = ?`,
+		d.Status, d.Error, d.MXHost, sentAt, failedAt, time.Now(), id,
+	)
+	return err
+}
+
+// UpdateItemServer records the server a job item was sent to
+func (r *JobRepository) UpdateItemServer(id, serverName string) error {
+	_, err := r.db.Exec("UPDATE send_job_items SET server_name = ? WHERE id = ?", serverName, id)
+	return err
+}
+
 // GetStats returns aggregated stats for a job
 func (r *JobRepository) GetStats(jobID string) (models.JobStats, error) {
+	return jobStats(r.db, jobID)
+}
+
+// rowQuerier is implemented by *sql.DB and *sql.Tx
+type rowQuerier interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+func jobStats(q rowQuerier, jobID string) (models.JobStats, error) {
 	var stats models.JobStats
 
-	err := r.db.QueryRow(`
+	err := q.QueryRow(`
 		SELECT
 			COUNT(*) as total,
-			SUM(CASE WHEN status = 'pending' THEN 1 ELSE 0 END) as pending,
-			SUM(CASE WHEN status = 'queued' THEN 1 ELSE 0 END) as queued,
-			SUM(CASE WHEN status = 'sent' THEN 1 ELSE 0 END) as sent,
-			SUM(CASE WHEN status = 'failed' THEN 1 ELSE 0 END) as failed
+			COALESCE(SUM(CASE WHEN status = 'pending' THEN 1 ELSE 0 END), 0) as pending,
+			COALESCE(SUM(CASE WHEN status = 'queued' THEN 1 ELSE 0 END), 0) as queued,
+			COALESCE(SUM(CASE WHEN status = 'sent' THEN 1 ELSE 0 END), 0) as sent,
+			COALESCE(SUM(CASE WHEN status = 'failed' THEN 1 ELSE 0 END), 0) as failed,
+			COALESCE(SUM(CASE WHEN status = 'bounced' THEN 1 ELSE 0 END), 0) as bounced,
+			COALESCE(SUM(CASE WHEN status = 'cancelled' THEN 1 ELSE 0 END), 0) as cancelled
 		FROM send_job_items WHERE job_id = ?`, jobID,
-	).Scan(&stats.Total, &stats.Pending, &stats.Queued, &stats.Sent, &stats.Failed)
+	).Scan(&stats.Total, &stats.Pending, &stats.Queued, &stats.Sent, &stats.Failed, &stats.Bounced, &stats.Cancelled)
 
 	return stats, err
 }
 
+// deliverabilityTop is the number of bounce reasons and recipient domains
+// in a deliverability report
+const deliverabilityTop = 10
+
+// CampaignDeliverability returns the delivery outcome of the items of all
+// jobs of a campaign, with the most frequent bounce reasons and the
+// recipient domains with most emails
+func (r *JobRepository) CampaignDeliverability(campaignID string) (models.DeliverabilityReport, error) {
+	var rep models.DeliverabilityReport
+
+	err := r.db.QueryRow(`
+		SELECT
+			COUNT(*),
+			COALESCE(SUM(CASE WHEN i.status = 'queued' THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN i.status = 'sent' THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN i.status = 'bounced' THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN i.status = 'failed' THEN 1 ELSE 0 END), 0)
+		FROM send_job_items i JOIN send_jobs j ON j.id = i.job_id
+		WHERE j.campaign_id = ? AND i.status != 'cancelled'`, campaignID,
+	).Scan(&rep.Total, &rep.Queued, &rep.Delivered, &rep.Bounced, &rep.Failed)
+	if err != nil {
+		return rep, err
+	}
+
+	rows, err := r.db.Query(`
+		SELECT i.error, COUNT(*)
+		FROM send_job_items i JOIN send_jobs j ON j.id = i.job_id
+		WHERE j.campaign_id = ? AND i.status IN ('bounced', 'failed') AND COALESCE(i.error, '') != ''
+		GROUP BY i.error ORDER BY COUNT(*) DESC, i.error LIMIT ?`, campaignID, deliverabilityTop)
+	if err != nil {
+		return rep, err
+	}
+	rep.Reasons = []models.BounceReason{}
+	for rows.Next() {
+		var b models.BounceReason
+		if err := rows.Scan(&b.Error, &b.Count); err != nil {
+			rows.Close()
+			return rep, err
+		}
+		rep.Reasons = append(rep.Reasons, b)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return rep, err
+	}
+
+	rows, err = r.db.Query(`
+		SELECT lower(substr(r.email, instr(r.email, '@') + 1)) AS domain, COUNT(*),
+			COALESCE(SUM(CASE WHEN i.status = 'sent' THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN i.status = 'bounced' THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN i.status = 'failed' THEN 1 ELSE 0 END), 0)
+		FROM send_job_items i
+		JOIN send_jobs j ON j.id = i.job_id
+		JOIN recipients r ON r.id = i.recipient_id
+		WHERE j.campaign_id = ? AND i.status != 'cancelled'
+		GROUP BY domain ORDER BY COUNT(*) DESC, domain LIMIT ?`, campaignID, deliverabilityTop)
+	if err != nil {
+		return rep, err
+	}
+	defer rows.Close()
+
+	rep.Domains = []models.DomainDeliverability{}
+	for rows.Next() {
+		var d models.DomainDeliverability
+		if err := rows.Scan(&d.Domain, &d.Total, &d.Delivered, &d.Bounced, &d.Failed); err != nil {
+			return rep, err
+		}
+		rep.Domains = append(rep.Domains, d)
+	}
+	return rep, rows.Err()
+}
+
 // GetRunningJobs returns all jobs with status 'running'
 func (r *JobRepository) GetRunningJobs() ([]models.SendJob, error) {
 	rows, err := r.db.Query(`
 		SELECT j.id, j.campaign_id, c.name, j.recipient_list_id, COALESCE(rl.name, ''), j.status,
-			j.scheduled_at, j.started_at, j.completed_at, j.servers, j.strategy, COALESCE(j.stats, '{}'), j.created_at, j.updated_at
+			j.scheduled_at, j.started_at, j.completed_at, j.servers, j.strategy, COALESCE(j.stats, '{}'),
+			COALESCE(j.freeze_override, 0), COALESCE(j.throttle, ''), j.created_at, j.updated_at
 		FROM send_jobs j
 		LEFT JOIN campaigns c ON j.campaign_id = c.id
 		LEFT JOIN recipient_lists rl ON j.recipient_list_id = rl.id
@@ -390,7 +561,8 @@ func (r *JobRepository) GetRunningJobs() ([]models.SendJob, error) {
 		var campaignName, listName sql.NullString
 
 		err := rows.Scan(&job.ID, &job.CampaignID, &campaignName, &job.RecipientListID, &listName, &job.Status,
-			&scheduledAt, &startedAt, &completedAt, &job.Servers, &job.Strategy, &job.Stats, &job.CreatedAt, &job.UpdatedAt)
+			&scheduledAt, &startedAt, &completedAt, &job.Servers, &job.Strategy, &job.Stats,
+			&job.FreezeOverride, &job.Throttle, &job.CreatedAt, &job.UpdatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -421,7 +593,8 @@ func (r *JobRepository) GetRunningJobs() ([]models.SendJob, error) {
 func (r *JobRepository) GetScheduledJobsDue() ([]models.SendJob, error) {
 	rows, err := r.db.Query(`
 		SELECT j.id, j.campaign_id, c.name, j.recipient_list_id, COALESCE(rl.name, ''), j.status,
-			j.scheduled_at, j.started_at, j.completed_at, j.servers, j.strategy, COALESCE(j.stats, '{}'), j.created_at, j.updated_at
+			j.scheduled_at, j.started_at, j.completed_at, j.servers, j.strategy, COALESCE(j.stats, '{}'),
+			COALESCE(j.freeze_override, 0), COALESCE(j.throttle, ''), j.created_at, j.updated_at
 		FROM send_jobs j
 		LEFT JOIN campaigns c ON j.campaign_id = c.id
 		LEFT JOIN recipient_lists rl ON j.recipient_list_id = rl.id
@@ -439,7 +612,8 @@ func (r *JobRepository) GetScheduledJobsDue() ([]models.SendJob, error) {
 		var campaignName, listName sql.NullString
 
 		err := rows.Scan(&job.ID, &job.CampaignID, &campaignName, &job.RecipientListID, &listName, &job.Status,
-			&scheduledAt, &startedAt, &completedAt, &job.Servers, &job.Strategy, &job.Stats, &job.CreatedAt, &job.UpdatedAt)
+			&scheduledAt, &startedAt, &completedAt, &job.Servers, &job.Strategy, &job.Stats,
+			&job.FreezeOverride, &job.Throttle, &job.CreatedAt, &job.UpdatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -506,6 +680,14 @@ func (r *JobRepository) GetPendingItems(jobID string, limit int) ([]models.SendJ
 	return items, nil
 }
 
+// CountQueuedSince returns the number of items of a job handed to a
+// Sendry server since the given time, for the job send rate
+func (r *JobRepository) CountQueuedSince(jobID string, since time.Time) (int, error) {
+	var n int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM send_job_items WHERE job_id = ? AND queued_at >= ?`, jobID, since).Scan(&n)
+	return n, err
+}
+
 // GetQueuedItems returns items with status 'queued' for status tracking
 func (r *JobRepository) GetQueuedItems(limit int) ([]models.SendJobItem, error) {
 	rows, err := r.db.Query(`
@@ -514,7 +696,7 @@ func (r *JobRepository) GetQueuedItems(limit int) ([]models.SendJobItem, error)
 		FROM send_job_items i
 		LEFT JOIN recipients r ON i.recipient_id = r.id
 		WHERE i.status = 'queued' AND i.sendry_msg_id != ''
-		ORDER BY i.created_at
+		ORDER BY COALESCE(i.status_checked_at, i.queued_at, i.created_at)
 		LIMIT ?`, limit,
 	)
 	if err != nil {
@@ -539,3 +721,59 @@ func (r *JobRepository) GetQueuedItems(limit int) ([]models.SendJobItem, error)
 
 	return items, nil
 }
+
+// ItemsByEmail returns the job items sent to an email across all
+// campaigns, ignoring case, newest first, with their opens and clicks
+func (r *JobRepository) ItemsByEmail(email string, limit int) ([]models.HistoryMessage, error) {
+	rows, err := r.db.Query(`
+		SELECT i.id, i.job_id, j.campaign_id, COALESCE(c.name, ''), COALESCE(v.name, ''),
+			COALESCE(i.server_name, ''), COALESCE(i.sendry_msg_id, ''), i.status, COALESCE(i.error, ''),
+			i.queued_at, i.sent_at, i.created_at,
+			(SELECT COUNT(*) FROM tracking_events e WHERE e.item_id = i.id AND e.type = 'open'),
+			(SELECT COUNT(*) FROM tracking_events e WHERE e.item_id = i.id AND e.type = 'click')
+		FROM send_job_items i
+		JOIN recipients r ON r.id = i.recipient_id
+		JOIN send_jobs j ON j.id = i.job_id
+		LEFT JOIN campaigns c ON c.id = j.campaign_id
+		LEFT JOIN campaign_variants v ON v.id = i.variant_id
+		WHERE r.email = ? COLLATE NOCASE
+		ORDER BY i.created_at DESC
+		LIMIT ?`, email, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.HistoryMessage{}
+	for rows.Next() {
+		m := models.HistoryMessage{Source: models.HistorySourceCampaign}
+		var queuedAt, sentAt sql.NullTime
+		if err := rows.Scan(&m.ID, &m.JobID, &m.CampaignID, &m.CampaignName, &m.VariantName,
+			&m.ServerName, &m.SendryMsgID, &m.Status, &m.Error,
+			&queuedAt, &sentAt, &m.CreatedAt, &m.Opens, &m.Clicks); err != nil {
+			return nil, err
+		}
+		if queuedAt.Valid {
+			m.QueuedAt = &queuedAt.Time
+		}
+		if sentAt.Valid {
+			m.SentAt = &sentAt.Time
+		}
+		items = append(items, m)
+	}
+	return items, rows.Err()
+}
+
+// ServerOutcomes counts the job items sent through a server that got a final
+// outcome since the given time, and how many of them bounced
+func (r *JobRepository) ServerOutcomes(server string, since time.Time) (completed, bounced int, err error) {
+	err = r.db.QueryRow(`
+		SELECT COUNT(*), COALESCE(SUM(CASE WHEN status = 'bounced' THEN 1 ELSE 0 END), 0)
+		FROM send_job_items
+		WHERE server_name = ? AND status IN ('sent', 'bounced', 'failed')
+			AND julianday(COALESCE(failed_at, sent_at)) >= julianday(?)`,
+		server, since.UTC(),
+	).Scan(&completed, &bounced)
+	return completed, bounced, err
+}
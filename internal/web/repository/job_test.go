@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/foxzi/sendry/internal/web/models"
+)
+
+func TestJobRepository_UpdateStatus(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewJobRepository(db)
+
+	seed := []string{
+		`INSERT INTO recipient_lists (id, name, source_type) VALUES ('list', 'List', 'manual')`,
+		`INSERT INTO recipients (id, list_id, email) VALUES ('r1', 'list', 'a@example.com'), ('r2', 'list', 'b@example.com'), ('r3', 'list', 'c@example.com'), ('r4', 'list', 'd@example.com')`,
+		`INSERT INTO campaigns (id, name, from_email) VALUES ('camp', 'Sale', 'news@example.com')`,
+		`INSERT INTO send_jobs (id, campaign_id, recipient_list_id, status) VALUES ('job', 'camp', 'list', 'scheduled')`,
+		`INSERT INTO send_job_items (id, job_id, recipient_id, status) VALUES
+			('i1', 'job', 'r1', 'sent'), ('i2', 'job', 'r2', 'queued'), ('i3', 'job', 'r3', 'pending'), ('i4', 'job', 'r4', 'pending')`,
+	}
+	for _, q := range seed {
+		if _, err := db.Exec(q); err != nil {
+			t.Fatalf("seed failed: %v", err)
+		}
+	}
+
+	if err := repo.UpdateStatus("job", "running"); err != nil {
+		t.Fatalf("UpdateStatus(running) error = %v", err)
+	}
+	var started string
+	db.QueryRow("SELECT started_at FROM send_jobs WHERE id = 'job'").Scan(&started)
+
+	// Pausing and resuming keeps the items and the start time
+	for _, status := range []string{"paused", "running"} {
+		if err := repo.UpdateStatus("job", status); err != nil {
+			t.Fatalf("UpdateStatus(%s) error = %v", status, err)
+		}
+	}
+	var resumed string
+	db.QueryRow("SELECT started_at FROM send_jobs WHERE id = 'job'").Scan(&resumed)
+	if resumed != started {
+		t.Errorf("resume changed started_at from %s to %s", started, resumed)
+	}
+	if status, _ := repo.Status("job"); status != "running" {
+		t.Errorf("Status() = %q, want running", status)
+	}
+
+	if err := repo.UpdateStatus("job", "cancelled"); err != nil {
+		t.Fatalf("UpdateStatus(cancelled) error = %v", err)
+	}
+	want := models.JobStats{Total: 4, Queued: 1, Sent: 1, Cancelled: 2}
+	stats, err := repo.GetStats("job")
+	if err != nil {
+		t.Fatalf("GetStats() error = %v", err)
+	}
+	if stats != want {
+		t.Errorf("GetStats() = %+v, want %+v", stats, want)
+	}
+
+	// The job keeps the stats it was cancelled with
+	var stored string
+	db.QueryRow("SELECT stats FROM send_jobs WHERE id = 'job'").Scan(&stored)
+	var saved models.JobStats
+	if err := json.Unmarshal([]byte(stored), &saved); err != nil || saved != want {
+		t.Errorf("stored stats = %s, want %+v", stored, want)
+	}
+
+	if status, err := repo.Status("missing"); err != nil || status != "" {
+		t.Errorf("Status(missing) = %q, %v, want empty", status, err)
+	}
+}
+
+func TestJobRepository_CampaignDeliverability(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewJobRepository(db)
+
+	seed := []string{
+		`INSERT INTO recipient_lists (id, name, source_type) VALUES ('list', 'List', 'manual')`,
+		`INSERT INTO recipients (id, list_id, email) VALUES ('r1', 'list', 'a@example.com'), ('r2', 'list', 'b@Example.com'), ('r3', 'list', 'c@other.org'), ('r4', 'list', 'd@other.org')`,
+		`INSERT INTO campaigns (id, name, from_email) VALUES ('camp', 'Sale', 'news@example.com')`,
+		`INSERT INTO templates (id, name, subject) VALUES ('tmpl', 'Sale', 'Sale')`,
+		`INSERT INTO campaign_variants (id, campaign_id, name, template_id) VALUES ('var', 'camp', 'A', 'tmpl')`,
+		`INSERT INTO send_jobs (id, campaign_id, recipient_list_id) VALUES ('job', 'camp', 'list')`,
+		`INSERT INTO send_job_items (id, job_id, recipient_id, variant_id, server_name, status, sendry_msg_id, error) VALUES
+			('i1', 'job', 'r1', 'var', 'mta', 'queued', 'm1', ''), ('i2', 'job', 'r2', 'var', 'mta', 'queued', 'm2', ''),
+			('i3', 'job', 'r3', 'var', 'mta', 'queued', 'm3', ''), ('i4', 'job', 'r4', 'var', 'mta', 'queued', 'm4', '')`,
+	}
+	for _, q := range seed {
+		if _, err := db.Exec(q); err != nil {
+			t.Fatalf("seed failed: %v", err)
+		}
+	}
+
+	at := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	updates := map[string]models.ItemDelivery{
+		"i1": {Status: "sent", MXHost: "mx.example.com", At: at},
+		"i2": {Status: "bounced", MXHost: "mx.example.com", Error: "550 5.1.1 user unknown", At: at},
+		"i3": {Status: "failed", Error: "delivery expired", At: at},
+		"i4": {Status: "queued", Error: "451 try again later"},
+	}
+	for id, d := range updates {
+		if err := repo.UpdateItemDelivery(id, d); err != nil {
+			t.Fatalf("UpdateItemDelivery(%s) error = %v", id, err)
+		}
+	}
+
+	items, _, err := repo.ListItems(models.JobItemFilter{JobID: "job", Status: "bounced"})
+	if err != nil || len(items) != 1 {
+		t.Fatalf("ListItems(bounced) = %d items, %v", len(items), err)
+	}
+	if items[0].MXHost != "mx.example.com" || items[0].FailedAt == nil || items[0].SentAt != nil {
+		t.Errorf("bounced item = %+v, want MX host and failure time", items[0])
+	}
+
+	stats, _ := repo.GetStats("job")
+	if stats.Sent != 1 || stats.Bounced != 1 || stats.Failed != 1 || stats.Queued != 1 {
+		t.Errorf("GetStats() = %+v", stats)
+	}
+
+	// Only the item still queued is tracked further
+	queued, err := repo.GetQueuedItems(10)
+	if err != nil || len(queued) != 1 || queued[0].ID != "i4" {
+		t.Fatalf("GetQueuedItems() = %v, %v", queued, err)
+	}
+
+	rep, err := repo.CampaignDeliverability("camp")
+	if err != nil {
+		t.Fatalf("CampaignDeliverability() error = %v", err)
+	}
+	if rep.Total != 4 || rep.Delivered != 1 || rep.Bounced != 1 || rep.Failed != 1 || rep.Queued != 1 {
+		t.Errorf("report counts = %+v", rep)
+	}
+	if rep.BounceRate() < 0.33 || rep.BounceRate() > 0.34 {
+		t.Errorf("BounceRate() = %v, want 1/3", rep.BounceRate())
+	}
+	if len(rep.Reasons) != 2 {
+		t.Errorf("Reasons = %v, want bounce and failure", rep.Reasons)
+	}
+	want := []models.DomainDeliverability{
+		{Domain: "example.com", Total: 2, Delivered: 1, Bounced: 1},
+		{Domain: "other.org", Total: 2, Failed: 1},
+	}
+	if len(rep.Domains) != 2 || rep.Domains[0] != want[0] || rep.Domains[1] != want[1] {
+		t.Errorf("Domains = %+v, want %+v", rep.Domains, want)
+	}
+
+	completed, bounced, err := repo.ServerOutcomes("mta", at.Add(-time.Hour))
+	if err != nil || completed != 3 || bounced != 1 {
+		t.Errorf("ServerOutcomes() = %d, %d, %v, want 3 completed, 1 bounced", completed, bounced, err)
+	}
+	if completed, _, _ := repo.ServerOutcomes("mta", at.Add(time.Minute)); completed != 0 {
+		t.Errorf("ServerOutcomes() after the outcomes = %d, want 0", completed)
+	}
+}
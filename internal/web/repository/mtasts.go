@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/foxzi/sendry/internal/web/models"
+)
+
+type MTASTSRepository struct {
+	db *sql.DB
+}
+
+func NewMTASTSRepository(db *sql.DB) *MTASTSRepository {
+	return &MTASTSRepository{db: db}
+}
+
+// Save creates or replaces the policy of a domain
+func (r *MTASTSRepository) Save(p *models.MTASTSPolicy) error {
+	now := time.Now().UTC()
+	p.UpdatedAt = now
+	mxJSON, _ := json.Marshal(p.MX)
+
+	err := r.db.QueryRow(`
+		INSERT INTO mta_sts_policies (domain_id, mode, mx, max_age, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(domain_id) DO UPDATE SET
+			mode = excluded.mode,
+			mx = excluded.mx,
+			max_age = excluded.max_age,
+			updated_at = excluded.updated_at
+		RETURNING created_at`,
+		p.DomainID, p.Mode, string(mxJSON), p.MaxAge, now, now,
+	).Scan(&p.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save mta-sts policy: %w", err)
+	}
+	return nil
+}
+
+// Get returns the policy of a domain, nil if it has none
+func (r *MTASTSRepository) Get(domainID string) (*models.MTASTSPolicy, error) {
+	return r.get(`SELECT domain_id, mode, mx, max_age, created_at, updated_at
+		FROM mta_sts_policies WHERE domain_id = ?`, domainID)
+}
+
+// GetByDomain returns the policy of a domain by name, nil if it has none
+func (r *MTASTSRepository) GetByDomain(domain string) (*models.MTASTSPolicy, error) {
+	return r.get(`SELECT p.domain_id, p.mode, p.mx, p.max_age, p.created_at, p.updated_at
+		FROM mta_sts_policies p JOIN domains d ON d.id = p.domain_id
+		WHERE d.domain = ?`, strings.ToLower(domain))
+}
+
+// Delete removes the policy of a domain
+func (r *MTASTSRepository) Delete(domainID string) error {
+	_, err := r.db.Exec("DELETE FROM mta_sts_policies WHERE domain_id = ?", domainID)
+	return err
+}
+
+func (r *MTASTSRepository) get(query string, args ...any) (*models.MTASTSPolicy, error) {
+	p := &models.MTASTSPolicy{}
+	var mxJSON string
+	err := r.db.QueryRow(query, args...).Scan(&p.DomainID, &p.Mode, &mxJSON, &p.MaxAge, &p.CreatedAt, &p.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	json.Unmarshal([]byte(mxJSON), &p.MX)
+	return p, nil
+}
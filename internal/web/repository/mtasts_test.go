@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/foxzi/sendry/internal/web/models"
+)
+
+func TestMTASTSRepository(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewMTASTSRepository(db)
+
+	if _, err := db.Exec(`INSERT INTO domains (id, domain) VALUES ('d1', 'example.com')`); err != nil {
+		t.Fatalf("insert domain: %v", err)
+	}
+
+	p, err := repo.Get("d1")
+	if err != nil || p != nil {
+		t.Fatalf("Get() = %+v, %v, want nil", p, err)
+	}
+
+	policy := &models.MTASTSPolicy{DomainID: "d1", Mode: "testing", MX: []string{"mx.example.com"}, MaxAge: 86400}
+	if err := repo.Save(policy); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	created := policy.CreatedAt
+
+	policy.Mode = "enforce"
+	policy.MX = append(policy.MX, "*.mail.example.com")
+	if err := repo.Save(policy); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if !policy.CreatedAt.Equal(created) {
+		t.Errorf("Save() changed created_at to %v, want %v", policy.CreatedAt, created)
+	}
+
+	p, err = repo.GetByDomain("Example.com")
+	if err != nil || p == nil {
+		t.Fatalf("GetByDomain() = %+v, %v", p, err)
+	}
+	if p.Mode != "enforce" || len(p.MX) != 2 || p.MX[1] != "*.mail.example.com" || p.MaxAge != 86400 {
+		t.Errorf("GetByDomain() = %+v", p)
+	}
+
+	if err := repo.Delete("d1"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if p, _ := repo.Get("d1"); p != nil {
+		t.Errorf("Get() after Delete() = %+v, want nil", p)
+	}
+}
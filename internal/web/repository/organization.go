@@ -0,0 +1,220 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/foxzi/sendry/internal/web/models"
+)
+
+// ErrOrganizationInUse is returned when deleting an organization that still
+// owns objects
+var ErrOrganizationInUse = errors.New("organization still owns templates, campaigns, lists, DKIM keys, domains or API keys")
+
+type OrganizationRepository struct {
+	db *sql.DB
+}
+
+func NewOrganizationRepository(db *sql.DB) *OrganizationRepository {
+	return &OrganizationRepository{db: db}
+}
+
+// Create creates a new organization
+func (r *OrganizationRepository) Create(o *models.Organization) error {
+	o.ID = uuid.New().String()
+	o.CreatedAt = time.Now()
+	servers, _ := json.Marshal(nonNilStrings(o.Servers))
+
+	_, err := r.db.Exec(`
+		INSERT INTO organizations (id, name, servers, created_at) VALUES (?, ?, ?, ?)`,
+		o.ID, o.Name, string(servers), o.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create organization: %w", err)
+	}
+	return nil
+}
+
+// GetByID returns an organization by ID
+func (r *OrganizationRepository) GetByID(id string) (*models.Organization, error) {
+	return r.getBy("id = ?", id)
+}
+
+// GetByName returns an organization by name
+func (r *OrganizationRepository) GetByName(name string) (*models.Organization, error) {
+	return r.getBy("name = ?", name)
+}
+
+func (r *OrganizationRepository) getBy(where string, arg any) (*models.Organization, error) {
+	o := &models.Organization{}
+	var servers string
+	err := r.db.QueryRow(`
+		SELECT id, name, servers, created_at,
+		       (SELECT COUNT(*) FROM organization_members m WHERE m.org_id = organizations.id)
+		FROM organizations WHERE `+where, arg,
+	).Scan(&o.ID, &o.Name, &servers, &o.CreatedAt, &o.MemberCount)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	json.Unmarshal([]byte(servers), &o.Servers)
+	return o, nil
+}
+
+// List returns all organizations ordered by name
+func (r *OrganizationRepository) List() ([]models.Organization, error) {
+	return r.list(`
+		SELECT o.id, o.name, o.servers, o.created_at,
+		       (SELECT COUNT(*) FROM organization_members m WHERE m.org_id = o.id)
+		FROM organizations o ORDER BY o.name`)
+}
+
+// ListForUser returns the organizations a user is a member of, ordered by
+// name
+func (r *OrganizationRepository) ListForUser(userID string) ([]models.Organization, error) {
+	return r.list(`
+		SELECT o.id, o.name, o.servers, o.created_at,
+		       (SELECT COUNT(*) FROM organization_members m WHERE m.org_id = o.id)
+		FROM organizations o
+		JOIN organization_members om ON om.org_id = o.id AND om.user_id = ?
+		ORDER BY o.name`, userID)
+}
+
+func (r *OrganizationRepository) list(query string, args ...any) ([]models.Organization, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organizations: %w", err)
+	}
+	defer rows.Close()
+
+	var orgs []models.Organization
+	for rows.Next() {
+		var o models.Organization
+		var servers string
+		if err := rows.Scan(&o.ID, &o.Name, &servers, &o.CreatedAt, &o.MemberCount); err != nil {
+			return nil, err
+		}
+		json.Unmarshal([]byte(servers), &o.Servers)
+		orgs = append(orgs, o)
+	}
+	return orgs, rows.Err()
+}
+
+// Update updates the name and servers of an organization
+func (r *OrganizationRepository) Update(o *models.Organization) error {
+	servers, _ := json.Marshal(nonNilStrings(o.Servers))
+	_, err := r.db.Exec("UPDATE organizations SET name = ?, servers = ? WHERE id = ?", o.Name, string(servers), o.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update organization: %w", err)
+	}
+	return nil
+}
+
+// Delete deletes an organization and its memberships. Organizations that
+// still own objects are not deleted.
+func (r *OrganizationRepository) Delete(id string) error {
+	var owned int
+	err := r.db.QueryRow(`
+		SELECT (SELECT COUNT(*) FROM templates WHERE org_id = ?1)
+		     + (SELECT COUNT(*) FROM campaigns WHERE org_id = ?1)
+		     + (SELECT COUNT(*) FROM recipient_lists WHERE org_id = ?1)
+		     + (SELECT COUNT(*) FROM dkim_keys WHERE org_id = ?1)
+		     + (SELECT COUNT(*) FROM domains WHERE org_id = ?1)
+		     + (SELECT COUNT(*) FROM api_keys WHERE org_id = ?1)`, id,
+	).Scan(&owned)
+	if err != nil {
+		return err
+	}
+	if owned > 0 {
+		return ErrOrganizationInUse
+	}
+
+	if _, err := r.db.Exec("DELETE FROM organizations WHERE id = ?", id); err != nil {
+		return fmt.Errorf("failed to delete organization: %w", err)
+	}
+	return nil
+}
+
+// SetMember adds a user to an organization or changes the user's role
+func (r *OrganizationRepository) SetMember(orgID, userID string, role models.OrgRole) error {
+	_, err := r.db.Exec(`
+		INSERT INTO organization_members (org_id, user_id, role, created_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(org_id, user_id) DO UPDATE SET role = excluded.role`,
+		orgID, userID, string(role), time.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set member: %w", err)
+	}
+	return nil
+}
+
+// RemoveMember removes a user from an organization
+func (r *OrganizationRepository) RemoveMember(orgID, userID string) error {
+	_, err := r.db.Exec("DELETE FROM organization_members WHERE org_id = ? AND user_id = ?", orgID, userID)
+	return err
+}
+
+// ListMembers returns the members of an organization ordered by email
+func (r *OrganizationRepository) ListMembers(orgID string) ([]models.OrganizationMember, error) {
+	rows, err := r.db.Query(`
+		SELECT m.org_id, m.user_id, u.email, COALESCE(u.name, ''), m.role, m.created_at
+		FROM organization_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.org_id = ?
+		ORDER BY u.email`, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list members: %w", err)
+	}
+	defer rows.Close()
+
+	var members []models.OrganizationMember
+	for rows.Next() {
+		var m models.OrganizationMember
+		if err := rows.Scan(&m.OrgID, &m.UserID, &m.Email, &m.Name, &m.Role, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+// MemberRole returns the role of a user in an organization, or "" if the
+// user is not a member
+func (r *OrganizationRepository) MemberRole(orgID, userID string) (models.OrgRole, error) {
+	var role string
+	err := r.db.QueryRow("SELECT role FROM organization_members WHERE org_id = ? AND user_id = ?", orgID, userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return models.OrgRole(role), err
+}
+
+// JoinSole makes a new user a member of the organization when there is only
+// one, so that single-tenant installations need no membership management
+func (r *OrganizationRepository) JoinSole(userID string) error {
+	return joinSoleOrganization(r.db, userID)
+}
+
+func joinSoleOrganization(db *sql.DB, userID string) error {
+	_, err := db.Exec(`
+		INSERT OR IGNORE INTO organization_members (org_id, user_id, role)
+		SELECT id, ?, 'member' FROM organizations WHERE (SELECT COUNT(*) FROM organizations) = 1`,
+		userID,
+	)
+	return err
+}
+
+func nonNilStrings(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
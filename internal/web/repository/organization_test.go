@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/foxzi/sendry/internal/web/models"
+)
+
+func TestOrganizationRepository(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewOrganizationRepository(db)
+
+	for _, u := range []string{"ann", "bob"} {
+		if _, err := db.Exec("INSERT INTO users (id, email, password_hash) VALUES (?, ?, '')", u, u+"@example.com"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// With a single organization new users join it
+	acme := &models.Organization{Name: "Acme", Servers: []string{"mta-1"}}
+	if err := repo.Create(acme); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := repo.JoinSole("ann"); err != nil {
+		t.Fatalf("JoinSole() error = %v", err)
+	}
+	if role, err := repo.MemberRole(acme.ID, "ann"); err != nil || role != models.OrgRoleMember {
+		t.Errorf("MemberRole(ann) = %q, %v, want member", role, err)
+	}
+
+	// With several they wait for an admin
+	globex := &models.Organization{Name: "Globex"}
+	if err := repo.Create(globex); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := repo.JoinSole("bob"); err != nil {
+		t.Fatalf("JoinSole() error = %v", err)
+	}
+	if role, _ := repo.MemberRole(acme.ID, "bob"); role != "" {
+		t.Errorf("MemberRole(bob) = %q, want not a member", role)
+	}
+
+	got, err := repo.GetByName("Acme")
+	if err != nil || got == nil {
+		t.Fatalf("GetByName() = %v, %v", got, err)
+	}
+	if !got.AllowsServer("mta-1") || got.AllowsServer("mta-2") || got.MemberCount != 1 {
+		t.Errorf("GetByName() = %+v, want mta-1 only and one member", got)
+	}
+	if g, _ := repo.GetByID(globex.ID); g == nil || len(g.Servers) != 0 || !g.AllowsServer("mta-2") {
+		t.Errorf("GetByID(globex) = %+v, want all servers", g)
+	}
+
+	if err := repo.SetMember(globex.ID, "ann", models.OrgRoleViewer); err != nil {
+		t.Fatalf("SetMember() error = %v", err)
+	}
+	if err := repo.SetMember(globex.ID, "ann", models.OrgRoleAdmin); err != nil {
+		t.Fatalf("SetMember(change role) error = %v", err)
+	}
+	members, err := repo.ListMembers(globex.ID)
+	if err != nil || len(members) != 1 || members[0].Email != "ann@example.com" || members[0].Role != models.OrgRoleAdmin {
+		t.Errorf("ListMembers() = %+v, %v, want ann as admin", members, err)
+	}
+	orgs, err := repo.ListForUser("ann")
+	if err != nil || len(orgs) != 2 {
+		t.Errorf("ListForUser(ann) = %d organizations, %v, want 2", len(orgs), err)
+	}
+	if orgs, _ := repo.ListForUser("bob"); len(orgs) != 0 {
+		t.Errorf("ListForUser(bob) = %d organizations, want 0", len(orgs))
+	}
+
+	acme.Name, acme.Servers = "Acme Corp", nil
+	if err := repo.Update(acme); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if got, _ := repo.GetByID(acme.ID); got.Name != "Acme Corp" || len(got.Servers) != 0 {
+		t.Errorf("after Update() = %+v", got)
+	}
+
+	// Organizations that own objects are kept
+	if _, err := db.Exec("INSERT INTO templates (id, name, subject, org_id) VALUES ('t1', 'welcome', 'Hi', ?)", globex.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Delete(globex.ID); !errors.Is(err, ErrOrganizationInUse) {
+		t.Errorf("Delete(in use) error = %v, want ErrOrganizationInUse", err)
+	}
+	if _, err := db.Exec("DELETE FROM templates WHERE id = 't1'"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Delete(globex.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if role, _ := repo.MemberRole(globex.ID, "ann"); role != "" {
+		t.Errorf("membership survived Delete(), role %q", role)
+	}
+	if err := repo.RemoveMember(acme.ID, "ann"); err != nil {
+		t.Fatalf("RemoveMember() error = %v", err)
+	}
+	if orgs, _ := repo.ListForUser("ann"); len(orgs) != 0 {
+		t.Errorf("ListForUser(ann) after RemoveMember() = %d organizations", len(orgs))
+	}
+}
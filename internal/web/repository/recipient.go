@@ -2,10 +2,7 @@ package repository
 
 import (
 	"database/sql"
-	"encoding/csv"
 	"fmt"
-	"io"
-	"strings"
 	"time"
 
 	"github.com/foxzi/sendry/internal/web/models"
@@ -27,9 +24,9 @@ func (r *RecipientRepository) CreateList(list *models.RecipientList) error {
 	list.UpdatedAt = list.CreatedAt
 
 	_, err := r.db.Exec(`
-		INSERT INTO recipient_lists (id, name, description, source_type, total_count, active_count, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
-		list.ID, list.Name, list.Description, list.SourceType, list.TotalCount, list.ActiveCount, list.CreatedAt, list.UpdatedAt,
+		INSERT INTO recipient_lists (id, name, description, source_type, total_count, active_count, org_id, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		list.ID, list.Name, list.Description, list.SourceType, list.TotalCount, list.ActiveCount, nullString(list.OrgID), list.CreatedAt, list.UpdatedAt,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to create recipient list: %w", err)
@@ -41,9 +38,9 @@ func (r *RecipientRepository) CreateList(list *models.RecipientList) error {
 func (r *RecipientRepository) GetListByID(id string) (*models.RecipientList, error) {
 	list := &models.RecipientList{}
 	err := r.db.QueryRow(`
-		SELECT id, name, description, source_type, total_count, active_count, created_at, updated_at
+		SELECT id, name, description, source_type, total_count, active_count, COALESCE(org_id, ''), created_at, updated_at
 		FROM recipient_lists WHERE id = ?`, id,
-	).Scan(&list.ID, &list.Name, &list.Description, &list.SourceType, &list.TotalCount, &list.ActiveCount, &list.CreatedAt, &list.UpdatedAt)
+	).Scan(&list.ID, &list.Name, &list.Description, &list.SourceType, &list.TotalCount, &list.ActiveCount, &list.OrgID, &list.CreatedAt, &list.UpdatedAt)
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -60,6 +57,10 @@ func (r *RecipientRepository) ListLists(filter models.RecipientListFilter) ([]mo
 	countQuery := "SELECT COUNT(*) FROM recipient_lists WHERE 1=1"
 	args := []any{}
 
+	if filter.OrgID != "" {
+		countQuery += " AND org_id = ?"
+		args = append(args, filter.OrgID)
+	}
 	if filter.Search != "" {
 		countQuery += " AND (name LIKE ? OR description LIKE ?)"
 		args = append(args, "%"+filter.Search+"%", "%"+filter.Search+"%")
@@ -72,10 +73,14 @@ func (r *RecipientRepository) ListLists(filter models.RecipientListFilter) ([]mo
 
 	// Get lists
 	query := `
-		SELECT id, name, description, source_type, total_count, active_count, created_at, updated_at
+		SELECT id, name, description, source_type, total_count, active_count, COALESCE(org_id, ''), created_at, updated_at
 		FROM recipient_lists WHERE 1=1`
 
 	args = []any{}
+	if filter.OrgID != "" {
+		query += " AND org_id = ?"
+		args = append(args, filter.OrgID)
+	}
 	if filter.Search != "" {
 		query += " AND (name LIKE ? OR description LIKE ?)"
 		args = append(args, "%"+filter.Search+"%", "%"+filter.Search+"%")
@@ -101,7 +106,7 @@ func (r *RecipientRepository) ListLists(filter models.RecipientListFilter) ([]mo
 	lists := []models.RecipientList{}
 	for rows.Next() {
 		var list models.RecipientList
-		err := rows.Scan(&list.ID, &list.Name, &list.Description, &list.SourceType, &list.TotalCount, &list.ActiveCount, &list.CreatedAt, &list.UpdatedAt)
+		err := rows.Scan(&list.ID, &list.Name, &list.Description, &list.SourceType, &list.TotalCount, &list.ActiveCount, &list.OrgID, &list.CreatedAt, &list.UpdatedAt)
 		if err != nil {
 			return nil, 0, err
 		}
@@ -200,6 +205,11 @@ func (r *RecipientRepository) ListRecipients(filter models.RecipientFilter) ([]m
 		countQuery += " AND tags LIKE ?"
 		args = append(args, "%\""+filter.Tag+"\"%")
 	}
+	if filter.Rules != nil {
+		where, whereArgs := segmentWhere(filter.Rules)
+		countQuery += " AND " + where
+		args = append(args, whereArgs...)
+	}
 
 	var total int
 	if err := r.db.QueryRow(countQuery, args...).Scan(&total); err != nil {
@@ -224,6 +234,11 @@ func (r *RecipientRepository) ListRecipients(filter models.RecipientFilter) ([]m
 		query += " AND tags LIKE ?"
 		args = append(args, "%\""+filter.Tag+"\"%")
 	}
+	if filter.Rules != nil {
+		where, whereArgs := segmentWhere(filter.Rules)
+		query += " AND " + where
+		args = append(args, whereArgs...)
+	}
 
 	query += " ORDER BY created_at DESC"
 
@@ -277,88 +292,6 @@ func (r *RecipientRepository) DeleteRecipient(id string, listID string) error {
 	return r.UpdateListCounts(listID)
 }
 
-// ImportCSV imports recipients from CSV data
-func (r *RecipientRepository) ImportCSV(listID string, reader io.Reader) (*models.RecipientImportResult, error) {
-	result := &models.RecipientImportResult{}
-
-	csvReader := csv.NewReader(reader)
-
-	// Read header
-	header, err := csvReader.Read()
-	if err != nil {
-		return nil, fmt.Errorf("failed to read CSV header: %w", err)
-	}
-
-	// Find column indices
-	emailIdx := -1
-	nameIdx := -1
-	for i, col := range header {
-		col = strings.ToLower(strings.TrimSpace(col))
-		switch col {
-		case "email", "e-mail", "email_address":
-			emailIdx = i
-		case "name", "full_name", "fullname":
-			nameIdx = i
-		}
-	}
-
-	if emailIdx == -1 {
-		return nil, fmt.Errorf("email column not found in CSV")
-	}
-
-	// Process rows
-	for {
-		record, err := csvReader.Read()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			result.Errors = append(result.Errors, fmt.Sprintf("row %d: %v", result.Total+1, err))
-			result.Total++
-			continue
-		}
-
-		result.Total++
-
-		if emailIdx >= len(record) {
-			result.Errors = append(result.Errors, fmt.Sprintf("row %d: missing email column", result.Total))
-			result.Skipped++
-			continue
-		}
-
-		email := strings.TrimSpace(record[emailIdx])
-		if email == "" {
-			result.Skipped++
-			continue
-		}
-
-		name := ""
-		if nameIdx >= 0 && nameIdx < len(record) {
-			name = strings.TrimSpace(record[nameIdx])
-		}
-
-		recipient := &models.Recipient{
-			ListID: listID,
-			Email:  email,
-			Name:   name,
-			Status: "active",
-		}
-
-		if err := r.AddRecipient(recipient); err != nil {
-			result.Errors = append(result.Errors, fmt.Sprintf("row %d (%s): %v", result.Total, email, err))
-			result.Skipped++
-			continue
-		}
-
-		result.Imported++
-	}
-
-	// Update counts after import
-	r.UpdateListCounts(listID)
-
-	return result, nil
-}
-
 // StreamRecipients returns an iterator for recipients to avoid loading all into memory.
 // Caller must call Close() on returned rows when done.
 func (r *RecipientRepository) StreamRecipients(listID string) (*sql.Rows, error) {
@@ -400,3 +333,27 @@ func (r *RecipientRepository) GetTags(listID string) ([]string, error) {
 	}
 	return tags, nil
 }
+
+// Memberships returns the lists containing an email, ignoring case
+func (r *RecipientRepository) Memberships(email string) ([]models.HistoryMembership, error) {
+	rows, err := r.db.Query(`
+		SELECT l.id, l.name, r.status, r.created_at
+		FROM recipients r JOIN recipient_lists l ON l.id = r.list_id
+		WHERE r.email = ? COLLATE NOCASE
+		ORDER BY l.name`, email,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lists := []models.HistoryMembership{}
+	for rows.Next() {
+		var m models.HistoryMembership
+		if err := rows.Scan(&m.ListID, &m.ListName, &m.Status, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		lists = append(lists, m)
+	}
+	return lists, rows.Err()
+}
@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/foxzi/sendry/internal/web/models"
+	"github.com/google/uuid"
+)
+
+// CreateImport stores an uploaded recipient file awaiting column mapping
+func (r *RecipientRepository) CreateImport(imp *models.RecipientImport) error {
+	imp.ID = uuid.New().String()
+	imp.Status = models.RecipientImportPending
+	imp.Total = len(imp.Rows)
+	imp.CreatedAt = time.Now()
+
+	columns, err := json.Marshal(imp.Columns)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(imp.Rows)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.Exec(`
+		INSERT INTO recipient_imports (id, list_id, file_name, columns, data, status, total, created_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		imp.ID, imp.ListID, imp.FileName, string(columns), string(data), imp.Status, imp.Total, imp.CreatedBy, imp.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create import: %w", err)
+	}
+	return nil
+}
+
+// GetImport returns an import by ID with its rows if it is still pending
+func (r *RecipientRepository) GetImport(id string) (*models.RecipientImport, error) {
+	imp := &models.RecipientImport{}
+	var columns string
+	var data, rejected, createdBy sql.NullString
+	var completedAt sql.NullTime
+	err := r.db.QueryRow(`
+		SELECT id, list_id, file_name, columns, data, status, total, imported, updated, rejected,
+			created_by, created_at, completed_at
+		FROM recipient_imports WHERE id = ?`, id,
+	).Scan(&imp.ID, &imp.ListID, &imp.FileName, &columns, &data, &imp.Status, &imp.Total, &imp.Imported, &imp.Updated, &rejected,
+		&createdBy, &imp.CreatedAt, &completedAt)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(columns), &imp.Columns); err != nil {
+		return nil, fmt.Errorf("invalid import columns: %w", err)
+	}
+	if data.Valid && data.String != "" {
+		if err := json.Unmarshal([]byte(data.String), &imp.Rows); err != nil {
+			return nil, fmt.Errorf("invalid import rows: %w", err)
+		}
+	}
+	if rejected.Valid && rejected.String != "" {
+		if err := json.Unmarshal([]byte(rejected.String), &imp.Rejected); err != nil {
+			return nil, fmt.Errorf("invalid import rejected rows: %w", err)
+		}
+	}
+	imp.CreatedBy = createdBy.String
+	if completedAt.Valid {
+		imp.CompletedAt = &completedAt.Time
+	}
+	return imp, nil
+}
+
+// CompleteImport stores the import report and drops the file rows
+func (r *RecipientRepository) CompleteImport(imp *models.RecipientImport) error {
+	now := time.Now()
+	imp.Status = models.RecipientImportCompleted
+	imp.CompletedAt = &now
+	imp.Rows = nil
+
+	rejected, err := json.Marshal(imp.Rejected)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.Exec(`
+		UPDATE recipient_imports
+		SET data = NULL, status = ?, imported = ?, updated = ?, rejected = ?, completed_at = ?
+		WHERE id = ?`,
+		imp.Status, imp.Imported, imp.Updated, string(rejected), now, imp.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to complete import: %w", err)
+	}
+	return nil
+}
+
+// ListEmails returns the emails of a list keyed by their lowercase form
+func (r *RecipientRepository) ListEmails(listID string) (map[string]string, error) {
+	rows, err := r.db.Query(`SELECT email FROM recipients WHERE list_id = ?`, listID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	emails := make(map[string]string)
+	for rows.Next() {
+		var email string
+		if err := rows.Scan(&email); err != nil {
+			return nil, err
+		}
+		emails[strings.ToLower(email)] = email
+	}
+	return emails, rows.Err()
+}
+
+// OtherListEmails returns which of the given lowercase emails are in lists
+// other than listID, mapped to the name of one such list
+func (r *RecipientRepository) OtherListEmails(listID string, emails map[string]bool) (map[string]string, error) {
+	rows, err := r.db.Query(`
+		SELECT r.email, l.name
+		FROM recipients r
+		JOIN recipient_lists l ON l.id = r.list_id
+		WHERE r.list_id != ?
+		ORDER BY l.name`, listID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	found := make(map[string]string)
+	for rows.Next() {
+		var email, list string
+		if err := rows.Scan(&email, &list); err != nil {
+			return nil, err
+		}
+		email = strings.ToLower(email)
+		if _, ok := found[email]; !ok && emails[email] {
+			found[email] = list
+		}
+	}
+	return found, rows.Err()
+}
+
+// ImportRecipients adds recipients to a list in one transaction. Existing
+// recipients (by email) keep their status; their name and tags are
+// replaced if given and the variables are merged.
+func (r *RecipientRepository) ImportRecipients(listID string, recipients []models.Recipient) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO recipients (id, list_id, email, name, variables, tags, status, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, 'active', ?)
+		ON CONFLICT(list_id, email) DO UPDATE SET
+			name = CASE WHEN excluded.name != '' THEN excluded.name ELSE recipients.name END,
+			variables = CASE
+				WHEN excluded.variables = '' THEN recipients.variables
+				WHEN json_valid(recipients.variables) THEN json_patch(recipients.variables, excluded.variables)
+				ELSE excluded.variables END,
+			tags = CASE WHEN excluded.tags != '' THEN excluded.tags ELSE recipients.tags END`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	now := time.Now()
+	for _, rec := range recipients {
+		if _, err := stmt.Exec(uuid.New().String(), listID, rec.Email, rec.Name, rec.Variables, rec.Tags, now); err != nil {
+			return fmt.Errorf("failed to import %s: %w", rec.Email, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return r.UpdateListCounts(listID)
+}
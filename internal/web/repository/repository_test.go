@@ -50,6 +50,7 @@ func setupTestDB(t *testing.T) *sql.DB {
 			page_background TEXT NOT NULL DEFAULT '',
 			container_radius_top INTEGER NOT NULL DEFAULT 0,
 			container_radius_bottom INTEGER NOT NULL DEFAULT 0,
+			block_external INTEGER NOT NULL DEFAULT 0,
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 		)`,
@@ -96,6 +97,9 @@ func setupTestDB(t *testing.T) *sql.DB {
 			remote_id TEXT,
 			deployed_version INTEGER NOT NULL,
 			deployed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			content_hash TEXT NOT NULL DEFAULT '',
+			drift TEXT NOT NULL DEFAULT '',
+			drift_checked_at TIMESTAMP,
 			UNIQUE(template_id, server_name)
 		)`,
 		`CREATE TABLE IF NOT EXISTS recipient_lists (
@@ -119,6 +123,15 @@ func setupTestDB(t *testing.T) *sql.DB {
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			UNIQUE(list_id, email)
 		)`,
+		`CREATE TABLE IF NOT EXISTS recipient_segments (
+			id TEXT PRIMARY KEY,
+			list_id TEXT NOT NULL REFERENCES recipient_lists(id) ON DELETE CASCADE,
+			name TEXT NOT NULL,
+			description TEXT NOT NULL DEFAULT '',
+			rules JSON NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
 		`CREATE TABLE IF NOT EXISTS campaigns (
 			id TEXT PRIMARY KEY,
 			name TEXT NOT NULL,
@@ -163,8 +176,11 @@ func setupTestDB(t *testing.T) *sql.DB {
 			status TEXT DEFAULT 'pending',
 			sendry_msg_id TEXT,
 			error TEXT,
+			mx_host TEXT NOT NULL DEFAULT '',
 			queued_at TIMESTAMP,
 			sent_at TIMESTAMP,
+			failed_at TIMESTAMP,
+			status_checked_at TIMESTAMP,
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 		)`,
 		`CREATE TABLE IF NOT EXISTS global_variables (
@@ -247,7 +263,18 @@ func setupTestDB(t *testing.T) *sql.DB {
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			last_used_at TIMESTAMP,
 			expires_at TIMESTAMP,
-			active INTEGER DEFAULT 1
+			active INTEGER DEFAULT 1,
+			user_id TEXT REFERENCES users(id) ON DELETE CASCADE
+		)`,
+		`CREATE TABLE IF NOT EXISTS sessions (
+			id TEXT PRIMARY KEY,
+			user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			expires_at TIMESTAMP NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			auth_method TEXT NOT NULL DEFAULT 'local',
+			ip TEXT NOT NULL DEFAULT '',
+			user_agent TEXT NOT NULL DEFAULT '',
+			last_seen_at TIMESTAMP
 		)`,
 		`CREATE TABLE IF NOT EXISTS sends (
 			id TEXT PRIMARY KEY,
@@ -267,6 +294,120 @@ func setupTestDB(t *testing.T) *sql.DB {
 			sent_at TIMESTAMP,
 			client_ip TEXT
 		)`,
+		`CREATE TABLE IF NOT EXISTS deployment_events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			correlation_id TEXT NOT NULL,
+			entity_type TEXT NOT NULL,
+			entity_id TEXT NOT NULL,
+			entity_name TEXT,
+			server_name TEXT NOT NULL,
+			status TEXT NOT NULL,
+			error TEXT,
+			user_email TEXT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS freeze_windows (
+			id TEXT PRIMARY KEY,
+			name TEXT NOT NULL,
+			domain TEXT NOT NULL DEFAULT '',
+			reason TEXT,
+			starts_at TIMESTAMP NOT NULL,
+			ends_at TIMESTAMP NOT NULL,
+			created_by TEXT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS tracking_events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			item_id TEXT NOT NULL REFERENCES send_job_items(id) ON DELETE CASCADE,
+			job_id TEXT NOT NULL REFERENCES send_jobs(id) ON DELETE CASCADE,
+			campaign_id TEXT NOT NULL,
+			type TEXT NOT NULL,
+			url TEXT NOT NULL DEFAULT '',
+			ip_address TEXT,
+			user_agent TEXT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS server_health_samples (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			server TEXT NOT NULL,
+			online INTEGER NOT NULL DEFAULT 0,
+			error TEXT NOT NULL DEFAULT '',
+			queue_pending INTEGER NOT NULL DEFAULT 0,
+			queue_retrying INTEGER NOT NULL DEFAULT 0,
+			dlq_size INTEGER NOT NULL DEFAULT 0,
+			oldest_queued INTEGER NOT NULL DEFAULT 0,
+			rate_limit_usage REAL NOT NULL DEFAULT 0,
+			sent_total REAL NOT NULL DEFAULT 0,
+			failed_total REAL NOT NULL DEFAULT 0,
+			bounced_total REAL NOT NULL DEFAULT 0,
+			cert_domain TEXT NOT NULL DEFAULT '',
+			cert_expires_at TIMESTAMP,
+			warnings JSON,
+			sampled_at TIMESTAMP NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS alerts (
+			id TEXT PRIMARY KEY,
+			rule TEXT NOT NULL,
+			type TEXT NOT NULL,
+			server TEXT NOT NULL,
+			message TEXT NOT NULL DEFAULT '',
+			state TEXT NOT NULL DEFAULT 'firing',
+			started_at TIMESTAMP NOT NULL,
+			resolved_at TIMESTAMP,
+			notified_at TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS alert_silences (
+			id TEXT PRIMARY KEY,
+			rule TEXT NOT NULL DEFAULT '',
+			server TEXT NOT NULL DEFAULT '',
+			reason TEXT,
+			starts_at TIMESTAMP NOT NULL,
+			ends_at TIMESTAMP NOT NULL,
+			created_by TEXT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS dns_records (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			domain_id TEXT NOT NULL REFERENCES domains(id) ON DELETE CASCADE,
+			kind TEXT NOT NULL,
+			type TEXT NOT NULL,
+			name TEXT NOT NULL,
+			value TEXT NOT NULL,
+			provider TEXT NOT NULL,
+			status TEXT NOT NULL DEFAULT 'published',
+			error TEXT,
+			published_at TIMESTAMP,
+			checked_at TIMESTAMP,
+			verified_at TIMESTAMP,
+			UNIQUE(domain_id, kind)
+		)`,
+		`CREATE TABLE IF NOT EXISTS mta_sts_policies (
+			domain_id TEXT PRIMARY KEY REFERENCES domains(id) ON DELETE CASCADE,
+			mode TEXT NOT NULL,
+			mx TEXT NOT NULL DEFAULT '[]',
+			max_age INTEGER NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS organizations (
+			id TEXT PRIMARY KEY,
+			name TEXT UNIQUE NOT NULL,
+			servers TEXT NOT NULL DEFAULT '[]',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS organization_members (
+			org_id TEXT NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+			user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			role TEXT NOT NULL DEFAULT 'member',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (org_id, user_id)
+		)`,
+		"ALTER TABLE templates ADD COLUMN org_id TEXT REFERENCES organizations(id)",
+		"ALTER TABLE campaigns ADD COLUMN org_id TEXT REFERENCES organizations(id)",
+		"ALTER TABLE recipient_lists ADD COLUMN org_id TEXT REFERENCES organizations(id)",
+		"ALTER TABLE dkim_keys ADD COLUMN org_id TEXT REFERENCES organizations(id)",
+		"ALTER TABLE domains ADD COLUMN org_id TEXT REFERENCES organizations(id)",
+		"ALTER TABLE api_keys ADD COLUMN org_id TEXT REFERENCES organizations(id)",
 	}
 
 	for _, m := range migrations {
@@ -0,0 +1,236 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/foxzi/sendry/internal/web/models"
+	"github.com/google/uuid"
+)
+
+// CreateSegment creates a segment of a recipient list
+func (r *RecipientRepository) CreateSegment(s *models.Segment) error {
+	s.ID = uuid.New().String()
+	s.CreatedAt = time.Now()
+	s.UpdatedAt = s.CreatedAt
+
+	_, err := r.db.Exec(`
+		INSERT INTO recipient_segments (id, list_id, name, description, rules, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		s.ID, s.ListID, s.Name, s.Description, s.Rules, s.CreatedAt, s.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create segment: %w", err)
+	}
+	return nil
+}
+
+// GetSegment returns a segment by ID
+func (r *RecipientRepository) GetSegment(id string) (*models.Segment, error) {
+	s := &models.Segment{}
+	err := r.db.QueryRow(`
+		SELECT id, list_id, name, description, rules, created_at, updated_at
+		FROM recipient_segments WHERE id = ?`, id,
+	).Scan(&s.ID, &s.ListID, &s.Name, &s.Description, &s.Rules, &s.CreatedAt, &s.UpdatedAt)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// ListSegments returns the segments of a list with their current counts
+func (r *RecipientRepository) ListSegments(listID string) ([]models.Segment, error) {
+	rows, err := r.db.Query(`
+		SELECT id, list_id, name, description, rules, created_at, updated_at
+		FROM recipient_segments WHERE list_id = ? ORDER BY name`, listID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	segments := []models.Segment{}
+	for rows.Next() {
+		var s models.Segment
+		if err := rows.Scan(&s.ID, &s.ListID, &s.Name, &s.Description, &s.Rules, &s.CreatedAt, &s.UpdatedAt); err != nil {
+			return nil, err
+		}
+		segments = append(segments, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for i := range segments {
+		rules, err := models.ParseSegmentRules(segments[i].Rules)
+		if err != nil {
+			return nil, fmt.Errorf("segment %s: %w", segments[i].Name, err)
+		}
+		if segments[i].Count, err = r.CountSegment(listID, rules); err != nil {
+			return nil, err
+		}
+	}
+	return segments, nil
+}
+
+// ListAllSegments returns the segments of all lists of an organization, or
+// of all lists if orgID is empty, ordered by name
+func (r *RecipientRepository) ListAllSegments(orgID string) ([]models.Segment, error) {
+	rows, err := r.db.Query(`
+		SELECT s.id, s.list_id, s.name, s.description, s.rules, s.created_at, s.updated_at
+		FROM recipient_segments s
+		JOIN recipient_lists l ON l.id = s.list_id
+		WHERE ? = '' OR l.org_id = ?
+		ORDER BY s.name`, orgID, orgID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	segments := []models.Segment{}
+	for rows.Next() {
+		var s models.Segment
+		if err := rows.Scan(&s.ID, &s.ListID, &s.Name, &s.Description, &s.Rules, &s.CreatedAt, &s.UpdatedAt); err != nil {
+			return nil, err
+		}
+		segments = append(segments, s)
+	}
+	return segments, rows.Err()
+}
+
+// UpdateSegment updates the name, description and rules of a segment
+func (r *RecipientRepository) UpdateSegment(s *models.Segment) error {
+	s.UpdatedAt = time.Now()
+	_, err := r.db.Exec(`
+		UPDATE recipient_segments SET name = ?, description = ?, rules = ?, updated_at = ?
+		WHERE id = ?`,
+		s.Name, s.Description, s.Rules, s.UpdatedAt, s.ID,
+	)
+	return err
+}
+
+// DeleteSegment deletes a segment, the recipients are kept
+func (r *RecipientRepository) DeleteSegment(id string) error {
+	_, err := r.db.Exec("DELETE FROM recipient_segments WHERE id = ?", id)
+	return err
+}
+
+// CountSegment returns the number of active recipients of a list that
+// match the rules
+func (r *RecipientRepository) CountSegment(listID string, rules *models.SegmentRules) (int, error) {
+	where, args := segmentWhere(rules)
+	var count int
+	err := r.db.QueryRow(
+		"SELECT COUNT(*) FROM recipients WHERE list_id = ? AND status = 'active' AND "+where,
+		append([]any{listID}, args...)...,
+	).Scan(&count)
+	return count, err
+}
+
+// segmentWhere returns an SQL condition on the recipients table matching
+// the rules. Rules must be validated.
+func segmentWhere(rules *models.SegmentRules) (string, []any) {
+	var parts []string
+	var args []any
+	for _, c := range rules.Conditions {
+		part, partArgs := segmentCondition(c)
+		parts = append(parts, part)
+		args = append(args, partArgs...)
+	}
+
+	join := " AND "
+	if rules.Match == "any" {
+		join = " OR "
+	}
+	return "(" + strings.Join(parts, join) + ")", args
+}
+
+// segmentCondition returns the SQL for one condition. Text comparisons
+// ignore case; a missing variable never equals a value.
+func segmentCondition(c models.SegmentCondition) (string, []any) {
+	if c.Field == models.SegmentFieldTag {
+		return segmentTagCondition(c)
+	}
+
+	// value is the raw field, text is the field as text
+	var value string
+	var args []any
+	switch c.Field {
+	case models.SegmentFieldEmail:
+		value = "email"
+	case models.SegmentFieldName:
+		value = "NULLIF(name, '')"
+	default:
+		value = "(CASE WHEN json_valid(variables) THEN json_extract(variables, ?) END)"
+		args = append(args, "$."+c.Field)
+	}
+	text := "CAST(" + value + " AS TEXT)"
+
+	// with returns the path argument, if any, followed by the values
+	with := func(extra ...any) []any {
+		return append(append([]any{}, args...), extra...)
+	}
+
+	switch c.Op {
+	case models.SegmentOpEq:
+		return text + " = ? COLLATE NOCASE", with(c.Value)
+	case models.SegmentOpNe:
+		return "IFNULL(" + text + ", '') != ? COLLATE NOCASE", with(c.Value)
+	case models.SegmentOpIn, models.SegmentOpNotIn:
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(c.Values)), ", ")
+		values := make([]any, len(c.Values))
+		for i, v := range c.Values {
+			values[i] = v
+		}
+		if c.Op == models.SegmentOpIn {
+			return text + " COLLATE NOCASE IN (" + placeholders + ")", with(values...)
+		}
+		return "IFNULL(" + text + ", '') COLLATE NOCASE NOT IN (" + placeholders + ")", with(values...)
+	case models.SegmentOpContains:
+		return text + ` LIKE ? ESCAPE '\'`, with("%" + escapeLike(c.Value) + "%")
+	case models.SegmentOpGt, models.SegmentOpLt:
+		n, _ := strconv.ParseFloat(c.Value, 64)
+		op := ">"
+		if c.Op == models.SegmentOpLt {
+			op = "<"
+		}
+		return "CAST(" + value + " AS REAL) " + op + " ?", with(n)
+	case models.SegmentOpExists:
+		return value + " IS NOT NULL", with()
+	case models.SegmentOpNotExists:
+		return value + " IS NULL", with()
+	}
+	return "0", nil
+}
+
+// segmentTagCondition returns the SQL for a condition on the tags array
+func segmentTagCondition(c models.SegmentCondition) (string, []any) {
+	values := []any{c.Value}
+	if c.Op == models.SegmentOpIn || c.Op == models.SegmentOpNotIn {
+		values = make([]any, len(c.Values))
+		for i, v := range c.Values {
+			values[i] = v
+		}
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ")
+	exists := "EXISTS (SELECT 1 FROM json_each(CASE WHEN json_valid(tags) THEN tags ELSE '[]' END) " +
+		"WHERE value COLLATE NOCASE IN (" + placeholders + "))"
+
+	if c.Op == models.SegmentOpNe || c.Op == models.SegmentOpNotIn {
+		return "NOT " + exists, values
+	}
+	return exists, values
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
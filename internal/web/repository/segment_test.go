@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/foxzi/sendry/internal/web/models"
+)
+
+func TestRecipientRepository_Segments(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewRecipientRepository(db)
+
+	list := &models.RecipientList{Name: "Customers", SourceType: "manual"}
+	if err := repo.CreateList(list); err != nil {
+		t.Fatalf("CreateList() error = %v", err)
+	}
+	for _, rec := range []models.Recipient{
+		{Email: "anna@example.de", Name: "Anna", Variables: `{"country": "DE", "plan": "pro", "orders": 12, "address": {"city": "Berlin"}}`, Tags: `["vip"]`},
+		{Email: "ben@example.de", Variables: `{"country": "de", "plan": "free", "orders": 2}`},
+		{Email: "carl@example.com", Name: "Carl", Variables: `{"country": "US", "plan": "team", "orders": "30"}`, Tags: `["beta", "vip"]`},
+		{Email: "dora@example.com", Variables: "", Tags: ""},
+		{Email: "eve@example.de", Variables: `{"country": "DE", "plan": "pro"}`, Status: "unsubscribed"},
+	} {
+		rec.ListID = list.ID
+		if err := repo.AddRecipient(&rec); err != nil {
+			t.Fatalf("AddRecipient() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		rules string
+		want  int
+	}{
+		{"eq ignores case", `{"conditions": [{"field": "country", "op": "eq", "value": "DE"}]}`, 2},
+		{"all", `{"conditions": [{"field": "country", "op": "eq", "value": "DE"}, {"field": "plan", "op": "in", "values": ["pro"]}]}`, 1},
+		{"any", `{"match": "any", "conditions": [{"field": "plan", "op": "eq", "value": "team"}, {"field": "tag", "op": "eq", "value": "VIP"}]}`, 2},
+		{"ne includes missing", `{"conditions": [{"field": "country", "op": "ne", "value": "DE"}]}`, 2},
+		{"not in", `{"conditions": [{"field": "plan", "op": "not_in", "values": ["pro", "free"]}]}`, 2},
+		{"gt number and numeric string", `{"conditions": [{"field": "orders", "op": "gt", "value": "10"}]}`, 2},
+		{"lt", `{"conditions": [{"field": "orders", "op": "lt", "value": "10"}]}`, 1},
+		{"nested variable", `{"conditions": [{"field": "address.city", "op": "eq", "value": "berlin"}]}`, 1},
+		{"contains email", `{"conditions": [{"field": "email", "op": "contains", "value": ".de"}]}`, 2},
+		{"contains escapes wildcards", `{"conditions": [{"field": "email", "op": "contains", "value": "%"}]}`, 0},
+		{"name exists", `{"conditions": [{"field": "name", "op": "exists"}]}`, 2},
+		{"variable not exists", `{"conditions": [{"field": "plan", "op": "not_exists"}]}`, 1},
+		{"tag not in", `{"conditions": [{"field": "tag", "op": "not_in", "values": ["beta"]}]}`, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := models.ParseSegmentRules(tt.rules)
+			if err != nil {
+				t.Fatalf("ParseSegmentRules() error = %v", err)
+			}
+			got, err := repo.CountSegment(list.ID, rules)
+			if err != nil {
+				t.Fatalf("CountSegment() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("CountSegment() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	// Rules select recipients together with the other filters
+	rules, _ := models.ParseSegmentRules(`{"conditions": [{"field": "country", "op": "eq", "value": "DE"}]}`)
+	recipients, total, err := repo.ListRecipients(models.RecipientFilter{ListID: list.ID, Status: "active", Rules: rules, Limit: 10})
+	if err != nil || total != 2 || len(recipients) != 2 {
+		t.Fatalf("ListRecipients() = %d recipients, total %d, error %v", len(recipients), total, err)
+	}
+
+	segment := &models.Segment{ListID: list.ID, Name: "Germany", Rules: `{"conditions": [{"field": "country", "op": "eq", "value": "DE"}]}`}
+	if err := repo.CreateSegment(segment); err != nil {
+		t.Fatalf("CreateSegment() error = %v", err)
+	}
+	segment.Rules = `{"conditions": [{"field": "tag", "op": "eq", "value": "vip"}]}`
+	if err := repo.UpdateSegment(segment); err != nil {
+		t.Fatalf("UpdateSegment() error = %v", err)
+	}
+	segments, err := repo.ListSegments(list.ID)
+	if err != nil || len(segments) != 1 || segments[0].Count != 2 {
+		t.Fatalf("ListSegments() = %+v, %v", segments, err)
+	}
+
+	if err := repo.DeleteList(list.ID); err != nil {
+		t.Fatalf("DeleteList() error = %v", err)
+	}
+	if s, err := repo.GetSegment(segment.ID); err != nil || s != nil {
+		t.Errorf("GetSegment() after list delete = %+v, %v", s, err)
+	}
+}
@@ -84,6 +84,10 @@ func (r *SendRepository) List(filter models.SendFilter) ([]models.SendWithDetail
 		countQuery += " AND api_key_id = ?"
 		args = append(args, filter.APIKeyID)
 	}
+	if filter.OrgID != "" {
+		countQuery += " AND api_key_id IN (SELECT id FROM api_keys WHERE org_id = ?)"
+		args = append(args, filter.OrgID)
+	}
 	if filter.Status != "" {
 		countQuery += " AND status = ?"
 		args = append(args, filter.Status)
@@ -135,6 +139,10 @@ func (r *SendRepository) List(filter models.SendFilter) ([]models.SendWithDetail
 		query += " AND s.api_key_id = ?"
 		queryArgs = append(queryArgs, filter.APIKeyID)
 	}
+	if filter.OrgID != "" {
+		query += " AND k.org_id = ?"
+		queryArgs = append(queryArgs, filter.OrgID)
+	}
 	if filter.Status != "" {
 		query += " AND s.status = ?"
 		queryArgs = append(queryArgs, filter.Status)
@@ -230,6 +238,10 @@ func (r *SendRepository) GetStats(filter models.SendFilter) (*models.SendStats,
 		FROM sends WHERE 1=1`
 
 	args := []any{}
+	if filter.OrgID != "" {
+		query += " AND api_key_id IN (SELECT id FROM api_keys WHERE org_id = ?)"
+		args = append(args, filter.OrgID)
+	}
 	if filter.SenderDomain != "" {
 		query += " AND sender_domain = ?"
 		args = append(args, filter.SenderDomain)
@@ -410,3 +422,41 @@ func FromJSON(s string) []string {
 	json.Unmarshal([]byte(s), &arr)
 	return arr
 }
+
+// ListByRecipient returns the API sends to, cc or bcc an email, ignoring
+// case, newest first
+func (r *SendRepository) ListByRecipient(email string, limit int) ([]models.HistoryMessage, error) {
+	rows, err := r.db.Query(`
+		SELECT s.id, COALESCE(s.subject, ''), s.server_name, COALESCE(s.server_msg_id, ''), s.status,
+			COALESCE(s.error_message, ''), s.created_at, s.sent_at
+		FROM sends s
+		WHERE EXISTS (
+			SELECT 1 FROM json_each(CASE WHEN json_valid(s.to_addresses) THEN s.to_addresses ELSE '[]' END) WHERE value = ? COLLATE NOCASE
+			UNION ALL
+			SELECT 1 FROM json_each(CASE WHEN json_valid(s.cc_addresses) THEN s.cc_addresses ELSE '[]' END) WHERE value = ? COLLATE NOCASE
+			UNION ALL
+			SELECT 1 FROM json_each(CASE WHEN json_valid(s.bcc_addresses) THEN s.bcc_addresses ELSE '[]' END) WHERE value = ? COLLATE NOCASE
+		)
+		ORDER BY s.created_at DESC
+		LIMIT ?`, email, email, email, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sends := []models.HistoryMessage{}
+	for rows.Next() {
+		m := models.HistoryMessage{Source: models.HistorySourceAPI}
+		var sentAt sql.NullTime
+		if err := rows.Scan(&m.ID, &m.Subject, &m.ServerName, &m.SendryMsgID, &m.Status,
+			&m.Error, &m.CreatedAt, &sentAt); err != nil {
+			return nil, err
+		}
+		if sentAt.Valid {
+			m.SentAt = &sentAt.Time
+		}
+		sends = append(sends, m)
+	}
+	return sends, rows.Err()
+}
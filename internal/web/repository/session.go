@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/foxzi/sendry/internal/web/models"
+)
+
+type SessionRepository struct {
+	db *sql.DB
+}
+
+func NewSessionRepository(db *sql.DB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+// SessionHandle returns the public handle of a session, so that session
+// cookies are never rendered into pages
+func SessionHandle(sessionID string) string {
+	sum := sha256.Sum256([]byte(sessionID))
+	return hex.EncodeToString(sum[:8])
+}
+
+// ListByUser returns the unexpired sessions of a user, most recently used
+// first. The session with the current ID is marked.
+func (r *SessionRepository) ListByUser(userID, currentID string) ([]models.Session, error) {
+	rows, err := r.db.Query(`
+		SELECT id, auth_method, ip, user_agent, created_at, last_seen_at, expires_at
+		FROM sessions
+		WHERE user_id = ? AND expires_at > datetime('now')
+		ORDER BY COALESCE(last_seen_at, created_at) DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []models.Session
+	for rows.Next() {
+		var s models.Session
+		var id string
+		var lastSeenAt sql.NullTime
+		if err := rows.Scan(&id, &s.AuthMethod, &s.IP, &s.UserAgent, &s.CreatedAt, &lastSeenAt, &s.ExpiresAt); err != nil {
+			return nil, err
+		}
+		s.ID = SessionHandle(id)
+		s.Current = id == currentID
+		if lastSeenAt.Valid {
+			s.LastSeenAt = &lastSeenAt.Time
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
+
+// Delete ends the session of a user with the given handle. It returns false
+// if the user has no such session.
+func (r *SessionRepository) Delete(userID, handle string) (bool, error) {
+	rows, err := r.db.Query("SELECT id FROM sessions WHERE user_id = ?", userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	var target string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return false, err
+		}
+		if SessionHandle(id) == handle {
+			target = id
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+	if target == "" {
+		return false, nil
+	}
+
+	if _, err := r.db.Exec("DELETE FROM sessions WHERE id = ? AND user_id = ?", target, userID); err != nil {
+		return false, fmt.Errorf("failed to delete session: %w", err)
+	}
+	return true, nil
+}
+
+// DeleteOthers ends all sessions of a user except the one with keepID and
+// returns how many were ended
+func (r *SessionRepository) DeleteOthers(userID, keepID string) (int64, error) {
+	res, err := r.db.Exec("DELETE FROM sessions WHERE user_id = ? AND id != ?", userID, keepID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete sessions: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// SetOrganization selects the organization a session works in
+func (r *SessionRepository) SetOrganization(sessionID, orgID string) error {
+	if _, err := r.db.Exec("UPDATE sessions SET org_id = ? WHERE id = ?", orgID, sessionID); err != nil {
+		return fmt.Errorf("failed to set session organization: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionRepository(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewSessionRepository(db)
+
+	for _, u := range []string{"ann", "bob"} {
+		if _, err := db.Exec("INSERT INTO users (id, email, password_hash) VALUES (?, ?, '')", u, u+"@example.com"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expires := time.Now().Add(time.Hour)
+	for _, s := range []struct{ id, user string }{{"s1", "ann"}, {"s2", "ann"}, {"s3", "ann"}, {"s4", "bob"}} {
+		if _, err := db.Exec("INSERT INTO sessions (id, user_id, expires_at, ip, user_agent) VALUES (?, ?, ?, '192.0.2.1', 'test')",
+			s.id, s.user, expires); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.Exec("INSERT INTO sessions (id, user_id, expires_at) VALUES ('old', 'ann', ?)", time.Now().Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	sessions, err := repo.ListByUser("ann", "s1")
+	if err != nil {
+		t.Fatalf("ListByUser() error = %v", err)
+	}
+	if len(sessions) != 3 {
+		t.Fatalf("ListByUser() = %d sessions, want 3 unexpired", len(sessions))
+	}
+	for _, s := range sessions {
+		if s.Current != (s.ID == SessionHandle("s1")) {
+			t.Errorf("session %s current = %v", s.ID, s.Current)
+		}
+	}
+
+	// Sessions of other users are not deleted by handle
+	if found, err := repo.Delete("ann", SessionHandle("s4")); err != nil || found {
+		t.Errorf("Delete(other user) = %v, %v, want false", found, err)
+	}
+	if found, err := repo.Delete("ann", SessionHandle("s2")); err != nil || !found {
+		t.Errorf("Delete() = %v, %v, want true", found, err)
+	}
+
+	n, err := repo.DeleteOthers("ann", "s1")
+	if err != nil || n != 2 {
+		t.Errorf("DeleteOthers() = %d, %v, want 2 (s3 and the expired one)", n, err)
+	}
+	sessions, _ = repo.ListByUser("ann", "s1")
+	if len(sessions) != 1 || !sessions[0].Current {
+		t.Errorf("after DeleteOthers() sessions = %+v, want the current one", sessions)
+	}
+	if bob, _ := repo.ListByUser("bob", ""); len(bob) != 1 {
+		t.Errorf("bob sessions = %d, want 1", len(bob))
+	}
+}
@@ -150,6 +150,9 @@ func (r *SettingsRepository) CreateUser(email, name, password string, role model
 	if err != nil {
 		return nil, err
 	}
+	if err := joinSoleOrganization(r.db, id); err != nil {
+		return nil, fmt.Errorf("failed to join organization: %w", err)
+	}
 	return &models.User{ID: id, Email: email, Name: name, Role: role, CreatedAt: now, UpdatedAt: now}, nil
 }
 
@@ -35,9 +35,9 @@ func (r *TemplateRepository) Create(t *models.Template, createdBy string) error
 
 	// Insert template
 	_, err = tx.Exec(`
-		INSERT INTO templates (id, name, description, subject, html, text, variables, folder, current_version, use_blocks, container_radius, container_transparent, container_width, container_padding_v, container_padding_h, page_background, container_radius_top, container_radius_bottom, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		t.ID, t.Name, t.Description, t.Subject, t.HTML, t.Text, t.Variables, t.Folder, t.CurrentVersion, t.UseBlocks, t.ContainerRadius, t.ContainerTransparent, t.ContainerWidth, t.ContainerPaddingV, t.ContainerPaddingH, t.PageBackground, t.ContainerRadiusTop, t.ContainerRadiusBottom, t.CreatedAt, t.UpdatedAt,
+		INSERT INTO templates (id, name, description, subject, html, text, variables, folder, current_version, use_blocks, container_radius, container_transparent, container_width, container_padding_v, container_padding_h, page_background, container_radius_top, container_radius_bottom, block_external, org_id, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		t.ID, t.Name, t.Description, t.Subject, t.HTML, t.Text, t.Variables, t.Folder, t.CurrentVersion, t.UseBlocks, t.ContainerRadius, t.ContainerTransparent, t.ContainerWidth, t.ContainerPaddingV, t.ContainerPaddingH, t.PageBackground, t.ContainerRadiusTop, t.ContainerRadiusBottom, t.BlockExternal, nullString(t.OrgID), t.CreatedAt, t.UpdatedAt,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to create template: %w", err)
@@ -60,9 +60,9 @@ func (r *TemplateRepository) Create(t *models.Template, createdBy string) error
 func (r *TemplateRepository) GetByID(id string) (*models.Template, error) {
 	t := &models.Template{}
 	err := r.db.QueryRow(`
-		SELECT id, name, description, subject, html, text, variables, folder, current_version, use_blocks, container_radius, container_transparent, container_width, container_padding_v, container_padding_h, page_background, container_radius_top, container_radius_bottom, created_at, updated_at
+		SELECT id, name, description, subject, html, text, variables, folder, COALESCE(org_id, ''), current_version, use_blocks, container_radius, container_transparent, container_width, container_padding_v, container_padding_h, page_background, container_radius_top, container_radius_bottom, block_external, created_at, updated_at
 		FROM templates WHERE id = ?`, id,
-	).Scan(&t.ID, &t.Name, &t.Description, &t.Subject, &t.HTML, &t.Text, &t.Variables, &t.Folder, &t.CurrentVersion, &t.UseBlocks, &t.ContainerRadius, &t.ContainerTransparent, &t.ContainerWidth, &t.ContainerPaddingV, &t.ContainerPaddingH, &t.PageBackground, &t.ContainerRadiusTop, &t.ContainerRadiusBottom, &t.CreatedAt, &t.UpdatedAt)
+	).Scan(&t.ID, &t.Name, &t.Description, &t.Subject, &t.HTML, &t.Text, &t.Variables, &t.Folder, &t.OrgID, &t.CurrentVersion, &t.UseBlocks, &t.ContainerRadius, &t.ContainerTransparent, &t.ContainerWidth, &t.ContainerPaddingV, &t.ContainerPaddingH, &t.PageBackground, &t.ContainerRadiusTop, &t.ContainerRadiusBottom, &t.BlockExternal, &t.CreatedAt, &t.UpdatedAt)
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -77,9 +77,9 @@ func (r *TemplateRepository) GetByID(id string) (*models.Template, error) {
 func (r *TemplateRepository) GetByName(name string) (*models.Template, error) {
 	t := &models.Template{}
 	err := r.db.QueryRow(`
-		SELECT id, name, description, subject, html, text, variables, folder, current_version, created_at, updated_at
+		SELECT id, name, description, subject, html, text, variables, folder, COALESCE(org_id, ''), current_version, created_at, updated_at
 		FROM templates WHERE name = ?`, name,
-	).Scan(&t.ID, &t.Name, &t.Description, &t.Subject, &t.HTML, &t.Text, &t.Variables, &t.Folder, &t.CurrentVersion, &t.CreatedAt, &t.UpdatedAt)
+	).Scan(&t.ID, &t.Name, &t.Description, &t.Subject, &t.HTML, &t.Text, &t.Variables, &t.Folder, &t.OrgID, &t.CurrentVersion, &t.CreatedAt, &t.UpdatedAt)
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -96,6 +96,10 @@ func (r *TemplateRepository) List(filter models.TemplateListFilter) ([]models.Te
 	countQuery := "SELECT COUNT(*) FROM templates WHERE 1=1"
 	args := []any{}
 
+	if filter.OrgID != "" {
+		countQuery += " AND org_id = ?"
+		args = append(args, filter.OrgID)
+	}
 	if filter.Search != "" {
 		countQuery += " AND (name LIKE ? OR description LIKE ?)"
 		args = append(args, "%"+filter.Search+"%", "%"+filter.Search+"%")
@@ -112,7 +116,7 @@ func (r *TemplateRepository) List(filter models.TemplateListFilter) ([]models.Te
 
 	// Get templates
 	query := `
-		SELECT t.id, t.name, t.description, t.subject, t.html, t.text, t.variables, t.folder, t.current_version, t.use_blocks, t.container_radius, t.container_transparent, t.container_width, t.container_padding_v, t.container_padding_h, t.page_background, t.container_radius_top, t.container_radius_bottom, t.created_at, t.updated_at,
+		SELECT t.id, t.name, t.description, t.subject, t.html, t.text, t.variables, t.folder, COALESCE(t.org_id, ''), t.current_version, t.use_blocks, t.container_radius, t.container_transparent, t.container_width, t.container_padding_v, t.container_padding_h, t.page_background, t.container_radius_top, t.container_radius_bottom, t.block_external, t.created_at, t.updated_at,
 			COALESCE(d.deployed_count, 0) as deployed_count,
 			COALESCE(d.out_of_sync_count, 0) as out_of_sync_count
 		FROM templates t
@@ -126,6 +130,10 @@ func (r *TemplateRepository) List(filter models.TemplateListFilter) ([]models.Te
 		WHERE 1=1`
 
 	args = []any{}
+	if filter.OrgID != "" {
+		query += " AND t.org_id = ?"
+		args = append(args, filter.OrgID)
+	}
 	if filter.Search != "" {
 		query += " AND (t.name LIKE ? OR t.description LIKE ?)"
 		args = append(args, "%"+filter.Search+"%", "%"+filter.Search+"%")
@@ -157,7 +165,7 @@ func (r *TemplateRepository) List(filter models.TemplateListFilter) ([]models.Te
 		var t models.TemplateWithStatus
 		err := rows.Scan(
 			&t.ID, &t.Name, &t.Description, &t.Subject, &t.HTML, &t.Text,
-			&t.Variables, &t.Folder, &t.CurrentVersion, &t.UseBlocks, &t.ContainerRadius, &t.ContainerTransparent, &t.ContainerWidth, &t.ContainerPaddingV, &t.ContainerPaddingH, &t.PageBackground, &t.ContainerRadiusTop, &t.ContainerRadiusBottom, &t.CreatedAt, &t.UpdatedAt,
+			&t.Variables, &t.Folder, &t.OrgID, &t.CurrentVersion, &t.UseBlocks, &t.ContainerRadius, &t.ContainerTransparent, &t.ContainerWidth, &t.ContainerPaddingV, &t.ContainerPaddingH, &t.PageBackground, &t.ContainerRadiusTop, &t.ContainerRadiusBottom, &t.BlockExternal, &t.CreatedAt, &t.UpdatedAt,
 			&t.DeployedCount, &t.OutOfSyncCount,
 		)
 		if err != nil {
@@ -200,9 +208,9 @@ func (r *TemplateRepository) Update(t *models.Template, changeNote, updatedBy st
 
 	// Update template
 	_, err = tx.Exec(`
-		UPDATE templates SET name = ?, description = ?, subject = ?, html = ?, text = ?, variables = ?, folder = ?, current_version = ?, use_blocks = ?, container_radius = ?, container_transparent = ?, container_width = ?, container_padding_v = ?, container_padding_h = ?, page_background = ?, container_radius_top = ?, container_radius_bottom = ?, updated_at = ?
+		UPDATE templates SET name = ?, description = ?, subject = ?, html = ?, text = ?, variables = ?, folder = ?, current_version = ?, use_blocks = ?, container_radius = ?, container_transparent = ?, container_width = ?, container_padding_v = ?, container_padding_h = ?, page_background = ?, container_radius_top = ?, container_radius_bottom = ?, block_external = ?, updated_at = ?
 		WHERE id = ?`,
-		t.Name, t.Description, t.Subject, t.HTML, t.Text, t.Variables, t.Folder, t.CurrentVersion, t.UseBlocks, t.ContainerRadius, t.ContainerTransparent, t.ContainerWidth, t.ContainerPaddingV, t.ContainerPaddingH, t.PageBackground, t.ContainerRadiusTop, t.ContainerRadiusBottom, t.UpdatedAt, t.ID,
+		t.Name, t.Description, t.Subject, t.HTML, t.Text, t.Variables, t.Folder, t.CurrentVersion, t.UseBlocks, t.ContainerRadius, t.ContainerTransparent, t.ContainerWidth, t.ContainerPaddingV, t.ContainerPaddingH, t.PageBackground, t.ContainerRadiusTop, t.ContainerRadiusBottom, t.BlockExternal, t.UpdatedAt, t.ID,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to update template: %w", err)
@@ -267,30 +275,57 @@ func (r *TemplateRepository) GetVersion(templateID string, version int) (*models
 	return v, nil
 }
 
+const deploymentColumns = `id, template_id, server_name, COALESCE(remote_id, ''), deployed_version, deployed_at,
+	content_hash, drift, drift_checked_at`
+
+func scanDeployment(s interface{ Scan(...any) error }) (*models.TemplateDeployment, error) {
+	var d models.TemplateDeployment
+	var checkedAt sql.NullTime
+	err := s.Scan(&d.ID, &d.TemplateID, &d.ServerName, &d.RemoteID, &d.DeployedVersion, &d.DeployedAt,
+		&d.ContentHash, &d.Drift, &checkedAt)
+	if err != nil {
+		return nil, err
+	}
+	if checkedAt.Valid {
+		d.DriftCheckedAt = &checkedAt.Time
+	}
+	return &d, nil
+}
+
 // GetDeployment returns a single deployment for a template on a specific server
 func (r *TemplateRepository) GetDeployment(templateID, serverName string) (*models.TemplateDeployment, error) {
-	var d models.TemplateDeployment
-	err := r.db.QueryRow(`
-		SELECT id, template_id, server_name, remote_id, deployed_version, deployed_at
+	d, err := scanDeployment(r.db.QueryRow(`
+		SELECT `+deploymentColumns+`
 		FROM template_deployments WHERE template_id = ? AND server_name = ?`,
 		templateID, serverName,
-	).Scan(&d.ID, &d.TemplateID, &d.ServerName, &d.RemoteID, &d.DeployedVersion, &d.DeployedAt)
-
+	))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, err
 	}
-	return &d, nil
+	return d, nil
 }
 
 // GetDeployments returns all deployments for a template
 func (r *TemplateRepository) GetDeployments(templateID string) ([]models.TemplateDeployment, error) {
-	rows, err := r.db.Query(`
-		SELECT id, template_id, server_name, remote_id, deployed_version, deployed_at
+	return r.queryDeployments(`
+		SELECT `+deploymentColumns+`
 		FROM template_deployments WHERE template_id = ? ORDER BY server_name`, templateID,
 	)
+}
+
+// ListServerDeployments returns all template deployments on a server
+func (r *TemplateRepository) ListServerDeployments(serverName string) ([]models.TemplateDeployment, error) {
+	return r.queryDeployments(`
+		SELECT `+deploymentColumns+`
+		FROM template_deployments WHERE server_name = ? ORDER BY template_id`, serverName,
+	)
+}
+
+func (r *TemplateRepository) queryDeployments(query string, args ...any) ([]models.TemplateDeployment, error) {
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -298,26 +333,41 @@ func (r *TemplateRepository) GetDeployments(templateID string) ([]models.Templat
 
 	deployments := []models.TemplateDeployment{}
 	for rows.Next() {
-		var d models.TemplateDeployment
-		err := rows.Scan(&d.ID, &d.TemplateID, &d.ServerName, &d.RemoteID, &d.DeployedVersion, &d.DeployedAt)
+		d, err := scanDeployment(rows)
 		if err != nil {
 			return nil, err
 		}
-		deployments = append(deployments, d)
+		deployments = append(deployments, *d)
 	}
-	return deployments, nil
+	return deployments, rows.Err()
 }
 
-// SaveDeployment saves or updates a deployment record
+// SaveDeployment saves or updates a deployment record. The content is
+// taken as in sync with the server.
 func (r *TemplateRepository) SaveDeployment(d *models.TemplateDeployment) error {
 	_, err := r.db.Exec(`
-		INSERT INTO template_deployments (template_id, server_name, remote_id, deployed_version, deployed_at)
-		VALUES (?, ?, ?, ?, ?)
+		INSERT INTO template_deployments (template_id, server_name, remote_id, deployed_version, deployed_at, content_hash, drift)
+		VALUES (?, ?, ?, ?, ?, ?, '')
 		ON CONFLICT(template_id, server_name) DO UPDATE SET
 			remote_id = excluded.remote_id,
 			deployed_version = excluded.deployed_version,
-			deployed_at = excluded.deployed_at`,
-		d.TemplateID, d.ServerName, d.RemoteID, d.DeployedVersion, time.Now(),
+			deployed_at = excluded.deployed_at,
+			content_hash = excluded.content_hash,
+			drift = ''`,
+		d.TemplateID, d.ServerName, d.RemoteID, d.DeployedVersion, time.Now(), d.ContentHash,
+	)
+	return err
+}
+
+// SetDeploymentDrift records the result of a drift check of a deployment.
+// A non-empty contentHash replaces the stored hash, for deployments made
+// before hashes were recorded.
+func (r *TemplateRepository) SetDeploymentDrift(id int64, drift, contentHash string) error {
+	_, err := r.db.Exec(`
+		UPDATE template_deployments
+		SET drift = ?, drift_checked_at = ?, content_hash = CASE WHEN ? = '' THEN content_hash ELSE ? END
+		WHERE id = ?`,
+		drift, time.Now(), contentHash, contentHash, id,
 	)
 	return err
 }
@@ -397,6 +447,34 @@ func (r *TemplateRepository) GetTemplatesByBlockID(blockID string) ([]models.Tem
 	return templates, rows.Err()
 }
 
+// GetTemplatesBySenderDomain returns the templates of campaigns sending
+// from an address at domain
+func (r *TemplateRepository) GetTemplatesBySenderDomain(domain string) ([]models.Template, error) {
+	rows, err := r.db.Query(`
+		SELECT DISTINCT t.id, t.name, t.description, t.subject, t.html, t.text, t.variables, t.folder, t.current_version, t.use_blocks, t.block_external, t.created_at, t.updated_at
+		FROM templates t
+		INNER JOIN campaign_variants v ON v.template_id = t.id
+		INNER JOIN campaigns c ON c.id = v.campaign_id
+		WHERE lower(rtrim(c.from_email, '>')) LIKE '%@' || lower(?)
+		ORDER BY t.name`,
+		domain,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := []models.Template{}
+	for rows.Next() {
+		var t models.Template
+		if err := rows.Scan(&t.ID, &t.Name, &t.Description, &t.Subject, &t.HTML, &t.Text, &t.Variables, &t.Folder, &t.CurrentVersion, &t.UseBlocks, &t.BlockExternal, &t.CreatedAt, &t.UpdatedAt); err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+	return templates, rows.Err()
+}
+
 // GetFolders returns distinct folder names
 func (r *TemplateRepository) GetFolders() ([]string, error) {
 	rows, err := r.db.Query("SELECT DISTINCT folder FROM templates WHERE folder != '' ORDER BY folder")
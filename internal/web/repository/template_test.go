@@ -140,6 +140,7 @@ func TestTemplateRepository_Update(t *testing.T) {
 	// Update
 	tmpl.Name = "Updated Name"
 	tmpl.Subject = "Updated Subject"
+	tmpl.BlockExternal = true
 
 	if err := repo.Update(tmpl, "Updated content", "test@example.com"); err != nil {
 		t.Fatalf("Update() error = %v", err)
@@ -159,6 +160,10 @@ func TestTemplateRepository_Update(t *testing.T) {
 		t.Errorf("Update() Subject = %v, want %v", got.Subject, "Updated Subject")
 	}
 
+	if !got.BlockExternal {
+		t.Error("Update() BlockExternal = false, want true")
+	}
+
 	// Check version incremented
 	if got.CurrentVersion != 2 {
 		t.Errorf("Update() CurrentVersion = %d, want 2", got.CurrentVersion)
@@ -326,3 +331,35 @@ func TestTemplateRepository_Folders(t *testing.T) {
 		t.Errorf("GetFolders() returned %d folders, want 3", len(got))
 	}
 }
+
+func TestTemplateRepository_GetTemplatesBySenderDomain(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewTemplateRepository(db)
+	campaigns := NewCampaignRepository(db)
+
+	for _, c := range []struct{ template, from string }{
+		{"Welcome", "news@brand.com"},
+		{"Receipt", "Shop <shop@Brand.com>"},
+		{"Other", "info@other.com"},
+	} {
+		tmpl := &models.Template{Name: c.template, Subject: "Subject"}
+		if err := repo.Create(tmpl, "test@example.com"); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		campaign := &models.Campaign{Name: c.template, FromEmail: c.from}
+		if err := campaigns.Create(campaign); err != nil {
+			t.Fatalf("Create() campaign error = %v", err)
+		}
+		if err := campaigns.AddVariant(&models.CampaignVariant{CampaignID: campaign.ID, Name: "A", TemplateID: tmpl.ID, Weight: 100}); err != nil {
+			t.Fatalf("AddVariant() error = %v", err)
+		}
+	}
+
+	got, err := repo.GetTemplatesBySenderDomain("brand.com")
+	if err != nil {
+		t.Fatalf("GetTemplatesBySenderDomain() error = %v", err)
+	}
+	if len(got) != 2 || got[0].Name != "Receipt" || got[1].Name != "Welcome" {
+		t.Errorf("GetTemplatesBySenderDomain() = %+v, want Receipt and Welcome", got)
+	}
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/foxzi/sendry/internal/web/models"
+)
+
+// topLinks is the number of links in engagement stats
+const topLinks = 10
+
+type TrackingRepository struct {
+	db *sql.DB
+}
+
+func NewTrackingRepository(db *sql.DB) *TrackingRepository {
+	return &TrackingRepository{db: db}
+}
+
+// Record stores an open or click of a job item. The job and campaign are
+// taken from the item. It returns false if the item does not exist.
+func (r *TrackingRepository) Record(e *models.TrackingEvent) (bool, error) {
+	res, err := r.db.Exec(`
+		INSERT INTO tracking_events (item_id, job_id, campaign_id, type, url, ip_address, user_agent)
+		SELECT i.id, i.job_id, j.campaign_id, ?, ?, ?, ?
+		FROM send_job_items i JOIN send_jobs j ON j.id = i.job_id
+		WHERE i.id = ?`,
+		e.Type, e.URL, e.IPAddress, e.UserAgent, e.ItemID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to record tracking event: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// JobStats returns the engagement stats of a job
+func (r *TrackingRepository) JobStats(jobID string) (models.EngagementStats, error) {
+	return r.stats("job_id", jobID)
+}
+
+// CampaignStats returns the engagement stats of all jobs of a campaign
+func (r *TrackingRepository) CampaignStats(campaignID string) (models.EngagementStats, error) {
+	return r.stats("campaign_id", campaignID)
+}
+
+// stats aggregates the events with the given job_id or campaign_id
+func (r *TrackingRepository) stats(column, id string) (models.EngagementStats, error) {
+	var s models.EngagementStats
+
+	delivered := `SELECT COUNT(*) FROM send_job_items WHERE status IN ('queued', 'sent') AND job_id = ?`
+	if column == "campaign_id" {
+		delivered = `SELECT COUNT(*) FROM send_job_items i JOIN send_jobs j ON j.id = i.job_id
+			WHERE i.status IN ('queued', 'sent') AND j.campaign_id = ?`
+	}
+	if err := r.db.QueryRow(delivered, id).Scan(&s.Delivered); err != nil {
+		return s, err
+	}
+
+	err := r.db.QueryRow(`
+		SELECT
+			COALESCE(SUM(CASE WHEN type = 'open' THEN 1 ELSE 0 END), 0),
+			COUNT(DISTINCT CASE WHEN type = 'open' THEN item_id END),
+			COALESCE(SUM(CASE WHEN type = 'click' THEN 1 ELSE 0 END), 0),
+			COUNT(DISTINCT CASE WHEN type = 'click' THEN item_id END)
+		FROM tracking_events WHERE `+column+` = ?`, id,
+	).Scan(&s.Opens, &s.UniqueOpens, &s.Clicks, &s.UniqueClicks)
+	if err != nil {
+		return s, err
+	}
+
+	rows, err := r.db.Query(`
+		SELECT url, COUNT(*), COUNT(DISTINCT item_id)
+		FROM tracking_events WHERE `+column+` = ? AND type = 'click'
+		GROUP BY url ORDER BY COUNT(*) DESC, url LIMIT ?`, id, topLinks)
+	if err != nil {
+		return s, err
+	}
+	defer rows.Close()
+
+	s.Links = []models.LinkStats{}
+	for rows.Next() {
+		var l models.LinkStats
+		if err := rows.Scan(&l.URL, &l.Clicks, &l.UniqueClicks); err != nil {
+			return s, err
+		}
+		s.Links = append(s.Links, l)
+	}
+	return s, rows.Err()
+}
+
+// ListByEmail returns the opens and clicks of the job items sent to an
+// email, ignoring case, newest first
+func (r *TrackingRepository) ListByEmail(email string, limit int) ([]models.TrackingEvent, error) {
+	rows, err := r.db.Query(`
+		SELECT e.id, e.item_id, e.job_id, e.campaign_id, e.type, e.url,
+			COALESCE(e.ip_address, ''), COALESCE(e.user_agent, ''), e.created_at
+		FROM tracking_events e
+		JOIN send_job_items i ON i.id = e.item_id
+		JOIN recipients r ON r.id = i.recipient_id
+		WHERE r.email = ? COLLATE NOCASE
+		ORDER BY e.created_at DESC
+		LIMIT ?`, email, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.TrackingEvent{}
+	for rows.Next() {
+		var e models.TrackingEvent
+		if err := rows.Scan(&e.ID, &e.ItemID, &e.JobID, &e.CampaignID, &e.Type, &e.URL,
+			&e.IPAddress, &e.UserAgent, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/foxzi/sendry/internal/web/models"
+)
+
+func TestTrackingRepository_Stats(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewTrackingRepository(db)
+
+	seed := []string{
+		`INSERT INTO recipient_lists (id, name, source_type) VALUES ('list', 'List', 'manual')`,
+		`INSERT INTO recipients (id, list_id, email) VALUES ('r1', 'list', 'a@example.com'), ('r2', 'list', 'b@example.com'), ('r3', 'list', 'c@example.com')`,
+		`INSERT INTO campaigns (id, name, from_email) VALUES ('camp', 'Sale', 'news@example.com')`,
+		`INSERT INTO send_jobs (id, campaign_id, recipient_list_id) VALUES ('job1', 'camp', 'list'), ('job2', 'camp', 'list')`,
+		`INSERT INTO send_job_items (id, job_id, recipient_id, status) VALUES
+			('i1', 'job1', 'r1', 'sent'), ('i2', 'job1', 'r2', 'queued'), ('i3', 'job1', 'r3', 'failed'),
+			('i4', 'job2', 'r1', 'sent')`,
+	}
+	for _, q := range seed {
+		if _, err := db.Exec(q); err != nil {
+			t.Fatalf("seed failed: %v", err)
+		}
+	}
+
+	events := []models.TrackingEvent{
+		{ItemID: "i1", Type: models.TrackingOpen},
+		{ItemID: "i1", Type: models.TrackingOpen},
+		{ItemID: "i2", Type: models.TrackingOpen},
+		{ItemID: "i1", Type: models.TrackingClick, URL: "https://example.com/sale"},
+		{ItemID: "i1", Type: models.TrackingClick, URL: "https://example.com/sale"},
+		{ItemID: "i2", Type: models.TrackingClick, URL: "https://example.com/sale"},
+		{ItemID: "i2", Type: models.TrackingClick, URL: "https://example.com/help"},
+		{ItemID: "i4", Type: models.TrackingOpen},
+	}
+	for _, e := range events {
+		if ok, err := repo.Record(&e); err != nil || !ok {
+			t.Fatalf("Record(%s, %s) = %v, %v", e.ItemID, e.Type, ok, err)
+		}
+	}
+	if ok, err := repo.Record(&models.TrackingEvent{ItemID: "missing", Type: models.TrackingOpen}); err != nil || ok {
+		t.Errorf("Record(missing item) = %v, %v, want false", ok, err)
+	}
+
+	s, err := repo.JobStats("job1")
+	if err != nil {
+		t.Fatalf("JobStats() error = %v", err)
+	}
+	if s.Delivered != 2 || s.Opens != 3 || s.UniqueOpens != 2 || s.Clicks != 4 || s.UniqueClicks != 2 {
+		t.Errorf("JobStats() = %+v", s)
+	}
+	if len(s.Links) != 2 || s.Links[0].URL != "https://example.com/sale" || s.Links[0].Clicks != 3 || s.Links[0].UniqueClicks != 2 {
+		t.Errorf("JobStats() links = %+v", s.Links)
+	}
+	if s.OpenRate() != 1 || s.ClickRate() != 1 {
+		t.Errorf("rates = %v, %v, want 1", s.OpenRate(), s.ClickRate())
+	}
+
+	c, err := repo.CampaignStats("camp")
+	if err != nil {
+		t.Fatalf("CampaignStats() error = %v", err)
+	}
+	if c.Delivered != 3 || c.Opens != 4 || c.UniqueOpens != 3 || c.Clicks != 4 {
+		t.Errorf("CampaignStats() = %+v", c)
+	}
+
+	empty, err := repo.JobStats("none")
+	if err != nil || empty.Opens != 0 || empty.OpenRate() != 0 || len(empty.Links) != 0 {
+		t.Errorf("JobStats(none) = %+v, %v", empty, err)
+	}
+}
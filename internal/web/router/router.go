@@ -14,6 +14,7 @@ import (
 	"github.com/foxzi/sendry/internal/web/repository"
 	"github.com/foxzi/sendry/internal/web/sendry"
 	emailtpl "github.com/foxzi/sendry/internal/web/template"
+	sendryclient "github.com/foxzi/sendry/pkg/client"
 )
 
 var (
@@ -52,7 +53,7 @@ type APISendResponse struct {
 // ServerInfo holds information about a server for routing
 type ServerInfo struct {
 	Name    string
-	Client  *sendry.Client
+	Client  *sendryclient.Client
 	Weight  int
 	Healthy bool
 }
@@ -241,8 +242,8 @@ func (r *EmailRouter) getDeployedServers(ctx context.Context, domain *models.Dom
 	return servers, nil
 }
 
-// buildSendRequest creates a sendry.SendRequest from APISendRequest
-func (r *EmailRouter) buildSendRequest(ctx context.Context, req *APISendRequest) (*sendry.SendRequest, string, error) {
+// buildSendRequest creates a sendryclient.SendRequest from APISendRequest
+func (r *EmailRouter) buildSendRequest(ctx context.Context, req *APISendRequest) (*sendryclient.SendRequest, string, error) {
 	// If template is specified, resolve it
 	if req.TemplateID != "" || req.TemplateName != "" {
 		return r.resolveTemplate(ctx, req)
@@ -253,7 +254,7 @@ func (r *EmailRouter) buildSendRequest(ctx context.Context, req *APISendRequest)
 		return nil, "", fmt.Errorf("%w: subject, body or html is required", ErrInvalidRequest)
 	}
 
-	return &sendry.SendRequest{
+	return &sendryclient.SendRequest{
 		From:    req.From,
 		To:      req.To,
 		CC:      req.CC,
@@ -266,7 +267,7 @@ func (r *EmailRouter) buildSendRequest(ctx context.Context, req *APISendRequest)
 }
 
 // resolveTemplate loads and renders a template
-func (r *EmailRouter) resolveTemplate(ctx context.Context, req *APISendRequest) (*sendry.SendRequest, string, error) {
+func (r *EmailRouter) resolveTemplate(ctx context.Context, req *APISendRequest) (*sendryclient.SendRequest, string, error) {
 	var tmpl *models.Template
 	var err error
 
@@ -313,7 +314,7 @@ func (r *EmailRouter) resolveTemplate(ctx context.Context, req *APISendRequest)
 		html = rewriteAssetURLs(html, r.publicURL, r.publicUploadURL)
 	}
 
-	return &sendry.SendRequest{
+	return &sendryclient.SendRequest{
 		From:    req.From,
 		To:      req.To,
 		CC:      req.CC,
@@ -388,7 +389,7 @@ func (r *EmailRouter) firstHealthySelect(servers []ServerInfo) *ServerInfo {
 }
 
 // sendWithFailover attempts to send with failover to other servers
-func (r *EmailRouter) sendWithFailover(ctx context.Context, req *sendry.SendRequest, servers []ServerInfo, primary *ServerInfo) (*sendry.SendResponse, string, error) {
+func (r *EmailRouter) sendWithFailover(ctx context.Context, req *sendryclient.SendRequest, servers []ServerInfo, primary *ServerInfo) (*sendryclient.SendResponse, string, error) {
 	failoverEnabled := r.cfg != nil && r.cfg.Failover.Enabled
 	maxRetries := 1
 	if failoverEnabled && r.cfg.Failover.MaxRetries > 0 {
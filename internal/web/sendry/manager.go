@@ -6,11 +6,13 @@ import (
 	"sync"
 
 	"github.com/foxzi/sendry/internal/web/config"
+	sendryclient "github.com/foxzi/sendry/pkg/client"
 )
 
 // Manager manages multiple Sendry server clients
 type Manager struct {
-	clients map[string]*Client
+	clients map[string]*sendryclient.Client
+	errs    map[string]error // Servers whose client could not be configured
 	servers []config.SendryServer
 	mu      sync.RWMutex
 }
@@ -18,22 +20,45 @@ type Manager struct {
 // NewManager creates a new Sendry manager
 func NewManager(servers []config.SendryServer) *Manager {
 	m := &Manager{
-		clients: make(map[string]*Client),
+		clients: make(map[string]*sendryclient.Client),
+		errs:    make(map[string]error),
 		servers: servers,
 	}
 
 	for _, s := range servers {
-		m.clients[s.Name] = NewClient(s.BaseURL, s.APIKey)
+		client, err := newServerClient(s)
+		if err != nil {
+			m.errs[s.Name] = err
+			continue
+		}
+		client.SetMetricsURL(s.MetricsURL)
+		m.clients[s.Name] = client
 	}
 
 	return m
 }
 
+// newServerClient creates the client of a server with its TLS settings
+func newServerClient(s config.SendryServer) (*sendryclient.Client, error) {
+	if !s.TLS.Enabled() {
+		return sendryclient.New(s.BaseURL, s.APIKey), nil
+	}
+	tlsConfig, err := s.TLS.ClientConfig()
+	if err != nil {
+		// Checked on config load, so this only happens when the files changed since
+		return nil, fmt.Errorf("server %q tls: %w", s.Name, err)
+	}
+	return sendryclient.NewWithTLS(s.BaseURL, s.APIKey, tlsConfig), nil
+}
+
 // GetClient returns a client by server name
-func (m *Manager) GetClient(name string) (*Client, error) {
+func (m *Manager) GetClient(name string) (*sendryclient.Client, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
+	if err, ok := m.errs[name]; ok {
+		return nil, err
+	}
 	client, ok := m.clients[name]
 	if !ok {
 		return nil, fmt.Errorf("server %q not found", name)
@@ -87,7 +112,12 @@ func (m *Manager) GetAllStatus(ctx context.Context) []*ServerStatus {
 				Env:     srv.Env,
 			}
 
-			client := m.clients[srv.Name]
+			client, ok := m.clients[srv.Name]
+			if !ok {
+				status.Error = m.errs[srv.Name].Error()
+				results[idx] = status
+				return
+			}
 			health, err := client.Health(ctx)
 			if err != nil {
 				status.Online = false
@@ -5,15 +5,25 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"strings"
 	"time"
 
+	"github.com/foxzi/sendry/internal/web/alert"
 	"github.com/foxzi/sendry/internal/web/auth"
+	"github.com/foxzi/sendry/internal/web/backup"
 	"github.com/foxzi/sendry/internal/web/config"
 	"github.com/foxzi/sendry/internal/web/db"
+	"github.com/foxzi/sendry/internal/web/dnspublish"
+	"github.com/foxzi/sendry/internal/web/drift"
 	"github.com/foxzi/sendry/internal/web/handlers"
+	"github.com/foxzi/sendry/internal/web/health"
 	"github.com/foxzi/sendry/internal/web/middleware"
+	"github.com/foxzi/sendry/internal/web/models"
+	"github.com/foxzi/sendry/internal/web/mtasts"
 	"github.com/foxzi/sendry/internal/web/repository"
+	"github.com/foxzi/sendry/internal/web/sendry"
 	"github.com/foxzi/sendry/internal/web/static"
+	"github.com/foxzi/sendry/internal/web/tracking"
 	"github.com/foxzi/sendry/internal/web/views"
 	"github.com/foxzi/sendry/internal/web/worker"
 )
@@ -26,6 +36,13 @@ type Server struct {
 	http   *http.Server
 	worker *worker.Worker
 	oidc   *auth.OIDCProvider
+
+	progress *worker.Progress
+	backups  *backup.Manager
+	drift    *drift.Checker
+	health   *health.Monitor
+	alerts   *alert.Engine
+	dns      *dnspublish.Publisher
 }
 
 func New(cfg *config.Config, logger *slog.Logger) (*Server, error) {
@@ -46,6 +63,17 @@ func New(cfg *config.Config, logger *slog.Logger) (*Server, error) {
 		return nil, fmt.Errorf("failed to initialize views: %w", err)
 	}
 
+	// Password sessions end when password login is disabled
+	if !cfg.Auth.LocalEnabled {
+		res, err := database.Exec("DELETE FROM sessions WHERE auth_method = 'local'")
+		if err != nil {
+			return nil, fmt.Errorf("failed to delete password sessions: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			logger.Info("password login disabled, ended password sessions", "sessions", n)
+		}
+	}
+
 	// Initialize OIDC provider if enabled
 	var oidcProvider *auth.OIDCProvider
 	if cfg.Auth.OIDC.Enabled {
@@ -58,12 +86,19 @@ func New(cfg *config.Config, logger *slog.Logger) (*Server, error) {
 
 	// Create server
 	s := &Server{
-		cfg:    cfg,
-		logger: logger,
-		db:     database,
-		views:  viewEngine,
-		oidc:   oidcProvider,
+		cfg:      cfg,
+		logger:   logger,
+		db:       database,
+		views:    viewEngine,
+		oidc:     oidcProvider,
+		progress: worker.NewProgress(),
+		backups:  backup.NewManager(cfg.Backup, database.DB, logger),
+		drift:    drift.New(cfg.TemplateSync, database.DB, sendry.NewManager(cfg.Sendry.Servers), logger),
+		health:   health.New(cfg.Health, database.DB, sendry.NewManager(cfg.Sendry.Servers), logger),
+		alerts:   alert.New(cfg.Alerts, database.DB, sendry.NewManager(cfg.Sendry.Servers), logger),
+		dns:      dnspublish.New(cfg.DNSPublishing, database.DB, logger),
 	}
+	s.health.SetAlerts(s.alerts)
 
 	if err := runWrapperRebuildMigration(database, viewEngine, cfg, oidcProvider, logger); err != nil {
 		logger.Error("wrapper rebuild migration failed", "error", err)
@@ -80,6 +115,7 @@ func New(cfg *config.Config, logger *slog.Logger) (*Server, error) {
 
 	// Initialize worker
 	s.worker = worker.New(cfg, database.DB, logger, worker.DefaultConfig())
+	s.worker.SetProgress(s.progress)
 
 	return s, nil
 }
@@ -89,6 +125,11 @@ func (s *Server) setupRoutes() http.Handler {
 
 	// Create handlers
 	h := handlers.New(s.cfg, s.db, s.logger, s.views, s.oidc)
+	h.SetJobProgress(s.progress)
+	h.SetBackupManager(s.backups)
+	h.SetHealthMonitor(s.health)
+	h.SetAlertEngine(s.alerts)
+	h.SetDNSPublisher(s.dns)
 
 	// Health check
 	mux.HandleFunc("GET /health", h.Health)
@@ -98,6 +139,13 @@ func (s *Server) setupRoutes() http.Handler {
 
 	mux.Handle("GET /uploads/", http.StripPrefix("/uploads/", http.FileServer(http.Dir(s.cfg.Server.UploadPath))))
 
+	// Open and click tracking (public, opened by recipients)
+	mux.HandleFunc("GET "+tracking.OpenPath+"{item}/{sig}", h.TrackOpen)
+	mux.HandleFunc("GET "+tracking.ClickPath+"{item}/{sig}", h.TrackClick)
+
+	// MTA-STS policies (public, fetched by sending servers from mta-sts.<domain>)
+	mux.HandleFunc("GET "+mtasts.WellKnownPath, h.MTASTSPolicy)
+
 	// Auth routes (public)
 	mux.HandleFunc("GET /auth/login", h.LoginPage)
 	mux.HandleFunc("POST /auth/login", h.Login)
@@ -105,15 +153,63 @@ func (s *Server) setupRoutes() http.Handler {
 	mux.HandleFunc("GET /auth/oidc/login", h.OIDCLogin)
 	mux.HandleFunc("GET /auth/callback", h.OIDCCallback)
 
-	// Public API routes (API key auth)
+	// Public API routes (API key auth), each requiring a key permission
 	apiMux := http.NewServeMux()
-	apiMux.HandleFunc("POST /api/v1/send", h.APISend)
-	apiMux.HandleFunc("POST /api/v1/send/template", h.APISendTemplate)
-	apiMux.HandleFunc("GET /api/v1/send/{id}/status", h.APIGetStatus)
+	api := func(pattern string, fn http.HandlerFunc, permissions ...string) {
+		apiMux.Handle(pattern, middleware.RequireAPIPermission(permissions...)(fn))
+	}
+	api("POST /api/v1/send", h.APISend, models.PermissionSend)
+	api("POST /api/v1/send/template", h.APISendTemplate, models.PermissionSend)
+	api("GET /api/v1/send/{id}/status", h.APIGetStatus, models.PermissionSend, models.PermissionStatus)
+
+	api("GET /api/v1/jobs", h.APIListJobs, models.PermissionSend, models.PermissionCampaign, models.PermissionStatus)
+	api("GET /api/v1/jobs/{id}", h.APIGetJob, models.PermissionSend, models.PermissionCampaign, models.PermissionStatus)
+	api("POST /api/v1/jobs/{id}/pause", h.APIJobPause, models.PermissionSend, models.PermissionCampaign)
+	api("POST /api/v1/jobs/{id}/resume", h.APIJobResume, models.PermissionSend, models.PermissionCampaign)
+	api("POST /api/v1/jobs/{id}/cancel", h.APIJobCancel, models.PermissionSend, models.PermissionCampaign)
+
+	api("GET /api/v1/templates", h.APIListTemplates, models.PermissionTemplate)
+	api("POST /api/v1/templates", h.APICreateTemplate, models.PermissionTemplate)
+	api("GET /api/v1/templates/{id}", h.APIGetTemplate, models.PermissionTemplate)
+	api("PUT /api/v1/templates/{id}", h.APIUpdateTemplate, models.PermissionTemplate)
+	api("DELETE /api/v1/templates/{id}", h.APIDeleteTemplate, models.PermissionTemplate)
+	api("POST /api/v1/templates/{id}/deploy", h.APIDeployTemplate, models.PermissionTemplate)
+
+	api("GET /api/v1/campaigns", h.APIListCampaigns, models.PermissionCampaign)
+	api("POST /api/v1/campaigns", h.APICreateCampaign, models.PermissionCampaign)
+	api("GET /api/v1/campaigns/{id}", h.APIGetCampaign, models.PermissionCampaign)
+	api("PUT /api/v1/campaigns/{id}", h.APIUpdateCampaign, models.PermissionCampaign)
+	api("DELETE /api/v1/campaigns/{id}", h.APIDeleteCampaign, models.PermissionCampaign)
+	api("POST /api/v1/campaigns/{id}/variants", h.APICreateVariant, models.PermissionCampaign)
+	api("DELETE /api/v1/campaigns/{id}/variants/{variantId}", h.APIDeleteVariant, models.PermissionCampaign)
+	api("POST /api/v1/campaigns/{id}/send", h.APISendCampaign, models.PermissionCampaign)
+
+	api("GET /api/v1/lists", h.APIListRecipientLists, models.PermissionRecipients)
+	api("POST /api/v1/lists", h.APICreateRecipientList, models.PermissionRecipients)
+	api("GET /api/v1/lists/{id}", h.APIGetRecipientList, models.PermissionRecipients)
+	api("PUT /api/v1/lists/{id}", h.APIUpdateRecipientList, models.PermissionRecipients)
+	api("DELETE /api/v1/lists/{id}", h.APIDeleteRecipientList, models.PermissionRecipients)
+	api("GET /api/v1/lists/{id}/recipients", h.APIListRecipients, models.PermissionRecipients)
+	api("POST /api/v1/lists/{id}/recipients", h.APIAddRecipients, models.PermissionRecipients)
+	api("DELETE /api/v1/lists/{id}/recipients/{recipientId}", h.APIDeleteRecipient, models.PermissionRecipients)
+
+	api("GET /api/v1/domains", h.APIListDomains, models.PermissionDomain)
+	api("POST /api/v1/domains", h.APICreateDomain, models.PermissionDomain)
+	api("GET /api/v1/domains/{id}", h.APIGetDomain, models.PermissionDomain)
+	api("PUT /api/v1/domains/{id}", h.APIUpdateDomain, models.PermissionDomain)
+	api("DELETE /api/v1/domains/{id}", h.APIDeleteDomain, models.PermissionDomain)
+	api("POST /api/v1/domains/{id}/deploy", h.APIDeployDomain, models.PermissionDomain)
+
+	api("GET /api/v1/dkim", h.APIListDKIM, models.PermissionDKIM)
+	api("POST /api/v1/dkim", h.APICreateDKIM, models.PermissionDKIM)
+	api("GET /api/v1/dkim/{id}", h.APIGetDKIM, models.PermissionDKIM)
+	api("DELETE /api/v1/dkim/{id}", h.APIDeleteDKIM, models.PermissionDKIM)
+	api("POST /api/v1/dkim/{id}/deploy", h.APIDeployDKIM, models.PermissionDKIM)
 
 	apiKeysRepo := repository.NewAPIKeyRepository(s.db.DB)
 	apiAuth := middleware.APIAuth(apiKeysRepo, s.logger)
-	mux.Handle("/api/", apiAuth(apiMux))
+	orgMiddleware := middleware.Organization(s.db, s.logger)
+	mux.Handle("/api/", apiAuth(orgMiddleware(apiMux)))
 
 	// Protected routes
 	protected := http.NewServeMux()
@@ -135,6 +231,7 @@ func (s *Server) setupRoutes() http.Handler {
 	protected.HandleFunc("PUT /templates/{id}", h.TemplateUpdate)
 	protected.HandleFunc("DELETE /templates/{id}", h.TemplateDelete)
 	protected.HandleFunc("GET /templates/{id}/versions", h.TemplateVersions)
+	protected.HandleFunc("POST /templates/{id}/versions/{version}/restore", h.TemplateRestore)
 	protected.HandleFunc("GET /templates/{id}/diff", h.TemplateDiff)
 	protected.HandleFunc("GET /templates/{id}/export", h.TemplateExport)
 	protected.HandleFunc("GET /templates/{id}/test", h.TemplateTestPage)
@@ -160,6 +257,19 @@ func (s *Server) setupRoutes() http.Handler {
 	protected.HandleFunc("POST /blocks/{id}/inline-edit", h.BlockInlineEdit)
 	protected.HandleFunc("POST /blocks/{id}/delete", h.BlockDelete)
 
+	// Organizations: switching and member management by organization admins
+	protected.HandleFunc("POST /organizations/switch", h.OrganizationSwitch)
+	protected.HandleFunc("GET /organizations/{id}/members", h.OrganizationMembers)
+	protected.HandleFunc("POST /organizations/{id}/members", h.OrganizationMemberSet)
+	protected.HandleFunc("POST /organizations/{id}/members/{user}/delete", h.OrganizationMemberRemove)
+
+	// Profile: own sessions and personal API tokens
+	protected.HandleFunc("GET /profile", h.Profile)
+	protected.HandleFunc("POST /profile/sessions/revoke-others", h.ProfileSessionsRevokeOthers)
+	protected.HandleFunc("POST /profile/sessions/{id}/revoke", h.ProfileSessionRevoke)
+	protected.HandleFunc("POST /profile/tokens", h.ProfileTokenCreate)
+	protected.HandleFunc("POST /profile/tokens/{id}/delete", h.ProfileTokenDelete)
+
 	protected.HandleFunc("GET /settings/smtp", h.SMTPList)
 	protected.HandleFunc("GET /settings/smtp/new", h.SMTPNew)
 	protected.HandleFunc("POST /settings/smtp", h.SMTPCreate)
@@ -171,6 +281,7 @@ func (s *Server) setupRoutes() http.Handler {
 	// Recipients
 	protected.HandleFunc("GET /recipients", h.RecipientListList)
 	protected.HandleFunc("GET /recipients/new", h.RecipientListNew)
+	protected.HandleFunc("GET /recipients/history", h.RecipientHistory)
 	protected.HandleFunc("POST /recipients", h.RecipientListCreate)
 	protected.HandleFunc("GET /recipients/{id}", h.RecipientListView)
 	protected.HandleFunc("GET /recipients/{id}/edit", h.RecipientListEdit)
@@ -178,10 +289,19 @@ func (s *Server) setupRoutes() http.Handler {
 	protected.HandleFunc("DELETE /recipients/{id}", h.RecipientListDelete)
 	protected.HandleFunc("GET /recipients/{id}/import", h.RecipientImportPage)
 	protected.HandleFunc("POST /recipients/{id}/import", h.RecipientImport)
+	protected.HandleFunc("GET /recipients/{id}/imports/{importId}", h.RecipientImportView)
+	protected.HandleFunc("POST /recipients/{id}/imports/{importId}", h.RecipientImportRun)
+	protected.HandleFunc("GET /recipients/{id}/imports/{importId}/rejected", h.RecipientImportRejected)
 	protected.HandleFunc("GET /recipients/{id}/export", h.RecipientListExport)
 	protected.HandleFunc("GET /recipients/{id}/recipients", h.RecipientsList)
 	protected.HandleFunc("POST /recipients/{id}/add", h.RecipientAdd)
 	protected.HandleFunc("DELETE /recipients/{id}/recipients/{recipientId}", h.RecipientDelete)
+	protected.HandleFunc("GET /recipients/{id}/segments/new", h.SegmentNew)
+	protected.HandleFunc("POST /recipients/{id}/segments", h.SegmentCreate)
+	protected.HandleFunc("POST /recipients/{id}/segments/count", h.SegmentCount)
+	protected.HandleFunc("GET /recipients/{id}/segments/{segmentId}/edit", h.SegmentEdit)
+	protected.HandleFunc("PUT /recipients/{id}/segments/{segmentId}", h.SegmentUpdate)
+	protected.HandleFunc("DELETE /recipients/{id}/segments/{segmentId}", h.SegmentDelete)
 
 	// Campaigns
 	protected.HandleFunc("GET /campaigns", h.CampaignList)
@@ -199,10 +319,13 @@ func (s *Server) setupRoutes() http.Handler {
 	protected.HandleFunc("GET /campaigns/{id}/send", h.CampaignSendPage)
 	protected.HandleFunc("POST /campaigns/{id}/send", h.CampaignSend)
 	protected.HandleFunc("GET /campaigns/{id}/jobs", h.CampaignJobs)
+	protected.HandleFunc("GET /campaigns/{id}/deliverability", h.CampaignDeliverability)
 
 	// Jobs
 	protected.HandleFunc("GET /jobs", h.JobList)
+	protected.HandleFunc("GET /jobs/events", h.JobsEvents)
 	protected.HandleFunc("GET /jobs/{id}", h.JobView)
+	protected.HandleFunc("GET /jobs/{id}/events", h.JobEvents)
 	protected.HandleFunc("GET /jobs/{id}/items", h.JobItems)
 	protected.HandleFunc("POST /jobs/{id}/pause", h.JobPause)
 	protected.HandleFunc("POST /jobs/{id}/resume", h.JobResume)
@@ -223,12 +346,25 @@ func (s *Server) setupRoutes() http.Handler {
 	protected.HandleFunc("GET /domains/new", h.CentralDomainsNew)
 	protected.HandleFunc("POST /domains", h.CentralDomainsCreate)
 	protected.HandleFunc("POST /domains/import", h.CentralDomainsImport)
+	protected.HandleFunc("GET /domains/bundle/import", h.DomainBundleImportPage)
+	protected.HandleFunc("POST /domains/bundle/preview", h.DomainBundlePreview)
+	protected.HandleFunc("POST /domains/bundle/import", h.DomainBundleImport)
 	protected.HandleFunc("GET /domains/{id}", h.CentralDomainsView)
 	protected.HandleFunc("GET /domains/{id}/edit", h.CentralDomainsEdit)
 	protected.HandleFunc("PUT /domains/{id}", h.CentralDomainsUpdate)
 	protected.HandleFunc("DELETE /domains/{id}", h.CentralDomainsDelete)
 	protected.HandleFunc("POST /domains/{id}/deploy", h.CentralDomainsDeploy)
 	protected.HandleFunc("POST /domains/{id}/sync", h.CentralDomainsSync)
+	protected.HandleFunc("POST /domains/{id}/bundle", h.DomainBundleExport)
+	protected.HandleFunc("POST /domains/{id}/dns/publish", h.CentralDomainsDNSPublish)
+	protected.HandleFunc("POST /domains/{id}/dns/verify", h.CentralDomainsDNSVerify)
+	protected.HandleFunc("POST /domains/{id}/mta-sts", h.CentralDomainsMTASTSSave)
+	protected.HandleFunc("POST /domains/{id}/mta-sts/delete", h.CentralDomainsMTASTSDelete)
+	protected.HandleFunc("GET /domains/{id}/mta-sts.txt", h.CentralDomainsMTASTSDownload)
+
+	// Deployment history
+	protected.HandleFunc("GET /deployments", h.Deployments)
+	protected.HandleFunc("GET /deployments/{id}", h.DeploymentView)
 
 	// Queue overview (all servers)
 	protected.HandleFunc("GET /queue", h.QueueOverview)
@@ -236,6 +372,9 @@ func (s *Server) setupRoutes() http.Handler {
 	// Servers
 	protected.HandleFunc("GET /servers", h.ServerList)
 	protected.HandleFunc("GET /servers/{name}", h.ServerView)
+	protected.HandleFunc("GET /servers/{name}/health", h.ServerHealth)
+	protected.HandleFunc("GET /servers/{name}/health/data", h.ServerHealthData)
+	protected.HandleFunc("POST /servers/{name}/tls/{domain}/renew", middleware.AdminOnly(http.HandlerFunc(h.ServerTLSRenew)).ServeHTTP)
 	protected.HandleFunc("GET /servers/{name}/queue", h.ServerQueue)
 	protected.HandleFunc("POST /servers/{name}/queue/purge", h.QueuePurge)
 	protected.HandleFunc("GET /servers/{name}/queue/{id}", h.QueueMessageView)
@@ -246,6 +385,23 @@ func (s *Server) setupRoutes() http.Handler {
 	protected.HandleFunc("POST /servers/{name}/dlq/{id}/delete", h.DLQMessageDelete)
 	protected.HandleFunc("GET /servers/{name}/sandbox", h.ServerSandbox)
 	protected.HandleFunc("POST /servers/{name}/sandbox", h.ServerSandbox)
+	protected.HandleFunc("GET /servers/{name}/sandbox/messages", h.ServerSandboxInbox)
+	protected.HandleFunc("POST /servers/{name}/sandbox/clear", h.ServerSandboxClear)
+	protected.HandleFunc("POST /servers/{name}/sandbox/release", h.ServerSandboxRelease)
+	protected.HandleFunc("GET /servers/{name}/sandbox/messages/{id}", h.ServerSandboxMessage)
+	protected.HandleFunc("GET /servers/{name}/sandbox/messages/{id}/raw", h.ServerSandboxRaw)
+	protected.HandleFunc("POST /servers/{name}/sandbox/messages/{id}/delete", h.ServerSandboxMessageDelete)
+	protected.HandleFunc("POST /servers/{name}/sandbox/messages/{id}/release", h.ServerSandboxMessageRelease)
+	protected.HandleFunc("GET /servers/{name}/reputation", h.ServerReputation)
+	protected.HandleFunc("GET /servers/{name}/complaints", h.ServerComplaints)
+	protected.HandleFunc("GET /servers/{name}/apikeys", h.ServerAPIKeys)
+	// Issuing and revoking server credentials — admin only
+	protected.HandleFunc("POST /servers/{name}/apikeys", middleware.AdminOnly(http.HandlerFunc(h.ServerAPIKeyCreate)).ServeHTTP)
+	protected.HandleFunc("POST /servers/{name}/apikeys/{id}/revoke", middleware.AdminOnly(http.HandlerFunc(h.ServerAPIKeyRevoke)).ServeHTTP)
+	// Lifting a suppression lets mail reach a complaining recipient again — admin only
+	protected.HandleFunc("POST /servers/{name}/suppressions/remove", middleware.AdminOnly(http.HandlerFunc(h.ServerSuppressionRemove)).ServeHTTP)
+	// Management audit log reveals who changed what — admin only
+	protected.HandleFunc("GET /servers/{name}/audit", middleware.AdminOnly(http.HandlerFunc(h.ServerAudit)).ServeHTTP)
 
 	// Domains (per server)
 	protected.HandleFunc("GET /servers/{server}/domains", h.DomainsList)
@@ -255,6 +411,9 @@ func (s *Server) setupRoutes() http.Handler {
 	protected.HandleFunc("GET /servers/{server}/domains/{domain}/edit", h.DomainsEdit)
 	protected.HandleFunc("POST /servers/{server}/domains/{domain}", h.DomainsUpdate)
 	protected.HandleFunc("POST /servers/{server}/domains/{domain}/delete", h.DomainsDelete)
+	protected.HandleFunc("GET /servers/{server}/domains/{domain}/shaping", h.DomainsShaping)
+	protected.HandleFunc("POST /servers/{server}/domains/{domain}/shaping", h.DomainsShapingUpdate)
+	protected.HandleFunc("POST /servers/{server}/domains/{domain}/shaping/delete", h.DomainsShapingDelete)
 
 	// Send History
 	protected.HandleFunc("GET /sends", h.SendsList)
@@ -280,7 +439,21 @@ func (s *Server) setupRoutes() http.Handler {
 	protected.HandleFunc("POST /settings/users/{id}", adminOnly(http.HandlerFunc(h.UserUpdate)).ServeHTTP)
 	protected.HandleFunc("POST /settings/users/{id}/password", adminOnly(http.HandlerFunc(h.UserChangePassword)).ServeHTTP)
 	protected.HandleFunc("DELETE /settings/users/{id}", adminOnly(http.HandlerFunc(h.UserDelete)).ServeHTTP)
+	protected.HandleFunc("GET /settings/organizations", adminOnly(http.HandlerFunc(h.OrganizationList)).ServeHTTP)
+	protected.HandleFunc("POST /settings/organizations", adminOnly(http.HandlerFunc(h.OrganizationCreate)).ServeHTTP)
+	protected.HandleFunc("POST /settings/organizations/{id}", adminOnly(http.HandlerFunc(h.OrganizationUpdate)).ServeHTTP)
+	protected.HandleFunc("POST /settings/organizations/{id}/delete", adminOnly(http.HandlerFunc(h.OrganizationDelete)).ServeHTTP)
 	protected.HandleFunc("GET /settings/audit", adminOnly(http.HandlerFunc(h.AuditLog)).ServeHTTP)
+	protected.HandleFunc("GET /settings/backups", adminOnly(http.HandlerFunc(h.Backups)).ServeHTTP)
+	protected.HandleFunc("POST /settings/backups", adminOnly(http.HandlerFunc(h.BackupRun)).ServeHTTP)
+	protected.HandleFunc("GET /settings/freeze", adminOnly(http.HandlerFunc(h.FreezeWindows)).ServeHTTP)
+	protected.HandleFunc("POST /settings/freeze", adminOnly(http.HandlerFunc(h.FreezeWindowCreate)).ServeHTTP)
+	protected.HandleFunc("POST /settings/freeze/{id}/delete", adminOnly(http.HandlerFunc(h.FreezeWindowDelete)).ServeHTTP)
+	protected.HandleFunc("GET /settings/alerts", adminOnly(http.HandlerFunc(h.Alerts)).ServeHTTP)
+	protected.HandleFunc("POST /settings/alerts/silences", adminOnly(http.HandlerFunc(h.AlertSilenceCreate)).ServeHTTP)
+	protected.HandleFunc("POST /settings/alerts/silences/{id}/delete", adminOnly(http.HandlerFunc(h.AlertSilenceDelete)).ServeHTTP)
+	protected.HandleFunc("POST /settings/alerts/test", adminOnly(http.HandlerFunc(h.AlertTest)).ServeHTTP)
+	protected.HandleFunc("POST /jobs/{id}/freeze-override", adminOnly(http.HandlerFunc(h.JobFreezeOverride)).ServeHTTP)
 	protected.HandleFunc("GET /settings/api-keys", adminOnly(http.HandlerFunc(h.APIKeysList)).ServeHTTP)
 	protected.HandleFunc("POST /settings/api-keys", adminOnly(http.HandlerFunc(h.APIKeyCreate)).ServeHTTP)
 	protected.HandleFunc("GET /settings/api-keys/{id}", adminOnly(http.HandlerFunc(h.APIKeyGet)).ServeHTTP)
@@ -302,27 +475,44 @@ func (s *Server) setupRoutes() http.Handler {
 	// DNS/IP Checks (per server)
 	protected.HandleFunc("GET /servers/{server}/dns-check", h.DNSCheck)
 	protected.HandleFunc("GET /servers/{server}/ip-check", h.IPCheck)
+	protected.HandleFunc("POST /servers/{server}/ip-check/monitored", h.IPMonitorAdd)
+	protected.HandleFunc("POST /servers/{server}/ip-check/monitored/{ip}/check", h.IPMonitorCheck)
+	protected.HandleFunc("POST /servers/{server}/ip-check/monitored/{ip}/delete", h.IPMonitorRemove)
 
 	// Wrap protected routes with auth middleware
 	authMiddleware := middleware.Auth(s.cfg, s.db, s.logger)
-	mux.Handle("/", authMiddleware(protected))
+	mux.Handle("/", authMiddleware(orgMiddleware(protected)))
 
 	// Apply global middleware
 	handler := middleware.MethodOverride(mux)
 	handler = middleware.Logger(s.logger)(handler)
 	handler = middleware.Recovery(s.logger)(handler)
 
-	// Apply IP filter if configured
+	// Apply IP filter if configured. Tracking links are opened by
+	// recipients and stay reachable from any address.
 	if len(s.cfg.Server.AllowedIPs) > 0 {
-		handler = middleware.IPFilter(s.cfg.Server.AllowedIPs, s.logger)(handler)
+		open := handler
+		filtered := middleware.IPFilter(s.cfg.Server.AllowedIPs, s.logger)(handler)
+		handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasPrefix(r.URL.Path, tracking.OpenPath) || strings.HasPrefix(r.URL.Path, tracking.ClickPath) {
+				open.ServeHTTP(w, r)
+				return
+			}
+			filtered.ServeHTTP(w, r)
+		})
 	}
 
 	return handler
 }
 
 func (s *Server) Run(ctx context.Context) error {
-	// Start background worker
+	// Start background worker, backup scheduler, template drift checker,
+	// server health monitor and DNS propagation checks
 	s.worker.Start()
+	s.backups.Start()
+	s.drift.Start()
+	s.health.Start()
+	s.dns.Start()
 
 	errCh := make(chan error, 1)
 
@@ -338,10 +528,18 @@ func (s *Server) Run(ctx context.Context) error {
 	select {
 	case err := <-errCh:
 		s.worker.Stop()
+		s.backups.Stop()
+		s.drift.Stop()
+		s.health.Stop()
+		s.dns.Stop()
 		return err
 	case <-ctx.Done():
 		// Stop worker first
 		s.worker.Stop()
+		s.backups.Stop()
+		s.drift.Stop()
+		s.health.Stop()
+		s.dns.Stop()
 
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
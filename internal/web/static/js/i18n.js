@@ -123,8 +123,18 @@ var I18n = (function() {
             'global_variables_desc': 'Manage template variables available across all campaigns',
             'users': 'Users',
             'users_desc': 'Manage user accounts and permissions',
+            'organizations': 'Organizations',
+            'organizations_desc': 'Organizations, their members and allowed servers',
             'audit_log': 'Audit Log',
             'audit_log_desc': 'View activity history and changes',
+            'backups': 'Backups',
+            'backups_desc': 'Nightly database backups and last backup status',
+            'freeze_windows': 'Freeze Windows',
+            'freeze_windows_desc': 'Hold campaign sending during code freezes and quiet periods',
+            'alerts': 'Alerts',
+            'alerts_desc': 'Alert rules, notification channels and silences',
+            'deployments': 'Deployments',
+            'deployments_desc': 'Trace deploys across servers with their server-side audit records',
             'api_keys': 'API Keys',
             'api_keys_desc': 'Manage API keys for external integrations',
             'send_test_email': 'Send Test Email',
@@ -301,8 +311,18 @@ var I18n = (function() {
             'global_variables_desc': 'Управление переменными шаблонов для всех кампаний',
             'users': 'Пользователи',
             'users_desc': 'Управление учётными записями',
+            'organizations': 'Организации',
+            'organizations_desc': 'Организации, их участники и доступные серверы',
             'audit_log': 'Журнал действий',
             'audit_log_desc': 'Просмотр истории изменений',
+            'backups': 'Резервные копии',
+            'backups_desc': 'Ночные резервные копии базы данных и статус последней копии',
+            'freeze_windows': 'Окна заморозки',
+            'freeze_windows_desc': 'Остановка рассылок кампаний на время заморозок и периодов тишины',
+            'alerts': 'Оповещения',
+            'alerts_desc': 'Правила оповещений, каналы уведомлений и подавления',
+            'deployments': 'Деплои',
+            'deployments_desc': 'Трассировка деплоев по серверам вместе с их журналами аудита',
             'api_keys': 'API ключи',
             'api_keys_desc': 'Управление ключами для внешних интеграций',
             'send_test_email': 'Отправить тестовое письмо',
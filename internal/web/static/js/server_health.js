@@ -0,0 +1,138 @@
+(function() {
+    'use strict';
+
+    var ServerHealthCharts = {
+        charts: {},
+        server: '',
+        currentPeriod: '24h',
+
+        init: function() {
+            var container = document.getElementById('health-charts');
+            if (!container) return;
+            this.server = container.getAttribute('data-server');
+
+            var self = this;
+            var periodSelect = document.getElementById('period-selector');
+            if (periodSelect) {
+                periodSelect.addEventListener('change', function() {
+                    self.currentPeriod = this.value;
+                    self.loadData();
+                });
+            }
+
+            this.loadData();
+        },
+
+        loadData: function() {
+            var self = this;
+            var url = '/servers/' + encodeURIComponent(this.server) + '/health/data?period=' + this.currentPeriod;
+
+            fetch(url)
+                .then(function(response) { return response.json(); })
+                .then(function(data) {
+                    self.render(data.samples || []);
+                })
+                .catch(function(error) {
+                    console.error('Failed to load server health:', error);
+                });
+        },
+
+        render: function(samples) {
+            var period = this.currentPeriod;
+            var labels = samples.map(function(s) {
+                var date = new Date(s.sampled_at);
+                if (period === '7d') {
+                    return date.toLocaleDateString([], {month: 'short', day: 'numeric', hour: '2-digit'});
+                }
+                return date.toLocaleTimeString([], {hour: '2-digit', minute: '2-digit'});
+            });
+
+            // Message totals are counters, chart the change between samples.
+            // A server restart resets the counters.
+            var delta = function(field) {
+                return samples.map(function(s, i) {
+                    if (i === 0) return 0;
+                    var d = s[field] - samples[i - 1][field];
+                    return d < 0 ? s[field] : d;
+                });
+            };
+
+            this.renderChart('queue-chart', labels, [
+                {label: 'Pending', data: samples.map(function(s) { return s.queue_pending; }), color: '#f59e0b'},
+                {label: 'Retrying', data: samples.map(function(s) { return s.queue_retrying; }), color: '#3b82f6'},
+                {label: 'DLQ', data: samples.map(function(s) { return s.dlq_size; }), color: '#ef4444'}
+            ]);
+            this.renderChart('delivery-chart', labels, [
+                {label: 'Sent', data: delta('sent_total'), color: '#10b981'},
+                {label: 'Failed', data: delta('failed_total'), color: '#ef4444'},
+                {label: 'Bounced', data: delta('bounced_total'), color: '#8b5cf6'}
+            ]);
+            this.renderChart('ratelimit-chart', labels, [
+                {label: 'Used %', data: samples.map(function(s) { return Math.round(s.rate_limit_usage * 100); }), color: '#3b82f6'}
+            ]);
+        },
+
+        renderChart: function(id, labels, series) {
+            var ctx = document.getElementById(id);
+            if (!ctx) return;
+
+            var isDark = document.documentElement.getAttribute('data-theme') === 'dark';
+            var gridColor = isDark ? 'rgba(255, 255, 255, 0.1)' : 'rgba(0, 0, 0, 0.1)';
+            var textColor = isDark ? '#ccc' : '#666';
+
+            if (this.charts[id]) {
+                this.charts[id].destroy();
+            }
+
+            this.charts[id] = new Chart(ctx, {
+                type: 'line',
+                data: {
+                    labels: labels,
+                    datasets: series.map(function(s) {
+                        return {
+                            label: s.label,
+                            data: s.data,
+                            borderColor: s.color,
+                            backgroundColor: 'transparent',
+                            pointRadius: 0,
+                            tension: 0.3
+                        };
+                    })
+                },
+                options: {
+                    responsive: true,
+                    maintainAspectRatio: false,
+                    interaction: {
+                        intersect: false,
+                        mode: 'index'
+                    },
+                    plugins: {
+                        legend: {
+                            position: 'top',
+                            labels: { color: textColor }
+                        }
+                    },
+                    scales: {
+                        x: {
+                            grid: { color: gridColor },
+                            ticks: { color: textColor, maxTicksLimit: 12 }
+                        },
+                        y: {
+                            beginAtZero: true,
+                            grid: { color: gridColor },
+                            ticks: { color: textColor }
+                        }
+                    }
+                }
+            });
+        }
+    };
+
+    if (document.readyState === 'loading') {
+        document.addEventListener('DOMContentLoaded', function() {
+            ServerHealthCharts.init();
+        });
+    } else {
+        ServerHealthCharts.init();
+    }
+})();
@@ -0,0 +1,215 @@
+package template
+
+import (
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	texttpl "text/template"
+	"text/template/parse"
+)
+
+// Lint warning codes
+const (
+	LintSyntax            = "syntax"             // Template does not parse, e.g. an unclosed action
+	LintUndefinedVariable = "undefined_variable" // Variable not in the declared variables
+	LintEmptyURL          = "empty_url"          // Empty href or src
+	LintBrokenURL         = "broken_url"         // href or src that is not a valid URL
+)
+
+// LintWarning is a problem found in a template
+type LintWarning struct {
+	Field   string `json:"field"` // subject, html or text
+	Line    int    `json:"line"`
+	Code    string `json:"code"`
+	Value   string `json:"value,omitempty"` // Variable name or URL
+	Message string `json:"message"`
+}
+
+var (
+	lintAttrRe   = regexp.MustCompile(`(?i)\s(href|src)\s*=\s*("[^"]*"|'[^']*'|[^\s"'>]+)`)
+	lintActionRe = regexp.MustCompile(`\{\{.*?\}\}`)
+	lintLineRe   = regexp.MustCompile(`^template: [^:]*:(\d+):`)
+)
+
+// Lint checks the subject, HTML and text of a template for syntax errors,
+// variables that are not declared and empty or broken link and image
+// URLs. Variables are not checked if declared is nil.
+func Lint(subject, html, text string, declared map[string]bool) []LintWarning {
+	var warnings []LintWarning
+	warnings = append(warnings, lintSource("subject", subject, declared)...)
+	warnings = append(warnings, lintSource("html", html, declared)...)
+	warnings = append(warnings, lintURLs("html", html)...)
+	warnings = append(warnings, lintSource("text", text, declared)...)
+	return warnings
+}
+
+// lintSource checks the syntax and variables of a template part
+func lintSource(field, src string, declared map[string]bool) []LintWarning {
+	if src == "" {
+		return nil
+	}
+	t, err := texttpl.New(field).Parse(Preprocess(src))
+	if err != nil {
+		msg := err.Error()
+		line := 0
+		if m := lintLineRe.FindStringSubmatch(msg); m != nil {
+			line, _ = strconv.Atoi(m[1])
+		}
+		return []LintWarning{{Field: field, Line: line, Code: LintSyntax, Message: msg}}
+	}
+	if declared == nil || t.Tree == nil {
+		return nil
+	}
+
+	used := map[string]int{} // Variable -> line of its first use
+	walkFields(t.Tree, t.Tree.Root, true, used)
+
+	names := make([]string, 0, len(used))
+	for name := range used {
+		if !declared[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if used[names[i]] != used[names[j]] {
+			return used[names[i]] < used[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	var warnings []LintWarning
+	for _, name := range names {
+		warnings = append(warnings, LintWarning{
+			Field:   field,
+			Line:    used[name],
+			Code:    LintUndefinedVariable,
+			Value:   name,
+			Message: "variable " + name + " is not declared in Variables",
+		})
+	}
+	return warnings
+}
+
+// walkFields records the line of the first use of each data field. root
+// is false inside range and with, where dot is no longer the data.
+func walkFields(tree *parse.Tree, node parse.Node, root bool, used map[string]int) {
+	use := func(name string, n parse.Node) {
+		if _, ok := used[name]; ok {
+			return
+		}
+		line := 0
+		if loc, _ := tree.ErrorContext(n); loc != "" {
+			if parts := strings.Split(loc, ":"); len(parts) >= 2 {
+				line, _ = strconv.Atoi(parts[1])
+			}
+		}
+		used[name] = line
+	}
+
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Nodes {
+			walkFields(tree, c, root, used)
+		}
+	case *parse.ActionNode:
+		walkFields(tree, n.Pipe, root, used)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Cmds {
+			walkFields(tree, c, root, used)
+		}
+	case *parse.CommandNode:
+		for _, a := range n.Args {
+			walkFields(tree, a, root, used)
+		}
+	case *parse.ChainNode:
+		walkFields(tree, n.Node, root, used)
+	case *parse.FieldNode:
+		if root {
+			use(n.Ident[0], n)
+		}
+	case *parse.VariableNode:
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			use(n.Ident[1], n)
+		}
+	case *parse.IfNode:
+		walkFields(tree, n.Pipe, root, used)
+		walkFields(tree, n.List, root, used)
+		walkFields(tree, n.ElseList, root, used)
+	case *parse.RangeNode:
+		walkFields(tree, n.Pipe, root, used)
+		walkFields(tree, n.List, false, used)
+		walkFields(tree, n.ElseList, root, used)
+	case *parse.WithNode:
+		walkFields(tree, n.Pipe, root, used)
+		walkFields(tree, n.List, false, used)
+		walkFields(tree, n.ElseList, root, used)
+	case *parse.TemplateNode:
+		walkFields(tree, n.Pipe, root, used)
+	}
+}
+
+// lintURLs reports empty and broken href and src URLs. URLs set entirely
+// by a template variable are not checked.
+func lintURLs(field, src string) []LintWarning {
+	var warnings []LintWarning
+	for _, tag := range tagRe.FindAllStringIndex(src, -1) {
+		for _, m := range lintAttrRe.FindAllStringSubmatchIndex(src[tag[0]:tag[1]], -1) {
+			attr := strings.ToLower(src[tag[0]+m[2] : tag[0]+m[3]])
+			value := strings.Trim(src[tag[0]+m[4]:tag[0]+m[5]], `"'`)
+			code, reason := lintURL(value)
+			if code == "" {
+				continue
+			}
+			warnings = append(warnings, LintWarning{
+				Field:   field,
+				Line:    strings.Count(src[:tag[0]+m[0]], "\n") + 1,
+				Code:    code,
+				Value:   value,
+				Message: attr + " " + reason,
+			})
+		}
+	}
+	return warnings
+}
+
+// lintURL returns the warning code and reason of an empty or broken URL
+func lintURL(raw string) (string, string) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return LintEmptyURL, "is empty"
+	}
+	masked := lintActionRe.ReplaceAllString(raw, "x")
+	if masked == "x" {
+		return "", ""
+	}
+	if strings.ContainsAny(masked, " \t\r\n") {
+		return LintBrokenURL, "contains whitespace"
+	}
+	u, err := url.Parse(masked)
+	if err != nil {
+		return LintBrokenURL, "is not a valid URL"
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		if u.Host == "" {
+			return LintBrokenURL, "has no host"
+		}
+	case "mailto", "tel":
+		if u.Opaque == "" {
+			return LintBrokenURL, "has no address"
+		}
+	case "":
+		if strings.HasPrefix(strings.ToLower(masked), "www.") {
+			return LintBrokenURL, "has no scheme"
+		}
+	}
+	return "", ""
+}
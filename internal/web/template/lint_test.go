@@ -0,0 +1,67 @@
+package template
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	declared := map[string]bool{"name": true, "items": true, "company": true}
+
+	cases := []struct {
+		name    string
+		subject string
+		html    string
+		want    []string // code:value
+	}{
+		{"clean", "Hi {{name}}", `<a href="https://example.com/{{name}}">Go</a>`, nil},
+		{"undefined variable", "Hi {{name}}", "<p>{{ coupon }}</p>\n<p>{{company}}</p>", []string{"undefined_variable:coupon"}},
+		{"dotted syntax", "Hi {{.first_name}}", "", []string{"undefined_variable:first_name"}},
+		{"range body is not checked", "", "{{range items}}<li>{{title}}</li>{{end}}", nil},
+		{"root inside range", "", "{{range items}}{{$.discount}}{{end}}", []string{"undefined_variable:discount"}},
+		{"if condition", "", "{{if vip}}VIP{{end}}", []string{"undefined_variable:vip"}},
+		{"unclosed action", "Hi {{name", "", []string{"syntax:"}},
+		{"unclosed block", "", "{{if name}}<p>Hi</p>", []string{"syntax:"}},
+		{"empty href", "", `<a href="">Link</a><img src=''>`, []string{"empty_url:", "empty_url:"}},
+		{"no host", "", `<a href="https://">Link</a>`, []string{"broken_url:https://"}},
+		{"no scheme", "", `<a href="www.example.com">Link</a>`, []string{"broken_url:www.example.com"}},
+		{"whitespace", "", `<a href="https://example.com/a b">Link</a>`, []string{"broken_url:https://example.com/a b"}},
+		{"empty mailto", "", `<a href="mailto:">Mail</a>`, []string{"broken_url:mailto:"}},
+		{"variable url", "", `<a href="{{name}}">Link</a>`, nil},
+		{"anchor and relative", "", `<a href="#top">Top</a><img src="/uploads/a.png"><img src="cid:logo">`, nil},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []string
+			for _, w := range Lint(tc.subject, tc.html, "", declared) {
+				value := w.Value
+				if w.Code == LintSyntax {
+					value = ""
+				}
+				got = append(got, w.Code+":"+value)
+			}
+			if strings.Join(got, "|") != strings.Join(tc.want, "|") {
+				t.Errorf("Lint() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLintLines(t *testing.T) {
+	html := "<p>Hi {{name}}</p>\n<p>{{coupon}}</p>\n<a href=\"\">x</a>"
+	warnings := Lint("", html, "", map[string]bool{"name": true})
+	if len(warnings) != 2 {
+		t.Fatalf("Lint() = %+v, want 2 warnings", warnings)
+	}
+	if w := warnings[0]; w.Field != "html" || w.Line != 2 || w.Value != "coupon" {
+		t.Errorf("variable warning = %+v, want html line 2", w)
+	}
+	if w := warnings[1]; w.Line != 3 || w.Code != LintEmptyURL {
+		t.Errorf("URL warning = %+v, want empty_url on line 3", w)
+	}
+
+	if warnings := Lint("", "Hi {{anything}}", "", nil); len(warnings) != 0 {
+		t.Errorf("Lint() without declared variables = %+v, want none", warnings)
+	}
+}
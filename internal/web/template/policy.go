@@ -0,0 +1,212 @@
+package template
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Policy restricts where resources referenced by template HTML (images,
+// fonts, stylesheets, scripts) may be loaded from. Resources embedded with
+// cid: or data: URLs and relative URLs are always allowed.
+type Policy struct {
+	// AllowedHosts are the asset hosts resources may be loaded from.
+	// "*.example.com" matches any subdomain of example.com.
+	AllowedHosts []string
+}
+
+// Violation is a resource reference forbidden by a policy
+type Violation struct {
+	Line   int    `json:"line"`
+	Tag    string `json:"tag"`
+	Attr   string `json:"attr"` // Attribute, or url() / @import for CSS
+	URL    string `json:"url"`
+	Reason string `json:"reason"`
+}
+
+var (
+	tagRe     = regexp.MustCompile(`<([a-zA-Z][a-zA-Z0-9:-]*)((?:\s+[^\s=/>]+(?:\s*=\s*(?:"[^"]*"|'[^']*'|[^\s"'>]+))?)*)\s*/?>`)
+	attrRe    = regexp.MustCompile(`([^\s=/>]+)(?:\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+)))?`)
+	styleRe   = regexp.MustCompile(`(?is)<style\b[^>]*>(.*?)</style>`)
+	cssURLRe  = regexp.MustCompile(`(?i)url\(\s*(?:"([^"]*)"|'([^']*)'|([^)]*?))\s*\)`)
+	importRe  = regexp.MustCompile(`(?i)@import\s+(?:"([^"]*)"|'([^']*)')`)
+	schemeRe  = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*:`)
+	linkTags  = map[string]bool{"link": true, "image": true, "use": true}
+	dataTags  = map[string]bool{"object": true}
+	plainAttr = map[string]bool{"src": true, "background": true, "poster": true, "xlink:href": true}
+)
+
+// Check returns the resources in the HTML that the policy forbids
+func (p Policy) Check(src string) []Violation {
+	var violations []Violation
+	add := func(offset int, tag, attr, raw string) {
+		rawURL := strings.TrimSpace(html.UnescapeString(raw))
+		if reason := p.check(rawURL); reason != "" {
+			violations = append(violations, Violation{
+				Line:   strings.Count(src[:offset], "\n") + 1,
+				Tag:    tag,
+				Attr:   attr,
+				URL:    rawURL,
+				Reason: reason,
+			})
+		}
+	}
+
+	for _, m := range tagRe.FindAllStringSubmatchIndex(src, -1) {
+		tag := strings.ToLower(src[m[2]:m[3]])
+		attrs := src[m[4]:m[5]]
+		for _, a := range attrRe.FindAllStringSubmatchIndex(attrs, -1) {
+			name := strings.ToLower(attrs[a[2]:a[3]])
+			value := ""
+			for i := 4; i < len(a); i += 2 {
+				if a[i] >= 0 {
+					value = attrs[a[i]:a[i+1]]
+					break
+				}
+			}
+			offset := m[4] + a[0]
+
+			switch {
+			case plainAttr[name],
+				name == "href" && linkTags[tag],
+				name == "data" && dataTags[tag]:
+				add(offset, tag, name, value)
+			case name == "srcset":
+				for _, candidate := range strings.Split(value, ",") {
+					if fields := strings.Fields(candidate); len(fields) > 0 {
+						add(offset, tag, name, fields[0])
+					}
+				}
+			case name == "style":
+				for _, u := range cssURLs(html.UnescapeString(value)) {
+					add(offset, tag, u.attr, u.url)
+				}
+			}
+		}
+	}
+
+	for _, m := range styleRe.FindAllStringSubmatchIndex(src, -1) {
+		for _, u := range cssURLs(src[m[2]:m[3]]) {
+			add(m[2]+u.offset, "style", u.attr, u.url)
+		}
+	}
+
+	sort.SliceStable(violations, func(i, j int) bool {
+		return violations[i].Line < violations[j].Line
+	})
+	return violations
+}
+
+type cssURL struct {
+	offset int
+	attr   string
+	url    string
+}
+
+// cssURLs returns the url() and @import references of a stylesheet
+func cssURLs(css string) []cssURL {
+	var urls []cssURL
+	for _, re := range []*regexp.Regexp{cssURLRe, importRe} {
+		attr := "url()"
+		if re == importRe {
+			attr = "@import"
+		}
+		for _, m := range re.FindAllStringSubmatchIndex(css, -1) {
+			for i := 2; i < len(m); i += 2 {
+				if m[i] >= 0 {
+					urls = append(urls, cssURL{offset: m[0], attr: attr, url: css[m[i]:m[i+1]]})
+					break
+				}
+			}
+		}
+	}
+	return urls
+}
+
+// check returns why a resource URL is forbidden, empty if it is allowed
+func (p Policy) check(rawURL string) string {
+	lower := strings.ToLower(rawURL)
+	switch {
+	case rawURL == "", strings.HasPrefix(lower, "cid:"), strings.HasPrefix(lower, "data:"):
+		return ""
+	case strings.HasPrefix(rawURL, "{{"):
+		return "URL is set by a template variable and cannot be verified"
+	case strings.HasPrefix(rawURL, "//"):
+		// Protocol-relative, resolved against the reader's mail client
+	case schemeRe.MatchString(rawURL):
+		if !strings.HasPrefix(lower, "http://") && !strings.HasPrefix(lower, "https://") {
+			return "URL scheme is not allowed"
+		}
+	default:
+		// Relative URLs are made absolute against the public URL
+		return ""
+	}
+
+	host := urlHost(rawURL)
+	if host == "" {
+		return "URL has no host"
+	}
+	if strings.Contains(host, "{{") {
+		return "host is set by a template variable and cannot be verified"
+	}
+	if !p.hostAllowed(host) {
+		return "host " + host + " is not an approved asset host"
+	}
+	return ""
+}
+
+// hostAllowed reports whether host matches an allowed host
+func (p Policy) hostAllowed(host string) bool {
+	for _, allowed := range p.AllowedHosts {
+		allowed = normalizeHost(allowed)
+		if allowed == "" {
+			continue
+		}
+		if suffix, ok := strings.CutPrefix(allowed, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+			continue
+		}
+		if host == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// urlHost returns the lower-case host of an absolute or protocol-relative URL
+func urlHost(rawURL string) string {
+	rest := rawURL
+	if i := strings.Index(rest, "//"); i >= 0 {
+		rest = rest[i+2:]
+	}
+	if i := strings.IndexAny(rest, "/?#"); i >= 0 {
+		rest = rest[:i]
+	}
+	if i := strings.LastIndex(rest, "@"); i >= 0 {
+		rest = rest[i+1:]
+	}
+	return normalizeHost(rest)
+}
+
+// normalizeHost lower-cases a host and strips a port or URL around it
+func normalizeHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if strings.Contains(host, "://") {
+		if u, err := url.Parse(host); err == nil {
+			return u.Hostname()
+		}
+	}
+	if strings.HasPrefix(host, "[") {
+		if i := strings.Index(host, "]"); i >= 0 {
+			return host[1:i]
+		}
+	}
+	if i := strings.LastIndex(host, ":"); i >= 0 && !strings.Contains(host[i:], "}") {
+		host = host[:i]
+	}
+	return strings.TrimSuffix(host, ".")
+}
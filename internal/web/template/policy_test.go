@@ -0,0 +1,73 @@
+package template
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPolicyCheck(t *testing.T) {
+	policy := Policy{AllowedHosts: []string{"assets.example.com", "*.cdn.example.com", "https://media.example.org/uploads"}}
+
+	cases := []struct {
+		name string
+		html string
+		want []string // Violating URLs
+	}{
+		{"cid image", `<img src="cid:logo">`, nil},
+		{"data image", `<img src="data:image/png;base64,AAAA">`, nil},
+		{"relative upload", `<img src="/uploads/logo.png">`, nil},
+		{"approved host", `<img src="https://assets.example.com/logo.png">`, nil},
+		{"approved host with port", `<img src="https://assets.example.com:8443/logo.png">`, nil},
+		{"approved wildcard", `<img src="https://eu.cdn.example.com/a.png">`, nil},
+		{"wildcard does not match apex", `<img src="https://cdn.example.com/a.png">`, []string{"https://cdn.example.com/a.png"}},
+		{"approved host from URL", `<img src="https://media.example.org/uploads/a.png">`, nil},
+		{"external image", `<img alt="x" src="https://tracker.example.net/p.gif">`, []string{"https://tracker.example.net/p.gif"}},
+		{"unquoted src", `<img src=http://evil.example/x.png>`, []string{"http://evil.example/x.png"}},
+		{"protocol relative", `<img src="//evil.example/x.png">`, []string{"//evil.example/x.png"}},
+		{"host lookalike", `<img src="https://assets.example.com.evil.example/x.png">`, []string{"https://assets.example.com.evil.example/x.png"}},
+		{"userinfo", `<img src="https://assets.example.com@evil.example/x.png">`, []string{"https://assets.example.com@evil.example/x.png"}},
+		{"external font", `<link rel="stylesheet" href="https://fonts.googleapis.com/css?family=Roboto">`, []string{"https://fonts.googleapis.com/css?family=Roboto"}},
+		{"external script", `<script src="https://evil.example/a.js"></script>`, []string{"https://evil.example/a.js"}},
+		{"links are not resources", `<a href="https://example.net/page">Read more</a>`, nil},
+		{"table background", `<td background="https://evil.example/bg.png">`, []string{"https://evil.example/bg.png"}},
+		{"srcset", `<img srcset="https://assets.example.com/a.png 1x, https://evil.example/b.png 2x">`, []string{"https://evil.example/b.png"}},
+		{"style attribute", `<div style="background: url('https://evil.example/bg.png')">`, []string{"https://evil.example/bg.png"}},
+		{"style attribute entities", `<div style="background:url(&quot;https://evil.example/bg.png&quot;)">`, []string{"https://evil.example/bg.png"}},
+		{"style block", "<style>\n@import 'https://evil.example/a.css';\n.x { background: url(https://evil.example/y.png) }\n</style>", []string{"https://evil.example/a.css", "https://evil.example/y.png"}},
+		{"font face", `<style>@font-face { src: url("https://fonts.gstatic.com/r.woff2") }</style>`, []string{"https://fonts.gstatic.com/r.woff2"}},
+		{"outlook vml", `<!--[if mso]><v:fill type="tile" src="https://evil.example/bg.png" /><![endif]-->`, []string{"https://evil.example/bg.png"}},
+		{"template variable url", `<img src="{{logo_url}}">`, []string{"{{logo_url}}"}},
+		{"template variable host", `<img src="https://{{host}}/logo.png">`, []string{"https://{{host}}/logo.png"}},
+		{"template variable path", `<img src="https://assets.example.com/{{id}}.png">`, nil},
+		{"scheme not allowed", `<iframe src="javascript:alert(1)"></iframe>`, []string{"javascript:alert(1)"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			violations := policy.Check(tc.html)
+			var got []string
+			for _, v := range violations {
+				got = append(got, v.URL)
+			}
+			if strings.Join(got, "|") != strings.Join(tc.want, "|") {
+				t.Errorf("Check(%q) = %v, want %v", tc.html, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPolicyCheckReport(t *testing.T) {
+	html := "<p>Hello</p>\n<p>\n  <img width=\"10\" src=\"https://evil.example/x.png\">\n</p>"
+
+	violations := Policy{}.Check(html)
+	if len(violations) != 1 {
+		t.Fatalf("got %d violations, want 1", len(violations))
+	}
+	v := violations[0]
+	if v.Line != 3 || v.Tag != "img" || v.Attr != "src" {
+		t.Errorf("violation = %+v, want line 3 img src", v)
+	}
+	if !strings.Contains(v.Reason, "evil.example") {
+		t.Errorf("reason = %q, want host", v.Reason)
+	}
+}
@@ -0,0 +1,116 @@
+// Package tracking rewrites campaign HTML for open and click tracking.
+// Links are replaced by signed redirect URLs and a tracking pixel is
+// added; the signatures keep the redirect from being used for other URLs.
+package tracking
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Paths of the tracking endpoints
+const (
+	OpenPath  = "/t/o/"
+	ClickPath = "/t/c/"
+)
+
+// Pixel is a transparent 1x1 GIF
+var Pixel = []byte{
+	0x47, 0x49, 0x46, 0x38, 0x39, 0x61, 0x01, 0x00, 0x01, 0x00, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0xff, 0xff, 0xff, 0x21, 0xf9, 0x04, 0x01, 0x00, 0x00, 0x00, 0x00, 0x2c, 0x00, 0x00, 0x00, 0x00,
+	0x01, 0x00, 0x01, 0x00, 0x00, 0x02, 0x02, 0x44, 0x01, 0x00, 0x3b,
+}
+
+// hrefPattern matches the href attribute of a link
+var hrefPattern = regexp.MustCompile(`(?i)(<a\s[^>]*?\bhref\s*=\s*)("[^"]*"|'[^']*')`)
+
+// bodyEndPattern matches the end of the HTML body
+var bodyEndPattern = regexp.MustCompile(`(?i)</body\s*>`)
+
+// Tracker builds and verifies tracking URLs
+type Tracker struct {
+	baseURL string
+	key     []byte
+}
+
+// New creates a tracker. Tracking URLs start with baseURL, signatures are
+// keyed with secret.
+func New(baseURL, secret string) *Tracker {
+	key := sha256.Sum256([]byte("sendry-web tracking\x00" + secret))
+	return &Tracker{baseURL: strings.TrimRight(baseURL, "/"), key: key[:]}
+}
+
+// OpenURL returns the URL of the tracking pixel of a job item
+func (t *Tracker) OpenURL(itemID string) string {
+	return t.baseURL + OpenPath + itemID + "/" + t.sign("open", itemID, "")
+}
+
+// ClickURL returns the tracked URL of a link in the email of a job item
+func (t *Tracker) ClickURL(itemID, target string) string {
+	return t.baseURL + ClickPath + itemID + "/" + t.sign("click", itemID, target) + "?u=" + url.QueryEscape(target)
+}
+
+// VerifyOpen reports whether sig is the signature of the pixel of an item
+func (t *Tracker) VerifyOpen(itemID, sig string) bool {
+	return hmac.Equal([]byte(sig), []byte(t.sign("open", itemID, "")))
+}
+
+// VerifyClick reports whether sig is the signature of a link of an item
+func (t *Tracker) VerifyClick(itemID, target, sig string) bool {
+	return hmac.Equal([]byte(sig), []byte(t.sign("click", itemID, target)))
+}
+
+func (t *Tracker) sign(kind, itemID, target string) string {
+	mac := hmac.New(sha256.New, t.key)
+	mac.Write([]byte(kind + "\x00" + itemID + "\x00" + target))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// Rewrite replaces the http and https links of an email by tracked URLs
+// and adds the tracking pixel before the end of the body. Other links
+// (mailto:, tel:, anchors) are kept.
+func (t *Tracker) Rewrite(body, itemID string) string {
+	if strings.TrimSpace(body) == "" {
+		return body
+	}
+
+	body = hrefPattern.ReplaceAllStringFunc(body, func(m string) string {
+		parts := hrefPattern.FindStringSubmatch(m)
+		quoted := parts[2]
+		target := strings.TrimSpace(html.UnescapeString(quoted[1 : len(quoted)-1]))
+		if !Trackable(target) {
+			return m
+		}
+		return parts[1] + `"` + html.EscapeString(t.ClickURL(itemID, target)) + `"`
+	})
+
+	pixel := `<img src="` + html.EscapeString(t.OpenURL(itemID)) + `" width="1" height="1" alt="" style="display:block;border:0;width:1px;height:1px">`
+	if loc := lastIndex(bodyEndPattern, body); loc >= 0 {
+		return body[:loc] + pixel + body[loc:]
+	}
+	return body + pixel
+}
+
+// Trackable reports whether a link target is an absolute http or https URL
+func Trackable(target string) bool {
+	u, err := url.Parse(target)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	return u.Scheme == "http" || u.Scheme == "https"
+}
+
+// lastIndex returns the start of the last match of re in s, -1 if there is
+// none
+func lastIndex(re *regexp.Regexp, s string) int {
+	matches := re.FindAllStringIndex(s, -1)
+	if len(matches) == 0 {
+		return -1
+	}
+	return matches[len(matches)-1][0]
+}
@@ -0,0 +1,79 @@
+package tracking
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestRewrite(t *testing.T) {
+	tr := New("https://mail.example.com/", "secret")
+	body := `<html><body>` +
+		`<a href="https://shop.example.com/sale?a=1&amp;b=2">Sale</a>` +
+		`<a class="btn" HREF='http://example.com/'>Home</a>` +
+		`<a href="mailto:help@example.com">Help</a>` +
+		`<a href="#top">Top</a>` +
+		`<a href="/relative">Relative</a>` +
+		`</body></html>`
+
+	got := tr.Rewrite(body, "item-1")
+
+	sale := tr.ClickURL("item-1", "https://shop.example.com/sale?a=1&b=2")
+	if !strings.Contains(got, `<a href="`+strings.ReplaceAll(sale, "&", "&amp;")+`">Sale</a>`) {
+		t.Errorf("link with entities not rewritten:\n%s", got)
+	}
+	if !strings.Contains(got, `<a class="btn" HREF="`+tr.ClickURL("item-1", "http://example.com/")+`">`) {
+		t.Errorf("single-quoted link not rewritten:\n%s", got)
+	}
+	for _, keep := range []string{`href="mailto:help@example.com"`, `href="#top"`, `href="/relative"`} {
+		if !strings.Contains(got, keep) {
+			t.Errorf("%s should not be rewritten:\n%s", keep, got)
+		}
+	}
+
+	pixel := `<img src="` + tr.OpenURL("item-1") + `"`
+	if i := strings.Index(got, pixel); i < 0 || !strings.HasSuffix(got[i:], `</body></html>`) {
+		t.Errorf("pixel not added before </body>:\n%s", got)
+	}
+
+	if got := tr.Rewrite(`<p>No body tag</p>`, "item-1"); !strings.HasPrefix(got, `<p>No body tag</p><img `) {
+		t.Errorf("pixel not appended: %s", got)
+	}
+	if got := tr.Rewrite("", "item-1"); got != "" {
+		t.Errorf("Rewrite(empty) = %q, want empty", got)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	tr := New("https://mail.example.com", "secret")
+
+	u, err := url.Parse(tr.ClickURL("item-1", "https://example.com/a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(strings.TrimPrefix(u.Path, ClickPath), "/")
+	if len(parts) != 2 || parts[0] != "item-1" {
+		t.Fatalf("unexpected click path %q", u.Path)
+	}
+	target := u.Query().Get("u")
+	if target != "https://example.com/a" {
+		t.Errorf("target = %q", target)
+	}
+	if !tr.VerifyClick("item-1", target, parts[1]) {
+		t.Error("VerifyClick() = false for a signed link")
+	}
+	if tr.VerifyClick("item-1", "https://evil.example.net/", parts[1]) {
+		t.Error("VerifyClick() = true for another target")
+	}
+	if tr.VerifyClick("item-2", target, parts[1]) {
+		t.Error("VerifyClick() = true for another item")
+	}
+	if New("https://mail.example.com", "other").VerifyClick("item-1", target, parts[1]) {
+		t.Error("VerifyClick() = true with another secret")
+	}
+
+	open := strings.TrimPrefix(tr.OpenURL("item-1"), "https://mail.example.com"+OpenPath+"item-1/")
+	if !tr.VerifyOpen("item-1", open) || tr.VerifyOpen("item-2", open) || tr.VerifyOpen("item-1", parts[1]) {
+		t.Error("VerifyOpen() accepts the wrong signatures")
+	}
+}
@@ -3,6 +3,7 @@ package views
 import (
 	"embed"
 	"encoding/json"
+	"fmt"
 	"html/template"
 	"io"
 	"io/fs"
@@ -39,6 +40,21 @@ var funcs = template.FuncMap{
 		s := strings.ReplaceAll(string(b), "</", `<\/`)
 		return template.JS(s)
 	},
+	"percent": func(f float64) string {
+		return fmt.Sprintf("%.2f%%", f*100)
+	},
+	"bytes": func(n int64) string {
+		const unit = 1024
+		if n < unit {
+			return fmt.Sprintf("%d B", n)
+		}
+		div, exp := int64(unit), 0
+		for m := n / unit; m >= unit; m /= unit {
+			div *= unit
+			exp++
+		}
+		return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+	},
 }
 
 type Engine struct {
@@ -118,6 +134,16 @@ func (e *Engine) Render(w io.Writer, name string, data any) error {
 	return tmpl.Execute(w, data)
 }
 
+// RenderFragment renders a template defined inside a page, without the
+// layout (for HTMX swaps of part of a page)
+func (e *Engine) RenderFragment(w io.Writer, page, name string, data any) error {
+	tmpl, ok := e.templates[page]
+	if !ok {
+		return fmt.Errorf("template %s not found", page)
+	}
+	return tmpl.ExecuteTemplate(w, name, data)
+}
+
 // RenderPartial renders a template without layout (for HTMX responses)
 func (e *Engine) RenderPartial(w io.Writer, name string, data any) error {
 	tmpl, err := template.New(name + ".html").Funcs(funcs).ParseFS(templatesFS, name+".html")
@@ -0,0 +1,121 @@
+package worker
+
+import (
+	"sync"
+	"time"
+
+	"github.com/foxzi/sendry/internal/web/models"
+	"github.com/foxzi/sendry/internal/web/repository"
+)
+
+// progressBuffer is the number of events a slow subscriber may lag behind
+// before new events are dropped for it
+const progressBuffer = 16
+
+// ProgressEvent describes the current state of a send job
+type ProgressEvent struct {
+	Job          models.SendJob
+	Stats        models.JobStats
+	Progress     int // Percent of items sent, bounced, failed or cancelled
+	ItemsChanged int // Items whose status changed since the previous event
+	Time         time.Time
+}
+
+// Progress fans out job progress events to live subscribers such as the
+// job view and dashboard event streams
+type Progress struct {
+	mu   sync.Mutex
+	subs map[*progressSub]struct{}
+}
+
+type progressSub struct {
+	jobID string // Empty for all jobs
+	ch    chan ProgressEvent
+}
+
+// NewProgress creates a progress event hub
+func NewProgress() *Progress {
+	return &Progress{subs: make(map[*progressSub]struct{})}
+}
+
+// Subscribe returns events of a job, or of all jobs if jobID is empty.
+// The returned function unsubscribes and must be called when done.
+func (p *Progress) Subscribe(jobID string) (<-chan ProgressEvent, func()) {
+	sub := &progressSub{jobID: jobID, ch: make(chan ProgressEvent, progressBuffer)}
+
+	p.mu.Lock()
+	p.subs[sub] = struct{}{}
+	p.mu.Unlock()
+
+	return sub.ch, func() {
+		p.mu.Lock()
+		delete(p.subs, sub)
+		p.mu.Unlock()
+	}
+}
+
+// Watched reports whether anyone is subscribed to the job
+func (p *Progress) Watched(jobID string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for sub := range p.subs {
+		if sub.jobID == "" || sub.jobID == jobID {
+			return true
+		}
+	}
+	return false
+}
+
+// Publish sends an event to the subscribers of its job. Subscribers that
+// are not keeping up miss the event; the next one carries the full state.
+func (p *Progress) Publish(ev ProgressEvent) {
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for sub := range p.subs {
+		if sub.jobID != "" && sub.jobID != ev.Job.ID {
+			continue
+		}
+		select {
+		case sub.ch <- ev:
+		default:
+		}
+	}
+}
+
+// PublishJob loads the job and its stats and publishes them if the job is
+// watched
+func (p *Progress) PublishJob(jobs *repository.JobRepository, jobID string, itemsChanged int) error {
+	if p == nil || !p.Watched(jobID) {
+		return nil
+	}
+
+	job, err := jobs.GetByID(jobID)
+	if err != nil || job == nil {
+		return err
+	}
+	stats, err := jobs.GetStats(jobID)
+	if err != nil {
+		return err
+	}
+
+	p.Publish(ProgressEvent{
+		Job:          *job,
+		Stats:        stats,
+		Progress:     JobProgress(stats),
+		ItemsChanged: itemsChanged,
+	})
+	return nil
+}
+
+// JobProgress returns the percent of job items that were sent, bounced,
+// failed or cancelled
+func JobProgress(stats models.JobStats) int {
+	if stats.Total == 0 {
+		return 0
+	}
+	return (stats.Sent + stats.Bounced + stats.Failed + stats.Cancelled) * 100 / stats.Total
+}
@@ -0,0 +1,76 @@
+package worker
+
+import (
+	"testing"
+
+	"github.com/foxzi/sendry/internal/web/models"
+)
+
+func TestProgressSubscribe(t *testing.T) {
+	p := NewProgress()
+
+	job1, cancel1 := p.Subscribe("job-1")
+	all, cancelAll := p.Subscribe("")
+	defer cancelAll()
+
+	if !p.Watched("job-1") || !p.Watched("job-2") {
+		t.Fatal("jobs should be watched while subscribed")
+	}
+
+	p.Publish(ProgressEvent{Job: models.SendJob{ID: "job-2"}})
+	p.Publish(ProgressEvent{Job: models.SendJob{ID: "job-1"}, Progress: 50})
+
+	if ev := <-job1; ev.Job.ID != "job-1" || ev.Progress != 50 {
+		t.Errorf("job-1 subscriber got %+v", ev)
+	}
+	if len(job1) != 0 {
+		t.Error("job-1 subscriber should not receive other jobs")
+	}
+	if len(all) != 2 {
+		t.Errorf("all-jobs subscriber got %d events, want 2", len(all))
+	}
+	if ev := <-all; ev.Time.IsZero() {
+		t.Error("Publish should set the event time")
+	}
+
+	cancel1()
+	cancelAll()
+	if p.Watched("job-1") {
+		t.Error("job should not be watched after unsubscribe")
+	}
+}
+
+func TestProgressSlowSubscriber(t *testing.T) {
+	p := NewProgress()
+	events, cancel := p.Subscribe("job-1")
+	defer cancel()
+
+	// Publishing never blocks on a subscriber that stopped reading
+	for i := 0; i < progressBuffer*2; i++ {
+		p.Publish(ProgressEvent{Job: models.SendJob{ID: "job-1"}, Progress: i})
+	}
+	if len(events) != progressBuffer {
+		t.Errorf("buffered %d events, want %d", len(events), progressBuffer)
+	}
+}
+
+func TestProgressPublishJobNil(t *testing.T) {
+	var p *Progress
+	if err := p.PublishJob(nil, "job-1", 0); err != nil {
+		t.Errorf("PublishJob() on nil hub = %v", err)
+	}
+
+	// Unwatched jobs are not loaded
+	if err := NewProgress().PublishJob(nil, "job-1", 0); err != nil {
+		t.Errorf("PublishJob() without subscribers = %v", err)
+	}
+}
+
+func TestJobProgress(t *testing.T) {
+	if got := JobProgress(models.JobStats{}); got != 0 {
+		t.Errorf("JobProgress(empty) = %d, want 0", got)
+	}
+	if got := JobProgress(models.JobStats{Total: 200, Sent: 90, Failed: 10, Queued: 50}); got != 50 {
+		t.Errorf("JobProgress() = %d, want 50", got)
+	}
+}
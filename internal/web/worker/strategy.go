@@ -0,0 +1,196 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/foxzi/sendry/internal/web/models"
+	"github.com/foxzi/sendry/internal/web/sendry"
+	sendryclient "github.com/foxzi/sendry/pkg/client"
+)
+
+// serverCooldown is how long a server is skipped after a send to it failed
+// or was rate limited
+const serverCooldown = time.Minute
+
+// loadTimeout bounds the queue stats request to each server of a
+// least-loaded job
+const loadTimeout = 5 * time.Second
+
+// cooldowns tracks servers that recently failed sends, shared by all jobs
+type cooldowns struct {
+	mu    sync.Mutex
+	until map[string]time.Time
+}
+
+func newCooldowns() *cooldowns {
+	return &cooldowns{until: make(map[string]time.Time)}
+}
+
+// mark skips the server for serverCooldown
+func (c *cooldowns) mark(server string, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.until[server] = now.Add(serverCooldown)
+}
+
+// active reports whether the server is cooling down
+func (c *cooldowns) active(server string, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	until, ok := c.until[server]
+	if ok && !now.Before(until) {
+		delete(c.until, server)
+		return false
+	}
+	return ok
+}
+
+// selector picks the Sendry server of each job item by the job strategy
+type selector struct {
+	mu       sync.Mutex
+	strategy string
+	servers  []string       // Job servers in order of preference
+	weights  map[string]int // Weighted strategy, 1 if not configured
+	current  map[string]int // Smooth weighted round-robin state
+	load     map[string]int // Messages queued on each server, least-loaded strategy
+	cool     *cooldowns
+}
+
+func newSelector(job *models.SendJob, weights map[string]int, cool *cooldowns) *selector {
+	var servers []string
+	json.Unmarshal([]byte(job.Servers), &servers)
+
+	s := &selector{
+		strategy: job.Strategy,
+		servers:  servers,
+		weights:  make(map[string]int),
+		current:  make(map[string]int),
+		load:     make(map[string]int),
+		cool:     cool,
+	}
+	for _, name := range servers {
+		s.weights[name] = 1
+		if w := weights[name]; w > 0 {
+			s.weights[name] = w
+		}
+	}
+	return s
+}
+
+// refreshLoad reads the queue size of each server for the least-loaded
+// strategy. Servers that do not answer cool down.
+func (s *selector) refreshLoad(ctx context.Context, manager *sendry.Manager) {
+	if s.strategy != models.StrategyLeastLoaded {
+		return
+	}
+
+	load := make(map[string]int, len(s.servers))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, name := range s.servers {
+		client, err := manager.GetClient(name)
+		if err != nil {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, loadTimeout)
+			defer cancel()
+
+			health, err := client.Health(ctx)
+			if err != nil {
+				s.cool.mark(name, time.Now())
+				return
+			}
+			queued := 0
+			if health.Queue != nil {
+				queued = health.Queue.Pending + health.Queue.Deferred
+			}
+			mu.Lock()
+			load[name] = queued
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	s.mu.Lock()
+	s.load = load
+	s.mu.Unlock()
+}
+
+// pick returns the server for an item, skipping the servers already tried
+// and those cooling down, or "" if no server can take the item now
+func (s *selector) pick(item *models.SendJobItem, tried map[string]bool) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	var candidates []string
+	for _, name := range s.servers {
+		if !tried[name] && !s.cool.active(name, now) {
+			candidates = append(candidates, name)
+		}
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+
+	switch s.strategy {
+	case models.StrategyFailover:
+		return candidates[0]
+
+	case models.StrategyRandom:
+		return candidates[rand.IntN(len(candidates))]
+
+	case models.StrategyWeighted:
+		// Smooth weighted round-robin spreads servers evenly over time
+		total, best := 0, ""
+		for _, name := range candidates {
+			s.current[name] += s.weights[name]
+			total += s.weights[name]
+			if best == "" || s.current[name] > s.current[best] {
+				best = name
+			}
+		}
+		s.current[best] -= total
+		return best
+
+	case models.StrategyLeastLoaded:
+		best := candidates[0]
+		for _, name := range candidates[1:] {
+			if s.load[name] < s.load[best] {
+				best = name
+			}
+		}
+		s.load[best]++
+		return best
+
+	default:
+		// Round-robin servers are assigned when the job is created
+		if slices.Contains(candidates, item.ServerName) {
+			return item.ServerName
+		}
+		return candidates[0]
+	}
+}
+
+// retryable reports whether a send error is worth retrying on another
+// server: connection errors, rate limits and server errors
+func retryable(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var apiErr *sendryclient.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= 500
+	}
+	return true
+}
@@ -0,0 +1,107 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/foxzi/sendry/internal/web/models"
+	sendryclient "github.com/foxzi/sendry/pkg/client"
+)
+
+func testSelector(strategy string, weights map[string]int) *selector {
+	job := &models.SendJob{Servers: `["mta-1","mta-2","mta-3"]`, Strategy: strategy}
+	return newSelector(job, weights, newCooldowns())
+}
+
+func TestSelector_Weighted(t *testing.T) {
+	s := testSelector(models.StrategyWeighted, map[string]int{"mta-1": 3, "mta-2": 1})
+
+	counts := make(map[string]int)
+	var order string
+	for i := 0; i < 10; i++ {
+		server := s.pick(&models.SendJobItem{}, nil)
+		counts[server]++
+		if i < 5 {
+			order += server[4:]
+		}
+	}
+	if counts["mta-1"] != 6 || counts["mta-2"] != 2 || counts["mta-3"] != 2 {
+		t.Errorf("weighted counts = %v, want 6/2/2", counts)
+	}
+	if order != "12131" {
+		t.Errorf("weighted order = %s, want servers interleaved", order)
+	}
+}
+
+func TestSelector_Failover(t *testing.T) {
+	s := testSelector(models.StrategyFailover, nil)
+	item := &models.SendJobItem{}
+
+	if got := s.pick(item, nil); got != "mta-1" {
+		t.Fatalf("pick() = %s, want primary mta-1", got)
+	}
+	if got := s.pick(item, map[string]bool{"mta-1": true}); got != "mta-2" {
+		t.Errorf("pick() after mta-1 failed = %s, want mta-2", got)
+	}
+
+	now := time.Now()
+	s.cool.mark("mta-1", now)
+	if got := s.pick(item, nil); got != "mta-2" {
+		t.Errorf("pick() with mta-1 cooling down = %s, want mta-2", got)
+	}
+	if !s.cool.active("mta-1", now) || s.cool.active("mta-1", now.Add(serverCooldown)) {
+		t.Error("cooldown should end after serverCooldown")
+	}
+
+	all := map[string]bool{"mta-1": true, "mta-2": true, "mta-3": true}
+	if got := s.pick(item, all); got != "" {
+		t.Errorf("pick() with all servers tried = %q, want none", got)
+	}
+}
+
+func TestSelector_LeastLoaded(t *testing.T) {
+	s := testSelector(models.StrategyLeastLoaded, nil)
+	s.load = map[string]int{"mta-1": 5, "mta-2": 3, "mta-3": 4}
+
+	counts := make(map[string]int)
+	for i := 0; i < 6; i++ {
+		counts[s.pick(&models.SendJobItem{}, nil)]++
+	}
+	// Picks fill the queues up evenly: 5, 6, 6 after six items
+	if counts["mta-1"] != 1 || counts["mta-2"] != 3 || counts["mta-3"] != 2 {
+		t.Errorf("least-loaded counts = %v", counts)
+	}
+}
+
+func TestSelector_RoundRobin(t *testing.T) {
+	s := testSelector(models.StrategyRoundRobin, nil)
+	item := &models.SendJobItem{ServerName: "mta-2"}
+
+	if got := s.pick(item, nil); got != "mta-2" {
+		t.Errorf("pick() = %s, want the assigned server", got)
+	}
+	if got := s.pick(item, map[string]bool{"mta-2": true}); got != "mta-1" {
+		t.Errorf("pick() after the assigned server failed = %s, want mta-1", got)
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&sendryclient.APIError{StatusCode: 429}, true},
+		{&sendryclient.APIError{StatusCode: 503}, true},
+		{&sendryclient.APIError{StatusCode: 400, Message: "invalid recipient"}, false},
+		{fmt.Errorf("do request: %w", errors.New("connection refused")), true},
+		{fmt.Errorf("do request: %w", context.Canceled), false},
+	}
+	for _, tt := range tests {
+		if got := retryable(tt.err); got != tt.want {
+			t.Errorf("retryable(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"database/sql"
 	"encoding/json"
 	"log/slog"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
@@ -13,6 +15,8 @@ import (
 	"github.com/foxzi/sendry/internal/web/repository"
 	"github.com/foxzi/sendry/internal/web/sendry"
 	emailtpl "github.com/foxzi/sendry/internal/web/template"
+	"github.com/foxzi/sendry/internal/web/tracking"
+	sendryclient "github.com/foxzi/sendry/pkg/client"
 )
 
 // Worker processes send jobs in the background
@@ -23,7 +27,13 @@ type Worker struct {
 	campaigns *repository.CampaignRepository
 	templates *repository.TemplateRepository
 	settings  *repository.SettingsRepository
+	freezes   *repository.FreezeRepository
+	tracker   *tracking.Tracker // nil if tracking is disabled
 	sendry    *sendry.Manager
+	progress  *Progress
+
+	cooldowns *cooldowns           // Servers skipped after failed sends
+	selectors map[string]*selector // Server selection state of running jobs
 
 	batchSize    int
 	pollInterval time.Duration
@@ -41,6 +51,10 @@ type Config struct {
 	Concurrency  int
 }
 
+// recipientWindowScan is the number of pending items read per poll for
+// jobs with a recipient-local send window
+const recipientWindowScan = 1000
+
 // DefaultConfig returns default worker configuration
 func DefaultConfig() Config {
 	return Config{
@@ -54,6 +68,11 @@ func DefaultConfig() Config {
 func New(cfg *config.Config, db *sql.DB, logger *slog.Logger, workerCfg Config) *Worker {
 	ctx, cancel := context.WithCancel(context.Background())
 
+	var tracker *tracking.Tracker
+	if cfg.Tracking.Enabled {
+		tracker = tracking.New(cfg.Tracking.BaseURL, cfg.Auth.SessionSecret)
+	}
+
 	return &Worker{
 		cfg:          cfg,
 		logger:       logger.With("component", "worker"),
@@ -61,7 +80,11 @@ func New(cfg *config.Config, db *sql.DB, logger *slog.Logger, workerCfg Config)
 		campaigns:    repository.NewCampaignRepository(db),
 		templates:    repository.NewTemplateRepository(db),
 		settings:     repository.NewSettingsRepository(db),
+		freezes:      repository.NewFreezeRepository(db),
+		tracker:      tracker,
 		sendry:       sendry.NewManager(cfg.Sendry.Servers),
+		cooldowns:    newCooldowns(),
+		selectors:    make(map[string]*selector),
 		batchSize:    workerCfg.BatchSize,
 		pollInterval: workerCfg.PollInterval,
 		concurrency:  workerCfg.Concurrency,
@@ -70,6 +93,11 @@ func New(cfg *config.Config, db *sql.DB, logger *slog.Logger, workerCfg Config)
 	}
 }
 
+// SetProgress sets the hub that receives job progress events
+func (w *Worker) SetProgress(p *Progress) {
+	w.progress = p
+}
+
 // Start starts the worker
 func (w *Worker) Start() {
 	w.wg.Add(1)
@@ -115,6 +143,13 @@ func (w *Worker) processJobs() {
 		return
 	}
 
+	// Forget the server selection of jobs that stopped running
+	for id := range w.selectors {
+		if !slices.ContainsFunc(jobs, func(j models.SendJob) bool { return j.ID == id }) {
+			delete(w.selectors, id)
+		}
+	}
+
 	for _, job := range jobs {
 		select {
 		case <-w.ctx.Done():
@@ -125,6 +160,16 @@ func (w *Worker) processJobs() {
 	}
 }
 
+// selectorFor returns the server selector of a running job
+func (w *Worker) selectorFor(job *models.SendJob) *selector {
+	sel, ok := w.selectors[job.ID]
+	if !ok {
+		sel = newSelector(job, w.cfg.Sendry.MultiSend.Weights, w.cooldowns)
+		w.selectors[job.ID] = sel
+	}
+	return sel
+}
+
 // trackQueuedItems checks status of queued items via Sendry API
 func (w *Worker) trackQueuedItems() {
 	items, err := w.jobs.GetQueuedItems(w.batchSize * 2)
@@ -133,6 +178,13 @@ func (w *Worker) trackQueuedItems() {
 		return
 	}
 
+	changed := make(map[string]int) // job ID -> items with a new status
+	defer func() {
+		for jobID, n := range changed {
+			w.publishProgress(jobID, n)
+		}
+	}()
+
 	for _, item := range items {
 		select {
 		case <-w.ctx.Done():
@@ -145,32 +197,91 @@ func (w *Worker) trackQueuedItems() {
 			continue
 		}
 
+		var d models.ItemDelivery
 		status, err := client.GetStatus(w.ctx, item.SendryMsgID)
-		if err != nil {
+		switch {
+		case sendryclient.IsNotFound(err):
+			// Removed from the server, e.g. by queue cleanup; checked again
+			// after the other queued items
+			d = models.ItemDelivery{Status: item.Status, Error: "message not found on " + item.ServerName}
+		case err != nil:
 			w.logger.Debug("failed to get status", "item_id", item.ID, "sendry_id", item.SendryMsgID, "error", err)
 			continue
+		default:
+			d = itemDelivery(item.Email, status)
+			if d.Status == "" {
+				continue
+			}
+		}
+		if d.At.IsZero() {
+			d.At = time.Now()
 		}
 
-		// Map Sendry status to local status
-		newStatus := mapSendryStatus(status.Status)
-		if newStatus != "" && newStatus != item.Status {
-			errorMsg := ""
-			if newStatus == "failed" {
-				errorMsg = status.LastError
-			}
-			if err := w.jobs.UpdateItemStatus(item.ID, newStatus, item.SendryMsgID, errorMsg); err != nil {
-				w.logger.Error("failed to update item status", "item_id", item.ID, "error", err)
-			} else {
-				w.logger.Debug("status updated", "item_id", item.ID, "old", item.Status, "new", newStatus)
-			}
+		if err := w.jobs.UpdateItemDelivery(item.ID, d); err != nil {
+			w.logger.Error("failed to update item status", "item_id", item.ID, "error", err)
+		} else if d.Status != item.Status {
+			changed[item.JobID]++
+			w.logger.Debug("status updated", "item_id", item.ID, "old", item.Status, "new", d.Status)
 		}
 	}
 }
 
+// itemDelivery returns the delivery state of a job item from the status of
+// its message, preferring the outcome of the item's recipient. Permanent
+// rejections by the recipient server are bounces; expired retries and other
+// failures are failed.
+func itemDelivery(email string, status *sendryclient.StatusResponse) models.ItemDelivery {
+	d := models.ItemDelivery{
+		Status: mapSendryStatus(status.Status),
+		Error:  status.LastError,
+		At:     status.UpdatedAt,
+	}
+
+	var result *sendryclient.RecipientResult
+	for rcpt, res := range status.Recipients {
+		if strings.EqualFold(rcpt, email) {
+			result = res
+			break
+		}
+	}
+	if result != nil {
+		if s := mapSendryStatus(result.Status); s != "" {
+			d.Status = s
+		}
+		d.MXHost = result.MXHost
+		d.Error = result.Error
+		if !result.UpdatedAt.IsZero() {
+			d.At = result.UpdatedAt
+		}
+	}
+
+	switch d.Status {
+	case "sent":
+		d.Error = ""
+	case "failed":
+		if status.Status == "bounced" || (result != nil && !result.Expired && permanentFailure(email, status.Attempts)) {
+			d.Status = "bounced"
+		}
+	}
+	return d
+}
+
+// permanentFailure reports whether the last delivery attempt to the
+// recipient was rejected permanently
+func permanentFailure(email string, attempts []sendryclient.DeliveryAttempt) bool {
+	for i := len(attempts) - 1; i >= 0; i-- {
+		a := attempts[i]
+		if a.Recipient == "" || strings.EqualFold(a.Recipient, email) {
+			return !a.Success && a.Permanent
+		}
+	}
+	return false
+}
+
 // mapSendryStatus maps Sendry API status to local status
 func mapSendryStatus(status string) string {
 	switch status {
-	case "queued", "pending", "processing":
+	case "queued", "pending", "processing", "sending", "deferred":
 		return "queued"
 	case "sent", "delivered":
 		return "sent"
@@ -198,19 +309,40 @@ func (w *Worker) startScheduledJobs() {
 		default:
 		}
 
+		// Jobs due during a freeze window stay scheduled until it ends
+		if held, err := w.heldBy(&job, nil); err != nil {
+			w.logger.Error("failed to check freeze windows", "job_id", job.ID, "error", err)
+			continue
+		} else if held != nil {
+			w.logger.Debug("scheduled job held by freeze window", "job_id", job.ID, "freeze", held.Name, "until", held.EndsAt)
+			continue
+		}
+
 		// Update job status to running
 		if err := w.jobs.UpdateStatus(job.ID, "running"); err != nil {
 			w.logger.Error("failed to start scheduled job", "job_id", job.ID, "error", err)
 			continue
 		}
+		w.publishProgress(job.ID, 0)
 
 		w.logger.Info("started scheduled job", "job_id", job.ID, "campaign", job.CampaignName, "scheduled_at", job.ScheduledAt)
 	}
 }
 
 func (w *Worker) processJob(job *models.SendJob) {
-	// Get pending items
-	items, err := w.jobs.GetPendingItems(job.ID, w.batchSize)
+	throttle, err := models.ParseJobThrottle(job.Throttle)
+	if err != nil {
+		w.logger.Error("invalid job throttle, sending without limits", "job_id", job.ID, "error", err)
+		throttle = nil
+	}
+
+	// Get pending items; with a recipient-local send window more items are
+	// read so recipients in other timezones do not block the job
+	limit := w.batchSize
+	if throttle != nil && throttle.Window != nil && throttle.Window.RecipientLocal {
+		limit = recipientWindowScan
+	}
+	items, err := w.jobs.GetPendingItems(job.ID, limit)
 	if err != nil {
 		w.logger.Error("failed to get pending items", "job_id", job.ID, "error", err)
 		return
@@ -227,13 +359,14 @@ func (w *Worker) processJob(job *models.SendJob) {
 		if stats.Pending == 0 {
 			// Job complete
 			status := "completed"
-			if stats.Failed > 0 && stats.Sent == 0 {
+			if stats.Failed+stats.Bounced > 0 && stats.Sent == 0 {
 				status = "failed"
 			}
 			if err := w.jobs.UpdateStatus(job.ID, status); err != nil {
 				w.logger.Error("failed to update job status", "job_id", job.ID, "error", err)
 			} else {
 				w.logger.Info("job completed", "job_id", job.ID, "status", status, "sent", stats.Sent, "failed", stats.Failed)
+				w.publishProgress(job.ID, 0)
 			}
 		}
 		return
@@ -246,6 +379,24 @@ func (w *Worker) processJob(job *models.SendJob) {
 		return
 	}
 
+	// Pending items are held while a freeze window covers the campaign
+	if held, err := w.heldBy(job, campaign); err != nil {
+		w.logger.Error("failed to check freeze windows", "job_id", job.ID, "error", err)
+		return
+	} else if held != nil {
+		w.logger.Debug("job held by freeze window", "job_id", job.ID, "freeze", held.Name, "until", held.EndsAt)
+		return
+	}
+
+	if items, err = w.throttle(job, throttle, items, time.Now()); err != nil {
+		w.logger.Error("failed to apply job throttle", "job_id", job.ID, "error", err)
+		return
+	}
+	if len(items) == 0 {
+		w.logger.Debug("job held by throttle", "job_id", job.ID, "throttle", throttle.String())
+		return
+	}
+
 	// Get variants for this campaign
 	variants, err := w.campaigns.GetVariants(job.CampaignID)
 	if err != nil {
@@ -286,17 +437,28 @@ func (w *Worker) processJob(job *models.SendJob) {
 		}
 	}
 
+	sel := w.selectorFor(job)
+	sel.refreshLoad(w.ctx, w.sendry)
+
 	// Process items concurrently
 	sem := make(chan struct{}, w.concurrency)
 	var wg sync.WaitGroup
 
-	for _, item := range items {
+	for i, item := range items {
 		select {
 		case <-w.ctx.Done():
 			return
 		default:
 		}
 
+		// Stop handing out items once the job is paused or cancelled
+		if i > 0 && i%w.concurrency == 0 {
+			if status, err := w.jobs.Status(job.ID); err == nil && status != "running" {
+				w.logger.Info("job stopped during batch", "job_id", job.ID, "status", status)
+				break
+			}
+		}
+
 		sem <- struct{}{}
 		wg.Add(1)
 
@@ -306,7 +468,7 @@ func (w *Worker) processJob(job *models.SendJob) {
 				wg.Done()
 			}()
 
-			w.processItem(&item, campaign, variantMap, templateMap, globalVars, campaignVars)
+			w.processItem(&item, sel, campaign, variantMap, templateMap, globalVars, campaignVars)
 		}(item)
 	}
 
@@ -319,10 +481,90 @@ func (w *Worker) processJob(job *models.SendJob) {
 			w.logger.Error("failed to update job stats", "job_id", job.ID, "error", err)
 		}
 	}
+	w.publishProgress(job.ID, len(items))
+}
+
+// throttle returns the pending items a job may send now. Items outside the
+// send window are held, and the number of items is limited to the current
+// rate: at most the rate per minute over the last minute, spread over the
+// polls of the minute.
+func (w *Worker) throttle(job *models.SendJob, t *models.JobThrottle, items []models.SendJobItem, now time.Time) ([]models.SendJobItem, error) {
+	if t.IsZero() {
+		return items, nil
+	}
+
+	if win := t.Window; win != nil {
+		if !win.RecipientLocal {
+			if !win.Open(now, win.Location()) {
+				return nil, nil
+			}
+		} else {
+			open := make([]models.SendJobItem, 0, len(items))
+			for _, item := range items {
+				if win.Open(now, win.RecipientLocation(item.RecipientVariables)) {
+					open = append(open, item)
+				}
+			}
+			items = open
+		}
+	}
+
+	started := job.CreatedAt
+	if job.StartedAt != nil {
+		started = *job.StartedAt
+	}
+	if rate := t.RateAt(now.Sub(started)); rate > 0 {
+		budget := int((int64(rate)*int64(w.pollInterval) + int64(time.Minute) - 1) / int64(time.Minute))
+		recent, err := w.jobs.CountQueuedSince(job.ID, now.Add(-time.Minute))
+		if err != nil {
+			return nil, err
+		}
+		if remaining := rate - recent; remaining < budget {
+			budget = remaining
+		}
+		if budget <= 0 {
+			return nil, nil
+		}
+		if len(items) > budget {
+			items = items[:budget]
+		}
+	}
+
+	if len(items) > w.batchSize {
+		items = items[:w.batchSize]
+	}
+	return items, nil
+}
+
+// heldBy returns the freeze window that holds the job, or nil if the job
+// may send. campaign is loaded if nil.
+func (w *Worker) heldBy(job *models.SendJob, campaign *models.Campaign) (*models.FreezeWindow, error) {
+	if job.FreezeOverride {
+		return nil, nil
+	}
+	if campaign == nil {
+		c, err := w.campaigns.GetByID(job.CampaignID)
+		if err != nil {
+			return nil, err
+		}
+		if c == nil {
+			return nil, nil
+		}
+		campaign = c
+	}
+	return w.freezes.Active(campaign.SenderDomain(), time.Now())
+}
+
+// publishProgress notifies live viewers of a job about its progress
+func (w *Worker) publishProgress(jobID string, itemsChanged int) {
+	if err := w.progress.PublishJob(w.jobs, jobID, itemsChanged); err != nil {
+		w.logger.Debug("failed to publish job progress", "job_id", jobID, "error", err)
+	}
 }
 
 func (w *Worker) processItem(
 	item *models.SendJobItem,
+	sel *selector,
 	campaign *models.Campaign,
 	variantMap map[string]*models.CampaignVariant,
 	templateMap map[string]*models.Template,
@@ -343,13 +585,6 @@ func (w *Worker) processItem(
 		return
 	}
 
-	// Get Sendry client
-	client, err := w.sendry.GetClient(item.ServerName)
-	if err != nil {
-		w.updateItemFailed(item.ID, "server not found: "+item.ServerName)
-		return
-	}
-
 	// Build merged variables map (priority: recipient > campaign > global)
 	vars := mergeVariables(globalVars, campaignVars, item.RecipientVariables)
 
@@ -384,8 +619,13 @@ func (w *Worker) processItem(
 		return
 	}
 
+	// Links and the pixel are signed for this item
+	if w.tracker != nil {
+		html = w.tracker.Rewrite(html, item.ID)
+	}
+
 	// Build email request
-	req := &sendry.SendRequest{
+	req := &sendryclient.SendRequest{
 		From:    formatFrom(campaign.FromEmail, campaign.FromName),
 		To:      []string{item.Email},
 		Subject: subject,
@@ -393,25 +633,55 @@ func (w *Worker) processItem(
 		HTML:    html,
 	}
 
+	// The campaign header attributes spam complaints to the campaign
+	req.Headers = map[string]string{sendryclient.CampaignHeader: campaign.ID}
 	if campaign.ReplyTo != "" {
-		req.Headers = map[string]string{"Reply-To": campaign.ReplyTo}
+		req.Headers["Reply-To"] = campaign.ReplyTo
 	}
 
-	// Send email
-	resp, err := client.Send(w.ctx, req)
-	if err != nil {
-		w.updateItemFailed(item.ID, err.Error())
-		w.logger.Debug("failed to send email", "item_id", item.ID, "email", item.Email, "error", err)
-		return
-	}
+	// Send email, moving on to the next server of the job strategy when a
+	// server is down or rate limits
+	tried := make(map[string]bool)
+	for {
+		server := sel.pick(item, tried)
+		if server == "" {
+			w.logger.Debug("no server available, item stays pending", "item_id", item.ID, "tried", len(tried))
+			return
+		}
+		tried[server] = true
 
-	// Update item as queued
-	if err := w.jobs.UpdateItemStatus(item.ID, "queued", resp.ID, ""); err != nil {
-		w.logger.Error("failed to update item status", "item_id", item.ID, "error", err)
+		client, err := w.sendry.GetClient(server)
+		if err != nil {
+			w.updateItemFailed(item.ID, "server not found: "+server)
+			return
+		}
+
+		resp, err := client.Send(w.ctx, req)
+		if err != nil {
+			if retryable(err) && w.ctx.Err() == nil {
+				sel.cool.mark(server, time.Now())
+				w.logger.Warn("server failed, trying next server", "item_id", item.ID, "server", server, "error", err)
+				continue
+			}
+			w.updateItemFailed(item.ID, err.Error())
+			w.logger.Debug("failed to send email", "item_id", item.ID, "email", item.Email, "error", err)
+			return
+		}
+
+		// Update item as queued on the server that took it
+		if server != item.ServerName {
+			if err := w.jobs.UpdateItemServer(item.ID, server); err != nil {
+				w.logger.Error("failed to update item server", "item_id", item.ID, "error", err)
+			}
+		}
+		if err := w.jobs.UpdateItemStatus(item.ID, "queued", resp.ID, ""); err != nil {
+			w.logger.Error("failed to update item status", "item_id", item.ID, "error", err)
+			return
+		}
+
+		w.logger.Debug("email queued", "item_id", item.ID, "email", item.Email, "server", server, "sendry_id", resp.ID)
 		return
 	}
-
-	w.logger.Debug("email queued", "item_id", item.ID, "email", item.Email, "sendry_id", resp.ID)
 }
 
 func mergeVariables(global, campaign map[string]string, recipientJSON string) map[string]any {
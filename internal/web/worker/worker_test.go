@@ -2,6 +2,10 @@ package worker
 
 import (
 	"testing"
+	"time"
+
+	"github.com/foxzi/sendry/internal/web/models"
+	sendryclient "github.com/foxzi/sendry/pkg/client"
 )
 
 func TestRenderTemplate_Flat(t *testing.T) {
@@ -219,3 +223,52 @@ func TestMapSendryStatus(t *testing.T) {
 		})
 	}
 }
+
+func TestItemDelivery(t *testing.T) {
+	at := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	rejected := []sendryclient.DeliveryAttempt{
+		{Recipient: "a@example.com", MXHost: "mx.example.com", Error: "451 greylisted"},
+		{Recipient: "a@example.com", MXHost: "mx.example.com", Permanent: true, Error: "550 5.1.1 user unknown"},
+	}
+
+	tests := []struct {
+		name   string
+		status *sendryclient.StatusResponse
+		want   models.ItemDelivery
+	}{
+		{
+			name: "delivered",
+			status: &sendryclient.StatusResponse{Status: "delivered", UpdatedAt: at, Recipients: map[string]*sendryclient.RecipientResult{
+				"A@example.com": {Status: "delivered", MXHost: "mx.example.com", UpdatedAt: at},
+			}},
+			want: models.ItemDelivery{Status: "sent", MXHost: "mx.example.com", At: at},
+		},
+		{
+			name: "bounced",
+			status: &sendryclient.StatusResponse{Status: "failed", Attempts: rejected, Recipients: map[string]*sendryclient.RecipientResult{
+				"a@example.com": {Status: "failed", MXHost: "mx.example.com", Error: "550 5.1.1 user unknown", UpdatedAt: at},
+			}},
+			want: models.ItemDelivery{Status: "bounced", MXHost: "mx.example.com", Error: "550 5.1.1 user unknown", At: at},
+		},
+		{
+			name: "expired",
+			status: &sendryclient.StatusResponse{Status: "failed", Attempts: rejected[:1], Recipients: map[string]*sendryclient.RecipientResult{
+				"a@example.com": {Status: "failed", Error: "451 greylisted", Expired: true, UpdatedAt: at},
+			}},
+			want: models.ItemDelivery{Status: "failed", Error: "451 greylisted", At: at},
+		},
+		{
+			name:   "deferred without recipient results",
+			status: &sendryclient.StatusResponse{Status: "deferred", LastError: "451 try later", UpdatedAt: at},
+			want:   models.ItemDelivery{Status: "queued", Error: "451 try later", At: at},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := itemDelivery("a@example.com", tt.status); got != tt.want {
+				t.Errorf("itemDelivery() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,1070 @@
+// Package client is a Go client of the Sendry HTTP API. The API is
+// described by the OpenAPI document openapi.json next to this package, which
+// is generated from the server's route definitions.
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CorrelationHeader carries the correlation ID of a web action to the server
+const CorrelationHeader = "X-Correlation-ID"
+
+// ActorHeader carries the web user an API request is made for
+const ActorHeader = "X-Audit-Actor"
+
+// IdempotencyHeader makes a repeated message submission return the response
+// of the first one instead of queuing the message again
+const IdempotencyHeader = "Idempotency-Key"
+
+// CampaignHeader names the campaign of a sent message, so the server can
+// attribute spam complaints to it
+const CampaignHeader = "X-Sendry-Campaign"
+
+type correlationKey struct{}
+
+type actorKey struct{}
+
+type idempotencyKey struct{}
+
+// WithCorrelationID returns a context whose API requests carry the correlation ID
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationKey{}, id)
+}
+
+// CorrelationID returns the correlation ID attached to the context
+func CorrelationID(ctx context.Context) string {
+	id, _ := ctx.Value(correlationKey{}).(string)
+	return id
+}
+
+// WithActor returns a context whose API requests name the web user that
+// made them, so server audit entries show who acted
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// Actor returns the web user attached to the context
+func Actor(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// WithIdempotencyKey returns a context whose message submissions carry the
+// idempotency key, so a retry after a timeout does not queue the message twice
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKey{}, key)
+}
+
+// IdempotencyKey returns the idempotency key attached to the context
+func IdempotencyKey(ctx context.Context) string {
+	key, _ := ctx.Value(idempotencyKey{}).(string)
+	return key
+}
+
+// Client is a Sendry API client
+type Client struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+	metricsURL string // Prometheus endpoint of the server, optional
+}
+
+// New creates a new Sendry API client. baseURL is the API address without
+// path, e.g. https://mail.example.com:8080.
+func New(baseURL, apiKey string) *Client {
+	return &Client{
+		baseURL: baseURL,
+		apiKey:  apiKey,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// NewWithTLS creates a Sendry API client that connects with the given TLS
+// settings, e.g. a client certificate for servers that require mTLS
+func NewWithTLS(baseURL, apiKey string, tlsConfig *tls.Config) *Client {
+	c := New(baseURL, apiKey)
+	c.httpClient.Transport = &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: tlsConfig,
+	}
+	return c
+}
+
+// SetMetricsURL sets the Prometheus endpoint read by Metrics
+func (c *Client) SetMetricsURL(metricsURL string) {
+	c.metricsURL = metricsURL
+}
+
+// APIError is an error response of the Sendry API
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("HTTP %d", e.StatusCode)
+	}
+	return "API error: " + e.Message
+}
+
+// IsNotFound reports whether err is a not found response of the Sendry API
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// rawBody is a request body sent as it is instead of as JSON
+type rawBody struct {
+	data        []byte
+	contentType string
+}
+
+// request performs an HTTP request to the Sendry API
+func (c *Client) request(ctx context.Context, method, path string, body any, result any) error {
+	var reqBody io.Reader
+	contentType := "application/json"
+	switch b := body.(type) {
+	case nil:
+	case rawBody:
+		reqBody = bytes.NewReader(b.data)
+		contentType = b.contentType
+	default:
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("marshal request: %w", err)
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reqBody)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Accept", "application/json")
+	if id := CorrelationID(ctx); id != "" {
+		req.Header.Set(CorrelationHeader, id)
+	}
+	if actor := Actor(ctx); actor != "" {
+		req.Header.Set(ActorHeader, actor)
+	}
+	if key := IdempotencyKey(ctx); key != "" && method == http.MethodPost {
+		req.Header.Set(IdempotencyHeader, key)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		var errResp ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil {
+			return &APIError{StatusCode: resp.StatusCode}
+		}
+		return &APIError{StatusCode: resp.StatusCode, Message: errResp.Error}
+	}
+
+	if raw, ok := result.(*[]byte); ok {
+		// Raw responses such as message sources are returned as they are
+		if *raw, err = io.ReadAll(resp.Body); err != nil {
+			return fmt.Errorf("read response: %w", err)
+		}
+		return nil
+	}
+
+	if result != nil && resp.StatusCode != http.StatusNoContent {
+		if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+			return fmt.Errorf("decode response: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Health checks server health
+func (c *Client) Health(ctx context.Context) (*HealthResponse, error) {
+	var resp HealthResponse
+	if err := c.request(ctx, http.MethodGet, "/health", nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Send sends an email
+func (c *Client) Send(ctx context.Context, req *SendRequest) (*SendResponse, error) {
+	var resp SendResponse
+	if err := c.request(ctx, http.MethodPost, "/api/v1/send", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// SendWithTemplate sends an email using a template
+func (c *Client) SendWithTemplate(ctx context.Context, req *SendTemplateRequest) (*SendResponse, error) {
+	var resp SendResponse
+	if err := c.request(ctx, http.MethodPost, "/api/v1/send/template", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// SendBatch sends up to 1000 emails. Messages are accepted or rejected one
+// by one, see the results of the response.
+func (c *Client) SendBatch(ctx context.Context, req *BatchSendRequest) (*BatchSendResponse, error) {
+	var resp BatchSendResponse
+	if err := c.request(ctx, http.MethodPost, "/api/v1/send/batch", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// SendRaw sends a complete RFC 5322 message as it is
+func (c *Client) SendRaw(ctx context.Context, message []byte, opts *RawOptions) (*SendResponse, error) {
+	params := url.Values{}
+	if opts != nil {
+		if opts.From != "" {
+			params.Set("from", opts.From)
+		}
+		for _, to := range opts.To {
+			params.Add("to", to)
+		}
+		if opts.SendAt != nil {
+			params.Set("send_at", opts.SendAt.Format(time.RFC3339))
+		}
+		if opts.Priority != "" {
+			params.Set("priority", opts.Priority)
+		}
+		if opts.SkipDKIM {
+			params.Set("skip_dkim", "true")
+		}
+	}
+	path := "/api/v1/send/raw"
+	if len(params) > 0 {
+		path += "?" + params.Encode()
+	}
+
+	var resp SendResponse
+	body := rawBody{data: message, contentType: "message/rfc822"}
+	if err := c.request(ctx, http.MethodPost, path, body, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetStatus gets message status
+func (c *Client) GetStatus(ctx context.Context, id string) (*StatusResponse, error) {
+	var resp StatusResponse
+	if err := c.request(ctx, http.MethodGet, "/api/v1/status/"+id, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetQueue gets queue status
+func (c *Client) GetQueue(ctx context.Context) (*QueueResponse, error) {
+	return c.ListQueue(ctx, QueueFilter{})
+}
+
+// ListQueue gets queue status with the queued messages matching the filter
+func (c *Client) ListQueue(ctx context.Context, filter QueueFilter) (*QueueResponse, error) {
+	params := url.Values{}
+	for name, value := range map[string]string{
+		"status":           filter.Status,
+		"sender":           filter.Sender,
+		"recipient":        filter.Recipient,
+		"sender_domain":    filter.SenderDomain,
+		"recipient_domain": filter.RecipientDomain,
+		"domain":           filter.Domain,
+		"id_prefix":        filter.IDPrefix,
+		"since":            filter.Since,
+		"until":            filter.Until,
+		"cursor":           filter.Cursor,
+	} {
+		if value != "" {
+			params.Set(name, value)
+		}
+	}
+	if filter.Limit > 0 {
+		params.Set("limit", strconv.Itoa(filter.Limit))
+	}
+	path := "/api/v1/queue"
+	if len(params) > 0 {
+		path += "?" + params.Encode()
+	}
+
+	var resp QueueResponse
+	if err := c.request(ctx, http.MethodGet, path, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetMessage gets a queued message with its DKIM signature
+func (c *Client) GetMessage(ctx context.Context, id string) (*MessageResponse, error) {
+	var resp MessageResponse
+	if err := c.request(ctx, http.MethodGet, "/api/v1/queue/"+url.PathEscape(id), nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetPauseStatus gets the paused delivery
+func (c *Client) GetPauseStatus(ctx context.Context) (*PauseStatus, error) {
+	var resp PauseStatus
+	if err := c.request(ctx, http.MethodGet, "/api/v1/queue/pause", nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Pause pauses delivery from a sender domain, or all delivery if domain is empty
+func (c *Client) Pause(ctx context.Context, domain string) (*PauseStatus, error) {
+	var resp PauseStatus
+	if err := c.request(ctx, http.MethodPost, "/api/v1/queue/pause", map[string]string{"domain": domain}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Resume resumes delivery paused with Pause
+func (c *Client) Resume(ctx context.Context, domain string) (*PauseStatus, error) {
+	var resp PauseStatus
+	if err := c.request(ctx, http.MethodPost, "/api/v1/queue/resume", map[string]string{"domain": domain}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// DeleteFromQueue deletes a message from queue
+func (c *Client) DeleteFromQueue(ctx context.Context, id string) error {
+	return c.request(ctx, http.MethodDelete, "/api/v1/queue/"+id, nil, nil)
+}
+
+// RetryMessage sends a deferred or failed message again now
+func (c *Client) RetryMessage(ctx context.Context, id string) error {
+	return c.request(ctx, http.MethodPost, "/api/v1/queue/"+url.PathEscape(id)+"/retry", nil, nil)
+}
+
+// PurgeQueue deletes all messages from queue
+func (c *Client) PurgeQueue(ctx context.Context) (int, error) {
+	queue, err := c.GetQueue(ctx)
+	if err != nil {
+		return 0, err
+	}
+	deleted := 0
+	for _, m := range queue.Messages {
+		if err := c.DeleteFromQueue(ctx, m.ID); err == nil {
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// GetDLQ gets dead letter queue
+func (c *Client) GetDLQ(ctx context.Context) (*DLQResponse, error) {
+	var resp DLQResponse
+	if err := c.request(ctx, http.MethodGet, "/api/v1/dlq", nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetDLQMessage gets a message of the DLQ
+func (c *Client) GetDLQMessage(ctx context.Context, id string) (*StatusResponse, error) {
+	var resp StatusResponse
+	if err := c.request(ctx, http.MethodGet, "/api/v1/dlq/"+url.PathEscape(id), nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// RetryDLQ retries a message from DLQ
+func (c *Client) RetryDLQ(ctx context.Context, id string) error {
+	return c.request(ctx, http.MethodPost, "/api/v1/dlq/"+id+"/retry", nil, nil)
+}
+
+// DeleteFromDLQ deletes a message from DLQ
+func (c *Client) DeleteFromDLQ(ctx context.Context, id string) error {
+	return c.request(ctx, http.MethodDelete, "/api/v1/dlq/"+id, nil, nil)
+}
+
+// PurgeDLQ deletes all messages from DLQ
+func (c *Client) PurgeDLQ(ctx context.Context) (int, error) {
+	dlq, err := c.GetDLQ(ctx)
+	if err != nil {
+		return 0, err
+	}
+	deleted := 0
+	for _, m := range dlq.Messages {
+		if err := c.DeleteFromDLQ(ctx, m.ID); err == nil {
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// ListDomains lists all domains
+func (c *Client) ListDomains(ctx context.Context) (*DomainsListResponse, error) {
+	var resp DomainsListResponse
+	if err := c.request(ctx, http.MethodGet, "/api/v1/domains", nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetDomain gets a domain by name
+func (c *Client) GetDomain(ctx context.Context, domain string) (*Domain, error) {
+	var resp Domain
+	if err := c.request(ctx, http.MethodGet, "/api/v1/domains/"+domain, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// CreateDomain creates a new domain
+func (c *Client) CreateDomain(ctx context.Context, req *DomainCreateRequest) (*Domain, error) {
+	var resp Domain
+	if err := c.request(ctx, http.MethodPost, "/api/v1/domains", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// UpdateDomain updates an existing domain
+func (c *Client) UpdateDomain(ctx context.Context, domain string, req *DomainUpdateRequest) (*Domain, error) {
+	var resp Domain
+	if err := c.request(ctx, http.MethodPut, "/api/v1/domains/"+domain, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// DeleteDomain deletes a domain
+func (c *Client) DeleteDomain(ctx context.Context, domain string) error {
+	return c.request(ctx, http.MethodDelete, "/api/v1/domains/"+domain, nil, nil)
+}
+
+// ListShapingSchedules lists per-domain send schedules
+func (c *Client) ListShapingSchedules(ctx context.Context) (*ShapingScheduleListResponse, error) {
+	var resp ShapingScheduleListResponse
+	if err := c.request(ctx, http.MethodGet, "/api/v1/shaping", nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetShapingSchedule gets the send schedule of a domain
+func (c *Client) GetShapingSchedule(ctx context.Context, domain string) (*ShapingSchedule, error) {
+	var resp ShapingSchedule
+	if err := c.request(ctx, http.MethodGet, "/api/v1/shaping/"+domain, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// PutShapingSchedule creates or replaces the send schedule of a domain
+func (c *Client) PutShapingSchedule(ctx context.Context, domain string, req *ShapingScheduleRequest) (*ShapingSchedule, error) {
+	var resp ShapingSchedule
+	if err := c.request(ctx, http.MethodPut, "/api/v1/shaping/"+domain, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// DeleteShapingSchedule deletes the send schedule of a domain
+func (c *Client) DeleteShapingSchedule(ctx context.Context, domain string) error {
+	return c.request(ctx, http.MethodDelete, "/api/v1/shaping/"+domain, nil, nil)
+}
+
+// ListAPIKeys lists the keys of the API key store
+func (c *Client) ListAPIKeys(ctx context.Context) (*APIKeyListResponse, error) {
+	var resp APIKeyListResponse
+	if err := c.request(ctx, http.MethodGet, "/api/v1/apikeys", nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// CreateAPIKey creates an API key, the token is returned only once
+func (c *Client) CreateAPIKey(ctx context.Context, req *APIKeyCreateRequest) (*APIKeyCreateResponse, error) {
+	var resp APIKeyCreateResponse
+	if err := c.request(ctx, http.MethodPost, "/api/v1/apikeys", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetAPIKey gets a key of the API key store
+func (c *Client) GetAPIKey(ctx context.Context, id string) (*APIKey, error) {
+	var resp APIKey
+	if err := c.request(ctx, http.MethodGet, "/api/v1/apikeys/"+url.PathEscape(id), nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// RevokeAPIKey revokes an API key
+func (c *Client) RevokeAPIKey(ctx context.Context, id string) error {
+	return c.request(ctx, http.MethodDelete, "/api/v1/apikeys/"+id, nil, nil)
+}
+
+// ListSMTPUsers lists the users of the SMTP credential store
+func (c *Client) ListSMTPUsers(ctx context.Context) (*SMTPUserListResponse, error) {
+	var resp SMTPUserListResponse
+	if err := c.request(ctx, http.MethodGet, "/api/v1/smtp-users", nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// CreateSMTPUser creates a user of the SMTP credential store
+func (c *Client) CreateSMTPUser(ctx context.Context, req *SMTPUserCreateRequest) (*SMTPUser, error) {
+	var resp SMTPUser
+	if err := c.request(ctx, http.MethodPost, "/api/v1/smtp-users", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetSMTPUser gets a user of the SMTP credential store
+func (c *Client) GetSMTPUser(ctx context.Context, username string) (*SMTPUser, error) {
+	var resp SMTPUser
+	if err := c.request(ctx, http.MethodGet, "/api/v1/smtp-users/"+url.PathEscape(username), nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// UpdateSMTPUser rotates the password, disables or enables an SMTP user or
+// changes its senders
+func (c *Client) UpdateSMTPUser(ctx context.Context, username string, req *SMTPUserUpdateRequest) (*SMTPUser, error) {
+	var resp SMTPUser
+	if err := c.request(ctx, http.MethodPut, "/api/v1/smtp-users/"+url.PathEscape(username), req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// DeleteSMTPUser deletes a user of the SMTP credential store
+func (c *Client) DeleteSMTPUser(ctx context.Context, username string) error {
+	return c.request(ctx, http.MethodDelete, "/api/v1/smtp-users/"+url.PathEscape(username), nil, nil)
+}
+
+// ListReputation lists the reputation scores of sender domains
+func (c *Client) ListReputation(ctx context.Context) (*ReputationListResponse, error) {
+	var resp ReputationListResponse
+	if err := c.request(ctx, http.MethodGet, "/api/v1/reputation", nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ListComplaints lists the spam complaints received by a server, newest
+// first. campaignID limits them to one campaign if set.
+func (c *Client) ListComplaints(ctx context.Context, campaignID string, limit int) (*ComplaintListResponse, error) {
+	params := url.Values{}
+	if campaignID != "" {
+		params.Set("campaign_id", campaignID)
+	}
+	if limit > 0 {
+		params.Set("limit", fmt.Sprintf("%d", limit))
+	}
+	path := "/api/v1/fbl/complaints"
+	if len(params) > 0 {
+		path += "?" + params.Encode()
+	}
+
+	var resp ComplaintListResponse
+	if err := c.request(ctx, http.MethodGet, path, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ListSuppressions lists the suppressed recipients of a server
+func (c *Client) ListSuppressions(ctx context.Context, limit, offset int) (*SuppressionListResponse, error) {
+	path := fmt.Sprintf("/api/v1/suppressions?limit=%d&offset=%d", limit, offset)
+	var resp SuppressionListResponse
+	if err := c.request(ctx, http.MethodGet, path, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetSuppression returns the suppression of a recipient on a server, or
+// nil if the recipient is not suppressed
+func (c *Client) GetSuppression(ctx context.Context, email string) (*Suppression, error) {
+	var resp Suppression
+	if err := c.request(ctx, http.MethodGet, "/api/v1/suppressions/"+url.PathEscape(email), nil, &resp); err != nil {
+		if IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// AddSuppression puts a recipient on the suppression list of a server
+func (c *Client) AddSuppression(ctx context.Context, req *SuppressionRequest) (*Suppression, error) {
+	var resp Suppression
+	if err := c.request(ctx, http.MethodPost, "/api/v1/suppressions", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// RemoveSuppression takes a recipient off the suppression list of a server
+func (c *Client) RemoveSuppression(ctx context.Context, email string) error {
+	return c.request(ctx, http.MethodDelete, "/api/v1/suppressions/"+url.PathEscape(email), nil, nil)
+}
+
+// GetReputation gets the reputation score of a domain with its score history
+func (c *Client) GetReputation(ctx context.Context, domain string) (*ReputationScore, error) {
+	var resp ReputationScore
+	if err := c.request(ctx, http.MethodGet, "/api/v1/reputation/"+domain, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetAuditLog returns management API audit entries linked to a correlation ID
+func (c *Client) GetAuditLog(ctx context.Context, correlationID string, limit int) (*AuditLogResponse, error) {
+	return c.ListAuditLog(ctx, AuditLogFilter{CorrelationID: correlationID, Limit: limit})
+}
+
+// ListAuditLog returns management API audit entries matching the filter
+func (c *Client) ListAuditLog(ctx context.Context, filter AuditLogFilter) (*AuditLogResponse, error) {
+	params := url.Values{}
+	if filter.CorrelationID != "" {
+		params.Set("correlation_id", filter.CorrelationID)
+	}
+	if filter.Actor != "" {
+		params.Set("actor", filter.Actor)
+	}
+	if filter.Path != "" {
+		params.Set("path", filter.Path)
+	}
+	if filter.Limit > 0 {
+		params.Set("limit", fmt.Sprintf("%d", filter.Limit))
+	}
+	path := "/api/v1/audit"
+	if len(params) > 0 {
+		path += "?" + params.Encode()
+	}
+
+	var resp AuditLogResponse
+	if err := c.request(ctx, http.MethodGet, path, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ListTemplates lists templates
+func (c *Client) ListTemplates(ctx context.Context, search string, limit, offset int) (*TemplateListResponse, error) {
+	path := "/api/v1/templates"
+	params := url.Values{}
+	if search != "" {
+		params.Set("search", search)
+	}
+	if limit > 0 {
+		params.Set("limit", fmt.Sprintf("%d", limit))
+	}
+	if offset > 0 {
+		params.Set("offset", fmt.Sprintf("%d", offset))
+	}
+	if len(params) > 0 {
+		path += "?" + params.Encode()
+	}
+
+	var resp TemplateListResponse
+	if err := c.request(ctx, http.MethodGet, path, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetTemplate gets a template by ID
+func (c *Client) GetTemplate(ctx context.Context, id string) (*TemplateResponse, error) {
+	var resp TemplateResponse
+	if err := c.request(ctx, http.MethodGet, "/api/v1/templates/"+id, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// CreateTemplate creates a new template
+func (c *Client) CreateTemplate(ctx context.Context, req *TemplateCreateRequest) (*TemplateResponse, error) {
+	var resp TemplateResponse
+	if err := c.request(ctx, http.MethodPost, "/api/v1/templates", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// UpdateTemplate updates a template
+func (c *Client) UpdateTemplate(ctx context.Context, id string, req *TemplateUpdateRequest) (*TemplateResponse, error) {
+	var resp TemplateResponse
+	if err := c.request(ctx, http.MethodPut, "/api/v1/templates/"+id, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// DeleteTemplate deletes a template
+func (c *Client) DeleteTemplate(ctx context.Context, id string) error {
+	return c.request(ctx, http.MethodDelete, "/api/v1/templates/"+id, nil, nil)
+}
+
+// PreviewTemplate previews a template with data
+func (c *Client) PreviewTemplate(ctx context.Context, id string, data map[string]any) (*TemplatePreviewResponse, error) {
+	var resp TemplatePreviewResponse
+	req := TemplatePreviewRequest{Data: data}
+	if err := c.request(ctx, http.MethodPost, "/api/v1/templates/"+id+"/preview", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ListSandboxMessages lists sandbox messages, newest first
+func (c *Client) ListSandboxMessages(ctx context.Context, filter SandboxFilter) (*SandboxListResponse, error) {
+	path := "/api/v1/sandbox/messages"
+	params := url.Values{}
+	if filter.Domain != "" {
+		params.Set("domain", filter.Domain)
+	}
+	if filter.Mode != "" {
+		params.Set("mode", filter.Mode)
+	}
+	if filter.Search != "" {
+		params.Set("q", filter.Search)
+	}
+	if filter.Limit > 0 {
+		params.Set("limit", fmt.Sprintf("%d", filter.Limit))
+	}
+	if filter.Offset > 0 {
+		params.Set("offset", fmt.Sprintf("%d", filter.Offset))
+	}
+	if len(params) > 0 {
+		path += "?" + params.Encode()
+	}
+
+	var resp SandboxListResponse
+	if err := c.request(ctx, http.MethodGet, path, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetSandboxMessage gets a sandbox message
+func (c *Client) GetSandboxMessage(ctx context.Context, id string) (*SandboxMessageDetailResponse, error) {
+	var resp SandboxMessageDetailResponse
+	if err := c.request(ctx, http.MethodGet, "/api/v1/sandbox/messages/"+url.PathEscape(id), nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetSandboxRaw gets the raw source of a sandbox message
+func (c *Client) GetSandboxRaw(ctx context.Context, id string) ([]byte, error) {
+	var data []byte
+	if err := c.request(ctx, http.MethodGet, "/api/v1/sandbox/messages/"+url.PathEscape(id)+"/raw", nil, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// DeleteSandboxMessage deletes a sandbox message
+func (c *Client) DeleteSandboxMessage(ctx context.Context, id string) error {
+	return c.request(ctx, http.MethodDelete, "/api/v1/sandbox/messages/"+url.PathEscape(id), nil, nil)
+}
+
+// ReleaseSandboxMessage queues a sandbox message for delivery to its
+// original recipients
+func (c *Client) ReleaseSandboxMessage(ctx context.Context, id string) (*SandboxReleaseResult, error) {
+	var resp SandboxReleaseResult
+	if err := c.request(ctx, http.MethodPost, "/api/v1/sandbox/messages/"+url.PathEscape(id)+"/release", nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ReleaseSandbox queues several sandbox messages, or those of a domain, for
+// delivery to their original recipients
+func (c *Client) ReleaseSandbox(ctx context.Context, req *SandboxReleaseRequest) (*SandboxReleaseResponse, error) {
+	var resp SandboxReleaseResponse
+	if err := c.request(ctx, http.MethodPost, "/api/v1/sandbox/release", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ClearSandbox deletes the sandbox messages of a domain, all domains if
+// empty, captured more than olderThan ago (a Go duration, empty for all)
+func (c *Client) ClearSandbox(ctx context.Context, domain, olderThan string) (*SandboxClearResponse, error) {
+	params := url.Values{}
+	if domain != "" {
+		params.Set("domain", domain)
+	}
+	if olderThan != "" {
+		params.Set("older_than", olderThan)
+	}
+	path := "/api/v1/sandbox/messages"
+	if len(params) > 0 {
+		path += "?" + params.Encode()
+	}
+
+	var resp SandboxClearResponse
+	if err := c.request(ctx, http.MethodDelete, path, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetSandboxStats gets sandbox statistics
+func (c *Client) GetSandboxStats(ctx context.Context) (*SandboxStatsResponse, error) {
+	var resp SandboxStatsResponse
+	if err := c.request(ctx, http.MethodGet, "/api/v1/sandbox/stats", nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GenerateDKIM generates a new DKIM key on the server
+func (c *Client) GenerateDKIM(ctx context.Context, domain, selector string) (*DKIMResponse, error) {
+	req := &DKIMGenerateRequest{Domain: domain, Selector: selector}
+	var resp DKIMResponse
+	if err := c.request(ctx, http.MethodPost, "/api/v1/dkim/generate", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// UploadDKIM uploads an existing DKIM key to the server
+func (c *Client) UploadDKIM(ctx context.Context, domain, selector, privateKey string) (*DKIMResponse, error) {
+	req := &DKIMUploadRequest{Domain: domain, Selector: selector, PrivateKey: privateKey}
+	var resp DKIMResponse
+	if err := c.request(ctx, http.MethodPost, "/api/v1/dkim/upload", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetDKIM gets DKIM information for a domain
+func (c *Client) GetDKIM(ctx context.Context, domain string) (*DKIMInfoResponse, error) {
+	var resp DKIMInfoResponse
+	if err := c.request(ctx, http.MethodGet, "/api/v1/dkim/"+domain, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// VerifyDKIM verifies DKIM setup for a domain
+func (c *Client) VerifyDKIM(ctx context.Context, domain string) (*DKIMVerifyResponse, error) {
+	var resp DKIMVerifyResponse
+	if err := c.request(ctx, http.MethodGet, "/api/v1/dkim/"+domain+"/verify", nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// DeleteDKIM deletes a DKIM key from the server
+func (c *Client) DeleteDKIM(ctx context.Context, domain, selector string) error {
+	return c.request(ctx, http.MethodDelete, "/api/v1/dkim/"+domain+"/"+selector, nil, nil)
+}
+
+// CheckDNS checks DNS records for a domain
+func (c *Client) CheckDNS(ctx context.Context, domain, selector string) (*DNSCheckResult, error) {
+	path := "/api/v1/dns/check/" + url.PathEscape(domain)
+	if selector != "" {
+		path += "?selector=" + url.QueryEscape(selector)
+	}
+	var resp DNSCheckResult
+	if err := c.request(ctx, http.MethodGet, path, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetDNSHistory gets the scheduled DNS check results of a domain over the
+// last hours. Returns nil without an error if the domain has not been checked
+// or DNS monitoring is disabled on the server.
+func (c *Client) GetDNSHistory(ctx context.Context, domain string, hours int) (*DNSHistoryResponse, error) {
+	path := fmt.Sprintf("/api/v1/domains/%s/dns/history?hours=%d", url.PathEscape(domain), hours)
+	var resp DNSHistoryResponse
+	if err := c.request(ctx, http.MethodGet, path, nil, &resp); err != nil {
+		if IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// CheckIP checks an IP address against DNSBL services
+func (c *Client) CheckIP(ctx context.Context, ip string) (*IPCheckResult, error) {
+	var resp IPCheckResult
+	if err := c.request(ctx, http.MethodGet, "/api/v1/ip/check/"+url.PathEscape(ip), nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ListDNSBLs lists available DNSBL services
+func (c *Client) ListDNSBLs(ctx context.Context) (*DNSBLListResponse, error) {
+	var resp DNSBLListResponse
+	if err := c.request(ctx, http.MethodGet, "/api/v1/ip/dnsbls", nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ListMonitoredIPs lists the outbound IPs monitored against DNSBLs with
+// their latest check. Returns nil without an error if DNSBL monitoring is
+// disabled on the server.
+func (c *Client) ListMonitoredIPs(ctx context.Context) (*MonitoredIPsResponse, error) {
+	var resp MonitoredIPsResponse
+	if err := c.request(ctx, http.MethodGet, "/api/v1/ip/monitored", nil, &resp); err != nil {
+		if IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// AddMonitoredIP registers an outbound IP for DNSBL monitoring
+func (c *Client) AddMonitoredIP(ctx context.Context, ip, note string) (*MonitoredIP, error) {
+	var resp MonitoredIP
+	body := map[string]string{"ip": ip, "note": note}
+	if err := c.request(ctx, http.MethodPost, "/api/v1/ip/monitored", body, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// RemoveMonitoredIP stops DNSBL monitoring of an outbound IP
+func (c *Client) RemoveMonitoredIP(ctx context.Context, ip string) error {
+	return c.request(ctx, http.MethodDelete, "/api/v1/ip/monitored/"+url.PathEscape(ip), nil, nil)
+}
+
+// CheckMonitoredIP checks a monitored IP against DNSBLs now
+func (c *Client) CheckMonitoredIP(ctx context.Context, ip string) (*DNSBLCheckRun, error) {
+	var resp DNSBLCheckRun
+	if err := c.request(ctx, http.MethodPost, "/api/v1/ip/monitored/"+url.PathEscape(ip)+"/check", nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetRateLimitStats returns the counters and limits of a rate limit key,
+// e.g. level "global" and key "global"
+func (c *Client) GetRateLimitStats(ctx context.Context, level, key string) (*RateLimitStats, error) {
+	var resp RateLimitStats
+	path := "/api/v1/ratelimits/" + url.PathEscape(level) + "/" + url.PathEscape(key)
+	if err := c.request(ctx, http.MethodGet, path, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ListTLSCertificates lists the TLS certificates of the server
+func (c *Client) ListTLSCertificates(ctx context.Context) (*TLSCertificateListResponse, error) {
+	var resp TLSCertificateListResponse
+	if err := c.request(ctx, http.MethodGet, "/api/v1/tls/certificates", nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// RenewTLSCertificate forces the renewal of an ACME certificate
+func (c *Client) RenewTLSCertificate(ctx context.Context, domain string) (*TLSCertificate, error) {
+	var resp TLSCertificate
+	path := "/api/v1/tls/certificates/" + url.PathEscape(domain) + "/renew"
+	if err := c.request(ctx, http.MethodPost, path, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Metrics reads the Prometheus metrics of the server, summed over labels by
+// metric name. It returns nil if no metrics URL is configured.
+func (c *Client) Metrics(ctx context.Context) (map[string]float64, error) {
+	if c.metricsURL == "" {
+		return nil, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.metricsURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &APIError{StatusCode: resp.StatusCode}
+	}
+	return parseMetrics(resp.Body)
+}
+
+// parseMetrics parses the Prometheus text format, adding up the samples of
+// each metric name
+func parseMetrics(r io.Reader) (map[string]float64, error) {
+	metrics := make(map[string]float64)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name, rest := line, ""
+		if i := strings.IndexAny(line, "{ "); i >= 0 {
+			name, rest = line[:i], line[i:]
+		}
+		if strings.HasPrefix(rest, "{") {
+			end := strings.LastIndex(rest, "}")
+			if end < 0 {
+				continue
+			}
+			rest = rest[end+1:]
+		}
+
+		// A timestamp may follow the value
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			continue
+		}
+		value, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			continue
+		}
+		metrics[name] += value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read metrics: %w", err)
+	}
+	return metrics, nil
+}
@@ -0,0 +1,536 @@
+package client
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func setupTestServer(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return New(server.URL, "test-key")
+}
+
+func TestClient_GetQueue(t *testing.T) {
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/queue" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if r.Method != "GET" {
+			t.Errorf("unexpected method: %s", r.Method)
+		}
+		json.NewEncoder(w).Encode(QueueResponse{
+			Stats: &QueueStats{Pending: 3},
+			Messages: []*MessageSummary{
+				{ID: "msg-1", From: "a@test.com", Subject: "Test 1"},
+				{ID: "msg-2", From: "b@test.com", Subject: "Test 2"},
+				{ID: "msg-3", From: "c@test.com", Subject: "Test 3"},
+			},
+		})
+	})
+
+	resp, err := client.GetQueue(context.Background())
+	if err != nil {
+		t.Fatalf("GetQueue() error = %v", err)
+	}
+	if resp.Stats.Pending != 3 {
+		t.Errorf("GetQueue() pending = %d, want 3", resp.Stats.Pending)
+	}
+	if len(resp.Messages) != 3 {
+		t.Errorf("GetQueue() messages = %d, want 3", len(resp.Messages))
+	}
+}
+
+func TestClient_DeleteFromQueue(t *testing.T) {
+	var gotPath string
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		if r.Method != "DELETE" {
+			t.Errorf("unexpected method: %s", r.Method)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	err := client.DeleteFromQueue(context.Background(), "msg-123")
+	if err != nil {
+		t.Fatalf("DeleteFromQueue() error = %v", err)
+	}
+	if gotPath != "/api/v1/queue/msg-123" {
+		t.Errorf("DeleteFromQueue() path = %q, want /api/v1/queue/msg-123", gotPath)
+	}
+}
+
+func TestClient_PurgeQueue(t *testing.T) {
+	var deletedIDs []string
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/queue" && r.Method == "GET" {
+			json.NewEncoder(w).Encode(QueueResponse{
+				Stats: &QueueStats{Pending: 2},
+				Messages: []*MessageSummary{
+					{ID: "msg-1"},
+					{ID: "msg-2"},
+				},
+			})
+			return
+		}
+		if r.Method == "DELETE" && strings.HasPrefix(r.URL.Path, "/api/v1/queue/") {
+			id := strings.TrimPrefix(r.URL.Path, "/api/v1/queue/")
+			deletedIDs = append(deletedIDs, id)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+	})
+
+	deleted, err := client.PurgeQueue(context.Background())
+	if err != nil {
+		t.Fatalf("PurgeQueue() error = %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("PurgeQueue() deleted = %d, want 2", deleted)
+	}
+	if len(deletedIDs) != 2 {
+		t.Errorf("PurgeQueue() delete requests = %d, want 2", len(deletedIDs))
+	}
+}
+
+func TestClient_GetDLQ(t *testing.T) {
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/dlq" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		json.NewEncoder(w).Encode(DLQResponse{
+			Stats:    &DLQStats{Total: 1},
+			Messages: []*MessageSummary{{ID: "dlq-1"}},
+		})
+	})
+
+	resp, err := client.GetDLQ(context.Background())
+	if err != nil {
+		t.Fatalf("GetDLQ() error = %v", err)
+	}
+	if resp.Stats.Total != 1 {
+		t.Errorf("GetDLQ() total = %d, want 1", resp.Stats.Total)
+	}
+}
+
+func TestClient_RetryDLQ(t *testing.T) {
+	var gotPath, gotMethod string
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotMethod = r.Method
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	err := client.RetryDLQ(context.Background(), "dlq-1")
+	if err != nil {
+		t.Fatalf("RetryDLQ() error = %v", err)
+	}
+	if gotPath != "/api/v1/dlq/dlq-1/retry" {
+		t.Errorf("RetryDLQ() path = %q, want /api/v1/dlq/dlq-1/retry", gotPath)
+	}
+	if gotMethod != "POST" {
+		t.Errorf("RetryDLQ() method = %q, want POST", gotMethod)
+	}
+}
+
+func TestClient_DeleteFromDLQ(t *testing.T) {
+	var gotPath string
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	err := client.DeleteFromDLQ(context.Background(), "dlq-1")
+	if err != nil {
+		t.Fatalf("DeleteFromDLQ() error = %v", err)
+	}
+	if gotPath != "/api/v1/dlq/dlq-1" {
+		t.Errorf("DeleteFromDLQ() path = %q, want /api/v1/dlq/dlq-1", gotPath)
+	}
+}
+
+func TestClient_PurgeDLQ(t *testing.T) {
+	var deletedIDs []string
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/dlq" && r.Method == "GET" {
+			json.NewEncoder(w).Encode(DLQResponse{
+				Stats: &DLQStats{Total: 3},
+				Messages: []*MessageSummary{
+					{ID: "dlq-1"},
+					{ID: "dlq-2"},
+					{ID: "dlq-3"},
+				},
+			})
+			return
+		}
+		if r.Method == "DELETE" && strings.HasPrefix(r.URL.Path, "/api/v1/dlq/") {
+			id := strings.TrimPrefix(r.URL.Path, "/api/v1/dlq/")
+			deletedIDs = append(deletedIDs, id)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+	})
+
+	deleted, err := client.PurgeDLQ(context.Background())
+	if err != nil {
+		t.Fatalf("PurgeDLQ() error = %v", err)
+	}
+	if deleted != 3 {
+		t.Errorf("PurgeDLQ() deleted = %d, want 3", deleted)
+	}
+}
+
+func TestClient_PurgeQueue_EmptyQueue(t *testing.T) {
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(QueueResponse{
+			Stats:    &QueueStats{Pending: 0},
+			Messages: []*MessageSummary{},
+		})
+	})
+
+	deleted, err := client.PurgeQueue(context.Background())
+	if err != nil {
+		t.Fatalf("PurgeQueue() error = %v", err)
+	}
+	if deleted != 0 {
+		t.Errorf("PurgeQueue() deleted = %d, want 0", deleted)
+	}
+}
+
+func TestClient_AuthHeader(t *testing.T) {
+	var gotAuth string
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		json.NewEncoder(w).Encode(HealthResponse{Status: "ok"})
+	})
+
+	_, err := client.Health(context.Background())
+	if err != nil {
+		t.Fatalf("Health() error = %v", err)
+	}
+	if gotAuth != "Bearer test-key" {
+		t.Errorf("Authorization header = %q, want %q", gotAuth, "Bearer test-key")
+	}
+}
+
+func TestClient_CorrelationHeader(t *testing.T) {
+	var gotIDs []string
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		gotIDs = append(gotIDs, r.Header.Get(CorrelationHeader))
+		json.NewEncoder(w).Encode(HealthResponse{Status: "ok"})
+	})
+
+	if _, err := client.Health(context.Background()); err != nil {
+		t.Fatalf("Health() error = %v", err)
+	}
+	ctx := WithCorrelationID(context.Background(), "deploy-1")
+	if _, err := client.Health(ctx); err != nil {
+		t.Fatalf("Health() error = %v", err)
+	}
+
+	if gotIDs[0] != "" {
+		t.Errorf("correlation header without ID = %q, want empty", gotIDs[0])
+	}
+	if gotIDs[1] != "deploy-1" {
+		t.Errorf("correlation header = %q, want deploy-1", gotIDs[1])
+	}
+}
+
+func TestClient_GetAuditLog(t *testing.T) {
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/audit" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if got := r.URL.Query().Get("correlation_id"); got != "deploy-1" {
+			t.Errorf("correlation_id = %q, want deploy-1", got)
+		}
+		json.NewEncoder(w).Encode(AuditLogResponse{
+			Entries: []AuditEntry{{CorrelationID: "deploy-1", Method: "PUT", Path: "/api/v1/domains/a.com", Status: 500, Error: "boom"}},
+			Total:   1,
+		})
+	})
+
+	resp, err := client.GetAuditLog(context.Background(), "deploy-1", 100)
+	if err != nil {
+		t.Fatalf("GetAuditLog() error = %v", err)
+	}
+	if len(resp.Entries) != 1 || !resp.Entries[0].Failed() {
+		t.Errorf("GetAuditLog() = %+v", resp.Entries)
+	}
+}
+
+func TestClient_ActorHeader(t *testing.T) {
+	var got string
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get(ActorHeader)
+		json.NewEncoder(w).Encode(HealthResponse{Status: "ok"})
+	})
+
+	if _, err := client.Health(WithActor(context.Background(), "admin@example.com")); err != nil {
+		t.Fatalf("Health() error = %v", err)
+	}
+	if got != "admin@example.com" {
+		t.Errorf("actor header = %q, want admin@example.com", got)
+	}
+}
+
+func TestClient_ListAuditLog(t *testing.T) {
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("actor") != "admin@example.com" || q.Get("path") != "/api/v1/domains/" || q.Get("limit") != "50" {
+			t.Errorf("unexpected query: %s", r.URL.RawQuery)
+		}
+		json.NewEncoder(w).Encode(AuditLogResponse{
+			Entries: []AuditEntry{{Actor: "admin@example.com", APIKeyID: "k1", Old: json.RawMessage(`{"mode":"sandbox"}`)}},
+			Total:   1,
+		})
+	})
+
+	resp, err := client.ListAuditLog(context.Background(), AuditLogFilter{Actor: "admin@example.com", Path: "/api/v1/domains/", Limit: 50})
+	if err != nil {
+		t.Fatalf("ListAuditLog() error = %v", err)
+	}
+	if len(resp.Entries) != 1 || resp.Entries[0].Caller() != "admin@example.com" || string(resp.Entries[0].Old) != `{"mode":"sandbox"}` {
+		t.Errorf("ListAuditLog() = %+v", resp.Entries)
+	}
+}
+
+func TestClient_Complaints(t *testing.T) {
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/fbl/complaints":
+			if r.URL.Query().Get("campaign_id") != "c1" {
+				t.Errorf("unexpected query: %s", r.URL.RawQuery)
+			}
+			json.NewEncoder(w).Encode(ComplaintListResponse{
+				Complaints: []Complaint{{ID: 1, FeedbackType: "abuse", Recipient: "user@example.net", CampaignID: "c1", Suppressed: true}},
+				Total:      1,
+			})
+		case r.Method == http.MethodDelete && r.URL.EscapedPath() == "/api/v1/suppressions/user+tag@example.net":
+			json.NewEncoder(w).Encode(map[string]string{"status": "removed"})
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/suppressions/user@example.net":
+			json.NewEncoder(w).Encode(Suppression{Email: "user@example.net", Reason: "complaint"})
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/suppressions/ok@example.net":
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "Address is not suppressed"})
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.EscapedPath())
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	resp, err := client.ListComplaints(context.Background(), "c1", 10)
+	if err != nil {
+		t.Fatalf("ListComplaints() error = %v", err)
+	}
+	if len(resp.Complaints) != 1 || !resp.Complaints[0].Suppressed {
+		t.Errorf("ListComplaints() = %+v", resp.Complaints)
+	}
+
+	if err := client.RemoveSuppression(context.Background(), "user+tag@example.net"); err != nil {
+		t.Errorf("RemoveSuppression() error = %v", err)
+	}
+
+	if s, err := client.GetSuppression(context.Background(), "user@example.net"); err != nil || s == nil || s.Reason != "complaint" {
+		t.Errorf("GetSuppression() = %+v, %v", s, err)
+	}
+	if s, err := client.GetSuppression(context.Background(), "ok@example.net"); err != nil || s != nil {
+		t.Errorf("GetSuppression() of an address that is not suppressed = %+v, %v, want nil", s, err)
+	}
+}
+
+func TestClient_GetDNSHistory(t *testing.T) {
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/domains/example.com/dns/history":
+			if r.URL.Query().Get("hours") != "168" {
+				t.Errorf("unexpected query: %s", r.URL.RawQuery)
+			}
+			run := DNSCheckRun{
+				Domain:      "example.com",
+				Results:     []DNSCheckItem{{Type: "DKIM Record (mail._domainkey)", Status: "not_found"}},
+				Regressions: []DNSRegression{{Type: "DKIM Record (mail._domainkey)", From: "ok", To: "not_found"}},
+			}
+			json.NewEncoder(w).Encode(DNSHistoryResponse{Domain: "example.com", Latest: &run, History: []DNSCheckRun{run}, Total: 1})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "DNS monitoring is not enabled"})
+		}
+	})
+
+	resp, err := client.GetDNSHistory(context.Background(), "example.com", 168)
+	if err != nil || resp == nil || resp.Latest == nil || len(resp.History[0].Regressions) != 1 {
+		t.Fatalf("GetDNSHistory() = %+v, %v", resp, err)
+	}
+	if resp, err := client.GetDNSHistory(context.Background(), "other.com", 168); err != nil || resp != nil {
+		t.Errorf("GetDNSHistory() without results = %+v, %v, want nil", resp, err)
+	}
+}
+
+func TestClient_MonitoredIPs(t *testing.T) {
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/ip/monitored":
+			run := DNSBLCheckRun{
+				IP:          "192.0.2.10",
+				Listings:    []DNSBLResult{{DNSBL: DNSBLInfo{Zone: "bl.spamcop.net", DelistURL: "https://www.spamcop.net/bl.shtml"}, Listed: true}},
+				NewlyListed: []string{"bl.spamcop.net"},
+			}
+			json.NewEncoder(w).Encode(MonitoredIPsResponse{IPs: []MonitoredIP{{IP: "192.0.2.10", Source: "config", Latest: &run}}, Total: 1})
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v1/ip/monitored":
+			var req map[string]string
+			json.NewDecoder(r.Body).Decode(&req)
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(MonitoredIP{IP: req["ip"], Note: req["note"], Source: "api"})
+		case r.Method == http.MethodDelete && r.URL.Path == "/api/v1/ip/monitored/192.0.2.10":
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "IP is configured in dnsbl_monitor.ips"})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	resp, err := client.ListMonitoredIPs(context.Background())
+	if err != nil || resp == nil || resp.Total != 1 || resp.IPs[0].Latest.Listings[0].DNSBL.DelistURL == "" {
+		t.Fatalf("ListMonitoredIPs() = %+v, %v", resp, err)
+	}
+	ip, err := client.AddMonitoredIP(context.Background(), "198.51.100.7", "relay")
+	if err != nil || ip.IP != "198.51.100.7" || ip.Note != "relay" {
+		t.Errorf("AddMonitoredIP() = %+v, %v", ip, err)
+	}
+	if err := client.RemoveMonitoredIP(context.Background(), "192.0.2.10"); err == nil {
+		t.Error("RemoveMonitoredIP(config ip) expected error, got nil")
+	}
+}
+
+func TestClient_RenewTLSCertificate(t *testing.T) {
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v1/tls/certificates/mx.example.com/renew" {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(ErrorResponse{Error: "domain is not managed by ACME"})
+			return
+		}
+		days := 89
+		json.NewEncoder(w).Encode(TLSCertificate{Domain: "mx.example.com", ACME: true, Issuer: "R11", DaysLeft: &days})
+	})
+
+	cert, err := client.RenewTLSCertificate(context.Background(), "mx.example.com")
+	if err != nil || cert.Issuer != "R11" || cert.DaysLeft == nil || *cert.DaysLeft != 89 {
+		t.Fatalf("RenewTLSCertificate() = %+v, %v", cert, err)
+	}
+	if _, err := client.RenewTLSCertificate(context.Background(), "other.example.com"); err == nil {
+		t.Error("RenewTLSCertificate(unknown domain) expected error, got nil")
+	}
+}
+
+func TestClient_APIError(t *testing.T) {
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ErrorResponse{Error: "not found"})
+	})
+
+	_, err := client.GetQueue(context.Background())
+	if err == nil {
+		t.Fatal("GetQueue() expected error, got nil")
+	}
+	if !strings.Contains(err.Error(), "not found") {
+		t.Errorf("error = %q, want to contain 'not found'", err.Error())
+	}
+}
+
+func TestClient_ClientCertificate(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "sendry-web"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 || r.TLS.PeerCertificates[0].Subject.CommonName != "sendry-web" {
+			http.Error(w, "client certificate required", http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(QueueResponse{Stats: &QueueStats{Pending: 1}})
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+
+	client := NewWithTLS(server.URL, "test-key", &tls.Config{
+		RootCAs:      roots,
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+	})
+	resp, err := client.GetQueue(context.Background())
+	if err != nil {
+		t.Fatalf("GetQueue() error = %v", err)
+	}
+	if resp.Stats.Pending != 1 {
+		t.Errorf("pending = %d, want 1", resp.Stats.Pending)
+	}
+
+	noCert := NewWithTLS(server.URL, "test-key", &tls.Config{RootCAs: roots})
+	if _, err := noCert.GetQueue(context.Background()); err == nil {
+		t.Error("expected error without client certificate")
+	}
+}
+
+func TestParseMetrics(t *testing.T) {
+	text := `# HELP sendry_messages_sent_total Total sent messages
+# TYPE sendry_messages_sent_total counter
+sendry_messages_sent_total{domain="example.com"} 10
+sendry_messages_sent_total{domain="other.org",note="a } b"} 5 1700000000000
+sendry_queue_oldest_seconds 42.5
+broken_line
+`
+	metrics, err := parseMetrics(strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("parseMetrics() error = %v", err)
+	}
+	if metrics["sendry_messages_sent_total"] != 15 {
+		t.Errorf("sent = %v, want 15", metrics["sendry_messages_sent_total"])
+	}
+	if metrics["sendry_queue_oldest_seconds"] != 42.5 {
+		t.Errorf("oldest = %v, want 42.5", metrics["sendry_queue_oldest_seconds"])
+	}
+	if _, ok := metrics["broken_line"]; ok {
+		t.Error("line without value should be skipped")
+	}
+}
+
+func TestRateLimitStats_Utilization(t *testing.T) {
+	stats := &RateLimitStats{MinuteCount: 10, MinuteLimit: 100, HourlyCount: 900, HourlyLimit: 1000, DailyCount: 5000}
+	if got := stats.Utilization(); got != 0.9 {
+		t.Errorf("Utilization() = %v, want 0.9", got)
+	}
+	if got := (&RateLimitStats{MinuteCount: 10}).Utilization(); got != 0 {
+		t.Errorf("Utilization() without limits = %v, want 0", got)
+	}
+}
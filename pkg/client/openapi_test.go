@@ -0,0 +1,139 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// documentedOperations returns the operations of openapi.json as path
+// patterns by method
+func documentedOperations(t *testing.T) map[string][]*regexp.Regexp {
+	t.Helper()
+	data, err := os.ReadFile("openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Paths map[string]map[string]any `json:"paths"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	ops := make(map[string][]*regexp.Regexp)
+	param := regexp.MustCompile(`\\\{[^}]+\\\}`)
+	for path, methods := range doc.Paths {
+		pattern := regexp.MustCompile("^" + param.ReplaceAllString(regexp.QuoteMeta(path), "[^/]+") + "$")
+		for method := range methods {
+			ops[strings.ToUpper(method)] = append(ops[strings.ToUpper(method)], pattern)
+		}
+	}
+	return ops
+}
+
+func TestClient_MatchesOpenAPI(t *testing.T) {
+	ops := documentedOperations(t)
+
+	var mu sync.Mutex
+	var requests []string
+	client := setupTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.Method+" "+r.URL.EscapedPath())
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("{}"))
+	})
+
+	ctx := context.Background()
+	client.Health(ctx)
+	client.Send(ctx, &SendRequest{})
+	client.SendBatch(ctx, &BatchSendRequest{})
+	client.SendRaw(ctx, []byte("Subject: test\r\n\r\nbody"), &RawOptions{From: "a@example.com"})
+	client.SendWithTemplate(ctx, &SendTemplateRequest{})
+	client.GetStatus(ctx, "msg1")
+	client.ListQueue(ctx, QueueFilter{Status: "deferred", Limit: 10})
+	client.GetMessage(ctx, "msg1")
+	client.DeleteFromQueue(ctx, "msg1")
+	client.RetryMessage(ctx, "msg1")
+	client.GetPauseStatus(ctx)
+	client.Pause(ctx, "example.com")
+	client.Resume(ctx, "")
+	client.GetDLQ(ctx)
+	client.GetDLQMessage(ctx, "msg1")
+	client.RetryDLQ(ctx, "msg1")
+	client.DeleteFromDLQ(ctx, "msg1")
+	client.ListDomains(ctx)
+	client.GetDomain(ctx, "example.com")
+	client.CreateDomain(ctx, &DomainCreateRequest{})
+	client.UpdateDomain(ctx, "example.com", &DomainUpdateRequest{})
+	client.DeleteDomain(ctx, "example.com")
+	client.ListShapingSchedules(ctx)
+	client.GetShapingSchedule(ctx, "example.com")
+	client.PutShapingSchedule(ctx, "example.com", &ShapingScheduleRequest{})
+	client.DeleteShapingSchedule(ctx, "example.com")
+	client.ListAPIKeys(ctx)
+	client.CreateAPIKey(ctx, &APIKeyCreateRequest{})
+	client.GetAPIKey(ctx, "key1")
+	client.RevokeAPIKey(ctx, "key1")
+	client.ListReputation(ctx)
+	client.GetReputation(ctx, "example.com")
+	client.ListComplaints(ctx, "spring", 10)
+	client.ListSuppressions(ctx, 10, 0)
+	client.AddSuppression(ctx, &SuppressionRequest{Email: "a@example.com"})
+	client.GetSuppression(ctx, "a@example.com")
+	client.RemoveSuppression(ctx, "a@example.com")
+	client.ListAuditLog(ctx, AuditLogFilter{Actor: "admin"})
+	client.ListTemplates(ctx, "welcome", 10, 0)
+	client.GetTemplate(ctx, "tpl1")
+	client.CreateTemplate(ctx, &TemplateCreateRequest{})
+	client.UpdateTemplate(ctx, "tpl1", &TemplateUpdateRequest{})
+	client.DeleteTemplate(ctx, "tpl1")
+	client.PreviewTemplate(ctx, "tpl1", nil)
+	client.ListSandboxMessages(ctx, SandboxFilter{Domain: "example.com"})
+	client.GetSandboxMessage(ctx, "sb1")
+	client.GetSandboxRaw(ctx, "sb1")
+	client.DeleteSandboxMessage(ctx, "sb1")
+	client.ReleaseSandboxMessage(ctx, "sb1")
+	client.ReleaseSandbox(ctx, &SandboxReleaseRequest{})
+	client.ClearSandbox(ctx, "example.com", "24h")
+	client.GetSandboxStats(ctx)
+	client.GenerateDKIM(ctx, "example.com", "sel")
+	client.UploadDKIM(ctx, "example.com", "sel", "key")
+	client.GetDKIM(ctx, "example.com")
+	client.VerifyDKIM(ctx, "example.com")
+	client.DeleteDKIM(ctx, "example.com", "sel")
+	client.CheckDNS(ctx, "example.com", "sel")
+	client.GetDNSHistory(ctx, "example.com", 24)
+	client.CheckIP(ctx, "192.0.2.1")
+	client.ListDNSBLs(ctx)
+	client.ListMonitoredIPs(ctx)
+	client.AddMonitoredIP(ctx, "192.0.2.1", "")
+	client.RemoveMonitoredIP(ctx, "192.0.2.1")
+	client.CheckMonitoredIP(ctx, "192.0.2.1")
+	client.GetRateLimitStats(ctx, "domain", "example.com")
+	client.ListTLSCertificates(ctx)
+	client.RenewTLSCertificate(ctx, "example.com")
+
+	if len(requests) == 0 {
+		t.Fatal("no requests made")
+	}
+	for _, req := range requests {
+		method, path, _ := strings.Cut(req, " ")
+		documented := false
+		for _, pattern := range ops[method] {
+			if pattern.MatchString(path) {
+				documented = true
+				break
+			}
+		}
+		if !documented {
+			t.Errorf("%s is not an operation of openapi.json", req)
+		}
+	}
+}
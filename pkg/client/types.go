@@ -0,0 +1,907 @@
+package client
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ErrorResponse represents an API error
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// HealthResponse represents health check response
+type HealthResponse struct {
+	Status  string      `json:"status"`
+	Version string      `json:"version"`
+	Uptime  string      `json:"uptime"`
+	Queue   *QueueStats `json:"queue,omitempty"`
+}
+
+// QueueStats represents queue statistics
+type QueueStats struct {
+	Pending   int `json:"pending"`
+	Sending   int `json:"sending"`
+	Delivered int `json:"delivered"`
+	Failed    int `json:"failed"`
+	Deferred  int `json:"deferred"`
+	Total     int `json:"total"`
+}
+
+// SendRequest represents email send request
+type SendRequest struct {
+	From        string            `json:"from"`
+	To          []string          `json:"to"`
+	CC          []string          `json:"cc,omitempty"`
+	BCC         []string          `json:"bcc,omitempty"`
+	Subject     string            `json:"subject"`
+	Body        string            `json:"body,omitempty"`
+	HTML        string            `json:"html,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Attachments []Attachment      `json:"attachments,omitempty"`
+	SendAt      *time.Time        `json:"send_at,omitempty"`   // Hold until this time
+	Priority    string            `json:"priority,omitempty"`  // high, normal or low
+	SkipDKIM    bool              `json:"skip_dkim,omitempty"` // Deliver without a DKIM signature
+}
+
+// Attachment represents a file attached to a message
+type Attachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type,omitempty"` // Detected from the filename if empty
+	Content     string `json:"content"`                // Base64 encoded
+}
+
+// SendResponse represents send response
+type SendResponse struct {
+	ID     string     `json:"id"`
+	Status string     `json:"status"`
+	SendAt *time.Time `json:"send_at,omitempty"`
+}
+
+// BatchSendRequest represents a batch of messages to send
+type BatchSendRequest struct {
+	Messages []SendRequest `json:"messages"`
+}
+
+// BatchSendResult represents the outcome of one message of a batch
+type BatchSendResult struct {
+	Index  int    `json:"index"`
+	ID     string `json:"id,omitempty"`
+	Status string `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// BatchSendResponse represents batch send response
+type BatchSendResponse struct {
+	Accepted int               `json:"accepted"`
+	Rejected int               `json:"rejected"`
+	Results  []BatchSendResult `json:"results"`
+}
+
+// RawOptions contains the envelope of a raw message. Empty fields are
+// taken from the message headers.
+type RawOptions struct {
+	From     string
+	To       []string
+	SendAt   *time.Time
+	Priority string
+	SkipDKIM bool
+}
+
+// SendTemplateRequest represents template send request
+type SendTemplateRequest struct {
+	TemplateID   string            `json:"template_id,omitempty"`
+	TemplateName string            `json:"template_name,omitempty"`
+	From         string            `json:"from"`
+	To           []string          `json:"to"`
+	CC           []string          `json:"cc,omitempty"`
+	BCC          []string          `json:"bcc,omitempty"`
+	Data         map[string]any    `json:"data,omitempty"`
+	Headers      map[string]string `json:"headers,omitempty"`
+	Attachments  []Attachment      `json:"attachments,omitempty"`
+	SendAt       *time.Time        `json:"send_at,omitempty"`
+	Priority     string            `json:"priority,omitempty"`
+	SkipDKIM     bool              `json:"skip_dkim,omitempty"`
+
+	// Recipients replaces to/cc/bcc: one message is rendered and queued
+	// per recipient, with its data merged over the shared data
+	Recipients []TemplateRecipient `json:"recipients,omitempty"`
+}
+
+// TemplateRecipient represents a recipient of a personalized template send
+type TemplateRecipient struct {
+	To   string         `json:"to"`
+	Data map[string]any `json:"data,omitempty"` // Overrides of the shared data
+}
+
+// StatusResponse represents message status
+type StatusResponse struct {
+	ID         string     `json:"id"`
+	Status     string     `json:"status"`
+	From       string     `json:"from"`
+	To         []string   `json:"to"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	RetryCount int        `json:"retry_count"`
+	LastError  string     `json:"last_error,omitempty"`
+	SendAt     *time.Time `json:"send_at,omitempty"`
+	Priority   string     `json:"priority,omitempty"`
+	TraceID    string     `json:"trace_id,omitempty"` // OpenTelemetry trace of the message
+
+	Recipients map[string]*RecipientResult `json:"recipients,omitempty"` // Per-recipient delivery outcome
+	Attempts   []DeliveryAttempt           `json:"attempts,omitempty"`
+}
+
+// RecipientResult is the delivery outcome of one recipient of a message
+type RecipientResult struct {
+	Status    string    `json:"status"` // delivered, failed or deferred
+	MXHost    string    `json:"mx_host,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Expired   bool      `json:"expired,omitempty"` // Failed because the delivery budget ran out
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// DeliveryAttempt is a delivery attempt of one recipient at one MX host
+type DeliveryAttempt struct {
+	Timestamp time.Time `json:"timestamp"`
+	Recipient string    `json:"recipient,omitempty"`
+	MXHost    string    `json:"mx_host"`
+	Success   bool      `json:"success"`
+	Permanent bool      `json:"permanent,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Response  string    `json:"response,omitempty"`
+}
+
+// MessageResponse represents a queued message
+type MessageResponse struct {
+	StatusResponse
+	Size int          `json:"size"` // Message size in bytes
+	DKIM *MessageDKIM `json:"dkim"`
+}
+
+// MessageDKIM represents the DKIM signature of a queued message
+type MessageDKIM struct {
+	Status    string `json:"status"`
+	Domain    string `json:"domain,omitempty"`
+	Selector  string `json:"selector,omitempty"`
+	Signature string `json:"signature,omitempty"` // DKIM-Signature header value
+	Preview   bool   `json:"preview,omitempty"`   // Computed now, delivery signs again
+}
+
+// QueueResponse represents queue response
+type QueueResponse struct {
+	Stats      *QueueStats       `json:"stats"`
+	Messages   []*MessageSummary `json:"messages"`
+	NextCursor string            `json:"next_cursor,omitempty"` // Set when more messages may follow
+}
+
+// QueueFilter selects queued messages. All set fields must match.
+type QueueFilter struct {
+	Status          string
+	Sender          string
+	Recipient       string
+	SenderDomain    string
+	RecipientDomain string
+	Domain          string // Sender or recipient domain
+	IDPrefix        string
+	Since           string // RFC 3339 time or date
+	Until           string // RFC 3339 time or date
+	Cursor          string // NextCursor of the previous page
+	Limit           int
+}
+
+// PauseStatus represents paused delivery
+type PauseStatus struct {
+	Global        bool     `json:"global"`
+	Domains       []string `json:"domains"`
+	ConfigDomains []string `json:"config_domains"`
+}
+
+// MessageSummary represents a message summary
+type MessageSummary struct {
+	ID         string     `json:"id"`
+	From       string     `json:"from"`
+	To         []string   `json:"to"`
+	Subject    string     `json:"subject,omitempty"`
+	Status     string     `json:"status"`
+	Priority   string     `json:"priority,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	SendAt     *time.Time `json:"send_at,omitempty"`
+	RetryCount int        `json:"retry_count"`
+}
+
+// DLQResponse represents DLQ response
+type DLQResponse struct {
+	Stats    *DLQStats         `json:"stats"`
+	Messages []*MessageSummary `json:"messages"`
+}
+
+// DLQStats represents DLQ statistics
+type DLQStats struct {
+	Total    int            `json:"total"`
+	ByReason map[string]int `json:"by_reason,omitempty"`
+}
+
+// DomainsListResponse represents domains list
+type DomainsListResponse struct {
+	Domains []*Domain `json:"domains"`
+}
+
+// Domain represents a domain configuration
+type Domain struct {
+	Domain      string        `json:"domain"`
+	DKIM        *DKIMConfig   `json:"dkim,omitempty"`
+	TLS         *TLSConfig    `json:"tls,omitempty"`
+	RateLimit   *RateLimitCfg `json:"rate_limit,omitempty"`
+	Mode        string        `json:"mode"`
+	DefaultFrom string        `json:"default_from,omitempty"`
+	RedirectTo  []string      `json:"redirect_to,omitempty"`
+	BCCTo       []string      `json:"bcc_to,omitempty"`
+}
+
+// ShapingSchedule represents a weekly send curve for a sender domain.
+// Hours has 7 rows (Sunday first) of 24 hourly limits, -1 = unlimited, 0 = paused.
+type ShapingSchedule struct {
+	Domain       string    `json:"domain"`
+	Timezone     string    `json:"timezone,omitempty"`
+	Hours        [][]int   `json:"hours"`
+	Enabled      bool      `json:"enabled"`
+	CurrentLimit int       `json:"current_limit,omitempty"`
+	SentThisHour int       `json:"sent_this_hour,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// ShapingScheduleListResponse represents send schedules list response
+type ShapingScheduleListResponse struct {
+	Schedules []ShapingSchedule `json:"schedules"`
+	Total     int               `json:"total"`
+}
+
+// ShapingScheduleRequest represents send schedule create/replace request
+type ShapingScheduleRequest struct {
+	Timezone string  `json:"timezone,omitempty"`
+	Hours    [][]int `json:"hours"`
+	Enabled  *bool   `json:"enabled,omitempty"`
+}
+
+// APIKey represents a key of the Sendry API key store
+type APIKey struct {
+	ID         string       `json:"id"`
+	Name       string       `json:"name"`
+	Prefix     string       `json:"prefix"`
+	Scopes     []string     `json:"scopes"`
+	RateLimit  *APIKeyLimit `json:"rate_limit,omitempty"`
+	Senders    []string     `json:"senders,omitempty"`
+	CreatedAt  time.Time    `json:"created_at"`
+	LastUsedAt *time.Time   `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time   `json:"revoked_at,omitempty"`
+}
+
+// APIKeyLimit represents the send request limits of an API key
+type APIKeyLimit struct {
+	MessagesPerMinute int `json:"messages_per_minute"`
+	MessagesPerHour   int `json:"messages_per_hour"`
+	MessagesPerDay    int `json:"messages_per_day"`
+}
+
+// APIKeyListResponse represents API keys list response
+type APIKeyListResponse struct {
+	Keys  []APIKey `json:"keys"`
+	Total int      `json:"total"`
+}
+
+// APIKeyCreateRequest represents API key create request
+type APIKeyCreateRequest struct {
+	Name      string       `json:"name"`
+	Scopes    []string     `json:"scopes"`
+	RateLimit *APIKeyLimit `json:"rate_limit,omitempty"`
+	Senders   []string     `json:"senders,omitempty"` // Domains, *.domains and addresses the key may send as
+}
+
+// APIKeyCreateResponse represents a new API key with its token, shown only once
+type APIKeyCreateResponse struct {
+	APIKey
+	Token string `json:"token"`
+}
+
+// SMTPUser represents a user of the SMTP credential store
+type SMTPUser struct {
+	Username          string    `json:"username"`
+	Senders           []string  `json:"senders,omitempty"`
+	Disabled          bool      `json:"disabled"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+	PasswordChangedAt time.Time `json:"password_changed_at"`
+}
+
+// SMTPUserListResponse represents SMTP users list response
+type SMTPUserListResponse struct {
+	Users []SMTPUser `json:"users"`
+	Total int        `json:"total"`
+}
+
+// SMTPUserCreateRequest represents SMTP user create request
+type SMTPUserCreateRequest struct {
+	Username string   `json:"username"`
+	Password string   `json:"password"`
+	Senders  []string `json:"senders,omitempty"`
+}
+
+// SMTPUserUpdateRequest represents SMTP user update request, nil fields are
+// kept
+type SMTPUserUpdateRequest struct {
+	Password *string   `json:"password,omitempty"`
+	Disabled *bool     `json:"disabled,omitempty"`
+	Senders  *[]string `json:"senders,omitempty"`
+}
+
+// ReputationScore represents the sending reputation of a sender domain
+type ReputationScore struct {
+	Domain    string            `json:"domain"`
+	Score     int               `json:"score"`
+	Grade     string            `json:"grade"` // good, fair or poor
+	Trend     string            `json:"trend"` // up, down or steady
+	Change    int               `json:"change"`
+	Counts    ReputationCounts  `json:"counts"`
+	Rates     ReputationRates   `json:"rates"`
+	Checks    ReputationChecks  `json:"checks"`
+	Penalties map[string]int    `json:"penalties,omitempty"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	History   []ReputationPoint `json:"history,omitempty"`
+}
+
+// BadgeClass returns the badge style of the grade
+func (s *ReputationScore) BadgeClass() string {
+	switch s.Grade {
+	case "good":
+		return "success"
+	case "fair":
+		return "warning"
+	}
+	return "danger"
+}
+
+// TrendArrow returns an arrow for the trend
+func (s *ReputationScore) TrendArrow() string {
+	switch s.Trend {
+	case "up":
+		return "↑"
+	case "down":
+		return "↓"
+	}
+	return "→"
+}
+
+// ReputationCounts represents per-recipient delivery outcomes and feedback
+type ReputationCounts struct {
+	Delivered    int `json:"delivered"`
+	Deferred     int `json:"deferred"`
+	Failed       int `json:"failed"`
+	Complaints   int `json:"complaints"`
+	Unsubscribes int `json:"unsubscribes"`
+}
+
+// ReputationRates represents the signal rates of a score
+type ReputationRates struct {
+	Bounce        float64 `json:"bounce"`
+	Complaint     float64 `json:"complaint"`
+	Unsubscribe   float64 `json:"unsubscribe"`
+	Deferral      float64 `json:"deferral"`
+	WorstProvider string  `json:"worst_provider,omitempty"`
+}
+
+// ReputationChecks represents the DNS-based signals of a score
+type ReputationChecks struct {
+	DMARC       string   `json:"dmarc"`
+	DKIMAligned bool     `json:"dkim_aligned"`
+	Listings    []string `json:"listings,omitempty"`
+}
+
+// ReputationPoint represents a score in the history of a domain
+type ReputationPoint struct {
+	Time  time.Time `json:"time"`
+	Score int       `json:"score"`
+}
+
+// Complaint represents a spam complaint received through a feedback loop
+type Complaint struct {
+	ID                uint64    `json:"id"`
+	FeedbackType      string    `json:"feedback_type"`
+	Recipient         string    `json:"recipient,omitempty"`
+	SenderDomain      string    `json:"sender_domain,omitempty"`
+	MessageID         string    `json:"message_id,omitempty"`
+	OriginalMessageID string    `json:"original_message_id,omitempty"`
+	CampaignID        string    `json:"campaign_id,omitempty"`
+	Attribution       string    `json:"attribution,omitempty"`
+	ReportingMTA      string    `json:"reporting_mta,omitempty"`
+	Source            string    `json:"source"`
+	Suppressed        bool      `json:"suppressed"`
+	ReceivedAt        time.Time `json:"received_at"`
+}
+
+// ComplaintListResponse represents complaint list response
+type ComplaintListResponse struct {
+	Complaints []Complaint `json:"complaints"`
+	Total      int         `json:"total"`
+}
+
+// Suppression represents a recipient on the suppression list
+type Suppression struct {
+	Email     string    `json:"email"`
+	Reason    string    `json:"reason"`
+	Detail    string    `json:"detail,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SuppressionRequest represents a recipient to suppress
+type SuppressionRequest struct {
+	Email  string `json:"email"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// SuppressionListResponse represents suppression list response
+type SuppressionListResponse struct {
+	Suppressions []Suppression `json:"suppressions"`
+	Total        int           `json:"total"`
+}
+
+// ReputationListResponse represents reputation scores list response
+type ReputationListResponse struct {
+	Domains []ReputationScore `json:"domains"`
+	Total   int               `json:"total"`
+}
+
+// DKIMConfig represents DKIM configuration
+type DKIMConfig struct {
+	Enabled  bool   `json:"Enabled"`
+	Selector string `json:"Selector"`
+	KeyFile  string `json:"KeyFile,omitempty"`
+}
+
+// TLSConfig represents TLS configuration
+type TLSConfig struct {
+	Required bool `json:"required"`
+}
+
+// RateLimitCfg represents rate limit configuration
+type RateLimitCfg struct {
+	MessagesPerHour      int `json:"messages_per_hour"`
+	MessagesPerDay       int `json:"messages_per_day"`
+	RecipientsPerMessage int `json:"recipients_per_message"`
+}
+
+// TemplateListResponse represents template list
+type TemplateListResponse struct {
+	Templates []*Template `json:"templates"`
+	Total     int         `json:"total"`
+}
+
+// Template represents a template
+type Template struct {
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	Subject     string     `json:"subject"`
+	HTML        string     `json:"html,omitempty"`
+	Text        string     `json:"text,omitempty"`
+	Variables   []Variable `json:"variables,omitempty"`
+	Version     int        `json:"version"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// Variable represents a template variable
+type Variable struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Default     string `json:"default,omitempty"`
+	Required    bool   `json:"required"`
+}
+
+// TemplateResponse is alias for Template
+type TemplateResponse = Template
+
+// TemplateCreateRequest represents template create request
+type TemplateCreateRequest struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	Subject     string     `json:"subject"`
+	HTML        string     `json:"html,omitempty"`
+	Text        string     `json:"text,omitempty"`
+	Variables   []Variable `json:"variables,omitempty"`
+}
+
+// TemplateUpdateRequest represents template update request
+type TemplateUpdateRequest = TemplateCreateRequest
+
+// TemplatePreviewRequest represents template preview request
+type TemplatePreviewRequest struct {
+	Data map[string]any `json:"data"`
+}
+
+// TemplatePreviewResponse represents template preview response
+type TemplatePreviewResponse struct {
+	Subject string `json:"subject"`
+	HTML    string `json:"html"`
+	Text    string `json:"text"`
+}
+
+// SandboxListResponse represents sandbox messages list
+type SandboxListResponse struct {
+	Messages []*SandboxMessage `json:"messages"`
+	Total    int               `json:"total"`
+}
+
+// SandboxMessage represents a sandbox message
+type SandboxMessage struct {
+	ID             string    `json:"id"`
+	From           string    `json:"from"`
+	To             []string  `json:"to"`
+	OriginalTo     []string  `json:"original_to,omitempty"`
+	Subject        string    `json:"subject"`
+	Domain         string    `json:"domain"`
+	Mode           string    `json:"mode"`
+	CapturedAt     time.Time `json:"captured_at"`
+	ClientIP       string    `json:"client_ip,omitempty"`
+	SimulatedError string    `json:"simulated_error,omitempty"`
+}
+
+// SandboxFilter contains filters for listing sandbox messages
+type SandboxFilter struct {
+	Domain string
+	Mode   string
+	Search string // Text in the sender, recipients or subject
+	Limit  int
+	Offset int
+}
+
+// SandboxMessageDetailResponse represents sandbox message details
+type SandboxMessageDetailResponse struct {
+	ID             string              `json:"id"`
+	From           string              `json:"from"`
+	To             []string            `json:"to"`
+	OriginalTo     []string            `json:"original_to,omitempty"`
+	Subject        string              `json:"subject"`
+	Domain         string              `json:"domain"`
+	Mode           string              `json:"mode"`
+	ClientIP       string              `json:"client_ip,omitempty"`
+	SimulatedError string              `json:"simulated_error,omitempty"`
+	Headers        map[string]string   `json:"headers"`
+	HeaderList     []SandboxHeader     `json:"header_list"`
+	Body           string              `json:"body"`
+	HTML           string              `json:"html,omitempty"` // Sanitized by the server
+	Attachments    []SandboxAttachment `json:"attachments"`
+	DKIMSigned     bool                `json:"dkim_signed"`
+	DKIM           []SandboxDKIMResult `json:"dkim"` // nil if the server does not verify DKIM
+	Size           int64               `json:"size"`
+	CapturedAt     time.Time           `json:"captured_at"`
+}
+
+// SandboxHeader represents a header field of a sandbox message
+type SandboxHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// SandboxAttachment represents an attachment of a sandbox message
+type SandboxAttachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+}
+
+// SandboxDKIMResult represents the verification of one DKIM signature
+type SandboxDKIMResult struct {
+	Domain string `json:"domain"`
+	Result string `json:"result"`
+	Reason string `json:"reason"`
+}
+
+// SandboxStatsResponse represents sandbox statistics
+type SandboxStatsResponse struct {
+	Total     int            `json:"total"`
+	ByDomain  map[string]int `json:"by_domain"`
+	ByMode    map[string]int `json:"by_mode"`
+	OldestAt  *time.Time     `json:"oldest_at,omitempty"`
+	NewestAt  *time.Time     `json:"newest_at,omitempty"`
+	TotalSize int64          `json:"total_size"`
+	MaxAge    string         `json:"max_age,omitempty"`
+	MaxCount  int            `json:"max_count,omitempty"`
+}
+
+// SandboxReleaseResult represents a sandbox message queued for delivery
+type SandboxReleaseResult struct {
+	Status    string `json:"status"`
+	MessageID string `json:"message_id"`
+}
+
+// SandboxReleaseRequest represents a bulk release of sandbox messages
+type SandboxReleaseRequest struct {
+	IDs     []string `json:"ids,omitempty"`
+	Domain  string   `json:"domain,omitempty"`
+	Confirm bool     `json:"confirm"`
+}
+
+// SandboxReleaseResponse represents the result of a bulk release
+type SandboxReleaseResponse struct {
+	Released []struct {
+		ID        string `json:"id"`
+		MessageID string `json:"message_id"`
+	} `json:"released"`
+	Skipped []struct {
+		ID    string `json:"id"`
+		Error string `json:"error"`
+	} `json:"skipped"`
+}
+
+// SandboxClearResponse represents the result of clearing sandbox messages
+type SandboxClearResponse struct {
+	Cleared int `json:"cleared"`
+}
+
+// DomainCreateRequest represents domain create/update request
+type DomainCreateRequest struct {
+	Domain      string        `json:"domain,omitempty"`
+	DKIM        *DKIMConfig   `json:"dkim,omitempty"`
+	TLS         *TLSConfig    `json:"tls,omitempty"`
+	RateLimit   *RateLimitCfg `json:"rate_limit,omitempty"`
+	Mode        string        `json:"mode,omitempty"`
+	DefaultFrom string        `json:"default_from,omitempty"`
+	RedirectTo  []string      `json:"redirect_to,omitempty"`
+	BCCTo       []string      `json:"bcc_to,omitempty"`
+}
+
+// DomainUpdateRequest is alias for DomainCreateRequest
+type DomainUpdateRequest = DomainCreateRequest
+
+// DKIMGenerateRequest represents DKIM generation request
+type DKIMGenerateRequest struct {
+	Domain   string `json:"domain"`
+	Selector string `json:"selector"`
+}
+
+// DKIMUploadRequest represents DKIM upload request
+type DKIMUploadRequest struct {
+	Domain     string `json:"domain"`
+	Selector   string `json:"selector"`
+	PrivateKey string `json:"private_key"`
+}
+
+// DKIMResponse represents DKIM generation/upload response
+type DKIMResponse struct {
+	Domain    string `json:"domain"`
+	Selector  string `json:"selector"`
+	DNSName   string `json:"dns_name"`
+	DNSRecord string `json:"dns_record"`
+	KeyFile   string `json:"key_file"`
+}
+
+// DKIMInfoResponse represents DKIM info response
+type DKIMInfoResponse struct {
+	Domain    string   `json:"domain"`
+	Enabled   bool     `json:"enabled"`
+	Selector  string   `json:"selector,omitempty"`
+	KeyFile   string   `json:"key_file,omitempty"`
+	DNSName   string   `json:"dns_name,omitempty"`
+	DNSRecord string   `json:"dns_record,omitempty"`
+	Selectors []string `json:"selectors,omitempty"`
+}
+
+// DKIMVerifyResponse represents DKIM verification response
+type DKIMVerifyResponse struct {
+	Domain   string `json:"domain"`
+	Selector string `json:"selector"`
+	Valid    bool   `json:"valid"`
+	Error    string `json:"error,omitempty"`
+	DNSName  string `json:"dns_name"`
+}
+
+// DNSCheckResult represents DNS check result for a domain
+type DNSCheckResult struct {
+	Domain  string          `json:"domain"`
+	Results []DNSCheckItem  `json:"results"`
+	Summary DNSCheckSummary `json:"summary"`
+}
+
+// DNSCheckItem represents a single DNS check result
+type DNSCheckItem struct {
+	Type    string `json:"type"`
+	Status  string `json:"status"` // ok, warning, error, not_found
+	Value   string `json:"value,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// DNSCheckSummary represents DNS check summary
+type DNSCheckSummary struct {
+	OK       int `json:"ok"`
+	Warnings int `json:"warnings"`
+	Errors   int `json:"errors"`
+	NotFound int `json:"not_found"`
+}
+
+// DNSCheckRun represents a scheduled DNS check of a domain
+type DNSCheckRun struct {
+	Domain      string          `json:"domain"`
+	CheckedAt   time.Time       `json:"checked_at"`
+	Results     []DNSCheckItem  `json:"results"`
+	Summary     DNSCheckSummary `json:"summary"`
+	Regressions []DNSRegression `json:"regressions,omitempty"`
+}
+
+// DNSRegression represents a DNS record that got worse since the previous check
+type DNSRegression struct {
+	Type    string `json:"type"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Message string `json:"message"`
+}
+
+// DNSHistoryResponse represents the scheduled DNS check results of a domain
+type DNSHistoryResponse struct {
+	Domain  string        `json:"domain"`
+	Latest  *DNSCheckRun  `json:"latest"`
+	History []DNSCheckRun `json:"history"`
+	Total   int           `json:"total"`
+}
+
+// IPCheckResult represents IP DNSBL check result
+type IPCheckResult struct {
+	IP      string         `json:"ip"`
+	Results []DNSBLResult  `json:"results"`
+	Summary IPCheckSummary `json:"summary"`
+}
+
+// DNSBLResult represents a single DNSBL check result
+type DNSBLResult struct {
+	DNSBL       DNSBLInfo `json:"dnsbl"`
+	Listed      bool      `json:"listed"`
+	ReturnCodes []string  `json:"return_codes,omitempty"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// DNSBLInfo represents DNSBL service info
+type DNSBLInfo struct {
+	Name        string `json:"name"`
+	Zone        string `json:"zone"`
+	Description string `json:"description"`
+	DelistURL   string `json:"delist_url,omitempty"`
+}
+
+// IPCheckSummary represents IP check summary
+type IPCheckSummary struct {
+	Clean  int `json:"clean"`
+	Listed int `json:"listed"`
+	Errors int `json:"errors"`
+}
+
+// DNSBLCheckRun represents a scheduled DNSBL check of a monitored IP
+type DNSBLCheckRun struct {
+	IP          string        `json:"ip"`
+	CheckedAt   time.Time     `json:"checked_at"`
+	Listings    []DNSBLResult `json:"listings"`
+	Checked     int           `json:"checked"`
+	Errors      int           `json:"errors"`
+	NewlyListed []string      `json:"newly_listed,omitempty"`
+	Delisted    []string      `json:"delisted,omitempty"`
+}
+
+// MonitoredIP represents an outbound IP checked against DNSBLs
+type MonitoredIP struct {
+	IP        string         `json:"ip"`
+	Note      string         `json:"note,omitempty"`
+	Source    string         `json:"source"`
+	CreatedAt time.Time      `json:"created_at"`
+	Latest    *DNSBLCheckRun `json:"latest,omitempty"`
+}
+
+// MonitoredIPsResponse represents monitored IPs list response
+type MonitoredIPsResponse struct {
+	IPs   []MonitoredIP `json:"ips"`
+	Total int           `json:"total"`
+}
+
+// DNSBLListResponse represents DNSBL list response
+type DNSBLListResponse struct {
+	DNSBLs []DNSBLInfo `json:"dnsbls"`
+	Count  int         `json:"count"`
+}
+
+// AuditEntry represents a management API audit record on a server
+type AuditEntry struct {
+	ID             uint64          `json:"id"`
+	Time           time.Time       `json:"time"`
+	CorrelationID  string          `json:"correlation_id,omitempty"`
+	Method         string          `json:"method"`
+	Path           string          `json:"path"`
+	Status         int             `json:"status"`
+	Error          string          `json:"error,omitempty"`
+	RemoteAddr     string          `json:"remote_addr,omitempty"`
+	APIKeyID       string          `json:"api_key_id,omitempty"`
+	ClientIdentity string          `json:"client_identity,omitempty"`
+	Actor          string          `json:"actor,omitempty"`
+	Old            json.RawMessage `json:"old,omitempty"`
+	New            json.RawMessage `json:"new,omitempty"`
+	Duration       time.Duration   `json:"duration"`
+}
+
+// Failed returns true if the request was rejected or failed
+func (e *AuditEntry) Failed() bool {
+	return e.Status >= 400
+}
+
+// Caller returns who made the request: the web user, the client
+// certificate identity or the API key, in that order
+func (e *AuditEntry) Caller() string {
+	switch {
+	case e.Actor != "":
+		return e.Actor
+	case e.ClientIdentity != "":
+		return e.ClientIdentity
+	case e.APIKeyID != "":
+		return "key " + e.APIKeyID
+	}
+	return ""
+}
+
+// AuditLogFilter selects management API audit entries
+type AuditLogFilter struct {
+	CorrelationID string
+	Actor         string
+	Path          string // Path prefix
+	Limit         int
+}
+
+// AuditLogResponse represents audit log list response
+type AuditLogResponse struct {
+	Entries []AuditEntry `json:"entries"`
+	Total   int          `json:"total"`
+}
+
+// RateLimitStats represents the counters of a rate limit key
+type RateLimitStats struct {
+	Level       string `json:"level"`
+	Key         string `json:"key"`
+	Algorithm   string `json:"algorithm"`
+	MinuteCount int    `json:"minute_count"`
+	HourlyCount int    `json:"hourly_count"`
+	DailyCount  int    `json:"daily_count"`
+	MinuteLimit int    `json:"minute_limit"`
+	HourlyLimit int    `json:"hourly_limit"`
+	DailyLimit  int    `json:"daily_limit"`
+}
+
+// Utilization returns the highest share of a limit used, 0 if no limit is set
+func (s *RateLimitStats) Utilization() float64 {
+	var max float64
+	for _, p := range [][2]int{
+		{s.MinuteCount, s.MinuteLimit},
+		{s.HourlyCount, s.HourlyLimit},
+		{s.DailyCount, s.DailyLimit},
+	} {
+		if p[1] > 0 && float64(p[0])/float64(p[1]) > max {
+			max = float64(p[0]) / float64(p[1])
+		}
+	}
+	return max
+}
+
+// TLSCertificate represents a TLS certificate of a server
+type TLSCertificate struct {
+	Domain    string     `json:"domain"`
+	CertFile  string     `json:"cert_file,omitempty"`
+	ACME      bool       `json:"acme"`
+	Issuer    string     `json:"issuer,omitempty"`
+	DNSNames  []string   `json:"dns_names,omitempty"`
+	NotBefore *time.Time `json:"not_before,omitempty"`
+	NotAfter  *time.Time `json:"not_after,omitempty"`
+	DaysLeft  *int       `json:"days_left,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// TLSCertificateListResponse represents TLS certificate list response
+type TLSCertificateListResponse struct {
+	Certificates []TLSCertificate `json:"certificates"`
+	ACMEEnabled  bool             `json:"acme_enabled"`
+	ACMEDomains  []string         `json:"acme_domains,omitempty"`
+}
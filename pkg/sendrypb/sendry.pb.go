@@ -0,0 +1,2905 @@
+// gRPC API of the Sendry MTA. Requests carry the API key in the
+// "authorization" ("Bearer <key>") or "x-api-key" metadata.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.8
+// 	protoc        (unknown)
+// source: sendry.proto
+
+package sendrypb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Priority of a message
+type Priority int32
+
+const (
+	Priority_PRIORITY_UNSPECIFIED Priority = 0 // normal
+	Priority_PRIORITY_HIGH        Priority = 1
+	Priority_PRIORITY_NORMAL      Priority = 2
+	Priority_PRIORITY_LOW         Priority = 3
+)
+
+// Enum value maps for Priority.
+var (
+	Priority_name = map[int32]string{
+		0: "PRIORITY_UNSPECIFIED",
+		1: "PRIORITY_HIGH",
+		2: "PRIORITY_NORMAL",
+		3: "PRIORITY_LOW",
+	}
+	Priority_value = map[string]int32{
+		"PRIORITY_UNSPECIFIED": 0,
+		"PRIORITY_HIGH":        1,
+		"PRIORITY_NORMAL":      2,
+		"PRIORITY_LOW":         3,
+	}
+)
+
+func (x Priority) Enum() *Priority {
+	p := new(Priority)
+	*p = x
+	return p
+}
+
+func (x Priority) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Priority) Descriptor() protoreflect.EnumDescriptor {
+	return file_sendry_proto_enumTypes[0].Descriptor()
+}
+
+func (Priority) Type() protoreflect.EnumType {
+	return &file_sendry_proto_enumTypes[0]
+}
+
+func (x Priority) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Priority.Descriptor instead.
+func (Priority) EnumDescriptor() ([]byte, []int) {
+	return file_sendry_proto_rawDescGZIP(), []int{0}
+}
+
+// Attachment of a message
+type Attachment struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Filename      string                 `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	ContentType   string                 `protobuf:"bytes,2,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"` // Detected from the filename if empty
+	Content       []byte                 `protobuf:"bytes,3,opt,name=content,proto3" json:"content,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Attachment) Reset() {
+	*x = Attachment{}
+	mi := &file_sendry_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Attachment) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Attachment) ProtoMessage() {}
+
+func (x *Attachment) ProtoReflect() protoreflect.Message {
+	mi := &file_sendry_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Attachment.ProtoReflect.Descriptor instead.
+func (*Attachment) Descriptor() ([]byte, []int) {
+	return file_sendry_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Attachment) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *Attachment) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *Attachment) GetContent() []byte {
+	if x != nil {
+		return x.Content
+	}
+	return nil
+}
+
+type SendRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	From          string                 `protobuf:"bytes,1,opt,name=from,proto3" json:"from,omitempty"`
+	To            []string               `protobuf:"bytes,2,rep,name=to,proto3" json:"to,omitempty"`
+	Cc            []string               `protobuf:"bytes,3,rep,name=cc,proto3" json:"cc,omitempty"`
+	Bcc           []string               `protobuf:"bytes,4,rep,name=bcc,proto3" json:"bcc,omitempty"`
+	Subject       string                 `protobuf:"bytes,5,opt,name=subject,proto3" json:"subject,omitempty"`
+	Body          string                 `protobuf:"bytes,6,opt,name=body,proto3" json:"body,omitempty"`
+	Html          string                 `protobuf:"bytes,7,opt,name=html,proto3" json:"html,omitempty"`
+	Headers       map[string]string      `protobuf:"bytes,8,rep,name=headers,proto3" json:"headers,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Attachments   []*Attachment          `protobuf:"bytes,9,rep,name=attachments,proto3" json:"attachments,omitempty"`
+	SendAt        *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=send_at,json=sendAt,proto3" json:"send_at,omitempty"` // Hold until this time
+	Priority      Priority               `protobuf:"varint,11,opt,name=priority,proto3,enum=sendry.v1.Priority" json:"priority,omitempty"`
+	SkipDkim      bool                   `protobuf:"varint,12,opt,name=skip_dkim,json=skipDkim,proto3" json:"skip_dkim,omitempty"` // Deliver without a DKIM signature, for debugging
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SendRequest) Reset() {
+	*x = SendRequest{}
+	mi := &file_sendry_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SendRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SendRequest) ProtoMessage() {}
+
+func (x *SendRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sendry_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SendRequest.ProtoReflect.Descriptor instead.
+func (*SendRequest) Descriptor() ([]byte, []int) {
+	return file_sendry_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *SendRequest) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *SendRequest) GetTo() []string {
+	if x != nil {
+		return x.To
+	}
+	return nil
+}
+
+func (x *SendRequest) GetCc() []string {
+	if x != nil {
+		return x.Cc
+	}
+	return nil
+}
+
+func (x *SendRequest) GetBcc() []string {
+	if x != nil {
+		return x.Bcc
+	}
+	return nil
+}
+
+func (x *SendRequest) GetSubject() string {
+	if x != nil {
+		return x.Subject
+	}
+	return ""
+}
+
+func (x *SendRequest) GetBody() string {
+	if x != nil {
+		return x.Body
+	}
+	return ""
+}
+
+func (x *SendRequest) GetHtml() string {
+	if x != nil {
+		return x.Html
+	}
+	return ""
+}
+
+func (x *SendRequest) GetHeaders() map[string]string {
+	if x != nil {
+		return x.Headers
+	}
+	return nil
+}
+
+func (x *SendRequest) GetAttachments() []*Attachment {
+	if x != nil {
+		return x.Attachments
+	}
+	return nil
+}
+
+func (x *SendRequest) GetSendAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.SendAt
+	}
+	return nil
+}
+
+func (x *SendRequest) GetPriority() Priority {
+	if x != nil {
+		return x.Priority
+	}
+	return Priority_PRIORITY_UNSPECIFIED
+}
+
+func (x *SendRequest) GetSkipDkim() bool {
+	if x != nil {
+		return x.SkipDkim
+	}
+	return false
+}
+
+type SendRawRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	From          string                 `protobuf:"bytes,1,opt,name=from,proto3" json:"from,omitempty"` // Envelope sender, the From header if empty
+	To            []string               `protobuf:"bytes,2,rep,name=to,proto3" json:"to,omitempty"`     // Envelope recipients, the To, Cc and Bcc headers if empty
+	Data          []byte                 `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"` // RFC 5322 message
+	SendAt        *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=send_at,json=sendAt,proto3" json:"send_at,omitempty"`
+	Priority      Priority               `protobuf:"varint,5,opt,name=priority,proto3,enum=sendry.v1.Priority" json:"priority,omitempty"`
+	SkipDkim      bool                   `protobuf:"varint,6,opt,name=skip_dkim,json=skipDkim,proto3" json:"skip_dkim,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SendRawRequest) Reset() {
+	*x = SendRawRequest{}
+	mi := &file_sendry_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SendRawRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SendRawRequest) ProtoMessage() {}
+
+func (x *SendRawRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sendry_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SendRawRequest.ProtoReflect.Descriptor instead.
+func (*SendRawRequest) Descriptor() ([]byte, []int) {
+	return file_sendry_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *SendRawRequest) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *SendRawRequest) GetTo() []string {
+	if x != nil {
+		return x.To
+	}
+	return nil
+}
+
+func (x *SendRawRequest) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *SendRawRequest) GetSendAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.SendAt
+	}
+	return nil
+}
+
+func (x *SendRawRequest) GetPriority() Priority {
+	if x != nil {
+		return x.Priority
+	}
+	return Priority_PRIORITY_UNSPECIFIED
+}
+
+func (x *SendRawRequest) GetSkipDkim() bool {
+	if x != nil {
+		return x.SkipDkim
+	}
+	return false
+}
+
+type SendResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Status        string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	SendAt        *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=send_at,json=sendAt,proto3" json:"send_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SendResponse) Reset() {
+	*x = SendResponse{}
+	mi := &file_sendry_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SendResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SendResponse) ProtoMessage() {}
+
+func (x *SendResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_sendry_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SendResponse.ProtoReflect.Descriptor instead.
+func (*SendResponse) Descriptor() ([]byte, []int) {
+	return file_sendry_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *SendResponse) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *SendResponse) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *SendResponse) GetSendAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.SendAt
+	}
+	return nil
+}
+
+type SendStreamResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Index         int64                  `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`  // Position of the request in the stream, from 0
+	Result        *SendResponse          `protobuf:"bytes,2,opt,name=result,proto3" json:"result,omitempty"` // Set when the message was queued
+	Code          int32                  `protobuf:"varint,3,opt,name=code,proto3" json:"code,omitempty"`    // google.rpc.Code of a refused message
+	Error         string                 `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SendStreamResponse) Reset() {
+	*x = SendStreamResponse{}
+	mi := &file_sendry_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SendStreamResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SendStreamResponse) ProtoMessage() {}
+
+func (x *SendStreamResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_sendry_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SendStreamResponse.ProtoReflect.Descriptor instead.
+func (*SendStreamResponse) Descriptor() ([]byte, []int) {
+	return file_sendry_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *SendStreamResponse) GetIndex() int64 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *SendStreamResponse) GetResult() *SendResponse {
+	if x != nil {
+		return x.Result
+	}
+	return nil
+}
+
+func (x *SendStreamResponse) GetCode() int32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *SendStreamResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type GetStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetStatusRequest) Reset() {
+	*x = GetStatusRequest{}
+	mi := &file_sendry_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStatusRequest) ProtoMessage() {}
+
+func (x *GetStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sendry_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStatusRequest.ProtoReflect.Descriptor instead.
+func (*GetStatusRequest) Descriptor() ([]byte, []int) {
+	return file_sendry_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GetStatusRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+// Delivery outcome of a recipient
+type RecipientResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Status        string                 `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"` // delivered, failed or deferred
+	MxHost        string                 `protobuf:"bytes,2,opt,name=mx_host,json=mxHost,proto3" json:"mx_host,omitempty"`
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	Expired       bool                   `protobuf:"varint,4,opt,name=expired,proto3" json:"expired,omitempty"`       // Failed because the delivery budget ran out
+	Bounced       bool                   `protobuf:"varint,5,opt,name=bounced,proto3" json:"bounced,omitempty"`       // Failed by a bounce received after delivery
+	Greylisted    bool                   `protobuf:"varint,6,opt,name=greylisted,proto3" json:"greylisted,omitempty"` // Deferred by greylisting
+	UpdatedAt     *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RecipientResult) Reset() {
+	*x = RecipientResult{}
+	mi := &file_sendry_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RecipientResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RecipientResult) ProtoMessage() {}
+
+func (x *RecipientResult) ProtoReflect() protoreflect.Message {
+	mi := &file_sendry_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RecipientResult.ProtoReflect.Descriptor instead.
+func (*RecipientResult) Descriptor() ([]byte, []int) {
+	return file_sendry_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *RecipientResult) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *RecipientResult) GetMxHost() string {
+	if x != nil {
+		return x.MxHost
+	}
+	return ""
+}
+
+func (x *RecipientResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *RecipientResult) GetExpired() bool {
+	if x != nil {
+		return x.Expired
+	}
+	return false
+}
+
+func (x *RecipientResult) GetBounced() bool {
+	if x != nil {
+		return x.Bounced
+	}
+	return false
+}
+
+func (x *RecipientResult) GetGreylisted() bool {
+	if x != nil {
+		return x.Greylisted
+	}
+	return false
+}
+
+func (x *RecipientResult) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+// Delivery attempt of a message
+type DeliveryAttempt struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Timestamp     *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Recipient     string                 `protobuf:"bytes,2,opt,name=recipient,proto3" json:"recipient,omitempty"`
+	MxHost        string                 `protobuf:"bytes,3,opt,name=mx_host,json=mxHost,proto3" json:"mx_host,omitempty"`
+	Success       bool                   `protobuf:"varint,4,opt,name=success,proto3" json:"success,omitempty"`
+	Permanent     bool                   `protobuf:"varint,5,opt,name=permanent,proto3" json:"permanent,omitempty"`
+	Error         string                 `protobuf:"bytes,6,opt,name=error,proto3" json:"error,omitempty"`
+	Response      string                 `protobuf:"bytes,7,opt,name=response,proto3" json:"response,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeliveryAttempt) Reset() {
+	*x = DeliveryAttempt{}
+	mi := &file_sendry_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeliveryAttempt) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeliveryAttempt) ProtoMessage() {}
+
+func (x *DeliveryAttempt) ProtoReflect() protoreflect.Message {
+	mi := &file_sendry_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeliveryAttempt.ProtoReflect.Descriptor instead.
+func (*DeliveryAttempt) Descriptor() ([]byte, []int) {
+	return file_sendry_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *DeliveryAttempt) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+func (x *DeliveryAttempt) GetRecipient() string {
+	if x != nil {
+		return x.Recipient
+	}
+	return ""
+}
+
+func (x *DeliveryAttempt) GetMxHost() string {
+	if x != nil {
+		return x.MxHost
+	}
+	return ""
+}
+
+func (x *DeliveryAttempt) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *DeliveryAttempt) GetPermanent() bool {
+	if x != nil {
+		return x.Permanent
+	}
+	return false
+}
+
+func (x *DeliveryAttempt) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *DeliveryAttempt) GetResponse() string {
+	if x != nil {
+		return x.Response
+	}
+	return ""
+}
+
+type MessageStatus struct {
+	state         protoimpl.MessageState      `protogen:"open.v1"`
+	Id            string                      `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Status        string                      `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	From          string                      `protobuf:"bytes,3,opt,name=from,proto3" json:"from,omitempty"`
+	To            []string                    `protobuf:"bytes,4,rep,name=to,proto3" json:"to,omitempty"`
+	CreatedAt     *timestamppb.Timestamp      `protobuf:"bytes,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt     *timestamppb.Timestamp      `protobuf:"bytes,6,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	RetryCount    int32                       `protobuf:"varint,7,opt,name=retry_count,json=retryCount,proto3" json:"retry_count,omitempty"`
+	LastError     string                      `protobuf:"bytes,8,opt,name=last_error,json=lastError,proto3" json:"last_error,omitempty"`
+	SendAt        *timestamppb.Timestamp      `protobuf:"bytes,9,opt,name=send_at,json=sendAt,proto3" json:"send_at,omitempty"`
+	Priority      string                      `protobuf:"bytes,10,opt,name=priority,proto3" json:"priority,omitempty"`
+	TraceId       string                      `protobuf:"bytes,11,opt,name=trace_id,json=traceId,proto3" json:"trace_id,omitempty"` // OpenTelemetry trace of the message
+	Recipients    map[string]*RecipientResult `protobuf:"bytes,12,rep,name=recipients,proto3" json:"recipients,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Attempts      []*DeliveryAttempt          `protobuf:"bytes,13,rep,name=attempts,proto3" json:"attempts,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MessageStatus) Reset() {
+	*x = MessageStatus{}
+	mi := &file_sendry_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MessageStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MessageStatus) ProtoMessage() {}
+
+func (x *MessageStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_sendry_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MessageStatus.ProtoReflect.Descriptor instead.
+func (*MessageStatus) Descriptor() ([]byte, []int) {
+	return file_sendry_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *MessageStatus) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *MessageStatus) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *MessageStatus) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *MessageStatus) GetTo() []string {
+	if x != nil {
+		return x.To
+	}
+	return nil
+}
+
+func (x *MessageStatus) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *MessageStatus) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+func (x *MessageStatus) GetRetryCount() int32 {
+	if x != nil {
+		return x.RetryCount
+	}
+	return 0
+}
+
+func (x *MessageStatus) GetLastError() string {
+	if x != nil {
+		return x.LastError
+	}
+	return ""
+}
+
+func (x *MessageStatus) GetSendAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.SendAt
+	}
+	return nil
+}
+
+func (x *MessageStatus) GetPriority() string {
+	if x != nil {
+		return x.Priority
+	}
+	return ""
+}
+
+func (x *MessageStatus) GetTraceId() string {
+	if x != nil {
+		return x.TraceId
+	}
+	return ""
+}
+
+func (x *MessageStatus) GetRecipients() map[string]*RecipientResult {
+	if x != nil {
+		return x.Recipients
+	}
+	return nil
+}
+
+func (x *MessageStatus) GetAttempts() []*DeliveryAttempt {
+	if x != nil {
+		return x.Attempts
+	}
+	return nil
+}
+
+type MessageSummary struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	From          string                 `protobuf:"bytes,2,opt,name=from,proto3" json:"from,omitempty"`
+	To            []string               `protobuf:"bytes,3,rep,name=to,proto3" json:"to,omitempty"`
+	Status        string                 `protobuf:"bytes,4,opt,name=status,proto3" json:"status,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	SendAt        *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=send_at,json=sendAt,proto3" json:"send_at,omitempty"`
+	Priority      string                 `protobuf:"bytes,7,opt,name=priority,proto3" json:"priority,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MessageSummary) Reset() {
+	*x = MessageSummary{}
+	mi := &file_sendry_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MessageSummary) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MessageSummary) ProtoMessage() {}
+
+func (x *MessageSummary) ProtoReflect() protoreflect.Message {
+	mi := &file_sendry_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MessageSummary.ProtoReflect.Descriptor instead.
+func (*MessageSummary) Descriptor() ([]byte, []int) {
+	return file_sendry_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *MessageSummary) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *MessageSummary) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *MessageSummary) GetTo() []string {
+	if x != nil {
+		return x.To
+	}
+	return nil
+}
+
+func (x *MessageSummary) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *MessageSummary) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *MessageSummary) GetSendAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.SendAt
+	}
+	return nil
+}
+
+func (x *MessageSummary) GetPriority() string {
+	if x != nil {
+		return x.Priority
+	}
+	return ""
+}
+
+type QueueStats struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Pending       int64                  `protobuf:"varint,1,opt,name=pending,proto3" json:"pending,omitempty"`
+	Sending       int64                  `protobuf:"varint,2,opt,name=sending,proto3" json:"sending,omitempty"`
+	Delivered     int64                  `protobuf:"varint,3,opt,name=delivered,proto3" json:"delivered,omitempty"`
+	Failed        int64                  `protobuf:"varint,4,opt,name=failed,proto3" json:"failed,omitempty"`
+	Deferred      int64                  `protobuf:"varint,5,opt,name=deferred,proto3" json:"deferred,omitempty"`
+	Total         int64                  `protobuf:"varint,6,opt,name=total,proto3" json:"total,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueueStats) Reset() {
+	*x = QueueStats{}
+	mi := &file_sendry_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueueStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueueStats) ProtoMessage() {}
+
+func (x *QueueStats) ProtoReflect() protoreflect.Message {
+	mi := &file_sendry_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueueStats.ProtoReflect.Descriptor instead.
+func (*QueueStats) Descriptor() ([]byte, []int) {
+	return file_sendry_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *QueueStats) GetPending() int64 {
+	if x != nil {
+		return x.Pending
+	}
+	return 0
+}
+
+func (x *QueueStats) GetSending() int64 {
+	if x != nil {
+		return x.Sending
+	}
+	return 0
+}
+
+func (x *QueueStats) GetDelivered() int64 {
+	if x != nil {
+		return x.Delivered
+	}
+	return 0
+}
+
+func (x *QueueStats) GetFailed() int64 {
+	if x != nil {
+		return x.Failed
+	}
+	return 0
+}
+
+func (x *QueueStats) GetDeferred() int64 {
+	if x != nil {
+		return x.Deferred
+	}
+	return 0
+}
+
+func (x *QueueStats) GetTotal() int64 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+// Filter of ListQueue, all set fields must match
+type ListQueueRequest struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Status          string                 `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	Sender          string                 `protobuf:"bytes,2,opt,name=sender,proto3" json:"sender,omitempty"`       // Substring of the sender address
+	Recipient       string                 `protobuf:"bytes,3,opt,name=recipient,proto3" json:"recipient,omitempty"` // Substring of a recipient address
+	SenderDomain    string                 `protobuf:"bytes,4,opt,name=sender_domain,json=senderDomain,proto3" json:"sender_domain,omitempty"`
+	RecipientDomain string                 `protobuf:"bytes,5,opt,name=recipient_domain,json=recipientDomain,proto3" json:"recipient_domain,omitempty"`
+	Domain          string                 `protobuf:"bytes,6,opt,name=domain,proto3" json:"domain,omitempty"` // Sender or recipient domain
+	IdPrefix        string                 `protobuf:"bytes,7,opt,name=id_prefix,json=idPrefix,proto3" json:"id_prefix,omitempty"`
+	Since           *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=since,proto3" json:"since,omitempty"`
+	Until           *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=until,proto3" json:"until,omitempty"`
+	Cursor          string                 `protobuf:"bytes,10,opt,name=cursor,proto3" json:"cursor,omitempty"` // next_cursor of the previous page
+	Limit           int32                  `protobuf:"varint,11,opt,name=limit,proto3" json:"limit,omitempty"`  // 1-1000, 100 if unset
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *ListQueueRequest) Reset() {
+	*x = ListQueueRequest{}
+	mi := &file_sendry_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListQueueRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListQueueRequest) ProtoMessage() {}
+
+func (x *ListQueueRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sendry_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListQueueRequest.ProtoReflect.Descriptor instead.
+func (*ListQueueRequest) Descriptor() ([]byte, []int) {
+	return file_sendry_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ListQueueRequest) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *ListQueueRequest) GetSender() string {
+	if x != nil {
+		return x.Sender
+	}
+	return ""
+}
+
+func (x *ListQueueRequest) GetRecipient() string {
+	if x != nil {
+		return x.Recipient
+	}
+	return ""
+}
+
+func (x *ListQueueRequest) GetSenderDomain() string {
+	if x != nil {
+		return x.SenderDomain
+	}
+	return ""
+}
+
+func (x *ListQueueRequest) GetRecipientDomain() string {
+	if x != nil {
+		return x.RecipientDomain
+	}
+	return ""
+}
+
+func (x *ListQueueRequest) GetDomain() string {
+	if x != nil {
+		return x.Domain
+	}
+	return ""
+}
+
+func (x *ListQueueRequest) GetIdPrefix() string {
+	if x != nil {
+		return x.IdPrefix
+	}
+	return ""
+}
+
+func (x *ListQueueRequest) GetSince() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Since
+	}
+	return nil
+}
+
+func (x *ListQueueRequest) GetUntil() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Until
+	}
+	return nil
+}
+
+func (x *ListQueueRequest) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+func (x *ListQueueRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type ListQueueResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Stats         *QueueStats            `protobuf:"bytes,1,opt,name=stats,proto3" json:"stats,omitempty"`
+	Messages      []*MessageSummary      `protobuf:"bytes,2,rep,name=messages,proto3" json:"messages,omitempty"`
+	NextCursor    string                 `protobuf:"bytes,3,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"` // Set when more messages may follow
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListQueueResponse) Reset() {
+	*x = ListQueueResponse{}
+	mi := &file_sendry_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListQueueResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListQueueResponse) ProtoMessage() {}
+
+func (x *ListQueueResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_sendry_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListQueueResponse.ProtoReflect.Descriptor instead.
+func (*ListQueueResponse) Descriptor() ([]byte, []int) {
+	return file_sendry_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ListQueueResponse) GetStats() *QueueStats {
+	if x != nil {
+		return x.Stats
+	}
+	return nil
+}
+
+func (x *ListQueueResponse) GetMessages() []*MessageSummary {
+	if x != nil {
+		return x.Messages
+	}
+	return nil
+}
+
+func (x *ListQueueResponse) GetNextCursor() string {
+	if x != nil {
+		return x.NextCursor
+	}
+	return ""
+}
+
+type GetMessageRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetMessageRequest) Reset() {
+	*x = GetMessageRequest{}
+	mi := &file_sendry_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetMessageRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetMessageRequest) ProtoMessage() {}
+
+func (x *GetMessageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sendry_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetMessageRequest.ProtoReflect.Descriptor instead.
+func (*GetMessageRequest) Descriptor() ([]byte, []int) {
+	return file_sendry_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *GetMessageRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type GetMessageResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Status        *MessageStatus         `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	Size          int64                  `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"` // Message size in bytes
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetMessageResponse) Reset() {
+	*x = GetMessageResponse{}
+	mi := &file_sendry_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetMessageResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetMessageResponse) ProtoMessage() {}
+
+func (x *GetMessageResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_sendry_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetMessageResponse.ProtoReflect.Descriptor instead.
+func (*GetMessageResponse) Descriptor() ([]byte, []int) {
+	return file_sendry_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *GetMessageResponse) GetStatus() *MessageStatus {
+	if x != nil {
+		return x.Status
+	}
+	return nil
+}
+
+func (x *GetMessageResponse) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+type DeleteMessageRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteMessageRequest) Reset() {
+	*x = DeleteMessageRequest{}
+	mi := &file_sendry_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteMessageRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteMessageRequest) ProtoMessage() {}
+
+func (x *DeleteMessageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sendry_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteMessageRequest.ProtoReflect.Descriptor instead.
+func (*DeleteMessageRequest) Descriptor() ([]byte, []int) {
+	return file_sendry_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *DeleteMessageRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type DeleteMessageResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteMessageResponse) Reset() {
+	*x = DeleteMessageResponse{}
+	mi := &file_sendry_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteMessageResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteMessageResponse) ProtoMessage() {}
+
+func (x *DeleteMessageResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_sendry_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteMessageResponse.ProtoReflect.Descriptor instead.
+func (*DeleteMessageResponse) Descriptor() ([]byte, []int) {
+	return file_sendry_proto_rawDescGZIP(), []int{16}
+}
+
+type DLQStats struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Total         int64                  `protobuf:"varint,1,opt,name=total,proto3" json:"total,omitempty"`
+	TotalSize     int64                  `protobuf:"varint,2,opt,name=total_size,json=totalSize,proto3" json:"total_size,omitempty"`
+	OldestAt      *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=oldest_at,json=oldestAt,proto3" json:"oldest_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DLQStats) Reset() {
+	*x = DLQStats{}
+	mi := &file_sendry_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DLQStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DLQStats) ProtoMessage() {}
+
+func (x *DLQStats) ProtoReflect() protoreflect.Message {
+	mi := &file_sendry_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DLQStats.ProtoReflect.Descriptor instead.
+func (*DLQStats) Descriptor() ([]byte, []int) {
+	return file_sendry_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *DLQStats) GetTotal() int64 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *DLQStats) GetTotalSize() int64 {
+	if x != nil {
+		return x.TotalSize
+	}
+	return 0
+}
+
+func (x *DLQStats) GetOldestAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.OldestAt
+	}
+	return nil
+}
+
+type ListDLQRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListDLQRequest) Reset() {
+	*x = ListDLQRequest{}
+	mi := &file_sendry_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListDLQRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListDLQRequest) ProtoMessage() {}
+
+func (x *ListDLQRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sendry_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListDLQRequest.ProtoReflect.Descriptor instead.
+func (*ListDLQRequest) Descriptor() ([]byte, []int) {
+	return file_sendry_proto_rawDescGZIP(), []int{18}
+}
+
+type ListDLQResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Stats         *DLQStats              `protobuf:"bytes,1,opt,name=stats,proto3" json:"stats,omitempty"`
+	Messages      []*MessageSummary      `protobuf:"bytes,2,rep,name=messages,proto3" json:"messages,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListDLQResponse) Reset() {
+	*x = ListDLQResponse{}
+	mi := &file_sendry_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListDLQResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListDLQResponse) ProtoMessage() {}
+
+func (x *ListDLQResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_sendry_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListDLQResponse.ProtoReflect.Descriptor instead.
+func (*ListDLQResponse) Descriptor() ([]byte, []int) {
+	return file_sendry_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *ListDLQResponse) GetStats() *DLQStats {
+	if x != nil {
+		return x.Stats
+	}
+	return nil
+}
+
+func (x *ListDLQResponse) GetMessages() []*MessageSummary {
+	if x != nil {
+		return x.Messages
+	}
+	return nil
+}
+
+type RetryDLQRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RetryDLQRequest) Reset() {
+	*x = RetryDLQRequest{}
+	mi := &file_sendry_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RetryDLQRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RetryDLQRequest) ProtoMessage() {}
+
+func (x *RetryDLQRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sendry_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RetryDLQRequest.ProtoReflect.Descriptor instead.
+func (*RetryDLQRequest) Descriptor() ([]byte, []int) {
+	return file_sendry_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *RetryDLQRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type RetryDLQResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RetryDLQResponse) Reset() {
+	*x = RetryDLQResponse{}
+	mi := &file_sendry_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RetryDLQResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RetryDLQResponse) ProtoMessage() {}
+
+func (x *RetryDLQResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_sendry_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RetryDLQResponse.ProtoReflect.Descriptor instead.
+func (*RetryDLQResponse) Descriptor() ([]byte, []int) {
+	return file_sendry_proto_rawDescGZIP(), []int{21}
+}
+
+type DeleteDLQRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteDLQRequest) Reset() {
+	*x = DeleteDLQRequest{}
+	mi := &file_sendry_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteDLQRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteDLQRequest) ProtoMessage() {}
+
+func (x *DeleteDLQRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sendry_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteDLQRequest.ProtoReflect.Descriptor instead.
+func (*DeleteDLQRequest) Descriptor() ([]byte, []int) {
+	return file_sendry_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *DeleteDLQRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type DeleteDLQResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteDLQResponse) Reset() {
+	*x = DeleteDLQResponse{}
+	mi := &file_sendry_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteDLQResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteDLQResponse) ProtoMessage() {}
+
+func (x *DeleteDLQResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_sendry_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteDLQResponse.ProtoReflect.Descriptor instead.
+func (*DeleteDLQResponse) Descriptor() ([]byte, []int) {
+	return file_sendry_proto_rawDescGZIP(), []int{23}
+}
+
+type GetPauseStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetPauseStatusRequest) Reset() {
+	*x = GetPauseStatusRequest{}
+	mi := &file_sendry_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPauseStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPauseStatusRequest) ProtoMessage() {}
+
+func (x *GetPauseStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sendry_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPauseStatusRequest.ProtoReflect.Descriptor instead.
+func (*GetPauseStatusRequest) Descriptor() ([]byte, []int) {
+	return file_sendry_proto_rawDescGZIP(), []int{24}
+}
+
+type PauseRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Domain        string                 `protobuf:"bytes,1,opt,name=domain,proto3" json:"domain,omitempty"` // All delivery if empty
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PauseRequest) Reset() {
+	*x = PauseRequest{}
+	mi := &file_sendry_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PauseRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PauseRequest) ProtoMessage() {}
+
+func (x *PauseRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sendry_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PauseRequest.ProtoReflect.Descriptor instead.
+func (*PauseRequest) Descriptor() ([]byte, []int) {
+	return file_sendry_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *PauseRequest) GetDomain() string {
+	if x != nil {
+		return x.Domain
+	}
+	return ""
+}
+
+type ResumeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Domain        string                 `protobuf:"bytes,1,opt,name=domain,proto3" json:"domain,omitempty"` // All delivery if empty
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResumeRequest) Reset() {
+	*x = ResumeRequest{}
+	mi := &file_sendry_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResumeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResumeRequest) ProtoMessage() {}
+
+func (x *ResumeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sendry_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResumeRequest.ProtoReflect.Descriptor instead.
+func (*ResumeRequest) Descriptor() ([]byte, []int) {
+	return file_sendry_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *ResumeRequest) GetDomain() string {
+	if x != nil {
+		return x.Domain
+	}
+	return ""
+}
+
+type PauseStatus struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Global        bool                   `protobuf:"varint,1,opt,name=global,proto3" json:"global,omitempty"`
+	Domains       []string               `protobuf:"bytes,2,rep,name=domains,proto3" json:"domains,omitempty"`
+	ConfigDomains []string               `protobuf:"bytes,3,rep,name=config_domains,json=configDomains,proto3" json:"config_domains,omitempty"` // Paused by domains.<name>.paused
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PauseStatus) Reset() {
+	*x = PauseStatus{}
+	mi := &file_sendry_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PauseStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PauseStatus) ProtoMessage() {}
+
+func (x *PauseStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_sendry_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PauseStatus.ProtoReflect.Descriptor instead.
+func (*PauseStatus) Descriptor() ([]byte, []int) {
+	return file_sendry_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *PauseStatus) GetGlobal() bool {
+	if x != nil {
+		return x.Global
+	}
+	return false
+}
+
+func (x *PauseStatus) GetDomains() []string {
+	if x != nil {
+		return x.Domains
+	}
+	return nil
+}
+
+func (x *PauseStatus) GetConfigDomains() []string {
+	if x != nil {
+		return x.ConfigDomains
+	}
+	return nil
+}
+
+type ListDomainsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListDomainsRequest) Reset() {
+	*x = ListDomainsRequest{}
+	mi := &file_sendry_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListDomainsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListDomainsRequest) ProtoMessage() {}
+
+func (x *ListDomainsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sendry_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListDomainsRequest.ProtoReflect.Descriptor instead.
+func (*ListDomainsRequest) Descriptor() ([]byte, []int) {
+	return file_sendry_proto_rawDescGZIP(), []int{28}
+}
+
+type ListDomainsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Domains       []*Domain              `protobuf:"bytes,1,rep,name=domains,proto3" json:"domains,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListDomainsResponse) Reset() {
+	*x = ListDomainsResponse{}
+	mi := &file_sendry_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListDomainsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListDomainsResponse) ProtoMessage() {}
+
+func (x *ListDomainsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_sendry_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListDomainsResponse.ProtoReflect.Descriptor instead.
+func (*ListDomainsResponse) Descriptor() ([]byte, []int) {
+	return file_sendry_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *ListDomainsResponse) GetDomains() []*Domain {
+	if x != nil {
+		return x.Domains
+	}
+	return nil
+}
+
+type GetDomainRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Domain        string                 `protobuf:"bytes,1,opt,name=domain,proto3" json:"domain,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetDomainRequest) Reset() {
+	*x = GetDomainRequest{}
+	mi := &file_sendry_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetDomainRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDomainRequest) ProtoMessage() {}
+
+func (x *GetDomainRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sendry_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDomainRequest.ProtoReflect.Descriptor instead.
+func (*GetDomainRequest) Descriptor() ([]byte, []int) {
+	return file_sendry_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *GetDomainRequest) GetDomain() string {
+	if x != nil {
+		return x.Domain
+	}
+	return ""
+}
+
+type DomainDKIM struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Enabled       bool                   `protobuf:"varint,1,opt,name=enabled,proto3" json:"enabled,omitempty"`
+	Selector      string                 `protobuf:"bytes,2,opt,name=selector,proto3" json:"selector,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DomainDKIM) Reset() {
+	*x = DomainDKIM{}
+	mi := &file_sendry_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DomainDKIM) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DomainDKIM) ProtoMessage() {}
+
+func (x *DomainDKIM) ProtoReflect() protoreflect.Message {
+	mi := &file_sendry_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DomainDKIM.ProtoReflect.Descriptor instead.
+func (*DomainDKIM) Descriptor() ([]byte, []int) {
+	return file_sendry_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *DomainDKIM) GetEnabled() bool {
+	if x != nil {
+		return x.Enabled
+	}
+	return false
+}
+
+func (x *DomainDKIM) GetSelector() string {
+	if x != nil {
+		return x.Selector
+	}
+	return ""
+}
+
+type DomainRateLimit struct {
+	state                protoimpl.MessageState `protogen:"open.v1"`
+	MessagesPerMinute    int32                  `protobuf:"varint,1,opt,name=messages_per_minute,json=messagesPerMinute,proto3" json:"messages_per_minute,omitempty"`
+	MessagesPerHour      int32                  `protobuf:"varint,2,opt,name=messages_per_hour,json=messagesPerHour,proto3" json:"messages_per_hour,omitempty"`
+	MessagesPerDay       int32                  `protobuf:"varint,3,opt,name=messages_per_day,json=messagesPerDay,proto3" json:"messages_per_day,omitempty"`
+	RecipientsPerMessage int32                  `protobuf:"varint,4,opt,name=recipients_per_message,json=recipientsPerMessage,proto3" json:"recipients_per_message,omitempty"`
+	unknownFields        protoimpl.UnknownFields
+	sizeCache            protoimpl.SizeCache
+}
+
+func (x *DomainRateLimit) Reset() {
+	*x = DomainRateLimit{}
+	mi := &file_sendry_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DomainRateLimit) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DomainRateLimit) ProtoMessage() {}
+
+func (x *DomainRateLimit) ProtoReflect() protoreflect.Message {
+	mi := &file_sendry_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DomainRateLimit.ProtoReflect.Descriptor instead.
+func (*DomainRateLimit) Descriptor() ([]byte, []int) {
+	return file_sendry_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *DomainRateLimit) GetMessagesPerMinute() int32 {
+	if x != nil {
+		return x.MessagesPerMinute
+	}
+	return 0
+}
+
+func (x *DomainRateLimit) GetMessagesPerHour() int32 {
+	if x != nil {
+		return x.MessagesPerHour
+	}
+	return 0
+}
+
+func (x *DomainRateLimit) GetMessagesPerDay() int32 {
+	if x != nil {
+		return x.MessagesPerDay
+	}
+	return 0
+}
+
+func (x *DomainRateLimit) GetRecipientsPerMessage() int32 {
+	if x != nil {
+		return x.RecipientsPerMessage
+	}
+	return 0
+}
+
+type Domain struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Domain        string                 `protobuf:"bytes,1,opt,name=domain,proto3" json:"domain,omitempty"`
+	Mode          string                 `protobuf:"bytes,2,opt,name=mode,proto3" json:"mode,omitempty"` // production, sandbox, redirect or bcc
+	DefaultFrom   string                 `protobuf:"bytes,3,opt,name=default_from,json=defaultFrom,proto3" json:"default_from,omitempty"`
+	RedirectTo    []string               `protobuf:"bytes,4,rep,name=redirect_to,json=redirectTo,proto3" json:"redirect_to,omitempty"`
+	BccTo         []string               `protobuf:"bytes,5,rep,name=bcc_to,json=bccTo,proto3" json:"bcc_to,omitempty"`
+	Paused        bool                   `protobuf:"varint,6,opt,name=paused,proto3" json:"paused,omitempty"`
+	Dkim          *DomainDKIM            `protobuf:"bytes,7,opt,name=dkim,proto3" json:"dkim,omitempty"`
+	RateLimit     *DomainRateLimit       `protobuf:"bytes,8,opt,name=rate_limit,json=rateLimit,proto3" json:"rate_limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Domain) Reset() {
+	*x = Domain{}
+	mi := &file_sendry_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Domain) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Domain) ProtoMessage() {}
+
+func (x *Domain) ProtoReflect() protoreflect.Message {
+	mi := &file_sendry_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Domain.ProtoReflect.Descriptor instead.
+func (*Domain) Descriptor() ([]byte, []int) {
+	return file_sendry_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *Domain) GetDomain() string {
+	if x != nil {
+		return x.Domain
+	}
+	return ""
+}
+
+func (x *Domain) GetMode() string {
+	if x != nil {
+		return x.Mode
+	}
+	return ""
+}
+
+func (x *Domain) GetDefaultFrom() string {
+	if x != nil {
+		return x.DefaultFrom
+	}
+	return ""
+}
+
+func (x *Domain) GetRedirectTo() []string {
+	if x != nil {
+		return x.RedirectTo
+	}
+	return nil
+}
+
+func (x *Domain) GetBccTo() []string {
+	if x != nil {
+		return x.BccTo
+	}
+	return nil
+}
+
+func (x *Domain) GetPaused() bool {
+	if x != nil {
+		return x.Paused
+	}
+	return false
+}
+
+func (x *Domain) GetDkim() *DomainDKIM {
+	if x != nil {
+		return x.Dkim
+	}
+	return nil
+}
+
+func (x *Domain) GetRateLimit() *DomainRateLimit {
+	if x != nil {
+		return x.RateLimit
+	}
+	return nil
+}
+
+// Filter of WatchEvents, all set fields must match
+type WatchEventsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Events        []string               `protobuf:"bytes,1,rep,name=events,proto3" json:"events,omitempty"` // accepted, delivered, deferred, bounced; all if empty
+	MessageId     string                 `protobuf:"bytes,2,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`
+	SenderDomain  string                 `protobuf:"bytes,3,opt,name=sender_domain,json=senderDomain,proto3" json:"sender_domain,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchEventsRequest) Reset() {
+	*x = WatchEventsRequest{}
+	mi := &file_sendry_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchEventsRequest) ProtoMessage() {}
+
+func (x *WatchEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sendry_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchEventsRequest.ProtoReflect.Descriptor instead.
+func (*WatchEventsRequest) Descriptor() ([]byte, []int) {
+	return file_sendry_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *WatchEventsRequest) GetEvents() []string {
+	if x != nil {
+		return x.Events
+	}
+	return nil
+}
+
+func (x *WatchEventsRequest) GetMessageId() string {
+	if x != nil {
+		return x.MessageId
+	}
+	return ""
+}
+
+func (x *WatchEventsRequest) GetSenderDomain() string {
+	if x != nil {
+		return x.SenderDomain
+	}
+	return ""
+}
+
+// Outcome of a recipient in a delivery event
+type EventRecipient struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Address       string                 `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	Status        string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	MxHost        string                 `protobuf:"bytes,3,opt,name=mx_host,json=mxHost,proto3" json:"mx_host,omitempty"`
+	Error         string                 `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EventRecipient) Reset() {
+	*x = EventRecipient{}
+	mi := &file_sendry_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EventRecipient) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EventRecipient) ProtoMessage() {}
+
+func (x *EventRecipient) ProtoReflect() protoreflect.Message {
+	mi := &file_sendry_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EventRecipient.ProtoReflect.Descriptor instead.
+func (*EventRecipient) Descriptor() ([]byte, []int) {
+	return file_sendry_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *EventRecipient) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *EventRecipient) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *EventRecipient) GetMxHost() string {
+	if x != nil {
+		return x.MxHost
+	}
+	return ""
+}
+
+func (x *EventRecipient) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// Delivery event of a message, as in the delivery log
+type Event struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Timestamp     *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Event         string                 `protobuf:"bytes,2,opt,name=event,proto3" json:"event,omitempty"` // accepted, delivered, deferred or bounced
+	Host          string                 `protobuf:"bytes,3,opt,name=host,proto3" json:"host,omitempty"`
+	MessageId     string                 `protobuf:"bytes,4,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`
+	From          string                 `protobuf:"bytes,5,opt,name=from,proto3" json:"from,omitempty"`
+	SenderDomain  string                 `protobuf:"bytes,6,opt,name=sender_domain,json=senderDomain,proto3" json:"sender_domain,omitempty"`
+	To            []string               `protobuf:"bytes,7,rep,name=to,proto3" json:"to,omitempty"`
+	Size          int64                  `protobuf:"varint,8,opt,name=size,proto3" json:"size,omitempty"`
+	RetryCount    int32                  `protobuf:"varint,9,opt,name=retry_count,json=retryCount,proto3" json:"retry_count,omitempty"`
+	ClientIp      string                 `protobuf:"bytes,10,opt,name=client_ip,json=clientIp,proto3" json:"client_ip,omitempty"`
+	AuthUser      string                 `protobuf:"bytes,11,opt,name=auth_user,json=authUser,proto3" json:"auth_user,omitempty"`
+	ApiKeyId      string                 `protobuf:"bytes,12,opt,name=api_key_id,json=apiKeyId,proto3" json:"api_key_id,omitempty"`
+	NextRetryAt   *timestamppb.Timestamp `protobuf:"bytes,13,opt,name=next_retry_at,json=nextRetryAt,proto3" json:"next_retry_at,omitempty"`
+	Error         string                 `protobuf:"bytes,14,opt,name=error,proto3" json:"error,omitempty"`
+	Recipients    []*EventRecipient      `protobuf:"bytes,15,rep,name=recipients,proto3" json:"recipients,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Event) Reset() {
+	*x = Event{}
+	mi := &file_sendry_proto_msgTypes[36]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Event) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Event) ProtoMessage() {}
+
+func (x *Event) ProtoReflect() protoreflect.Message {
+	mi := &file_sendry_proto_msgTypes[36]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Event.ProtoReflect.Descriptor instead.
+func (*Event) Descriptor() ([]byte, []int) {
+	return file_sendry_proto_rawDescGZIP(), []int{36}
+}
+
+func (x *Event) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+func (x *Event) GetEvent() string {
+	if x != nil {
+		return x.Event
+	}
+	return ""
+}
+
+func (x *Event) GetHost() string {
+	if x != nil {
+		return x.Host
+	}
+	return ""
+}
+
+func (x *Event) GetMessageId() string {
+	if x != nil {
+		return x.MessageId
+	}
+	return ""
+}
+
+func (x *Event) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *Event) GetSenderDomain() string {
+	if x != nil {
+		return x.SenderDomain
+	}
+	return ""
+}
+
+func (x *Event) GetTo() []string {
+	if x != nil {
+		return x.To
+	}
+	return nil
+}
+
+func (x *Event) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *Event) GetRetryCount() int32 {
+	if x != nil {
+		return x.RetryCount
+	}
+	return 0
+}
+
+func (x *Event) GetClientIp() string {
+	if x != nil {
+		return x.ClientIp
+	}
+	return ""
+}
+
+func (x *Event) GetAuthUser() string {
+	if x != nil {
+		return x.AuthUser
+	}
+	return ""
+}
+
+func (x *Event) GetApiKeyId() string {
+	if x != nil {
+		return x.ApiKeyId
+	}
+	return ""
+}
+
+func (x *Event) GetNextRetryAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.NextRetryAt
+	}
+	return nil
+}
+
+func (x *Event) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *Event) GetRecipients() []*EventRecipient {
+	if x != nil {
+		return x.Recipients
+	}
+	return nil
+}
+
+var File_sendry_proto protoreflect.FileDescriptor
+
+const file_sendry_proto_rawDesc = "" +
+	"\n" +
+	"\fsendry.proto\x12\tsendry.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"e\n" +
+	"\n" +
+	"Attachment\x12\x1a\n" +
+	"\bfilename\x18\x01 \x01(\tR\bfilename\x12!\n" +
+	"\fcontent_type\x18\x02 \x01(\tR\vcontentType\x12\x18\n" +
+	"\acontent\x18\x03 \x01(\fR\acontent\"\xcc\x03\n" +
+	"\vSendRequest\x12\x12\n" +
+	"\x04from\x18\x01 \x01(\tR\x04from\x12\x0e\n" +
+	"\x02to\x18\x02 \x03(\tR\x02to\x12\x0e\n" +
+	"\x02cc\x18\x03 \x03(\tR\x02cc\x12\x10\n" +
+	"\x03bcc\x18\x04 \x03(\tR\x03bcc\x12\x18\n" +
+	"\asubject\x18\x05 \x01(\tR\asubject\x12\x12\n" +
+	"\x04body\x18\x06 \x01(\tR\x04body\x12\x12\n" +
+	"\x04html\x18\a \x01(\tR\x04html\x12=\n" +
+	"\aheaders\x18\b \x03(\v2#.sendry.v1.SendRequest.HeadersEntryR\aheaders\x127\n" +
+	"\vattachments\x18\t \x03(\v2\x15.sendry.v1.AttachmentR\vattachments\x123\n" +
+	"\asend_at\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\x06sendAt\x12/\n" +
+	"\bpriority\x18\v \x01(\x0e2\x13.sendry.v1.PriorityR\bpriority\x12\x1b\n" +
+	"\tskip_dkim\x18\f \x01(\bR\bskipDkim\x1a:\n" +
+	"\fHeadersEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xcb\x01\n" +
+	"\x0eSendRawRequest\x12\x12\n" +
+	"\x04from\x18\x01 \x01(\tR\x04from\x12\x0e\n" +
+	"\x02to\x18\x02 \x03(\tR\x02to\x12\x12\n" +
+	"\x04data\x18\x03 \x01(\fR\x04data\x123\n" +
+	"\asend_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\x06sendAt\x12/\n" +
+	"\bpriority\x18\x05 \x01(\x0e2\x13.sendry.v1.PriorityR\bpriority\x12\x1b\n" +
+	"\tskip_dkim\x18\x06 \x01(\bR\bskipDkim\"k\n" +
+	"\fSendResponse\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x123\n" +
+	"\asend_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\x06sendAt\"\x85\x01\n" +
+	"\x12SendStreamResponse\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\x03R\x05index\x12/\n" +
+	"\x06result\x18\x02 \x01(\v2\x17.sendry.v1.SendResponseR\x06result\x12\x12\n" +
+	"\x04code\x18\x03 \x01(\x05R\x04code\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\"\"\n" +
+	"\x10GetStatusRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\xe7\x01\n" +
+	"\x0fRecipientResult\x12\x16\n" +
+	"\x06status\x18\x01 \x01(\tR\x06status\x12\x17\n" +
+	"\amx_host\x18\x02 \x01(\tR\x06mxHost\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\x12\x18\n" +
+	"\aexpired\x18\x04 \x01(\bR\aexpired\x12\x18\n" +
+	"\abounced\x18\x05 \x01(\bR\abounced\x12\x1e\n" +
+	"\n" +
+	"greylisted\x18\x06 \x01(\bR\n" +
+	"greylisted\x129\n" +
+	"\n" +
+	"updated_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\"\xec\x01\n" +
+	"\x0fDeliveryAttempt\x128\n" +
+	"\ttimestamp\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x12\x1c\n" +
+	"\trecipient\x18\x02 \x01(\tR\trecipient\x12\x17\n" +
+	"\amx_host\x18\x03 \x01(\tR\x06mxHost\x12\x18\n" +
+	"\asuccess\x18\x04 \x01(\bR\asuccess\x12\x1c\n" +
+	"\tpermanent\x18\x05 \x01(\bR\tpermanent\x12\x14\n" +
+	"\x05error\x18\x06 \x01(\tR\x05error\x12\x1a\n" +
+	"\bresponse\x18\a \x01(\tR\bresponse\"\xda\x04\n" +
+	"\rMessageStatus\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x12\x12\n" +
+	"\x04from\x18\x03 \x01(\tR\x04from\x12\x0e\n" +
+	"\x02to\x18\x04 \x03(\tR\x02to\x129\n" +
+	"\n" +
+	"created_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\x12\x1f\n" +
+	"\vretry_count\x18\a \x01(\x05R\n" +
+	"retryCount\x12\x1d\n" +
+	"\n" +
+	"last_error\x18\b \x01(\tR\tlastError\x123\n" +
+	"\asend_at\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\x06sendAt\x12\x1a\n" +
+	"\bpriority\x18\n" +
+	" \x01(\tR\bpriority\x12\x19\n" +
+	"\btrace_id\x18\v \x01(\tR\atraceId\x12H\n" +
+	"\n" +
+	"recipients\x18\f \x03(\v2(.sendry.v1.MessageStatus.RecipientsEntryR\n" +
+	"recipients\x126\n" +
+	"\battempts\x18\r \x03(\v2\x1a.sendry.v1.DeliveryAttemptR\battempts\x1aY\n" +
+	"\x0fRecipientsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x120\n" +
+	"\x05value\x18\x02 \x01(\v2\x1a.sendry.v1.RecipientResultR\x05value:\x028\x01\"\xe8\x01\n" +
+	"\x0eMessageSummary\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04from\x18\x02 \x01(\tR\x04from\x12\x0e\n" +
+	"\x02to\x18\x03 \x03(\tR\x02to\x12\x16\n" +
+	"\x06status\x18\x04 \x01(\tR\x06status\x129\n" +
+	"\n" +
+	"created_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x123\n" +
+	"\asend_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\x06sendAt\x12\x1a\n" +
+	"\bpriority\x18\a \x01(\tR\bpriority\"\xa8\x01\n" +
+	"\n" +
+	"QueueStats\x12\x18\n" +
+	"\apending\x18\x01 \x01(\x03R\apending\x12\x18\n" +
+	"\asending\x18\x02 \x01(\x03R\asending\x12\x1c\n" +
+	"\tdelivered\x18\x03 \x01(\x03R\tdelivered\x12\x16\n" +
+	"\x06failed\x18\x04 \x01(\x03R\x06failed\x12\x1a\n" +
+	"\bdeferred\x18\x05 \x01(\x03R\bdeferred\x12\x14\n" +
+	"\x05total\x18\x06 \x01(\x03R\x05total\"\xf7\x02\n" +
+	"\x10ListQueueRequest\x12\x16\n" +
+	"\x06status\x18\x01 \x01(\tR\x06status\x12\x16\n" +
+	"\x06sender\x18\x02 \x01(\tR\x06sender\x12\x1c\n" +
+	"\trecipient\x18\x03 \x01(\tR\trecipient\x12#\n" +
+	"\rsender_domain\x18\x04 \x01(\tR\fsenderDomain\x12)\n" +
+	"\x10recipient_domain\x18\x05 \x01(\tR\x0frecipientDomain\x12\x16\n" +
+	"\x06domain\x18\x06 \x01(\tR\x06domain\x12\x1b\n" +
+	"\tid_prefix\x18\a \x01(\tR\bidPrefix\x120\n" +
+	"\x05since\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\x05since\x120\n" +
+	"\x05until\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\x05until\x12\x16\n" +
+	"\x06cursor\x18\n" +
+	" \x01(\tR\x06cursor\x12\x14\n" +
+	"\x05limit\x18\v \x01(\x05R\x05limit\"\x98\x01\n" +
+	"\x11ListQueueResponse\x12+\n" +
+	"\x05stats\x18\x01 \x01(\v2\x15.sendry.v1.QueueStatsR\x05stats\x125\n" +
+	"\bmessages\x18\x02 \x03(\v2\x19.sendry.v1.MessageSummaryR\bmessages\x12\x1f\n" +
+	"\vnext_cursor\x18\x03 \x01(\tR\n" +
+	"nextCursor\"#\n" +
+	"\x11GetMessageRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"Z\n" +
+	"\x12GetMessageResponse\x120\n" +
+	"\x06status\x18\x01 \x01(\v2\x18.sendry.v1.MessageStatusR\x06status\x12\x12\n" +
+	"\x04size\x18\x02 \x01(\x03R\x04size\"&\n" +
+	"\x14DeleteMessageRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\x17\n" +
+	"\x15DeleteMessageResponse\"x\n" +
+	"\bDLQStats\x12\x14\n" +
+	"\x05total\x18\x01 \x01(\x03R\x05total\x12\x1d\n" +
+	"\n" +
+	"total_size\x18\x02 \x01(\x03R\ttotalSize\x127\n" +
+	"\toldest_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\boldestAt\"\x10\n" +
+	"\x0eListDLQRequest\"s\n" +
+	"\x0fListDLQResponse\x12)\n" +
+	"\x05stats\x18\x01 \x01(\v2\x13.sendry.v1.DLQStatsR\x05stats\x125\n" +
+	"\bmessages\x18\x02 \x03(\v2\x19.sendry.v1.MessageSummaryR\bmessages\"!\n" +
+	"\x0fRetryDLQRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\x12\n" +
+	"\x10RetryDLQResponse\"\"\n" +
+	"\x10DeleteDLQRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\x13\n" +
+	"\x11DeleteDLQResponse\"\x17\n" +
+	"\x15GetPauseStatusRequest\"&\n" +
+	"\fPauseRequest\x12\x16\n" +
+	"\x06domain\x18\x01 \x01(\tR\x06domain\"'\n" +
+	"\rResumeRequest\x12\x16\n" +
+	"\x06domain\x18\x01 \x01(\tR\x06domain\"f\n" +
+	"\vPauseStatus\x12\x16\n" +
+	"\x06global\x18\x01 \x01(\bR\x06global\x12\x18\n" +
+	"\adomains\x18\x02 \x03(\tR\adomains\x12%\n" +
+	"\x0econfig_domains\x18\x03 \x03(\tR\rconfigDomains\"\x14\n" +
+	"\x12ListDomainsRequest\"B\n" +
+	"\x13ListDomainsResponse\x12+\n" +
+	"\adomains\x18\x01 \x03(\v2\x11.sendry.v1.DomainR\adomains\"*\n" +
+	"\x10GetDomainRequest\x12\x16\n" +
+	"\x06domain\x18\x01 \x01(\tR\x06domain\"B\n" +
+	"\n" +
+	"DomainDKIM\x12\x18\n" +
+	"\aenabled\x18\x01 \x01(\bR\aenabled\x12\x1a\n" +
+	"\bselector\x18\x02 \x01(\tR\bselector\"\xcd\x01\n" +
+	"\x0fDomainRateLimit\x12.\n" +
+	"\x13messages_per_minute\x18\x01 \x01(\x05R\x11messagesPerMinute\x12*\n" +
+	"\x11messages_per_hour\x18\x02 \x01(\x05R\x0fmessagesPerHour\x12(\n" +
+	"\x10messages_per_day\x18\x03 \x01(\x05R\x0emessagesPerDay\x124\n" +
+	"\x16recipients_per_message\x18\x04 \x01(\x05R\x14recipientsPerMessage\"\x8d\x02\n" +
+	"\x06Domain\x12\x16\n" +
+	"\x06domain\x18\x01 \x01(\tR\x06domain\x12\x12\n" +
+	"\x04mode\x18\x02 \x01(\tR\x04mode\x12!\n" +
+	"\fdefault_from\x18\x03 \x01(\tR\vdefaultFrom\x12\x1f\n" +
+	"\vredirect_to\x18\x04 \x03(\tR\n" +
+	"redirectTo\x12\x15\n" +
+	"\x06bcc_to\x18\x05 \x03(\tR\x05bccTo\x12\x16\n" +
+	"\x06paused\x18\x06 \x01(\bR\x06paused\x12)\n" +
+	"\x04dkim\x18\a \x01(\v2\x15.sendry.v1.DomainDKIMR\x04dkim\x129\n" +
+	"\n" +
+	"rate_limit\x18\b \x01(\v2\x1a.sendry.v1.DomainRateLimitR\trateLimit\"p\n" +
+	"\x12WatchEventsRequest\x12\x16\n" +
+	"\x06events\x18\x01 \x03(\tR\x06events\x12\x1d\n" +
+	"\n" +
+	"message_id\x18\x02 \x01(\tR\tmessageId\x12#\n" +
+	"\rsender_domain\x18\x03 \x01(\tR\fsenderDomain\"q\n" +
+	"\x0eEventRecipient\x12\x18\n" +
+	"\aaddress\x18\x01 \x01(\tR\aaddress\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x12\x17\n" +
+	"\amx_host\x18\x03 \x01(\tR\x06mxHost\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\"\xf1\x03\n" +
+	"\x05Event\x128\n" +
+	"\ttimestamp\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x12\x14\n" +
+	"\x05event\x18\x02 \x01(\tR\x05event\x12\x12\n" +
+	"\x04host\x18\x03 \x01(\tR\x04host\x12\x1d\n" +
+	"\n" +
+	"message_id\x18\x04 \x01(\tR\tmessageId\x12\x12\n" +
+	"\x04from\x18\x05 \x01(\tR\x04from\x12#\n" +
+	"\rsender_domain\x18\x06 \x01(\tR\fsenderDomain\x12\x0e\n" +
+	"\x02to\x18\a \x03(\tR\x02to\x12\x12\n" +
+	"\x04size\x18\b \x01(\x03R\x04size\x12\x1f\n" +
+	"\vretry_count\x18\t \x01(\x05R\n" +
+	"retryCount\x12\x1b\n" +
+	"\tclient_ip\x18\n" +
+	" \x01(\tR\bclientIp\x12\x1b\n" +
+	"\tauth_user\x18\v \x01(\tR\bauthUser\x12\x1c\n" +
+	"\n" +
+	"api_key_id\x18\f \x01(\tR\bapiKeyId\x12>\n" +
+	"\rnext_retry_at\x18\r \x01(\v2\x1a.google.protobuf.TimestampR\vnextRetryAt\x12\x14\n" +
+	"\x05error\x18\x0e \x01(\tR\x05error\x129\n" +
+	"\n" +
+	"recipients\x18\x0f \x03(\v2\x19.sendry.v1.EventRecipientR\n" +
+	"recipients*^\n" +
+	"\bPriority\x12\x18\n" +
+	"\x14PRIORITY_UNSPECIFIED\x10\x00\x12\x11\n" +
+	"\rPRIORITY_HIGH\x10\x01\x12\x13\n" +
+	"\x0fPRIORITY_NORMAL\x10\x02\x12\x10\n" +
+	"\fPRIORITY_LOW\x10\x032\xd2\b\n" +
+	"\x06Sendry\x127\n" +
+	"\x04Send\x12\x16.sendry.v1.SendRequest\x1a\x17.sendry.v1.SendResponse\x12=\n" +
+	"\aSendRaw\x12\x19.sendry.v1.SendRawRequest\x1a\x17.sendry.v1.SendResponse\x12G\n" +
+	"\n" +
+	"SendStream\x12\x16.sendry.v1.SendRequest\x1a\x1d.sendry.v1.SendStreamResponse(\x010\x01\x12B\n" +
+	"\tGetStatus\x12\x1b.sendry.v1.GetStatusRequest\x1a\x18.sendry.v1.MessageStatus\x12F\n" +
+	"\tListQueue\x12\x1b.sendry.v1.ListQueueRequest\x1a\x1c.sendry.v1.ListQueueResponse\x12I\n" +
+	"\n" +
+	"GetMessage\x12\x1c.sendry.v1.GetMessageRequest\x1a\x1d.sendry.v1.GetMessageResponse\x12R\n" +
+	"\rDeleteMessage\x12\x1f.sendry.v1.DeleteMessageRequest\x1a .sendry.v1.DeleteMessageResponse\x12@\n" +
+	"\aListDLQ\x12\x19.sendry.v1.ListDLQRequest\x1a\x1a.sendry.v1.ListDLQResponse\x12C\n" +
+	"\bRetryDLQ\x12\x1a.sendry.v1.RetryDLQRequest\x1a\x1b.sendry.v1.RetryDLQResponse\x12F\n" +
+	"\tDeleteDLQ\x12\x1b.sendry.v1.DeleteDLQRequest\x1a\x1c.sendry.v1.DeleteDLQResponse\x12J\n" +
+	"\x0eGetPauseStatus\x12 .sendry.v1.GetPauseStatusRequest\x1a\x16.sendry.v1.PauseStatus\x128\n" +
+	"\x05Pause\x12\x17.sendry.v1.PauseRequest\x1a\x16.sendry.v1.PauseStatus\x12:\n" +
+	"\x06Resume\x12\x18.sendry.v1.ResumeRequest\x1a\x16.sendry.v1.PauseStatus\x12L\n" +
+	"\vListDomains\x12\x1d.sendry.v1.ListDomainsRequest\x1a\x1e.sendry.v1.ListDomainsResponse\x12;\n" +
+	"\tGetDomain\x12\x1b.sendry.v1.GetDomainRequest\x1a\x11.sendry.v1.Domain\x12@\n" +
+	"\vWatchEvents\x12\x1d.sendry.v1.WatchEventsRequest\x1a\x10.sendry.v1.Event0\x01B&Z$github.com/foxzi/sendry/pkg/sendrypbb\x06proto3"
+
+var (
+	file_sendry_proto_rawDescOnce sync.Once
+	file_sendry_proto_rawDescData []byte
+)
+
+func file_sendry_proto_rawDescGZIP() []byte {
+	file_sendry_proto_rawDescOnce.Do(func() {
+		file_sendry_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_sendry_proto_rawDesc), len(file_sendry_proto_rawDesc)))
+	})
+	return file_sendry_proto_rawDescData
+}
+
+var file_sendry_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_sendry_proto_msgTypes = make([]protoimpl.MessageInfo, 39)
+var file_sendry_proto_goTypes = []any{
+	(Priority)(0),                 // 0: sendry.v1.Priority
+	(*Attachment)(nil),            // 1: sendry.v1.Attachment
+	(*SendRequest)(nil),           // 2: sendry.v1.SendRequest
+	(*SendRawRequest)(nil),        // 3: sendry.v1.SendRawRequest
+	(*SendResponse)(nil),          // 4: sendry.v1.SendResponse
+	(*SendStreamResponse)(nil),    // 5: sendry.v1.SendStreamResponse
+	(*GetStatusRequest)(nil),      // 6: sendry.v1.GetStatusRequest
+	(*RecipientResult)(nil),       // 7: sendry.v1.RecipientResult
+	(*DeliveryAttempt)(nil),       // 8: sendry.v1.DeliveryAttempt
+	(*MessageStatus)(nil),         // 9: sendry.v1.MessageStatus
+	(*MessageSummary)(nil),        // 10: sendry.v1.MessageSummary
+	(*QueueStats)(nil),            // 11: sendry.v1.QueueStats
+	(*ListQueueRequest)(nil),      // 12: sendry.v1.ListQueueRequest
+	(*ListQueueResponse)(nil),     // 13: sendry.v1.ListQueueResponse
+	(*GetMessageRequest)(nil),     // 14: sendry.v1.GetMessageRequest
+	(*GetMessageResponse)(nil),    // 15: sendry.v1.GetMessageResponse
+	(*DeleteMessageRequest)(nil),  // 16: sendry.v1.DeleteMessageRequest
+	(*DeleteMessageResponse)(nil), // 17: sendry.v1.DeleteMessageResponse
+	(*DLQStats)(nil),              // 18: sendry.v1.DLQStats
+	(*ListDLQRequest)(nil),        // 19: sendry.v1.ListDLQRequest
+	(*ListDLQResponse)(nil),       // 20: sendry.v1.ListDLQResponse
+	(*RetryDLQRequest)(nil),       // 21: sendry.v1.RetryDLQRequest
+	(*RetryDLQResponse)(nil),      // 22: sendry.v1.RetryDLQResponse
+	(*DeleteDLQRequest)(nil),      // 23: sendry.v1.DeleteDLQRequest
+	(*DeleteDLQResponse)(nil),     // 24: sendry.v1.DeleteDLQResponse
+	(*GetPauseStatusRequest)(nil), // 25: sendry.v1.GetPauseStatusRequest
+	(*PauseRequest)(nil),          // 26: sendry.v1.PauseRequest
+	(*ResumeRequest)(nil),         // 27: sendry.v1.ResumeRequest
+	(*PauseStatus)(nil),           // 28: sendry.v1.PauseStatus
+	(*ListDomainsRequest)(nil),    // 29: sendry.v1.ListDomainsRequest
+	(*ListDomainsResponse)(nil),   // 30: sendry.v1.ListDomainsResponse
+	(*GetDomainRequest)(nil),      // 31: sendry.v1.GetDomainRequest
+	(*DomainDKIM)(nil),            // 32: sendry.v1.DomainDKIM
+	(*DomainRateLimit)(nil),       // 33: sendry.v1.DomainRateLimit
+	(*Domain)(nil),                // 34: sendry.v1.Domain
+	(*WatchEventsRequest)(nil),    // 35: sendry.v1.WatchEventsRequest
+	(*EventRecipient)(nil),        // 36: sendry.v1.EventRecipient
+	(*Event)(nil),                 // 37: sendry.v1.Event
+	nil,                           // 38: sendry.v1.SendRequest.HeadersEntry
+	nil,                           // 39: sendry.v1.MessageStatus.RecipientsEntry
+	(*timestamppb.Timestamp)(nil), // 40: google.protobuf.Timestamp
+}
+var file_sendry_proto_depIdxs = []int32{
+	38, // 0: sendry.v1.SendRequest.headers:type_name -> sendry.v1.SendRequest.HeadersEntry
+	1,  // 1: sendry.v1.SendRequest.attachments:type_name -> sendry.v1.Attachment
+	40, // 2: sendry.v1.SendRequest.send_at:type_name -> google.protobuf.Timestamp
+	0,  // 3: sendry.v1.SendRequest.priority:type_name -> sendry.v1.Priority
+	40, // 4: sendry.v1.SendRawRequest.send_at:type_name -> google.protobuf.Timestamp
+	0,  // 5: sendry.v1.SendRawRequest.priority:type_name -> sendry.v1.Priority
+	40, // 6: sendry.v1.SendResponse.send_at:type_name -> google.protobuf.Timestamp
+	4,  // 7: sendry.v1.SendStreamResponse.result:type_name -> sendry.v1.SendResponse
+	40, // 8: sendry.v1.RecipientResult.updated_at:type_name -> google.protobuf.Timestamp
+	40, // 9: sendry.v1.DeliveryAttempt.timestamp:type_name -> google.protobuf.Timestamp
+	40, // 10: sendry.v1.MessageStatus.created_at:type_name -> google.protobuf.Timestamp
+	40, // 11: sendry.v1.MessageStatus.updated_at:type_name -> google.protobuf.Timestamp
+	40, // 12: sendry.v1.MessageStatus.send_at:type_name -> google.protobuf.Timestamp
+	39, // 13: sendry.v1.MessageStatus.recipients:type_name -> sendry.v1.MessageStatus.RecipientsEntry
+	8,  // 14: sendry.v1.MessageStatus.attempts:type_name -> sendry.v1.DeliveryAttempt
+	40, // 15: sendry.v1.MessageSummary.created_at:type_name -> google.protobuf.Timestamp
+	40, // 16: sendry.v1.MessageSummary.send_at:type_name -> google.protobuf.Timestamp
+	40, // 17: sendry.v1.ListQueueRequest.since:type_name -> google.protobuf.Timestamp
+	40, // 18: sendry.v1.ListQueueRequest.until:type_name -> google.protobuf.Timestamp
+	11, // 19: sendry.v1.ListQueueResponse.stats:type_name -> sendry.v1.QueueStats
+	10, // 20: sendry.v1.ListQueueResponse.messages:type_name -> sendry.v1.MessageSummary
+	9,  // 21: sendry.v1.GetMessageResponse.status:type_name -> sendry.v1.MessageStatus
+	40, // 22: sendry.v1.DLQStats.oldest_at:type_name -> google.protobuf.Timestamp
+	18, // 23: sendry.v1.ListDLQResponse.stats:type_name -> sendry.v1.DLQStats
+	10, // 24: sendry.v1.ListDLQResponse.messages:type_name -> sendry.v1.MessageSummary
+	34, // 25: sendry.v1.ListDomainsResponse.domains:type_name -> sendry.v1.Domain
+	32, // 26: sendry.v1.Domain.dkim:type_name -> sendry.v1.DomainDKIM
+	33, // 27: sendry.v1.Domain.rate_limit:type_name -> sendry.v1.DomainRateLimit
+	40, // 28: sendry.v1.Event.timestamp:type_name -> google.protobuf.Timestamp
+	40, // 29: sendry.v1.Event.next_retry_at:type_name -> google.protobuf.Timestamp
+	36, // 30: sendry.v1.Event.recipients:type_name -> sendry.v1.EventRecipient
+	7,  // 31: sendry.v1.MessageStatus.RecipientsEntry.value:type_name -> sendry.v1.RecipientResult
+	2,  // 32: sendry.v1.Sendry.Send:input_type -> sendry.v1.SendRequest
+	3,  // 33: sendry.v1.Sendry.SendRaw:input_type -> sendry.v1.SendRawRequest
+	2,  // 34: sendry.v1.Sendry.SendStream:input_type -> sendry.v1.SendRequest
+	6,  // 35: sendry.v1.Sendry.GetStatus:input_type -> sendry.v1.GetStatusRequest
+	12, // 36: sendry.v1.Sendry.ListQueue:input_type -> sendry.v1.ListQueueRequest
+	14, // 37: sendry.v1.Sendry.GetMessage:input_type -> sendry.v1.GetMessageRequest
+	16, // 38: sendry.v1.Sendry.DeleteMessage:input_type -> sendry.v1.DeleteMessageRequest
+	19, // 39: sendry.v1.Sendry.ListDLQ:input_type -> sendry.v1.ListDLQRequest
+	21, // 40: sendry.v1.Sendry.RetryDLQ:input_type -> sendry.v1.RetryDLQRequest
+	23, // 41: sendry.v1.Sendry.DeleteDLQ:input_type -> sendry.v1.DeleteDLQRequest
+	25, // 42: sendry.v1.Sendry.GetPauseStatus:input_type -> sendry.v1.GetPauseStatusRequest
+	26, // 43: sendry.v1.Sendry.Pause:input_type -> sendry.v1.PauseRequest
+	27, // 44: sendry.v1.Sendry.Resume:input_type -> sendry.v1.ResumeRequest
+	29, // 45: sendry.v1.Sendry.ListDomains:input_type -> sendry.v1.ListDomainsRequest
+	31, // 46: sendry.v1.Sendry.GetDomain:input_type -> sendry.v1.GetDomainRequest
+	35, // 47: sendry.v1.Sendry.WatchEvents:input_type -> sendry.v1.WatchEventsRequest
+	4,  // 48: sendry.v1.Sendry.Send:output_type -> sendry.v1.SendResponse
+	4,  // 49: sendry.v1.Sendry.SendRaw:output_type -> sendry.v1.SendResponse
+	5,  // 50: sendry.v1.Sendry.SendStream:output_type -> sendry.v1.SendStreamResponse
+	9,  // 51: sendry.v1.Sendry.GetStatus:output_type -> sendry.v1.MessageStatus
+	13, // 52: sendry.v1.Sendry.ListQueue:output_type -> sendry.v1.ListQueueResponse
+	15, // 53: sendry.v1.Sendry.GetMessage:output_type -> sendry.v1.GetMessageResponse
+	17, // 54: sendry.v1.Sendry.DeleteMessage:output_type -> sendry.v1.DeleteMessageResponse
+	20, // 55: sendry.v1.Sendry.ListDLQ:output_type -> sendry.v1.ListDLQResponse
+	22, // 56: sendry.v1.Sendry.RetryDLQ:output_type -> sendry.v1.RetryDLQResponse
+	24, // 57: sendry.v1.Sendry.DeleteDLQ:output_type -> sendry.v1.DeleteDLQResponse
+	28, // 58: sendry.v1.Sendry.GetPauseStatus:output_type -> sendry.v1.PauseStatus
+	28, // 59: sendry.v1.Sendry.Pause:output_type -> sendry.v1.PauseStatus
+	28, // 60: sendry.v1.Sendry.Resume:output_type -> sendry.v1.PauseStatus
+	30, // 61: sendry.v1.Sendry.ListDomains:output_type -> sendry.v1.ListDomainsResponse
+	34, // 62: sendry.v1.Sendry.GetDomain:output_type -> sendry.v1.Domain
+	37, // 63: sendry.v1.Sendry.WatchEvents:output_type -> sendry.v1.Event
+	48, // [48:64] is the sub-list for method output_type
+	32, // [32:48] is the sub-list for method input_type
+	32, // [32:32] is the sub-list for extension type_name
+	32, // [32:32] is the sub-list for extension extendee
+	0,  // [0:32] is the sub-list for field type_name
+}
+
+func init() { file_sendry_proto_init() }
+func file_sendry_proto_init() {
+	if File_sendry_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_sendry_proto_rawDesc), len(file_sendry_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   39,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_sendry_proto_goTypes,
+		DependencyIndexes: file_sendry_proto_depIdxs,
+		EnumInfos:         file_sendry_proto_enumTypes,
+		MessageInfos:      file_sendry_proto_msgTypes,
+	}.Build()
+	File_sendry_proto = out.File
+	file_sendry_proto_goTypes = nil
+	file_sendry_proto_depIdxs = nil
+}
@@ -0,0 +1,730 @@
+// gRPC API of the Sendry MTA. Requests carry the API key in the
+// "authorization" ("Bearer <key>") or "x-api-key" metadata.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             (unknown)
+// source: sendry.proto
+
+package sendrypb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Sendry_Send_FullMethodName           = "/sendry.v1.Sendry/Send"
+	Sendry_SendRaw_FullMethodName        = "/sendry.v1.Sendry/SendRaw"
+	Sendry_SendStream_FullMethodName     = "/sendry.v1.Sendry/SendStream"
+	Sendry_GetStatus_FullMethodName      = "/sendry.v1.Sendry/GetStatus"
+	Sendry_ListQueue_FullMethodName      = "/sendry.v1.Sendry/ListQueue"
+	Sendry_GetMessage_FullMethodName     = "/sendry.v1.Sendry/GetMessage"
+	Sendry_DeleteMessage_FullMethodName  = "/sendry.v1.Sendry/DeleteMessage"
+	Sendry_ListDLQ_FullMethodName        = "/sendry.v1.Sendry/ListDLQ"
+	Sendry_RetryDLQ_FullMethodName       = "/sendry.v1.Sendry/RetryDLQ"
+	Sendry_DeleteDLQ_FullMethodName      = "/sendry.v1.Sendry/DeleteDLQ"
+	Sendry_GetPauseStatus_FullMethodName = "/sendry.v1.Sendry/GetPauseStatus"
+	Sendry_Pause_FullMethodName          = "/sendry.v1.Sendry/Pause"
+	Sendry_Resume_FullMethodName         = "/sendry.v1.Sendry/Resume"
+	Sendry_ListDomains_FullMethodName    = "/sendry.v1.Sendry/ListDomains"
+	Sendry_GetDomain_FullMethodName      = "/sendry.v1.Sendry/GetDomain"
+	Sendry_WatchEvents_FullMethodName    = "/sendry.v1.Sendry/WatchEvents"
+)
+
+// SendryClient is the client API for Sendry service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Sendry sends mail and manages the queue of a Sendry MTA
+type SendryClient interface {
+	// Send queues a message built from its parts, like POST /api/v1/send
+	Send(ctx context.Context, in *SendRequest, opts ...grpc.CallOption) (*SendResponse, error)
+	// SendRaw queues a complete RFC 5322 message, like POST /api/v1/send/raw
+	SendRaw(ctx context.Context, in *SendRawRequest, opts ...grpc.CallOption) (*SendResponse, error)
+	// SendStream queues the messages of a stream and answers each one, in
+	// order, with its result. A refused message does not end the stream.
+	SendStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[SendRequest, SendStreamResponse], error)
+	// GetStatus returns the delivery status of a message
+	GetStatus(ctx context.Context, in *GetStatusRequest, opts ...grpc.CallOption) (*MessageStatus, error)
+	// ListQueue returns the queue counters and a page of messages
+	ListQueue(ctx context.Context, in *ListQueueRequest, opts ...grpc.CallOption) (*ListQueueResponse, error)
+	// GetMessage returns a queued message
+	GetMessage(ctx context.Context, in *GetMessageRequest, opts ...grpc.CallOption) (*GetMessageResponse, error)
+	// DeleteMessage removes a message from the queue
+	DeleteMessage(ctx context.Context, in *DeleteMessageRequest, opts ...grpc.CallOption) (*DeleteMessageResponse, error)
+	// ListDLQ returns the dead letter queue counters and messages
+	ListDLQ(ctx context.Context, in *ListDLQRequest, opts ...grpc.CallOption) (*ListDLQResponse, error)
+	// RetryDLQ moves a message of the dead letter queue back to the queue
+	RetryDLQ(ctx context.Context, in *RetryDLQRequest, opts ...grpc.CallOption) (*RetryDLQResponse, error)
+	// DeleteDLQ removes a message from the dead letter queue
+	DeleteDLQ(ctx context.Context, in *DeleteDLQRequest, opts ...grpc.CallOption) (*DeleteDLQResponse, error)
+	// GetPauseStatus returns the paused deliveries
+	GetPauseStatus(ctx context.Context, in *GetPauseStatusRequest, opts ...grpc.CallOption) (*PauseStatus, error)
+	// Pause pauses delivery of a domain, or all delivery
+	Pause(ctx context.Context, in *PauseRequest, opts ...grpc.CallOption) (*PauseStatus, error)
+	// Resume resumes delivery of a domain, or all delivery
+	Resume(ctx context.Context, in *ResumeRequest, opts ...grpc.CallOption) (*PauseStatus, error)
+	// ListDomains returns the sending domains
+	ListDomains(ctx context.Context, in *ListDomainsRequest, opts ...grpc.CallOption) (*ListDomainsResponse, error)
+	// GetDomain returns a sending domain
+	GetDomain(ctx context.Context, in *GetDomainRequest, opts ...grpc.CallOption) (*Domain, error)
+	// WatchEvents streams the delivery events of messages as they happen
+	WatchEvents(ctx context.Context, in *WatchEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error)
+}
+
+type sendryClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSendryClient(cc grpc.ClientConnInterface) SendryClient {
+	return &sendryClient{cc}
+}
+
+func (c *sendryClient) Send(ctx context.Context, in *SendRequest, opts ...grpc.CallOption) (*SendResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SendResponse)
+	err := c.cc.Invoke(ctx, Sendry_Send_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sendryClient) SendRaw(ctx context.Context, in *SendRawRequest, opts ...grpc.CallOption) (*SendResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SendResponse)
+	err := c.cc.Invoke(ctx, Sendry_SendRaw_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sendryClient) SendStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[SendRequest, SendStreamResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Sendry_ServiceDesc.Streams[0], Sendry_SendStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SendRequest, SendStreamResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Sendry_SendStreamClient = grpc.BidiStreamingClient[SendRequest, SendStreamResponse]
+
+func (c *sendryClient) GetStatus(ctx context.Context, in *GetStatusRequest, opts ...grpc.CallOption) (*MessageStatus, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MessageStatus)
+	err := c.cc.Invoke(ctx, Sendry_GetStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sendryClient) ListQueue(ctx context.Context, in *ListQueueRequest, opts ...grpc.CallOption) (*ListQueueResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListQueueResponse)
+	err := c.cc.Invoke(ctx, Sendry_ListQueue_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sendryClient) GetMessage(ctx context.Context, in *GetMessageRequest, opts ...grpc.CallOption) (*GetMessageResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetMessageResponse)
+	err := c.cc.Invoke(ctx, Sendry_GetMessage_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sendryClient) DeleteMessage(ctx context.Context, in *DeleteMessageRequest, opts ...grpc.CallOption) (*DeleteMessageResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteMessageResponse)
+	err := c.cc.Invoke(ctx, Sendry_DeleteMessage_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sendryClient) ListDLQ(ctx context.Context, in *ListDLQRequest, opts ...grpc.CallOption) (*ListDLQResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListDLQResponse)
+	err := c.cc.Invoke(ctx, Sendry_ListDLQ_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sendryClient) RetryDLQ(ctx context.Context, in *RetryDLQRequest, opts ...grpc.CallOption) (*RetryDLQResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RetryDLQResponse)
+	err := c.cc.Invoke(ctx, Sendry_RetryDLQ_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sendryClient) DeleteDLQ(ctx context.Context, in *DeleteDLQRequest, opts ...grpc.CallOption) (*DeleteDLQResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteDLQResponse)
+	err := c.cc.Invoke(ctx, Sendry_DeleteDLQ_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sendryClient) GetPauseStatus(ctx context.Context, in *GetPauseStatusRequest, opts ...grpc.CallOption) (*PauseStatus, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PauseStatus)
+	err := c.cc.Invoke(ctx, Sendry_GetPauseStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sendryClient) Pause(ctx context.Context, in *PauseRequest, opts ...grpc.CallOption) (*PauseStatus, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PauseStatus)
+	err := c.cc.Invoke(ctx, Sendry_Pause_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sendryClient) Resume(ctx context.Context, in *ResumeRequest, opts ...grpc.CallOption) (*PauseStatus, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PauseStatus)
+	err := c.cc.Invoke(ctx, Sendry_Resume_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sendryClient) ListDomains(ctx context.Context, in *ListDomainsRequest, opts ...grpc.CallOption) (*ListDomainsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListDomainsResponse)
+	err := c.cc.Invoke(ctx, Sendry_ListDomains_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sendryClient) GetDomain(ctx context.Context, in *GetDomainRequest, opts ...grpc.CallOption) (*Domain, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Domain)
+	err := c.cc.Invoke(ctx, Sendry_GetDomain_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sendryClient) WatchEvents(ctx context.Context, in *WatchEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Sendry_ServiceDesc.Streams[1], Sendry_WatchEvents_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchEventsRequest, Event]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Sendry_WatchEventsClient = grpc.ServerStreamingClient[Event]
+
+// SendryServer is the server API for Sendry service.
+// All implementations must embed UnimplementedSendryServer
+// for forward compatibility.
+//
+// Sendry sends mail and manages the queue of a Sendry MTA
+type SendryServer interface {
+	// Send queues a message built from its parts, like POST /api/v1/send
+	Send(context.Context, *SendRequest) (*SendResponse, error)
+	// SendRaw queues a complete RFC 5322 message, like POST /api/v1/send/raw
+	SendRaw(context.Context, *SendRawRequest) (*SendResponse, error)
+	// SendStream queues the messages of a stream and answers each one, in
+	// order, with its result. A refused message does not end the stream.
+	SendStream(grpc.BidiStreamingServer[SendRequest, SendStreamResponse]) error
+	// GetStatus returns the delivery status of a message
+	GetStatus(context.Context, *GetStatusRequest) (*MessageStatus, error)
+	// ListQueue returns the queue counters and a page of messages
+	ListQueue(context.Context, *ListQueueRequest) (*ListQueueResponse, error)
+	// GetMessage returns a queued message
+	GetMessage(context.Context, *GetMessageRequest) (*GetMessageResponse, error)
+	// DeleteMessage removes a message from the queue
+	DeleteMessage(context.Context, *DeleteMessageRequest) (*DeleteMessageResponse, error)
+	// ListDLQ returns the dead letter queue counters and messages
+	ListDLQ(context.Context, *ListDLQRequest) (*ListDLQResponse, error)
+	// RetryDLQ moves a message of the dead letter queue back to the queue
+	RetryDLQ(context.Context, *RetryDLQRequest) (*RetryDLQResponse, error)
+	// DeleteDLQ removes a message from the dead letter queue
+	DeleteDLQ(context.Context, *DeleteDLQRequest) (*DeleteDLQResponse, error)
+	// GetPauseStatus returns the paused deliveries
+	GetPauseStatus(context.Context, *GetPauseStatusRequest) (*PauseStatus, error)
+	// Pause pauses delivery of a domain, or all delivery
+	Pause(context.Context, *PauseRequest) (*PauseStatus, error)
+	// Resume resumes delivery of a domain, or all delivery
+	Resume(context.Context, *ResumeRequest) (*PauseStatus, error)
+	// ListDomains returns the sending domains
+	ListDomains(context.Context, *ListDomainsRequest) (*ListDomainsResponse, error)
+	// GetDomain returns a sending domain
+	GetDomain(context.Context, *GetDomainRequest) (*Domain, error)
+	// WatchEvents streams the delivery events of messages as they happen
+	WatchEvents(*WatchEventsRequest, grpc.ServerStreamingServer[Event]) error
+	mustEmbedUnimplementedSendryServer()
+}
+
+// UnimplementedSendryServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedSendryServer struct{}
+
+func (UnimplementedSendryServer) Send(context.Context, *SendRequest) (*SendResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Send not implemented")
+}
+func (UnimplementedSendryServer) SendRaw(context.Context, *SendRawRequest) (*SendResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method SendRaw not implemented")
+}
+func (UnimplementedSendryServer) SendStream(grpc.BidiStreamingServer[SendRequest, SendStreamResponse]) error {
+	return status.Error(codes.Unimplemented, "method SendStream not implemented")
+}
+func (UnimplementedSendryServer) GetStatus(context.Context, *GetStatusRequest) (*MessageStatus, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetStatus not implemented")
+}
+func (UnimplementedSendryServer) ListQueue(context.Context, *ListQueueRequest) (*ListQueueResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListQueue not implemented")
+}
+func (UnimplementedSendryServer) GetMessage(context.Context, *GetMessageRequest) (*GetMessageResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetMessage not implemented")
+}
+func (UnimplementedSendryServer) DeleteMessage(context.Context, *DeleteMessageRequest) (*DeleteMessageResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method DeleteMessage not implemented")
+}
+func (UnimplementedSendryServer) ListDLQ(context.Context, *ListDLQRequest) (*ListDLQResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListDLQ not implemented")
+}
+func (UnimplementedSendryServer) RetryDLQ(context.Context, *RetryDLQRequest) (*RetryDLQResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method RetryDLQ not implemented")
+}
+func (UnimplementedSendryServer) DeleteDLQ(context.Context, *DeleteDLQRequest) (*DeleteDLQResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method DeleteDLQ not implemented")
+}
+func (UnimplementedSendryServer) GetPauseStatus(context.Context, *GetPauseStatusRequest) (*PauseStatus, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetPauseStatus not implemented")
+}
+func (UnimplementedSendryServer) Pause(context.Context, *PauseRequest) (*PauseStatus, error) {
+	return nil, status.Error(codes.Unimplemented, "method Pause not implemented")
+}
+func (UnimplementedSendryServer) Resume(context.Context, *ResumeRequest) (*PauseStatus, error) {
+	return nil, status.Error(codes.Unimplemented, "method Resume not implemented")
+}
+func (UnimplementedSendryServer) ListDomains(context.Context, *ListDomainsRequest) (*ListDomainsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListDomains not implemented")
+}
+func (UnimplementedSendryServer) GetDomain(context.Context, *GetDomainRequest) (*Domain, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetDomain not implemented")
+}
+func (UnimplementedSendryServer) WatchEvents(*WatchEventsRequest, grpc.ServerStreamingServer[Event]) error {
+	return status.Error(codes.Unimplemented, "method WatchEvents not implemented")
+}
+func (UnimplementedSendryServer) mustEmbedUnimplementedSendryServer() {}
+func (UnimplementedSendryServer) testEmbeddedByValue()                {}
+
+// UnsafeSendryServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SendryServer will
+// result in compilation errors.
+type UnsafeSendryServer interface {
+	mustEmbedUnimplementedSendryServer()
+}
+
+func RegisterSendryServer(s grpc.ServiceRegistrar, srv SendryServer) {
+	// If the following call panics, it indicates UnimplementedSendryServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Sendry_ServiceDesc, srv)
+}
+
+func _Sendry_Send_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SendRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SendryServer).Send(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sendry_Send_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SendryServer).Send(ctx, req.(*SendRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sendry_SendRaw_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SendRawRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SendryServer).SendRaw(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sendry_SendRaw_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SendryServer).SendRaw(ctx, req.(*SendRawRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sendry_SendStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(SendryServer).SendStream(&grpc.GenericServerStream[SendRequest, SendStreamResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Sendry_SendStreamServer = grpc.BidiStreamingServer[SendRequest, SendStreamResponse]
+
+func _Sendry_GetStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SendryServer).GetStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sendry_GetStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SendryServer).GetStatus(ctx, req.(*GetStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sendry_ListQueue_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListQueueRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SendryServer).ListQueue(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sendry_ListQueue_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SendryServer).ListQueue(ctx, req.(*ListQueueRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sendry_GetMessage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMessageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SendryServer).GetMessage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sendry_GetMessage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SendryServer).GetMessage(ctx, req.(*GetMessageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sendry_DeleteMessage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteMessageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SendryServer).DeleteMessage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sendry_DeleteMessage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SendryServer).DeleteMessage(ctx, req.(*DeleteMessageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sendry_ListDLQ_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListDLQRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SendryServer).ListDLQ(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sendry_ListDLQ_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SendryServer).ListDLQ(ctx, req.(*ListDLQRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sendry_RetryDLQ_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RetryDLQRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SendryServer).RetryDLQ(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sendry_RetryDLQ_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SendryServer).RetryDLQ(ctx, req.(*RetryDLQRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sendry_DeleteDLQ_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteDLQRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SendryServer).DeleteDLQ(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sendry_DeleteDLQ_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SendryServer).DeleteDLQ(ctx, req.(*DeleteDLQRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sendry_GetPauseStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPauseStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SendryServer).GetPauseStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sendry_GetPauseStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SendryServer).GetPauseStatus(ctx, req.(*GetPauseStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sendry_Pause_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PauseRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SendryServer).Pause(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sendry_Pause_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SendryServer).Pause(ctx, req.(*PauseRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sendry_Resume_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResumeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SendryServer).Resume(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sendry_Resume_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SendryServer).Resume(ctx, req.(*ResumeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sendry_ListDomains_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListDomainsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SendryServer).ListDomains(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sendry_ListDomains_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SendryServer).ListDomains(ctx, req.(*ListDomainsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sendry_GetDomain_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDomainRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SendryServer).GetDomain(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sendry_GetDomain_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SendryServer).GetDomain(ctx, req.(*GetDomainRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sendry_WatchEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SendryServer).WatchEvents(m, &grpc.GenericServerStream[WatchEventsRequest, Event]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Sendry_WatchEventsServer = grpc.ServerStreamingServer[Event]
+
+// Sendry_ServiceDesc is the grpc.ServiceDesc for Sendry service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Sendry_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "sendry.v1.Sendry",
+	HandlerType: (*SendryServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Send",
+			Handler:    _Sendry_Send_Handler,
+		},
+		{
+			MethodName: "SendRaw",
+			Handler:    _Sendry_SendRaw_Handler,
+		},
+		{
+			MethodName: "GetStatus",
+			Handler:    _Sendry_GetStatus_Handler,
+		},
+		{
+			MethodName: "ListQueue",
+			Handler:    _Sendry_ListQueue_Handler,
+		},
+		{
+			MethodName: "GetMessage",
+			Handler:    _Sendry_GetMessage_Handler,
+		},
+		{
+			MethodName: "DeleteMessage",
+			Handler:    _Sendry_DeleteMessage_Handler,
+		},
+		{
+			MethodName: "ListDLQ",
+			Handler:    _Sendry_ListDLQ_Handler,
+		},
+		{
+			MethodName: "RetryDLQ",
+			Handler:    _Sendry_RetryDLQ_Handler,
+		},
+		{
+			MethodName: "DeleteDLQ",
+			Handler:    _Sendry_DeleteDLQ_Handler,
+		},
+		{
+			MethodName: "GetPauseStatus",
+			Handler:    _Sendry_GetPauseStatus_Handler,
+		},
+		{
+			MethodName: "Pause",
+			Handler:    _Sendry_Pause_Handler,
+		},
+		{
+			MethodName: "Resume",
+			Handler:    _Sendry_Resume_Handler,
+		},
+		{
+			MethodName: "ListDomains",
+			Handler:    _Sendry_ListDomains_Handler,
+		},
+		{
+			MethodName: "GetDomain",
+			Handler:    _Sendry_GetDomain_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SendStream",
+			Handler:       _Sendry_SendStream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "WatchEvents",
+			Handler:       _Sendry_WatchEvents_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "sendry.proto",
+}